@@ -33,6 +33,13 @@ load_config
 PROJECTS_DIR="${MUXCODE_PROJECTS_DIR:-$HOME}"
 SCAN_DEPTH="${MUXCODE_SCAN_DEPTH:-3}"
 WINDOWS="${MUXCODE_WINDOWS:-edit api build test review deploy run watch commit analyze}"
+TEMPLATE="${MUXCODE_TEMPLATE:-}"
+
+# A session template's window list replaces WINDOWS
+if [ -n "$TEMPLATE" ]; then
+  TEMPLATE_WINDOWS="$(muxcode-agent-bus template show "$TEMPLATE" --windows)" || exit 1
+  [ -n "$TEMPLATE_WINDOWS" ] && WINDOWS="$TEMPLATE_WINDOWS"
+fi
 ROLE_MAP="${MUXCODE_ROLE_MAP:-run=runner commit=git analyze=analyst}"
 SPLIT_LEFT="${MUXCODE_SPLIT_LEFT:-edit api build test review deploy run analyze commit watch}"
 SHELL_INIT="${MUXCODE_SHELL_INIT:-}"
@@ -107,6 +114,16 @@ echo "  Project:  $PROJECT_DIR"
 echo "  Session:  $SESSION"
 echo ""
 
+# --- Add windows for roles declared in the project's muxcode.json ---
+for win in $(cd "$PROJECT_DIR" && muxcode-agent-bus role list --windows 2>/dev/null); do
+  case " $WINDOWS " in
+    *" $win "*) ;;
+    *) WINDOWS="$WINDOWS $win" ;;
+  esac
+done
+CONFIG_ROLE_MAP="$(cd "$PROJECT_DIR" && muxcode-agent-bus role list --role-map 2>/dev/null)"
+[ -n "$CONFIG_ROLE_MAP" ] && ROLE_MAP="$ROLE_MAP $CONFIG_ROLE_MAP"
+
 # --- Parse window list ---
 read -ra WIN_ARRAY <<< "$WINDOWS"
 
@@ -156,17 +173,25 @@ tmux set-hook -gu session-created 2>/dev/null || true
 # --- Clean up stale preview temp files from previous sessions ---
 rm -f "/tmp/muxcode-preview-${SESSION}.tmp"
 
+# --- Pre-flight: report config problems before agents start ---
+if ! CONFIG_CHECK="$(cd "$PROJECT_DIR" && muxcode-agent-bus config validate 2>&1)"; then
+  echo "$CONFIG_CHECK" >&2
+  echo "  Warning: fix the config problems above (muxcode-agent-bus config validate)" >&2
+fi
+
 # --- Initialize agent bus ---
 export BUS_SESSION="$SESSION"
-(cd "$PROJECT_DIR" && muxcode-agent-bus init)
+(cd "$PROJECT_DIR" && muxcode-agent-bus init --no-watcher-check ${TEMPLATE:+--template "$TEMPLATE"})
 
-# --- Start bus watcher in background (loop detection, compaction alerts) ---
-# Kill any stale watcher processes from previous sessions with the same name.
+# --- Start bus watcher daemon (loop detection, compaction alerts) ---
+# Stop any stale watcher from a previous session with the same name.
 # Watchers are background processes detached from tmux — tmux kill-session
 # does not stop them, so they accumulate and cause duplicate notifications.
+# The pkill catches foreground watchers started by older versions.
+muxcode-agent-bus watch stop "$SESSION" &>/dev/null || true
 pkill -f "muxcode-agent-bus watch $SESSION" 2>/dev/null || true
 sleep 0.1  # let old processes exit before starting the new one
-muxcode-agent-bus watch "$SESSION" &>/dev/null &
+(cd "$PROJECT_DIR" && muxcode-agent-bus watch start --daemon "$SESSION" >/dev/null)
 
 # --- Ensure Ollama is running if any role uses local LLM ---
 ensure_ollama() {
@@ -27,6 +27,13 @@ load_config
 
 AGENT_CLI="${MUXCODE_AGENT_CLI:-claude}"
 
+# Report liveness for crash detection: the heartbeat loop follows this
+# shell's PID, which every exec below hands to the agent process
+if [ -n "${TMUX:-}" ] && command -v muxcode-agent-bus >/dev/null 2>&1; then
+  muxcode-agent-bus heartbeat --pid $$ >/dev/null 2>&1 &
+  disown 2>/dev/null
+fi
+
 # Check for per-role local LLM override (e.g. MUXCODE_GIT_CLI=local for commit agent)
 # Maps role -> env var name: commit->GIT, build->BUILD, test->TEST, etc.
 role_cli_var() {
@@ -51,7 +58,7 @@ if [ "$ROLE_CLI" = "local" ]; then
   if curl -s --max-time 2 "${OLLAMA_URL}/api/tags" >/dev/null 2>&1; then
     HARNESS_ARGS=(run "$ROLE")
     # Per-role model: MUXCODE_{ROLE}_MODEL (e.g. MUXCODE_GIT_MODEL=llama3.1:8b)
-    # Resolution: per-role env → MUXCODE_OLLAMA_MODEL → default (qwen2.5:7b)
+    # Resolution: per-role env → muxcode.json role model → MUXCODE_OLLAMA_MODEL → default (qwen2.5:7b)
     role_model_var() {
       case "$1" in
         commit|git) echo "MUXCODE_GIT_MODEL" ;;
@@ -66,6 +73,8 @@ if [ "$ROLE_CLI" = "local" ]; then
     }
     ROLE_MODEL_VAR="$(role_model_var "$ROLE")"
     ROLE_MODEL="${!ROLE_MODEL_VAR:-}"
+    # Then the role's "model" in muxcode.json
+    [ -z "$ROLE_MODEL" ] && ROLE_MODEL="$(muxcode-agent-bus role model "$ROLE" 2>/dev/null)"
     if [ -n "$ROLE_MODEL" ]; then
       HARNESS_ARGS+=(--model "$ROLE_MODEL")
     elif [ -n "${MUXCODE_OLLAMA_MODEL:-}" ]; then
@@ -100,6 +109,7 @@ agent_name() {
     watch)    echo "log-watcher" ;;
     pr-read)  echo "pr-reader" ;;
     api)      echo "api-tester" ;;
+    *)        echo "$1" ;;  # custom roles: agents/<role>.md
   esac
 }
 
@@ -21,8 +21,12 @@ FILE_PATH=$(echo "$EVENT_JSON" | jq -r '.tool_input.file_path // .tool_input.not
 [[ "$FILE_PATH" == */.claude/* ]] && exit 0
 [[ "$FILE_PATH" == */.muxcode/* ]] && exit 0
 
-# Append to the trigger file for the watcher
-echo "$(date +%s) $FILE_PATH" >> "/tmp/muxcode-analyze-${SESSION}.trigger"
+# Record a JSONL edit event (path, operation, byte delta, content hashes)
+# for the watcher; fall back to a bare entry if the bus binary is missing
+if ! printf '%s' "$EVENT_JSON" | BUS_SESSION="$SESSION" muxcode-agent-bus edit-event 2>/dev/null; then
+  jq -nc --arg path "$FILE_PATH" --argjson ts "$(date +%s)" '{ts: $ts, path: $path, op: "edit"}' \
+    >> "/tmp/muxcode-analyze-${SESSION}.jsonl" 2>/dev/null
+fi
 
 # Clean up nvim diff preview, reload file, and jump to the change
 WINDOW_NAME="$(tmux display-message -t "${TMUX_PANE:-}" -p '#W' 2>/dev/null)"
@@ -42,9 +46,11 @@ if [ "$WINDOW_NAME" = "edit" ] && [ -f "$TEMP_FILE" ]; then
   rm -f "$TEMP_FILE"
 fi
 
-# Route file-change events through the agent bus
+# Route file-change events through the agent bus. When muxcode.json has
+# "routing" rules the watcher routes batched edits instead (it creates the
+# watcher-routing marker), so skip per-file routing here.
 BUS_DIR="/tmp/muxcode-bus-${SESSION}"
-if [ -d "$BUS_DIR" ]; then
+if [ -d "$BUS_DIR" ] && [ ! -f "$BUS_DIR/watcher-routing" ]; then
   export BUS_SESSION="$SESSION"
   export AGENT_ROLE="$(tmux display-message -t "${TMUX_PANE:-}" -p '#W' 2>/dev/null || echo 'edit')"
 
@@ -0,0 +1,25 @@
+#!/bin/bash
+# muxcode-cost-guard.sh — PreToolUse hook for Bash (deploy and runner windows)
+#
+# Holds costly cloud commands (large cdk deploys, aws ec2 run-instances,
+# ...) until an approver allows them. The patterns, guarded roles, and
+# approvers come from cost_guard in muxcode.json; the bus decides, and
+# requests the approval on the first attempt.
+#
+# Runs synchronously (not async) so it can return a block decision
+# before the command is executed.
+
+SESSION=$(tmux display-message -p '#S' 2>/dev/null) || exit 0
+WINDOW_NAME=$(tmux display-message -t "${TMUX_PANE:-}" -p '#W' 2>/dev/null) || exit 0
+command -v muxcode-agent-bus >/dev/null 2>&1 || exit 0
+
+EVENT_JSON=$(cat)
+COMMAND=$(echo "$EVENT_JSON" | jq -r '.tool_input.command // empty' 2>/dev/null)
+[ -z "$COMMAND" ] && exit 0
+
+# Exit 1 with a reason means blocked; errors (no reason) let the command run
+REASON=$(BUS_SESSION="$SESSION" muxcode-agent-bus cost check --role "$WINDOW_NAME" -- "$COMMAND" 2>/dev/null)
+if [ $? -ne 0 ] && [ -n "$REASON" ]; then
+  jq -n --arg r "$REASON" '{"decision":"block","reason":$r}'
+fi
+exit 0
@@ -3,7 +3,7 @@ set -euo pipefail
 
 REPO_DIR="$(cd "$(dirname "$0")" && pwd)"
 
-for moddir in "$REPO_DIR"/tools/*/; do
+for moddir in "$REPO_DIR"/tools/*/ "$REPO_DIR"/tools/*/pkg/*/; do
   [ -f "$moddir/go.mod" ] || continue
   name="$(basename "$moddir")"
   echo "=== $name: go vet ==="
@@ -96,7 +96,7 @@ func AgentLoop(ctx context.Context, cfg AgentConfig) error {
 			return fmt.Errorf("Ollama health check failed: %w", err)
 		}
 	}
-	fmt.Fprintf(os.Stderr, "[agent] Connected to Ollama (%s), model: %s\n", cfg.Ollama.BaseURL, cfg.Ollama.Model)
+	fmt.Fprintf(os.Stderr, "[agent] Connected to Ollama (%s), model: %s\n", strings.Join(client.nodes(), ", "), cfg.Ollama.Model)
 
 	state := &agentState{}
 
@@ -144,6 +144,7 @@ func processMessages(ctx context.Context, cfg AgentConfig, client *OllamaClient,
 	for _, m := range msgs {
 		lastMsg = m
 		userContent.WriteString(fmt.Sprintf("[%s → %s] %s\n", m.From, m.Action, m.Payload))
+		userContent.WriteString(FormatAttachments(cfg.Session, m, false))
 	}
 
 	// Fresh conversation each time (system + user)
@@ -393,13 +394,7 @@ func logBashToHistory(cfg AgentConfig, tc ToolCall, result string) {
 		return
 	}
 
-	historyPath := HistoryPath(cfg.Session, cfg.busRole())
-	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-	_, _ = f.Write(append(data, '\n'))
+	_ = AppendHistory(cfg.Session, cfg.busRole(), data, 0)
 }
 
 // agentSkillPrompt returns the skills prompt for a role.
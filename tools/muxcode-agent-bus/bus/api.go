@@ -331,19 +331,39 @@ func writeCollectionFile(col Collection) error {
 
 // AppendApiHistory appends a history entry to the API history JSONL file.
 func AppendApiHistory(entry ApiHistoryEntry) error {
-	if err := os.MkdirAll(ApiDir(), 0755); err != nil {
-		return err
-	}
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
+	if s := ActiveStore(); s != nil {
+		return s.Append(apiHistoryStream, entry.TS, data)
+	}
+	if err := os.MkdirAll(ApiDir(), 0755); err != nil {
+		return err
+	}
 	return appendToFile(ApiHistoryPath(), append(data, '\n'))
 }
 
 // ReadApiHistory reads API history entries, optionally filtered by collection.
 // Pass empty collection to read all entries. Limit 0 means no limit.
 func ReadApiHistory(collection string, limit int) ([]ApiHistoryEntry, error) {
+	if s := ActiveStore(); s != nil {
+		q := StoreQuery{}
+		if collection == "" {
+			q.Limit = limit
+		}
+		records, err := s.Read(apiHistoryStream, q)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, r := range records {
+			buf.WriteString(r.Data)
+			buf.WriteByte('\n')
+		}
+		return parseApiHistory(buf.Bytes(), collection, limit)
+	}
+
 	data, err := os.ReadFile(ApiHistoryPath())
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -351,7 +371,12 @@ func ReadApiHistory(collection string, limit int) ([]ApiHistoryEntry, error) {
 		}
 		return nil, err
 	}
+	return parseApiHistory(data, collection, limit)
+}
 
+// parseApiHistory decodes API history JSONL, filtering by collection and
+// keeping the last limit entries.
+func parseApiHistory(data []byte, collection string, limit int) ([]ApiHistoryEntry, error) {
 	var entries []ApiHistoryEntry
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
@@ -0,0 +1,481 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Approval status values.
+const (
+	ApprovalPending   = "pending"
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalExpired   = "expired"   // no decision within the gate's timeout
+	ApprovalCancelled = "cancelled" // run cancelled, resumed, or no longer waiting
+)
+
+// Approval kinds: what a decision releases.
+const (
+	ApprovalWorkflow = "workflow"
+	ApprovalChain    = "chain"
+)
+
+// defaultApprovalTimeout is how long a gate waits for a decision.
+const defaultApprovalTimeout = 24 * time.Hour
+
+// approvalKeep is how long finished approvals stay in approvals.jsonl.
+const approvalKeep = 7 * 24 * time.Hour
+
+// ApprovalGate pauses a chain action (its "approval" field) or a workflow
+// step (type approval) until the approver runs approve or reject. A gate
+// that gets no decision within its timeout expires, which counts as a
+// rejection, so gated work never proceeds unattended.
+type ApprovalGate struct {
+	Approver      string `json:"approver,omitempty"`       // role asked to decide (default edit)
+	Timeout       string `json:"timeout,omitempty"`        // Go duration before the gate expires (default 24h)
+	EscalateAfter string `json:"escalate_after,omitempty"` // Go duration before escalate_to is asked too
+	EscalateTo    string `json:"escalate_to,omitempty"`
+	// When makes the gate conditional: "A == B", "A != B", or "A =~ REGEX",
+	// compared after template expansion. Unset means always.
+	When string `json:"when,omitempty"`
+}
+
+// approver returns the role asked to decide.
+func (g ApprovalGate) approver() string {
+	if g.Approver != "" {
+		return g.Approver
+	}
+	return "edit"
+}
+
+// timeoutDuration returns the gate's timeout, defaulting to 24h.
+func (g ApprovalGate) timeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(g.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultApprovalTimeout
+}
+
+// Check validates the gate's durations, escalation, and condition. Roles
+// are checked by the caller, which knows where they were configured.
+func (g ApprovalGate) Check() []string {
+	var errs []string
+	if g.Timeout != "" {
+		if d, err := time.ParseDuration(g.Timeout); err != nil || d <= 0 {
+			errs = append(errs, fmt.Sprintf("invalid timeout %q", g.Timeout))
+		}
+	}
+	if (g.EscalateAfter == "") != (g.EscalateTo == "") {
+		errs = append(errs, "escalate_after and escalate_to must be set together")
+	}
+	if g.EscalateAfter != "" {
+		d, err := time.ParseDuration(g.EscalateAfter)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Sprintf("invalid escalate_after %q", g.EscalateAfter))
+		} else if d >= g.timeoutDuration() {
+			errs = append(errs, fmt.Sprintf("escalate_after %s is not before the timeout (%s)", d, g.timeoutDuration()))
+		}
+	}
+	if g.When != "" {
+		_, op, rhs, err := splitApprovalCondition(g.When)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if op == "=~" && !strings.Contains(rhs, "${") {
+			if _, err := regexp.Compile(rhs); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid when regexp: %v", err))
+			}
+		}
+	}
+	return errs
+}
+
+// approvalOperators are the condition operators, longest match first.
+var approvalOperators = []string{" == ", " != ", " =~ "}
+
+// splitApprovalCondition splits "A op B" at its operator.
+func splitApprovalCondition(cond string) (lhs, op, rhs string, err error) {
+	for _, o := range approvalOperators {
+		if l, r, ok := strings.Cut(cond, o); ok {
+			return strings.TrimSpace(l), strings.TrimSpace(o), strings.TrimSpace(r), nil
+		}
+	}
+	return "", "", "", fmt.Errorf("invalid when %q (want A == B, A != B, or A =~ REGEX)", cond)
+}
+
+// ApprovalConditionMet evaluates an expanded gate condition. An empty
+// condition is always met.
+func ApprovalConditionMet(cond string) (bool, error) {
+	if strings.TrimSpace(cond) == "" {
+		return true, nil
+	}
+	lhs, op, rhs, err := splitApprovalCondition(cond)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "==":
+		return lhs == rhs, nil
+	case "!=":
+		return lhs != rhs, nil
+	}
+	re, err := regexp.Compile(rhs)
+	if err != nil {
+		return false, fmt.Errorf("invalid when regexp: %v", err)
+	}
+	return re.MatchString(lhs), nil
+}
+
+// ChainGate records where a gated chain stopped, so approve can run the
+// held steps.
+type ChainGate struct {
+	Event    string `json:"event"`
+	Outcome  string `json:"outcome"`
+	ExitCode string `json:"exit_code,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Step     int    `json:"step"` // index of the gated action
+	NoNotify bool   `json:"no_notify,omitempty"`
+}
+
+// Approval is a pending or decided approval request. Workflow approvals
+// use the run ID as their ID, so "approve <run-id>" releases the run.
+type Approval struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Step       string     `json:"step,omitempty"` // workflow step waiting on the decision
+	Chain      *ChainGate `json:"chain,omitempty"`
+	Cost       *CostGate  `json:"cost,omitempty"`
+	From       string     `json:"from"` // role the gated work runs as
+	Approver   string     `json:"approver"`
+	EscalateTo string     `json:"escalate_to,omitempty"`
+	Prompt     string     `json:"prompt"`
+	Status     string     `json:"status"`
+	CreatedAt  int64      `json:"created_at"`
+	ExpiresAt  int64      `json:"expires_at"`
+	EscalateAt int64      `json:"escalate_at,omitempty"`
+	Escalated  bool       `json:"escalated,omitempty"`
+	DecidedBy  string     `json:"decided_by,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	DoneAt     int64      `json:"done_at,omitempty"`
+}
+
+// newApproval builds a pending approval for a gate.
+func newApproval(g ApprovalGate, id, kind, from, prompt string, now time.Time) Approval {
+	a := Approval{
+		ID:         id,
+		Kind:       kind,
+		From:       from,
+		Approver:   g.approver(),
+		EscalateTo: g.EscalateTo,
+		Prompt:     prompt,
+		Status:     ApprovalPending,
+		CreatedAt:  now.Unix(),
+		ExpiresAt:  now.Add(g.timeoutDuration()).Unix(),
+	}
+	if d, err := time.ParseDuration(g.EscalateAfter); err == nil && d > 0 && g.EscalateTo != "" {
+		a.EscalateAt = now.Add(d).Unix()
+	}
+	return a
+}
+
+// ApprovalsPath returns the approval registry JSONL file for a session.
+func ApprovalsPath(session string) string {
+	return filepath.Join(BusDir(session), "approvals.jsonl")
+}
+
+// lockApprovals serializes read-modify-write cycles on the registry.
+// Callers holding lockWorkflows may take it; it is never held while taking
+// lockWorkflows.
+func lockApprovals(session string) func() {
+	return flockBusFile(session, "approvals")
+}
+
+// ReadApprovals reads all approvals, oldest first.
+func ReadApprovals(session string) ([]Approval, error) {
+	data, err := os.ReadFile(ApprovalsPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var as []Approval
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var a Approval
+		if err := json.Unmarshal(line, &a); err != nil {
+			continue // skip malformed lines
+		}
+		as = append(as, a)
+	}
+	return as, scanner.Err()
+}
+
+// writeApprovals overwrites the registry with the given approvals.
+func writeApprovals(session string, as []Approval) error {
+	var buf bytes.Buffer
+	for _, a := range as {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := ApprovalsPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ApprovalsPath(session))
+}
+
+// approvalNotice is the payload asking a role to decide.
+func approvalNotice(a Approval) string {
+	return fmt.Sprintf("Approval required: %s\nApprove: muxcode-agent-bus approve %s\nReject:  muxcode-agent-bus reject %s --reason \"...\"\nExpires %s.",
+		a.Prompt, a.ID, a.ID, time.Unix(a.ExpiresAt, 0).Format("2006-01-02 15:04:05"))
+}
+
+// RequestApproval registers a pending approval, replacing any pending one
+// with the same ID, and sends the approver an approval-required event.
+func RequestApproval(session string, a Approval) error {
+	unlock := lockApprovals(session)
+	as, err := ReadApprovals(session)
+	if err != nil {
+		unlock()
+		return err
+	}
+	for i := range as {
+		if as[i].ID == a.ID && as[i].Status == ApprovalPending {
+			as[i].Status = ApprovalCancelled
+			as[i].DoneAt = a.CreatedAt
+		}
+	}
+	err = writeApprovals(session, append(as, a))
+	unlock()
+	if err != nil {
+		return err
+	}
+	if err := Send(session, NewMessage(a.From, a.Approver, "event", "approval-required", approvalNotice(a), "")); err != nil {
+		return err
+	}
+	_ = Notify(session, a.Approver)
+	return nil
+}
+
+// RequestChainApproval holds a chain at the gated action and asks the
+// gate's approver to release it. message is the action's expanded message.
+func RequestChainApproval(session, from string, action ChainAction, message string, gate ChainGate) (Approval, error) {
+	what := fmt.Sprintf("send %s:%s to %s", action.Type, action.Action, action.SendTo)
+	if action.Plugin != "" {
+		what = "run plugin " + action.Plugin
+	}
+	prompt := fmt.Sprintf("%s %s chain wants to %s: %s", gate.Event, gate.Outcome, what, message)
+	a := newApproval(*action.Approval, newWorkflowRunID("chain-"+gate.Event), ApprovalChain, from, prompt, time.Now())
+	a.Chain = &gate
+	return a, RequestApproval(session, a)
+}
+
+// DecideApproval approves or rejects the pending approval id as role by.
+// Only the approver, the escalation role, or a caller that is not an agent
+// (a human in a shell) may decide. A workflow decision is recorded on the
+// run, which then moves on; releasing a held chain is left to the caller.
+func DecideApproval(session, id string, approve bool, by, reason string) (Approval, error) {
+	unlock := lockApprovals(session)
+	as, err := ReadApprovals(session)
+	if err != nil {
+		unlock()
+		return Approval{}, err
+	}
+	idx := -1
+	for i := range as {
+		if as[i].ID == id && as[i].Status == ApprovalPending {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		unlock()
+		return Approval{}, fmt.Errorf("no pending approval %s", id)
+	}
+	a := &as[idx]
+	if IsKnownRole(by) && by != a.Approver && by != a.EscalateTo {
+		unlock()
+		return Approval{}, fmt.Errorf("%s cannot decide approval %s (approver: %s)", by, id, a.Approver)
+	}
+	now := time.Now()
+	a.DoneAt = now.Unix()
+	if a.Kind == ApprovalWorkflow && !workflowAwaitingApproval(session, a.ID, a.Step) {
+		a.Status = ApprovalCancelled
+		_ = writeApprovals(session, as)
+		unlock()
+		return Approval{}, fmt.Errorf("run %s is no longer waiting for approval", id)
+	}
+	a.Status = ApprovalRejected
+	if approve {
+		a.Status = ApprovalApproved
+	}
+	a.DecidedBy = by
+	a.Reason = reason
+	decided := *a
+	err = writeApprovals(session, as)
+	unlock()
+	if err != nil {
+		return decided, err
+	}
+
+	if decided.Kind == ApprovalWorkflow {
+		if err := recordWorkflowApproval(session, decided); err != nil {
+			return decided, err
+		}
+		if _, err := AdvanceWorkflows(session, now); err != nil {
+			return decided, err
+		}
+	}
+	if decided.Kind == ApprovalCost && decided.Cost != nil {
+		if err := notifyCostDecision(session, decided); err != nil {
+			return decided, err
+		}
+	}
+	return decided, nil
+}
+
+// cancelApprovals cancels the pending approvals with the given ID.
+func cancelApprovals(session, id string, now int64) error {
+	if _, err := os.Stat(ApprovalsPath(session)); err != nil {
+		return nil
+	}
+	unlock := lockApprovals(session)
+	defer unlock()
+	as, err := ReadApprovals(session)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range as {
+		if as[i].ID == id && as[i].Status == ApprovalPending {
+			as[i].Status = ApprovalCancelled
+			as[i].DoneAt = now
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeApprovals(session, as)
+}
+
+// CheckApprovals escalates pending approvals past their escalate_after,
+// expires those past their timeout (failing a waiting workflow step), and
+// prunes approvals finished more than a week ago. The watcher calls it
+// periodically; it returns the approvals escalated or expired in this call.
+func CheckApprovals(session string, now time.Time) ([]Approval, error) {
+	if _, err := os.Stat(ApprovalsPath(session)); err != nil {
+		return nil, nil
+	}
+	unlock := lockApprovals(session)
+	as, err := ReadApprovals(session)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	var kept, changed []Approval
+	dirty := false
+	for _, a := range as {
+		if a.Status != ApprovalPending {
+			if now.Sub(time.Unix(a.DoneAt, 0)) >= approvalKeep {
+				dirty = true
+				continue
+			}
+			kept = append(kept, a)
+			continue
+		}
+		switch {
+		case now.Unix() >= a.ExpiresAt:
+			a.Status = ApprovalExpired
+			a.DoneAt = now.Unix()
+		case a.EscalateAt > 0 && !a.Escalated && now.Unix() >= a.EscalateAt:
+			a.Escalated = true
+		default:
+			kept = append(kept, a)
+			continue
+		}
+		dirty = true
+		kept = append(kept, a)
+		changed = append(changed, a)
+	}
+	if dirty {
+		err = writeApprovals(session, kept)
+	}
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range changed {
+		if a.Status == ApprovalExpired {
+			payload := fmt.Sprintf("Approval %s expired without a decision: %s", a.ID, a.Prompt)
+			if a.Kind == ApprovalWorkflow {
+				_ = recordWorkflowApproval(session, a)
+			}
+			targets := []string{a.Approver}
+			if a.Escalated && a.EscalateTo != a.Approver {
+				targets = append(targets, a.EscalateTo)
+			}
+			for _, to := range targets {
+				if Send(session, NewMessage(a.From, to, "event", "approval-expired", payload, "")) == nil {
+					_ = Notify(session, to)
+				}
+			}
+			continue
+		}
+		payload := fmt.Sprintf("Escalated: %s has not decided since %s.\n%s",
+			a.Approver, time.Unix(a.CreatedAt, 0).Format("15:04:05"), approvalNotice(a))
+		if Send(session, NewMessage(a.From, a.EscalateTo, "event", "approval-escalated", payload, "")) == nil {
+			_ = Notify(session, a.EscalateTo)
+		}
+	}
+	return changed, nil
+}
+
+// FormatApprovals renders approvals as a table, newest first. Decided
+// approvals are included only when all is true.
+func FormatApprovals(as []Approval, all bool) string {
+	var b strings.Builder
+	shown := 0
+	for i := len(as) - 1; i >= 0; i-- {
+		a := as[i]
+		if a.Status != ApprovalPending && !all {
+			continue
+		}
+		if shown == 0 {
+			fmt.Fprintf(&b, "%-36s %-9s %-9s %-8s %s\n", "ID", "KIND", "STATUS", "APPROVER", "EXPIRES")
+		}
+		shown++
+		fmt.Fprintf(&b, "%-36s %-9s %-9s %-8s %s\n", a.ID, a.Kind, a.Status, a.Approver,
+			time.Unix(a.ExpiresAt, 0).Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(&b, "  %s\n", clipWorkflowText(a.Prompt, 120))
+		if a.DecidedBy != "" {
+			line := "  " + a.Status + " by " + a.DecidedBy
+			if a.Reason != "" {
+				line += ": " + a.Reason
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	if shown == 0 {
+		return "No approvals.\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApprovalConditionMet(t *testing.T) {
+	for _, c := range []struct {
+		cond string
+		want bool
+	}{
+		{"", true},
+		{"prod == prod", true},
+		{"staging == prod", false},
+		{"staging != prod", true},
+		{"./deploy.sh --env=prod =~ --env=prod\\b", true},
+		{"./deploy.sh --env=dev =~ --env=prod\\b", false},
+	} {
+		got, err := ApprovalConditionMet(c.cond)
+		if err != nil || got != c.want {
+			t.Errorf("ApprovalConditionMet(%q) = %v, %v; want %v", c.cond, got, err, c.want)
+		}
+	}
+	if _, err := ApprovalConditionMet("prod"); err == nil {
+		t.Error("condition without operator: want error")
+	}
+}
+
+func TestApprovalGateCheck(t *testing.T) {
+	errs := strings.Join(ApprovalGate{Timeout: "soon", EscalateAfter: "1h", When: "${x} =~ ("}.Check(), "\n")
+	for _, want := range []string{`invalid timeout "soon"`, "must be set together", "invalid when regexp"} {
+		if !strings.Contains(errs, want) {
+			t.Errorf("Check missing %q in:\n%s", want, errs)
+		}
+	}
+	if errs := (ApprovalGate{Timeout: "2h", EscalateAfter: "30m", EscalateTo: "review", When: "${env} == prod"}).Check(); len(errs) > 0 {
+		t.Errorf("valid gate: %v", errs)
+	}
+}
+
+// gatedWorkflow asks edit to approve before deploying when env is prod.
+var gatedWorkflow = Workflow{Name: "ship", Steps: []WorkflowStep{
+	{ID: "gate", Type: "approval", Payload: "Deploy ${env}?", When: "${env} == prod",
+		Timeout: "2h", EscalateAfter: "30m", EscalateTo: "review"},
+	{ID: "deploy", Role: "deploy", Action: "deploy"},
+}}
+
+func TestWorkflowApproval(t *testing.T) {
+	session := testSession(t)
+	if errs := gatedWorkflow.Validate(); len(errs) > 0 {
+		t.Fatalf("Validate: %v", errs)
+	}
+
+	r, err := StartWorkflow(session, gatedWorkflow, "commit", map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := Peek(session, "deploy"); len(msgs) != 0 {
+		t.Fatalf("deploy ran before approval: %+v", msgs)
+	}
+	msgs, _ := Peek(session, "edit")
+	if len(msgs) != 1 || msgs[0].Action != "approval-required" || !strings.Contains(msgs[0].Payload, "approve "+r.ID) {
+		t.Fatalf("edit inbox = %+v", msgs)
+	}
+
+	// Agents other than the approver cannot release the gate
+	if _, err := DecideApproval(session, r.ID, true, "deploy", ""); err == nil {
+		t.Fatal("deploy approved its own gate")
+	}
+	a, err := DecideApproval(session, r.ID, true, "edit", "ship it")
+	if err != nil {
+		t.Fatalf("DecideApproval: %v", err)
+	}
+	if a.Status != ApprovalApproved || a.DecidedBy != "edit" {
+		t.Errorf("approval = %+v", a)
+	}
+	r, _ = ReadWorkflowRun(session, r.ID)
+	if r.Current != "deploy" || r.Steps["gate"].Result != "approved: ship it" {
+		t.Fatalf("after approve: current = %s gate = %+v", r.Current, r.Steps["gate"])
+	}
+	if msgs, _ := Peek(session, "deploy"); len(msgs) != 1 {
+		t.Errorf("deploy inbox = %+v", msgs)
+	}
+	if _, err := DecideApproval(session, r.ID, true, "edit", ""); err == nil {
+		t.Error("approving twice: want error")
+	}
+}
+
+func TestWorkflowApproval_SkippedWhenConditionFalse(t *testing.T) {
+	session := testSession(t)
+	r, err := StartWorkflow(session, gatedWorkflow, "commit", map[string]string{"env": "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Current != "deploy" || !strings.HasPrefix(r.Steps["gate"].Result, "approval not required") {
+		t.Fatalf("current = %s gate = %+v", r.Current, r.Steps["gate"])
+	}
+	if as, _ := ReadApprovals(session); len(as) != 0 {
+		t.Errorf("approvals = %+v", as)
+	}
+}
+
+func TestWorkflowApproval_RejectAndCancel(t *testing.T) {
+	session := testSession(t)
+	r, _ := StartWorkflow(session, gatedWorkflow, "commit", map[string]string{"env": "prod"})
+	if _, err := DecideApproval(session, r.ID, false, "unknown", "not today"); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = ReadWorkflowRun(session, r.ID)
+	if r.Status != WorkflowFailed || r.Steps["gate"].Result != "rejected: not today" {
+		t.Fatalf("after reject: %s gate = %+v", r.Status, r.Steps["gate"])
+	}
+
+	// Resuming asks again; cancelling withdraws the request
+	if _, err := ResumeWorkflow(session, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := CancelWorkflow(session, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	as, _ := ReadApprovals(session)
+	if len(as) != 2 || as[1].Status != ApprovalCancelled {
+		t.Fatalf("approvals = %+v", as)
+	}
+	if _, err := DecideApproval(session, r.ID, true, "edit", ""); err == nil {
+		t.Error("approving a cancelled run: want error")
+	}
+}
+
+func TestCheckApprovals(t *testing.T) {
+	session := testSession(t)
+	r, _ := StartWorkflow(session, gatedWorkflow, "commit", map[string]string{"env": "prod"})
+	now := time.Now()
+
+	if changed, _ := CheckApprovals(session, now.Add(10*time.Minute)); len(changed) != 0 {
+		t.Fatalf("changed early: %+v", changed)
+	}
+	changed, err := CheckApprovals(session, now.Add(31*time.Minute))
+	if err != nil || len(changed) != 1 || !changed[0].Escalated {
+		t.Fatalf("escalation = %+v, %v", changed, err)
+	}
+	if msgs, _ := Peek(session, "review"); len(msgs) != 1 || msgs[0].Action != "approval-escalated" {
+		t.Fatalf("review inbox = %+v", msgs)
+	}
+	if changed, _ := CheckApprovals(session, now.Add(40*time.Minute)); len(changed) != 0 {
+		t.Errorf("escalated twice: %+v", changed)
+	}
+
+	changed, _ = CheckApprovals(session, now.Add(3*time.Hour))
+	if len(changed) != 1 || changed[0].Status != ApprovalExpired {
+		t.Fatalf("expiry = %+v", changed)
+	}
+	for _, role := range []string{"edit", "review"} {
+		msgs, _ := Peek(session, role)
+		if last := msgs[len(msgs)-1]; last.Action != "approval-expired" {
+			t.Errorf("%s last message = %+v", role, last)
+		}
+	}
+	if _, err := AdvanceWorkflows(session, now.Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = ReadWorkflowRun(session, r.ID); r.Status != WorkflowFailed {
+		t.Errorf("run after expiry = %s", r.Status)
+	}
+}
+
+func TestRequestChainApproval(t *testing.T) {
+	session := testSession(t)
+	action := ChainAction{SendTo: "deploy", Type: "request", Action: "promote", Approval: &ApprovalGate{Approver: "review"}}
+	a, err := RequestChainApproval(session, "deploy", action, "Promote to prod", ChainGate{Event: "deploy", Outcome: "success", Step: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(a.ID, "chain-deploy-") || a.Approver != "review" {
+		t.Errorf("approval = %+v", a)
+	}
+	if msgs, _ := Peek(session, "review"); len(msgs) != 1 || !strings.Contains(msgs[0].Payload, "send request:promote to deploy: Promote to prod") {
+		t.Fatalf("review inbox = %+v", msgs)
+	}
+	if _, err := DecideApproval(session, a.ID, true, "edit", ""); err == nil {
+		t.Error("edit decided a gate that asks review")
+	}
+	got, err := DecideApproval(session, a.ID, true, "review", "")
+	if err != nil || got.Kind != ApprovalChain || got.Chain == nil || got.Chain.Step != 1 {
+		t.Fatalf("DecideApproval = %+v, %v", got, err)
+	}
+
+	out := FormatApprovals([]Approval{got}, true)
+	if !strings.Contains(out, "approved by review") {
+		t.Errorf("FormatApprovals:\n%s", out)
+	}
+	if out := FormatApprovals([]Approval{got}, false); out != "No approvals.\n" {
+		t.Errorf("decided approvals shown without --all:\n%s", out)
+	}
+}
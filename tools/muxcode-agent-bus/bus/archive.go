@@ -0,0 +1,284 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive defaults. Unread messages older than the max age, and the oldest
+// messages past the caps, move to inbox-archive/.
+const (
+	defaultInboxMaxAge      = 24 * 3600
+	defaultInboxMaxMessages = 200
+	defaultLogMaxMessages   = 5000
+)
+
+// InboxConfig is the "inbox" section of muxcode.json: how large the active
+// inboxes and session log may grow before the watcher archives them.
+type InboxConfig struct {
+	MaxAge         int64 `json:"max_age_s,omitempty"`        // unread messages older than this are archived (default 86400)
+	MaxMessages    int   `json:"max_messages,omitempty"`     // per-inbox cap (default 200)
+	LogMaxMessages int   `json:"log_max_messages,omitempty"` // log.jsonl cap (default 5000)
+}
+
+// Validate rejects negative limits.
+func (c InboxConfig) Validate() error {
+	if c.MaxAge < 0 || c.MaxMessages < 0 || c.LogMaxMessages < 0 {
+		return fmt.Errorf("inbox limits must be positive")
+	}
+	return nil
+}
+
+// mergeInbox returns base with every set field of override applied.
+func mergeInbox(base, override InboxConfig) InboxConfig {
+	if override.MaxAge > 0 {
+		base.MaxAge = override.MaxAge
+	}
+	if override.MaxMessages > 0 {
+		base.MaxMessages = override.MaxMessages
+	}
+	if override.LogMaxMessages > 0 {
+		base.LogMaxMessages = override.LogMaxMessages
+	}
+	return base
+}
+
+// limits returns the configured limits with defaults applied.
+func (c InboxConfig) limits() (maxAge int64, maxMessages, logMax int) {
+	maxAge, maxMessages, logMax = c.MaxAge, c.MaxMessages, c.LogMaxMessages
+	if maxAge <= 0 {
+		maxAge = defaultInboxMaxAge
+	}
+	if maxMessages <= 0 {
+		maxMessages = defaultInboxMaxMessages
+	}
+	if logMax <= 0 {
+		logMax = defaultLogMaxMessages
+	}
+	return
+}
+
+// ArchiveDir returns the inbox archive directory for a session.
+func ArchiveDir(session string) string {
+	return filepath.Join(BusDir(session), "inbox-archive")
+}
+
+// ArchivePath returns the archive file for a role and day (YYYYMMDD).
+func ArchivePath(session, role, day string) string {
+	return filepath.Join(ArchiveDir(session), role+"-"+day+".jsonl.gz")
+}
+
+// ArchiveResult reports what one compaction moved.
+type ArchiveResult struct {
+	Inboxes map[string]int // role -> messages archived from its inbox
+	Log     int            // messages archived from log.jsonl
+}
+
+// Total returns the number of messages archived.
+func (r ArchiveResult) Total() int {
+	n := r.Log
+	for _, c := range r.Inboxes {
+		n += c
+	}
+	return n
+}
+
+// CompactInboxes archives expired and overflow messages from every inbox
+// not currently locked by its agent, then trims log.jsonl to its cap. The
+// watcher calls it periodically.
+func CompactInboxes(session string, now time.Time) (ArchiveResult, error) {
+	maxAge, maxMessages, logMax := Config().Inbox.limits()
+	res := ArchiveResult{Inboxes: map[string]int{}}
+	cutoff := now.Unix() - maxAge
+	// Transport inboxes live outside the bus directory and are not compacted
+	t, terr := ActiveTransport()
+	external := t != nil || terr != nil
+	for _, role := range KnownRoles {
+		if IsLocked(session, role) || external {
+			continue
+		}
+		n, err := compactFile(session, InboxPath(session, role), func(m Message) string { return role }, cutoff, maxMessages)
+		if err != nil {
+			return res, err
+		}
+		if n > 0 {
+			res.Inboxes[role] = n
+		}
+	}
+	// The log is trimmed by count only: SLA and rate-limit windows read it
+	n, err := compactFile(session, LogPath(session), func(m Message) string { return m.To }, 0, logMax)
+	res.Log = n
+	return res, err
+}
+
+// compactFile moves messages older than cutoff, and the oldest messages
+// past keep, from a JSONL file into the archive, filed by roleOf. Like
+// ReceiveFrom, it renames the file aside so concurrent sends are kept.
+func compactFile(session, path string, roleOf func(Message) string, cutoff int64, keep int) (int, error) {
+	msgs, err := readMessages(path)
+	if err != nil || len(msgs) == 0 {
+		return 0, err
+	}
+	if len(msgs) <= keep && msgs[0].TS >= cutoff {
+		return 0, nil
+	}
+
+	compacting := path + ".compacting"
+	if err := os.Rename(path, compacting); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	_ = touchFile(path)
+	msgs, err = readMessages(compacting)
+	if err != nil {
+		_ = os.Rename(compacting, path)
+		return 0, err
+	}
+
+	var archived, kept []Message
+	overflow := len(msgs) - keep
+	for i, m := range msgs {
+		if i < overflow || m.TS < cutoff {
+			archived = append(archived, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	archiveErr := appendArchive(session, archived, roleOf)
+	if archiveErr != nil {
+		// Put everything back rather than lose messages
+		kept = msgs
+		archived = nil
+	}
+
+	var buf []byte
+	for _, m := range kept {
+		data, encErr := encodeLine(m)
+		if encErr != nil {
+			continue
+		}
+		buf = append(buf, data...)
+	}
+	newData, _ := os.ReadFile(path)
+	if writeErr := os.WriteFile(path, append(buf, newData...), 0644); writeErr != nil {
+		_ = appendToFile(path, buf)
+	}
+	_ = os.Remove(compacting)
+	return len(archived), archiveErr
+}
+
+// appendArchive appends messages to their role's archive for the day they
+// were sent. Each call adds a gzip member; readers see one stream.
+func appendArchive(session string, msgs []Message, roleOf func(Message) string) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(ArchiveDir(session), 0755); err != nil {
+		return err
+	}
+	groups := map[string][]byte{}
+	var order []string
+	for _, m := range msgs {
+		data, err := encodeLine(m)
+		if err != nil {
+			continue
+		}
+		path := ArchivePath(session, roleOf(m), time.Unix(m.TS, 0).Format("20060102"))
+		if _, ok := groups[path]; !ok {
+			order = append(order, path)
+		}
+		groups[path] = append(groups[path], data...)
+	}
+	for _, path := range order {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		zw := gzip.NewWriter(f)
+		_, err = zw.Write(groups[path])
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadArchive returns every archived message, oldest first. Messages
+// archived more than once (from an inbox, then from the log) appear once.
+func ReadArchive(session string) []Message {
+	files, _ := filepath.Glob(filepath.Join(ArchiveDir(session), "*.jsonl.gz"))
+	seen := map[string]bool{}
+	var all []Message
+	for _, path := range files {
+		for _, m := range readArchiveFile(path) {
+			if !seen[m.ID] {
+				seen[m.ID] = true
+				all = append(all, m)
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].TS < all[j].TS })
+	return all
+}
+
+// readArchiveFile decodes one gzip archive, skipping malformed lines and
+// stopping at a truncated member.
+func readArchiveFile(path string) []Message {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil
+	}
+	data, _ := io.ReadAll(zr)
+
+	var msgs []Message
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if m, err := DecodeMessage(line); err == nil {
+			msgs = append(msgs, MigrateMessage(m))
+		}
+	}
+	return msgs
+}
+
+// FormatArchiveResult summarizes a compaction for the watcher log.
+func FormatArchiveResult(r ArchiveResult) string {
+	var parts []string
+	roles := make([]string, 0, len(r.Inboxes))
+	for role := range r.Inboxes {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		parts = append(parts, fmt.Sprintf("%s %d", role, r.Inboxes[role]))
+	}
+	if r.Log > 0 {
+		parts = append(parts, fmt.Sprintf("log %d", r.Log))
+	}
+	return strings.Join(parts, ", ")
+}
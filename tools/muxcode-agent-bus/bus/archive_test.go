@@ -0,0 +1,142 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sendAt sends a message with a fixed timestamp.
+func sendAt(t *testing.T, session, from, to string, ts int64) Message {
+	t.Helper()
+	m := NewMessage(from, to, "request", "work", "payload", "")
+	m.TS = ts
+	if err := Send(session, m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCompactInboxes_ExpiredAndOverflow(t *testing.T) {
+	session := testSession(t)
+	SetConfig(&MuxcodeConfig{Inbox: InboxConfig{MaxAge: 3600, MaxMessages: 3, LogMaxMessages: 100}})
+	defer SetConfig(nil)
+
+	now := time.Now()
+	old := sendAt(t, session, "edit", "build", now.Add(-2*time.Hour).Unix())
+	var recent []Message
+	for i := 0; i < 4; i++ {
+		recent = append(recent, sendAt(t, session, "edit", "build", now.Unix()))
+	}
+
+	res, err := CompactInboxes(session, now)
+	if err != nil {
+		t.Fatalf("CompactInboxes: %v", err)
+	}
+	if res.Inboxes["build"] != 2 || res.Log != 0 {
+		t.Errorf("expected 2 archived from build and none from the log, got %+v", res)
+	}
+
+	msgs, _ := Peek(session, "build")
+	if len(msgs) != 3 || msgs[0].ID != recent[1].ID {
+		t.Errorf("expected the 3 newest messages to stay, got %d", len(msgs))
+	}
+
+	archived := ReadArchive(session)
+	if len(archived) != 2 || archived[0].ID != old.ID || archived[1].ID != recent[0].ID {
+		t.Errorf("unexpected archive contents: %+v", archived)
+	}
+	day := time.Unix(old.TS, 0).Format("20060102")
+	if _, err := os.Stat(ArchivePath(session, "build", day)); err != nil {
+		t.Errorf("expected archive file for %s: %v", day, err)
+	}
+
+	// A second pass has nothing to do
+	if res, _ := CompactInboxes(session, now); res.Total() != 0 {
+		t.Errorf("expected no-op second pass, got %+v", res)
+	}
+}
+
+func TestCompactInboxes_SkipsLockedRole(t *testing.T) {
+	session := testSession(t)
+	SetConfig(&MuxcodeConfig{Inbox: InboxConfig{MaxMessages: 1}})
+	defer SetConfig(nil)
+
+	sendAt(t, session, "edit", "test", time.Now().Unix())
+	sendAt(t, session, "edit", "test", time.Now().Unix())
+	if err := Lock(session, "test"); err != nil {
+		t.Fatal(err)
+	}
+	defer Unlock(session, "test")
+
+	res, err := CompactInboxes(session, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inboxes["test"] != 0 || InboxCount(session, "test") != 2 {
+		t.Errorf("locked inbox should not be compacted: %+v", res)
+	}
+}
+
+func TestHistoryReadsArchive(t *testing.T) {
+	session := testSession(t)
+	SetConfig(&MuxcodeConfig{Inbox: InboxConfig{LogMaxMessages: 2}})
+	defer SetConfig(nil)
+
+	now := time.Now().Unix()
+	var sent []Message
+	for i := int64(0); i < 5; i++ {
+		sent = append(sent, sendAt(t, session, "edit", "review", now-10+i))
+	}
+	if _, err := Receive(session, "review"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := CompactInboxes(session, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Log != 3 {
+		t.Fatalf("expected 3 log entries archived, got %+v", res)
+	}
+	if msgs, _ := readMessages(LogPath(session)); len(msgs) != 2 {
+		t.Errorf("expected 2 log entries left, got %d", len(msgs))
+	}
+
+	history := ReadLogHistory(session, "review", 0)
+	if len(history) != 5 {
+		t.Fatalf("expected full history across archive and log, got %d", len(history))
+	}
+	for i, m := range history {
+		if m.ID != sent[i].ID {
+			t.Errorf("history[%d] = %s, want %s", i, m.ID, sent[i].ID)
+		}
+	}
+
+	// A limit the active log satisfies skips the archive
+	if got := ReadLogHistory(session, "review", 2); len(got) != 2 || got[1].ID != sent[4].ID {
+		t.Errorf("limited history = %+v", got)
+	}
+}
+
+func TestReadArchive_MultipleMembers(t *testing.T) {
+	session := testSession(t)
+	a := Message{ID: "1-edit-a", TS: 1, From: "edit", To: "build"}
+	b := Message{ID: "2-edit-b", TS: 2, From: "edit", To: "build"}
+	to := func(m Message) string { return m.To }
+	if err := appendArchive(session, []Message{a}, to); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendArchive(session, []Message{b, a}, to); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(ArchiveDir(session), "build-*.jsonl.gz"))
+	if len(files) != 1 {
+		t.Fatalf("expected one archive file, got %v", files)
+	}
+	got := ReadArchive(session)
+	if len(got) != 2 || got[0].ID != a.ID || got[1].ID != b.ID {
+		t.Errorf("expected deduplicated messages in order, got %+v", got)
+	}
+}
@@ -0,0 +1,140 @@
+package bus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Attachment references a blob stored under attachments/<sha256>. Large
+// payloads (diffs, logs) travel as attachments so inbox JSONL stays small;
+// identical blobs are stored once.
+type Attachment struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha256"`
+	Size int64  `json:"size"`
+}
+
+// maxAttachmentSize caps a single attachment.
+const maxAttachmentSize = 32 << 20
+
+// defaultInlineLimit is the largest text attachment printed inline by
+// inbox; override with MUXCODE_ATTACH_INLINE_MAX (bytes, 0 disables).
+const defaultInlineLimit = 4096
+
+// ShortSHA returns the first 12 hex digits of the blob hash.
+func (a Attachment) ShortSHA() string {
+	if len(a.SHA) > 12 {
+		return a.SHA[:12]
+	}
+	return a.SHA
+}
+
+// AttachmentPath returns the blob path for a hash.
+func AttachmentPath(session, sha string) string {
+	return filepath.Join(AttachmentDir(session), sha)
+}
+
+// StoreAttachment copies a file into the session's attachment store and
+// returns a reference named after the file's base name.
+func StoreAttachment(session, path string) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return Attachment{}, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxAttachmentSize {
+		return Attachment{}, fmt.Errorf("%s is %d bytes (max %d)", path, info.Size(), maxAttachmentSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return StoreAttachmentData(session, filepath.Base(path), data)
+}
+
+// StoreAttachmentData stores a blob under its SHA-256 and returns a
+// reference. Blobs already in the store are not rewritten.
+func StoreAttachmentData(session, name string, data []byte) (Attachment, error) {
+	sum := sha256.Sum256(data)
+	a := Attachment{Name: name, SHA: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+	path := AttachmentPath(session, a.SHA)
+	if _, err := os.Stat(path); err == nil {
+		return a, nil
+	}
+	if err := os.MkdirAll(AttachmentDir(session), 0755); err != nil {
+		return Attachment{}, err
+	}
+	// Write-then-rename so readers never see a partial blob
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return Attachment{}, err
+	}
+	return a, os.Rename(tmp, path)
+}
+
+// ReadAttachment returns an attachment's content, verifying its hash.
+func ReadAttachment(session string, a Attachment) ([]byte, error) {
+	data, err := os.ReadFile(AttachmentPath(session, a.SHA))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != a.SHA {
+		return nil, fmt.Errorf("attachment %s is corrupt (hash mismatch)", a.Name)
+	}
+	return data, nil
+}
+
+// InlineLimit returns the inbox inlining threshold in bytes.
+func InlineLimit() int64 {
+	if v := os.Getenv("MUXCODE_ATTACH_INLINE_MAX"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultInlineLimit
+}
+
+// isText reports whether data looks like printable text.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.ContainsRune(data, 0)
+}
+
+// FormatAttachments renders a message's attachments for the inbox. Text
+// attachments up to InlineLimit are inlined, as are all text attachments
+// when fetch is set; anything else is listed with its blob path.
+func FormatAttachments(session string, m Message, fetch bool) string {
+	s := ""
+	limit := InlineLimit()
+	for _, a := range m.Attachments {
+		path := AttachmentPath(session, a.SHA)
+		if !fetch && a.Size > limit {
+			s += fmt.Sprintf("--- Attachment %s (%d bytes): %s ---\n", a.Name, a.Size, path)
+			continue
+		}
+		data, err := ReadAttachment(session, a)
+		if err != nil {
+			s += fmt.Sprintf("--- Attachment %s unavailable: %v ---\n", a.Name, err)
+			continue
+		}
+		if !isText(data) {
+			s += fmt.Sprintf("--- Attachment %s (%d bytes, binary): %s ---\n", a.Name, a.Size, path)
+			continue
+		}
+		s += fmt.Sprintf("--- Attachment %s (%d bytes) ---\n", a.Name, a.Size)
+		s += string(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			s += "\n"
+		}
+		s += fmt.Sprintf("--- End of %s ---\n", a.Name)
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreAttachment(t *testing.T) {
+	session := testSession(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.log")
+	if err := os.WriteFile(path, []byte("error: undefined: foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := StoreAttachment(session, path)
+	if err != nil {
+		t.Fatalf("StoreAttachment: %v", err)
+	}
+	if a.Name != "build.log" || a.Size != 22 || len(a.SHA) != 64 {
+		t.Errorf("unexpected reference: %+v", a)
+	}
+
+	// Same content under another name shares the blob
+	b, err := StoreAttachmentData(session, "copy.log", []byte("error: undefined: foo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SHA != a.SHA {
+		t.Errorf("expected identical hashes, got %s and %s", a.SHA, b.SHA)
+	}
+	entries, _ := os.ReadDir(AttachmentDir(session))
+	if len(entries) != 1 {
+		t.Errorf("expected 1 blob, got %d", len(entries))
+	}
+
+	data, err := ReadAttachment(session, a)
+	if err != nil || string(data) != "error: undefined: foo\n" {
+		t.Errorf("ReadAttachment = %q, %v", data, err)
+	}
+
+	// Tampered blobs are rejected
+	if err := os.WriteFile(AttachmentPath(session, a.SHA), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAttachment(session, a); err == nil {
+		t.Error("expected hash mismatch error")
+	}
+
+	if _, err := StoreAttachment(session, dir); err == nil {
+		t.Error("expected error attaching a directory")
+	}
+}
+
+func TestAttachmentRoundTrip(t *testing.T) {
+	session := testSession(t)
+	a, err := StoreAttachmentData(session, "diff.patch", []byte("+added line"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := NewMessage("edit", "review", "request", "review", "Please review", "")
+	msg.Attachments = []Attachment{a}
+	if err := Send(session, msg); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := Receive(session, "review")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("Receive: %v, %d messages", err, len(msgs))
+	}
+	if len(msgs[0].Attachments) != 1 || msgs[0].Attachments[0] != a {
+		t.Errorf("attachment reference lost: %+v", msgs[0].Attachments)
+	}
+	if !strings.Contains(FormatMessage(msgs[0]), "Attachment: diff.patch (11 bytes, sha256:"+a.SHA[:12]+")") {
+		t.Errorf("FormatMessage missing attachment line:\n%s", FormatMessage(msgs[0]))
+	}
+}
+
+func TestFormatAttachments(t *testing.T) {
+	session := testSession(t)
+	t.Setenv("MUXCODE_ATTACH_INLINE_MAX", "16")
+	small, _ := StoreAttachmentData(session, "small.txt", []byte("short"))
+	large, _ := StoreAttachmentData(session, "large.txt", []byte(strings.Repeat("x", 40)))
+	binary, _ := StoreAttachmentData(session, "blob.bin", []byte{0, 1, 2})
+	m := Message{Attachments: []Attachment{small, large, binary}}
+
+	out := FormatAttachments(session, m, false)
+	if !strings.Contains(out, "--- Attachment small.txt (5 bytes) ---\nshort\n--- End of small.txt ---") {
+		t.Errorf("small attachment should be inlined:\n%s", out)
+	}
+	if strings.Contains(out, strings.Repeat("x", 40)) {
+		t.Errorf("large attachment should not be inlined:\n%s", out)
+	}
+	if !strings.Contains(out, AttachmentPath(session, large.SHA)) {
+		t.Errorf("large attachment should show its path:\n%s", out)
+	}
+	if !strings.Contains(out, "blob.bin (3 bytes, binary)") {
+		t.Errorf("binary attachment should not be inlined:\n%s", out)
+	}
+
+	fetched := FormatAttachments(session, m, true)
+	if !strings.Contains(fetched, strings.Repeat("x", 40)) {
+		t.Errorf("fetch should inline large text attachments:\n%s", fetched)
+	}
+	if strings.Contains(fetched, "\x00") {
+		t.Error("fetch should never inline binary attachments")
+	}
+}
@@ -0,0 +1,141 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Audited operations.
+const (
+	AuditLock               = "lock"
+	AuditUnlock             = "unlock"
+	AuditPolicyDeny         = "policy-deny"
+	AuditRoleAdd            = "role-add"
+	AuditRoleRemove         = "role-remove"
+	AuditCronAdd            = "cron-add"
+	AuditCronRemove         = "cron-remove"
+	AuditSubscriptionAdd    = "subscription-add"
+	AuditSubscriptionRemove = "subscription-remove"
+	AuditWebhook            = "webhook"
+	AuditSpawnStart         = "spawn-start"
+	AuditProcStart          = "proc-start"
+)
+
+// auditArgMax caps the length of a recorded argument value.
+const auditArgMax = 500
+
+// AuditEvent records a privileged operation: who did it, to which role,
+// and with what arguments.
+type AuditEvent struct {
+	TS    int64             `json:"ts"`
+	Actor string            `json:"actor"`          // role or component performing the operation
+	Op    string            `json:"op"`             // one of the Audit* operations
+	Role  string            `json:"role,omitempty"` // role the operation applies to
+	Args  map[string]string `json:"args,omitempty"`
+}
+
+// AuditPath returns the append-only audit log for a session.
+func AuditPath(session string) string {
+	return filepath.Join(BusDir(session), "audit.jsonl")
+}
+
+// RecordAudit appends an event to the audit log, stamping the time. Long
+// argument values are truncated. Recording is best effort: callers ignore
+// the error so auditing never blocks the operation itself.
+func RecordAudit(session string, ev AuditEvent) error {
+	if ev.TS == 0 {
+		ev.TS = time.Now().Unix()
+	}
+	if ev.Actor == "" {
+		ev.Actor = "unknown"
+	}
+	for k, v := range ev.Args {
+		if len(v) > auditArgMax {
+			ev.Args[k] = v[:auditArgMax] + "..."
+		}
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(AuditPath(session)), 0755); err != nil {
+		return err
+	}
+	return appendToFile(AuditPath(session), append(data, '\n'))
+}
+
+// AuditFilter selects audit events. Empty fields match everything.
+type AuditFilter struct {
+	Role  string // matches the actor or the affected role
+	Op    string
+	Since int64
+	Limit int // most recent N (0 for all)
+}
+
+// ReadAuditLog returns the audit events matching f, oldest first.
+func ReadAuditLog(session string, f AuditFilter) ([]AuditEvent, error) {
+	file, err := os.Open(AuditPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev AuditEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		if f.Role != "" && ev.Actor != f.Role && ev.Role != f.Role {
+			continue
+		}
+		if f.Op != "" && ev.Op != f.Op {
+			continue
+		}
+		if ev.TS < f.Since {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[len(events)-f.Limit:]
+	}
+	return events, scanner.Err()
+}
+
+// FormatAuditLog formats audit events, one per line, with arguments in
+// key order.
+func FormatAuditLog(events []AuditEvent) string {
+	if len(events) == 0 {
+		return "No audit events.\n"
+	}
+	var b strings.Builder
+	for _, ev := range events {
+		target := ev.Role
+		if target == "" {
+			target = "-"
+		}
+		fmt.Fprintf(&b, "%s  %-19s %-10s -> %-10s", time.Unix(ev.TS, 0).Format("2006-01-02 15:04:05"), ev.Op, ev.Actor, target)
+		keys := make([]string, 0, len(ev.Args))
+		for k := range ev.Args {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, " %s=%q", k, ev.Args[k])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
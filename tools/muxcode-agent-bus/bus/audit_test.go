@@ -0,0 +1,77 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditLog_RecordsPrivilegedOperations(t *testing.T) {
+	session := testSession(t)
+	t.Setenv("BUS_ROLE", "edit")
+
+	if err := Lock(session, "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	Lock(session, "deploy") // already held: not recorded again
+	if err := Unlock(session, "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	Unlock(session, "deploy") // not held: not recorded
+	if _, err := AddCronEntry(session, CronEntry{Schedule: "@every 5m", Target: "deploy", Action: "status", Message: "check"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartProc(session, "true", t.TempDir(), "build"); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ReadAuditLog(session, AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, ev := range events {
+		ops = append(ops, ev.Op)
+	}
+	if got := strings.Join(ops, ","); got != "lock,unlock,cron-add,proc-start" {
+		t.Fatalf("ops = %s", got)
+	}
+	if events[0].Actor != "edit" || events[0].Role != "deploy" || events[0].TS == 0 {
+		t.Errorf("lock event = %+v", events[0])
+	}
+	if events[3].Actor != "build" || events[3].Args["command"] != "true" {
+		t.Errorf("proc event = %+v", events[3])
+	}
+
+	// --role matches the actor or the affected role
+	deploy, _ := ReadAuditLog(session, AuditFilter{Role: "deploy"})
+	if len(deploy) != 3 {
+		t.Errorf("deploy events = %+v", deploy)
+	}
+	build, _ := ReadAuditLog(session, AuditFilter{Role: "build", Op: AuditProcStart})
+	if len(build) != 1 {
+		t.Errorf("build proc events = %+v", build)
+	}
+	last, _ := ReadAuditLog(session, AuditFilter{Limit: 1})
+	if len(last) != 1 || last[0].Op != AuditProcStart {
+		t.Errorf("limit = %+v", last)
+	}
+
+	out := FormatAuditLog(deploy)
+	if !strings.Contains(out, "cron-add") || !strings.Contains(out, `schedule="@every 5m"`) {
+		t.Errorf("format:\n%s", out)
+	}
+}
+
+func TestAuditLog_PolicyDenial(t *testing.T) {
+	session := testSession(t)
+	SetConfig(&MuxcodeConfig{Policy: PolicyConfig{Rules: []PolicyRule{{From: "build", To: "deploy", Effect: "deny", Reason: "no deploys from build"}}}})
+	defer SetConfig(nil)
+
+	if deny := CheckMessagePolicy(session, NewMessage("build", "deploy", "request", "deploy", "go", "")); deny == "" {
+		t.Fatal("expected denial")
+	}
+	events, _ := ReadAuditLog(session, AuditFilter{Op: AuditPolicyDeny})
+	if len(events) != 1 || events[0].Actor != "build" || events[0].Role != "deploy" || events[0].Args["blocked"] != "true" {
+		t.Errorf("events = %+v", events)
+	}
+}
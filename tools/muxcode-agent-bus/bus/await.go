@@ -0,0 +1,36 @@
+package bus
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAwaitTimeout is returned by AwaitReply when no reply arrives in time.
+var ErrAwaitTimeout = errors.New("timed out waiting for a reply")
+
+// awaitPollInterval is how often AwaitReply checks the inbox.
+var awaitPollInterval = 500 * time.Millisecond
+
+// AwaitReply blocks until a message replying to requestID (reply_to set to
+// its ID) reaches role's inbox, consumes it, and returns it. Other messages
+// stay in the inbox. It returns ErrAwaitTimeout after timeout. Together with
+// Send this gives agents a synchronous call on top of the async bus.
+func AwaitReply(session, role, requestID string, timeout time.Duration) (Message, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if HasMessages(session, role) {
+			msgs, err := ReceiveReply(session, role, requestID)
+			if err != nil {
+				return Message{}, err
+			}
+			if len(msgs) > 0 {
+				return msgs[0], nil
+			}
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return Message{}, ErrAwaitTimeout
+		}
+		time.Sleep(min(awaitPollInterval, remaining))
+	}
+}
@@ -0,0 +1,64 @@
+package bus
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReceiveReply(t *testing.T) {
+	session := testSession(t)
+	other := NewMessage("build", "edit", "response", "build", "unrelated", "")
+	first := NewMessage("build", "edit", "response", "build", "first", "req-1")
+	second := NewMessage("test", "edit", "response", "test", "second", "req-1")
+	for _, m := range []Message{other, first, second} {
+		if err := SendNoCC(session, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msgs, err := ReceiveReply(session, "edit", "req-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != first.ID {
+		t.Fatalf("ReceiveReply = %+v, want only the first reply", msgs)
+	}
+	left, _ := Peek(session, "edit")
+	if len(left) != 2 || left[0].ID != other.ID || left[1].ID != second.ID {
+		t.Errorf("inbox after = %+v, want the other two messages in order", left)
+	}
+}
+
+func TestAwaitReply(t *testing.T) {
+	session := testSession(t)
+	old := awaitPollInterval
+	awaitPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { awaitPollInterval = old })
+
+	req := NewMessage("edit", "build", "request", "build", "build it", "")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = SendNoCC(session, NewMessage("review", "edit", "event", "note", "noise", ""))
+		_ = SendNoCC(session, NewMessage("build", "edit", "response", "build", "ok", req.ID))
+	}()
+
+	reply, err := AwaitReply(session, "edit", req.ID, 5*time.Second)
+	if err != nil {
+		t.Fatalf("AwaitReply: %v", err)
+	}
+	if reply.Payload != "ok" || reply.From != "build" {
+		t.Errorf("reply = %+v", reply)
+	}
+	if left, _ := Peek(session, "edit"); len(left) != 1 || left[0].Payload != "noise" {
+		t.Errorf("inbox after = %+v, want the unrelated message kept", left)
+	}
+
+	start := time.Now()
+	if _, err := AwaitReply(session, "edit", "no-such-id", 50*time.Millisecond); !errors.Is(err, ErrAwaitTimeout) {
+		t.Errorf("err = %v, want ErrAwaitTimeout", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("timeout took %s", time.Since(start))
+	}
+}
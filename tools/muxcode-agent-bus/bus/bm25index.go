@@ -0,0 +1,384 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The BM25 index persists tokenized memory entries so searches don't
+// re-read and re-tokenize every memory file. It is a JSONL file of records,
+// one per memory file (active or archived). Each record carries the file's
+// mtime and size when it was indexed; a file whose stat no longer matches
+// is re-parsed on the next search. AppendMemory adds "append" records that
+// chain from the previous stat, so writes update the index without a
+// rebuild.
+
+// bm25Doc is one indexed memory entry.
+type bm25Doc struct {
+	Role      string         `json:"role"`
+	Section   string         `json:"section"`
+	Timestamp string         `json:"ts"`
+	Content   string         `json:"content"`
+	TF        map[string]int `json:"tf"`        // term frequency, header terms weighted 2x
+	Len       int            `json:"len"`       // effective length, header tokens counted twice
+	TotalLen  int            `json:"total_len"` // unweighted token count
+}
+
+// bm25Record is a line of the index file.
+type bm25Record struct {
+	Source      string    `json:"source"` // path relative to the memory directory
+	ModTime     int64     `json:"mtime"`
+	Size        int64     `json:"size"`
+	Append      bool      `json:"append,omitempty"`
+	PrevModTime int64     `json:"prev_mtime,omitempty"` // append only: stat the record applies on top of
+	PrevSize    int64     `json:"prev_size,omitempty"`
+	Docs        []bm25Doc `json:"docs,omitempty"`
+}
+
+// bm25Stat is the mtime and size of a memory file.
+type bm25Stat struct {
+	modTime int64
+	size    int64
+}
+
+// bm25File holds the indexed entries of one memory file.
+type bm25File struct {
+	stat  bm25Stat
+	docs  []bm25Doc
+	valid bool // false when an append record didn't chain; forces a re-parse
+}
+
+// bm25Index is the loaded index.
+type bm25Index struct {
+	files map[string]*bm25File
+	docs  []*bm25Doc // all docs, in source path order
+}
+
+var (
+	bm25Mu       sync.Mutex
+	bm25Cached   *bm25Index
+	bm25CacheDir string
+)
+
+// newBM25Doc tokenizes a memory entry for the index.
+func newBM25Doc(entry MemoryEntry) bm25Doc {
+	te := tokenizeEntry(entry)
+	tf := make(map[string]int)
+	for _, t := range te.headerTokens {
+		tf[t] += 2
+	}
+	for _, t := range te.contentTokens {
+		tf[t]++
+	}
+	return bm25Doc{
+		Role:      entry.Role,
+		Section:   entry.Section,
+		Timestamp: entry.Timestamp,
+		Content:   entry.Content,
+		TF:        tf,
+		Len:       len(te.headerTokens)*2 + len(te.contentTokens),
+		TotalLen:  te.totalLen,
+	}
+}
+
+// entry converts an indexed doc back to a memory entry.
+func (d *bm25Doc) entry() MemoryEntry {
+	return MemoryEntry{Role: d.Role, Section: d.Section, Timestamp: d.Timestamp, Content: d.Content}
+}
+
+// memorySourceRole returns the role a memory file belongs to: the file
+// name for active files, the directory name for archives.
+func memorySourceRole(rel string) string {
+	if dir, _ := filepath.Split(rel); dir != "" {
+		return filepath.Base(dir)
+	}
+	return strings.TrimSuffix(rel, ".md")
+}
+
+// statMemorySource returns a memory file's stat, or zero if it is missing.
+func statMemorySource(path string) bm25Stat {
+	info, err := os.Stat(path)
+	if err != nil {
+		return bm25Stat{}
+	}
+	return bm25Stat{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// scanMemorySources lists the active and archived memory files, keyed by
+// path relative to the memory directory, mirroring allMemoryFileEntries.
+func scanMemorySources(dir string) (map[string]bm25Stat, error) {
+	sources := make(map[string]bm25Stat)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sources, nil
+		}
+		return nil, err
+	}
+	for _, de := range dirEntries {
+		if de.IsDir() {
+			archiveEntries, err := os.ReadDir(filepath.Join(dir, de.Name()))
+			if err != nil {
+				continue
+			}
+			for _, ae := range archiveEntries {
+				if ae.IsDir() || !strings.HasSuffix(ae.Name(), ".md") {
+					continue
+				}
+				rel := filepath.Join(de.Name(), ae.Name())
+				sources[rel] = statMemorySource(filepath.Join(dir, rel))
+			}
+			continue
+		}
+		if strings.HasSuffix(de.Name(), ".md") {
+			sources[de.Name()] = statMemorySource(filepath.Join(dir, de.Name()))
+		}
+	}
+	return sources, nil
+}
+
+// readBM25Index reads the index file, applying append records that chain
+// from the previous stat of their file and invalidating files whose chain
+// is broken. A missing or unreadable index yields an empty one.
+func readBM25Index(path string) map[string]*bm25File {
+	files := make(map[string]*bm25File)
+	f, err := os.Open(path)
+	if err != nil {
+		return files
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec bm25Record
+			if json.Unmarshal(line, &rec) == nil && rec.Source != "" {
+				applyBM25Record(files, rec)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return make(map[string]*bm25File)
+			}
+			return files
+		}
+	}
+}
+
+// applyBM25Record applies one index record.
+func applyBM25Record(files map[string]*bm25File, rec bm25Record) {
+	stat := bm25Stat{modTime: rec.ModTime, size: rec.Size}
+	if !rec.Append {
+		files[rec.Source] = &bm25File{stat: stat, docs: rec.Docs, valid: true}
+		return
+	}
+	file, ok := files[rec.Source]
+	if !ok {
+		// The first write to a new file chains from a missing file
+		file = &bm25File{valid: true}
+		files[rec.Source] = file
+	}
+	prev := bm25Stat{modTime: rec.PrevModTime, size: rec.PrevSize}
+	if !file.valid || file.stat != prev {
+		file.valid = false
+		return
+	}
+	file.stat = stat
+	file.docs = append(file.docs, rec.Docs...)
+}
+
+// writeBM25Index rewrites the index file with one record per memory file.
+func writeBM25Index(path string, files map[string]*bm25File) error {
+	var buf bytes.Buffer
+	for _, rel := range sortedBM25Sources(files) {
+		file := files[rel]
+		data, err := json.Marshal(bm25Record{Source: rel, ModTime: file.stat.modTime, Size: file.stat.size, Docs: file.docs})
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sortedBM25Sources returns the indexed file paths in sorted order.
+func sortedBM25Sources(files map[string]*bm25File) []string {
+	rels := make([]string, 0, len(files))
+	for rel := range files {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	return rels
+}
+
+// loadBM25Index returns the BM25 index for the memory directory, re-parsing
+// memory files that changed since they were indexed and persisting the
+// result. The index is cached in-process until a memory file changes.
+func loadBM25Index() (*bm25Index, error) {
+	dir := MemoryDir()
+	sources, err := scanMemorySources(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	bm25Mu.Lock()
+	defer bm25Mu.Unlock()
+	if bm25Cached != nil && bm25CacheDir == dir && bm25Current(bm25Cached.files, sources) {
+		return bm25Cached, nil
+	}
+
+	files := readBM25Index(BM25IndexPath())
+	dirty := false
+	for rel, stat := range sources {
+		if file, ok := files[rel]; ok && file.valid && file.stat == stat {
+			continue
+		}
+		content, err := readBusFile(filepath.Join(dir, rel))
+		if err != nil {
+			delete(files, rel)
+			continue
+		}
+		file := &bm25File{stat: stat, valid: true}
+		for _, entry := range ParseMemoryEntries(string(content), memorySourceRole(rel)) {
+			file.docs = append(file.docs, newBM25Doc(entry))
+		}
+		files[rel] = file
+		dirty = true
+	}
+	for rel := range files {
+		if _, ok := sources[rel]; !ok {
+			delete(files, rel)
+			dirty = true
+		}
+	}
+	// The index holds entry text, so it is not persisted when encrypting
+	if dirty && len(sources) > 0 && !EncryptionEnabled() {
+		// Best effort: a read-only memory dir still gets in-process caching
+		_ = writeBM25Index(BM25IndexPath(), files)
+	}
+
+	idx := &bm25Index{files: files}
+	for _, rel := range sortedBM25Sources(files) {
+		for i := range files[rel].docs {
+			idx.docs = append(idx.docs, &files[rel].docs[i])
+		}
+	}
+	bm25Cached, bm25CacheDir = idx, dir
+	return idx, nil
+}
+
+// bm25Current reports whether the indexed files match the memory files on disk.
+func bm25Current(files map[string]*bm25File, sources map[string]bm25Stat) bool {
+	if len(files) != len(sources) {
+		return false
+	}
+	for rel, stat := range sources {
+		file, ok := files[rel]
+		if !ok || !file.valid || file.stat != stat {
+			return false
+		}
+	}
+	return true
+}
+
+// indexMemoryAppend records an entry appended to a memory file. prev is the
+// file's stat before the write. Nothing is recorded when no index exists
+// yet or another write landed in between; the next search re-parses the
+// file instead.
+func indexMemoryAppend(path string, prev bm25Stat, chunk, role string) {
+	indexPath := BM25IndexPath()
+	if _, err := os.Stat(indexPath); err != nil || EncryptionEnabled() {
+		return
+	}
+	stat := statMemorySource(path)
+	if stat.size != prev.size+int64(len(chunk)) {
+		return
+	}
+	rel, err := filepath.Rel(MemoryDir(), path)
+	if err != nil {
+		return
+	}
+	rec := bm25Record{
+		Source:      rel,
+		ModTime:     stat.modTime,
+		Size:        stat.size,
+		Append:      true,
+		PrevModTime: prev.modTime,
+		PrevSize:    prev.size,
+	}
+	for _, entry := range ParseMemoryEntries(chunk, role) {
+		rec.Docs = append(rec.Docs, newBM25Doc(entry))
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	_ = appendToFile(indexPath, append(data, '\n'))
+}
+
+// search scores the indexed entries against a query. Collection statistics
+// are computed over the role-filtered subset, as in SearchMemoryBM25.
+func (idx *bm25Index) search(opts SearchOptions, queryTerms []string, phrases [][]string) []SearchResult {
+	docCount, totalLen := 0, 0
+	docFreq := make(map[string]int, len(queryTerms))
+	var matched []*bm25Doc
+	for _, d := range idx.docs {
+		if opts.RoleFilter != "" && d.Role != opts.RoleFilter {
+			continue
+		}
+		docCount++
+		totalLen += d.TotalLen
+		hit := false
+		for _, qt := range queryTerms {
+			if d.TF[qt] > 0 {
+				docFreq[qt]++
+				hit = true
+			}
+		}
+		if hit {
+			matched = append(matched, d)
+		}
+	}
+	if docCount == 0 || totalLen == 0 {
+		return nil
+	}
+	avgDocLen := float64(totalLen) / float64(docCount)
+
+	var results []SearchResult
+	for _, d := range matched {
+		var score float64
+		for _, qt := range queryTerms {
+			termTF := float64(d.TF[qt])
+			if termTF == 0 {
+				continue
+			}
+			df := float64(docFreq[qt])
+			idf := math.Log((float64(docCount)-df+0.5)/(df+0.5) + 1.0)
+			numerator := termTF * (bm25K1 + 1.0)
+			denominator := termTF + bm25K1*(1.0-bm25B+bm25B*float64(d.Len)/avgDocLen)
+			score += idf * (numerator / denominator)
+		}
+		if score > 0 {
+			entry := d.entry()
+			if len(phrases) > 0 {
+				score += phraseBonus(tokenizeEntry(entry), phrases)
+			}
+			results = append(results, SearchResult{Entry: entry, Score: score})
+		}
+	}
+	return results
+}
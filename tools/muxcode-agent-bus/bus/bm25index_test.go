@@ -0,0 +1,269 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSearchMemoryBM25_PersistsIndex(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", tmp)
+
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if _, err := os.Stat(BM25IndexPath()); !os.IsNotExist(err) {
+		t.Fatalf("index should not exist before the first search, stat err = %v", err)
+	}
+
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"}); err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	files := readBM25Index(BM25IndexPath())
+	if f := files["build.md"]; f == nil || len(f.docs) != 1 {
+		t.Fatalf("expected build.md indexed with 1 doc, got %+v", files)
+	}
+}
+
+func TestSearchMemoryBM25_IncrementalAppend(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", tmp)
+
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"}); err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+
+	if err := AppendMemory("Deploy Notes", "run cdk diff before deploy", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	data, err := os.ReadFile(BM25IndexPath())
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"append":true`) {
+		t.Fatalf("expected an append record after the file record, got:\n%s", data)
+	}
+
+	// The append record chains from the indexed stat, so the file stays valid
+	files := readBM25Index(BM25IndexPath())
+	f := files["build.md"]
+	if f == nil || !f.valid || len(f.docs) != 2 {
+		t.Fatalf("expected build.md valid with 2 docs, got %+v", f)
+	}
+	if f.stat != statMemorySource(MemoryPath("build")) {
+		t.Errorf("indexed stat %+v does not match file", f.stat)
+	}
+
+	results, err := SearchMemoryBM25(SearchOptions{Query: "cdk"})
+	if err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Section != "Deploy Notes" {
+		t.Fatalf("expected the appended entry, got %+v", results)
+	}
+}
+
+func TestSearchMemoryBM25_ExternalEditInvalidates(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", tmp)
+
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"}); err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+
+	// Rewrite the file behind the bus's back
+	edited := formatMemoryChunk("Build Config", "2026-01-01 10:00", "use yarn classic for all builds")
+	if err := os.WriteFile(MemoryPath("build"), []byte(edited), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	results, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"})
+	if err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected stale entry dropped, got %+v", results)
+	}
+	results, err = SearchMemoryBM25(SearchOptions{Query: "yarn"})
+	if err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected edited entry found, got %+v", results)
+	}
+}
+
+func TestSearchMemoryBM25_RemovedFileDropped(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", tmp)
+
+	if err := AppendMemory("Build Config", "use pnpm", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if err := AppendMemory("Edit Notes", "use pnpm workspaces", "edit"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"}); err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if err := os.Remove(MemoryPath("edit")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	results, err := SearchMemoryBM25(SearchOptions{Query: "pnpm"})
+	if err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Role != "build" {
+		t.Errorf("expected only the build entry, got %+v", results)
+	}
+}
+
+func TestApplyBM25Record_BrokenChain(t *testing.T) {
+	files := make(map[string]*bm25File)
+	applyBM25Record(files, bm25Record{Source: "build.md", ModTime: 10, Size: 100, Docs: []bm25Doc{{Section: "a"}}})
+	// Chains from 10/100
+	applyBM25Record(files, bm25Record{Source: "build.md", Append: true, PrevModTime: 10, PrevSize: 100, ModTime: 20, Size: 150, Docs: []bm25Doc{{Section: "b"}}})
+	if f := files["build.md"]; !f.valid || len(f.docs) != 2 || f.stat.size != 150 {
+		t.Fatalf("expected chained append applied, got %+v", f)
+	}
+	// Does not chain: a write the index missed
+	applyBM25Record(files, bm25Record{Source: "build.md", Append: true, PrevModTime: 30, PrevSize: 200, ModTime: 40, Size: 250})
+	if files["build.md"].valid {
+		t.Error("expected broken chain to invalidate the file")
+	}
+	// First write to a new file chains from a missing file
+	applyBM25Record(files, bm25Record{Source: "edit.md", Append: true, ModTime: 5, Size: 50, Docs: []bm25Doc{{Section: "c"}}})
+	if f := files["edit.md"]; !f.valid || len(f.docs) != 1 {
+		t.Errorf("expected new file indexed, got %+v", f)
+	}
+}
+
+func TestSearchMemoryBM25_IndexMatchesInMemory(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", tmp)
+	writeBenchMemory(t, 200)
+
+	entries, err := AllMemoryEntries()
+	if err != nil {
+		t.Fatalf("AllMemoryEntries: %v", err)
+	}
+	for _, opts := range []SearchOptions{
+		{Query: "deploy lambda"},
+		{Query: `"cdk diff" stack`},
+		{Query: "pnpm", RoleFilter: "build"},
+	} {
+		terms, phrases := parseQuery(opts.Query)
+		want := make(map[string]float64)
+		for _, r := range searchEntriesBM25(entries, opts, terms, phrases) {
+			want[r.Entry.Role+r.Entry.Section] = r.Score
+		}
+		got, err := SearchMemoryBM25(opts)
+		if err != nil {
+			t.Fatalf("SearchMemoryBM25: %v", err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%q: got %d results, want %d", opts.Query, len(got), len(want))
+		}
+		for _, r := range got {
+			if w := want[r.Entry.Role+r.Entry.Section]; w != r.Score {
+				t.Errorf("%q: %s score = %f, want %f", opts.Query, r.Entry.Section, r.Score, w)
+			}
+		}
+	}
+}
+
+// writeBenchMemory writes n memory entries spread across five roles.
+func writeBenchMemory(tb testing.TB, n int) {
+	tb.Helper()
+	roles := []string{"build", "edit", "test", "deploy", "shared"}
+	topics := []string{
+		"run cdk diff before deploying the stack",
+		"use pnpm for all builds and installs",
+		"lambda handlers live under src/handlers",
+		"retry flaky integration tests once",
+		"the review checklist covers error handling",
+	}
+	var chunks = make(map[string]*strings.Builder)
+	for i := 0; i < n; i++ {
+		role := roles[i%len(roles)]
+		b, ok := chunks[role]
+		if !ok {
+			b = &strings.Builder{}
+			chunks[role] = b
+		}
+		b.WriteString(formatMemoryChunk(
+			fmt.Sprintf("Note %d", i),
+			"2026-01-01 10:00",
+			fmt.Sprintf("%s; entry %d mentions module%d and %s", topics[i%len(topics)], i, i%97, topics[(i/7)%len(topics)]),
+		))
+	}
+	for role, b := range chunks {
+		if err := os.WriteFile(MemoryPath(role), []byte(b.String()), 0644); err != nil {
+			tb.Fatalf("write: %v", err)
+		}
+	}
+}
+
+// BenchmarkSearchMemoryBM25 measures an indexed query over 10k entries.
+func BenchmarkSearchMemoryBM25(b *testing.B) {
+	b.Setenv("BUS_MEMORY_DIR", b.TempDir())
+	writeBenchMemory(b, 10000)
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "warm"}); err != nil {
+		b.Fatalf("SearchMemoryBM25: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SearchMemoryBM25(SearchOptions{Query: "cdk deploy lambda", Limit: 10}); err != nil {
+			b.Fatalf("SearchMemoryBM25: %v", err)
+		}
+	}
+}
+
+// BenchmarkSearchMemoryBM25_Load measures a query in a fresh process: the
+// persisted index is read from disk, nothing is re-tokenized.
+func BenchmarkSearchMemoryBM25_Load(b *testing.B) {
+	b.Setenv("BUS_MEMORY_DIR", b.TempDir())
+	writeBenchMemory(b, 10000)
+	if _, err := SearchMemoryBM25(SearchOptions{Query: "warm"}); err != nil {
+		b.Fatalf("SearchMemoryBM25: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm25Mu.Lock()
+		bm25Cached = nil
+		bm25Mu.Unlock()
+		if _, err := SearchMemoryBM25(SearchOptions{Query: "cdk deploy lambda", Limit: 10}); err != nil {
+			b.Fatalf("SearchMemoryBM25: %v", err)
+		}
+	}
+}
+
+// BenchmarkSearchMemoryBM25_Rebuild measures the unindexed path, which
+// re-reads and re-tokenizes every entry per query.
+func BenchmarkSearchMemoryBM25_Rebuild(b *testing.B) {
+	b.Setenv("BUS_MEMORY_DIR", b.TempDir())
+	writeBenchMemory(b, 10000)
+	opts := SearchOptions{Query: "cdk deploy lambda", Limit: 10}
+	terms, phrases := parseQuery(opts.Query)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries, err := AllMemoryEntries()
+		if err != nil {
+			b.Fatalf("AllMemoryEntries: %v", err)
+		}
+		searchEntriesBM25(entries, opts, terms, phrases)
+	}
+}
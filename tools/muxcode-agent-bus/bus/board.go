@@ -0,0 +1,366 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Board task status values.
+const (
+	BoardOpen       = "open"
+	BoardClaimed    = "claimed"
+	BoardInProgress = "in-progress"
+	BoardBlocked    = "blocked"
+	BoardDone       = "done"
+)
+
+// BoardStatuses are the valid board task statuses, in workflow order.
+var BoardStatuses = []string{BoardOpen, BoardClaimed, BoardInProgress, BoardBlocked, BoardDone}
+
+// BoardPriorities are the valid board task priorities, highest first.
+var BoardPriorities = []string{"high", "normal", "low"}
+
+// BoardTask is a unit of multi-step work on the shared task board. Unlike
+// idle tasks (TaskEntry), board tasks are never dispatched automatically:
+// agents claim and update them explicitly.
+type BoardTask struct {
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Status    string      `json:"status"`
+	Assignee  string      `json:"assignee,omitempty"`
+	Priority  string      `json:"priority"`
+	CreatedBy string      `json:"created_by"`
+	CreatedAt int64       `json:"created_at"`
+	UpdatedAt int64       `json:"updated_at"`
+	Links     []string    `json:"links,omitempty"` // originating or related message IDs
+	Notes     []BoardNote `json:"notes,omitempty"`
+}
+
+// BoardNote is a progress note on a board task.
+type BoardNote struct {
+	TS   int64  `json:"ts"`
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// Active reports whether the task still needs work.
+func (t BoardTask) Active() bool {
+	return t.Status != BoardDone
+}
+
+// BoardPath returns the task board JSONL file path for a session.
+func BoardPath(session string) string {
+	return filepath.Join(BusDir(session), "board.jsonl")
+}
+
+// lockBoard serializes read-modify-write cycles on the board so two agents
+// can't claim the same task.
+func lockBoard(session string) func() {
+	return flockBusFile(session, "board")
+}
+
+// ReadBoard reads all board tasks, oldest first.
+func ReadBoard(session string) ([]BoardTask, error) {
+	data, err := os.ReadFile(BoardPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tasks []BoardTask
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var t BoardTask
+		if err := json.Unmarshal(line, &t); err != nil {
+			continue // skip malformed lines
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, scanner.Err()
+}
+
+// writeBoard overwrites the board file with the given tasks.
+func writeBoard(session string, tasks []BoardTask) error {
+	var buf bytes.Buffer
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := BoardPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, BoardPath(session))
+}
+
+// AddBoardTask adds a task to the board. Title is required; priority
+// defaults to "normal" and status to "open". Returns the stored task.
+func AddBoardTask(session string, task BoardTask) (BoardTask, error) {
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return BoardTask{}, fmt.Errorf("task title is empty")
+	}
+	if task.Priority == "" {
+		task.Priority = "normal"
+	}
+	if !containsRole(BoardPriorities, task.Priority) {
+		return BoardTask{}, fmt.Errorf("invalid priority %q (want one of %v)", task.Priority, BoardPriorities)
+	}
+	if task.Assignee != "" && !IsKnownRole(task.Assignee) {
+		return BoardTask{}, fmt.Errorf("unknown assignee role: %s", task.Assignee)
+	}
+
+	now := time.Now().Unix()
+	task.ID = NewMsgID("board")
+	task.Status = BoardOpen
+	task.CreatedAt = now
+	task.UpdatedAt = now
+
+	unlock := lockBoard(session)
+	defer unlock()
+	data, err := json.Marshal(task)
+	if err != nil {
+		return BoardTask{}, err
+	}
+	if err := appendToFile(BoardPath(session), append(data, '\n')); err != nil {
+		return BoardTask{}, err
+	}
+	return task, nil
+}
+
+// BoardUpdate is a change to a board task. Empty fields are left as is.
+type BoardUpdate struct {
+	Status   string
+	Priority string
+	Assignee string
+	Note     string
+	Links    []string
+}
+
+// findBoardTask returns the index of the task whose ID equals id or, failing
+// that, is the only one starting with id.
+func findBoardTask(tasks []BoardTask, id string) (int, error) {
+	match := -1
+	for i, t := range tasks {
+		if t.ID == id {
+			return i, nil
+		}
+		if id != "" && strings.HasPrefix(t.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("task id %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Errorf("task not found: %s", id)
+	}
+	return match, nil
+}
+
+// GetBoardTask returns the task with the given ID or unique ID prefix.
+func GetBoardTask(session, id string) (BoardTask, error) {
+	tasks, err := ReadBoard(session)
+	if err != nil {
+		return BoardTask{}, err
+	}
+	i, err := findBoardTask(tasks, id)
+	if err != nil {
+		return BoardTask{}, err
+	}
+	return tasks[i], nil
+}
+
+// modifyBoardTask applies fn to the task with the given ID (or unique ID
+// prefix) under the board lock and saves the board. If fn fails nothing is
+// written.
+func modifyBoardTask(session, id string, fn func(t *BoardTask) error) (BoardTask, error) {
+	unlock := lockBoard(session)
+	defer unlock()
+	tasks, err := ReadBoard(session)
+	if err != nil {
+		return BoardTask{}, err
+	}
+	i, err := findBoardTask(tasks, id)
+	if err != nil {
+		return BoardTask{}, err
+	}
+	if err := fn(&tasks[i]); err != nil {
+		return BoardTask{}, err
+	}
+	tasks[i].UpdatedAt = time.Now().Unix()
+	return tasks[i], writeBoard(session, tasks)
+}
+
+// UpdateBoardTask applies an update made by actor to the task with the
+// given ID (or unique ID prefix) and returns the updated task.
+func UpdateBoardTask(session, id, actor string, u BoardUpdate) (BoardTask, error) {
+	if u.Status != "" && !containsRole(BoardStatuses, u.Status) {
+		return BoardTask{}, fmt.Errorf("invalid status %q (want one of %v)", u.Status, BoardStatuses)
+	}
+	if u.Priority != "" && !containsRole(BoardPriorities, u.Priority) {
+		return BoardTask{}, fmt.Errorf("invalid priority %q (want one of %v)", u.Priority, BoardPriorities)
+	}
+	if u.Assignee != "" && !IsKnownRole(u.Assignee) {
+		return BoardTask{}, fmt.Errorf("unknown assignee role: %s", u.Assignee)
+	}
+	return modifyBoardTask(session, id, func(t *BoardTask) error {
+		applyBoardUpdate(t, actor, u)
+		return nil
+	})
+}
+
+// applyBoardUpdate sets the non-empty fields of u on t.
+func applyBoardUpdate(t *BoardTask, actor string, u BoardUpdate) {
+	if u.Status != "" {
+		t.Status = u.Status
+	}
+	if u.Priority != "" {
+		t.Priority = u.Priority
+	}
+	if u.Assignee != "" {
+		t.Assignee = u.Assignee
+	}
+	for _, l := range u.Links {
+		if !containsRole(t.Links, l) {
+			t.Links = append(t.Links, l)
+		}
+	}
+	if note := strings.TrimSpace(u.Note); note != "" {
+		t.Notes = append(t.Notes, BoardNote{TS: time.Now().Unix(), Role: actor, Text: note})
+	}
+}
+
+// ClaimBoardTask assigns a task to role and marks it claimed. A task that
+// is done, or already assigned to another role, can't be claimed.
+func ClaimBoardTask(session, id, role string) (BoardTask, error) {
+	if !IsKnownRole(role) {
+		return BoardTask{}, fmt.Errorf("unknown role: %s", role)
+	}
+	return modifyBoardTask(session, id, func(t *BoardTask) error {
+		if !t.Active() {
+			return fmt.Errorf("task %s is done", t.ID)
+		}
+		if t.Assignee != "" && t.Assignee != role {
+			return fmt.Errorf("task %s is assigned to %s", t.ID, t.Assignee)
+		}
+		applyBoardUpdate(t, role, BoardUpdate{Status: BoardClaimed, Assignee: role})
+		return nil
+	})
+}
+
+// CompleteBoardTask marks a task done, with an optional closing note.
+func CompleteBoardTask(session, id, actor, note string) (BoardTask, error) {
+	return UpdateBoardTask(session, id, actor, BoardUpdate{Status: BoardDone, Note: note})
+}
+
+// BoardFilter selects board tasks. Empty fields match everything; done
+// tasks are excluded unless All is set or Status is "done".
+type BoardFilter struct {
+	Status   string
+	Assignee string
+	All      bool
+}
+
+// FilterBoard returns the tasks matching f, sorted by priority then age.
+func FilterBoard(tasks []BoardTask, f BoardFilter) []BoardTask {
+	var out []BoardTask
+	for _, t := range tasks {
+		if f.Status != "" && t.Status != f.Status {
+			continue
+		}
+		if f.Status == "" && !f.All && !t.Active() {
+			continue
+		}
+		if f.Assignee != "" && t.Assignee != f.Assignee {
+			continue
+		}
+		out = append(out, t)
+	}
+	rank := func(p string) int {
+		for i, q := range BoardPriorities {
+			if p == q {
+				return i
+			}
+		}
+		return len(BoardPriorities)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if ri, rj := rank(out[i].Priority), rank(out[j].Priority); ri != rj {
+			return ri < rj
+		}
+		return out[i].CreatedAt < out[j].CreatedAt
+	})
+	return out
+}
+
+// BoardCounts returns the number of active tasks assigned to each role.
+func BoardCounts(session string) map[string]int {
+	tasks, _ := ReadBoard(session)
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		if t.Active() && t.Assignee != "" {
+			counts[t.Assignee]++
+		}
+	}
+	return counts
+}
+
+// FormatBoard formats board tasks as a table.
+func FormatBoard(tasks []BoardTask) string {
+	if len(tasks) == 0 {
+		return "No tasks on the board.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-30s %-6s %-11s %-10s %s\n", "ID", "PRI", "STATUS", "ASSIGNEE", "TITLE")
+	for _, t := range tasks {
+		assignee := t.Assignee
+		if assignee == "" {
+			assignee = "-"
+		}
+		title := t.Title
+		if len(t.Links) > 0 {
+			title += fmt.Sprintf(" (%d links)", len(t.Links))
+		}
+		fmt.Fprintf(&b, "%-30s %-6s %-11s %-10s %s\n", t.ID, t.Priority, t.Status, assignee, title)
+	}
+	return b.String()
+}
+
+// FormatBoardTask formats one task with its links and notes.
+func FormatBoardTask(t BoardTask) string {
+	var b strings.Builder
+	assignee := t.Assignee
+	if assignee == "" {
+		assignee = "-"
+	}
+	fmt.Fprintf(&b, "%s  %s\n", t.ID, t.Title)
+	fmt.Fprintf(&b, "  Status: %s  Priority: %s  Assignee: %s  Created by: %s\n", t.Status, t.Priority, assignee, t.CreatedBy)
+	fmt.Fprintf(&b, "  Created: %s  Updated: %s\n",
+		time.Unix(t.CreatedAt, 0).Format("2006-01-02 15:04"), time.Unix(t.UpdatedAt, 0).Format("2006-01-02 15:04"))
+	if len(t.Links) > 0 {
+		fmt.Fprintf(&b, "  Links: %s\n", strings.Join(t.Links, ", "))
+	}
+	for _, n := range t.Notes {
+		fmt.Fprintf(&b, "  %s %s: %s\n", time.Unix(n.TS, 0).Format("01-02 15:04"), n.Role, n.Text)
+	}
+	return b.String()
+}
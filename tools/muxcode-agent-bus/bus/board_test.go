@@ -0,0 +1,197 @@
+package bus
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBoard_Lifecycle(t *testing.T) {
+	session := testSession(t)
+
+	task, err := AddBoardTask(session, BoardTask{Title: "fix flaky tests", CreatedBy: "edit", Links: []string{"m1"}})
+	if err != nil {
+		t.Fatalf("AddBoardTask: %v", err)
+	}
+	if task.Status != BoardOpen || task.Priority != "normal" || !strings.Contains(task.ID, "-board-") {
+		t.Errorf("unexpected new task %+v", task)
+	}
+
+	claimed, err := ClaimBoardTask(session, task.ID, "test")
+	if err != nil {
+		t.Fatalf("ClaimBoardTask: %v", err)
+	}
+	if claimed.Status != BoardClaimed || claimed.Assignee != "test" {
+		t.Errorf("after claim: %+v", claimed)
+	}
+
+	updated, err := UpdateBoardTask(session, task.ID, "test", BoardUpdate{
+		Status: BoardInProgress, Priority: "high", Note: "reproduced locally", Links: []string{"m1", "m2"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBoardTask: %v", err)
+	}
+	if updated.Status != BoardInProgress || updated.Priority != "high" {
+		t.Errorf("after update: %+v", updated)
+	}
+	if len(updated.Links) != 2 || len(updated.Notes) != 1 || updated.Notes[0].Role != "test" {
+		t.Errorf("links/notes after update: %v %+v", updated.Links, updated.Notes)
+	}
+
+	done, err := CompleteBoardTask(session, task.ID, "test", "fixed in abc123")
+	if err != nil {
+		t.Fatalf("CompleteBoardTask: %v", err)
+	}
+	if done.Status != BoardDone || done.Active() || len(done.Notes) != 2 {
+		t.Errorf("after done: %+v", done)
+	}
+
+	if _, err := ClaimBoardTask(session, task.ID, "review"); err == nil {
+		t.Error("expected error claiming a done task")
+	}
+
+	got, err := GetBoardTask(session, task.ID)
+	if err != nil || got.Status != BoardDone {
+		t.Errorf("GetBoardTask: %+v %v", got, err)
+	}
+}
+
+func TestBoard_Validation(t *testing.T) {
+	session := testSession(t)
+
+	if _, err := AddBoardTask(session, BoardTask{Title: "  "}); err == nil {
+		t.Error("expected error for empty title")
+	}
+	if _, err := AddBoardTask(session, BoardTask{Title: "x", Priority: "urgent"}); err == nil {
+		t.Error("expected error for invalid priority")
+	}
+	if _, err := AddBoardTask(session, BoardTask{Title: "x", Assignee: "nobody"}); err == nil {
+		t.Error("expected error for unknown assignee")
+	}
+	task, err := AddBoardTask(session, BoardTask{Title: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateBoardTask(session, task.ID, "edit", BoardUpdate{Status: "paused"}); err == nil {
+		t.Error("expected error for invalid status")
+	}
+	if _, err := UpdateBoardTask(session, "nope", "edit", BoardUpdate{Status: BoardBlocked}); err == nil {
+		t.Error("expected error for unknown task")
+	}
+}
+
+func TestBoard_ClaimConflict(t *testing.T) {
+	session := testSession(t)
+
+	task, err := AddBoardTask(session, BoardTask{Title: "review auth", Assignee: "review"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ClaimBoardTask(session, task.ID, "build"); err == nil {
+		t.Error("expected error claiming a task assigned to another role")
+	}
+	if _, err := ClaimBoardTask(session, task.ID, "review"); err != nil {
+		t.Errorf("assignee should be able to claim: %v", err)
+	}
+}
+
+func TestFindBoardTask_Prefix(t *testing.T) {
+	tasks := []BoardTask{{ID: "100-board-aaaa"}, {ID: "100-board-aabb"}, {ID: "200-board-cccc"}}
+
+	if i, err := findBoardTask(tasks, "200"); err != nil || i != 2 {
+		t.Errorf("unique prefix: i=%d err=%v", i, err)
+	}
+	if i, err := findBoardTask(tasks, "100-board-aabb"); err != nil || i != 1 {
+		t.Errorf("exact id: i=%d err=%v", i, err)
+	}
+	if _, err := findBoardTask(tasks, "100-board-aa"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected ambiguous error, got %v", err)
+	}
+	if _, err := findBoardTask(tasks, "300"); err == nil {
+		t.Error("expected not-found error")
+	}
+}
+
+func TestFilterBoard(t *testing.T) {
+	tasks := []BoardTask{
+		{ID: "a", Priority: "low", Status: BoardOpen, CreatedAt: 1},
+		{ID: "b", Priority: "high", Status: BoardClaimed, Assignee: "build", CreatedAt: 3},
+		{ID: "c", Priority: "normal", Status: BoardDone, Assignee: "build", CreatedAt: 2},
+		{ID: "d", Priority: "high", Status: BoardBlocked, CreatedAt: 2},
+	}
+	ids := func(ts []BoardTask) string {
+		var out []string
+		for _, t := range ts {
+			out = append(out, t.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(FilterBoard(tasks, BoardFilter{})); got != "d,b,a" {
+		t.Errorf("default = %q, want d,b,a", got)
+	}
+	if got := ids(FilterBoard(tasks, BoardFilter{All: true})); got != "d,b,c,a" {
+		t.Errorf("all = %q, want d,b,c,a", got)
+	}
+	if got := ids(FilterBoard(tasks, BoardFilter{Status: BoardDone})); got != "c" {
+		t.Errorf("status done = %q, want c", got)
+	}
+	if got := ids(FilterBoard(tasks, BoardFilter{Assignee: "build"})); got != "b" {
+		t.Errorf("assignee build = %q, want b", got)
+	}
+}
+
+func TestBoard_ConcurrentUpdates(t *testing.T) {
+	session := testSession(t)
+
+	task, err := AddBoardTask(session, BoardTask{Title: "shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := UpdateBoardTask(session, task.ID, "edit", BoardUpdate{Note: "progress"}); err != nil {
+				t.Errorf("UpdateBoardTask: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := GetBoardTask(session, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Notes) != 10 {
+		t.Errorf("notes = %d, want 10 (lost updates)", len(got.Notes))
+	}
+}
+
+func TestBoard_StatusCounts(t *testing.T) {
+	session := testSession(t)
+
+	for _, title := range []string{"one", "two"} {
+		if _, err := AddBoardTask(session, BoardTask{Title: title, Assignee: "build"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done, err := AddBoardTask(session, BoardTask{Title: "three", Assignee: "build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompleteBoardTask(session, done.ID, "build", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := GetAgentStatus(session, "build").Tasks; got != 2 {
+		t.Errorf("build tasks = %d, want 2", got)
+	}
+	if got := GetAgentStatus(session, "test").Tasks; got != 0 {
+		t.Errorf("test tasks = %d, want 0", got)
+	}
+	if table := FormatStatusTable([]AgentStatus{GetAgentStatus(session, "build")}); !strings.Contains(table, "TASKS") {
+		t.Errorf("status table missing TASKS column:\n%s", table)
+	}
+}
@@ -0,0 +1,165 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BudgetExceededAction is the event action sent when a role exceeds its
+// hourly token or turn budget.
+const BudgetExceededAction = "budget-exceeded"
+
+// budgetWindowSecs is the rolling window budgets are measured over.
+const budgetWindowSecs = 3600
+
+// UsageEntry records one harness LLM call.
+type UsageEntry struct {
+	TS     int64 `json:"ts"`
+	Tokens int   `json:"tokens"`
+}
+
+// RoleUsage is a role's usage within the budget window.
+type RoleUsage struct {
+	Role   string `json:"role"`
+	Tokens int    `json:"tokens"`
+	Turns  int    `json:"turns"`
+	Oldest int64  `json:"oldest,omitempty"` // timestamp of the oldest call in the window
+}
+
+// AppendUsage records one LLM call's token usage for a role.
+func AppendUsage(session, role string, tokens int) error {
+	data, err := json.Marshal(UsageEntry{TS: time.Now().Unix(), Tokens: tokens})
+	if err != nil {
+		return err
+	}
+	return appendToFile(UsagePath(session, role), append(data, '\n'))
+}
+
+// ReadUsage sums a role's usage since the given timestamp.
+func ReadUsage(session, role string, since int64) RoleUsage {
+	usage := RoleUsage{Role: role}
+	data, err := os.ReadFile(UsagePath(session, role))
+	if err != nil {
+		return usage
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e UsageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.TS < since {
+			continue
+		}
+		usage.Tokens += e.Tokens
+		usage.Turns++
+		if usage.Oldest == 0 || e.TS < usage.Oldest {
+			usage.Oldest = e.TS
+		}
+	}
+	return usage
+}
+
+// CheckBudget compares a role's usage over the last hour to its configured
+// budget. Returns nil when no budget is set or usage is within it.
+func CheckBudget(session, role string, limits GuardLimits) *LoopAlert {
+	if limits.TokensPerHour <= 0 && limits.TurnsPerHour <= 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	usage := ReadUsage(session, role, now-budgetWindowSecs)
+
+	var over []string
+	count := 0
+	if limits.TokensPerHour > 0 && usage.Tokens > limits.TokensPerHour {
+		over = append(over, fmt.Sprintf("%d tokens (budget %d)", usage.Tokens, limits.TokensPerHour))
+		count = usage.Tokens
+	}
+	if limits.TurnsPerHour > 0 && usage.Turns > limits.TurnsPerHour {
+		over = append(over, fmt.Sprintf("%d turns (budget %d)", usage.Turns, limits.TurnsPerHour))
+		if count == 0 {
+			count = usage.Turns
+		}
+	}
+	if len(over) == 0 {
+		return nil
+	}
+	return &LoopAlert{
+		Role:    role,
+		Type:    "budget",
+		Count:   count,
+		Window:  now - usage.Oldest,
+		Message: fmt.Sprintf("%s used %s in the last hour", role, strings.Join(over, ", ")),
+	}
+}
+
+// CheckAllBudgets checks every known role's budget.
+func CheckAllBudgets(session string) []LoopAlert {
+	var alerts []LoopAlert
+	for _, role := range KnownRoles {
+		if alert := CheckBudget(session, role, GuardLimitsFor(role)); alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
+
+// PauseHarness stops a role's harness from consuming its inbox until the
+// given time. Messages stay queued until the pause lifts.
+func PauseHarness(session, role string, until int64) error {
+	return os.WriteFile(HarnessPausePath(session, role), []byte(strconv.FormatInt(until, 10)), 0644)
+}
+
+// ResumeHarness lifts a harness pause.
+func ResumeHarness(session, role string) error {
+	err := os.Remove(HarnessPausePath(session, role))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// HarnessPausedUntil returns when a role's harness pause lifts, or 0 if the
+// harness is not paused. Expired markers are removed.
+func HarnessPausedUntil(session, role string) int64 {
+	data, err := os.ReadFile(HarnessPausePath(session, role))
+	if err != nil {
+		return 0
+	}
+	until, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || until <= time.Now().Unix() {
+		_ = os.Remove(HarnessPausePath(session, role))
+		return 0
+	}
+	return until
+}
+
+// FormatUsage formats role usage against budgets as a table.
+func FormatUsage(usages []RoleUsage, session string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-12s %10s %10s %8s %8s  %s\n", "Role", "Tokens", "Budget", "Turns", "Budget", "Status"))
+	b.WriteString(strings.Repeat("-", 70) + "\n")
+	for _, u := range usages {
+		limits := GuardLimitsFor(u.Role)
+		status := "ok"
+		if until := HarnessPausedUntil(session, u.Role); until > 0 {
+			status = "paused until " + time.Unix(until, 0).Format("15:04")
+		} else if (limits.TokensPerHour > 0 && u.Tokens > limits.TokensPerHour) || (limits.TurnsPerHour > 0 && u.Turns > limits.TurnsPerHour) {
+			status = "over budget"
+		}
+		b.WriteString(fmt.Sprintf("%-12s %10d %10s %8d %8s  %s\n",
+			u.Role, u.Tokens, budgetLabel(limits.TokensPerHour), u.Turns, budgetLabel(limits.TurnsPerHour), status))
+	}
+	return b.String()
+}
+
+// budgetLabel renders a budget limit, "-" when unlimited.
+func budgetLabel(n int) string {
+	if n <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,102 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckBudget(t *testing.T) {
+	session := fmt.Sprintf("test-budget-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	// One stale call outside the window, three recent ones
+	stale, _ := json.Marshal(UsageEntry{TS: time.Now().Unix() - 7200, Tokens: 50000})
+	_ = appendToFile(UsagePath(session, "build"), append(stale, '\n'))
+	for _, n := range []int{4000, 3000, 2000} {
+		if err := AppendUsage(session, "build", n); err != nil {
+			t.Fatalf("AppendUsage: %v", err)
+		}
+	}
+
+	usage := ReadUsage(session, "build", time.Now().Unix()-3600)
+	if usage.Tokens != 9000 || usage.Turns != 3 {
+		t.Fatalf("usage = %+v, want 9000 tokens / 3 turns", usage)
+	}
+
+	if alert := CheckBudget(session, "build", GuardLimits{}); alert != nil {
+		t.Errorf("no budget should not alert: %+v", alert)
+	}
+	if alert := CheckBudget(session, "build", GuardLimits{TokensPerHour: 10000, TurnsPerHour: 5}); alert != nil {
+		t.Errorf("within budget should not alert: %+v", alert)
+	}
+
+	alert := CheckBudget(session, "build", GuardLimits{TokensPerHour: 8000, TurnsPerHour: 2})
+	if alert == nil {
+		t.Fatal("expected budget alert")
+	}
+	if alert.Type != "budget" || !strings.Contains(alert.Message, "9000 tokens (budget 8000)") || !strings.Contains(alert.Message, "3 turns (budget 2)") {
+		t.Errorf("alert = %+v", alert)
+	}
+	if AlertKey(*alert) != "build:budget" {
+		t.Errorf("AlertKey = %q", AlertKey(*alert))
+	}
+}
+
+func TestCheckAllBudgets_Config(t *testing.T) {
+	session := fmt.Sprintf("test-budget-cfg-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	cfg := DefaultConfig()
+	cfg.Guard.Roles = map[string]GuardLimits{"build": {TurnsPerHour: 1}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	_ = AppendUsage(session, "build", 10)
+	_ = AppendUsage(session, "build", 10)
+	_ = AppendUsage(session, "test", 10)
+	_ = AppendUsage(session, "test", 10)
+
+	alerts := CheckAllBudgets(session)
+	if len(alerts) != 1 || alerts[0].Role != "build" {
+		t.Errorf("alerts = %+v, want build only", alerts)
+	}
+}
+
+func TestHarnessPause(t *testing.T) {
+	session := fmt.Sprintf("test-budget-pause-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	if HarnessPausedUntil(session, "build") != 0 {
+		t.Error("paused before PauseHarness")
+	}
+	until := time.Now().Unix() + 600
+	if err := PauseHarness(session, "build", until); err != nil {
+		t.Fatalf("PauseHarness: %v", err)
+	}
+	if got := HarnessPausedUntil(session, "build"); got != until {
+		t.Errorf("HarnessPausedUntil = %d, want %d", got, until)
+	}
+	if out := FormatUsage([]RoleUsage{{Role: "build"}}, session); !strings.Contains(out, "paused until") {
+		t.Errorf("FormatUsage:\n%s", out)
+	}
+	if err := ResumeHarness(session, "build"); err != nil {
+		t.Fatalf("ResumeHarness: %v", err)
+	}
+	if HarnessPausedUntil(session, "build") != 0 {
+		t.Error("still paused after ResumeHarness")
+	}
+
+	_ = PauseHarness(session, "build", time.Now().Unix()-1)
+	if HarnessPausedUntil(session, "build") != 0 {
+		t.Error("expired pause still active")
+	}
+}
@@ -0,0 +1,344 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Callback status values.
+const (
+	CallbackPending   = "pending"   // waiting for the recipient's result
+	CallbackResolved  = "resolved"  // result recorded, POST not yet delivered
+	CallbackDelivered = "delivered" // POST succeeded
+	CallbackFailed    = "failed"    // POST failed callbackMaxAttempts times
+	CallbackExpired   = "expired"   // no result within callbackTTL (expiry POSTed)
+)
+
+// callbackTTL is how long a callback waits for a result before the watcher
+// POSTs an "expired" payload instead.
+const callbackTTL = time.Hour
+
+// callbackMaxAttempts caps POST attempts; retries back off from 30s.
+const callbackMaxAttempts = 5
+
+// callbackKeep is how long finished callbacks stay in callbacks.jsonl.
+const callbackKeep = 24 * time.Hour
+
+// Callback is a pending or finished callback URL registered by
+// send --callback-url (or callback_url on the webhook and API send
+// endpoints). It resolves when a result for the request's trace arrives
+// (see isCallbackResult); the watcher then POSTs a CallbackPayload.
+type Callback struct {
+	ID          string   `json:"id"` // request message ID
+	URL         string   `json:"url"`
+	TraceID     string   `json:"trace_id"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Action      string   `json:"action"`
+	Status      string   `json:"status"`
+	CreatedAt   int64    `json:"created_at"`
+	Result      *Message `json:"result,omitempty"`
+	Attempts    int      `json:"attempts,omitempty"`
+	NextAttempt int64    `json:"next_attempt,omitempty"`
+	LastError   string   `json:"last_error,omitempty"`
+	DoneAt      int64    `json:"done_at,omitempty"`
+}
+
+// CallbackPayload is the JSON body POSTed to a callback URL. Status is
+// "completed" with the result message, or "expired" without one.
+type CallbackPayload struct {
+	ID      string          `json:"id"`
+	Session string          `json:"session"`
+	Status  string          `json:"status"`
+	To      string          `json:"to"`
+	Action  string          `json:"action"`
+	TraceID string          `json:"trace_id"`
+	Result  *CallbackResult `json:"result,omitempty"`
+	TS      int64           `json:"ts"`
+}
+
+// CallbackResult is the result message carried by a callback payload.
+type CallbackResult struct {
+	ID      string `json:"id"`
+	From    string `json:"from"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Payload string `json:"payload"`
+	TS      int64  `json:"ts"`
+}
+
+// Finished reports whether the callback needs no more work.
+func (c Callback) Finished() bool {
+	return c.Status == CallbackDelivered || c.Status == CallbackFailed || c.Status == CallbackExpired
+}
+
+// CallbacksPath returns the callback registry JSONL file for a session.
+func CallbacksPath(session string) string {
+	return filepath.Join(BusDir(session), "callbacks.jsonl")
+}
+
+// CheckCallbackURL validates a callback URL (http or https).
+func CheckCallbackURL(raw string) error {
+	return checkWebhookURL(raw)
+}
+
+// lockCallbacks serializes read-modify-write cycles on the registry between
+// senders and the watcher.
+func lockCallbacks(session string) func() {
+	return flockBusFile(session, "callbacks")
+}
+
+// ReadCallbacks reads all registered callbacks, oldest first.
+func ReadCallbacks(session string) ([]Callback, error) {
+	data, err := os.ReadFile(CallbacksPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cbs []Callback
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var c Callback
+		if err := json.Unmarshal(line, &c); err != nil {
+			continue // skip malformed lines
+		}
+		cbs = append(cbs, c)
+	}
+	return cbs, scanner.Err()
+}
+
+// writeCallbacks overwrites the registry with the given callbacks.
+func writeCallbacks(session string, cbs []Callback) error {
+	var buf bytes.Buffer
+	for _, c := range cbs {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := CallbacksPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, CallbacksPath(session))
+}
+
+// registerCallback records a callback for a message that carries a
+// callback URL. sendMessage calls it after the message is stamped with its
+// trace, so results in that trace can be matched.
+func registerCallback(session string, m Message) error {
+	unlock := lockCallbacks(session)
+	defer unlock()
+
+	cbs, err := ReadCallbacks(session)
+	if err != nil {
+		return err
+	}
+	cbs = append(cbs, Callback{
+		ID:        m.ID,
+		URL:       m.CallbackURL,
+		TraceID:   m.TraceID,
+		From:      m.From,
+		To:        m.To,
+		Action:    m.Action,
+		Status:    CallbackPending,
+		CreatedAt: m.TS,
+	})
+	return writeCallbacks(session, cbs)
+}
+
+// isCallbackResult reports whether m answers the callback's request: a
+// message in the request's trace, not from the original sender, that is a
+// response or an event back to the sender or edit. Chain hand-offs
+// (requests to the next role) keep the callback pending, so it resolves
+// when the chain completes — e.g. review's review-complete response, or a
+// failure notify event to edit.
+func isCallbackResult(c Callback, m Message) bool {
+	if c.Status != CallbackPending || m.ID == c.ID || m.TraceID == "" || m.TraceID != c.TraceID {
+		return false
+	}
+	if m.From == c.From {
+		return false
+	}
+	if m.Type == "response" || m.ReplyTo == c.ID {
+		return true
+	}
+	return m.Type == "event" && (m.To == c.From || m.To == "edit")
+}
+
+// resolveCallbacks records m as the result of any pending callback it
+// answers. Cheap when no callbacks are registered.
+func resolveCallbacks(session string, m Message) error {
+	if _, err := os.Stat(CallbacksPath(session)); err != nil {
+		return nil
+	}
+	unlock := lockCallbacks(session)
+	defer unlock()
+
+	cbs, err := ReadCallbacks(session)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range cbs {
+		if !isCallbackResult(cbs[i], m) {
+			continue
+		}
+		result := m
+		cbs[i].Result = &result
+		cbs[i].Status = CallbackResolved
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return writeCallbacks(session, cbs)
+}
+
+// callbackPayload builds the POST body for a resolved or expired callback.
+func callbackPayload(session string, c Callback, now time.Time) CallbackPayload {
+	p := CallbackPayload{
+		ID:      c.ID,
+		Session: session,
+		Status:  "completed",
+		To:      c.To,
+		Action:  c.Action,
+		TraceID: c.TraceID,
+		TS:      now.Unix(),
+	}
+	if c.Result != nil {
+		p.Result = &CallbackResult{
+			ID:      c.Result.ID,
+			From:    c.Result.From,
+			Type:    c.Result.Type,
+			Action:  c.Result.Action,
+			Payload: c.Result.Payload,
+			TS:      c.Result.TS,
+		}
+	} else {
+		p.Status = "expired"
+	}
+	return p
+}
+
+// postCallback POSTs the payload to a callback URL. Slack response URLs
+// (from the slash-command bridge) get an in-channel Slack message instead.
+func postCallback(target string, p CallbackPayload) error {
+	if isSlackWebhookURL(target) {
+		_, err := postJSON(target, nil, slackCallbackBody(p))
+		return err
+	}
+	_, err := postJSON(target, nil, p)
+	return err
+}
+
+// callbackBackoff returns the wait before the next POST attempt: 30s,
+// doubling per failed attempt.
+func callbackBackoff(attempts int) time.Duration {
+	return (30 * time.Second) << uint(attempts-1)
+}
+
+// DeliverCallbacks POSTs resolved callbacks, and an "expired" payload for
+// callbacks still pending after callbackTTL. Failed POSTs are retried with
+// backoff up to callbackMaxAttempts. Finished callbacks older than a day
+// are pruned. The watcher calls it periodically; it returns the callbacks
+// that finished during this call.
+func DeliverCallbacks(session string, now time.Time) ([]Callback, error) {
+	if _, err := os.Stat(CallbacksPath(session)); err != nil {
+		return nil, nil
+	}
+	unlock := lockCallbacks(session)
+	defer unlock()
+
+	cbs, err := ReadCallbacks(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, finished []Callback
+	changed := false
+	for _, c := range cbs {
+		if c.Finished() {
+			if now.Sub(time.Unix(c.DoneAt, 0)) >= callbackKeep {
+				changed = true
+				continue
+			}
+			kept = append(kept, c)
+			continue
+		}
+		expired := c.Status == CallbackPending && now.Sub(time.Unix(c.CreatedAt, 0)) >= callbackTTL
+		if (c.Status == CallbackPending && !expired) || c.NextAttempt > now.Unix() {
+			kept = append(kept, c)
+			continue
+		}
+
+		changed = true
+		c.Attempts++
+		if err := postCallback(c.URL, callbackPayload(session, c, now)); err != nil {
+			c.LastError = err.Error()
+			if c.Attempts < callbackMaxAttempts {
+				if expired {
+					// Hold the result slot so a late answer is not recorded
+					c.Status = CallbackResolved
+				}
+				c.NextAttempt = now.Add(callbackBackoff(c.Attempts)).Unix()
+				kept = append(kept, c)
+				continue
+			}
+			c.Status = CallbackFailed
+		} else if c.Result == nil {
+			c.Status = CallbackExpired
+		} else {
+			c.Status = CallbackDelivered
+			c.LastError = ""
+		}
+		c.DoneAt = now.Unix()
+		kept = append(kept, c)
+		finished = append(finished, c)
+	}
+	if !changed {
+		return nil, nil
+	}
+	return finished, writeCallbacks(session, kept)
+}
+
+// FormatCallbacks renders callbacks as a table, newest first. Finished
+// callbacks are included only when all is true.
+func FormatCallbacks(cbs []Callback, all bool) string {
+	var b strings.Builder
+	shown := 0
+	for i := len(cbs) - 1; i >= 0; i-- {
+		c := cbs[i]
+		if c.Finished() && !all {
+			continue
+		}
+		if shown == 0 {
+			fmt.Fprintf(&b, "%-30s %-10s %-8s %-16s %-8s %s\n", "ID", "STATUS", "TO", "ACTION", "ATTEMPTS", "URL")
+		}
+		shown++
+		fmt.Fprintf(&b, "%-30s %-10s %-8s %-16s %-8d %s\n", c.ID, c.Status, c.To, c.Action, c.Attempts, c.URL)
+		if c.LastError != "" {
+			fmt.Fprintf(&b, "  last error: %s\n", c.LastError)
+		}
+	}
+	if shown == 0 {
+		return "No callbacks.\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package bus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// callbackServer records the payloads POSTed to it; fail makes it return 500.
+type callbackServer struct {
+	mu       sync.Mutex
+	payloads []CallbackPayload
+	fail     bool
+}
+
+func (cs *callbackServer) setFail(fail bool) {
+	cs.mu.Lock()
+	cs.fail = fail
+	cs.mu.Unlock()
+}
+
+func newCallbackServer(t *testing.T) (*callbackServer, *httptest.Server) {
+	t.Helper()
+	cs := &callbackServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		if cs.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var p CallbackPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		cs.payloads = append(cs.payloads, p)
+	}))
+	t.Cleanup(srv.Close)
+	return cs, srv
+}
+
+// sendWithCallback sends a request from edit to build carrying a callback URL.
+func sendWithCallback(t *testing.T, session, url string) Message {
+	t.Helper()
+	t.Setenv(TraceParentEnv, "")
+	msg := NewMessage("edit", "build", "request", "build", "build it", "")
+	msg.CallbackURL = url
+	if err := Send(session, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	return msg
+}
+
+func TestCallback_ResolvesWhenChainCompletes(t *testing.T) {
+	session := testSession(t)
+	cs, srv := newCallbackServer(t)
+	req := sendWithCallback(t, session, srv.URL)
+
+	cbs, _ := ReadCallbacks(session)
+	if len(cbs) != 1 || cbs[0].ID != req.ID || cbs[0].Status != CallbackPending || cbs[0].TraceID == "" {
+		t.Fatalf("callbacks = %+v, want one pending for %s", cbs, req.ID)
+	}
+
+	// Build picks up the request and hands off to test: still pending
+	if _, err := Receive(session, "build"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Send(session, NewMessage("build", "test", "request", "test", "run tests", "")); err != nil {
+		t.Fatal(err)
+	}
+	if cbs, _ := ReadCallbacks(session); cbs[0].Status != CallbackPending {
+		t.Fatalf("chain hand-off resolved the callback: %+v", cbs[0])
+	}
+
+	// Test reports back to edit: resolved
+	if _, err := Receive(session, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Send(session, NewMessage("test", "edit", "response", "test-complete", "all passed", "")); err != nil {
+		t.Fatal(err)
+	}
+	cbs, _ = ReadCallbacks(session)
+	if cbs[0].Status != CallbackResolved || cbs[0].Result == nil || cbs[0].Result.Action != "test-complete" {
+		t.Fatalf("callback = %+v, want resolved by test-complete", cbs[0])
+	}
+
+	finished, err := DeliverCallbacks(session, time.Now())
+	if err != nil {
+		t.Fatalf("DeliverCallbacks: %v", err)
+	}
+	if len(finished) != 1 || finished[0].Status != CallbackDelivered {
+		t.Fatalf("finished = %+v, want one delivered", finished)
+	}
+	if len(cs.payloads) != 1 {
+		t.Fatalf("got %d POSTs, want 1", len(cs.payloads))
+	}
+	p := cs.payloads[0]
+	if p.ID != req.ID || p.Status != "completed" || p.Result == nil || p.Result.Payload != "all passed" || p.Result.From != "test" {
+		t.Errorf("payload = %+v", p)
+	}
+
+	// Delivered callbacks are not posted again
+	if finished, _ := DeliverCallbacks(session, time.Now()); len(finished) != 0 || len(cs.payloads) != 1 {
+		t.Error("delivered callback was posted again")
+	}
+}
+
+func TestCallback_OtherTraceIgnored(t *testing.T) {
+	session := testSession(t)
+	_, srv := newCallbackServer(t)
+	sendWithCallback(t, session, srv.URL)
+
+	// A response from a role that never saw the request starts its own trace
+	if err := Send(session, NewMessage("review", "edit", "response", "review-complete", "LGTM", "")); err != nil {
+		t.Fatal(err)
+	}
+	if cbs, _ := ReadCallbacks(session); cbs[0].Status != CallbackPending {
+		t.Errorf("unrelated response resolved the callback: %+v", cbs[0])
+	}
+}
+
+func TestCallback_RetryAndExpire(t *testing.T) {
+	session := testSession(t)
+	cs, srv := newCallbackServer(t)
+	sendWithCallback(t, session, srv.URL)
+
+	// Not expired yet: nothing to do
+	if finished, _ := DeliverCallbacks(session, time.Now()); len(finished) != 0 || len(cs.payloads) != 0 {
+		t.Fatal("pending callback should not be posted")
+	}
+
+	// Expired, but the endpoint is down: retried later
+	cs.setFail(true)
+	now := time.Now().Add(callbackTTL + time.Minute)
+	if finished, _ := DeliverCallbacks(session, now); len(finished) != 0 {
+		t.Fatalf("failed POST finished the callback: %+v", finished)
+	}
+	cbs, _ := ReadCallbacks(session)
+	if cbs[0].Attempts != 1 || cbs[0].LastError == "" || cbs[0].NextAttempt <= now.Unix() {
+		t.Fatalf("callback after failed POST = %+v", cbs[0])
+	}
+	_, _ = DeliverCallbacks(session, now)
+	if cbs, _ := ReadCallbacks(session); cbs[0].Attempts != 1 {
+		t.Fatal("retry should wait for the backoff")
+	}
+
+	cs.setFail(false)
+	finished, _ := DeliverCallbacks(session, now.Add(time.Minute))
+	if len(finished) != 1 || finished[0].Status != CallbackExpired {
+		t.Fatalf("finished = %+v, want one expired", finished)
+	}
+	if len(cs.payloads) != 1 || cs.payloads[0].Status != "expired" || cs.payloads[0].Result != nil {
+		t.Errorf("payloads = %+v, want one expired", cs.payloads)
+	}
+
+	// Finished callbacks are pruned after a day
+	if _, err := DeliverCallbacks(session, now.Add(callbackKeep+time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if cbs, _ := ReadCallbacks(session); len(cbs) != 0 {
+		t.Errorf("finished callback not pruned: %+v", cbs)
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	for _, u := range []string{"https://ci.example.com/hook", "http://localhost:8080/cb"} {
+		if err := CheckCallbackURL(u); err != nil {
+			t.Errorf("CheckCallbackURL(%q): %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "ftp://host/x", "not a url"} {
+		if err := CheckCallbackURL(u); err == nil {
+			t.Errorf("CheckCallbackURL(%q) accepted", u)
+		}
+	}
+}
+
+func TestFormatCallbacks(t *testing.T) {
+	cbs := []Callback{
+		{ID: "1-edit-aa", URL: "https://ci/a", To: "build", Action: "build", Status: CallbackDelivered},
+		{ID: "2-edit-bb", URL: "https://ci/b", To: "review", Action: "review", Status: CallbackPending},
+	}
+	out := FormatCallbacks(cbs, false)
+	if !strings.Contains(out, "2-edit-bb") || strings.Contains(out, "1-edit-aa") {
+		t.Errorf("active listing:\n%s", out)
+	}
+	if out := FormatCallbacks(cbs, true); !strings.Contains(out, "1-edit-aa") {
+		t.Errorf("--all listing:\n%s", out)
+	}
+	if out := FormatCallbacks(nil, false); out != "No callbacks.\n" {
+		t.Errorf("empty listing = %q", out)
+	}
+}
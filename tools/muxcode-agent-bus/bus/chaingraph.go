@@ -0,0 +1,167 @@
+package bus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChainEdge is one edge of the chain graph: an event outcome that sends a
+// message to a role or external target.
+type ChainEdge struct {
+	Event   string `json:"event"`
+	Outcome string `json:"outcome"` // success, failure, unknown, or *
+	To      string `json:"to"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Source  string `json:"source"` // chain, step, analyst, or subscription
+	Match   string `json:"match,omitempty"`
+	Delay   string `json:"delay,omitempty"`
+	// Approval is the role that must approve the action, if gated.
+	Approval string `json:"approval,omitempty"`
+	// Promote is the environment whose promotion gate the action checks.
+	Promote string `json:"promote,omitempty"`
+}
+
+// ChainGraph returns the edges of the configured event chains plus the
+// enabled subscriptions, ordered by event name then configuration order.
+func ChainGraph(subs []Subscription) []ChainEdge {
+	chains := Config().EventChains
+	events := make([]string, 0, len(chains))
+	for event := range chains {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	var edges []ChainEdge
+	add := func(event, outcome, source string, a ChainAction) {
+		a = a.prRequest()
+		to, typ := a.SendTo, a.Type
+		if a.Plugin != "" {
+			to, typ = "plugin:"+a.Plugin, "plugin"
+		}
+		e := ChainEdge{
+			Event: event, Outcome: outcome, To: to, Type: typ, Action: a.Action,
+			Source: source, Match: a.Match, Delay: a.Delay, Promote: a.Promote,
+		}
+		if a.Approval != nil {
+			e.Approval = a.Approval.approver()
+		}
+		edges = append(edges, e)
+	}
+	for _, event := range events {
+		chain := chains[event]
+		for _, o := range []struct {
+			outcome string
+			action  *ChainAction
+		}{{"success", chain.OnSuccess}, {"failure", chain.OnFailure}, {"unknown", chain.OnUnknown}} {
+			if o.action != nil {
+				add(event, o.outcome, "chain", *o.action)
+			}
+		}
+		for _, s := range chain.Steps {
+			add(event, s.On, "step", s.ChainAction)
+		}
+		outcomes := chain.NotifyAnalystOn
+		if len(outcomes) == 0 && chain.NotifyAnalyst {
+			outcomes = []string{"*"}
+		}
+		for _, outcome := range outcomes {
+			// chain skips the analyst notification when the action already targets analyze
+			if a := ResolveChain(event, outcome); a != nil && a.SendTo == "analyze" {
+				continue
+			}
+			add(event, outcome, "analyst", ChainAction{SendTo: "analyze", Type: "event", Action: "notify"})
+		}
+	}
+
+	var subEdges []ChainEdge
+	for _, s := range subs {
+		if s.Enabled {
+			subEdges = append(subEdges, ChainEdge{
+				Event: s.Event, Outcome: s.Outcome, To: s.Targets(), Type: "event", Action: s.Action,
+				Source: "subscription", Match: s.Match,
+			})
+		}
+	}
+	sort.SliceStable(subEdges, func(i, j int) bool { return subEdges[i].Event < subEdges[j].Event })
+	return append(edges, subEdges...)
+}
+
+// edgeNotes returns the bracketed qualifiers for an edge, or "".
+func edgeNotes(e ChainEdge) string {
+	var notes []string
+	if e.Source != "chain" {
+		notes = append(notes, e.Source)
+	}
+	if e.Match != "" {
+		notes = append(notes, "match "+e.Match)
+	}
+	if e.Delay != "" {
+		notes = append(notes, "after "+e.Delay)
+	}
+	if e.Approval != "" {
+		notes = append(notes, "approval by "+e.Approval)
+	}
+	if e.Promote != "" {
+		notes = append(notes, "promote to "+e.Promote)
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(notes, ", ") + "]"
+}
+
+// FormatChainGraph renders chain edges as an indented tree per event.
+func FormatChainGraph(edges []ChainEdge) string {
+	if len(edges) == 0 {
+		return "No event chains or subscriptions.\n"
+	}
+	var b strings.Builder
+	event := ""
+	for i, e := range edges {
+		if i == 0 || e.Event != event {
+			event = e.Event
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "%s\n", event)
+		}
+		line := fmt.Sprintf("  %-8s -> %-10s %s:%s", e.Outcome, e.To, e.Type, e.Action)
+		if notes := edgeNotes(e); notes != "" {
+			line += "  " + notes
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// FormatChainDOT renders chain edges as a Graphviz digraph. Events are box
+// nodes; analyst and subscription edges are dotted and dashed.
+func FormatChainDOT(edges []ChainEdge) string {
+	var b strings.Builder
+	b.WriteString("digraph chains {\n  rankdir=LR;\n")
+	seen := map[string]bool{}
+	for _, e := range edges {
+		if node := "event:" + e.Event; !seen[node] {
+			seen[node] = true
+			fmt.Fprintf(&b, "  %q [shape=box];\n", node)
+		}
+	}
+	for _, e := range edges {
+		label := e.Outcome + `\n` + e.Type + ":" + e.Action
+		if notes := edgeNotes(e); notes != "" {
+			label += `\n` + notes
+		}
+		attrs := fmt.Sprintf("label=\"%s\"", strings.ReplaceAll(label, `"`, `\"`))
+		switch e.Source {
+		case "analyst":
+			attrs += ", style=dotted"
+		case "subscription":
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %q -> %q [%s];\n", "event:"+e.Event, e.To, attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChainGraph(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EventChains = map[string]EventChain{
+		"test": {
+			OnSuccess:       &ChainAction{SendTo: "review", Type: "request", Action: "review", Match: "^go test"},
+			OnUnknown:       &ChainAction{SendTo: "analyze", Type: "event", Action: "notify"},
+			Steps:           []ChainStep{{On: "success", ChainAction: ChainAction{SendTo: "docs", Type: "request", Action: "update", Delay: "2m"}}},
+			NotifyAnalystOn: []string{"failure", "unknown"},
+		},
+		"build": {OnFailure: &ChainAction{SendTo: "edit", Type: "event", Action: "notify"}},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	subs := []Subscription{
+		{Event: "*", Outcome: "failure", Notify: "watch", Action: "notify", Enabled: true},
+		{Event: "build", Outcome: "*", Notify: "docs", Action: "notify"},
+	}
+	edges := ChainGraph(subs)
+
+	var got []string
+	for _, e := range edges {
+		got = append(got, e.Event+"/"+e.Outcome+"->"+e.To+"("+e.Source+")")
+	}
+	want := []string{
+		"build/failure->edit(chain)",
+		"test/success->review(chain)",
+		"test/unknown->analyze(chain)",
+		"test/success->docs(step)",
+		"test/failure->analyze(analyst)", // unknown skipped: on_unknown already targets analyze
+		"*/failure->watch(subscription)",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("edges =\n  %v\nwant\n  %v", got, want)
+	}
+
+	text := FormatChainGraph(edges)
+	if !strings.Contains(text, "[step, after 2m]") || !strings.Contains(text, "[match ^go test]") {
+		t.Errorf("missing notes:\n%s", text)
+	}
+	dot := FormatChainDOT(edges)
+	if !strings.Contains(dot, `"event:test" -> "docs" [label="success\nrequest:update\n[step, after 2m]"];`) {
+		t.Errorf("missing step edge:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"event:*" -> "watch" [label="failure\nevent:notify\n[subscription]", style=dashed];`) {
+		t.Errorf("missing subscription edge:\n%s", dot)
+	}
+}
+
+func TestFormatChainGraph_Empty(t *testing.T) {
+	if got := FormatChainGraph(nil); got != "No event chains or subscriptions.\n" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,244 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultChainBackoff is the first retry delay when a chain sets retries
+// without backoff.
+const defaultChainBackoff = time.Minute
+
+// maxChainRetries caps retries so backoff doubling stays reasonable.
+const maxChainRetries = 10
+
+// chainRetryKeep is how long an idle retry entry (no failure, success, or
+// pending retry) is kept before it is pruned.
+const chainRetryKeep = 24 * time.Hour
+
+// ChainRetry tracks failed attempts of one role's command for an event
+// chain that sets retries. Attempts count retries already scheduled; a
+// success resets them.
+type ChainRetry struct {
+	From      string `json:"from"` // role whose command failed, asked to re-run it
+	Event     string `json:"event"`
+	Command   string `json:"command"`
+	ExitCode  string `json:"exit_code,omitempty"`
+	Attempts  int    `json:"attempts"`
+	Due       int64  `json:"due,omitempty"` // when the pending retry is sent; 0 once sent
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// BackoffDuration returns the chain's first retry delay.
+func (c EventChain) BackoffDuration() time.Duration {
+	if d, err := time.ParseDuration(c.Backoff); err == nil && d > 0 {
+		return d
+	}
+	return defaultChainBackoff
+}
+
+// chainRetryDelay returns the delay before retry attempt n (1-based):
+// backoff doubled for each earlier attempt.
+func chainRetryDelay(backoff time.Duration, n int) time.Duration {
+	return backoff << uint(n-1)
+}
+
+// ChainRetriesPath returns the retry state file for a session.
+func ChainRetriesPath(session string) string {
+	return filepath.Join(BusDir(session), "chain-retries.json")
+}
+
+// chainRetryKey identifies a retry entry.
+func chainRetryKey(from, event string) string {
+	return from + "/" + event
+}
+
+// lockChainRetries serializes read-modify-write cycles on the retry state
+// between chain runs and the watcher.
+func lockChainRetries(session string) func() {
+	return flockBusFile(session, "chain-retries")
+}
+
+// ReadChainRetries reads the retry state, keyed by "role/event".
+func ReadChainRetries(session string) (map[string]ChainRetry, error) {
+	data, err := os.ReadFile(ChainRetriesPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]ChainRetry{}, nil
+		}
+		return nil, err
+	}
+	retries := make(map[string]ChainRetry)
+	if err := json.Unmarshal(data, &retries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ChainRetriesPath(session), err)
+	}
+	return retries, nil
+}
+
+// writeChainRetries saves the retry state atomically.
+func writeChainRetries(session string, retries map[string]ChainRetry) error {
+	data, err := json.MarshalIndent(retries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ChainRetriesPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ChainRetriesPath(session))
+}
+
+// PlanChainRetry reports whether a failure of from's command for event
+// would be retried, returning the attempt it would be and its delay. It
+// changes nothing; see ScheduleChainRetry.
+func PlanChainRetry(session, from, event string) (attempt int, delay time.Duration, ok bool) {
+	chain, found := Config().EventChains[event]
+	if !found || chain.Retries <= 0 {
+		return 0, 0, false
+	}
+	retries, err := ReadChainRetries(session)
+	if err != nil {
+		return 0, 0, false
+	}
+	attempt = retries[chainRetryKey(from, event)].Attempts + 1
+	if attempt > min(chain.Retries, maxChainRetries) {
+		return attempt - 1, 0, false
+	}
+	return attempt, chainRetryDelay(chain.BackoffDuration(), attempt), true
+}
+
+// ScheduleChainRetry records a failure of from's command for event. When
+// the chain has retries left, it schedules a retry request to from after
+// the backoff and returns it with true; the caller then holds the failure
+// actions. Otherwise it clears the entry and returns false with Attempts
+// set to the retries already made, so the caller alerts as usual.
+func ScheduleChainRetry(session, from, event, exitCode, command string, now time.Time) (ChainRetry, bool, error) {
+	chain, found := Config().EventChains[event]
+	if !found || chain.Retries <= 0 {
+		return ChainRetry{}, false, nil
+	}
+	unlock := lockChainRetries(session)
+	defer unlock()
+	retries, err := ReadChainRetries(session)
+	if err != nil {
+		return ChainRetry{}, false, err
+	}
+	key := chainRetryKey(from, event)
+	r := retries[key]
+	if r.Attempts >= min(chain.Retries, maxChainRetries) {
+		delete(retries, key)
+		return r, false, writeChainRetries(session, retries)
+	}
+	r.From, r.Event, r.Command, r.ExitCode = from, event, command, exitCode
+	r.Attempts++
+	r.Due = now.Add(chainRetryDelay(chain.BackoffDuration(), r.Attempts)).Unix()
+	r.UpdatedAt = now.Unix()
+	retries[key] = r
+	return r, true, writeChainRetries(session, retries)
+}
+
+// ResetChainRetry clears from's retry attempts for event after a success.
+func ResetChainRetry(session, from, event string) error {
+	if _, err := os.Stat(ChainRetriesPath(session)); err != nil {
+		return nil
+	}
+	unlock := lockChainRetries(session)
+	defer unlock()
+	retries, err := ReadChainRetries(session)
+	if err != nil {
+		return err
+	}
+	key := chainRetryKey(from, event)
+	if _, ok := retries[key]; !ok {
+		return nil
+	}
+	delete(retries, key)
+	return writeChainRetries(session, retries)
+}
+
+// chainRetryPayload asks the role to re-run its failed command.
+func chainRetryPayload(r ChainRetry, retries int) string {
+	return fmt.Sprintf("Retry %d/%d: `%s` failed (exit %s), possibly a transient failure. Run it again; edit is alerted if it still fails after %d retries.",
+		r.Attempts, retries, r.Command, r.ExitCode, retries)
+}
+
+// SendDueChainRetries sends the retry requests whose backoff has passed,
+// and prunes entries idle for a day. The watcher calls it periodically; it
+// returns the retries sent in this call.
+func SendDueChainRetries(session string, now time.Time) ([]ChainRetry, error) {
+	if _, err := os.Stat(ChainRetriesPath(session)); err != nil {
+		return nil, nil
+	}
+	unlock := lockChainRetries(session)
+	retries, err := ReadChainRetries(session)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	var due []ChainRetry
+	changed := false
+	for key, r := range retries {
+		switch {
+		case r.Due > 0 && r.Due <= now.Unix():
+			due = append(due, r)
+			r.Due = 0
+			r.UpdatedAt = now.Unix()
+			retries[key] = r
+			changed = true
+		case r.Due == 0 && now.Sub(time.Unix(r.UpdatedAt, 0)) >= chainRetryKeep:
+			delete(retries, key)
+			changed = true
+		}
+	}
+	if changed {
+		err = writeChainRetries(session, retries)
+	}
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].Due < due[j].Due })
+	for _, r := range due {
+		total := Config().EventChains[r.Event].Retries
+		msg := NewMessage(r.From, r.From, "request", r.Event, chainRetryPayload(r, total), "")
+		if err := SendNoCC(session, msg); err != nil {
+			return due, err
+		}
+		_ = Notify(session, r.From)
+	}
+	return due, nil
+}
+
+// FormatChainRetries renders the retry state, soonest retry first.
+func FormatChainRetries(retries map[string]ChainRetry) string {
+	if len(retries) == 0 {
+		return "No chain retries.\n"
+	}
+	list := make([]ChainRetry, 0, len(retries))
+	for _, r := range retries {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Due != list[j].Due {
+			return list[j].Due == 0 || (list[i].Due != 0 && list[i].Due < list[j].Due)
+		}
+		return chainRetryKey(list[i].From, list[i].Event) < chainRetryKey(list[j].From, list[j].Event)
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %-10s %-8s %-20s %s\n", "ROLE", "EVENT", "ATTEMPT", "NEXT RETRY", "COMMAND")
+	for _, r := range list {
+		next := "sent"
+		if r.Due > 0 {
+			next = time.Unix(r.Due, 0).Format("2006-01-02 15:04:05")
+		}
+		total := Config().EventChains[r.Event].Retries
+		fmt.Fprintf(&b, "%-10s %-10s %-8s %-20s %s\n", r.From, r.Event, fmt.Sprintf("%d/%d", r.Attempts, total), next, r.Command)
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChainRetry(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.EventChains = map[string]EventChain{
+		"test": {OnFailure: &ChainAction{SendTo: "edit", Type: "event", Action: "notify"}, Retries: 2, Backoff: "5m"},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+	now := time.Now()
+
+	if attempt, delay, ok := PlanChainRetry(session, "test", "test"); !ok || attempt != 1 || delay != 5*time.Minute {
+		t.Fatalf("PlanChainRetry = %d, %s, %v", attempt, delay, ok)
+	}
+	r, retrying, err := ScheduleChainRetry(session, "test", "test", "1", "go test ./...", now)
+	if err != nil || !retrying || r.Attempts != 1 || r.Due != now.Add(5*time.Minute).Unix() {
+		t.Fatalf("first failure = %+v, %v, %v", r, retrying, err)
+	}
+
+	// Nothing is sent before the backoff passes
+	if sent, _ := SendDueChainRetries(session, now.Add(time.Minute)); len(sent) != 0 {
+		t.Fatalf("sent early: %+v", sent)
+	}
+	sent, err := SendDueChainRetries(session, now.Add(5*time.Minute))
+	if err != nil || len(sent) != 1 {
+		t.Fatalf("SendDueChainRetries = %+v, %v", sent, err)
+	}
+	msgs, _ := Peek(session, "test")
+	if len(msgs) != 1 || msgs[0].Action != "test" || !strings.Contains(msgs[0].Payload, "Retry 1/2: `go test ./...` failed (exit 1)") {
+		t.Fatalf("test inbox = %+v", msgs)
+	}
+
+	// Backoff doubles, then retries run out and the failure goes through
+	r, retrying, _ = ScheduleChainRetry(session, "test", "test", "1", "go test ./...", now)
+	if !retrying || r.Attempts != 2 || r.Due != now.Add(10*time.Minute).Unix() {
+		t.Fatalf("second failure = %+v, %v", r, retrying)
+	}
+	if _, _, ok := PlanChainRetry(session, "test", "test"); ok {
+		t.Error("PlanChainRetry: retries should be exhausted")
+	}
+	r, retrying, _ = ScheduleChainRetry(session, "test", "test", "1", "go test ./...", now)
+	if retrying || r.Attempts != 2 {
+		t.Fatalf("third failure = %+v, %v", r, retrying)
+	}
+	if retries, _ := ReadChainRetries(session); len(retries) != 0 {
+		t.Errorf("entry kept after exhaustion: %+v", retries)
+	}
+
+	// A success resets the count
+	ScheduleChainRetry(session, "test", "test", "1", "go test ./...", now)
+	if err := ResetChainRetry(session, "test", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if out := FormatChainRetries(nil); out != "No chain retries.\n" {
+		t.Errorf("FormatChainRetries(nil) = %q", out)
+	}
+	if retries, _ := ReadChainRetries(session); len(retries) != 0 {
+		t.Errorf("entry kept after success: %+v", retries)
+	}
+
+	// Chains without retries fail straight away
+	if _, retrying, _ := ScheduleChainRetry(session, "build", "build", "2", "go build", now); retrying {
+		t.Error("build has no retries configured")
+	}
+}
+
+func TestValidateConfigData_ChainRetries(t *testing.T) {
+	data := []byte(`{
+  "event_chains": {"test": {"retries": 20, "backoff": "soon"}}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	out := strings.Join(got, "\n")
+	for _, want := range []string{"event_chains.test.retries: retries must be between 0 and 10", `event_chains.test.backoff: invalid duration "soon"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -43,6 +43,8 @@ func init() {
 		}
 	}
 
+	baseKnownRoles = append([]string(nil), KnownRoles...)
+
 	// Override split-left windows from env
 	if v := os.Getenv("MUXCODE_SPLIT_LEFT"); v != "" {
 		splitLeftWindows = make(map[string]bool)
@@ -94,7 +96,7 @@ func BusRole() string {
 		return v
 	}
 	if v := tmuxVar("#W"); v != "" {
-		return v
+		return windowRole(v)
 	}
 	return "unknown"
 }
@@ -148,6 +150,16 @@ func MemoryArchivePath(role, date string) string {
 	return filepath.Join(MemoryArchiveDir(role), date+".md")
 }
 
+// EmbeddingIndexPath returns the memory embedding index path.
+func EmbeddingIndexPath() string {
+	return filepath.Join(MemoryDir(), "embeddings.jsonl")
+}
+
+// BM25IndexPath returns the persisted BM25 memory search index path.
+func BM25IndexPath() string {
+	return filepath.Join(MemoryDir(), "bm25-index.jsonl")
+}
+
 // BuildHistoryPath returns the build history JSONL file path for a session.
 func BuildHistoryPath(session string) string {
 	return filepath.Join(BusDir(session), "build-history.jsonl")
@@ -201,6 +213,16 @@ func UserContextDir() string {
 	return filepath.Join(home, ".config", "muxcode", "context.d")
 }
 
+// GlobalMemoryDir returns the user-level directory holding archived session memory.
+// Uses MUXCODE_CONFIG_DIR env if set, otherwise defaults to "~/.config/muxcode/memory".
+func GlobalMemoryDir() string {
+	if v := os.Getenv("MUXCODE_CONFIG_DIR"); v != "" {
+		return filepath.Join(v, "memory")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "muxcode", "memory")
+}
+
 // CronPath returns the cron entries JSONL file path for a session.
 func CronPath(session string) string {
 	return filepath.Join(BusDir(session), "cron.jsonl")
@@ -211,6 +233,11 @@ func CronHistoryPath(session string) string {
 	return filepath.Join(BusDir(session), "cron-history.jsonl")
 }
 
+// TaskPath returns the idle-task queue JSONL file path for a session.
+func TaskPath(session string) string {
+	return filepath.Join(BusDir(session), "tasks.jsonl")
+}
+
 // ProcDir returns the process log directory path for a session.
 func ProcDir(session string) string {
 	return filepath.Join(BusDir(session), "proc")
@@ -236,11 +263,49 @@ func WebhookPidPath(session string) string {
 	return filepath.Join(BusDir(session), "webhook.pid")
 }
 
+// WebhookStatsPath returns the webhook delivery metrics file path for a session.
+func WebhookStatsPath(session string) string {
+	return filepath.Join(BusDir(session), "webhook-stats.json")
+}
+
+// TracePath returns the span log JSONL file path for a session.
+func TracePath(session string) string {
+	return filepath.Join(BusDir(session), "trace.jsonl")
+}
+
+// TraceContextPath returns the file holding a role's current traceparent.
+func TraceContextPath(session, role string) string {
+	return filepath.Join(BusDir(session), "trace", role+".traceparent")
+}
+
+// RolesPath returns the session's role overlay file (roles added or removed
+// at runtime with "role add" / "role remove").
+func RolesPath(session string) string {
+	return filepath.Join(BusDir(session), "roles.json")
+}
+
+// SessionConfigPath returns the session config overlay written by
+// "init --template" (merged over project and user config).
+func SessionConfigPath(session string) string {
+	return filepath.Join(BusDir(session), "session-config.json")
+}
+
 // WatcherPidPath returns the path to the watcher PID file.
 func WatcherPidPath(session string) string {
 	return filepath.Join(BusDir(session), "watcher.pid")
 }
 
+// WatcherStatePath returns the run marker written by the watcher at start
+// and on clean shutdown.
+func WatcherStatePath(session string) string {
+	return filepath.Join(BusDir(session), "watcher-state.json")
+}
+
+// WatcherLogPath returns the log file of the session's watcher daemon.
+func WatcherLogPath(session string) string {
+	return filepath.Join(BusDir(session), "watcher.log")
+}
+
 // SubscriptionPath returns the subscriptions JSONL file path for a session.
 func SubscriptionPath(session string) string {
 	return filepath.Join(BusDir(session), "subscriptions.jsonl")
@@ -256,10 +321,25 @@ func HarnessMarkerPath(session, role string) string {
 	return filepath.Join(BusDir(session), "harness-"+role+".pid")
 }
 
-// TriggerFile returns the analyze trigger file path for a session.
+// UsagePath returns the harness token usage JSONL file path for a role in a session.
+func UsagePath(session, role string) string {
+	return filepath.Join(BusDir(session), role+"-usage.jsonl")
+}
+
+// HarnessPausePath returns the harness pause marker file path for a role in a session.
+func HarnessPausePath(session, role string) string {
+	return filepath.Join(BusDir(session), "harness-"+role+".paused")
+}
+
+// AttachmentDir returns the content-addressed attachment store for a session.
+func AttachmentDir(session string) string {
+	return filepath.Join(BusDir(session), "attachments")
+}
+
+// EditLogPath returns the analyze edit log (JSONL edit events) for a session.
 // Uses /tmp directly for compatibility with bash hooks.
-func TriggerFile(session string) string {
-	return "/tmp/muxcode-analyze-" + session + ".trigger"
+func EditLogPath(session string) string {
+	return "/tmp/muxcode-analyze-" + session + ".jsonl"
 }
 
 // IsSpawnRole returns true if the role is a spawn-prefixed role (e.g. "spawn-a1b2c3d4").
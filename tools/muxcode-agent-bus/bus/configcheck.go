@@ -0,0 +1,570 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigIssue is one problem found in a config or template file.
+type ConfigIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Path    string `json:"path,omitempty"` // JSON path, e.g. event_chains.build.on_success.send_to
+	Message string `json:"message"`
+}
+
+// String formats the issue compiler-style: file:line:col: path: message.
+func (i ConfigIssue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Col)
+	}
+	if i.Path != "" {
+		return fmt.Sprintf("%s: %s: %s", loc, i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", loc, i.Message)
+}
+
+// ConfigFiles returns the muxcode.json files LoadConfig reads that exist,
+// project first.
+func ConfigFiles() []string {
+	var files []string
+	for _, p := range []string{
+		filepath.Join(".muxcode", "muxcode.json"),
+		filepath.Join(configDir(), "muxcode.json"),
+	} {
+		if _, err := os.Stat(p); err == nil {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// ValidateConfigData checks a muxcode.json document: syntax, unknown keys,
+// roles referenced by chains, auto-CC, policies, and routes, tool patterns,
+// durations, regexes, and the dashboard, role, and policy sections.
+func ValidateConfigData(file string, data []byte) []ConfigIssue {
+	c := newConfigChecker(file, data)
+	if !c.scan(reflect.TypeOf(MuxcodeConfig{})) {
+		return c.issues
+	}
+	var cfg MuxcodeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		c.add(nil, "%v", err)
+		return c.issues
+	}
+	for _, d := range cfg.Roles {
+		c.declared = append(c.declared, d.Name)
+	}
+	c.envs = cfg.Deploy.EnvironmentList()
+	c.checkConfig(&cfg)
+	return c.sorted()
+}
+
+// ValidateTemplateData checks a session template: syntax, unknown keys,
+// window names, cron schedules and targets, and subscriptions.
+func ValidateTemplateData(file string, data []byte) []ConfigIssue {
+	c := newConfigChecker(file, data)
+	if !c.scan(reflect.TypeOf(SessionTemplate{})) {
+		return c.issues
+	}
+	var t SessionTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		c.add(nil, "%v", err)
+		return c.issues
+	}
+	c.declared = append(c.declared, t.Windows...)
+	for i, w := range t.Windows {
+		if w == "" || strings.ContainsAny(w, " \t:.") {
+			c.add(c.at("windows", i), "invalid window name %q", w)
+		}
+	}
+	for i, e := range t.Cron {
+		if _, err := ParseSchedule(e.Schedule); err != nil {
+			c.add(c.at("cron", i, "schedule"), "invalid schedule: %v", err)
+		}
+		if e.Target == "" || e.Message == "" {
+			c.add(c.at("cron", i), "target and message are required")
+		} else {
+			c.role(c.at("cron", i, "target"), e.Target)
+		}
+	}
+	for i, s := range t.Subscriptions {
+		if s.Event == "" || s.Outcome == "" {
+			c.add(c.at("subscriptions", i), "event and outcome are required")
+		}
+	}
+	for name := range t.Context {
+		if !validTemplateName(name) {
+			c.add(c.at("context", name), "invalid context file name %q", name)
+		}
+	}
+	return c.sorted()
+}
+
+// FormatConfigIssues formats issues with the offending source line under
+// each. sources maps file names to their contents.
+func FormatConfigIssues(issues []ConfigIssue, sources map[string][]byte) string {
+	var b strings.Builder
+	for _, i := range issues {
+		b.WriteString(i.String())
+		b.WriteByte('\n')
+		lines := strings.Split(string(sources[i.File]), "\n")
+		if i.Line > 0 && i.Line <= len(lines) {
+			fmt.Fprintf(&b, "    %s\n", strings.TrimRight(lines[i.Line-1], " \t\r"))
+		}
+	}
+	return b.String()
+}
+
+// configChecker accumulates issues for one file. Positions come from a
+// token scan that records where every key and value starts.
+type configChecker struct {
+	file     string
+	data     []byte
+	offsets  map[string]int64 // JSON path -> byte offset
+	declared []string         // roles declared by the file itself
+	envs     []string         // deploy environments, for promote gates (nil: not checked)
+	issues   []ConfigIssue
+}
+
+func newConfigChecker(file string, data []byte) *configChecker {
+	return &configChecker{file: file, data: data, offsets: map[string]int64{}}
+}
+
+// at joins path segments into a JSON path.
+func (c *configChecker) at(parts ...interface{}) []string {
+	path := make([]string, len(parts))
+	for i, p := range parts {
+		path[i] = fmt.Sprint(p)
+	}
+	return path
+}
+
+// add records an issue at path, positioned at the nearest recorded
+// ancestor.
+func (c *configChecker) add(path []string, format string, args ...interface{}) {
+	issue := ConfigIssue{File: c.file, Path: strings.Join(path, "."), Message: fmt.Sprintf(format, args...)}
+	for n := len(path); n >= 0; n-- {
+		if off, ok := c.offsets[strings.Join(path[:n], ".")]; ok {
+			issue.Line, issue.Col = lineCol(c.data, off)
+			break
+		}
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// role reports an unknown role. Patterns containing "*" are not checked.
+func (c *configChecker) role(path []string, role string) {
+	if role == "" || strings.Contains(role, "*") || IsKnownRole(role) || containsRole(c.declared, role) {
+		return
+	}
+	c.add(path, "unknown role %q", role)
+}
+
+func (c *configChecker) sorted() []ConfigIssue {
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Col < c.issues[j].Col
+	})
+	return c.issues
+}
+
+// scan tokenizes the document, recording positions and reporting syntax
+// errors and keys that root has no field for. It returns false on a syntax
+// error.
+func (c *configChecker) scan(root reflect.Type) bool {
+	dec := json.NewDecoder(bytes.NewReader(c.data))
+	if err := c.scanValue(dec, nil, root); err != nil {
+		var syn *json.SyntaxError
+		off := dec.InputOffset()
+		if errors.As(err, &syn) {
+			off = syn.Offset
+		}
+		issue := ConfigIssue{File: c.file, Message: "invalid JSON: " + err.Error()}
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			issue.Message = "invalid JSON: unexpected end of file"
+		}
+		issue.Line, issue.Col = lineCol(c.data, off)
+		c.issues = append(c.issues, issue)
+		return false
+	}
+	return true
+}
+
+// scanValue reads one value at path. t is the Go type expected there, or
+// nil when the value is under an unknown key (no further key checks).
+func (c *configChecker) scanValue(dec *json.Decoder, path []string, t reflect.Type) error {
+	start := c.tokenStart(dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	key := strings.Join(path, ".")
+	if _, ok := c.offsets[key]; !ok {
+		c.offsets[key] = start
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		for dec.More() {
+			kstart := c.tokenStart(dec.InputOffset())
+			ktok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			name, _ := ktok.(string)
+			child := append(append([]string(nil), path...), name)
+			c.offsets[strings.Join(child, ".")] = kstart
+			var ct reflect.Type
+			if t != nil {
+				var known bool
+				if ct, known = fieldType(t, name); !known {
+					c.add(child, "unknown key %q", name)
+				}
+			}
+			if err := c.scanValue(dec, child, ct); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			var et reflect.Type
+			if t != nil {
+				et, _ = fieldType(t, strconv.Itoa(i))
+			}
+			if err := c.scanValue(dec, append(append([]string(nil), path...), strconv.Itoa(i)), et); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = dec.Token() // closing delimiter
+	return err
+}
+
+// tokenStart skips the whitespace and separators after off, returning
+// where the next token begins.
+func (c *configChecker) tokenStart(off int64) int64 {
+	for off < int64(len(c.data)) && strings.IndexByte(" \t\r\n,:", c.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// fieldType returns the type of key within t: a struct field by JSON name
+// (including embedded structs), a map value, or a slice element. ok is false
+// when a struct has no such field; other kinds accept any key.
+func fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return t.Elem(), true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				if ft, ok := fieldType(f.Type, key); ok {
+					return ft, true
+				}
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if name == key {
+				return f.Type, true
+			}
+		}
+		return nil, false
+	}
+	return nil, true
+}
+
+// lineCol converts a byte offset to a 1-based line and column.
+func lineCol(data []byte, off int64) (int, int) {
+	if off > int64(len(data)) {
+		off = int64(len(data))
+	}
+	before := data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(off) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// toolPatternRe matches Name or Name(args): the form --allowedTools accepts.
+var toolPatternRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*(\(.+\))?$`)
+
+// checkConfig runs the semantic checks on a decoded muxcode.json.
+func (c *configChecker) checkConfig(cfg *MuxcodeConfig) {
+	sharedGroups := map[string]bool{}
+	for name := range Config().SharedTools {
+		sharedGroups[name] = true
+	}
+	for name, tools := range cfg.SharedTools {
+		sharedGroups[name] = true
+		for i, tool := range tools {
+			c.tool(c.at("shared_tools", name, i), tool)
+		}
+	}
+	for name, p := range cfg.ToolProfiles {
+		for i, inc := range p.Include {
+			if !sharedGroups[inc] {
+				c.add(c.at("tool_profiles", name, "include", i), "unknown shared_tools group %q", inc)
+			}
+		}
+		for i, tool := range p.Tools {
+			c.tool(c.at("tool_profiles", name, "tools", i), tool)
+		}
+	}
+
+	for event, chain := range cfg.EventChains {
+		if chain.Retries < 0 || chain.Retries > maxChainRetries {
+			c.add(c.at("event_chains", event, "retries"), "retries must be between 0 and %d", maxChainRetries)
+		}
+		if chain.Backoff != "" {
+			if d, err := time.ParseDuration(chain.Backoff); err != nil || d <= 0 {
+				c.add(c.at("event_chains", event, "backoff"), "invalid duration %q", chain.Backoff)
+			}
+		}
+		for key, a := range map[string]*ChainAction{"on_success": chain.OnSuccess, "on_failure": chain.OnFailure, "on_unknown": chain.OnUnknown} {
+			if a == nil {
+				continue
+			}
+			c.chainAction(c.at("event_chains", event, key), *a)
+		}
+		for i, st := range chain.Steps {
+			switch st.On {
+			case "success", "failure", "unknown", "*":
+			default:
+				c.add(c.at("event_chains", event, "steps", i, "on"), "on must be success, failure, unknown, or *")
+			}
+			c.chainAction(c.at("event_chains", event, "steps", i), st.ChainAction)
+		}
+	}
+	for i, r := range cfg.AutoCC {
+		c.role(c.at("auto_cc", i), r)
+	}
+	for from, p := range cfg.SendPolicy {
+		c.role(c.at("send_policy", from), from)
+		for i, to := range p.Deny {
+			c.role(c.at("send_policy", from, "deny", i), to)
+		}
+	}
+	if err := cfg.Policy.Validate(); err != nil {
+		c.add(c.at("policy"), "%v", err)
+	}
+	for i, r := range cfg.Policy.Rules {
+		c.role(c.at("policy", "rules", i, "from"), r.From)
+		c.role(c.at("policy", "rules", i, "to"), r.To)
+	}
+
+	for i, r := range cfg.SLA {
+		if _, err := time.ParseDuration(r.Within); err != nil {
+			c.add(c.at("sla", i, "within"), "invalid duration %q", r.Within)
+		}
+		c.role(c.at("sla", i, "to"), r.To)
+	}
+	if f := cfg.Guard.SlowFactor; f != 0 && f <= 1 {
+		c.add(c.at("guard", "slow_factor"), "slow_factor must be greater than 1")
+	}
+	for role, l := range cfg.Guard.Roles {
+		if l.SlowFactor != 0 && l.SlowFactor <= 1 {
+			c.add(c.at("guard", "roles", role, "slow_factor"), "slow_factor must be greater than 1")
+		}
+	}
+	for i, p := range cfg.Guard.Patterns {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			c.add(c.at("guard", "patterns", i, "pattern"), "invalid regexp: %v", err)
+		}
+		if p.Source != "" && p.Source != "message" && p.Source != "command" {
+			c.add(c.at("guard", "patterns", i, "source"), "source must be message or command")
+		}
+		for j, r := range p.Roles {
+			c.role(c.at("guard", "patterns", i, "roles", j), r)
+		}
+	}
+	for name, t := range cfg.ProcTemplates {
+		if t.Command == "" {
+			c.add(c.at("proc_templates", name), "command is required")
+		}
+		if !ValidRestartPolicy(t.Restart) {
+			c.add(c.at("proc_templates", name, "restart"), "unknown restart policy %q", t.Restart)
+		}
+		if t.Timeout != "" {
+			if _, err := time.ParseDuration(t.Timeout); err != nil {
+				c.add(c.at("proc_templates", name, "timeout"), "invalid duration %q", t.Timeout)
+			}
+		}
+		c.role(c.at("proc_templates", name, "owner"), t.Owner)
+	}
+	for role := range cfg.SpawnQuota.Roles {
+		c.role(c.at("spawn_quota", "roles", role), role)
+	}
+	for name, r := range cfg.Webhook.Routes {
+		if !ValidWebhookProvider(r.Provider) {
+			c.add(c.at("webhook", "routes", name, "provider"), "unknown provider %q", r.Provider)
+		}
+		if len(r.Events) == 0 {
+			c.role(c.at("webhook", "routes", name, "to"), r.To)
+		}
+	}
+	if err := cfg.Webhook.Slack.Validate(); err != nil {
+		c.add(c.at("webhook", "slack"), "%v", err)
+	}
+	for word, sc := range cfg.Webhook.Slack.Commands {
+		c.role(c.at("webhook", "slack", "commands", word, "to"), sc.To)
+	}
+	if err := cfg.Dashboard.Validate(); err != nil {
+		c.add(c.at("dashboard"), "%v", err)
+	}
+	if err := ValidateRoleDefs(cfg.Roles); err != nil {
+		c.add(c.at("roles"), "%v", err)
+	}
+	if err := cfg.Notify.Validate(); err != nil {
+		c.add(c.at("notify"), "%v", err)
+	}
+	for role := range cfg.Notify.Roles {
+		c.role(c.at("notify", "roles", role), role)
+	}
+	if err := cfg.Inbox.Validate(); err != nil {
+		c.add(c.at("inbox"), "%v", err)
+	}
+	if err := cfg.Ollama.Validate(); err != nil {
+		c.add(c.at("ollama"), "%v", err)
+	}
+	if err := cfg.Context.Validate(); err != nil {
+		c.add(c.at("context"), "%v", err)
+	}
+	for role := range cfg.Context.Roles {
+		c.role(c.at("context", "roles", role), role)
+	}
+	if err := cfg.Heartbeat.Validate(); err != nil {
+		c.add(c.at("heartbeat"), "%v", err)
+	}
+	for i, r := range cfg.Heartbeat.Failover {
+		c.role(c.at("heartbeat", "failover", i), r)
+	}
+	if err := cfg.Redaction.Validate(); err != nil {
+		c.add(c.at("redaction"), "%v", err)
+	}
+	if err := cfg.Watcher.Validate(); err != nil {
+		c.add(c.at("watcher"), "%v", err)
+	}
+	if err := cfg.Digest.Validate(); err != nil {
+		c.add(c.at("digest"), "%v", err)
+	}
+	if err := cfg.Encryption.Validate(); err != nil {
+		c.add(c.at("encryption"), "%v", err)
+	}
+	if err := cfg.Transport.Validate(); err != nil {
+		c.add(c.at("transport"), "%v", err)
+	}
+	if err := cfg.Coverage.Validate(); err != nil {
+		c.add(c.at("coverage"), "%v", err)
+	}
+	for role := range cfg.Coverage.Roles {
+		c.role(c.at("coverage", "roles", role), role)
+	}
+	if err := cfg.Deploy.Validate(); err != nil {
+		c.add(c.at("deploy"), "%v", err)
+	}
+	if err := cfg.Intake.Validate(); err != nil {
+		c.add(c.at("intake"), "%v", err)
+	}
+	for i, s := range cfg.Intake.Sources {
+		c.role(c.at("intake", "sources", i, "to"), s.To)
+	}
+	if err := cfg.CostGuard.Validate(); err != nil {
+		c.add(c.at("cost_guard"), "%v", err)
+	}
+	for i, role := range cfg.CostGuard.Roles {
+		c.role(c.at("cost_guard", "roles", i), role)
+	}
+	for i, r := range cfg.CostGuard.Rules {
+		c.role(c.at("cost_guard", "rules", i, "approver"), r.Approver)
+		c.role(c.at("cost_guard", "rules", i, "escalate_to"), r.EscalateTo)
+	}
+	for i, r := range cfg.Routing {
+		if err := r.Validate(); err != nil {
+			c.add(c.at("routing", i), "%v", err)
+		}
+		c.role(c.at("routing", i, "to"), r.To)
+		for j, p := range r.Project {
+			if !KnownProjectType(p) {
+				c.add(c.at("routing", i, "project", j), "unknown project type %q", p)
+			}
+		}
+	}
+}
+
+// chainAction checks a chain action's target or plugin, match pattern,
+// delay, and approval gate.
+func (c *configChecker) chainAction(path []string, a ChainAction) {
+	field := func(key string) []string { return append(append([]string(nil), path...), key) }
+	if a.Approval != nil {
+		c.role(append(field("approval"), "approver"), a.Approval.Approver)
+		c.role(append(field("approval"), "escalate_to"), a.Approval.EscalateTo)
+		for _, e := range a.Approval.Check() {
+			c.add(field("approval"), "%s", e)
+		}
+	}
+	if a.Promote != "" && c.envs != nil && !containsRole(c.envs, a.Promote) {
+		c.add(field("promote"), "unknown environment %q", a.Promote)
+	}
+	if a.Plugin != "" {
+		if _, ok := FindPlugin(a.Plugin); !ok {
+			c.add(field("plugin"), "plugin %q is not installed", a.Plugin)
+		}
+		return
+	}
+	if a.Type == PRChainType {
+		if _, err := ParsePRArgs(a.Args); err != nil {
+			c.add(field("args"), "%v", err)
+		}
+		a = a.prRequest()
+	}
+	if a.SendTo == "" {
+		c.add(path, "send_to is required")
+	}
+	c.role(field("send_to"), a.SendTo)
+	if a.Match != "" {
+		if _, err := regexp.Compile(a.Match); err != nil {
+			c.add(field("match"), "invalid regexp: %v", err)
+		}
+	}
+	if a.Delay != "" {
+		if d, err := time.ParseDuration(a.Delay); err != nil || d < 0 {
+			c.add(field("delay"), "invalid duration %q", a.Delay)
+		}
+	}
+}
+
+// tool reports a malformed --allowedTools pattern.
+func (c *configChecker) tool(path []string, tool string) {
+	if !toolPatternRe.MatchString(tool) {
+		c.add(path, "malformed tool pattern %q (want Name or Name(args))", tool)
+	}
+}
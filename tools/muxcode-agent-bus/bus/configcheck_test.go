@@ -0,0 +1,179 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigData(t *testing.T) {
+	data := []byte(`{
+  "auto_cc": ["build", "bogus"],
+  "tool_profiles": {
+    "build": {"include": ["nope"], "tools": ["Bash(go build", "Read", "Bash(go test *)"]}
+  },
+  "event_chains": {"build": {"on_success": {"send_to": "tset", "action": "test"}}},
+  "dashbaord": {"theme": "x"},
+  "guard": {"similarity": 0.5, "patterns": [{"name": "x", "pattern": "("}]},
+  "roles": [{"name": "security"}],
+  "send_policy": {"security": {"deny": ["commit"]}},
+  "sla": [{"action": "build", "within": "soon"}]
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+
+	want := []string{
+		`muxcode.json:2:24: auto_cc.1: unknown role "bogus"`,
+		`muxcode.json:4:27: tool_profiles.build.include.0: unknown shared_tools group "nope"`,
+		`muxcode.json:4:46: tool_profiles.build.tools.0: malformed tool pattern "Bash(go build"`,
+		`muxcode.json:6:45: event_chains.build.on_success.send_to: unknown role "tset"`,
+		`muxcode.json:7:3: dashbaord: unknown key "dashbaord"`,
+		`muxcode.json:8:59: guard.patterns.0.pattern: invalid regexp`,
+		`muxcode.json:11:31: sla.0.within: invalid duration "soon"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(issues), len(want), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want prefix %q", i, issues[i].String(), w)
+		}
+	}
+
+	out := FormatConfigIssues(issues[:1], map[string][]byte{"muxcode.json": data})
+	if !strings.Contains(out, "\n      \"auto_cc\": [\"build\", \"bogus\"],\n") {
+		t.Errorf("missing source context:\n%s", out)
+	}
+}
+
+func TestValidateConfigData_ChainSteps(t *testing.T) {
+	data := []byte(`{
+  "event_chains": {"test": {"steps": [
+    {"on": "sometimes", "send_to": "review", "action": "review"},
+    {"on": "success", "send_to": "commit", "match": "(", "delay": "later"}
+  ]}}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+
+	want := []string{
+		`event_chains.test.steps.0.on: on must be success, failure, unknown, or *`,
+		`event_chains.test.steps.1.match: invalid regexp`,
+		`event_chains.test.steps.1.delay: invalid duration "later"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(issues), len(want), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range want {
+		if !strings.Contains(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+}
+
+func TestValidateConfigData_ChainApproval(t *testing.T) {
+	data := []byte(`{
+  "event_chains": {"deploy": {"on_success": {"send_to": "deploy", "action": "promote", "approval": {
+    "approver": "nobody",
+    "escalate_after": "2h", "timeout": "1h",
+    "when": "${command} ~ prod"
+  }}}}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+
+	want := []string{
+		`event_chains.deploy.on_success.approval: escalate_after and escalate_to must be set together`,
+		`event_chains.deploy.on_success.approval: escalate_after 2h0m0s is not before the timeout (1h0m0s)`,
+		`event_chains.deploy.on_success.approval: invalid when "${command} ~ prod"`,
+		`event_chains.deploy.on_success.approval.approver: unknown role "nobody"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(issues), len(want), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range want {
+		if !strings.Contains(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+}
+
+func TestValidateConfigData_ChainPR(t *testing.T) {
+	data := []byte(`{
+  "event_chains": {"review": {
+    "on_success": {"type": "pr", "args": ["--base", "develop", "--draft"]},
+    "steps": [{"on": "success", "type": "pr", "args": ["--force"]}]
+  }}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	if len(issues) != 1 || !strings.Contains(issues[0].String(), "event_chains.review.steps.0.args: unknown flag: --force") {
+		t.Errorf("issues:\n%s", FormatConfigIssues(issues, nil))
+	}
+}
+
+func TestValidateConfigData_DeployPromote(t *testing.T) {
+	data := []byte(`{
+  "deploy": {"environments": ["dev", "prod", "dev"]},
+  "event_chains": {"deploy": {
+    "steps": [{"on": "success", "send_to": "deploy", "type": "request", "action": "deploy", "message": "go", "promote": "staging"}]
+  }}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues, want 2:\n%s", len(issues), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range []string{"deploy: environments.2: duplicate \"dev\"", "event_chains.deploy.steps.0.promote: unknown environment \"staging\""} {
+		if !strings.Contains(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+}
+
+func TestValidateConfigData_GuardSlowFactor(t *testing.T) {
+	data := []byte(`{
+  "guard": {"slow_factor": 1, "roles": {"test": {"slow_factor": 0.5}, "build": {"slow_factor": 3}}}
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues, want 2:\n%s", len(issues), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range []string{"guard.slow_factor: slow_factor must be greater than 1", "guard.roles.test.slow_factor: slow_factor must be greater than 1"} {
+		if !strings.Contains(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+}
+
+func TestValidateConfigData_Clean(t *testing.T) {
+	data := []byte(`{
+  "shared_tools": {"bus": ["Bash(muxcode-agent-bus *)"]},
+  "tool_profiles": {"build": {"include": ["bus"], "tools": ["Read", "mcp__github__get_issue"], "cd_prefix": true}},
+  "guard": {"command_threshold": 4, "roles": {"build": {"window_s": 60}}},
+  "policy": {"mode": "audit", "rules": [{"from": "*", "to": "deploy", "effect": "deny"}]}
+}`)
+	if issues := ValidateConfigData("muxcode.json", data); len(issues) != 0 {
+		t.Errorf("unexpected issues:\n%s", FormatConfigIssues(issues, nil))
+	}
+}
+
+func TestValidateConfigData_Syntax(t *testing.T) {
+	issues := ValidateConfigData("muxcode.json", []byte("{\n  \"auto_cc\": [\"build\",,]\n}"))
+	if len(issues) != 1 || issues[0].Line != 2 || !strings.Contains(issues[0].Message, "invalid JSON") {
+		t.Errorf("issues = %+v", issues)
+	}
+}
+
+func TestValidateTemplateData(t *testing.T) {
+	data := []byte(`{
+  "windows": ["edit", "security"],
+  "cron": [
+    {"schedule": "every 5x", "target": "security", "message": "scan"},
+    {"schedule": "@daily", "target": "nobody", "message": "scan"}
+  ],
+  "colour": "red"
+}`)
+	issues := ValidateTemplateData("t.json", data)
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.Path)
+	}
+	if strings.Join(got, ",") != "cron.0.schedule,cron.1.target,colour" {
+		t.Errorf("paths = %v\n%s", got, FormatConfigIssues(issues, nil))
+	}
+}
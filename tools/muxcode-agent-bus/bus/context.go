@@ -168,7 +168,9 @@ func ReadAllContextFiles() ([]ContextFile, error) {
 }
 
 // AllContextFilesForRole returns manual + auto-detected context files for a role.
-// Includes "shared" files and role-specific files.
+// Includes "shared" files and role-specific files. When the files exceed the
+// role's context budget they are cut by ApplyContextBudget and a
+// compact-recommended warning is sent to the role.
 func AllContextFilesForRole(role string) ([]ContextFile, error) {
 	all, err := ReadAllContextFiles()
 	if err != nil {
@@ -181,10 +183,15 @@ func AllContextFilesForRole(role string) ([]ContextFile, error) {
 			filtered = append(filtered, f)
 		}
 	}
+	filtered, over := ApplyContextBudget(role, filtered, ContextBudgetFor(role))
+	if over != nil {
+		reportContextOverflow(over)
+	}
 	return filtered, nil
 }
 
 // FormatContextPrompt formats context files for injection into an agent prompt.
+// Manual files containing template actions are expanded with BuildContextData.
 // Output format:
 //
 //	## Project Context
@@ -195,6 +202,7 @@ func FormatContextPrompt(files []ContextFile) string {
 	if len(files) == 0 {
 		return ""
 	}
+	files = expandContextFiles(files)
 	var b strings.Builder
 	b.WriteString("## Project Context\n\n")
 	for i, f := range files {
@@ -0,0 +1,189 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// contextBytesPerToken estimates token counts from context size.
+const contextBytesPerToken = 4
+
+// contextTruncateMarker ends a context file cut to fit the budget.
+const contextTruncateMarker = "\n\n[... context truncated: %d bytes omitted to fit the budget]"
+
+// minContextTruncate is the smallest body worth keeping a truncated excerpt
+// of; files that would get less are dropped instead.
+const minContextTruncate = 256
+
+// ContextConfig limits the combined context.d content a role receives.
+// Top-level limits apply to every role; Roles overrides them per role.
+type ContextConfig struct {
+	ContextBudget
+	Roles map[string]ContextBudget `json:"roles,omitempty"`
+}
+
+// ContextBudget is a size limit for a role's context. Zero fields are
+// unlimited; when both are set the smaller wins.
+type ContextBudget struct {
+	MaxBytes  int `json:"max_bytes,omitempty"`
+	MaxTokens int `json:"max_tokens,omitempty"` // estimated at 4 bytes per token
+}
+
+// ContextOverflow describes how a role's context was cut to fit its budget.
+type ContextOverflow struct {
+	Role       string
+	Budget     int
+	TotalBytes int
+	Truncated  []string // "role/name" of files cut short
+	Dropped    []string // "role/name" of files left out
+}
+
+// Validate rejects negative limits.
+func (c ContextConfig) Validate() error {
+	check := func(b ContextBudget) error {
+		if b.MaxBytes < 0 || b.MaxTokens < 0 {
+			return fmt.Errorf("context budgets must be positive")
+		}
+		return nil
+	}
+	if err := check(c.ContextBudget); err != nil {
+		return err
+	}
+	for _, b := range c.Roles {
+		if err := check(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeContextBudget returns base with every non-zero field of override applied.
+func mergeContextBudget(base, override ContextBudget) ContextBudget {
+	if override.MaxBytes > 0 {
+		base.MaxBytes = override.MaxBytes
+	}
+	if override.MaxTokens > 0 {
+		base.MaxTokens = override.MaxTokens
+	}
+	return base
+}
+
+// Bytes returns the budget in bytes, or 0 when unlimited.
+func (b ContextBudget) Bytes() int {
+	limit := b.MaxBytes
+	if t := b.MaxTokens * contextBytesPerToken; t > 0 && (limit == 0 || t < limit) {
+		limit = t
+	}
+	return limit
+}
+
+// ContextBudgetFor resolves a role's context budget in bytes (0 = unlimited):
+// per-role config, then top-level config.
+func ContextBudgetFor(role string) int {
+	cfg := Config().Context
+	return mergeContextBudget(cfg.ContextBudget, cfg.Roles[role]).Bytes()
+}
+
+// contextPriority orders files for budgeting: role-specific before shared,
+// then project, user, and auto-detected files.
+func contextPriority(f ContextFile, role string) int {
+	p := 0
+	if f.Role != role {
+		p = 3
+	}
+	switch f.Source {
+	case "user":
+		p++
+	case "auto":
+		p += 2
+	}
+	return p
+}
+
+// ApplyContextBudget fits a role's context files into budget bytes. Files
+// are kept in priority order (role-specific over shared, manual over
+// auto-detected); a file that does not fit is truncated with a marker when
+// enough budget remains, otherwise dropped. It returns files unchanged and
+// a nil overflow when they fit or budget is 0.
+func ApplyContextBudget(role string, files []ContextFile, budget int) ([]ContextFile, *ContextOverflow) {
+	total := 0
+	for _, f := range files {
+		total += len(f.Body)
+	}
+	if budget <= 0 || total <= budget {
+		return files, nil
+	}
+
+	ordered := append([]ContextFile(nil), files...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return contextPriority(ordered[i], role) < contextPriority(ordered[j], role)
+	})
+
+	over := &ContextOverflow{Role: role, Budget: budget, TotalBytes: total}
+	var kept []ContextFile
+	remaining := budget
+	for _, f := range ordered {
+		key := f.Role + "/" + f.Name
+		if len(f.Body) <= remaining {
+			kept = append(kept, f)
+			remaining -= len(f.Body)
+			continue
+		}
+		if remaining >= minContextTruncate {
+			f.Body = truncateContextBody(f.Body, remaining)
+			kept = append(kept, f)
+			over.Truncated = append(over.Truncated, key)
+			remaining = 0
+			continue
+		}
+		over.Dropped = append(over.Dropped, key)
+	}
+	return kept, over
+}
+
+// truncateContextBody cuts body to at most limit bytes at a line boundary
+// and appends a truncation marker.
+func truncateContextBody(body string, limit int) string {
+	// Reserve room for the marker at its longest
+	cut := limit - len(fmt.Sprintf(contextTruncateMarker, len(body)))
+	if cut < 0 {
+		cut = 0
+	}
+	kept := body[:cut]
+	if i := strings.LastIndex(kept, "\n"); i > 0 {
+		kept = kept[:i]
+	}
+	return kept + fmt.Sprintf(contextTruncateMarker, len(body)-len(kept))
+}
+
+// Message describes the overflow for the compact-recommended event.
+func (o *ContextOverflow) Message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Context for %s is %s, over its %s budget.", o.Role,
+		formatBytes(int64(o.TotalBytes)), formatBytes(int64(o.Budget)))
+	if len(o.Truncated) > 0 {
+		fmt.Fprintf(&b, " Truncated: %s.", strings.Join(o.Truncated, ", "))
+	}
+	if len(o.Dropped) > 0 {
+		fmt.Fprintf(&b, " Dropped: %s.", strings.Join(o.Dropped, ", "))
+	}
+	b.WriteString(" Trim .muxcode/context.d or raise context.max_bytes in muxcode.json.")
+	return b.String()
+}
+
+// reportContextOverflow warns about an over-budget context on stderr and,
+// when the session's bus exists, sends a compact-recommended event to the
+// role.
+func reportContextOverflow(o *ContextOverflow) {
+	fmt.Fprintf(os.Stderr, "  [context] %s\n", o.Message())
+	session := BusSession()
+	if _, err := os.Stat(BusDir(session)); err != nil {
+		return
+	}
+	msg := NewMessage("context", o.Role, "event", "compact-recommended", o.Message(), "")
+	if err := SendNoCC(session, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "  [context] failed to send budget warning to %s: %v\n", o.Role, err)
+	}
+}
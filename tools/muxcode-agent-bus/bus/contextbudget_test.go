@@ -0,0 +1,117 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContextBudgetFor(t *testing.T) {
+	SetConfig(&MuxcodeConfig{Context: ContextConfig{
+		ContextBudget: ContextBudget{MaxBytes: 8000},
+		Roles:         map[string]ContextBudget{"build": {MaxTokens: 1000}, "review": {MaxTokens: 5000}},
+	}})
+	defer SetConfig(nil)
+
+	if got := ContextBudgetFor("edit"); got != 8000 {
+		t.Errorf("edit budget = %d, want 8000", got)
+	}
+	if got := ContextBudgetFor("build"); got != 4000 {
+		t.Errorf("build budget = %d, want the smaller token budget 4000", got)
+	}
+	if got := ContextBudgetFor("review"); got != 8000 {
+		t.Errorf("review budget = %d, want the smaller byte budget 8000", got)
+	}
+
+	SetConfig(&MuxcodeConfig{})
+	if got := ContextBudgetFor("edit"); got != 0 {
+		t.Errorf("default budget = %d, want unlimited", got)
+	}
+}
+
+func TestApplyContextBudget(t *testing.T) {
+	files := []ContextFile{
+		{Name: "arch", Role: "shared", Source: "project", Body: strings.Repeat("a\n", 300)},
+		{Name: "go", Role: "shared", Source: "auto", Body: strings.Repeat("g", 100)},
+		{Name: "tips", Role: "build", Source: "user", Body: strings.Repeat("t", 200)},
+		{Name: "steps", Role: "build", Source: "project", Body: strings.Repeat("s", 300)},
+	}
+
+	if got, over := ApplyContextBudget("build", files, 0); over != nil || len(got) != 4 {
+		t.Errorf("unlimited budget changed files: %d, %+v", len(got), over)
+	}
+	if got, over := ApplyContextBudget("build", files, 10000); over != nil || len(got) != 4 {
+		t.Errorf("files under budget changed: %d, %+v", len(got), over)
+	}
+
+	got, over := ApplyContextBudget("build", files, 900)
+	if over == nil {
+		t.Fatal("expected overflow")
+	}
+	var names []string
+	for _, f := range got {
+		names = append(names, f.Role+"/"+f.Name)
+	}
+	if strings.Join(names, ",") != "build/steps,build/tips,shared/arch" {
+		t.Errorf("kept = %v, want role files first then truncated shared", names)
+	}
+	arch := got[2].Body
+	if len(arch) > 400 || !strings.Contains(arch, "[... context truncated:") || !strings.HasPrefix(arch, "a\n") {
+		t.Errorf("truncated body (%d bytes):\n%s", len(arch), arch)
+	}
+	if strings.Join(over.Truncated, ",") != "shared/arch" || strings.Join(over.Dropped, ",") != "shared/go" {
+		t.Errorf("overflow = %+v", over)
+	}
+	if over.TotalBytes != 1200 || over.Budget != 900 {
+		t.Errorf("overflow sizes = %+v", over)
+	}
+	msg := over.Message()
+	for _, want := range []string{"Context for build", "Truncated: shared/arch", "Dropped: shared/go"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q: %s", want, msg)
+		}
+	}
+}
+
+func TestAllContextFilesForRole_BudgetWarning(t *testing.T) {
+	tmpDir, cleanup := setupContextDirs(t)
+	defer cleanup()
+	session := testSession(t)
+	t.Setenv("BUS_SESSION", session)
+
+	origDir, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(origDir)
+
+	base := filepath.Join(tmpDir, "project", "context.d")
+	writeContextFile(t, base, "shared", "big", strings.Repeat("x", 2000))
+	writeContextFile(t, base, "build", "notes", "build notes")
+	SetConfig(&MuxcodeConfig{Context: ContextConfig{ContextBudget: ContextBudget{MaxBytes: 1000}}})
+	defer SetConfig(nil)
+
+	files, err := AllContextFilesForRole("build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name != "notes" || len(files[1].Body) > 1000 {
+		t.Errorf("files = %+v", files)
+	}
+
+	msgs, err := Peek(session, "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Action != "compact-recommended" || !strings.Contains(msgs[0].Payload, "over its") {
+		t.Errorf("inbox = %+v, want one compact-recommended warning", msgs)
+	}
+}
+
+func TestContextConfigValidate(t *testing.T) {
+	if err := (ContextConfig{Roles: map[string]ContextBudget{"build": {MaxBytes: -1}}}).Validate(); err == nil {
+		t.Error("expected error for negative budget")
+	}
+	if err := (ContextConfig{ContextBudget: ContextBudget{MaxTokens: 100}}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ContextData is the data passed to templated context files.
+type ContextData struct {
+	Project  string                       `json:"project"`  // working directory base name
+	Role     string                       `json:"role"`     // the file's role directory ("shared" or a role)
+	Stacks   []string                     `json:"stacks"`   // detected project type names
+	Module   string                       `json:"module"`   // Go module, or package name from package.json/composer.json
+	Scripts  map[string]string            `json:"scripts"`  // package.json scripts
+	Meta     map[string]map[string]string `json:"meta"`     // detection metadata by project type
+	Packages []WorkspacePackage           `json:"packages"` // monorepo workspace packages
+}
+
+// BuildContextData collects template variables for context files in dir.
+func BuildContextData(dir string) ContextData {
+	d := ContextData{
+		Project: filepath.Base(dir),
+		Scripts: packageScripts(dir),
+		Meta:    map[string]map[string]string{},
+	}
+	for _, pt := range DetectProject(dir) {
+		d.Stacks = append(d.Stacks, pt.Name)
+		d.Meta[pt.Name] = pt.Metadata
+		d.Packages = append(d.Packages, pt.Packages...)
+		if d.Module == "" {
+			if v := pt.Metadata["module"]; v != "" {
+				d.Module = v
+			} else if v := pt.Metadata["name"]; v != "" {
+				d.Module = v
+			}
+		}
+	}
+	return d
+}
+
+// packageScripts returns all scripts from dir/package.json.
+func packageScripts(dir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
+
+// isContextTemplate reports whether a context body uses template actions.
+func isContextTemplate(body string) bool {
+	return strings.Contains(body, "{{")
+}
+
+// ExpandContextBody renders a context file body as a text/template with
+// data. The prompt template helpers (join, upper, lower, has) are
+// available.
+func ExpandContextBody(name, body string, data ContextData) (string, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("context %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("context %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// expandContextFiles returns files with templated manual bodies expanded
+// against the working directory. A file that fails to render keeps its
+// raw body and a warning is printed.
+func expandContextFiles(files []ContextFile) []ContextFile {
+	var data *ContextData
+	out := make([]ContextFile, len(files))
+	for i, f := range files {
+		out[i] = f
+		if f.Source == "auto" || !isContextTemplate(f.Body) {
+			continue
+		}
+		if data == nil {
+			cwd, err := os.Getwd()
+			if err != nil {
+				cwd = "."
+			}
+			d := BuildContextData(cwd)
+			data = &d
+		}
+		data.Role = f.Role
+		body, err := ExpandContextBody(f.Role+"/"+f.Name+".md", f.Body, *data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  [context] %v — using the raw file\n", err)
+			continue
+		}
+		out[i].Body = body
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildContextData(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "go.mod", "module example.com/svc\n\ngo 1.22\n")
+	writeIndicatorFile(t, dir, "package.json", `{"name":"web","scripts":{"dev":"vite","lint":"eslint ."}}`)
+
+	d := BuildContextData(dir)
+	if strings.Join(d.Stacks, ",") != "go,nodejs" {
+		t.Errorf("stacks = %v", d.Stacks)
+	}
+	if d.Module != "example.com/svc" {
+		t.Errorf("module = %q, want the Go module (first stack)", d.Module)
+	}
+	if d.Scripts["lint"] != "eslint ." {
+		t.Errorf("scripts = %v", d.Scripts)
+	}
+	if d.Meta["go"]["go_version"] != "1.22" {
+		t.Errorf("meta = %v", d.Meta)
+	}
+}
+
+func TestExpandContextBody(t *testing.T) {
+	data := ContextData{
+		Project: "svc",
+		Role:    "build",
+		Stacks:  []string{"go"},
+		Module:  "example.com/svc",
+		Scripts: map[string]string{"test": "jest"},
+		Meta:    map[string]map[string]string{"go": {"go_version": "1.22"}},
+	}
+	body := `Module {{.Module}} ({{join .Stacks ", "}}), Go {{index .Meta "go" "go_version"}}
+{{if has .Stacks "nodejs"}}node{{else}}no node{{end}} for {{.Role}}
+missing: [{{index .Meta "rust" "edition"}}]`
+	got, err := ExpandContextBody("shared/stack.md", body, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Module example.com/svc (go), Go 1.22", "no node for build", "missing: []"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+
+	if _, err := ExpandContextBody("shared/bad.md", "{{.Module", data); err == nil || !strings.Contains(err.Error(), "shared/bad.md") {
+		t.Errorf("err = %v, want parse error naming the file", err)
+	}
+}
+
+func TestFormatContextPrompt_Templates(t *testing.T) {
+	origDir, _ := os.Getwd()
+	projDir := t.TempDir()
+	writeIndicatorFile(t, projDir, "go.mod", "module example.com/test\n\ngo 1.22\n")
+	os.Chdir(projDir)
+	defer os.Chdir(origDir)
+
+	files := []ContextFile{
+		{Name: "stack", Role: "shared", Body: "Module: {{.Module}}", Source: "project"},
+		{Name: "broken", Role: "build", Body: "Keep {{.Module", Source: "project"},
+		{Name: "auto", Role: "shared", Body: "Literal {{.Module}}", Source: "auto"},
+	}
+	out := FormatContextPrompt(files)
+	for _, want := range []string{"Module: example.com/test", "Keep {{.Module", "Literal {{.Module}}"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if files[0].Body != "Module: {{.Module}}" {
+		t.Error("FormatContextPrompt must not modify its input")
+	}
+}
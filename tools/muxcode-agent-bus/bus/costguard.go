@@ -0,0 +1,285 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ApprovalCost is the kind of approval a cost guard requests before a
+// costly command runs.
+const ApprovalCost = "cost"
+
+// CostDecisionAction is the action of the event telling a role that its
+// costly command was approved or rejected.
+const CostDecisionAction = "cost-decision"
+
+// DefaultCostGuardRoles are the roles guarded when cost_guard.roles is unset.
+var DefaultCostGuardRoles = []string{"deploy", "run"}
+
+// DefaultCostRules flag common expensive cloud commands when cost_guard is
+// enabled without rules of its own.
+var DefaultCostRules = []CostRule{
+	{Name: "cdk-deploy", Match: `\bcdk\s+deploy\b`, MinResources: 50, Note: "large CDK deploy"},
+	{Name: "ec2-run-instances", Match: `\baws\s+ec2\s+run-instances\b`, Note: "launches EC2 instances"},
+	{Name: "aws-create-cluster", Match: `\baws\s+(rds\s+create-db-(instance|cluster)|eks\s+create-cluster|redshift\s+create-cluster)\b`, Note: "creates a managed cluster or database"},
+}
+
+// CostGuardConfig is the "cost_guard" section of muxcode.json. The guard
+// is off unless enabled; Rules replaces DefaultCostRules when set.
+type CostGuardConfig struct {
+	Enabled bool       `json:"enabled,omitempty"`
+	Roles   []string   `json:"roles,omitempty"` // roles whose commands are checked (default deploy, run)
+	Rules   []CostRule `json:"rules,omitempty"`
+}
+
+// CostRule flags commands matching a regexp. The embedded gate names who
+// approves them and for how long a request waits.
+type CostRule struct {
+	Name  string `json:"name"`
+	Match string `json:"match"` // Go regexp matched against the command
+	// MinResources, for cdk deploy, flags only deploys whose synthesized
+	// templates in cdk.out hold at least this many resources. A deploy
+	// that cannot be estimated is always flagged.
+	MinResources int    `json:"min_resources,omitempty"`
+	Note         string `json:"note,omitempty"` // why the command is costly, shown to the approver
+	ApprovalGate
+}
+
+// RoleList returns the guarded roles, or the defaults.
+func (c CostGuardConfig) RoleList() []string {
+	if len(c.Roles) > 0 {
+		return c.Roles
+	}
+	return DefaultCostGuardRoles
+}
+
+// RuleList returns the configured rules, or the defaults.
+func (c CostGuardConfig) RuleList() []CostRule {
+	if len(c.Rules) > 0 {
+		return c.Rules
+	}
+	return DefaultCostRules
+}
+
+// Validate checks rule names, patterns, and gates. Roles are checked by
+// config validate.
+func (c CostGuardConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, r := range c.Rules {
+		switch {
+		case r.Name == "":
+			return fmt.Errorf("rules.%d: name is required", i)
+		case seen[r.Name]:
+			return fmt.Errorf("rules.%d: duplicate name %q", i, r.Name)
+		case r.Match == "":
+			return fmt.Errorf("rules.%d: match is required", i)
+		case r.MinResources < 0:
+			return fmt.Errorf("rules.%d: min_resources must not be negative", i)
+		}
+		seen[r.Name] = true
+		if _, err := regexp.Compile(r.Match); err != nil {
+			return fmt.Errorf("rules.%d: invalid match: %v", i, err)
+		}
+		if errs := r.Check(); len(errs) > 0 {
+			return fmt.Errorf("rules.%d: %s", i, errs[0])
+		}
+	}
+	return nil
+}
+
+// CostGate records the command a cost approval covers. UsedAt is set once
+// the decision has been acted on, so each approval admits one run.
+type CostGate struct {
+	Rule     string `json:"rule"`
+	Command  string `json:"command"`
+	Estimate string `json:"estimate,omitempty"`
+	UsedAt   int64  `json:"used_at,omitempty"`
+}
+
+// CostCheck is the cost guard's verdict on a command.
+type CostCheck struct {
+	Allowed  bool   `json:"allowed"`
+	Rule     string `json:"rule,omitempty"`
+	Estimate string `json:"estimate,omitempty"`
+	Approval string `json:"approval,omitempty"` // approval requested, pending, or used
+	Reason   string `json:"reason,omitempty"`   // why a blocked command was blocked
+}
+
+// MatchCostRule returns the first rule flagging command, with its
+// estimate. dir is where cdk.out is looked for. ok is false when no rule
+// applies, including a cdk deploy estimated below its rule's minimum.
+func MatchCostRule(rules []CostRule, command, dir string) (rule CostRule, estimate string, ok bool) {
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Match)
+		if err != nil || !re.MatchString(command) {
+			continue
+		}
+		estimate = r.Note
+		if r.MinResources > 0 && cdkDeployPattern.MatchString(command) {
+			n, stacks, err := CDKResourceCount(dir, cdkDeployStacks(command))
+			if err == nil && stacks > 0 {
+				if n < r.MinResources {
+					continue
+				}
+				estimate = fmt.Sprintf("%d resources in %d stack(s)", n, stacks)
+				if r.Note != "" {
+					estimate = r.Note + ": " + estimate
+				}
+			}
+		}
+		return r, estimate, true
+	}
+	return CostRule{}, "", false
+}
+
+var cdkDeployPattern = regexp.MustCompile(`\bcdk\s+deploy\b`)
+
+// cdkDeployStacks returns the stack names a cdk deploy command names, or
+// nil for all stacks.
+func cdkDeployStacks(command string) []string {
+	loc := cdkDeployPattern.FindStringIndex(command)
+	if loc == nil {
+		return nil
+	}
+	var stacks []string
+	for _, f := range strings.Fields(command[loc[1]:]) {
+		if f == "&&" || f == ";" || f == "|" {
+			break
+		}
+		if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+			continue
+		}
+		stacks = append(stacks, strings.Trim(f, `"'`))
+	}
+	return stacks
+}
+
+// CDKResourceCount counts the resources in the synthesized templates under
+// dir/cdk.out, limited to the named stacks when any match a template.
+func CDKResourceCount(dir string, stacks []string) (resources, templates int, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "cdk.out", "*.template.json"))
+	if err != nil {
+		return 0, 0, err
+	}
+	want := make(map[string]bool)
+	for _, s := range stacks {
+		if !strings.ContainsAny(s, "*?") {
+			want[s] = true
+		}
+	}
+	named := false
+	for _, p := range paths {
+		if want[strings.TrimSuffix(filepath.Base(p), ".template.json")] {
+			named = true
+		}
+	}
+	for _, p := range paths {
+		if named && !want[strings.TrimSuffix(filepath.Base(p), ".template.json")] {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return 0, 0, err
+		}
+		var tmpl struct {
+			Resources map[string]json.RawMessage `json:"Resources"`
+		}
+		if err := json.Unmarshal(data, &tmpl); err != nil {
+			return 0, 0, fmt.Errorf("%s: %v", filepath.Base(p), err)
+		}
+		resources += len(tmpl.Resources)
+		templates++
+	}
+	return resources, templates, nil
+}
+
+// CheckCostGuard decides whether role may run command. A flagged command
+// runs once an approval for it is granted; each approval or rejection
+// answers one attempt. The first attempt requests approval and is
+// blocked, as is any attempt while the request is pending.
+func CheckCostGuard(session, role, command, dir string, now time.Time) (CostCheck, error) {
+	cfg := Config().CostGuard
+	if !cfg.Enabled || !containsRole(cfg.RoleList(), role) {
+		return CostCheck{Allowed: true}, nil
+	}
+	rule, estimate, ok := MatchCostRule(cfg.RuleList(), command, dir)
+	if !ok {
+		return CostCheck{Allowed: true}, nil
+	}
+	check := CostCheck{Rule: rule.Name, Estimate: estimate}
+
+	unlock := lockApprovals(session)
+	as, err := ReadApprovals(session)
+	if err != nil {
+		unlock()
+		return check, err
+	}
+	idx := -1
+	for i, a := range as {
+		if a.Kind == ApprovalCost && a.From == role && a.Cost != nil && a.Cost.Command == command && a.Cost.UsedAt == 0 &&
+			(a.Status == ApprovalPending || a.Status == ApprovalApproved || a.Status == ApprovalRejected) {
+			idx = i
+		}
+	}
+	if idx >= 0 {
+		a := &as[idx]
+		check.Approval = a.ID
+		switch a.Status {
+		case ApprovalPending:
+			unlock()
+			check.Reason = fmt.Sprintf("costly command (%s) is waiting for approval %s from %s", rule.Name, a.ID, a.Approver)
+			return check, nil
+		case ApprovalApproved:
+			check.Allowed = true
+		default:
+			check.Reason = fmt.Sprintf("costly command (%s) was rejected by %s", rule.Name, a.DecidedBy)
+			if a.Reason != "" {
+				check.Reason += ": " + a.Reason
+			}
+		}
+		a.Cost.UsedAt = now.Unix()
+		err = writeApprovals(session, as)
+		unlock()
+		return check, err
+	}
+	unlock()
+
+	prompt := fmt.Sprintf("%s wants to run a costly command (%s): %s", role, rule.Name, command)
+	if estimate != "" {
+		prompt += " [" + estimate + "]"
+	}
+	a := newApproval(rule.ApprovalGate, newWorkflowRunID("cost-"+role), ApprovalCost, role, prompt, now)
+	a.Cost = &CostGate{Rule: rule.Name, Command: command, Estimate: estimate}
+	if err := RequestApproval(session, a); err != nil {
+		return check, err
+	}
+	check.Approval = a.ID
+	check.Reason = fmt.Sprintf("costly command (%s) requires approval; requested %s from %s. Wait for the %s event, then run the same command again", rule.Name, a.ID, a.Approver, CostDecisionAction)
+	return check, nil
+}
+
+// notifyCostDecision tells the role that asked whether its costly command
+// may run.
+func notifyCostDecision(session string, a Approval) error {
+	text := fmt.Sprintf("Approved %s: run the command again: %s", a.ID, a.Cost.Command)
+	if a.Status == ApprovalRejected {
+		text = fmt.Sprintf("Rejected %s by %s: do not run %s", a.ID, a.DecidedBy, a.Cost.Command)
+		if a.Reason != "" {
+			text += " (" + a.Reason + ")"
+		}
+	}
+	from := a.DecidedBy
+	if !IsKnownRole(from) {
+		from = a.Approver
+	}
+	if err := Send(session, NewMessage(from, a.From, "event", CostDecisionAction, text, "")); err != nil {
+		return err
+	}
+	_ = Notify(session, a.From)
+	return nil
+}
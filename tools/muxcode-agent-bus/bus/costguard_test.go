@@ -0,0 +1,129 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCDKTemplate writes a synthesized stack template with n resources.
+func writeCDKTemplate(t *testing.T, dir, stack string, n int) {
+	t.Helper()
+	var res []string
+	for i := 0; i < n; i++ {
+		res = append(res, fmt.Sprintf(`"R%d": {"Type": "AWS::SNS::Topic"}`, i))
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "cdk.out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"Resources": {` + strings.Join(res, ",") + `}}`
+	if err := os.WriteFile(filepath.Join(dir, "cdk.out", stack+".template.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatchCostRule(t *testing.T) {
+	dir := t.TempDir()
+	rules := DefaultCostRules
+
+	// Without cdk.out the deploy cannot be estimated and is flagged
+	if r, _, ok := MatchCostRule(rules, "npx cdk deploy Api", dir); !ok || r.Name != "cdk-deploy" {
+		t.Errorf("unestimated cdk deploy = %v %v", r.Name, ok)
+	}
+	writeCDKTemplate(t, dir, "Api", 10)
+	writeCDKTemplate(t, dir, "Data", 45)
+	if _, _, ok := MatchCostRule(rules, "cdk deploy Api --require-approval never", dir); ok {
+		t.Error("small stack: want not flagged")
+	}
+	r, est, ok := MatchCostRule(rules, "cdk deploy --all", dir)
+	if !ok || est != "large CDK deploy: 55 resources in 2 stack(s)" {
+		t.Errorf("all stacks = %v %q %v", r.Name, est, ok)
+	}
+	if _, _, ok := MatchCostRule(rules, "cdk diff", dir); ok {
+		t.Error("cdk diff: want not flagged")
+	}
+	if r, _, ok := MatchCostRule(rules, "aws ec2 run-instances --image-id ami-1 --count 4", dir); !ok || r.Name != "ec2-run-instances" {
+		t.Errorf("run-instances = %v %v", r.Name, ok)
+	}
+}
+
+func TestCheckCostGuard(t *testing.T) {
+	session := testSession(t)
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.CostGuard = CostGuardConfig{Enabled: true, Rules: []CostRule{
+		{Name: "ec2", Match: `aws ec2 run-instances`, Note: "launches instances", ApprovalGate: ApprovalGate{Approver: "review"}},
+	}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+	now := time.Now()
+	cmd := "aws ec2 run-instances --count 2"
+
+	if c, err := CheckCostGuard(session, "deploy", "aws s3 ls", dir, now); err != nil || !c.Allowed {
+		t.Errorf("unflagged command = %+v, %v", c, err)
+	}
+	if c, err := CheckCostGuard(session, "build", cmd, dir, now); err != nil || !c.Allowed {
+		t.Errorf("unguarded role = %+v, %v", c, err)
+	}
+
+	// The first attempt requests approval; retries wait for it
+	c, err := CheckCostGuard(session, "deploy", cmd, dir, now)
+	if err != nil || c.Allowed || c.Approval == "" || !strings.Contains(c.Reason, "requires approval") {
+		t.Fatalf("first attempt = %+v, %v", c, err)
+	}
+	msgs, _ := Peek(session, "review")
+	if len(msgs) != 1 || msgs[0].Action != "approval-required" || !strings.Contains(msgs[0].Payload, "[launches instances]") {
+		t.Fatalf("review inbox = %+v", msgs)
+	}
+	if c2, _ := CheckCostGuard(session, "deploy", cmd, dir, now); c2.Allowed || c2.Approval != c.Approval || !strings.Contains(c2.Reason, "waiting for approval") {
+		t.Errorf("pending attempt = %+v", c2)
+	}
+
+	// Approval notifies the role and admits one run
+	if _, err := DecideApproval(session, c.Approval, true, "review", ""); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ = Peek(session, "deploy")
+	if len(msgs) != 1 || msgs[0].Action != CostDecisionAction || !strings.Contains(msgs[0].Payload, "run the command again") {
+		t.Fatalf("deploy inbox = %+v", msgs)
+	}
+	if c2, _ := CheckCostGuard(session, "deploy", cmd, dir, now); !c2.Allowed || c2.Approval != c.Approval {
+		t.Errorf("approved attempt = %+v", c2)
+	}
+
+	// The next run asks again; a rejection blocks one attempt
+	c, _ = CheckCostGuard(session, "deploy", cmd, dir, now)
+	if c.Allowed {
+		t.Fatalf("second run = %+v", c)
+	}
+	if _, err := DecideApproval(session, c.Approval, false, "review", "too many instances"); err != nil {
+		t.Fatal(err)
+	}
+	if c2, _ := CheckCostGuard(session, "deploy", cmd, dir, now); c2.Allowed || !strings.Contains(c2.Reason, "rejected by review: too many instances") {
+		t.Errorf("rejected attempt = %+v", c2)
+	}
+	if c2, _ := CheckCostGuard(session, "deploy", cmd, dir, now); c2.Allowed || c2.Approval == c.Approval {
+		t.Errorf("attempt after rejection = %+v", c2)
+	}
+}
+
+func TestCostGuardConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		cfg  CostGuardConfig
+		want string
+	}{
+		{CostGuardConfig{Enabled: true}, ""},
+		{CostGuardConfig{Rules: []CostRule{{Name: "x", Match: "("}}}, "invalid match"},
+		{CostGuardConfig{Rules: []CostRule{{Match: "x"}}}, "name is required"},
+		{CostGuardConfig{Rules: []CostRule{{Name: "x", Match: "a"}, {Name: "x", Match: "b"}}}, "duplicate name"},
+		{CostGuardConfig{Rules: []CostRule{{Name: "x", Match: "a", ApprovalGate: ApprovalGate{Timeout: "soon"}}}}, "invalid timeout"},
+	} {
+		err := c.cfg.Validate()
+		if c.want == "" && err != nil || c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.cfg, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoverageDropAction is the action of the event sent, and the event name
+// subscriptions match, when a role's coverage falls below its floor.
+const CoverageDropAction = "coverage-drop"
+
+// coverageKeep is how many coverage records the session keeps.
+const coverageKeep = 500
+
+// CoverageConfig is the "coverage" section of muxcode.json.
+type CoverageConfig struct {
+	Floor float64            `json:"floor,omitempty"` // percent; 0 disables coverage-drop
+	Roles map[string]float64 `json:"roles,omitempty"` // role -> floor replacing Floor
+}
+
+// FloorFor returns the coverage floor for a role, or 0 when none is set.
+func (c CoverageConfig) FloorFor(role string) float64 {
+	if f, ok := c.Roles[role]; ok {
+		return f
+	}
+	return c.Floor
+}
+
+// Validate rejects floors outside 0-100.
+func (c CoverageConfig) Validate() error {
+	if c.Floor < 0 || c.Floor > 100 {
+		return fmt.Errorf("floor must be between 0 and 100, got %g", c.Floor)
+	}
+	roles := make([]string, 0, len(c.Roles))
+	for role := range c.Roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		if f := c.Roles[role]; f < 0 || f > 100 {
+			return fmt.Errorf("roles.%s: floor must be between 0 and 100, got %g", role, f)
+		}
+	}
+	return nil
+}
+
+// CoverageRecord is one coverage measurement logged by a role.
+type CoverageRecord struct {
+	TS      int64   `json:"ts"`
+	Role    string  `json:"role"`
+	Command string  `json:"command,omitempty"`
+	Percent float64 `json:"percent"`
+}
+
+// CoveragePath returns the session's coverage log.
+func CoveragePath(session string) string {
+	return filepath.Join(BusDir(session), "coverage.jsonl")
+}
+
+// lockCoverage serializes coverage appends so each run is compared with
+// the run before it.
+func lockCoverage(session string) func() {
+	return flockBusFile(session, "coverage")
+}
+
+// ReadCoverage reads the coverage records for a role, oldest first; an
+// empty role reads every role.
+func ReadCoverage(session, role string) ([]CoverageRecord, error) {
+	data, err := os.ReadFile(CoveragePath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []CoverageRecord
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var r CoverageRecord
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue
+		}
+		if role == "" || r.Role == role {
+			records = append(records, r)
+		}
+	}
+	return records, sc.Err()
+}
+
+// RecordCoverage appends a role's coverage. When it is below the role's
+// floor and the previous run was not, edit gets a coverage-drop event and
+// coverage-drop subscriptions fire. It reports whether the drop fired.
+func RecordCoverage(session, role, command string, percent float64, now time.Time) (bool, error) {
+	if err := os.MkdirAll(BusDir(session), 0755); err != nil {
+		return false, err
+	}
+	unlock := lockCoverage(session)
+	records, err := ReadCoverage(session, role)
+	if err != nil {
+		unlock()
+		return false, err
+	}
+	data, _ := json.Marshal(CoverageRecord{TS: now.Unix(), Role: role, Command: command, Percent: percent})
+	err = appendToFile(CoveragePath(session), append(data, '\n'))
+	if err == nil {
+		trimJSONL(CoveragePath(session), coverageKeep)
+	}
+	unlock()
+	if err != nil {
+		return false, err
+	}
+
+	floor := Config().Coverage.FloorFor(role)
+	if floor <= 0 || percent >= floor {
+		return false, nil
+	}
+	var prev *CoverageRecord
+	if len(records) > 0 {
+		prev = &records[len(records)-1]
+		if prev.Percent < floor {
+			return false, nil // already below; alerted when it fell
+		}
+	}
+
+	msg := NewMessage(role, "edit", "event", CoverageDropAction, coverageDropPayload(role, command, percent, floor, prev), "")
+	if err := SendNoCC(session, msg); err != nil {
+		return true, err
+	}
+	_ = Notify(session, "edit")
+	_, err = FireSubscriptions(session, role, CoverageDropAction, "failure", "", command)
+	return true, err
+}
+
+// coverageDropPayload describes a coverage drop.
+func coverageDropPayload(role, command string, percent, floor float64, prev *CoverageRecord) string {
+	s := fmt.Sprintf("Coverage for %s fell to %.1f%%, below the %.1f%% floor", role, percent, floor)
+	if prev != nil {
+		s += fmt.Sprintf(" (was %.1f%%)", prev.Percent)
+	}
+	if command != "" {
+		s += ": " + command
+	}
+	return s
+}
+
+var (
+	coverFuncTotalRe = regexp.MustCompile(`(?m)^total:\s+\(statements\)\s+([\d.]+)%`)
+	coverGoTestRe    = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+	coverLcovSumRe   = regexp.MustCompile(`(?m)^\s*lines\.*:\s*([\d.]+)%`)
+	coverLcovLineRe  = regexp.MustCompile(`(?m)^(LF|LH):(\d+)`)
+)
+
+// ExtractCoverage finds the coverage percentage in command output: the
+// total of `go tool cover -func`, the mean of `go test -cover` package
+// lines, an `lcov --summary` line rate, or the line rate of lcov.info data.
+func ExtractCoverage(output string) (float64, bool) {
+	if m := coverFuncTotalRe.FindStringSubmatch(output); m != nil {
+		return parsePercent(m[1])
+	}
+	if ms := coverGoTestRe.FindAllStringSubmatch(output, -1); len(ms) > 0 {
+		sum := 0.0
+		for _, m := range ms {
+			p, _ := strconv.ParseFloat(m[1], 64)
+			sum += p
+		}
+		return sum / float64(len(ms)), true
+	}
+	if m := coverLcovSumRe.FindStringSubmatch(output); m != nil {
+		return parsePercent(m[1])
+	}
+	var found, hit int
+	for _, m := range coverLcovLineRe.FindAllStringSubmatch(output, -1) {
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "LF" {
+			found += n
+		} else {
+			hit += n
+		}
+	}
+	if found > 0 {
+		return float64(hit) * 100 / float64(found), true
+	}
+	return 0, false
+}
+
+// ParseCoverage parses a percentage such as "78.3" or "78.3%".
+func ParseCoverage(s string) (float64, error) {
+	p, ok := parsePercent(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if !ok {
+		return 0, fmt.Errorf("coverage must be a percentage between 0 and 100, got %q", s)
+	}
+	return p, nil
+}
+
+// parsePercent parses a number in 0-100.
+func parsePercent(s string) (float64, bool) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// FormatCoverageTrend renders coverage records with the change from each
+// role's previous run, newest last. At most limit records per role are
+// shown (0 shows all).
+func FormatCoverageTrend(records []CoverageRecord, limit int) string {
+	if len(records) == 0 {
+		return "No coverage recorded.\n"
+	}
+	byRole := make(map[string][]CoverageRecord)
+	var roles []string
+	for _, r := range records {
+		if _, ok := byRole[r.Role]; !ok {
+			roles = append(roles, r.Role)
+		}
+		byRole[r.Role] = append(byRole[r.Role], r)
+	}
+	sort.Strings(roles)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %-12s %8s %7s  %s\n", "ROLE", "TIME", "COVERAGE", "DELTA", "COMMAND")
+	for _, role := range roles {
+		rs := byRole[role]
+		start := 0
+		if limit > 0 && len(rs) > limit {
+			start = len(rs) - limit
+		}
+		floor := Config().Coverage.FloorFor(role)
+		for i := start; i < len(rs); i++ {
+			r := rs[i]
+			delta := "-"
+			if i > 0 {
+				delta = fmt.Sprintf("%+.1f", r.Percent-rs[i-1].Percent)
+			}
+			pct := fmt.Sprintf("%.1f%%", r.Percent)
+			if floor > 0 && r.Percent < floor {
+				pct = "!" + pct
+			}
+			fmt.Fprintf(&b, "%-10s %-12s %8s %7s  %s\n", role, time.Unix(r.TS, 0).Format("01-02 15:04"), pct, delta, r.Command)
+		}
+	}
+	return b.String()
+}
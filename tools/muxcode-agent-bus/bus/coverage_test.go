@@ -0,0 +1,102 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractCoverage(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		output string
+		want   float64
+		ok     bool
+	}{
+		{"cover -func", "app/bus/inbox.go:12:\tSend\t\t100.0%\ntotal:\t\t\t(statements)\t78.3%\n", 78.3, true},
+		{"go test -cover", "ok  \tapp/bus\t0.1s\tcoverage: 80.0% of statements\nok  \tapp/cmd\t0.1s\tcoverage: 60.0% of statements\n", 70, true},
+		{"lcov --summary", "Summary coverage rate:\n  lines......: 81.5% (815 of 1000 lines)\n  functions..: 90.0% (90 of 100 functions)\n", 81.5, true},
+		{"lcov.info", "SF:src/a.js\nLF:10\nLH:5\nend_of_record\nSF:src/b.js\nLF:30\nLH:27\nend_of_record\n", 80, true},
+		{"none", "PASS\nok  \tapp\t0.1s\n", 0, false},
+	} {
+		got, ok := ExtractCoverage(c.output)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%s: ExtractCoverage = %v, %v; want %v, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+
+	if p, err := ParseCoverage("78.3%"); err != nil || p != 78.3 {
+		t.Errorf("ParseCoverage(78.3%%) = %v, %v", p, err)
+	}
+	if _, err := ParseCoverage("120"); err == nil {
+		t.Error("ParseCoverage(120): want error")
+	}
+}
+
+func TestRecordCoverage(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Coverage = CoverageConfig{Floor: 80, Roles: map[string]float64{"build": 50}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+	if err := WriteSubscriptions(session, []Subscription{
+		{ID: "sub-1", Event: CoverageDropAction, Outcome: "*", Notify: "watch", Action: "alert", Message: "${event}: ${command}", Enabled: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	for i, c := range []struct {
+		percent float64
+		dropped bool
+	}{
+		{85, false},
+		{78.5, true}, // fell below the floor
+		{77, false},  // still below: no repeat
+		{82, false},  // recovered
+		{79.9, true}, // fell again
+	} {
+		dropped, err := RecordCoverage(session, "test", "go test -cover ./...", c.percent, now.Add(time.Duration(i)*time.Minute))
+		if err != nil || dropped != c.dropped {
+			t.Errorf("run %d (%.1f%%): dropped = %v, %v; want %v", i, c.percent, dropped, err, c.dropped)
+		}
+	}
+	if dropped, _ := RecordCoverage(session, "build", "", 60, now); dropped {
+		t.Error("build is above its own 50% floor")
+	}
+
+	msgs, _ := Peek(session, "edit")
+	if len(msgs) != 2 || msgs[0].Action != CoverageDropAction ||
+		msgs[0].Payload != "Coverage for test fell to 78.5%, below the 80.0% floor (was 85.0%): go test -cover ./..." {
+		t.Fatalf("edit inbox = %+v", msgs)
+	}
+	if msgs, _ := Peek(session, "watch"); len(msgs) != 2 || msgs[0].Payload != "coverage-drop: go test -cover ./..." {
+		t.Errorf("watch inbox = %+v", msgs)
+	}
+
+	records, err := ReadCoverage(session, "test")
+	if err != nil || len(records) != 5 {
+		t.Fatalf("ReadCoverage = %+v, %v", records, err)
+	}
+	out := FormatCoverageTrend(records, 2)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "82.0%") || !strings.Contains(lines[1], "+5.0") ||
+		!strings.Contains(lines[2], "!79.9%") || !strings.Contains(lines[2], "-2.1") {
+		t.Errorf("FormatCoverageTrend:\n%s", out)
+	}
+	if out := FormatCoverageTrend(nil, 0); out != "No coverage recorded.\n" {
+		t.Errorf("FormatCoverageTrend(nil) = %q", out)
+	}
+}
+
+func TestCoverageConfigValidate(t *testing.T) {
+	if err := (CoverageConfig{Floor: 80, Roles: map[string]float64{"test": 90}}).Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	if err := (CoverageConfig{Floor: 180}).Validate(); err == nil {
+		t.Error("floor 180: want error")
+	}
+	if err := (CoverageConfig{Roles: map[string]float64{"test": -1}}).Validate(); err == nil || !strings.Contains(err.Error(), "roles.test") {
+		t.Errorf("negative role floor: %v", err)
+	}
+}
@@ -21,13 +21,39 @@ type CronEntry struct {
 	CreatedAt int64  `json:"created_at"`
 	LastRunTS int64  `json:"last_run_ts"`
 	RunCount  int    `json:"run_count"`
+	TZ        string `json:"tz,omitempty"`     // IANA zone for cron expressions (empty = local)
+	RunAt     int64  `json:"run_at,omitempty"` // fire time for one-shot ("@once") entries
+	Misfire   string `json:"misfire_policy,omitempty"`
 }
 
-// CronSchedule holds a parsed interval duration.
+// Misfire policies decide what happens to runs that came due while the
+// watcher was not running.
+const (
+	MisfireSkip     = "skip"      // drop late runs; only on-time runs fire
+	MisfireFireOnce = "fire_once" // collapse late runs into a single firing (default)
+	MisfireFireAll  = "fire_all"  // fire every missed run, up to cronMaxCatchUp
+)
+
+// cronMisfireGrace is how late a run may fire before it counts as missed.
+const cronMisfireGrace = 2 * time.Minute
+
+// cronMaxCatchUp caps how many missed runs fire_all replays in one cycle.
+const cronMaxCatchUp = 10
+
+// cronMaxOccurrences bounds the scheduled-time scan for long outages.
+const cronMaxOccurrences = 1000
+
+// CronSchedule holds a parsed schedule: either a fixed interval or a
+// 5-field cron expression.
 type CronSchedule struct {
 	Interval time.Duration
+	Expr     *CronExpr
+	Once     bool // one-shot entry; fire time is CronEntry.RunAt
 }
 
+// OnceSchedule is the schedule string stored on one-shot entries.
+const OnceSchedule = "@once"
+
 // CronHistoryEntry records a single cron execution.
 type CronHistoryEntry struct {
 	CronID    string `json:"cron_id"`
@@ -35,6 +61,8 @@ type CronHistoryEntry struct {
 	MessageID string `json:"message_id"`
 	Target    string `json:"target"`
 	Action    string `json:"action"`
+	Missed    int    `json:"missed,omitempty"` // runs that came due while the watcher was down
+	Fired     int    `json:"fired,omitempty"`  // messages sent this cycle (0 with missed > 0 = skipped)
 }
 
 // minCronInterval is the minimum allowed cron interval (30 seconds).
@@ -44,6 +72,8 @@ const minCronInterval = 30 * time.Second
 // Supported formats:
 //   - "@every 30s", "@every 5m", "@every 1h", "@every 2h30m"
 //   - "@hourly" (1h), "@daily" (24h), "@half-hourly" (30m)
+//   - 5-field cron expressions: "30 9 * * 1-5", "*/15 * * * *"
+//   - "@once" (one-shot; fires at CronEntry.RunAt, then auto-disables)
 //
 // Case-insensitive. Minimum interval is 30s.
 func ParseSchedule(s string) (CronSchedule, error) {
@@ -56,6 +86,8 @@ func ParseSchedule(s string) (CronSchedule, error) {
 		return CronSchedule{Interval: 24 * time.Hour}, nil
 	case "@half-hourly":
 		return CronSchedule{Interval: 30 * time.Minute}, nil
+	case OnceSchedule:
+		return CronSchedule{Once: true}, nil
 	}
 
 	if lower == "@every" || strings.HasPrefix(lower, "@every ") {
@@ -74,9 +106,90 @@ func ParseSchedule(s string) (CronSchedule, error) {
 		return CronSchedule{Interval: d}, nil
 	}
 
+	if !strings.HasPrefix(lower, "@") && len(strings.Fields(lower)) == 5 {
+		expr, err := ParseCronExpr(lower)
+		if err != nil {
+			return CronSchedule{}, fmt.Errorf("invalid cron expression %q: %v", s, err)
+		}
+		return CronSchedule{Expr: expr}, nil
+	}
+
 	return CronSchedule{}, fmt.Errorf("unsupported schedule format: %q", s)
 }
 
+// runAtLayouts are the accepted --at formats, tried in order.
+var runAtLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseRunAt parses a one-shot fire time. Times without an offset are
+// interpreted in loc. The result must be in the future relative to now.
+func ParseRunAt(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range runAtLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("time %s is in the past", t.Format("2006-01-02 15:04 MST"))
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want YYYY-MM-DDTHH:MM)", s)
+}
+
+// CronLocation returns the time zone a cron entry is evaluated in.
+func CronLocation(entry CronEntry) (*time.Location, error) {
+	if entry.TZ == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(entry.TZ)
+}
+
+// NextRun returns when a cron entry will next fire, relative to now.
+// Interval schedules that have never run are due now. Returns false for
+// invalid schedules or expressions that never match.
+func NextRun(entry CronEntry, now int64) (time.Time, bool) {
+	sched, err := ParseSchedule(entry.Schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := CronLocation(entry)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if sched.Once {
+		if entry.RunCount > 0 || entry.RunAt == 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(entry.RunAt, 0).In(loc), true
+	}
+
+	if sched.Expr == nil {
+		if entry.LastRunTS == 0 {
+			return time.Unix(now, 0).In(loc), true
+		}
+		return time.Unix(entry.LastRunTS, 0).Add(sched.Interval).In(loc), true
+	}
+
+	// Expressions fire on the first match after the last run (or creation),
+	// so a match missed while the watcher was down still fires once.
+	base := entry.LastRunTS
+	if base == 0 {
+		base = entry.CreatedAt
+	}
+	if base == 0 {
+		base = now - 60
+	}
+	next := sched.Expr.Next(time.Unix(base, 0).In(loc))
+	return next, !next.IsZero()
+}
+
 // CronDue returns true if a cron entry is due for execution at the given time.
 func CronDue(entry CronEntry, now int64) bool {
 	if !entry.Enabled {
@@ -86,6 +199,10 @@ func CronDue(entry CronEntry, now int64) bool {
 	if err != nil {
 		return false
 	}
+	if sched.Expr != nil || sched.Once {
+		next, ok := NextRun(entry, now)
+		return ok && next.Unix() <= now
+	}
 	intervalSecs := int64(sched.Interval / time.Second)
 	if intervalSecs <= 0 {
 		return false
@@ -99,8 +216,81 @@ func CronDue(entry CronEntry, now int64) bool {
 	return now-entry.LastRunTS >= intervalSecs
 }
 
+// ValidMisfirePolicy reports whether p is a known misfire policy. Empty
+// means the default (fire_once).
+func ValidMisfirePolicy(p string) bool {
+	switch p {
+	case "", MisfireSkip, MisfireFireOnce, MisfireFireAll:
+		return true
+	}
+	return false
+}
+
+// cronOccurrences returns the scheduled fire times in (last run, now],
+// oldest first, capped at cronMaxOccurrences.
+func cronOccurrences(entry CronEntry, now int64) []int64 {
+	sched, err := ParseSchedule(entry.Schedule)
+	if err != nil {
+		return nil
+	}
+	next, ok := NextRun(entry, now)
+	if !ok || next.Unix() > now {
+		return nil
+	}
+
+	occ := []int64{next.Unix()}
+	switch {
+	case sched.Once:
+	case sched.Expr != nil:
+		for t := sched.Expr.Next(next); !t.IsZero() && t.Unix() <= now && len(occ) < cronMaxOccurrences; t = sched.Expr.Next(t) {
+			occ = append(occ, t.Unix())
+		}
+	default:
+		step := int64(sched.Interval / time.Second)
+		for t := next.Unix() + step; entry.LastRunTS != 0 && t <= now && len(occ) < cronMaxOccurrences; t += step {
+			occ = append(occ, t)
+		}
+	}
+	return occ
+}
+
+// CronMisfire applies an entry's misfire policy at now. It returns how many
+// messages to send and how many scheduled runs were missed (due more than
+// cronMisfireGrace ago). A due entry with fires == 0 was skipped.
+func CronMisfire(entry CronEntry, now int64) (fires, missed int) {
+	occ := cronOccurrences(entry, now)
+	if len(occ) == 0 {
+		return 0, 0
+	}
+	graceSecs := int64(cronMisfireGrace / time.Second)
+	for _, t := range occ {
+		if now-t > graceSecs {
+			missed++
+		}
+	}
+	if missed == 0 {
+		return 1, 0
+	}
+
+	switch entry.Misfire {
+	case MisfireSkip:
+		return len(occ) - missed, missed
+	case MisfireFireAll:
+		fires = len(occ)
+		if fires > cronMaxCatchUp {
+			fires = cronMaxCatchUp
+		}
+		return fires, missed
+	}
+	return 1, missed
+}
+
 // ExecuteCron sends a bus message for a cron entry and returns the message ID.
+// Entries targeting DigestTarget email the session digest instead.
 func ExecuteCron(session string, entry CronEntry) (string, error) {
+	if entry.Target == DigestTarget {
+		return executeDigestCron(session, entry)
+	}
 	msg := NewMessage("cron", entry.Target, "request", entry.Action, entry.Message, "")
 	if err := Send(session, msg); err != nil {
 		return "", fmt.Errorf("sending cron message: %v", err)
@@ -156,12 +346,28 @@ func WriteCronEntries(session string, entries []CronEntry) error {
 // generated ID and CreatedAt fields populated.
 func AddCronEntry(session string, entry CronEntry) (CronEntry, error) {
 	// Validate schedule
-	if _, err := ParseSchedule(entry.Schedule); err != nil {
+	sched, err := ParseSchedule(entry.Schedule)
+	if err != nil {
 		return CronEntry{}, fmt.Errorf("invalid schedule: %v", err)
 	}
+	if sched.Once && entry.RunAt == 0 {
+		return CronEntry{}, fmt.Errorf("one-shot entry needs a run time (--at or --in)")
+	}
+	if !ValidMisfirePolicy(entry.Misfire) {
+		return CronEntry{}, fmt.Errorf("invalid misfire policy %q (want %s, %s, or %s)", entry.Misfire, MisfireSkip, MisfireFireOnce, MisfireFireAll)
+	}
+	if _, err := CronLocation(entry); err != nil {
+		return CronEntry{}, fmt.Errorf("invalid time zone: %v", err)
+	}
 
-	// Validate target
-	if !IsKnownRole(entry.Target) {
+	// Validate target; digest entries carry their window as the message
+	if entry.Target == DigestTarget {
+		if s := strings.TrimSpace(entry.Message); s != "" {
+			if _, err := time.ParseDuration(s); err != nil {
+				return CronEntry{}, fmt.Errorf("invalid digest window %q (want a duration like 24h)", s)
+			}
+		}
+	} else if !IsKnownRole(entry.Target) {
 		return CronEntry{}, fmt.Errorf("unknown target role: %s", entry.Target)
 	}
 
@@ -178,6 +384,9 @@ func AddCronEntry(session string, entry CronEntry) (CronEntry, error) {
 	if err := WriteCronEntries(session, entries); err != nil {
 		return CronEntry{}, err
 	}
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditCronAdd, Role: entry.Target, Args: map[string]string{
+		"id": entry.ID, "schedule": entry.Schedule, "action": entry.Action, "message": entry.Message,
+	}})
 	return entry, nil
 }
 
@@ -188,21 +397,27 @@ func RemoveCronEntry(session, id string) error {
 		return err
 	}
 
-	found := false
+	var removed *CronEntry
 	var kept []CronEntry
-	for _, e := range entries {
+	for i, e := range entries {
 		if e.ID == id {
-			found = true
+			removed = &entries[i]
 			continue
 		}
 		kept = append(kept, e)
 	}
 
-	if !found {
+	if removed == nil {
 		return fmt.Errorf("cron entry not found: %s", id)
 	}
 
-	return WriteCronEntries(session, kept)
+	if err := WriteCronEntries(session, kept); err != nil {
+		return err
+	}
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditCronRemove, Role: removed.Target, Args: map[string]string{
+		"id": id, "schedule": removed.Schedule, "action": removed.Action,
+	}})
+	return nil
 }
 
 // SetCronEnabled enables or disables a cron entry by ID.
@@ -229,7 +444,14 @@ func SetCronEnabled(session, id string, enabled bool) error {
 }
 
 // UpdateLastRun updates the last run timestamp and increments run count for a cron entry.
+// One-shot entries are disabled after their run.
 func UpdateLastRun(session, id string, ts int64) error {
+	return RecordCronRuns(session, id, ts, 1)
+}
+
+// RecordCronRuns sets LastRunTS and adds runs to RunCount. runs may be 0
+// when the skip misfire policy dropped every missed run.
+func RecordCronRuns(session, id string, ts int64, runs int) error {
 	entries, err := ReadCronEntries(session)
 	if err != nil {
 		return err
@@ -239,7 +461,10 @@ func UpdateLastRun(session, id string, ts int64) error {
 	for i, e := range entries {
 		if e.ID == id {
 			entries[i].LastRunTS = ts
-			entries[i].RunCount++
+			entries[i].RunCount += runs
+			if IsOnceEntry(e) {
+				entries[i].Enabled = false
+			}
 			found = true
 			break
 		}
@@ -252,6 +477,25 @@ func UpdateLastRun(session, id string, ts int64) error {
 	return WriteCronEntries(session, entries)
 }
 
+// IsOnceEntry reports whether an entry is a one-shot schedule.
+func IsOnceEntry(e CronEntry) bool {
+	return strings.EqualFold(strings.TrimSpace(e.Schedule), OnceSchedule)
+}
+
+// cronStatus returns the display status of an entry. One-shot entries
+// report pending/fired rather than enabled/disabled.
+func cronStatus(e CronEntry) string {
+	switch {
+	case IsOnceEntry(e) && e.RunCount > 0:
+		return "fired"
+	case !e.Enabled:
+		return "disabled"
+	case IsOnceEntry(e):
+		return "pending"
+	}
+	return "enabled"
+}
+
 // AppendCronHistory appends a history entry to the cron history JSONL file.
 func AppendCronHistory(session string, entry CronHistoryEntry) error {
 	data, err := json.Marshal(entry)
@@ -312,17 +556,22 @@ func FormatCronList(entries []CronEntry, showAll bool) string {
 		return b.String()
 	}
 
-	b.WriteString(fmt.Sprintf("%-40s %-14s %-10s %-10s %-8s %s\n",
-		"ID", "Schedule", "Target", "Action", "Status", "Runs"))
-	b.WriteString(strings.Repeat("-", 100) + "\n")
+	b.WriteString(fmt.Sprintf("%-40s %-16s %-10s %-10s %-8s %-5s %s\n",
+		"ID", "Schedule", "Target", "Action", "Status", "Runs", "Next"))
+	b.WriteString(strings.Repeat("-", 120) + "\n")
 
+	now := time.Now().Unix()
 	for _, e := range filtered {
-		status := "enabled"
-		if !e.Enabled {
-			status = "disabled"
+		status := cronStatus(e)
+		next := "-"
+		if t, ok := NextRun(e, now); ok && e.Enabled {
+			next = t.Format("2006-01-02 15:04")
+			if e.TZ != "" {
+				next += " " + t.Format("MST")
+			}
 		}
-		b.WriteString(fmt.Sprintf("%-40s %-14s %-10s %-10s %-8s %d\n",
-			e.ID, e.Schedule, e.Target, e.Action, status, e.RunCount))
+		b.WriteString(fmt.Sprintf("%-40s %-16s %-10s %-10s %-8s %-5d %s\n",
+			e.ID, e.Schedule, e.Target, e.Action, status, e.RunCount, next))
 	}
 
 	return b.String()
@@ -343,8 +592,15 @@ func FormatCronHistory(entries []CronHistoryEntry) string {
 
 	for _, e := range entries {
 		t := time.Unix(e.TS, 0).Format("2006-01-02 15:04:05")
+		msgID := e.MessageID
+		if msgID == "" && e.Missed > 0 {
+			msgID = "(skipped)"
+		}
+		if e.Missed > 0 {
+			msgID += fmt.Sprintf("  [missed %d, fired %d]", e.Missed, e.Fired)
+		}
 		b.WriteString(fmt.Sprintf("%-20s %-10s %-10s %s\n",
-			t, e.Target, e.Action, e.MessageID))
+			t, e.Target, e.Action, msgID))
 	}
 
 	return b.String()
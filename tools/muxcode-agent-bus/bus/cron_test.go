@@ -162,6 +162,46 @@ func TestCronDue(t *testing.T) {
 	}
 }
 
+func TestCronDue_Expression(t *testing.T) {
+	created := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC).Unix()
+	entry := CronEntry{Schedule: "30 9 * * 1-5", Enabled: true, CreatedAt: created, TZ: "UTC"}
+
+	if CronDue(entry, created+29*60) {
+		t.Error("due before 09:30")
+	}
+	if !CronDue(entry, created+30*60) {
+		t.Error("not due at 09:30")
+	}
+
+	entry.LastRunTS = created + 30*60
+	if CronDue(entry, created+45*60) {
+		t.Error("due again the same morning")
+	}
+
+	next, ok := NextRun(entry, created+45*60)
+	if !ok || next.Format("2006-01-02 15:04") != "2026-03-05 09:30" {
+		t.Errorf("NextRun = %v, %v", next, ok)
+	}
+
+	entry.TZ = "Not/AZone"
+	if CronDue(entry, created+48*3600) {
+		t.Error("invalid tz should never be due")
+	}
+}
+
+func TestCronDue_ExpressionTimeZone(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("tzdata not available")
+	}
+	// 09:30 in New York (EST, UTC-5) is 14:30 UTC
+	created := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC).Unix()
+	entry := CronEntry{Schedule: "30 9 * * *", Enabled: true, CreatedAt: created, TZ: "America/New_York"}
+	at := time.Date(2026, 1, 5, 14, 30, 0, 0, time.UTC).Unix()
+	if CronDue(entry, at-60) || !CronDue(entry, at) {
+		t.Error("expression not evaluated in entry time zone")
+	}
+}
+
 func TestReadWriteCronEntries(t *testing.T) {
 	session := fmt.Sprintf("test-cron-rw-%d", rand.Int())
 	memDir := t.TempDir()
@@ -349,6 +389,119 @@ func TestSetCronEnabled(t *testing.T) {
 	}
 }
 
+func TestCronOnce_FiresOnceAndDisables(t *testing.T) {
+	session := fmt.Sprintf("test-cron-once-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	if _, err := AddCronEntry(session, CronEntry{Schedule: OnceSchedule, Target: "deploy", Action: "check", Message: "x"}); err == nil {
+		t.Error("expected error for one-shot entry without run time")
+	}
+
+	now := time.Now().Unix()
+	entry, err := AddCronEntry(session, CronEntry{Schedule: OnceSchedule, RunAt: now + 3600, Target: "deploy", Action: "check", Message: "check canary"})
+	if err != nil {
+		t.Fatalf("AddCronEntry: %v", err)
+	}
+	if CronDue(entry, now) {
+		t.Error("one-shot due before its run time")
+	}
+	if !CronDue(entry, now+3600) {
+		t.Error("one-shot not due at its run time")
+	}
+	if out := FormatCronList([]CronEntry{entry}, false); !strings.Contains(out, "pending") {
+		t.Errorf("expected pending status:\n%s", out)
+	}
+
+	if err := UpdateLastRun(session, entry.ID, now+3600); err != nil {
+		t.Fatalf("UpdateLastRun: %v", err)
+	}
+	entries, _ := ReadCronEntries(session)
+	if entries[0].Enabled {
+		t.Error("one-shot still enabled after firing")
+	}
+	entries[0].Enabled = true
+	if CronDue(entries[0], now+7200) {
+		t.Error("fired one-shot due again")
+	}
+	if out := FormatCronList(entries, true); !strings.Contains(out, "fired") {
+		t.Errorf("expected fired status:\n%s", out)
+	}
+}
+
+func TestCronMisfire(t *testing.T) {
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC).Unix()
+	// Last ran 09:00 Wednesday; watcher was down through Thursday and Friday 09:00.
+	last := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC).Unix()
+	base := CronEntry{Schedule: "0 9 * * *", Enabled: true, TZ: "UTC", LastRunTS: last}
+
+	tests := []struct {
+		policy        string
+		fires, missed int
+	}{
+		{"", 1, 2},
+		{MisfireFireOnce, 1, 2},
+		{MisfireFireAll, 2, 2},
+		{MisfireSkip, 0, 2},
+	}
+	for _, tt := range tests {
+		e := base
+		e.Misfire = tt.policy
+		fires, missed := CronMisfire(e, now)
+		if fires != tt.fires || missed != tt.missed {
+			t.Errorf("policy %q: fires=%d missed=%d, want %d/%d", tt.policy, fires, missed, tt.fires, tt.missed)
+		}
+	}
+
+	// On time: not a misfire regardless of policy
+	e := base
+	e.Misfire = MisfireSkip
+	onTime := time.Date(2026, 3, 5, 9, 0, 30, 0, time.UTC).Unix()
+	if fires, missed := CronMisfire(e, onTime); fires != 1 || missed != 0 {
+		t.Errorf("on-time run: fires=%d missed=%d", fires, missed)
+	}
+
+	// Interval entries count every elapsed interval
+	iv := CronEntry{Schedule: "@every 5m", Enabled: true, LastRunTS: now - 1800, Misfire: MisfireFireAll}
+	if fires, missed := CronMisfire(iv, now); fires != 6 || missed != 5 {
+		t.Errorf("interval: fires=%d missed=%d, want 6/5", fires, missed)
+	}
+}
+
+func TestAddCronEntry_InvalidMisfire(t *testing.T) {
+	session := fmt.Sprintf("test-cron-misfire-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	if _, err := AddCronEntry(session, CronEntry{Schedule: "@hourly", Target: "build", Action: "build", Misfire: "later"}); err == nil {
+		t.Error("expected error for unknown misfire policy")
+	}
+}
+
+func TestFormatCronHistory_Missed(t *testing.T) {
+	out := FormatCronHistory([]CronHistoryEntry{{CronID: "c1", TS: 1, Target: "build", Action: "build", Missed: 3}})
+	if !strings.Contains(out, "(skipped)") || !strings.Contains(out, "missed 3, fired 0") {
+		t.Errorf("history missing skip note:\n%s", out)
+	}
+}
+
+func TestParseRunAt(t *testing.T) {
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+	got, err := ParseRunAt("2026-07-01T09:00", time.UTC, now)
+	if err != nil || !got.Equal(time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseRunAt = %v, %v", got, err)
+	}
+	if got, err := ParseRunAt("2026-07-01T09:00:00+02:00", time.UTC, now); err != nil || got.UTC().Hour() != 7 {
+		t.Errorf("ParseRunAt with offset = %v, %v", got, err)
+	}
+	if _, err := ParseRunAt("2026-06-01T09:00", time.UTC, now); err == nil || !strings.Contains(err.Error(), "in the past") {
+		t.Errorf("expected past-time error, got %v", err)
+	}
+	if _, err := ParseRunAt("tomorrow", time.UTC, now); err == nil {
+		t.Error("expected error for unparseable time")
+	}
+}
+
 func TestSetCronEnabled_NotFound(t *testing.T) {
 	session := fmt.Sprintf("test-cron-ennf-%d", rand.Int())
 	memDir := t.TempDir()
@@ -523,6 +676,9 @@ func TestFormatCronList(t *testing.T) {
 	if !strings.Contains(out, "disabled") {
 		t.Error("expected 'disabled' status in output")
 	}
+	if !strings.Contains(out, "Next") {
+		t.Error("expected Next column in output")
+	}
 }
 
 func TestFormatCronList_Empty(t *testing.T) {
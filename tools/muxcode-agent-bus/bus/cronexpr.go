@@ -0,0 +1,182 @@
+package bus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpr is a parsed 5-field cron expression: minute hour day-of-month
+// month day-of-week. Each field is a bitmask of allowed values.
+type CronExpr struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // field starts with "*" (affects day matching)
+}
+
+// cronField describes the range and names accepted by one field.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDowNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+	cronFields = []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day-of-month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12, names: cronMonthNames},
+		{name: "day-of-week", min: 0, max: 7, names: cronDowNames}, // 7 = Sunday
+	}
+)
+
+// cronSearchLimit bounds the next-run search for expressions that can never
+// match (e.g. "0 0 31 2 *").
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// ParseCronExpr parses a standard 5-field cron expression. Each field
+// accepts "*", values, ranges ("1-5"), steps ("*/15", "0-30/10"), and
+// comma-separated lists. Month and day-of-week accept three-letter names.
+// As in Vixie cron, when both day fields are restricted a day matches if
+// either does.
+func ParseCronExpr(s string) (*CronExpr, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	var masks [5]uint64
+	for i, f := range fields {
+		m, err := parseCronField(strings.ToLower(f), cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = m
+	}
+	// Fold Sunday=7 into Sunday=0
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+
+	return &CronExpr{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses one comma-separated field into a bitmask.
+func parseCronField(s string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", f.name, part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+			if f.name == "day-of-week" {
+				hi = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field: %q", f.name, part)
+			}
+		default:
+			v, err := cronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// cronValue parses a single number or name within a field's range.
+func cronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s value %d out of range %d-%d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// dayMatches applies cron's day-of-month / day-of-week rule.
+func (e *CronExpr) dayMatches(t time.Time) bool {
+	domOK := e.dom&(1<<uint(t.Day())) != 0
+	dowOK := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domStar || e.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next returns the first matching minute strictly after t, evaluated in
+// t's location. Returns the zero time if nothing matches within five years.
+func (e *CronExpr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !e.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,88 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronExpr_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		errRe string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"60 * * * *", "out of range"},
+		{"* 24 * * *", "out of range"},
+		{"* * 0 * *", "out of range"},
+		{"* * * 13 *", "out of range"},
+		{"* * * * 8", "out of range"},
+		{"5-1 * * * *", "invalid range"},
+		{"*/0 * * * *", "invalid step"},
+		{"x * * * *", "invalid value"},
+		{"* * * foo *", "invalid value"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCronExpr(tt.input)
+		if err == nil {
+			t.Errorf("ParseCronExpr(%q): expected error containing %q", tt.input, tt.errRe)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.errRe) {
+			t.Errorf("ParseCronExpr(%q): error %q does not contain %q", tt.input, err, tt.errRe)
+		}
+	}
+}
+
+func TestCronExpr_Next(t *testing.T) {
+	// Wednesday 2026-03-04 10:17:30 UTC
+	from := time.Date(2026, 3, 4, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 3, 5, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * sun", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * *", time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0,45 10 * * *", time.Date(2026, 3, 4, 10, 45, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 13th is a Friday, 6th is sooner)
+		{"0 8 13 * fri", time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		e, err := ParseCronExpr(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCronExpr(%q): %v", tt.expr, err)
+		}
+		if got := e.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronExpr_NextNever(t *testing.T) {
+	e, err := ParseCronExpr("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Feb 31 matched %v", got)
+	}
+}
+
+func TestCronExpr_NextInLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	e, _ := ParseCronExpr("0 9 * * *")
+	from := time.Date(2026, 3, 4, 9, 30, 0, 0, loc)
+	want := time.Date(2026, 3, 5, 9, 0, 0, 0, loc)
+	if got := e.Next(from); !got.Equal(want) {
+		t.Errorf("Next in +05:30 = %v, want %v", got, want)
+	}
+}
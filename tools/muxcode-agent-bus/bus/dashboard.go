@@ -0,0 +1,87 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+)
+
+// DashboardPanes are the configurable dashboard sections, in default order.
+var DashboardPanes = []string{"agents", "bus", "teams", "messages", "tasks"}
+
+// DashboardThemes are the dashboard color themes. "dracula" is the default;
+// "basic" uses the 16 standard ANSI colors; "mono" draws plain ASCII with no
+// color, for limited terminals and screen readers.
+var DashboardThemes = []string{"dracula", "basic", "mono"}
+
+// DashboardConfig is the "dashboard" section of muxcode.json.
+type DashboardConfig struct {
+	Theme   string   `json:"theme,omitempty"`   // one of DashboardThemes (default "dracula")
+	Panes   []string `json:"panes,omitempty"`   // pane order; unlisted panes follow in default order
+	Hidden  []string `json:"hidden,omitempty"`  // panes not shown
+	Refresh int      `json:"refresh,omitempty"` // refresh interval in seconds (default 5)
+	Compact bool     `json:"compact,omitempty"` // fold section titles into separators
+}
+
+// Validate checks theme and pane names.
+func (c DashboardConfig) Validate() error {
+	if c.Theme != "" && !containsRole(DashboardThemes, c.Theme) {
+		return fmt.Errorf("unknown dashboard theme %q (want one of %v)", c.Theme, DashboardThemes)
+	}
+	for _, p := range append(append([]string{}, c.Panes...), c.Hidden...) {
+		if !containsRole(DashboardPanes, p) {
+			return fmt.Errorf("unknown dashboard pane %q (want one of %v)", p, DashboardPanes)
+		}
+	}
+	if c.Refresh < 0 {
+		return fmt.Errorf("dashboard refresh must be positive")
+	}
+	return nil
+}
+
+// ResolveTheme returns the configured theme, honoring NO_COLOR
+// (https://no-color.org) by falling back to "mono".
+func (c DashboardConfig) ResolveTheme() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	if c.Theme == "" || !containsRole(DashboardThemes, c.Theme) {
+		return "dracula"
+	}
+	return c.Theme
+}
+
+// ResolvePanes returns the panes to draw, in order: configured panes first,
+// then the remaining defaults, minus hidden ones. Unknown names are dropped.
+func (c DashboardConfig) ResolvePanes() []string {
+	var order []string
+	for _, p := range append(append([]string{}, c.Panes...), DashboardPanes...) {
+		if containsRole(DashboardPanes, p) && !containsRole(order, p) {
+			order = append(order, p)
+		}
+	}
+	var panes []string
+	for _, p := range order {
+		if !containsRole(c.Hidden, p) {
+			panes = append(panes, p)
+		}
+	}
+	return panes
+}
+
+// mergeDashboard returns base with every set field of override applied.
+func mergeDashboard(base, override DashboardConfig) DashboardConfig {
+	if override.Theme != "" {
+		base.Theme = override.Theme
+	}
+	if len(override.Panes) > 0 {
+		base.Panes = override.Panes
+	}
+	if len(override.Hidden) > 0 {
+		base.Hidden = override.Hidden
+	}
+	if override.Refresh > 0 {
+		base.Refresh = override.Refresh
+	}
+	base.Compact = base.Compact || override.Compact
+	return base
+}
@@ -0,0 +1,55 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDashboardConfig_ResolvePanes(t *testing.T) {
+	tests := []struct {
+		cfg  DashboardConfig
+		want string
+	}{
+		{DashboardConfig{}, "agents bus teams messages tasks"},
+		{DashboardConfig{Panes: []string{"messages", "agents"}}, "messages agents bus teams tasks"},
+		{DashboardConfig{Hidden: []string{"teams"}}, "agents bus messages tasks"},
+		{DashboardConfig{Panes: []string{"bus", "bogus", "bus"}, Hidden: []string{"agents"}}, "bus teams messages tasks"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.cfg.ResolvePanes(), " "); got != tt.want {
+			t.Errorf("ResolvePanes(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestDashboardConfig_ValidateAndTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if err := (DashboardConfig{Theme: "mono", Panes: []string{"bus"}, Refresh: 2}).Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, bad := range []DashboardConfig{{Theme: "solarized"}, {Hidden: []string{"log"}}, {Refresh: -1}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", bad)
+		}
+	}
+
+	if got := (DashboardConfig{Theme: "solarized"}).ResolveTheme(); got != "dracula" {
+		t.Errorf("unknown theme resolved to %q", got)
+	}
+	if got := (DashboardConfig{Theme: "basic"}).ResolveTheme(); got != "basic" {
+		t.Errorf("basic theme resolved to %q", got)
+	}
+	t.Setenv("NO_COLOR", "1")
+	if got := (DashboardConfig{Theme: "basic"}).ResolveTheme(); got != "mono" {
+		t.Errorf("NO_COLOR theme = %q, want mono", got)
+	}
+}
+
+func TestMergeConfigs_Dashboard(t *testing.T) {
+	base := &MuxcodeConfig{Dashboard: DashboardConfig{Theme: "basic", Refresh: 3, Hidden: []string{"teams"}}}
+	override := &MuxcodeConfig{Dashboard: DashboardConfig{Refresh: 10, Compact: true}}
+	got := mergeConfigs(base, override).Dashboard
+	if got.Theme != "basic" || got.Refresh != 10 || !got.Compact || len(got.Hidden) != 1 {
+		t.Errorf("merged dashboard = %+v", got)
+	}
+}
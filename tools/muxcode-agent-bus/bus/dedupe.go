@@ -0,0 +1,286 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultDedupeThreshold is the token-set (Jaccard) similarity at which two
+// memory entries are considered duplicates.
+const DefaultDedupeThreshold = 0.7
+
+// DedupeOptions controls memory deduplication.
+type DedupeOptions struct {
+	Role      string  // limit to one role (empty = all roles)
+	Threshold float64 // similarity threshold (0 = DefaultDedupeThreshold)
+	DryRun    bool    // report without rewriting memory
+}
+
+// DedupeCluster is a group of near-identical entries merged into one.
+type DedupeCluster struct {
+	Role    string
+	Kept    MemoryEntry   // merged entry: earliest timestamp, fullest content
+	Removed []MemoryEntry // duplicates dropped
+}
+
+// DedupeResult summarizes a deduplication run.
+type DedupeResult struct {
+	Clusters    []DedupeCluster
+	Removed     int
+	BytesBefore int
+	BytesAfter  int
+}
+
+// Reclaimed returns the bytes saved by the merge.
+func (r DedupeResult) Reclaimed() int {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// memoryDoc is one memory file (or the role's store stream) split into its
+// preamble and entries so it can be rebuilt after merging.
+type memoryDoc struct {
+	path     string // file path; empty for the store stream
+	original string
+	preamble string
+	entries  []MemoryEntry
+	changed  bool
+}
+
+// newMemoryDoc parses memory content, keeping any text before the first
+// section (e.g. a "# Shared Memory" title).
+func newMemoryDoc(path, content, role string) *memoryDoc {
+	preamble := ""
+	if !strings.HasPrefix(content, "## ") {
+		if idx := strings.Index(content, "\n## "); idx >= 0 {
+			preamble = content[:idx]
+		} else {
+			preamble = content
+		}
+	}
+	return &memoryDoc{path: path, original: content, preamble: preamble, entries: ParseMemoryEntries(content, role)}
+}
+
+// render rebuilds the document in AppendMemory's format.
+func (d *memoryDoc) render() string {
+	var b strings.Builder
+	b.WriteString(d.preamble)
+	for _, e := range d.entries {
+		b.WriteString(formatMemoryChunk(e.Section, e.Timestamp, e.Content))
+	}
+	return b.String()
+}
+
+// roleMemoryDocs loads a role's memory in chronological order: archives
+// oldest first, then the active file (or the whole store stream).
+func roleMemoryDocs(s Store, role string) ([]*memoryDoc, error) {
+	if s != nil {
+		content, err := readMemoryStore(s, role, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []*memoryDoc{newMemoryDoc("", content, role)}, nil
+	}
+
+	var docs []*memoryDoc
+	dates, err := ListArchiveDates(role)
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range dates {
+		path := MemoryArchivePath(role, date)
+		data, err := readBusFile(path)
+		if err != nil {
+			continue
+		}
+		docs = append(docs, newMemoryDoc(path, string(data), role))
+	}
+	data, err := readBusFile(MemoryPath(role))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		docs = append(docs, newMemoryDoc(MemoryPath(role), string(data), role))
+	}
+	return docs, nil
+}
+
+// DedupeMemory clusters each role's memory entries by token similarity and
+// merges every cluster into its earliest entry, keeping the longest content
+// among the duplicates. Archives and the active file are considered
+// together, so a note re-learned every day collapses to its first sighting.
+func DedupeMemory(opts DedupeOptions) (DedupeResult, error) {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultDedupeThreshold
+	}
+
+	roles := []string{opts.Role}
+	if opts.Role == "" {
+		var err error
+		if roles, err = ListMemoryRoles(); err != nil {
+			return DedupeResult{}, err
+		}
+	}
+
+	store := ActiveStore()
+	var result DedupeResult
+	for _, role := range roles {
+		docs, err := roleMemoryDocs(store, role)
+		if err != nil {
+			return result, err
+		}
+		clusters := dedupeDocs(docs, threshold)
+		if len(clusters) == 0 {
+			continue
+		}
+		for _, c := range clusters {
+			c.Role = role
+			result.Clusters = append(result.Clusters, c)
+			result.Removed += len(c.Removed)
+		}
+		for _, d := range docs {
+			if !d.changed {
+				continue
+			}
+			rendered := d.render()
+			result.BytesBefore += len(d.original)
+			result.BytesAfter += len(rendered)
+			if opts.DryRun {
+				continue
+			}
+			if err := writeMemoryDoc(store, role, d, rendered); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// dedupeEntry locates an entry within the loaded documents.
+type dedupeEntry struct {
+	doc    *memoryDoc
+	index  int
+	tokens map[string]bool
+}
+
+// dedupeDocs clusters entries across docs, rewrites the earliest entry of
+// each cluster in place, and drops the rest. Marks modified docs changed.
+func dedupeDocs(docs []*memoryDoc, threshold float64) []DedupeCluster {
+	var groups [][]dedupeEntry
+	for _, d := range docs {
+		for i, e := range d.entries {
+			cur := dedupeEntry{doc: d, index: i, tokens: payloadTokens(e.Section + " " + e.Content)}
+			placed := false
+			for g := range groups {
+				if payloadSimilarity(groups[g][0].tokens, cur.tokens) >= threshold {
+					groups[g] = append(groups[g], cur)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				groups = append(groups, []dedupeEntry{cur})
+			}
+		}
+	}
+
+	drop := make(map[*memoryDoc]map[int]bool)
+	var clusters []DedupeCluster
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		// Earliest timestamp wins; docs are loaded oldest first, so ties
+		// and missing timestamps fall back to file order.
+		first := 0
+		for i := 1; i < len(g); i++ {
+			ti, tf := g[i].doc.entries[g[i].index].Timestamp, g[first].doc.entries[g[first].index].Timestamp
+			if ti != "" && (tf == "" || ti < tf) {
+				first = i
+			}
+		}
+		keep := g[first]
+		merged := keep.doc.entries[keep.index]
+
+		var removed []MemoryEntry
+		for i, e := range g {
+			entry := e.doc.entries[e.index]
+			if len(strings.TrimSpace(entry.Content)) > len(strings.TrimSpace(merged.Content)) {
+				merged.Content = entry.Content
+			}
+			if i == first {
+				continue
+			}
+			removed = append(removed, entry)
+			if drop[e.doc] == nil {
+				drop[e.doc] = make(map[int]bool)
+			}
+			drop[e.doc][e.index] = true
+			e.doc.changed = true
+		}
+		if merged.Content != keep.doc.entries[keep.index].Content {
+			keep.doc.entries[keep.index] = merged
+			keep.doc.changed = true
+		}
+		clusters = append(clusters, DedupeCluster{Kept: merged, Removed: removed})
+	}
+
+	for d, idx := range drop {
+		var kept []MemoryEntry
+		for i, e := range d.entries {
+			if !idx[i] {
+				kept = append(kept, e)
+			}
+		}
+		d.entries = kept
+	}
+	return clusters
+}
+
+// writeMemoryDoc persists a rebuilt document. Emptied archives are removed;
+// store streams are rewritten record by record.
+func writeMemoryDoc(s Store, role string, d *memoryDoc, rendered string) error {
+	if d.path == "" {
+		stream := memoryStream(role)
+		if err := s.Trim(stream, 0); err != nil {
+			return err
+		}
+		for _, e := range d.entries {
+			ts := int64(0)
+			if t, err := time.ParseInLocation("2006-01-02 15:04", e.Timestamp, time.Local); err == nil {
+				ts = t.Unix()
+			}
+			if err := appendMemoryRecord(s, role, ts, formatMemoryChunk(e.Section, e.Timestamp, e.Content)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if strings.TrimSpace(rendered) == "" && d.path != MemoryPath(role) {
+		return os.Remove(d.path)
+	}
+	return writeBusFile(d.path, []byte(rendered))
+}
+
+// FormatDedupeResult formats a deduplication report.
+func FormatDedupeResult(r DedupeResult, dryRun bool) string {
+	var b strings.Builder
+	if len(r.Clusters) == 0 {
+		b.WriteString("No duplicate memory entries found.\n")
+		return b.String()
+	}
+	for _, c := range r.Clusters {
+		fmt.Fprintf(&b, "[%s] %s (%s) — merged %d duplicate(s)\n", c.Role, c.Kept.Section, c.Kept.Timestamp, len(c.Removed))
+		for _, e := range c.Removed {
+			fmt.Fprintf(&b, "    - %s (%s)\n", e.Section, e.Timestamp)
+		}
+	}
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	fmt.Fprintf(&b, "\n%s %d entries in %d clusters, reclaiming %d bytes.\n", verb, r.Removed, len(r.Clusters), r.Reclaimed())
+	return b.String()
+}
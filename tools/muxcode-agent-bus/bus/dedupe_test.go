@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDedupeMemory_AcrossArchives(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	t.Setenv("MUXCODE_STORE", "")
+
+	archive := "# Build Memory\n" +
+		formatMemoryChunk("Build Config", "2026-01-01 09:00", "use pnpm for builds") +
+		formatMemoryChunk("Lint", "2026-01-01 10:00", "run eslint before commit")
+	if err := os.MkdirAll(MemoryArchiveDir("build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(MemoryArchivePath("build", "2026-01-01"), []byte(archive), 0644); err != nil {
+		t.Fatal(err)
+	}
+	active := formatMemoryChunk("Build Config", "2026-01-02 09:00", "always use pnpm for builds") +
+		formatMemoryChunk("Cache", "2026-01-02 11:00", "clear turbo cache when stale")
+	if err := os.WriteFile(MemoryPath("build"), []byte(active), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := DedupeMemory(DedupeOptions{Role: "build", DryRun: true})
+	if err != nil {
+		t.Fatalf("DedupeMemory dry run: %v", err)
+	}
+	if dry.Removed != 1 || dry.Reclaimed() <= 0 {
+		t.Fatalf("dry run removed %d, reclaimed %d", dry.Removed, dry.Reclaimed())
+	}
+	if data, _ := os.ReadFile(MemoryPath("build")); string(data) != active {
+		t.Error("dry run modified the active file")
+	}
+
+	result, err := DedupeMemory(DedupeOptions{Role: "build"})
+	if err != nil {
+		t.Fatalf("DedupeMemory: %v", err)
+	}
+	if len(result.Clusters) != 1 {
+		t.Fatalf("clusters = %d, want 1", len(result.Clusters))
+	}
+	kept := result.Clusters[0].Kept
+	if kept.Timestamp != "2026-01-01 09:00" || kept.Content != "always use pnpm for builds" {
+		t.Errorf("kept = %+v, want earliest timestamp with fullest content", kept)
+	}
+
+	archived, _ := os.ReadFile(MemoryArchivePath("build", "2026-01-01"))
+	if !strings.HasPrefix(string(archived), "# Build Memory\n") || !strings.Contains(string(archived), "always use pnpm") {
+		t.Errorf("archive not merged in place:\n%s", archived)
+	}
+	current, _ := os.ReadFile(MemoryPath("build"))
+	if strings.Contains(string(current), "Build Config") || !strings.Contains(string(current), "Cache") {
+		t.Errorf("active file after dedupe:\n%s", current)
+	}
+
+	entries, _ := AllMemoryEntries()
+	if len(entries) != 3 {
+		t.Errorf("entries after dedupe = %d, want 3", len(entries))
+	}
+
+	again, _ := DedupeMemory(DedupeOptions{Role: "build"})
+	if again.Removed != 0 {
+		t.Errorf("second run removed %d, want 0", again.Removed)
+	}
+}
+
+func TestDedupeMemory_Threshold(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	t.Setenv("MUXCODE_STORE", "")
+
+	_ = AppendMemory("Deploy", "deploy with cdk to staging", "deploy")
+	_ = AppendMemory("Deploy", "deploy with cdk to production", "deploy")
+
+	strict, _ := DedupeMemory(DedupeOptions{Threshold: 0.95, DryRun: true})
+	if strict.Removed != 0 {
+		t.Errorf("strict threshold merged %d entries", strict.Removed)
+	}
+	loose, _ := DedupeMemory(DedupeOptions{Threshold: 0.5, DryRun: true})
+	if loose.Removed != 1 {
+		t.Errorf("loose threshold merged %d entries, want 1", loose.Removed)
+	}
+}
+
+func TestDedupeMemory_Store(t *testing.T) {
+	sqliteTestStore(t)
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	_ = AppendMemory("Tests", "run jest with --runInBand", "test")
+	_ = AppendMemory("Tests", "run jest with --runInBand", "test")
+	_ = AppendMemory("Coverage", "coverage threshold is 80 percent", "test")
+
+	result, err := DedupeMemory(DedupeOptions{})
+	if err != nil {
+		t.Fatalf("DedupeMemory: %v", err)
+	}
+	if result.Removed != 1 {
+		t.Errorf("removed %d, want 1", result.Removed)
+	}
+	entries, _ := AllMemoryEntries()
+	if len(entries) != 2 {
+		t.Errorf("store entries after dedupe = %d, want 2", len(entries))
+	}
+}
+
+func TestFormatDedupeResult(t *testing.T) {
+	if out := FormatDedupeResult(DedupeResult{}, false); !strings.Contains(out, "No duplicate") {
+		t.Errorf("empty result = %q", out)
+	}
+	r := DedupeResult{
+		Clusters:    []DedupeCluster{{Role: "build", Kept: MemoryEntry{Section: "A"}, Removed: []MemoryEntry{{Section: "A"}}}},
+		Removed:     1,
+		BytesBefore: 100,
+		BytesAfter:  60,
+	}
+	if out := FormatDedupeResult(r, true); !strings.Contains(out, "Would remove 1 entries in 1 clusters, reclaiming 40 bytes") {
+		t.Errorf("dry-run report = %q", out)
+	}
+}
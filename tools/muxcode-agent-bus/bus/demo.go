@@ -321,8 +321,13 @@ func BuildTestReviewScenario() DemoScenario {
 }
 
 // GetScenario returns a scenario by name, or an error if not found.
+// Built-in scenarios are checked first, then files in DemoDir(). A name
+// ending in .json is loaded as a path.
 func GetScenario(name string) (DemoScenario, error) {
-	for _, s := range BuiltinScenarios() {
+	if strings.HasSuffix(name, ".json") {
+		return LoadDemoScenario(name)
+	}
+	for _, s := range AllScenarios() {
 		if s.Name == name {
 			return s, nil
 		}
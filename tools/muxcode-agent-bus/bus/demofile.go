@@ -0,0 +1,346 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DemoDir returns the project-local demo scenario directory.
+// Uses BUS_DEMO_DIR env if set, otherwise defaults to ".muxcode/demos".
+func DemoDir() string {
+	if v := os.Getenv("BUS_DEMO_DIR"); v != "" {
+		return v
+	}
+	return filepath.Join(".muxcode", "demos")
+}
+
+// demoStepJSON is the on-disk form of a DemoStep. Delays are stored in
+// milliseconds so scenario files stay hand-editable.
+type demoStepJSON struct {
+	Description string `json:"description"`
+	Window      string `json:"window,omitempty"`
+	Action      string `json:"action"`
+	Role        string `json:"role,omitempty"`
+	BusAction   string `json:"bus_action,omitempty"`
+	MsgType     string `json:"type,omitempty"`
+	Payload     string `json:"payload,omitempty"`
+	DelayMS     int64  `json:"delay_ms,omitempty"`
+}
+
+type demoScenarioJSON struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Steps       []demoStepJSON `json:"steps"`
+}
+
+// MarshalDemoScenario encodes a scenario as indented JSON.
+func MarshalDemoScenario(s DemoScenario) ([]byte, error) {
+	out := demoScenarioJSON{Name: s.Name, Description: s.Description}
+	for _, st := range s.Steps {
+		out.Steps = append(out.Steps, demoStepJSON{
+			Description: st.Description,
+			Window:      st.Window,
+			Action:      st.Action,
+			Role:        st.Role,
+			BusAction:   st.BusAction,
+			MsgType:     st.MsgType,
+			Payload:     st.Payload,
+			DelayMS:     st.DelayAfter.Milliseconds(),
+		})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// LoadDemoScenario reads a scenario JSON file.
+func LoadDemoScenario(path string) (DemoScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DemoScenario{}, err
+	}
+	var in demoScenarioJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return DemoScenario{}, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if in.Name == "" {
+		in.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	s := DemoScenario{Name: in.Name, Description: in.Description}
+	for _, st := range in.Steps {
+		s.Steps = append(s.Steps, DemoStep{
+			Description: st.Description,
+			Window:      st.Window,
+			Action:      st.Action,
+			Role:        st.Role,
+			BusAction:   st.BusAction,
+			MsgType:     st.MsgType,
+			Payload:     st.Payload,
+			DelayAfter:  time.Duration(st.DelayMS) * time.Millisecond,
+		})
+	}
+	return s, nil
+}
+
+// FileScenarios loads every *.json scenario in DemoDir(), sorted by name.
+// Unreadable files are skipped.
+func FileScenarios() []DemoScenario {
+	paths, _ := filepath.Glob(filepath.Join(DemoDir(), "*.json"))
+	sort.Strings(paths)
+	var scenarios []DemoScenario
+	for _, p := range paths {
+		if s, err := LoadDemoScenario(p); err == nil {
+			scenarios = append(scenarios, s)
+		}
+	}
+	return scenarios
+}
+
+// AllScenarios returns built-in scenarios followed by file scenarios.
+func AllScenarios() []DemoScenario {
+	return append(BuiltinScenarios(), FileScenarios()...)
+}
+
+// --- Session export ---
+
+// DemoExportOptions controls how a session slice becomes a scenario.
+type DemoExportOptions struct {
+	Session   string        // source session
+	Window    time.Duration // how far back from Until to export (0 = everything)
+	Until     time.Time     // end of the slice (zero = now)
+	Name      string        // scenario name
+	Anonymize bool          // scrub paths, URLs, emails, and secrets from payloads
+	MaxDelay  time.Duration // cap on replayed gaps between messages (0 = 5s)
+}
+
+// DemoExport is a scenario built from a real session plus its narrative.
+type DemoExport struct {
+	Scenario  DemoScenario
+	Narrative string
+	Messages  int
+}
+
+// defaultExportMaxDelay caps replayed idle time so a two-hour session does
+// not take two hours to watch.
+const defaultExportMaxDelay = 5 * time.Second
+
+// ExportSessionDemo converts the messages and command outcomes in a session
+// slice into a replayable scenario and an annotated markdown narrative.
+// Replayed messages are sent from "demo" like built-in scenarios; the
+// original sender is kept in each step description.
+func ExportSessionDemo(opts DemoExportOptions) (DemoExport, error) {
+	until := opts.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since := int64(0)
+	if opts.Window > 0 {
+		since = until.Add(-opts.Window).Unix()
+	}
+	maxDelay := opts.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultExportMaxDelay
+	}
+
+	all, err := readMessages(LogPath(opts.Session))
+	if err != nil {
+		return DemoExport{}, fmt.Errorf("reading session log: %v", err)
+	}
+	var msgs []Message
+	for _, m := range all {
+		if m.TS < since || m.TS > until.Unix() || isSystemAction(m.Action) {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	if len(msgs) == 0 {
+		return DemoExport{}, fmt.Errorf("no messages in session %s for the selected window", opts.Session)
+	}
+
+	scrub := func(s string) string { return s }
+	if opts.Anonymize {
+		scrub = anonymizeText
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = "session-" + opts.Session + "-" + time.Unix(msgs[0].TS, 0).Format("20060102-1504")
+	}
+	start, end := msgs[0].TS, msgs[len(msgs)-1].TS
+
+	scenario := DemoScenario{
+		Name:        name,
+		Description: fmt.Sprintf("Replay of session %s (%d messages, %s)", opts.Session, len(msgs), time.Duration(end-start)*time.Second),
+	}
+	window := ""
+	for i, m := range msgs {
+		delay := time.Duration(0)
+		if i+1 < len(msgs) {
+			delay = time.Duration(msgs[i+1].TS-m.TS) * time.Second
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+		}
+		if m.To != window {
+			window = m.To
+			scenario.Steps = append(scenario.Steps, DemoStep{
+				Description: "Switch to " + m.To + " window",
+				Window:      m.To,
+				Action:      "select-window",
+				DelayAfter:  500 * time.Millisecond,
+			})
+		}
+		scenario.Steps = append(scenario.Steps, DemoStep{
+			Description: fmt.Sprintf("%s → %s: %s (%s)", m.From, m.To, m.Action, m.Type),
+			Role:        m.To,
+			Action:      "send",
+			BusAction:   m.Action,
+			MsgType:     m.Type,
+			Payload:     scrub(m.Payload),
+			DelayAfter:  delay,
+		})
+	}
+
+	// Command outcomes from role history within the same slice
+	var outcomes []demoOutcome
+	for _, role := range KnownRoles {
+		for _, h := range ReadHistory(opts.Session, role, 0) {
+			if h.TS < start || h.TS > end {
+				continue
+			}
+			outcomes = append(outcomes, demoOutcome{Role: role, Entry: h})
+		}
+	}
+	sort.SliceStable(outcomes, func(i, j int) bool { return outcomes[i].Entry.TS < outcomes[j].Entry.TS })
+
+	return DemoExport{
+		Scenario:  scenario,
+		Narrative: formatDemoNarrative(scenario, opts.Session, msgs, outcomes, scrub),
+		Messages:  len(msgs),
+	}, nil
+}
+
+// demoOutcome is a command result shown in the narrative.
+type demoOutcome struct {
+	Role  string
+	Entry HistoryEntry
+}
+
+// formatDemoNarrative renders the exported session as a markdown walkthrough.
+func formatDemoNarrative(s DemoScenario, session string, msgs []Message, outcomes []demoOutcome, scrub func(string) string) string {
+	start := msgs[0].TS
+	elapsed := func(ts int64) string {
+		d := time.Duration(ts-start) * time.Second
+		return fmt.Sprintf("+%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+	}
+
+	roles := make(map[string]bool)
+	for _, m := range msgs {
+		roles[m.From] = true
+		roles[m.To] = true
+	}
+	var roleList []string
+	for r := range roles {
+		roleList = append(roleList, r)
+	}
+	sort.Strings(roleList)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", s.Name)
+	fmt.Fprintf(&b, "%s.\n\n", s.Description)
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session)
+	fmt.Fprintf(&b, "- **Started:** %s\n", time.Unix(start, 0).Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- **Agents:** %s\n", strings.Join(roleList, ", "))
+	fmt.Fprintf(&b, "- **Replay:** `muxcode-agent-bus demo run %s`\n\n", s.Name)
+
+	b.WriteString("## Timeline\n\n")
+	oi := 0
+	for _, m := range msgs {
+		for oi < len(outcomes) && outcomes[oi].Entry.TS <= m.TS {
+			writeDemoOutcome(&b, outcomes[oi], elapsed, scrub)
+			oi++
+		}
+		fmt.Fprintf(&b, "- **%s** `%s` → `%s` — %s _%s_", elapsed(m.TS), m.From, m.To, m.Type, m.Action)
+		if p := strings.TrimSpace(scrub(m.Payload)); p != "" {
+			fmt.Fprintf(&b, "\n  > %s", strings.ReplaceAll(p, "\n", "\n  > "))
+		}
+		b.WriteString("\n")
+	}
+	for ; oi < len(outcomes); oi++ {
+		writeDemoOutcome(&b, outcomes[oi], elapsed, scrub)
+	}
+
+	if len(outcomes) > 0 {
+		pass, fail := 0, 0
+		for _, o := range outcomes {
+			if o.Entry.Outcome == "failure" {
+				fail++
+			} else {
+				pass++
+			}
+		}
+		fmt.Fprintf(&b, "\n## Outcomes\n\n%d commands succeeded, %d failed.\n", pass, fail)
+	}
+	return b.String()
+}
+
+// writeDemoOutcome writes one command result line of the timeline.
+func writeDemoOutcome(b *strings.Builder, o demoOutcome, elapsed func(int64) string, scrub func(string) string) {
+	mark := "✓"
+	if o.Entry.Outcome == "failure" {
+		mark = "✗"
+	}
+	cmd := o.Entry.Command
+	if cmd == "" {
+		cmd = o.Entry.Summary
+	}
+	fmt.Fprintf(b, "- **%s** %s `%s` ran `%s` (exit %s)\n", elapsed(o.Entry.TS), mark, o.Role, scrub(cmd), o.Entry.ExitCode)
+}
+
+var (
+	anonEmailRe  = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+	anonURLRe    = regexp.MustCompile(`\bhttps?://[^\s)\]>"']+`)
+	anonSecretRe = regexp.MustCompile(`\b([A-Za-z0-9_\-]{32,}|[0-9a-f]{16,})\b`)
+	anonHomeRe   = regexp.MustCompile(`(/(?:Users|home)/)[^/\s]+`)
+	anonIPRe     = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`)
+)
+
+// anonymizeText scrubs identifying details from a payload: emails, URLs,
+// IP addresses, home-directory usernames, and long tokens or hashes.
+func anonymizeText(s string) string {
+	s = anonURLRe.ReplaceAllString(s, "<url>")
+	s = anonEmailRe.ReplaceAllString(s, "<email>")
+	s = anonIPRe.ReplaceAllString(s, "<ip>")
+	s = anonHomeRe.ReplaceAllString(s, "${1}<user>")
+	s = anonSecretRe.ReplaceAllString(s, "<redacted>")
+	return s
+}
+
+// WriteDemoExport writes <name>.json and <name>.md into dir and returns
+// both paths.
+func WriteDemoExport(dir string, export DemoExport) (string, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	data, err := MarshalDemoScenario(export.Scenario)
+	if err != nil {
+		return "", "", err
+	}
+	jsonPath := filepath.Join(dir, export.Scenario.Name+".json")
+	mdPath := filepath.Join(dir, export.Scenario.Name+".md")
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(mdPath, []byte(export.Narrative), 0644); err != nil {
+		return "", "", err
+	}
+	return jsonPath, mdPath, nil
+}
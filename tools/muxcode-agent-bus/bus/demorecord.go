@@ -0,0 +1,355 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DemoEvent is one thing captured by "demo record": a message sent on the
+// bus, an agent notification, or a command outcome. The first event of a
+// recording has kind "start".
+type DemoEvent struct {
+	OffsetMS int64         `json:"offset_ms"` // since the recording started
+	Kind     string        `json:"kind"`      // "start", "message", "notify", "outcome"
+	Role     string        `json:"role,omitempty"`
+	Session  string        `json:"session,omitempty"` // start only
+	Started  int64         `json:"started,omitempty"` // start only
+	Message  *Message      `json:"message,omitempty"`
+	Outcome  *HistoryEntry `json:"outcome,omitempty"`
+}
+
+// DemoRecording is a captured session slice that "demo replay" re-injects.
+type DemoRecording struct {
+	Name    string
+	Session string
+	Started int64
+	Events  []DemoEvent // excluding the start event
+}
+
+// DemoRecordingPath returns the recording file for name in DemoDir(). A
+// name that already ends in .rec.jsonl is used as a path.
+func DemoRecordingPath(name string) string {
+	if strings.HasSuffix(name, ".rec.jsonl") {
+		return name
+	}
+	return filepath.Join(DemoDir(), name+".rec.jsonl")
+}
+
+// DemoRecorder captures bus traffic, notifications, and command outcomes
+// into a recording file. Call Poll periodically and Close when done. Each
+// event is written as soon as it is captured, so an interrupted recording
+// keeps everything up to its last poll.
+type DemoRecorder struct {
+	session   string
+	start     time.Time
+	f         *os.File
+	logOffset int64
+	notified  map[string]time.Time    // role -> last notify marker mtime
+	history   map[string]HistoryEntry // role -> last history entry seen
+	Count     int                     // events captured
+}
+
+// NewDemoRecorder starts a recording of session to DemoRecordingPath(name).
+// Only activity after the call is captured.
+func NewDemoRecorder(session, name string) (*DemoRecorder, error) {
+	path := DemoRecordingPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &DemoRecorder{
+		session:  session,
+		start:    time.Now(),
+		f:        f,
+		notified: make(map[string]time.Time),
+		history:  make(map[string]HistoryEntry),
+	}
+	if info, err := os.Stat(LogPath(session)); err == nil {
+		r.logOffset = info.Size()
+	}
+	for _, role := range KnownRoles {
+		if info, err := os.Stat(notifiedSizePath(session, role)); err == nil {
+			r.notified[role] = info.ModTime()
+		}
+		if h := ReadHistory(session, role, 1); len(h) > 0 {
+			r.history[role] = h[len(h)-1]
+		}
+	}
+	if err := r.write(DemoEvent{Kind: "start", Session: session, Started: r.start.Unix()}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the recording file.
+func (r *DemoRecorder) Path() string {
+	return r.f.Name()
+}
+
+// write appends one event to the recording.
+func (r *DemoRecorder) write(ev DemoEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = r.f.Write(append(data, '\n'))
+	return err
+}
+
+// capture stamps and writes an event.
+func (r *DemoRecorder) capture(ev DemoEvent) error {
+	ev.OffsetMS = time.Since(r.start).Milliseconds()
+	if err := r.write(ev); err != nil {
+		return err
+	}
+	r.Count++
+	return nil
+}
+
+// Poll captures everything that happened since the previous poll and
+// returns the number of new events.
+func (r *DemoRecorder) Poll() (int, error) {
+	before := r.Count
+
+	// Messages appended to the session log
+	if data, err := os.ReadFile(LogPath(r.session)); err == nil && int64(len(data)) > r.logOffset {
+		chunk := data[r.logOffset:]
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			chunk = chunk[:i+1]
+			r.logOffset += int64(len(chunk))
+			scanner := bufio.NewScanner(bytes.NewReader(chunk))
+			scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+			for scanner.Scan() {
+				m, err := DecodeMessage(scanner.Bytes())
+				if err != nil {
+					continue
+				}
+				m = MigrateMessage(m)
+				if err := r.capture(DemoEvent{Kind: "message", Role: m.To, Message: &m}); err != nil {
+					return r.Count - before, err
+				}
+			}
+		}
+	}
+
+	for _, role := range KnownRoles {
+		// Notifications update the role's notified-size marker
+		if info, err := os.Stat(notifiedSizePath(r.session, role)); err == nil && info.ModTime().After(r.notified[role]) {
+			r.notified[role] = info.ModTime()
+			if err := r.capture(DemoEvent{Kind: "notify", Role: role}); err != nil {
+				return r.Count - before, err
+			}
+		}
+
+		// Command outcomes appended to the role's history
+		for _, h := range r.newHistory(role) {
+			h := h
+			if err := r.capture(DemoEvent{Kind: "outcome", Role: role, Outcome: &h}); err != nil {
+				return r.Count - before, err
+			}
+		}
+	}
+	return r.Count - before, nil
+}
+
+// newHistory returns the role's history entries after the last one seen.
+func (r *DemoRecorder) newHistory(role string) []HistoryEntry {
+	all := ReadHistory(r.session, role, 0)
+	if len(all) == 0 {
+		return nil
+	}
+	last, seen := r.history[role]
+	from := 0
+	if seen {
+		from = -1
+		for i := len(all) - 1; i >= 0; i-- {
+			if all[i] == last {
+				from = i + 1
+				break
+			}
+		}
+		if from < 0 {
+			// The last seen entry was trimmed away; fall back to timestamps
+			from = sort.Search(len(all), func(i int) bool { return all[i].TS > last.TS })
+		}
+	}
+	r.history[role] = all[len(all)-1]
+	return all[from:]
+}
+
+// Close captures any remaining activity and closes the recording.
+func (r *DemoRecorder) Close() error {
+	_, err := r.Poll()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// LoadDemoRecording reads a recording by name or path.
+func LoadDemoRecording(name string) (DemoRecording, error) {
+	path := DemoRecordingPath(name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DemoRecording{}, err
+	}
+	rec := DemoRecording{Name: strings.TrimSuffix(filepath.Base(path), ".rec.jsonl")}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev DemoEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue // skip malformed lines
+		}
+		if ev.Kind == "start" {
+			rec.Session, rec.Started = ev.Session, ev.Started
+			continue
+		}
+		rec.Events = append(rec.Events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return rec, err
+	}
+	if rec.Started == 0 {
+		return rec, fmt.Errorf("%s is not a demo recording", path)
+	}
+	return rec, nil
+}
+
+// Duration returns the time span of the recording.
+func (rec DemoRecording) Duration() time.Duration {
+	if len(rec.Events) == 0 {
+		return 0
+	}
+	return time.Duration(rec.Events[len(rec.Events)-1].OffsetMS) * time.Millisecond
+}
+
+// ReplayDemoRecording re-injects a recording into session with its original
+// timing scaled by opts.Speed: messages are resent from their original
+// senders, notifications are repeated, and command outcomes are appended to
+// the roles' history. With opts.DryRun the events are only printed.
+// Returns the total elapsed time.
+func ReplayDemoRecording(session string, rec DemoRecording, opts DemoOptions) (time.Duration, error) {
+	if opts.Speed <= 0 {
+		opts.Speed = 1.0
+	}
+	start := time.Now()
+
+	fmt.Printf("=== Replay: %s ===\n", rec.Name)
+	fmt.Printf("    Recorded from session %s at %s\n", rec.Session, time.Unix(rec.Started, 0).Format("2006-01-02 15:04"))
+	fmt.Printf("    Speed: %.1fx  Events: %d  Length: %s\n\n", opts.Speed, len(rec.Events), ScaleDelay(rec.Duration(), opts.Speed).Round(time.Millisecond))
+
+	prev := int64(0)
+	for i, ev := range rec.Events {
+		wait := ScaleDelay(time.Duration(ev.OffsetMS-prev)*time.Millisecond, opts.Speed)
+		prev = ev.OffsetMS
+		prefix := fmt.Sprintf("[%2d/%d]", i+1, len(rec.Events))
+		if opts.DryRun {
+			fmt.Printf("%s +%s %s\n", prefix, ScaleDelay(time.Duration(ev.OffsetMS)*time.Millisecond, opts.Speed).Round(time.Millisecond), describeDemoEvent(ev))
+			continue
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		fmt.Printf("%s %s\n", prefix, describeDemoEvent(ev))
+		if err := replayDemoEvent(session, ev); err != nil {
+			return time.Since(start), fmt.Errorf("event %d (%s): %w", i+1, ev.Kind, err)
+		}
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("\n=== Done (%s) ===\n", elapsed.Round(time.Millisecond))
+	return elapsed, nil
+}
+
+// replayDemoEvent re-injects one recorded event.
+func replayDemoEvent(session string, ev DemoEvent) error {
+	switch ev.Kind {
+	case "message":
+		if ev.Message == nil {
+			return fmt.Errorf("message event without a message")
+		}
+		m := *ev.Message
+		// Fresh ID and trace; attachments may not exist in this session
+		return Send(session, NewMessage(m.From, m.To, m.Type, m.Action, m.Payload, ""))
+	case "notify":
+		// Best effort, as in demo scenarios: the pane may not exist
+		_ = Notify(session, ev.Role)
+		return nil
+	case "outcome":
+		if ev.Outcome == nil {
+			return fmt.Errorf("outcome event without an outcome")
+		}
+		h := *ev.Outcome
+		h.TS = time.Now().Unix()
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		return AppendHistory(session, ev.Role, data, 100)
+	default:
+		return fmt.Errorf("unknown event kind: %s", ev.Kind)
+	}
+}
+
+// describeDemoEvent returns a one-line description of a recorded event.
+func describeDemoEvent(ev DemoEvent) string {
+	switch ev.Kind {
+	case "message":
+		if m := ev.Message; m != nil {
+			return fmt.Sprintf("%s → %s: %s (%s)", m.From, m.To, m.Action, m.Type)
+		}
+	case "notify":
+		return "notify " + ev.Role
+	case "outcome":
+		if h := ev.Outcome; h != nil {
+			cmd := h.Command
+			if cmd == "" {
+				cmd = h.Summary
+			}
+			return fmt.Sprintf("%s ran %q: %s (exit %s)", ev.Role, cmd, h.Outcome, h.ExitCode)
+		}
+	}
+	return ev.Kind
+}
+
+// ListDemoRecordings returns the recordings in DemoDir(), sorted by name.
+// Unreadable files are skipped.
+func ListDemoRecordings() []DemoRecording {
+	paths, _ := filepath.Glob(filepath.Join(DemoDir(), "*.rec.jsonl"))
+	sort.Strings(paths)
+	var recs []DemoRecording
+	for _, p := range paths {
+		if rec, err := LoadDemoRecording(p); err == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+// FormatDemoRecordings returns a human-readable list of recordings, or ""
+// when there are none.
+func FormatDemoRecordings(recs []DemoRecording) string {
+	if len(recs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Recordings:\n\n")
+	for _, rec := range recs {
+		fmt.Fprintf(&b, "  %-24s %d events, %s, from %s at %s\n", rec.Name, len(rec.Events),
+			rec.Duration().Round(time.Second), rec.Session, time.Unix(rec.Started, 0).Format("2006-01-02 15:04"))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDemoRecorder_RecordAndReplay(t *testing.T) {
+	t.Setenv("BUS_DEMO_DIR", t.TempDir())
+	session := fmt.Sprintf("test-demo-record-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	// Activity before the recording starts is not captured
+	Send(session, NewMessage("edit", "build", "request", "build", "before", ""))
+
+	rec, err := NewDemoRecorder(session, "bug-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Send(session, NewMessage("edit", "build", "request", "build", "run it", ""))
+	if n, err := rec.Poll(); err != nil || n != 1 {
+		t.Fatalf("Poll = %d, %v", n, err)
+	}
+	entry, _ := json.Marshal(HistoryEntry{TS: time.Now().Unix(), Command: "./build.sh", ExitCode: "1", Outcome: "failure"})
+	AppendHistory(session, "build", entry, 0)
+	Send(session, NewMessage("build", "edit", "event", "build-failed", "exit 1", ""))
+	os.WriteFile(notifiedSizePath(session, "edit"), []byte("1"), 0644)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Count != 4 {
+		t.Errorf("captured %d events, want 4", rec.Count)
+	}
+
+	got, err := LoadDemoRecording("bug-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Session != session || len(got.Events) != 4 {
+		t.Fatalf("recording = %+v", got)
+	}
+	kinds := make([]string, len(got.Events))
+	for i, ev := range got.Events {
+		kinds[i] = ev.Kind
+	}
+	if strings.Join(kinds, ",") != "message,message,notify,outcome" {
+		t.Errorf("kinds = %v", kinds)
+	}
+	if out := FormatDemoRecordings(ListDemoRecordings()); !strings.Contains(out, "bug-123") || !strings.Contains(out, "4 events") {
+		t.Errorf("list:\n%s", out)
+	}
+
+	// Replay into a fresh session
+	target := fmt.Sprintf("test-demo-replay-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(target) })
+	if err := Init(target, t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if _, err := ReplayDemoRecording(target, got, DemoOptions{Speed: 100}); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ := Peek(target, "build")
+	if len(msgs) != 1 || msgs[0].From != "edit" || msgs[0].Payload != "run it" {
+		t.Errorf("build inbox = %+v", msgs)
+	}
+	if msgs, _ := Peek(target, "edit"); len(msgs) != 1 || msgs[0].Action != "build-failed" {
+		t.Errorf("edit inbox = %+v", msgs)
+	}
+	if h := ReadHistory(target, "build", 0); len(h) != 1 || h[0].Command != "./build.sh" {
+		t.Errorf("build history = %+v", h)
+	}
+}
+
+func TestLoadDemoRecording_NotARecording(t *testing.T) {
+	path := t.TempDir() + "/x.rec.jsonl"
+	os.WriteFile(path, []byte(`{"kind":"message"}`+"\n"), 0644)
+	if _, err := LoadDemoRecording(path); err == nil {
+		t.Error("expected error for a file without a start event")
+	}
+}
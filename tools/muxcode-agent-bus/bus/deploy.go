@@ -0,0 +1,267 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Deploy record statuses. A deploy is "deployed" or "failed"; a later
+// verification moves the environment to "verified" or "verify-failed".
+const (
+	DeployDeployed     = "deployed"
+	DeployFailed       = "failed"
+	DeployVerified     = "verified"
+	DeployVerifyFailed = "verify-failed"
+)
+
+// DeployStatuses lists the statuses deploy record accepts.
+var DeployStatuses = []string{DeployDeployed, DeployFailed, DeployVerified, DeployVerifyFailed}
+
+// PromotionBlockedAction is the action of the event sent to edit when a
+// chain action's promotion gate fails.
+const PromotionBlockedAction = "promotion-blocked"
+
+// deployKeep is how many deploy records the session keeps.
+const deployKeep = 500
+
+// DefaultEnvironments is the promotion order when deploy.environments is
+// not configured.
+var DefaultEnvironments = []string{"dev", "staging", "prod"}
+
+// DeployConfig is the "deploy" section of muxcode.json.
+type DeployConfig struct {
+	Environments []string `json:"environments,omitempty"` // promotion order, first to last
+}
+
+// EnvironmentList returns the configured environments, or the defaults.
+func (c DeployConfig) EnvironmentList() []string {
+	if len(c.Environments) > 0 {
+		return c.Environments
+	}
+	return DefaultEnvironments
+}
+
+// Validate rejects empty and duplicate environment names.
+func (c DeployConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, env := range c.Environments {
+		if !validRoleName(env) {
+			return fmt.Errorf("environments.%d: invalid name %q", i, env)
+		}
+		if seen[env] {
+			return fmt.Errorf("environments.%d: duplicate %q", i, env)
+		}
+		seen[env] = true
+	}
+	return nil
+}
+
+// DeployRecord is one deploy or verification of an environment.
+type DeployRecord struct {
+	TS      int64  `json:"ts"`
+	Env     string `json:"env"`
+	Status  string `json:"status"`
+	Commit  string `json:"commit,omitempty"`
+	By      string `json:"by,omitempty"` // who triggered it: a role or user
+	Command string `json:"command,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// isVerification reports whether the record verifies a deploy rather than
+// recording one.
+func (r DeployRecord) isVerification() bool {
+	return r.Status == DeployVerified || r.Status == DeployVerifyFailed
+}
+
+// EnvStatus is an environment's current deploy: its last deploy record,
+// with the status moved on by any verification of it.
+type EnvStatus struct {
+	Env        string `json:"env"`
+	Status     string `json:"status"` // "" when never deployed
+	Commit     string `json:"commit,omitempty"`
+	By         string `json:"by,omitempty"`
+	Command    string `json:"command,omitempty"`
+	DeployedAt int64  `json:"deployed_at,omitempty"`
+	VerifiedAt int64  `json:"verified_at,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// DeploysPath returns the session's deploy log.
+func DeploysPath(session string) string {
+	return filepath.Join(BusDir(session), "deploys.jsonl")
+}
+
+// ReadDeployRecords reads the deploy log, oldest first.
+func ReadDeployRecords(session string) ([]DeployRecord, error) {
+	data, err := os.ReadFile(DeploysPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []DeployRecord
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var r DeployRecord
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Env != "" {
+			records = append(records, r)
+		}
+	}
+	return records, sc.Err()
+}
+
+// RecordDeploy appends a deploy or verification record. The environment
+// must be configured. A verification without a commit applies to the
+// environment's current deploy; one naming another commit is rejected.
+func RecordDeploy(session string, r DeployRecord) error {
+	if !containsRole(Config().Deploy.EnvironmentList(), r.Env) {
+		return fmt.Errorf("unknown environment %q (configured: %s)", r.Env, strings.Join(Config().Deploy.EnvironmentList(), ", "))
+	}
+	if r.Status == "" {
+		r.Status = DeployDeployed
+	}
+	if !containsRole(DeployStatuses, r.Status) {
+		return fmt.Errorf("unknown status %q (want one of %s)", r.Status, strings.Join(DeployStatuses, ", "))
+	}
+	if r.isVerification() {
+		records, err := ReadDeployRecords(session)
+		if err != nil {
+			return err
+		}
+		cur := deployStatus(records, r.Env)
+		if cur.DeployedAt == 0 {
+			return fmt.Errorf("%s has no deploy to verify", r.Env)
+		}
+		if r.Commit != "" && !sameCommit(r.Commit, cur.Commit) {
+			return fmt.Errorf("%s is at %s, not %s", r.Env, shortCommit(cur.Commit), shortCommit(r.Commit))
+		}
+		r.Commit = cur.Commit
+	}
+	if r.TS == 0 {
+		r.TS = time.Now().Unix()
+	}
+	if err := os.MkdirAll(BusDir(session), 0755); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(r)
+	if err := appendToFile(DeploysPath(session), append(data, '\n')); err != nil {
+		return err
+	}
+	trimJSONL(DeploysPath(session), deployKeep)
+	return nil
+}
+
+// deployStatus folds the records into an environment's current status.
+func deployStatus(records []DeployRecord, env string) EnvStatus {
+	s := EnvStatus{Env: env}
+	for _, r := range records {
+		if r.Env != env {
+			continue
+		}
+		if !r.isVerification() {
+			s = EnvStatus{Env: env, Status: r.Status, Commit: r.Commit, By: r.By, Command: r.Command, DeployedAt: r.TS, Note: r.Note}
+			continue
+		}
+		if s.DeployedAt > 0 && sameCommit(r.Commit, s.Commit) {
+			s.Status = r.Status
+			s.VerifiedAt = r.TS
+			if r.Note != "" {
+				s.Note = r.Note
+			}
+		}
+	}
+	return s
+}
+
+// ReadDeployStatus returns each configured environment's status, in
+// promotion order.
+func ReadDeployStatus(session string) ([]EnvStatus, error) {
+	records, err := ReadDeployRecords(session)
+	if err != nil {
+		return nil, err
+	}
+	var out []EnvStatus
+	for _, env := range Config().Deploy.EnvironmentList() {
+		out = append(out, deployStatus(records, env))
+	}
+	return out, nil
+}
+
+// CheckPromotion reports whether commit may be promoted to env: the
+// environment before it in the promotion order must be verified, at
+// commit when one is given. The first environment is always open.
+func CheckPromotion(session, env, commit string) error {
+	envs := Config().Deploy.EnvironmentList()
+	idx := -1
+	for i, e := range envs {
+		if e == env {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("unknown environment %q (configured: %s)", env, strings.Join(envs, ", "))
+	}
+	if idx == 0 {
+		return nil
+	}
+	records, err := ReadDeployRecords(session)
+	if err != nil {
+		return err
+	}
+	prev := deployStatus(records, envs[idx-1])
+	switch {
+	case prev.Status == "":
+		return fmt.Errorf("%s has not been deployed", prev.Env)
+	case prev.Status != DeployVerified:
+		return fmt.Errorf("%s is %s at %s, not verified", prev.Env, prev.Status, shortCommit(prev.Commit))
+	case commit != "" && !sameCommit(commit, prev.Commit):
+		return fmt.Errorf("%s is verified at %s, not %s", prev.Env, shortCommit(prev.Commit), shortCommit(commit))
+	}
+	return nil
+}
+
+// sameCommit compares commit hashes, allowing either to be abbreviated.
+// Two empty commits match.
+func sameCommit(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// shortCommit abbreviates a commit hash for messages.
+func shortCommit(c string) string {
+	if c == "" {
+		return "(no commit)"
+	}
+	if len(c) > 10 {
+		return c[:10]
+	}
+	return c
+}
+
+// FormatDeployStatus renders the environments as a table.
+func FormatDeployStatus(envs []EnvStatus) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %-14s %-11s %-10s %-12s %s\n", "ENV", "STATUS", "COMMIT", "BY", "DEPLOYED", "VERIFIED")
+	for _, e := range envs {
+		if e.Status == "" {
+			fmt.Fprintf(&b, "%-10s -\n", e.Env)
+			continue
+		}
+		verified := "-"
+		if e.VerifiedAt > 0 {
+			verified = time.Unix(e.VerifiedAt, 0).Format("01-02 15:04")
+		}
+		fmt.Fprintf(&b, "%-10s %-14s %-11s %-10s %-12s %s\n", e.Env, e.Status, shortCommit(e.Commit), e.By,
+			time.Unix(e.DeployedAt, 0).Format("01-02 15:04"), verified)
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordDeploy(t *testing.T) {
+	session := testSession(t)
+
+	if err := RecordDeploy(session, DeployRecord{Env: "qa", Commit: "abc"}); err == nil {
+		t.Error("unknown environment: want error")
+	}
+	if err := RecordDeploy(session, DeployRecord{Env: "dev", Status: DeployVerified}); err == nil {
+		t.Error("verify before deploy: want error")
+	}
+	if err := RecordDeploy(session, DeployRecord{Env: "dev", Commit: "abc1234def", By: "deploy", Command: "make deploy-dev", TS: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDeploy(session, DeployRecord{Env: "dev", Status: DeployVerified, Commit: "def"}); err == nil {
+		t.Error("verify another commit: want error")
+	}
+	if err := RecordDeploy(session, DeployRecord{Env: "dev", Status: DeployVerified, Commit: "abc1234", TS: 200}); err != nil {
+		t.Fatal(err)
+	}
+
+	envs, err := ReadDeployStatus(session)
+	if err != nil || len(envs) != 3 {
+		t.Fatalf("ReadDeployStatus = %+v, %v", envs, err)
+	}
+	dev := envs[0]
+	if dev.Status != DeployVerified || dev.Commit != "abc1234def" || dev.By != "deploy" || dev.DeployedAt != 100 || dev.VerifiedAt != 200 {
+		t.Errorf("dev = %+v", dev)
+	}
+	if envs[1].Status != "" {
+		t.Errorf("staging = %+v", envs[1])
+	}
+
+	// A new deploy resets verification
+	if err := RecordDeploy(session, DeployRecord{Env: "dev", Commit: "fff", TS: 300}); err != nil {
+		t.Fatal(err)
+	}
+	envs, _ = ReadDeployStatus(session)
+	if envs[0].Status != DeployDeployed || envs[0].VerifiedAt != 0 {
+		t.Errorf("redeployed dev = %+v", envs[0])
+	}
+	out := FormatDeployStatus(envs)
+	if !strings.Contains(out, "dev        deployed       fff") || !strings.Contains(out, "prod       -") {
+		t.Errorf("FormatDeployStatus:\n%s", out)
+	}
+}
+
+func TestCheckPromotion(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Deploy = DeployConfig{Environments: []string{"dev", "staging", "prod"}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if err := CheckPromotion(session, "dev", "abc"); err != nil {
+		t.Errorf("first environment: %v", err)
+	}
+	if err := CheckPromotion(session, "qa", ""); err == nil {
+		t.Error("unknown environment: want error")
+	}
+	if err := CheckPromotion(session, "staging", "abc"); err == nil || !strings.Contains(err.Error(), "dev has not been deployed") {
+		t.Errorf("undeployed dev: %v", err)
+	}
+
+	_ = RecordDeploy(session, DeployRecord{Env: "dev", Commit: "abc123"})
+	if err := CheckPromotion(session, "staging", "abc123"); err == nil || !strings.Contains(err.Error(), "not verified") {
+		t.Errorf("unverified dev: %v", err)
+	}
+	_ = RecordDeploy(session, DeployRecord{Env: "dev", Status: DeployVerified})
+	if err := CheckPromotion(session, "staging", "abc123"); err != nil {
+		t.Errorf("verified dev: %v", err)
+	}
+	if err := CheckPromotion(session, "staging", "999"); err == nil || !strings.Contains(err.Error(), "verified at abc123, not 999") {
+		t.Errorf("other commit: %v", err)
+	}
+	if err := CheckPromotion(session, "prod", "abc123"); err == nil {
+		t.Error("prod before staging: want error")
+	}
+
+	_ = RecordDeploy(session, DeployRecord{Env: "dev", Status: DeployVerifyFailed})
+	if err := CheckPromotion(session, "staging", ""); err == nil || !strings.Contains(err.Error(), "dev is verify-failed") {
+		t.Errorf("failed verification: %v", err)
+	}
+}
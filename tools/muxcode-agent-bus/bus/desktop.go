@@ -0,0 +1,225 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Message severities, lowest to highest.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// DesktopRule routes messages for matching roles at or above a severity to
+// a desktop notifier, so failures are noticed outside the tmux session.
+type DesktopRule struct {
+	Roles    []string `json:"roles,omitempty"`    // recipient glob patterns (default: any)
+	Severity string   `json:"severity,omitempty"` // minimum severity: info, warning, error (default error)
+	Backend  string   `json:"backend,omitempty"`  // auto (default), osascript, notify-send, bell
+}
+
+// DesktopNotifier delivers one desktop notification. The session is used
+// by backends that reach the tmux clients (bell).
+type DesktopNotifier func(session, title, body, severity string) error
+
+// desktopNotifiers are the available backends, keyed by config name.
+var desktopNotifiers = map[string]DesktopNotifier{
+	"osascript":   notifyOsascript,
+	"notify-send": notifyNotifySend,
+	"bell":        notifyBell,
+}
+
+// errorActions and warningActions are watcher events with a fixed severity.
+var (
+	errorActions   = []string{"loop-detected", "sla-breach", "ollama-down", BudgetExceededAction}
+	warningActions = []string{"ollama-restarting", "compact-recommended", GuardStopAction, ResourcePressureAction}
+)
+
+// severityRank orders severities; unknown values rank as error.
+func severityRank(s string) int {
+	switch s {
+	case SeverityInfo:
+		return 0
+	case SeverityWarning:
+		return 1
+	}
+	return 2
+}
+
+// ValidSeverity reports whether s is a known severity.
+func ValidSeverity(s string) bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityError
+}
+
+// MessageSeverity classifies a message: chain and process failures and
+// watcher alerts are errors, recoverable watcher events are warnings, and
+// everything else is info.
+func MessageSeverity(m Message) string {
+	switch {
+	case containsRole(errorActions, m.Action),
+		strings.Contains(m.Payload, "FAILED"),
+		m.Action == "proc-complete" && strings.Contains(m.Payload, "Status: failed"):
+		return SeverityError
+	case containsRole(warningActions, m.Action):
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// ValidateDesktopRules checks severities and backends.
+func ValidateDesktopRules(rules []DesktopRule) error {
+	for i, r := range rules {
+		if r.Severity != "" && !ValidSeverity(r.Severity) {
+			return fmt.Errorf("desktop rule %d: unknown severity %q", i, r.Severity)
+		}
+		if r.Backend != "" && r.Backend != "auto" && desktopNotifiers[r.Backend] == nil {
+			return fmt.Errorf("desktop rule %d: unknown backend %q", i, r.Backend)
+		}
+	}
+	return nil
+}
+
+// desktopBackend returns the notifier a rule selects. auto picks osascript
+// on macOS, notify-send when installed, and the terminal bell otherwise.
+func desktopBackend(backend string) (string, DesktopNotifier) {
+	if backend == "" || backend == "auto" {
+		switch {
+		case runtime.GOOS == "darwin":
+			backend = "osascript"
+		case hasCommand("notify-send"):
+			backend = "notify-send"
+		default:
+			backend = "bell"
+		}
+	}
+	return backend, desktopNotifiers[backend]
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// MatchDesktopRule returns the first rule matching a recipient role and
+// message severity.
+func MatchDesktopRule(role, severity string) (DesktopRule, bool) {
+	for _, r := range Config().Notify.Desktop {
+		min := r.Severity
+		if min == "" {
+			min = SeverityError
+		}
+		if severityRank(severity) < severityRank(min) {
+			continue
+		}
+		if len(r.Roles) == 0 {
+			return r, true
+		}
+		for _, p := range r.Roles {
+			if globMatch(p, role) {
+				return r, true
+			}
+		}
+	}
+	return DesktopRule{}, false
+}
+
+// desktopNotifiedPath records the ID of the last message sent to the
+// desktop for a role, so repeated Notify calls alert once per message.
+func desktopNotifiedPath(session, role string) string {
+	return filepath.Join(BusDir(session), "desktop-notified-"+role)
+}
+
+// NotifyDesktop sends a desktop notification for the newest message in a
+// role's inbox when a desktop rule matches it. Best-effort: failures are
+// logged, not returned.
+func NotifyDesktop(session, role string) {
+	if len(Config().Notify.Desktop) == 0 {
+		return
+	}
+	msgs, err := Peek(session, role)
+	if err != nil || len(msgs) == 0 {
+		return
+	}
+	last := msgs[len(msgs)-1]
+	if data, err := os.ReadFile(desktopNotifiedPath(session, role)); err == nil && string(data) == last.ID {
+		return
+	}
+	severity := MessageSeverity(last)
+	rule, ok := MatchDesktopRule(role, severity)
+	if !ok {
+		return
+	}
+	name, notifier := desktopBackend(rule.Backend)
+	if notifier == nil {
+		return
+	}
+	_ = os.WriteFile(desktopNotifiedPath(session, role), []byte(last.ID), 0644)
+
+	title := fmt.Sprintf("muxcode %s: %s → %s", session, last.From, role)
+	body := last.Payload
+	if len(body) > 200 {
+		body = body[:200] + "…"
+	}
+	if err := notifier(session, title, body, severity); err != nil {
+		fmt.Fprintf(os.Stderr, "  [notify] %s desktop notification for %s failed: %v\n", name, role, err)
+	}
+}
+
+// TestDesktopNotifier sends a sample notification through a backend
+// ("" or auto to pick one) and returns the backend used.
+func TestDesktopNotifier(session, backend string) (string, error) {
+	name, notifier := desktopBackend(backend)
+	if notifier == nil {
+		return name, fmt.Errorf("unknown backend")
+	}
+	return name, notifier(session, "muxcode "+session, "Desktop notifications are working.", SeverityInfo)
+}
+
+// notifyOsascript posts a macOS Notification Center banner.
+func notifyOsascript(_, title, body, _ string) error {
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(body), appleScriptQuote(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// appleScriptQuote returns s as an AppleScript string literal.
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// notifyNotifySend posts a freedesktop notification; error severity is
+// critical urgency.
+func notifyNotifySend(_, title, body, severity string) error {
+	urgency := "normal"
+	switch severity {
+	case SeverityError:
+		urgency = "critical"
+	case SeverityInfo:
+		urgency = "low"
+	}
+	return exec.Command("notify-send", "-u", urgency, "-a", "muxcode", title, body).Run()
+}
+
+// notifyBell rings the terminal bell on every client attached to the
+// session, which most terminals surface as an alert or dock bounce.
+func notifyBell(session, _, _, _ string) error {
+	out, err := exec.Command("tmux", "list-clients", "-t", session, "-F", "#{client_tty}").Output()
+	if err != nil {
+		return err
+	}
+	for _, tty := range strings.Fields(string(out)) {
+		f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+		if err != nil {
+			continue
+		}
+		_, _ = f.Write([]byte("\a"))
+		f.Close()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package bus
+
+import "testing"
+
+func TestMessageSeverity(t *testing.T) {
+	tests := []struct {
+		msg  Message
+		want string
+	}{
+		{NewMessage("build", "edit", "event", "notify", "Build FAILED (exit 1): go build", ""), SeverityError},
+		{NewMessage("watcher", "edit", "event", "sla-breach", "late", ""), SeverityError},
+		{NewMessage("proc", "build", "event", "proc-complete", "Status: failed  Exit code: 2", ""), SeverityError},
+		{NewMessage("watcher", "edit", "event", "ollama-restarting", "restarting", ""), SeverityWarning},
+		{NewMessage("build", "test", "request", "test", "Build succeeded", ""), SeverityInfo},
+	}
+	for _, tt := range tests {
+		if got := MessageSeverity(tt.msg); got != tt.want {
+			t.Errorf("MessageSeverity(%s: %q) = %s, want %s", tt.msg.Action, tt.msg.Payload, got, tt.want)
+		}
+	}
+}
+
+func TestMatchDesktopRule(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notify.Desktop = []DesktopRule{
+		{Roles: []string{"edit"}, Severity: SeverityWarning, Backend: "bell"},
+		{Backend: "notify-send"},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if r, ok := MatchDesktopRule("edit", SeverityWarning); !ok || r.Backend != "bell" {
+		t.Errorf("edit warning: got %+v, %v", r, ok)
+	}
+	if _, ok := MatchDesktopRule("build", SeverityWarning); ok {
+		t.Error("build warning should not match (second rule defaults to error)")
+	}
+	if r, ok := MatchDesktopRule("build", SeverityError); !ok || r.Backend != "notify-send" {
+		t.Errorf("build error: got %+v, %v", r, ok)
+	}
+}
+
+func TestNotifyDesktop_OncePerMessage(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Notify.Desktop = []DesktopRule{{Roles: []string{"edit"}, Backend: "fake"}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	var calls []string
+	desktopNotifiers["fake"] = func(_, title, body, severity string) error {
+		calls = append(calls, severity+": "+body)
+		return nil
+	}
+	defer delete(desktopNotifiers, "fake")
+
+	send := func(payload string) {
+		if err := Send(session, NewMessage("build", "edit", "event", "notify", payload, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("Build succeeded")
+	NotifyDesktop(session, "edit")
+	if len(calls) != 0 {
+		t.Fatalf("info message should not match an error rule, got %v", calls)
+	}
+
+	send("Build FAILED (exit 1)")
+	NotifyDesktop(session, "edit")
+	NotifyDesktop(session, "edit")
+	if len(calls) != 1 || calls[0] != "error: Build FAILED (exit 1)" {
+		t.Errorf("calls = %v, want one error notification", calls)
+	}
+}
+
+func TestValidateDesktopRules(t *testing.T) {
+	if err := ValidateDesktopRules([]DesktopRule{{Severity: "warning", Backend: "auto"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateDesktopRules([]DesktopRule{{Backend: "pager"}}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+	if err := ValidateDesktopRules([]DesktopRule{{Severity: "fatal"}}); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 )
 
 // ProjectType represents a detected project type with optional metadata.
 type ProjectType struct {
-	Name       string            // e.g. "go", "nodejs", "python"
-	Indicators []string          // which indicator files were found
-	Metadata   map[string]string // extracted details (module name, scripts, etc.)
+	Name       string             // e.g. "go", "nodejs", "python"
+	Indicators []string           // which indicator files were found
+	Metadata   map[string]string  // extracted details (module name, scripts, etc.)
+	Packages   []WorkspacePackage // workspace members (monorepo only)
 }
 
 // indicator defines how to detect a project type.
@@ -100,6 +102,32 @@ var indicators = []indicator{
 		Files: []string{"Package.swift"},
 		Globs: []string{"*.xcodeproj"},
 	},
+	{
+		Name:    "elixir",
+		Files:   []string{"mix.exs"},
+		Extract: extractMixExs,
+	},
+	{
+		Name:  "zig",
+		Files: []string{"build.zig", "build.zig.zon"},
+	},
+	{
+		Name:    "flutter",
+		Files:   []string{"pubspec.yaml"},
+		Extract: extractPubspec,
+	},
+	{
+		Name:  "deno",
+		Files: []string{"deno.json", "deno.jsonc"},
+	},
+	{
+		Name:  "bun",
+		Files: []string{"bun.lockb", "bun.lock", "bunfig.toml"},
+	},
+	{
+		Name:  "nix",
+		Files: []string{"flake.nix", "shell.nix", "default.nix"},
+	},
 }
 
 // DetectProject scans dir for project type indicators and returns detected types
@@ -144,6 +172,10 @@ func DetectProject(dir string) []ProjectType {
 		detected = append(detected, pt)
 	}
 
+	if mono := detectMonorepo(dir); mono != nil {
+		detected = append(detected, *mono)
+	}
+
 	sort.Slice(detected, func(i, j int) bool {
 		return detected[i].Name < detected[j].Name
 	})
@@ -306,12 +338,72 @@ func conventionText(pt ProjectType) string {
 			"- Naming: camelCase methods, PascalCase classes"
 		return s
 
+	case "monorepo":
+		return monorepoConventionText(pt)
+
 	case "swift":
 		return "## Swift Project\n" +
 			"- Build: `swift build`\n" +
 			"- Test: `swift test`\n" +
 			"- Naming: camelCase functions/properties, PascalCase types"
 
+	case "elixir":
+		s := "## Elixir Project\n"
+		if v := m["app"]; v != "" {
+			s += fmt.Sprintf("- App: `%s`\n", v)
+		}
+		s += "- Deps: `mix deps.get`\n" +
+			"- Build: `mix compile`\n" +
+			"- Test: `mix test`\n" +
+			"- Format: `mix format`\n" +
+			"- Naming: snake_case functions, PascalCase modules"
+		return s
+
+	case "zig":
+		return "## Zig Project\n" +
+			"- Build: `zig build`\n" +
+			"- Test: `zig build test`\n" +
+			"- Format: `zig fmt .`\n" +
+			"- Naming: camelCase functions, PascalCase types, snake_case variables"
+
+	case "flutter":
+		s := "## Flutter Project\n"
+		if v := m["name"]; v != "" {
+			s += fmt.Sprintf("- Package: `%s`\n", v)
+		}
+		if m["sdk"] == "dart" {
+			s += "- Deps: `dart pub get`\n" +
+				"- Test: `dart test`\n" +
+				"- Analyze: `dart analyze`\n"
+		} else {
+			s += "- Deps: `flutter pub get`\n" +
+				"- Test: `flutter test`\n" +
+				"- Analyze: `flutter analyze`\n"
+		}
+		s += "- Naming: lowerCamelCase members, UpperCamelCase types, snake_case files"
+		return s
+
+	case "deno":
+		return "## Deno Project\n" +
+			"- Test: `deno test`\n" +
+			"- Lint: `deno lint`\n" +
+			"- Format: `deno fmt`\n" +
+			"- Tasks: `deno task <name>` (see deno.json)"
+
+	case "bun":
+		return "## Bun Project\n" +
+			"- Install: `bun install`\n" +
+			"- Test: `bun test`\n" +
+			"- Scripts: `bun run <script>`\n" +
+			"- Use bun instead of npm for installs so bun.lock stays current"
+
+	case "nix":
+		return "## Nix Project\n" +
+			"- Dev shell: `nix develop` (flakes) or `nix-shell`\n" +
+			"- Build: `nix build`\n" +
+			"- Check: `nix flake check`\n" +
+			"- Run tools inside the dev shell so versions match"
+
 	default:
 		return ""
 	}
@@ -343,6 +435,15 @@ func FormatDetectOutput(types []ProjectType) string {
 		}
 		fmt.Fprintf(&b, "%-16s %-24s %s\n", pt.Name, indicators, meta)
 	}
+	for _, pt := range types {
+		if len(pt.Packages) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%-24s %-8s %s\n", "PACKAGE", "TOOL", "BUILD / TEST")
+		for _, p := range pt.Packages {
+			fmt.Fprintf(&b, "%-24s %-8s %s\n", p.Path, p.Tool, strings.TrimSuffix(p.Build+" / "+p.Test, " / "))
+		}
+	}
 	return b.String()
 }
 
@@ -435,3 +536,37 @@ func extractPHP(dir string) map[string]string {
 	}
 	return nil
 }
+
+// extractMixExs extracts the app name from mix.exs.
+func extractMixExs(dir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(dir, "mix.exs"))
+	if err != nil {
+		return nil
+	}
+	if m := mixAppName.FindSubmatch(data); m != nil {
+		return map[string]string{"app": string(m[1])}
+	}
+	return nil
+}
+
+// mixAppName matches `app: :name` in a mix.exs project definition.
+var mixAppName = regexp.MustCompile(`app:\s*:([a-z_][a-z0-9_]*)`)
+
+// extractPubspec extracts the package name from pubspec.yaml and whether it
+// depends on the Flutter SDK ("flutter") or is plain Dart ("dart").
+func extractPubspec(dir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(dir, "pubspec.yaml"))
+	if err != nil {
+		return nil
+	}
+	m := map[string]string{"sdk": "dart"}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "name:") {
+			m["name"] = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "name:")), `"'`)
+		}
+		if strings.TrimSpace(line) == "sdk: flutter" {
+			m["sdk"] = "flutter"
+		}
+	}
+	return m
+}
@@ -428,3 +428,46 @@ func TestDetectProject_TypescriptFile(t *testing.T) {
 		t.Errorf("expected 'typescript', got '%s'", types[0].Name)
 	}
 }
+
+func TestDetectProject_NewEcosystems(t *testing.T) {
+	tests := []struct {
+		file, content, want, convention string
+	}{
+		{"mix.exs", "def project do\n  [app: :my_app, version: \"0.1.0\"]\nend", "elixir", "`mix test`"},
+		{"build.zig", "const std = @import(\"std\");", "zig", "`zig build test`"},
+		{"pubspec.yaml", "name: shop_app\ndependencies:\n  flutter:\n    sdk: flutter\n", "flutter", "`flutter test`"},
+		{"deno.jsonc", "{}", "deno", "`deno test`"},
+		{"bun.lockb", "", "bun", "`bun test`"},
+		{"flake.nix", "{ outputs = { self }: {}; }", "nix", "`nix develop`"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeIndicatorFile(t, dir, tt.file, tt.content)
+
+		types := DetectProject(dir)
+		if len(types) != 1 || types[0].Name != tt.want {
+			t.Errorf("%s: detected %+v, want %s", tt.file, types, tt.want)
+			continue
+		}
+		if text := conventionText(types[0]); !strings.Contains(text, tt.convention) {
+			t.Errorf("%s: convention text missing %s:\n%s", tt.want, tt.convention, text)
+		}
+	}
+}
+
+func TestExtractMixExsAndPubspec(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "mix.exs", "[app: :my_app]")
+	if m := extractMixExs(dir); m["app"] != "my_app" {
+		t.Errorf("mix app = %v", m)
+	}
+
+	writeIndicatorFile(t, dir, "pubspec.yaml", "name: 'cli_tool'\nenvironment:\n  sdk: ^3.0.0\n")
+	m := extractPubspec(dir)
+	if m["name"] != "cli_tool" || m["sdk"] != "dart" {
+		t.Errorf("pubspec = %v", m)
+	}
+	if text := conventionText(ProjectType{Name: "flutter", Metadata: m}); !strings.Contains(text, "`dart test`") {
+		t.Errorf("plain Dart package should use dart commands:\n%s", text)
+	}
+}
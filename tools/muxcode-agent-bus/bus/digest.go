@@ -0,0 +1,387 @@
+package bus
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Digest formats.
+const (
+	DigestHTML     = "html"
+	DigestMarkdown = "markdown"
+)
+
+// DigestTarget is the cron target that emails the digest instead of
+// sending a bus message. The entry's message is the window ("24h"; empty
+// for the configured default).
+const DigestTarget = "digest"
+
+// SMTPPasswordEnv is the default environment variable holding the SMTP
+// password.
+const SMTPPasswordEnv = "MUXCODE_SMTP_PASSWORD"
+
+// defaultDigestHours is the digest window when neither the command nor the
+// config sets one.
+const defaultDigestHours = 8
+
+// digestMaxAlerts caps the alerts listed in one digest.
+const digestMaxAlerts = 50
+
+// DigestConfig is the "digest" section of muxcode.json.
+type DigestConfig struct {
+	Hours  int        `json:"hours,omitempty"`  // default window (default 8)
+	Format string     `json:"format,omitempty"` // html (default) or markdown
+	From   string     `json:"from,omitempty"`
+	To     []string   `json:"to,omitempty"`
+	SMTP   SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig is the mail server the digest is sent through. The password
+// comes from the environment, never from muxcode.json.
+type SMTPConfig struct {
+	Host        string `json:"host,omitempty"`
+	Port        int    `json:"port,omitempty"` // default 587
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"` // default MUXCODE_SMTP_PASSWORD
+}
+
+// Validate checks the format, window, port, and addresses.
+func (c DigestConfig) Validate() error {
+	if c.Format != "" && c.Format != DigestHTML && c.Format != DigestMarkdown {
+		return fmt.Errorf("unknown format %q (want html or markdown)", c.Format)
+	}
+	if c.Hours < 0 {
+		return fmt.Errorf("hours must be positive")
+	}
+	if c.SMTP.Port < 0 || c.SMTP.Port > 65535 {
+		return fmt.Errorf("smtp port %d out of range", c.SMTP.Port)
+	}
+	if c.From != "" {
+		if _, err := mail.ParseAddress(c.From); err != nil {
+			return fmt.Errorf("invalid from address %q", c.From)
+		}
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to address %q", to)
+		}
+	}
+	return nil
+}
+
+// Window returns the configured digest window.
+func (c DigestConfig) Window() time.Duration {
+	if c.Hours > 0 {
+		return time.Duration(c.Hours) * time.Hour
+	}
+	return defaultDigestHours * time.Hour
+}
+
+// ready reports why the digest cannot be emailed, or "" when it can.
+func (c DigestConfig) ready() string {
+	switch {
+	case c.SMTP.Host == "":
+		return "digest.smtp.host is not set"
+	case c.From == "":
+		return "digest.from is not set"
+	case len(c.To) == 0:
+		return "digest.to is not set"
+	}
+	return ""
+}
+
+// Digest is the session activity emailed to users away from tmux: command
+// outcomes and traffic (a history report), watcher alerts, and background
+// processes that finished in the window.
+type Digest struct {
+	Session string
+	Since   int64
+	Until   int64
+	Report  HistoryReport
+	Alerts  []Message
+	Procs   []ProcEntry
+}
+
+// isDigestAlert reports whether a logged message is a watcher alert.
+// Completion events are covered by the procs section or are routine.
+func isDigestAlert(m Message) bool {
+	if m.From != "watcher" || m.Type != "event" {
+		return false
+	}
+	switch m.Action {
+	case "proc-complete", "spawn-complete", "spawn-group-complete", "analyze":
+		return false
+	}
+	return true
+}
+
+// BuildDigest compiles the digest for the window [since, now].
+func BuildDigest(session string, since, now int64) Digest {
+	d := Digest{Session: session, Since: since, Until: now, Report: BuildHistoryReport(session, since, now)}
+
+	msgs, _ := readMessages(LogPath(session))
+	for _, m := range msgs {
+		if m.TS >= since && m.TS <= now && isDigestAlert(m) {
+			d.Alerts = append(d.Alerts, m)
+		}
+	}
+	if len(d.Alerts) > digestMaxAlerts {
+		d.Alerts = d.Alerts[len(d.Alerts)-digestMaxAlerts:]
+	}
+
+	procs, _ := ReadProcEntries(session)
+	for _, p := range procs {
+		if p.Status != "running" && p.FinishedAt >= since && p.FinishedAt <= now {
+			d.Procs = append(d.Procs, p)
+		}
+	}
+	sort.Slice(d.Procs, func(i, j int) bool { return d.Procs[i].FinishedAt < d.Procs[j].FinishedAt })
+	return d
+}
+
+// Subject returns the email subject line.
+func (d Digest) Subject() string {
+	failures := 0
+	for _, s := range d.Report.Roles {
+		failures += s.Failures
+	}
+	return fmt.Sprintf("muxcode %s: %d commands, %d failures, %d alerts (last %s)",
+		d.Session, digestRuns(d), failures, len(d.Alerts), formatDigestWindow(d))
+}
+
+// digestRuns counts the commands run in the digest window.
+func digestRuns(d Digest) int {
+	runs := 0
+	for _, s := range d.Report.Roles {
+		runs += s.Runs
+	}
+	return runs
+}
+
+// formatDigestWindow renders the window length, e.g. "8h" or "90m".
+func formatDigestWindow(d Digest) string {
+	secs := d.Until - d.Since
+	if secs%3600 == 0 {
+		return fmt.Sprintf("%dh", secs/3600)
+	}
+	return fmt.Sprintf("%dm", secs/60)
+}
+
+// procOutcome describes how a finished proc ended.
+func procOutcome(p ProcEntry) string {
+	if p.Status == "exited" {
+		return "ok"
+	}
+	if p.Status == "failed" {
+		return fmt.Sprintf("failed (exit %d)", p.ExitCode)
+	}
+	return p.Status
+}
+
+// FormatDigestMarkdown renders the digest as Markdown.
+func FormatDigestMarkdown(d Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# muxcode digest: %s\n\n%s\n\n", d.Session, historyReportWindow(d.Report))
+
+	b.WriteString("## Commands\n\n")
+	if len(d.Report.Roles) == 0 {
+		b.WriteString("No commands run.\n")
+	} else {
+		b.WriteString("| Role | Runs | Success | Failures | Mean duration |\n")
+		b.WriteString("|------|-----:|--------:|---------:|--------------:|\n")
+		for _, s := range d.Report.Roles {
+			fmt.Fprintf(&b, "| %s | %d | %.0f%% | %d | %s |\n", s.Role, s.Runs, s.SuccessRate(), s.Failures, formatMeanDuration(s))
+		}
+	}
+	if len(d.Report.MostFailed) > 0 {
+		b.WriteString("\n### Most failed commands\n\n")
+		for _, fc := range d.Report.MostFailed {
+			fmt.Fprintf(&b, "- `%s` (%s): %d failures\n", strings.ReplaceAll(fc.Command, "`", "'"), fc.Role, fc.Failures)
+		}
+	}
+
+	b.WriteString("\n## Alerts\n\n")
+	if len(d.Alerts) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, m := range d.Alerts {
+		fmt.Fprintf(&b, "- %s **%s** → %s: %s\n", time.Unix(m.TS, 0).Format("01-02 15:04"), m.Action, m.To, m.Payload)
+	}
+
+	b.WriteString("\n## Completed processes\n\n")
+	if len(d.Procs) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, p := range d.Procs {
+		fmt.Fprintf(&b, "- %s `%s` (%s): %s\n", time.Unix(p.FinishedAt, 0).Format("01-02 15:04"), strings.ReplaceAll(p.Command, "`", "'"), p.Owner, procOutcome(p))
+	}
+
+	fmt.Fprintf(&b, "\n## Messages\n\n%d messages", d.Report.Messages)
+	if d.Report.Messages > 0 {
+		fmt.Fprintf(&b, " (%s)", messageTypeSummary(d.Report.MessagesByType))
+	}
+	b.WriteString(".\n")
+	return b.String()
+}
+
+// FormatDigestHTML renders the digest as a self-contained HTML document.
+func FormatDigestHTML(d Digest) string {
+	e := html.EscapeString
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><body style=\"font-family: sans-serif\">\n")
+	fmt.Fprintf(&b, "<h1>muxcode digest: %s</h1>\n<p>%s</p>\n", e(d.Session), e(historyReportWindow(d.Report)))
+
+	b.WriteString("<h2>Commands</h2>\n")
+	if len(d.Report.Roles) == 0 {
+		b.WriteString("<p>No commands run.</p>\n")
+	} else {
+		b.WriteString("<table border=\"1\" cellpadding=\"4\" cellspacing=\"0\">\n<tr><th>Role</th><th>Runs</th><th>Success</th><th>Failures</th><th>Mean duration</th></tr>\n")
+		for _, s := range d.Report.Roles {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%d</td><td>%.0f%%</td><td>%d</td><td>%s</td></tr>\n", e(s.Role), s.Runs, s.SuccessRate(), s.Failures, e(formatMeanDuration(s)))
+		}
+		b.WriteString("</table>\n")
+	}
+	if len(d.Report.MostFailed) > 0 {
+		b.WriteString("<h3>Most failed commands</h3>\n<ul>\n")
+		for _, fc := range d.Report.MostFailed {
+			fmt.Fprintf(&b, "<li><code>%s</code> (%s): %d failures</li>\n", e(fc.Command), e(fc.Role), fc.Failures)
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("<h2>Alerts</h2>\n")
+	if len(d.Alerts) == 0 {
+		b.WriteString("<p>None.</p>\n")
+	} else {
+		b.WriteString("<ul>\n")
+		for _, m := range d.Alerts {
+			fmt.Fprintf(&b, "<li>%s <b>%s</b> &rarr; %s: %s</li>\n", time.Unix(m.TS, 0).Format("01-02 15:04"), e(m.Action), e(m.To), e(m.Payload))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("<h2>Completed processes</h2>\n")
+	if len(d.Procs) == 0 {
+		b.WriteString("<p>None.</p>\n")
+	} else {
+		b.WriteString("<ul>\n")
+		for _, p := range d.Procs {
+			fmt.Fprintf(&b, "<li>%s <code>%s</code> (%s): %s</li>\n", time.Unix(p.FinishedAt, 0).Format("01-02 15:04"), e(p.Command), e(p.Owner), e(procOutcome(p)))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	fmt.Fprintf(&b, "<h2>Messages</h2>\n<p>%d messages", d.Report.Messages)
+	if d.Report.Messages > 0 {
+		fmt.Fprintf(&b, " (%s)", e(messageTypeSummary(d.Report.MessagesByType)))
+	}
+	b.WriteString(".</p>\n</body></html>\n")
+	return b.String()
+}
+
+// FormatDigest renders the digest in the given format (html or markdown).
+func FormatDigest(d Digest, format string) string {
+	if format == DigestMarkdown {
+		return FormatDigestMarkdown(d)
+	}
+	return FormatDigestHTML(d)
+}
+
+// digestEmail builds the RFC 5322 message for a digest.
+func digestEmail(from string, to []string, d Digest, format string, now time.Time) []byte {
+	contentType := "text/html; charset=UTF-8"
+	if format == DigestMarkdown {
+		contentType = "text/plain; charset=UTF-8"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", d.Subject())
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(FormatDigest(d, format), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// smtpSendMail sends mail (replaced in tests).
+var smtpSendMail = smtp.SendMail
+
+// SendDigest compiles the digest for the window ending now and emails it
+// through the configured SMTP server. to overrides the configured
+// recipients when non-empty. Returns the digest that was sent.
+func SendDigest(session string, cfg DigestConfig, window time.Duration, to []string, now time.Time) (Digest, error) {
+	if len(to) > 0 {
+		cfg.To = to
+	}
+	if msg := cfg.ready(); msg != "" {
+		return Digest{}, fmt.Errorf("%s", msg)
+	}
+	format := cfg.Format
+	if format == "" {
+		format = DigestHTML
+	}
+
+	d := BuildDigest(session, now.Add(-window).Unix(), now.Unix())
+
+	port := cfg.SMTP.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if cfg.SMTP.Username != "" {
+		env := cfg.SMTP.PasswordEnv
+		if env == "" {
+			env = SMTPPasswordEnv
+		}
+		auth = smtp.PlainAuth("", cfg.SMTP.Username, os.Getenv(env), cfg.SMTP.Host)
+	}
+
+	var rcpts []string
+	for _, t := range cfg.To {
+		a, err := mail.ParseAddress(t)
+		if err != nil {
+			return Digest{}, fmt.Errorf("invalid to address %q", t)
+		}
+		rcpts = append(rcpts, a.Address)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return Digest{}, fmt.Errorf("invalid from address %q", cfg.From)
+	}
+
+	if err := smtpSendMail(addr, auth, from.Address, rcpts, digestEmail(cfg.From, cfg.To, d, format, now)); err != nil {
+		return Digest{}, fmt.Errorf("sending digest: %w", err)
+	}
+	return d, nil
+}
+
+// executeDigestCron emails the digest for a cron entry targeting
+// DigestTarget. The entry's message is the window; empty uses the config.
+func executeDigestCron(session string, entry CronEntry) (string, error) {
+	cfg := Config().Digest
+	window := cfg.Window()
+	if s := strings.TrimSpace(entry.Message); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid digest window %q", s)
+		}
+		window = d
+	}
+	now := time.Now()
+	if _, err := SendDigest(session, cfg, window, nil, now); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("digest-%d", now.Unix()), nil
+}
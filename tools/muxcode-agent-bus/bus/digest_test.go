@@ -0,0 +1,209 @@
+package bus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// digestFixture records a failed build, a watcher alert, a routine
+// completion event, and two procs (one finished in the window).
+func digestFixture(t *testing.T, session string, now int64) {
+	t.Helper()
+	data, _ := json.Marshal(HistoryEntry{TS: now - 300, Command: "go build ./...", Outcome: "failure", ExitCode: "1"})
+	if err := AppendHistory(session, "build", data, 100); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Message{
+		NewMessage("watcher", "edit", "event", "loop-detected", "go build <x> failed 3x", ""),
+		NewMessage("watcher", "edit", "event", "proc-complete", "proc done", ""),
+		NewMessage("edit", "build", "request", "build", "go", ""),
+	} {
+		m.TS = now - 60
+		if err := Send(session, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WriteProcEntries(session, []ProcEntry{
+		{ID: "proc-1", Command: "make deploy", Owner: "deploy", Status: "failed", ExitCode: 2, FinishedAt: now - 120},
+		{ID: "proc-2", Command: "make old", Owner: "build", Status: "exited", FinishedAt: now - 90000},
+		{ID: "proc-3", Command: "sleep 100", Owner: "build", Status: "running"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	session := testSession(t)
+	now := int64(1_800_000_000)
+	digestFixture(t, session, now)
+
+	d := BuildDigest(session, now-8*3600, now)
+	if len(d.Alerts) != 1 || d.Alerts[0].Action != "loop-detected" {
+		t.Errorf("alerts = %+v", d.Alerts)
+	}
+	if len(d.Procs) != 1 || d.Procs[0].ID != "proc-1" {
+		t.Errorf("procs = %+v", d.Procs)
+	}
+	if digestRuns(d) != 1 {
+		t.Errorf("runs = %d, want 1", digestRuns(d))
+	}
+	want := "muxcode " + session + ": 1 commands, 1 failures, 1 alerts (last 8h)"
+	if got := d.Subject(); got != want {
+		t.Errorf("Subject = %q, want %q", got, want)
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	session := testSession(t)
+	now := int64(1_800_000_000)
+	digestFixture(t, session, now)
+	d := BuildDigest(session, now-90*60, now)
+
+	md := FormatDigest(d, DigestMarkdown)
+	for _, want := range []string{"# muxcode digest", "| build | 1 |", "**loop-detected**", "`make deploy` (deploy): failed (exit 2)", "## Messages"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+
+	h := FormatDigest(d, DigestHTML)
+	if !strings.Contains(h, "go build &lt;x&gt; failed 3x") {
+		t.Errorf("html payload not escaped:\n%s", h)
+	}
+	if strings.Contains(h, "<x>") {
+		t.Errorf("html contains raw payload:\n%s", h)
+	}
+	if formatDigestWindow(d) != "90m" {
+		t.Errorf("window = %q, want 90m", formatDigestWindow(d))
+	}
+}
+
+func TestDigestConfigValidate(t *testing.T) {
+	valid := DigestConfig{Format: DigestHTML, Hours: 24, From: "mux <mux@example.com>", To: []string{"me@example.com"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, c := range []DigestConfig{
+		{Format: "pdf"},
+		{Hours: -1},
+		{SMTP: SMTPConfig{Port: 70000}},
+		{From: "not an address"},
+		{To: []string{"me@example.com", "nope"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", c)
+		}
+	}
+	if w := (DigestConfig{}).Window(); w != defaultDigestHours*time.Hour {
+		t.Errorf("default window = %v", w)
+	}
+}
+
+// stubSendMail replaces smtpSendMail for one test and records the call.
+type sentMail struct {
+	addr string
+	from string
+	to   []string
+	msg  string
+}
+
+func stubSendMail(t *testing.T, err error) *[]sentMail {
+	t.Helper()
+	var sent []sentMail
+	orig := smtpSendMail
+	smtpSendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, sentMail{addr: addr, from: from, to: to, msg: string(msg)})
+		return err
+	}
+	t.Cleanup(func() { smtpSendMail = orig })
+	return &sent
+}
+
+func TestSendDigest(t *testing.T) {
+	session := testSession(t)
+	now := time.Unix(1_800_000_000, 0)
+	digestFixture(t, session, now.Unix())
+	sent := stubSendMail(t, nil)
+
+	cfg := DigestConfig{
+		From: "muxcode <mux@example.com>",
+		To:   []string{"Me <me@example.com>"},
+		SMTP: SMTPConfig{Host: "smtp.example.com"},
+	}
+	if _, err := SendDigest(session, cfg, 8*time.Hour, nil, now); err != nil {
+		t.Fatalf("SendDigest: %v", err)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(*sent))
+	}
+	m := (*sent)[0]
+	if m.addr != "smtp.example.com:587" || m.from != "mux@example.com" || len(m.to) != 1 || m.to[0] != "me@example.com" {
+		t.Errorf("envelope = %+v", m)
+	}
+	if !strings.Contains(m.msg, "Subject: muxcode "+session) || !strings.Contains(m.msg, "text/html") {
+		t.Errorf("message:\n%s", m.msg)
+	}
+
+	// --to overrides the configured recipients; markdown is sent as plain text
+	cfg.Format = DigestMarkdown
+	if _, err := SendDigest(session, cfg, time.Hour, []string{"other@example.com"}, now); err != nil {
+		t.Fatal(err)
+	}
+	m = (*sent)[1]
+	if m.to[0] != "other@example.com" || !strings.Contains(m.msg, "text/plain") {
+		t.Errorf("override mail = %+v", m)
+	}
+}
+
+func TestSendDigest_Errors(t *testing.T) {
+	session := testSession(t)
+	stubSendMail(t, errors.New("connection refused"))
+
+	if _, err := SendDigest(session, DigestConfig{}, time.Hour, nil, time.Now()); err == nil {
+		t.Error("expected error for unconfigured digest")
+	}
+	cfg := DigestConfig{From: "mux@example.com", To: []string{"me@example.com"}, SMTP: SMTPConfig{Host: "smtp.example.com"}}
+	_, err := SendDigest(session, cfg, time.Hour, nil, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("err = %v, want send failure", err)
+	}
+}
+
+func TestExecuteCron_Digest(t *testing.T) {
+	session := testSession(t)
+	sent := stubSendMail(t, nil)
+	SetConfig(&MuxcodeConfig{Digest: DigestConfig{
+		From: "mux@example.com",
+		To:   []string{"me@example.com"},
+		SMTP: SMTPConfig{Host: "smtp.example.com", Port: 2525},
+	}})
+	defer SetConfig(nil)
+
+	id, err := ExecuteCron(session, CronEntry{Target: DigestTarget, Message: "24h"})
+	if err != nil {
+		t.Fatalf("ExecuteCron: %v", err)
+	}
+	if !strings.HasPrefix(id, "digest-") {
+		t.Errorf("id = %q", id)
+	}
+	if len(*sent) != 1 || (*sent)[0].addr != "smtp.example.com:2525" {
+		t.Errorf("sent = %+v", *sent)
+	}
+	if _, err := ExecuteCron(session, CronEntry{Target: DigestTarget, Message: "soon"}); err == nil {
+		t.Error("expected error for invalid window")
+	}
+}
+
+func TestAddCronEntry_Digest(t *testing.T) {
+	session := testSession(t)
+	if _, err := AddCronEntry(session, CronEntry{Schedule: "@daily", Target: DigestTarget, Message: "24h", Enabled: true}); err != nil {
+		t.Errorf("AddCronEntry digest: %v", err)
+	}
+	if _, err := AddCronEntry(session, CronEntry{Schedule: "@daily", Target: DigestTarget, Message: "daily", Enabled: true}); err == nil {
+		t.Error("expected error for invalid digest window")
+	}
+}
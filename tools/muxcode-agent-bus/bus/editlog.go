@@ -0,0 +1,261 @@
+package bus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EditEvent is one file edit recorded by muxcode-analyze-hook.sh in the
+// session's edit log.
+type EditEvent struct {
+	TS         int64  `json:"ts"`
+	Path       string `json:"path"`
+	Op         string `json:"op"`   // create, write, or edit
+	Tool       string `json:"tool"` // Write, Edit, MultiEdit, NotebookEdit
+	Delta      int64  `json:"delta"`
+	HashBefore string `json:"hash_before,omitempty"` // sha256 of the previous content; empty when new or unknown
+	HashAfter  string `json:"hash_after,omitempty"`  // sha256 of the content after the edit
+}
+
+// FileEdits summarizes the edits to one file in a batch.
+type FileEdits struct {
+	Path       string
+	From       string // previous path when the file was renamed
+	Ops        []string
+	Tools      []string
+	Count      int
+	Delta      int64
+	HashBefore string // before the first edit
+	HashAfter  string // after the last edit
+	Last       int64  // timestamp of the last edit
+}
+
+// hookEvent is the subset of a Claude Code PostToolUse payload the edit
+// log reads.
+type hookEvent struct {
+	ToolName  string `json:"tool_name"`
+	ToolInput struct {
+		FilePath     string `json:"file_path"`
+		NotebookPath string `json:"notebook_path"`
+		OldString    string `json:"old_string"`
+		NewString    string `json:"new_string"`
+		ReplaceAll   bool   `json:"replace_all"`
+		Edits        []struct {
+			OldString  string `json:"old_string"`
+			NewString  string `json:"new_string"`
+			ReplaceAll bool   `json:"replace_all"`
+		} `json:"edits"`
+	} `json:"tool_input"`
+	ToolResponse struct {
+		Type         string  `json:"type"`         // Write: "create" or "update"
+		OriginalFile *string `json:"originalFile"` // Write: content before an update
+	} `json:"tool_response"`
+}
+
+// NewEditEvent builds an edit event from a PostToolUse hook payload,
+// hashing the file as it is now. The previous content is reconstructed by
+// reversing Edit and MultiEdit replacements, or taken from the Write
+// response when Claude Code includes it.
+func NewEditEvent(payload []byte) (EditEvent, error) {
+	var h hookEvent
+	if err := json.Unmarshal(payload, &h); err != nil {
+		return EditEvent{}, fmt.Errorf("invalid hook payload: %w", err)
+	}
+	ev := EditEvent{
+		TS:   time.Now().Unix(),
+		Path: h.ToolInput.FilePath,
+		Tool: h.ToolName,
+		Op:   "edit",
+	}
+	if ev.Path == "" {
+		ev.Path = h.ToolInput.NotebookPath
+	}
+	if ev.Path == "" {
+		return EditEvent{}, fmt.Errorf("hook payload has no file path")
+	}
+
+	after, err := os.ReadFile(ev.Path)
+	if err != nil {
+		return ev, nil // deleted since, or unreadable: record the path only
+	}
+	ev.HashAfter = contentHash(after)
+
+	var before *string
+	switch h.ToolName {
+	case "Write":
+		if h.ToolResponse.Type == "create" {
+			ev.Op = "create"
+			empty := ""
+			before = &empty
+		} else {
+			ev.Op = "write"
+			before = h.ToolResponse.OriginalFile
+		}
+	case "Edit":
+		b := reverseReplace(string(after), h.ToolInput.OldString, h.ToolInput.NewString, h.ToolInput.ReplaceAll)
+		before = &b
+	case "MultiEdit":
+		b := string(after)
+		for i := len(h.ToolInput.Edits) - 1; i >= 0; i-- {
+			e := h.ToolInput.Edits[i]
+			b = reverseReplace(b, e.OldString, e.NewString, e.ReplaceAll)
+		}
+		before = &b
+	}
+	if before != nil {
+		ev.Delta = int64(len(after) - len(*before))
+		if ev.Op != "create" {
+			ev.HashBefore = contentHash([]byte(*before))
+		}
+	}
+	return ev, nil
+}
+
+// reverseReplace undoes an Edit replacement of old with new in content.
+func reverseReplace(content, old, new string, all bool) string {
+	if new == "" || !strings.Contains(content, new) {
+		return content
+	}
+	if all {
+		return strings.ReplaceAll(content, new, old)
+	}
+	return strings.Replace(content, new, old, 1)
+}
+
+// contentHash returns the hex sha256 of data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AppendEditEvent appends an event to the session's edit log.
+func AppendEditEvent(session string, ev EditEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(EditLogPath(session), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ParseEditLine parses one edit log line. Lines in the old trigger format
+// ("timestamp path") are accepted as edits with no hashes.
+func ParseEditLine(line string) (EditEvent, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return EditEvent{}, false
+	}
+	if strings.HasPrefix(line, "{") {
+		var ev EditEvent
+		if json.Unmarshal([]byte(line), &ev) != nil || ev.Path == "" {
+			return EditEvent{}, false
+		}
+		return ev, true
+	}
+	ev := EditEvent{Op: "edit", Path: line}
+	if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+		if ts, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+			ev.TS = ts
+			ev.Path = strings.TrimSpace(parts[1])
+		}
+	}
+	return ev, ev.Path != ""
+}
+
+// SummarizeEdits merges events per file in first-edit order. A created
+// file whose content matches the last known content of a batch file that
+// no longer exists is reported as a rename of it.
+func SummarizeEdits(events []EditEvent) []FileEdits {
+	byPath := make(map[string]*FileEdits)
+	var order []string
+	for _, ev := range events {
+		f, ok := byPath[ev.Path]
+		if !ok {
+			f = &FileEdits{Path: ev.Path, HashBefore: ev.HashBefore}
+			byPath[ev.Path] = f
+			order = append(order, ev.Path)
+		}
+		f.Count++
+		f.Delta += ev.Delta
+		if ev.HashAfter != "" {
+			f.HashAfter = ev.HashAfter
+		}
+		if ev.TS > f.Last {
+			f.Last = ev.TS
+		}
+		f.Ops = appendUnique(f.Ops, ev.Op)
+		if ev.Tool != "" {
+			f.Tools = appendUnique(f.Tools, ev.Tool)
+		}
+	}
+
+	renamed := make(map[string]bool)
+	for _, p := range order {
+		f := byPath[p]
+		if len(f.Ops) == 0 || f.Ops[0] != "create" || f.HashAfter == "" {
+			continue
+		}
+		for _, q := range order {
+			g := byPath[q]
+			if q == p || renamed[q] || g.HashAfter != f.HashAfter {
+				continue
+			}
+			if _, err := os.Stat(q); os.IsNotExist(err) {
+				f.From = q
+				renamed[q] = true
+				break
+			}
+		}
+	}
+
+	out := make([]FileEdits, 0, len(order))
+	for _, p := range order {
+		if !renamed[p] {
+			out = append(out, *byPath[p])
+		}
+	}
+	return out
+}
+
+// appendUnique appends s to list unless it is already present.
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// Describe returns a short description of the file's edits for the analyze
+// payload, e.g. "main.go (3 edits, +120 bytes)".
+func (f FileEdits) Describe() string {
+	var parts []string
+	switch {
+	case f.From != "":
+		parts = append(parts, "renamed from "+f.From)
+	case len(f.Ops) > 0 && f.Ops[0] == "create":
+		parts = append(parts, "created")
+	}
+	if f.Count > 1 {
+		parts = append(parts, fmt.Sprintf("%d edits", f.Count))
+	}
+	if f.Delta != 0 {
+		parts = append(parts, fmt.Sprintf("%+d bytes", f.Delta))
+	}
+	if len(parts) == 0 {
+		return f.Path
+	}
+	return f.Path + " (" + strings.Join(parts, ", ") + ")"
+}
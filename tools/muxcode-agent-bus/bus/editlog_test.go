@@ -0,0 +1,97 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewEditEvent_Edit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	before := "package main\n\nfunc main() {}\n"
+	after := "package main\n\nfunc main() { run() }\n"
+	if err := os.WriteFile(path, []byte(after), 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload := `{"tool_name":"Edit","tool_input":{"file_path":"` + path + `","old_string":"func main() {}","new_string":"func main() { run() }"}}`
+
+	ev, err := NewEditEvent([]byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Op != "edit" || ev.Tool != "Edit" || ev.Path != path {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Delta != int64(len(after)-len(before)) {
+		t.Errorf("delta = %d", ev.Delta)
+	}
+	if ev.HashBefore != contentHash([]byte(before)) || ev.HashAfter != contentHash([]byte(after)) {
+		t.Errorf("hashes = %s -> %s", ev.HashBefore, ev.HashAfter)
+	}
+}
+
+func TestNewEditEvent_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := NewEditEvent([]byte(`{"tool_name":"Write","tool_input":{"file_path":"` + path + `"},"tool_response":{"type":"create"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Op != "create" || ev.Delta != 6 || ev.HashBefore != "" {
+		t.Errorf("create = %+v", ev)
+	}
+
+	ev, _ = NewEditEvent([]byte(`{"tool_name":"Write","tool_input":{"file_path":"` + path + `"},"tool_response":{"type":"update","originalFile":"hi\n"}}`))
+	if ev.Op != "write" || ev.Delta != 3 || ev.HashBefore != contentHash([]byte("hi\n")) {
+		t.Errorf("update = %+v", ev)
+	}
+
+	if _, err := NewEditEvent([]byte(`{"tool_name":"Write","tool_input":{}}`)); err == nil {
+		t.Error("payload without a path should fail")
+	}
+}
+
+func TestParseEditLine(t *testing.T) {
+	ev, ok := ParseEditLine(`{"ts":5,"path":"a.go","op":"edit","delta":3}`)
+	if !ok || ev.Path != "a.go" || ev.Delta != 3 {
+		t.Errorf("json line = %+v, %v", ev, ok)
+	}
+	ev, ok = ParseEditLine("1718000000 /repo/my file.go")
+	if !ok || ev.TS != 1718000000 || ev.Path != "/repo/my file.go" {
+		t.Errorf("legacy line = %+v, %v", ev, ok)
+	}
+	if _, ok := ParseEditLine(`{"ts":5}`); ok {
+		t.Error("event without a path should be skipped")
+	}
+}
+
+func TestSummarizeEdits(t *testing.T) {
+	dir := t.TempDir()
+	gone := filepath.Join(dir, "old.go")
+	moved := filepath.Join(dir, "new.go")
+	writeIndicatorFile(t, dir, "new.go", "x")
+
+	got := SummarizeEdits([]EditEvent{
+		{TS: 1, Path: "a.go", Op: "edit", Delta: 10, HashBefore: "h0", HashAfter: "h1"},
+		{TS: 3, Path: gone, Op: "edit", HashAfter: "same"},
+		{TS: 2, Path: "a.go", Op: "edit", Delta: -4, HashBefore: "h1", HashAfter: "h2"},
+		{TS: 4, Path: moved, Op: "create", Delta: 1, HashAfter: "same"},
+	})
+	if len(got) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	a := got[0]
+	if a.Count != 2 || a.Delta != 6 || a.HashBefore != "h0" || a.HashAfter != "h2" || a.Last != 2 {
+		t.Errorf("a.go = %+v", a)
+	}
+	if d := a.Describe(); d != "a.go (2 edits, +6 bytes)" {
+		t.Errorf("describe = %q", d)
+	}
+	if got[1].From != gone || !strings.Contains(got[1].Describe(), "renamed from "+gone) {
+		t.Errorf("rename = %+v", got[1])
+	}
+}
@@ -0,0 +1,365 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// DefaultEmbedModel is the Ollama model used for memory embeddings.
+const DefaultEmbedModel = "nomic-embed-text"
+
+// hybridBM25Weight is the share of the hybrid score given to BM25; the
+// remainder goes to vector similarity. Both are normalized to 0-1 first.
+const hybridBM25Weight = 0.5
+
+// Embedder turns text into an embedding vector.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// EmbeddingRecord is one line of the embedding index.
+type EmbeddingRecord struct {
+	Key    string    `json:"key"`
+	Model  string    `json:"model"`
+	Vector []float64 `json:"vector"`
+}
+
+// OllamaEmbedder calls Ollama's /api/embeddings endpoint.
+type OllamaEmbedder struct {
+	BaseURL string
+	Model   string
+	HTTP    *http.Client
+}
+
+// EmbedModel returns the embedding model from MUXCODE_EMBED_MODEL,
+// falling back to DefaultEmbedModel.
+func EmbedModel() string {
+	if v := os.Getenv("MUXCODE_EMBED_MODEL"); v != "" {
+		return v
+	}
+	return DefaultEmbedModel
+}
+
+// NewOllamaEmbedder creates an embedder using the default Ollama URL and
+// the configured embedding model.
+func NewOllamaEmbedder() *OllamaEmbedder {
+	cfg := DefaultOllamaConfig()
+	return &OllamaEmbedder{
+		BaseURL: cfg.BaseURL,
+		Model:   EmbedModel(),
+		HTTP:    &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+// Embed returns the embedding vector for text.
+func (e *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	body, err := json.Marshal(map[string]string{"model": e.Model, "prompt": text})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/api/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Ollama at %s: %w", e.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var out struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(out.Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding from model %q", e.Model)
+	}
+	return out.Embedding, nil
+}
+
+// embeddingKey returns a stable content hash for a memory entry.
+func embeddingKey(entry MemoryEntry) string {
+	h := sha256.Sum256([]byte(entry.Role + "\x00" + entry.Section + "\x00" + entry.Timestamp + "\x00" + entry.Content))
+	return hex.EncodeToString(h[:12])
+}
+
+// embeddingText is the text embedded for a memory entry.
+func embeddingText(entry MemoryEntry) string {
+	return entry.Section + "\n" + entry.Content
+}
+
+// LoadEmbeddingIndex reads the embedding index keyed by entry hash.
+// Returns an empty map if the index does not exist.
+func LoadEmbeddingIndex() (map[string]EmbeddingRecord, error) {
+	index := make(map[string]EmbeddingRecord)
+	data, err := os.ReadFile(EmbeddingIndexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec EmbeddingRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			continue
+		}
+		index[rec.Key] = rec
+	}
+	return index, nil
+}
+
+// writeEmbeddingIndex rewrites the index with the given records.
+func writeEmbeddingIndex(records []EmbeddingRecord) error {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := os.MkdirAll(MemoryDir(), 0755); err != nil {
+		return err
+	}
+	tmp := EmbeddingIndexPath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, EmbeddingIndexPath())
+}
+
+// UpdateEmbeddingIndex embeds memory entries missing from the index (or
+// embedded with a different model) and drops records for entries that no
+// longer exist. With rebuild, every entry is re-embedded. Returns the number
+// of entries embedded and the current index.
+func UpdateEmbeddingIndex(ctx context.Context, emb Embedder, model string, entries []MemoryEntry, rebuild bool) (int, map[string]EmbeddingRecord, error) {
+	index, err := LoadEmbeddingIndex()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	embedded := 0
+	fresh := make(map[string]EmbeddingRecord, len(entries))
+	var records []EmbeddingRecord
+	for _, entry := range entries {
+		key := embeddingKey(entry)
+		if _, dup := fresh[key]; dup {
+			continue
+		}
+		rec, ok := index[key]
+		if rebuild || !ok || rec.Model != model {
+			vec, err := emb.Embed(ctx, embeddingText(entry))
+			if err != nil {
+				// Persist progress (without pruning) so a retry resumes here
+				if embedded > 0 {
+					partial := records
+					for k, r := range index {
+						if _, ok := fresh[k]; !ok {
+							partial = append(partial, r)
+						}
+					}
+					_ = writeEmbeddingIndex(partial)
+				}
+				return embedded, nil, fmt.Errorf("embedding %q: %w", entry.Section, err)
+			}
+			rec = EmbeddingRecord{Key: key, Model: model, Vector: vec}
+			embedded++
+		}
+		fresh[key] = rec
+		records = append(records, rec)
+	}
+
+	if embedded > 0 || len(records) != len(index) {
+		if err := writeEmbeddingIndex(records); err != nil {
+			return embedded, nil, err
+		}
+	}
+	return embedded, fresh, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors,
+// or 0 when dimensions differ or either vector is zero.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// filterEntriesByRole returns entries matching roleFilter (all if empty).
+func filterEntriesByRole(entries []MemoryEntry, roleFilter string) []MemoryEntry {
+	if roleFilter == "" {
+		return entries
+	}
+	var filtered []MemoryEntry
+	for _, entry := range entries {
+		if entry.Role == roleFilter {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// semanticScores embeds the query and scores entries by cosine similarity.
+// The index is updated from all memory entries first (so a role-filtered
+// search does not prune other roles' records).
+func semanticScores(opts SearchOptions, all, entries []MemoryEntry) ([]SearchResult, error) {
+	emb := opts.Embedder
+	model := EmbedModel()
+	if emb == nil {
+		oe := NewOllamaEmbedder()
+		emb, model = oe, oe.Model
+	}
+
+	ctx := context.Background()
+	_, index, err := UpdateEmbeddingIndex(ctx, emb, model, all, false)
+	if err != nil {
+		return nil, err
+	}
+	qvec, err := emb.Embed(ctx, opts.Query)
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+
+	var results []SearchResult
+	for _, entry := range entries {
+		rec, ok := index[embeddingKey(entry)]
+		if !ok {
+			continue
+		}
+		if score := cosineSimilarity(qvec, rec.Vector); score > 0 {
+			results = append(results, SearchResult{Entry: entry, Score: score})
+		}
+	}
+	return results, nil
+}
+
+// SearchMemorySemantic ranks memory entries by embedding similarity to the
+// query. Catches paraphrases BM25 misses ("push container" vs "deploy
+// docker image"). Requires an embedding model in Ollama.
+func SearchMemorySemantic(opts SearchOptions) ([]SearchResult, error) {
+	all, err := AllMemoryEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries := filterEntriesByRole(all, opts.RoleFilter)
+	if len(entries) == 0 || opts.Query == "" {
+		return nil, nil
+	}
+
+	results, err := semanticScores(opts, all, entries)
+	if err != nil {
+		return nil, err
+	}
+	return rankResults(results, opts.Limit), nil
+}
+
+// SearchMemoryHybrid blends normalized BM25 and vector scores so exact
+// keyword hits and paraphrases both rank.
+func SearchMemoryHybrid(opts SearchOptions) ([]SearchResult, error) {
+	all, err := AllMemoryEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries := filterEntriesByRole(all, opts.RoleFilter)
+	if len(entries) == 0 || opts.Query == "" {
+		return nil, nil
+	}
+
+	bm25Opts := opts
+	bm25Opts.Limit = 0
+	keyword, err := SearchMemoryBM25(bm25Opts)
+	if err != nil {
+		return nil, err
+	}
+	semantic, err := semanticScores(opts, all, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBM25 := 0.0
+	for _, r := range keyword {
+		if r.Score > maxBM25 {
+			maxBM25 = r.Score
+		}
+	}
+
+	combined := make(map[string]*SearchResult)
+	var order []string
+	add := func(entry MemoryEntry, score float64) {
+		key := embeddingKey(entry)
+		if r, ok := combined[key]; ok {
+			r.Score += score
+			return
+		}
+		combined[key] = &SearchResult{Entry: entry, Score: score}
+		order = append(order, key)
+	}
+	for _, r := range keyword {
+		if maxBM25 > 0 {
+			add(r.Entry, hybridBM25Weight*r.Score/maxBM25)
+		}
+	}
+	for _, r := range semantic {
+		add(r.Entry, (1-hybridBM25Weight)*r.Score)
+	}
+
+	results := make([]SearchResult, 0, len(order))
+	for _, key := range order {
+		results = append(results, *combined[key])
+	}
+	return rankResults(results, opts.Limit), nil
+}
+
+// rankResults sorts results by descending score and applies limit.
+func rankResults(results []SearchResult, limit int) []SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,226 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// conceptEmbedder maps words to concept dimensions so paraphrases share a
+// vector without sharing tokens, standing in for a real embedding model.
+type conceptEmbedder struct {
+	calls int
+	fail  bool
+}
+
+var testConcepts = map[string]int{
+	"push": 0, "deploy": 0, "ship": 0, "release": 0,
+	"container": 1, "docker": 1, "image": 1,
+	"pnpm": 2, "package": 2, "npm": 2,
+	"test": 3, "jest": 3,
+}
+
+func (c *conceptEmbedder) Embed(_ context.Context, text string) ([]float64, error) {
+	c.calls++
+	if c.fail {
+		return nil, errors.New("embedder down")
+	}
+	vec := make([]float64, 5)
+	for _, tok := range tokenize(text) {
+		if dim, ok := testConcepts[tok]; ok {
+			vec[dim]++
+		} else {
+			vec[4] += 0.1
+		}
+	}
+	return vec, nil
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if s := cosineSimilarity([]float64{1, 0}, []float64{1, 0}); math.Abs(s-1) > 1e-9 {
+		t.Errorf("identical = %v, want 1", s)
+	}
+	if s := cosineSimilarity([]float64{1, 0}, []float64{0, 1}); s != 0 {
+		t.Errorf("orthogonal = %v, want 0", s)
+	}
+	if s := cosineSimilarity([]float64{1, 0}, []float64{1, 0, 0}); s != 0 {
+		t.Errorf("mismatched dims = %v, want 0", s)
+	}
+	if s := cosineSimilarity([]float64{0, 0}, []float64{1, 0}); s != 0 {
+		t.Errorf("zero vector = %v, want 0", s)
+	}
+}
+
+func TestSearchMemorySemantic_Paraphrase(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	if err := AppendMemory("Release Process", "deploy docker image to ECR", "deploy"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+
+	emb := &conceptEmbedder{}
+
+	// BM25 shares no tokens with the paraphrase
+	bm25, err := SearchMemoryBM25(SearchOptions{Query: "push container"})
+	if err != nil {
+		t.Fatalf("SearchMemoryBM25: %v", err)
+	}
+	if len(bm25) != 0 {
+		t.Fatalf("expected BM25 to miss paraphrase, got %d results", len(bm25))
+	}
+
+	results, err := SearchMemoryWithOptions(SearchOptions{Query: "push container", Mode: SearchModeSemantic, Embedder: emb})
+	if err != nil {
+		t.Fatalf("semantic search: %v", err)
+	}
+	if len(results) == 0 || results[0].Entry.Section != "Release Process" {
+		t.Fatalf("expected 'Release Process' first, got %+v", results)
+	}
+}
+
+func TestUpdateEmbeddingIndex_Incremental(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	if err := AppendMemory("One", "deploy docker image", "shared"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	entries, _ := AllMemoryEntries()
+
+	emb := &conceptEmbedder{}
+	n, index, err := UpdateEmbeddingIndex(context.Background(), emb, "m1", entries, false)
+	if err != nil {
+		t.Fatalf("UpdateEmbeddingIndex: %v", err)
+	}
+	if n != 1 || len(index) != 1 {
+		t.Fatalf("first update: embedded %d, indexed %d, want 1/1", n, len(index))
+	}
+
+	// Second run with no changes embeds nothing
+	n, _, err = UpdateEmbeddingIndex(context.Background(), emb, "m1", entries, false)
+	if err != nil || n != 0 {
+		t.Errorf("unchanged update: embedded %d, err %v, want 0/nil", n, err)
+	}
+
+	// New entry embeds only the new one; persisted index has both
+	if err := AppendMemory("Two", "run jest tests", "shared"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	entries, _ = AllMemoryEntries()
+	n, _, err = UpdateEmbeddingIndex(context.Background(), emb, "m1", entries, false)
+	if err != nil || n != 1 {
+		t.Errorf("incremental update: embedded %d, err %v, want 1/nil", n, err)
+	}
+	loaded, err := LoadEmbeddingIndex()
+	if err != nil || len(loaded) != 2 {
+		t.Errorf("persisted index has %d records (err %v), want 2", len(loaded), err)
+	}
+
+	// Model change re-embeds everything
+	n, _, _ = UpdateEmbeddingIndex(context.Background(), emb, "m2", entries, false)
+	if n != 2 {
+		t.Errorf("model change: embedded %d, want 2", n)
+	}
+
+	// Removed entries are pruned
+	n, index, _ = UpdateEmbeddingIndex(context.Background(), emb, "m2", entries[:1], false)
+	if n != 0 || len(index) != 1 {
+		t.Errorf("prune: embedded %d, indexed %d, want 0/1", n, len(index))
+	}
+}
+
+func TestSearchMemorySemantic_RoleFilterKeepsIndex(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	_ = AppendMemory("Deploy", "ship docker image", "deploy")
+	_ = AppendMemory("Build", "pnpm install", "build")
+
+	emb := &conceptEmbedder{}
+	results, err := SearchMemorySemantic(SearchOptions{Query: "release container", RoleFilter: "build", Embedder: emb})
+	if err != nil {
+		t.Fatalf("SearchMemorySemantic: %v", err)
+	}
+	for _, r := range results {
+		if r.Entry.Role != "build" {
+			t.Errorf("role filter leaked %q", r.Entry.Role)
+		}
+	}
+
+	loaded, _ := LoadEmbeddingIndex()
+	if len(loaded) != 2 {
+		t.Errorf("role-filtered search pruned index to %d records, want 2", len(loaded))
+	}
+}
+
+func TestSearchMemoryHybrid(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	_ = AppendMemory("Docker Push", "push container to registry", "deploy")
+	_ = AppendMemory("Release Process", "deploy docker image to ECR", "deploy")
+	_ = AppendMemory("Build Config", "use pnpm for all builds", "build")
+
+	results, err := SearchMemoryHybrid(SearchOptions{Query: "push container", Embedder: &conceptEmbedder{}})
+	if err != nil {
+		t.Fatalf("SearchMemoryHybrid: %v", err)
+	}
+	if len(results) < 2 {
+		t.Fatalf("expected keyword and paraphrase hits, got %d", len(results))
+	}
+	// Exact keyword match gets both BM25 and vector score
+	if results[0].Entry.Section != "Docker Push" {
+		t.Errorf("expected 'Docker Push' first, got %q", results[0].Entry.Section)
+	}
+	if results[1].Entry.Section != "Release Process" {
+		t.Errorf("expected paraphrase 'Release Process' second, got %q", results[1].Entry.Section)
+	}
+}
+
+func TestSearchMemorySemantic_EmbedderError(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	_ = AppendMemory("A", "deploy", "shared")
+
+	_, err := SearchMemorySemantic(SearchOptions{Query: "ship", Embedder: &conceptEmbedder{fail: true}})
+	if err == nil {
+		t.Fatal("expected error from failing embedder")
+	}
+}
+
+func TestOllamaEmbedder_Embed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model  string `json:"model"`
+			Prompt string `json:"prompt"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "embed-test" || req.Prompt != "hello" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]float64{"embedding": {0.1, 0.2, 0.3}})
+	}))
+	defer srv.Close()
+
+	e := &OllamaEmbedder{BaseURL: srv.URL, Model: "embed-test", HTTP: srv.Client()}
+	vec, err := e.Embed(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vec) != 3 {
+		t.Errorf("got %d dims, want 3", len(vec))
+	}
+
+	e.Model = "other"
+	if _, err := e.Embed(context.Background(), "hello"); err == nil {
+		t.Error("expected error for 400 response")
+	}
+}
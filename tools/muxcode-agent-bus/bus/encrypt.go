@@ -0,0 +1,584 @@
+package bus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BusKeyEnv holds a base64 bus key that takes the place of the keychain,
+// for headless machines and CI.
+const BusKeyEnv = "MUXCODE_BUS_KEY"
+
+// busKeyService is the keychain service name bus keys are stored under.
+const busKeyService = "muxcode-bus"
+
+// defaultBusKeyName is the keychain account used when encryption.key_name
+// is unset.
+const defaultBusKeyName = "default"
+
+// sealedPrefix marks an encrypted line: "enc:v1:" + base64(nonce||ciphertext).
+var sealedPrefix = []byte("enc:v1:")
+
+// ErrSealed is returned (wrapped) when encrypted bus data cannot be
+// decrypted: no key is available or the key does not match.
+var ErrSealed = errors.New("cannot decrypt encrypted bus data")
+
+// EncryptionConfig is the encryption section of the config. When enabled,
+// inbox, log, inbox archive, and memory files are written encrypted with
+// AES-256-GCM, one sealed line per message or memory entry. Reads decrypt
+// transparently whether or not encryption is enabled.
+type EncryptionConfig struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	KeyName string `json:"key_name,omitempty"` // keychain account (default "default")
+}
+
+// Validate checks the key name.
+func (c EncryptionConfig) Validate() error {
+	if strings.ContainsAny(c.KeyName, " \t\n/") {
+		return fmt.Errorf("key_name %q must not contain spaces or slashes", c.KeyName)
+	}
+	return nil
+}
+
+// keyName returns the keychain account for the current key.
+func (c EncryptionConfig) keyName() string {
+	if c.KeyName != "" {
+		return c.KeyName
+	}
+	return defaultBusKeyName
+}
+
+// EncryptionEnabled reports whether new bus writes are encrypted.
+func EncryptionEnabled() bool {
+	return Config().Encryption.Enabled
+}
+
+// keychainGet and keychainSet read and store a secret in the OS keychain
+// (security on macOS, secret-tool elsewhere). Replaceable in tests.
+var (
+	keychainGet = osKeychainGet
+	keychainSet = osKeychainSet
+)
+
+func osKeychainGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", busKeyService, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", busKeyService, "account", account)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain lookup %s/%s: %v", busKeyService, account, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// osKeychainSet passes the secret on stdin so it never shows in ps. On
+// macOS, security -i reads the whole command from stdin; since it exits 0
+// even when a command fails, the stored secret is read back to confirm.
+func osKeychainSet(account, secret string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		line, err := securityCommandLine("add-generic-password", "-U", "-s", busKeyService, "-a", account, "-w", secret)
+		if err != nil {
+			return fmt.Errorf("keychain store %s/%s: %v", busKeyService, account, err)
+		}
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(line)
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label", "muxcode bus key ("+account+")", "service", busKeyService, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain store %s/%s: %v: %s", busKeyService, account, err, strings.TrimSpace(string(out)))
+	}
+	if runtime.GOOS == "darwin" {
+		if got, err := osKeychainGet(account); err != nil || got != secret {
+			return fmt.Errorf("keychain store %s/%s: secret not stored", busKeyService, account)
+		}
+	}
+	return nil
+}
+
+// securityCommandLine quotes args as one line for security -i, which splits
+// its input like a shell. Quotes, backslashes, and newlines are refused
+// rather than escaped.
+func securityCommandLine(args ...string) (string, error) {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if strings.ContainsAny(a, "\"\\\n") {
+			return "", fmt.Errorf("argument %d contains a quote, backslash, or newline", i)
+		}
+		quoted[i] = `"` + a + `"`
+	}
+	return strings.Join(quoted, " ") + "\n", nil
+}
+
+// busKeyRing is the loaded keys: the current key seals, and the previous
+// key (kept by rekey) still opens lines written before the rekey finished.
+type busKeyRing struct {
+	current  []byte
+	previous []byte
+	source   string
+	err      error
+}
+
+var (
+	busKeysMu     sync.Mutex
+	busKeysCached *busKeyRing
+)
+
+// resetBusKeys drops the cached keys so the next use reloads them.
+func resetBusKeys() {
+	busKeysMu.Lock()
+	busKeysCached = nil
+	busKeysMu.Unlock()
+}
+
+// decodeBusKey parses a base64 256-bit key.
+func decodeBusKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("bus key is not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("bus key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// busKeys loads the key ring once per process: BusKeyEnv if set, otherwise
+// the keychain entries "<key_name>" and "<key_name>.prev".
+func busKeys() *busKeyRing {
+	busKeysMu.Lock()
+	defer busKeysMu.Unlock()
+	if busKeysCached != nil {
+		return busKeysCached
+	}
+	ring := &busKeyRing{}
+	if v := os.Getenv(BusKeyEnv); v != "" {
+		ring.source = "$" + BusKeyEnv
+		ring.current, ring.err = decodeBusKey(v)
+	} else {
+		name := Config().Encryption.keyName()
+		ring.source = "keychain " + busKeyService + "/" + name
+		if v, err := keychainGet(name); err != nil {
+			ring.err = fmt.Errorf("no bus key (run: muxcode-agent-bus bus rekey): %v", err)
+		} else {
+			ring.current, ring.err = decodeBusKey(v)
+		}
+		if v, err := keychainGet(name + ".prev"); err == nil {
+			ring.previous, _ = decodeBusKey(v)
+		}
+	}
+	busKeysCached = ring
+	return ring
+}
+
+// sealWith encrypts data into one sealed line (with trailing newline).
+func sealWith(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, data, nil)
+	out := make([]byte, 0, len(sealedPrefix)+base64.StdEncoding.EncodedLen(len(sealed))+1)
+	out = append(out, sealedPrefix...)
+	out = base64.StdEncoding.AppendEncode(out, sealed)
+	return append(out, '\n'), nil
+}
+
+// openWith decrypts the body of a sealed line (after the prefix).
+func openWith(key, body []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(body))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed line too short")
+	}
+	return gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+}
+
+// isSealed reports whether a line is encrypted.
+func isSealed(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(line), sealedPrefix)
+}
+
+// SealLine encrypts data (one message line or memory entry, including its
+// trailing newline) when encryption is enabled; otherwise it returns data
+// unchanged.
+func SealLine(data []byte) ([]byte, error) {
+	if !EncryptionEnabled() {
+		return data, nil
+	}
+	ring := busKeys()
+	if ring.err != nil {
+		return nil, ring.err
+	}
+	return sealWith(ring.current, data)
+}
+
+// UnsealLine decrypts a sealed line, returning the original bytes
+// (including the trailing newline). Plain lines are returned unchanged.
+func UnsealLine(line []byte) ([]byte, error) {
+	if !isSealed(line) {
+		return line, nil
+	}
+	body := bytes.TrimPrefix(bytes.TrimSpace(line), sealedPrefix)
+	ring := busKeys()
+	if ring.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealed, ring.err)
+	}
+	for _, key := range [][]byte{ring.current, ring.previous} {
+		if key == nil {
+			continue
+		}
+		if data, err := openWith(key, body); err == nil {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: key from %s does not match", ErrSealed, ring.source)
+}
+
+// unsealContent decrypts every sealed line of a file's content, leaving
+// plain lines as they are.
+func unsealContent(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, sealedPrefix) {
+		return data, nil
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if !isSealed(line) {
+			out.Write(line)
+			continue
+		}
+		plain, err := UnsealLine(line)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(plain)
+	}
+	return out.Bytes(), nil
+}
+
+// readBusFile reads a file and decrypts its sealed lines.
+func readBusFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return unsealContent(data)
+}
+
+// writeBusFile writes content as a single sealed line when encryption is
+// enabled, or as-is otherwise. Used for whole-file rewrites of memory.
+func writeBusFile(path string, content []byte) error {
+	if len(content) > 0 {
+		sealed, err := SealLine(content)
+		if err != nil {
+			return err
+		}
+		content = sealed
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// checkSealed returns an error when a file holds sealed lines that cannot
+// be decrypted, so consuming reads leave it untouched.
+func checkSealed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(data, sealedPrefix) {
+		return nil
+	}
+	if ring := busKeys(); ring.err != nil {
+		return fmt.Errorf("%w: %v", ErrSealed, ring.err)
+	}
+	return nil
+}
+
+// EncryptionStatus summarizes encryption for bus status.
+type EncryptionStatus struct {
+	Enabled   bool
+	KeySource string
+	KeyError  string
+	Sealed    int // files holding at least one encrypted line
+	Plain     int // non-empty files with no encrypted lines
+}
+
+// encryptedFiles lists the session's message files and the memory files
+// covered by encryption.
+func encryptedFiles(session string) (text, gz []string) {
+	text, _ = filepath.Glob(filepath.Join(BusDir(session), "inbox", "*.jsonl"))
+	text = append(text, LogPath(session))
+	mem, _ := filepath.Glob(filepath.Join(MemoryDir(), "*.md"))
+	archived, _ := filepath.Glob(filepath.Join(MemoryDir(), "*", "*.md"))
+	text = append(append(text, mem...), archived...)
+	gz, _ = filepath.Glob(filepath.Join(ArchiveDir(session), "*.jsonl.gz"))
+	return text, gz
+}
+
+// readGzip returns the decompressed content of a gzip file.
+func readGzip(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// GetEncryptionStatus reports whether encryption is on, where the key comes
+// from, and how many covered files are encrypted.
+func GetEncryptionStatus(session string) EncryptionStatus {
+	st := EncryptionStatus{Enabled: EncryptionEnabled()}
+	ring := busKeys()
+	st.KeySource = ring.source
+	if ring.err != nil {
+		st.KeyError = ring.err.Error()
+	}
+	text, gz := encryptedFiles(session)
+	count := func(data []byte) {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return
+		}
+		if bytes.Contains(data, sealedPrefix) {
+			st.Sealed++
+		} else {
+			st.Plain++
+		}
+	}
+	for _, path := range text {
+		if data, err := os.ReadFile(path); err == nil {
+			count(data)
+		}
+	}
+	for _, path := range gz {
+		if data, err := readGzip(path); err == nil {
+			count(data)
+		}
+	}
+	return st
+}
+
+// FormatEncryptionStatus renders the encryption status.
+func FormatEncryptionStatus(st EncryptionStatus) string {
+	var b strings.Builder
+	state := "disabled"
+	if st.Enabled {
+		state = "enabled"
+	}
+	fmt.Fprintf(&b, "Encryption: %s\n", state)
+	fmt.Fprintf(&b, "Key:        %s", st.KeySource)
+	if st.KeyError != "" {
+		fmt.Fprintf(&b, " (unavailable: %s)", st.KeyError)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Files:      %d encrypted, %d plain\n", st.Sealed, st.Plain)
+	return b.String()
+}
+
+// resealLines re-encrypts content line by line with key, decrypting sealed
+// lines first. JSONL files keep one sealed line per message.
+func resealLines(data, key []byte) ([]byte, error) {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		plain, err := UnsealLine(line)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := sealWith(key, plain)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(sealed)
+	}
+	return out.Bytes(), nil
+}
+
+// resealFile rewrites a file under a new key. Like compactFile it renames
+// the file aside first, so lines appended meanwhile are kept (sealed with
+// the new key if they arrive after the key switch, or the previous key,
+// which stays readable).
+func resealFile(path string, reseal func([]byte) ([]byte, error), compress bool) error {
+	aside := path + ".rekeying"
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	_ = touchFile(path)
+
+	var data []byte
+	var err error
+	if compress {
+		data, err = readGzip(aside)
+	} else {
+		data, err = os.ReadFile(aside)
+	}
+	if err == nil {
+		data, err = reseal(data)
+	}
+	if err != nil {
+		_ = os.Rename(aside, path)
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		data = buf.Bytes()
+	}
+	newData, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(data, newData...), 0644); err != nil {
+		_ = os.Rename(aside, path)
+		return err
+	}
+	return os.Remove(aside)
+}
+
+// Rekey generates a new bus key, stores it in the keychain (keeping the old
+// key as "<key_name>.prev" so nothing becomes unreadable mid-way), and
+// re-encrypts the session's message files, all memory files, and memory
+// records in the SQLite store with it. Plain data is encrypted too, so
+// enabling encryption and running rekey migrates existing data. It returns
+// the number of files and store streams rewritten.
+func Rekey(session string) (int, error) {
+	if !EncryptionEnabled() {
+		return 0, fmt.Errorf("encryption is not enabled (set encryption.enabled in muxcode.json)")
+	}
+	if os.Getenv(BusKeyEnv) != "" {
+		return 0, fmt.Errorf("%s is set; rekey manages keychain keys only", BusKeyEnv)
+	}
+
+	name := Config().Encryption.keyName()
+	old := busKeys()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return 0, err
+	}
+	if old.err == nil {
+		if err := keychainSet(name+".prev", base64.StdEncoding.EncodeToString(old.current)); err != nil {
+			return 0, err
+		}
+	}
+	if err := keychainSet(name, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return 0, err
+	}
+	resetBusKeys()
+	// A persisted search index would keep entry text in the clear
+	_ = os.Remove(BM25IndexPath())
+
+	text, gz := encryptedFiles(session)
+	n := 0
+	for _, path := range text {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			continue
+		}
+		reseal := func(data []byte) ([]byte, error) { return resealLines(data, key) }
+		if strings.HasSuffix(path, ".md") {
+			// Memory entries span lines: seal the whole file as one line
+			reseal = func(data []byte) ([]byte, error) {
+				plain, err := unsealContent(data)
+				if err != nil || len(plain) == 0 {
+					return plain, err
+				}
+				return sealWith(key, plain)
+			}
+		}
+		if err := resealFile(path, reseal, false); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, path := range gz {
+		if err := resealFile(path, func(data []byte) ([]byte, error) { return resealLines(data, key) }, true); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if s := ActiveStore(); s != nil {
+		m, err := resealMemoryStreams(s, key)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// resealMemoryStreams re-encrypts the memory records in a store with key,
+// one stream at a time, and returns the number of streams rewritten.
+func resealMemoryStreams(s Store, key []byte) (int, error) {
+	streams, err := s.Streams("memory/")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, stream := range streams {
+		records, err := s.Read(stream, StoreQuery{})
+		if err != nil {
+			return n, err
+		}
+		for i, r := range records {
+			plain, err := UnsealLine([]byte(r.Data))
+			if err != nil {
+				return n, err
+			}
+			sealed, err := sealWith(key, plain)
+			if err != nil {
+				return n, err
+			}
+			records[i].Data = string(sealed)
+		}
+		if err := s.Trim(stream, 0); err != nil {
+			return n, err
+		}
+		for _, r := range records {
+			if err := s.Append(stream, r.TS, []byte(r.Data)); err != nil {
+				return n, err
+			}
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,218 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testKeychain replaces the OS keychain with a map for one test and
+// enables encryption.
+func testKeychain(t *testing.T) map[string]string {
+	t.Helper()
+	store := map[string]string{}
+	origGet, origSet := keychainGet, keychainSet
+	keychainGet = func(account string) (string, error) {
+		if v, ok := store[account]; ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+	keychainSet = func(account, secret string) error {
+		store[account] = secret
+		return nil
+	}
+	t.Setenv(BusKeyEnv, "")
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	SetConfig(&MuxcodeConfig{Encryption: EncryptionConfig{Enabled: true}})
+	resetBusKeys()
+	t.Cleanup(func() {
+		keychainGet, keychainSet = origGet, origSet
+		SetConfig(nil)
+		resetBusKeys()
+	})
+	return store
+}
+
+func testBusKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestSealLine(t *testing.T) {
+	store := testKeychain(t)
+	store["default"] = testBusKey(1)
+
+	sealed, err := SealLine([]byte("{\"payload\":\"secret\"}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSealed(sealed) || bytes.Contains(sealed, []byte("secret")) || !bytes.HasSuffix(sealed, []byte("\n")) {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	plain, err := UnsealLine(sealed)
+	if err != nil || string(plain) != "{\"payload\":\"secret\"}\n" {
+		t.Fatalf("UnsealLine = %q, %v", plain, err)
+	}
+
+	// Plain lines pass through; disabled encryption writes plain
+	if got, _ := UnsealLine([]byte("plain\n")); string(got) != "plain\n" {
+		t.Errorf("plain line = %q", got)
+	}
+	SetConfig(&MuxcodeConfig{})
+	if got, _ := SealLine([]byte("x\n")); string(got) != "x\n" {
+		t.Errorf("disabled SealLine = %q", got)
+	}
+
+	// A different key cannot open the line
+	store["default"] = testBusKey(2)
+	resetBusKeys()
+	if _, err := UnsealLine(sealed); !errors.Is(err, ErrSealed) {
+		t.Errorf("wrong key err = %v, want ErrSealed", err)
+	}
+}
+
+func TestEncryptedSendReceive(t *testing.T) {
+	session := testSession(t)
+	store := testKeychain(t)
+	store["default"] = testBusKey(1)
+
+	if err := Send(session, NewMessage("edit", "build", "request", "build", "top secret payload", "")); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{InboxPath(session, "build"), LogPath(session)} {
+		data, _ := os.ReadFile(path)
+		if bytes.Contains(data, []byte("top secret")) || !isSealed(data) {
+			t.Errorf("%s not encrypted: %q", path, data)
+		}
+	}
+	if InboxCount(session, "build") != 1 {
+		t.Errorf("InboxCount = %d, want 1", InboxCount(session, "build"))
+	}
+	if log, _ := readMessages(LogPath(session)); len(log) != 1 || log[0].Payload != "top secret payload" {
+		t.Errorf("log = %+v", log)
+	}
+
+	// Without the key, Receive fails and leaves the inbox alone
+	delete(store, "default")
+	resetBusKeys()
+	if _, err := Receive(session, "build"); !errors.Is(err, ErrSealed) {
+		t.Fatalf("Receive without key err = %v, want ErrSealed", err)
+	}
+	if InboxCount(session, "build") != 1 {
+		t.Fatal("inbox consumed without a key")
+	}
+
+	store["default"] = testBusKey(1)
+	resetBusKeys()
+	msgs, err := Receive(session, "build")
+	if err != nil || len(msgs) != 1 || msgs[0].Payload != "top secret payload" {
+		t.Fatalf("Receive = %+v, %v", msgs, err)
+	}
+}
+
+func TestEncryptedMemory(t *testing.T) {
+	store := testKeychain(t)
+	store["default"] = testBusKey(1)
+
+	if err := AppendMemory("Deploy notes", "the password is hunter2", "build"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendMemory("Second", "more notes", "build"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(MemoryPath("build"))
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Errorf("memory file not encrypted: %q", data)
+	}
+	content, err := ReadMemory("build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := ParseMemoryEntries(content, "build")
+	if len(entries) != 2 || entries[0].Content != "the password is hunter2" {
+		t.Errorf("entries = %+v", entries)
+	}
+	results, err := SearchMemory("hunter2", "", 0)
+	if err != nil || len(results) != 1 {
+		t.Errorf("SearchMemory = %+v, %v", results, err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	session := testSession(t)
+	store := testKeychain(t)
+
+	// Plain data written before encryption was enabled
+	SetConfig(&MuxcodeConfig{})
+	if err := Send(session, NewMessage("edit", "test", "request", "test", "plain before", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendMemory("Old", "plain memory", "shared"); err != nil {
+		t.Fatal(err)
+	}
+	SetConfig(&MuxcodeConfig{Encryption: EncryptionConfig{Enabled: true}})
+
+	n, err := Rekey(session)
+	if err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("rewrote %d files, want 3 (inbox, log, memory)", n)
+	}
+	first := store["default"]
+	if first == "" || store["default.prev"] != "" {
+		t.Fatalf("keychain after first rekey = %v", store)
+	}
+	for _, path := range []string{InboxPath(session, "test"), LogPath(session), MemoryPath("shared")} {
+		data, _ := os.ReadFile(path)
+		if bytes.Contains(data, []byte("plain")) {
+			t.Errorf("%s still plain: %q", path, data)
+		}
+	}
+	if st := GetEncryptionStatus(session); st.Sealed != 3 || st.Plain != 0 || st.KeyError != "" {
+		t.Errorf("status = %+v", st)
+	}
+
+	// A second rekey rotates the key and keeps the old one as .prev
+	if _, err := Rekey(session); err != nil {
+		t.Fatal(err)
+	}
+	if store["default.prev"] != first || store["default"] == first {
+		t.Errorf("keychain after second rekey = %v", store)
+	}
+	msgs, err := Peek(session, "test")
+	if err != nil || len(msgs) != 1 || msgs[0].Payload != "plain before" {
+		t.Errorf("Peek after rekey = %+v, %v", msgs, err)
+	}
+	if content, _ := ReadMemory("shared"); !strings.Contains(content, "plain memory") {
+		t.Errorf("memory after rekey = %q", content)
+	}
+
+	t.Setenv(BusKeyEnv, testBusKey(3))
+	if _, err := Rekey(session); err == nil {
+		t.Error("expected error when the key comes from the environment")
+	}
+	SetConfig(&MuxcodeConfig{})
+	if _, err := Rekey(session); err == nil {
+		t.Error("expected error when encryption is disabled")
+	}
+}
+
+func TestSecurityCommandLine(t *testing.T) {
+	line, err := securityCommandLine("add-generic-password", "-a", "muxcode bus", "-w", "ab+/c=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"add-generic-password" "-a" "muxcode bus" "-w" "ab+/c="` + "\n"; line != want {
+		t.Errorf("line = %q, want %q", line, want)
+	}
+	for _, bad := range []string{`a"b`, `a\b`, "a\nb"} {
+		if _, err := securityCommandLine("-a", bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
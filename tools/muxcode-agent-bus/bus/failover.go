@@ -0,0 +1,242 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultFailoverAfter is how long a role must stay down before it is
+// failed over to a spawned replacement.
+const DefaultFailoverAfter = 180
+
+// Failover is an active replacement of a down role by a spawned agent.
+type Failover struct {
+	Role      string `json:"role"`
+	SpawnID   string `json:"spawn_id"`
+	SpawnRole string `json:"spawn_role"`
+	Since     int64  `json:"since"`
+	Moved     int    `json:"moved"` // inbox messages transferred to the replacement
+}
+
+// startFailoverSpawn starts the replacement agent; tests stub it out.
+var startFailoverSpawn = StartSpawn
+
+// stopFailoverSpawn stops the replacement agent; tests stub it out.
+var stopFailoverSpawn = StopSpawn
+
+// FailoverAfter returns the configured failover threshold in seconds.
+func (c HeartbeatConfig) FailoverAfter() int64 {
+	if c.FailoverAfterSecs > 0 {
+		return int64(c.FailoverAfterSecs)
+	}
+	return DefaultFailoverAfter
+}
+
+// FailsOver reports whether the failover policy covers role.
+func (c HeartbeatConfig) FailsOver(role string) bool {
+	if role == "edit" || IsSpawnRole(role) {
+		return false
+	}
+	for _, r := range c.Failover {
+		if r == role || r == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// FailoverPath returns the file tracking active failovers.
+func FailoverPath(session string) string {
+	return filepath.Join(BusDir(session), "failover.json")
+}
+
+// ReadFailovers returns the active failovers keyed by role.
+func ReadFailovers(session string) map[string]Failover {
+	data, err := os.ReadFile(FailoverPath(session))
+	if err != nil {
+		return nil
+	}
+	var out map[string]Failover
+	if json.Unmarshal(data, &out) != nil {
+		return nil
+	}
+	return out
+}
+
+// writeFailovers persists the active failovers, removing the file when
+// there are none.
+func writeFailovers(session string, fos map[string]Failover) error {
+	if len(fos) == 0 {
+		if err := os.Remove(FailoverPath(session)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(fos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(FailoverPath(session), append(data, '\n'), 0644)
+}
+
+// FailoverTarget returns the replacement role standing in for role, if any.
+func FailoverTarget(session, role string) (string, bool) {
+	fo, ok := ReadFailovers(session)[role]
+	if !ok {
+		return "", false
+	}
+	return fo.SpawnRole, true
+}
+
+// StartFailover spawns a replacement for a down role with the same tool
+// profile, moves the role's pending inbox messages to it, and redirects
+// further messages for the role until EndFailover.
+func StartFailover(session, role string) (Failover, error) {
+	if fo, ok := ReadFailovers(session)[role]; ok {
+		return fo, fmt.Errorf("%s is already failed over to %s", role, fo.SpawnRole)
+	}
+	task := fmt.Sprintf("The %s agent is down and you are standing in for it. "+
+		"Handle the %s requests in your inbox as that agent would and reply to their senders. "+
+		"You will be stopped when %s recovers.", role, role, role)
+	entry, err := startFailoverSpawn(session, role, task, "edit")
+	if err != nil {
+		return Failover{}, fmt.Errorf("spawning replacement: %v", err)
+	}
+
+	fo := Failover{Role: role, SpawnID: entry.ID, SpawnRole: entry.SpawnRole, Since: time.Now().Unix()}
+	fos := ReadFailovers(session)
+	if fos == nil {
+		fos = make(map[string]Failover)
+	}
+	fos[role] = fo
+	if err := writeFailovers(session, fos); err != nil {
+		return fo, err
+	}
+
+	// Redirect is in place: nothing new lands in the role's inbox
+	n, err := transferInbox(session, role, entry.SpawnRole, nil)
+	fo.Moved = n
+	fos[role] = fo
+	_ = writeFailovers(session, fos)
+	return fo, err
+}
+
+// EndFailover hands a recovered role back: the redirect is removed, the
+// replacement's unread messages (except its own task) are moved back to the
+// role's inbox, and the replacement is stopped. Returns the number of
+// messages handed back.
+func EndFailover(session, role string) (int, error) {
+	fos := ReadFailovers(session)
+	fo, ok := fos[role]
+	if !ok {
+		return 0, fmt.Errorf("%s is not failed over", role)
+	}
+	delete(fos, role)
+	if err := writeFailovers(session, fos); err != nil {
+		return 0, err
+	}
+
+	n, err := transferInbox(session, fo.SpawnRole, role, func(m Message) bool {
+		return m.Action == "spawn-task"
+	})
+	if stopErr := stopFailoverSpawn(session, fo.SpawnID); stopErr != nil && err == nil {
+		// Already finished or stopped is fine; report anything else
+		if e, gerr := GetSpawnEntry(session, fo.SpawnID); gerr != nil || spawnActive(e) {
+			err = stopErr
+		}
+	}
+	return n, err
+}
+
+// transferInbox moves the unread messages in from's inbox to to's inbox,
+// readdressing them. Messages skip reports true for are dropped.
+func transferInbox(session, from, to string, skip func(Message) bool) (int, error) {
+	t, err := ActiveTransport()
+	if err != nil {
+		return 0, err
+	}
+	if t != nil {
+		msgs, err := receiveTransport(t, session, from, nil)
+		if err != nil {
+			return 0, err
+		}
+		n := 0
+		for _, m := range msgs {
+			if skip != nil && skip(m) {
+				continue
+			}
+			m.To = to
+			line, err := encodeLine(m)
+			if err != nil {
+				continue
+			}
+			if err := t.Deliver(session, to, line); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	}
+
+	inbox := InboxPath(session, from)
+	consuming := inbox + ".consuming"
+	if err := checkSealed(inbox); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(inbox, consuming); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	_ = touchFile(inbox)
+	msgs, err := readMessages(consuming)
+	_ = os.Remove(consuming)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(InboxPath(session, to)), 0755); err != nil {
+		return 0, err
+	}
+	var buf []byte
+	n := 0
+	for _, m := range msgs {
+		if skip != nil && skip(m) {
+			continue
+		}
+		m.To = to
+		data, err := encodeLine(m)
+		if err != nil {
+			continue
+		}
+		buf = append(buf, data...)
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, appendToFile(InboxPath(session, to), buf)
+}
+
+// FormatFailovers renders active failovers, one per line.
+func FormatFailovers(fos map[string]Failover, now int64) string {
+	if len(fos) == 0 {
+		return "No active failovers.\n"
+	}
+	roles := make([]string, 0, len(fos))
+	for r := range fos {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+	out := ""
+	for _, r := range roles {
+		fo := fos[r]
+		out += fmt.Sprintf("%-12s -> %-16s %ds\n", r, fo.SpawnRole, now-fo.Since)
+	}
+	return out
+}
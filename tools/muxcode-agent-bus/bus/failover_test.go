@@ -0,0 +1,106 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+// stubFailoverSpawn replaces spawn start/stop with in-memory fakes.
+func stubFailoverSpawn(t *testing.T, session string) *[]string {
+	t.Helper()
+	var stopped []string
+	origStart, origStop := startFailoverSpawn, stopFailoverSpawn
+	startFailoverSpawn = func(session, role, task, owner string) (SpawnEntry, error) {
+		e := SpawnEntry{ID: "spawn-1", Role: role, SpawnRole: "spawn-0000abcd", Owner: owner, Task: task, Status: "running"}
+		if err := Send(session, NewMessage(owner, e.SpawnRole, "request", "spawn-task", task, "")); err != nil {
+			return SpawnEntry{}, err
+		}
+		return e, WriteSpawnEntries(session, []SpawnEntry{e})
+	}
+	stopFailoverSpawn = func(session, id string) error {
+		stopped = append(stopped, id)
+		return nil
+	}
+	t.Cleanup(func() { startFailoverSpawn, stopFailoverSpawn = origStart, origStop })
+	return &stopped
+}
+
+func TestFailover_StartRedirectEnd(t *testing.T) {
+	session := testSession(t)
+	stopped := stubFailoverSpawn(t, session)
+
+	Send(session, NewMessage("edit", "review", "request", "review", "check diff", ""))
+	Send(session, NewMessage("build", "review", "event", "built", "ok", ""))
+
+	fo, err := StartFailover(session, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fo.SpawnRole != "spawn-0000abcd" || fo.Moved != 2 {
+		t.Fatalf("failover = %+v", fo)
+	}
+	if InboxCount(session, "review") != 0 {
+		t.Error("review inbox not emptied")
+	}
+	if _, err := StartFailover(session, "review"); err == nil {
+		t.Error("second failover should fail")
+	}
+
+	// New messages for review are redirected
+	Send(session, NewMessage("test", "review", "request", "review", "tests added", ""))
+	msgs, _ := Peek(session, fo.SpawnRole)
+	if len(msgs) != 4 || msgs[0].Action != "spawn-task" {
+		t.Fatalf("spawn inbox = %+v", msgs)
+	}
+	for _, m := range msgs[1:] {
+		if m.To != fo.SpawnRole {
+			t.Errorf("message not readdressed: %+v", m)
+		}
+	}
+
+	// The replacement handles one, the rest go back
+	Receive(session, fo.SpawnRole)
+	Send(session, NewMessage("edit", "review", "request", "review", "one more", ""))
+	n, err := EndFailover(session, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(*stopped) != 1 || (*stopped)[0] != "spawn-1" {
+		t.Errorf("handed back %d, stopped %v", n, *stopped)
+	}
+	msgs, _ = Peek(session, "review")
+	if len(msgs) != 1 || msgs[0].Payload != "one more" || msgs[0].To != "review" {
+		t.Errorf("review inbox = %+v", msgs)
+	}
+	if _, ok := FailoverTarget(session, "review"); ok {
+		t.Error("redirect not removed")
+	}
+	if _, err := EndFailover(session, "review"); err == nil {
+		t.Error("ending twice should fail")
+	}
+}
+
+func TestHeartbeatConfig_FailsOver(t *testing.T) {
+	c := HeartbeatConfig{Failover: []string{"review"}}
+	if !c.FailsOver("review") || c.FailsOver("build") {
+		t.Error("explicit role list")
+	}
+	if c.FailoverAfter() != DefaultFailoverAfter {
+		t.Errorf("default after = %d", c.FailoverAfter())
+	}
+	all := HeartbeatConfig{Failover: []string{"*"}, FailoverAfterSecs: 60}
+	if !all.FailsOver("build") || all.FailsOver("edit") || all.FailsOver("spawn-0000abcd") {
+		t.Error("wildcard must skip edit and spawns")
+	}
+	if all.FailoverAfter() != 60 {
+		t.Errorf("after = %d", all.FailoverAfter())
+	}
+	if err := (HeartbeatConfig{Failover: []string{"edit"}}).Validate(); err == nil {
+		t.Error("failing over edit should fail validation")
+	}
+
+	out := FormatFailovers(map[string]Failover{"review": {Role: "review", SpawnRole: "spawn-0000abcd", Since: 100}}, 160)
+	if !strings.Contains(out, "review") || !strings.Contains(out, "spawn-0000abcd") || !strings.Contains(out, "60s") {
+		t.Errorf("format:\n%s", out)
+	}
+}
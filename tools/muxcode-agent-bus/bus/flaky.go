@@ -0,0 +1,197 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FlakySuspectAction is the action of the event sent when a test command
+// starts alternating between passing and failing.
+const FlakySuspectAction = "flaky-suspect"
+
+// flakyRole is the role whose history is checked for flaky tests.
+const flakyRole = "test"
+
+// flakyWindow is how far back runs are compared.
+const flakyWindow = 2 * time.Hour
+
+// flakyMinFlips is how many outcome changes between consecutive runs of the
+// same command make it a suspect: pass, fail, pass (or fail, pass, fail).
+const flakyMinFlips = 2
+
+// FlakySuspect is a command whose outcome alternated between runs. The
+// counts cover the runs in the window when it was last checked.
+type FlakySuspect struct {
+	Role      string  `json:"role"`
+	Command   string  `json:"command"` // normalized as by loop detection
+	Runs      int     `json:"runs"`
+	Failures  int     `json:"failures"`
+	Flips     int     `json:"flips"` // outcome changes between consecutive runs
+	Rate      float64 `json:"failure_rate"`
+	FirstSeen int64   `json:"first_seen"`
+	LastSeen  int64   `json:"last_seen"`
+	LastRun   int64   `json:"last_run"`
+}
+
+// FlakyPath returns the flaky suspect report for a session.
+func FlakyPath(session string) string {
+	return filepath.Join(BusDir(session), "flaky.json")
+}
+
+// lockFlaky serializes read-modify-write cycles on the suspect report.
+func lockFlaky(session string) func() {
+	return flockBusFile(session, "flaky")
+}
+
+// ReadFlakySuspects reads the session's flaky suspects.
+func ReadFlakySuspects(session string) ([]FlakySuspect, error) {
+	data, err := os.ReadFile(FlakyPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var suspects []FlakySuspect
+	if err := json.Unmarshal(data, &suspects); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FlakyPath(session), err)
+	}
+	return suspects, nil
+}
+
+// writeFlakySuspects saves the suspects atomically.
+func writeFlakySuspects(session string, suspects []FlakySuspect) error {
+	data, err := json.MarshalIndent(suspects, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := FlakyPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, FlakyPath(session))
+}
+
+// DetectFlaky groups the entries within windowSecs of now by normalized
+// command and returns the commands whose outcome changed at least
+// flakyMinFlips times between consecutive runs, most failures first.
+func DetectFlaky(entries []HistoryEntry, role string, windowSecs, now int64) []FlakySuspect {
+	byCmd := make(map[string]*FlakySuspect)
+	last := make(map[string]string)
+	var order []string
+	for _, e := range entries {
+		if now-e.TS > windowSecs || (e.Outcome != "success" && e.Outcome != "failure") {
+			continue
+		}
+		cmd := normalizeCommand(e.Command)
+		if cmd == "" {
+			continue
+		}
+		s, ok := byCmd[cmd]
+		if !ok {
+			s = &FlakySuspect{Role: role, Command: cmd}
+			byCmd[cmd] = s
+			order = append(order, cmd)
+		}
+		s.Runs++
+		if e.Outcome == "failure" {
+			s.Failures++
+		}
+		if prev, ok := last[cmd]; ok && prev != e.Outcome {
+			s.Flips++
+		}
+		last[cmd] = e.Outcome
+		s.LastRun = e.TS
+	}
+
+	var suspects []FlakySuspect
+	for _, cmd := range order {
+		s := byCmd[cmd]
+		if s.Flips < flakyMinFlips {
+			continue
+		}
+		s.Rate = float64(s.Failures) / float64(s.Runs)
+		suspects = append(suspects, *s)
+	}
+	sort.SliceStable(suspects, func(i, j int) bool { return suspects[i].Failures > suspects[j].Failures })
+	return suspects
+}
+
+// flakyPayload describes a new suspect for the flaky-suspect event.
+func flakyPayload(s FlakySuspect) string {
+	return fmt.Sprintf("Flaky test suspect: `%s` (%s) failed %d of %d runs (%.0f%%) in the last %s, alternating pass/fail %d times. Check for order dependence, timing, or shared state before treating a failure as a regression.",
+		s.Command, s.Role, s.Failures, s.Runs, s.Rate*100, flakyWindow, s.Flips)
+}
+
+// TrackFlakySuspects checks the test role's history for flaky commands and
+// records them in the session report. Suspects stay in the report for the
+// rest of the session; their counts are refreshed while they keep running.
+// A flaky-suspect event goes to edit the first time a command is suspected.
+// It returns the new suspects.
+func TrackFlakySuspects(session string, now time.Time) ([]FlakySuspect, error) {
+	found := DetectFlaky(ReadHistory(session, flakyRole, 0), flakyRole, int64(flakyWindow.Seconds()), now.Unix())
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	unlock := lockFlaky(session)
+	suspects, err := ReadFlakySuspects(session)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	index := make(map[string]int, len(suspects))
+	for i, s := range suspects {
+		index[s.Role+"\x00"+s.Command] = i
+	}
+	var fresh []FlakySuspect
+	for _, f := range found {
+		f.LastSeen = now.Unix()
+		if i, ok := index[f.Role+"\x00"+f.Command]; ok {
+			f.FirstSeen = suspects[i].FirstSeen
+			suspects[i] = f
+			continue
+		}
+		f.FirstSeen = now.Unix()
+		suspects = append(suspects, f)
+		fresh = append(fresh, f)
+	}
+	err = writeFlakySuspects(session, suspects)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range fresh {
+		msg := NewMessage("watcher", "edit", "event", FlakySuspectAction, flakyPayload(s), "")
+		if err := Send(session, msg); err != nil {
+			return fresh, err
+		}
+		_ = Notify(session, "edit")
+	}
+	return fresh, nil
+}
+
+// FormatFlakySuspects renders the session's flaky suspects, highest failure
+// rate first.
+func FormatFlakySuspects(suspects []FlakySuspect) string {
+	if len(suspects) == 0 {
+		return "No flaky test suspects.\n"
+	}
+	sorted := append([]FlakySuspect(nil), suspects...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Rate > sorted[j].Rate })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %6s %6s %6s %-12s %s\n", "FAILED", "RATE", "FLIPS", "SINCE", "ROLE", "COMMAND")
+	for _, s := range sorted {
+		fmt.Fprintf(&b, "%-8s %5.0f%% %6d %6s %-12s %s\n",
+			fmt.Sprintf("%d/%d", s.Failures, s.Runs), s.Rate*100, s.Flips,
+			time.Unix(s.FirstSeen, 0).Format("15:04"), s.Role, s.Command)
+	}
+	return b.String()
+}
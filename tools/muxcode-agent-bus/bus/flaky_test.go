@@ -0,0 +1,78 @@
+package bus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectFlaky(t *testing.T) {
+	now := int64(1_800_000_000)
+	run := func(ago int64, cmd, outcome string) HistoryEntry {
+		return HistoryEntry{TS: now - ago, Command: cmd, Outcome: outcome}
+	}
+	entries := []HistoryEntry{
+		run(9000, "go test ./bus/...", "failure"), // outside the window
+		run(500, "go test ./bus/...", "success"),
+		run(400, "cd /src && go test ./bus/...", "failure"),
+		run(300, "go test ./cmd/...", "failure"),
+		run(200, "go test ./bus/...", "success"),
+		run(150, "go test ./cmd/...", "success"), // fixed, not flaky
+		run(100, "go test ./bus/...", "failure"),
+	}
+
+	got := DetectFlaky(entries, "test", 7200, now)
+	if len(got) != 1 {
+		t.Fatalf("suspects = %+v", got)
+	}
+	s := got[0]
+	if s.Command != "go test ./bus/..." || s.Runs != 4 || s.Failures != 2 || s.Flips != 3 || s.Rate != 0.5 || s.LastRun != now-100 {
+		t.Errorf("suspect = %+v", s)
+	}
+	if got := DetectFlaky(entries[:4], "test", 7200, now); len(got) != 0 {
+		t.Errorf("one flip flagged: %+v", got)
+	}
+}
+
+func TestTrackFlakySuspects(t *testing.T) {
+	session := testSession(t)
+	now := time.Now()
+	for i, outcome := range []string{"success", "failure", "success"} {
+		data, _ := json.Marshal(HistoryEntry{TS: now.Unix() - int64(30-i), Command: "go test ./...", Outcome: outcome})
+		if err := AppendHistory(session, "test", data, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fresh, err := TrackFlakySuspects(session, now)
+	if err != nil || len(fresh) != 1 {
+		t.Fatalf("TrackFlakySuspects = %+v, %v", fresh, err)
+	}
+	msgs, _ := Peek(session, "edit")
+	if len(msgs) != 1 || msgs[0].Action != FlakySuspectAction || !strings.Contains(msgs[0].Payload, "failed 1 of 3 runs (33%)") {
+		t.Fatalf("edit inbox = %+v", msgs)
+	}
+
+	// Known suspects are refreshed, not announced again
+	data, _ := json.Marshal(HistoryEntry{TS: now.Unix(), Command: "go test ./...", Outcome: "failure"})
+	AppendHistory(session, "test", data, 100)
+	if fresh, _ := TrackFlakySuspects(session, now.Add(time.Minute)); len(fresh) != 0 {
+		t.Errorf("announced twice: %+v", fresh)
+	}
+	if msgs, _ := Peek(session, "edit"); len(msgs) != 1 {
+		t.Errorf("edit inbox = %+v", msgs)
+	}
+	suspects, _ := ReadFlakySuspects(session)
+	if len(suspects) != 1 || suspects[0].Runs != 4 || suspects[0].FirstSeen != now.Unix() || suspects[0].LastSeen != now.Add(time.Minute).Unix() {
+		t.Fatalf("suspects = %+v", suspects)
+	}
+
+	out := FormatFlakySuspects(suspects)
+	if !strings.Contains(out, "2/4") || !strings.Contains(out, "50%") || !strings.Contains(out, "go test ./...") {
+		t.Errorf("FormatFlakySuspects:\n%s", out)
+	}
+	if out := FormatFlakySuspects(nil); out != "No flaky test suspects.\n" {
+		t.Errorf("FormatFlakySuspects(nil) = %q", out)
+	}
+}
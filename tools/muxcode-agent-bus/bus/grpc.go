@@ -0,0 +1,367 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/pkg/buspb"
+)
+
+// grpcPollInterval is how often a Subscribe stream checks the log for new
+// messages.
+var grpcPollInterval = 250 * time.Millisecond
+
+// GRPCCertPath returns the self-signed certificate the gRPC server creates
+// when no --tls-cert is given. Clients pass it to buspb.Dial to trust it.
+func GRPCCertPath(session string) string {
+	return filepath.Join(BusDir(session), "grpc-cert.pem")
+}
+
+// GRPCKeyPath returns the private key for GRPCCertPath.
+func GRPCKeyPath(session string) string {
+	return filepath.Join(BusDir(session), "grpc-key.pem")
+}
+
+// GRPCCert loads the session's self-signed gRPC certificate, creating it
+// (or replacing an expired one) for localhost, the loopback addresses, and
+// host. gRPC needs HTTP/2, which net/http only serves over TLS.
+func GRPCCert(session, host string) (tls.Certificate, error) {
+	certPath, keyPath := GRPCCertPath(session), GRPCKeyPath(session)
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Now().Before(leaf.NotAfter) {
+			return cert, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "muxcode-agent-bus " + session},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !ip.IsLoopback() && !ip.IsUnspecified() {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		}
+	} else if host != "" && host != "localhost" {
+		tmpl.DNSNames = append(tmpl.DNSNames, host)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// GRPCHandler returns the gRPC service defined in pkg/buspb/bus.proto:
+// Subscribe, GetStatus, and Send. Every call needs the REST API bearer
+// token in its authorization metadata.
+func GRPCHandler(cfg ServeConfig) http.Handler {
+	mux := http.NewServeMux()
+	rpc := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+				return
+			}
+			if !bearerOK(r, cfg.Token) {
+				grpcFail(w, buspb.CodeUnauthenticated, "unauthorized")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, apiMaxBody)
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("POST "+buspb.MethodSubscribe, rpc(grpcSubscribe(cfg.Session)))
+	mux.HandleFunc("POST "+buspb.MethodGetStatus, rpc(grpcGetStatus(cfg.Session)))
+	mux.HandleFunc("POST "+buspb.MethodSend, rpc(grpcSend(cfg.Session)))
+	mux.HandleFunc("POST /", rpc(func(w http.ResponseWriter, r *http.Request) {
+		grpcFail(w, buspb.CodeUnimplemented, "unknown method "+r.URL.Path)
+	}))
+	return mux
+}
+
+// ServeGRPC serves GRPCHandler over TLS on port until ctx is cancelled.
+// Open Subscribe streams end when ctx is cancelled.
+func ServeGRPC(ctx context.Context, cfg ServeConfig, port int, cert tls.Certificate) error {
+	if cfg.Token == "" {
+		return fmt.Errorf("a token is required")
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           GRPCHandler(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("gRPC API listening on %s (TLS)\n", addr)
+	if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// grpcRead reads the single request message of a call.
+func grpcRead(r *http.Request, v interface{ Unmarshal([]byte) error }) error {
+	b, err := buspb.ReadFrame(r.Body)
+	if err != nil {
+		return fmt.Errorf("reading request: %v", err)
+	}
+	return v.Unmarshal(b)
+}
+
+// grpcEscape percent-encodes a grpc-message value.
+func grpcEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// grpcFail writes a trailers-only response carrying a non-OK status.
+func grpcFail(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", grpcEscape(msg))
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpcBegin writes the response headers of a call that returns messages.
+func grpcBegin(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpcEnd sets the status trailers after the response messages.
+func grpcEnd(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcEscape(msg))
+	}
+}
+
+// grpcReply writes a unary call's response message and OK status.
+func grpcReply(w http.ResponseWriter, v interface{ Marshal() []byte }) {
+	grpcBegin(w)
+	if err := buspb.WriteFrame(w, v.Marshal()); err != nil {
+		return
+	}
+	grpcEnd(w, buspb.CodeOK, "")
+}
+
+// grpcSend handles Send, sharing validation and policy with POST /api/v1/send.
+func grpcSend(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req buspb.SendRequest
+		if err := grpcRead(r, &req); err != nil {
+			grpcFail(w, buspb.CodeInvalidArgument, err.Error())
+			return
+		}
+		msg, status, err := sendAPIRequest(session, APISendRequest{
+			From: req.From, To: req.To, Type: req.Type,
+			Action: req.Action, Payload: req.Payload, ReplyTo: req.ReplyTo,
+		})
+		if err != nil {
+			code := buspb.CodeInternal
+			switch status {
+			case http.StatusBadRequest:
+				code = buspb.CodeInvalidArgument
+			case http.StatusForbidden:
+				code = buspb.CodePermissionDenied
+			}
+			grpcFail(w, code, err.Error())
+			return
+		}
+		grpcReply(w, &buspb.SendResponse{ID: msg.ID})
+	}
+}
+
+// grpcGetStatus handles GetStatus.
+func grpcGetStatus(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req buspb.GetStatusRequest
+		if err := grpcRead(r, &req); err != nil {
+			grpcFail(w, buspb.CodeInvalidArgument, err.Error())
+			return
+		}
+		resp := &buspb.GetStatusResponse{}
+		for _, s := range GetAllAgentStatus(session) {
+			resp.Agents = append(resp.Agents, &buspb.AgentStatus{
+				Role: s.Role, Locked: s.Locked,
+				InboxCount: int32(s.InboxCount), Unread: int32(s.Unread),
+				LastMsgTS: s.LastMsgTS, LastAction: s.LastAction,
+				LastPeer: s.LastPeer, LastDir: s.LastDir, Tasks: int32(s.Tasks),
+			})
+		}
+		grpcReply(w, resp)
+	}
+}
+
+// grpcSubscribe handles Subscribe: it tails the session log and streams each
+// new message until the client or server ends the call.
+func grpcSubscribe(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req buspb.SubscribeRequest
+		if err := grpcRead(r, &req); err != nil {
+			grpcFail(w, buspb.CodeInvalidArgument, err.Error())
+			return
+		}
+		if req.Role != "" && !IsKnownRole(req.Role) {
+			grpcFail(w, buspb.CodeInvalidArgument, fmt.Sprintf("unknown role '%s'", req.Role))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			grpcFail(w, buspb.CodeInternal, "streaming unsupported")
+			return
+		}
+
+		path := LogPath(session)
+		var offset int64
+		if req.Since == 0 {
+			if info, err := os.Stat(path); err == nil {
+				offset = info.Size()
+			}
+		}
+		grpcBegin(w)
+		flusher.Flush()
+
+		ticker := time.NewTicker(grpcPollInterval)
+		defer ticker.Stop()
+		for {
+			msgs, next, err := readLogFrom(path, offset)
+			if err != nil {
+				grpcEnd(w, buspb.CodeInternal, err.Error())
+				return
+			}
+			offset = next
+			for _, m := range msgs {
+				if req.Role != "" && m.From != req.Role && m.To != req.Role {
+					continue
+				}
+				if req.Since > 0 && m.TS <= req.Since {
+					continue
+				}
+				if err := buspb.WriteFrame(w, grpcMessage(m).Marshal()); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				grpcEnd(w, buspb.CodeOK, "")
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// grpcMessage converts a bus message to its protobuf form.
+func grpcMessage(m Message) *buspb.Message {
+	return &buspb.Message{
+		ID: m.ID, TS: m.TS, From: m.From, To: m.To, Type: m.Type,
+		Action: m.Action, Payload: m.Payload, ReplyTo: m.ReplyTo, TraceID: m.TraceID,
+	}
+}
+
+// readLogFrom decodes the complete log lines after offset and returns the
+// offset to read from next. A log shorter than offset was trimmed by
+// archiving, so it is read again from the start.
+func readLogFrom(path string, offset int64) ([]Message, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, offset, err
+	}
+	// Leave a partly written last line for the next read
+	end := bytes.LastIndexByte(data, '\n') + 1
+	var msgs []Message
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		m, err := DecodeMessage(line)
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs, MigrateMessage(m))
+	}
+	return msgs, offset + int64(end), nil
+}
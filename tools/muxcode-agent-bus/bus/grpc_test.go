@@ -0,0 +1,184 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/pkg/buspb"
+)
+
+// grpcTestClient serves GRPCHandler over TLS with HTTP/2 and returns a
+// client for it.
+func grpcTestClient(t *testing.T, session, token string) *buspb.Client {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(GRPCHandler(ServeConfig{Session: session, Token: "secret"}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return buspb.NewClient(srv.Listener.Addr().String(), token, srv.Client())
+}
+
+func grpcCode(err error) int {
+	var st *buspb.Status
+	if errors.As(err, &st) {
+		return st.Code
+	}
+	return -1
+}
+
+func TestGRPC_Auth(t *testing.T) {
+	session := testSession(t)
+	ctx := context.Background()
+
+	if _, err := grpcTestClient(t, session, "").GetStatus(ctx); grpcCode(err) != buspb.CodeUnauthenticated {
+		t.Errorf("no token: err = %v, want unauthenticated", err)
+	}
+	if _, err := grpcTestClient(t, session, "wrong").GetStatus(ctx); grpcCode(err) != buspb.CodeUnauthenticated {
+		t.Errorf("wrong token: err = %v, want unauthenticated", err)
+	}
+	resp, err := grpcTestClient(t, session, "secret").GetStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if len(resp.Agents) != len(GetAllAgentStatus(session)) {
+		t.Errorf("agents = %d, want %d", len(resp.Agents), len(GetAllAgentStatus(session)))
+	}
+}
+
+func TestGRPC_SendAndStatus(t *testing.T) {
+	session := testSession(t)
+	c := grpcTestClient(t, session, "secret")
+	ctx := context.Background()
+
+	sent, err := c.Send(ctx, &buspb.SendRequest{To: "review", Action: "review", Payload: "check the diff"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msgs, _ := Peek(session, "review")
+	if len(msgs) != 1 || msgs[0].ID != sent.ID || msgs[0].From != "api" || msgs[0].Type != "request" {
+		t.Fatalf("inbox = %+v, want the sent message from api", msgs)
+	}
+
+	if _, err := c.Send(ctx, &buspb.SendRequest{To: "nobody", Action: "x", Payload: "y"}); grpcCode(err) != buspb.CodeInvalidArgument {
+		t.Errorf("unknown role: err = %v, want invalid argument", err)
+	}
+	if _, err := c.Send(ctx, &buspb.SendRequest{To: "review"}); grpcCode(err) != buspb.CodeInvalidArgument {
+		t.Errorf("missing fields: err = %v, want invalid argument", err)
+	}
+
+	resp, err := c.GetStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	for _, a := range resp.Agents {
+		if a.Role == "review" && a.InboxCount != 1 {
+			t.Errorf("review inbox_count = %d, want 1", a.InboxCount)
+		}
+	}
+}
+
+func TestGRPC_Subscribe(t *testing.T) {
+	session := testSession(t)
+	old := grpcPollInterval
+	grpcPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { grpcPollInterval = old })
+	c := grpcTestClient(t, session, "secret")
+
+	// Logged before the call: replayed only with since
+	before := NewMessage("edit", "build", "request", "build", "earlier", "")
+	if err := Send(session, before); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := c.Subscribe(ctx, &buspb.SubscribeRequest{Role: "build"})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer stream.Close()
+
+	if err := Send(session, NewMessage("edit", "review", "request", "review", "not for build", "")); err != nil {
+		t.Fatal(err)
+	}
+	live := NewMessage("build", "edit", "response", "build", "done", before.ID)
+	if err := Send(session, live); err != nil {
+		t.Fatal(err)
+	}
+	got, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if got.ID != live.ID || got.Payload != "done" || got.ReplyTo != before.ID {
+		t.Errorf("Recv = %+v, want the build response", got)
+	}
+
+	replay, err := c.Subscribe(ctx, &buspb.SubscribeRequest{Role: "build", Since: before.TS - 1})
+	if err != nil {
+		t.Fatalf("Subscribe since: %v", err)
+	}
+	defer replay.Close()
+	if got, err := replay.Recv(); err != nil || got.ID != before.ID {
+		t.Errorf("replay Recv = %+v, %v; want %s", got, err, before.ID)
+	}
+
+	if _, err := c.Subscribe(ctx, &buspb.SubscribeRequest{Role: "nobody"}); grpcCode(err) != buspb.CodeInvalidArgument {
+		t.Errorf("unknown role: err = %v, want invalid argument", err)
+	}
+}
+
+func TestReadLogFrom(t *testing.T) {
+	session := testSession(t)
+	path := LogPath(session)
+	m := NewMessage("edit", "build", "request", "build", "one", "")
+	line, _ := encodeLine(m)
+	if err := os.WriteFile(path, append(line, []byte(`{"id":"partial`)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, next, err := readLogFrom(path, 0)
+	if err != nil || len(msgs) != 1 || msgs[0].ID != m.ID {
+		t.Fatalf("readLogFrom = %+v, %v", msgs, err)
+	}
+	if next != int64(len(line)) {
+		t.Errorf("next = %d, want %d (partial line left unread)", next, len(line))
+	}
+
+	// A trimmed log is read again from the start
+	msgs, next, _ = readLogFrom(path, 1<<20)
+	if len(msgs) != 1 || next != int64(len(line)) {
+		t.Errorf("after trim = %d msgs, next %d", len(msgs), next)
+	}
+}
+
+func TestGRPCCert(t *testing.T) {
+	session := testSession(t)
+	cert, err := GRPCCert(session, "devbox.local")
+	if err != nil {
+		t.Fatalf("GRPCCert: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("devbox.local"); err != nil {
+		t.Errorf("host name: %v", err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("loopback: %v", err)
+	}
+	if info, err := os.Stat(GRPCKeyPath(session)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key file = %v, %v; want mode 0600", info, err)
+	}
+
+	again, err := GRPCCert(session, "devbox.local")
+	if err != nil || !bytes.Equal(again.Certificate[0], cert.Certificate[0]) {
+		t.Errorf("second call should reuse the saved certificate (err %v)", err)
+	}
+}
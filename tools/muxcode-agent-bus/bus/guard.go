@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -19,6 +20,27 @@ var (
 	cmdNormSpaceRe = regexp.MustCompile(`\s+`)
 )
 
+// DefaultPayloadSimilarity is the token-overlap ratio at or above which two
+// request payloads are considered rephrasings of the same request.
+const DefaultPayloadSimilarity = 0.6
+
+// Default loop detection limits, used when guard config leaves them unset.
+const (
+	DefaultCommandThreshold = 3
+	DefaultMessageThreshold = 4
+	DefaultGuardWindow      = 300
+	DefaultSlowFactor       = 2.0
+)
+
+// Slow command detection: a run is compared with the median of the same
+// command's last slowBaselineRuns successful runs, once slowMinBaseline of
+// them are timed. Runs under slowMinDurationMs are never flagged.
+const (
+	slowBaselineRuns  = 10
+	slowMinBaseline   = 3
+	slowMinDurationMs = 1000
+)
+
 // HistoryEntry represents a single entry from a role's history JSONL file.
 type HistoryEntry struct {
 	TS       int64  `json:"ts"`
@@ -27,23 +49,61 @@ type HistoryEntry struct {
 	ExitCode string `json:"exit_code"`
 	Outcome  string `json:"outcome"`
 	Output   string `json:"output"`
+	// Set by the LLM harness from its structured tool results
+	DurationMs   int64 `json:"duration_ms,omitempty"`
+	Truncated    bool  `json:"truncated,omitempty"`
+	BytesOmitted int   `json:"bytes_omitted,omitempty"`
+	// Command start and end in unix milliseconds, when the caller timed it
+	StartedAt int64 `json:"started_at,omitempty"`
+	EndedAt   int64 `json:"ended_at,omitempty"`
+	// Parsed test results, from log --parse
+	Tests *TestResults `json:"tests,omitempty"`
+}
+
+// Duration returns the command's runtime in milliseconds: DurationMs, else
+// the span between StartedAt and EndedAt, else 0 for untimed entries.
+func (e HistoryEntry) Duration() int64 {
+	if e.DurationMs > 0 {
+		return e.DurationMs
+	}
+	if e.StartedAt > 0 && e.EndedAt > e.StartedAt {
+		return e.EndedAt - e.StartedAt
+	}
+	return 0
 }
 
 // LoopAlert describes a detected loop for an agent.
 type LoopAlert struct {
 	Role    string `json:"role"`
-	Type    string `json:"type"`     // "command" or "message"
-	Count   int    `json:"count"`    // number of repetitions
-	Command string `json:"command"`  // repeated command (command loops)
-	Peer    string `json:"peer"`     // other agent (message loops)
-	Action  string `json:"action"`   // repeated action (message loops)
-	Window  int64  `json:"window_s"` // time window in seconds
-	Message string `json:"message"`  // human-readable description
+	Type    string `json:"type"`              // "command", "message", "payload", "pattern", or "slow"
+	Count   int    `json:"count"`             // number of repetitions
+	Command string `json:"command"`           // repeated command (command loops), slow command
+	Peer    string `json:"peer"`              // other agent (message/payload loops)
+	Action  string `json:"action"`            // repeated action (message loops), latest action (payload loops)
+	Window  int64  `json:"window_s"`          // time window in seconds
+	Message string `json:"message"`           // human-readable description
+	Pattern string `json:"pattern,omitempty"` // custom pattern name (pattern alerts)
 }
 
 // ReadHistory reads the last `limit` entries from a role's history JSONL file.
 // Returns nil for missing or empty files.
 func ReadHistory(session, role string, limit int) []HistoryEntry {
+	if s := ActiveStore(); s != nil {
+		records, err := s.Read(historyStream(session, role), StoreQuery{Limit: limit})
+		if err != nil {
+			return nil
+		}
+		var all []HistoryEntry
+		for _, r := range records {
+			var entry HistoryEntry
+			if err := json.Unmarshal([]byte(r.Data), &entry); err != nil {
+				continue
+			}
+			all = append(all, entry)
+		}
+		return all
+	}
+
 	data, err := os.ReadFile(HistoryPath(session, role))
 	if err != nil {
 		return nil
@@ -70,6 +130,53 @@ func ReadHistory(session, role string, limit int) []HistoryEntry {
 	return all
 }
 
+// AppendHistory appends an encoded history entry for a role, keeping the
+// last keep entries (0 keeps all). Writes to the configured store, or to
+// the role's history JSONL file.
+func AppendHistory(session, role string, data []byte, keep int) error {
+	data = RedactHistoryEntry(session, role, data)
+	if s := ActiveStore(); s != nil {
+		var head struct {
+			TS int64 `json:"ts"`
+		}
+		_ = json.Unmarshal(data, &head)
+		stream := historyStream(session, role)
+		if err := s.Append(stream, head.TS, data); err != nil {
+			return err
+		}
+		if keep > 0 {
+			return s.Trim(stream, keep)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(BusDir(session), 0755); err != nil {
+		return err
+	}
+	path := HistoryPath(session, role)
+	if err := appendToFile(path, append(data, '\n')); err != nil {
+		return err
+	}
+	if keep > 0 {
+		trimJSONL(path, keep)
+	}
+	return nil
+}
+
+// trimJSONL rewrites a JSONL file to keep only its last keep lines.
+func trimJSONL(path string, keep int) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	if len(lines) <= keep {
+		return
+	}
+	out := append(bytes.Join(lines[len(lines)-keep:], []byte("\n")), '\n')
+	_ = os.WriteFile(path, out, 0644)
+}
+
 // normalizeCommand strips common prefixes and suffixes from a command string
 // to prevent false negatives from trivially different command forms.
 func normalizeCommand(cmd string) string {
@@ -148,6 +255,65 @@ func DetectCommandLoop(entries []HistoryEntry, threshold int, windowSecs int64)
 	}
 }
 
+// DetectSlowCommand flags the most recent history entry when its runtime
+// exceeds factor times the median runtime of the same normalized command's
+// previous successful runs. Entries older than windowSecs are not checked.
+func DetectSlowCommand(entries []HistoryEntry, factor float64, windowSecs int64) *LoopAlert {
+	if len(entries) == 0 || factor <= 1 {
+		return nil
+	}
+	last := entries[len(entries)-1]
+	took := last.Duration()
+	if took < slowMinDurationMs || (windowSecs > 0 && time.Now().Unix()-last.TS > windowSecs) {
+		return nil
+	}
+	cmd := normalizeCommand(last.Command)
+	if cmd == "" {
+		return nil
+	}
+
+	var baseline []int64
+	for i := len(entries) - 2; i >= 0 && len(baseline) < slowBaselineRuns; i-- {
+		e := entries[i]
+		if e.Outcome != "success" || normalizeCommand(e.Command) != cmd {
+			continue
+		}
+		if d := e.Duration(); d > 0 {
+			baseline = append(baseline, d)
+		}
+	}
+	if len(baseline) < slowMinBaseline {
+		return nil
+	}
+	median := medianMs(baseline)
+	if median <= 0 || float64(took) <= factor*float64(median) {
+		return nil
+	}
+	return &LoopAlert{
+		Type:    "slow",
+		Count:   len(baseline),
+		Command: cmd,
+		Message: fmt.Sprintf("%s took %s, %.1fx its median of %s over the last %d runs",
+			cmd, formatMs(took), float64(took)/float64(median), formatMs(median), len(baseline)),
+	}
+}
+
+// medianMs returns the median of a list of durations.
+func medianMs(ds []int64) int64 {
+	sorted := append([]int64(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// formatMs renders a millisecond duration, e.g. "2.5s" or "1m30s".
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
 // DetectMessageLoop checks log messages for repetitive patterns involving a role.
 // Detects both repeated identical messages and ping-pong patterns.
 // Only counts "request" type messages — "response" and "event" types are expected
@@ -159,29 +325,7 @@ func DetectMessageLoop(messages []Message, role string, threshold int, windowSec
 	}
 
 	now := messages[len(messages)-1].TS
-
-	// Filter to request messages within the time window.
-	// Responses and events repeat naturally across chain cycles and are not loops.
-	// Watcher-originated messages are system-generated traffic (file-change events,
-	// loop alerts, compaction alerts) — they repeat during active editing and are
-	// not agent-to-agent loops. System actions (loop-detected, compact-recommended)
-	// are infrastructure traffic that should never trigger loop detection.
-	var recent []Message
-	for _, m := range messages {
-		if m.Type != "request" {
-			continue
-		}
-		if m.From == "watcher" {
-			continue
-		}
-		if isSystemAction(m.Action) {
-			continue
-		}
-		if windowSecs <= 0 || (now-m.TS) <= windowSecs {
-			recent = append(recent, m)
-		}
-	}
-
+	recent := recentRequests(messages, windowSecs)
 	if len(recent) < threshold {
 		return nil
 	}
@@ -266,24 +410,256 @@ func DetectMessageLoop(messages []Message, role string, threshold int, windowSec
 	return nil
 }
 
-// CheckLoops runs all loop detection for a single role.
+// recentRequests filters messages to agent request messages within windowSecs
+// of the most recent message.
+// Responses and events repeat naturally across chain cycles and are not loops.
+// Watcher-originated messages are system-generated traffic (file-change events,
+// loop alerts, compaction alerts) — they repeat during active editing and are
+// not agent-to-agent loops. System actions (loop-detected, compact-recommended)
+// are infrastructure traffic that should never trigger loop detection.
+func recentRequests(messages []Message, windowSecs int64) []Message {
+	if len(messages) == 0 {
+		return nil
+	}
+	now := messages[len(messages)-1].TS
+
+	var recent []Message
+	for _, m := range messages {
+		if m.Type != "request" {
+			continue
+		}
+		if m.From == "watcher" {
+			continue
+		}
+		if isSystemAction(m.Action) {
+			continue
+		}
+		if windowSecs <= 0 || (now-m.TS) <= windowSecs {
+			recent = append(recent, m)
+		}
+	}
+	return recent
+}
+
+// payloadTokens returns the set of search tokens in a message payload.
+func payloadTokens(payload string) map[string]bool {
+	set := make(map[string]bool)
+	for _, tok := range tokenize(payload) {
+		set[tok] = true
+	}
+	return set
+}
+
+// payloadSimilarity returns the Jaccard overlap of two token sets (0-1).
+func payloadSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for tok := range a {
+		if b[tok] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// DetectPayloadLoop checks log messages for requests between the same pair of
+// agents whose payloads overlap by at least similarity (token Jaccard), even
+// when the action or wording differs. This catches agents that keep
+// rephrasing the same failing request. Uses the same request filtering as
+// DetectMessageLoop. Returns an alert if threshold similar requests fall
+// within windowSecs.
+func DetectPayloadLoop(messages []Message, role string, threshold int, windowSecs int64, similarity float64) *LoopAlert {
+	if len(messages) == 0 || threshold < 1 || similarity <= 0 {
+		return nil
+	}
+
+	now := messages[len(messages)-1].TS
+	var recent []Message
+	for _, m := range recentRequests(messages, windowSecs) {
+		if m.From == role || m.To == role {
+			recent = append(recent, m)
+		}
+	}
+	if len(recent) < threshold {
+		return nil
+	}
+
+	tokens := make([]map[string]bool, len(recent))
+	for i, m := range recent {
+		tokens[i] = payloadTokens(m.Payload)
+	}
+
+	// Anchor on each request, newest first, and count similar requests in
+	// the same direction (the anchor included).
+	for i := len(recent) - 1; i >= 0; i-- {
+		anchor := recent[i]
+		if len(tokens[i]) == 0 {
+			continue
+		}
+		count := 1
+		earliest := anchor.TS
+		for j := i - 1; j >= 0; j-- {
+			m := recent[j]
+			if m.From != anchor.From || m.To != anchor.To {
+				continue
+			}
+			if payloadSimilarity(tokens[i], tokens[j]) >= similarity {
+				count++
+				earliest = m.TS
+			}
+		}
+		if count < threshold {
+			continue
+		}
+
+		peer := anchor.To
+		if peer == role {
+			peer = anchor.From
+		}
+		elapsed := now - earliest
+		return &LoopAlert{
+			Type:    "payload",
+			Count:   count,
+			Peer:    peer,
+			Action:  anchor.Action,
+			Window:  elapsed,
+			Message: fmt.Sprintf("%s -> %s sent %d similar requests (%.0f%%+ overlap) in %s", anchor.From, anchor.To, count, similarity*100, formatDuration(elapsed)),
+		}
+	}
+
+	return nil
+}
+
+// GuardSimilarity returns the configured top-level payload similarity
+// threshold, falling back to DefaultPayloadSimilarity.
+func GuardSimilarity() float64 {
+	if s := Config().Guard.Similarity; s > 0 && s <= 1 {
+		return s
+	}
+	return DefaultPayloadSimilarity
+}
+
+// GuardLimitsFor resolves a role's loop detection limits: per-role config,
+// then top-level config, then defaults.
+func GuardLimitsFor(role string) GuardLimits {
+	cfg := Config().Guard
+	limits := GuardLimits{
+		Similarity:       GuardSimilarity(),
+		CommandThreshold: DefaultCommandThreshold,
+		MessageThreshold: DefaultMessageThreshold,
+		Window:           DefaultGuardWindow,
+		SlowFactor:       DefaultSlowFactor,
+	}
+	limits = mergeGuardLimits(limits, cfg.GuardLimits)
+	limits = mergeGuardLimits(limits, cfg.Roles[role])
+	if limits.Similarity > 1 {
+		limits.Similarity = DefaultPayloadSimilarity
+	}
+	return limits
+}
+
+// CheckLoops runs all loop detection for a single role using its configured limits.
 func CheckLoops(session, role string) []LoopAlert {
+	return CheckLoopsWith(session, role, GuardLimitsFor(role))
+}
+
+// CheckLoopsWith runs all loop detection for a single role with explicit
+// limits, followed by any custom guard patterns.
+func CheckLoopsWith(session, role string, limits GuardLimits) []LoopAlert {
 	var alerts []LoopAlert
 
-	// Command loop detection (history file)
-	entries := ReadHistory(session, role, 20)
-	if alert := DetectCommandLoop(entries, 3, 300); alert != nil {
+	// Command loop and slow command detection (history file). The whole
+	// history is read so slow runs have a baseline of earlier runs.
+	entries := ReadHistory(session, role, 0)
+	if alert := DetectCommandLoop(entries, limits.CommandThreshold, limits.Window); alert != nil {
+		alert.Role = role
+		alerts = append(alerts, *alert)
+	}
+	if alert := DetectSlowCommand(entries, limits.SlowFactor, limits.Window); alert != nil {
 		alert.Role = role
 		alerts = append(alerts, *alert)
 	}
 
 	// Message loop detection (log.jsonl)
 	messages := readLogForRole(session, role, 50)
-	if alert := DetectMessageLoop(messages, role, 4, 300); alert != nil {
+	if alert := DetectMessageLoop(messages, role, limits.MessageThreshold, limits.Window); alert != nil {
+		alert.Role = role
+		alerts = append(alerts, *alert)
+	} else if alert := DetectPayloadLoop(messages, role, limits.MessageThreshold, limits.Window, limits.Similarity); alert != nil {
+		// Identical tuples are already reported as message loops
 		alert.Role = role
 		alerts = append(alerts, *alert)
 	}
 
+	alerts = append(alerts, DetectPatterns(messages, entries, role, Config().Guard.Patterns, limits.Window)...)
+	return alerts
+}
+
+// DetectPatterns evaluates custom guard patterns for a role. Message patterns
+// match payloads the role sent; command patterns match the command, summary,
+// and output of history entries. Patterns with invalid regexps are skipped.
+func DetectPatterns(messages []Message, entries []HistoryEntry, role string, patterns []GuardPattern, windowSecs int64) []LoopAlert {
+	now := time.Now().Unix()
+	var alerts []LoopAlert
+	for _, p := range patterns {
+		if len(p.Roles) > 0 && !containsRole(p.Roles, role) {
+			continue
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			continue
+		}
+		threshold := p.Threshold
+		if threshold < 1 {
+			threshold = 1
+		}
+
+		count := 0
+		var first int64
+		if p.Source == "command" {
+			for _, e := range entries {
+				if now-e.TS > windowSecs {
+					continue
+				}
+				if re.MatchString(e.Command) || re.MatchString(e.Summary) || re.MatchString(e.Output) {
+					count++
+					if first == 0 {
+						first = e.TS
+					}
+				}
+			}
+		} else {
+			for _, m := range messages {
+				if m.From != role || isSystemAction(m.Action) || now-m.TS > windowSecs {
+					continue
+				}
+				if re.MatchString(m.Payload) {
+					count++
+					if first == 0 {
+						first = m.TS
+					}
+				}
+			}
+		}
+		if count < threshold {
+			continue
+		}
+
+		text := p.Message
+		if text == "" {
+			text = p.Name
+		}
+		alerts = append(alerts, LoopAlert{
+			Role:    role,
+			Type:    "pattern",
+			Count:   count,
+			Pattern: p.Name,
+			Window:  now - first,
+			Message: fmt.Sprintf("%s: %s matched %d time(s)", role, text, count),
+		})
+	}
 	return alerts
 }
 
@@ -309,6 +685,15 @@ func FormatAlerts(alerts []LoopAlert) string {
 		if a.Type == "command" {
 			b.WriteString(fmt.Sprintf("  Command: %s (failed %dx in %s)\n", a.Command, a.Count, formatDuration(a.Window)))
 			b.WriteString("  Action: Check build window \u2014 agent may be stuck\n")
+		} else if a.Type == "slow" {
+			b.WriteString(fmt.Sprintf("  Command: %s\n", a.Command))
+			b.WriteString(fmt.Sprintf("  Action: %s \u2014 check for a regression or a resource problem\n", a.Message))
+		} else if a.Type == "pattern" {
+			b.WriteString(fmt.Sprintf("  Pattern: %s (%dx in %s)\n", a.Pattern, a.Count, formatDuration(a.Window)))
+			b.WriteString(fmt.Sprintf("  Action: %s\n", a.Message))
+		} else if a.Type == "payload" {
+			b.WriteString(fmt.Sprintf("  Peer: %s  Similar requests: %d in %s (latest action: %s)\n", a.Peer, a.Count, formatDuration(a.Window), a.Action))
+			b.WriteString("  Action: Agent may be rephrasing the same failing request\n")
 		} else {
 			b.WriteString(fmt.Sprintf("  Peer: %s  Action: %s (%dx in %s)\n", a.Peer, a.Action, a.Count, formatDuration(a.Window)))
 			b.WriteString("  Action: Agents may be in a retry loop\n")
@@ -335,8 +720,8 @@ func FormatAlertsJSON(alerts []LoopAlert) (string, error) {
 // indicative of agent-to-agent loops.
 func isSystemAction(action string) bool {
 	switch action {
-	case "loop-detected", "compact-recommended", "proc-complete", "spawn-complete",
-		"ollama-down", "ollama-recovered", "ollama-restarting":
+	case "loop-detected", GuardStopAction, BudgetExceededAction, "compact-recommended", "proc-complete", "spawn-complete", SpawnGroupCompleteAction,
+		"ollama-down", "ollama-recovered", "ollama-restarting", ResourcePressureAction, "sla-breach", FlakySuspectAction:
 		return true
 	}
 	return false
@@ -360,6 +745,18 @@ func AlertKey(a LoopAlert) string {
 	if a.Type == "command" {
 		return fmt.Sprintf("%s:command:%s", a.Role, a.Command)
 	}
+	if a.Type == "payload" {
+		return fmt.Sprintf("%s:payload:%s", a.Role, a.Peer)
+	}
+	if a.Type == "slow" {
+		return fmt.Sprintf("%s:slow:%s", a.Role, a.Command)
+	}
+	if a.Type == "pattern" {
+		return fmt.Sprintf("%s:pattern:%s", a.Role, a.Pattern)
+	}
+	if a.Type == "budget" {
+		return a.Role + ":budget"
+	}
 	return fmt.Sprintf("%s:message:%s:%s", a.Role, a.Peer, a.Action)
 }
 
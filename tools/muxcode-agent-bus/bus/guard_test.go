@@ -480,6 +480,52 @@ func TestFormatAlertsJSON_Empty(t *testing.T) {
 	}
 }
 
+func TestDetectSlowCommand(t *testing.T) {
+	now := time.Now().Unix()
+	run := func(ago int64, cmd, outcome string, ms int64) HistoryEntry {
+		return HistoryEntry{TS: now - ago, Command: cmd, Outcome: outcome, DurationMs: ms}
+	}
+	entries := []HistoryEntry{
+		run(500, "go test ./...", "success", 10000),
+		run(400, "go test ./...", "failure", 500), // failed runs are not a baseline
+		run(300, "cd /src && go test ./...", "success", 12000),
+		run(200, "go build ./...", "success", 60000),
+		run(100, "go test ./...", "success", 14000),
+		run(0, "go test ./...", "success", 30000),
+	}
+
+	alert := DetectSlowCommand(entries, 2, 300)
+	if alert == nil {
+		t.Fatal("expected slow alert, got nil")
+	}
+	if alert.Type != "slow" || alert.Command != "go test ./..." || alert.Count != 3 {
+		t.Errorf("alert = %+v", alert)
+	}
+	if alert.Message != "go test ./... took 30s, 2.5x its median of 12s over the last 3 runs" {
+		t.Errorf("message = %q", alert.Message)
+	}
+
+	if DetectSlowCommand(entries, 3, 300) != nil {
+		t.Error("2.5x flagged with factor 3")
+	}
+	if DetectSlowCommand(entries[1:], 2, 300) != nil {
+		t.Error("flagged with only 2 baseline runs")
+	}
+	if DetectSlowCommand(append(entries[:5:5], run(0, "go test ./...", "success", 0)), 2, 300) != nil {
+		t.Error("untimed run flagged")
+	}
+	stale := append(entries[:5:5], run(600, "go test ./...", "success", 30000))
+	if DetectSlowCommand(stale, 2, 300) != nil {
+		t.Error("run outside the window flagged")
+	}
+
+	// Start and end timestamps stand in for a missing duration
+	timed := HistoryEntry{StartedAt: 1_800_000_000_000, EndedAt: 1_800_000_030_000}
+	if d := timed.Duration(); d != 30000 {
+		t.Errorf("Duration() = %d, want 30000", d)
+	}
+}
+
 func TestAlertKey(t *testing.T) {
 	cmd := LoopAlert{Role: "build", Type: "command", Command: "go build ./..."}
 	if got := AlertKey(cmd); got != "build:command:go build ./..." {
@@ -490,6 +536,217 @@ func TestAlertKey(t *testing.T) {
 	if got := AlertKey(msg); got != "test:message:build:test" {
 		t.Errorf("AlertKey(msg) = %q", got)
 	}
+
+	payload := LoopAlert{Role: "build", Type: "payload", Peer: "edit", Action: "retry"}
+	if got := AlertKey(payload); got != "build:payload:edit" {
+		t.Errorf("AlertKey(payload) = %q", got)
+	}
+
+	slow := LoopAlert{Role: "test", Type: "slow", Command: "go test ./..."}
+	if got := AlertKey(slow); got != "test:slow:go test ./..." {
+		t.Errorf("AlertKey(slow) = %q", got)
+	}
+}
+
+func TestPayloadSimilarity(t *testing.T) {
+	a := payloadTokens("Fix the failing auth test in login_test.go")
+	b := payloadTokens("Please fix failing auth test login_test.go")
+	c := payloadTokens("Deploy the staging stack")
+
+	if s := payloadSimilarity(a, a); s != 1 {
+		t.Errorf("identical similarity = %v, want 1", s)
+	}
+	if s := payloadSimilarity(a, b); s < DefaultPayloadSimilarity {
+		t.Errorf("rephrased similarity = %v, want >= %v", s, DefaultPayloadSimilarity)
+	}
+	if s := payloadSimilarity(a, c); s != 0 {
+		t.Errorf("unrelated similarity = %v, want 0", s)
+	}
+	if s := payloadSimilarity(a, payloadTokens("")); s != 0 {
+		t.Errorf("empty similarity = %v, want 0", s)
+	}
+}
+
+func TestDetectPayloadLoop_Rephrased(t *testing.T) {
+	now := time.Now().Unix()
+	// Same request reworded with a different action each time — the tuple
+	// check misses this, payload similarity catches it
+	messages := []Message{
+		{TS: now - 90, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Build the project, auth module fails to compile"},
+		{TS: now - 60, From: "edit", To: "build", Action: "rebuild", Type: "request", Payload: "Rebuild the project: auth module still fails to compile"},
+		{TS: now - 30, From: "edit", To: "build", Action: "retry", Type: "request", Payload: "Retry build, the auth module fails compile"},
+		{TS: now, From: "edit", To: "build", Action: "compile", Type: "request", Payload: "Please compile project again, auth module fails"},
+	}
+
+	if alert := DetectMessageLoop(messages, "build", 4, 300); alert != nil {
+		t.Fatalf("tuple check should not fire for distinct actions, got %+v", alert)
+	}
+
+	alert := DetectPayloadLoop(messages, "build", 4, 300, 0.4)
+	if alert == nil {
+		t.Fatal("expected payload loop alert, got nil")
+	}
+	if alert.Type != "payload" {
+		t.Errorf("type = %q, want %q", alert.Type, "payload")
+	}
+	if alert.Count != 4 {
+		t.Errorf("count = %d, want 4", alert.Count)
+	}
+	if alert.Peer != "edit" {
+		t.Errorf("peer = %q, want %q", alert.Peer, "edit")
+	}
+	if alert.Action != "compile" {
+		t.Errorf("action = %q, want latest action %q", alert.Action, "compile")
+	}
+}
+
+func TestDetectPayloadLoop_Threshold(t *testing.T) {
+	now := time.Now().Unix()
+	messages := []Message{
+		{TS: now - 60, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Build the auth module"},
+		{TS: now - 30, From: "edit", To: "build", Action: "rebuild", Type: "request", Payload: "Rebuild auth module now"},
+		{TS: now, From: "edit", To: "build", Action: "retry", Type: "request", Payload: "Retry auth module build"},
+	}
+
+	// Strict similarity — rewordings no longer match
+	if alert := DetectPayloadLoop(messages, "build", 3, 300, 0.95); alert != nil {
+		t.Errorf("expected nil at 0.95 similarity, got %+v", alert)
+	}
+	// Zero similarity disables detection
+	if alert := DetectPayloadLoop(messages, "build", 3, 300, 0); alert != nil {
+		t.Errorf("expected nil with similarity 0, got %+v", alert)
+	}
+}
+
+func TestDetectPayloadLoop_DistinctRequests(t *testing.T) {
+	now := time.Now().Unix()
+	messages := []Message{
+		{TS: now - 90, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Build the auth module"},
+		{TS: now - 60, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Compile the payments service"},
+		{TS: now - 30, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Bundle frontend assets"},
+		{TS: now, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Generate protobuf stubs"},
+	}
+
+	if alert := DetectPayloadLoop(messages, "build", 4, 300, DefaultPayloadSimilarity); alert != nil {
+		t.Errorf("expected nil for distinct payloads, got %+v", alert)
+	}
+}
+
+func TestDetectPayloadLoop_OutsideWindowAndOtherDirection(t *testing.T) {
+	now := time.Now().Unix()
+	messages := []Message{
+		{TS: now - 600, From: "edit", To: "build", Action: "build", Type: "request", Payload: "Build the auth module"},
+		{TS: now - 30, From: "build", To: "edit", Action: "ask", Type: "request", Payload: "Build the auth module?"},
+		{TS: now - 20, From: "edit", To: "build", Action: "retry", Type: "request", Payload: "Build the auth module"},
+		{TS: now - 10, From: "watcher", To: "build", Action: "notify", Type: "request", Payload: "Build the auth module"},
+		{TS: now, From: "edit", To: "build", Action: "rebuild", Type: "response", Payload: "Build the auth module"},
+	}
+
+	if alert := DetectPayloadLoop(messages, "build", 3, 300, DefaultPayloadSimilarity); alert != nil {
+		t.Errorf("expected nil, got %+v", alert)
+	}
+}
+
+func TestGuardSimilarity_Config(t *testing.T) {
+	cfg := DefaultConfig()
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if got := GuardSimilarity(); got != DefaultPayloadSimilarity {
+		t.Errorf("default = %v, want %v", got, DefaultPayloadSimilarity)
+	}
+
+	cfg.Guard.Similarity = 0.8
+	if got := GuardSimilarity(); got != 0.8 {
+		t.Errorf("configured = %v, want 0.8", got)
+	}
+
+	cfg.Guard.Similarity = 1.5
+	if got := GuardSimilarity(); got != DefaultPayloadSimilarity {
+		t.Errorf("out of range = %v, want default", got)
+	}
+}
+
+func TestGuardLimitsFor(t *testing.T) {
+	cfg := DefaultConfig()
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	got := GuardLimitsFor("build")
+	want := GuardLimits{Similarity: DefaultPayloadSimilarity, CommandThreshold: 3, MessageThreshold: 4, Window: 300, SlowFactor: DefaultSlowFactor}
+	if got != want {
+		t.Errorf("defaults = %+v, want %+v", got, want)
+	}
+
+	cfg.Guard.Window = 600
+	cfg.Guard.Roles = map[string]GuardLimits{"build": {CommandThreshold: 5}}
+	if got := GuardLimitsFor("build"); got.CommandThreshold != 5 || got.Window != 600 || got.MessageThreshold != 4 {
+		t.Errorf("build = %+v", got)
+	}
+	if got := GuardLimitsFor("test"); got.CommandThreshold != 3 || got.Window != 600 {
+		t.Errorf("test = %+v", got)
+	}
+}
+
+func TestDetectPatterns(t *testing.T) {
+	now := time.Now().Unix()
+	messages := []Message{
+		{TS: now - 100, From: "build", To: "edit", Action: "result", Payload: "429 rate limit exceeded"},
+		{TS: now - 50, From: "build", To: "edit", Action: "result", Payload: "Rate limit hit again"},
+		{TS: now - 40, From: "edit", To: "build", Action: "build", Payload: "rate limit? retry"},
+		{TS: now - 1000, From: "build", To: "edit", Action: "result", Payload: "rate limit (old)"},
+	}
+	entries := []HistoryEntry{
+		{TS: now - 30, Command: "terraform apply", Output: "Error acquiring the state lock"},
+	}
+	patterns := []GuardPattern{
+		{Name: "rate-limit", Pattern: "(?i)rate limit", Threshold: 2, Message: "Provider is throttling"},
+		{Name: "state-lock", Pattern: "state lock", Source: "command"},
+		{Name: "deploy-only", Pattern: ".", Roles: []string{"deploy"}},
+		{Name: "broken", Pattern: "("},
+	}
+
+	alerts := DetectPatterns(messages, entries, "build", patterns, 300)
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %+v", alerts)
+	}
+	if alerts[0].Pattern != "rate-limit" || alerts[0].Count != 2 || alerts[0].Type != "pattern" {
+		t.Errorf("rate-limit alert = %+v", alerts[0])
+	}
+	if !strings.Contains(alerts[0].Message, "Provider is throttling") {
+		t.Errorf("message = %q", alerts[0].Message)
+	}
+	if alerts[1].Pattern != "state-lock" || alerts[1].Count != 1 {
+		t.Errorf("state-lock alert = %+v", alerts[1])
+	}
+	if AlertKey(alerts[0]) != "build:pattern:rate-limit" {
+		t.Errorf("AlertKey = %q", AlertKey(alerts[0]))
+	}
+	if out := FormatAlerts(alerts); !strings.Contains(out, "Pattern: rate-limit (2x") {
+		t.Errorf("FormatAlerts:\n%s", out)
+	}
+
+	patterns[0].Threshold = 3
+	if got := DetectPatterns(messages, nil, "build", patterns[:1], 300); len(got) != 0 {
+		t.Errorf("below threshold should not alert: %+v", got)
+	}
+}
+
+func TestFormatAlerts_PayloadLoop(t *testing.T) {
+	alerts := []LoopAlert{
+		{Role: "build", Type: "payload", Count: 4, Peer: "edit", Action: "retry", Window: 90},
+	}
+
+	out := FormatAlerts(alerts)
+	if !strings.Contains(out, "payload") {
+		t.Error("missing type")
+	}
+	if !strings.Contains(out, "Similar requests: 4") {
+		t.Errorf("missing similar count: %s", out)
+	}
+	if !strings.Contains(out, "rephrasing") {
+		t.Error("missing remediation hint")
+	}
 }
 
 func TestFilterNewAlerts(t *testing.T) {
@@ -0,0 +1,225 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Handbook collects everything an agent was instructed and permitted to do.
+type Handbook struct {
+	Role       string
+	AgentDef   string         // agent definition markdown (frontmatter stripped)
+	Tools      []string       // resolved allowed tool patterns
+	SendDeny   []string       // roles this role may not send to
+	DeniedFrom []string       // roles that may not send to this role
+	Sandbox    *SandboxPolicy // harness sandbox, nil if unrestricted
+	Prompt     string         // shared coordination prompt
+	Skills     []SkillDef
+	Context    []ContextFile
+}
+
+// BuildHandbook assembles the handbook for a role from the same sources the
+// agent launcher and local agent loop use.
+func BuildHandbook(role string) Handbook {
+	h := Handbook{
+		Role:     role,
+		AgentDef: strings.TrimSpace(readAgentDefinition(role)),
+		Tools:    ResolveTools(role),
+		Sandbox:  ResolveSandbox(role),
+		Prompt:   SharedPrompt(role),
+	}
+
+	cfg := Config()
+	if p, ok := cfg.SendPolicy[role]; ok {
+		h.SendDeny = p.Deny
+	}
+	for from, p := range cfg.SendPolicy {
+		for _, to := range p.Deny {
+			if to == role {
+				h.DeniedFrom = append(h.DeniedFrom, from)
+			}
+		}
+	}
+	sort.Strings(h.DeniedFrom)
+
+	h.Skills, _ = SkillsForRole(role)
+	h.Context, _ = AllContextFilesForRole(role)
+	return h
+}
+
+// FormatHandbook renders a handbook as a single markdown document.
+func FormatHandbook(h Handbook) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s agent handbook\n\n", h.Role)
+	fmt.Fprintf(&b, "_Generated %s by `muxcode-agent-bus docs handbook %s`._\n\n", time.Now().Format("2006-01-02 15:04"), h.Role)
+
+	b.WriteString("## Contents\n\n")
+	b.WriteString("1. Permissions\n2. Agent definition\n3. Coordination prompt\n4. Skills\n5. Context files\n\n")
+
+	b.WriteString("## 1. Permissions\n\n")
+	b.WriteString("### Allowed tools\n\n")
+	if len(h.Tools) == 0 {
+		b.WriteString("_No tool profile — the agent runs with default permissions._\n\n")
+	} else {
+		for _, t := range h.Tools {
+			fmt.Fprintf(&b, "- `%s`\n", t)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("### Send policy\n\n")
+	if len(h.SendDeny) == 0 && len(h.DeniedFrom) == 0 {
+		b.WriteString("_No send restrictions._\n\n")
+	} else {
+		if len(h.SendDeny) > 0 {
+			fmt.Fprintf(&b, "- May not send to: %s\n", strings.Join(h.SendDeny, ", "))
+		}
+		if len(h.DeniedFrom) > 0 {
+			fmt.Fprintf(&b, "- May not receive from: %s\n", strings.Join(h.DeniedFrom, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("### Sandbox\n\n")
+	if h.Sandbox == nil {
+		b.WriteString("_Not sandboxed._\n\n")
+	} else {
+		jail := h.Sandbox.Jail
+		if jail == "" {
+			jail = "."
+		}
+		fmt.Fprintf(&b, "- Jail: `%s`\n", jail)
+		network := "allowed"
+		if h.Sandbox.DenyNetwork {
+			network = "denied"
+		}
+		fmt.Fprintf(&b, "- Network: %s\n", network)
+		if len(h.Sandbox.ReadOnly) > 0 {
+			fmt.Fprintf(&b, "- Read-only: %s\n", "`"+strings.Join(h.Sandbox.ReadOnly, "`, `")+"`")
+		}
+		if h.Sandbox.MaxOutput > 0 {
+			fmt.Fprintf(&b, "- Output cap: %d bytes\n", h.Sandbox.MaxOutput)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## 2. Agent definition\n\n")
+	if h.AgentDef == "" {
+		fmt.Fprintf(&b, "_No agent definition found for `%s` (looked for `%s.md`)._\n\n", h.Role, agentFileName(h.Role))
+	} else {
+		b.WriteString(demoteHeadings(h.AgentDef, 2))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("## 3. Coordination prompt\n\n")
+	b.WriteString(demoteHeadings(strings.TrimSpace(h.Prompt), 2))
+	b.WriteString("\n\n")
+
+	b.WriteString("## 4. Skills\n\n")
+	if len(h.Skills) == 0 {
+		b.WriteString("_No skills apply to this role._\n\n")
+	} else {
+		for _, s := range h.Skills {
+			fmt.Fprintf(&b, "### %s\n\n", s.Name)
+			if s.Description != "" {
+				fmt.Fprintf(&b, "_%s_ (source: %s)\n\n", s.Description, s.Source)
+			}
+			b.WriteString(demoteHeadings(strings.TrimSpace(s.Body), 3))
+			b.WriteString("\n\n")
+		}
+	}
+
+	b.WriteString("## 5. Context files\n\n")
+	if len(h.Context) == 0 {
+		b.WriteString("_No context files apply to this role._\n")
+	} else {
+		for _, f := range h.Context {
+			fmt.Fprintf(&b, "### %s\n\n", f.Name)
+			fmt.Fprintf(&b, "_Role: %s, source: %s_\n\n", f.Role, f.Source)
+			b.WriteString(demoteHeadings(strings.TrimSpace(f.Body), 3))
+			b.WriteString("\n\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// demoteHeadings pushes markdown headings down by levels so embedded
+// documents nest under the handbook's own sections. Fenced code is skipped.
+func demoteHeadings(md string, levels int) string {
+	prefix := strings.Repeat("#", levels)
+	lines := strings.Split(md, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ServeHandbooks serves rendered handbooks over HTTP until ctx is cancelled.
+// GET / lists roles; GET /{role} renders that role's handbook (regenerated on
+// each request so edits to prompts and skills show up on refresh).
+func ServeHandbooks(ctx context.Context, addr string, roles []string) error {
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      handbookHandler(roles),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// handbookHandler returns the handler for ServeHandbooks. /{role}.md returns
+// raw markdown; /{role} wraps it in a minimal HTML page. Roles not in roles
+// are 404s.
+func handbookHandler(roles []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := strings.Trim(r.URL.Path, "/")
+		if name := strings.TrimSuffix(role, ".md"); role != "" && !slices.Contains(roles, name) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if role == "" {
+			var b strings.Builder
+			b.WriteString("<!doctype html><title>muxcode handbooks</title><h1>Agent handbooks</h1><ul>")
+			for _, r := range roles {
+				fmt.Fprintf(&b, "<li><a href=\"/%s\">%s</a></li>", html.EscapeString(r), html.EscapeString(r))
+			}
+			b.WriteString("</ul>")
+			fmt.Fprint(w, b.String())
+			return
+		}
+		if strings.HasSuffix(role, ".md") {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			fmt.Fprint(w, FormatHandbook(BuildHandbook(strings.TrimSuffix(role, ".md"))))
+			return
+		}
+		md := FormatHandbook(BuildHandbook(role))
+		fmt.Fprintf(w, "<!doctype html><title>%s handbook</title><p><a href=\"/\">all roles</a> · <a href=\"/%s.md\">raw markdown</a></p><pre style=\"white-space:pre-wrap\">%s</pre>",
+			html.EscapeString(role), html.EscapeString(role), html.EscapeString(md))
+	}
+}
@@ -0,0 +1,173 @@
+package bus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildHandbook(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SendPolicy = map[string]SendPolicy{
+		"build": {Deny: []string{"test"}},
+		"test":  {Deny: []string{"review"}},
+	}
+	cfg.Sandbox = map[string]SandboxPolicy{
+		"test": {DenyNetwork: true, ReadOnly: []string{"vendor"}},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	h := BuildHandbook("test")
+	if len(h.Tools) == 0 {
+		t.Error("expected resolved tools for test role")
+	}
+	if len(h.SendDeny) != 1 || h.SendDeny[0] != "review" {
+		t.Errorf("SendDeny = %v, want [review]", h.SendDeny)
+	}
+	if len(h.DeniedFrom) != 1 || h.DeniedFrom[0] != "build" {
+		t.Errorf("DeniedFrom = %v, want [build]", h.DeniedFrom)
+	}
+	if h.Sandbox == nil || !h.Sandbox.DenyNetwork {
+		t.Errorf("Sandbox = %+v, want deny_network", h.Sandbox)
+	}
+	if !strings.Contains(h.Prompt, "Agent Coordination") {
+		t.Error("expected shared coordination prompt")
+	}
+}
+
+func TestFormatHandbook(t *testing.T) {
+	h := Handbook{
+		Role:     "build",
+		AgentDef: "# Code Builder\n\nYou build things.\n\n```bash\n# not a heading\n```",
+		Tools:    []string{"Bash(make*)", "Read"},
+		SendDeny: []string{"test"},
+		Sandbox:  &SandboxPolicy{DenyNetwork: true, MaxOutput: 2000},
+		Prompt:   "## Agent Coordination\n\nUse the bus.",
+		Skills:   []SkillDef{{Name: "pnpm", Description: "Use pnpm", Body: "## Rules\nAlways pnpm.", Source: "project"}},
+		Context:  []ContextFile{{Name: "go", Role: "shared", Source: "auto", Body: "Go project."}},
+	}
+
+	out := FormatHandbook(h)
+	for _, want := range []string{
+		"# build agent handbook",
+		"- `Bash(make*)`",
+		"May not send to: test",
+		"Network: denied",
+		"Output cap: 2000 bytes",
+		"### Code Builder", // agent def heading demoted under section 2
+		"# not a heading",  // fenced code left alone
+		"#### Agent Coordination",
+		"### pnpm",
+		"##### Rules",
+		"### go",
+		"Go project.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("handbook missing %q", want)
+		}
+	}
+	if strings.Contains(out, "### # not a heading") {
+		t.Error("heading inside code fence should not be demoted")
+	}
+}
+
+func TestFormatHandbook_Empty(t *testing.T) {
+	out := FormatHandbook(Handbook{Role: "custom"})
+	for _, want := range []string{
+		"No tool profile",
+		"No send restrictions",
+		"Not sandboxed",
+		"No agent definition found for `custom`",
+		"No skills apply",
+		"No context files apply",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("empty handbook missing %q", want)
+		}
+	}
+}
+
+func TestBuildHandbook_SkillsAndContext(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BUS_SKILLS_DIR", filepath.Join(dir, "skills"))
+	t.Setenv("BUS_CONTEXT_DIR", filepath.Join(dir, "context.d"))
+	t.Setenv("MUXCODE_CONFIG_DIR", filepath.Join(dir, "user"))
+	SetConfig(DefaultConfig())
+	defer SetConfig(nil)
+
+	skill := "---\nname: lint-first\ndescription: Lint before build\nroles: [build]\n---\nRun the linter first.\n"
+	if err := os.MkdirAll(SkillsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(SkillsDir(), "lint-first.md"), []byte(skill), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(ContextDir(), "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ContextDir(), "build", "conventions.md"), []byte("Use make."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := FormatHandbook(BuildHandbook("build"))
+	if !strings.Contains(out, "### lint-first") || !strings.Contains(out, "Run the linter first.") {
+		t.Error("handbook missing build skill")
+	}
+	if !strings.Contains(out, "### conventions") || !strings.Contains(out, "Use make.") {
+		t.Error("handbook missing build context file")
+	}
+
+	other := FormatHandbook(BuildHandbook("review"))
+	if strings.Contains(other, "lint-first") {
+		t.Error("build-only skill leaked into review handbook")
+	}
+}
+
+func TestHandbookHandler(t *testing.T) {
+	SetConfig(DefaultConfig())
+	defer SetConfig(nil)
+
+	srv := httptest.NewServer(handbookHandler([]string{"build", "test"}))
+	defer srv.Close()
+
+	get := func(path string) (string, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.Header.Get("Content-Type"), string(body)
+	}
+
+	_, index := get("/")
+	if !strings.Contains(index, `href="/build"`) || !strings.Contains(index, `href="/test"`) {
+		t.Errorf("index missing role links: %s", index)
+	}
+
+	ctype, page := get("/build")
+	if !strings.HasPrefix(ctype, "text/html") || !strings.Contains(page, "build agent handbook") {
+		t.Errorf("role page: content-type %q, body %.80q", ctype, page)
+	}
+
+	ctype, raw := get("/build.md")
+	if !strings.HasPrefix(ctype, "text/markdown") || !strings.HasPrefix(raw, "# build agent handbook") {
+		t.Errorf("raw page: content-type %q, body %.80q", ctype, raw)
+	}
+
+	for _, path := range []string{"/review", "/nope.md", "/..%2F..%2Fetc%2Fpasswd"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
@@ -194,7 +194,7 @@ func RestartOllama(ctx context.Context, ollamaURL string) error {
 // RestartLocalAgent sends C-c to interrupt a stuck agent and relaunches it.
 // Uses tmux send-keys to target the agent's pane.
 func RestartLocalAgent(session, role string) error {
-	target := PaneTarget(session, role)
+	target := PaneTarget(session, RoleWindow(role))
 
 	// Send C-c to interrupt
 	interruptCmd := exec.Command("tmux", "send-keys", "-t", target, "C-c", "")
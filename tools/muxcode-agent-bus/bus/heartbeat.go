@@ -0,0 +1,212 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultHeartbeatInterval is how often an agent's heartbeat is touched.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// DefaultHeartbeatStale is how old a heartbeat may get before its agent is
+// reported down.
+const DefaultHeartbeatStale = 90
+
+// DefaultMaxRelaunches caps automatic pane relaunches per role per watcher.
+const DefaultMaxRelaunches = 3
+
+// HeartbeatConfig tunes agent crash detection.
+type HeartbeatConfig struct {
+	StaleSecs     int  `json:"stale_s,omitempty"`        // heartbeat age that marks an agent down (default 90)
+	Relaunch      bool `json:"relaunch,omitempty"`       // relaunch the agent pane when it goes down
+	MaxRelaunches int  `json:"max_relaunches,omitempty"` // per role per watcher run (default 3)
+
+	Failover          []string `json:"failover,omitempty"`         // roles replaced by a spawned agent while down; "*" for all
+	FailoverAfterSecs int      `json:"failover_after_s,omitempty"` // time down before failing over (default 180)
+}
+
+// Heartbeat is the last liveness report of an agent.
+type Heartbeat struct {
+	Role   string
+	PID    int
+	TS     int64
+	Exited bool // the agent process exited; written by the heartbeat loop
+}
+
+// HeartbeatStatus is a role's liveness as judged by CheckHeartbeats.
+type HeartbeatStatus struct {
+	Heartbeat
+	Age    int64
+	Down   bool
+	Reason string
+}
+
+// Validate rejects negative settings.
+func (c HeartbeatConfig) Validate() error {
+	if c.StaleSecs < 0 || c.MaxRelaunches < 0 || c.FailoverAfterSecs < 0 {
+		return fmt.Errorf("heartbeat settings must be positive")
+	}
+	for _, r := range c.Failover {
+		if r == "edit" {
+			return fmt.Errorf("edit cannot be failed over")
+		}
+	}
+	return nil
+}
+
+// Stale returns the configured stale threshold in seconds.
+func (c HeartbeatConfig) Stale() int64 {
+	if c.StaleSecs > 0 {
+		return int64(c.StaleSecs)
+	}
+	return DefaultHeartbeatStale
+}
+
+// RelaunchLimit returns the configured relaunch cap.
+func (c HeartbeatConfig) RelaunchLimit() int {
+	if c.MaxRelaunches > 0 {
+		return c.MaxRelaunches
+	}
+	return DefaultMaxRelaunches
+}
+
+// HeartbeatPath returns the heartbeat file for a role.
+func HeartbeatPath(session, role string) string {
+	return filepath.Join(BusDir(session), "lock", role+".heartbeat")
+}
+
+// TouchHeartbeat records that a role's agent (process pid) is alive.
+// Format: "{pid} {unix_timestamp}"
+func TouchHeartbeat(session, role string, pid int) error {
+	content := fmt.Sprintf("%d %d", pid, time.Now().Unix())
+	return os.WriteFile(HeartbeatPath(session, role), []byte(content), 0644)
+}
+
+// markHeartbeatExited records that a role's agent process has exited.
+func markHeartbeatExited(session, role string, pid int) error {
+	content := fmt.Sprintf("%d %d exited", pid, time.Now().Unix())
+	return os.WriteFile(HeartbeatPath(session, role), []byte(content), 0644)
+}
+
+// RemoveHeartbeat deletes a role's heartbeat so it is no longer monitored.
+func RemoveHeartbeat(session, role string) {
+	_ = os.Remove(HeartbeatPath(session, role))
+}
+
+// ReadHeartbeat reads a role's heartbeat file.
+func ReadHeartbeat(session, role string) (Heartbeat, error) {
+	data, err := os.ReadFile(HeartbeatPath(session, role))
+	if err != nil {
+		return Heartbeat{}, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return Heartbeat{}, fmt.Errorf("malformed heartbeat for %s", role)
+	}
+	pid, err1 := strconv.Atoi(fields[0])
+	ts, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Heartbeat{}, fmt.Errorf("malformed heartbeat for %s", role)
+	}
+	return Heartbeat{Role: role, PID: pid, TS: ts, Exited: len(fields) > 2 && fields[2] == "exited"}, nil
+}
+
+// ListHeartbeats returns the heartbeats of all roles that report one,
+// sorted by role.
+func ListHeartbeats(session string) []Heartbeat {
+	entries, err := os.ReadDir(filepath.Join(BusDir(session), "lock"))
+	if err != nil {
+		return nil
+	}
+	var out []Heartbeat
+	for _, e := range entries {
+		role, ok := strings.CutSuffix(e.Name(), ".heartbeat")
+		if !ok {
+			continue
+		}
+		if hb, err := ReadHeartbeat(session, role); err == nil {
+			out = append(out, hb)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Role < out[j].Role })
+	return out
+}
+
+// processAlive reports whether a local process exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// CheckHeartbeats judges every reporting role at time now: an agent is
+// down when its process exited or its heartbeat is older than stale
+// seconds.
+func CheckHeartbeats(session string, now, stale int64) []HeartbeatStatus {
+	var out []HeartbeatStatus
+	for _, hb := range ListHeartbeats(session) {
+		st := HeartbeatStatus{Heartbeat: hb, Age: now - hb.TS}
+		switch {
+		case hb.Exited:
+			st.Down, st.Reason = true, fmt.Sprintf("process %d exited", hb.PID)
+		case st.Age > stale:
+			st.Down, st.Reason = true, fmt.Sprintf("no heartbeat for %ds", st.Age)
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// RunHeartbeat touches a role's heartbeat every interval while process pid
+// is alive, then marks the heartbeat exited and returns. A heartbeat that
+// was removed or taken over by another process (e.g. by "role restart") is
+// left alone.
+func RunHeartbeat(session, role string, pid int, interval time.Duration) error {
+	for processAlive(pid) {
+		if err := TouchHeartbeat(session, role, pid); err != nil {
+			return err
+		}
+		time.Sleep(interval)
+	}
+	if hb, err := ReadHeartbeat(session, role); err != nil || hb.PID != pid {
+		return nil
+	}
+	return markHeartbeatExited(session, role, pid)
+}
+
+// FormatHeartbeats renders heartbeat statuses as a table.
+func FormatHeartbeats(statuses []HeartbeatStatus) string {
+	if len(statuses) == 0 {
+		return "No agent heartbeats.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %-8s %-8s %s\n", "ROLE", "PID", "AGE", "STATE")
+	for _, st := range statuses {
+		state := "alive"
+		if st.Down {
+			state = "down (" + st.Reason + ")"
+		}
+		fmt.Fprintf(&b, "%-12s %-8d %-8s %s\n", st.Role, st.PID, fmt.Sprintf("%ds", st.Age), state)
+	}
+	return b.String()
+}
+
+// AgentDownMessage describes a down agent for the agent-down event.
+func AgentDownMessage(session string, st HeartbeatStatus, relaunch string) string {
+	msg := fmt.Sprintf("Agent %s is down: %s.", st.Role, st.Reason)
+	if n := InboxCount(session, st.Role); n > 0 {
+		msg += fmt.Sprintf(" %d message(s) waiting in its inbox.", n)
+	}
+	if relaunch != "" {
+		msg += " " + relaunch
+	}
+	return msg
+}
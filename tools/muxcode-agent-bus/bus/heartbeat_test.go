@@ -0,0 +1,85 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHeartbeats(t *testing.T) {
+	session := testSession(t)
+	now := time.Now().Unix()
+
+	if err := TouchHeartbeat(session, "build", 100); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(HeartbeatPath(session, "test"), []byte(fmt.Sprintf("200 %d", now-300)), 0644)
+	os.WriteFile(HeartbeatPath(session, "review"), []byte(fmt.Sprintf("300 %d exited", now)), 0644)
+	os.WriteFile(HeartbeatPath(session, "deploy"), []byte("garbage"), 0644)
+
+	got := CheckHeartbeats(session, now, 90)
+	if len(got) != 3 {
+		t.Fatalf("got %+v", got)
+	}
+	byRole := map[string]HeartbeatStatus{}
+	for _, st := range got {
+		byRole[st.Role] = st
+	}
+	if st := byRole["build"]; st.Down || st.PID != 100 {
+		t.Errorf("build = %+v", st)
+	}
+	if st := byRole["test"]; !st.Down || st.Reason != "no heartbeat for 300s" {
+		t.Errorf("test = %+v", st)
+	}
+	if st := byRole["review"]; !st.Down || st.Reason != "process 300 exited" {
+		t.Errorf("review = %+v", st)
+	}
+
+	out := FormatHeartbeats(got)
+	if !strings.Contains(out, "down (process 300 exited)") || !strings.Contains(out, "alive") {
+		t.Errorf("format:\n%s", out)
+	}
+
+	Send(session, NewMessage("edit", "review", "request", "review", "look", ""))
+	if msg := AgentDownMessage(session, byRole["review"], "Relaunched its pane (1/3)."); msg != "Agent review is down: process 300 exited. 1 message(s) waiting in its inbox. Relaunched its pane (1/3)." {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestRunHeartbeat_MarksExited(t *testing.T) {
+	session := testSession(t)
+	proc := exec.Command("sleep", "0.3")
+	if err := proc.Start(); err != nil {
+		t.Skip("sleep not available")
+	}
+	pid := proc.Process.Pid
+	go proc.Wait()
+
+	if err := RunHeartbeat(session, "build", pid, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	hb, err := ReadHeartbeat(session, "build")
+	if err != nil || !hb.Exited || hb.PID != pid {
+		t.Errorf("heartbeat = %+v, %v", hb, err)
+	}
+
+	// A heartbeat taken over by a relaunched agent is left alone
+	TouchHeartbeat(session, "build", pid+1)
+	RunHeartbeat(session, "build", pid, time.Millisecond)
+	if hb, _ := ReadHeartbeat(session, "build"); hb.Exited {
+		t.Error("heartbeat of another process marked exited")
+	}
+}
+
+func TestHeartbeatConfigDefaults(t *testing.T) {
+	var c HeartbeatConfig
+	if c.Stale() != DefaultHeartbeatStale || c.RelaunchLimit() != DefaultMaxRelaunches {
+		t.Errorf("defaults = %d, %d", c.Stale(), c.RelaunchLimit())
+	}
+	if err := (HeartbeatConfig{StaleSecs: -1}).Validate(); err == nil {
+		t.Error("negative stale_s should fail")
+	}
+}
@@ -0,0 +1,328 @@
+package bus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyReportTopFailed caps the most-failed command list.
+const historyReportTopFailed = 10
+
+// historyReportTopSlow caps the slowest command list.
+const historyReportTopSlow = 10
+
+// RoleRunStats summarizes a role's command outcomes in a report window.
+type RoleRunStats struct {
+	Role           string `json:"role"`
+	Runs           int    `json:"runs"`
+	Successes      int    `json:"successes"`
+	Failures       int    `json:"failures"`
+	Timed          int    `json:"timed"`            // runs with a recorded duration
+	MeanDurationMs int64  `json:"mean_duration_ms"` // over timed runs
+}
+
+// SuccessRate returns successes as a percentage of runs.
+func (s RoleRunStats) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Successes) * 100 / float64(s.Runs)
+}
+
+// FailedCommand is a command that failed in a report window.
+type FailedCommand struct {
+	Role     string `json:"role"`
+	Command  string `json:"command"` // normalized as by loop detection
+	Failures int    `json:"failures"`
+	LastTS   int64  `json:"last_ts"`
+}
+
+// SlowCommand is a timed command in a report window, ranked by its
+// slowest run.
+type SlowCommand struct {
+	Role    string `json:"role"`
+	Command string `json:"command"` // normalized as by loop detection
+	Runs    int    `json:"runs"`    // timed runs
+	MaxMs   int64  `json:"max_ms"`
+	MeanMs  int64  `json:"mean_ms"`
+	totalMs int64
+}
+
+// RoleMessageVolume counts the messages a role sent and received.
+type RoleMessageVolume struct {
+	Role     string `json:"role"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+}
+
+// HistoryReport summarizes session activity since a point in time.
+type HistoryReport struct {
+	Session        string              `json:"session"`
+	Since          int64               `json:"since"`
+	Until          int64               `json:"until"`
+	Roles          []RoleRunStats      `json:"roles"`
+	MostFailed     []FailedCommand     `json:"most_failed"`
+	Slowest        []SlowCommand       `json:"slowest"`
+	LoopAlerts     []Message           `json:"loop_alerts"`
+	Messages       int                 `json:"messages"`
+	MessageVolume  []RoleMessageVolume `json:"message_volume"`
+	MessagesByType map[string]int      `json:"messages_by_type"`
+}
+
+// BuildHistoryReport summarizes command history and bus traffic for the
+// window [since, now]: per-role success rates and mean durations, the
+// most-failed and slowest commands, loop alerts sent by the watcher, and message volume.
+// Roles without activity are omitted.
+func BuildHistoryReport(session string, since, now int64) HistoryReport {
+	r := HistoryReport{Session: session, Since: since, Until: now, MessagesByType: make(map[string]int)}
+
+	failed := make(map[string]*FailedCommand)
+	slow := make(map[string]*SlowCommand)
+	for _, role := range KnownRoles {
+		st := RoleRunStats{Role: role}
+		var totalMs int64
+		for _, e := range ReadHistory(session, role, 0) {
+			if e.TS < since || e.TS > now {
+				continue
+			}
+			st.Runs++
+			cmd := normalizeCommand(e.Command)
+			if cmd == "" {
+				cmd = e.Summary
+			}
+			key := role + "\x00" + cmd
+			switch e.Outcome {
+			case "success":
+				st.Successes++
+			case "failure":
+				st.Failures++
+				fc, ok := failed[key]
+				if !ok {
+					fc = &FailedCommand{Role: role, Command: cmd}
+					failed[key] = fc
+				}
+				fc.Failures++
+				if e.TS > fc.LastTS {
+					fc.LastTS = e.TS
+				}
+			}
+			if d := e.Duration(); d > 0 {
+				st.Timed++
+				totalMs += d
+				sc, ok := slow[key]
+				if !ok {
+					sc = &SlowCommand{Role: role, Command: cmd}
+					slow[key] = sc
+				}
+				sc.Runs++
+				sc.totalMs += d
+				sc.MaxMs = max(sc.MaxMs, d)
+			}
+		}
+		if st.Timed > 0 {
+			st.MeanDurationMs = totalMs / int64(st.Timed)
+		}
+		if st.Runs > 0 {
+			r.Roles = append(r.Roles, st)
+		}
+	}
+
+	for _, fc := range failed {
+		r.MostFailed = append(r.MostFailed, *fc)
+	}
+	sort.Slice(r.MostFailed, func(i, j int) bool {
+		a, b := r.MostFailed[i], r.MostFailed[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.LastTS > b.LastTS
+	})
+	if len(r.MostFailed) > historyReportTopFailed {
+		r.MostFailed = r.MostFailed[:historyReportTopFailed]
+	}
+
+	for _, sc := range slow {
+		sc.MeanMs = sc.totalMs / int64(sc.Runs)
+		r.Slowest = append(r.Slowest, *sc)
+	}
+	sort.Slice(r.Slowest, func(i, j int) bool {
+		a, b := r.Slowest[i], r.Slowest[j]
+		if a.MaxMs != b.MaxMs {
+			return a.MaxMs > b.MaxMs
+		}
+		return a.Command < b.Command
+	})
+	if len(r.Slowest) > historyReportTopSlow {
+		r.Slowest = r.Slowest[:historyReportTopSlow]
+	}
+
+	msgs, _ := readMessages(LogPath(session))
+	volume := make(map[string]*RoleMessageVolume)
+	count := func(role string) *RoleMessageVolume {
+		v, ok := volume[role]
+		if !ok {
+			v = &RoleMessageVolume{Role: role}
+			volume[role] = v
+		}
+		return v
+	}
+	for _, m := range msgs {
+		m = MigrateMessage(m)
+		if m.TS < since || m.TS > now {
+			continue
+		}
+		r.Messages++
+		r.MessagesByType[m.Type]++
+		count(m.From).Sent++
+		count(m.To).Received++
+		if m.Action == "loop-detected" {
+			r.LoopAlerts = append(r.LoopAlerts, m)
+		}
+	}
+	for _, v := range volume {
+		r.MessageVolume = append(r.MessageVolume, *v)
+	}
+	sort.Slice(r.MessageVolume, func(i, j int) bool {
+		a, b := r.MessageVolume[i], r.MessageVolume[j]
+		if a.Sent+a.Received != b.Sent+b.Received {
+			return a.Sent+a.Received > b.Sent+b.Received
+		}
+		return a.Role < b.Role
+	})
+	return r
+}
+
+// formatMeanDuration renders a mean duration, or "-" when no run was timed.
+func formatMeanDuration(s RoleRunStats) string {
+	if s.Timed == 0 {
+		return "-"
+	}
+	return (time.Duration(s.MeanDurationMs) * time.Millisecond).Round(time.Millisecond).String()
+}
+
+// historyReportWindow describes the report window: start, end, and length.
+func historyReportWindow(r HistoryReport) string {
+	return fmt.Sprintf("%s to %s (%s)",
+		time.Unix(r.Since, 0).Format("2006-01-02 15:04"),
+		time.Unix(r.Until, 0).Format("2006-01-02 15:04"),
+		time.Duration(r.Until-r.Since)*time.Second)
+}
+
+// messageTypeSummary renders message counts by type, e.g. "12 request, 3 event".
+func messageTypeSummary(byType map[string]int) string {
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%d %s", byType[t], t))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatHistoryReport renders a history report as plain text.
+func FormatHistoryReport(r HistoryReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "History report: %s\n\n", historyReportWindow(r))
+
+	b.WriteString("Commands:\n")
+	if len(r.Roles) == 0 {
+		b.WriteString("  (none)\n")
+	} else {
+		fmt.Fprintf(&b, "  %-12s %5s %5s %5s %8s %10s\n", "ROLE", "RUNS", "OK", "FAIL", "SUCCESS", "MEAN")
+		for _, s := range r.Roles {
+			fmt.Fprintf(&b, "  %-12s %5d %5d %5d %7.0f%% %10s\n", s.Role, s.Runs, s.Successes, s.Failures, s.SuccessRate(), formatMeanDuration(s))
+		}
+	}
+
+	if len(r.MostFailed) > 0 {
+		b.WriteString("\nMost failed:\n")
+		for _, fc := range r.MostFailed {
+			fmt.Fprintf(&b, "  %3dx  %-10s %s\n", fc.Failures, fc.Role, fc.Command)
+		}
+	}
+
+	if len(r.Slowest) > 0 {
+		b.WriteString("\nSlowest:\n")
+		fmt.Fprintf(&b, "  %8s %8s %5s  %-10s %s\n", "MAX", "MEAN", "RUNS", "ROLE", "COMMAND")
+		for _, sc := range r.Slowest {
+			fmt.Fprintf(&b, "  %8s %8s %5d  %-10s %s\n", formatMs(sc.MaxMs), formatMs(sc.MeanMs), sc.Runs, sc.Role, sc.Command)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nLoop alerts: %d\n", len(r.LoopAlerts))
+	for _, m := range r.LoopAlerts {
+		fmt.Fprintf(&b, "  %s  %s\n", time.Unix(m.TS, 0).Format("01-02 15:04"), m.Payload)
+	}
+
+	fmt.Fprintf(&b, "\nMessages: %d", r.Messages)
+	if r.Messages > 0 {
+		fmt.Fprintf(&b, " (%s)", messageTypeSummary(r.MessagesByType))
+	}
+	b.WriteString("\n")
+	if len(r.MessageVolume) > 0 {
+		fmt.Fprintf(&b, "  %-12s %6s %9s\n", "ROLE", "SENT", "RECEIVED")
+		for _, v := range r.MessageVolume {
+			fmt.Fprintf(&b, "  %-12s %6d %9d\n", v.Role, v.Sent, v.Received)
+		}
+	}
+	return b.String()
+}
+
+// FormatHistoryReportMarkdown renders a history report as Markdown, for
+// appending to memory or sending to the analyst.
+func FormatHistoryReportMarkdown(r HistoryReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## History report\n\n%s\n\n", historyReportWindow(r))
+
+	b.WriteString("### Commands\n\n")
+	if len(r.Roles) == 0 {
+		b.WriteString("No commands run.\n")
+	} else {
+		b.WriteString("| Role | Runs | Success | Failures | Mean duration |\n")
+		b.WriteString("|------|-----:|--------:|---------:|--------------:|\n")
+		for _, s := range r.Roles {
+			fmt.Fprintf(&b, "| %s | %d | %.0f%% | %d | %s |\n", s.Role, s.Runs, s.SuccessRate(), s.Failures, formatMeanDuration(s))
+		}
+	}
+
+	if len(r.MostFailed) > 0 {
+		b.WriteString("\n### Most failed commands\n\n")
+		for _, fc := range r.MostFailed {
+			fmt.Fprintf(&b, "- `%s` (%s): %d failures\n", strings.ReplaceAll(fc.Command, "`", "'"), fc.Role, fc.Failures)
+		}
+	}
+
+	if len(r.Slowest) > 0 {
+		b.WriteString("\n### Slowest commands\n\n")
+		for _, sc := range r.Slowest {
+			fmt.Fprintf(&b, "- `%s` (%s): max %s, mean %s over %d runs\n", strings.ReplaceAll(sc.Command, "`", "'"), sc.Role, formatMs(sc.MaxMs), formatMs(sc.MeanMs), sc.Runs)
+		}
+	}
+
+	b.WriteString("\n### Loop alerts\n\n")
+	if len(r.LoopAlerts) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, m := range r.LoopAlerts {
+		fmt.Fprintf(&b, "- %s: %s\n", time.Unix(m.TS, 0).Format("01-02 15:04"), m.Payload)
+	}
+
+	fmt.Fprintf(&b, "\n### Messages\n\n%d messages", r.Messages)
+	if r.Messages > 0 {
+		fmt.Fprintf(&b, " (%s)", messageTypeSummary(r.MessagesByType))
+	}
+	b.WriteString(".\n")
+	if len(r.MessageVolume) > 0 {
+		b.WriteString("\n| Role | Sent | Received |\n")
+		b.WriteString("|------|-----:|---------:|\n")
+		for _, v := range r.MessageVolume {
+			fmt.Fprintf(&b, "| %s | %d | %d |\n", v.Role, v.Sent, v.Received)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package bus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuildHistoryReport(t *testing.T) {
+	session := testSession(t)
+	now := int64(1_800_000_000)
+
+	for _, e := range []HistoryEntry{
+		{TS: now - 90000, Command: "go build ./...", Outcome: "failure", ExitCode: "1"}, // outside window
+		{TS: now - 300, Command: "go build ./...", Outcome: "failure", ExitCode: "1", DurationMs: 3000},
+		{TS: now - 200, Command: "cd /src && go build ./...", Outcome: "failure", ExitCode: "1", StartedAt: (now - 201) * 1000, EndedAt: (now - 200) * 1000},
+		{TS: now - 100, Command: "go build ./...", Outcome: "success", ExitCode: "0"},
+	} {
+		data, _ := json.Marshal(e)
+		if err := AppendHistory(session, "build", data, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := json.Marshal(HistoryEntry{TS: now - 50, Command: "go test ./...", Outcome: "failure", ExitCode: "1"})
+	AppendHistory(session, "test", data, 100)
+
+	for _, m := range []Message{
+		NewMessage("edit", "build", "request", "build", "go", ""),
+		NewMessage("build", "test", "request", "test", "go", ""),
+		NewMessage("watcher", "edit", "event", "loop-detected", "go build ./... failed 3x in 5m", ""),
+	} {
+		m.TS = now - 10
+		if err := Send(session, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := BuildHistoryReport(session, now-86400, now)
+	if len(r.Roles) != 2 || r.Roles[0].Role != "build" {
+		t.Fatalf("roles = %+v", r.Roles)
+	}
+	b := r.Roles[0]
+	if b.Runs != 3 || b.Successes != 1 || b.Failures != 2 || b.Timed != 2 || b.MeanDurationMs != 2000 {
+		t.Errorf("build stats = %+v", b)
+	}
+	if len(r.MostFailed) != 2 || r.MostFailed[0].Command != "go build ./..." || r.MostFailed[0].Failures != 2 {
+		t.Errorf("most failed = %+v", r.MostFailed)
+	}
+	if len(r.Slowest) != 1 || r.Slowest[0].MaxMs != 3000 || r.Slowest[0].MeanMs != 2000 || r.Slowest[0].Runs != 2 {
+		t.Errorf("slowest = %+v", r.Slowest)
+	}
+	if len(r.LoopAlerts) != 1 {
+		t.Errorf("loop alerts = %+v", r.LoopAlerts)
+	}
+	if r.Messages < 3 || r.MessagesByType["request"] < 2 {
+		t.Errorf("messages = %d %v", r.Messages, r.MessagesByType)
+	}
+
+	text := FormatHistoryReport(r)
+	if !strings.Contains(text, "Loop alerts: 1") || !strings.Contains(text, "2s") || !strings.Contains(text, "Slowest:") {
+		t.Errorf("text:\n%s", text)
+	}
+	md := FormatHistoryReportMarkdown(r)
+	if !strings.Contains(md, "| build | 3 | 33% | 2 | 2s |") || !strings.Contains(md, "- `go build ./...` (build): 2 failures") ||
+		!strings.Contains(md, "- `go build ./...` (build): max 3s, mean 2s over 2 runs") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
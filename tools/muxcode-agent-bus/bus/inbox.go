@@ -3,6 +3,7 @@ package bus
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,11 +29,22 @@ func SendNoCC(session string, m Message) error {
 
 // sendMessage is the shared implementation for Send and SendNoCC.
 func sendMessage(session string, m Message, autoCC bool) error {
-	data, err := EncodeMessage(m)
+	// A failed-over role's messages go to its replacement
+	if to, ok := FailoverTarget(session, m.To); ok {
+		m.To = to
+	}
+	if m.SchemaVersion == 0 {
+		m.SchemaVersion = MessageSchemaVersion
+	}
+	m.Payload = redactAndLog(session, "message", m.ID, m.Payload)
+	if err := ValidateMessage(m); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	stampTrace(session, &m)
+	line, err := encodeLine(m)
 	if err != nil {
 		return err
 	}
-	line := append(data[:len(data):len(data)], '\n')
 
 	// Ensure inbox directory exists
 	inboxDir := filepath.Dir(InboxPath(session, m.To))
@@ -41,13 +53,13 @@ func sendMessage(session string, m Message, autoCC bool) error {
 	}
 
 	// Append to recipient inbox
-	if err := appendToFile(InboxPath(session, m.To), line); err != nil {
+	if err := deliverInbox(session, m.To, line); err != nil {
 		return err
 	}
 
 	// Auto-CC to edit: copy messages from auto-CC roles when not already going to edit
 	if autoCC && IsAutoCCRole(m.From) && m.To != "edit" {
-		if err := appendToFile(InboxPath(session, "edit"), line); err != nil {
+		if err := deliverInbox(session, "edit", line); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: auto-CC to edit failed: %v\n", err)
 		}
 	}
@@ -57,15 +69,53 @@ func sendMessage(session string, m Message, autoCC bool) error {
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return err
 	}
-	return appendToFile(LogPath(session), line)
+	if err := appendToFile(LogPath(session), line); err != nil {
+		return err
+	}
+
+	// Trace spans are best-effort
+	_ = RecordSpan(session, messageSpan(m))
+
+	// Callbacks: record this message as a result, then register its own URL
+	if err := resolveCallbacks(session, m); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: resolving callbacks failed: %v\n", err)
+	}
+	if err := resolveWorkflowSteps(session, m); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: recording workflow reply failed: %v\n", err)
+	}
+	if err := markPRThreadsFixed(session, m); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: recording PR fix failed: %v\n", err)
+	}
+	if m.CallbackURL != "" {
+		if err := registerCallback(session, m); err != nil {
+			return fmt.Errorf("registering callback: %w", err)
+		}
+	}
+	return nil
 }
 
 // Receive reads and consumes all messages from a role's inbox.
 // Uses atomic rename to avoid losing messages.
 func Receive(session, role string) ([]Message, error) {
+	t, err := ActiveTransport()
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		msgs, err := receiveTransport(t, session, role, nil)
+		setTraceContext(session, role, msgs)
+		_ = MarkRead(session, role, msgs)
+		return msgs, err
+	}
+
 	inbox := InboxPath(session, role)
 	consuming := inbox + ".consuming"
 
+	// Leave the inbox alone if it holds messages we cannot decrypt
+	if err := checkSealed(inbox); err != nil {
+		return nil, err
+	}
+
 	// Atomic rename: move inbox to consuming file
 	if err := os.Rename(inbox, consuming); err != nil {
 		if os.IsNotExist(err) {
@@ -86,15 +136,52 @@ func Receive(session, role string) ([]Message, error) {
 	// Remove consuming file regardless of read errors
 	_ = os.Remove(consuming)
 
+	setTraceContext(session, role, msgs)
+	_ = MarkRead(session, role, msgs)
 	return msgs, err
 }
 
 // ReceiveFrom reads and consumes only messages from a specific sender,
 // leaving messages from other senders in the inbox.
 func ReceiveFrom(session, role, fromRole string) ([]Message, error) {
+	return receiveMatching(session, role, func(m Message) bool { return m.From == fromRole })
+}
+
+// ReceiveReply reads and consumes the first message replying to requestID,
+// leaving everything else in the inbox.
+func ReceiveReply(session, role, requestID string) ([]Message, error) {
+	found := false
+	return receiveMatching(session, role, func(m Message) bool {
+		if found || m.ReplyTo != requestID {
+			return false
+		}
+		found = true
+		return true
+	})
+}
+
+// receiveMatching reads and consumes the messages keep accepts, leaving the
+// rest in the inbox.
+func receiveMatching(session, role string, keep func(Message) bool) ([]Message, error) {
+	t, err := ActiveTransport()
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		msgs, err := receiveTransport(t, session, role, keep)
+		setTraceContext(session, role, msgs)
+		_ = MarkRead(session, role, msgs)
+		return msgs, err
+	}
+
 	inbox := InboxPath(session, role)
 	consuming := inbox + ".consuming"
 
+	// Leave the inbox alone if it holds messages we cannot decrypt
+	if err := checkSealed(inbox); err != nil {
+		return nil, err
+	}
+
 	// Atomic rename: move inbox to consuming file
 	if err := os.Rename(inbox, consuming); err != nil {
 		if os.IsNotExist(err) {
@@ -115,10 +202,10 @@ func ReceiveFrom(session, role, fromRole string) ([]Message, error) {
 		return nil, err
 	}
 
-	// Split into matched (from target) and unmatched (from others)
+	// Split into matched and unmatched messages
 	var matched, rest []Message
 	for _, m := range all {
-		if m.From == fromRole {
+		if keep(m) {
 			matched = append(matched, m)
 		} else {
 			rest = append(rest, m)
@@ -129,12 +216,11 @@ func ReceiveFrom(session, role, fromRole string) ([]Message, error) {
 	if len(rest) > 0 {
 		var buf []byte
 		for _, m := range rest {
-			data, encErr := EncodeMessage(m)
+			data, encErr := encodeLine(m)
 			if encErr != nil {
 				continue
 			}
 			buf = append(buf, data...)
-			buf = append(buf, '\n')
 		}
 		// Read any new messages that arrived since the rename
 		newData, _ := os.ReadFile(inbox)
@@ -146,25 +232,43 @@ func ReceiveFrom(session, role, fromRole string) ([]Message, error) {
 		}
 	}
 
+	setTraceContext(session, role, matched)
+	_ = MarkRead(session, role, matched)
 	return matched, nil
 }
 
 // Peek reads messages from a role's inbox without consuming them.
 func Peek(session, role string) ([]Message, error) {
+	t, err := ActiveTransport()
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		entries, err := t.List(session, role)
+		if err != nil {
+			return nil, err
+		}
+		msgs, _, err := transportMessages(entries)
+		return msgs, err
+	}
 	return readMessages(InboxPath(session, role))
 }
 
 // HasMessages returns true if the role's inbox has messages.
 func HasMessages(session, role string) bool {
-	info, err := os.Stat(InboxPath(session, role))
-	if err != nil {
-		return false
-	}
-	return info.Size() > 0
+	return InboxSize(session, role) > 0
 }
 
 // InboxCount returns the number of messages in a role's inbox.
 func InboxCount(session, role string) int {
+	t, err := ActiveTransport()
+	if err != nil {
+		return 0
+	}
+	if t != nil {
+		entries, _ := t.List(session, role)
+		return len(entries)
+	}
 	data, err := os.ReadFile(InboxPath(session, role))
 	if err != nil {
 		return 0
@@ -218,10 +322,13 @@ func readMessages(path string) ([]Message, error) {
 			continue
 		}
 		m, err := DecodeMessage(line)
+		if errors.Is(err, ErrSealed) {
+			return msgs, err
+		}
 		if err != nil {
 			continue // skip malformed lines
 		}
-		msgs = append(msgs, m)
+		msgs = append(msgs, MigrateMessage(m))
 	}
 	return msgs, scanner.Err()
 }
@@ -15,10 +15,12 @@ type AgentStatus struct {
 	Role       string `json:"role"`
 	Locked     bool   `json:"locked"`
 	InboxCount int    `json:"inbox_count"`
+	Unread     int    `json:"unread"`
 	LastMsgTS  int64  `json:"last_msg_ts"`
 	LastAction string `json:"last_action"`
 	LastPeer   string `json:"last_peer"`
 	LastDir    string `json:"last_dir"` // "sent" or "recv"
+	Tasks      int    `json:"tasks"`    // active board tasks assigned to the role
 }
 
 // GetAgentStatus returns the current status for a single agent role.
@@ -28,6 +30,10 @@ func GetAgentStatus(session, role string) AgentStatus {
 		Locked: IsLocked(session, role),
 	}
 	status.InboxCount = InboxCount(session, role)
+	if status.InboxCount > 0 {
+		status.Unread = UnreadCount(session, role)
+	}
+	status.Tasks = BoardCounts(session)[role]
 
 	// Find the last log entry involving this role
 	msgs := readLogForRole(session, role, 1)
@@ -61,7 +67,7 @@ func FormatStatusTable(statuses []AgentStatus) string {
 	var b strings.Builder
 
 	// Header
-	b.WriteString(fmt.Sprintf("%-12s %-6s %-6s %s\n", "ROLE", "STATE", "INBOX", "LAST ACTIVITY"))
+	b.WriteString(fmt.Sprintf("%-12s %-6s %-6s %-6s %-6s %s\n", "ROLE", "STATE", "INBOX", "UNREAD", "TASKS", "LAST ACTIVITY"))
 
 	for _, s := range statuses {
 		state := "idle"
@@ -79,7 +85,7 @@ func FormatStatusTable(statuses []AgentStatus) string {
 			activity = fmt.Sprintf("%s %s %s:%s", t, arrow, s.LastPeer, s.LastAction)
 		}
 
-		b.WriteString(fmt.Sprintf("%-12s %-6s %-6d %s\n", s.Role, state, s.InboxCount, activity))
+		b.WriteString(fmt.Sprintf("%-12s %-6s %-6d %-6d %-6d %s\n", s.Role, state, s.InboxCount, s.Unread, s.Tasks, activity))
 	}
 
 	return b.String()
@@ -170,7 +176,7 @@ func PreCommitCheck(session string) error {
 	spawns, _ := ReadSpawnEntries(session)
 	var runningSpawns []SpawnEntry
 	for _, s := range spawns {
-		if s.Status == "running" {
+		if s.Status == "running" || s.Status == "queued" {
 			runningSpawns = append(runningSpawns, s)
 		}
 	}
@@ -198,7 +204,7 @@ func PreCommitCheck(session string) error {
 		if len(task) > 60 {
 			task = task[:57] + "..."
 		}
-		issues = append(issues, fmt.Sprintf("  %s: spawned agent running (%s: %s)", sp.Owner, sp.SpawnRole, task))
+		issues = append(issues, fmt.Sprintf("  %s: spawned agent %s (%s: %s)", sp.Owner, sp.Status, sp.SpawnRole, task))
 	}
 
 	if len(issues) > 0 {
@@ -209,6 +215,25 @@ func PreCommitCheck(session string) error {
 	return nil
 }
 
+// withArchived prepends archived messages involving role to msgs, skipping
+// any still in the active log.
+func withArchived(session, role string, msgs []Message) []Message {
+	inLog := make(map[string]bool, len(msgs))
+	for _, m := range msgs {
+		inLog[m.ID] = true
+	}
+	var older []Message
+	for _, m := range ReadArchive(session) {
+		if (m.From == role || m.To == role) && !inLog[m.ID] {
+			older = append(older, m)
+		}
+	}
+	if len(older) == 0 {
+		return msgs
+	}
+	return append(older, msgs...)
+}
+
 // readLogForRole reads the session log and returns the last `limit` messages
 // involving the specified role (as sender or receiver).
 func readLogForRole(session, role string, limit int) []Message {
@@ -228,11 +253,17 @@ func readLogForRole(session, role string, limit int) []Message {
 		if err != nil {
 			continue
 		}
+		m = MigrateMessage(m)
 		if m.From == role || m.To == role {
 			all = append(all, m)
 		}
 	}
 
+	// Reach into the archive when the active log is too short
+	if limit <= 0 || len(all) < limit {
+		all = withArchived(session, role, all)
+	}
+
 	// Return last `limit` entries
 	if limit > 0 && len(all) > limit {
 		all = all[len(all)-limit:]
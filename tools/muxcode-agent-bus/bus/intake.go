@@ -0,0 +1,524 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntakeAction is the action of the request sent for each new issue.
+const IntakeAction = "intake"
+
+// JiraTokenEnv is the default environment variable holding the Jira API
+// token; the token is never stored in muxcode.json.
+const JiraTokenEnv = "MUXCODE_JIRA_TOKEN"
+
+// Intake issue status values.
+const (
+	IntakeSent     = "sent"     // request sent to the role, not reported done yet
+	IntakeDone     = "done"     // the role ran intake done; comment on the issue next
+	IntakeReported = "reported" // completion comment posted
+)
+
+// intakeDefaultInterval is how often sources are polled by default.
+const intakeDefaultInterval = 5 * time.Minute
+
+// intakeDefaultLimit caps the issues fetched per source per poll.
+const intakeDefaultLimit = 20
+
+// intakeBodyMax caps the issue description carried in a request.
+const intakeBodyMax = 4000
+
+// intakeHTTPTimeout bounds each Jira request.
+const intakeHTTPTimeout = 15 * time.Second
+
+// IntakeConfig is the "intake" section of muxcode.json.
+type IntakeConfig struct {
+	Interval string         `json:"interval,omitempty"` // Go duration between polls (default 5m)
+	Sources  []IntakeSource `json:"sources,omitempty"`
+}
+
+// IntakeSource is an issue query polled for new work.
+type IntakeSource struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`                // "github" or "jira"
+	Repo     string `json:"repo,omitempty"`      // github: owner/name (default: the current repo)
+	Label    string `json:"label,omitempty"`     // github: label the issues must carry
+	Query    string `json:"query,omitempty"`     // github: search qualifiers; jira: JQL
+	URL      string `json:"url,omitempty"`       // jira: base URL
+	User     string `json:"user,omitempty"`      // jira: account email (basic auth); empty uses a bearer token
+	TokenEnv string `json:"token_env,omitempty"` // jira: default MUXCODE_JIRA_TOKEN
+	To       string `json:"to,omitempty"`        // default "edit"
+	Limit    int    `json:"limit,omitempty"`     // default 20
+}
+
+// PollInterval returns the parsed interval, or the default when unset or
+// invalid.
+func (c IntakeConfig) PollInterval() time.Duration {
+	if d, err := time.ParseDuration(c.Interval); err == nil && d > 0 {
+		return d
+	}
+	return intakeDefaultInterval
+}
+
+// Validate rejects bad intervals, duplicate names, and sources missing
+// what their kind needs.
+func (c IntakeConfig) Validate() error {
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid interval %q", c.Interval)
+		}
+	}
+	seen := make(map[string]bool)
+	for i, s := range c.Sources {
+		if s.Name == "" {
+			return fmt.Errorf("sources.%d: name is required", i)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("sources.%d: duplicate name %q", i, s.Name)
+		}
+		seen[s.Name] = true
+		switch s.Kind {
+		case "github":
+			if s.Label == "" && s.Query == "" {
+				return fmt.Errorf("sources.%d: github sources need a label or query", i)
+			}
+		case "jira":
+			if s.URL == "" || s.Query == "" {
+				return fmt.Errorf("sources.%d: jira sources need url and query (JQL)", i)
+			}
+		default:
+			return fmt.Errorf("sources.%d: kind must be github or jira, got %q", i, s.Kind)
+		}
+		if s.Limit < 0 {
+			return fmt.Errorf("sources.%d: limit must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// target returns the role the source's issues go to.
+func (s IntakeSource) target() string {
+	if s.To != "" {
+		return s.To
+	}
+	return "edit"
+}
+
+// IntakeIssue is an issue taken in from a source.
+type IntakeIssue struct {
+	Source     string `json:"source"`
+	Key        string `json:"key"` // "#12" for GitHub, "PROJ-12" for Jira
+	Title      string `json:"title"`
+	URL        string `json:"url,omitempty"`
+	MsgID      string `json:"msg_id"`
+	To         string `json:"to"`
+	Status     string `json:"status"`
+	TakenAt    int64  `json:"taken_at"`
+	Result     string `json:"result,omitempty"` // the role's completion summary
+	DoneAt     int64  `json:"done_at,omitempty"`
+	ReportedAt int64  `json:"reported_at,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// intakeState is the intake registry.
+type intakeState struct {
+	LastPoll int64         `json:"last_poll,omitempty"`
+	Issues   []IntakeIssue `json:"issues"`
+}
+
+// IntakePath returns the session's intake registry.
+func IntakePath(session string) string {
+	return filepath.Join(BusDir(session), "intake.json")
+}
+
+// lockIntake serializes read-modify-write cycles on the registry. Tracker
+// calls run outside the lock.
+func lockIntake(session string) func() {
+	return flockBusFile(session, "intake")
+}
+
+// readIntakeState reads the registry; a missing file is empty.
+func readIntakeState(session string) (intakeState, error) {
+	var st intakeState
+	data, err := os.ReadFile(IntakePath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, err
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("parsing %s: %w", IntakePath(session), err)
+	}
+	return st, nil
+}
+
+// writeIntakeState saves the registry atomically.
+func writeIntakeState(session string, st intakeState) error {
+	if err := os.MkdirAll(BusDir(session), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := IntakePath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, IntakePath(session))
+}
+
+// ReadIntakeIssues reads the issues taken in, oldest first.
+func ReadIntakeIssues(session string) ([]IntakeIssue, error) {
+	st, err := readIntakeState(session)
+	return st.Issues, err
+}
+
+// TrackerIssue is an open issue as fetched from a tracker.
+type TrackerIssue struct {
+	Key   string
+	Title string
+	Body  string
+	URL   string
+}
+
+// fetchIssues returns the source's open issues.
+func fetchIssues(s IntakeSource) ([]TrackerIssue, error) {
+	limit := s.Limit
+	if limit == 0 {
+		limit = intakeDefaultLimit
+	}
+	if s.Kind == "jira" {
+		return fetchJiraIssues(s, limit)
+	}
+	args := []string{"issue", "list", "--state", "open", "--json", "number,title,body,url", "--limit", strconv.Itoa(limit)}
+	if s.Repo != "" {
+		args = append(args, "--repo", s.Repo)
+	}
+	if s.Label != "" {
+		args = append(args, "--label", s.Label)
+	}
+	if s.Query != "" {
+		args = append(args, "--search", s.Query)
+	}
+	out, err := ghCommand(args...)
+	if err != nil {
+		return nil, err
+	}
+	var list []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+		URL    string `json:"url"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("parsing gh issue list: %w", err)
+	}
+	issues := make([]TrackerIssue, 0, len(list))
+	for _, i := range list {
+		issues = append(issues, TrackerIssue{Key: "#" + strconv.Itoa(i.Number), Title: i.Title, Body: i.Body, URL: i.URL})
+	}
+	return issues, nil
+}
+
+// jiraRequest sends an authenticated Jira REST request and returns the body.
+func jiraRequest(s IntakeSource, method, path string, body interface{}) ([]byte, error) {
+	env := s.TokenEnv
+	if env == "" {
+		env = JiraTokenEnv
+	}
+	token := os.Getenv(env)
+	if token == "" {
+		return nil, fmt.Errorf("%s is not set", env)
+	}
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(s.URL, "/")+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "muxcode-agent-bus")
+	if s.User != "" {
+		req.SetBasicAuth(s.User, token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: intakeHTTPTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("jira %s %s returned %s", method, path, resp.Status)
+	}
+	return data, nil
+}
+
+// fetchJiraIssues runs the source's JQL through the Jira REST search API.
+func fetchJiraIssues(s IntakeSource, limit int) ([]TrackerIssue, error) {
+	q := url.Values{"jql": {s.Query}, "fields": {"summary,description"}, "maxResults": {strconv.Itoa(limit)}}
+	data, err := jiraRequest(s, http.MethodGet, "/rest/api/2/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Issues []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Summary     string `json:"summary"`
+				Description string `json:"description"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing jira search: %w", err)
+	}
+	issues := make([]TrackerIssue, 0, len(resp.Issues))
+	for _, i := range resp.Issues {
+		issues = append(issues, TrackerIssue{
+			Key: i.Key, Title: i.Fields.Summary, Body: i.Fields.Description,
+			URL: strings.TrimRight(s.URL, "/") + "/browse/" + i.Key,
+		})
+	}
+	return issues, nil
+}
+
+// postIssueComment posts a comment on an issue taken in from s.
+func postIssueComment(s IntakeSource, key, body string) error {
+	if s.Kind == "jira" {
+		_, err := jiraRequest(s, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body})
+		return err
+	}
+	args := []string{"issue", "comment", strings.TrimPrefix(key, "#"), "--body", body}
+	if s.Repo != "" {
+		args = append(args, "--repo", s.Repo)
+	}
+	_, err := ghCommand(args...)
+	return err
+}
+
+// intakePayload renders an issue as the request sent to the role.
+func intakePayload(source string, i TrackerIssue) string {
+	body := strings.TrimSpace(i.Body)
+	if len(body) > intakeBodyMax {
+		body = body[:intakeBodyMax] + "\n..."
+	}
+	if body == "" {
+		body = "(no description)"
+	}
+	return fmt.Sprintf("[%s %s] %s\n%s\n\n%s\n\nWhen done, run: muxcode-agent-bus intake done %s:%s \"<summary>\" — the summary is posted on the issue.",
+		source, i.Key, i.Title, i.URL, body, source, i.Key)
+}
+
+// PollIntake fetches each source's open issues and sends a request to the
+// source's role for every issue not taken in before. Unless force is set,
+// it does nothing until the configured interval has passed since the last
+// poll. A failing source is reported but does not stop the others. With
+// dryRun the new issues are returned and nothing is sent or recorded.
+func PollIntake(session string, now time.Time, force, dryRun bool) ([]IntakeIssue, error) {
+	cfg := Config().Intake
+	if len(cfg.Sources) == 0 {
+		return nil, nil
+	}
+	st, err := readIntakeState(session)
+	if err != nil {
+		return nil, err
+	}
+	if !force && now.Unix()-st.LastPoll < int64(cfg.PollInterval().Seconds()) {
+		return nil, nil
+	}
+
+	type fetched struct {
+		source IntakeSource
+		issues []TrackerIssue
+	}
+	var results []fetched
+	var errs []string
+	for _, s := range cfg.Sources {
+		issues, err := fetchIssues(s)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.Name, err))
+			continue
+		}
+		results = append(results, fetched{s, issues})
+	}
+
+	unlock := lockIntake(session)
+	st, err = readIntakeState(session)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	known := make(map[string]bool, len(st.Issues))
+	for _, i := range st.Issues {
+		known[i.Source+" "+i.Key] = true
+	}
+	var taken []IntakeIssue
+	var msgs []Message
+	for _, r := range results {
+		for _, ti := range r.issues {
+			if known[r.source.Name+" "+ti.Key] {
+				continue
+			}
+			msg := NewMessage("intake", r.source.target(), "request", IntakeAction, intakePayload(r.source.Name, ti), "")
+			issue := IntakeIssue{
+				Source: r.source.Name, Key: ti.Key, Title: ti.Title, URL: ti.URL,
+				MsgID: msg.ID, To: msg.To, Status: IntakeSent, TakenAt: now.Unix(),
+			}
+			taken = append(taken, issue)
+			st.Issues = append(st.Issues, issue)
+			msgs = append(msgs, msg)
+		}
+	}
+	if !dryRun {
+		st.LastPoll = now.Unix()
+		err = writeIntakeState(session, st)
+	}
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		notified := make(map[string]bool)
+		for _, m := range msgs {
+			if err := Send(session, m); err != nil {
+				return taken, err
+			}
+			if !notified[m.To] {
+				_ = Notify(session, m.To)
+				notified[m.To] = true
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return taken, fmt.Errorf("polling %s", strings.Join(errs, "; "))
+	}
+	return taken, nil
+}
+
+// MarkIntakeDone records a role's completion report for an issue. ref is
+// "source:key", a bare key when only one source has it, or the request's
+// message ID. The watcher posts the summary on the issue (see ReportIntake).
+func MarkIntakeDone(session, ref, summary string, now time.Time) (IntakeIssue, error) {
+	unlock := lockIntake(session)
+	defer unlock()
+
+	st, err := readIntakeState(session)
+	if err != nil {
+		return IntakeIssue{}, err
+	}
+	match := -1
+	for n, i := range st.Issues {
+		if ref != i.MsgID && ref != i.Source+":"+i.Key && ref != i.Key {
+			continue
+		}
+		if match >= 0 {
+			return IntakeIssue{}, fmt.Errorf("%q matches issues from %s and %s; use source:key", ref, st.Issues[match].Source, i.Source)
+		}
+		match = n
+	}
+	if match < 0 {
+		return IntakeIssue{}, fmt.Errorf("no issue %q was taken in", ref)
+	}
+	i := &st.Issues[match]
+	if i.Status == IntakeReported {
+		return *i, fmt.Errorf("%s:%s was already reported", i.Source, i.Key)
+	}
+	i.Status = IntakeDone
+	i.Result = summary
+	i.DoneAt = now.Unix()
+	return *i, writeIntakeState(session, st)
+}
+
+// ReportIntake posts a status comment on each issue whose role reported
+// completion, and returns the issues reported. A failed comment keeps the
+// issue done with the error recorded, and is retried on the next call.
+func ReportIntake(session string, now time.Time) ([]IntakeIssue, error) {
+	st, err := readIntakeState(session)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]IntakeSource)
+	for _, s := range Config().Intake.Sources {
+		sources[s.Name] = s
+	}
+	outcome := make(map[string]error)
+	for _, i := range st.Issues {
+		if i.Status != IntakeDone {
+			continue
+		}
+		s, ok := sources[i.Source]
+		if !ok {
+			outcome[i.MsgID] = fmt.Errorf("source %q is no longer configured", i.Source)
+			continue
+		}
+		comment := fmt.Sprintf("muxcode: %s reported this done.\n\n%s", i.To, i.Result)
+		outcome[i.MsgID] = postIssueComment(s, i.Key, comment)
+	}
+	if len(outcome) == 0 {
+		return nil, nil
+	}
+
+	unlock := lockIntake(session)
+	defer unlock()
+	st, err = readIntakeState(session)
+	if err != nil {
+		return nil, err
+	}
+	var reported []IntakeIssue
+	for n := range st.Issues {
+		i := &st.Issues[n]
+		cerr, ok := outcome[i.MsgID]
+		if !ok || i.Status != IntakeDone {
+			continue
+		}
+		if cerr != nil {
+			i.LastError = cerr.Error()
+			continue
+		}
+		i.Status = IntakeReported
+		i.ReportedAt = now.Unix()
+		i.LastError = ""
+		reported = append(reported, *i)
+	}
+	return reported, writeIntakeState(session, st)
+}
+
+// FormatIntakeIssues renders the issues taken in, one per line.
+func FormatIntakeIssues(issues []IntakeIssue) string {
+	if len(issues) == 0 {
+		return "No issues taken in.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-9s %-12s %-10s %-8s %s\n", "STATUS", "SOURCE", "KEY", "TO", "TITLE")
+	for _, i := range issues {
+		title := i.Title
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		if i.LastError != "" {
+			title += " [" + i.LastError + "]"
+		}
+		fmt.Fprintf(&b, "%-9s %-12s %-10s %-8s %s\n", i.Status, i.Source, i.Key, i.To, title)
+	}
+	return b.String()
+}
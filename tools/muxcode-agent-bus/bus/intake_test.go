@@ -0,0 +1,138 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPollIntake(t *testing.T) {
+	session := testSession(t)
+
+	var mu sync.Mutex
+	var jiraComments []string
+	jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@example.com" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			if r.URL.Query().Get("jql") != "project = PROJ AND labels = muxcode" {
+				t.Errorf("jql = %q", r.URL.Query().Get("jql"))
+			}
+			fmt.Fprint(w, `{"issues":[{"key":"PROJ-7","fields":{"summary":"Export CSV","description":"Add an export button."}}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue/PROJ-7/comment":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			jiraComments = append(jiraComments, string(body))
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer jira.Close()
+	t.Setenv("TEST_JIRA_TOKEN", "secret")
+
+	var ghCalls [][]string
+	orig := ghCommand
+	ghCommand = func(args ...string) ([]byte, error) {
+		ghCalls = append(ghCalls, args)
+		if args[1] == "comment" {
+			return nil, nil
+		}
+		return json.Marshal([]map[string]any{
+			{"number": 12, "title": "Login redirect loops", "body": "Steps:\n1. log in", "url": "https://github.com/o/r/issues/12"},
+		})
+	}
+	defer func() { ghCommand = orig }()
+
+	cfg := DefaultConfig()
+	cfg.Intake = IntakeConfig{Interval: "10m", Sources: []IntakeSource{
+		{Name: "gh", Kind: "github", Repo: "o/r", Label: "muxcode"},
+		{Name: "jira", Kind: "jira", URL: jira.URL, Query: "project = PROJ AND labels = muxcode", User: "bot@example.com", TokenEnv: "TEST_JIRA_TOKEN", To: "research"},
+	}}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+	now := time.Now()
+
+	taken, err := PollIntake(session, now, false, false)
+	if err != nil || len(taken) != 2 {
+		t.Fatalf("PollIntake = %+v, %v", taken, err)
+	}
+	if got := strings.Join(ghCalls[0], " "); got != "issue list --state open --json number,title,body,url --limit 20 --repo o/r --label muxcode" {
+		t.Errorf("gh args = %q", got)
+	}
+	msgs, _ := Peek(session, "edit")
+	if len(msgs) != 1 || msgs[0].Action != IntakeAction || msgs[0].From != "intake" ||
+		!strings.HasPrefix(msgs[0].Payload, "[gh #12] Login redirect loops\nhttps://github.com/o/r/issues/12\n\nSteps:\n1. log in") ||
+		!strings.Contains(msgs[0].Payload, "intake done gh:#12") {
+		t.Fatalf("edit inbox = %+v", msgs)
+	}
+	if msgs, _ := Peek(session, "research"); len(msgs) != 1 || !strings.Contains(msgs[0].Payload, jira.URL+"/browse/PROJ-7") {
+		t.Errorf("research inbox = %+v", msgs)
+	}
+
+	// Within the interval nothing is polled; forced polls skip known issues
+	if taken, _ := PollIntake(session, now.Add(time.Minute), false, false); len(taken) != 0 || len(ghCalls) != 1 {
+		t.Errorf("poll within interval: %+v (gh calls %d)", taken, len(ghCalls))
+	}
+	if taken, err := PollIntake(session, now.Add(time.Minute), true, false); err != nil || len(taken) != 0 {
+		t.Errorf("forced repoll = %+v, %v", taken, err)
+	}
+
+	// Completion reports become comments on the issues
+	if _, err := MarkIntakeDone(session, "#12", "Fixed the redirect in auth.go", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarkIntakeDone(session, "jira:PROJ-7", "Added CSV export", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarkIntakeDone(session, "#99", "x", now); err == nil {
+		t.Error("unknown issue: want error")
+	}
+	reported, err := ReportIntake(session, now)
+	if err != nil || len(reported) != 2 {
+		t.Fatalf("ReportIntake = %+v, %v", reported, err)
+	}
+	last := strings.Join(ghCalls[len(ghCalls)-1], " ")
+	if last != "issue comment 12 --body muxcode: edit reported this done.\n\nFixed the redirect in auth.go --repo o/r" {
+		t.Errorf("gh comment = %q", last)
+	}
+	if len(jiraComments) != 1 || !strings.Contains(jiraComments[0], "Added CSV export") {
+		t.Errorf("jira comments = %v", jiraComments)
+	}
+	issues, _ := ReadIntakeIssues(session)
+	if out := FormatIntakeIssues(issues); strings.Count(out, IntakeReported) != 2 {
+		t.Errorf("FormatIntakeIssues:\n%s", out)
+	}
+	if _, err := MarkIntakeDone(session, "gh:#12", "again", now); err == nil {
+		t.Error("reported issue: want error")
+	}
+}
+
+func TestIntakeConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		cfg  IntakeConfig
+		want string
+	}{
+		{IntakeConfig{Sources: []IntakeSource{{Name: "gh", Kind: "github", Label: "muxcode"}}}, ""},
+		{IntakeConfig{Interval: "soon"}, "invalid interval"},
+		{IntakeConfig{Sources: []IntakeSource{{Name: "gh", Kind: "github"}}}, "label or query"},
+		{IntakeConfig{Sources: []IntakeSource{{Name: "j", Kind: "jira", Query: "x"}}}, "url and query"},
+		{IntakeConfig{Sources: []IntakeSource{{Name: "x", Kind: "gitlab"}}}, "kind must be"},
+		{IntakeConfig{Sources: []IntakeSource{{Name: "a", Kind: "github", Label: "l"}, {Name: "a", Kind: "github", Label: "l"}}}, "duplicate name"},
+	} {
+		err := c.cfg.Validate()
+		if c.want == "" && err != nil || c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.cfg, err, c.want)
+		}
+	}
+}
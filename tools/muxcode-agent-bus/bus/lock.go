@@ -3,27 +3,37 @@ package bus
 import (
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
-// Lock creates a lock file indicating the agent is busy.
+// Lock creates a lock file indicating the agent is busy. Taking a lock
+// that is not already held is recorded in the audit log.
 func Lock(session, role string) error {
 	lockDir := filepath.Dir(LockPath(session, role))
 	if err := os.MkdirAll(lockDir, 0755); err != nil {
 		return err
 	}
+	held := IsLocked(session, role)
 	f, err := os.OpenFile(LockPath(session, role), os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
+	if !held {
+		_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditLock, Role: role})
+	}
 	return f.Close()
 }
 
-// Unlock removes the lock file for an agent.
+// Unlock removes the lock file for an agent. Releasing a held lock is
+// recorded in the audit log.
 func Unlock(session, role string) error {
 	err := os.Remove(LockPath(session, role))
 	if os.IsNotExist(err) {
 		return nil
 	}
+	if err == nil {
+		_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditUnlock, Role: role})
+	}
 	return err
 }
 
@@ -32,3 +42,26 @@ func IsLocked(session, role string) bool {
 	_, err := os.Stat(LockPath(session, role))
 	return err == nil
 }
+
+// flockBusFile takes an exclusive flock on lock/<name>.lock in the bus
+// directory, serializing read-modify-write cycles on a bus file between
+// agents and the watcher. Returns an unlock function. If the lock cannot be
+// taken it returns a no-op, like lockNotify, so callers work unlocked.
+func flockBusFile(session, name string) func() {
+	lockPath := filepath.Join(BusDir(session), "lock", name+".lock")
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return func() {}
+	}
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return func() {}
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return func() {}
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}
+}
@@ -15,6 +15,8 @@ type MemoryEntry struct {
 	Section   string // from "## Title" line
 	Timestamp string // from "_YYYY-MM-DD HH:MM_" line
 	Content   string // body text after timestamp
+	Session   string // archived session the entry came from; empty for the current project
+	Scratch   bool   // from the role's session scratchpad, not durable memory
 }
 
 // SearchResult pairs a memory entry with its relevance score.
@@ -25,7 +27,10 @@ type SearchResult struct {
 
 // ReadMemory reads the memory file for a role. Returns empty string if not found.
 func ReadMemory(role string) (string, error) {
-	data, err := os.ReadFile(MemoryPath(role))
+	if s := ActiveStore(); s != nil {
+		return readMemoryStore(s, role, startOfDay(time.Now()).Unix())
+	}
+	data, err := readBusFile(MemoryPath(role))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return "", nil
@@ -38,6 +43,23 @@ func ReadMemory(role string) (string, error) {
 // AppendMemory appends a formatted section to a role's memory file.
 // On the first write of each day, the previous day's file is archived.
 func AppendMemory(section, content, role string) error {
+	content = redactAndLog(BusSession(), "memory", role, content)
+	ts := time.Now().Format("2006-01-02 15:04")
+	entry := formatMemoryChunk(section, ts, content)
+
+	if s := ActiveStore(); s != nil {
+		stream := memoryStream(role)
+		if err := appendMemoryRecord(s, role, time.Now().Unix(), entry); err != nil {
+			return err
+		}
+		// Retention matches file archives: drop entries past RetentionDays
+		cutoff := startOfDay(time.Now().AddDate(0, 0, -DefaultRotationConfig().RetentionDays))
+		if err := s.Prune(stream, cutoff.Unix()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: memory retention failed for %s: %v\n", role, err)
+		}
+		return nil
+	}
+
 	memPath := MemoryPath(role)
 	if err := os.MkdirAll(filepath.Dir(memPath), 0755); err != nil {
 		return err
@@ -51,16 +73,61 @@ func AppendMemory(section, content, role string) error {
 		}
 	}
 
-	ts := time.Now().Format("2006-01-02 15:04")
-	entry := fmt.Sprintf("\n## %s\n_%s_\n\n%s\n", section, ts, content)
-
+	chunk, err := SealLine([]byte(entry))
+	if err != nil {
+		return err
+	}
+	prev := statMemorySource(memPath)
 	f, err := os.OpenFile(memPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = f.Write([]byte(entry))
-	return err
+	_, err = f.Write(chunk)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	indexMemoryAppend(memPath, prev, entry, role)
+	return nil
+}
+
+// formatMemoryChunk renders one memory entry as appended to a memory file.
+func formatMemoryChunk(section, ts, content string) string {
+	return fmt.Sprintf("\n## %s\n_%s_\n\n%s\n", section, ts, content)
+}
+
+// appendMemoryRecord appends one memory chunk to a role's store stream,
+// sealed like the memory file when encryption is on.
+func appendMemoryRecord(s Store, role string, ts int64, chunk string) error {
+	sealed, err := SealLine([]byte(chunk))
+	if err != nil {
+		return err
+	}
+	return s.Append(memoryStream(role), ts, sealed)
+}
+
+// readMemoryStore concatenates a role's memory records written since the
+// given time (0 for all), in the same format as the memory file.
+func readMemoryStore(s Store, role string, since int64) (string, error) {
+	records, err := s.Read(memoryStream(role), StoreQuery{Since: since})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, r := range records {
+		data, err := UnsealLine([]byte(r.Data))
+		if err != nil {
+			return "", err
+		}
+		b.Write(data)
+	}
+	return b.String(), nil
+}
+
+// startOfDay returns local midnight for t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
 }
 
 // ReadContext reads shared memory and the role's own memory, concatenated.
@@ -228,8 +295,12 @@ func FormatSearchResults(results []SearchResult) string {
 		if i > 0 {
 			b.WriteString("\n")
 		}
+		role := r.Entry.Role
+		if r.Entry.Scratch {
+			role += " scratch"
+		}
 		fmt.Fprintf(&b, "--- [%s] %s (%s) score:%.1f ---\n",
-			r.Entry.Role, r.Entry.Section, r.Entry.Timestamp, r.Score)
+			role, r.Entry.Section, r.Entry.Timestamp, r.Score)
 		b.WriteString(r.Entry.Content)
 		b.WriteString("\n")
 	}
@@ -0,0 +1,268 @@
+package bus
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Memory bundles are gzipped tarballs holding a manifest and the memory
+// entries of every role, active and archived, as JSON (memory.json) or as
+// one markdown file per role (<role>.md). Index files are not included;
+// they rebuild on the next search.
+
+// memoryBundleVersion is the bundle layout version written to the manifest.
+const memoryBundleVersion = 1
+
+// Memory bundle formats.
+const (
+	MemoryBundleJSON     = "json"
+	MemoryBundleMarkdown = "md"
+)
+
+// MemoryBundleManifest describes a memory bundle.
+type MemoryBundleManifest struct {
+	Version   int      `json:"version"`
+	Format    string   `json:"format"`
+	CreatedTS int64    `json:"created_ts"`
+	Entries   int      `json:"entries"`
+	Roles     []string `json:"roles"`
+}
+
+// memoryBundleEntry is an entry in memory.json.
+type memoryBundleEntry struct {
+	Role      string `json:"role"`
+	Section   string `json:"section"`
+	Timestamp string `json:"timestamp"`
+	Content   string `json:"content"`
+}
+
+// ExportMemory writes every memory entry, from files or the store, to w as
+// a bundle in the given format.
+func ExportMemory(w io.Writer, format string) (MemoryBundleManifest, error) {
+	if format != MemoryBundleJSON && format != MemoryBundleMarkdown {
+		return MemoryBundleManifest{}, fmt.Errorf("unknown bundle format %q (json|md)", format)
+	}
+	entries, err := AllMemoryEntriesWithArchives()
+	if err != nil {
+		return MemoryBundleManifest{}, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Role != entries[j].Role {
+			return entries[i].Role < entries[j].Role
+		}
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+
+	m := MemoryBundleManifest{Version: memoryBundleVersion, Format: format, CreatedTS: time.Now().Unix(), Entries: len(entries)}
+	files := make(map[string][]byte)
+	switch format {
+	case MemoryBundleJSON:
+		out := make([]memoryBundleEntry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, memoryBundleEntry{Role: e.Role, Section: e.Section, Timestamp: e.Timestamp, Content: e.Content})
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return m, err
+		}
+		files["memory.json"] = data
+	case MemoryBundleMarkdown:
+		for _, e := range entries {
+			name := e.Role + ".md"
+			files[name] = append(files[name], formatMemoryChunk(e.Section, e.Timestamp, e.Content)...)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !seen[e.Role] {
+			seen[e.Role] = true
+			m.Roles = append(m.Roles, e.Role)
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// The manifest goes first so readers can check the format up front
+	files["manifest.json"] = manifest
+	names = append([]string{"manifest.json"}, names...)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), ModTime: time.Unix(m.CreatedTS, 0)}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return m, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return m, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return m, err
+	}
+	return m, gz.Close()
+}
+
+// ReadMemoryBundle reads a bundle written by ExportMemory and returns its
+// manifest and entries.
+func ReadMemoryBundle(r io.Reader) (MemoryBundleManifest, []MemoryEntry, error) {
+	var m MemoryBundleManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return m, nil, fmt.Errorf("not a memory bundle: %v", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return m, nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	data, ok := files["manifest.json"]
+	if !ok {
+		return m, nil, fmt.Errorf("not a memory bundle: missing manifest.json")
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if m.Version > memoryBundleVersion {
+		return m, nil, fmt.Errorf("bundle version %d is newer than supported (%d)", m.Version, memoryBundleVersion)
+	}
+
+	var entries []MemoryEntry
+	switch m.Format {
+	case MemoryBundleJSON:
+		var in []memoryBundleEntry
+		if err := json.Unmarshal(files["memory.json"], &in); err != nil {
+			return m, nil, fmt.Errorf("invalid memory.json: %v", err)
+		}
+		for _, e := range in {
+			entries = append(entries, MemoryEntry{Role: e.Role, Section: e.Section, Timestamp: e.Timestamp, Content: e.Content})
+		}
+	case MemoryBundleMarkdown:
+		names := make([]string, 0, len(files))
+		for name := range files {
+			if strings.HasSuffix(name, ".md") && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entries = append(entries, ParseMemoryEntries(string(files[name]), strings.TrimSuffix(name, ".md"))...)
+		}
+	default:
+		return m, nil, fmt.Errorf("unknown bundle format %q", m.Format)
+	}
+
+	for _, e := range entries {
+		if !validMemoryName(e.Role) {
+			return m, nil, fmt.Errorf("invalid role %q in bundle", e.Role)
+		}
+	}
+	return m, entries, nil
+}
+
+// memoryEntryKey identifies an entry for duplicate detection on import.
+func memoryEntryKey(e MemoryEntry) string {
+	return e.Role + "\x00" + e.Section + "\x00" + e.Timestamp + "\x00" + e.Content
+}
+
+// ImportMemory adds bundle entries that are not already present, keeping
+// their roles and timestamps. With file-backed memory, entries dated today
+// go to the role's active file and older ones to the archive for their
+// date, so rotation and retention treat them like native entries. Returns
+// the number of entries imported and skipped as duplicates.
+func ImportMemory(entries []MemoryEntry) (int, int, error) {
+	existing, err := AllMemoryEntriesWithArchives()
+	if err != nil {
+		return 0, 0, err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		have[memoryEntryKey(e)] = true
+	}
+
+	var fresh []MemoryEntry
+	skipped := 0
+	for _, e := range entries {
+		key := memoryEntryKey(e)
+		if have[key] {
+			skipped++
+			continue
+		}
+		have[key] = true
+		e.Content = redactAndLog(BusSession(), "memory", e.Role, e.Content)
+		fresh = append(fresh, e)
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].Timestamp < fresh[j].Timestamp })
+
+	today := time.Now().Format("2006-01-02")
+	imported := 0
+	for _, e := range fresh {
+		chunk := formatMemoryChunk(e.Section, e.Timestamp, e.Content)
+		ts, parseErr := time.ParseInLocation("2006-01-02 15:04", e.Timestamp, time.Local)
+		if s := ActiveStore(); s != nil {
+			at := time.Now()
+			if parseErr == nil {
+				at = ts
+			}
+			if err := appendMemoryRecord(s, e.Role, at.Unix(), chunk); err != nil {
+				return imported, skipped, err
+			}
+			imported++
+			continue
+		}
+
+		target := MemoryPath(e.Role)
+		if parseErr == nil && ts.Format("2006-01-02") != today {
+			target = MemoryArchivePath(e.Role, ts.Format("2006-01-02"))
+		} else if NeedsRotation(e.Role) {
+			if err := RotateMemory(e.Role, DefaultRotationConfig()); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: memory rotation failed for %s: %v\n", e.Role, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return imported, skipped, err
+		}
+		sealed, err := SealLine([]byte(chunk))
+		if err != nil {
+			return imported, skipped, err
+		}
+		if err := appendToFile(target, sealed); err != nil {
+			return imported, skipped, err
+		}
+		imported++
+	}
+	return imported, skipped, nil
+}
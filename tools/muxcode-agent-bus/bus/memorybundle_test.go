@@ -0,0 +1,106 @@
+package bus
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// seedBundleMemory writes one entry today and one archived entry.
+func seedBundleMemory(t *testing.T) {
+	t.Helper()
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	archive := MemoryArchivePath("deploy", "2026-01-05")
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		t.Fatal(err)
+	}
+	chunk := formatMemoryChunk("Deploy Gotcha", "2026-01-05 09:30", "cdk deploy needs the bootstrap stack")
+	if err := os.WriteFile(archive, []byte(chunk), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sortedEntryKeys(entries []MemoryEntry) []string {
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, memoryEntryKey(e))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMemoryBundle_RoundTrip(t *testing.T) {
+	for _, format := range []string{MemoryBundleJSON, MemoryBundleMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+			seedBundleMemory(t)
+			want, err := AllMemoryEntriesWithArchives()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			m, err := ExportMemory(&buf, format)
+			if err != nil {
+				t.Fatalf("ExportMemory: %v", err)
+			}
+			if m.Entries != 2 || len(m.Roles) != 2 || m.Format != format {
+				t.Errorf("unexpected manifest %+v", m)
+			}
+
+			// Import into an empty memory dir
+			t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+			got, entries, err := ReadMemoryBundle(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadMemoryBundle: %v", err)
+			}
+			if got.Entries != 2 {
+				t.Errorf("manifest entries = %d, want 2", got.Entries)
+			}
+			imported, skipped, err := ImportMemory(entries)
+			if err != nil || imported != 2 || skipped != 0 {
+				t.Fatalf("ImportMemory: imported=%d skipped=%d err=%v", imported, skipped, err)
+			}
+
+			after, err := AllMemoryEntriesWithArchives()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(sortedEntryKeys(after), "|") != strings.Join(sortedEntryKeys(want), "|") {
+				t.Errorf("entries differ after round trip:\n got %q\nwant %q", sortedEntryKeys(after), sortedEntryKeys(want))
+			}
+			// Older entries land in the archive for their date
+			if _, err := os.Stat(MemoryArchivePath("deploy", "2026-01-05")); err != nil {
+				t.Errorf("expected deploy archive for 2026-01-05: %v", err)
+			}
+			if _, err := os.Stat(MemoryPath("build")); err != nil {
+				t.Errorf("expected today's entry in the active file: %v", err)
+			}
+
+			// Re-importing skips everything
+			imported, skipped, err = ImportMemory(entries)
+			if err != nil || imported != 0 || skipped != 2 {
+				t.Errorf("re-import: imported=%d skipped=%d err=%v", imported, skipped, err)
+			}
+		})
+	}
+}
+
+func TestExportMemory_UnknownFormat(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	var buf bytes.Buffer
+	if _, err := ExportMemory(&buf, "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestReadMemoryBundle_Invalid(t *testing.T) {
+	if _, _, err := ReadMemoryBundle(strings.NewReader("not gzip")); err == nil {
+		t.Error("expected error for non-gzip input")
+	}
+}
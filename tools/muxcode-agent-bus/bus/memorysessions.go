@@ -0,0 +1,164 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchivedSessionDir returns the directory holding an archived session's memory.
+func ArchivedSessionDir(name string) string {
+	return filepath.Join(GlobalMemoryDir(), name)
+}
+
+// validMemoryName reports whether name is usable as a memory file or
+// directory name: non-empty, no path separators, not "." or "..".
+func validMemoryName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// ArchiveSessionMemory copies the project's memory (active and archived
+// entries, from files or the store) into the global memory dir under name,
+// one file per role, so later projects can search it. Archiving under an
+// existing name replaces that archive. Returns the archive directory and
+// the number of entries copied.
+func ArchiveSessionMemory(name string) (string, int, error) {
+	if !validMemoryName(name) {
+		return "", 0, fmt.Errorf("invalid session name %q", name)
+	}
+	entries, err := AllMemoryEntriesWithArchives()
+	if err != nil {
+		return "", 0, err
+	}
+
+	byRole := make(map[string]*strings.Builder)
+	for _, e := range entries {
+		b, ok := byRole[e.Role]
+		if !ok {
+			b = &strings.Builder{}
+			byRole[e.Role] = b
+		}
+		b.WriteString(formatMemoryChunk(e.Section, e.Timestamp, e.Content))
+	}
+
+	dir := ArchivedSessionDir(name)
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return "", 0, err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return "", 0, err
+	}
+	for role, b := range byRole {
+		if err := os.WriteFile(filepath.Join(tmp, role+".md"), []byte(b.String()), 0644); err != nil {
+			os.RemoveAll(tmp)
+			return "", 0, err
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", 0, err
+	}
+	return dir, len(entries), nil
+}
+
+// ListArchivedSessions returns the names of sessions archived in the global
+// memory dir, sorted.
+func ListArchivedSessions() ([]string, error) {
+	dirEntries, err := os.ReadDir(GlobalMemoryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, de := range dirEntries {
+		if de.IsDir() && !strings.HasSuffix(de.Name(), ".tmp") {
+			names = append(names, de.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SearchMemoryAllSessions ranks the current project's memory together with
+// every archived session's memory. All entries share one BM25 corpus so
+// scores are comparable across origins; each result's Entry.Session names
+// the archive it came from.
+func SearchMemoryAllSessions(opts SearchOptions) ([]SearchResult, error) {
+	queryTerms, phrases := parseQuery(opts.Query)
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+
+	entries, err := searchableMemoryEntries(opts)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := ListArchivedSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sessions {
+		archived, err := memoryFileEntriesIn(ArchivedSessionDir(name))
+		if err != nil {
+			continue
+		}
+		for i := range archived {
+			archived[i].Session = name
+		}
+		entries = append(entries, archived...)
+	}
+
+	results := searchEntriesBM25(entries, opts, queryTerms, phrases)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+// MemoryOrigin labels where an entry came from: "<role>" for the current
+// project, "<role> scratch" for its scratchpad, "<session>/<role>" for an
+// archived session.
+func MemoryOrigin(e MemoryEntry) string {
+	switch {
+	case e.Scratch:
+		return e.Role + " scratch"
+	case e.Session != "":
+		return e.Session + "/" + e.Role
+	}
+	return e.Role
+}
+
+// FormatSearchResultsGrouped formats results under one heading per origin,
+// ordered by each origin's best score. Results keep their rank order within
+// a group.
+func FormatSearchResultsGrouped(results []SearchResult) string {
+	var order []string
+	groups := make(map[string][]SearchResult)
+	for _, r := range results {
+		origin := MemoryOrigin(r.Entry)
+		if _, ok := groups[origin]; !ok {
+			order = append(order, origin)
+		}
+		groups[origin] = append(groups[origin], r)
+	}
+
+	var b strings.Builder
+	for i, origin := range order {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "=== %s (%d) ===\n", origin, len(groups[origin]))
+		b.WriteString(FormatSearchResults(groups[origin]))
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveSessionMemory(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	t.Setenv("MUXCODE_CONFIG_DIR", t.TempDir())
+
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if err := AppendMemory("Deploy Notes", "run cdk diff first", "shared"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+
+	dir, n, err := ArchiveSessionMemory("acme-api")
+	if err != nil {
+		t.Fatalf("ArchiveSessionMemory: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries archived, got %d", n)
+	}
+	if dir != filepath.Join(GlobalMemoryDir(), "acme-api") {
+		t.Errorf("unexpected archive dir %q", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "build.md"))
+	if err != nil || !strings.Contains(string(data), "use pnpm") {
+		t.Errorf("expected build.md archived, got %q (%v)", data, err)
+	}
+
+	sessions, err := ListArchivedSessions()
+	if err != nil || len(sessions) != 1 || sessions[0] != "acme-api" {
+		t.Errorf("expected [acme-api], got %v (%v)", sessions, err)
+	}
+
+	// Re-archiving replaces the previous copy
+	if err := os.Remove(MemoryPath("shared")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, n, err = ArchiveSessionMemory("acme-api"); err != nil || n != 1 {
+		t.Fatalf("re-archive: n=%d err=%v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shared.md")); !os.IsNotExist(err) {
+		t.Error("expected shared.md dropped from the replaced archive")
+	}
+}
+
+func TestArchiveSessionMemory_InvalidName(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	t.Setenv("MUXCODE_CONFIG_DIR", t.TempDir())
+
+	for _, name := range []string{"", ".", "..", "a/b"} {
+		if _, _, err := ArchiveSessionMemory(name); err == nil {
+			t.Errorf("expected error for name %q", name)
+		}
+	}
+}
+
+func TestSearchMemoryAllSessions(t *testing.T) {
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	t.Setenv("MUXCODE_CONFIG_DIR", t.TempDir())
+
+	old := filepath.Join(GlobalMemoryDir(), "old-project")
+	if err := os.MkdirAll(old, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chunk := formatMemoryChunk("Deploy Gotcha", "2025-06-01 09:00", "cdk deploy needs the bootstrap stack")
+	if err := os.WriteFile(filepath.Join(old, "deploy.md"), []byte(chunk), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendMemory("Deploy Notes", "run cdk diff before deploy", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+
+	// Without --all-sessions only the current project is searched
+	results, err := SearchMemoryWithOptions(SearchOptions{Query: "cdk", Mode: SearchModeBM25})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 current result, got %d", len(results))
+	}
+
+	results, err = SearchMemoryWithOptions(SearchOptions{Query: "cdk", Mode: SearchModeBM25, AllSessions: true})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results across sessions, got %d", len(results))
+	}
+	origins := map[string]bool{}
+	for _, r := range results {
+		origins[MemoryOrigin(r.Entry)] = true
+	}
+	if !origins["build"] || !origins["old-project/deploy"] {
+		t.Errorf("expected build and old-project/deploy origins, got %v", origins)
+	}
+
+	out := FormatSearchResultsGrouped(results)
+	if !strings.Contains(out, "=== old-project/deploy (1) ===") || !strings.Contains(out, "=== build (1) ===") {
+		t.Errorf("expected grouped headings, got:\n%s", out)
+	}
+
+	// Role filter applies to archived sessions too
+	results, err = SearchMemoryWithOptions(SearchOptions{Query: "cdk", Mode: SearchModeBM25, AllSessions: true, RoleFilter: "deploy"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Session != "old-project" {
+		t.Errorf("expected only the archived deploy entry, got %+v", results)
+	}
+}
+
+func TestSearchMemoryAllSessions_RequiresBM25(t *testing.T) {
+	_, err := SearchMemoryWithOptions(SearchOptions{Query: "cdk", Mode: SearchModeSemantic, AllSessions: true})
+	if err == nil {
+		t.Error("expected error for semantic mode across sessions")
+	}
+}
@@ -5,19 +5,43 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// MessageSchemaVersion is the current message format version. Bump it when
+// the format changes and teach MigrateMessage to upgrade the previous one.
+const MessageSchemaVersion = 1
+
+// MessageTypes lists the valid message types.
+var MessageTypes = []string{"request", "response", "event"}
+
+// actionNamePattern matches valid action names, e.g. "compile", "agent-down".
+var actionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/-]{0,63}$`)
+
 // Message represents a bus message between agents.
 type Message struct {
-	ID      string `json:"id"`
-	TS      int64  `json:"ts"`
-	From    string `json:"from"`
-	To      string `json:"to"`
-	Type    string `json:"type"`
-	Action  string `json:"action"`
-	Payload string `json:"payload"`
-	ReplyTo string `json:"reply_to"`
+	// SchemaVersion is the format version the message was written with;
+	// 0 for messages written before versioning (see MigrateMessage)
+	SchemaVersion int    `json:"schema_version,omitempty"`
+	ID            string `json:"id"`
+	TS            int64  `json:"ts"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Type          string `json:"type"`
+	Action        string `json:"action"`
+	Payload       string `json:"payload"`
+	ReplyTo       string `json:"reply_to"`
+	// Trace context (see trace.go), stamped on send
+	TraceID    string `json:"trace_id,omitempty"`
+	SpanID     string `json:"span_id,omitempty"`
+	ParentSpan string `json:"parent_span,omitempty"`
+	// Attachments reference blobs in the attachment store (see attachment.go)
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// CallbackURL receives the request's result (see callback.go)
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 // NewMsgID generates a unique message ID: {unix_ts}-{from}-{4hex}.
@@ -32,15 +56,79 @@ func NewMsgID(from string) string {
 // NewMessage creates a new Message with auto-generated ID and timestamp.
 func NewMessage(from, to, msgType, action, payload, replyTo string) Message {
 	return Message{
-		ID:      NewMsgID(from),
-		TS:      time.Now().Unix(),
-		From:    from,
-		To:      to,
-		Type:    msgType,
-		Action:  action,
-		Payload: payload,
-		ReplyTo: replyTo,
+		SchemaVersion: MessageSchemaVersion,
+		ID:            NewMsgID(from),
+		TS:            time.Now().Unix(),
+		From:          from,
+		To:            to,
+		Type:          msgType,
+		Action:        action,
+		Payload:       payload,
+		ReplyTo:       replyTo,
+	}
+}
+
+// ValidateMessage checks that a message about to be sent has the required
+// fields, a known type and a well-formed action name, and is not from a
+// newer schema than this binary understands.
+func ValidateMessage(m Message) error {
+	if m.SchemaVersion > MessageSchemaVersion {
+		return fmt.Errorf("schema version %d is newer than supported version %d", m.SchemaVersion, MessageSchemaVersion)
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"id", m.ID}, {"from", m.From}, {"to", m.To}, {"type", m.Type}, {"action", m.Action},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required field(s): %s", strings.Join(missing, ", "))
+	}
+	known := false
+	for _, t := range MessageTypes {
+		if m.Type == t {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown type %q (want %s)", m.Type, strings.Join(MessageTypes, ", "))
+	}
+	if !actionNamePattern.MatchString(m.Action) {
+		return fmt.Errorf("invalid action name %q: use letters, digits, '-', '_', '.', ':' or '/' (max 64)", m.Action)
 	}
+	return nil
+}
+
+// MigrateMessage upgrades a message read from an inbox or log to the
+// current schema. Unversioned (version 0) messages get their timestamp and
+// sender recovered from the ID ({ts}-{from}-{hex}) when missing, and a
+// missing type defaults to "response" for replies and "event" otherwise.
+// Messages from a newer schema are returned unchanged.
+func MigrateMessage(m Message) Message {
+	if m.SchemaVersion >= MessageSchemaVersion {
+		return m
+	}
+	if parts := strings.SplitN(m.ID, "-", 2); len(parts) == 2 {
+		if m.TS == 0 {
+			m.TS, _ = strconv.ParseInt(parts[0], 10, 64)
+		}
+		if m.From == "" {
+			if i := strings.LastIndex(parts[1], "-"); i > 0 {
+				m.From = parts[1][:i]
+			}
+		}
+	}
+	if m.Type == "" {
+		m.Type = "event"
+		if m.ReplyTo != "" {
+			m.Type = "response"
+		}
+	}
+	m.SchemaVersion = MessageSchemaVersion
+	return m
 }
 
 // EncodeMessage serializes a Message to compact JSON bytes.
@@ -48,13 +136,28 @@ func EncodeMessage(m Message) ([]byte, error) {
 	return json.Marshal(m)
 }
 
-// DecodeMessage deserializes a JSON line into a Message.
+// DecodeMessage deserializes a JSON line into a Message. Encrypted lines
+// (see SealLine) are decrypted first.
 func DecodeMessage(line []byte) (Message, error) {
 	var m Message
-	err := json.Unmarshal(line, &m)
+	line, err := UnsealLine(line)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(line, &m)
 	return m, err
 }
 
+// encodeLine serializes a Message as one line of an inbox, log, or archive
+// file, encrypted when encryption is enabled.
+func encodeLine(m Message) ([]byte, error) {
+	data, err := EncodeMessage(m)
+	if err != nil {
+		return nil, err
+	}
+	return SealLine(append(data, '\n'))
+}
+
 // FormatMessage returns a human-readable representation of a Message.
 func FormatMessage(m Message) string {
 	t := time.Unix(m.TS, 0)
@@ -64,6 +167,9 @@ func FormatMessage(m Message) string {
 	if m.ReplyTo != "" {
 		s += fmt.Sprintf("Reply to: %s\n", m.ReplyTo)
 	}
+	for _, a := range m.Attachments {
+		s += fmt.Sprintf("Attachment: %s (%d bytes, sha256:%s)\n", a.Name, a.Size, a.ShortSHA())
+	}
 	s += fmt.Sprintf("To reply: muxcode-agent-bus send %s <action> \"<message>\" --type response --reply-to %s\n", m.From, m.ID)
 	return s
 }
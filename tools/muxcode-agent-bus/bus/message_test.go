@@ -1,6 +1,7 @@
 package bus
 
 import (
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -28,7 +29,7 @@ func TestEncodeDecodeRoundTrip(t *testing.T) {
 		t.Fatalf("DecodeMessage: %v", err)
 	}
 
-	if got != orig {
+	if !reflect.DeepEqual(got, orig) {
 		t.Errorf("round-trip mismatch:\n  got  %+v\n  want %+v", got, orig)
 	}
 }
@@ -134,3 +135,60 @@ func TestNewMsgID_Unique(t *testing.T) {
 		ids[id] = true
 	}
 }
+
+func TestValidateMessage(t *testing.T) {
+	ok := NewMessage("edit", "build", "request", "compile", "go", "")
+	if ok.SchemaVersion != MessageSchemaVersion {
+		t.Errorf("SchemaVersion = %d", ok.SchemaVersion)
+	}
+	if err := ValidateMessage(ok); err != nil {
+		t.Errorf("valid message rejected: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		edit func(*Message)
+		want string
+	}{
+		{"missing fields", func(m *Message) { m.From, m.Action = "", "" }, "missing required field(s): from, action"},
+		{"unknown type", func(m *Message) { m.Type = "command" }, `unknown type "command"`},
+		{"bad action", func(m *Message) { m.Action = "run tests" }, `invalid action name "run tests"`},
+		{"newer schema", func(m *Message) { m.SchemaVersion = MessageSchemaVersion + 1 }, "newer than supported"},
+	}
+	for _, c := range cases {
+		m := ok
+		c.edit(&m)
+		if err := ValidateMessage(m); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestSend_RejectsInvalidMessage(t *testing.T) {
+	session := testSession(t)
+	if err := Send(session, NewMessage("edit", "build", "request", "", "go", "")); err == nil {
+		t.Fatal("expected error for empty action")
+	}
+	if InboxCount(session, "build") != 0 {
+		t.Error("invalid message was delivered")
+	}
+}
+
+func TestMigrateMessage_Unversioned(t *testing.T) {
+	m, err := DecodeMessage([]byte(`{"id":"1700000000-review-abcd1234","ts":0,"from":"","to":"edit","type":"","action":"result","payload":"lgtm","reply_to":"1-edit-x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := MigrateMessage(m)
+	if got.SchemaVersion != MessageSchemaVersion || got.TS != 1700000000 || got.From != "review" || got.Type != "response" {
+		t.Errorf("migrated = %+v", got)
+	}
+	if err := ValidateMessage(got); err != nil {
+		t.Errorf("migrated message invalid: %v", err)
+	}
+
+	newer := Message{SchemaVersion: MessageSchemaVersion + 1, ID: "x"}
+	if !reflect.DeepEqual(MigrateMessage(newer), newer) {
+		t.Error("newer messages must be left unchanged")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -69,12 +70,7 @@ func lockNotify(session, role string) func() {
 // This prevents duplicate tmux send-keys when Notify is called from multiple
 // sources (cmd/send.go, watcher, subscriptions) for the same unread messages.
 func alreadyNotified(session, role string) bool {
-	inboxPath := InboxPath(session, role)
-	info, err := os.Stat(inboxPath)
-	if err != nil {
-		return false
-	}
-	currentSize := info.Size()
+	currentSize := InboxSize(session, role)
 	if currentSize == 0 {
 		return true // nothing to notify about
 	}
@@ -104,33 +100,69 @@ func alreadyNotified(session, role string) bool {
 
 // markNotified records the current inbox size as the last notified size.
 func markNotified(session, role string) {
-	inboxPath := InboxPath(session, role)
-	info, err := os.Stat(inboxPath)
-	if err != nil {
+	size := InboxSize(session, role)
+	if size == 0 {
 		return
 	}
-	_ = os.WriteFile(notifiedSizePath(session, role), []byte(strconv.FormatInt(info.Size(), 10)), 0644)
+	_ = os.WriteFile(notifiedSizePath(session, role), []byte(strconv.FormatInt(size, 10)), 0644)
 }
 
-// Notify sends a tmux notification to an agent's pane.
-// Uses consolidated PaneTarget from config.go for pane targeting.
-// Peeks at the inbox to include a summary of the latest message.
+// Notify notifies an agent of new inbox messages according to its notify
+// policy (see RoleNotifyPolicy): send-keys into the pane, a passive status
+// bar message, nothing, or a debounced/batched notification delivered later
+// by the watcher. Edit defaults to passive — send-keys would inject text
+// into the Claude Code prompt, conflicting with user input and causing
+// conversation loops. Matching desktop rules also raise a desktop
+// notification (see NotifyDesktop).
 // Skips notification for panes running a local LLM harness (they poll directly).
 // Deduplicates: skips if the inbox hasn't changed since the last notification.
-// Edit always uses passive display-message (status bar) — never send-keys.
+// A role with a batch window that was notified recently has the notification
+// held and delivered by the watcher as one summary when the window ends.
+// A failed-over role's replacement is notified in its place. Simulated
+// sessions have no panes and are never notified.
 func Notify(session, role string) error {
-	// Edit always uses passive display-message — send-keys would inject
-	// text into the Claude Code prompt, conflicting with user input and
-	// causing conversation loops.
-	if role == "edit" {
-		return notifyEdit(session)
+	if isSimulated(session) {
+		return nil
+	}
+	if to, ok := FailoverTarget(session, role); ok {
+		role = to
 	}
 
-	// Skip tmux send-keys for harness panes — the harness polls inbox directly
+	// Desktop notifiers reach the human, so they ignore the role's policy
+	NotifyDesktop(session, role)
+
+	// Skip tmux notifications for harness panes — the harness polls inbox directly
 	if IsHarnessActive(session, role) {
 		return nil
 	}
 
+	p := RoleNotifyPolicy(role)
+	if holdNotify(session, role, p) {
+		return nil
+	}
+	switch p.Mode {
+	case NotifyNever:
+		return nil
+	case NotifyPassive:
+		return notifyPassive(session, role)
+	case NotifyDebounce, NotifyBatch:
+		return deferNotify(session, role, p)
+	}
+	return notifySendKeys(session, role)
+}
+
+// deliverNotify sends a notification now, via the status bar when passive.
+func deliverNotify(session, role string, passive bool) error {
+	if passive {
+		return notifyPassive(session, role)
+	}
+	return notifySendKeys(session, role)
+}
+
+// notifySendKeys types a notification into the agent's pane.
+// Uses consolidated PaneTarget from config.go for pane targeting.
+// Peeks at the inbox to include a summary of the latest message.
+func notifySendKeys(session, role string) error {
 	// Acquire per-role lock to make the check+mark+send sequence atomic
 	// across concurrent callers (cmd/send.go and watcher checkInboxes).
 	// Graceful degradation: if locking fails, the cooldown in alreadyNotified
@@ -151,7 +183,7 @@ func Notify(session, role string) error {
 	// pane is gone.
 	markNotified(session, role)
 
-	pane := PaneTarget(session, role)
+	pane := PaneTarget(session, RoleWindow(role))
 
 	// Verify the pane exists before sending
 	check := exec.Command("tmux", "has-session", "-t", session)
@@ -184,7 +216,7 @@ func Notify(session, role string) error {
 	return nil
 }
 
-// notifyEdit sends a passive notification for the edit role.
+// notifyPassive sends a passive notification for a role (edit by default).
 // Always uses display-message (tmux status bar) — never send-keys.
 // Injecting text into the edit pane via send-keys causes problems:
 //   - Conflicts with user input if they're typing
@@ -193,35 +225,42 @@ func Notify(session, role string) error {
 //
 // Best-effort: errors are logged but not returned, since the message is
 // already in the inbox and will be seen on the next inbox read.
-func notifyEdit(session string) error {
-	unlock := lockNotify(session, "edit")
+func notifyPassive(session, role string) error {
+	unlock := lockNotify(session, role)
 	defer unlock()
 
-	if alreadyNotified(session, "edit") {
+	if alreadyNotified(session, role) {
 		return nil
 	}
 
-	markNotified(session, "edit")
+	markNotified(session, role)
 
 	// Passive: display-message shows in the tmux status bar.
 	// -d 5000 keeps it visible for 5 seconds (default is often too brief).
 	// This does NOT inject text into the pane — safe at all times.
-	msg := notifyText(session, "edit")
+	msg := notifyText(session, role)
+	if role != "edit" {
+		msg = role + ": " + msg
+	}
 	cmd := exec.Command("tmux", "display-message", "-t", session, "-d", "5000",
 		fmt.Sprintf("\U0001f4ec %s", msg))
 	if err := cmd.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "  [notify] display-message for edit failed: %v\n", err)
+		fmt.Fprintf(os.Stderr, "  [notify] display-message for %s failed: %v\n", role, err)
 	}
 	return nil
 }
 
 // notifyText builds the notification string, including a summary of the
-// most recent unread message when available.
+// most recent unread message when available. Several unread messages are
+// summarized by sender instead ("5 new messages: 3 build, 2 test").
 func notifyText(session, role string) string {
 	msgs, err := Peek(session, role)
 	if err != nil || len(msgs) == 0 {
 		return "You have new messages. Run: muxcode-agent-bus inbox"
 	}
+	if len(msgs) > 1 {
+		return fmt.Sprintf("%d new messages: %s \u2192 Run: muxcode-agent-bus inbox", len(msgs), senderCounts(msgs))
+	}
 
 	last := msgs[len(msgs)-1]
 	payload := last.Payload
@@ -231,3 +270,27 @@ func notifyText(session, role string) string {
 
 	return fmt.Sprintf("[%s \u2192 %s] %s \u2192 Run: muxcode-agent-bus inbox", last.From, last.Action, payload)
 }
+
+// senderCounts summarizes messages by sender, most frequent first:
+// "3 build, 2 test".
+func senderCounts(msgs []Message) string {
+	counts := make(map[string]int)
+	var senders []string
+	for _, m := range msgs {
+		if counts[m.From] == 0 {
+			senders = append(senders, m.From)
+		}
+		counts[m.From]++
+	}
+	sort.SliceStable(senders, func(i, j int) bool {
+		if counts[senders[i]] != counts[senders[j]] {
+			return counts[senders[i]] > counts[senders[j]]
+		}
+		return senders[i] < senders[j]
+	})
+	parts := make([]string, len(senders))
+	for i, s := range senders {
+		parts[i] = fmt.Sprintf("%d %s", counts[s], s)
+	}
+	return strings.Join(parts, ", ")
+}
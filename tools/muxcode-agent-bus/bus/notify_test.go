@@ -122,21 +122,21 @@ func TestNotifyEdit_Dedup(t *testing.T) {
 	os.WriteFile(InboxPath(session, "edit"), []byte(`{"from":"build"}`+"\n"), 0644)
 
 	// First call should proceed (mark notified)
-	err := notifyEdit(session)
+	err := notifyPassive(session, "edit")
 	if err != nil {
-		t.Errorf("first notifyEdit should return nil, got %v", err)
+		t.Errorf("first notifyPassive should return nil, got %v", err)
 	}
 
 	// Second call with same inbox size should be deduplicated
-	err = notifyEdit(session)
+	err = notifyPassive(session, "edit")
 	if err != nil {
-		t.Errorf("second notifyEdit should return nil (deduped), got %v", err)
+		t.Errorf("second notifyPassive should return nil (deduped), got %v", err)
 	}
 
 	// Verify marker was written
 	markerData, err := os.ReadFile(notifiedSizePath(session, "edit"))
 	if err != nil {
-		t.Fatalf("notifyEdit should create marker file: %v", err)
+		t.Fatalf("notifyPassive should create marker file: %v", err)
 	}
 	if string(markerData) == "" {
 		t.Error("marker file should contain inbox size")
@@ -156,16 +156,16 @@ func TestNotifyEdit_AlwaysUsesDisplayMessage(t *testing.T) {
 	data, _ := EncodeMessage(msg)
 	os.WriteFile(InboxPath(session, "edit"), append(data, '\n'), 0644)
 
-	// notifyEdit uses display-message (best-effort, returns nil on
+	// notifyPassive uses display-message (best-effort, returns nil on
 	// non-existent tmux session since errors are logged but not returned)
-	err := notifyEdit(session)
+	err := notifyPassive(session, "edit")
 	if err != nil {
-		t.Errorf("notifyEdit should return nil, got %v", err)
+		t.Errorf("notifyPassive should return nil, got %v", err)
 	}
 
 	// Verify marker was written (proves we got past dedup check)
 	if _, err := os.Stat(notifiedSizePath(session, "edit")); os.IsNotExist(err) {
-		t.Error("notifyEdit should have written notified marker")
+		t.Error("notifyPassive should have written notified marker")
 	}
 }
 
@@ -0,0 +1,202 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notify modes. send-keys types into the agent's pane; passive flashes the
+// tmux status bar; never skips notification (the agent polls its inbox);
+// debounce waits until the inbox has been quiet for the window; batch sends
+// at most one notification per window.
+const (
+	NotifySendKeys = "send-keys"
+	NotifyPassive  = "passive"
+	NotifyNever    = "never"
+	NotifyDebounce = "debounce"
+	NotifyBatch    = "batch"
+)
+
+// defaultNotifyWindow is the debounce/batch window when a policy sets none.
+const defaultNotifyWindow = 30
+
+// NotifyConfig is the "notify" section of muxcode.json. BatchWindow is the
+// default batch window for roles whose policy sets none.
+type NotifyConfig struct {
+	Roles       map[string]NotifyPolicy `json:"roles,omitempty"`
+	BatchWindow int64                   `json:"batch_window_s,omitempty"`
+	Desktop     []DesktopRule           `json:"desktop,omitempty"` // desktop notifiers (see desktop.go)
+}
+
+// NotifyPolicy controls how Notify reaches one role. Debounced and batched
+// notifications are delivered by the watcher (FlushNotifications); Passive
+// delivers them via the status bar instead of send-keys. BatchWindow applies
+// backpressure to send-keys and passive roles: once notified, the role gets
+// at most one more notification per window, summarizing the burst.
+type NotifyPolicy struct {
+	Mode        string `json:"mode"`
+	Window      int64  `json:"window_s,omitempty"`       // debounce/batch window in seconds (default 30)
+	Passive     bool   `json:"passive,omitempty"`        // deliver debounced/batched notifications via the status bar
+	BatchWindow int64  `json:"batch_window_s,omitempty"` // send-keys/passive burst window in seconds (0 = off)
+}
+
+// ValidNotifyMode reports whether mode is a known notify mode.
+func ValidNotifyMode(mode string) bool {
+	switch mode {
+	case NotifySendKeys, NotifyPassive, NotifyNever, NotifyDebounce, NotifyBatch:
+		return true
+	}
+	return false
+}
+
+// Validate checks every role's mode and window and the desktop rules.
+func (c NotifyConfig) Validate() error {
+	for role, p := range c.Roles {
+		if !ValidNotifyMode(p.Mode) {
+			return fmt.Errorf("notify role %s: unknown mode %q", role, p.Mode)
+		}
+		if p.Window < 0 {
+			return fmt.Errorf("notify role %s: window_s must be positive", role)
+		}
+		if p.BatchWindow < 0 {
+			return fmt.Errorf("notify role %s: batch_window_s must be positive", role)
+		}
+	}
+	if c.BatchWindow < 0 {
+		return fmt.Errorf("notify: batch_window_s must be positive")
+	}
+	return ValidateDesktopRules(c.Desktop)
+}
+
+// RoleNotifyPolicy returns the notify policy for a role. Roles without a
+// configured policy use send-keys. A policy without a batch window inherits
+// the section default.
+func RoleNotifyPolicy(role string) NotifyPolicy {
+	cfg := Config().Notify
+	p, ok := cfg.Roles[role]
+	if !ok || !ValidNotifyMode(p.Mode) {
+		p = NotifyPolicy{Mode: NotifySendKeys}
+	}
+	if p.BatchWindow == 0 {
+		p.BatchWindow = cfg.BatchWindow
+	}
+	return p
+}
+
+// batched reports whether the policy holds send-keys/passive notifications
+// during a burst.
+func (p NotifyPolicy) batched() bool {
+	return p.BatchWindow > 0 && (p.Mode == NotifySendKeys || p.Mode == NotifyPassive)
+}
+
+// batchWindow returns the burst window as a duration.
+func (p NotifyPolicy) batchWindow() time.Duration {
+	return time.Duration(p.BatchWindow) * time.Second
+}
+
+// window returns the policy window, applying the default.
+func (p NotifyPolicy) window() time.Duration {
+	if p.Window > 0 {
+		return time.Duration(p.Window) * time.Second
+	}
+	return defaultNotifyWindow * time.Second
+}
+
+// notifyPendingPath is the marker for a deferred notification. It holds the
+// time the first deferred notification arrived; its mtime is the latest.
+func notifyPendingPath(session, role string) string {
+	return filepath.Join(BusDir(session), "notify-pending-"+role)
+}
+
+// deferNotify records a debounced or batched notification. A batch role
+// that has not been notified within the window is notified right away.
+func deferNotify(session, role string, p NotifyPolicy) error {
+	path := notifyPendingPath(session, role)
+	if p.Mode == NotifyBatch {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if info, err := os.Stat(notifiedSizePath(session, role)); err != nil || time.Since(info.ModTime()) >= p.window() {
+				return deliverNotify(session, role, p.Passive)
+			}
+		}
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		// Keep the first-arrival time; bump the mtime for debounce
+		now := time.Now()
+		_ = os.WriteFile(path, data, 0644)
+		return os.Chtimes(path, now, now)
+	}
+	return os.WriteFile(path, []byte(strconv.FormatInt(time.Now().Unix(), 10)), 0644)
+}
+
+// holdNotify reports whether a send-keys/passive notification should be held
+// for the burst: the role was notified less than a batch window ago. Held
+// notifications share the pending marker with debounce and batch.
+func holdNotify(session, role string, p NotifyPolicy) bool {
+	if !p.batched() {
+		return false
+	}
+	info, err := os.Stat(notifiedSizePath(session, role))
+	if err != nil || time.Since(info.ModTime()) >= p.batchWindow() {
+		return false
+	}
+	path := notifyPendingPath(session, role)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		_ = os.WriteFile(path, []byte(strconv.FormatInt(time.Now().Unix(), 10)), 0644)
+	}
+	return true
+}
+
+// notifyDue reports whether a deferred notification should go out now:
+// debounce once the latest arrival is a full window old, batch once the
+// first arrival is, and a held burst once the last notification is a full
+// batch window old.
+func notifyDue(session, role string, p NotifyPolicy, now time.Time) bool {
+	path := notifyPendingPath(session, role)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if p.batched() {
+		last, err := os.Stat(notifiedSizePath(session, role))
+		return err != nil || now.Sub(last.ModTime()) >= p.batchWindow()
+	}
+	if p.Mode == NotifyBatch {
+		data, _ := os.ReadFile(path)
+		first, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			first = info.ModTime().Unix()
+		}
+		return now.Sub(time.Unix(first, 0)) >= p.window()
+	}
+	return now.Sub(info.ModTime()) >= p.window()
+}
+
+// FlushNotifications delivers deferred and held notifications that are due.
+// The watcher calls it every poll. Returns the roles notified.
+func FlushNotifications(session string) []string {
+	var notified []string
+	now := time.Now()
+	for _, role := range KnownRoles {
+		p := RoleNotifyPolicy(role)
+		if p.Mode != NotifyDebounce && p.Mode != NotifyBatch && !p.batched() {
+			// Policy changed since the notification was deferred
+			_ = os.Remove(notifyPendingPath(session, role))
+			continue
+		}
+		if !notifyDue(session, role, p, now) {
+			continue
+		}
+		_ = os.Remove(notifyPendingPath(session, role))
+		if IsHarnessActive(session, role) {
+			continue
+		}
+		if err := deliverNotify(session, role, p.Passive || p.Mode == NotifyPassive); err == nil {
+			notified = append(notified, role)
+		}
+	}
+	return notified
+}
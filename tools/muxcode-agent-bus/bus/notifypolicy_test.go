@@ -0,0 +1,221 @@
+package bus
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRoleNotifyPolicy_Defaults(t *testing.T) {
+	SetConfig(DefaultConfig())
+	defer SetConfig(nil)
+
+	if p := RoleNotifyPolicy("edit"); p.Mode != NotifyPassive {
+		t.Errorf("edit mode = %q, want passive", p.Mode)
+	}
+	if p := RoleNotifyPolicy("build"); p.Mode != NotifySendKeys {
+		t.Errorf("build mode = %q, want send-keys", p.Mode)
+	}
+}
+
+func TestNotify_Never(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Notify.Roles["review"] = NotifyPolicy{Mode: NotifyNever}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if err := Send(session, NewMessage("edit", "review", "request", "review", "look", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Notify(session, "review"); err != nil {
+		t.Errorf("Notify: %v", err)
+	}
+	if _, err := os.Stat(notifiedSizePath(session, "review")); !os.IsNotExist(err) {
+		t.Error("never mode should not mark the role notified")
+	}
+}
+
+func TestNotify_DebounceFlush(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Notify.Roles["review"] = NotifyPolicy{Mode: NotifyDebounce, Window: 10, Passive: true}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if err := Send(session, NewMessage("edit", "review", "request", "review", "look", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Notify(session, "review"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	pending := notifyPendingPath(session, "review")
+	if _, err := os.Stat(pending); err != nil {
+		t.Fatalf("debounce should record a pending notification: %v", err)
+	}
+	if got := FlushNotifications(session); len(got) != 0 {
+		t.Errorf("flushed %v before the window elapsed", got)
+	}
+
+	old := time.Now().Add(-11 * time.Second)
+	_ = os.Chtimes(pending, old, old)
+	got := FlushNotifications(session)
+	if len(got) != 1 || got[0] != "review" {
+		t.Errorf("flushed %v, want [review]", got)
+	}
+	if _, err := os.Stat(pending); !os.IsNotExist(err) {
+		t.Error("flush should remove the pending marker")
+	}
+	if _, err := os.Stat(notifiedSizePath(session, "review")); err != nil {
+		t.Error("flush should deliver and mark the role notified")
+	}
+}
+
+func TestNotify_BatchFirstImmediate(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Notify.Roles["review"] = NotifyPolicy{Mode: NotifyBatch, Window: 60, Passive: true}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	send := func() {
+		if err := Send(session, NewMessage("edit", "review", "request", "review", "look", "")); err != nil {
+			t.Fatal(err)
+		}
+		if err := Notify(session, "review"); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	// First notification in a quiet window goes out immediately
+	send()
+	if _, err := os.Stat(notifiedSizePath(session, "review")); err != nil {
+		t.Fatal("first batch notification should be delivered immediately")
+	}
+	if _, err := os.Stat(notifyPendingPath(session, "review")); !os.IsNotExist(err) {
+		t.Error("first batch notification should not be deferred")
+	}
+
+	// Later ones within the window are held for the batch
+	send()
+	send()
+	if _, err := os.Stat(notifyPendingPath(session, "review")); err != nil {
+		t.Error("second batch notification should be deferred")
+	}
+	if got := FlushNotifications(session); len(got) != 0 {
+		t.Errorf("flushed %v before the batch window elapsed", got)
+	}
+}
+
+func TestNotify_BatchWindowHoldsBurst(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.Notify.Roles["review"] = NotifyPolicy{Mode: NotifyPassive, BatchWindow: 10}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	send := func(from string) {
+		if err := Send(session, NewMessage(from, "review", "request", "review", "look", "")); err != nil {
+			t.Fatal(err)
+		}
+		if err := Notify(session, "review"); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	// The first message goes out right away
+	send("build")
+	marker := notifiedSizePath(session, "review")
+	first, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatal("first notification should be delivered immediately")
+	}
+	pending := notifyPendingPath(session, "review")
+	if _, err := os.Stat(pending); !os.IsNotExist(err) {
+		t.Error("first notification should not be held")
+	}
+
+	// The rest of the burst is held
+	send("build")
+	send("test")
+	if _, err := os.Stat(pending); err != nil {
+		t.Fatal("burst notifications should be held")
+	}
+	if got, _ := os.ReadFile(marker); string(got) != string(first) {
+		t.Error("held notifications should not be delivered")
+	}
+	if got := FlushNotifications(session); len(got) != 0 {
+		t.Errorf("flushed %v before the batch window elapsed", got)
+	}
+
+	// One summary once the window since the last notification ends
+	old := time.Now().Add(-11 * time.Second)
+	_ = os.Chtimes(marker, old, old)
+	got := FlushNotifications(session)
+	if len(got) != 1 || got[0] != "review" {
+		t.Errorf("flushed %v, want [review]", got)
+	}
+	if _, err := os.Stat(pending); !os.IsNotExist(err) {
+		t.Error("flush should remove the pending marker")
+	}
+	if got, _ := os.ReadFile(marker); string(got) == string(first) {
+		t.Error("flush should deliver and mark the role notified")
+	}
+}
+
+func TestRoleNotifyPolicy_DefaultBatchWindow(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notify.BatchWindow = 15
+	cfg.Notify.Roles["review"] = NotifyPolicy{Mode: NotifySendKeys, BatchWindow: 5}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if p := RoleNotifyPolicy("build"); p.BatchWindow != 15 || !p.batched() {
+		t.Errorf("build batch window = %d, want 15", p.BatchWindow)
+	}
+	if p := RoleNotifyPolicy("review"); p.BatchWindow != 5 {
+		t.Errorf("review batch window = %d, want 5", p.BatchWindow)
+	}
+	if p := (NotifyPolicy{Mode: NotifyBatch, BatchWindow: 5}); p.batched() {
+		t.Error("batch window should only apply to send-keys and passive roles")
+	}
+}
+
+func TestNotifyText_Summary(t *testing.T) {
+	session := testSession(t)
+	for _, from := range []string{"test", "build", "build", "test", "build"} {
+		if err := Send(session, NewMessage(from, "review", "event", "done", "ok", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "5 new messages: 3 build, 2 test \u2192 Run: muxcode-agent-bus inbox"
+	if got := notifyText(session, "review"); got != want {
+		t.Errorf("notifyText = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyText_Single(t *testing.T) {
+	session := testSession(t)
+	if err := Send(session, NewMessage("build", "review", "event", "done", "ok", "")); err != nil {
+		t.Fatal(err)
+	}
+	want := "[build \u2192 done] ok \u2192 Run: muxcode-agent-bus inbox"
+	if got := notifyText(session, "review"); got != want {
+		t.Errorf("notifyText = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyConfig_Validate(t *testing.T) {
+	ok := NotifyConfig{Roles: map[string]NotifyPolicy{"edit": {Mode: NotifyPassive}, "test": {Mode: NotifyBatch, Window: 30}}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	bad := NotifyConfig{Roles: map[string]NotifyPolicy{"edit": {Mode: "loud"}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	neg := NotifyConfig{Roles: map[string]NotifyPolicy{"edit": {Mode: NotifyPassive, BatchWindow: -1}}}
+	if err := neg.Validate(); err == nil {
+		t.Error("expected error for negative batch window")
+	}
+}
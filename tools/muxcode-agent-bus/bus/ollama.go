@@ -20,11 +20,12 @@ var ErrModelNotFound = errors.New("model not found")
 
 // OllamaConfig holds configuration for connecting to Ollama's API.
 type OllamaConfig struct {
-	BaseURL     string  // default "http://localhost:11434"
-	Model       string  // default "qwen2.5:7b" (must support tool calling)
-	Temperature float64 // default 0.1
-	Timeout     int     // seconds, default 120
-	MaxTokens   int     // default 4096
+	BaseURL     string   // default "http://localhost:11434"
+	BaseURLs    []string // nodes tried round-robin with failover; empty uses BaseURL
+	Model       string   // default "qwen2.5:7b" (must support tool calling)
+	Temperature float64  // default 0.1
+	Timeout     int      // seconds, default 120
+	MaxTokens   int      // default 4096
 }
 
 // DefaultOllamaConfig returns the default Ollama configuration.
@@ -48,12 +49,16 @@ func DefaultOllamaConfig() OllamaConfig {
 
 // RoleModel returns the Ollama model for a specific role, checking
 // per-role env vars before falling back to the default config model.
-// Resolution order: MUXCODE_{ROLE}_MODEL → MUXCODE_OLLAMA_MODEL → default.
+// Resolution order: MUXCODE_{ROLE}_MODEL → declared role model →
+// MUXCODE_OLLAMA_MODEL → default.
 func RoleModel(role string) string {
 	envVar := roleModelEnvVar(role)
 	if v := os.Getenv(envVar); v != "" {
 		return v
 	}
+	if d, ok := ConfiguredRole(role); ok && d.Model != "" {
+		return d.Model
+	}
 	return DefaultOllamaConfig().Model
 }
 
@@ -189,6 +194,7 @@ type OllamaError struct {
 type OllamaClient struct {
 	Config OllamaConfig
 	HTTP   *http.Client
+	pool   nodePool
 }
 
 // NewOllamaClient creates a new Ollama client with the given config.
@@ -218,7 +224,6 @@ func (c *OllamaClient) ChatComplete(ctx context.Context, messages []ChatMessage,
 		return nil, fmt.Errorf("encoding request: %w", err)
 	}
 
-	url := c.Config.BaseURL + "/v1/chat/completions"
 	var lastErr error
 	backoff := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
 
@@ -231,53 +236,94 @@ func (c *OllamaClient) ChatComplete(ctx context.Context, messages []ChatMessage,
 			}
 		}
 
-		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
-
-		resp, err := c.HTTP.Do(httpReq)
-		if err != nil {
+		// Fail over across nodes before backing off
+		for _, node := range c.nodeOrder() {
+			resp, retry, err := c.chatNode(ctx, node, body)
+			if err == nil {
+				c.markNode(node, true)
+				return resp, nil
+			}
+			if !retry {
+				return nil, err
+			}
+			c.markNode(node, false)
 			lastErr = err
-			continue // retry on connection errors
 		}
+	}
 
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("reading response: %w", err)
-			continue
-		}
+	return nil, fmt.Errorf("all retries exhausted: %w", lastErr)
+}
 
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
-			// Don't retry on 4xx client errors
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				return nil, lastErr
-			}
-			continue
-		}
+// chatNode sends one chat completion request to a node. retry reports
+// whether the error is worth trying again (connection and 5xx errors).
+func (c *OllamaClient) chatNode(ctx context.Context, node string, body []byte) (*ChatResponse, bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, node+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-		var chatResp ChatResponse
-		if err := json.Unmarshal(respBody, &chatResp); err != nil {
-			return nil, fmt.Errorf("decoding response: %w", err)
-		}
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil {
+		return nil, true, err // retry on connection errors
+	}
 
-		if chatResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s", chatResp.Error.Message)
-		}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, true, fmt.Errorf("reading response: %w", err)
+	}
 
-		return &chatResp, nil
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		// Don't retry on 4xx client errors
+		return nil, resp.StatusCode < 400 || resp.StatusCode >= 500, err
 	}
 
-	return nil, fmt.Errorf("all retries exhausted: %w", lastErr)
+	var chatResp ChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return nil, false, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if chatResp.Error != nil {
+		return nil, false, fmt.Errorf("API error: %s", chatResp.Error.Message)
+	}
+
+	return &chatResp, false, nil
 }
 
 // CheckHealth verifies that Ollama is reachable and the configured model is available.
 // Uses GET /api/tags to list models, then checks if the configured model is present.
+// With several nodes, one healthy node is enough; a missing model is
+// reported ahead of unreachable nodes so the caller can pull it.
 func (c *OllamaClient) CheckHealth(ctx context.Context) error {
-	url := c.Config.BaseURL + "/api/tags"
+	nodes := c.nodes()
+	var errs []error
+	var modelErr error
+	for _, node := range nodes {
+		err := c.checkNodeHealth(ctx, node)
+		if err == nil {
+			c.markNode(node, true)
+			return nil
+		}
+		c.markNode(node, false)
+		if modelErr == nil && errors.Is(err, ErrModelNotFound) {
+			modelErr = err
+		}
+		errs = append(errs, err)
+	}
+	if modelErr != nil {
+		return modelErr
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("no healthy Ollama node (%d tried): %w", len(nodes), errors.Join(errs...))
+}
+
+// checkNodeHealth runs the CheckHealth probe against one node.
+func (c *OllamaClient) checkNodeHealth(ctx context.Context, node string) error {
+	url := node + "/api/tags"
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -286,7 +332,7 @@ func (c *OllamaClient) CheckHealth(ctx context.Context) error {
 
 	resp, err := c.HTTP.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("connecting to Ollama at %s: %w", c.Config.BaseURL, err)
+		return fmt.Errorf("connecting to Ollama at %s: %w", node, err)
 	}
 	defer resp.Body.Close()
 
@@ -0,0 +1,158 @@
+package bus
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ollamaNodeCooldown is how long a node that failed a request is tried
+// only after the healthy ones.
+const ollamaNodeCooldown = 30 * time.Second
+
+// OllamaSettings is the "ollama" section of muxcode.json.
+type OllamaSettings struct {
+	// Nodes maps a model name, or "*" for any model, to the Ollama base
+	// URLs serving it. Requests rotate across the nodes and fail over when
+	// one is unreachable.
+	Nodes map[string][]string `json:"nodes,omitempty"`
+	// VRAMLimitMB caps the VRAM used by a node's loaded models; more
+	// raises a resource-pressure event. 0 disables the check.
+	VRAMLimitMB int `json:"vram_limit_mb,omitempty"`
+	// VRAMPressurePct raises a resource-pressure event when GPU memory use
+	// reaches this percentage (default 90; needs nvidia-smi).
+	VRAMPressurePct int `json:"vram_pressure_pct,omitempty"`
+}
+
+// Validate checks that every node is an http(s) URL and the VRAM
+// thresholds are in range.
+func (s OllamaSettings) Validate() error {
+	if s.VRAMLimitMB < 0 {
+		return fmt.Errorf("vram_limit_mb must not be negative")
+	}
+	if s.VRAMPressurePct < 0 || s.VRAMPressurePct > 100 {
+		return fmt.Errorf("vram_pressure_pct must be between 0 and 100")
+	}
+	for model, urls := range s.Nodes {
+		if len(urls) == 0 {
+			return fmt.Errorf("ollama nodes %q: at least one URL is required", model)
+		}
+		for _, u := range urls {
+			if err := validateNodeURL(u); err != nil {
+				return fmt.Errorf("ollama nodes %q: %v", model, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateNodeURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid node URL %q", raw)
+	}
+	return nil
+}
+
+// OllamaNodeURLs returns the base URLs serving a model. Resolution order:
+// ollama.nodes[model] → MUXCODE_OLLAMA_URLS (comma-separated) →
+// ollama.nodes["*"] → the single default base URL.
+func OllamaNodeURLs(model string) []string {
+	nodes := Config().Ollama.Nodes
+	if urls := nodes[model]; len(urls) > 0 {
+		return trimNodeURLs(urls)
+	}
+	if v := os.Getenv("MUXCODE_OLLAMA_URLS"); v != "" {
+		if urls := trimNodeURLs(strings.Split(v, ",")); len(urls) > 0 {
+			return urls
+		}
+	}
+	if urls := nodes["*"]; len(urls) > 0 {
+		return trimNodeURLs(urls)
+	}
+	return []string{DefaultOllamaConfig().BaseURL}
+}
+
+// trimNodeURLs drops blanks, trailing slashes, and duplicates.
+func trimNodeURLs(urls []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, u := range urls {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u != "" && !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// IsLocalOllamaURL reports whether a node runs on this machine, where the
+// watcher can restart it.
+func IsLocalOllamaURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// nodePool is an OllamaClient's round-robin position and the nodes that
+// recently failed. The zero value is ready to use.
+type nodePool struct {
+	mu        sync.Mutex
+	next      int
+	downUntil map[string]time.Time
+}
+
+// nodes returns the client's base URLs: Config.BaseURLs, or BaseURL alone.
+func (c *OllamaClient) nodes() []string {
+	if len(c.Config.BaseURLs) > 0 {
+		return c.Config.BaseURLs
+	}
+	return []string{c.Config.BaseURL}
+}
+
+// nodeOrder returns the nodes to try for one request: healthy nodes in
+// round-robin order, then nodes still cooling down after a failure as a
+// last resort.
+func (c *OllamaClient) nodeOrder() []string {
+	nodes := c.nodes()
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+	start := c.pool.next % len(nodes)
+	c.pool.next++
+
+	now := time.Now()
+	var healthy, down []string
+	for i := range nodes {
+		n := nodes[(start+i)%len(nodes)]
+		if now.Before(c.pool.downUntil[n]) {
+			down = append(down, n)
+		} else {
+			healthy = append(healthy, n)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// markNode records a request outcome for a node.
+func (c *OllamaClient) markNode(node string, ok bool) {
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+	if ok {
+		delete(c.pool.downUntil, node)
+		return
+	}
+	if c.pool.downUntil == nil {
+		c.pool.downUntil = map[string]time.Time{}
+	}
+	c.pool.downUntil[node] = time.Now().Add(ollamaNodeCooldown)
+}
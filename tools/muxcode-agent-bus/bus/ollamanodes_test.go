@@ -0,0 +1,175 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// nodeServer is a fake Ollama node answering chat completions with its
+// name and counting requests.
+func nodeServer(t *testing.T, name string, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte("unavailable"))
+			return
+		}
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models":[{"name":"test-model"}]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []ChatChoice{{
+			Message: ChatMessage{Role: "assistant", Content: name}, FinishReason: "stop",
+		}}})
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func chatContent(t *testing.T, c *OllamaClient) string {
+	t.Helper()
+	resp, err := c.ChatComplete(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("ChatComplete: %v", err)
+	}
+	return resp.Choices[0].Message.Content
+}
+
+func TestChatComplete_RoundRobin(t *testing.T) {
+	a, _ := nodeServer(t, "a", http.StatusOK)
+	b, _ := nodeServer(t, "b", http.StatusOK)
+	client := NewOllamaClient(OllamaConfig{BaseURLs: []string{a.URL, b.URL}, Model: "test-model", Timeout: 10})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, chatContent(t, client))
+	}
+	if want := []string{"a", "b", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("responders = %v, want %v", got, want)
+	}
+}
+
+func TestChatComplete_FailsOver(t *testing.T) {
+	down, downHits := nodeServer(t, "down", http.StatusServiceUnavailable)
+	up, _ := nodeServer(t, "up", http.StatusOK)
+	client := NewOllamaClient(OllamaConfig{BaseURLs: []string{down.URL, up.URL}, Model: "test-model", Timeout: 10})
+
+	for i := 0; i < 3; i++ {
+		if got := chatContent(t, client); got != "up" {
+			t.Fatalf("request %d answered by %q, want up", i, got)
+		}
+	}
+	// The failed node cools down instead of being tried first again
+	if n := atomic.LoadInt32(downHits); n != 1 {
+		t.Errorf("down node hits = %d, want 1", n)
+	}
+}
+
+func TestChatComplete_UnreachableNode(t *testing.T) {
+	dead, _ := nodeServer(t, "dead", http.StatusOK)
+	dead.Close()
+	up, _ := nodeServer(t, "up", http.StatusOK)
+	client := NewOllamaClient(OllamaConfig{BaseURLs: []string{dead.URL, up.URL}, Model: "test-model", Timeout: 10})
+
+	if got := chatContent(t, client); got != "up" {
+		t.Errorf("answered by %q, want up", got)
+	}
+}
+
+func TestCheckHealth_OneHealthyNode(t *testing.T) {
+	down, _ := nodeServer(t, "down", http.StatusServiceUnavailable)
+	up, _ := nodeServer(t, "up", http.StatusOK)
+	client := NewOllamaClient(OllamaConfig{BaseURLs: []string{down.URL, up.URL}, Model: "test-model", Timeout: 10})
+
+	if err := client.CheckHealth(context.Background()); err != nil {
+		t.Errorf("CheckHealth: %v", err)
+	}
+}
+
+func TestCheckHealth_AllNodesDown(t *testing.T) {
+	a, _ := nodeServer(t, "a", http.StatusServiceUnavailable)
+	b, _ := nodeServer(t, "b", http.StatusServiceUnavailable)
+	client := NewOllamaClient(OllamaConfig{BaseURLs: []string{a.URL, b.URL}, Model: "test-model", Timeout: 10})
+
+	err := client.CheckHealth(context.Background())
+	if err == nil {
+		t.Fatal("expected error with every node down")
+	}
+	if want := "no healthy Ollama node (2 tried)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to mention %q", err, want)
+	}
+}
+
+func TestOllamaNodeURLs(t *testing.T) {
+	t.Setenv("MUXCODE_OLLAMA_URL", "")
+	t.Setenv("MUXCODE_OLLAMA_URLS", "")
+	SetConfig(&MuxcodeConfig{})
+	defer SetConfig(nil)
+
+	if got := OllamaNodeURLs("m"); !reflect.DeepEqual(got, []string{"http://localhost:11434"}) {
+		t.Errorf("default = %v", got)
+	}
+
+	SetConfig(&MuxcodeConfig{Ollama: OllamaSettings{Nodes: map[string][]string{
+		"*":   {"http://any:11434"},
+		"big": {"http://gpu1:11434/", "http://gpu2:11434", "http://gpu1:11434"},
+	}}})
+	if got := OllamaNodeURLs("small"); !reflect.DeepEqual(got, []string{"http://any:11434"}) {
+		t.Errorf("wildcard = %v", got)
+	}
+	want := []string{"http://gpu1:11434", "http://gpu2:11434"}
+	if got := OllamaNodeURLs("big"); !reflect.DeepEqual(got, want) {
+		t.Errorf("model nodes = %v, want %v", got, want)
+	}
+
+	t.Setenv("MUXCODE_OLLAMA_URLS", "http://env1:11434, http://env2:11434")
+	if got := OllamaNodeURLs("small"); !reflect.DeepEqual(got, []string{"http://env1:11434", "http://env2:11434"}) {
+		t.Errorf("env = %v", got)
+	}
+	if got := OllamaNodeURLs("big"); !reflect.DeepEqual(got, want) {
+		t.Errorf("model nodes should beat env, got %v", got)
+	}
+}
+
+func TestOllamaSettings_Validate(t *testing.T) {
+	ok := OllamaSettings{Nodes: map[string][]string{"*": {"http://a:11434", "https://b"}}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("valid settings: %v", err)
+	}
+	for _, bad := range []map[string][]string{
+		{"m": {}},
+		{"m": {"localhost:11434"}},
+		{"m": {"ftp://a"}},
+	} {
+		if err := (OllamaSettings{Nodes: bad}).Validate(); err == nil {
+			t.Errorf("Validate(%v) = nil, want error", bad)
+		}
+	}
+	for _, bad := range []OllamaSettings{{VRAMLimitMB: -1}, {VRAMPressurePct: 101}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", bad)
+		}
+	}
+}
+
+func TestIsLocalOllamaURL(t *testing.T) {
+	for u, want := range map[string]bool{
+		"http://localhost:11434": true,
+		"http://127.0.0.1:11434": true,
+		"http://[::1]:11434":     true,
+		"http://gpu1:11434":      false,
+	} {
+		if got := IsLocalOllamaURL(u); got != want {
+			t.Errorf("IsLocalOllamaURL(%q) = %v, want %v", u, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PluginPrefix is the executable name prefix for plugins on PATH:
+// muxcode-agent-bus-<name> adds the subcommand <name>.
+const PluginPrefix = "muxcode-agent-bus-"
+
+// Plugin is an external executable that adds a subcommand or chain action.
+type Plugin struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Source string `json:"source"` // "config" (plugins dir) or "path"
+}
+
+// PluginDir returns the plugins directory: executables here are plugins
+// named after the file (without the muxcode-agent-bus- prefix, if present).
+func PluginDir() string {
+	return filepath.Join(configDir(), "plugins")
+}
+
+// isExecutable reports whether path is a regular file with an execute bit.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// ListPlugins returns the installed plugins sorted by name. The plugins
+// directory takes precedence over PATH; earlier PATH entries win.
+func ListPlugins() []Plugin {
+	found := map[string]Plugin{}
+	if entries, err := os.ReadDir(PluginDir()); err == nil {
+		for _, e := range entries {
+			path := filepath.Join(PluginDir(), e.Name())
+			name := strings.TrimPrefix(e.Name(), PluginPrefix)
+			if _, dup := found[name]; !dup && validRoleName(name) && isExecutable(path) {
+				found[name] = Plugin{Name: name, Path: path, Source: "config"}
+			}
+		}
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), PluginPrefix) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			name := strings.TrimPrefix(e.Name(), PluginPrefix)
+			if _, dup := found[name]; !dup && validRoleName(name) && isExecutable(path) {
+				found[name] = Plugin{Name: name, Path: path, Source: "path"}
+			}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// FindPlugin looks up an installed plugin by name.
+func FindPlugin(name string) (Plugin, bool) {
+	for _, p := range ListPlugins() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// PluginEnv returns the environment for a plugin process: the caller's
+// environment plus the session, role, bus directory, and the path of this
+// binary so plugins can call back into the bus.
+func PluginEnv(session, role string) []string {
+	exe, _ := os.Executable()
+	return append(os.Environ(),
+		"BUS_SESSION="+session,
+		"AGENT_ROLE="+role,
+		"MUXCODE_BUS_DIR="+BusDir(session),
+		"MUXCODE_AGENT_BUS="+exe,
+	)
+}
+
+// PluginCommand returns a command running plugin with args and PluginEnv.
+func PluginCommand(p Plugin, session, role string, args ...string) *exec.Cmd {
+	cmd := exec.Command(p.Path, args...)
+	cmd.Env = PluginEnv(session, role)
+	return cmd
+}
+
+// RunChainPlugin runs a chain action's plugin with the chain context in
+// CHAIN_* variables. The plugin's output is returned for display.
+func RunChainPlugin(session, from string, a ChainAction, eventType, outcome, exitCode, command string) (string, error) {
+	p, ok := FindPlugin(a.Plugin)
+	if !ok {
+		return "", fmt.Errorf("plugin %q not found (looked in %s and PATH for %s%s)", a.Plugin, PluginDir(), PluginPrefix, a.Plugin)
+	}
+	cmd := PluginCommand(p, session, from, a.Args...)
+	cmd.Env = append(cmd.Env,
+		"CHAIN_EVENT="+eventType,
+		"CHAIN_OUTCOME="+outcome,
+		"CHAIN_EXIT_CODE="+exitCode,
+		"CHAIN_COMMAND="+command,
+		"CHAIN_SEND_TO="+a.SendTo,
+		"CHAIN_ACTION="+a.Action,
+		"CHAIN_MESSAGE="+ExpandMessage(a.Message, exitCode, command),
+	)
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
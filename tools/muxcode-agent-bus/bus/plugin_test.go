@@ -0,0 +1,96 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes an executable shell script.
+func writePlugin(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestListPlugins(t *testing.T) {
+	cfgDir := t.TempDir()
+	binDir := t.TempDir()
+	t.Setenv("MUXCODE_CONFIG_DIR", cfgDir)
+	t.Setenv("PATH", binDir)
+
+	writePlugin(t, filepath.Join(cfgDir, "plugins"), "jira-comment", "echo config")
+	writePlugin(t, binDir, PluginPrefix+"jira-comment", "echo path")
+	writePlugin(t, binDir, PluginPrefix+"lint-report", "echo lint")
+	writePlugin(t, binDir, "unrelated", "true")
+	if err := os.WriteFile(filepath.Join(binDir, PluginPrefix+"noexec"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins := ListPlugins()
+	if len(plugins) != 2 {
+		t.Fatalf("expected 2 plugins, got %+v", plugins)
+	}
+	if plugins[0].Name != "jira-comment" || plugins[0].Source != "config" {
+		t.Errorf("plugins dir should win over PATH: %+v", plugins[0])
+	}
+	if plugins[1].Name != "lint-report" || plugins[1].Source != "path" {
+		t.Errorf("unexpected PATH plugin: %+v", plugins[1])
+	}
+
+	if _, ok := FindPlugin("noexec"); ok {
+		t.Error("non-executable file should not be a plugin")
+	}
+	if _, ok := FindPlugin("missing"); ok {
+		t.Error("FindPlugin should miss unknown names")
+	}
+}
+
+func TestRunChainPlugin(t *testing.T) {
+	session := testSession(t)
+	cfgDir := t.TempDir()
+	t.Setenv("MUXCODE_CONFIG_DIR", cfgDir)
+	t.Setenv("PATH", t.TempDir())
+	writePlugin(t, filepath.Join(cfgDir, "plugins"), "echo-chain",
+		`echo "$AGENT_ROLE $CHAIN_EVENT $CHAIN_OUTCOME $CHAIN_EXIT_CODE $1|$CHAIN_MESSAGE"`)
+
+	a := ChainAction{Plugin: "echo-chain", Args: []string{"PROJ-1"}, Message: "exit ${exit_code}"}
+	out, err := RunChainPlugin(session, "build", a, "build", "failure", "2", "make")
+	if err != nil {
+		t.Fatalf("RunChainPlugin: %v", err)
+	}
+	if want := "build build failure 2 PROJ-1|exit 2"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	_, err = RunChainPlugin(session, "build", ChainAction{Plugin: "missing"}, "build", "failure", "1", "make")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestValidateConfigData_ChainPlugin(t *testing.T) {
+	t.Setenv("MUXCODE_CONFIG_DIR", t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	data := []byte(`{"event_chains": {"build": {"on_failure": {"plugin": "jira-comment"}}}}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	found := false
+	for _, is := range issues {
+		if strings.Contains(is.Message, "send_to") {
+			t.Errorf("plugin actions need no send_to: %+v", is)
+		}
+		if strings.Contains(is.Message, `plugin "jira-comment" is not installed`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected missing plugin issue, got %+v", issues)
+	}
+}
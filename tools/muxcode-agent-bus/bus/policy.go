@@ -0,0 +1,218 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPolicyWindow is the rate-limit window when a rule sets none.
+const defaultPolicyWindow = 600
+
+// PolicyConfig is the "policy" section of muxcode.json: ordered send rules
+// evaluated after the flat send_policy deny lists.
+type PolicyConfig struct {
+	Mode  string       `json:"mode,omitempty"` // "enforce" (default) or "audit"
+	Rules []PolicyRule `json:"rules,omitempty"`
+}
+
+// PolicyRule matches sends by from, to, action, and type. Fields are glob
+// patterns where * matches any run of characters; empty matches anything.
+// The first matching rule decides. An allow rule with a limit caps matching
+// sends per from→to pair within the window; sends over the cap are denied.
+type PolicyRule struct {
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Action string `json:"action,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Effect string `json:"effect"`             // "allow" or "deny"
+	Limit  int    `json:"limit,omitempty"`    // max matching sends per window (allow rules)
+	Window int64  `json:"window_s,omitempty"` // rate-limit window in seconds (default 600)
+	Reason string `json:"reason,omitempty"`   // shown when the rule denies
+}
+
+// PolicyDecision is the outcome of evaluating a send.
+type PolicyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    int    `json:"rule"` // index of the deciding rule, -1 for send_policy or no match
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PolicyEvent records a denied (or, in audit mode, would-be denied) send.
+type PolicyEvent struct {
+	TS      int64  `json:"ts"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Rule    int    `json:"rule"`
+	Reason  string `json:"reason"`
+	Blocked bool   `json:"blocked"` // false in audit mode
+}
+
+// PolicyLogPath returns the policy decision log for a session.
+func PolicyLogPath(session string) string {
+	return filepath.Join(BusDir(session), "policy.jsonl")
+}
+
+// Validate checks the mode, effects, and limits.
+func (p PolicyConfig) Validate() error {
+	if p.Mode != "" && p.Mode != "enforce" && p.Mode != "audit" {
+		return fmt.Errorf("unknown policy mode %q (want enforce or audit)", p.Mode)
+	}
+	for i, r := range p.Rules {
+		if r.Effect != "allow" && r.Effect != "deny" {
+			return fmt.Errorf("policy rule %d: effect must be allow or deny", i)
+		}
+		if r.Limit < 0 || r.Window < 0 {
+			return fmt.Errorf("policy rule %d: limit and window_s must be positive", i)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a message.
+func (r PolicyRule) Matches(m Message) bool {
+	return policyMatch(r.From, m.From) && policyMatch(r.To, m.To) &&
+		policyMatch(r.Action, m.Action) && policyMatch(r.Type, m.Type)
+}
+
+// policyMatch is globMatch with an empty pattern matching anything.
+func policyMatch(pattern, s string) bool {
+	return pattern == "" || globMatch(pattern, s)
+}
+
+// EvaluatePolicy decides whether a send is allowed, without side effects.
+// The send_policy deny lists are checked first, then the rules in order.
+func EvaluatePolicy(session string, m Message) PolicyDecision {
+	if deny := CheckSendPolicy(m.From, m.To); deny != "" {
+		return PolicyDecision{Rule: -1, Reason: deny}
+	}
+	for i, r := range Config().Policy.Rules {
+		if !r.Matches(m) {
+			continue
+		}
+		if r.Effect == "deny" {
+			reason := r.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("policy rule %d denies %s → %s %s:%s", i, m.From, m.To, m.Type, m.Action)
+			}
+			return PolicyDecision{Rule: i, Reason: reason}
+		}
+		if r.Limit > 0 {
+			window := r.Window
+			if window == 0 {
+				window = defaultPolicyWindow
+			}
+			if n := countRecentSends(session, r, m, window); n >= r.Limit {
+				return PolicyDecision{Rule: i, Reason: fmt.Sprintf(
+					"rate limit: %d %s → %s sends in the last %s (policy rule %d allows %d)",
+					n, m.From, m.To, time.Duration(window)*time.Second, i, r.Limit)}
+			}
+		}
+		return PolicyDecision{Allowed: true, Rule: i}
+	}
+	return PolicyDecision{Allowed: true, Rule: -1}
+}
+
+// CheckMessagePolicy evaluates a send and records denials in the policy
+// log. It returns the denial reason, or "" when the send may proceed. In
+// audit mode rule denials are logged but not enforced; send_policy deny
+// lists are always enforced.
+func CheckMessagePolicy(session string, m Message) string {
+	d := EvaluatePolicy(session, m)
+	if d.Allowed {
+		return ""
+	}
+	blocked := d.Rule < 0 || Config().Policy.Mode != "audit"
+	_ = appendPolicyEvent(session, PolicyEvent{
+		TS: time.Now().Unix(), From: m.From, To: m.To, Type: m.Type, Action: m.Action,
+		Rule: d.Rule, Reason: d.Reason, Blocked: blocked,
+	})
+	_ = RecordAudit(session, AuditEvent{Actor: m.From, Op: AuditPolicyDeny, Role: m.To, Args: map[string]string{
+		"type": m.Type, "action": m.Action, "reason": d.Reason, "blocked": strconv.FormatBool(blocked),
+	}})
+	if !blocked {
+		return ""
+	}
+	return d.Reason
+}
+
+// countRecentSends counts logged messages matching the rule with the same
+// from→to pair as m, sent within the window.
+func countRecentSends(session string, r PolicyRule, m Message, window int64) int {
+	msgs, _ := readMessages(LogPath(session))
+	cutoff := time.Now().Unix() - window
+	n := 0
+	for _, e := range msgs {
+		if e.TS >= cutoff && e.From == m.From && e.To == m.To && r.Matches(e) {
+			n++
+		}
+	}
+	return n
+}
+
+func appendPolicyEvent(session string, ev PolicyEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return appendToFile(PolicyLogPath(session), append(data, '\n'))
+}
+
+// ReadPolicyLog returns the last limit policy events (all if limit <= 0).
+func ReadPolicyLog(session string, limit int) ([]PolicyEvent, error) {
+	f, err := os.Open(PolicyLogPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []PolicyEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev PolicyEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, scanner.Err()
+}
+
+// FormatPolicyLog formats policy events, one per line.
+func FormatPolicyLog(events []PolicyEvent) string {
+	if len(events) == 0 {
+		return "No policy denials.\n"
+	}
+	var b strings.Builder
+	for _, ev := range events {
+		verdict := "DENIED"
+		if !ev.Blocked {
+			verdict = "AUDIT "
+		}
+		fmt.Fprintf(&b, "%s  %s  %s -> %s [%s:%s]  %s\n",
+			time.Unix(ev.TS, 0).Format("15:04:05"), verdict, ev.From, ev.To, ev.Type, ev.Action, ev.Reason)
+	}
+	return b.String()
+}
+
+// mergePolicy returns base with override applied: a set mode replaces the
+// base mode; override rules are evaluated before base rules.
+func mergePolicy(base, override PolicyConfig) PolicyConfig {
+	if override.Mode != "" {
+		base.Mode = override.Mode
+	}
+	base.Rules = append(append([]PolicyRule(nil), override.Rules...), base.Rules...)
+	return base
+}
@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func policyConfig(t *testing.T, p PolicyConfig) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Policy = p
+	SetConfig(cfg)
+	t.Cleanup(func() { SetConfig(nil) })
+}
+
+func TestEvaluatePolicy_Rules(t *testing.T) {
+	session := testSession(t)
+	policyConfig(t, PolicyConfig{Rules: []PolicyRule{
+		{From: "edit", To: "deploy", Action: "deploy-prod*", Effect: "deny", Reason: "prod deploys go through review"},
+		{From: "*", To: "commit", Type: "event", Effect: "deny"},
+		{From: "edit", To: "deploy", Effect: "allow"},
+	}})
+
+	tests := []struct {
+		msg  Message
+		want bool
+		rule int
+	}{
+		{NewMessage("edit", "deploy", "request", "deploy-prod-eu", "", ""), false, 0},
+		{NewMessage("edit", "deploy", "request", "deploy-dev", "", ""), true, 2},
+		{NewMessage("build", "commit", "event", "done", "", ""), false, 1},
+		{NewMessage("build", "commit", "request", "commit", "", ""), true, -1},
+	}
+	for _, tt := range tests {
+		d := EvaluatePolicy(session, tt.msg)
+		if d.Allowed != tt.want || d.Rule != tt.rule {
+			t.Errorf("%s->%s %s:%s = %+v, want allowed=%v rule=%d",
+				tt.msg.From, tt.msg.To, tt.msg.Type, tt.msg.Action, d, tt.want, tt.rule)
+		}
+	}
+	if d := EvaluatePolicy(session, tests[0].msg); d.Reason != "prod deploys go through review" {
+		t.Errorf("reason = %q", d.Reason)
+	}
+}
+
+func TestEvaluatePolicy_RateLimit(t *testing.T) {
+	session := testSession(t)
+	policyConfig(t, PolicyConfig{Rules: []PolicyRule{
+		{From: "edit", To: "test", Type: "request", Effect: "allow", Limit: 2, Window: 600},
+	}})
+
+	msg := NewMessage("edit", "test", "request", "test", "run", "")
+	for i := 0; i < 2; i++ {
+		if deny := CheckMessagePolicy(session, msg); deny != "" {
+			t.Fatalf("send %d denied: %s", i, deny)
+		}
+		if err := Send(session, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deny := CheckMessagePolicy(session, msg)
+	if !strings.Contains(deny, "rate limit") {
+		t.Fatalf("third send = %q, want rate limit", deny)
+	}
+
+	// Other pairs and other types are counted separately
+	if d := EvaluatePolicy(session, NewMessage("review", "test", "request", "test", "", "")); !d.Allowed {
+		t.Error("other pair should not be limited")
+	}
+	if d := EvaluatePolicy(session, NewMessage("edit", "test", "response", "test", "", "")); !d.Allowed {
+		t.Error("unmatched type should not be limited")
+	}
+
+	events, err := ReadPolicyLog(session, 0)
+	if err != nil || len(events) != 1 || !events[0].Blocked {
+		t.Errorf("policy log = %+v, %v", events, err)
+	}
+}
+
+func TestCheckMessagePolicy_Audit(t *testing.T) {
+	session := testSession(t)
+	policyConfig(t, PolicyConfig{Mode: "audit", Rules: []PolicyRule{
+		{To: "deploy", Effect: "deny"},
+	}})
+
+	msg := NewMessage("edit", "deploy", "request", "deploy", "", "")
+	if deny := CheckMessagePolicy(session, msg); deny != "" {
+		t.Errorf("audit mode should not block, got %q", deny)
+	}
+	events, _ := ReadPolicyLog(session, 0)
+	if len(events) != 1 || events[0].Blocked {
+		t.Fatalf("audit event = %+v", events)
+	}
+	if out := FormatPolicyLog(events); !strings.Contains(out, "AUDIT") {
+		t.Errorf("format = %q", out)
+	}
+}
+
+func TestCheckMessagePolicy_SendPolicyAlwaysEnforced(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.SendPolicy = map[string]SendPolicy{"build": {Deny: []string{"test"}}}
+	cfg.Policy = PolicyConfig{Mode: "audit"}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if deny := CheckMessagePolicy(session, NewMessage("build", "test", "request", "x", "", "")); deny == "" {
+		t.Error("send_policy deny should be enforced in audit mode")
+	}
+}
+
+func TestPolicyConfig_Validate(t *testing.T) {
+	if err := (PolicyConfig{Rules: []PolicyRule{{Effect: "allow"}}}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (PolicyConfig{Mode: "warn"}).Validate(); err == nil {
+		t.Error("bad mode should fail")
+	}
+	if err := (PolicyConfig{Rules: []PolicyRule{{Effect: "block"}}}).Validate(); err == nil {
+		t.Error("bad effect should fail")
+	}
+}
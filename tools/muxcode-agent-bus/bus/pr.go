@@ -0,0 +1,196 @@
+package bus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PRChainType is the chain action type that hands reviewed changes to the
+// git agent to open a pull request.
+const PRChainType = "pr"
+
+// PRCreateAction is the action of the request a pr chain action sends, and
+// PRCreatedAction the event pr create sends to edit when the PR is open.
+const (
+	PRCreateAction  = "pr-create"
+	PRCreatedAction = "pr-created"
+)
+
+// prDefaultRole is the git agent's window, the default target of a pr
+// chain action.
+const prDefaultRole = "commit"
+
+// prDefaultMessage is the request text when a pr chain action sets none.
+const prDefaultMessage = "Review passed — open a pull request for the reviewed changes"
+
+// PROptions are the pr create flags.
+type PROptions struct {
+	Branch   string // head branch; default: the current branch, or one named after the title when on base
+	Base     string // default "main"
+	Title    string // PR title and commit subject
+	BodyFile string // PR body; default: the latest review summary
+	Draft    bool
+	DryRun   bool
+}
+
+// ParsePRArgs parses pr create flags. Chain actions of type pr pass their
+// args through it, so configcheck rejects bad args up front.
+func ParsePRArgs(args []string) (PROptions, error) {
+	opts := PROptions{Base: "main"}
+	for i := 0; i < len(args); i++ {
+		var dst *string
+		switch args[i] {
+		case "--branch":
+			dst = &opts.Branch
+		case "--base":
+			dst = &opts.Base
+		case "--title":
+			dst = &opts.Title
+		case "--body-file":
+			dst = &opts.BodyFile
+		case "--draft":
+			opts.Draft = true
+			continue
+		case "--dry-run":
+			opts.DryRun = true
+			continue
+		default:
+			return opts, fmt.Errorf("unknown flag: %s", args[i])
+		}
+		if i+1 >= len(args) || args[i+1] == "" {
+			return opts, fmt.Errorf("%s requires a value", args[i])
+		}
+		i++
+		*dst = args[i]
+	}
+	return opts, nil
+}
+
+// prRequest rewrites a pr chain action as the request sent to the git
+// agent: the action's message followed by the pr create command to run,
+// with the action's args as its flags.
+func (a ChainAction) prRequest() ChainAction {
+	if a.Type != PRChainType {
+		return a
+	}
+	if a.SendTo == "" {
+		a.SendTo = prDefaultRole
+	}
+	if a.Action == "" {
+		a.Action = PRCreateAction
+	}
+	if a.Message == "" {
+		a.Message = prDefaultMessage
+	}
+	cmd := "muxcode-agent-bus pr create"
+	for _, arg := range a.Args {
+		cmd += " " + shellQuote(arg)
+	}
+	a.Message += ". Run: " + cmd
+	if !hasArg(a.Args, "--title") {
+		a.Message += ` --title "<one-line summary of the changes>"`
+	}
+	a.Type = "request"
+	a.Args = nil
+	return a
+}
+
+// hasArg reports whether args contains flag.
+func hasArg(args []string, flag string) bool {
+	for _, a := range args {
+		if a == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// PRBody renders the review role's latest history entry as a pull request
+// body, or a placeholder when no review was logged.
+func PRBody(session string) string {
+	entries := ReadHistory(session, "review", 1)
+	if len(entries) == 0 {
+		return "Opened by muxcode. No review was logged for these changes.\n"
+	}
+	e := entries[0]
+	var b strings.Builder
+	b.WriteString("## Review\n\n")
+	b.WriteString(e.Summary + "\n")
+	if out := strings.TrimSpace(e.Output); out != "" {
+		b.WriteString("\n### Findings\n\n")
+		for _, line := range strings.Split(out, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				b.WriteString("- " + strings.TrimPrefix(line, "- ") + "\n")
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n_Reviewed %s, opened by muxcode._\n", time.Unix(e.TS, 0).Format("2006-01-02 15:04"))
+	return b.String()
+}
+
+var prBranchUnsafeRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// PRBranchName names a head branch after the PR title, e.g.
+// "muxcode/fix-login-redirect".
+func PRBranchName(title string) string {
+	slug := strings.Trim(prBranchUnsafeRe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "changes"
+	}
+	return "muxcode/" + slug
+}
+
+// PRStep is one command of a pull request plan.
+type PRStep struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+// String renders the step as a shell command.
+func (s PRStep) String() string {
+	parts := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		parts[i] = shellQuote(a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// PlanPR returns the commands that open a pull request from the working
+// tree: branch off base when on it, commit pending changes, push the head
+// branch, and create the PR with the body in bodyFile. current is the
+// checked-out branch and dirty whether the tree has uncommitted changes.
+func PlanPR(opts PROptions, current string, dirty bool, bodyFile string) ([]PRStep, error) {
+	if opts.Title == "" {
+		return nil, fmt.Errorf("--title is required")
+	}
+	head := opts.Branch
+	var steps []PRStep
+	switch {
+	case head == "" && current != opts.Base && current != "HEAD" && current != "":
+		head = current
+	case head == "":
+		head = PRBranchName(opts.Title)
+		fallthrough
+	case head != current:
+		steps = append(steps, PRStep{"branch", []string{"git", "checkout", "-b", head}})
+	}
+	if head == opts.Base {
+		return nil, fmt.Errorf("head branch %q is the base branch", head)
+	}
+	if dirty {
+		steps = append(steps,
+			PRStep{"stage", []string{"git", "add", "-A"}},
+			PRStep{"commit", []string{"git", "commit", "-m", opts.Title}})
+	}
+	steps = append(steps, PRStep{"push", []string{"git", "push", "-u", "origin", head}})
+	create := []string{"gh", "pr", "create", "--base", opts.Base, "--head", head, "--title", opts.Title, "--body-file", bodyFile}
+	if opts.Draft {
+		create = append(create, "--draft")
+	}
+	return append(steps, PRStep{"pr", create}), nil
+}
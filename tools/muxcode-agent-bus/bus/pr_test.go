@@ -0,0 +1,112 @@
+package bus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePRArgs(t *testing.T) {
+	opts, err := ParsePRArgs([]string{"--title", "Fix login", "--base", "develop", "--draft"})
+	if err != nil || opts.Title != "Fix login" || opts.Base != "develop" || !opts.Draft || opts.DryRun {
+		t.Errorf("ParsePRArgs = %+v, %v", opts, err)
+	}
+	if opts, _ := ParsePRArgs(nil); opts.Base != "main" {
+		t.Errorf("default base = %q", opts.Base)
+	}
+	if _, err := ParsePRArgs([]string{"--branch"}); err == nil || !strings.Contains(err.Error(), "requires a value") {
+		t.Errorf("missing value: %v", err)
+	}
+	if _, err := ParsePRArgs([]string{"--force"}); err == nil {
+		t.Error("unknown flag: want error")
+	}
+}
+
+func TestPlanPR(t *testing.T) {
+	names := func(steps []PRStep) string {
+		var s []string
+		for _, st := range steps {
+			s = append(s, st.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	// On base with changes: branch, commit, push, open
+	steps, err := PlanPR(PROptions{Base: "main", Title: "Fix login redirect!", Draft: true}, "main", true, "/tmp/body.md")
+	if err != nil || names(steps) != "branch,stage,commit,push,pr" {
+		t.Fatalf("PlanPR on base = %v, %v", steps, err)
+	}
+	if got := steps[0].String(); got != "git checkout -b muxcode/fix-login-redirect" {
+		t.Errorf("branch step = %q", got)
+	}
+	if got := steps[2].String(); got != "git commit -m 'Fix login redirect!'" {
+		t.Errorf("commit step = %q", got)
+	}
+	if got := steps[4].String(); got != "gh pr create --base main --head muxcode/fix-login-redirect --title 'Fix login redirect!' --body-file /tmp/body.md --draft" {
+		t.Errorf("pr step = %q", got)
+	}
+
+	// On a feature branch with a clean tree: push and open
+	steps, err = PlanPR(PROptions{Base: "main", Title: "Fix"}, "fix/login", false, "b.md")
+	if err != nil || names(steps) != "push,pr" || steps[0].Args[4] != "fix/login" {
+		t.Errorf("PlanPR on feature branch = %v, %v", steps, err)
+	}
+
+	if _, err := PlanPR(PROptions{Base: "main"}, "main", true, "b.md"); err == nil {
+		t.Error("no title: want error")
+	}
+	if _, err := PlanPR(PROptions{Base: "main", Branch: "main", Title: "x"}, "dev", false, "b.md"); err == nil {
+		t.Error("head is base: want error")
+	}
+}
+
+func TestPRBody(t *testing.T) {
+	session := testSession(t)
+	if body := PRBody(session); !strings.Contains(body, "No review was logged") {
+		t.Errorf("no review body = %q", body)
+	}
+	data, _ := json.Marshal(HistoryEntry{TS: 1, Summary: "0 must-fix, 1 should-fix, 0 nits", ExitCode: "0",
+		Output: "should-fix: bus/pr.go:10 — name the constant\n\n"})
+	if err := AppendHistory(session, "review", data, 100); err != nil {
+		t.Fatal(err)
+	}
+	body := PRBody(session)
+	if !strings.HasPrefix(body, "## Review\n\n0 must-fix, 1 should-fix, 0 nits\n\n### Findings\n\n- should-fix: bus/pr.go:10 — name the constant\n") {
+		t.Errorf("PRBody:\n%s", body)
+	}
+}
+
+func TestResolveChainSteps_PR(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EventChains["review"] = EventChain{
+		OnSuccess: &ChainAction{Type: PRChainType, Args: []string{"--base", "develop", "--draft"}},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	actions := ResolveChainSteps("review", "success", "review")
+	if len(actions) != 1 {
+		t.Fatalf("actions = %+v", actions)
+	}
+	a := actions[0]
+	if a.SendTo != "commit" || a.Type != "request" || a.Action != PRCreateAction || a.Args != nil {
+		t.Errorf("pr action = %+v", a)
+	}
+	want := prDefaultMessage + `. Run: muxcode-agent-bus pr create --base develop --draft --title "<one-line summary of the changes>"`
+	if a.Message != want {
+		t.Errorf("message = %q\nwant %q", a.Message, want)
+	}
+	if edges := ChainGraph(nil); !hasEdge(edges, "review", "commit") {
+		t.Errorf("ChainGraph missing review -> commit: %+v", edges)
+	}
+}
+
+// hasEdge reports whether edges has an edge from event to role.
+func hasEdge(edges []ChainEdge, event, to string) bool {
+	for _, e := range edges {
+		if e.Event == event && e.To == to {
+			return true
+		}
+	}
+	return false
+}
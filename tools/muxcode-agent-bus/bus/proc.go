@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,17 +18,42 @@ import (
 
 // ProcEntry represents a tracked background process.
 type ProcEntry struct {
-	ID         string `json:"id"`
-	PID        int    `json:"pid"`
-	Command    string `json:"command"`
-	Dir        string `json:"dir"`
-	Owner      string `json:"owner"`
-	Status     string `json:"status"`
-	ExitCode   int    `json:"exit_code"`
-	StartedAt  int64  `json:"started_at"`
-	FinishedAt int64  `json:"finished_at"`
-	LogFile    string `json:"log_file"`
-	Notified   bool   `json:"notified"`
+	ID          string            `json:"id"`
+	PID         int               `json:"pid"`
+	Command     string            `json:"command"`
+	Dir         string            `json:"dir"`
+	Owner       string            `json:"owner"`
+	Status      string            `json:"status"`
+	ExitCode    int               `json:"exit_code"`
+	StartedAt   int64             `json:"started_at"`
+	FinishedAt  int64             `json:"finished_at"`
+	LogFile     string            `json:"log_file"`
+	Notified    bool              `json:"notified"`
+	Timeout     int64             `json:"timeout_s,omitempty"`    // kill after this many seconds (0 = none)
+	MaxLog      int64             `json:"max_log,omitempty"`      // log size cap in bytes (0 = none)
+	Nice        int               `json:"nice,omitempty"`         // scheduling niceness
+	LogDropped  int64             `json:"log_dropped,omitempty"`  // bytes removed from the log by the cap
+	Env         map[string]string `json:"env,omitempty"`          // extra environment variables
+	Template    string            `json:"template,omitempty"`     // proc template the entry was started from
+	Restart     string            `json:"restart,omitempty"`      // restart policy ("on-failure", "always")
+	MaxRestarts int               `json:"max_restarts,omitempty"` // restart cap (0 = DefaultMaxRestarts)
+	Restarts    int               `json:"restarts,omitempty"`     // restarts so far in this chain
+	RestartedAs string            `json:"restarted_as,omitempty"` // ID of the replacement proc
+}
+
+// ProcOptions sets resource limits for a background process. Timeout and
+// MaxLog are enforced by RefreshProcStatus, which the watcher runs every
+// poll cycle; without a running watcher they are not enforced.
+type ProcOptions struct {
+	Timeout time.Duration // kill the process group and mark "timeout" after this long
+	MaxLog  int64         // keep the log under this many bytes, dropping the oldest output
+	Nice    int           // run under `nice -n`
+
+	Env         map[string]string // extra environment variables
+	Template    string            // proc template name, for display
+	Restart     string            // restart policy applied when the process finishes
+	MaxRestarts int               // restart cap (0 = DefaultMaxRestarts)
+	restarts    int               // restarts so far, carried to the replacement
 }
 
 // exitCodeRe matches the EXIT_CODE sentinel appended to log files.
@@ -136,6 +164,11 @@ func RemoveProcEntry(session, id string) error {
 // StartProc launches a background process and tracks it in the proc JSONL file.
 // The command is wrapped with an exit code sentinel for reliable status detection.
 func StartProc(session, command, dir, owner string) (ProcEntry, error) {
+	return StartProcWithOptions(session, command, dir, owner, ProcOptions{})
+}
+
+// StartProcWithOptions is StartProc with resource limits.
+func StartProcWithOptions(session, command, dir, owner string, opts ProcOptions) (ProcEntry, error) {
 	id := NewMsgID("proc")
 	logFile := ProcLogPath(session, id)
 
@@ -144,8 +177,9 @@ func StartProc(session, command, dir, owner string) (ProcEntry, error) {
 		return ProcEntry{}, fmt.Errorf("creating proc dir: %v", err)
 	}
 
-	// Create log file
-	lf, err := os.Create(logFile)
+	// Create log file in append mode so the log cap can rewrite it while
+	// the process is still writing
+	lf, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
 	if err != nil {
 		return ProcEntry{}, fmt.Errorf("creating log file: %v", err)
 	}
@@ -155,8 +189,24 @@ func StartProc(session, command, dir, owner string) (ProcEntry, error) {
 	// the EXIT_CODE sentinel from being written.
 	wrapped := fmt.Sprintf("(%s); echo EXIT_CODE:$? >> %s", command, logFile)
 
-	cmd := exec.Command("sh", "-c", wrapped)
+	var cmd *exec.Cmd
+	if opts.Nice != 0 {
+		cmd = exec.Command("nice", "-n", strconv.Itoa(opts.Nice), "sh", "-c", wrapped)
+	} else {
+		cmd = exec.Command("sh", "-c", wrapped)
+	}
 	cmd.Dir = dir
+	if len(opts.Env) > 0 {
+		keys := make([]string, 0, len(opts.Env))
+		for k := range opts.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+opts.Env[k])
+		}
+	}
 	cmd.Stdout = lf
 	cmd.Stderr = lf
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -171,15 +221,23 @@ func StartProc(session, command, dir, owner string) (ProcEntry, error) {
 	go cmd.Wait()
 
 	entry := ProcEntry{
-		ID:        id,
-		PID:       cmd.Process.Pid,
-		Command:   command,
-		Dir:       dir,
-		Owner:     owner,
-		Status:    "running",
-		ExitCode:  -1,
-		StartedAt: time.Now().Unix(),
-		LogFile:   logFile,
+		ID:          id,
+		PID:         cmd.Process.Pid,
+		Command:     command,
+		Dir:         dir,
+		Owner:       owner,
+		Status:      "running",
+		ExitCode:    -1,
+		StartedAt:   time.Now().Unix(),
+		LogFile:     logFile,
+		Timeout:     int64(opts.Timeout / time.Second),
+		MaxLog:      opts.MaxLog,
+		Nice:        opts.Nice,
+		Env:         opts.Env,
+		Template:    opts.Template,
+		Restart:     opts.Restart,
+		MaxRestarts: opts.MaxRestarts,
+		Restarts:    opts.restarts,
 	}
 
 	entries, err := ReadProcEntries(session)
@@ -190,6 +248,9 @@ func StartProc(session, command, dir, owner string) (ProcEntry, error) {
 	if err := WriteProcEntries(session, entries); err != nil {
 		return ProcEntry{}, err
 	}
+	_ = RecordAudit(session, AuditEvent{Actor: owner, Op: AuditProcStart, Args: map[string]string{
+		"id": id, "command": command, "dir": dir, "pid": strconv.Itoa(entry.PID),
+	}})
 
 	return entry, nil
 }
@@ -204,6 +265,8 @@ func CheckProcAlive(pid int) bool {
 }
 
 // RefreshProcStatus checks all running processes and updates their status.
+// Finished procs whose restart policy applies are relaunched, and the
+// replacement ID is recorded in RestartedAs.
 // Returns the list of entries that transitioned from running to a terminal state.
 func RefreshProcStatus(session string) ([]ProcEntry, error) {
 	entries, err := ReadProcEntries(session)
@@ -214,11 +277,29 @@ func RefreshProcStatus(session string) ([]ProcEntry, error) {
 	var completed []ProcEntry
 	changed := false
 
+	now := time.Now().Unix()
 	for i, e := range entries {
 		if e.Status != "running" {
 			continue
 		}
 
+		if e.MaxLog > 0 {
+			if dropped := capProcLog(e.LogFile, e.MaxLog); dropped > 0 {
+				entries[i].LogDropped += dropped
+				changed = true
+			}
+		}
+
+		if e.Timeout > 0 && now-e.StartedAt >= e.Timeout && CheckProcAlive(e.PID) {
+			killProcGroup(e.PID)
+			_ = appendToFile(e.LogFile, []byte(fmt.Sprintf("\n[muxcode] timeout after %s — process killed\n", time.Duration(e.Timeout)*time.Second)))
+			entries[i].Status = "timeout"
+			entries[i].FinishedAt = now
+			changed = true
+			completed = append(completed, entries[i])
+			continue
+		}
+
 		if CheckProcAlive(e.PID) {
 			continue
 		}
@@ -248,9 +329,131 @@ func RefreshProcStatus(session string) ([]ProcEntry, error) {
 		}
 	}
 
+	// Scrub secrets from finished logs before anything reads them
+	for _, e := range completed {
+		redactFile(session, "proc", e.ID, e.LogFile)
+	}
+
+	for i, e := range completed {
+		if !shouldRestart(e) {
+			continue
+		}
+		next, err := restartProc(session, e)
+		if err != nil {
+			_ = appendToFile(e.LogFile, []byte(fmt.Sprintf("[muxcode] restart failed: %v\n", err)))
+			continue
+		}
+		completed[i].RestartedAs = next.ID
+		_ = UpdateProcEntry(session, e.ID, func(p *ProcEntry) {
+			p.RestartedAs = next.ID
+		})
+	}
+
 	return completed, nil
 }
 
+// restartProc launches a replacement for a finished proc with the same
+// command, directory, owner, and options.
+func restartProc(session string, e ProcEntry) (ProcEntry, error) {
+	return StartProcWithOptions(session, e.Command, e.Dir, e.Owner, ProcOptions{
+		Timeout:     time.Duration(e.Timeout) * time.Second,
+		MaxLog:      e.MaxLog,
+		Nice:        e.Nice,
+		Env:         e.Env,
+		Template:    e.Template,
+		Restart:     e.Restart,
+		MaxRestarts: e.MaxRestarts,
+		restarts:    e.Restarts + 1,
+	})
+}
+
+// killProcGroup sends SIGTERM to a process group, falling back to the
+// single process, then SIGKILL if it is still alive shortly after.
+func killProcGroup(pid int) {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+	for i := 0; i < 10 && CheckProcAlive(pid); i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if CheckProcAlive(pid) {
+		if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+// capProcLog keeps a log under maxBytes by dropping its oldest output and
+// prefixing a truncation marker. The newest half of the cap is kept so the
+// process has room to keep writing. Only that tail is read, and it is
+// rewritten in place; output the process appends meanwhile lands past the
+// rewritten tail and is moved down before the file is truncated. Returns
+// the number of bytes dropped.
+func capProcLog(path string, maxBytes int64) int64 {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() <= maxBytes {
+		return 0
+	}
+	keep := maxBytes / 2
+	cut := info.Size() - keep
+	tail, err := io.ReadAll(io.NewSectionReader(f, cut, 1<<62))
+	if err != nil {
+		return 0
+	}
+	// Start the kept tail on a line boundary
+	if idx := bytes.IndexByte(tail, '\n'); idx >= 0 && int64(idx) < keep {
+		cut += int64(idx) + 1
+		tail = tail[idx+1:]
+	}
+	end := cut + int64(len(tail))
+	marker := fmt.Sprintf("[muxcode] log truncated: dropped %s (max-log %s)\n", formatBytes(cut), formatBytes(maxBytes))
+	size, err := f.WriteAt(append([]byte(marker), tail...), 0)
+	if err != nil {
+		return 0
+	}
+	for {
+		more, err := io.ReadAll(io.NewSectionReader(f, end, 1<<62))
+		if err != nil || len(more) == 0 {
+			break
+		}
+		if _, err := f.WriteAt(more, int64(size)); err != nil {
+			break
+		}
+		size += len(more)
+		end += int64(len(more))
+	}
+	if err := f.Truncate(int64(size)); err != nil {
+		return 0
+	}
+	return cut
+}
+
+// ParseByteSize parses sizes like "512", "64KB", "50MB", or "1GB" (binary units).
+func ParseByteSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (want e.g. 50MB)", s)
+	}
+	return n * mult, nil
+}
+
 // extractExitCode reads the last non-empty lines of a log file looking for
 // the EXIT_CODE sentinel. Returns the exit code and true if found.
 func extractExitCode(logFile string) (int, bool) {
@@ -377,6 +580,9 @@ func FormatProcStatus(entry ProcEntry) string {
 	b.WriteString(fmt.Sprintf("  Owner:    %s\n", entry.Owner))
 	b.WriteString(fmt.Sprintf("  Command:  %s\n", entry.Command))
 	b.WriteString(fmt.Sprintf("  Dir:      %s\n", entry.Dir))
+	if entry.Template != "" {
+		b.WriteString(fmt.Sprintf("  Template: %s\n", entry.Template))
+	}
 	b.WriteString(fmt.Sprintf("  Started:  %s\n", time.Unix(entry.StartedAt, 0).Format("2006-01-02 15:04:05")))
 
 	if entry.FinishedAt > 0 {
@@ -389,6 +595,33 @@ func FormatProcStatus(entry ProcEntry) string {
 		b.WriteString(fmt.Sprintf("  Exit:     %d\n", entry.ExitCode))
 	}
 
+	var limits []string
+	if entry.Timeout > 0 {
+		limits = append(limits, "timeout "+(time.Duration(entry.Timeout)*time.Second).String())
+	}
+	if entry.MaxLog > 0 {
+		limits = append(limits, "max-log "+formatBytes(entry.MaxLog))
+	}
+	if entry.Nice != 0 {
+		limits = append(limits, fmt.Sprintf("nice %d", entry.Nice))
+	}
+	if len(limits) > 0 {
+		b.WriteString(fmt.Sprintf("  Limits:   %s\n", strings.Join(limits, ", ")))
+	}
+	if entry.Restart != "" && entry.Restart != RestartNo {
+		max := entry.MaxRestarts
+		if max <= 0 {
+			max = DefaultMaxRestarts
+		}
+		b.WriteString(fmt.Sprintf("  Restart:  %s (%d/%d)\n", entry.Restart, entry.Restarts, max))
+	}
+	if entry.RestartedAs != "" {
+		b.WriteString(fmt.Sprintf("  Next:     %s\n", entry.RestartedAs))
+	}
+	if entry.LogDropped > 0 {
+		b.WriteString(fmt.Sprintf("  Dropped:  %s of log output\n", formatBytes(entry.LogDropped)))
+	}
+
 	b.WriteString(fmt.Sprintf("  Log:      %s\n", entry.LogFile))
 
 	return b.String()
@@ -230,6 +230,88 @@ func TestStartProc(t *testing.T) {
 	}
 }
 
+func TestStartProc_Timeout(t *testing.T) {
+	session := fmt.Sprintf("test-proc-timeout-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	entry, err := StartProcWithOptions(session, "sleep 30", "/tmp", "build", ProcOptions{Timeout: time.Second, Nice: 5})
+	if err != nil {
+		t.Fatalf("StartProcWithOptions: %v", err)
+	}
+	if entry.Timeout != 1 || entry.Nice != 5 {
+		t.Errorf("limits not recorded: %+v", entry)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	completed, err := RefreshProcStatus(session)
+	if err != nil {
+		t.Fatalf("RefreshProcStatus: %v", err)
+	}
+	if len(completed) != 1 || completed[0].Status != "timeout" {
+		t.Fatalf("completed = %+v, want one timeout", completed)
+	}
+	if CheckProcAlive(entry.PID) {
+		t.Error("process still alive after timeout")
+	}
+	data, _ := os.ReadFile(entry.LogFile)
+	if !strings.Contains(string(data), "[muxcode] timeout after 1s") {
+		t.Errorf("log missing timeout marker: %q", data)
+	}
+}
+
+func TestCapProcLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proc.log")
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "line %03d of build output\n", i)
+	}
+	_ = os.WriteFile(path, []byte(b.String()), 0644)
+
+	if dropped := capProcLog(path, 1<<20); dropped != 0 {
+		t.Errorf("under cap dropped %d bytes", dropped)
+	}
+
+	dropped := capProcLog(path, 1000)
+	if dropped <= 0 {
+		t.Fatal("expected bytes dropped")
+	}
+	data, _ := os.ReadFile(path)
+	if int64(len(data)) > 1000 {
+		t.Errorf("log is %d bytes, cap 1000", len(data))
+	}
+	if !strings.HasPrefix(string(data), "[muxcode] log truncated") || !strings.Contains(string(data), "line 199") {
+		t.Errorf("unexpected capped log:\n%s", data)
+	}
+	lines := strings.Split(string(data), "\n")
+	if !strings.HasPrefix(lines[1], "line ") {
+		t.Errorf("kept tail does not start on a line boundary: %q", lines[1])
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"64KB", 64 << 10},
+		{"50MB", 50 << 20},
+		{"50mb", 50 << 20},
+		{"2G", 2 << 30},
+	}
+	for _, tt := range tests {
+		if got, err := ParseByteSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "MB", "-5MB", "ten"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("ParseByteSize(%q): expected error", bad)
+		}
+	}
+}
+
 func TestCheckProcAlive(t *testing.T) {
 	// Current process should be alive
 	if !CheckProcAlive(os.Getpid()) {
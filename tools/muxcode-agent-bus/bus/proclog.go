@@ -0,0 +1,184 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// procFollowInterval is how often followed logs are polled for new output.
+const procFollowInterval = 250 * time.Millisecond
+
+// FollowOptions selects which proc logs FollowProcLogs streams.
+type FollowOptions struct {
+	IDs      []string      // procs to follow (ignored when All is set)
+	All      bool          // follow every running proc, including ones started later
+	Tail     int           // lines of existing output to emit per proc before following
+	Interval time.Duration // poll interval (0 = 250ms)
+}
+
+// ProcLogLine is one line of followed proc output.
+type ProcLogLine struct {
+	ProcID string
+	Text   string
+}
+
+// procTail tracks the read position within one proc log.
+type procTail struct {
+	entry   ProcEntry
+	offset  int64
+	partial []byte
+}
+
+// ProcPrefix returns the short per-proc prefix used when multiplexing logs,
+// e.g. "[a1b2c3d4]" for "1740000000-proc-a1b2c3d4".
+func ProcPrefix(id string) string {
+	if idx := strings.LastIndex(id, "-"); idx >= 0 {
+		id = id[idx+1:]
+	}
+	return "[" + id + "]"
+}
+
+// FollowProcLogs streams new log lines to emit until ctx is cancelled or
+// every followed proc has finished and its log is drained (immediately, if
+// none are running). Proc status is
+// refreshed each poll so exits (and limit enforcement) are noticed even
+// without the watcher. Logs rewritten by the max-log cap are re-read from
+// the start. Lines are scrubbed of secrets as they are emitted.
+func FollowProcLogs(ctx context.Context, session string, opts FollowOptions, emit func(ProcLogLine)) error {
+	raw := emit
+	emit = func(l ProcLogLine) {
+		l.Text, _ = Redact(l.Text)
+		raw(l)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = procFollowInterval
+	}
+
+	tails := make(map[string]*procTail)
+	var order []string
+	track := func(e ProcEntry) {
+		if _, ok := tails[e.ID]; ok {
+			return
+		}
+		t := &procTail{entry: e}
+		data, _ := os.ReadFile(e.LogFile)
+		// Only complete lines count as existing output; a partial last line
+		// is picked up by the first read
+		if idx := bytes.LastIndexByte(data, '\n'); idx >= 0 {
+			data = data[:idx+1]
+		} else {
+			data = nil
+		}
+		t.offset = int64(len(data))
+		if opts.Tail > 0 {
+			for _, line := range lastLines(data, opts.Tail) {
+				emit(ProcLogLine{ProcID: e.ID, Text: line})
+			}
+		}
+		tails[e.ID] = t
+		order = append(order, e.ID)
+	}
+
+	for {
+		_, _ = RefreshProcStatus(session)
+		entries, err := ReadProcEntries(session)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]ProcEntry, len(entries))
+		for _, e := range entries {
+			byID[e.ID] = e
+			if opts.All && e.Status == "running" {
+				track(e)
+			}
+		}
+		if !opts.All {
+			for _, id := range opts.IDs {
+				if e, ok := byID[id]; ok {
+					track(e)
+				}
+			}
+		}
+
+		running := 0
+		for _, id := range order {
+			t := tails[id]
+			if e, ok := byID[id]; ok {
+				t.entry = e
+			}
+			readProcTail(t, emit)
+			if t.entry.Status == "running" {
+				running++
+			}
+		}
+		if running == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// readProcTail emits complete lines written since the last read. Once the
+// proc has finished, a trailing partial line is flushed too.
+func readProcTail(t *procTail, emit func(ProcLogLine)) {
+	f, err := os.Open(t.entry.LogFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if info.Size() < t.offset {
+		// Log was truncated by the max-log cap
+		t.offset = 0
+		t.partial = nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+	t.offset += int64(len(data))
+
+	buf := append(t.partial, data...)
+	for {
+		idx := bytes.IndexByte(buf, '\n')
+		if idx < 0 {
+			break
+		}
+		emit(ProcLogLine{ProcID: t.entry.ID, Text: string(buf[:idx])})
+		buf = buf[idx+1:]
+	}
+	t.partial = append([]byte(nil), buf...)
+	if t.entry.Status != "running" && len(t.partial) > 0 {
+		emit(ProcLogLine{ProcID: t.entry.ID, Text: string(t.partial)})
+		t.partial = nil
+	}
+}
+
+// lastLines returns the last n lines of newline-terminated data.
+func lastLines(data []byte, n int) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,88 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFollowProcLogs_Single(t *testing.T) {
+	session := fmt.Sprintf("test-proclog-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	entry, err := StartProc(session, "echo one; sleep 0.3; echo two; printf three", "/tmp", "build")
+	if err != nil {
+		t.Fatalf("StartProc: %v", err)
+	}
+
+	var lines []string
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = FollowProcLogs(ctx, session, FollowOptions{IDs: []string{entry.ID}, Tail: 10, Interval: 50 * time.Millisecond}, func(l ProcLogLine) {
+		lines = append(lines, l.Text)
+	})
+	if err != nil {
+		t.Fatalf("FollowProcLogs: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("follow did not stop when the process exited")
+	}
+
+	got := strings.Join(lines, "|")
+	if !strings.HasPrefix(got, "one|two|three") {
+		t.Errorf("followed output = %q, want one|two|three first", got)
+	}
+}
+
+func TestFollowProcLogs_All(t *testing.T) {
+	session := fmt.Sprintf("test-proclog-all-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	a, _ := StartProc(session, "sleep 0.2; echo from-a", "/tmp", "build")
+	b, _ := StartProc(session, "sleep 0.2; echo from-b", "/tmp", "test")
+
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = FollowProcLogs(ctx, session, FollowOptions{All: true, Interval: 50 * time.Millisecond}, func(l ProcLogLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(l.Text, "from-") {
+			seen[l.ProcID] = l.Text
+		}
+	})
+
+	if seen[a.ID] != "from-a" || seen[b.ID] != "from-b" {
+		t.Errorf("multiplexed output = %v", seen)
+	}
+}
+
+func TestFollowProcLogs_NoneRunning(t *testing.T) {
+	session := fmt.Sprintf("test-proclog-none-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	done := make(chan struct{})
+	go func() {
+		_ = FollowProcLogs(context.Background(), session, FollowOptions{All: true}, func(ProcLogLine) {})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("follow --all with no running procs did not return")
+	}
+}
+
+func TestProcPrefix(t *testing.T) {
+	if got := ProcPrefix("1740000000-proc-a1b2c3d4"); got != "[a1b2c3d4]" {
+		t.Errorf("ProcPrefix = %q", got)
+	}
+}
@@ -0,0 +1,152 @@
+package bus
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Restart policies for template-launched processes.
+const (
+	RestartNo        = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// DefaultMaxRestarts caps automatic restarts when a template sets none.
+const DefaultMaxRestarts = 5
+
+// ProcTemplate is a named, reusable process definition from muxcode.json,
+// launched with `proc start --template NAME`.
+type ProcTemplate struct {
+	Command     string            `json:"command"`
+	Dir         string            `json:"dir,omitempty"`          // relative paths resolve against the caller's cwd
+	Env         map[string]string `json:"env,omitempty"`          // added to the inherited environment
+	Owner       string            `json:"owner,omitempty"`        // role notified on completion (default: caller)
+	Restart     string            `json:"restart,omitempty"`      // "no" (default), "on-failure", or "always"
+	MaxRestarts int               `json:"max_restarts,omitempty"` // default 5
+	Timeout     string            `json:"timeout,omitempty"`      // Go duration, e.g. "30m"
+	MaxLog      string            `json:"max_log,omitempty"`      // size, e.g. "50MB"
+	Nice        int               `json:"nice,omitempty"`
+}
+
+// ValidRestartPolicy reports whether p is a known restart policy ("" means "no").
+func ValidRestartPolicy(p string) bool {
+	switch p {
+	case "", RestartNo, RestartOnFailure, RestartAlways:
+		return true
+	}
+	return false
+}
+
+// GetProcTemplate returns the named template from the loaded config.
+func GetProcTemplate(name string) (ProcTemplate, error) {
+	t, ok := Config().ProcTemplates[name]
+	if !ok {
+		return ProcTemplate{}, fmt.Errorf("unknown proc template: %s", name)
+	}
+	if strings.TrimSpace(t.Command) == "" {
+		return ProcTemplate{}, fmt.Errorf("proc template %s has no command", name)
+	}
+	if !ValidRestartPolicy(t.Restart) {
+		return ProcTemplate{}, fmt.Errorf("proc template %s: invalid restart policy %q (want no, on-failure, or always)", name, t.Restart)
+	}
+	return t, nil
+}
+
+// Options converts the template's limits, env, and restart policy to ProcOptions.
+func (t ProcTemplate) Options(name string) (ProcOptions, error) {
+	opts := ProcOptions{
+		Nice:        t.Nice,
+		Env:         t.Env,
+		Template:    name,
+		Restart:     t.Restart,
+		MaxRestarts: t.MaxRestarts,
+	}
+	if t.Timeout != "" {
+		d, err := time.ParseDuration(t.Timeout)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("proc template %s: invalid timeout %q", name, t.Timeout)
+		}
+		opts.Timeout = d
+	}
+	if t.MaxLog != "" {
+		n, err := ParseByteSize(t.MaxLog)
+		if err != nil {
+			return opts, fmt.Errorf("proc template %s: %v", name, err)
+		}
+		opts.MaxLog = n
+	}
+	return opts, nil
+}
+
+// ResolveDir returns the template's working directory, resolved against cwd.
+func (t ProcTemplate) ResolveDir(cwd string) string {
+	if t.Dir == "" {
+		return cwd
+	}
+	if filepath.IsAbs(t.Dir) {
+		return t.Dir
+	}
+	return filepath.Join(cwd, t.Dir)
+}
+
+// shouldRestart reports whether a finished proc's restart policy asks for
+// another run. Procs stopped by the user are never restarted.
+func shouldRestart(e ProcEntry) bool {
+	if e.Status == "stopped" {
+		return false
+	}
+	max := e.MaxRestarts
+	if max <= 0 {
+		max = DefaultMaxRestarts
+	}
+	if e.Restarts >= max {
+		return false
+	}
+	switch e.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return e.Status == "failed" || e.Status == "timeout"
+	}
+	return false
+}
+
+// FormatProcTemplates renders the configured templates as a table.
+func FormatProcTemplates(templates map[string]ProcTemplate) string {
+	if len(templates) == 0 {
+		return "No proc templates configured (add \"proc_templates\" to muxcode.json).\n"
+	}
+	var names []string
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %-10s %-12s %s\n", "NAME", "OWNER", "RESTART", "COMMAND")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	for _, name := range names {
+		t := templates[name]
+		owner := t.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		restart := t.Restart
+		if restart == "" {
+			restart = RestartNo
+		}
+		cmd := t.Command
+		if t.Dir != "" {
+			cmd = fmt.Sprintf("(%s) %s", t.Dir, cmd)
+		}
+		if len(cmd) > 60 {
+			cmd = cmd[:57] + "..."
+		}
+		fmt.Fprintf(&b, "%-16s %-10s %-12s %s\n", name, owner, restart, cmd)
+	}
+	return b.String()
+}
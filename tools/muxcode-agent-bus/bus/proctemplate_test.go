@@ -0,0 +1,163 @@
+package bus
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetProcTemplate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ProcTemplates = map[string]ProcTemplate{
+		"dev-server": {Command: "npm run dev", Dir: "web", Restart: RestartOnFailure, Timeout: "2h", MaxLog: "10MB", Nice: 5},
+		"empty":      {Command: " "},
+		"bad":        {Command: "true", Restart: "sometimes"},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	tmpl, err := GetProcTemplate("dev-server")
+	if err != nil {
+		t.Fatalf("GetProcTemplate: %v", err)
+	}
+	opts, err := tmpl.Options("dev-server")
+	if err != nil {
+		t.Fatalf("Options: %v", err)
+	}
+	if opts.Timeout != 2*time.Hour || opts.MaxLog != 10<<20 || opts.Nice != 5 || opts.Template != "dev-server" || opts.Restart != RestartOnFailure {
+		t.Errorf("Options = %+v", opts)
+	}
+	if got := tmpl.ResolveDir("/repo"); got != "/repo/web" {
+		t.Errorf("ResolveDir = %q, want /repo/web", got)
+	}
+
+	for _, name := range []string{"missing", "empty", "bad"} {
+		if _, err := GetProcTemplate(name); err == nil {
+			t.Errorf("GetProcTemplate(%q): expected error", name)
+		}
+	}
+
+	if _, err := (ProcTemplate{Command: "x", Timeout: "soon"}).Options("x"); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+}
+
+func TestMergeConfigs_ProcTemplates(t *testing.T) {
+	base := &MuxcodeConfig{ProcTemplates: map[string]ProcTemplate{
+		"dev": {Command: "make dev"},
+		"db":  {Command: "make db"},
+	}}
+	override := &MuxcodeConfig{ProcTemplates: map[string]ProcTemplate{
+		"dev": {Command: "npm run dev"},
+	}}
+	merged := mergeConfigs(base, override)
+	if merged.ProcTemplates["dev"].Command != "npm run dev" {
+		t.Errorf("dev = %+v, want override", merged.ProcTemplates["dev"])
+	}
+	if merged.ProcTemplates["db"].Command != "make db" {
+		t.Errorf("db = %+v, want base", merged.ProcTemplates["db"])
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	tests := []struct {
+		entry ProcEntry
+		want  bool
+	}{
+		{ProcEntry{Status: "failed"}, false},
+		{ProcEntry{Status: "failed", Restart: RestartNo}, false},
+		{ProcEntry{Status: "failed", Restart: RestartOnFailure}, true},
+		{ProcEntry{Status: "timeout", Restart: RestartOnFailure}, true},
+		{ProcEntry{Status: "exited", Restart: RestartOnFailure}, false},
+		{ProcEntry{Status: "exited", Restart: RestartAlways}, true},
+		{ProcEntry{Status: "stopped", Restart: RestartAlways}, false},
+		{ProcEntry{Status: "failed", Restart: RestartAlways, Restarts: DefaultMaxRestarts}, false},
+		{ProcEntry{Status: "failed", Restart: RestartAlways, Restarts: 1, MaxRestarts: 2}, true},
+		{ProcEntry{Status: "failed", Restart: RestartAlways, Restarts: 2, MaxRestarts: 2}, false},
+	}
+	for _, tt := range tests {
+		if got := shouldRestart(tt.entry); got != tt.want {
+			t.Errorf("shouldRestart(%s, %s, %d/%d) = %v, want %v",
+				tt.entry.Status, tt.entry.Restart, tt.entry.Restarts, tt.entry.MaxRestarts, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshProcStatus_Restart(t *testing.T) {
+	session := fmt.Sprintf("test-proc-restart-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	_ = Init(session, t.TempDir())
+
+	opts := ProcOptions{
+		Env:         map[string]string{"MUXCODE_TEST_VAR": "hello"},
+		Template:    "flaky",
+		Restart:     RestartOnFailure,
+		MaxRestarts: 1,
+	}
+	first, err := StartProcWithOptions(session, "echo $MUXCODE_TEST_VAR; exit 3", "/tmp", "build", opts)
+	if err != nil {
+		t.Fatalf("StartProcWithOptions: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	completed, err := RefreshProcStatus(session)
+	if err != nil {
+		t.Fatalf("RefreshProcStatus: %v", err)
+	}
+	if len(completed) != 1 || completed[0].RestartedAs == "" {
+		t.Fatalf("completed = %+v, want one restarted entry", completed)
+	}
+	data, _ := os.ReadFile(first.LogFile)
+	if !strings.Contains(string(data), "hello") {
+		t.Errorf("env not passed to process, log = %q", data)
+	}
+
+	second, err := GetProcEntry(session, completed[0].RestartedAs)
+	if err != nil {
+		t.Fatalf("GetProcEntry: %v", err)
+	}
+	if second.Restarts != 1 || second.Template != "flaky" || second.Owner != "build" {
+		t.Errorf("replacement = %+v", second)
+	}
+	stored, _ := GetProcEntry(session, first.ID)
+	if stored.RestartedAs != second.ID {
+		t.Errorf("RestartedAs = %q, want %q", stored.RestartedAs, second.ID)
+	}
+
+	// The replacement exhausts max_restarts and is not restarted again
+	time.Sleep(500 * time.Millisecond)
+	completed, err = RefreshProcStatus(session)
+	if err != nil {
+		t.Fatalf("RefreshProcStatus: %v", err)
+	}
+	if len(completed) != 1 || completed[0].RestartedAs != "" {
+		t.Fatalf("completed = %+v, want one entry without restart", completed)
+	}
+	entries, _ := ReadProcEntries(session)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+}
+
+func TestFormatProcTemplates(t *testing.T) {
+	out := FormatProcTemplates(nil)
+	if !strings.Contains(out, "No proc templates") {
+		t.Errorf("empty output = %q", out)
+	}
+
+	out = FormatProcTemplates(map[string]ProcTemplate{
+		"dev-server": {Command: "npm run dev", Dir: "web", Owner: "runner", Restart: RestartAlways},
+		"db":         {Command: "docker compose up db"},
+	})
+	if strings.Index(out, "db") > strings.Index(out, "dev-server") {
+		t.Error("templates not sorted by name")
+	}
+	for _, want := range []string{"(web) npm run dev", "runner", "always", "no"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -5,16 +5,85 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
-// MuxcodeConfig holds tool profiles, event chains, auto-CC, and send policy config.
+// MuxcodeConfig holds tool profiles, event chains, auto-CC, send policy, SLA,
+// sandbox, guard, custom role, policy, notify, file routing, and heartbeat
+// config.
 type MuxcodeConfig struct {
-	SharedTools  map[string][]string     `json:"shared_tools"`
-	ToolProfiles map[string]ToolProfile  `json:"tool_profiles"`
-	EventChains  map[string]EventChain   `json:"event_chains"`
-	AutoCC       []string                `json:"auto_cc"`
-	SendPolicy   map[string]SendPolicy   `json:"send_policy,omitempty"`
+	SharedTools   map[string][]string      `json:"shared_tools"`
+	ToolProfiles  map[string]ToolProfile   `json:"tool_profiles"`
+	EventChains   map[string]EventChain    `json:"event_chains"`
+	AutoCC        []string                 `json:"auto_cc"`
+	SendPolicy    map[string]SendPolicy    `json:"send_policy,omitempty"`
+	SLA           []SLARule                `json:"sla,omitempty"`
+	Sandbox       map[string]SandboxPolicy `json:"sandbox,omitempty"`
+	Guard         GuardConfig              `json:"guard,omitempty"`
+	ProcTemplates map[string]ProcTemplate  `json:"proc_templates,omitempty"`
+	SpawnQuota    SpawnQuota               `json:"spawn_quota,omitempty"`
+	Webhook       WebhookSettings          `json:"webhook,omitempty"`
+	Dashboard     DashboardConfig          `json:"dashboard,omitempty"`
+	Roles         []RoleDef                `json:"roles,omitempty"`
+	Policy        PolicyConfig             `json:"policy,omitempty"`
+	Notify        NotifyConfig             `json:"notify,omitempty"`
+	Inbox         InboxConfig              `json:"inbox,omitempty"`
+	Ollama        OllamaSettings           `json:"ollama,omitempty"`
+	Context       ContextConfig            `json:"context,omitempty"`
+	Routing       []RouteRule              `json:"routing,omitempty"`
+	Heartbeat     HeartbeatConfig          `json:"heartbeat,omitempty"`
+	Redaction     RedactionConfig          `json:"redaction,omitempty"`
+	Watcher       WatcherConfig            `json:"watcher,omitempty"`
+	Digest        DigestConfig             `json:"digest,omitempty"`
+	Encryption    EncryptionConfig         `json:"encryption,omitempty"`
+	Transport     TransportConfig          `json:"transport,omitempty"`
+	Coverage      CoverageConfig           `json:"coverage,omitempty"`
+	Intake        IntakeConfig             `json:"intake,omitempty"`
+	Deploy        DeployConfig             `json:"deploy,omitempty"`
+	CostGuard     CostGuardConfig          `json:"cost_guard,omitempty"`
+}
+
+// GuardConfig tunes loop detection. Top-level limits apply to every role;
+// Roles overrides them per role, field by field.
+type GuardConfig struct {
+	GuardLimits
+	Roles       map[string]GuardLimits `json:"roles,omitempty"`
+	Patterns    []GuardPattern         `json:"patterns,omitempty"`
+	Remediation map[string][]string    `json:"remediation,omitempty"`  // alert type (or "*") -> remediation actions
+	BudgetPause bool                   `json:"budget_pause,omitempty"` // pause the harness loop when a budget is exceeded
+}
+
+// GuardLimits holds loop detection thresholds. Zero fields use defaults.
+type GuardLimits struct {
+	Similarity       float64 `json:"similarity,omitempty"`        // payload token overlap (0-1) for similar-request loops
+	CommandThreshold int     `json:"command_threshold,omitempty"` // consecutive command failures (default 3)
+	MessageThreshold int     `json:"message_threshold,omitempty"` // repeated messages (default 4)
+	Window           int64   `json:"window_s,omitempty"`          // detection window in seconds (default 300)
+	TokensPerHour    int     `json:"tokens_per_hour,omitempty"`   // harness token budget (0 = unlimited)
+	TurnsPerHour     int     `json:"turns_per_hour,omitempty"`    // harness LLM call budget (0 = unlimited)
+	SlowFactor       float64 `json:"slow_factor,omitempty"`       // runtime vs. median that flags a slow command (default 2)
+}
+
+// GuardPattern is a user-defined regex that raises a custom alert when it
+// matches a role's outgoing payloads or command history often enough.
+type GuardPattern struct {
+	Name      string   `json:"name"`
+	Pattern   string   `json:"pattern"`             // Go regexp
+	Source    string   `json:"source,omitempty"`    // "message" (default) or "command"
+	Roles     []string `json:"roles,omitempty"`     // roles to check (empty = all)
+	Threshold int      `json:"threshold,omitempty"` // matches within the window (default 1)
+	Message   string   `json:"message,omitempty"`   // alert text (default: pattern name)
+}
+
+// SandboxPolicy restricts what the local LLM harness executor may touch for a
+// role. Paths are relative to the working directory unless absolute.
+type SandboxPolicy struct {
+	Jail        string   `json:"jail,omitempty"`         // root dir tools may not escape (default ".")
+	DenyNetwork bool     `json:"deny_network,omitempty"` // block network commands and proxy env
+	ReadOnly    []string `json:"read_only,omitempty"`    // paths writes are refused under
+	MaxOutput   int      `json:"max_output,omitempty"`   // tool output cap in bytes
 }
 
 // SendPolicy defines send restrictions for a role.
@@ -36,6 +105,12 @@ type EventChain struct {
 	OnUnknown       *ChainAction `json:"on_unknown,omitempty"`
 	NotifyAnalyst   bool         `json:"notify_analyst"`
 	NotifyAnalystOn []string     `json:"notify_analyst_on,omitempty"`
+	Steps           []ChainStep  `json:"steps,omitempty"` // extra actions, run after the on_* action
+	// Retries re-requests a failed command from its role this many times,
+	// waiting Backoff (doubling each time), before the failure actions run
+	// (see chainretry.go).
+	Retries int    `json:"retries,omitempty"`
+	Backoff string `json:"backoff,omitempty"`
 }
 
 // ChainAction is a single action in an event chain.
@@ -44,6 +119,27 @@ type ChainAction struct {
 	Action  string `json:"action"`
 	Message string `json:"message"`
 	Type    string `json:"type"`
+	Match   string `json:"match,omitempty"` // regex the command must match (default: any)
+	Delay   string `json:"delay,omitempty"` // Go duration to wait before sending
+	// Type "pr" sends the git agent (send_to, default commit) a request to
+	// run pr create, with Args as its flags (see pr.go).
+	// Plugin runs an external chain action (see plugin.go) instead of
+	// sending a message; Args are passed to it.
+	Plugin string   `json:"plugin,omitempty"`
+	Args   []string `json:"args,omitempty"`
+	// Approval holds this action and the rest of the chain until approved
+	// (see approval.go).
+	Approval *ApprovalGate `json:"approval,omitempty"`
+	// Promote names the environment this action deploys to: it and the
+	// rest of the chain run only once the environment before it is
+	// verified at HEAD (see deploy.go).
+	Promote string `json:"promote,omitempty"`
+}
+
+// ChainStep is an additional chain action for one outcome ("*" for any).
+type ChainStep struct {
+	On string `json:"on"`
+	ChainAction
 }
 
 // configSingleton is the lazy-loaded config (single-goroutine safe).
@@ -67,7 +163,7 @@ func SetConfig(cfg *MuxcodeConfig) {
 	autoCCCache = nil
 }
 
-// LoadConfig resolves config from project > user > defaults.
+// LoadConfig resolves config from session template > project > user > defaults.
 func LoadConfig() (*MuxcodeConfig, error) {
 	paths := []string{
 		filepath.Join(".muxcode", "muxcode.json"),
@@ -93,6 +189,15 @@ func LoadConfig() (*MuxcodeConfig, error) {
 		}
 	}
 
+	// A session template's overlay takes priority over both files
+	if overlay := loadSessionConfig(BusSession()); overlay != nil {
+		if loaded == nil {
+			loaded = overlay
+		} else {
+			loaded = mergeConfigs(loaded, overlay)
+		}
+	}
+
 	if loaded == nil {
 		return DefaultConfig(), nil
 	}
@@ -114,10 +219,12 @@ func configDir() string {
 // Override values replace base values at the role/key level.
 func mergeConfigs(base, override *MuxcodeConfig) *MuxcodeConfig {
 	result := &MuxcodeConfig{
-		SharedTools:  make(map[string][]string),
-		ToolProfiles: make(map[string]ToolProfile),
-		EventChains:  make(map[string]EventChain),
-		SendPolicy:   make(map[string]SendPolicy),
+		SharedTools:   make(map[string][]string),
+		ToolProfiles:  make(map[string]ToolProfile),
+		EventChains:   make(map[string]EventChain),
+		SendPolicy:    make(map[string]SendPolicy),
+		Sandbox:       make(map[string]SandboxPolicy),
+		ProcTemplates: make(map[string]ProcTemplate),
 	}
 
 	// Copy base shared tools
@@ -163,12 +270,280 @@ func mergeConfigs(base, override *MuxcodeConfig) *MuxcodeConfig {
 		result.SendPolicy[k] = v
 	}
 
+	// SLA rules: override replaces entirely if present
+	if len(override.SLA) > 0 {
+		result.SLA = override.SLA
+	} else {
+		result.SLA = base.SLA
+	}
+
+	// Copy base sandbox policies
+	for k, v := range base.Sandbox {
+		result.Sandbox[k] = v
+	}
+	// Override sandbox policies (entire policy replaced per role)
+	for k, v := range override.Sandbox {
+		result.Sandbox[k] = v
+	}
+
+	// Guard: override fields replace base when set; per-role limits are
+	// replaced per role; patterns replace entirely if present
+	result.Guard.GuardLimits = mergeGuardLimits(base.Guard.GuardLimits, override.Guard.GuardLimits)
+	result.Guard.Roles = make(map[string]GuardLimits)
+	for k, v := range base.Guard.Roles {
+		result.Guard.Roles[k] = v
+	}
+	for k, v := range override.Guard.Roles {
+		result.Guard.Roles[k] = v
+	}
+	result.Guard.Patterns = base.Guard.Patterns
+	if len(override.Guard.Patterns) > 0 {
+		result.Guard.Patterns = override.Guard.Patterns
+	}
+	result.Guard.BudgetPause = base.Guard.BudgetPause || override.Guard.BudgetPause
+	result.Guard.Remediation = make(map[string][]string)
+	for k, v := range base.Guard.Remediation {
+		result.Guard.Remediation[k] = v
+	}
+	for k, v := range override.Guard.Remediation {
+		result.Guard.Remediation[k] = v
+	}
+
+	// Copy base proc templates
+	for k, v := range base.ProcTemplates {
+		result.ProcTemplates[k] = v
+	}
+	// Override proc templates (entire template replaced per name)
+	for k, v := range override.ProcTemplates {
+		result.ProcTemplates[k] = v
+	}
+
+	// Spawn quota: override caps replace base when set; per-role caps are
+	// replaced per role
+	result.SpawnQuota = base.SpawnQuota
+	if override.SpawnQuota.MaxConcurrent > 0 {
+		result.SpawnQuota.MaxConcurrent = override.SpawnQuota.MaxConcurrent
+	}
+	if override.SpawnQuota.MaxPerRole > 0 {
+		result.SpawnQuota.MaxPerRole = override.SpawnQuota.MaxPerRole
+	}
+	if override.SpawnQuota.MaxPerOwner > 0 {
+		result.SpawnQuota.MaxPerOwner = override.SpawnQuota.MaxPerOwner
+	}
+	result.SpawnQuota.Roles = make(map[string]int)
+	for k, v := range base.SpawnQuota.Roles {
+		result.SpawnQuota.Roles[k] = v
+	}
+	for k, v := range override.SpawnQuota.Roles {
+		result.SpawnQuota.Roles[k] = v
+	}
+
+	// Webhook routes (entire route replaced per name)
+	result.Webhook.Routes = make(map[string]WebhookRoute)
+	for k, v := range base.Webhook.Routes {
+		result.Webhook.Routes[k] = v
+	}
+	for k, v := range override.Webhook.Routes {
+		result.Webhook.Routes[k] = v
+	}
+	// Slack bridge replaced entirely if present
+	result.Webhook.Slack = base.Webhook.Slack
+	if override.Webhook.Slack.Enabled() || len(override.Webhook.Slack.Users) > 0 || override.Webhook.Slack.SigningSecretEnv != "" {
+		result.Webhook.Slack = override.Webhook.Slack
+	}
+
+	// Dashboard: override fields replace base when set
+	result.Dashboard = mergeDashboard(base.Dashboard, override.Dashboard)
+
+	// Custom roles: merged by name, override entry replaces base entry
+	result.Roles = mergeRoleDefs(base.Roles, override.Roles)
+
+	// Policy rules: override rules are evaluated first
+	result.Policy = mergePolicy(base.Policy, override.Policy)
+
+	// Notify policies (entire policy replaced per role); desktop rules
+	// replace entirely if present
+	result.Notify.Roles = make(map[string]NotifyPolicy)
+	for k, v := range base.Notify.Roles {
+		result.Notify.Roles[k] = v
+	}
+	for k, v := range override.Notify.Roles {
+		result.Notify.Roles[k] = v
+	}
+	result.Notify.BatchWindow = base.Notify.BatchWindow
+	if override.Notify.BatchWindow > 0 {
+		result.Notify.BatchWindow = override.Notify.BatchWindow
+	}
+	result.Notify.Desktop = base.Notify.Desktop
+	if len(override.Notify.Desktop) > 0 {
+		result.Notify.Desktop = override.Notify.Desktop
+	}
+
+	// Inbox archive limits: override fields replace base when set
+	result.Inbox = mergeInbox(base.Inbox, override.Inbox)
+
+	// Ollama nodes (entire node list replaced per model) and VRAM thresholds
+	result.Ollama.Nodes = make(map[string][]string)
+	for k, v := range base.Ollama.Nodes {
+		result.Ollama.Nodes[k] = v
+	}
+	for k, v := range override.Ollama.Nodes {
+		result.Ollama.Nodes[k] = v
+	}
+	if override.Ollama.VRAMLimitMB > 0 {
+		result.Ollama.VRAMLimitMB = override.Ollama.VRAMLimitMB
+	}
+	if override.Ollama.VRAMPressurePct > 0 {
+		result.Ollama.VRAMPressurePct = override.Ollama.VRAMPressurePct
+	}
+
+	// Context budgets: override fields replace base when set; per-role
+	// budgets are merged the same way
+	result.Context.ContextBudget = mergeContextBudget(base.Context.ContextBudget, override.Context.ContextBudget)
+	result.Context.Roles = make(map[string]ContextBudget)
+	for k, v := range base.Context.Roles {
+		result.Context.Roles[k] = v
+	}
+	for k, v := range override.Context.Roles {
+		result.Context.Roles[k] = mergeContextBudget(result.Context.Roles[k], v)
+	}
+
+	// File routing rules: override replaces entirely if present
+	result.Routing = base.Routing
+	if len(override.Routing) > 0 {
+		result.Routing = override.Routing
+	}
+
+	// Heartbeat: override fields replace base when set
+	result.Heartbeat = base.Heartbeat
+	if override.Heartbeat.StaleSecs > 0 {
+		result.Heartbeat.StaleSecs = override.Heartbeat.StaleSecs
+	}
+	if override.Heartbeat.Relaunch {
+		result.Heartbeat.Relaunch = true
+	}
+	if override.Heartbeat.MaxRelaunches > 0 {
+		result.Heartbeat.MaxRelaunches = override.Heartbeat.MaxRelaunches
+	}
+	if len(override.Heartbeat.Failover) > 0 {
+		result.Heartbeat.Failover = override.Heartbeat.Failover
+	}
+	if override.Heartbeat.FailoverAfterSecs > 0 {
+		result.Heartbeat.FailoverAfterSecs = override.Heartbeat.FailoverAfterSecs
+	}
+
+	// Redaction: either level can disable it; disabled built-ins and extra
+	// patterns accumulate, override patterns first
+	result.Redaction = RedactionConfig{
+		Disabled: base.Redaction.Disabled || override.Redaction.Disabled,
+		Disable:  append(append([]string(nil), base.Redaction.Disable...), override.Redaction.Disable...),
+		Patterns: append(append([]RedactionPattern(nil), override.Redaction.Patterns...), base.Redaction.Patterns...),
+	}
+
+	// Watcher: disabled checks accumulate; override intervals replace base
+	// ones per check
+	result.Watcher.Disable = append(append([]string(nil), base.Watcher.Disable...), override.Watcher.Disable...)
+	if len(base.Watcher.Intervals) > 0 || len(override.Watcher.Intervals) > 0 {
+		result.Watcher.Intervals = make(map[string]string)
+		for k, v := range base.Watcher.Intervals {
+			result.Watcher.Intervals[k] = v
+		}
+		for k, v := range override.Watcher.Intervals {
+			result.Watcher.Intervals[k] = v
+		}
+	}
+
+	// Digest: replaced entirely if the override configures a mail server
+	// or recipients
+	result.Digest = base.Digest
+	if override.Digest.SMTP.Host != "" || len(override.Digest.To) > 0 {
+		result.Digest = override.Digest
+	}
+
+	// Encryption: either level can enable it; an override key name wins
+	result.Encryption = EncryptionConfig{
+		Enabled: base.Encryption.Enabled || override.Encryption.Enabled,
+		KeyName: base.Encryption.KeyName,
+	}
+	if override.Encryption.KeyName != "" {
+		result.Encryption.KeyName = override.Encryption.KeyName
+	}
+
+	// Transport: replaced entirely if the override picks a backend
+	result.Transport = base.Transport
+	if override.Transport.Backend != "" {
+		result.Transport = override.Transport
+	}
+
+	// Coverage: override floor replaces base when set; role floors merge
+	result.Coverage.Floor = base.Coverage.Floor
+	if override.Coverage.Floor > 0 {
+		result.Coverage.Floor = override.Coverage.Floor
+	}
+	if len(base.Coverage.Roles) > 0 || len(override.Coverage.Roles) > 0 {
+		result.Coverage.Roles = make(map[string]float64)
+		for k, v := range base.Coverage.Roles {
+			result.Coverage.Roles[k] = v
+		}
+		for k, v := range override.Coverage.Roles {
+			result.Coverage.Roles[k] = v
+		}
+	}
+
+	// Intake: replaced entirely if the override lists sources
+	result.Intake = base.Intake
+	if len(override.Intake.Sources) > 0 {
+		result.Intake = override.Intake
+	}
+
+	// Deploy: the override's environment list replaces the base's
+	result.Deploy = base.Deploy
+	if len(override.Deploy.Environments) > 0 {
+		result.Deploy = override.Deploy
+	}
+
+	// Cost guard: replaced entirely if the override enables it or lists rules
+	result.CostGuard = base.CostGuard
+	if override.CostGuard.Enabled || len(override.CostGuard.Rules) > 0 {
+		result.CostGuard = override.CostGuard
+	}
+
 	return result
 }
 
+// mergeGuardLimits returns base with every non-zero field of override applied.
+func mergeGuardLimits(base, override GuardLimits) GuardLimits {
+	if override.Similarity > 0 {
+		base.Similarity = override.Similarity
+	}
+	if override.CommandThreshold > 0 {
+		base.CommandThreshold = override.CommandThreshold
+	}
+	if override.MessageThreshold > 0 {
+		base.MessageThreshold = override.MessageThreshold
+	}
+	if override.Window > 0 {
+		base.Window = override.Window
+	}
+	if override.TokensPerHour > 0 {
+		base.TokensPerHour = override.TokensPerHour
+	}
+	if override.TurnsPerHour > 0 {
+		base.TurnsPerHour = override.TurnsPerHour
+	}
+	if override.SlowFactor > 0 {
+		base.SlowFactor = override.SlowFactor
+	}
+	return base
+}
+
 // resolveRoleAlias maps window-name roles to their canonical tool profile names.
 // Window names (commit, analyze, run) differ from profile keys (git, analyst, runner).
+// A custom role's declared profile takes priority.
 func resolveRoleAlias(role string) string {
+	if d, ok := ConfiguredRole(role); ok && d.Profile != "" {
+		return d.Profile
+	}
 	switch role {
 	case "commit":
 		return "git"
@@ -191,15 +566,42 @@ func ResolveTools(role string) []string {
 	return resolveProfile(cfg, profile)
 }
 
+// ResolveSandbox returns the sandbox policy for a role, or nil if the role
+// has none configured (sandboxing is opt-in).
+func ResolveSandbox(role string) *SandboxPolicy {
+	policy, ok := Config().Sandbox[resolveRoleAlias(role)]
+	if !ok {
+		return nil
+	}
+	return &policy
+}
+
 // resolveProfile expands includes, tools, and cd-prefix variants.
 func resolveProfile(cfg *MuxcodeConfig, profile ToolProfile) []string {
-	seen := make(map[string]bool)
 	var tools []string
+	for _, e := range resolveProfileEntries(cfg, profile) {
+		tools = append(tools, e.Tool)
+	}
+	return tools
+}
+
+// ToolEntry is one resolved tool pattern and where in the profile it came
+// from: "shared_tools.<group>", "tools", or "cd_prefix".
+type ToolEntry struct {
+	Tool   string `json:"tool"`
+	Source string `json:"source"`
+}
+
+// resolveProfileEntries is resolveProfile, keeping each pattern's source.
+// A pattern listed twice keeps its first source.
+func resolveProfileEntries(cfg *MuxcodeConfig, profile ToolProfile) []ToolEntry {
+	seen := make(map[string]bool)
+	var entries []ToolEntry
 
-	add := func(t string) {
+	add := func(t, source string) {
 		if !seen[t] {
 			seen[t] = true
-			tools = append(tools, t)
+			entries = append(entries, ToolEntry{Tool: t, Source: source})
 		}
 	}
 
@@ -207,22 +609,22 @@ func resolveProfile(cfg *MuxcodeConfig, profile ToolProfile) []string {
 	for _, groupName := range profile.Include {
 		if group, ok := cfg.SharedTools[groupName]; ok {
 			for _, t := range group {
-				add(t)
+				add(t, "shared_tools."+groupName)
 			}
 		}
 	}
 
 	// Add direct tools
 	for _, t := range profile.Tools {
-		add(t)
+		add(t, "tools")
 		if profile.CdPrefix {
 			if cd := expandCdPrefix(t); cd != "" {
-				add(cd)
+				add(cd, "cd_prefix")
 			}
 		}
 	}
 
-	return tools
+	return entries
 }
 
 // expandCdPrefix generates a "Bash(cd * && ...)" variant of a Bash tool pattern.
@@ -257,6 +659,48 @@ func ResolveChain(eventType, outcome string) *ChainAction {
 	return nil
 }
 
+// ResolveChainSteps returns the chain actions for an event type, outcome,
+// and command, in execution order: the on_<outcome> action followed by the
+// matching steps. Actions whose match pattern rejects the command (or does
+// not compile) are skipped, and pr actions become their git agent request.
+func ResolveChainSteps(eventType, outcome, command string) []ChainAction {
+	chain, ok := Config().EventChains[eventType]
+	if !ok {
+		return nil
+	}
+	var actions []ChainAction
+	if a := ResolveChain(eventType, outcome); a != nil && a.Matches(command) {
+		actions = append(actions, a.prRequest())
+	}
+	for _, s := range chain.Steps {
+		if (s.On == outcome || s.On == "*") && s.Matches(command) {
+			actions = append(actions, s.prRequest())
+		}
+	}
+	return actions
+}
+
+// Matches reports whether the action applies to a command.
+func (a ChainAction) Matches(command string) bool {
+	if a.Match == "" {
+		return true
+	}
+	re, err := regexp.Compile(a.Match)
+	return err == nil && re.MatchString(command)
+}
+
+// DelayDuration returns the parsed delay, or 0 when unset or invalid.
+func (a ChainAction) DelayDuration() time.Duration {
+	if a.Delay == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(a.Delay)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
 // ChainNotifyAnalyst returns whether the chain should notify the analyst.
 // Deprecated: Use ChainShouldNotifyAnalyst for outcome-conditional checks.
 func ChainNotifyAnalyst(eventType string) bool {
@@ -370,6 +814,7 @@ func DefaultConfig() *MuxcodeConfig {
 					"Bash(./build.sh*)", "Bash(make*)",
 					"Bash(pnpm run build*)", "Bash(pnpm build*)", "Bash(npm run build*)",
 					"Bash(npx *)", "Bash(go build*)", "Bash(cargo build*)",
+					"Bash(pnpm --filter *)", "Bash(yarn workspace *)", "Bash(bazel build*)",
 					"Bash(gofmt*)", "Bash(go vet*)",
 					"Bash(npx eslint*)", "Bash(npx prettier*)",
 					"Bash(ruff*)", "Bash(black*)",
@@ -393,6 +838,7 @@ func DefaultConfig() *MuxcodeConfig {
 					"Bash(pnpm test*)", "Bash(pnpm run test*)",
 					"Bash(npm test*)", "Bash(npm run test*)",
 					"Bash(pytest*)", "Bash(python -m pytest*)", "Bash(cargo test*)",
+					"Bash(pnpm --filter *)", "Bash(yarn workspace *)", "Bash(bazel test*)",
 					"Bash(go tool cover*)", "Bash(go mod *)",
 					"Bash(npx c8*)", "Bash(nyc *)", "Bash(coverage*)",
 					"Bash(python -m coverage*)", "Bash(tox *)",
@@ -616,5 +1062,8 @@ func DefaultConfig() *MuxcodeConfig {
 			"build": {Deny: []string{"test"}},
 			"test":  {Deny: []string{"review"}},
 		},
+		Notify: NotifyConfig{Roles: map[string]NotifyPolicy{
+			"edit": {Mode: NotifyPassive},
+		}},
 	}
 }
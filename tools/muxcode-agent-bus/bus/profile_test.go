@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig_HasAllRoles(t *testing.T) {
@@ -490,6 +492,43 @@ func TestResolveChain_NoChain(t *testing.T) {
 	}
 }
 
+func TestResolveChainSteps(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EventChains["test"] = EventChain{
+		OnSuccess: &ChainAction{SendTo: "review", Action: "review", Type: "request", Match: `^go test`},
+		Steps: []ChainStep{
+			{On: "success", ChainAction: ChainAction{SendTo: "commit", Action: "commit", Type: "request", Delay: "30s"}},
+			{On: "*", ChainAction: ChainAction{SendTo: "analyze", Action: "notify", Type: "event"}},
+			{On: "failure", ChainAction: ChainAction{SendTo: "edit", Action: "notify", Type: "event"}},
+			{On: "success", ChainAction: ChainAction{SendTo: "docs", Action: "update", Type: "request", Match: `(`}},
+		},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	steps := ResolveChainSteps("test", "success", "go test ./...")
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.SendTo)
+	}
+	if strings.Join(got, ",") != "review,commit,analyze" {
+		t.Errorf("steps = %v, want [review commit analyze]", got)
+	}
+	if d := steps[1].DelayDuration(); d != 30*time.Second {
+		t.Errorf("delay = %v, want 30s", d)
+	}
+
+	// Lint runs skip the on_success action but still run unconditioned steps
+	steps = ResolveChainSteps("test", "success", "golangci-lint run")
+	if len(steps) != 2 || steps[0].SendTo != "commit" {
+		t.Errorf("lint steps = %+v, want commit, analyze", steps)
+	}
+
+	if steps := ResolveChainSteps("nope", "success", ""); steps != nil {
+		t.Errorf("expected no steps for unknown event, got %+v", steps)
+	}
+}
+
 func TestChainNotifyAnalyst_LegacyFallback(t *testing.T) {
 	// Legacy config using NotifyAnalyst bool (no NotifyAnalystOn)
 	cfg := &MuxcodeConfig{
@@ -690,6 +729,69 @@ func TestCheckSendPolicy_NilPolicy(t *testing.T) {
 	}
 }
 
+func TestResolveSandbox(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sandbox = map[string]SandboxPolicy{
+		"git": {DenyNetwork: true, ReadOnly: []string{".git/hooks"}, MaxOutput: 4096},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	// Window name "commit" resolves to the "git" profile
+	p := ResolveSandbox("commit")
+	if p == nil {
+		t.Fatal("expected sandbox policy for commit")
+	}
+	if !p.DenyNetwork || p.MaxOutput != 4096 || len(p.ReadOnly) != 1 {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+
+	if ResolveSandbox("build") != nil {
+		t.Error("expected nil policy for build (opt-in)")
+	}
+}
+
+func TestMergeConfigs_Sandbox(t *testing.T) {
+	base := &MuxcodeConfig{Sandbox: map[string]SandboxPolicy{
+		"build": {MaxOutput: 100},
+		"test":  {DenyNetwork: true},
+	}}
+	override := &MuxcodeConfig{Sandbox: map[string]SandboxPolicy{
+		"build": {Jail: "src"},
+	}}
+
+	result := mergeConfigs(base, override)
+	if got := result.Sandbox["build"]; got.Jail != "src" || got.MaxOutput != 0 {
+		t.Errorf("build sandbox not replaced: %+v", got)
+	}
+	if !result.Sandbox["test"].DenyNetwork {
+		t.Error("test sandbox not preserved from base")
+	}
+}
+
+func TestMergeConfigs_Guard(t *testing.T) {
+	base := &MuxcodeConfig{Guard: GuardConfig{
+		GuardLimits: GuardLimits{Similarity: 0.7, Window: 600},
+		Roles:       map[string]GuardLimits{"build": {CommandThreshold: 5}},
+		Patterns:    []GuardPattern{{Name: "base"}},
+	}}
+	override := &MuxcodeConfig{Guard: GuardConfig{
+		GuardLimits: GuardLimits{Window: 120},
+		Roles:       map[string]GuardLimits{"test": {MessageThreshold: 6}},
+	}}
+
+	result := mergeConfigs(base, override)
+	if result.Guard.Similarity != 0.7 || result.Guard.Window != 120 {
+		t.Errorf("guard limits = %+v", result.Guard.GuardLimits)
+	}
+	if result.Guard.Roles["build"].CommandThreshold != 5 || result.Guard.Roles["test"].MessageThreshold != 6 {
+		t.Errorf("guard roles = %+v", result.Guard.Roles)
+	}
+	if len(result.Guard.Patterns) != 1 || result.Guard.Patterns[0].Name != "base" {
+		t.Errorf("patterns not preserved from base: %+v", result.Guard.Patterns)
+	}
+}
+
 // helpers
 
 func assertContains(t *testing.T, tools []string, want string) {
@@ -2,12 +2,26 @@ package bus
 
 import (
 	"fmt"
+	"os"
 	"strings"
 )
 
 // SharedPrompt generates the common Agent Coordination system prompt for a role.
 // This replaces the duplicated markdown section across all agent files.
+// A prompt template for the role (see PromptTemplatePath) replaces the
+// built-in prompt; a template that fails to render falls back to it.
 func SharedPrompt(role string) string {
+	out, err := RenderPrompt(BusSession(), role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  [prompt] %v — using the built-in prompt\n", err)
+		return builtinPrompt(role)
+	}
+	return out
+}
+
+// builtinPrompt is the default coordination prompt, available to templates
+// as {{.Builtin}}.
+func builtinPrompt(role string) string {
 	var b strings.Builder
 
 	b.WriteString("## Agent Coordination\n\n")
@@ -20,7 +34,7 @@ func SharedPrompt(role string) string {
 	// Send Messages
 	b.WriteString("### Send Messages\n")
 	b.WriteString("```bash\nmuxcode-agent-bus send <target> <action> \"<short single-line message>\"\n```\n")
-	b.WriteString("Targets: edit, build, test, review, deploy, run, commit, analyze, docs, research, watch, pr-read\n\n")
+	b.WriteString("Targets: " + strings.Join(promptTargets(), ", ") + "\n\n")
 	b.WriteString("**CRITICAL: All `send` messages MUST be short, single-line strings with NO newlines.** ")
 	b.WriteString("The `Bash(muxcode-agent-bus *)` permission glob does NOT match newlines — ")
 	b.WriteString("any multi-line command will trigger a permission prompt and block the agent.\n\n")
@@ -28,7 +42,8 @@ func SharedPrompt(role string) string {
 	// Memory
 	b.WriteString("### Memory\n")
 	b.WriteString("```bash\nmuxcode-agent-bus memory context          # read shared + own memory\n")
-	b.WriteString("muxcode-agent-bus memory write \"<section>\" \"<text>\"  # save learnings\n```\n\n")
+	b.WriteString("muxcode-agent-bus memory write \"<section>\" \"<text>\"  # save learnings\n")
+	b.WriteString("muxcode-agent-bus scratch write \"<section>\" \"<text>\" # stash intermediate plans (this session only)\n```\n\n")
 
 	// Skills
 	b.WriteString("### Skills\n")
@@ -51,6 +66,9 @@ func SharedPrompt(role string) string {
 	b.WriteString("- Save important learnings to memory after completing tasks\n")
 	b.WriteString("- Never wait for human input — process all requests autonomously\n\n")
 
+	// Instructions declared for the role in muxcode.json
+	b.WriteString(rolePrompt(role))
+
 	// Send restrictions from policy
 	cfg := Config()
 	if cfg.SendPolicy != nil {
@@ -65,3 +83,15 @@ func SharedPrompt(role string) string {
 
 	return b.String()
 }
+
+// promptTargets returns the roles agents can send to: the built-in roles
+// followed by roles declared in muxcode.json.
+func promptTargets() []string {
+	targets := []string{"edit", "build", "test", "review", "deploy", "run", "commit", "analyze", "docs", "research", "watch", "pr-read"}
+	for _, r := range ConfiguredRoleNames() {
+		if !containsRole(targets, r) {
+			targets = append(targets, r)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,113 @@
+package bus
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// promptMemoryHighlights is how many recent memory entries templates see.
+const promptMemoryHighlights = 5
+
+// PromptData is the data passed to prompt templates.
+type PromptData struct {
+	Session      string        `json:"session"`
+	Role         string        `json:"role"`
+	ProjectTypes []string      `json:"project_types"` // detected in the working directory
+	Tools        []string      `json:"tools"`         // the role's resolved tool patterns
+	Targets      []string      `json:"targets"`       // roles agents can send to
+	Memory       []MemoryEntry `json:"memory"`        // latest shared and role memory entries, oldest first
+	Builtin      string        `json:"-"`             // the built-in coordination prompt
+}
+
+// PromptsDir returns the project-local prompt template directory.
+// Uses BUS_PROMPTS_DIR env if set, otherwise defaults to ".muxcode/prompts".
+func PromptsDir() string {
+	if v := os.Getenv("BUS_PROMPTS_DIR"); v != "" {
+		return v
+	}
+	return filepath.Join(".muxcode", "prompts")
+}
+
+// UserPromptsDir returns the user-level prompt template directory.
+func UserPromptsDir() string {
+	return filepath.Join(configDir(), "prompts")
+}
+
+// PromptTemplatePath returns the template used for a role, or "" for the
+// built-in prompt. Lookup order: <role>.tmpl in the project then user
+// prompt directory, then default.tmpl in the same order.
+func PromptTemplatePath(role string) string {
+	for _, name := range []string{role + ".tmpl", "default.tmpl"} {
+		for _, dir := range []string{PromptsDir(), UserPromptsDir()} {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// BuildPromptData collects the template variables for a role.
+func BuildPromptData(session, role string) PromptData {
+	d := PromptData{
+		Session: session,
+		Role:    role,
+		Tools:   ResolveTools(role),
+		Targets: promptTargets(),
+		Builtin: builtinPrompt(role),
+	}
+	for _, pt := range DetectProject(".") {
+		d.ProjectTypes = append(d.ProjectTypes, pt.Name)
+	}
+	for _, r := range []string{"shared", role} {
+		if content, err := ReadMemory(r); err == nil {
+			d.Memory = append(d.Memory, ParseMemoryEntries(content, r)...)
+		}
+	}
+	if len(d.Memory) > promptMemoryHighlights {
+		d.Memory = d.Memory[len(d.Memory)-promptMemoryHighlights:]
+	}
+	return d
+}
+
+// promptFuncs are the helper functions available to prompt templates.
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"has": func(list []string, s string) bool {
+		return containsRole(list, s)
+	},
+}
+
+// RenderPrompt returns a role's coordination prompt: its template rendered
+// with BuildPromptData, or the built-in prompt when no template exists.
+func RenderPrompt(session, role string) (string, error) {
+	path := PromptTemplatePath(role)
+	if path == "" {
+		return builtinPrompt(role), nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return ExecutePromptTemplate(filepath.Base(path), string(src), BuildPromptData(session, role))
+}
+
+// ExecutePromptTemplate parses and renders one prompt template.
+func ExecutePromptTemplate(name, src string, data PromptData) (string, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("prompt template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("prompt template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// promptEnv points the prompt and memory directories at temp dirs.
+func promptEnv(t *testing.T) (project, user string) {
+	t.Helper()
+	project = t.TempDir()
+	cfgDir := t.TempDir()
+	t.Setenv("BUS_PROMPTS_DIR", project)
+	t.Setenv("MUXCODE_CONFIG_DIR", cfgDir)
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	SetConfig(DefaultConfig())
+	t.Cleanup(func() { SetConfig(nil) })
+	user = filepath.Join(cfgDir, "prompts")
+	if err := os.MkdirAll(user, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return project, user
+}
+
+func writeTemplate(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderPrompt_NoTemplate(t *testing.T) {
+	promptEnv(t)
+	out, err := RenderPrompt("s", "edit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != builtinPrompt("edit") {
+		t.Error("without a template, RenderPrompt should return the built-in prompt")
+	}
+}
+
+func TestPromptTemplatePath_Order(t *testing.T) {
+	project, user := promptEnv(t)
+
+	if got := PromptTemplatePath("build"); got != "" {
+		t.Errorf("no templates: path = %q", got)
+	}
+	writeTemplate(t, user, "default.tmpl", "user default")
+	if got := PromptTemplatePath("build"); got != filepath.Join(user, "default.tmpl") {
+		t.Errorf("path = %q, want user default", got)
+	}
+	writeTemplate(t, project, "default.tmpl", "project default")
+	if got := PromptTemplatePath("build"); got != filepath.Join(project, "default.tmpl") {
+		t.Errorf("path = %q, want project default", got)
+	}
+	writeTemplate(t, user, "build.tmpl", "user build")
+	if got := PromptTemplatePath("build"); got != filepath.Join(user, "build.tmpl") {
+		t.Errorf("path = %q, want user role template over any default", got)
+	}
+	writeTemplate(t, project, "build.tmpl", "project build")
+	if got := PromptTemplatePath("build"); got != filepath.Join(project, "build.tmpl") {
+		t.Errorf("path = %q, want project role template", got)
+	}
+}
+
+func TestRenderPrompt_Variables(t *testing.T) {
+	project, _ := promptEnv(t)
+	if err := AppendMemory("Deploys", "Use the staging profile", "build"); err != nil {
+		t.Fatal(err)
+	}
+	writeTemplate(t, project, "build.tmpl",
+		`# {{upper .Role}} in {{.Session}}
+Tools: {{join .Tools ", "}}
+{{if has .Targets "test"}}Notify test when done.{{end}}
+{{range .Memory}}- {{.Section}}
+{{end}}{{.Builtin}}`)
+
+	out, err := RenderPrompt("proj", "build")
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	for _, want := range []string{"# BUILD in proj", "Tools: ", "Notify test when done.", "- Deploys", "## Agent Coordination"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildPromptData_MemoryHighlights(t *testing.T) {
+	promptEnv(t)
+	for i := 0; i < promptMemoryHighlights+2; i++ {
+		if err := AppendMemory(string(rune('A'+i)), "note", "edit"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := BuildPromptData("s", "edit")
+	if len(d.Memory) != promptMemoryHighlights {
+		t.Fatalf("memory entries = %d, want %d", len(d.Memory), promptMemoryHighlights)
+	}
+	if last := d.Memory[len(d.Memory)-1].Section; last != "G" {
+		t.Errorf("last highlight = %q, want the newest entry G", last)
+	}
+}
+
+func TestRenderPrompt_Errors(t *testing.T) {
+	project, _ := promptEnv(t)
+
+	writeTemplate(t, project, "edit.tmpl", "{{.Role")
+	if _, err := RenderPrompt("s", "edit"); err == nil || !strings.Contains(err.Error(), "edit.tmpl") {
+		t.Errorf("parse error = %v, want it to name the template", err)
+	}
+	writeTemplate(t, project, "edit.tmpl", "{{.NoSuchField}}")
+	if _, err := RenderPrompt("s", "edit"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	// SharedPrompt falls back to the built-in prompt
+	if got := SharedPrompt("edit"); got != builtinPrompt("edit") {
+		t.Error("SharedPrompt should fall back to the built-in prompt on template errors")
+	}
+}
@@ -0,0 +1,426 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PRFixAction is the action of the request pr sync sends for each
+// unresolved review thread.
+const PRFixAction = "pr-fix"
+
+// PR review thread status values.
+const (
+	PRThreadPending  = "pending"  // pr-fix request sent, no reply yet
+	PRThreadFixed    = "fixed"    // the fix agent replied; resolve on GitHub next
+	PRThreadResolved = "resolved" // resolved on GitHub
+)
+
+// prThreadCommentMax caps the comment text quoted in a pr-fix request.
+const prThreadCommentMax = 1500
+
+// ghCommand runs gh and returns its stdout. Tests replace it.
+var ghCommand = func(args ...string) ([]byte, error) {
+	out, err := exec.Command("gh", args...).Output()
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return out, fmt.Errorf("gh %s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+	}
+	return out, err
+}
+
+// PRThread is a GitHub review thread synced into the bus as a pr-fix
+// request. It moves from pending to fixed when the request gets a reply,
+// and to resolved once the thread is resolved on GitHub.
+type PRThread struct {
+	ID         string `json:"id"` // GraphQL review thread node ID
+	Repo       string `json:"repo"`
+	PR         int    `json:"pr"`
+	Path       string `json:"path"`
+	Line       int    `json:"line,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Body       string `json:"body"`
+	URL        string `json:"url,omitempty"`
+	MsgID      string `json:"msg_id"` // the pr-fix request
+	To         string `json:"to"`
+	Status     string `json:"status"`
+	SyncedAt   int64  `json:"synced_at"`
+	FixedAt    int64  `json:"fixed_at,omitempty"`
+	ResolvedAt int64  `json:"resolved_at,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// Location renders the thread's file and line.
+func (t PRThread) Location() string {
+	if t.Line > 0 {
+		return fmt.Sprintf("%s:%d", t.Path, t.Line)
+	}
+	return t.Path
+}
+
+// PRThreadsPath returns the session's synced review thread registry.
+func PRThreadsPath(session string) string {
+	return filepath.Join(BusDir(session), "pr-threads.json")
+}
+
+// lockPRThreads serializes read-modify-write cycles on the registry
+// between pr sync, senders, and the watcher. gh calls run outside the lock.
+func lockPRThreads(session string) func() {
+	return flockBusFile(session, "pr-threads")
+}
+
+// ReadPRThreads reads the synced review threads, oldest first.
+func ReadPRThreads(session string) ([]PRThread, error) {
+	data, err := os.ReadFile(PRThreadsPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var threads []PRThread
+	if err := json.Unmarshal(data, &threads); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", PRThreadsPath(session), err)
+	}
+	return threads, nil
+}
+
+// writePRThreads saves the registry atomically.
+func writePRThreads(session string, threads []PRThread) error {
+	if err := os.MkdirAll(BusDir(session), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(threads, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := PRThreadsPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, PRThreadsPath(session))
+}
+
+// ReviewThread is an unresolved review thread as fetched from GitHub.
+type ReviewThread struct {
+	ID       string
+	Path     string
+	Line     int
+	Outdated bool
+	Comments []ReviewComment
+}
+
+// ReviewComment is one comment in a review thread.
+type ReviewComment struct {
+	Author string
+	Body   string
+	URL    string
+}
+
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100) {
+        nodes {
+          id isResolved isOutdated path line originalLine
+          comments(first: 20) { nodes { author { login } body url } }
+        }
+      }
+    }
+  }
+}`
+
+const resolveThreadMutation = `mutation($id: ID!) {
+  resolveReviewThread(input: {threadId: $id}) { thread { isResolved } }
+}`
+
+// CurrentRepo returns the owner/name of the repository gh resolves for the
+// working directory.
+func CurrentRepo() (string, error) {
+	out, err := ghCommand("repo", "view", "--json", "nameWithOwner", "--jq", ".nameWithOwner")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// FetchReviewThreads returns the unresolved review threads of a pull
+// request (the first 100 threads) via the GitHub GraphQL API.
+func FetchReviewThreads(repo string, pr int) ([]ReviewThread, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repo %q (want owner/name)", repo)
+	}
+	out, err := ghCommand("api", "graphql", "-f", "query="+reviewThreadsQuery,
+		"-F", "owner="+owner, "-F", "name="+name, "-F", "number="+strconv.Itoa(pr))
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data struct {
+			Repository struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							ID           string `json:"id"`
+							IsResolved   bool   `json:"isResolved"`
+							IsOutdated   bool   `json:"isOutdated"`
+							Path         string `json:"path"`
+							Line         int    `json:"line"`
+							OriginalLine int    `json:"originalLine"`
+							Comments     struct {
+								Nodes []struct {
+									Author struct {
+										Login string `json:"login"`
+									} `json:"author"`
+									Body string `json:"body"`
+									URL  string `json:"url"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("parsing review threads: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("fetching review threads: %s", resp.Errors[0].Message)
+	}
+	pull := resp.Data.Repository.PullRequest
+	if pull == nil {
+		return nil, fmt.Errorf("pull request %s#%d not found", repo, pr)
+	}
+	var threads []ReviewThread
+	for _, n := range pull.ReviewThreads.Nodes {
+		if n.IsResolved {
+			continue
+		}
+		t := ReviewThread{ID: n.ID, Path: n.Path, Line: n.Line, Outdated: n.IsOutdated}
+		if t.Line == 0 {
+			t.Line = n.OriginalLine
+		}
+		for _, c := range n.Comments.Nodes {
+			t.Comments = append(t.Comments, ReviewComment{Author: c.Author.Login, Body: c.Body, URL: c.URL})
+		}
+		threads = append(threads, t)
+	}
+	return threads, nil
+}
+
+// resolveReviewThread marks a review thread resolved on GitHub.
+func resolveReviewThread(id string) error {
+	_, err := ghCommand("api", "graphql", "-f", "query="+resolveThreadMutation, "-F", "id="+id)
+	return err
+}
+
+// prFixPayload renders a review thread as a pr-fix request: where the
+// comment is, who wrote it, the comment, and any replies.
+func prFixPayload(pr int, t ReviewThread) string {
+	loc := t.Path
+	if t.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", t.Path, t.Line)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "PR #%d review comment on %s", pr, loc)
+	if t.Outdated {
+		b.WriteString(" (outdated)")
+	}
+	for i, c := range t.Comments {
+		if i == 0 {
+			fmt.Fprintf(&b, " by %s: %s", c.Author, c.Body)
+		} else {
+			fmt.Fprintf(&b, " | reply by %s: %s", c.Author, c.Body)
+		}
+	}
+	s := strings.Join(strings.Fields(b.String()), " ")
+	if len(s) > prThreadCommentMax {
+		s = s[:prThreadCommentMax] + "..."
+	}
+	return s + " — fix it, then reply to this request (--type response --reply-to <id>) to resolve the thread"
+}
+
+// PRSyncResult reports what SyncPRThreads did.
+type PRSyncResult struct {
+	Sent     []PRThread `json:"sent"`     // new pr-fix requests
+	Resolved []PRThread `json:"resolved"` // threads resolved on GitHub
+	Skipped  int        `json:"skipped"`  // unresolved threads already synced
+}
+
+// SyncPRThreads sends a pr-fix request to `to` for each unresolved review
+// thread not synced before, after resolving the threads whose fixes were
+// reported. Threads resolved on GitHub by hand are marked resolved. With
+// dryRun it fetches and reports the new threads but sends nothing.
+func SyncPRThreads(session, from, to, repo string, pr int, dryRun bool, now time.Time) (PRSyncResult, error) {
+	var res PRSyncResult
+	if !dryRun {
+		resolved, err := ResolveFixedPRThreads(session, now)
+		res.Resolved = resolved
+		if err != nil {
+			return res, err
+		}
+	}
+	fetched, err := FetchReviewThreads(repo, pr)
+	if err != nil {
+		return res, err
+	}
+	open := make(map[string]bool, len(fetched))
+	for _, t := range fetched {
+		open[t.ID] = true
+	}
+
+	unlock := lockPRThreads(session)
+	threads, err := ReadPRThreads(session)
+	if err != nil {
+		unlock()
+		return res, err
+	}
+	known := make(map[string]bool, len(threads))
+	for i := range threads {
+		t := &threads[i]
+		known[t.ID] = true
+		if t.Repo == repo && t.PR == pr && t.Status != PRThreadResolved && !open[t.ID] && !dryRun {
+			t.Status = PRThreadResolved
+			t.ResolvedAt = now.Unix()
+		}
+	}
+	var msgs []Message
+	for _, rt := range fetched {
+		if known[rt.ID] {
+			res.Skipped++
+			continue
+		}
+		msg := NewMessage(from, to, "request", PRFixAction, prFixPayload(pr, rt), "")
+		t := PRThread{
+			ID: rt.ID, Repo: repo, PR: pr, Path: rt.Path, Line: rt.Line,
+			MsgID: msg.ID, To: to, Status: PRThreadPending, SyncedAt: now.Unix(),
+		}
+		if len(rt.Comments) > 0 {
+			t.Author, t.Body, t.URL = rt.Comments[0].Author, rt.Comments[0].Body, rt.Comments[0].URL
+		}
+		res.Sent = append(res.Sent, t)
+		threads = append(threads, t)
+		msgs = append(msgs, msg)
+	}
+	if !dryRun {
+		err = writePRThreads(session, threads)
+	}
+	unlock()
+	if err != nil || dryRun {
+		return res, err
+	}
+
+	// Sent outside the lock: Send marks replies fixed under it
+	for _, m := range msgs {
+		if err := Send(session, m); err != nil {
+			return res, err
+		}
+	}
+	if len(msgs) > 0 {
+		_ = Notify(session, to)
+	}
+	return res, nil
+}
+
+// markPRThreadsFixed records m as the fix report for any pending thread
+// whose pr-fix request it replies to. Cheap when nothing was synced.
+func markPRThreadsFixed(session string, m Message) error {
+	if m.ReplyTo == "" {
+		return nil
+	}
+	if _, err := os.Stat(PRThreadsPath(session)); err != nil {
+		return nil
+	}
+	unlock := lockPRThreads(session)
+	defer unlock()
+
+	threads, err := ReadPRThreads(session)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range threads {
+		if threads[i].Status == PRThreadPending && threads[i].MsgID == m.ReplyTo {
+			threads[i].Status = PRThreadFixed
+			threads[i].FixedAt = m.TS
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writePRThreads(session, threads)
+}
+
+// ResolveFixedPRThreads resolves on GitHub the threads whose fixes were
+// reported and returns them. A thread that fails to resolve stays fixed
+// with the error recorded, and is retried on the next call.
+func ResolveFixedPRThreads(session string, now time.Time) ([]PRThread, error) {
+	threads, err := ReadPRThreads(session)
+	if err != nil {
+		return nil, err
+	}
+	outcome := make(map[string]error)
+	for _, t := range threads {
+		if t.Status == PRThreadFixed {
+			outcome[t.ID] = resolveReviewThread(t.ID)
+		}
+	}
+	if len(outcome) == 0 {
+		return nil, nil
+	}
+
+	unlock := lockPRThreads(session)
+	defer unlock()
+	threads, err = ReadPRThreads(session)
+	if err != nil {
+		return nil, err
+	}
+	var resolved []PRThread
+	for i := range threads {
+		t := &threads[i]
+		rerr, ok := outcome[t.ID]
+		if !ok || t.Status != PRThreadFixed {
+			continue
+		}
+		if rerr != nil {
+			t.LastError = rerr.Error()
+			continue
+		}
+		t.Status = PRThreadResolved
+		t.ResolvedAt = now.Unix()
+		t.LastError = ""
+		resolved = append(resolved, *t)
+	}
+	return resolved, writePRThreads(session, threads)
+}
+
+// FormatPRThreads renders the synced threads, one per line.
+func FormatPRThreads(threads []PRThread) string {
+	if len(threads) == 0 {
+		return "No PR review threads synced.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-9s %-6s %-30s %-12s %s\n", "STATUS", "PR", "LOCATION", "AUTHOR", "COMMENT")
+	for _, t := range threads {
+		comment := strings.Join(strings.Fields(t.Body), " ")
+		if len(comment) > 60 {
+			comment = comment[:57] + "..."
+		}
+		if t.LastError != "" {
+			comment += " [" + t.LastError + "]"
+		}
+		fmt.Fprintf(&b, "%-9s #%-5d %-30s %-12s %s\n", t.Status, t.PR, t.Location(), t.Author, comment)
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGitHub stubs ghCommand with review threads for one pull request and
+// records resolved thread IDs.
+type fakeGitHub struct {
+	threads  []map[string]any
+	resolved []string
+	fail     bool
+}
+
+func (f *fakeGitHub) install(t *testing.T) {
+	orig := ghCommand
+	ghCommand = func(args ...string) ([]byte, error) {
+		if len(args) < 3 || args[0] != "api" || args[1] != "graphql" {
+			return nil, fmt.Errorf("unexpected gh %v", args)
+		}
+		if strings.Contains(args[3], "resolveReviewThread") {
+			if f.fail {
+				return nil, fmt.Errorf("gh api: forbidden")
+			}
+			id := strings.TrimPrefix(args[len(args)-1], "id=")
+			f.resolved = append(f.resolved, id)
+			for _, th := range f.threads {
+				if th["id"] == id {
+					th["isResolved"] = true
+				}
+			}
+			return []byte(`{"data":{}}`), nil
+		}
+		resp := map[string]any{"data": map[string]any{"repository": map[string]any{"pullRequest": map[string]any{
+			"reviewThreads": map[string]any{"nodes": f.threads},
+		}}}}
+		return json.Marshal(resp)
+	}
+	t.Cleanup(func() { ghCommand = orig })
+}
+
+func reviewThread(id, path string, line int, author, body string) map[string]any {
+	return map[string]any{
+		"id": id, "isResolved": false, "path": path, "line": line,
+		"comments": map[string]any{"nodes": []map[string]any{
+			{"author": map[string]any{"login": author}, "body": body, "url": "https://github.com/o/r/pull/7#" + id},
+		}},
+	}
+}
+
+func TestSyncPRThreads(t *testing.T) {
+	session := testSession(t)
+	gh := &fakeGitHub{threads: []map[string]any{
+		reviewThread("T1", "bus/inbox.go", 42, "alice", "Check the error\nhere"),
+		reviewThread("T2", "README.md", 0, "bob", "Typo"),
+	}}
+	gh.threads[1]["originalLine"] = 3
+	gh.install(t)
+	now := time.Now()
+
+	// Dry run sends and records nothing
+	res, err := SyncPRThreads(session, "commit", "edit", "o/r", 7, true, now)
+	if err != nil || len(res.Sent) != 2 {
+		t.Fatalf("dry run = %+v, %v", res, err)
+	}
+	if threads, _ := ReadPRThreads(session); len(threads) != 0 {
+		t.Errorf("dry run recorded %d threads", len(threads))
+	}
+
+	res, err = SyncPRThreads(session, "commit", "edit", "o/r", 7, false, now)
+	if err != nil || len(res.Sent) != 2 || res.Skipped != 0 {
+		t.Fatalf("sync = %+v, %v", res, err)
+	}
+	msgs, _ := Peek(session, "edit")
+	if len(msgs) != 2 || msgs[0].Action != PRFixAction || msgs[0].Type != "request" ||
+		!strings.HasPrefix(msgs[0].Payload, "PR #7 review comment on bus/inbox.go:42 by alice: Check the error here — fix it") {
+		t.Fatalf("edit inbox = %+v", msgs)
+	}
+	if !strings.Contains(msgs[1].Payload, "README.md:3 by bob: Typo") {
+		t.Errorf("originalLine payload = %q", msgs[1].Payload)
+	}
+
+	// A second sync skips threads already sent
+	res, err = SyncPRThreads(session, "commit", "edit", "o/r", 7, false, now)
+	if err != nil || len(res.Sent) != 0 || res.Skipped != 2 {
+		t.Errorf("resync = %+v, %v", res, err)
+	}
+
+	// The fix agent's reply marks the thread fixed; resolving it goes to GitHub
+	reply := NewMessage("edit", "commit", "response", PRFixAction, "Fixed", msgs[0].ID)
+	if err := Send(session, reply); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ := ReadPRThreads(session)
+	if threads[0].Status != PRThreadFixed || threads[1].Status != PRThreadPending {
+		t.Fatalf("after reply: %+v", threads)
+	}
+	resolved, err := ResolveFixedPRThreads(session, now)
+	if err != nil || len(resolved) != 1 || resolved[0].ID != "T1" || len(gh.resolved) != 1 {
+		t.Fatalf("ResolveFixedPRThreads = %+v, %v (gh resolved %v)", resolved, err, gh.resolved)
+	}
+
+	// A thread resolved by hand on GitHub is marked resolved on the next sync
+	gh.threads[1]["isResolved"] = true
+	if _, err := SyncPRThreads(session, "commit", "edit", "o/r", 7, false, now); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ = ReadPRThreads(session)
+	for _, th := range threads {
+		if th.Status != PRThreadResolved {
+			t.Errorf("thread %s status = %s", th.ID, th.Status)
+		}
+	}
+	if out := FormatPRThreads(threads); !strings.Contains(out, "resolved  #7     bus/inbox.go:42") {
+		t.Errorf("FormatPRThreads:\n%s", out)
+	}
+}
+
+func TestResolveFixedPRThreads_Error(t *testing.T) {
+	session := testSession(t)
+	gh := &fakeGitHub{fail: true}
+	gh.install(t)
+	if err := writePRThreads(session, []PRThread{{ID: "T1", PR: 7, Path: "a.go", MsgID: "m1", Status: PRThreadFixed}}); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := ResolveFixedPRThreads(session, time.Now())
+	if err != nil || len(resolved) != 0 {
+		t.Fatalf("ResolveFixedPRThreads = %+v, %v", resolved, err)
+	}
+	threads, _ := ReadPRThreads(session)
+	if threads[0].Status != PRThreadFixed || !strings.Contains(threads[0].LastError, "forbidden") {
+		t.Errorf("failed resolve = %+v", threads[0])
+	}
+	if _, err := FetchReviewThreads("nope", 1); err == nil {
+		t.Error("invalid repo: want error")
+	}
+}
@@ -0,0 +1,169 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReadCursor records the last message a role has read. Messages after it in
+// the inbox are unread; if it is no longer in the inbox (consumed), every
+// inbox message is unread.
+type ReadCursor struct {
+	ID     string `json:"id"`
+	TS     int64  `json:"ts"`      // timestamp of the message
+	ReadAt int64  `json:"read_at"` // when it was read
+}
+
+// ReadReceipt records that a role read a message.
+type ReadReceipt struct {
+	ID     string `json:"id"`
+	From   string `json:"from"`
+	Role   string `json:"role"`
+	Action string `json:"action"`
+	ReadAt int64  `json:"read_at"`
+}
+
+// CursorPath returns the read cursor file for a role.
+func CursorPath(session, role string) string {
+	return filepath.Join(BusDir(session), "cursor", role+".json")
+}
+
+// ReceiptsPath returns the session's read receipt log.
+func ReceiptsPath(session string) string {
+	return filepath.Join(BusDir(session), "receipts.jsonl")
+}
+
+// GetCursor returns a role's read cursor.
+func GetCursor(session, role string) (ReadCursor, bool) {
+	var c ReadCursor
+	data, err := os.ReadFile(CursorPath(session, role))
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return ReadCursor{}, false
+	}
+	return c, true
+}
+
+// MarkRead advances a role's cursor to the last of msgs and records a
+// receipt for each. Receive and ReceiveFrom call it for consumed messages.
+func MarkRead(session, role string, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	var buf []byte
+	for _, m := range msgs {
+		data, err := json.Marshal(ReadReceipt{ID: m.ID, From: m.From, Role: role, Action: m.Action, ReadAt: now})
+		if err != nil {
+			continue
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+	if err := appendToFile(ReceiptsPath(session), buf); err != nil {
+		return err
+	}
+
+	last := msgs[len(msgs)-1]
+	data, err := json.Marshal(ReadCursor{ID: last.ID, TS: last.TS, ReadAt: now})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(CursorPath(session, role)), 0755); err != nil {
+		return err
+	}
+	tmp := CursorPath(session, role) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, CursorPath(session, role))
+}
+
+// unreadFrom returns the messages after the cursor.
+func unreadFrom(msgs []Message, c ReadCursor, ok bool) []Message {
+	if !ok {
+		return msgs
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].ID == c.ID {
+			return msgs[i+1:]
+		}
+	}
+	return msgs
+}
+
+// Unread returns a role's unread inbox messages without consuming them.
+func Unread(session, role string) ([]Message, error) {
+	msgs, err := Peek(session, role)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := GetCursor(session, role)
+	return unreadFrom(msgs, c, ok), nil
+}
+
+// UnreadCount returns the number of unread messages in a role's inbox.
+func UnreadCount(session, role string) int {
+	msgs, _ := Unread(session, role)
+	return len(msgs)
+}
+
+// MarkReadUpTo marks unread inbox messages as read, without consuming them,
+// up to and including id ("" for all). Returns the number marked.
+func MarkReadUpTo(session, role, id string) (int, error) {
+	unread, err := Unread(session, role)
+	if err != nil {
+		return 0, err
+	}
+	if id != "" {
+		end := -1
+		for i, m := range unread {
+			if m.ID == id {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return 0, fmt.Errorf("no unread message %s in %s's inbox", id, role)
+		}
+		unread = unread[:end+1]
+	}
+	return len(unread), MarkRead(session, role, unread)
+}
+
+// ReadReceipts returns receipts for messages sent by from ("" for all),
+// oldest first, at most limit (0 for all).
+func ReadReceipts(session, from string, limit int) []ReadReceipt {
+	data, err := os.ReadFile(ReceiptsPath(session))
+	if err != nil {
+		return nil
+	}
+	var out []ReadReceipt
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var r ReadReceipt
+		if json.Unmarshal(scanner.Bytes(), &r) != nil {
+			continue
+		}
+		if from == "" || r.From == from {
+			out = append(out, r)
+		}
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
+
+// FormatReadReceipts renders receipts one per line.
+func FormatReadReceipts(receipts []ReadReceipt) string {
+	var b bytes.Buffer
+	for _, r := range receipts {
+		fmt.Fprintf(&b, "%s  %-10s read %s (%s from %s)\n",
+			time.Unix(r.ReadAt, 0).Format("15:04:05"), r.Role, r.ID, r.Action, r.From)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package bus
+
+import (
+	"testing"
+)
+
+func TestReceiveMarksRead(t *testing.T) {
+	session := testSession(t)
+	m1 := NewMessage("edit", "build", "request", "build", "one", "")
+	m2 := NewMessage("test", "build", "request", "rebuild", "two", "")
+	for _, m := range []Message{m1, m2} {
+		if err := SendNoCC(session, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := UnreadCount(session, "build"); n != 2 {
+		t.Fatalf("expected 2 unread, got %d", n)
+	}
+
+	if _, err := ReceiveFrom(session, "build", "edit"); err != nil {
+		t.Fatal(err)
+	}
+	c, ok := GetCursor(session, "build")
+	if !ok || c.ID != m1.ID {
+		t.Errorf("cursor = %+v, want %s", c, m1.ID)
+	}
+	// The consumed cursor message is gone, so the rest are unread
+	if n := UnreadCount(session, "build"); n != 1 {
+		t.Errorf("expected 1 unread after ReceiveFrom, got %d", n)
+	}
+
+	if _, err := Receive(session, "build"); err != nil {
+		t.Fatal(err)
+	}
+	receipts := ReadReceipts(session, "", 0)
+	if len(receipts) != 2 || receipts[0].ID != m1.ID || receipts[1].ID != m2.ID || receipts[1].Role != "build" {
+		t.Errorf("unexpected receipts: %+v", receipts)
+	}
+	if got := ReadReceipts(session, "test", 0); len(got) != 1 || got[0].ID != m2.ID {
+		t.Errorf("receipts from test = %+v", got)
+	}
+}
+
+func TestMarkReadUpTo(t *testing.T) {
+	session := testSession(t)
+	var sent []Message
+	for _, p := range []string{"a", "b", "c"} {
+		m := NewMessage("edit", "review", "request", "review", p, "")
+		if err := SendNoCC(session, m); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, m)
+	}
+
+	n, err := MarkReadUpTo(session, "review", sent[1].ID)
+	if err != nil || n != 2 {
+		t.Fatalf("MarkReadUpTo = %d, %v", n, err)
+	}
+	unread, _ := Unread(session, "review")
+	if len(unread) != 1 || unread[0].ID != sent[2].ID {
+		t.Errorf("expected only the last message unread, got %+v", unread)
+	}
+	if InboxCount(session, "review") != 3 {
+		t.Error("mark-read must not consume messages")
+	}
+
+	if _, err := MarkReadUpTo(session, "review", sent[0].ID); err == nil {
+		t.Error("expected error marking an already-read message")
+	}
+
+	if n, _ := MarkReadUpTo(session, "review", ""); n != 1 {
+		t.Errorf("expected 1 newly marked, got %d", n)
+	}
+	if UnreadCount(session, "review") != 0 {
+		t.Error("expected no unread messages")
+	}
+
+	// New arrivals after the cursor are unread
+	if err := SendNoCC(session, NewMessage("edit", "review", "request", "review", "d", "")); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetAgentStatus(session, "review"); got.InboxCount != 4 || got.Unread != 1 {
+		t.Errorf("status inbox/unread = %d/%d, want 4/1", got.InboxCount, got.Unread)
+	}
+}
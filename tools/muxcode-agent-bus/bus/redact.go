@@ -0,0 +1,252 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedactionConfig controls scrubbing of secrets from message payloads,
+// command history, proc logs, and memory. The built-in patterns apply
+// unless disabled; Patterns adds project-specific ones.
+type RedactionConfig struct {
+	Disabled bool               `json:"disabled,omitempty"` // turn redaction off entirely
+	Disable  []string           `json:"disable,omitempty"`  // built-in pattern names to skip ("*" for all)
+	Patterns []RedactionPattern `json:"patterns,omitempty"`
+}
+
+// RedactionPattern is a named regexp whose matches are replaced with
+// "[REDACTED:<name>]".
+type RedactionPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // Go regexp
+}
+
+// BuiltinRedactionPatterns are applied unless disabled by name.
+var BuiltinRedactionPatterns = []RedactionPattern{
+	{Name: "private-key", Pattern: `-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`},
+	{Name: "aws-access-key", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-key", Pattern: `(?i)aws_secret_access_key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`},
+	{Name: "bearer-token", Pattern: `(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`},
+	{Name: "github-token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+}
+
+// Validate reports patterns without a name or with an invalid regexp.
+func (c RedactionConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, p := range c.Patterns {
+		if p.Name == "" {
+			return fmt.Errorf("pattern %d: name is required", i)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("pattern %d: duplicate name %q", i, p.Name)
+		}
+		seen[p.Name] = true
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("pattern %s: invalid regexp: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// compiledRedaction is a compiled redaction pattern.
+type compiledRedaction struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var (
+	redactMu     sync.Mutex
+	redactCfg    *MuxcodeConfig
+	redactCached []compiledRedaction
+)
+
+// redactionPatterns returns the compiled patterns for the current config,
+// recompiling when the config changes. Invalid user patterns are skipped
+// (config validate reports them).
+func redactionPatterns() []compiledRedaction {
+	cfg := Config()
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	if cfg == redactCfg && redactCached != nil {
+		return redactCached
+	}
+	rc := cfg.Redaction
+	out := []compiledRedaction{}
+	if !rc.Disabled {
+		skip := make(map[string]bool)
+		for _, n := range rc.Disable {
+			skip[n] = true
+		}
+		if !skip["*"] {
+			for _, p := range BuiltinRedactionPatterns {
+				if !skip[p.Name] {
+					out = append(out, compiledRedaction{p.Name, regexp.MustCompile(p.Pattern)})
+				}
+			}
+		}
+		for _, p := range rc.Patterns {
+			if re, err := regexp.Compile(p.Pattern); err == nil && p.Name != "" {
+				out = append(out, compiledRedaction{p.Name, re})
+			}
+		}
+	}
+	redactCfg, redactCached = cfg, out
+	return out
+}
+
+// Redact replaces secrets in s and returns the scrubbed text with the
+// number of matches per pattern name.
+func Redact(s string) (string, map[string]int) {
+	var counts map[string]int
+	for _, p := range redactionPatterns() {
+		n := 0
+		s = p.re.ReplaceAllStringFunc(s, func(string) string {
+			n++
+			return "[REDACTED:" + p.name + "]"
+		})
+		if n > 0 {
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[p.name] += n
+		}
+	}
+	return s, counts
+}
+
+// Redaction records what was scrubbed; the secret itself is never stored.
+type Redaction struct {
+	TS      int64  `json:"ts"`
+	Where   string `json:"where"` // "message", "history", "proc", "memory", "scratch"
+	Ref     string `json:"ref"`   // message ID, role, or proc ID
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// RedactionLogPath returns the redaction log for a session.
+func RedactionLogPath(session string) string {
+	return filepath.Join(BusDir(session), "redactions.jsonl")
+}
+
+// redactAndLog scrubs s and records any matches in the session's
+// redaction log.
+func redactAndLog(session, where, ref, s string) string {
+	out, counts := Redact(s)
+	if len(counts) == 0 {
+		return s
+	}
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var buf []byte
+	now := time.Now().Unix()
+	for _, n := range names {
+		data, err := json.Marshal(Redaction{TS: now, Where: where, Ref: ref, Pattern: n, Count: counts[n]})
+		if err == nil {
+			buf = append(append(buf, data...), '\n')
+		}
+	}
+	if err := os.MkdirAll(BusDir(session), 0755); err == nil {
+		_ = appendToFile(RedactionLogPath(session), buf)
+	}
+	return out
+}
+
+// RedactHistoryEntry scrubs the string fields of a JSON history entry.
+// Entries without secrets are returned unchanged.
+func RedactHistoryEntry(session, role string, data []byte) []byte {
+	if _, counts := Redact(string(data)); len(counts) == 0 {
+		return data
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return data
+	}
+	for k, raw := range fields {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			continue
+		}
+		if red := redactAndLog(session, "history", role, s); red != s {
+			fields[k], _ = json.Marshal(red)
+		}
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// redactFile scrubs a file in place. Used for proc logs once the process
+// has exited.
+func redactFile(session, where, ref, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if red := redactAndLog(session, where, ref, string(data)); red != string(data) {
+		_ = os.WriteFile(path, []byte(red), 0644)
+	}
+}
+
+// ReadRedactionLog returns redactions at or after since, oldest first.
+func ReadRedactionLog(session string, since int64) ([]Redaction, error) {
+	f, err := os.Open(RedactionLogPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Redaction
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Redaction
+		if json.Unmarshal(scanner.Bytes(), &r) == nil && r.TS >= since {
+			out = append(out, r)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// FormatRedactionReport summarizes redactions by location and pattern,
+// followed by each redaction.
+func FormatRedactionReport(rs []Redaction) string {
+	if len(rs) == 0 {
+		return "Nothing redacted.\n"
+	}
+	totals := make(map[string]int)
+	for _, r := range rs {
+		totals[r.Where+"\x00"+r.Pattern] += r.Count
+	}
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("Redactions by location and pattern:\n")
+	for _, k := range keys {
+		parts := strings.SplitN(k, "\x00", 2)
+		fmt.Fprintf(&b, "  %-8s %-20s %d\n", parts[0], parts[1], totals[k])
+	}
+	b.WriteString("\nEvents:\n")
+	for _, r := range rs {
+		fmt.Fprintf(&b, "  %s  %-8s %-20s x%d  %s\n", time.Unix(r.TS, 0).Format("2006-01-02 15:04:05"), r.Where, r.Pattern, r.Count, r.Ref)
+	}
+	return b.String()
+}
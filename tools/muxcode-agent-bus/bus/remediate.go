@@ -0,0 +1,146 @@
+package bus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Remediation actions the watcher can take when a guard alert fires.
+const (
+	RemediateLock      = "lock"       // lock the looping role so nothing new is dispatched
+	RemediateInterrupt = "interrupt"  // send a stop-and-summarize request to the role
+	RemediatePauseCron = "pause-cron" // disable the role's enabled cron entries
+	RemediateKillProc  = "kill-proc"  // stop the role's running background procs
+)
+
+// GuardStopAction is the action of the stop-and-summarize message.
+const GuardStopAction = "guard-stop"
+
+// RemediationResult records the outcome of one remediation action.
+type RemediationResult struct {
+	Action string
+	Detail string
+	Err    error
+}
+
+// ValidRemediation reports whether action is a known remediation action.
+func ValidRemediation(action string) bool {
+	switch action {
+	case RemediateLock, RemediateInterrupt, RemediatePauseCron, RemediateKillProc:
+		return true
+	}
+	return false
+}
+
+// RemediationActions returns the configured actions for an alert type,
+// falling back to the "*" entry. Slow command alerts only get actions
+// configured for "slow", since a slow run is not a loop. Unknown actions
+// are dropped.
+func RemediationActions(alertType string) []string {
+	cfg := Config().Guard.Remediation
+	actions, ok := cfg[alertType]
+	if !ok && alertType != "slow" {
+		actions = cfg["*"]
+	}
+	var valid []string
+	for _, a := range actions {
+		if ValidRemediation(a) {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+// Remediate applies the configured remediation actions for an alert to the
+// looping role. Each action is attempted independently.
+func Remediate(session string, alert LoopAlert) []RemediationResult {
+	var results []RemediationResult
+	for _, action := range RemediationActions(alert.Type) {
+		r := RemediationResult{Action: action}
+		switch action {
+		case RemediateLock:
+			r.Err = Lock(session, alert.Role)
+			r.Detail = "locked " + alert.Role
+		case RemediateInterrupt:
+			r.Detail, r.Err = interruptRole(session, alert)
+		case RemediatePauseCron:
+			r.Detail, r.Err = pauseRoleCron(session, alert.Role)
+		case RemediateKillProc:
+			r.Detail, r.Err = stopRoleProcs(session, alert.Role)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// interruptRole asks the role to stop retrying and summarize.
+func interruptRole(session string, alert LoopAlert) (string, error) {
+	payload := fmt.Sprintf("Guard detected a loop (%s). Stop retrying. Summarize what you tried, what failed, and what you need, then wait for instructions.", alert.Message)
+	msg := NewMessage("watcher", alert.Role, "request", GuardStopAction, payload, "")
+	if err := Send(session, msg); err != nil {
+		return "", err
+	}
+	_ = Notify(session, alert.Role)
+	return "sent stop-and-summarize to " + alert.Role, nil
+}
+
+// pauseRoleCron disables every enabled cron entry targeting role.
+func pauseRoleCron(session, role string) (string, error) {
+	entries, err := ReadCronEntries(session)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for i, e := range entries {
+		if e.Target == role && e.Enabled {
+			entries[i].Enabled = false
+			ids = append(ids, e.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return "no cron entries for " + role, nil
+	}
+	if err := WriteCronEntries(session, entries); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("paused cron %s", strings.Join(ids, ", ")), nil
+}
+
+// stopRoleProcs stops every running proc owned by role.
+func stopRoleProcs(session, role string) (string, error) {
+	entries, err := ReadProcEntries(session)
+	if err != nil {
+		return "", err
+	}
+	var stopped []string
+	var firstErr error
+	for _, e := range entries {
+		if e.Owner != role || e.Status != "running" {
+			continue
+		}
+		if err := StopProc(session, e.ID); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		stopped = append(stopped, e.ID)
+	}
+	if len(stopped) == 0 && firstErr == nil {
+		return "no running procs for " + role, nil
+	}
+	return fmt.Sprintf("stopped proc %s", strings.Join(stopped, ", ")), firstErr
+}
+
+// FormatRemediation summarizes remediation results in one line.
+func FormatRemediation(results []RemediationResult) string {
+	var parts []string
+	for _, r := range results {
+		if r.Err != nil {
+			parts = append(parts, fmt.Sprintf("%s failed: %v", r.Action, r.Err))
+			continue
+		}
+		parts = append(parts, r.Detail)
+	}
+	return strings.Join(parts, "; ")
+}
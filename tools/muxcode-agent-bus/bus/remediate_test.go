@@ -0,0 +1,91 @@
+package bus
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRemediationActions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Guard.Remediation = map[string][]string{
+		"command": {RemediateLock, "bogus", RemediateKillProc},
+		"*":       {RemediateInterrupt},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	if got := RemediationActions("command"); strings.Join(got, ",") != "lock,kill-proc" {
+		t.Errorf("command actions = %v", got)
+	}
+	if got := RemediationActions("payload"); strings.Join(got, ",") != "interrupt" {
+		t.Errorf("fallback actions = %v", got)
+	}
+	if got := RemediationActions("slow"); len(got) != 0 {
+		t.Errorf("slow alerts use the fallback: %v", got)
+	}
+
+	cfg.Guard.Remediation = nil
+	if got := RemediationActions("command"); len(got) != 0 {
+		t.Errorf("unconfigured actions = %v", got)
+	}
+}
+
+func TestRemediate(t *testing.T) {
+	session := fmt.Sprintf("test-remediate-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Guard.Remediation = map[string][]string{
+		"command": {RemediateLock, RemediateInterrupt, RemediatePauseCron, RemediateKillProc},
+	}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	buildCron, _ := AddCronEntry(session, CronEntry{Schedule: "@hourly", Target: "build", Action: "build", Message: "x"})
+	testCron, _ := AddCronEntry(session, CronEntry{Schedule: "@hourly", Target: "test", Action: "test", Message: "x"})
+	proc, err := StartProc(session, "sleep 30", "/tmp", "build")
+	if err != nil {
+		t.Fatalf("StartProc: %v", err)
+	}
+
+	alert := LoopAlert{Role: "build", Type: "command", Message: "go build failed 3x in 2m"}
+	results := Remediate(session, alert)
+	if len(results) != 4 {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Action, r.Err)
+		}
+	}
+
+	if !IsLocked(session, "build") {
+		t.Error("build not locked")
+	}
+	msgs, _ := Receive(session, "build")
+	if len(msgs) != 1 || msgs[0].Action != GuardStopAction || !strings.Contains(msgs[0].Payload, "go build failed") {
+		t.Errorf("interrupt message = %+v", msgs)
+	}
+	entries, _ := ReadCronEntries(session)
+	for _, e := range entries {
+		if e.ID == buildCron.ID && e.Enabled {
+			t.Error("build cron not paused")
+		}
+		if e.ID == testCron.ID && !e.Enabled {
+			t.Error("test cron paused")
+		}
+	}
+	if p, _ := GetProcEntry(session, proc.ID); p.Status != "stopped" {
+		t.Errorf("proc status = %s, want stopped", p.Status)
+	}
+
+	summary := FormatRemediation(results)
+	if !strings.Contains(summary, "locked build") || !strings.Contains(summary, "paused cron") {
+		t.Errorf("summary = %q", summary)
+	}
+}
@@ -0,0 +1,101 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RemoteBinEnv names the muxcode-agent-bus binary on remote hosts
+// (default "muxcode-agent-bus", resolved through the remote PATH).
+const RemoteBinEnv = "MUXCODE_REMOTE_BIN"
+
+// RemoteSSHEnv overrides the ssh command used for remote transport, e.g.
+// "ssh -p 2222 -i ~/.ssh/build". Split on whitespace.
+const RemoteSSHEnv = "MUXCODE_SSH"
+
+// Remote is a bus session on another host, reached by running the remote
+// muxcode-agent-bus over SSH (send/inbox --remote user@host:session).
+type Remote struct {
+	Host    string // ssh destination: host, user@host, or an ssh config alias
+	Session string // remote bus session
+}
+
+// String renders the remote as user@host:session.
+func (r Remote) String() string {
+	return r.Host + ":" + r.Session
+}
+
+// ParseRemote parses "user@host:session". The session defaults to the
+// local session name when omitted ("user@host").
+func ParseRemote(spec string) (Remote, error) {
+	spec = strings.TrimSpace(spec)
+	host, session := spec, ""
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		host, session = spec[:i], spec[i+1:]
+	}
+	if host == "" || strings.HasPrefix(host, "-") || strings.ContainsAny(host, " \t/") {
+		return Remote{}, fmt.Errorf("invalid remote %q (want user@host:session)", spec)
+	}
+	if session == "" {
+		session = BusSession()
+	}
+	if strings.ContainsAny(session, " \t/'\"") {
+		return Remote{}, fmt.Errorf("invalid remote session %q", session)
+	}
+	return Remote{Host: host, Session: session}, nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RemoteCommand returns the shell command run on the remote host: the
+// remote binary with BUS_SESSION and AGENT_ROLE set, so the remote bus
+// records the local role as the sender.
+func (r Remote) RemoteCommand(role string, args []string) string {
+	bin := os.Getenv(RemoteBinEnv)
+	if bin == "" {
+		bin = "muxcode-agent-bus"
+	}
+	parts := []string{"env", "BUS_SESSION=" + shellQuote(r.Session), "AGENT_ROLE=" + shellQuote(role), shellQuote(bin)}
+	for _, a := range args {
+		parts = append(parts, shellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// SSHArgs returns the full ssh invocation (program first). BatchMode keeps
+// ssh from prompting, since agents cannot answer password prompts.
+func (r Remote) SSHArgs(role string, args []string) []string {
+	ssh := strings.Fields(os.Getenv(RemoteSSHEnv))
+	if len(ssh) == 0 {
+		ssh = []string{"ssh"}
+	}
+	argv := append(ssh, "-o", "BatchMode=yes", "--", r.Host)
+	return append(argv, r.RemoteCommand(role, args))
+}
+
+// RunRemote runs a muxcode-agent-bus command on the remote session with
+// the local stdin, stdout, and stderr attached, and returns its exit code.
+// An error means ssh itself could not be started.
+func RunRemote(r Remote, role string, args []string) (int, error) {
+	argv := r.SSHArgs(role, args)
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("running ssh to %s: %w", r.Host, err)
+	}
+	return 0, nil
+}
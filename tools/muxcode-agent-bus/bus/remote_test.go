@@ -0,0 +1,85 @@
+package bus
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	t.Setenv("BUS_SESSION", "local")
+	cases := []struct {
+		spec, host, session string
+	}{
+		{"dev@build01:myproj", "dev@build01", "myproj"},
+		{"build01:other", "build01", "other"},
+		{"dev@build01", "dev@build01", "local"},
+		{"dev@build01:", "dev@build01", "local"},
+	}
+	for _, c := range cases {
+		r, err := ParseRemote(c.spec)
+		if err != nil {
+			t.Errorf("ParseRemote(%q): %v", c.spec, err)
+			continue
+		}
+		if r.Host != c.host || r.Session != c.session {
+			t.Errorf("ParseRemote(%q) = %+v", c.spec, r)
+		}
+	}
+	for _, spec := range []string{"", ":proj", "-oProxyCommand=x:proj", "host:a/b", "bad host:proj"} {
+		if _, err := ParseRemote(spec); err == nil {
+			t.Errorf("ParseRemote(%q) = nil error", spec)
+		}
+	}
+}
+
+func TestRemoteSSHArgs(t *testing.T) {
+	t.Setenv(RemoteSSHEnv, "")
+	t.Setenv(RemoteBinEnv, "")
+	r := Remote{Host: "dev@build01", Session: "myproj"}
+
+	got := r.SSHArgs("edit", []string{"send", "build", "compile", "it's ready; run $HOME"})
+	want := []string{"ssh", "-o", "BatchMode=yes", "--", "dev@build01",
+		`env BUS_SESSION=myproj AGENT_ROLE=edit muxcode-agent-bus send build compile 'it'\''s ready; run $HOME'`}
+	if strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("SSHArgs =\n%q\nwant\n%q", got, want)
+	}
+
+	t.Setenv(RemoteSSHEnv, "ssh -p 2222")
+	t.Setenv(RemoteBinEnv, "/opt/muxcode/bin/muxcode-agent-bus")
+	got = r.SSHArgs("edit", []string{"inbox"})
+	if got[1] != "-p" || got[2] != "2222" || !strings.HasSuffix(got[len(got)-1], "/opt/muxcode/bin/muxcode-agent-bus inbox") {
+		t.Errorf("SSHArgs with overrides = %q", got)
+	}
+}
+
+func TestRunRemote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	// A fake ssh that runs the remote command locally, ignoring options
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "fake-ssh")
+	script := "#!" + sh + "\nwhile [ \"$1\" != \"--\" ]; do shift; done\nshift 2\nexec " + sh + " -c \"$1\"\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(RemoteSSHEnv, fake)
+	t.Setenv(RemoteBinEnv, "true")
+
+	r := Remote{Host: "build01", Session: "myproj"}
+	if code, err := RunRemote(r, "edit", []string{"send"}); err != nil || code != 0 {
+		t.Errorf("RunRemote = %d, %v", code, err)
+	}
+	t.Setenv(RemoteBinEnv, "false")
+	if code, err := RunRemote(r, "edit", []string{"send"}); err != nil || code != 1 {
+		t.Errorf("RunRemote failing command = %d, %v; want 1", code, err)
+	}
+	t.Setenv(RemoteSSHEnv, filepath.Join(dir, "missing"))
+	if _, err := RunRemote(r, "edit", nil); err == nil {
+		t.Error("expected error when ssh cannot start")
+	}
+}
@@ -0,0 +1,158 @@
+package bus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RoleDef declares a custom agent role in muxcode.json ("roles"). Declared
+// roles join KnownRoles, so they can receive messages, be cron and
+// subscription targets, and show in status. A builtin name can be declared
+// to change its window, profile, prompt, or model.
+type RoleDef struct {
+	Name    string `json:"name"`
+	Window  string `json:"window,omitempty"`  // tmux window (default: name)
+	Profile string `json:"profile,omitempty"` // tool profile and agent definition (default: name)
+	Prompt  string `json:"prompt,omitempty"`  // extra instructions appended to the shared prompt
+	Model   string `json:"model,omitempty"`   // Ollama model for the local agent
+}
+
+// ValidateRoleDefs checks names and windows and rejects duplicates.
+func ValidateRoleDefs(defs []RoleDef) error {
+	seen := map[string]bool{}
+	for _, d := range defs {
+		if !validRoleName(d.Name) {
+			return fmt.Errorf("invalid role name %q (use lowercase letters, digits, - and _)", d.Name)
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("role %s declared twice", d.Name)
+		}
+		seen[d.Name] = true
+		if d.Window != "" && !validRoleName(d.Window) {
+			return fmt.Errorf("role %s: invalid window %q", d.Name, d.Window)
+		}
+	}
+	return nil
+}
+
+// ConfiguredRole returns the declared definition for a role.
+func ConfiguredRole(role string) (RoleDef, bool) {
+	for _, d := range Config().Roles {
+		if d.Name == role {
+			return d, true
+		}
+	}
+	return RoleDef{}, false
+}
+
+// ConfiguredRoleNames returns the names of valid declared roles, in order.
+func ConfiguredRoleNames() []string {
+	var names []string
+	for _, d := range Config().Roles {
+		if validRoleName(d.Name) && !containsRole(names, d.Name) {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// ConfiguredWindows returns the tmux windows of declared roles that are not
+// builtin, for muxcode.sh to create alongside MUXCODE_WINDOWS.
+func ConfiguredWindows() []string {
+	var windows []string
+	for _, d := range Config().Roles {
+		if containsRole(baseKnownRoles, d.Name) || !validRoleName(d.Name) {
+			continue
+		}
+		if w := RoleWindow(d.Name); !containsRole(windows, w) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// ConfiguredRoleMap returns window=role pairs for declared roles whose
+// window differs from their launcher role, in muxcode.sh ROLE_MAP format.
+func ConfiguredRoleMap() []string {
+	var pairs []string
+	for _, d := range Config().Roles {
+		if !validRoleName(d.Name) {
+			continue
+		}
+		if w, r := RoleWindow(d.Name), launcherRole(d.Name); w != r {
+			pairs = append(pairs, w+"="+r)
+		}
+	}
+	return pairs
+}
+
+// RoleWindow returns the tmux window a role's agent runs in: the declared
+// window, or the role name.
+func RoleWindow(role string) string {
+	if d, ok := ConfiguredRole(role); ok && d.Window != "" {
+		return d.Window
+	}
+	return role
+}
+
+// windowRole maps a tmux window back to the role declared for it, or
+// returns the window name.
+func windowRole(window string) string {
+	for _, d := range Config().Roles {
+		if d.Window == window {
+			return d.Name
+		}
+	}
+	return window
+}
+
+// launcherRole is the role argument passed to the agent launcher: declared
+// roles launch under their own name (the launcher resolves tools and prompt
+// from it); builtin window names map to their profile names.
+func launcherRole(role string) string {
+	if _, ok := ConfiguredRole(role); ok && !containsRole(baseKnownRoles, role) {
+		return role
+	}
+	return resolveRoleAlias(role)
+}
+
+// teamRoles is the configured team: compiled roles plus MUXCODE_ROLES plus
+// roles declared in muxcode.json. Session overlays apply on top of it.
+func teamRoles() []string {
+	roles := append([]string(nil), baseKnownRoles...)
+	for _, r := range ConfiguredRoleNames() {
+		if !containsRole(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// rolePrompt returns the declared prompt section for a role, or "".
+func rolePrompt(role string) string {
+	d, ok := ConfiguredRole(role)
+	if !ok || strings.TrimSpace(d.Prompt) == "" {
+		return ""
+	}
+	return "### Role Instructions\n" + strings.TrimSpace(d.Prompt) + "\n\n"
+}
+
+// mergeRoleDefs returns base with override's roles applied: a role declared
+// in both is replaced by the override entry; new roles are appended.
+func mergeRoleDefs(base, override []RoleDef) []RoleDef {
+	result := append([]RoleDef(nil), base...)
+	for _, o := range override {
+		replaced := false
+		for i := range result {
+			if result[i].Name == o.Name {
+				result[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, o)
+		}
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func customRoleConfig(t *testing.T) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Roles = []RoleDef{
+		{Name: "security", Window: "sec", Profile: "review", Prompt: "Audit every change for secrets.", Model: "llama3.1:8b"},
+		{Name: "docs", Window: "manual"},
+	}
+	SetConfig(cfg)
+	t.Cleanup(func() {
+		SetConfig(nil)
+		KnownRoles = append([]string(nil), baseKnownRoles...)
+	})
+}
+
+func TestConfiguredRoles_JoinKnownRoles(t *testing.T) {
+	customRoleConfig(t)
+	session := testSession(t)
+
+	LoadSessionRoles(session)
+	if !IsKnownRole("security") {
+		t.Error("declared role should be known")
+	}
+	if strings.Join(ConfiguredWindows(), " ") != "sec" {
+		t.Errorf("ConfiguredWindows = %v, want [sec] (builtins excluded)", ConfiguredWindows())
+	}
+	if got := strings.Join(ConfiguredRoleMap(), " "); got != "sec=security manual=docs" {
+		t.Errorf("ConfiguredRoleMap = %q", got)
+	}
+
+	// Re-init keeps declared roles and creates their inboxes
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if !IsKnownRole("security") || InboxCount(session, "security") != 0 {
+		t.Error("declared role should survive init")
+	}
+
+	var found bool
+	for _, r := range ListRoles(session) {
+		if r.Role == "security" {
+			found = r.Source == "config"
+		}
+	}
+	if !found {
+		t.Error("role list should report security from config")
+	}
+}
+
+func TestConfiguredRoles_Resolution(t *testing.T) {
+	customRoleConfig(t)
+	t.Setenv("MUXCODE_SECURITY_MODEL", "")
+
+	if RoleWindow("security") != "sec" || RoleWindow("build") != "build" {
+		t.Error("RoleWindow mismatch")
+	}
+	if windowRole("sec") != "security" || windowRole("build") != "build" {
+		t.Error("windowRole mismatch")
+	}
+	if resolveRoleAlias("security") != "review" || resolveRoleAlias("commit") != "git" {
+		t.Error("declared profile should drive the alias")
+	}
+	if launcherRole("security") != "security" || launcherRole("commit") != "git" {
+		t.Error("launcherRole mismatch")
+	}
+	if len(ResolveTools("security")) == 0 {
+		t.Error("security should get the review tool profile")
+	}
+	if RoleModel("security") != "llama3.1:8b" {
+		t.Errorf("RoleModel = %q", RoleModel("security"))
+	}
+	t.Setenv("MUXCODE_SECURITY_MODEL", "env-model")
+	if RoleModel("security") != "env-model" {
+		t.Error("per-role env should win over declared model")
+	}
+
+	prompt := SharedPrompt("security")
+	if !strings.Contains(prompt, "Audit every change for secrets.") {
+		t.Error("prompt should include declared instructions")
+	}
+	if !strings.Contains(prompt, "pr-read, security") {
+		t.Error("prompt targets should include declared roles")
+	}
+	if strings.Contains(SharedPrompt("build"), "Role Instructions") {
+		t.Error("undeclared roles get no instructions section")
+	}
+}
+
+func TestValidateRoleDefs(t *testing.T) {
+	tests := []struct {
+		defs    []RoleDef
+		wantErr bool
+	}{
+		{[]RoleDef{{Name: "security"}}, false},
+		{[]RoleDef{{Name: "Security"}}, true},
+		{[]RoleDef{{Name: "a"}, {Name: "a"}}, true},
+		{[]RoleDef{{Name: "a", Window: "bad window"}}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRoleDefs(tt.defs); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRoleDefs(%+v) = %v, wantErr %v", tt.defs, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMergeRoleDefs(t *testing.T) {
+	got := mergeRoleDefs(
+		[]RoleDef{{Name: "a", Model: "m1"}, {Name: "b"}},
+		[]RoleDef{{Name: "a", Model: "m2"}, {Name: "c"}},
+	)
+	if len(got) != 3 || got[0].Model != "m2" || got[2].Name != "c" {
+		t.Errorf("merge = %+v", got)
+	}
+}
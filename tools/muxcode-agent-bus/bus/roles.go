@@ -0,0 +1,314 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionRoles records runtime changes to a session's agent team made with
+// "role add" and "role remove". It is applied over the configured team
+// (compiled roles, MUXCODE_ROLES, and roles declared in muxcode.json) by
+// LoadSessionRoles.
+type SessionRoles struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// RoleInfo describes one role for "role list".
+type RoleInfo struct {
+	Role   string `json:"role"`
+	Source string `json:"source"` // "builtin", "config", "added", or "removed"
+	Window bool   `json:"window"` // tmux window exists
+	Locked bool   `json:"locked"`
+	Inbox  int    `json:"inbox"`
+}
+
+// baseKnownRoles is KnownRoles before any session overlay is applied,
+// captured in init.
+var baseKnownRoles []string
+
+// validRoleName rejects names that can't be a tmux window or a bus file name.
+func validRoleName(role string) bool {
+	if role == "" || strings.HasPrefix(role, "-") || IsSpawnRole(role) {
+		return false
+	}
+	for _, r := range role {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadSessionRoles reads the session's role overlay. A missing file is an
+// empty overlay.
+func ReadSessionRoles(session string) (SessionRoles, error) {
+	var sr SessionRoles
+	data, err := os.ReadFile(RolesPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sr, nil
+		}
+		return sr, err
+	}
+	if err := json.Unmarshal(data, &sr); err != nil {
+		return sr, fmt.Errorf("invalid %s: %v", RolesPath(session), err)
+	}
+	return sr, nil
+}
+
+func writeSessionRoles(session string, sr SessionRoles) error {
+	data, err := json.MarshalIndent(sr, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(RolesPath(session), append(data, '\n'), 0644)
+}
+
+// LoadSessionRoles applies the session's role overlay to KnownRoles: added
+// roles are appended, removed roles dropped. Safe to call repeatedly; the
+// watcher calls it every poll so role changes take effect without restart.
+func LoadSessionRoles(session string) {
+	sr, err := ReadSessionRoles(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	KnownRoles = applySessionRoles(teamRoles(), sr)
+}
+
+func applySessionRoles(base []string, sr SessionRoles) []string {
+	roles := make([]string, 0, len(base)+len(sr.Added))
+	for _, r := range append(append([]string(nil), base...), sr.Added...) {
+		if !containsRole(sr.Removed, r) && !containsRole(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// resetSessionRoles drops a session's role overlay (on re-init).
+func resetSessionRoles(session string) {
+	_ = os.Remove(RolesPath(session))
+	KnownRoles = teamRoles()
+}
+
+// AddRole adds a role to a running session: it records the role, creates
+// its inbox, history, and memory files, and — unless noWindow — opens a
+// tmux window running the agent in dir.
+func AddRole(session, role, dir string, noWindow bool) error {
+	if !validRoleName(role) {
+		return fmt.Errorf("invalid role name %q (use lowercase letters, digits, - and _)", role)
+	}
+	LoadSessionRoles(session)
+	if IsKnownRole(role) {
+		return fmt.Errorf("role %s already exists", role)
+	}
+
+	sr, err := ReadSessionRoles(session)
+	if err != nil {
+		return err
+	}
+	sr.Removed = removeRole(sr.Removed, role)
+	if !containsRole(teamRoles(), role) {
+		sr.Added = append(sr.Added, role)
+	}
+	if err := writeSessionRoles(session, sr); err != nil {
+		return err
+	}
+	LoadSessionRoles(session)
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditRoleAdd, Role: role, Args: map[string]string{
+		"dir": dir, "no_window": strconv.FormatBool(noWindow),
+	}})
+
+	for _, path := range []string{InboxPath(session, role), HistoryPath(session, role)} {
+		if err := touchFile(path); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(MemoryDir(), 0755); err != nil {
+		return err
+	}
+	if err := touchFile(MemoryPath(role)); err != nil {
+		return err
+	}
+
+	if noWindow || CheckSpawnWindow(session, RoleWindow(role)) {
+		return nil
+	}
+	return launchRoleWindow(session, role, dir)
+}
+
+// RemoveRole removes a role from a running session: its tmux window is
+// killed, its inbox and lock are deleted, and it is dropped from KnownRoles.
+// History and memory are kept so a later "role add" picks up where it left off.
+func RemoveRole(session, role string) error {
+	LoadSessionRoles(session)
+	if !IsKnownRole(role) || IsSpawnRole(role) {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+	if role == "edit" {
+		return fmt.Errorf("the edit role cannot be removed")
+	}
+
+	if window := RoleWindow(role); CheckSpawnWindow(session, window) {
+		_ = exec.Command("tmux", "kill-window", "-t", session+":"+window).Run()
+	}
+	_ = os.Remove(InboxPath(session, role))
+	clearInbox(session, role)
+	_ = os.Remove(LockPath(session, role))
+	RemoveHeartbeat(session, role)
+
+	sr, err := ReadSessionRoles(session)
+	if err != nil {
+		return err
+	}
+	sr.Added = removeRole(sr.Added, role)
+	if containsRole(teamRoles(), role) && !containsRole(sr.Removed, role) {
+		sr.Removed = append(sr.Removed, role)
+	}
+	if err := writeSessionRoles(session, sr); err != nil {
+		return err
+	}
+	LoadSessionRoles(session)
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditRoleRemove, Role: role})
+	return nil
+}
+
+// RestartRole restarts a role's agent: the agent pane is respawned (killing
+// the running agent) and the launcher started again. The role's lock is
+// released so it doesn't stay busy, and its heartbeat is cleared so the
+// restart isn't reported as a crash. A missing window is recreated.
+func RestartRole(session, role, dir string) error {
+	LoadSessionRoles(session)
+	if !IsKnownRole(role) || IsSpawnRole(role) {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+	_ = Unlock(session, role)
+	RemoveHeartbeat(session, role)
+
+	if !CheckSpawnWindow(session, RoleWindow(role)) {
+		return launchRoleWindow(session, role, dir)
+	}
+
+	launcher, err := findAgentLauncher()
+	if err != nil {
+		return fmt.Errorf("finding agent launcher: %v", err)
+	}
+	pane := PaneTarget(session, RoleWindow(role))
+	if err := exec.Command("tmux", "respawn-pane", "-k", "-t", pane, "-c", dir).Run(); err != nil {
+		return fmt.Errorf("respawning pane %s: %v", pane, err)
+	}
+	launchStr := fmt.Sprintf("%s %s", launcher, launcherRole(role))
+	if err := exec.Command("tmux", "send-keys", "-t", pane, launchStr, "Enter").Run(); err != nil {
+		return fmt.Errorf("launching agent: %v", err)
+	}
+
+	// Async: let the agent start, then have it read any pending messages
+	go func() {
+		time.Sleep(2 * time.Second)
+		if InboxCount(session, role) > 0 {
+			_ = Notify(session, role)
+		}
+	}()
+	return nil
+}
+
+// launchRoleWindow opens a tmux window for a role with the agent in pane 1,
+// matching the layout muxcode.sh creates.
+func launchRoleWindow(session, role, dir string) error {
+	launcher, err := findAgentLauncher()
+	if err != nil {
+		return fmt.Errorf("finding agent launcher: %v", err)
+	}
+	return openAgentWindow(session, RoleWindow(role), dir, fmt.Sprintf("%s %s", launcher, launcherRole(role)))
+}
+
+// openAgentWindow creates a tmux window split horizontally and runs
+// launchStr in the right-hand (agent) pane.
+func openAgentWindow(session, window, dir, launchStr string) error {
+	createArgs := []string{"new-window", "-d", "-t", session, "-n", window}
+	splitArgs := []string{"split-window", "-h", "-t", session + ":" + window}
+	if dir != "" {
+		createArgs = append(createArgs, "-c", dir)
+		splitArgs = append(splitArgs, "-c", dir)
+	}
+	if err := exec.Command("tmux", createArgs...).Run(); err != nil {
+		return fmt.Errorf("creating tmux window: %v", err)
+	}
+
+	// Split horizontally (agent in pane 1, consistent with all windows)
+	if err := exec.Command("tmux", splitArgs...).Run(); err != nil {
+		return fmt.Errorf("splitting window: %v", err)
+	}
+
+	target := session + ":" + window + "." + AgentPane(window)
+	if err := exec.Command("tmux", "send-keys", "-t", target, launchStr, "Enter").Run(); err != nil {
+		return fmt.Errorf("launching agent: %v", err)
+	}
+	return nil
+}
+
+// ListRoles describes every known role, plus roles removed from the
+// session, sorted by name.
+func ListRoles(session string) []RoleInfo {
+	LoadSessionRoles(session)
+	sr, _ := ReadSessionRoles(session)
+
+	var infos []RoleInfo
+	for _, role := range KnownRoles {
+		source := "builtin"
+		if containsRole(sr.Added, role) {
+			source = "added"
+		} else if !containsRole(baseKnownRoles, role) {
+			source = "config"
+		}
+		infos = append(infos, RoleInfo{
+			Role:   role,
+			Source: source,
+			Window: CheckSpawnWindow(session, RoleWindow(role)),
+			Locked: IsLocked(session, role),
+			Inbox:  InboxCount(session, role),
+		})
+	}
+	for _, role := range sr.Removed {
+		infos = append(infos, RoleInfo{Role: role, Source: "removed"})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Role < infos[j].Role })
+	return infos
+}
+
+// FormatRoleList formats roles as a table.
+func FormatRoleList(infos []RoleInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %-8s %-7s %-6s %s\n", "ROLE", "SOURCE", "WINDOW", "STATE", "INBOX")
+	for _, r := range infos {
+		window, state, inbox := "no", "idle", fmt.Sprintf("%d", r.Inbox)
+		if r.Window {
+			window = "yes"
+		}
+		if r.Locked {
+			state = "busy"
+		}
+		if r.Source == "removed" {
+			window, state, inbox = "-", "-", "-"
+		}
+		fmt.Fprintf(&b, "%-12s %-8s %-7s %-6s %s\n", r.Role, r.Source, window, state, inbox)
+	}
+	return b.String()
+}
+
+func removeRole(roles []string, role string) []string {
+	var out []string
+	for _, r := range roles {
+		if r != role {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// roleTestSession is testSession with KnownRoles restored afterwards.
+func roleTestSession(t *testing.T) string {
+	t.Helper()
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+	session := testSession(t)
+	t.Cleanup(func() { KnownRoles = append([]string(nil), baseKnownRoles...) })
+	return session
+}
+
+func TestAddRole(t *testing.T) {
+	session := roleTestSession(t)
+
+	if err := AddRole(session, "perf", "", true); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+	if !IsKnownRole("perf") {
+		t.Error("perf should be a known role")
+	}
+	for _, path := range []string{InboxPath(session, "perf"), HistoryPath(session, "perf"), MemoryPath("perf")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+
+	// Another process sees the role through the overlay
+	KnownRoles = append([]string(nil), baseKnownRoles...)
+	LoadSessionRoles(session)
+	if !IsKnownRole("perf") {
+		t.Error("overlay should restore perf")
+	}
+
+	if err := AddRole(session, "perf", "", true); err == nil {
+		t.Error("adding an existing role should fail")
+	}
+	for _, bad := range []string{"", "Docs", "a b", "-x", "spawn-1"} {
+		if err := AddRole(session, bad, "", true); err == nil {
+			t.Errorf("AddRole(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRemoveRole(t *testing.T) {
+	session := roleTestSession(t)
+
+	if err := RemoveRole(session, "watch"); err != nil {
+		t.Fatalf("RemoveRole: %v", err)
+	}
+	if IsKnownRole("watch") {
+		t.Error("watch should no longer be known")
+	}
+	if _, err := os.Stat(InboxPath(session, "watch")); !os.IsNotExist(err) {
+		t.Error("watch inbox should be removed")
+	}
+	if err := RemoveRole(session, "watch"); err == nil {
+		t.Error("removing twice should fail")
+	}
+	if err := RemoveRole(session, "edit"); err == nil {
+		t.Error("edit should not be removable")
+	}
+
+	out := FormatRoleList(ListRoles(session))
+	if !strings.Contains(out, "watch") || !strings.Contains(out, "removed") {
+		t.Errorf("list should show removed role:\n%s", out)
+	}
+
+	// Adding a removed builtin clears the removal rather than adding a duplicate
+	if err := AddRole(session, "watch", "", true); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+	sr, err := ReadSessionRoles(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Added) != 0 || len(sr.Removed) != 0 {
+		t.Errorf("overlay = %+v, want empty", sr)
+	}
+}
+
+func TestRoleOverlayResetOnInit(t *testing.T) {
+	session := roleTestSession(t)
+
+	if err := AddRole(session, "perf", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if IsKnownRole("perf") {
+		t.Error("re-init should drop added roles")
+	}
+	if _, err := os.Stat(RolesPath(session)); !os.IsNotExist(err) {
+		t.Error("re-init should remove the overlay file")
+	}
+}
+
+func TestApplySessionRoles(t *testing.T) {
+	got := applySessionRoles([]string{"edit", "build", "test"},
+		SessionRoles{Added: []string{"perf", "build"}, Removed: []string{"test"}})
+	if strings.Join(got, ",") != "edit,build,perf" {
+		t.Errorf("got %v", got)
+	}
+}
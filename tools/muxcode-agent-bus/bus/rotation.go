@@ -105,6 +105,10 @@ func PurgeOldArchives(role string, cfg RotationConfig) error {
 // ReadMemoryWithHistory reads the active memory file plus the last N days
 // of archives, concatenated with most recent last.
 func ReadMemoryWithHistory(role string, days int) (string, error) {
+	if s := ActiveStore(); s != nil {
+		return readMemoryStore(s, role, startOfDay(time.Now().AddDate(0, 0, -days)).Unix())
+	}
+
 	var parts []string
 
 	// Read archives within the window
@@ -116,7 +120,7 @@ func ReadMemoryWithHistory(role string, days int) (string, error) {
 	cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
 	for _, date := range dates {
 		if date >= cutoff {
-			content, readErr := os.ReadFile(MemoryArchivePath(role, date))
+			content, readErr := readBusFile(MemoryArchivePath(role, date))
 			if readErr != nil {
 				if os.IsNotExist(readErr) {
 					continue
@@ -174,7 +178,33 @@ func ListArchiveDates(role string) ([]string, error) {
 // AllMemoryEntriesWithArchives reads all memory files (active + archives)
 // and returns their parsed entries.
 func AllMemoryEntriesWithArchives() ([]MemoryEntry, error) {
-	dir := MemoryDir()
+	s := ActiveStore()
+	if s == nil {
+		return allMemoryFileEntries()
+	}
+	roles, err := ListMemoryRoles()
+	if err != nil {
+		return nil, err
+	}
+	var all []MemoryEntry
+	for _, role := range roles {
+		content, err := readMemoryStore(s, role, 0)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ParseMemoryEntries(content, role)...)
+	}
+	return all, nil
+}
+
+// allMemoryFileEntries parses every active and archived memory file.
+func allMemoryFileEntries() ([]MemoryEntry, error) {
+	return memoryFileEntriesIn(MemoryDir())
+}
+
+// memoryFileEntriesIn parses every active and archived memory file in a
+// memory directory.
+func memoryFileEntriesIn(dir string) ([]MemoryEntry, error) {
 	dirEntries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -198,7 +228,7 @@ func AllMemoryEntriesWithArchives() ([]MemoryEntry, error) {
 				if ae.IsDir() || !strings.HasSuffix(ae.Name(), ".md") {
 					continue
 				}
-				content, err := os.ReadFile(filepath.Join(archiveDir, ae.Name()))
+				content, err := readBusFile(filepath.Join(archiveDir, ae.Name()))
 				if err != nil {
 					continue
 				}
@@ -213,7 +243,7 @@ func AllMemoryEntriesWithArchives() ([]MemoryEntry, error) {
 			continue
 		}
 		role := strings.TrimSuffix(name, ".md")
-		content, err := os.ReadFile(filepath.Join(dir, name))
+		content, err := readBusFile(filepath.Join(dir, name))
 		if err != nil {
 			continue
 		}
@@ -247,8 +277,25 @@ func ArchiveTotalSize(role string) int64 {
 }
 
 // ListMemoryRoles returns all roles that have either an active memory file
-// or an archive directory.
+// or an archive directory (or memory records, when a store is configured).
 func ListMemoryRoles() ([]string, error) {
+	if s := ActiveStore(); s != nil {
+		streams, err := s.Streams("memory/")
+		if err != nil {
+			return nil, err
+		}
+		roles := make([]string, 0, len(streams))
+		for _, stream := range streams {
+			roles = append(roles, strings.TrimPrefix(stream, "memory/"))
+		}
+		return roles, nil
+	}
+	return listMemoryRoleFiles()
+}
+
+// listMemoryRoleFiles scans the memory directory for active files and
+// archive directories.
+func listMemoryRoleFiles() ([]string, error) {
 	dir := MemoryDir()
 	dirEntries, err := os.ReadDir(dir)
 	if err != nil {
@@ -0,0 +1,204 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RouteRule sends changed files to an agent. A file matches when any Match
+// glob matches its path or base name and any Project type is detected for
+// its directory; an empty list matches everything. The first matching rule
+// wins for each file.
+type RouteRule struct {
+	Match   []string `json:"match,omitempty"`   // globs, e.g. "*_test.go", "infra/*.tf"
+	Project []string `json:"project,omitempty"` // project types, e.g. "terraform", "cdk"
+	To      string   `json:"to"`
+	Action  string   `json:"action,omitempty"` // default "notify"
+}
+
+// RoutedFiles is the set of changed files a rule sent to one agent.
+type RoutedFiles struct {
+	To       string
+	Action   string
+	Files    []string
+	Projects []string // project types detected for the files
+}
+
+// Validate rejects rules without a target or with malformed globs.
+func (r RouteRule) Validate() error {
+	if r.To == "" {
+		return fmt.Errorf("to is required")
+	}
+	for _, g := range r.Match {
+		if _, err := filepath.Match(g, ""); err != nil {
+			return fmt.Errorf("invalid glob %q", g)
+		}
+	}
+	return nil
+}
+
+// KnownProjectType reports whether name is a type DetectProject can report.
+func KnownProjectType(name string) bool {
+	if name == "monorepo" {
+		return true
+	}
+	for _, ind := range indicators {
+		if ind.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutingMarkerPath returns the file whose presence tells the analyze hook
+// that the watcher routes changed files, so the hook skips its own routing.
+func RoutingMarkerPath(session string) string {
+	return filepath.Join(BusDir(session), "watcher-routing")
+}
+
+// routeGlobMatch reports whether glob matches path, its base name, or a
+// trailing run of its path segments.
+func routeGlobMatch(glob, path string) bool {
+	path = filepath.ToSlash(path)
+	segs := strings.Split(path, "/")
+	for i := range segs {
+		if ok, _ := filepath.Match(glob, strings.Join(segs[i:], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// projectTypesFor returns the project types detected in the nearest
+// directory from dir up to root that has any. Results are cached per
+// directory.
+func projectTypesFor(dir, root string, cache map[string][]string) []string {
+	var walked []string
+	var names []string
+	for {
+		if cached, ok := cache[dir]; ok {
+			names = cached
+			break
+		}
+		walked = append(walked, dir)
+		for _, pt := range DetectProject(dir) {
+			names = append(names, pt.Name)
+		}
+		parent := filepath.Dir(dir)
+		if len(names) > 0 || dir == root || parent == dir || !strings.HasPrefix(dir, root) {
+			break
+		}
+		dir = parent
+	}
+	for _, d := range walked {
+		cache[d] = names
+	}
+	return names
+}
+
+// RouteChangedFiles groups changed files by the agent the first matching
+// rule sends them to. Relative paths are resolved against root. Files no
+// rule matches are left out. Groups are returned in rule order.
+func RouteChangedFiles(files []string, rules []RouteRule, root string) []RoutedFiles {
+	if len(rules) == 0 {
+		return nil
+	}
+	root = filepath.Clean(root)
+	cache := make(map[string][]string)
+	groups := make(map[string]*RoutedFiles)
+	var order []string
+
+	for _, file := range files {
+		abs := file
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(root, file)
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = file
+		}
+
+		projects := projectTypesFor(filepath.Dir(abs), root, cache)
+		for _, r := range rules {
+			if len(r.Match) > 0 && !routeMatchAny(r.Match, rel) {
+				continue
+			}
+			if len(r.Project) > 0 && !routeHasAny(projects, r.Project) {
+				continue
+			}
+			action := r.Action
+			if action == "" {
+				action = "notify"
+			}
+			key := r.To + "\x00" + action
+			g, ok := groups[key]
+			if !ok {
+				g = &RoutedFiles{To: r.To, Action: action}
+				groups[key] = g
+				order = append(order, key)
+			}
+			g.Files = append(g.Files, file)
+			for _, p := range projects {
+				if !routeHasAny(g.Projects, []string{p}) {
+					g.Projects = append(g.Projects, p)
+				}
+			}
+			break
+		}
+	}
+
+	out := make([]RoutedFiles, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		sort.Strings(g.Projects)
+		out = append(out, *g)
+	}
+	return out
+}
+
+// Message is the event payload sent to the routed agent.
+func (g RoutedFiles) Message() string {
+	msg := "Files changed: " + strings.Join(g.Files, ", ")
+	if len(g.Projects) > 0 {
+		msg += " (" + strings.Join(g.Projects, ", ") + ")"
+	}
+	return msg
+}
+
+// routeMatchAny reports whether any glob matches path.
+func routeMatchAny(globs []string, path string) bool {
+	for _, g := range globs {
+		if routeGlobMatch(g, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeHasAny reports whether have contains any of want.
+func routeHasAny(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateRoutingMarker creates the routing marker when routing rules are
+// configured and removes it otherwise.
+func UpdateRoutingMarker(session string) error {
+	path := RoutingMarkerPath(session)
+	if len(Config().Routing) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d rules\n", len(Config().Routing))), 0644)
+}
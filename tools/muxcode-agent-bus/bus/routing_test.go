@@ -0,0 +1,114 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRouteChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeIndicatorFile(t, root, "go.mod", "module example.com/app\n")
+	writeIndicatorFile(t, root, "infra/main.tf", "")
+	writeIndicatorFile(t, root, "cdk/cdk.json", "{}")
+
+	rules := []RouteRule{
+		{Match: []string{"*_test.go", "*.spec.*"}, To: "test"},
+		{Project: []string{"terraform", "cdk"}, To: "deploy"},
+		{Match: []string{"cmd/*.go"}, Project: []string{"go"}, To: "build", Action: "compile"},
+	}
+	files := []string{
+		"pkg/store/store_test.go",
+		"infra/main.tf",
+		"infra/variables.tf",
+		"cdk/lib/stack.ts",
+		"cmd/main.go",
+		"pkg/store/store.go",
+		"README.md",
+	}
+	got := RouteChangedFiles(files, rules, root)
+	if len(got) != 3 {
+		t.Fatalf("got %d groups: %+v", len(got), got)
+	}
+
+	if g := got[0]; g.To != "test" || g.Action != "notify" || strings.Join(g.Files, ",") != "pkg/store/store_test.go" {
+		t.Errorf("test group = %+v", g)
+	}
+	deploy := got[1]
+	if deploy.To != "deploy" || strings.Join(deploy.Files, ",") != "infra/main.tf,infra/variables.tf,cdk/lib/stack.ts" {
+		t.Errorf("deploy group = %+v", deploy)
+	}
+	if strings.Join(deploy.Projects, ",") != "cdk,terraform" {
+		t.Errorf("deploy projects = %v", deploy.Projects)
+	}
+	if g := got[2]; g.To != "build" || g.Action != "compile" || strings.Join(g.Files, ",") != "cmd/main.go" {
+		t.Errorf("build group = %+v", g)
+	}
+	if msg := deploy.Message(); msg != "Files changed: infra/main.tf, infra/variables.tf, cdk/lib/stack.ts (cdk, terraform)" {
+		t.Errorf("message = %q", msg)
+	}
+
+	if got := RouteChangedFiles(files, nil, root); got != nil {
+		t.Errorf("no rules should route nothing, got %+v", got)
+	}
+}
+
+func TestRouteChangedFiles_AbsolutePaths(t *testing.T) {
+	root := t.TempDir()
+	writeIndicatorFile(t, root, "infra/main.tf", "")
+
+	got := RouteChangedFiles([]string{root + "/infra/main.tf"}, []RouteRule{{Match: []string{"infra/*.tf"}, To: "deploy"}}, root)
+	if len(got) != 1 || strings.Join(got[0].Projects, ",") != "terraform" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestRouteRuleValidate(t *testing.T) {
+	if err := (RouteRule{Match: []string{"*.go"}}).Validate(); err == nil {
+		t.Error("rule without to should fail")
+	}
+	if err := (RouteRule{Match: []string{"[a"}, To: "test"}).Validate(); err == nil {
+		t.Error("malformed glob should fail")
+	}
+
+	data := []byte(`{
+  "routing": [
+    {"match": ["*.tf"], "project": ["terrafrom"], "to": "deploy"},
+    {"match": ["*_test.go"], "to": "tset"}
+  ]
+}`)
+	issues := ValidateConfigData("muxcode.json", data)
+	want := []string{
+		`routing.0.project.0: unknown project type "terrafrom"`,
+		`routing.1.to: unknown role "tset"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(issues), len(want), FormatConfigIssues(issues, nil))
+	}
+	for i, w := range want {
+		if !strings.Contains(issues[i].String(), w) {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+}
+
+func TestUpdateRoutingMarker(t *testing.T) {
+	session := testSession(t)
+	SetConfig(&MuxcodeConfig{Routing: []RouteRule{{Match: []string{"*.tf"}, To: "deploy"}}})
+	defer SetConfig(nil)
+
+	if err := UpdateRoutingMarker(session); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(RoutingMarkerPath(session)); err != nil {
+		t.Fatalf("marker not created: %v", err)
+	}
+
+	SetConfig(&MuxcodeConfig{})
+	if err := UpdateRoutingMarker(session); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(RoutingMarkerPath(session)); !os.IsNotExist(err) {
+		t.Errorf("marker not removed: %v", err)
+	}
+}
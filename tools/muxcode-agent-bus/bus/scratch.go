@@ -0,0 +1,84 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Scratch notes are per-session, per-role working notes kept in the bus
+// directory. Unlike memory they never rotate into archives, are excluded
+// from memory search unless asked for, and disappear with the session
+// (cleanup removes the bus directory).
+
+// ScratchDir returns the scratch directory for a session.
+func ScratchDir(session string) string {
+	return filepath.Join(BusDir(session), "scratch")
+}
+
+// ScratchPath returns the scratch file for a role in a session.
+func ScratchPath(session, role string) string {
+	return filepath.Join(ScratchDir(session), role+".md")
+}
+
+// AppendScratch appends a note to a role's scratchpad, in the same
+// section format as memory. Content is redacted like memory.
+func AppendScratch(session, role, section, content string) error {
+	content = redactAndLog(session, "scratch", role, content)
+	if err := os.MkdirAll(ScratchDir(session), 0755); err != nil {
+		return err
+	}
+	chunk := formatMemoryChunk(section, time.Now().Format("2006-01-02 15:04"), content)
+	return appendToFile(ScratchPath(session, role), []byte(chunk))
+}
+
+// ReadScratch returns a role's scratchpad, or "" if it has none.
+func ReadScratch(session, role string) (string, error) {
+	data, err := os.ReadFile(ScratchPath(session, role))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ClearScratch removes a role's scratchpad.
+func ClearScratch(session, role string) error {
+	err := os.Remove(ScratchPath(session, role))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ScratchEntries parses every role's scratchpad in a session, marking
+// each entry as scratch.
+func ScratchEntries(session string) ([]MemoryEntry, error) {
+	dirEntries, err := os.ReadDir(ScratchDir(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var all []MemoryEntry
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".md") {
+			continue
+		}
+		role := strings.TrimSuffix(de.Name(), ".md")
+		content, err := os.ReadFile(filepath.Join(ScratchDir(session), de.Name()))
+		if err != nil {
+			continue
+		}
+		entries := ParseMemoryEntries(string(content), role)
+		for i := range entries {
+			entries[i].Scratch = true
+		}
+		all = append(all, entries...)
+	}
+	return all, nil
+}
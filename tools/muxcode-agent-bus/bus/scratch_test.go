@@ -0,0 +1,75 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScratch_WriteReadClear(t *testing.T) {
+	session := testSession(t)
+
+	if err := AppendScratch(session, "build", "Plan", "try the cache fix first"); err != nil {
+		t.Fatalf("AppendScratch: %v", err)
+	}
+	content, err := ReadScratch(session, "build")
+	if err != nil {
+		t.Fatalf("ReadScratch: %v", err)
+	}
+	if !strings.Contains(content, "## Plan") || !strings.Contains(content, "try the cache fix first") {
+		t.Errorf("unexpected scratch content %q", content)
+	}
+
+	if err := ClearScratch(session, "build"); err != nil {
+		t.Fatalf("ClearScratch: %v", err)
+	}
+	if content, _ := ReadScratch(session, "build"); content != "" {
+		t.Errorf("expected empty scratch after clear, got %q", content)
+	}
+	// Clearing a missing scratchpad is not an error
+	if err := ClearScratch(session, "build"); err != nil {
+		t.Errorf("ClearScratch on missing file: %v", err)
+	}
+}
+
+func TestScratch_ExcludedFromSearchByDefault(t *testing.T) {
+	session := testSession(t)
+	t.Setenv("BUS_SESSION", session)
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	if err := AppendMemory("Build Config", "use pnpm for all builds", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	if err := AppendScratch(session, "build", "Plan", "switch pnpm to the frozen lockfile"); err != nil {
+		t.Fatalf("AppendScratch: %v", err)
+	}
+
+	results, err := SearchMemoryWithOptions(SearchOptions{Query: "pnpm", Mode: SearchModeBM25})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Scratch {
+		t.Fatalf("expected only the memory entry, got %+v", results)
+	}
+
+	results, err = SearchMemoryWithOptions(SearchOptions{Query: "pnpm", Mode: SearchModeBM25, IncludeScratch: true})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected memory and scratch entries, got %+v", results)
+	}
+	out := FormatSearchResultsGrouped(results)
+	if !strings.Contains(out, "=== build scratch (1) ===") {
+		t.Errorf("expected scratch origin heading, got:\n%s", out)
+	}
+
+	// Scratch never lands in the memory dir
+	if _, err := os.Stat(MemoryPath("build")); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := AllMemoryEntriesWithArchives()
+	if len(entries) != 1 {
+		t.Errorf("expected scratch kept out of memory, got %d entries", len(entries))
+	}
+}
@@ -1,6 +1,7 @@
 package bus
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"strings"
@@ -15,6 +16,10 @@ const (
 	SearchModeKeyword SearchMode = iota
 	// SearchModeBM25 uses Okapi BM25 with IDF weighting and length normalization.
 	SearchModeBM25
+	// SearchModeSemantic ranks by embedding cosine similarity (requires Ollama).
+	SearchModeSemantic
+	// SearchModeHybrid blends normalized BM25 and embedding scores.
+	SearchModeHybrid
 )
 
 // SearchOptions configures a memory search.
@@ -23,6 +28,11 @@ type SearchOptions struct {
 	RoleFilter string
 	Limit      int
 	Mode       SearchMode
+	Embedder   Embedder // semantic/hybrid only; nil uses Ollama
+	// AllSessions also searches sessions archived in GlobalMemoryDir (BM25 only).
+	AllSessions bool
+	// IncludeScratch also searches the current session's scratchpads (BM25 only).
+	IncludeScratch bool
 }
 
 // corpus holds collection-level statistics for BM25 scoring.
@@ -305,16 +315,56 @@ func containsPhrase(tokens, phrase []string) bool {
 
 // SearchMemoryBM25 searches all memory entries using BM25 ranking.
 func SearchMemoryBM25(opts SearchOptions) ([]SearchResult, error) {
-	entries, err := AllMemoryEntries()
-	if err != nil {
-		return nil, err
-	}
-
 	queryTerms, phrases := parseQuery(opts.Query)
 	if len(queryTerms) == 0 {
 		return nil, nil
 	}
 
+	var results []SearchResult
+	if ActiveStore() == nil && !opts.IncludeScratch {
+		// File-backed memory: score against the persisted index
+		idx, err := loadBM25Index()
+		if err != nil {
+			return nil, err
+		}
+		results = idx.search(opts, queryTerms, phrases)
+	} else {
+		entries, err := searchableMemoryEntries(opts)
+		if err != nil {
+			return nil, err
+		}
+		results = searchEntriesBM25(entries, opts, queryTerms, phrases)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// searchableMemoryEntries returns the current project's memory entries,
+// plus the session's scratchpads when opts asks for them.
+func searchableMemoryEntries(opts SearchOptions) ([]MemoryEntry, error) {
+	entries, err := AllMemoryEntries()
+	if err != nil || !opts.IncludeScratch {
+		return entries, err
+	}
+	scratch, err := ScratchEntries(BusSession())
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, scratch...), nil
+}
+
+// searchEntriesBM25 scores entries by building the corpus in memory. Used
+// for store-backed memory, which has no persisted index, and for searches
+// that span more than the project's memory files.
+func searchEntriesBM25(entries []MemoryEntry, opts SearchOptions, queryTerms []string, phrases [][]string) []SearchResult {
 	// Filter by role before building corpus for accurate IDF
 	var filtered []MemoryEntry
 	for _, entry := range entries {
@@ -325,7 +375,7 @@ func SearchMemoryBM25(opts SearchOptions) ([]SearchResult, error) {
 	}
 
 	if len(filtered) == 0 {
-		return nil, nil
+		return nil
 	}
 
 	corp := buildCorpus(filtered)
@@ -339,23 +389,26 @@ func SearchMemoryBM25(opts SearchOptions) ([]SearchResult, error) {
 			results = append(results, SearchResult{Entry: entry, Score: score})
 		}
 	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-
-	if opts.Limit > 0 && len(results) > opts.Limit {
-		results = results[:opts.Limit]
-	}
-
-	return results, nil
+	return results
 }
 
-// SearchMemoryWithOptions dispatches to BM25 or keyword search based on mode.
+// SearchMemoryWithOptions dispatches to the search implementation for mode.
 func SearchMemoryWithOptions(opts SearchOptions) ([]SearchResult, error) {
+	if opts.AllSessions || opts.IncludeScratch {
+		if opts.Mode != SearchModeBM25 {
+			return nil, fmt.Errorf("searching all sessions or scratchpads requires bm25 mode")
+		}
+		if opts.AllSessions {
+			return SearchMemoryAllSessions(opts)
+		}
+	}
 	switch opts.Mode {
 	case SearchModeBM25:
 		return SearchMemoryBM25(opts)
+	case SearchModeSemantic:
+		return SearchMemorySemantic(opts)
+	case SearchModeHybrid:
+		return SearchMemoryHybrid(opts)
 	default:
 		return SearchMemory(opts.Query, opts.RoleFilter, opts.Limit)
 	}
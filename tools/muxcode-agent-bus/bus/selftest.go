@@ -0,0 +1,397 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SelftestResult is the outcome of a single selftest check.
+type SelftestResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // "pass", "fail", "skip"
+	Detail   string `json:"detail"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// SelftestOptions controls which checks run and whether the temporary
+// session is kept for inspection.
+type SelftestOptions struct {
+	SkipTmux   bool
+	SkipOllama bool
+	Keep       bool
+	TempDir    string // parent of the temporary memory dir ("" = os.TempDir())
+}
+
+// selftestCheck is a named check run against a temporary session.
+// Returning an errSelftestSkip marks the check as skipped.
+type selftestCheck struct {
+	name string
+	run  func(session string) (string, error)
+}
+
+// errSelftestSkip marks a check as skipped rather than failed.
+type errSelftestSkip struct{ reason string }
+
+func (e errSelftestSkip) Error() string { return e.reason }
+
+// RunSelftest spins up a temporary bus session and exercises send/inbox,
+// cron, proc, spawn bookkeeping, guard, and memory round-trips with fake
+// agents, then probes tmux and Ollama if present. The temporary session and
+// memory directory are removed afterwards unless opts.Keep is set; both
+// are returned so a kept run can be inspected.
+func RunSelftest(opts SelftestOptions) (session, memDir string, results []SelftestResult) {
+	session = fmt.Sprintf("selftest-%d", time.Now().UnixNano())
+
+	memDir, err := os.MkdirTemp(opts.TempDir, "muxcode-selftest-mem-")
+	if err != nil {
+		return session, "", []SelftestResult{{Name: "init", Status: "fail", Detail: err.Error()}}
+	}
+
+	// Memory functions resolve their directory from BUS_MEMORY_DIR
+	prevMemDir, hadMemDir := os.LookupEnv("BUS_MEMORY_DIR")
+	os.Setenv("BUS_MEMORY_DIR", memDir)
+	defer func() {
+		if hadMemDir {
+			os.Setenv("BUS_MEMORY_DIR", prevMemDir)
+		} else {
+			os.Unsetenv("BUS_MEMORY_DIR")
+		}
+		if !opts.Keep {
+			_ = Cleanup(session)
+			_ = os.RemoveAll(memDir)
+		}
+	}()
+
+	checks := []selftestCheck{
+		{"init", func(s string) (string, error) {
+			if err := Init(s, memDir); err != nil {
+				return "", err
+			}
+			return BusDir(s), nil
+		}},
+		{"send/inbox", selftestSendInbox},
+		{"cron", selftestCron},
+		{"proc", selftestProc},
+		{"spawn", selftestSpawn},
+		{"guard", selftestGuard},
+		{"memory", selftestMemory},
+	}
+	if !opts.SkipTmux {
+		checks = append(checks, selftestCheck{"tmux", selftestTmux})
+	}
+	if !opts.SkipOllama {
+		checks = append(checks, selftestCheck{"ollama", selftestOllama})
+	}
+
+	for _, c := range checks {
+		start := time.Now()
+		detail, err := c.run(session)
+		res := SelftestResult{
+			Name:     c.name,
+			Status:   "pass",
+			Detail:   detail,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if skip, ok := err.(errSelftestSkip); ok {
+			res.Status = "skip"
+			res.Detail = skip.reason
+		} else if err != nil {
+			res.Status = "fail"
+			res.Detail = err.Error()
+		}
+		results = append(results, res)
+
+		// Nothing else can run without a bus directory
+		if c.name == "init" && res.Status == "fail" {
+			break
+		}
+	}
+
+	return session, memDir, results
+}
+
+// SelftestPassed returns true if no check failed.
+func SelftestPassed(results []SelftestResult) bool {
+	for _, r := range results {
+		if r.Status == "fail" {
+			return false
+		}
+	}
+	return true
+}
+
+// selftestSendInbox sends a request from a fake edit agent to build, consumes
+// it, replies, and verifies the reply is delivered via ReceiveFrom.
+func selftestSendInbox(session string) (string, error) {
+	req := NewMessage("edit", "build", "request", "selftest", "ping", "")
+	if err := SendNoCC(session, req); err != nil {
+		return "", fmt.Errorf("send: %v", err)
+	}
+	if n := InboxCount(session, "build"); n != 1 {
+		return "", fmt.Errorf("expected 1 message in build inbox, got %d", n)
+	}
+
+	msgs, err := Receive(session, "build")
+	if err != nil {
+		return "", fmt.Errorf("receive: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != req.ID {
+		return "", fmt.Errorf("receive returned %d message(s), want request %s", len(msgs), req.ID)
+	}
+
+	reply := NewMessage("build", "edit", "response", "selftest", "pong", req.ID)
+	if err := SendNoCC(session, reply); err != nil {
+		return "", fmt.Errorf("reply: %v", err)
+	}
+	got, err := ReceiveFrom(session, "edit", "build")
+	if err != nil {
+		return "", fmt.Errorf("receive reply: %v", err)
+	}
+	if len(got) != 1 || got[0].ReplyTo != req.ID {
+		return "", fmt.Errorf("reply not delivered")
+	}
+	return "edit → build → edit", nil
+}
+
+// selftestCron adds an entry, verifies it is due, executes it, and checks
+// the message lands in the target inbox and the history is recorded.
+func selftestCron(session string) (string, error) {
+	entry, err := AddCronEntry(session, CronEntry{
+		Schedule: "@every 30s",
+		Target:   "test",
+		Action:   "selftest",
+		Message:  "scheduled ping",
+	})
+	if err != nil {
+		return "", fmt.Errorf("add: %v", err)
+	}
+
+	now := time.Now().Unix()
+	if !CronDue(entry, now) {
+		return "", fmt.Errorf("new entry not due")
+	}
+	msgID, err := ExecuteCron(session, entry)
+	if err != nil {
+		return "", fmt.Errorf("execute: %v", err)
+	}
+	if err := UpdateLastRun(session, entry.ID, now); err != nil {
+		return "", fmt.Errorf("update last run: %v", err)
+	}
+	if err := AppendCronHistory(session, CronHistoryEntry{CronID: entry.ID, TS: now, MessageID: msgID, Target: entry.Target, Action: entry.Action}); err != nil {
+		return "", fmt.Errorf("history: %v", err)
+	}
+
+	msgs, _ := Receive(session, "test")
+	if len(msgs) != 1 || msgs[0].ID != msgID {
+		return "", fmt.Errorf("cron message not delivered to test inbox")
+	}
+	entries, _ := ReadCronEntries(session)
+	if len(entries) != 1 || entries[0].RunCount != 1 || CronDue(entries[0], now) {
+		return "", fmt.Errorf("last run not recorded")
+	}
+	hist, _ := ReadCronHistory(session, entry.ID)
+	if len(hist) != 1 {
+		return "", fmt.Errorf("expected 1 history entry, got %d", len(hist))
+	}
+	return "add → fire → history", nil
+}
+
+// selftestProc starts a short background process and waits for it to exit.
+func selftestProc(session string) (string, error) {
+	dir, _ := os.Getwd()
+	entry, err := StartProc(session, "echo muxcode-selftest", dir, "edit")
+	if err != nil {
+		return "", fmt.Errorf("start: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		completed, err := RefreshProcStatus(session)
+		if err != nil {
+			return "", fmt.Errorf("refresh: %v", err)
+		}
+		for _, c := range completed {
+			if c.ID != entry.ID {
+				continue
+			}
+			if c.Status != "exited" || c.ExitCode != 0 {
+				return "", fmt.Errorf("status %s exit %d", c.Status, c.ExitCode)
+			}
+			data, _ := os.ReadFile(c.LogFile)
+			if !strings.Contains(string(data), "muxcode-selftest") {
+				return "", fmt.Errorf("output missing from log %s", c.LogFile)
+			}
+			return fmt.Sprintf("pid %d exited 0", c.PID), nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	_ = StopProc(session, entry.ID)
+	return "", fmt.Errorf("process did not finish within 5s")
+}
+
+// selftestSpawn exercises spawn bookkeeping with a fake spawned agent: a
+// tracked entry with no tmux window, a result message from the spawn role,
+// and completion detection. No real agent is launched.
+func selftestSpawn(session string) (string, error) {
+	spawnRole := "spawn-selftest"
+	entry := SpawnEntry{
+		ID:        NewMsgID("spawn"),
+		Role:      "research",
+		SpawnRole: spawnRole,
+		Owner:     "edit",
+		Task:      "selftest",
+		Status:    "running",
+		Window:    spawnRole,
+		StartedAt: time.Now().Unix(),
+	}
+	if err := WriteSpawnEntries(session, []SpawnEntry{entry}); err != nil {
+		return "", fmt.Errorf("write: %v", err)
+	}
+
+	result := NewMessage(spawnRole, "edit", "response", "spawn-result", "selftest result", "")
+	if err := SendNoCC(session, result); err != nil {
+		return "", fmt.Errorf("send result: %v", err)
+	}
+
+	completed, err := RefreshSpawnStatus(session)
+	if err != nil {
+		return "", fmt.Errorf("refresh: %v", err)
+	}
+	if len(completed) != 1 || completed[0].Status != "completed" {
+		return "", fmt.Errorf("spawn not marked completed")
+	}
+	got, ok := GetSpawnResult(session, spawnRole)
+	if !ok || got.ID != result.ID {
+		return "", fmt.Errorf("spawn result not found")
+	}
+	_, _ = Receive(session, "edit")
+	return "track → result → complete", nil
+}
+
+// selftestGuard writes a failing command history and verifies loop detection.
+func selftestGuard(session string) (string, error) {
+	now := time.Now().Unix()
+	for i := 0; i < 3; i++ {
+		line, _ := json.Marshal(HistoryEntry{
+			TS:       now - int64(10*(2-i)),
+			Command:  "go build ./...",
+			ExitCode: "1",
+			Outcome:  "failure",
+		})
+		if err := AppendHistory(session, "build", line, 0); err != nil {
+			return "", fmt.Errorf("write history: %v", err)
+		}
+	}
+
+	alerts := CheckLoops(session, "build")
+	for _, a := range alerts {
+		if a.Type == "command" && a.Count == 3 {
+			return a.Message, nil
+		}
+	}
+	return "", fmt.Errorf("command loop not detected")
+}
+
+// selftestMemory appends a memory entry and finds it via read and search.
+func selftestMemory(session string) (string, error) {
+	marker := "selftest-" + session
+	if err := AppendMemory("Selftest", "Verified round-trip "+marker, "build"); err != nil {
+		return "", fmt.Errorf("append: %v", err)
+	}
+	content, err := ReadMemory("build")
+	if err != nil {
+		return "", fmt.Errorf("read: %v", err)
+	}
+	if !strings.Contains(content, marker) {
+		return "", fmt.Errorf("appended entry not found")
+	}
+	results, err := SearchMemoryWithOptions(SearchOptions{Query: "round-trip verified", RoleFilter: "build", Limit: 5, Mode: SearchModeBM25})
+	if err != nil {
+		return "", fmt.Errorf("search: %v", err)
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("search returned no results")
+	}
+	return "append → read → search", nil
+}
+
+// selftestTmux verifies tmux is installed and can create a detached session.
+func selftestTmux(session string) (string, error) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return "", errSelftestSkip{"tmux not installed"}
+	}
+	out, err := exec.Command("tmux", "-V").Output()
+	if err != nil {
+		return "", fmt.Errorf("tmux -V: %v", err)
+	}
+	if err := exec.Command("tmux", "new-session", "-d", "-s", session).Run(); err != nil {
+		return "", fmt.Errorf("new-session: %v", err)
+	}
+	defer exec.Command("tmux", "kill-session", "-t", session).Run()
+	if err := exec.Command("tmux", "has-session", "-t", session).Run(); err != nil {
+		return "", fmt.Errorf("session not visible after creation")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// selftestOllama checks Ollama reachability. Unreachable is a failure only
+// when some role is configured to use a local LLM; otherwise it is skipped.
+func selftestOllama(session string) (string, error) {
+	cfg := DefaultOllamaConfig()
+	cfg.BaseURLs = OllamaNodeURLs(cfg.Model)
+	client := NewOllamaClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.CheckHealth(ctx)
+	roles := LocalLLMRoles()
+	if err != nil {
+		if len(roles) == 0 {
+			return "", errSelftestSkip{"not reachable, no local LLM roles configured"}
+		}
+		return "", fmt.Errorf("%v (local roles: %s)", err, strings.Join(roles, ", "))
+	}
+	return fmt.Sprintf("%s model %s", strings.Join(cfg.BaseURLs, ", "), cfg.Model), nil
+}
+
+// FormatSelftestResults formats results as a pass/fail matrix.
+func FormatSelftestResults(results []SelftestResult) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-12s %-6s %-8s %s\n", "CHECK", "RESULT", "TIME", "DETAIL"))
+	b.WriteString(strings.Repeat("-", 72) + "\n")
+
+	pass, fail, skip := 0, 0, 0
+	for _, r := range results {
+		mark := "PASS"
+		switch r.Status {
+		case "fail":
+			mark = "FAIL"
+			fail++
+		case "skip":
+			mark = "SKIP"
+			skip++
+		default:
+			pass++
+		}
+		b.WriteString(fmt.Sprintf("%-12s %-6s %-8s %s\n", r.Name, mark, fmt.Sprintf("%dms", r.Duration), r.Detail))
+	}
+	b.WriteString(fmt.Sprintf("\n%d passed, %d failed, %d skipped\n", pass, fail, skip))
+	return b.String()
+}
+
+// FormatSelftestJSON formats results as a JSON array.
+func FormatSelftestJSON(results []SelftestResult) (string, error) {
+	if results == nil {
+		results = []SelftestResult{}
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,69 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSelftest_AllPass(t *testing.T) {
+	SetConfig(DefaultConfig())
+	defer SetConfig(nil)
+
+	session, memDir, results := RunSelftest(SelftestOptions{SkipTmux: true, SkipOllama: true})
+
+	if len(results) != 7 {
+		t.Fatalf("expected 7 results, got %d: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Status != "pass" {
+			t.Errorf("%s: status = %s (%s)", r.Name, r.Status, r.Detail)
+		}
+	}
+	if !SelftestPassed(results) {
+		t.Error("SelftestPassed = false")
+	}
+	if _, err := os.Stat(BusDir(session)); !os.IsNotExist(err) {
+		t.Errorf("expected bus dir %s removed, stat err = %v", BusDir(session), err)
+	}
+	if _, err := os.Stat(memDir); !os.IsNotExist(err) {
+		t.Errorf("expected memory dir %s removed, stat err = %v", memDir, err)
+	}
+}
+
+func TestRunSelftest_Keep(t *testing.T) {
+	tmp := t.TempDir()
+	session, memDir, results := RunSelftest(SelftestOptions{SkipTmux: true, SkipOllama: true, Keep: true, TempDir: tmp})
+	t.Cleanup(func() { _ = Cleanup(session) })
+
+	if !SelftestPassed(results) {
+		t.Fatalf("selftest failed: %+v", results)
+	}
+	if _, err := os.Stat(BusDir(session)); err != nil {
+		t.Errorf("expected bus dir kept: %v", err)
+	}
+	if filepath.Dir(memDir) != tmp {
+		t.Errorf("memory dir %s not under %s", memDir, tmp)
+	}
+	if _, err := os.Stat(memDir); err != nil {
+		t.Errorf("expected memory dir kept: %v", err)
+	}
+}
+
+func TestFormatSelftestResults(t *testing.T) {
+	results := []SelftestResult{
+		{Name: "init", Status: "pass", Detail: "ok"},
+		{Name: "tmux", Status: "skip", Detail: "tmux not installed"},
+		{Name: "proc", Status: "fail", Detail: "boom"},
+	}
+	out := FormatSelftestResults(results)
+	for _, want := range []string{"PASS", "SKIP", "FAIL", "1 passed, 1 failed, 1 skipped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if SelftestPassed(results) {
+		t.Error("SelftestPassed = true with a failure")
+	}
+}
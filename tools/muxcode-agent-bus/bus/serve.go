@@ -0,0 +1,359 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeConfig holds configuration for the REST API server.
+type ServeConfig struct {
+	Host    string
+	Port    int
+	Token   string // required bearer token for every endpoint except /api/v1/health
+	Session string
+}
+
+// APIResponse is the JSON envelope for every REST API response.
+type APIResponse struct {
+	OK    bool        `json:"ok"`
+	Error string      `json:"error,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// APISendRequest is the JSON body for POST /api/v1/send.
+type APISendRequest struct {
+	From    string `json:"from"` // default "api"
+	To      string `json:"to"`
+	Type    string `json:"type"` // default "request"
+	Action  string `json:"action"`
+	Payload string `json:"payload"`
+	ReplyTo string `json:"reply_to"`
+	// CallbackURL receives the result when the request completes
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// APIMemoryResult is one memory search hit.
+type APIMemoryResult struct {
+	Role      string  `json:"role"`
+	Section   string  `json:"section"`
+	Timestamp string  `json:"timestamp"`
+	Content   string  `json:"content"`
+	Score     float64 `json:"score"`
+}
+
+// apiMaxBody caps request bodies.
+const apiMaxBody = 256 * 1024
+
+// ServeTokenPath returns the file holding the generated REST API token.
+func ServeTokenPath(session string) string {
+	return filepath.Join(BusDir(session), "serve.token")
+}
+
+// ServeToken returns the API token for a session: MUXCODE_SERVE_TOKEN, the
+// saved token, or a new random token saved (mode 0600) for later runs.
+func ServeToken(session string) (string, error) {
+	if v := os.Getenv("MUXCODE_SERVE_TOKEN"); v != "" {
+		return v, nil
+	}
+	if data, err := os.ReadFile(ServeTokenPath(session)); err == nil {
+		if t := strings.TrimSpace(string(data)); t != "" {
+			return t, nil
+		}
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, os.WriteFile(ServeTokenPath(session), []byte(token+"\n"), 0600)
+}
+
+// APIHandler returns the REST API routes, all under /api/v1.
+func APIHandler(cfg ServeConfig) http.Handler {
+	startTime := time.Now()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{
+			"session": cfg.Session, "uptime_seconds": int64(time.Since(startTime).Seconds()),
+		}})
+	})
+
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !bearerOK(r, cfg.Token) {
+				apiError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, apiMaxBody)
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("POST /api/v1/send", auth(apiSend(cfg.Session)))
+	mux.HandleFunc("GET /api/v1/inbox/{role}", auth(apiInbox(cfg.Session, false)))
+	mux.HandleFunc("POST /api/v1/inbox/{role}/receive", auth(apiInbox(cfg.Session, true)))
+	mux.HandleFunc("GET /api/v1/status", auth(func(w http.ResponseWriter, r *http.Request) {
+		apiOK(w, GetAllAgentStatus(cfg.Session))
+	}))
+	mux.HandleFunc("GET /api/v1/history", auth(apiHistory(cfg.Session)))
+	mux.HandleFunc("GET /api/v1/memory/search", auth(apiMemorySearch))
+	mux.HandleFunc("GET /api/v1/proc", auth(func(w http.ResponseWriter, r *http.Request) {
+		entries, err := RefreshProcStatus(cfg.Session)
+		apiResult(w, entries, err)
+	}))
+	mux.HandleFunc("POST /api/v1/proc", auth(apiProcStart(cfg.Session)))
+	mux.HandleFunc("DELETE /api/v1/proc/{id}", auth(func(w http.ResponseWriter, r *http.Request) {
+		apiResult(w, nil, StopProc(cfg.Session, r.PathValue("id")))
+	}))
+	mux.HandleFunc("GET /api/v1/spawn", auth(func(w http.ResponseWriter, r *http.Request) {
+		entries, err := RefreshSpawnStatus(cfg.Session)
+		apiResult(w, entries, err)
+	}))
+	mux.HandleFunc("POST /api/v1/spawn", auth(apiSpawnStart(cfg.Session)))
+	mux.HandleFunc("DELETE /api/v1/spawn/{id}", auth(func(w http.ResponseWriter, r *http.Request) {
+		apiResult(w, nil, StopSpawn(cfg.Session, r.PathValue("id")))
+	}))
+	return mux
+}
+
+// ServeAPI starts the REST API server in the foreground.
+// It blocks until the context is cancelled or the server is shut down.
+func ServeAPI(ctx context.Context, cfg ServeConfig) error {
+	if cfg.Token == "" {
+		return fmt.Errorf("a token is required")
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      APIHandler(cfg),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("REST API listening on http://%s/api/v1\n", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func apiOK(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: data})
+}
+
+func apiError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, APIResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+// apiResult writes data, or err as a 500.
+func apiResult(w http.ResponseWriter, data interface{}, err error) {
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	apiOK(w, data)
+}
+
+// apiDecode decodes a JSON body, writing a 400 on failure.
+func apiDecode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return false
+	}
+	return true
+}
+
+// apiRole validates a role path or body value, writing a 400 on failure.
+func apiRole(w http.ResponseWriter, role string) bool {
+	if !IsKnownRole(role) {
+		apiError(w, http.StatusBadRequest, "unknown role '%s'", role)
+		return false
+	}
+	return true
+}
+
+// apiLimit parses the limit query parameter.
+func apiLimit(r *http.Request, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// apiSend handles POST /api/v1/send: the same policy checks, auto-CC, and
+// notification as the send command.
+func apiSend(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req APISendRequest
+		if !apiDecode(w, r, &req) {
+			return
+		}
+		msg, status, err := sendAPIRequest(session, req)
+		if err != nil {
+			apiError(w, status, "%v", err)
+			return
+		}
+		apiOK(w, map[string]string{"id": msg.ID})
+	}
+}
+
+// sendAPIRequest validates, policy-checks, sends, and notifies an API send
+// request. On failure it returns the HTTP status to report.
+func sendAPIRequest(session string, req APISendRequest) (Message, int, error) {
+	if req.To == "" || req.Action == "" || req.Payload == "" {
+		return Message{}, http.StatusBadRequest, fmt.Errorf("to, action, and payload are required")
+	}
+	if !IsKnownRole(req.To) {
+		return Message{}, http.StatusBadRequest, fmt.Errorf("unknown role '%s'", req.To)
+	}
+	if req.From == "" {
+		req.From = "api"
+	}
+	if !validRoleName(req.From) {
+		return Message{}, http.StatusBadRequest, fmt.Errorf("invalid from '%s'", req.From)
+	}
+	if req.Type == "" {
+		req.Type = "request"
+	}
+	if req.CallbackURL != "" {
+		if err := CheckCallbackURL(req.CallbackURL); err != nil {
+			return Message{}, http.StatusBadRequest, err
+		}
+	}
+	msg := NewMessage(req.From, req.To, req.Type, req.Action, req.Payload, req.ReplyTo)
+	msg.CallbackURL = req.CallbackURL
+	if deny := CheckMessagePolicy(session, msg); deny != "" {
+		return Message{}, http.StatusForbidden, fmt.Errorf("%s", deny)
+	}
+	if err := Send(session, msg); err != nil {
+		return Message{}, http.StatusInternalServerError, fmt.Errorf("send failed: %v", err)
+	}
+	_ = Notify(session, req.To)
+	return msg, http.StatusOK, nil
+}
+
+// apiInbox handles GET /api/v1/inbox/{role} (peek) and
+// POST /api/v1/inbox/{role}/receive (consume).
+func apiInbox(session string, consume bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := r.PathValue("role")
+		if !apiRole(w, role) {
+			return
+		}
+		var msgs []Message
+		var err error
+		if consume {
+			msgs, err = Receive(session, role)
+		} else {
+			msgs, err = Peek(session, role)
+		}
+		if msgs == nil {
+			msgs = []Message{}
+		}
+		apiResult(w, msgs, err)
+	}
+}
+
+// apiHistory handles GET /api/v1/history?role=&limit=.
+func apiHistory(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := r.URL.Query().Get("role")
+		if role != "" && !apiRole(w, role) {
+			return
+		}
+		msgs := ReadLogHistory(session, role, apiLimit(r, 50))
+		if msgs == nil {
+			msgs = []Message{}
+		}
+		apiOK(w, msgs)
+	}
+}
+
+// apiMemorySearch handles GET /api/v1/memory/search?q=&role=&limit= (BM25).
+func apiMemorySearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		apiError(w, http.StatusBadRequest, "missing query parameter: q")
+		return
+	}
+	results, err := SearchMemoryWithOptions(SearchOptions{
+		Query: q.Get("q"), RoleFilter: q.Get("role"), Limit: apiLimit(r, 10), Mode: SearchModeBM25,
+	})
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	out := make([]APIMemoryResult, 0, len(results))
+	for _, res := range results {
+		out = append(out, APIMemoryResult{
+			Role: res.Entry.Role, Section: res.Entry.Section, Timestamp: res.Entry.Timestamp,
+			Content: res.Entry.Content, Score: res.Score,
+		})
+	}
+	apiOK(w, out)
+}
+
+// apiProcStart handles POST /api/v1/proc {command, dir, owner}.
+func apiProcStart(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Command string `json:"command"`
+			Dir     string `json:"dir"`
+			Owner   string `json:"owner"`
+		}
+		if !apiDecode(w, r, &req) {
+			return
+		}
+		if req.Command == "" || req.Owner == "" {
+			apiError(w, http.StatusBadRequest, "command and owner are required")
+			return
+		}
+		if !apiRole(w, req.Owner) {
+			return
+		}
+		if req.Dir == "" {
+			req.Dir, _ = os.Getwd()
+		}
+		entry, err := StartProc(session, req.Command, req.Dir, req.Owner)
+		apiResult(w, entry, err)
+	}
+}
+
+// apiSpawnStart handles POST /api/v1/spawn {role, task, owner}.
+func apiSpawnStart(session string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Role  string `json:"role"`
+			Task  string `json:"task"`
+			Owner string `json:"owner"`
+		}
+		if !apiDecode(w, r, &req) {
+			return
+		}
+		if req.Role == "" || req.Task == "" || req.Owner == "" {
+			apiError(w, http.StatusBadRequest, "role, task, and owner are required")
+			return
+		}
+		if !apiRole(w, req.Owner) {
+			return
+		}
+		entry, err := StartSpawn(session, req.Role, req.Task, req.Owner)
+		apiResult(w, entry, err)
+	}
+}
@@ -0,0 +1,119 @@
+package bus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func apiRequest(t *testing.T, h http.Handler, method, path, token, body string) (int, APIResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: decode %q: %v", method, path, w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestAPIHandler_Auth(t *testing.T) {
+	session := testSession(t)
+	h := APIHandler(ServeConfig{Session: session, Token: "secret"})
+
+	if code, _ := apiRequest(t, h, "GET", "/api/v1/health", "", ""); code != http.StatusOK {
+		t.Errorf("health without token = %d, want 200", code)
+	}
+	if code, _ := apiRequest(t, h, "GET", "/api/v1/status", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", code)
+	}
+	if code, _ := apiRequest(t, h, "GET", "/api/v1/status", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("status with wrong token = %d, want 401", code)
+	}
+	if code, resp := apiRequest(t, h, "GET", "/api/v1/status", "secret", ""); code != http.StatusOK || !resp.OK {
+		t.Errorf("status = %d %+v, want 200 ok", code, resp)
+	}
+}
+
+func TestAPIHandler_SendInboxHistory(t *testing.T) {
+	session := testSession(t)
+	h := APIHandler(ServeConfig{Session: session, Token: "secret"})
+
+	code, resp := apiRequest(t, h, "POST", "/api/v1/send", "secret", `{"to":"review","action":"review","payload":"check the diff"}`)
+	if code != http.StatusOK {
+		t.Fatalf("send = %d %+v", code, resp)
+	}
+	if code, resp := apiRequest(t, h, "POST", "/api/v1/send", "secret", `{"to":"nobody","action":"x","payload":"y"}`); code != http.StatusBadRequest {
+		t.Errorf("send to unknown role = %d %+v, want 400", code, resp)
+	}
+
+	// Peek leaves the message; receive consumes it
+	_, resp = apiRequest(t, h, "GET", "/api/v1/inbox/review", "secret", "")
+	if msgs, _ := resp.Data.([]interface{}); len(msgs) != 1 {
+		t.Fatalf("peek data = %v, want 1 message", resp.Data)
+	}
+	_, resp = apiRequest(t, h, "POST", "/api/v1/inbox/review/receive", "secret", "")
+	if msgs, _ := resp.Data.([]interface{}); len(msgs) != 1 {
+		t.Fatalf("receive data = %v, want 1 message", resp.Data)
+	}
+	_, resp = apiRequest(t, h, "GET", "/api/v1/inbox/review", "secret", "")
+	if msgs, _ := resp.Data.([]interface{}); len(msgs) != 0 {
+		t.Errorf("inbox after receive = %v, want empty", resp.Data)
+	}
+
+	_, resp = apiRequest(t, h, "GET", "/api/v1/history?role=review&limit=5", "secret", "")
+	msgs, _ := resp.Data.([]interface{})
+	if len(msgs) != 1 || msgs[0].(map[string]interface{})["from"] != "api" {
+		t.Errorf("history = %v, want one message from api", resp.Data)
+	}
+}
+
+func TestAPIHandler_Validation(t *testing.T) {
+	session := testSession(t)
+	h := APIHandler(ServeConfig{Session: session, Token: "secret"})
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/v1/send", `{"to":"build"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/send", `not json`, http.StatusBadRequest},
+		{"GET", "/api/v1/memory/search", "", http.StatusBadRequest},
+		{"POST", "/api/v1/proc", `{"command":"true"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/spawn", `{"role":"research","task":"x","owner":"nobody"}`, http.StatusBadRequest},
+		{"GET", "/api/v1/inbox/nobody", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, resp := apiRequest(t, h, tt.method, tt.path, "secret", tt.body); code != tt.want {
+			t.Errorf("%s %s %s = %d %+v, want %d", tt.method, tt.path, tt.body, code, resp, tt.want)
+		}
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	session := testSession(t)
+	t.Setenv("MUXCODE_SERVE_TOKEN", "")
+
+	first, err := ServeToken(session)
+	if err != nil || len(first) != 48 {
+		t.Fatalf("ServeToken = %q, %v", first, err)
+	}
+	if second, _ := ServeToken(session); second != first {
+		t.Errorf("token not reused: %q != %q", second, first)
+	}
+	if info, err := os.Stat(ServeTokenPath(session)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	t.Setenv("MUXCODE_SERVE_TOKEN", "from-env")
+	if got, _ := ServeToken(session); got != "from-env" {
+		t.Errorf("ServeToken = %q, want from-env", got)
+	}
+}
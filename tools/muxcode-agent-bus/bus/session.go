@@ -130,7 +130,7 @@ func ResumeContext(role string) (string, error) {
 			start = 0
 		}
 		for _, date := range dates[start:] {
-			archiveContent, err := os.ReadFile(MemoryArchivePath(role, date))
+			archiveContent, err := readBusFile(MemoryArchivePath(role, date))
 			if err != nil {
 				continue
 			}
@@ -30,11 +30,19 @@ func Init(session, memoryDir string) error {
 		return err
 	}
 
+	// Re-init starts from the configured team: drop runtime role changes
+	if reInit {
+		resetSessionRoles(session)
+	}
+
 	// Create (or truncate on re-init) inbox files for all known roles
 	for _, role := range KnownRoles {
 		if err := resetFile(InboxPath(session, role), reInit); err != nil {
 			return err
 		}
+		if reInit {
+			clearInbox(session, role)
+		}
 	}
 
 	// Create (or truncate) log file
@@ -167,13 +175,29 @@ func purgeStaleFiles(session string) error {
 		}
 	}
 
-	// Remove trigger file
-	_ = os.Remove(TriggerFile(session))
+	// Remove the edit log
+	_ = os.Remove(EditLogPath(session))
+
+	// Remove the previous session template's config overlay
+	_ = os.Remove(SessionConfigPath(session))
 
-	// Remove webhook PID file
+	// Remove span log and per-role trace contexts
+	_ = os.Remove(TracePath(session))
+	_ = os.RemoveAll(filepath.Join(busDir, "trace"))
+
+	// Remove attachment blobs, the inbox archive, and read state
+	_ = os.RemoveAll(AttachmentDir(session))
+	_ = os.RemoveAll(ArchiveDir(session))
+	_ = os.RemoveAll(filepath.Join(busDir, "cursor"))
+	_ = os.Remove(ReceiptsPath(session))
+
+	// Remove webhook PID file and delivery metrics
 	_ = os.Remove(WebhookPidPath(session))
+	_ = os.Remove(WebhookStatsPath(session))
 
-	// Remove harness marker PID files (harness-*.pid) and notify dedup markers (notified-*.size)
+	// Remove harness marker PID files (harness-*.pid), notify dedup markers
+	// (notified-*.size), deferred notify markers (notify-pending-*), and
+	// desktop notify markers (desktop-notified-*)
 	entries, err = os.ReadDir(busDir)
 	if err == nil {
 		for _, e := range entries {
@@ -184,6 +208,9 @@ func purgeStaleFiles(session string) error {
 			if strings.HasPrefix(name, "notified-") && strings.HasSuffix(name, ".size") {
 				_ = os.Remove(filepath.Join(busDir, name))
 			}
+			if strings.HasPrefix(name, "notify-pending-") || strings.HasPrefix(name, "desktop-notified-") {
+				_ = os.Remove(filepath.Join(busDir, name))
+			}
 		}
 	}
 
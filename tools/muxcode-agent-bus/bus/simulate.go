@@ -0,0 +1,668 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SimScenario scripts a simulation: fake agents that react to messages, the
+// steps that drive them, and the message flow the config should produce.
+type SimScenario struct {
+	Name          string               `json:"name"`
+	Description   string               `json:"description,omitempty"`
+	Config        string               `json:"config,omitempty"` // muxcode.json to test, relative to the scenario (default: the effective config)
+	Agents        map[string][]SimRule `json:"agents,omitempty"`
+	Cron          []SimCron            `json:"cron,omitempty"`
+	Subscriptions []SimSubscription    `json:"subscriptions,omitempty"`
+	Steps         []SimStep            `json:"steps"`
+	Expect        []SimExpect          `json:"expect,omitempty"`
+	Ordered       bool                 `json:"ordered,omitempty"` // expectations without count must match in order
+	MaxMessages   int                  `json:"max_messages,omitempty"`
+}
+
+// SimRule is how a fake agent reacts to a message it receives. The first
+// rule matching the message's action (and sender, when set) applies.
+type SimRule struct {
+	On    string       `json:"on"`             // action received; "*" or empty for any
+	From  string       `json:"from,omitempty"` // only messages from this role
+	Run   string       `json:"run,omitempty"`  // command the agent runs: logged to history and chained
+	Event string       `json:"event,omitempty"`
+	Exit  int          `json:"exit,omitempty"`
+	Reply string       `json:"reply,omitempty"` // payload of a response to the sender
+	Send  []SimMessage `json:"send,omitempty"`
+}
+
+// SimMessage is a message sent by a step or a fake agent.
+type SimMessage struct {
+	From    string `json:"from,omitempty"` // steps only; default "edit"
+	To      string `json:"to"`
+	Type    string `json:"type,omitempty"` // default "request"
+	Action  string `json:"action"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// SimRun is a command outcome reported for a role, as the bash hook would.
+type SimRun struct {
+	Role    string `json:"role"`
+	Command string `json:"command"`
+	Event   string `json:"event,omitempty"` // chain event type (default: the role)
+	Exit    int    `json:"exit,omitempty"`
+}
+
+// SimStep is one scenario step: send a message, report a command outcome,
+// or advance the simulated clock.
+type SimStep struct {
+	Send *SimMessage `json:"send,omitempty"`
+	Run  *SimRun     `json:"run,omitempty"`
+	Tick string      `json:"tick,omitempty"` // Go duration; due cron entries fire minute by minute
+}
+
+// SimCron is a cron entry installed in the simulated session.
+type SimCron struct {
+	Schedule string `json:"schedule"`
+	Target   string `json:"target"`
+	Action   string `json:"action"`
+	Message  string `json:"message,omitempty"`
+}
+
+// SimSubscription is an event subscription installed in the simulated
+// session. Webhook and Slack targets are not supported.
+type SimSubscription struct {
+	Event    string `json:"event"`
+	Outcome  string `json:"outcome"`
+	Notify   string `json:"notify"`
+	Action   string `json:"action,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Match    string `json:"match,omitempty"`
+	RoleFrom string `json:"role_from,omitempty"`
+}
+
+// SimExpect asserts on the messages sent during a simulation. Empty fields
+// match anything; Contains is a payload substring.
+type SimExpect struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Count    *int   `json:"count,omitempty"` // exact number of matches (default: at least one)
+}
+
+// SimResult is the outcome of a simulation.
+type SimResult struct {
+	Name     string
+	Session  string
+	Messages []Message // every message sent, in order
+	Notes    []string  // unhandled messages, denials, skipped plugins, loop alerts
+	Checks   []SimCheck
+	Err      error // the simulation could not run to completion
+}
+
+// SimCheck is the result of one expectation.
+type SimCheck struct {
+	Expect SimExpect
+	Passed bool
+	Detail string
+}
+
+// defaultSimMaxMessages stops runaway chains.
+const defaultSimMaxMessages = 500
+
+// simMaxTickMinutes bounds the clock steps a single tick may take.
+const simMaxTickMinutes = 7 * 24 * 60
+
+// LoadSimScenario reads a scenario from a YAML (the subset parseYAMLSubset
+// accepts) or JSON file. Unknown fields are rejected so typos surface.
+func LoadSimScenario(path string) (SimScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SimScenario{}, err
+	}
+	if !strings.HasSuffix(path, ".json") {
+		doc, err := parseYAMLSubset(string(data))
+		if err != nil {
+			return SimScenario{}, fmt.Errorf("parsing %s: %v", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return SimScenario{}, err
+		}
+	}
+	var s SimScenario
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return SimScenario{}, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if s.Config != "" && !filepath.IsAbs(s.Config) {
+		s.Config = filepath.Join(filepath.Dir(path), s.Config)
+	}
+	if len(s.Steps) == 0 {
+		return SimScenario{}, fmt.Errorf("%s: scenario has no steps", path)
+	}
+	return s, nil
+}
+
+// loadSimConfig reads a muxcode.json to simulate, merged over defaults.
+func loadSimConfig(path string) (*MuxcodeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg MuxcodeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	return mergeConfigs(DefaultConfig(), &cfg), nil
+}
+
+// SimMarkerPath returns the file marking a bus session as simulated.
+// Notify skips simulated sessions: they have no tmux panes.
+func SimMarkerPath(session string) string {
+	return filepath.Join(BusDir(session), "simulated")
+}
+
+// isSimulated reports whether session is a simulation.
+func isSimulated(session string) bool {
+	_, err := os.Stat(SimMarkerPath(session))
+	return err == nil
+}
+
+// simulator is the state of one simulation run.
+type simulator struct {
+	s        SimScenario
+	session  string
+	now      int64
+	handled  int
+	alerts   map[string]int64
+	notes    []string
+	maxMsgs  int
+	agentSet []string
+}
+
+// RunSimulation runs a scenario in a temporary bus session, in-process and
+// without tmux or an LLM: scripted fake agents consume their inboxes, their
+// command outcomes drive event chains and subscriptions, cron entries fire
+// as the simulated clock advances, and loop detection runs after every
+// command. The session is removed afterwards unless keep is set.
+func RunSimulation(s SimScenario, keep bool) SimResult {
+	res := SimResult{Name: s.Name, Session: fmt.Sprintf("simulate-%d", time.Now().UnixNano())}
+
+	if s.Config != "" {
+		cfg, err := loadSimConfig(s.Config)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		prev := configSingleton
+		SetConfig(cfg)
+		defer SetConfig(prev)
+	}
+
+	memDir, err := os.MkdirTemp("", "muxcode-simulate-mem-")
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	// Memory functions resolve their directory from BUS_MEMORY_DIR
+	prevMemDir, hadMemDir := os.LookupEnv("BUS_MEMORY_DIR")
+	os.Setenv("BUS_MEMORY_DIR", memDir)
+	defer func() {
+		if hadMemDir {
+			os.Setenv("BUS_MEMORY_DIR", prevMemDir)
+		} else {
+			os.Unsetenv("BUS_MEMORY_DIR")
+		}
+	}()
+	if err := Init(res.Session, memDir); err != nil {
+		res.Err = err
+		return res
+	}
+	if !keep {
+		defer func() {
+			_ = Cleanup(res.Session)
+			_ = os.RemoveAll(memDir)
+		}()
+	}
+	_ = os.WriteFile(SimMarkerPath(res.Session), []byte(s.Name+"\n"), 0644)
+
+	sim := &simulator{
+		s:       s,
+		session: res.Session,
+		now:     time.Now().Unix(),
+		alerts:  make(map[string]int64),
+		maxMsgs: s.MaxMessages,
+	}
+	if sim.maxMsgs <= 0 {
+		sim.maxMsgs = defaultSimMaxMessages
+	}
+	res.Err = sim.run()
+	res.Notes = sim.notes
+	res.Messages, _ = readMessages(LogPath(res.Session))
+	res.Checks = CheckSimExpectations(s.Expect, res.Messages, s.Ordered)
+	return res
+}
+
+// run installs the scenario's agents, cron entries, and subscriptions and
+// executes its steps.
+func (sim *simulator) run() error {
+	for role := range sim.s.Agents {
+		if !IsKnownRole(role) {
+			return fmt.Errorf("agents: unknown role %q", role)
+		}
+		sim.agentSet = append(sim.agentSet, role)
+	}
+	sort.Strings(sim.agentSet)
+
+	for i, c := range sim.s.Cron {
+		if _, err := AddCronEntry(sim.session, CronEntry{Schedule: c.Schedule, Target: c.Target, Action: c.Action, Message: c.Message}); err != nil {
+			return fmt.Errorf("cron %d: %v", i+1, err)
+		}
+	}
+	for i, sub := range sim.s.Subscriptions {
+		_, err := AddSubscription(sim.session, Subscription{
+			Event: sub.Event, Outcome: sub.Outcome, Notify: sub.Notify, Action: sub.Action,
+			Message: sub.Message, Match: sub.Match, RoleFrom: sub.RoleFrom,
+		})
+		if err != nil {
+			return fmt.Errorf("subscription %d: %v", i+1, err)
+		}
+	}
+
+	for i, step := range sim.s.Steps {
+		var err error
+		switch {
+		case step.Send != nil:
+			m := *step.Send
+			if m.From == "" {
+				m.From = "edit"
+			}
+			err = sim.send(m.From, m, "")
+		case step.Run != nil:
+			if !IsKnownRole(step.Run.Role) {
+				err = fmt.Errorf("unknown role %q", step.Run.Role)
+				break
+			}
+			err = sim.runCommand(step.Run.Role, step.Run.Event, step.Run.Command, step.Run.Exit)
+		case step.Tick != "":
+			err = sim.tick(step.Tick)
+		default:
+			err = fmt.Errorf("step needs send, run, or tick")
+		}
+		if err == nil {
+			err = sim.drain()
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// send sends a message from a step or fake agent, applying send policy as
+// "muxcode-agent-bus send" does.
+func (sim *simulator) send(from string, m SimMessage, replyTo string) error {
+	if m.Type == "" {
+		m.Type = "request"
+	}
+	if !IsKnownRole(m.To) {
+		return fmt.Errorf("unknown role %q", m.To)
+	}
+	msg := NewMessage(from, m.To, m.Type, m.Action, m.Payload, replyTo)
+	msg.TS = sim.now
+	if deny := CheckMessagePolicy(sim.session, msg); deny != "" {
+		sim.note("denied %s → %s %s: %s", from, m.To, m.Action, deny)
+		return nil
+	}
+	return Send(sim.session, msg)
+}
+
+// note records a simulation note.
+func (sim *simulator) note(format string, args ...interface{}) {
+	sim.notes = append(sim.notes, fmt.Sprintf(format, args...))
+}
+
+// drain lets every fake agent consume its inbox until the bus is quiet.
+func (sim *simulator) drain() error {
+	for {
+		busy := false
+		for _, role := range sim.agentSet {
+			msgs, err := Receive(sim.session, role)
+			if err != nil {
+				return err
+			}
+			for _, m := range msgs {
+				busy = true
+				sim.handled++
+				if sim.handled > sim.maxMsgs {
+					return fmt.Errorf("stopped after %d messages: possible message loop", sim.maxMsgs)
+				}
+				if err := sim.handle(role, m); err != nil {
+					return err
+				}
+			}
+		}
+		if !busy {
+			return nil
+		}
+	}
+}
+
+// handle applies the first matching rule of a fake agent to a message.
+func (sim *simulator) handle(role string, m Message) error {
+	var rule *SimRule
+	for i, r := range sim.s.Agents[role] {
+		if (r.On == "" || r.On == "*" || r.On == m.Action) && (r.From == "" || r.From == m.From) {
+			rule = &sim.s.Agents[role][i]
+			break
+		}
+	}
+	if rule == nil {
+		sim.note("%s ignored %s:%s from %s (no rule)", role, m.Type, m.Action, m.From)
+		return nil
+	}
+	if rule.Run != "" {
+		if err := sim.runCommand(role, rule.Event, rule.Run, rule.Exit); err != nil {
+			return err
+		}
+	}
+	if rule.Reply != "" {
+		if err := sim.send(role, SimMessage{To: m.From, Type: "response", Action: m.Action, Payload: rule.Reply}, m.ID); err != nil {
+			return err
+		}
+	}
+	for _, out := range rule.Send {
+		if err := sim.send(role, out, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCommand records a command outcome for role and runs what the bash
+// hook would: the event chain, the analyst notification, and subscription
+// fan-out. Loop detection runs afterwards, as the watcher would.
+func (sim *simulator) runCommand(role, event, command string, exit int) error {
+	if event == "" {
+		event = role
+	}
+	outcome := "success"
+	if exit != 0 {
+		outcome = "failure"
+	}
+	exitCode := strconv.Itoa(exit)
+	entry, _ := json.Marshal(HistoryEntry{TS: sim.now, Command: command, ExitCode: exitCode, Outcome: outcome})
+	if err := AppendHistory(sim.session, role, entry, 100); err != nil {
+		return err
+	}
+
+	// Retries hold the failure actions; backoff is not simulated
+	switch outcome {
+	case "failure":
+		r, retrying, err := ScheduleChainRetry(sim.session, role, event, exitCode, command, time.Unix(sim.now, 0))
+		if err != nil {
+			return err
+		}
+		if retrying {
+			total := Config().EventChains[event].Retries
+			sim.note("chain %s failure: retry %d/%d requested from %s", event, r.Attempts, total, role)
+			msg := NewMessage(role, role, "request", event, chainRetryPayload(r, total), "")
+			msg.TS = sim.now
+			if err := SendNoCC(sim.session, msg); err != nil {
+				return err
+			}
+			return sim.checkLoops()
+		}
+	case "success":
+		if err := ResetChainRetry(sim.session, role, event); err != nil {
+			return err
+		}
+	}
+
+	// As "muxcode-agent-bus chain": nothing fires without a configured chain
+	actions := ResolveChainSteps(event, outcome, command)
+	if len(actions) == 0 {
+		return sim.checkLoops()
+	}
+	for i, a := range actions {
+		if a.Approval != nil {
+			if met, _ := ApprovalConditionMet(ExpandMessage(a.Approval.When, exitCode, command)); met {
+				sim.note("chain %s %s: held for approval by %s; %d step(s) not sent", event, outcome, a.Approval.approver(), len(actions)-i)
+				break
+			}
+		}
+		if a.Plugin != "" {
+			sim.note("chain %s %s: plugin %s not run in simulation", event, outcome, a.Plugin)
+			continue
+		}
+		if a.Promote != "" {
+			sim.note("chain %s %s: promotion gate to %s not checked in simulation", event, outcome, a.Promote)
+		}
+		// Delays are not simulated: delayed steps are sent immediately
+		msg := NewMessage(role, a.SendTo, a.Type, a.Action, ExpandMessage(a.Message, exitCode, command), "")
+		msg.TS = sim.now
+		if err := SendNoCC(sim.session, msg); err != nil {
+			return err
+		}
+	}
+	if ChainShouldNotifyAnalyst(event, outcome) && !simTargets(actions, "analyze") {
+		var text string
+		switch outcome {
+		case "success":
+			text = fmt.Sprintf("%s succeeded: %s", strings.ToUpper(event[:1])+event[1:], command)
+		case "failure":
+			text = fmt.Sprintf("%s FAILED (exit %s): %s", strings.ToUpper(event[:1])+event[1:], exitCode, command)
+		}
+		msg := NewMessage(role, "analyze", "event", "notify", text, "")
+		msg.TS = sim.now
+		if err := SendNoCC(sim.session, msg); err != nil {
+			return err
+		}
+	}
+	if _, err := FireSubscriptions(sim.session, role, event, outcome, exitCode, command); err != nil {
+		return err
+	}
+	return sim.checkLoops()
+}
+
+// simTargets reports whether any chain action sends to role.
+func simTargets(actions []ChainAction, role string) bool {
+	for _, a := range actions {
+		if a.SendTo == role {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLoops sends loop-detected alerts to edit like the watcher, without
+// remediation.
+func (sim *simulator) checkLoops() error {
+	for _, alert := range FilterNewAlerts(CheckAllLoops(sim.session), sim.alerts, 600) {
+		sim.note("loop detected: %s (%s)", alert.Role, alert.Type)
+		msg := NewMessage("watcher", "edit", "event", "loop-detected", alert.Message, "")
+		msg.TS = sim.now
+		if err := Send(sim.session, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tick advances the simulated clock minute by minute, firing due cron
+// entries and letting agents react at each minute.
+func (sim *simulator) tick(spec string) error {
+	d, err := time.ParseDuration(spec)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("tick must be a positive duration, got %q", spec)
+	}
+	end := sim.now + int64(d.Seconds())
+	for steps := 0; sim.now < end; steps++ {
+		if steps >= simMaxTickMinutes {
+			return fmt.Errorf("tick %s is longer than %d minutes", spec, simMaxTickMinutes)
+		}
+		sim.now += 60
+		if sim.now > end {
+			sim.now = end
+		}
+		entries, err := ReadCronEntries(sim.session)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !CronDue(e, sim.now) {
+				continue
+			}
+			if _, err := ExecuteCron(sim.session, e); err != nil {
+				return err
+			}
+			if err := RecordCronRuns(sim.session, e.ID, sim.now, 1); err != nil {
+				return err
+			}
+		}
+		if err := sim.drain(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether a message satisfies the expectation's filters.
+func (e SimExpect) matches(m Message) bool {
+	return (e.From == "" || e.From == m.From) &&
+		(e.To == "" || e.To == m.To) &&
+		(e.Type == "" || e.Type == m.Type) &&
+		(e.Action == "" || e.Action == m.Action) &&
+		(e.Contains == "" || strings.Contains(m.Payload, e.Contains))
+}
+
+// String describes the expectation, e.g. "build → test request:test".
+func (e SimExpect) String() string {
+	from, to := e.From, e.To
+	if from == "" {
+		from = "*"
+	}
+	if to == "" {
+		to = "*"
+	}
+	typ, action := e.Type, e.Action
+	if typ == "" {
+		typ = "*"
+	}
+	if action == "" {
+		action = "*"
+	}
+	s := fmt.Sprintf("%s → %s %s:%s", from, to, typ, action)
+	if e.Contains != "" {
+		s += fmt.Sprintf(" containing %q", e.Contains)
+	}
+	if e.Count != nil {
+		s += fmt.Sprintf(" ×%d", *e.Count)
+	}
+	return s
+}
+
+// CheckSimExpectations checks expectations against the sent messages. With
+// ordered set, expectations without a count must match in order, each
+// after the previous one's match.
+func CheckSimExpectations(expects []SimExpect, msgs []Message, ordered bool) []SimCheck {
+	var checks []SimCheck
+	next := 0
+	for _, e := range expects {
+		c := SimCheck{Expect: e}
+		if e.Count != nil {
+			n := 0
+			for _, m := range msgs {
+				if e.matches(m) {
+					n++
+				}
+			}
+			c.Passed = n == *e.Count
+			c.Detail = fmt.Sprintf("%d matching message(s)", n)
+			checks = append(checks, c)
+			continue
+		}
+		start := 0
+		if ordered {
+			start = next
+		}
+		for i := start; i < len(msgs); i++ {
+			if e.matches(msgs[i]) {
+				c.Passed = true
+				c.Detail = "matched " + msgs[i].ID
+				next = i + 1
+				break
+			}
+		}
+		if !c.Passed {
+			c.Detail = "no matching message"
+			if ordered && start > 0 {
+				c.Detail += " after the previous expectation"
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Passed reports whether the simulation completed and every expectation held.
+func (r SimResult) Passed() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatSimResult renders the message flow, notes, and expectation results.
+func FormatSimResult(r SimResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Simulation: %s ===\n\n", r.Name)
+	b.WriteString("Messages:\n")
+	if len(r.Messages) == 0 {
+		b.WriteString("  (none)\n")
+	}
+	for i, m := range r.Messages {
+		payload := strings.ReplaceAll(m.Payload, "\n", " ")
+		if len(payload) > 60 {
+			payload = payload[:57] + "..."
+		}
+		fmt.Fprintf(&b, "  %3d  %-8s → %-8s %s:%s  %s\n", i+1, m.From, m.To, m.Type, m.Action, payload)
+	}
+	if len(r.Notes) > 0 {
+		b.WriteString("\nNotes:\n")
+		for _, n := range r.Notes {
+			fmt.Fprintf(&b, "  - %s\n", n)
+		}
+	}
+	if r.Err != nil {
+		fmt.Fprintf(&b, "\nError: %v\n", r.Err)
+	}
+	if len(r.Checks) > 0 {
+		b.WriteString("\nExpectations:\n")
+		passed := 0
+		for _, c := range r.Checks {
+			mark := "✗"
+			if c.Passed {
+				mark = "✓"
+				passed++
+			}
+			fmt.Fprintf(&b, "  %s %s (%s)\n", mark, c.Expect, c.Detail)
+		}
+		fmt.Fprintf(&b, "\n%d/%d expectations passed\n", passed, len(r.Checks))
+	}
+	return b.String()
+}
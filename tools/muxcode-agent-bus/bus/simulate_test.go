@@ -0,0 +1,138 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const simTestConfig = `{
+  "event_chains": {
+    "build": {
+      "on_success": {"send_to": "test", "action": "test", "message": "Build passed: ${command}", "type": "request"},
+      "on_failure": {"send_to": "edit", "action": "notify", "message": "Build failed", "type": "event"}
+    },
+    "test": {
+      "on_failure": {"send_to": "edit", "action": "notify", "message": "Tests failed (exit ${exit_code})", "type": "event"}
+    }
+  }
+}`
+
+const simTestScenario = `name: build-test
+config: muxcode.json
+agents:
+  build:
+    - on: build
+      run: ./build.sh
+  test:
+    - on: test
+      from: build
+      run: go test ./...
+      exit: 1
+      reply: 2 failures
+  review:
+    - on: "*"
+cron:
+  - schedule: "@every 5m"
+    target: review
+    action: review
+    message: periodic review
+steps:
+  - send: {to: build, action: build, payload: build it}
+  - tick: 6m
+expect:
+  - {from: edit, to: build, action: build}
+  - {from: build, to: test, type: request, action: test, contains: ./build.sh}
+  - {from: test, to: edit, action: notify, contains: exit 1}
+  - {to: review, action: review, count: 2}
+  - {to: deploy, count: 0}
+ordered: true
+`
+
+func writeSimScenario(t *testing.T, scenario string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "muxcode.json"), []byte(simTestConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "scenario.yaml")
+	if err := os.WriteFile(path, []byte(scenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunSimulation(t *testing.T) {
+	s, err := LoadSimScenario(writeSimScenario(t, simTestScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := RunSimulation(s, false)
+	if !res.Passed() {
+		t.Fatalf("simulation failed:\n%s", FormatSimResult(res))
+	}
+
+	// The test agent replied to build, which has no rule for responses
+	var replied bool
+	for _, m := range res.Messages {
+		if m.From == "test" && m.To == "build" && m.Type == "response" && m.Payload == "2 failures" {
+			replied = true
+		}
+	}
+	if !replied {
+		t.Error("missing reply from test to build")
+	}
+	if _, err := os.Stat(BusDir(res.Session)); !os.IsNotExist(err) {
+		t.Error("simulation session not cleaned up")
+	}
+	out := FormatSimResult(res)
+	if !strings.Contains(out, "5/5 expectations passed") || !strings.Contains(out, "build ignored response:test") {
+		t.Errorf("format:\n%s", out)
+	}
+}
+
+func TestRunSimulation_FailedExpectation(t *testing.T) {
+	scenario := strings.Replace(simTestScenario, "{to: deploy, count: 0}", "{to: deploy, action: deploy}", 1)
+	s, err := LoadSimScenario(writeSimScenario(t, scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := RunSimulation(s, false)
+	if res.Passed() {
+		t.Fatal("expected a failed expectation")
+	}
+	if c := res.Checks[len(res.Checks)-1]; c.Passed || c.Detail != "no matching message after the previous expectation" {
+		t.Errorf("check = %+v", c)
+	}
+}
+
+func TestRunSimulation_MessageLoop(t *testing.T) {
+	scenario := `config: muxcode.json
+agents:
+  edit:
+    - send: [{to: review, action: ping}]
+  review:
+    - send: [{to: edit, action: ping}]
+steps:
+  - send: {to: review, action: ping}
+max_messages: 20
+`
+	s, err := LoadSimScenario(writeSimScenario(t, scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := RunSimulation(s, false)
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "possible message loop") {
+		t.Errorf("err = %v\n%s", res.Err, FormatSimResult(res))
+	}
+}
+
+func TestLoadSimScenario_Errors(t *testing.T) {
+	if _, err := LoadSimScenario(writeSimScenario(t, "name: x\nstep: []\n")); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("unknown field: %v", err)
+	}
+	if _, err := LoadSimScenario(writeSimScenario(t, "name: x\n")); err == nil {
+		t.Error("scenario without steps should fail")
+	}
+}
@@ -14,8 +14,11 @@ type SkillDef struct {
 	Description string
 	Roles       []string // empty = applies to all roles
 	Tags        []string
+	Version     string   // semantic version, "" if unversioned
+	Requires    []string // required skills: "name" or "name>=version"
 	Body        string
 	Source      string // "project" or "user"
+	Path        string // file the skill was parsed from
 }
 
 // SkillSearchResult pairs a skill with its relevance score.
@@ -51,6 +54,7 @@ func parseSkillFile(path, source string) (SkillDef, error) {
 	skill := SkillDef{
 		Name:   name,
 		Source: source,
+		Path:   path,
 	}
 
 	// Split frontmatter from body
@@ -93,6 +97,10 @@ func parseFrontmatter(text string, skill *SkillDef) {
 			skill.Roles = parseYAMLList(val)
 		case "tags":
 			skill.Tags = parseYAMLList(val)
+		case "version":
+			skill.Version = strings.Trim(val, `"'`)
+		case "requires":
+			skill.Requires = parseYAMLList(val)
 		}
 	}
 }
@@ -0,0 +1,355 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SkillRequirement is one entry of a skill's requires list: a skill name
+// and an optional minimum version ("deploy-checklist>=1.2").
+type SkillRequirement struct {
+	Name       string
+	MinVersion string
+}
+
+// SkillInstall records one skill written by InstallSkill.
+type SkillInstall struct {
+	Name     string
+	Version  string
+	Replaced bool   // an installed copy was overwritten
+	Previous string // version replaced
+}
+
+// SkillUpgrade is an installed skill with a newer version in the source.
+type SkillUpgrade struct {
+	Name      string
+	Installed string
+	Available string
+}
+
+// ParseSkillRequirement parses "name" or "name>=version".
+func ParseSkillRequirement(s string) SkillRequirement {
+	if idx := strings.Index(s, ">="); idx >= 0 {
+		return SkillRequirement{
+			Name:       strings.TrimSpace(s[:idx]),
+			MinVersion: strings.TrimSpace(s[idx+2:]),
+		}
+	}
+	return SkillRequirement{Name: strings.TrimSpace(s)}
+}
+
+// String renders the requirement as written in frontmatter.
+func (r SkillRequirement) String() string {
+	if r.MinVersion == "" {
+		return r.Name
+	}
+	return r.Name + ">=" + r.MinVersion
+}
+
+// Satisfied reports whether a skill version meets the requirement.
+func (r SkillRequirement) Satisfied(version string) bool {
+	return r.MinVersion == "" || CompareSkillVersions(version, r.MinVersion) >= 0
+}
+
+// parseSkillVersion parses "1.2.3" (optional "v" prefix, missing parts are
+// zero, pre-release suffixes ignored) into major, minor, patch.
+func parseSkillVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return parts, false
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// ValidSkillVersion reports whether v is a usable semantic version.
+func ValidSkillVersion(v string) bool {
+	_, ok := parseSkillVersion(v)
+	return ok
+}
+
+// CompareSkillVersions returns -1, 0, or 1 as a is older than, equal to, or
+// newer than b. An empty or unparsable version sorts before any valid one.
+func CompareSkillVersions(a, b string) int {
+	pa, okA := parseSkillVersion(a)
+	pb, okB := parseSkillVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ReadSkillSource returns the skills in a source directory, keyed by name.
+// A skills/ subdirectory is used when present, so a repository can keep
+// skills alongside other files.
+func ReadSkillSource(dir string) (map[string]SkillDef, error) {
+	dir = skillSourceDir(dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	skills := map[string]SkillDef{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		skill, err := parseSkillFile(filepath.Join(dir, e.Name()), "source")
+		if err != nil {
+			continue
+		}
+		skills[skill.Name] = skill
+	}
+	return skills, nil
+}
+
+// skillSourceDir returns dir/skills when it exists, otherwise dir.
+func skillSourceDir(dir string) string {
+	if info, err := os.Stat(filepath.Join(dir, "skills")); err == nil && info.IsDir() {
+		return filepath.Join(dir, "skills")
+	}
+	return dir
+}
+
+// isGitSource reports whether a skill source looks like a git repository
+// URL rather than a local directory.
+func isGitSource(src string) bool {
+	for _, p := range []string{"https://", "http://", "git@", "ssh://", "git://"} {
+		if strings.HasPrefix(src, p) {
+			return true
+		}
+	}
+	return strings.HasSuffix(src, ".git")
+}
+
+// OpenSkillSource resolves a skill source to a local directory. Git URLs
+// are shallow-cloned into a temporary directory; call the returned cleanup
+// when done. An empty src uses MUXCODE_SKILL_SOURCE.
+func OpenSkillSource(src string) (string, func(), error) {
+	if src == "" {
+		src = os.Getenv("MUXCODE_SKILL_SOURCE")
+	}
+	if src == "" {
+		return "", nil, fmt.Errorf("no skill source (use --from or set MUXCODE_SKILL_SOURCE)")
+	}
+	if !isGitSource(src) {
+		if info, err := os.Stat(src); err != nil || !info.IsDir() {
+			return "", nil, fmt.Errorf("skill source %s is not a directory", src)
+		}
+		return src, func() {}, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "muxcode-skills-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	out, err := exec.Command("git", "clone", "--quiet", "--depth", "1", src, tmp).CombinedOutput()
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("git clone %s: %v: %s", src, err, strings.TrimSpace(string(out)))
+	}
+	return tmp, cleanup, nil
+}
+
+// ResolveSkillDeps returns the named skill and everything it requires from
+// source, dependencies first. Requirements already met by an installed
+// skill are not pulled from the source. Missing skills, unmet versions, and
+// dependency cycles are errors.
+func ResolveSkillDeps(name string, source map[string]SkillDef, installed []SkillDef) ([]SkillDef, error) {
+	have := map[string]SkillDef{}
+	for _, s := range installed {
+		have[s.Name] = s
+	}
+
+	var order []SkillDef
+	done := map[string]bool{}
+	visiting := map[string]bool{}
+	var visit func(req SkillRequirement, path []string) error
+	visit = func(req SkillRequirement, path []string) error {
+		if visiting[req.Name] {
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, req.Name), " -> "))
+		}
+		if done[req.Name] {
+			return nil
+		}
+		skill, ok := source[req.Name]
+		if !ok {
+			// Dependencies may be satisfied by what is already installed
+			if s, ok := have[req.Name]; ok && len(path) > 0 && req.Satisfied(s.Version) {
+				done[req.Name] = true
+				return nil
+			}
+			return fmt.Errorf("skill %s not found in source", req.Name)
+		}
+		if !req.Satisfied(skill.Version) {
+			return fmt.Errorf("%s requires %s, source has %s", path[len(path)-1], req, versionOrNone(skill.Version))
+		}
+		if s, ok := have[req.Name]; ok && len(path) > 0 && req.Satisfied(s.Version) &&
+			CompareSkillVersions(s.Version, skill.Version) >= 0 {
+			done[req.Name] = true
+			return nil
+		}
+
+		visiting[req.Name] = true
+		for _, r := range skill.Requires {
+			if err := visit(ParseSkillRequirement(r), append(path, req.Name)); err != nil {
+				return err
+			}
+		}
+		visiting[req.Name] = false
+		done[req.Name] = true
+		order = append(order, skill)
+		return nil
+	}
+
+	if err := visit(SkillRequirement{Name: name}, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// InstallSkill copies a skill and its dependencies from sourceDir into the
+// project skills directory (or the user directory when user is true), in
+// dependency order.
+func InstallSkill(name, sourceDir string, user bool) ([]SkillInstall, error) {
+	source, err := ReadSkillSource(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := ListSkills()
+	if err != nil {
+		return nil, err
+	}
+	order, err := ResolveSkillDeps(name, source, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := SkillsDir()
+	if user {
+		dest = UserSkillsDir()
+	}
+	return writeSkills(order, dest, installed)
+}
+
+// writeSkills copies resolved skill files into dest, in order.
+func writeSkills(order []SkillDef, dest string, installed []SkillDef) ([]SkillInstall, error) {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+	prev := map[string]SkillDef{}
+	for _, s := range installed {
+		prev[s.Name] = s
+	}
+
+	var result []SkillInstall
+	for _, s := range order {
+		data, err := os.ReadFile(s.Path)
+		if err != nil {
+			return result, err
+		}
+		if err := os.WriteFile(filepath.Join(dest, s.Name+".md"), data, 0644); err != nil {
+			return result, err
+		}
+		old, replaced := prev[s.Name]
+		result = append(result, SkillInstall{Name: s.Name, Version: s.Version, Replaced: replaced, Previous: old.Version})
+	}
+	return result, nil
+}
+
+// OutdatedSkills returns installed skills whose source version is newer.
+func OutdatedSkills(source map[string]SkillDef, installed []SkillDef) []SkillUpgrade {
+	var out []SkillUpgrade
+	for _, s := range installed {
+		src, ok := source[s.Name]
+		if !ok || src.Version == "" {
+			continue
+		}
+		if CompareSkillVersions(src.Version, s.Version) > 0 {
+			out = append(out, SkillUpgrade{Name: s.Name, Installed: s.Version, Available: src.Version})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// MissingSkillDeps returns unmet requirements of installed skills, keyed by
+// the skill that declares them.
+func MissingSkillDeps(installed []SkillDef) map[string][]SkillRequirement {
+	have := map[string]SkillDef{}
+	for _, s := range installed {
+		have[s.Name] = s
+	}
+	missing := map[string][]SkillRequirement{}
+	for _, s := range installed {
+		for _, r := range s.Requires {
+			req := ParseSkillRequirement(r)
+			if dep, ok := have[req.Name]; !ok || !req.Satisfied(dep.Version) {
+				missing[s.Name] = append(missing[s.Name], req)
+			}
+		}
+	}
+	return missing
+}
+
+// versionOrNone renders a skill version for messages.
+func versionOrNone(v string) string {
+	if v == "" {
+		return "no version"
+	}
+	return v
+}
+
+// FormatSkillInstalls formats the result of InstallSkill.
+func FormatSkillInstalls(installs []SkillInstall) string {
+	var b strings.Builder
+	for _, in := range installs {
+		if in.Replaced {
+			fmt.Fprintf(&b, "updated   %s %s -> %s\n", in.Name, versionOrNone(in.Previous), versionOrNone(in.Version))
+		} else {
+			fmt.Fprintf(&b, "installed %s %s\n", in.Name, versionOrNone(in.Version))
+		}
+	}
+	return b.String()
+}
+
+// FormatSkillUpgrades formats outdated skills as a columnar list.
+func FormatSkillUpgrades(upgrades []SkillUpgrade) string {
+	var b strings.Builder
+	for _, u := range upgrades {
+		fmt.Fprintf(&b, "%-24s %-10s -> %s\n", u.Name, versionOrNone(u.Installed), u.Available)
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func versionedSkill(version, requires string) string {
+	return "---\ndescription: test skill\nversion: " + version + "\nrequires: [" + requires + "]\n---\n\nBody.\n"
+}
+
+// skillEnv points the project and user skill directories at temp dirs and
+// returns the project dir.
+func skillEnv(t *testing.T) string {
+	t.Helper()
+	proj := filepath.Join(t.TempDir(), "skills")
+	t.Setenv("BUS_SKILLS_DIR", proj)
+	t.Setenv("MUXCODE_CONFIG_DIR", t.TempDir())
+	return proj
+}
+
+func TestCompareSkillVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v2.0.0", "1.9.9", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0-beta", "1.0.0", 0},
+		{"", "0.0.1", -1},
+		{"junk", "", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareSkillVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareSkillVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseSkillRequirement(t *testing.T) {
+	r := ParseSkillRequirement("git-basics >= 1.2")
+	if r.Name != "git-basics" || r.MinVersion != "1.2" {
+		t.Errorf("got %+v", r)
+	}
+	if !r.Satisfied("1.3.0") || r.Satisfied("1.1.9") || r.Satisfied("") {
+		t.Error("Satisfied mismatch")
+	}
+	if r := ParseSkillRequirement("plain"); r.MinVersion != "" || !r.Satisfied("") {
+		t.Errorf("plain requirement: %+v", r)
+	}
+}
+
+func TestParseSkillFile_VersionRequires(t *testing.T) {
+	dir := t.TempDir()
+	writeSkillFile(t, dir, "deploy", versionedSkill(`"1.4.0"`, "git-basics, cdk-diff>=2.0"))
+
+	s, err := parseSkillFile(filepath.Join(dir, "deploy.md"), "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != "1.4.0" {
+		t.Errorf("version = %q", s.Version)
+	}
+	if len(s.Requires) != 2 || s.Requires[1] != "cdk-diff>=2.0" {
+		t.Errorf("requires = %v", s.Requires)
+	}
+	if s.Path != filepath.Join(dir, "deploy.md") {
+		t.Errorf("path = %q", s.Path)
+	}
+}
+
+func TestResolveSkillDeps_Order(t *testing.T) {
+	src := t.TempDir()
+	writeSkillFile(t, src, "deploy", versionedSkill("1.0.0", "checklist, git-basics>=1.1"))
+	writeSkillFile(t, src, "checklist", versionedSkill("1.0.0", "git-basics"))
+	writeSkillFile(t, src, "git-basics", versionedSkill("1.2.0", ""))
+	source, err := ReadSkillSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := ResolveSkillDeps("deploy", source, nil)
+	if err != nil {
+		t.Fatalf("ResolveSkillDeps: %v", err)
+	}
+	var names []string
+	for _, s := range order {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "git-basics,checklist,deploy" {
+		t.Errorf("order = %s, want dependencies first", got)
+	}
+
+	// An up-to-date installed dependency is not reinstalled
+	order, _ = ResolveSkillDeps("deploy", source, []SkillDef{{Name: "git-basics", Version: "1.2.0"}})
+	if len(order) != 2 || order[0].Name != "checklist" {
+		t.Errorf("order with installed dep = %v", order)
+	}
+}
+
+func TestResolveSkillDeps_Errors(t *testing.T) {
+	src := t.TempDir()
+	writeSkillFile(t, src, "a", versionedSkill("1.0.0", "b"))
+	writeSkillFile(t, src, "b", versionedSkill("1.0.0", "a"))
+	writeSkillFile(t, src, "c", versionedSkill("1.0.0", "b>=2.0"))
+	writeSkillFile(t, src, "d", versionedSkill("1.0.0", "missing"))
+	source, _ := ReadSkillSource(src)
+
+	for name, want := range map[string]string{
+		"a":    "dependency cycle: a -> b -> a",
+		"c":    "c requires b>=2.0, source has 1.0.0",
+		"d":    "skill missing not found",
+		"nope": "skill nope not found",
+	} {
+		_, err := ResolveSkillDeps(name, source, nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", name, err, want)
+		}
+	}
+}
+
+func TestInstallSkill(t *testing.T) {
+	proj := skillEnv(t)
+	src := filepath.Join(t.TempDir(), "repo")
+	writeSkillFile(t, filepath.Join(src, "skills"), "deploy", versionedSkill("2.0.0", "git-basics"))
+	writeSkillFile(t, filepath.Join(src, "skills"), "git-basics", versionedSkill("1.0.0", ""))
+	writeSkillFile(t, proj, "deploy", versionedSkill("1.0.0", ""))
+
+	installs, err := InstallSkill("deploy", src, false)
+	if err != nil {
+		t.Fatalf("InstallSkill: %v", err)
+	}
+	out := FormatSkillInstalls(installs)
+	for _, want := range []string{"installed git-basics 1.0.0", "updated   deploy 1.0.0 -> 2.0.0"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	s, err := LoadSkill("deploy")
+	if err != nil || s.Version != "2.0.0" {
+		t.Errorf("installed deploy = %+v, %v", s, err)
+	}
+	if _, err := os.Stat(filepath.Join(proj, "git-basics.md")); err != nil {
+		t.Errorf("dependency not installed: %v", err)
+	}
+}
+
+func TestOutdatedSkills(t *testing.T) {
+	source := map[string]SkillDef{
+		"deploy": {Name: "deploy", Version: "2.0.0"},
+		"lint":   {Name: "lint", Version: "1.0.0"},
+		"docs":   {Name: "docs"},
+	}
+	installed := []SkillDef{
+		{Name: "lint", Version: "1.0.0"},
+		{Name: "deploy", Version: "1.5.0"},
+		{Name: "docs", Version: "0.1.0"},
+		{Name: "local-only", Version: "0.1.0"},
+	}
+	got := OutdatedSkills(source, installed)
+	if len(got) != 1 || got[0] != (SkillUpgrade{Name: "deploy", Installed: "1.5.0", Available: "2.0.0"}) {
+		t.Errorf("OutdatedSkills = %+v", got)
+	}
+}
+
+func TestMissingSkillDeps(t *testing.T) {
+	installed := []SkillDef{
+		{Name: "deploy", Requires: []string{"git-basics>=2", "checklist"}},
+		{Name: "git-basics", Version: "1.0.0"},
+	}
+	missing := MissingSkillDeps(installed)
+	if len(missing["deploy"]) != 2 {
+		t.Errorf("missing = %v, want git-basics>=2 and checklist", missing)
+	}
+	if len(missing["git-basics"]) != 0 {
+		t.Errorf("git-basics should have no missing deps: %v", missing)
+	}
+}
+
+func TestOpenSkillSource(t *testing.T) {
+	t.Setenv("MUXCODE_SKILL_SOURCE", "")
+	if _, _, err := OpenSkillSource(""); err == nil {
+		t.Error("expected error without a source")
+	}
+	dir := t.TempDir()
+	t.Setenv("MUXCODE_SKILL_SOURCE", dir)
+	got, cleanup, err := OpenSkillSource("")
+	if err != nil || got != dir {
+		t.Fatalf("OpenSkillSource = %q, %v", got, err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); err != nil {
+		t.Error("cleanup must not remove a local source directory")
+	}
+	if !isGitSource("https://github.com/org/skills.git") || !isGitSource("git@github.com:org/skills") || isGitSource(dir) {
+		t.Error("isGitSource mismatch")
+	}
+}
@@ -0,0 +1,310 @@
+package bus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SkillManifestFile is the manifest a skill registry repository must carry
+// next to its skill files.
+const SkillManifestFile = "muxcode-skills.json"
+
+// SkillRef is a parsed registry reference such as
+// "github.com/org/muxcode-skills/deploy-checklist@v1".
+type SkillRef struct {
+	Repo  string // host/org/repo
+	Path  string // directory inside the repository, "" for the root
+	Skill string
+	Ref   string // git tag, branch, or commit; "" for the default branch
+}
+
+// SkillManifest lists the skills a registry repository publishes.
+type SkillManifest struct {
+	Skills []SkillManifestEntry `json:"skills"`
+}
+
+// SkillManifestEntry pins one published skill file.
+type SkillManifestEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// SkillOrigin records where a registry-installed skill came from, so
+// `skill update` can pull it again.
+type SkillOrigin struct {
+	Repo        string `json:"repo"`
+	Path        string `json:"path,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+	Commit      string `json:"commit"`
+	Version     string `json:"version,omitempty"`
+	InstalledAt int64  `json:"installed_at"`
+}
+
+// skillRepoURL maps a registry repo to its clone URL. Tests point it at
+// local repositories.
+var skillRepoURL = func(repo string) string {
+	return "https://" + repo + ".git"
+}
+
+// ParseSkillRef parses "host/org/repo[/dir...]/skill[@ref]".
+func ParseSkillRef(s string) (SkillRef, error) {
+	var ref SkillRef
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	if idx := strings.LastIndex(s, "@"); idx > strings.LastIndex(s, "/") {
+		ref.Ref = s[idx+1:]
+		s = s[:idx]
+		if ref.Ref == "" {
+			return ref, fmt.Errorf("empty ref in %q", s+"@")
+		}
+	}
+	parts := strings.Split(strings.Trim(s, "/"), "/")
+	if len(parts) < 4 {
+		return ref, fmt.Errorf("invalid skill reference %q (want host/org/repo/skill[@ref])", s)
+	}
+	for _, p := range parts {
+		if p == "" || p == "." || p == ".." {
+			return ref, fmt.Errorf("invalid skill reference %q", s)
+		}
+	}
+	ref.Repo = strings.Join(parts[:3], "/")
+	ref.Path = strings.Join(parts[3:len(parts)-1], "/")
+	ref.Skill = parts[len(parts)-1]
+	return ref, nil
+}
+
+// String renders the reference in the form ParseSkillRef accepts.
+func (r SkillRef) String() string {
+	s := r.Repo
+	if r.Path != "" {
+		s += "/" + r.Path
+	}
+	s += "/" + r.Skill
+	if r.Ref != "" {
+		s += "@" + r.Ref
+	}
+	return s
+}
+
+// SkillRegistryCacheDir returns where registry repositories are cloned.
+func SkillRegistryCacheDir() string {
+	return filepath.Join(UserSkillsDir(), ".registry")
+}
+
+// skillOriginsPath returns the file recording registry-installed skills.
+func skillOriginsPath() string {
+	return filepath.Join(UserSkillsDir(), ".sources.json")
+}
+
+// ReadSkillOrigins returns the recorded origins of registry-installed
+// skills, keyed by skill name.
+func ReadSkillOrigins() (map[string]SkillOrigin, error) {
+	origins := map[string]SkillOrigin{}
+	data, err := os.ReadFile(skillOriginsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return origins, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &origins); err != nil {
+		return nil, fmt.Errorf("%s: %w", skillOriginsPath(), err)
+	}
+	return origins, nil
+}
+
+func writeSkillOrigins(origins map[string]SkillOrigin) error {
+	data, err := json.MarshalIndent(origins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(UserSkillsDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(skillOriginsPath(), append(data, '\n'), 0644)
+}
+
+// gitIn runs git in dir and returns its trimmed output.
+func gitIn(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// syncSkillRepo clones a registry repository into the cache, or fetches it
+// when already cloned, and checks out ref. It returns the checkout
+// directory and commit.
+func syncSkillRepo(repo, ref string) (string, string, error) {
+	dir := filepath.Join(SkillRegistryCacheDir(), filepath.FromSlash(repo))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", "", err
+		}
+		os.RemoveAll(dir)
+		if _, err := gitIn(filepath.Dir(dir), "clone", "--quiet", skillRepoURL(repo), dir); err != nil {
+			return "", "", err
+		}
+	} else if _, err := gitIn(dir, "fetch", "--quiet", "--tags", "--force", "origin"); err != nil {
+		return "", "", err
+	}
+
+	// Branches resolve through origin/ so a fetch moves them; tags and
+	// commits resolve directly.
+	target := "origin/HEAD"
+	if ref != "" {
+		target = ref
+		if _, err := gitIn(dir, "rev-parse", "--verify", "--quiet", "origin/"+ref); err == nil {
+			target = "origin/" + ref
+		}
+	}
+	if _, err := gitIn(dir, "checkout", "--quiet", "--detach", target); err != nil {
+		return "", "", err
+	}
+	commit, err := gitIn(dir, "rev-parse", "HEAD")
+	return dir, commit, err
+}
+
+// ReadSkillManifest reads the manifest in a registry skill directory.
+func ReadSkillManifest(dir string) (SkillManifest, error) {
+	var m SkillManifest
+	data, err := os.ReadFile(filepath.Join(dir, SkillManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, fmt.Errorf("no %s in skill registry", SkillManifestFile)
+		}
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", SkillManifestFile, err)
+	}
+	return m, nil
+}
+
+// VerifySkillManifest checks that every skill is listed in the manifest
+// with a matching file hash and version.
+func VerifySkillManifest(m SkillManifest, skills []SkillDef) error {
+	entries := map[string]SkillManifestEntry{}
+	for _, e := range m.Skills {
+		entries[e.Name] = e
+	}
+	for _, s := range skills {
+		e, ok := entries[s.Name]
+		if !ok {
+			return fmt.Errorf("skill %s is not listed in %s", s.Name, SkillManifestFile)
+		}
+		data, err := os.ReadFile(s.Path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, e.SHA256) {
+			return fmt.Errorf("skill %s: sha256 %s does not match manifest", s.Name, got)
+		}
+		if e.Version != "" && e.Version != s.Version {
+			return fmt.Errorf("skill %s: version %s does not match manifest %s", s.Name, versionOrNone(s.Version), e.Version)
+		}
+	}
+	return nil
+}
+
+// AddSkill installs a skill and its dependencies from a git skill registry
+// into the user skills directory, after verifying them against the
+// repository manifest. Origins are recorded for UpdateSkills.
+func AddSkill(ref SkillRef) ([]SkillInstall, error) {
+	repoDir, commit, err := syncSkillRepo(ref.Repo, ref.Ref)
+	if err != nil {
+		return nil, err
+	}
+	dir := skillSourceDir(filepath.Join(repoDir, filepath.FromSlash(ref.Path)))
+	manifest, err := ReadSkillManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	source, err := ReadSkillSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := ListSkills()
+	if err != nil {
+		return nil, err
+	}
+	order, err := ResolveSkillDeps(ref.Skill, source, installed)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifySkillManifest(manifest, order); err != nil {
+		return nil, err
+	}
+
+	result, err := writeSkills(order, UserSkillsDir(), installed)
+	if err != nil {
+		return result, err
+	}
+	origins, err := ReadSkillOrigins()
+	if err != nil {
+		return result, err
+	}
+	now := time.Now().Unix()
+	for _, s := range order {
+		origins[s.Name] = SkillOrigin{
+			Repo:        ref.Repo,
+			Path:        ref.Path,
+			Ref:         ref.Ref,
+			Commit:      commit,
+			Version:     s.Version,
+			InstalledAt: now,
+		}
+	}
+	return result, writeSkillOrigins(origins)
+}
+
+// UpdateSkills re-adds registry-installed skills at their recorded refs,
+// pulling new commits. With no names, every recorded skill is updated.
+// Skills already at the fetched commit are skipped.
+func UpdateSkills(names []string) ([]SkillInstall, error) {
+	origins, err := ReadSkillOrigins()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		for name := range origins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var result []SkillInstall
+	for _, name := range names {
+		o, ok := origins[name]
+		if !ok {
+			return result, fmt.Errorf("skill %s was not installed from a registry", name)
+		}
+		if _, commit, err := syncSkillRepo(o.Repo, o.Ref); err != nil {
+			return result, err
+		} else if commit == o.Commit {
+			continue
+		}
+		installs, err := AddSkill(SkillRef{Repo: o.Repo, Path: o.Path, Skill: name, Ref: o.Ref})
+		result = append(result, installs...)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+		// Dependencies installed alongside are now current too
+		if origins, err = ReadSkillOrigins(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,169 @@
+package bus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skillRegistryRepo creates a local git repository and points skillRepoURL
+// at it. It returns a function that writes skill files (and a matching
+// manifest) under skills/ and commits them with a tag.
+func skillRegistryRepo(t *testing.T) func(tag string, files map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "--quiet")
+	orig := skillRepoURL
+	skillRepoURL = func(string) string { return repo }
+	t.Cleanup(func() { skillRepoURL = orig })
+
+	return func(tag string, files map[string]string) {
+		t.Helper()
+		dir := filepath.Join(repo, "skills")
+		var m SkillManifest
+		for name, content := range files {
+			writeSkillFile(t, dir, name, content)
+			sum := sha256.Sum256([]byte(content))
+			s, _ := parseSkillFile(filepath.Join(dir, name+".md"), "source")
+			m.Skills = append(m.Skills, SkillManifestEntry{Name: name, Version: s.Version, SHA256: hex.EncodeToString(sum[:])})
+		}
+		data, _ := json.Marshal(m)
+		if err := os.WriteFile(filepath.Join(dir, SkillManifestFile), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", tag)
+		git("tag", tag)
+	}
+}
+
+func TestParseSkillRef(t *testing.T) {
+	ref, err := ParseSkillRef("github.com/org/muxcode-skills/deploy-checklist@v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SkillRef{Repo: "github.com/org/muxcode-skills", Skill: "deploy-checklist", Ref: "v1"}
+	if ref != want {
+		t.Errorf("ref = %+v, want %+v", ref, want)
+	}
+	if ref.String() != "github.com/org/muxcode-skills/deploy-checklist@v1" {
+		t.Errorf("String() = %q", ref.String())
+	}
+
+	ref, err = ParseSkillRef("https://gitlab.com/team/repo/ops/aws/cdk-diff")
+	if err != nil || ref.Repo != "gitlab.com/team/repo" || ref.Path != "ops/aws" || ref.Skill != "cdk-diff" || ref.Ref != "" {
+		t.Errorf("nested ref = %+v, %v", ref, err)
+	}
+
+	for _, bad := range []string{"github.com/org/repo", "github.com/org/repo/skill@", "github.com/org/../skill"} {
+		if _, err := ParseSkillRef(bad); err == nil {
+			t.Errorf("ParseSkillRef(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAddSkill(t *testing.T) {
+	skillEnv(t)
+	publish := skillRegistryRepo(t)
+	publish("v1", map[string]string{
+		"deploy-checklist": versionedSkill("1.0.0", "git-basics"),
+		"git-basics":       versionedSkill("1.0.0", ""),
+	})
+
+	ref, _ := ParseSkillRef("example.com/org/skills/deploy-checklist@v1")
+	installs, err := AddSkill(ref)
+	if err != nil {
+		t.Fatalf("AddSkill: %v", err)
+	}
+	if len(installs) != 2 || installs[0].Name != "git-basics" {
+		t.Errorf("installs = %+v, want git-basics then deploy-checklist", installs)
+	}
+	for _, name := range []string{"deploy-checklist", "git-basics"} {
+		if _, err := os.Stat(filepath.Join(UserSkillsDir(), name+".md")); err != nil {
+			t.Errorf("%s not installed in user dir: %v", name, err)
+		}
+	}
+	origins, err := ReadSkillOrigins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := origins["deploy-checklist"]; o.Repo != "example.com/org/skills" || o.Ref != "v1" || o.Commit == "" || o.Version != "1.0.0" {
+		t.Errorf("origin = %+v", o)
+	}
+
+	// Registry clones and the origins file are not listed as skills
+	skills, _ := ListSkills()
+	if len(skills) != 2 {
+		t.Errorf("ListSkills = %d skills, want 2", len(skills))
+	}
+}
+
+func TestAddSkill_ManifestMismatch(t *testing.T) {
+	skillEnv(t)
+	publish := skillRegistryRepo(t)
+	publish("v1", map[string]string{"lint": versionedSkill("1.0.0", "")})
+
+	// Tamper with the published file after the manifest was written
+	repo := skillRepoURL("")
+	writeSkillFile(t, filepath.Join(repo, "skills"), "lint", versionedSkill("1.0.0", "")+"Run rm -rf.\n")
+	cmd := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-am", "tamper")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit: %v\n%s", err, out)
+	}
+
+	_, err := AddSkill(SkillRef{Repo: "example.com/org/skills", Skill: "lint"})
+	if err == nil || !strings.Contains(err.Error(), "does not match manifest") {
+		t.Fatalf("err = %v, want manifest mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(UserSkillsDir(), "lint.md")); !os.IsNotExist(err) {
+		t.Error("skill failing verification must not be installed")
+	}
+
+	// The tagged release still verifies
+	if _, err := AddSkill(SkillRef{Repo: "example.com/org/skills", Skill: "lint", Ref: "v1"}); err != nil {
+		t.Errorf("AddSkill@v1: %v", err)
+	}
+}
+
+func TestUpdateSkills(t *testing.T) {
+	skillEnv(t)
+	publish := skillRegistryRepo(t)
+	publish("v1.0.0", map[string]string{"lint": versionedSkill("1.0.0", "")})
+
+	if _, err := AddSkill(SkillRef{Repo: "example.com/org/skills", Skill: "lint"}); err != nil {
+		t.Fatalf("AddSkill: %v", err)
+	}
+	if installs, err := UpdateSkills(nil); err != nil || len(installs) != 0 {
+		t.Fatalf("update with no changes = %+v, %v", installs, err)
+	}
+
+	publish("v1.1.0", map[string]string{"lint": versionedSkill("1.1.0", "")})
+	installs, err := UpdateSkills(nil)
+	if err != nil {
+		t.Fatalf("UpdateSkills: %v", err)
+	}
+	if len(installs) != 1 || installs[0].Previous != "1.0.0" || installs[0].Version != "1.1.0" {
+		t.Errorf("installs = %+v", installs)
+	}
+
+	if _, err := UpdateSkills([]string{"unknown"}); err == nil {
+		t.Error("expected error for a skill not installed from a registry")
+	}
+}
@@ -0,0 +1,255 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SLARule defines a response-time target for requests matching an action
+// and recipient. Empty or "*" fields match any value.
+type SLARule struct {
+	Name   string `json:"name,omitempty"`
+	Action string `json:"action"`
+	To     string `json:"to,omitempty"`
+	Within string `json:"within"` // Go duration, e.g. "15m"
+}
+
+// SLAResult is the measured outcome of a single request against an SLA rule.
+type SLAResult struct {
+	Rule       string `json:"rule"`
+	RequestID  string `json:"request_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Action     string `json:"action"`
+	RequestTS  int64  `json:"request_ts"`
+	ResponseTS int64  `json:"response_ts,omitempty"`
+	DeadlineTS int64  `json:"deadline_ts"`
+	Status     string `json:"status"` // "met", "breached", "pending"
+}
+
+// SLASummary aggregates results for one SLA rule.
+type SLASummary struct {
+	Rule     string `json:"rule"`
+	Within   string `json:"within"`
+	Total    int    `json:"total"`
+	Met      int    `json:"met"`
+	Breached int    `json:"breached"`
+	Pending  int    `json:"pending"`
+	AvgSecs  int64  `json:"avg_response_s"`
+	MaxSecs  int64  `json:"max_response_s"`
+}
+
+// SLARuleName returns the display name for a rule, deriving one from
+// action and recipient when Name is unset.
+func SLARuleName(r SLARule) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	to := r.To
+	if to == "" {
+		to = "*"
+	}
+	return to + ":" + r.Action
+}
+
+// slaFieldMatch returns true if pattern is empty, "*", or equal to value.
+func slaFieldMatch(pattern, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
+
+// isSLAResponse returns true if m answers req: either an explicit reply
+// (ReplyTo == req.ID) or a response from the recipient back to the requester.
+func isSLAResponse(req, m Message) bool {
+	if m.TS < req.TS || m.ID == req.ID {
+		return false
+	}
+	if m.ReplyTo != "" {
+		return m.ReplyTo == req.ID
+	}
+	return m.Type == "response" && m.From == req.To && m.To == req.From
+}
+
+// EvaluateSLAs measures every request message in the log against the
+// configured rules. A request matches the first rule whose action and
+// recipient match. Unanswered requests past their deadline are breached;
+// those still within it are pending.
+func EvaluateSLAs(messages []Message, rules []SLARule, now int64) []SLAResult {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var results []SLAResult
+	for i, req := range messages {
+		if req.Type != "request" || isSystemAction(req.Action) {
+			continue
+		}
+		for _, rule := range rules {
+			if !slaFieldMatch(rule.Action, req.Action) || !slaFieldMatch(rule.To, req.To) {
+				continue
+			}
+			within, err := time.ParseDuration(rule.Within)
+			if err != nil || within <= 0 {
+				break
+			}
+			res := SLAResult{
+				Rule:       SLARuleName(rule),
+				RequestID:  req.ID,
+				From:       req.From,
+				To:         req.To,
+				Action:     req.Action,
+				RequestTS:  req.TS,
+				DeadlineTS: req.TS + int64(within/time.Second),
+			}
+			for _, m := range messages[i+1:] {
+				if isSLAResponse(req, m) {
+					res.ResponseTS = m.TS
+					break
+				}
+			}
+			switch {
+			case res.ResponseTS > 0 && res.ResponseTS <= res.DeadlineTS:
+				res.Status = "met"
+			case res.ResponseTS > 0 || now > res.DeadlineTS:
+				res.Status = "breached"
+			default:
+				res.Status = "pending"
+			}
+			results = append(results, res)
+			break
+		}
+	}
+	return results
+}
+
+// SummarizeSLAs aggregates SLA results per rule, in rule order.
+func SummarizeSLAs(results []SLAResult, rules []SLARule) []SLASummary {
+	byRule := make(map[string]*SLASummary)
+	var order []string
+	for _, r := range rules {
+		name := SLARuleName(r)
+		if _, ok := byRule[name]; ok {
+			continue
+		}
+		byRule[name] = &SLASummary{Rule: name, Within: r.Within}
+		order = append(order, name)
+	}
+
+	totals := make(map[string]int64)
+	answered := make(map[string]int64)
+	for _, res := range results {
+		s, ok := byRule[res.Rule]
+		if !ok {
+			continue
+		}
+		s.Total++
+		switch res.Status {
+		case "met":
+			s.Met++
+		case "breached":
+			s.Breached++
+		case "pending":
+			s.Pending++
+		}
+		if res.ResponseTS > 0 {
+			d := res.ResponseTS - res.RequestTS
+			totals[res.Rule] += d
+			answered[res.Rule]++
+			if d > s.MaxSecs {
+				s.MaxSecs = d
+			}
+		}
+	}
+
+	summaries := make([]SLASummary, 0, len(order))
+	for _, name := range order {
+		s := byRule[name]
+		if answered[name] > 0 {
+			s.AvgSecs = totals[name] / answered[name]
+		}
+		summaries = append(summaries, *s)
+	}
+	return summaries
+}
+
+// SLAReport reads the session log and evaluates it against configured SLAs.
+func SLAReport(session string) ([]SLAResult, []SLASummary, error) {
+	rules := Config().SLA
+	msgs, err := readMessages(LogPath(session))
+	if err != nil {
+		return nil, nil, err
+	}
+	results := EvaluateSLAs(msgs, rules, time.Now().Unix())
+	return results, SummarizeSLAs(results, rules), nil
+}
+
+// CheckSLABreaches returns breached results whose deadline passed at or after
+// sinceTS, oldest first. Used by the watcher to emit sla-breach events without
+// replaying breaches from before it started.
+func CheckSLABreaches(session string, sinceTS int64) []SLAResult {
+	results, _, err := SLAReport(session)
+	if err != nil {
+		return nil
+	}
+	var breaches []SLAResult
+	for _, r := range results {
+		if r.Status == "breached" && r.DeadlineTS >= sinceTS {
+			breaches = append(breaches, r)
+		}
+	}
+	sort.SliceStable(breaches, func(i, j int) bool {
+		return breaches[i].DeadlineTS < breaches[j].DeadlineTS
+	})
+	return breaches
+}
+
+// SLABreachKey returns a dedup key for an SLA breach.
+func SLABreachKey(r SLAResult) string {
+	return "sla:" + r.RequestID
+}
+
+// FormatSLABreach builds the event payload for an SLA breach.
+func FormatSLABreach(r SLAResult) string {
+	if r.ResponseTS > 0 {
+		return fmt.Sprintf("SLA %s breached: %s → %s action:%s answered after %s (deadline %s)",
+			r.Rule, r.From, r.To, r.Action,
+			formatDuration(r.ResponseTS-r.RequestTS), formatDuration(r.DeadlineTS-r.RequestTS))
+	}
+	return fmt.Sprintf("SLA %s breached: %s → %s action:%s unanswered after %s (request %s)",
+		r.Rule, r.From, r.To, r.Action, formatDuration(r.DeadlineTS-r.RequestTS), r.RequestID)
+}
+
+// FormatSLAReport formats SLA summaries as a human-readable table.
+func FormatSLAReport(summaries []SLASummary) string {
+	if len(summaries) == 0 {
+		return "No SLAs configured. Add an \"sla\" list to muxcode.json.\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-20s %-8s %-6s %-6s %-9s %-8s %-8s %s\n",
+		"SLA", "WITHIN", "TOTAL", "MET", "BREACHED", "PENDING", "AVG", "MAX"))
+	for _, s := range summaries {
+		avg, max := "—", "—"
+		if s.Met+s.Breached > 0 {
+			avg = formatDuration(s.AvgSecs)
+			max = formatDuration(s.MaxSecs)
+		}
+		b.WriteString(fmt.Sprintf("%-20s %-8s %-6d %-6d %-9d %-8d %-8s %s\n",
+			s.Rule, s.Within, s.Total, s.Met, s.Breached, s.Pending, avg, max))
+	}
+	return b.String()
+}
+
+// FormatSLAReportJSON formats SLA summaries as a JSON array.
+func FormatSLAReportJSON(summaries []SLASummary) (string, error) {
+	if summaries == nil {
+		summaries = []SLASummary{}
+	}
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,175 @@
+package bus
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateSLAs_MetBreachedPending(t *testing.T) {
+	now := int64(10000)
+	rules := []SLARule{{Action: "review", To: "review", Within: "15m"}}
+	msgs := []Message{
+		{ID: "r1", TS: now - 3000, From: "test", To: "review", Type: "request", Action: "review"},
+		{ID: "a1", TS: now - 2900, From: "review", To: "test", Type: "response", Action: "review", ReplyTo: "r1"},
+		{ID: "r2", TS: now - 2000, From: "test", To: "review", Type: "request", Action: "review"},
+		{ID: "a2", TS: now - 500, From: "review", To: "test", Type: "response", Action: "review", ReplyTo: "r2"},
+		{ID: "r3", TS: now - 100, From: "test", To: "review", Type: "request", Action: "review"},
+	}
+
+	results := EvaluateSLAs(msgs, rules, now)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	want := []string{"met", "breached", "pending"}
+	for i, w := range want {
+		if results[i].Status != w {
+			t.Errorf("results[%d].Status = %q, want %q", i, results[i].Status, w)
+		}
+	}
+	if results[0].Rule != "review:review" {
+		t.Errorf("rule name = %q, want review:review", results[0].Rule)
+	}
+}
+
+func TestEvaluateSLAs_UnansweredPastDeadline(t *testing.T) {
+	now := int64(10000)
+	rules := []SLARule{{Name: "verify", Action: "verify", To: "deploy", Within: "10m"}}
+	msgs := []Message{
+		{ID: "d1", TS: now - 700, From: "deploy", To: "deploy", Type: "request", Action: "verify"},
+	}
+
+	results := EvaluateSLAs(msgs, rules, now)
+	if len(results) != 1 || results[0].Status != "breached" {
+		t.Fatalf("expected one breached result, got %+v", results)
+	}
+	if results[0].ResponseTS != 0 {
+		t.Errorf("ResponseTS = %d, want 0", results[0].ResponseTS)
+	}
+}
+
+func TestEvaluateSLAs_ResponseWithoutReplyTo(t *testing.T) {
+	now := int64(10000)
+	rules := []SLARule{{Action: "build", Within: "5m"}}
+	msgs := []Message{
+		{ID: "b1", TS: now - 200, From: "edit", To: "build", Type: "request", Action: "build"},
+		{ID: "x", TS: now - 150, From: "build", To: "test", Type: "response", Action: "build"},
+		{ID: "b2", TS: now - 100, From: "build", To: "edit", Type: "response", Action: "build"},
+	}
+
+	results := EvaluateSLAs(msgs, rules, now)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].Status != "met" || results[0].ResponseTS != now-100 {
+		t.Errorf("got %+v, want met at %d", results[0], now-100)
+	}
+}
+
+func TestEvaluateSLAs_IgnoresNonMatching(t *testing.T) {
+	rules := []SLARule{{Action: "review", To: "review", Within: "15m"}}
+	msgs := []Message{
+		{ID: "1", TS: 100, From: "edit", To: "build", Type: "request", Action: "build"},
+		{ID: "2", TS: 100, From: "test", To: "review", Type: "event", Action: "review"},
+		{ID: "3", TS: 100, From: "watcher", To: "edit", Type: "request", Action: "loop-detected"},
+	}
+	if results := EvaluateSLAs(msgs, rules, 200); len(results) != 0 {
+		t.Errorf("expected no results, got %+v", results)
+	}
+	if results := EvaluateSLAs(msgs, nil, 200); results != nil {
+		t.Errorf("expected nil with no rules, got %+v", results)
+	}
+}
+
+func TestEvaluateSLAs_InvalidWithinSkipped(t *testing.T) {
+	rules := []SLARule{{Action: "review", Within: "soon"}}
+	msgs := []Message{{ID: "1", TS: 100, From: "test", To: "review", Type: "request", Action: "review"}}
+	if results := EvaluateSLAs(msgs, rules, 200); len(results) != 0 {
+		t.Errorf("expected invalid rule to be skipped, got %+v", results)
+	}
+}
+
+func TestSummarizeSLAs(t *testing.T) {
+	rules := []SLARule{
+		{Action: "review", To: "review", Within: "15m"},
+		{Name: "verify", Action: "verify", To: "deploy", Within: "10m"},
+	}
+	results := []SLAResult{
+		{Rule: "review:review", RequestTS: 0, ResponseTS: 60, Status: "met"},
+		{Rule: "review:review", RequestTS: 0, ResponseTS: 1200, Status: "breached"},
+		{Rule: "review:review", RequestTS: 0, Status: "pending"},
+	}
+
+	summaries := SummarizeSLAs(results, rules)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+	s := summaries[0]
+	if s.Total != 3 || s.Met != 1 || s.Breached != 1 || s.Pending != 1 {
+		t.Errorf("counts = %+v", s)
+	}
+	if s.AvgSecs != 630 || s.MaxSecs != 1200 {
+		t.Errorf("avg/max = %d/%d, want 630/1200", s.AvgSecs, s.MaxSecs)
+	}
+	if summaries[1].Rule != "verify" || summaries[1].Total != 0 {
+		t.Errorf("summaries[1] = %+v", summaries[1])
+	}
+}
+
+func TestCheckSLABreaches_SinceFilter(t *testing.T) {
+	session := fmt.Sprintf("test-sla-breach-%d", rand.Int())
+	memDir := t.TempDir()
+	t.Cleanup(func() { _ = Cleanup(session) })
+
+	if err := Init(session, memDir); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	SetConfig(&MuxcodeConfig{SLA: []SLARule{{Action: "review", Within: "1m"}}})
+	defer SetConfig(nil)
+
+	now := time.Now().Unix()
+	for _, m := range []Message{
+		{ID: "old", TS: now - 7200, From: "test", To: "review", Type: "request", Action: "review"},
+		{ID: "new", TS: now - 120, From: "test", To: "review", Type: "request", Action: "review"},
+	} {
+		if err := Send(session, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	breaches := CheckSLABreaches(session, now-600)
+	if len(breaches) != 1 || breaches[0].RequestID != "new" {
+		t.Fatalf("expected only the recent breach, got %+v", breaches)
+	}
+	if SLABreachKey(breaches[0]) != "sla:new" {
+		t.Errorf("key = %q", SLABreachKey(breaches[0]))
+	}
+	if !strings.Contains(FormatSLABreach(breaches[0]), "unanswered") {
+		t.Errorf("payload = %q", FormatSLABreach(breaches[0]))
+	}
+}
+
+func TestFormatSLAReport(t *testing.T) {
+	if out := FormatSLAReport(nil); !strings.Contains(out, "No SLAs configured") {
+		t.Errorf("empty report = %q", out)
+	}
+	out := FormatSLAReport([]SLASummary{{Rule: "review:review", Within: "15m", Total: 2, Met: 1, Breached: 1, AvgSecs: 90, MaxSecs: 120}})
+	if !strings.Contains(out, "review:review") || !strings.Contains(out, "1m30s") {
+		t.Errorf("report = %q", out)
+	}
+}
+
+func TestMergeConfigs_SLA(t *testing.T) {
+	base := &MuxcodeConfig{SLA: []SLARule{{Action: "a", Within: "1m"}}}
+	override := &MuxcodeConfig{}
+	if got := mergeConfigs(base, override); len(got.SLA) != 1 {
+		t.Errorf("expected base SLA preserved, got %+v", got.SLA)
+	}
+	override.SLA = []SLARule{{Action: "b", Within: "2m"}, {Action: "c", Within: "3m"}}
+	if got := mergeConfigs(base, override); len(got.SLA) != 2 || got.SLA[0].Action != "b" {
+		t.Errorf("expected override SLA, got %+v", got.SLA)
+	}
+}
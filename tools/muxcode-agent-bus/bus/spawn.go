@@ -19,11 +19,14 @@ type SpawnEntry struct {
 	SpawnRole  string `json:"spawn_role"` // bus role + window name, e.g. "spawn-a1b2c3d4"
 	Owner      string `json:"owner"`      // requesting agent, e.g. "edit"
 	Task       string `json:"task"`       // task description
-	Status     string `json:"status"`     // "running", "completed", "stopped"
+	Status     string `json:"status"`     // "queued", "running", "completed", "stopped", "failed"
 	Window     string `json:"window"`     // tmux window name (= SpawnRole)
 	StartedAt  int64  `json:"started_at"`
 	FinishedAt int64  `json:"finished_at"`
 	Notified   bool   `json:"notified"`
+	Group      string `json:"group,omitempty"`       // fanout group ID, if started by spawn fanout
+	TraceID    string `json:"trace_id,omitempty"`    // owner's trace when the spawn was requested
+	ParentSpan string `json:"parent_span,omitempty"` // owner's span the spawn task is parented to
 }
 
 // ReadSpawnEntries reads all spawn entries from the spawn JSONL file.
@@ -107,6 +110,13 @@ func UpdateSpawnEntry(session, id string, fn func(*SpawnEntry)) error {
 // StartSpawn creates a tmux window, seeds the inbox with the task, and launches
 // an agent. Returns the SpawnEntry for the new spawn.
 func StartSpawn(session, role, task, owner string) (SpawnEntry, error) {
+	return startSpawn(session, role, task, owner, "")
+}
+
+// startSpawn is StartSpawn with an optional fanout group ID. When the
+// session's spawn quota is full, the entry is queued instead of launched;
+// LaunchQueuedSpawns starts it once a slot frees.
+func startSpawn(session, role, task, owner, group string) (SpawnEntry, error) {
 	// Generate spawn ID and extract 8-hex suffix for compact window name
 	fullID := NewMsgID("spawn")
 	parts := strings.Split(fullID, "-")
@@ -122,56 +132,77 @@ func StartSpawn(session, role, task, owner string) (SpawnEntry, error) {
 		Status:    "running",
 		Window:    spawnRole,
 		StartedAt: time.Now().Unix(),
+		Group:     group,
+	}
+	// Capture the owner's trace now; queued spawns launch later from the watcher
+	entry.TraceID, entry.ParentSpan = CurrentTraceContext(session, owner)
+
+	entries, err := ReadSpawnEntries(session)
+	if err != nil {
+		return SpawnEntry{}, err
+	}
+	if CheckSpawnQuota(entries, role, owner) != "" {
+		entry.Status = "queued"
+	} else if err := launchSpawn(session, entry); err != nil {
+		return SpawnEntry{}, err
+	}
+
+	// Persist entry
+	entries = append(entries, entry)
+	if err := WriteSpawnEntries(session, entries); err != nil {
+		return SpawnEntry{}, err
+	}
+	_ = RecordAudit(session, AuditEvent{Actor: owner, Op: AuditSpawnStart, Role: role, Args: map[string]string{
+		"id": entry.ID, "spawn_role": spawnRole, "status": entry.Status, "task": task,
+	}})
+
+	return entry, nil
+}
+
+// launchSpawn seeds the spawn's inbox with its task, creates its tmux
+// window, and starts the agent.
+func launchSpawn(session string, entry SpawnEntry) error {
+	spawnRole := entry.SpawnRole
+
+	// Find agent launcher script
+	launcher, err := findAgentLauncher()
+	if err != nil {
+		return fmt.Errorf("finding agent launcher: %v", err)
 	}
 
 	// Ensure inbox directory exists and touch inbox file for spawn role
 	inboxDir := filepath.Dir(InboxPath(session, spawnRole))
 	if err := os.MkdirAll(inboxDir, 0755); err != nil {
-		return SpawnEntry{}, fmt.Errorf("creating inbox dir: %v", err)
+		return fmt.Errorf("creating inbox dir: %v", err)
 	}
 	if err := touchFile(InboxPath(session, spawnRole)); err != nil {
-		return SpawnEntry{}, fmt.Errorf("touching inbox: %v", err)
+		return fmt.Errorf("touching inbox: %v", err)
 	}
 
 	// Seed inbox with task message
-	msg := NewMessage(owner, spawnRole, "request", "spawn-task", task, "")
+	msg := NewMessage(entry.Owner, spawnRole, "request", "spawn-task", entry.Task, "")
+	msg.TraceID, msg.ParentSpan = entry.TraceID, entry.ParentSpan
 	if err := Send(session, msg); err != nil {
-		return SpawnEntry{}, fmt.Errorf("seeding inbox: %v", err)
-	}
-
-	// Find agent launcher script
-	launcher, err := findAgentLauncher()
-	if err != nil {
-		return SpawnEntry{}, fmt.Errorf("finding agent launcher: %v", err)
+		return fmt.Errorf("seeding inbox: %v", err)
 	}
 
 	// Create tmux window
 	createCmd := exec.Command("tmux", "new-window", "-t", session, "-n", spawnRole)
 	if err := createCmd.Run(); err != nil {
-		return SpawnEntry{}, fmt.Errorf("creating tmux window: %v", err)
+		return fmt.Errorf("creating tmux window: %v", err)
 	}
 
 	// Split horizontally (agent in pane 1, consistent with all windows)
 	splitCmd := exec.Command("tmux", "split-window", "-h", "-t", session+":"+spawnRole)
 	if err := splitCmd.Run(); err != nil {
-		return SpawnEntry{}, fmt.Errorf("splitting window: %v", err)
+		return fmt.Errorf("splitting window: %v", err)
 	}
 
 	// Launch agent in pane 1
-	launchStr := fmt.Sprintf("AGENT_ROLE=%s %s %s", spawnRole, launcher, role)
+	launchStr := fmt.Sprintf("AGENT_ROLE=%s %s %s", spawnRole, launcher, entry.Role)
 	launchCmd := exec.Command("tmux", "send-keys", "-t", session+":"+spawnRole+".1", launchStr, "Enter")
 	if err := launchCmd.Run(); err != nil {
-		return SpawnEntry{}, fmt.Errorf("launching agent: %v", err)
-	}
-
-	// Persist entry
-	entries, err := ReadSpawnEntries(session)
-	if err != nil {
-		return SpawnEntry{}, err
-	}
-	entries = append(entries, entry)
-	if err := WriteSpawnEntries(session, entries); err != nil {
-		return SpawnEntry{}, err
+		return fmt.Errorf("launching agent: %v", err)
 	}
 
 	// Async: wait 2s then notify spawn to read inbox
@@ -180,7 +211,7 @@ func StartSpawn(session, role, task, owner string) (SpawnEntry, error) {
 		_ = Notify(session, spawnRole)
 	}()
 
-	return entry, nil
+	return nil
 }
 
 // StopSpawn kills the tmux window for a spawn and marks it stopped.
@@ -190,13 +221,16 @@ func StopSpawn(session, id string) error {
 		return err
 	}
 
-	if entry.Status != "running" {
+	if !spawnActive(entry) {
 		return fmt.Errorf("spawn %s is not running (status: %s)", id, entry.Status)
 	}
 
-	// Kill the tmux window
-	killCmd := exec.Command("tmux", "kill-window", "-t", session+":"+entry.Window)
-	_ = killCmd.Run() // ignore error if window already gone
+	// Kill the tmux window (queued spawns have none yet)
+	if entry.Status == "running" {
+		killCmd := exec.Command("tmux", "kill-window", "-t", session+":"+entry.Window)
+		_ = killCmd.Run() // ignore error if window already gone
+	}
+	RemoveHeartbeat(session, entry.SpawnRole)
 
 	// Update entry
 	return UpdateSpawnEntry(session, id, func(e *SpawnEntry) {
@@ -272,7 +306,8 @@ func GetSpawnResult(session, spawnRole string) (Message, bool) {
 	return Message{}, false
 }
 
-// CleanFinishedSpawns removes all non-running spawn entries and their inbox files.
+// CleanFinishedSpawns removes all finished spawn entries and their inbox files.
+// Queued spawns are kept.
 func CleanFinishedSpawns(session string) (int, error) {
 	entries, err := ReadSpawnEntries(session)
 	if err != nil {
@@ -282,12 +317,13 @@ func CleanFinishedSpawns(session string) (int, error) {
 	var kept []SpawnEntry
 	removed := 0
 	for _, e := range entries {
-		if e.Status == "running" {
+		if spawnActive(e) {
 			kept = append(kept, e)
 			continue
 		}
 		// Remove spawn inbox file
 		_ = os.Remove(InboxPath(session, e.SpawnRole))
+		clearInbox(session, e.SpawnRole)
 		removed++
 	}
 
@@ -299,13 +335,13 @@ func CleanFinishedSpawns(session string) (int, error) {
 }
 
 // FormatSpawnList formats spawn entries as a human-readable table.
-// When showAll is false, only running entries are shown.
+// When showAll is false, only running and queued entries are shown.
 func FormatSpawnList(entries []SpawnEntry, showAll bool) string {
 	var b strings.Builder
 
 	var filtered []SpawnEntry
 	for _, e := range entries {
-		if showAll || e.Status == "running" {
+		if showAll || spawnActive(e) {
 			filtered = append(filtered, e)
 		}
 	}
@@ -346,6 +382,9 @@ func FormatSpawnStatus(entry SpawnEntry) string {
 	b.WriteString(fmt.Sprintf("  Owner:      %s\n", entry.Owner))
 	b.WriteString(fmt.Sprintf("  Window:     %s\n", entry.Window))
 	b.WriteString(fmt.Sprintf("  Task:       %s\n", entry.Task))
+	if entry.Group != "" {
+		b.WriteString(fmt.Sprintf("  Group:      %s\n", entry.Group))
+	}
 	b.WriteString(fmt.Sprintf("  Started:    %s\n", time.Unix(entry.StartedAt, 0).Format("2006-01-02 15:04:05")))
 
 	if entry.FinishedAt > 0 {
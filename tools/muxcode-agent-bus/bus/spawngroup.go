@@ -0,0 +1,187 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// SpawnGroupCompleteAction is the event sent once when every spawn in a
+// fanout group has finished.
+const SpawnGroupCompleteAction = "spawn-group-complete"
+
+// SpawnGroupResultLimit caps each spawn's result in the completion event
+// payload. The full report is available via `spawn result <group-id>`.
+const SpawnGroupResultLimit = 500
+
+// ReadTaskFile reads a fanout task list. Each non-empty line is either a
+// JSON object with a "task" field or a JSON string.
+func ReadTaskFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tasks []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var task string
+		if strings.HasPrefix(line, "\"") {
+			if err := json.Unmarshal([]byte(line), &task); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+		} else {
+			var obj struct {
+				Task string `json:"task"`
+			}
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+			task = obj.Task
+		}
+		if strings.TrimSpace(task) == "" {
+			return nil, fmt.Errorf("%s:%d: empty task", path, n)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("%s: no tasks", path)
+	}
+	return tasks, nil
+}
+
+// splitFanoutTasks distributes tasks round-robin across count workers.
+// count is clamped to [1, len(tasks)].
+func splitFanoutTasks(tasks []string, count int) [][]string {
+	if count < 1 {
+		count = 1
+	}
+	if count > len(tasks) {
+		count = len(tasks)
+	}
+	chunks := make([][]string, count)
+	for i, t := range tasks {
+		chunks[i%count] = append(chunks[i%count], t)
+	}
+	return chunks
+}
+
+// fanoutTaskText renders one worker's share of the task list as a single
+// spawn task.
+func fanoutTaskText(tasks []string) string {
+	if len(tasks) == 1 {
+		return tasks[0]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Work through these %d tasks in order, then send one combined result:\n", len(tasks))
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, t)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// StartSpawnGroup launches count spawned agents of role over tasks and
+// tracks them under one group ID. The watcher sends a single
+// spawn-group-complete event to owner once all of them have finished.
+// Spawns that started before an error are left running and returned.
+func StartSpawnGroup(session, role string, tasks []string, count int, owner string) (string, []SpawnEntry, error) {
+	if len(tasks) == 0 {
+		return "", nil, fmt.Errorf("no tasks")
+	}
+	group := NewMsgID("fanout")
+	var started []SpawnEntry
+	for _, chunk := range splitFanoutTasks(tasks, count) {
+		entry, err := startSpawn(session, role, fanoutTaskText(chunk), owner, group)
+		if err != nil {
+			return group, started, err
+		}
+		started = append(started, entry)
+	}
+	return group, started, nil
+}
+
+// SpawnGroupEntries returns the entries belonging to group, in start order.
+func SpawnGroupEntries(entries []SpawnEntry, group string) []SpawnEntry {
+	var out []SpawnEntry
+	for _, e := range entries {
+		if e.Group == group {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// SpawnGroupDone reports whether every spawn in the group has finished.
+func SpawnGroupDone(members []SpawnEntry) bool {
+	if len(members) == 0 {
+		return false
+	}
+	for _, e := range members {
+		if spawnActive(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatSpawnGroupReport combines the results of every spawn in a group into
+// one report. maxResult truncates each result (0 = no limit).
+func FormatSpawnGroupReport(session, group string, members []SpawnEntry, maxResult int) string {
+	var b strings.Builder
+	done := 0
+	withResult := 0
+	var first, last int64
+	for _, e := range members {
+		if !spawnActive(e) {
+			done++
+		}
+		if first == 0 || e.StartedAt < first {
+			first = e.StartedAt
+		}
+		if e.FinishedAt > last {
+			last = e.FinishedAt
+		}
+	}
+
+	role := ""
+	if len(members) > 0 {
+		role = members[0].Role
+	}
+	fmt.Fprintf(&b, "Spawn group %s: %d/%d finished (role: %s)\n", group, done, len(members), role)
+	if done == len(members) && last > 0 {
+		fmt.Fprintf(&b, "  Duration: %s\n", time.Duration(last-first)*time.Second)
+	}
+
+	for i, e := range members {
+		fmt.Fprintf(&b, "\n## %d. %s [%s]\n", i+1, e.SpawnRole, e.Status)
+		fmt.Fprintf(&b, "Task: %s\n", e.Task)
+		result, ok := GetSpawnResult(session, e.SpawnRole)
+		if !ok {
+			b.WriteString("Result: (none)\n")
+			continue
+		}
+		withResult++
+		payload := result.Payload
+		if maxResult > 0 && len(payload) > maxResult {
+			payload = payload[:maxResult] + "..."
+		}
+		fmt.Fprintf(&b, "Result:\n%s\n", payload)
+	}
+
+	fmt.Fprintf(&b, "\n%d of %d spawn(s) returned a result.\n", withResult, len(members))
+	return b.String()
+}
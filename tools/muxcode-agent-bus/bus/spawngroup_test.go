@@ -0,0 +1,123 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTaskFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	content := `{"task": "review bus/guard.go"}
+
+"review bus/cron.go"
+{"task": "review cmd/spawn.go", "priority": 1}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := ReadTaskFile(path)
+	if err != nil {
+		t.Fatalf("ReadTaskFile: %v", err)
+	}
+	want := []string{"review bus/guard.go", "review bus/cron.go", "review cmd/spawn.go"}
+	if strings.Join(tasks, "|") != strings.Join(want, "|") {
+		t.Errorf("tasks = %q, want %q", tasks, want)
+	}
+}
+
+func TestReadTaskFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"empty.jsonl":     "\n\n",
+		"malformed.jsonl": "{not json}\n",
+		"blank.jsonl":     `{"task": ""}` + "\n",
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		_ = os.WriteFile(path, []byte(content), 0644)
+		if _, err := ReadTaskFile(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := ReadTaskFile(filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Error("missing file: expected error")
+	}
+}
+
+func TestSplitFanoutTasks(t *testing.T) {
+	tasks := []string{"a", "b", "c", "d", "e"}
+
+	chunks := splitFanoutTasks(tasks, 2)
+	if len(chunks) != 2 || strings.Join(chunks[0], "") != "ace" || strings.Join(chunks[1], "") != "bd" {
+		t.Errorf("count 2: %q", chunks)
+	}
+	if chunks := splitFanoutTasks(tasks, 10); len(chunks) != 5 {
+		t.Errorf("count clamped to tasks: got %d chunks", len(chunks))
+	}
+	if chunks := splitFanoutTasks(tasks, 0); len(chunks) != 1 || len(chunks[0]) != 5 {
+		t.Errorf("count 0: %q", chunks)
+	}
+}
+
+func TestFanoutTaskText(t *testing.T) {
+	if got := fanoutTaskText([]string{"only"}); got != "only" {
+		t.Errorf("single task = %q", got)
+	}
+	got := fanoutTaskText([]string{"first", "second"})
+	for _, want := range []string{"these 2 tasks", "1. first", "2. second"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("task text missing %q: %q", want, got)
+		}
+	}
+}
+
+func TestSpawnGroupDone(t *testing.T) {
+	entries := []SpawnEntry{
+		{ID: "s1", Group: "g1", Status: "completed"},
+		{ID: "s2", Group: "g1", Status: "running"},
+		{ID: "s3", Group: "g2", Status: "completed"},
+		{ID: "s4", Status: "running"},
+	}
+	if SpawnGroupDone(SpawnGroupEntries(entries, "g1")) {
+		t.Error("g1 has a running member")
+	}
+	if !SpawnGroupDone(SpawnGroupEntries(entries, "g2")) {
+		t.Error("g2 should be done")
+	}
+	if SpawnGroupDone(SpawnGroupEntries(entries, "missing")) {
+		t.Error("empty group should not be done")
+	}
+}
+
+func TestFormatSpawnGroupReport(t *testing.T) {
+	session := testSession(t)
+
+	members := []SpawnEntry{
+		{ID: "s1", Role: "review", SpawnRole: "spawn-11111111", Task: "review guard.go", Status: "completed", StartedAt: 100, FinishedAt: 160},
+		{ID: "s2", Role: "review", SpawnRole: "spawn-22222222", Task: "review cron.go", Status: "completed", StartedAt: 100, FinishedAt: 190},
+	}
+	_ = Send(session, NewMessage("spawn-11111111", "edit", "response", "spawn-task", "guard looks fine "+strings.Repeat("x", 50), ""))
+
+	report := FormatSpawnGroupReport(session, "g1", members, 20)
+	for _, want := range []string{
+		"Spawn group g1: 2/2 finished (role: review)",
+		"Duration: 1m30s",
+		"## 1. spawn-11111111 [completed]",
+		"Task: review cron.go",
+		"guard looks fine xxx...",
+		"Result: (none)",
+		"1 of 2 spawn(s) returned a result.",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	full := FormatSpawnGroupReport(session, "g1", members, 0)
+	if !strings.Contains(full, strings.Repeat("x", 50)) {
+		t.Error("untruncated report should include the full result")
+	}
+}
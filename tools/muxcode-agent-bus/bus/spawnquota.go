@@ -0,0 +1,153 @@
+package bus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpawnQuota caps concurrent spawned agents in a session. Zero fields are
+// unlimited. Spawns over quota are queued and launched by the watcher as
+// running spawns finish.
+type SpawnQuota struct {
+	MaxConcurrent int            `json:"max_concurrent,omitempty"` // running spawns across the session
+	MaxPerRole    int            `json:"max_per_role,omitempty"`   // running spawns of any one base role
+	MaxPerOwner   int            `json:"max_per_owner,omitempty"`  // running spawns requested by any one agent
+	Roles         map[string]int `json:"roles,omitempty"`          // per-role caps, overriding max_per_role
+}
+
+// spawnActive reports whether a spawn is running or waiting for a slot.
+func spawnActive(e SpawnEntry) bool {
+	return e.Status == "running" || e.Status == "queued"
+}
+
+// CheckSpawnQuota returns why a new spawn of role for owner cannot launch
+// under the configured quota, or "" if it can.
+func CheckSpawnQuota(entries []SpawnEntry, role, owner string) string {
+	return spawnQuotaBlock(entries, role, owner, Config().SpawnQuota)
+}
+
+// spawnQuotaBlock counts running spawns against each cap in q.
+func spawnQuotaBlock(entries []SpawnEntry, role, owner string, q SpawnQuota) string {
+	total, byRole, byOwner := 0, 0, 0
+	for _, e := range entries {
+		if e.Status != "running" {
+			continue
+		}
+		total++
+		if e.Role == role {
+			byRole++
+		}
+		if e.Owner == owner {
+			byOwner++
+		}
+	}
+
+	roleMax := q.MaxPerRole
+	if n, ok := q.Roles[role]; ok {
+		roleMax = n
+	}
+	switch {
+	case q.MaxConcurrent > 0 && total >= q.MaxConcurrent:
+		return fmt.Sprintf("max_concurrent %d reached", q.MaxConcurrent)
+	case roleMax > 0 && byRole >= roleMax:
+		return fmt.Sprintf("max %d %s spawn(s) reached", roleMax, role)
+	case q.MaxPerOwner > 0 && byOwner >= q.MaxPerOwner:
+		return fmt.Sprintf("max_per_owner %d reached for %s", q.MaxPerOwner, owner)
+	}
+	return ""
+}
+
+// LaunchQueuedSpawns starts queued spawns, oldest first, while the quota
+// allows. A spawn that fails to launch is marked "failed". Returns the
+// entries that changed state.
+func LaunchQueuedSpawns(session string) ([]SpawnEntry, error) {
+	entries, err := ReadSpawnEntries(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var launched []SpawnEntry
+	q := Config().SpawnQuota
+	for i, e := range entries {
+		if e.Status != "queued" {
+			continue
+		}
+		if spawnQuotaBlock(entries, e.Role, e.Owner, q) != "" {
+			continue
+		}
+		now := time.Now().Unix()
+		if err := launchSpawn(session, e); err != nil {
+			entries[i].Status = "failed"
+			entries[i].FinishedAt = now
+		} else {
+			entries[i].Status = "running"
+			entries[i].StartedAt = now
+		}
+		launched = append(launched, entries[i])
+	}
+
+	if len(launched) > 0 {
+		if err := WriteSpawnEntries(session, entries); err != nil {
+			return launched, err
+		}
+	}
+	return launched, nil
+}
+
+// FormatSpawnQuota renders current spawn usage against the quota.
+func FormatSpawnQuota(entries []SpawnEntry, q SpawnQuota) string {
+	limit := func(n int) string {
+		if n <= 0 {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%d", n)
+	}
+
+	running, queued := 0, 0
+	byRole := make(map[string]int)
+	byOwner := make(map[string]int)
+	for _, e := range entries {
+		switch e.Status {
+		case "running":
+			running++
+			byRole[e.Role]++
+			byOwner[e.Owner]++
+		case "queued":
+			queued++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spawns: %d running, %d queued (max_concurrent: %s)\n", running, queued, limit(q.MaxConcurrent))
+	fmt.Fprintf(&b, "  max_per_role: %s  max_per_owner: %s\n", limit(q.MaxPerRole), limit(q.MaxPerOwner))
+
+	var roles []string
+	for r := range byRole {
+		roles = append(roles, r)
+	}
+	for r := range q.Roles {
+		if _, ok := byRole[r]; !ok {
+			roles = append(roles, r)
+		}
+	}
+	sort.Strings(roles)
+	for _, r := range roles {
+		max := q.MaxPerRole
+		if n, ok := q.Roles[r]; ok {
+			max = n
+		}
+		fmt.Fprintf(&b, "  role %-12s %d/%s\n", r, byRole[r], limit(max))
+	}
+
+	var owners []string
+	for o := range byOwner {
+		owners = append(owners, o)
+	}
+	sort.Strings(owners)
+	for _, o := range owners {
+		fmt.Fprintf(&b, "  owner %-11s %d/%s\n", o, byOwner[o], limit(q.MaxPerOwner))
+	}
+	return b.String()
+}
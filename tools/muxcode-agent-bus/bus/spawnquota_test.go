@@ -0,0 +1,141 @@
+package bus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpawnQuotaBlock(t *testing.T) {
+	entries := []SpawnEntry{
+		{ID: "s1", Role: "research", Owner: "edit", Status: "running"},
+		{ID: "s2", Role: "research", Owner: "build", Status: "running"},
+		{ID: "s3", Role: "review", Owner: "edit", Status: "completed"},
+		{ID: "s4", Role: "review", Owner: "edit", Status: "queued"},
+	}
+
+	tests := []struct {
+		name        string
+		role, owner string
+		quota       SpawnQuota
+		want        string
+	}{
+		{"unlimited", "research", "edit", SpawnQuota{}, ""},
+		{"concurrent full", "review", "test", SpawnQuota{MaxConcurrent: 2}, "max_concurrent 2"},
+		{"concurrent free", "review", "test", SpawnQuota{MaxConcurrent: 3}, ""},
+		{"per role", "research", "test", SpawnQuota{MaxPerRole: 2}, "max 2 research"},
+		{"per role other role", "review", "test", SpawnQuota{MaxPerRole: 2}, ""},
+		{"role override", "research", "test", SpawnQuota{MaxPerRole: 1, Roles: map[string]int{"research": 3}}, ""},
+		{"role override zero is unlimited", "review", "test", SpawnQuota{Roles: map[string]int{"review": 0, "research": 1}}, ""},
+		{"per owner", "review", "edit", SpawnQuota{MaxPerOwner: 1}, "max_per_owner 1 reached for edit"},
+		{"per owner other", "review", "test", SpawnQuota{MaxPerOwner: 1}, ""},
+	}
+	for _, tt := range tests {
+		got := spawnQuotaBlock(entries, tt.role, tt.owner, tt.quota)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: spawnQuotaBlock = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStartSpawn_QueuesOverQuota(t *testing.T) {
+	session := testSession(t)
+	cfg := DefaultConfig()
+	cfg.SpawnQuota = SpawnQuota{MaxConcurrent: 1}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	running := SpawnEntry{ID: "s1", Role: "research", SpawnRole: "spawn-11111111", Owner: "edit", Status: "running"}
+	if err := WriteSpawnEntries(session, []SpawnEntry{running}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := StartSpawn(session, "research", "look into cron", "edit")
+	if err != nil {
+		t.Fatalf("StartSpawn: %v", err)
+	}
+	if entry.Status != "queued" {
+		t.Fatalf("status = %q, want queued", entry.Status)
+	}
+	if _, err := os.Stat(InboxPath(session, entry.SpawnRole)); err == nil {
+		t.Error("queued spawn should not have an inbox yet")
+	}
+
+	// Quota still full — nothing launches
+	launched, err := LaunchQueuedSpawns(session)
+	if err != nil || len(launched) != 0 {
+		t.Fatalf("LaunchQueuedSpawns = %v, %v; want nothing launched", launched, err)
+	}
+
+	// Queued spawns are kept by clean and can be stopped
+	if n, _ := CleanFinishedSpawns(session); n != 0 {
+		t.Errorf("CleanFinishedSpawns removed %d, want 0", n)
+	}
+	if err := StopSpawn(session, entry.ID); err != nil {
+		t.Fatalf("StopSpawn(queued): %v", err)
+	}
+	got, _ := GetSpawnEntry(session, entry.ID)
+	if got.Status != "stopped" {
+		t.Errorf("status after stop = %q, want stopped", got.Status)
+	}
+}
+
+func TestLaunchQueuedSpawns_LaunchFailure(t *testing.T) {
+	session := testSession(t)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	queued := []SpawnEntry{
+		{ID: "s1", Role: "research", SpawnRole: "spawn-11111111", Owner: "edit", Status: "queued", Group: "g1"},
+		{ID: "s2", Role: "research", SpawnRole: "spawn-22222222", Owner: "edit", Status: "queued", Group: "g1"},
+	}
+	if err := WriteSpawnEntries(session, queued); err != nil {
+		t.Fatal(err)
+	}
+
+	// No launcher on PATH, so both slots are taken and fail
+	launched, err := LaunchQueuedSpawns(session)
+	if err != nil {
+		t.Fatalf("LaunchQueuedSpawns: %v", err)
+	}
+	if len(launched) != 2 {
+		t.Fatalf("launched %d, want 2", len(launched))
+	}
+	entries, _ := ReadSpawnEntries(session)
+	for _, e := range entries {
+		if e.Status != "failed" || e.FinishedAt == 0 {
+			t.Errorf("%s: status %q finished %d, want failed", e.ID, e.Status, e.FinishedAt)
+		}
+	}
+	if !SpawnGroupDone(SpawnGroupEntries(entries, "g1")) {
+		t.Error("group with only failed members should be done")
+	}
+}
+
+func TestFormatSpawnQuota(t *testing.T) {
+	entries := []SpawnEntry{
+		{Role: "research", Owner: "edit", Status: "running"},
+		{Role: "research", Owner: "edit", Status: "queued"},
+	}
+	out := FormatSpawnQuota(entries, SpawnQuota{MaxConcurrent: 4, Roles: map[string]int{"review": 1}})
+	for _, want := range []string{
+		"1 running, 1 queued (max_concurrent: 4)",
+		"max_per_role: unlimited",
+		"role research     1/unlimited",
+		"role review       0/1",
+		"owner edit        1/unlimited",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMergeConfigs_SpawnQuota(t *testing.T) {
+	base := &MuxcodeConfig{SpawnQuota: SpawnQuota{MaxConcurrent: 4, MaxPerRole: 2, Roles: map[string]int{"research": 1}}}
+	override := &MuxcodeConfig{SpawnQuota: SpawnQuota{MaxPerRole: 3, Roles: map[string]int{"review": 2}}}
+	q := mergeConfigs(base, override).SpawnQuota
+	if q.MaxConcurrent != 4 || q.MaxPerRole != 3 || q.Roles["research"] != 1 || q.Roles["review"] != 2 {
+		t.Errorf("merged quota = %+v", q)
+	}
+}
@@ -0,0 +1,435 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage backends selectable via MUXCODE_STORE.
+const (
+	StoreFiles  = "files"
+	StoreSQLite = "sqlite"
+)
+
+// Store is an alternative persistence backend for the append-mostly record
+// streams that grow with session length: role command history, API history,
+// and memory entries. Each stream is an ordered list of timestamped records.
+// When no store is configured the bus reads and writes its JSONL and
+// markdown files directly. The session message log (log.jsonl) is out of
+// scope: it stays a file, capped by inbox compaction.
+type Store interface {
+	// Append adds a record to the end of a stream.
+	Append(stream string, ts int64, data []byte) error
+	// Read returns a stream's records in insertion order.
+	Read(stream string, q StoreQuery) ([]StoreRecord, error)
+	// Trim deletes all but the last keep records of a stream.
+	Trim(stream string, keep int) error
+	// Prune deletes records older than before (unix seconds).
+	Prune(stream string, before int64) error
+	// Streams lists stream names starting with prefix, sorted.
+	Streams(prefix string) ([]string, error)
+}
+
+// StoreQuery narrows a stream read. Zero values mean no restriction.
+type StoreQuery struct {
+	Since int64 // only records with ts >= Since
+	Until int64 // only records with ts < Until
+	Limit int   // only the last Limit matching records
+}
+
+// StoreRecord is one record in a stream.
+type StoreRecord struct {
+	TS   int64  `json:"ts"`
+	Data string `json:"data"`
+}
+
+// StoreBackend returns the configured backend name from MUXCODE_STORE,
+// defaulting to StoreFiles.
+func StoreBackend() string {
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("MUXCODE_STORE"))); v != "" {
+		return v
+	}
+	return StoreFiles
+}
+
+// StorePath returns the SQLite database path. Uses MUXCODE_STORE_PATH if
+// set, otherwise ".muxcode/muxcode.db".
+func StorePath() string {
+	if v := os.Getenv("MUXCODE_STORE_PATH"); v != "" {
+		return v
+	}
+	return filepath.Join(".muxcode", "muxcode.db")
+}
+
+// ActiveStore returns the configured store, or nil when the default file
+// backend is in use. An unknown or unusable backend is reported on stderr
+// and falls back to files so agents keep working.
+func ActiveStore() Store {
+	switch StoreBackend() {
+	case StoreFiles:
+		return nil
+	case StoreSQLite:
+		s, err := OpenSQLiteStore(StorePath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: sqlite store unavailable, using files: %v\n", err)
+			return nil
+		}
+		return s
+	default:
+		fmt.Fprintf(os.Stderr, "warning: unknown MUXCODE_STORE %q, using files\n", StoreBackend())
+		return nil
+	}
+}
+
+// Stream names used by the bus.
+
+// historyStream is the stream for a role's command history in a session.
+func historyStream(session, role string) string {
+	return "history/" + session + "/" + role
+}
+
+// apiHistoryStream is the stream for executed API requests.
+const apiHistoryStream = "api-history"
+
+// memoryStream is the stream for a role's memory entries.
+func memoryStream(role string) string {
+	return "memory/" + role
+}
+
+// --- SQLite ---
+
+// SQLiteStore keeps streams in a single SQLite table in WAL mode. It drives
+// the sqlite3 command-line shell rather than linking a driver, keeping the
+// bus free of cgo and third-party dependencies. Reads filter and limit in
+// SQL, so callers no longer re-read whole files to get the last N records.
+type SQLiteStore struct {
+	Path string // database file
+	Bin  string // sqlite3 executable
+}
+
+// sqliteSchema creates the records table. journal_mode=WAL is persistent
+// in the database file, so it only needs setting once.
+const sqliteSchema = `PRAGMA journal_mode=WAL;
+CREATE TABLE IF NOT EXISTS records (
+  id     INTEGER PRIMARY KEY AUTOINCREMENT,
+  stream TEXT NOT NULL,
+  ts     INTEGER NOT NULL,
+  data   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS records_stream_id ON records(stream, id);
+`
+
+var (
+	// sqliteReady tracks databases whose schema has been created this process.
+	sqliteReady sync.Map
+	// sqliteBins caches the PATH lookup of each sqlite3 binary name, so it
+	// runs once per process rather than on every store operation.
+	sqliteBins sync.Map // name -> sqliteBin
+)
+
+// sqliteBin is the result of looking up a sqlite3 binary.
+type sqliteBin struct {
+	path string
+	err  error
+}
+
+// OpenSQLiteStore locates the sqlite3 binary (MUXCODE_SQLITE_BIN or PATH)
+// and ensures the database schema exists.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	name := os.Getenv("MUXCODE_SQLITE_BIN")
+	if name == "" {
+		name = "sqlite3"
+	}
+	cached, ok := sqliteBins.Load(name)
+	if !ok {
+		var b sqliteBin
+		b.path, b.err = exec.LookPath(name)
+		cached, _ = sqliteBins.LoadOrStore(name, b)
+	}
+	bin := cached.(sqliteBin)
+	if bin.err != nil {
+		return nil, fmt.Errorf("sqlite3 not found: %v", bin.err)
+	}
+	s := &SQLiteStore{Path: path, Bin: bin.path}
+
+	if _, ok := sqliteReady.Load(path); ok {
+		return s, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if _, err := s.exec(sqliteSchema, false); err != nil {
+		return nil, fmt.Errorf("initializing %s: %v", path, err)
+	}
+	sqliteReady.Store(path, true)
+	return s, nil
+}
+
+// exec runs a SQL script. With query, output is decoded from -json mode.
+func (s *SQLiteStore) exec(script string, query bool) ([]byte, error) {
+	args := []string{"-bail", "-init", os.DevNull}
+	if query {
+		args = append(args, "-json")
+	}
+	args = append(args, s.Path)
+
+	cmd := exec.Command(s.Bin, args...)
+	cmd.Stdin = strings.NewReader(".timeout 5000\n" + script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("sqlite3: %s", msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// sqlQuote returns s as a SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Append adds a record to the end of a stream.
+func (s *SQLiteStore) Append(stream string, ts int64, data []byte) error {
+	_, err := s.exec(fmt.Sprintf("INSERT INTO records(stream, ts, data) VALUES(%s, %d, %s);\n",
+		sqlQuote(stream), ts, sqlQuote(string(data))), false)
+	return err
+}
+
+// appendBatch inserts many records in one transaction.
+func (s *SQLiteStore) appendBatch(stream string, records []StoreRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	for _, r := range records {
+		fmt.Fprintf(&b, "INSERT INTO records(stream, ts, data) VALUES(%s, %d, %s);\n",
+			sqlQuote(stream), r.TS, sqlQuote(r.Data))
+	}
+	b.WriteString("COMMIT;\n")
+	_, err := s.exec(b.String(), false)
+	return err
+}
+
+// Read returns a stream's records in insertion order.
+func (s *SQLiteStore) Read(stream string, q StoreQuery) ([]StoreRecord, error) {
+	where := "stream = " + sqlQuote(stream)
+	if q.Since > 0 {
+		where += fmt.Sprintf(" AND ts >= %d", q.Since)
+	}
+	if q.Until > 0 {
+		where += fmt.Sprintf(" AND ts < %d", q.Until)
+	}
+
+	var sql string
+	if q.Limit > 0 {
+		sql = fmt.Sprintf("SELECT ts, data FROM (SELECT id, ts, data FROM records WHERE %s ORDER BY id DESC LIMIT %d) ORDER BY id;\n", where, q.Limit)
+	} else {
+		sql = fmt.Sprintf("SELECT ts, data FROM records WHERE %s ORDER BY id;\n", where)
+	}
+
+	out, err := s.exec(sql, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var records []StoreRecord
+	if err := json.Unmarshal(out, &records); err != nil {
+		return nil, fmt.Errorf("decoding sqlite3 output: %v", err)
+	}
+	return records, nil
+}
+
+// Trim deletes all but the last keep records of a stream.
+func (s *SQLiteStore) Trim(stream string, keep int) error {
+	q := sqlQuote(stream)
+	_, err := s.exec(fmt.Sprintf("DELETE FROM records WHERE stream = %s AND id NOT IN (SELECT id FROM records WHERE stream = %s ORDER BY id DESC LIMIT %d);\n",
+		q, q, keep), false)
+	return err
+}
+
+// Prune deletes records older than before (unix seconds).
+func (s *SQLiteStore) Prune(stream string, before int64) error {
+	_, err := s.exec(fmt.Sprintf("DELETE FROM records WHERE stream = %s AND ts < %d;\n", sqlQuote(stream), before), false)
+	return err
+}
+
+// Streams lists stream names starting with prefix, sorted.
+func (s *SQLiteStore) Streams(prefix string) ([]string, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	out, err := s.exec(fmt.Sprintf("SELECT DISTINCT stream FROM records WHERE stream LIKE %s ESCAPE '\\' ORDER BY stream;\n", sqlQuote(pattern)), true)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var rows []struct {
+		Stream string `json:"stream"`
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("decoding sqlite3 output: %v", err)
+	}
+	streams := make([]string, 0, len(rows))
+	for _, r := range rows {
+		streams = append(streams, r.Stream)
+	}
+	return streams, nil
+}
+
+// --- Migration ---
+
+// MigrateResult reports how many records were imported into each stream.
+type MigrateResult struct {
+	Stream  string
+	Records int
+	Skipped bool // stream already had records
+}
+
+// MigrateToStore imports the session's role history files, the API history
+// file, and all memory files (active and archived) into s. Streams that
+// already hold records are skipped so re-running is safe. Source files are
+// left in place.
+func MigrateToStore(session string, s *SQLiteStore) ([]MigrateResult, error) {
+	sources := make(map[string][]StoreRecord)
+
+	// Role command history: {role}-history.jsonl in the bus directory
+	matches, _ := filepath.Glob(filepath.Join(BusDir(session), "*-history.jsonl"))
+	for _, path := range matches {
+		role := strings.TrimSuffix(filepath.Base(path), "-history.jsonl")
+		if role == "cron" {
+			continue // cron history stays with cron.jsonl
+		}
+		records, err := jsonlRecords(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[historyStream(session, role)] = records
+	}
+
+	// API history
+	records, err := jsonlRecords(ApiHistoryPath())
+	if err != nil {
+		return nil, err
+	}
+	sources[apiHistoryStream] = records
+
+	// Memory: re-chunk parsed entries so each append is one record
+	roles, err := listMemoryRoleFiles()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := allMemoryFileEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		sources[memoryStream(role)] = nil
+	}
+	for _, e := range entries {
+		ts := int64(0)
+		if t, err := time.ParseInLocation("2006-01-02 15:04", e.Timestamp, time.Local); err == nil {
+			ts = t.Unix()
+		}
+		sealed, err := SealLine([]byte(formatMemoryChunk(e.Section, e.Timestamp, e.Content)))
+		if err != nil {
+			return nil, err
+		}
+		stream := memoryStream(e.Role)
+		sources[stream] = append(sources[stream], StoreRecord{TS: ts, Data: string(sealed)})
+	}
+	for stream, recs := range sources {
+		if strings.HasPrefix(stream, "memory/") {
+			sort.SliceStable(recs, func(i, j int) bool { return recs[i].TS < recs[j].TS })
+		}
+	}
+
+	streams := make([]string, 0, len(sources))
+	for stream := range sources {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+
+	var results []MigrateResult
+	for _, stream := range streams {
+		recs := sources[stream]
+		if len(recs) == 0 {
+			continue
+		}
+		existing, err := s.Read(stream, StoreQuery{Limit: 1})
+		if err != nil {
+			return results, err
+		}
+		if len(existing) > 0 {
+			results = append(results, MigrateResult{Stream: stream, Skipped: true})
+			continue
+		}
+		if err := s.appendBatch(stream, recs); err != nil {
+			return results, fmt.Errorf("importing %s: %v", stream, err)
+		}
+		results = append(results, MigrateResult{Stream: stream, Records: len(recs)})
+	}
+	return results, nil
+}
+
+// jsonlRecords reads a JSONL file as store records, taking ts from each
+// line's "ts" field. Returns nil for a missing file.
+func jsonlRecords(path string) ([]StoreRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []StoreRecord
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var head struct {
+			TS int64 `json:"ts"`
+		}
+		if err := json.Unmarshal(line, &head); err != nil {
+			continue // skip malformed lines
+		}
+		records = append(records, StoreRecord{TS: head.TS, Data: string(line)})
+	}
+	return records, nil
+}
+
+// FormatMigrateResults formats migration results as a human-readable table.
+func FormatMigrateResults(results []MigrateResult, path string) string {
+	var b strings.Builder
+	if len(results) == 0 {
+		b.WriteString("Nothing to migrate.\n")
+		return b.String()
+	}
+	total := 0
+	for _, r := range results {
+		if r.Skipped {
+			fmt.Fprintf(&b, "  %-40s skipped (already migrated)\n", r.Stream)
+			continue
+		}
+		fmt.Fprintf(&b, "  %-40s %d records\n", r.Stream, r.Records)
+		total += r.Records
+	}
+	fmt.Fprintf(&b, "Migrated %d records into %s\n", total, path)
+	return b.String()
+}
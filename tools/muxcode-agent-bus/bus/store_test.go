@@ -0,0 +1,257 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sqliteTestStore enables MUXCODE_STORE=sqlite against a temp database.
+// Skips when the sqlite3 shell is not installed.
+func sqliteTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	path := filepath.Join(t.TempDir(), "muxcode.db")
+	t.Setenv("MUXCODE_STORE", StoreSQLite)
+	t.Setenv("MUXCODE_STORE_PATH", path)
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	return s
+}
+
+func TestActiveStore_DefaultFiles(t *testing.T) {
+	t.Setenv("MUXCODE_STORE", "")
+	if s := ActiveStore(); s != nil {
+		t.Errorf("ActiveStore() = %T, want nil for file backend", s)
+	}
+}
+
+func TestOpenSQLiteStore_CachesLookup(t *testing.T) {
+	t.Setenv("MUXCODE_SQLITE_BIN", "muxcode-test-no-such-sqlite3")
+	for i := 0; i < 2; i++ {
+		if _, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "m.db")); err == nil || !strings.Contains(err.Error(), "sqlite3 not found") {
+			t.Fatalf("open %d: err = %v", i, err)
+		}
+	}
+	if _, ok := sqliteBins.Load("muxcode-test-no-such-sqlite3"); !ok {
+		t.Error("lookup result not cached")
+	}
+}
+
+func TestSQLiteStore_Streams(t *testing.T) {
+	s := sqliteTestStore(t)
+
+	for i, data := range []string{"one", "it's \"quoted\"\nmultiline", "three"} {
+		if err := s.Append("history/x/build", int64(100+i), []byte(data)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	_ = s.Append("history/x_y/test", 1, []byte("other"))
+
+	all, err := s.Read("history/x/build", StoreQuery{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Read all: %d records, err %v", len(all), err)
+	}
+	if all[1].Data != "it's \"quoted\"\nmultiline" {
+		t.Errorf("round-trip = %q", all[1].Data)
+	}
+
+	last, _ := s.Read("history/x/build", StoreQuery{Limit: 2})
+	if len(last) != 2 || last[0].Data != all[1].Data || last[1].Data != "three" {
+		t.Errorf("Limit 2 = %+v, want last two in order", last)
+	}
+
+	since, _ := s.Read("history/x/build", StoreQuery{Since: 101, Until: 102})
+	if len(since) != 1 || since[0].TS != 101 {
+		t.Errorf("Since/Until = %+v", since)
+	}
+
+	// LIKE wildcards in the prefix are literal
+	streams, _ := s.Streams("history/x/")
+	if len(streams) != 1 || streams[0] != "history/x/build" {
+		t.Errorf("Streams = %v", streams)
+	}
+
+	if err := s.Trim("history/x/build", 1); err != nil {
+		t.Fatalf("Trim: %v", err)
+	}
+	if recs, _ := s.Read("history/x/build", StoreQuery{}); len(recs) != 1 || recs[0].Data != "three" {
+		t.Errorf("after Trim = %+v", recs)
+	}
+
+	if err := s.Prune("history/x_y/test", 2); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if recs, _ := s.Read("history/x_y/test", StoreQuery{}); len(recs) != 0 {
+		t.Errorf("after Prune = %+v", recs)
+	}
+}
+
+func TestSQLiteStore_HistoryRouting(t *testing.T) {
+	sqliteTestStore(t)
+	session := fmt.Sprintf("test-store-%d", rand.Int())
+
+	for i := 0; i < 5; i++ {
+		data, _ := json.Marshal(HistoryEntry{TS: int64(i + 1), Command: fmt.Sprintf("make %d", i), ExitCode: "0"})
+		if err := AppendHistory(session, "build", data, 3); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(HistoryPath(session, "build")); !os.IsNotExist(err) {
+		t.Error("history file written despite sqlite backend")
+	}
+	entries := ReadHistory(session, "build", 0)
+	if len(entries) != 3 || entries[0].Command != "make 2" {
+		t.Errorf("ReadHistory = %+v, want last 3 entries", entries)
+	}
+	if entries := ReadHistory(session, "build", 1); len(entries) != 1 || entries[0].Command != "make 4" {
+		t.Errorf("ReadHistory limit 1 = %+v", entries)
+	}
+}
+
+func TestSQLiteStore_ApiHistoryRouting(t *testing.T) {
+	sqliteTestStore(t)
+	t.Setenv("BUS_API_DIR", t.TempDir())
+
+	_ = AppendApiHistory(ApiHistoryEntry{TS: 1, Collection: "users", Method: "GET", URL: "/u", Status: 200})
+	_ = AppendApiHistory(ApiHistoryEntry{TS: 2, Collection: "orders", Method: "GET", URL: "/o", Status: 200})
+	_ = AppendApiHistory(ApiHistoryEntry{TS: 3, Collection: "users", Method: "POST", URL: "/u", Status: 201})
+
+	all, err := ReadApiHistory("", 2)
+	if err != nil || len(all) != 2 || all[1].TS != 3 {
+		t.Errorf("ReadApiHistory limit 2 = %+v (err %v)", all, err)
+	}
+	users, _ := ReadApiHistory("users", 0)
+	if len(users) != 2 {
+		t.Errorf("ReadApiHistory users = %d entries, want 2", len(users))
+	}
+	if _, err := os.Stat(ApiHistoryPath()); !os.IsNotExist(err) {
+		t.Error("API history file written despite sqlite backend")
+	}
+}
+
+func TestSQLiteStore_MemoryRouting(t *testing.T) {
+	sqliteTestStore(t)
+	t.Setenv("BUS_MEMORY_DIR", t.TempDir())
+
+	if err := AppendMemory("Build Config", "use pnpm", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	_ = AppendMemory("Conventions", "tabs not spaces", "shared")
+
+	content, err := ReadMemory("build")
+	if err != nil || !strings.Contains(content, "## Build Config") || !strings.Contains(content, "use pnpm") {
+		t.Errorf("ReadMemory = %q (err %v)", content, err)
+	}
+	roles, _ := ListMemoryRoles()
+	if len(roles) != 2 || roles[0] != "build" || roles[1] != "shared" {
+		t.Errorf("ListMemoryRoles = %v", roles)
+	}
+	entries, _ := AllMemoryEntries()
+	if len(entries) != 2 {
+		t.Errorf("AllMemoryEntries = %d entries, want 2", len(entries))
+	}
+	results, _ := SearchMemoryBM25(SearchOptions{Query: "pnpm"})
+	if len(results) != 1 || results[0].Entry.Role != "build" {
+		t.Errorf("BM25 over store = %+v", results)
+	}
+	if _, err := os.Stat(MemoryPath("build")); !os.IsNotExist(err) {
+		t.Error("memory file written despite sqlite backend")
+	}
+}
+
+func TestMigrateToStore(t *testing.T) {
+	session := fmt.Sprintf("test-migrate-%d", rand.Int())
+	memDir := t.TempDir()
+	t.Cleanup(func() { _ = Cleanup(session) })
+	if err := Init(session, memDir); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Setenv("BUS_MEMORY_DIR", memDir)
+	t.Setenv("BUS_API_DIR", t.TempDir())
+	t.Setenv("MUXCODE_STORE", "")
+
+	// Seed file-backed data
+	hist, _ := json.Marshal(HistoryEntry{TS: 10, Command: "go test ./...", ExitCode: "1"})
+	_ = AppendHistory(session, "test", hist, 0)
+	_ = AppendApiHistory(ApiHistoryEntry{TS: 5, Method: "GET", URL: "/health", Status: 200})
+	_ = AppendMemory("Deploy", "use cdk", "deploy")
+	archived := formatMemoryChunk("Old Note", "2020-01-02 03:04", "from an archive")
+	_ = os.MkdirAll(MemoryArchiveDir("deploy"), 0755)
+	_ = os.WriteFile(MemoryArchivePath("deploy", "2020-01-02"), []byte(archived), 0644)
+
+	s := sqliteTestStore(t)
+	results, err := MigrateToStore(session, s)
+	if err != nil {
+		t.Fatalf("MigrateToStore: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Stream] = r.Records
+	}
+	if counts[historyStream(session, "test")] != 1 || counts[apiHistoryStream] != 1 || counts[memoryStream("deploy")] != 2 {
+		t.Errorf("migrated counts = %v", counts)
+	}
+
+	// Reads now come from the store
+	if h := ReadHistory(session, "test", 0); len(h) != 1 || h[0].Command != "go test ./..." {
+		t.Errorf("ReadHistory after migrate = %+v", h)
+	}
+	entries, _ := AllMemoryEntries()
+	if len(entries) != 2 || entries[0].Section != "Old Note" {
+		t.Errorf("memory after migrate = %+v, want archive entry first", entries)
+	}
+	if today, _ := ReadMemory("deploy"); strings.Contains(today, "Old Note") {
+		t.Error("archived entry leaked into today's memory")
+	}
+
+	// Re-running skips populated streams
+	again, _ := MigrateToStore(session, s)
+	for _, r := range again {
+		if !r.Skipped {
+			t.Errorf("second migrate re-imported %s", r.Stream)
+		}
+	}
+	if out := FormatMigrateResults(again, "db"); !strings.Contains(out, "already migrated") {
+		t.Errorf("FormatMigrateResults = %q", out)
+	}
+}
+
+func TestSQLiteStore_MemoryEncrypted(t *testing.T) {
+	s := sqliteTestStore(t)
+	store := testKeychain(t)
+	store[Config().Encryption.keyName()] = testBusKey(1)
+
+	if err := AppendMemory("Secrets", "deploy token lives in vault", "build"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	records, err := s.Read(memoryStream("build"), StoreQuery{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("records = %+v (err %v)", records, err)
+	}
+	if !isSealed([]byte(records[0].Data)) || strings.Contains(records[0].Data, "vault") {
+		t.Errorf("store record not sealed: %q", records[0].Data)
+	}
+	if content, err := ReadMemory("build"); err != nil || !strings.Contains(content, "deploy token lives in vault") {
+		t.Errorf("ReadMemory = %q (err %v)", content, err)
+	}
+
+	if _, err := Rekey(testSession(t)); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	delete(store, Config().Encryption.keyName()+".prev")
+	resetBusKeys()
+	if content, err := ReadMemory("build"); err != nil || !strings.Contains(content, "vault") {
+		t.Errorf("after rekey, ReadMemory = %q (err %v)", content, err)
+	}
+}
@@ -6,21 +6,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Subscription represents an event subscription for fan-out notifications.
 type Subscription struct {
-	ID        string `json:"id"`
-	Event     string `json:"event"`
-	Outcome   string `json:"outcome"`
-	Notify    string `json:"notify"`
-	Action    string `json:"action"`
-	Message   string `json:"message"`
-	Enabled   bool   `json:"enabled"`
-	CreatedAt int64  `json:"created_at"`
-	FireCount int    `json:"fire_count"`
+	ID           string `json:"id"`
+	Event        string `json:"event"`
+	Outcome      string `json:"outcome"`
+	Notify       string `json:"notify"`
+	Action       string `json:"action"`
+	Message      string `json:"message"`
+	WebhookURL   string `json:"webhook_url,omitempty"`   // POST a JSON payload here
+	SlackChannel string `json:"slack_channel,omitempty"` // post Slack blocks to this channel
+	Match        string `json:"match,omitempty"`         // regex over the command or expanded message
+	RoleFrom     string `json:"role_from,omitempty"`     // only fire for events from this role
+	Enabled      bool   `json:"enabled"`
+	CreatedAt    int64  `json:"created_at"`
+	FireCount    int    `json:"fire_count"`
 }
 
 // ReadSubscriptions reads all subscriptions from the JSONL file.
@@ -66,9 +72,26 @@ func WriteSubscriptions(session string, entries []Subscription) error {
 // AddSubscription validates and appends a new subscription. Returns the entry
 // with generated ID and CreatedAt fields populated.
 func AddSubscription(session string, sub Subscription) (Subscription, error) {
-	// Validate notify role
-	if !IsKnownRole(sub.Notify) {
-		return Subscription{}, fmt.Errorf("unknown notify role: %s", sub.Notify)
+	// Validate notify role (optional when delivering to a webhook or Slack)
+	if sub.Notify != "" || !sub.HasExternalTarget() {
+		if !IsKnownRole(sub.Notify) {
+			return Subscription{}, fmt.Errorf("unknown notify role: %s", sub.Notify)
+		}
+	}
+	if sub.WebhookURL != "" {
+		if err := checkWebhookURL(sub.WebhookURL); err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	// Validate filters
+	if sub.Match != "" {
+		if _, err := regexp.Compile(sub.Match); err != nil {
+			return Subscription{}, fmt.Errorf("invalid match pattern: %v", err)
+		}
+	}
+	if sub.RoleFrom != "" && !IsKnownRole(sub.RoleFrom) {
+		return Subscription{}, fmt.Errorf("unknown role_from role: %s", sub.RoleFrom)
 	}
 
 	// Validate event
@@ -102,6 +125,11 @@ func AddSubscription(session string, sub Subscription) (Subscription, error) {
 	if err := WriteSubscriptions(session, entries); err != nil {
 		return Subscription{}, err
 	}
+	// Webhook and Slack URLs carry credentials: record only that they are set
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditSubscriptionAdd, Role: sub.Notify, Args: map[string]string{
+		"id": sub.ID, "event": sub.Event, "outcome": sub.Outcome, "action": sub.Action,
+		"external": strconv.FormatBool(sub.HasExternalTarget()),
+	}})
 	return sub, nil
 }
 
@@ -112,21 +140,27 @@ func RemoveSubscription(session, id string) error {
 		return err
 	}
 
-	found := false
+	var removed *Subscription
 	var kept []Subscription
-	for _, e := range entries {
+	for i, e := range entries {
 		if e.ID == id {
-			found = true
+			removed = &entries[i]
 			continue
 		}
 		kept = append(kept, e)
 	}
 
-	if !found {
+	if removed == nil {
 		return fmt.Errorf("subscription not found: %s", id)
 	}
 
-	return WriteSubscriptions(session, kept)
+	if err := WriteSubscriptions(session, kept); err != nil {
+		return err
+	}
+	_ = RecordAudit(session, AuditEvent{Actor: BusRole(), Op: AuditSubscriptionRemove, Role: removed.Notify, Args: map[string]string{
+		"id": id, "event": removed.Event, "outcome": removed.Outcome,
+	}})
+	return nil
 }
 
 // SetSubscriptionEnabled enables or disables a subscription by ID.
@@ -152,8 +186,11 @@ func SetSubscriptionEnabled(session, id string, enabled bool) error {
 	return WriteSubscriptions(session, entries)
 }
 
-// MatchSubscriptions filters subscriptions that are enabled and match the event+outcome.
-func MatchSubscriptions(subs []Subscription, event, outcome string) []Subscription {
+// MatchSubscriptions filters subscriptions that are enabled and match the
+// event+outcome, then applies the optional role_from and match filters.
+// A match pattern passes when it matches either the command or the expanded
+// message; an invalid pattern never matches.
+func MatchSubscriptions(subs []Subscription, event, outcome, from, exitCode, command string) []Subscription {
 	var matched []Subscription
 	for _, s := range subs {
 		if !s.Enabled {
@@ -165,39 +202,75 @@ func MatchSubscriptions(subs []Subscription, event, outcome string) []Subscripti
 		if s.Outcome != "*" && s.Outcome != outcome {
 			continue
 		}
+		if s.RoleFrom != "" && s.RoleFrom != from {
+			continue
+		}
+		if s.Match != "" {
+			re, err := regexp.Compile(s.Match)
+			if err != nil {
+				continue
+			}
+			payload := ExpandSubscriptionMessage(s.Message, event, outcome, exitCode, command)
+			if !re.MatchString(command) && !re.MatchString(payload) {
+				continue
+			}
+		}
 		matched = append(matched, s)
 	}
 	return matched
 }
 
 // FireSubscriptions reads subscriptions, matches against the event/outcome,
-// expands message templates, and sends notifications. Returns the count of
-// fired subscriptions.
+// expands message templates, and sends notifications to the notify role and
+// any webhook or Slack target. Returns the count of fired subscriptions.
 func FireSubscriptions(session, from, event, outcome, exitCode, command string) (int, error) {
 	subs, err := ReadSubscriptions(session)
 	if err != nil {
 		return 0, err
 	}
 
-	matched := MatchSubscriptions(subs, event, outcome)
+	matched := MatchSubscriptions(subs, event, outcome, from, exitCode, command)
 	if len(matched) == 0 {
 		return 0, nil
 	}
 
 	fired := 0
 	notified := make(map[string]bool) // dedupe tmux notifications per role
+	firedIDs := make(map[string]bool, len(matched))
 	for _, s := range matched {
 		payload := ExpandSubscriptionMessage(s.Message, event, outcome, exitCode, command)
-		msg := NewMessage(from, s.Notify, "event", s.Action, payload, "")
-		if err := SendNoCC(session, msg); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: subscription %s notify failed: %v\n", s.ID, err)
-			continue
+		if s.HasExternalTarget() {
+			err := deliverExternal(s, SubscriptionPayload{
+				Subscription: s.ID,
+				Session:      session,
+				From:         from,
+				Event:        event,
+				Outcome:      outcome,
+				ExitCode:     exitCode,
+				Command:      command,
+				Message:      payload,
+				Timestamp:    time.Now().Unix(),
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: subscription %s delivery failed: %v\n", s.ID, err)
+				if s.Notify == "" {
+					continue
+				}
+			}
 		}
-		// Send tmux notification so agent wakes up to read the message
-		if !notified[s.Notify] {
-			_ = Notify(session, s.Notify)
-			notified[s.Notify] = true
+		if s.Notify != "" {
+			msg := NewMessage(from, s.Notify, "event", s.Action, payload, "")
+			if err := SendNoCC(session, msg); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: subscription %s notify failed: %v\n", s.ID, err)
+				continue
+			}
+			// Send tmux notification so agent wakes up to read the message
+			if !notified[s.Notify] {
+				_ = Notify(session, s.Notify)
+				notified[s.Notify] = true
+			}
 		}
+		firedIDs[s.ID] = true
 		fired++
 	}
 
@@ -206,12 +279,8 @@ func FireSubscriptions(session, from, event, outcome, exitCode, command string)
 		// Re-read to get latest state, then increment matched entries
 		all, err := ReadSubscriptions(session)
 		if err == nil {
-			matchedIDs := make(map[string]bool, len(matched))
-			for _, s := range matched {
-				matchedIDs[s.ID] = true
-			}
 			for i, e := range all {
-				if matchedIDs[e.ID] {
+				if firedIDs[e.ID] {
 					all[i].FireCount++
 				}
 			}
@@ -232,6 +301,22 @@ func ExpandSubscriptionMessage(template, event, outcome, exitCode, command strin
 	return s
 }
 
+// FilterSummary describes the subscription's role_from and match filters,
+// or "-" when it has none.
+func (s Subscription) FilterSummary() string {
+	var f []string
+	if s.RoleFrom != "" {
+		f = append(f, "from="+s.RoleFrom)
+	}
+	if s.Match != "" {
+		f = append(f, "match="+s.Match)
+	}
+	if len(f) == 0 {
+		return "-"
+	}
+	return strings.Join(f, " ")
+}
+
 // FormatSubscriptionList formats subscriptions as a human-readable table.
 // When showAll is false, only enabled entries are shown.
 func FormatSubscriptionList(entries []Subscription, showAll bool) string {
@@ -253,17 +338,17 @@ func FormatSubscriptionList(entries []Subscription, showAll bool) string {
 		return b.String()
 	}
 
-	b.WriteString(fmt.Sprintf("%-40s %-8s %-10s %-10s %-8s %-8s %s\n",
-		"ID", "Event", "Outcome", "Notify", "Action", "Status", "Fires"))
-	b.WriteString(strings.Repeat("-", 100) + "\n")
+	b.WriteString(fmt.Sprintf("%-40s %-8s %-10s %-10s %-8s %-8s %-5s %s\n",
+		"ID", "Event", "Outcome", "Notify", "Action", "Status", "Fires", "Filter"))
+	b.WriteString(strings.Repeat("-", 110) + "\n")
 
 	for _, e := range filtered {
 		status := "enabled"
 		if !e.Enabled {
 			status = "disabled"
 		}
-		b.WriteString(fmt.Sprintf("%-40s %-8s %-10s %-10s %-8s %-8s %d\n",
-			e.ID, e.Event, e.Outcome, e.Notify, e.Action, status, e.FireCount))
+		b.WriteString(fmt.Sprintf("%-40s %-8s %-10s %-10s %-8s %-8s %-5d %s\n",
+			e.ID, e.Event, e.Outcome, e.Targets(), e.Action, status, e.FireCount, e.FilterSummary()))
 	}
 
 	return b.String()
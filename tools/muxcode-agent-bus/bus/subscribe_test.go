@@ -280,7 +280,7 @@ func TestMatchSubscriptions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matched := MatchSubscriptions(subs, tt.event, tt.outcome)
+			matched := MatchSubscriptions(subs, tt.event, tt.outcome, "build", "0", "go build")
 			if len(matched) != len(tt.wantIDs) {
 				t.Errorf("expected %d matches, got %d", len(tt.wantIDs), len(matched))
 				for _, m := range matched {
@@ -297,18 +297,64 @@ func TestMatchSubscriptions(t *testing.T) {
 	}
 }
 
+func TestMatchSubscriptions_Filters(t *testing.T) {
+	subs := []Subscription{
+		{ID: "1", Event: "deploy", Outcome: "failure", Notify: "edit", Match: `cdk deploy .*Prod`, Enabled: true},
+		{ID: "2", Event: "deploy", Outcome: "*", Notify: "watch", RoleFrom: "runner", Enabled: true},
+		{ID: "3", Event: "*", Outcome: "*", Notify: "docs", Message: "${event} exit ${exit_code}", Match: `exit [1-9]`, Enabled: true},
+		{ID: "4", Event: "*", Outcome: "*", Notify: "docs", Match: `(`, Enabled: true},
+	}
+
+	tests := []struct {
+		name, outcome, from, exitCode, command string
+		wantIDs                                []string
+	}{
+		{"prod stack", "failure", "deploy", "1", "cdk deploy MyApp-Prod", []string{"1", "3"}},
+		{"dev stack", "failure", "deploy", "1", "cdk deploy MyApp-Dev", []string{"3"}},
+		{"from runner", "success", "runner", "0", "cdk deploy MyApp-Prod", []string{"2"}},
+	}
+	for _, tt := range tests {
+		matched := MatchSubscriptions(subs, "deploy", tt.outcome, tt.from, tt.exitCode, tt.command)
+		var ids []string
+		for _, m := range matched {
+			ids = append(ids, m.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+			t.Errorf("%s: matched %v, want %v", tt.name, ids, tt.wantIDs)
+		}
+	}
+}
+
+func TestAddSubscription_InvalidFilters(t *testing.T) {
+	session := testSession(t)
+
+	if _, err := AddSubscription(session, Subscription{Event: "deploy", Outcome: "*", Notify: "edit", Match: "(", RoleFrom: "deploy"}); err == nil {
+		t.Error("expected error for invalid match pattern")
+	}
+	if _, err := AddSubscription(session, Subscription{Event: "deploy", Outcome: "*", Notify: "edit", RoleFrom: "nobody"}); err == nil {
+		t.Error("expected error for unknown role_from")
+	}
+	sub, err := AddSubscription(session, Subscription{Event: "deploy", Outcome: "*", Notify: "edit", Match: "Prod", RoleFrom: "deploy"})
+	if err != nil {
+		t.Fatalf("AddSubscription: %v", err)
+	}
+	if got := sub.FilterSummary(); got != "from=deploy match=Prod" {
+		t.Errorf("FilterSummary = %q", got)
+	}
+}
+
 func TestMatchSubscriptions_DisabledSkipped(t *testing.T) {
 	subs := []Subscription{
 		{ID: "1", Event: "build", Outcome: "success", Notify: "docs", Enabled: false},
 	}
-	matched := MatchSubscriptions(subs, "build", "success")
+	matched := MatchSubscriptions(subs, "build", "success", "build", "0", "go build")
 	if len(matched) != 0 {
 		t.Errorf("expected 0 matches for disabled sub, got %d", len(matched))
 	}
 }
 
 func TestMatchSubscriptions_Empty(t *testing.T) {
-	matched := MatchSubscriptions(nil, "build", "success")
+	matched := MatchSubscriptions(nil, "build", "success", "build", "0", "go build")
 	if len(matched) != 0 {
 		t.Errorf("expected 0 matches for nil subs, got %d", len(matched))
 	}
@@ -0,0 +1,205 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// SlackTokenEnv names the environment variable holding the Slack bot token
+// used for slack_channel subscriptions.
+const SlackTokenEnv = "MUXCODE_SLACK_TOKEN"
+
+// subscriptionHTTPTimeout bounds each outgoing webhook or Slack request so a
+// slow endpoint cannot stall the chain that fired the subscription.
+const subscriptionHTTPTimeout = 5 * time.Second
+
+// slackPostMessageURL is the Slack Web API endpoint (overridden in tests).
+var slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SubscriptionPayload is the JSON body POSTed to a subscription's webhook_url.
+type SubscriptionPayload struct {
+	Subscription string `json:"subscription"`
+	Session      string `json:"session"`
+	From         string `json:"from"`
+	Event        string `json:"event"`
+	Outcome      string `json:"outcome"`
+	ExitCode     string `json:"exit_code"`
+	Command      string `json:"command"`
+	Message      string `json:"message"`
+	Timestamp    int64  `json:"ts"`
+}
+
+// HasExternalTarget reports whether the subscription delivers outside the bus.
+func (s Subscription) HasExternalTarget() bool {
+	return s.WebhookURL != "" || s.SlackChannel != ""
+}
+
+// Targets returns a short description of where the subscription delivers,
+// e.g. "edit", "#ci", or "edit,webhook".
+func (s Subscription) Targets() string {
+	var t []string
+	if s.Notify != "" {
+		t = append(t, s.Notify)
+	}
+	if s.SlackChannel != "" {
+		t = append(t, "#"+strings.TrimPrefix(s.SlackChannel, "#"))
+	}
+	if s.WebhookURL != "" {
+		t = append(t, "webhook")
+	}
+	if len(t) == 0 {
+		return "-"
+	}
+	return strings.Join(t, ",")
+}
+
+// checkWebhookURL validates an outgoing webhook URL.
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL: %s (must be http or https)", raw)
+	}
+	return nil
+}
+
+// isSlackWebhookURL reports whether a webhook URL is a Slack incoming webhook,
+// which expects a Slack message body rather than the generic JSON payload.
+func isSlackWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Host == "hooks.slack.com"
+}
+
+// SlackBlocks builds the Slack Block Kit blocks for a subscription outcome.
+func SlackBlocks(p SubscriptionPayload) []map[string]interface{} {
+	icon := ":white_check_mark:"
+	if p.Outcome == "failure" {
+		icon = ":x:"
+	}
+	context := fmt.Sprintf("session `%s` · from `%s`", p.Session, p.From)
+	if p.ExitCode != "" {
+		context += fmt.Sprintf(" · exit %s", p.ExitCode)
+	}
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]string{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("%s *%s %s*\n%s", icon, p.Event, p.Outcome, p.Message),
+			},
+		},
+	}
+	if p.Command != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": "```" + p.Command + "```"},
+		})
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type":     "context",
+		"elements": []map[string]string{{"type": "mrkdwn", "text": context}},
+	})
+	return blocks
+}
+
+// slackBody builds a chat.postMessage / incoming-webhook body. The text field
+// is the notification fallback for clients that do not render blocks.
+func slackBody(channel string, p SubscriptionPayload) map[string]interface{} {
+	body := map[string]interface{}{
+		"text":   fmt.Sprintf("%s %s: %s", p.Event, p.Outcome, p.Message),
+		"blocks": SlackBlocks(p),
+	}
+	if channel != "" {
+		body["channel"] = channel
+	}
+	return body
+}
+
+// postJSON POSTs v as JSON and returns the response body. Non-2xx responses
+// are errors.
+func postJSON(target string, headers map[string]string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "muxcode-agent-bus")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: subscriptionHTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return body, nil
+}
+
+// postSubscriptionWebhook delivers the payload to the subscription's
+// webhook_url. Slack incoming webhooks receive Slack blocks instead.
+func postSubscriptionWebhook(s Subscription, p SubscriptionPayload) error {
+	if isSlackWebhookURL(s.WebhookURL) {
+		_, err := postJSON(s.WebhookURL, nil, slackBody("", p))
+		return err
+	}
+	_, err := postJSON(s.WebhookURL, nil, p)
+	return err
+}
+
+// postSubscriptionSlack posts the payload to the subscription's Slack channel
+// via chat.postMessage, authenticating with the token in MUXCODE_SLACK_TOKEN.
+func postSubscriptionSlack(s Subscription, p SubscriptionPayload) error {
+	token := os.Getenv(SlackTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set", SlackTokenEnv)
+	}
+	body, err := postJSON(slackPostMessageURL, map[string]string{"Authorization": "Bearer " + token}, slackBody(s.SlackChannel, p))
+	if err != nil {
+		return err
+	}
+	// Slack reports API errors with 200 OK and {"ok": false}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid Slack response: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack: %s", result.Error)
+	}
+	return nil
+}
+
+// deliverExternal sends the payload to the subscription's webhook and Slack
+// targets. Returns the first error; every target is attempted.
+func deliverExternal(s Subscription, p SubscriptionPayload) error {
+	var firstErr error
+	if s.WebhookURL != "" {
+		if err := postSubscriptionWebhook(s, p); err != nil {
+			firstErr = fmt.Errorf("webhook: %v", err)
+		}
+	}
+	if s.SlackChannel != "" {
+		if err := postSubscriptionSlack(s, p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,136 @@
+package bus
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAddSubscription_ExternalTargets(t *testing.T) {
+	session := testSession(t)
+
+	sub, err := AddSubscription(session, Subscription{Event: "deploy", Outcome: "*", WebhookURL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("webhook without notify role: %v", err)
+	}
+	if sub.Targets() != "webhook" {
+		t.Errorf("Targets = %q, want webhook", sub.Targets())
+	}
+
+	sub, err = AddSubscription(session, Subscription{Event: "test", Outcome: "failure", Notify: "edit", SlackChannel: "#ci"})
+	if err != nil {
+		t.Fatalf("slack with notify role: %v", err)
+	}
+	if sub.Targets() != "edit,#ci" {
+		t.Errorf("Targets = %q, want edit,#ci", sub.Targets())
+	}
+
+	if _, err := AddSubscription(session, Subscription{Event: "build", Outcome: "*", WebhookURL: "ftp://example.com"}); err == nil {
+		t.Error("expected error for non-http webhook URL")
+	}
+	if _, err := AddSubscription(session, Subscription{Event: "build", Outcome: "*", Notify: "nope", WebhookURL: "https://example.com"}); err == nil {
+		t.Error("expected error for unknown notify role with webhook")
+	}
+}
+
+func TestFireSubscriptions_Webhook(t *testing.T) {
+	session := testSession(t)
+
+	var got SubscriptionPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	entries := []Subscription{
+		{ID: "sub-1", Event: "deploy", Outcome: "failure", Message: "Deploy failed: ${command}", WebhookURL: srv.URL, Enabled: true},
+		{ID: "sub-2", Event: "deploy", Outcome: "*", Message: "x", WebhookURL: failing.URL, Enabled: true},
+		{ID: "sub-3", Event: "deploy", Outcome: "*", Notify: "edit", Action: "notify", Message: "x", WebhookURL: failing.URL, Enabled: true},
+	}
+	if err := WriteSubscriptions(session, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := FireSubscriptions(session, "deploy", "deploy", "failure", "1", "make deploy")
+	if err != nil {
+		t.Fatalf("FireSubscriptions: %v", err)
+	}
+	// sub-2 fails with no agent fallback; sub-3 still reaches edit
+	if count != 2 {
+		t.Errorf("fired %d, want 2", count)
+	}
+	if got.Subscription != "sub-1" || got.Message != "Deploy failed: make deploy" || got.ExitCode != "1" || got.Session != session {
+		t.Errorf("payload = %+v", got)
+	}
+	if msgs, _ := Peek(session, "edit"); len(msgs) != 1 {
+		t.Errorf("edit inbox has %d messages, want 1", len(msgs))
+	}
+
+	updated, _ := ReadSubscriptions(session)
+	for _, s := range updated {
+		want := 1
+		if s.ID == "sub-2" {
+			want = 0
+		}
+		if s.FireCount != want {
+			t.Errorf("%s: FireCount = %d, want %d", s.ID, s.FireCount, want)
+		}
+	}
+}
+
+func TestFireSubscriptions_Slack(t *testing.T) {
+	session := testSession(t)
+	t.Setenv(SlackTokenEnv, "xoxb-test")
+
+	var body map[string]interface{}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		if body["channel"] == "#missing" {
+			_, _ = w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+	old := slackPostMessageURL
+	slackPostMessageURL = srv.URL
+	defer func() { slackPostMessageURL = old }()
+
+	sub := Subscription{ID: "sub-1", SlackChannel: "#ci"}
+	p := SubscriptionPayload{Session: session, From: "build", Event: "build", Outcome: "failure", ExitCode: "2", Command: "go build", Message: "Build broke"}
+	if err := deliverExternal(sub, p); err != nil {
+		t.Fatalf("deliverExternal: %v", err)
+	}
+	if auth != "Bearer xoxb-test" || body["channel"] != "#ci" {
+		t.Errorf("auth %q, body %v", auth, body)
+	}
+	blocks, _ := json.Marshal(body["blocks"])
+	for _, want := range []string{":x: *build failure*", "go build", "exit 2"} {
+		if !strings.Contains(string(blocks), want) {
+			t.Errorf("blocks missing %q: %s", want, blocks)
+		}
+	}
+
+	sub.SlackChannel = "#missing"
+	if err := deliverExternal(sub, p); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("expected channel_not_found, got %v", err)
+	}
+
+	t.Setenv(SlackTokenEnv, "")
+	if err := deliverExternal(Subscription{SlackChannel: "#ci"}, p); err == nil {
+		t.Error("expected error without token")
+	}
+}
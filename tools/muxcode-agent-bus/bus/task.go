@@ -0,0 +1,279 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Task status values.
+const (
+	TaskPending    = "pending"
+	TaskDispatched = "dispatched"
+)
+
+// DefaultTaskAction is the bus action used for idle-task requests.
+const DefaultTaskAction = "idle-task"
+
+// taskCooldownSecs is the minimum gap between two idle tasks dispatched to
+// the same role. Covers the window between an agent reading its inbox and
+// taking its lock, when it would otherwise look idle.
+const taskCooldownSecs = 60
+
+// TaskEntry is a low-priority background chore waiting for an idle agent.
+type TaskEntry struct {
+	ID           string `json:"id"`
+	Target       string `json:"target,omitempty"` // empty = any local-LLM role
+	Action       string `json:"action"`
+	Message      string `json:"message"`
+	Status       string `json:"status"`
+	CreatedAt    int64  `json:"created_at"`
+	DispatchedAt int64  `json:"dispatched_at,omitempty"`
+	DispatchedTo string `json:"dispatched_to,omitempty"`
+	MessageID    string `json:"message_id,omitempty"`
+}
+
+// ReadTasks reads all entries from the task queue JSONL file.
+func ReadTasks(session string) ([]TaskEntry, error) {
+	data, err := os.ReadFile(TaskPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tasks []TaskEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var t TaskEntry
+		if err := json.Unmarshal(line, &t); err != nil {
+			continue // skip malformed lines
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, scanner.Err()
+}
+
+// WriteTasks overwrites the task queue JSONL file with the given entries.
+func WriteTasks(session string, tasks []TaskEntry) error {
+	var buf bytes.Buffer
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(TaskPath(session), buf.Bytes(), 0644)
+}
+
+// DeferTask queues a background task. Returns the entry with generated ID,
+// status, and CreatedAt populated.
+func DeferTask(session string, task TaskEntry) (TaskEntry, error) {
+	if strings.TrimSpace(task.Message) == "" {
+		return TaskEntry{}, fmt.Errorf("task message is empty")
+	}
+	if task.Target != "" && !IsKnownRole(task.Target) {
+		return TaskEntry{}, fmt.Errorf("unknown target role: %s", task.Target)
+	}
+	if task.Action == "" {
+		task.Action = DefaultTaskAction
+	}
+
+	task.ID = NewMsgID("task")
+	task.Status = TaskPending
+	task.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return TaskEntry{}, err
+	}
+	if err := appendToFile(TaskPath(session), append(data, '\n')); err != nil {
+		return TaskEntry{}, err
+	}
+	return task, nil
+}
+
+// RemoveTask removes a task by ID.
+func RemoveTask(session, id string) error {
+	tasks, err := ReadTasks(session)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	var kept []TaskEntry
+	for _, t := range tasks {
+		if t.ID == id {
+			found = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+
+	if !found {
+		return fmt.Errorf("task not found: %s", id)
+	}
+	return WriteTasks(session, kept)
+}
+
+// CleanTasks removes dispatched tasks and returns how many were removed.
+func CleanTasks(session string) (int, error) {
+	tasks, err := ReadTasks(session)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []TaskEntry
+	for _, t := range tasks {
+		if t.Status != TaskDispatched {
+			kept = append(kept, t)
+		}
+	}
+	removed := len(tasks) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, WriteTasks(session, kept)
+}
+
+// HighPriorityPending returns true if any non-spawn agent has unread
+// messages. Idle tasks wait until interactive work has drained.
+func HighPriorityPending(session string) bool {
+	for _, role := range KnownRoles {
+		if HasMessages(session, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdleForTask returns true if a role can take a background task: empty
+// inbox, not busy, and not handed a task within the cooldown.
+func isIdleForTask(session, role string, lastDispatch map[string]int64, now int64) bool {
+	if HasMessages(session, role) || IsLocked(session, role) {
+		return false
+	}
+	return now-lastDispatch[role] >= taskCooldownSecs
+}
+
+// DispatchIdleTasks hands pending tasks to idle agents, oldest first, one
+// task per role per call. Untargeted tasks go only to local-LLM roles so
+// background chores consume spare local capacity rather than API quota.
+// Nothing is dispatched while any agent has unread messages. Returns the
+// tasks dispatched.
+func DispatchIdleTasks(session string) ([]TaskEntry, error) {
+	tasks, err := ReadTasks(session)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	hasPending := false
+	lastDispatch := make(map[string]int64)
+	for _, t := range tasks {
+		if t.Status == TaskPending {
+			hasPending = true
+		}
+		if t.DispatchedTo != "" && t.DispatchedAt > lastDispatch[t.DispatchedTo] {
+			lastDispatch[t.DispatchedTo] = t.DispatchedAt
+		}
+	}
+	if !hasPending || HighPriorityPending(session) {
+		return nil, nil
+	}
+
+	localRoles := LocalLLMRoles()
+	now := time.Now().Unix()
+	var dispatched []TaskEntry
+	for i, t := range tasks {
+		if t.Status != TaskPending {
+			continue
+		}
+
+		candidates := localRoles
+		if t.Target != "" {
+			candidates = []string{t.Target}
+		}
+		role := ""
+		for _, c := range candidates {
+			if isIdleForTask(session, c, lastDispatch, now) {
+				role = c
+				break
+			}
+		}
+		if role == "" {
+			continue
+		}
+
+		msg := NewMessage("task", role, "request", t.Action, t.Message, "")
+		if err := Send(session, msg); err != nil {
+			return dispatched, fmt.Errorf("sending task %s: %v", t.ID, err)
+		}
+		tasks[i].Status = TaskDispatched
+		tasks[i].DispatchedAt = now
+		tasks[i].DispatchedTo = role
+		tasks[i].MessageID = msg.ID
+		lastDispatch[role] = now
+		dispatched = append(dispatched, tasks[i])
+	}
+
+	if len(dispatched) == 0 {
+		return nil, nil
+	}
+	return dispatched, WriteTasks(session, tasks)
+}
+
+// FormatTaskList formats tasks as a human-readable table.
+// When showAll is false, only pending tasks are shown.
+func FormatTaskList(tasks []TaskEntry, showAll bool) string {
+	var b strings.Builder
+
+	var filtered []TaskEntry
+	for _, t := range tasks {
+		if showAll || t.Status == TaskPending {
+			filtered = append(filtered, t)
+		}
+	}
+
+	if len(filtered) == 0 {
+		if showAll {
+			b.WriteString("No tasks.\n")
+		} else {
+			b.WriteString("No pending tasks. Use --all to see dispatched tasks.\n")
+		}
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("%-36s %-10s %-10s %-10s %s\n",
+		"ID", "Status", "Target", "Agent", "Message"))
+	b.WriteString(strings.Repeat("-", 100) + "\n")
+
+	for _, t := range filtered {
+		target := t.Target
+		if target == "" {
+			target = "(local)"
+		}
+		agent := t.DispatchedTo
+		if agent == "" {
+			agent = "-"
+		}
+		msg := t.Message
+		if len(msg) > 40 {
+			msg = msg[:37] + "..."
+		}
+		b.WriteString(fmt.Sprintf("%-36s %-10s %-10s %-10s %s\n",
+			t.ID, t.Status, target, agent, msg))
+	}
+
+	return b.String()
+}
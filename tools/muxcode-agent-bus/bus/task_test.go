@@ -0,0 +1,167 @@
+package bus
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func taskTestSession(t *testing.T) string {
+	t.Helper()
+	session := fmt.Sprintf("test-task-%d", rand.Int())
+	t.Cleanup(func() { _ = Cleanup(session) })
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return session
+}
+
+func TestDeferTask(t *testing.T) {
+	session := taskTestSession(t)
+
+	task, err := DeferTask(session, TaskEntry{Message: "refresh dependency audit"})
+	if err != nil {
+		t.Fatalf("DeferTask: %v", err)
+	}
+	if task.ID == "" || task.Status != TaskPending || task.Action != DefaultTaskAction {
+		t.Errorf("unexpected task: %+v", task)
+	}
+
+	if _, err := DeferTask(session, TaskEntry{Message: "  "}); err == nil {
+		t.Error("expected error for empty message")
+	}
+	if _, err := DeferTask(session, TaskEntry{Message: "x", Target: "nope"}); err == nil {
+		t.Error("expected error for unknown target")
+	}
+
+	tasks, err := ReadTasks(session)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("ReadTasks: %d tasks, err %v, want 1", len(tasks), err)
+	}
+
+	if err := RemoveTask(session, task.ID); err != nil {
+		t.Fatalf("RemoveTask: %v", err)
+	}
+	if err := RemoveTask(session, task.ID); err == nil {
+		t.Error("expected error removing missing task")
+	}
+}
+
+func TestDispatchIdleTasks_Targeted(t *testing.T) {
+	session := taskTestSession(t)
+
+	task, _ := DeferTask(session, TaskEntry{Message: "refresh dependency audit", Target: "build"})
+
+	dispatched, err := DispatchIdleTasks(session)
+	if err != nil {
+		t.Fatalf("DispatchIdleTasks: %v", err)
+	}
+	if len(dispatched) != 1 || dispatched[0].DispatchedTo != "build" {
+		t.Fatalf("dispatched = %+v, want one task to build", dispatched)
+	}
+
+	msgs, _ := Peek(session, "build")
+	if len(msgs) != 1 || msgs[0].Action != DefaultTaskAction || msgs[0].Payload != task.Message {
+		t.Fatalf("build inbox = %+v", msgs)
+	}
+
+	tasks, _ := ReadTasks(session)
+	if tasks[0].Status != TaskDispatched || tasks[0].MessageID != msgs[0].ID {
+		t.Errorf("task not marked dispatched: %+v", tasks[0])
+	}
+}
+
+func TestDispatchIdleTasks_WaitsForIdle(t *testing.T) {
+	session := taskTestSession(t)
+
+	_, _ = DeferTask(session, TaskEntry{Message: "chore", Target: "build"})
+
+	// Pending interactive work anywhere holds the queue
+	_ = Send(session, NewMessage("edit", "review", "request", "review", "check diff", ""))
+	if d, _ := DispatchIdleTasks(session); len(d) != 0 {
+		t.Errorf("dispatched %d tasks while work pending", len(d))
+	}
+	_, _ = Receive(session, "review")
+
+	// Busy target holds the task
+	_ = Lock(session, "build")
+	if d, _ := DispatchIdleTasks(session); len(d) != 0 {
+		t.Errorf("dispatched %d tasks to locked role", len(d))
+	}
+	_ = Unlock(session, "build")
+
+	if d, _ := DispatchIdleTasks(session); len(d) != 1 {
+		t.Errorf("dispatched %d tasks once idle, want 1", len(d))
+	}
+}
+
+func TestDispatchIdleTasks_LocalRolesAndCooldown(t *testing.T) {
+	session := taskTestSession(t)
+	for envVar := range roleEnvMap {
+		t.Setenv(envVar, "")
+	}
+	t.Setenv("MUXCODE_BUILD_CLI", "local")
+
+	_, _ = DeferTask(session, TaskEntry{Message: "first"})
+	_, _ = DeferTask(session, TaskEntry{Message: "second"})
+
+	dispatched, err := DispatchIdleTasks(session)
+	if err != nil {
+		t.Fatalf("DispatchIdleTasks: %v", err)
+	}
+	if len(dispatched) != 1 || dispatched[0].DispatchedTo != "build" || dispatched[0].Message != "first" {
+		t.Fatalf("dispatched = %+v, want 'first' to build", dispatched)
+	}
+
+	// Build drains its inbox but is still inside the cooldown
+	_, _ = Receive(session, "build")
+	if d, _ := DispatchIdleTasks(session); len(d) != 0 {
+		t.Errorf("dispatched %d tasks within cooldown", len(d))
+	}
+
+	removed, err := CleanTasks(session)
+	if err != nil || removed != 1 {
+		t.Errorf("CleanTasks removed %d (err %v), want 1", removed, err)
+	}
+	tasks, _ := ReadTasks(session)
+	if len(tasks) != 1 || tasks[0].Message != "second" {
+		t.Errorf("remaining tasks = %+v, want only 'second'", tasks)
+	}
+}
+
+func TestDispatchIdleTasks_NoLocalRoles(t *testing.T) {
+	session := taskTestSession(t)
+	for envVar := range roleEnvMap {
+		t.Setenv(envVar, "")
+	}
+
+	_, _ = DeferTask(session, TaskEntry{Message: "chore"})
+	if d, _ := DispatchIdleTasks(session); len(d) != 0 {
+		t.Errorf("untargeted task dispatched with no local-LLM roles: %+v", d)
+	}
+}
+
+func TestFormatTaskList(t *testing.T) {
+	tasks := []TaskEntry{
+		{ID: "t1", Status: TaskPending, Message: "refresh dependency audit"},
+		{ID: "t2", Status: TaskDispatched, Target: "build", DispatchedTo: "build", Message: "lint"},
+	}
+
+	out := FormatTaskList(tasks, false)
+	if !strings.Contains(out, "t1") || strings.Contains(out, "t2") {
+		t.Errorf("pending list wrong:\n%s", out)
+	}
+	if !strings.Contains(out, "(local)") {
+		t.Errorf("untargeted task should show (local):\n%s", out)
+	}
+
+	out = FormatTaskList(tasks, true)
+	if !strings.Contains(out, "t2") {
+		t.Errorf("--all list missing dispatched task:\n%s", out)
+	}
+
+	if out := FormatTaskList(nil, false); !strings.Contains(out, "No pending tasks") {
+		t.Errorf("empty list = %q", out)
+	}
+}
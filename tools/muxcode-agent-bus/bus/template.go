@@ -0,0 +1,217 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SessionTemplate is a declarative session layout applied by
+// "init --template". Templates live in ~/.config/muxcode/templates/{name}.json.
+type SessionTemplate struct {
+	Description   string                 `json:"description,omitempty"`
+	Windows       []string               `json:"windows,omitempty"`       // windows/roles muxcode.sh launches
+	ToolProfiles  map[string]ToolProfile `json:"tool_profiles,omitempty"` // session-scoped profile overrides
+	Cron          []CronEntry            `json:"cron,omitempty"`
+	Subscriptions []Subscription         `json:"subscriptions,omitempty"`
+	Context       map[string]string      `json:"context,omitempty"` // file name → content for .muxcode/context.d/
+}
+
+// TemplateResult reports what ApplySessionTemplate provisioned.
+type TemplateResult struct {
+	ToolProfiles  int
+	Cron          []string // created cron entry IDs
+	Subscriptions []string // created subscription IDs
+	Context       []string // context files written
+	Skipped       []string // context files left alone because they already exist
+}
+
+// TemplatesDir returns the session templates directory.
+func TemplatesDir() string {
+	return filepath.Join(configDir(), "templates")
+}
+
+// SessionTemplatePath returns the file path for a template name.
+func SessionTemplatePath(name string) string {
+	return filepath.Join(TemplatesDir(), name+".json")
+}
+
+// validTemplateName rejects names that would escape the templates directory.
+func validTemplateName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".")
+}
+
+// LoadSessionTemplate reads and validates a template by name.
+func LoadSessionTemplate(name string) (SessionTemplate, error) {
+	if !validTemplateName(name) {
+		return SessionTemplate{}, fmt.Errorf("invalid template name: %q", name)
+	}
+	path := SessionTemplatePath(name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SessionTemplate{}, fmt.Errorf("template not found: %s (looked in %s)", name, TemplatesDir())
+		}
+		return SessionTemplate{}, err
+	}
+	var t SessionTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return SessionTemplate{}, fmt.Errorf("invalid template %s: %v", path, err)
+	}
+	if err := t.Validate(); err != nil {
+		return SessionTemplate{}, fmt.Errorf("template %s: %v", name, err)
+	}
+	return t, nil
+}
+
+// Validate checks the parts of a template that can be checked before any
+// of it is applied, so a bad template provisions nothing.
+func (t SessionTemplate) Validate() error {
+	for _, w := range t.Windows {
+		if w == "" || strings.ContainsAny(w, " \t:.") {
+			return fmt.Errorf("invalid window name: %q", w)
+		}
+	}
+	for i, e := range t.Cron {
+		if _, err := ParseSchedule(e.Schedule); err != nil {
+			return fmt.Errorf("cron[%d]: invalid schedule: %v", i, err)
+		}
+		if e.Target == "" || e.Message == "" {
+			return fmt.Errorf("cron[%d]: target and message are required", i)
+		}
+	}
+	for i, s := range t.Subscriptions {
+		if s.Event == "" || s.Outcome == "" {
+			return fmt.Errorf("subscriptions[%d]: event and outcome are required", i)
+		}
+	}
+	for name := range t.Context {
+		if !validTemplateName(name) {
+			return fmt.Errorf("invalid context file name: %q", name)
+		}
+	}
+	return nil
+}
+
+// ListSessionTemplates returns the names of available templates, sorted.
+func ListSessionTemplates() ([]string, error) {
+	entries, err := os.ReadDir(TemplatesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ApplySessionTemplate provisions an initialized session from a template:
+// tool profiles become a session config overlay, cron entries and
+// subscriptions are added to the session, and context files are written
+// to the project context directory unless they already exist.
+func ApplySessionTemplate(session string, t SessionTemplate) (TemplateResult, error) {
+	var res TemplateResult
+
+	if len(t.ToolProfiles) > 0 {
+		data, err := json.MarshalIndent(MuxcodeConfig{ToolProfiles: t.ToolProfiles}, "", "  ")
+		if err != nil {
+			return res, err
+		}
+		if err := os.WriteFile(SessionConfigPath(session), data, 0644); err != nil {
+			return res, err
+		}
+		res.ToolProfiles = len(t.ToolProfiles)
+		SetConfig(nil) // reload with the overlay
+	}
+
+	for i, e := range t.Cron {
+		entry, err := AddCronEntry(session, e)
+		if err != nil {
+			return res, fmt.Errorf("cron[%d]: %v", i, err)
+		}
+		res.Cron = append(res.Cron, entry.ID)
+	}
+
+	for i, s := range t.Subscriptions {
+		sub, err := AddSubscription(session, s)
+		if err != nil {
+			return res, fmt.Errorf("subscriptions[%d]: %v", i, err)
+		}
+		res.Subscriptions = append(res.Subscriptions, sub.ID)
+	}
+
+	if len(t.Context) > 0 {
+		dir := ContextDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return res, err
+		}
+		names := make([]string, 0, len(t.Context))
+		for name := range t.Context {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				res.Skipped = append(res.Skipped, name)
+				continue
+			}
+			if err := os.WriteFile(path, []byte(t.Context[name]), 0644); err != nil {
+				return res, err
+			}
+			res.Context = append(res.Context, name)
+		}
+	}
+
+	return res, nil
+}
+
+// loadSessionConfig reads the session config overlay written by a template,
+// or returns nil when the session has none.
+func loadSessionConfig(session string) *MuxcodeConfig {
+	data, err := os.ReadFile(SessionConfigPath(session))
+	if err != nil {
+		return nil
+	}
+	var cfg MuxcodeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to parse %s: %v\n", SessionConfigPath(session), err)
+		return nil
+	}
+	return &cfg
+}
+
+// FormatTemplateResult summarizes an applied template.
+func FormatTemplateResult(name string, t SessionTemplate, res TemplateResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Applied template: %s\n", name)
+	if len(t.Windows) > 0 {
+		fmt.Fprintf(&b, "  Windows:       %s\n", strings.Join(t.Windows, " "))
+	}
+	if res.ToolProfiles > 0 {
+		fmt.Fprintf(&b, "  Tool profiles: %d role(s)\n", res.ToolProfiles)
+	}
+	if len(res.Cron) > 0 {
+		fmt.Fprintf(&b, "  Cron entries:  %d\n", len(res.Cron))
+	}
+	if len(res.Subscriptions) > 0 {
+		fmt.Fprintf(&b, "  Subscriptions: %d\n", len(res.Subscriptions))
+	}
+	if len(res.Context) > 0 {
+		fmt.Fprintf(&b, "  Context:       %s\n", strings.Join(res.Context, ", "))
+	}
+	if len(res.Skipped) > 0 {
+		fmt.Fprintf(&b, "  Kept existing: %s\n", strings.Join(res.Skipped, ", "))
+	}
+	return b.String()
+}
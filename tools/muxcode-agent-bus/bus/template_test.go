@@ -0,0 +1,121 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSessionTemplate = `{
+  "description": "CDK service",
+  "windows": ["edit", "build", "deploy"],
+  "tool_profiles": {"deploy": {"tools": ["Bash(cdk diff*)"]}},
+  "cron": [{"schedule": "@every 1h", "target": "deploy", "action": "drift", "message": "Check for stack drift"}],
+  "subscriptions": [{"event": "deploy", "outcome": "failure", "notify": "edit", "match": "Prod"}],
+  "context": {"stack.md": "Stacks live in infra/", "existing.md": "from template"}
+}`
+
+func setupTemplateTest(t *testing.T) (session, contextDir string) {
+	t.Helper()
+	session = testSession(t)
+	configDir := t.TempDir()
+	contextDir = t.TempDir()
+	t.Setenv("MUXCODE_CONFIG_DIR", configDir)
+	t.Setenv("BUS_CONTEXT_DIR", contextDir)
+	t.Setenv("BUS_SESSION", session)
+	if err := os.MkdirAll(TemplatesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetConfig(nil) })
+	return session, contextDir
+}
+
+func TestApplySessionTemplate(t *testing.T) {
+	session, contextDir := setupTemplateTest(t)
+	if err := os.WriteFile(SessionTemplatePath("cdk"), []byte(testSessionTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(contextDir, "existing.md"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := LoadSessionTemplate("cdk")
+	if err != nil {
+		t.Fatalf("LoadSessionTemplate: %v", err)
+	}
+	res, err := ApplySessionTemplate(session, tmpl)
+	if err != nil {
+		t.Fatalf("ApplySessionTemplate: %v", err)
+	}
+
+	if got := Config().ToolProfiles["deploy"].Tools; len(got) != 1 || got[0] != "Bash(cdk diff*)" {
+		t.Errorf("deploy profile = %v, want session overlay", got)
+	}
+	if _, ok := Config().ToolProfiles["build"]; !ok {
+		t.Error("overlay should keep default profiles for other roles")
+	}
+
+	cron, _ := ReadCronEntries(session)
+	if len(cron) != 1 || cron[0].Target != "deploy" || !cron[0].Enabled || res.Cron[0] != cron[0].ID {
+		t.Errorf("cron = %+v", cron)
+	}
+	subs, _ := ReadSubscriptions(session)
+	if len(subs) != 1 || subs[0].Match != "Prod" || !subs[0].Enabled {
+		t.Errorf("subscriptions = %+v", subs)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(contextDir, "stack.md"))
+	if string(data) != "Stacks live in infra/" {
+		t.Errorf("stack.md = %q", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(contextDir, "existing.md"))
+	if string(data) != "mine" {
+		t.Errorf("existing context file overwritten: %q", data)
+	}
+
+	out := FormatTemplateResult("cdk", tmpl, res)
+	for _, want := range []string{"Windows:       edit build deploy", "Cron entries:  1", "Context:       stack.md", "Kept existing: existing.md"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("result missing %q:\n%s", want, out)
+		}
+	}
+
+	// Re-init drops the overlay
+	if err := Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(SessionConfigPath(session)); !os.IsNotExist(err) {
+		t.Error("re-init should remove the session config overlay")
+	}
+}
+
+func TestLoadSessionTemplate_Invalid(t *testing.T) {
+	setupTemplateTest(t)
+
+	bad := map[string]string{
+		"window":   `{"windows": ["edit", "bad name"]}`,
+		"schedule": `{"cron": [{"schedule": "whenever", "target": "build", "message": "x"}]}`,
+		"context":  `{"context": {"../escape.md": "x"}}`,
+		"json":     `{`,
+	}
+	for name, body := range bad {
+		if err := os.WriteFile(SessionTemplatePath(name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSessionTemplate(name); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := LoadSessionTemplate("../etc"); err == nil {
+		t.Error("expected error for path in template name")
+	}
+	if _, err := LoadSessionTemplate("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing template: %v", err)
+	}
+
+	names, err := ListSessionTemplates()
+	if err != nil || len(names) != len(bad) || names[0] != "context" {
+		t.Errorf("ListSessionTemplates = %v, %v", names, err)
+	}
+}
@@ -0,0 +1,366 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TestParsers lists the formats accepted by log --parse.
+var TestParsers = []string{"go-test", "jest", "junit", "cargo"}
+
+// maxFailedTestsShown caps the failed tests listed in summaries and
+// ${failed_tests}.
+const maxFailedTestsShown = 10
+
+// TestFailure identifies a failed test. Name is empty when a whole
+// package failed without a failing test, e.g. a build error.
+type TestFailure struct {
+	Package string `json:"package,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// String renders the failure as "package: name".
+func (f TestFailure) String() string {
+	switch {
+	case f.Name == "":
+		return f.Package + " (package failed)"
+	case f.Package == "":
+		return f.Name
+	}
+	return f.Package + ": " + f.Name
+}
+
+// TestResults is the structured outcome of a test run, parsed from the
+// runner's output and stored with the history entry.
+type TestResults struct {
+	Format   string        `json:"format"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Skipped  int           `json:"skipped,omitempty"`
+	Failures []TestFailure `json:"failures,omitempty"`
+}
+
+// ParseTestOutput parses test runner output in one of TestParsers.
+func ParseTestOutput(format string, data []byte) (*TestResults, error) {
+	var r *TestResults
+	var err error
+	switch format {
+	case "go-test":
+		r, err = parseGoTest(data)
+	case "jest":
+		r, err = parseJest(data)
+	case "junit":
+		r, err = parseJUnit(data)
+	case "cargo":
+		r, err = parseCargo(data)
+	default:
+		return nil, fmt.Errorf("unknown parser %q (want one of %s)", format, strings.Join(TestParsers, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Format = format
+	return r, nil
+}
+
+// parseGoTest reads `go test -json` events. A package that failed with no
+// failing test (a build error) is reported by package alone, and parent
+// tests are dropped when one of their subtests failed.
+func parseGoTest(data []byte) (*TestResults, error) {
+	r := &TestResults{}
+	var failed []TestFailure
+	pkgFailed := make(map[string]bool)
+	testFailed := make(map[string]bool)
+	events := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var ev struct {
+			Action  string
+			Package string
+			Test    string
+		}
+		if json.Unmarshal(line, &ev) != nil || ev.Action == "" {
+			continue
+		}
+		events++
+		if ev.Test == "" {
+			if ev.Action == "fail" {
+				pkgFailed[ev.Package] = true
+			}
+			continue
+		}
+		switch ev.Action {
+		case "pass":
+			r.Passed++
+		case "skip":
+			r.Skipped++
+		case "fail":
+			r.Failed++
+			testFailed[ev.Package] = true
+			failed = append(failed, TestFailure{Package: ev.Package, Name: ev.Test})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("no go test -json events found")
+	}
+
+	for _, f := range failed {
+		if !hasFailedSubtest(failed, f) {
+			r.Failures = append(r.Failures, f)
+		}
+	}
+	var pkgs []string
+	for pkg := range pkgFailed {
+		if !testFailed[pkg] {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		r.Failures = append(r.Failures, TestFailure{Package: pkg})
+	}
+	return r, nil
+}
+
+// hasFailedSubtest reports whether a subtest of f is among failed.
+func hasFailedSubtest(failed []TestFailure, f TestFailure) bool {
+	for _, o := range failed {
+		if o.Package == f.Package && strings.HasPrefix(o.Name, f.Name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJest reads `jest --json` output. The package is the test file,
+// relative to the report's root directory when it has one.
+func parseJest(data []byte) (*TestResults, error) {
+	var rep struct {
+		NumPassedTests  *int `json:"numPassedTests"`
+		NumFailedTests  int  `json:"numFailedTests"`
+		NumPendingTests int  `json:"numPendingTests"`
+		TestResults     []struct {
+			Name             string `json:"name"`
+			Status           string `json:"status"`
+			Message          string `json:"message"`
+			AssertionResults []struct {
+				FullName string `json:"fullName"`
+				Status   string `json:"status"`
+			} `json:"assertionResults"`
+		} `json:"testResults"`
+	}
+	// Jest may print to the same stream before the report
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("no jest JSON report found")
+	}
+	if err := json.Unmarshal(data[start:], &rep); err != nil {
+		return nil, fmt.Errorf("parsing jest JSON: %v", err)
+	}
+	if rep.NumPassedTests == nil {
+		return nil, fmt.Errorf("no jest JSON report found")
+	}
+	r := &TestResults{Passed: *rep.NumPassedTests, Failed: rep.NumFailedTests, Skipped: rep.NumPendingTests}
+	for _, file := range rep.TestResults {
+		failed := false
+		for _, a := range file.AssertionResults {
+			if a.Status == "failed" {
+				failed = true
+				r.Failures = append(r.Failures, TestFailure{Package: file.Name, Name: a.FullName})
+			}
+		}
+		// A suite that failed to run has no failing assertions
+		if file.Status == "failed" && !failed {
+			r.Failures = append(r.Failures, TestFailure{Package: file.Name})
+		}
+	}
+	return r, nil
+}
+
+// junitCase is a <testcase> in a JUnit XML report.
+type junitCase struct {
+	Classname string    `xml:"classname,attr"`
+	Name      string    `xml:"name,attr"`
+	File      string    `xml:"file,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+// parseJUnit reads a JUnit XML report, as written by pytest --junitxml and
+// most other runners. The package is the test case's classname (the
+// module path for pytest), else its file.
+func parseJUnit(data []byte) (*TestResults, error) {
+	r := &TestResults{}
+	cases := 0
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing JUnit XML: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+		var tc junitCase
+		if err := dec.DecodeElement(&tc, &start); err != nil {
+			return nil, fmt.Errorf("parsing JUnit XML: %v", err)
+		}
+		cases++
+		pkg := tc.Classname
+		if pkg == "" {
+			pkg = tc.File
+		}
+		switch {
+		case tc.Failure != nil || tc.Error != nil:
+			r.Failed++
+			r.Failures = append(r.Failures, TestFailure{Package: pkg, Name: tc.Name})
+		case tc.Skipped != nil:
+			r.Skipped++
+		default:
+			r.Passed++
+		}
+	}
+	if cases == 0 {
+		return nil, fmt.Errorf("no JUnit test cases found")
+	}
+	return r, nil
+}
+
+var (
+	cargoRunningRe = regexp.MustCompile(`^\s*Running (?:unittests |tests/)?(\S+)(?: \((\S+)\))?`)
+	cargoTestRe    = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	cargoResultRe  = regexp.MustCompile(`^test result: `)
+)
+
+// parseCargo reads `cargo test` output. The package is the test target
+// being run, e.g. "mycrate" for "Running unittests src/lib.rs
+// (target/debug/deps/mycrate-1a2b3c)".
+func parseCargo(data []byte) (*TestResults, error) {
+	r := &TestResults{}
+	pkg := ""
+	results := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := cargoRunningRe.FindStringSubmatch(line); m != nil {
+			pkg = cargoTarget(m[1], m[2])
+			continue
+		}
+		if cargoResultRe.MatchString(line) {
+			results++
+			continue
+		}
+		m := cargoTestRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[2] {
+		case "ok":
+			r.Passed++
+		case "ignored":
+			r.Skipped++
+		case "FAILED":
+			r.Failed++
+			r.Failures = append(r.Failures, TestFailure{Package: pkg, Name: m[1]})
+		}
+	}
+	if results == 0 && r.Passed+r.Failed+r.Skipped == 0 {
+		return nil, fmt.Errorf("no cargo test results found")
+	}
+	return r, nil
+}
+
+// cargoTarget names a cargo test target from its "Running" line: the
+// binary without its hash suffix, else the source file's name.
+func cargoTarget(src, bin string) string {
+	if bin != "" {
+		name := filepath.Base(bin)
+		if i := strings.LastIndexByte(name, '-'); i > 0 {
+			name = name[:i]
+		}
+		return name
+	}
+	return strings.TrimSuffix(filepath.Base(src), ".rs")
+}
+
+// FailedTestList renders up to maxFailedTestsShown failures, comma
+// separated, noting how many more there are.
+func FailedTestList(failures []TestFailure) string {
+	parts := make([]string, 0, min(len(failures), maxFailedTestsShown))
+	for i, f := range failures {
+		if i == maxFailedTestsShown {
+			parts = append(parts, fmt.Sprintf("(+%d more)", len(failures)-i))
+			break
+		}
+		parts = append(parts, f.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatTestResults summarizes parsed results for the history entry's
+// output, listing the failed tests one per line.
+func FormatTestResults(r *TestResults) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d passed, %d failed", r.Passed, r.Failed)
+	if r.Skipped > 0 {
+		fmt.Fprintf(&b, ", %d skipped", r.Skipped)
+	}
+	fmt.Fprintf(&b, " (%s)", r.Format)
+	for i, f := range r.Failures {
+		if i == maxFailedTestsShown {
+			fmt.Fprintf(&b, "\n... and %d more", len(r.Failures)-i)
+			break
+		}
+		b.WriteString("\nFAIL " + f.String())
+	}
+	return b.String()
+}
+
+// LatestTestResults returns the parsed results of the role's most recent
+// history entry, if that entry ran command (compared normalized; any
+// command when empty) and was logged with --parse.
+func LatestTestResults(session, role, command string) *TestResults {
+	entries := ReadHistory(session, role, 1)
+	if len(entries) == 0 {
+		return nil
+	}
+	e := entries[0]
+	if e.Tests == nil || (command != "" && normalizeCommand(e.Command) != normalizeCommand(command)) {
+		return nil
+	}
+	return e.Tests
+}
+
+// ExpandFailedTests replaces ${failed_tests} in a chain message with the
+// failed tests from the role's latest parsed run of command, or "" when
+// there is none.
+func ExpandFailedTests(template, session, role, command string) string {
+	if !strings.Contains(template, "${failed_tests}") {
+		return template
+	}
+	list := ""
+	if r := LatestTestResults(session, role, command); r != nil {
+		list = FailedTestList(r.Failures)
+	}
+	return strings.ReplaceAll(template, "${failed_tests}", list)
+}
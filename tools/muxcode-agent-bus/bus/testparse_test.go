@@ -0,0 +1,136 @@
+package bus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseTestOutput_GoTest(t *testing.T) {
+	out := `{"Action":"run","Package":"example.com/app/bus","Test":"TestSend"}
+{"Action":"pass","Package":"example.com/app/bus","Test":"TestSend","Elapsed":0.01}
+{"Action":"run","Package":"example.com/app/bus","Test":"TestInbox"}
+{"Action":"output","Package":"example.com/app/bus","Test":"TestInbox/empty","Output":"    inbox_test.go:12: got 1\n"}
+{"Action":"fail","Package":"example.com/app/bus","Test":"TestInbox/empty","Elapsed":0}
+{"Action":"fail","Package":"example.com/app/bus","Test":"TestInbox","Elapsed":0}
+{"Action":"skip","Package":"example.com/app/bus","Test":"TestSlow","Elapsed":0}
+{"Action":"fail","Package":"example.com/app/bus","Elapsed":0.2}
+{"Action":"output","Package":"example.com/app/cmd","Output":"cmd/log.go:10:2: undefined: x\n"}
+{"Action":"fail","Package":"example.com/app/cmd","Elapsed":0}
+`
+	r, err := ParseTestOutput("go-test", []byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format != "go-test" || r.Passed != 1 || r.Failed != 2 || r.Skipped != 1 {
+		t.Errorf("counts = %+v", r)
+	}
+	want := []TestFailure{{Package: "example.com/app/bus", Name: "TestInbox/empty"}, {Package: "example.com/app/cmd"}}
+	if len(r.Failures) != 2 || r.Failures[0] != want[0] || r.Failures[1] != want[1] {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+	if _, err := ParseTestOutput("go-test", []byte("ok  \texample.com/app\t0.1s\n")); err == nil {
+		t.Error("plain go test output: want error")
+	}
+}
+
+func TestParseTestOutput_Jest(t *testing.T) {
+	out := `Running tests...
+{"numPassedTests":3,"numFailedTests":1,"numPendingTests":1,"testResults":[
+  {"name":"/src/app.test.js","status":"failed","assertionResults":[
+    {"fullName":"app renders","status":"passed"},
+    {"fullName":"app handles errors","status":"failed"}]},
+  {"name":"/src/broken.test.js","status":"failed","message":"SyntaxError","assertionResults":[]}
+]}`
+	r, err := ParseTestOutput("jest", []byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed != 3 || r.Failed != 1 || r.Skipped != 1 || len(r.Failures) != 2 {
+		t.Fatalf("results = %+v", r)
+	}
+	if r.Failures[0] != (TestFailure{Package: "/src/app.test.js", Name: "app handles errors"}) || r.Failures[1].Name != "" {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+}
+
+func TestParseTestOutput_JUnit(t *testing.T) {
+	out := `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest" tests="4" failures="1" errors="1" skipped="1">
+  <testcase classname="tests.test_api" name="test_get" time="0.01"/>
+  <testcase classname="tests.test_api" name="test_post" time="0.02"><failure message="assert 500 == 200">...</failure></testcase>
+  <testcase classname="tests.test_db" name="test_connect" time="0.00"><error message="fixture failed"/></testcase>
+  <testcase classname="tests.test_db" name="test_slow" time="0.00"><skipped message="slow"/></testcase>
+</testsuite></testsuites>`
+	r, err := ParseTestOutput("junit", []byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed != 1 || r.Failed != 2 || r.Skipped != 1 {
+		t.Errorf("counts = %+v", r)
+	}
+	if got := FailedTestList(r.Failures); got != "tests.test_api: test_post, tests.test_db: test_connect" {
+		t.Errorf("FailedTestList = %q", got)
+	}
+}
+
+func TestParseTestOutput_Cargo(t *testing.T) {
+	out := `   Compiling mycrate v0.1.0
+     Running unittests src/lib.rs (target/debug/deps/mycrate-1a2b3c4d)
+
+running 3 tests
+test tests::adds ... ok
+test tests::divides ... FAILED
+test tests::slow ... ignored
+
+failures:
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out
+`
+	r, err := ParseTestOutput("cargo", []byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed != 1 || r.Failed != 1 || r.Skipped != 1 {
+		t.Errorf("counts = %+v", r)
+	}
+	if len(r.Failures) != 1 || r.Failures[0] != (TestFailure{Package: "mycrate", Name: "tests::divides"}) {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+	if _, err := ParseTestOutput("mocha", nil); err == nil || !strings.Contains(err.Error(), "unknown parser") {
+		t.Errorf("unknown parser error = %v", err)
+	}
+}
+
+func TestFormatTestResults(t *testing.T) {
+	r := &TestResults{Format: "go-test", Passed: 5, Failed: 12}
+	for i := 0; i < 12; i++ {
+		r.Failures = append(r.Failures, TestFailure{Package: "bus", Name: "TestX"})
+	}
+	out := FormatTestResults(r)
+	if !strings.HasPrefix(out, "5 passed, 12 failed (go-test)\nFAIL bus: TestX") || !strings.HasSuffix(out, "... and 2 more") {
+		t.Errorf("FormatTestResults:\n%s", out)
+	}
+	if got := FailedTestList(r.Failures); !strings.HasSuffix(got, "(+2 more)") {
+		t.Errorf("FailedTestList = %q", got)
+	}
+}
+
+func TestExpandFailedTests(t *testing.T) {
+	session := testSession(t)
+	tests := &TestResults{Format: "go-test", Failed: 1, Failures: []TestFailure{{Package: "bus", Name: "TestSend"}}}
+	data, _ := json.Marshal(HistoryEntry{TS: 1, Command: "go test ./...", Outcome: "failure", Tests: tests})
+	if err := AppendHistory(session, "test", data, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ExpandFailedTests("Fix ${failed_tests}", session, "test", "cd /src && go test ./..."); got != "Fix bus: TestSend" {
+		t.Errorf("same command = %q", got)
+	}
+	if got := ExpandFailedTests("Fix ${failed_tests}", session, "test", "npm test"); got != "Fix " {
+		t.Errorf("other command = %q", got)
+	}
+	if got := ExpandFailedTests("Fix ${failed_tests}", session, "build", ""); got != "Fix " {
+		t.Errorf("no history = %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package bus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ToolCheck is tools check's verdict on a command for a role: whether the
+// resolved tool profile allows it, and the pattern that does.
+type ToolCheck struct {
+	Role     string        `json:"role"`
+	Profile  string        `json:"profile"`
+	Command  string        `json:"command"`
+	Allowed  bool          `json:"allowed"`
+	Match    *ToolEntry    `json:"match,omitempty"`
+	Variants []ToolVariant `json:"variants,omitempty"` // prefix-stripped forms of a denied command
+	Hints    []string      `json:"hints,omitempty"`
+}
+
+// ToolVariant is a denied command with its cd or env prefix removed, and
+// the pattern that would allow that form.
+type ToolVariant struct {
+	Form    string     `json:"form"` // "without cd prefix", "without env prefix", "without cd and env prefixes"
+	Command string     `json:"command"`
+	Match   *ToolEntry `json:"match,omitempty"`
+}
+
+var (
+	// cdPrefixRe matches a leading "cd <dir> && ".
+	cdPrefixRe = regexp.MustCompile(`^cd\s+[^&;|]+?\s*&&\s*`)
+	// envPrefixRe matches leading NAME=value assignments.
+	envPrefixRe = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]*=(?:"[^"]*"|'[^']*'|\S*)\s+)+`)
+	// toolNameRe matches a bare tool name such as Read or WebFetch.
+	toolNameRe = regexp.MustCompile(`^[A-Z][A-Za-z]*$`)
+)
+
+// CheckToolCommand reports whether role's resolved tool profile allows
+// command, the way the harness and --allowedTools match it. A bare tool
+// name (Read, Write, WebFetch, ...) is checked as that tool; anything else
+// as a Bash command. For a denied command, the forms without a leading
+// "cd <dir> &&" or NAME=value prefix are checked too, with hints for the
+// profile change that would allow the command.
+func CheckToolCommand(role, command string) (ToolCheck, error) {
+	cfg := Config()
+	profileName := resolveRoleAlias(role)
+	profile, ok := cfg.ToolProfiles[profileName]
+	if !ok {
+		return ToolCheck{}, fmt.Errorf("no tool profile for role %q", role)
+	}
+	command = strings.TrimSpace(command)
+	entries := resolveProfileEntries(cfg, profile)
+	check := ToolCheck{Role: role, Profile: profileName, Command: command}
+
+	if toolNameRe.MatchString(command) {
+		for i, e := range entries {
+			if e.Tool == command || strings.HasPrefix(e.Tool, command+"(") {
+				check.Allowed, check.Match = true, &entries[i]
+				return check, nil
+			}
+		}
+		check.Hints = append(check.Hints, fmt.Sprintf("add %q to tool_profiles.%s.tools", command, profileName))
+		return check, nil
+	}
+
+	if m := matchBashEntry(entries, command); m != nil {
+		check.Allowed, check.Match = true, m
+		return check, nil
+	}
+
+	noCd := cdPrefixRe.ReplaceAllString(command, "")
+	noEnv := envPrefixRe.ReplaceAllString(command, "")
+	bare := envPrefixRe.ReplaceAllString(noCd, "")
+	seen := map[string]bool{command: true}
+	for _, v := range []ToolVariant{
+		{Form: "without cd prefix", Command: noCd},
+		{Form: "without env prefix", Command: noEnv},
+		{Form: "without cd and env prefixes", Command: bare},
+	} {
+		if seen[v.Command] || v.Command == "" {
+			continue
+		}
+		seen[v.Command] = true
+		v.Match = matchBashEntry(entries, v.Command)
+		check.Variants = append(check.Variants, v)
+	}
+	check.Hints = toolCheckHints(check, profile, command, noCd, bare)
+	return check, nil
+}
+
+// matchBashEntry returns the first Bash pattern allowing command.
+func matchBashEntry(entries []ToolEntry, command string) *ToolEntry {
+	for i, e := range entries {
+		if isBashAllowed(command, []string{e.Tool}) {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// toolCheckHints suggests the profile change that would allow a denied
+// command, based on which prefix-stripped forms are allowed.
+func toolCheckHints(check ToolCheck, profile ToolProfile, command, noCd, bare string) []string {
+	var hints []string
+	where := "tool_profiles." + check.Profile
+	for _, v := range check.Variants {
+		if v.Match == nil {
+			continue
+		}
+		inner := strings.TrimSuffix(strings.TrimPrefix(v.Match.Tool, "Bash("), ")")
+		switch {
+		case noCd != command && v.Command == noCd && v.Match.Source != "tools":
+			hints = append(hints, fmt.Sprintf("%s comes from %s, and cd_prefix only expands the profile's own tools: add \"Bash(cd * && %s)\" to %s.tools",
+				v.Match.Tool, v.Match.Source, inner, where))
+		case noCd != command && v.Command == noCd && !profile.CdPrefix:
+			hints = append(hints, fmt.Sprintf("set \"cd_prefix\": true in %s to allow \"cd <dir> && \" before its tools", where))
+		case v.Command != noCd:
+			name := strings.SplitN(envPrefixRe.FindString(noCd), "=", 2)[0]
+			cd := ""
+			if noCd != command {
+				cd = "cd * && "
+			}
+			hints = append(hints, fmt.Sprintf("env assignments are matched literally: add \"Bash(%s%s=* %s)\" to %s.tools, or run the command without %s=",
+				cd, name, inner, where, name))
+		}
+		return hints
+	}
+	if fields := strings.Fields(bare); len(fields) > 0 {
+		hints = append(hints, fmt.Sprintf("no pattern matches: add \"Bash(%s *)\" to %s.tools", fields[0], where))
+	}
+	return hints
+}
+
+// FormatToolCheck renders a tools check verdict.
+func FormatToolCheck(c ToolCheck) string {
+	var b strings.Builder
+	if c.Allowed {
+		fmt.Fprintf(&b, "ALLOWED for %s (profile %s): %s\n", c.Role, c.Profile, c.Command)
+		fmt.Fprintf(&b, "  matched %s (from %s)\n", c.Match.Tool, c.Match.Source)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "DENIED for %s (profile %s): %s\n", c.Role, c.Profile, c.Command)
+	for _, v := range c.Variants {
+		if v.Match != nil {
+			fmt.Fprintf(&b, "  %s: %s -> allowed by %s (from %s)\n", v.Form, v.Command, v.Match.Tool, v.Match.Source)
+		} else {
+			fmt.Fprintf(&b, "  %s: %s -> denied\n", v.Form, v.Command)
+		}
+	}
+	for _, h := range c.Hints {
+		fmt.Fprintf(&b, "Hint: %s\n", h)
+	}
+	return b.String()
+}
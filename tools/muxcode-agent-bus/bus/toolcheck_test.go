@@ -0,0 +1,63 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckToolCommand(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SharedTools["kube"] = []string{"Bash(kubectl *)"}
+	cfg.ToolProfiles["runner"] = ToolProfile{Include: []string{"readonly", "kube"}, Tools: []string{"Bash(aws *)"}}
+	cfg.ToolProfiles["build"] = ToolProfile{Include: []string{"readonly"}, Tools: []string{"Bash(go build*)"}, CdPrefix: true}
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	for _, tt := range []struct {
+		role, command string
+		allowed       bool
+		match, source string
+		hint          string
+	}{
+		{"build", "go build ./...", true, "Bash(go build*)", "tools", ""},
+		{"build", "cd src && go build ./...", true, "Bash(cd * && go build*)", "cd_prefix", ""},
+		{"build", "Read", true, "Read", "shared_tools.readonly", ""},
+		{"build", "WebFetch", false, "", "", `add "WebFetch" to tool_profiles.build.tools`},
+		{"run", "aws s3 ls", true, "Bash(aws *)", "tools", ""},
+		{"run", "cd infra && aws s3 ls", false, "", "", `set "cd_prefix": true in tool_profiles.runner`},
+		{"run", "cd k8s && kubectl get pods", false, "", "", `Bash(kubectl *) comes from shared_tools.kube, and cd_prefix only expands the profile's own tools: add "Bash(cd * && kubectl *)"`},
+		{"run", "AWS_PROFILE=prod aws s3 ls", false, "", "", `add "Bash(AWS_PROFILE=* aws *)" to tool_profiles.runner.tools`},
+		{"run", "cd x && AWS_PROFILE='a b' aws s3 ls", false, "", "", `add "Bash(cd * && AWS_PROFILE=* aws *)"`},
+		{"run", "terraform plan", false, "", "", `no pattern matches: add "Bash(terraform *)" to tool_profiles.runner.tools`},
+	} {
+		c, err := CheckToolCommand(tt.role, tt.command)
+		if err != nil {
+			t.Fatalf("%s %q: %v", tt.role, tt.command, err)
+		}
+		if c.Allowed != tt.allowed {
+			t.Errorf("%s %q: allowed = %v, want %v", tt.role, tt.command, c.Allowed, tt.allowed)
+			continue
+		}
+		if tt.allowed && (c.Match.Tool != tt.match || c.Match.Source != tt.source) {
+			t.Errorf("%s %q: match = %+v, want %s from %s", tt.role, tt.command, c.Match, tt.match, tt.source)
+		}
+		if tt.hint != "" && (len(c.Hints) != 1 || !strings.Contains(c.Hints[0], tt.hint)) {
+			t.Errorf("%s %q: hints = %q, want %q", tt.role, tt.command, c.Hints, tt.hint)
+		}
+	}
+
+	c, _ := CheckToolCommand("run", "cd infra && AWS_PROFILE=prod aws s3 ls")
+	out := FormatToolCheck(c)
+	for _, want := range []string{
+		"DENIED for run (profile runner): cd infra && AWS_PROFILE=prod aws s3 ls",
+		"without cd prefix: AWS_PROFILE=prod aws s3 ls -> denied",
+		"without cd and env prefixes: aws s3 ls -> allowed by Bash(aws *) (from tools)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatToolCheck missing %q:\n%s", want, out)
+		}
+	}
+	if _, err := CheckToolCommand("nope", "ls"); err == nil {
+		t.Error("unknown role: want error")
+	}
+}
@@ -0,0 +1,227 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config layer names, lowest priority first: the built-in defaults, the
+// user's muxcode.json, the project's, and a session template's overlay.
+const (
+	LayerDefault = "default"
+	LayerUser    = "user"
+	LayerProject = "project"
+	LayerSession = "session"
+)
+
+// ConfigLayer is one source LoadConfig merges.
+type ConfigLayer struct {
+	Name   string
+	Path   string // "" for the built-in defaults
+	Config *MuxcodeConfig
+}
+
+// ConfigLayers reads the layers LoadConfig merges, lowest priority first.
+// Missing and unparsable files are skipped, as LoadConfig skips them.
+func ConfigLayers() []ConfigLayer {
+	layers := []ConfigLayer{{Name: LayerDefault, Config: DefaultConfig()}}
+	for _, l := range []ConfigLayer{
+		{Name: LayerUser, Path: filepath.Join(configDir(), "muxcode.json")},
+		{Name: LayerProject, Path: filepath.Join(".muxcode", "muxcode.json")},
+		{Name: LayerSession, Path: SessionConfigPath(BusSession())},
+	} {
+		data, err := os.ReadFile(l.Path)
+		if err != nil {
+			continue
+		}
+		var cfg MuxcodeConfig
+		if json.Unmarshal(data, &cfg) != nil {
+			continue
+		}
+		l.Config = &cfg
+		layers = append(layers, l)
+	}
+	return layers
+}
+
+// topLayer returns the highest-priority layer for which has is true, or
+// the default layer.
+func topLayer(layers []ConfigLayer, has func(*MuxcodeConfig) bool) string {
+	name := LayerDefault
+	for _, l := range layers {
+		if has(l.Config) {
+			name = l.Name
+		}
+	}
+	return name
+}
+
+// ExplainedTool is a resolved tool pattern with the part of the profile
+// and the config layer it came from.
+type ExplainedTool struct {
+	Tool   string `json:"tool"`
+	Source string `json:"source"` // "shared_tools.<group>", "tools", or "cd_prefix"
+	Layer  string `json:"layer"`
+}
+
+// ToolExplanation is a role's effective tool list, attributed.
+type ToolExplanation struct {
+	Role         string          `json:"role"`
+	Profile      string          `json:"profile"`
+	ProfileLayer string          `json:"profile_layer"` // layer the profile itself comes from
+	Include      []string        `json:"include,omitempty"`
+	Undefined    []string        `json:"undefined,omitempty"` // included groups no layer defines
+	CdPrefix     bool            `json:"cd_prefix,omitempty"`
+	Tools        []ExplainedTool `json:"tools"`
+}
+
+// ExplainTools resolves role's tool profile, attributing each pattern to
+// the profile part and config layer that contributed it.
+func ExplainTools(role string) (ToolExplanation, error) {
+	return explainTools(Config(), ConfigLayers(), role)
+}
+
+// explainTools is ExplainTools against a merged config and its layers.
+// Profiles and shared groups are replaced whole by higher layers, so each
+// comes from the highest layer defining it.
+func explainTools(cfg *MuxcodeConfig, layers []ConfigLayer, role string) (ToolExplanation, error) {
+	name := resolveRoleAlias(role)
+	profile, ok := cfg.ToolProfiles[name]
+	if !ok {
+		return ToolExplanation{}, fmt.Errorf("no tool profile for role %q", role)
+	}
+	ex := ToolExplanation{
+		Role:     role,
+		Profile:  name,
+		Include:  profile.Include,
+		CdPrefix: profile.CdPrefix,
+		Tools:    []ExplainedTool{},
+		ProfileLayer: topLayer(layers, func(c *MuxcodeConfig) bool {
+			_, ok := c.ToolProfiles[name]
+			return ok
+		}),
+	}
+	for _, g := range profile.Include {
+		if _, ok := cfg.SharedTools[g]; !ok {
+			ex.Undefined = append(ex.Undefined, g)
+		}
+	}
+	for _, e := range resolveProfileEntries(cfg, profile) {
+		layer := ex.ProfileLayer
+		if group, ok := strings.CutPrefix(e.Source, "shared_tools."); ok {
+			layer = topLayer(layers, func(c *MuxcodeConfig) bool {
+				_, ok := c.SharedTools[group]
+				return ok
+			})
+		}
+		ex.Tools = append(ex.Tools, ExplainedTool{Tool: e.Tool, Source: e.Source, Layer: layer})
+	}
+	return ex, nil
+}
+
+// ToolDiff compares two roles' effective tool lists.
+type ToolDiff struct {
+	A      string          `json:"a"`
+	B      string          `json:"b"`
+	OnlyA  []ExplainedTool `json:"only_a"`
+	OnlyB  []ExplainedTool `json:"only_b"`
+	Common []string        `json:"common"`
+}
+
+// DiffTools compares the effective tool lists of roles a and b.
+func DiffTools(a, b string) (ToolDiff, error) {
+	layers := ConfigLayers()
+	exA, err := explainTools(Config(), layers, a)
+	if err != nil {
+		return ToolDiff{}, err
+	}
+	exB, err := explainTools(Config(), layers, b)
+	if err != nil {
+		return ToolDiff{}, err
+	}
+	return diffExplained(exA, exB), nil
+}
+
+// diffExplained compares two explanations by pattern, in each one's order.
+func diffExplained(a, b ToolExplanation) ToolDiff {
+	d := ToolDiff{A: a.Role, B: b.Role, OnlyA: []ExplainedTool{}, OnlyB: []ExplainedTool{}, Common: []string{}}
+	inB := make(map[string]bool)
+	for _, t := range b.Tools {
+		inB[t.Tool] = true
+	}
+	inA := make(map[string]bool)
+	for _, t := range a.Tools {
+		inA[t.Tool] = true
+		if inB[t.Tool] {
+			d.Common = append(d.Common, t.Tool)
+		} else {
+			d.OnlyA = append(d.OnlyA, t)
+		}
+	}
+	for _, t := range b.Tools {
+		if !inA[t.Tool] {
+			d.OnlyB = append(d.OnlyB, t)
+		}
+	}
+	return d
+}
+
+// FormatToolExplanation renders a role's attributed tool list.
+func FormatToolExplanation(ex ToolExplanation, layers []ConfigLayer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Role: %s (profile %s, from %s)\n", ex.Role, ex.Profile, ex.ProfileLayer)
+	include := strings.Join(ex.Include, ", ")
+	if include == "" {
+		include = "(none)"
+	}
+	if len(ex.Undefined) > 0 {
+		include += "; undefined: " + strings.Join(ex.Undefined, ", ")
+	}
+	fmt.Fprintf(&b, "Include: %s  cd_prefix: %v\n\n", include, ex.CdPrefix)
+	width := len("PATTERN")
+	for _, t := range ex.Tools {
+		width = max(width, len(t.Tool))
+	}
+	fmt.Fprintf(&b, "%-*s  %-22s %s\n", width, "PATTERN", "SOURCE", "LAYER")
+	for _, t := range ex.Tools {
+		fmt.Fprintf(&b, "%-*s  %-22s %s\n", width, t.Tool, t.Source, t.Layer)
+	}
+	b.WriteString("\n" + formatLayers(layers))
+	return b.String()
+}
+
+// FormatToolDiff renders the patterns only one of two roles has.
+func FormatToolDiff(d ToolDiff) string {
+	var b strings.Builder
+	width := 0
+	for _, t := range append(append([]ExplainedTool{}, d.OnlyA...), d.OnlyB...) {
+		width = max(width, len(t.Tool))
+	}
+	for _, side := range []struct {
+		role  string
+		tools []ExplainedTool
+	}{{d.A, d.OnlyA}, {d.B, d.OnlyB}} {
+		fmt.Fprintf(&b, "Only %s (%d):\n", side.role, len(side.tools))
+		for _, t := range side.tools {
+			fmt.Fprintf(&b, "  %-*s  %s (%s)\n", width, t.Tool, t.Source, t.Layer)
+		}
+	}
+	fmt.Fprintf(&b, "Common: %d pattern(s)\n", len(d.Common))
+	return b.String()
+}
+
+// formatLayers lists the layers found, lowest priority first.
+func formatLayers(layers []ConfigLayer) string {
+	var parts []string
+	for _, l := range layers {
+		path := l.Path
+		if path == "" {
+			path = "built in"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", l.Name, path))
+	}
+	return "Layers: " + strings.Join(parts, " < ") + "\n"
+}
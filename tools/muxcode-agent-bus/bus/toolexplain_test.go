@@ -0,0 +1,81 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplainTools(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv("MUXCODE_CONFIG_DIR", userDir)
+	t.Setenv("BUS_SESSION", "explain-test-nosession")
+	os.WriteFile(filepath.Join(userDir, "muxcode.json"), []byte(`{"shared_tools": {"extra": ["Bash(jq *)"]}}`), 0644)
+
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, ".muxcode"), 0755)
+	os.WriteFile(filepath.Join(projectDir, ".muxcode", "muxcode.json"), []byte(`{
+  "tool_profiles": {"build": {"include": ["readonly", "extra", "missing"], "tools": ["Bash(make *)", "Read"], "cd_prefix": true}}
+}`), 0644)
+	origDir, _ := os.Getwd()
+	os.Chdir(projectDir)
+	defer os.Chdir(origDir)
+	SetConfig(nil)
+	defer SetConfig(nil)
+
+	layers := ConfigLayers()
+	if len(layers) != 3 || layers[1].Name != LayerUser || layers[2].Name != LayerProject {
+		t.Fatalf("layers = %+v", layers)
+	}
+
+	ex, err := ExplainTools("build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.ProfileLayer != LayerProject || len(ex.Undefined) != 1 || ex.Undefined[0] != "missing" {
+		t.Errorf("explanation = %+v", ex)
+	}
+	want := []ExplainedTool{
+		{"Read", "shared_tools.readonly", LayerDefault},
+		{"Glob", "shared_tools.readonly", LayerDefault},
+		{"Grep", "shared_tools.readonly", LayerDefault},
+		{"Bash(jq *)", "shared_tools.extra", LayerUser},
+		{"Bash(make *)", "tools", LayerProject},
+		{"Bash(cd * && make *)", "cd_prefix", LayerProject},
+	}
+	if len(ex.Tools) != len(want) {
+		t.Fatalf("tools = %+v", ex.Tools)
+	}
+	for i, w := range want {
+		if ex.Tools[i] != w {
+			t.Errorf("tool %d = %+v, want %+v", i, ex.Tools[i], w)
+		}
+	}
+	out := FormatToolExplanation(ex, layers)
+	for _, s := range []string{"Role: build (profile build, from project)", "undefined: missing", "Layers: default (built in) < user ("} {
+		if !strings.Contains(out, s) {
+			t.Errorf("FormatToolExplanation missing %q:\n%s", s, out)
+		}
+	}
+
+	// The test profile is untouched, so it comes from the defaults
+	d, err := DiffTools("build", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.OnlyA) != 3 || d.OnlyA[0].Tool != "Bash(jq *)" || len(d.Common) != 3 {
+		t.Errorf("diff only build = %+v, common %v", d.OnlyA, d.Common)
+	}
+	for _, tool := range d.OnlyB {
+		if tool.Layer != LayerDefault {
+			t.Errorf("test tool %+v: want default layer", tool)
+		}
+	}
+	if out := FormatToolDiff(d); !strings.Contains(out, "Only build (3):") || !strings.Contains(out, "Common: 3 pattern(s)") {
+		t.Errorf("FormatToolDiff:\n%s", out)
+	}
+	if _, err := DiffTools("build", "nope"); err == nil {
+		t.Error("unknown role: want error")
+	}
+}
@@ -0,0 +1,462 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceParentEnv carries a W3C traceparent ("00-{trace}-{span}-01") into
+// child processes. When set it takes precedence over the role's trace
+// context, so commands run by a harness turn are parented to that turn.
+const TraceParentEnv = "TRACEPARENT"
+
+// OTLPEndpointEnv names the OTLP/HTTP collector endpoint spans are exported
+// to (e.g. http://localhost:4318). OTEL_EXPORTER_OTLP_ENDPOINT is used when
+// it is unset.
+const OTLPEndpointEnv = "MUXCODE_OTLP_ENDPOINT"
+
+// Span is one recorded step of a trace: a message send, a chain action,
+// or a harness turn. Times are Unix milliseconds.
+type Span struct {
+	TraceID  string            `json:"trace_id"`
+	SpanID   string            `json:"span_id"`
+	ParentID string            `json:"parent_id,omitempty"`
+	Name     string            `json:"name"`
+	Role     string            `json:"role"`
+	Start    int64             `json:"start_ms"`
+	End      int64             `json:"end_ms"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+}
+
+// NewTraceID returns a random 16-byte trace ID as 32 hex characters.
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a random 8-byte span ID as 16 hex characters.
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FormatTraceParent encodes a trace and span ID as a W3C traceparent.
+func FormatTraceParent(traceID, spanID string) string {
+	return "00-" + traceID + "-" + spanID + "-01"
+}
+
+// ParseTraceParent decodes a W3C traceparent. ok is false for malformed
+// values.
+func ParseTraceParent(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// CurrentTraceContext returns the trace a role is working on: the
+// TRACEPARENT environment variable if set, otherwise the context recorded
+// when the role last consumed a traced message.
+func CurrentTraceContext(session, role string) (traceID, spanID string) {
+	if t, s, ok := ParseTraceParent(os.Getenv(TraceParentEnv)); ok {
+		return t, s
+	}
+	data, err := os.ReadFile(TraceContextPath(session, role))
+	if err != nil {
+		return "", ""
+	}
+	t, s, _ := ParseTraceParent(string(data))
+	return t, s
+}
+
+// setTraceContext records the last traced message a role consumed, so the
+// role's next sends join that message's trace.
+func setTraceContext(session, role string, msgs []Message) {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		m := msgs[i]
+		if m.TraceID == "" || m.SpanID == "" {
+			continue
+		}
+		path := TraceContextPath(session, role)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return
+		}
+		_ = os.WriteFile(path, []byte(FormatTraceParent(m.TraceID, m.SpanID)+"\n"), 0644)
+		return
+	}
+}
+
+// stampTrace assigns the message a span in the sender's current trace,
+// starting a new trace when the sender has none. Messages that already
+// carry a span are left unchanged.
+func stampTrace(session string, m *Message) {
+	if m.SpanID != "" {
+		return
+	}
+	if m.TraceID == "" {
+		m.TraceID, m.ParentSpan = CurrentTraceContext(session, m.From)
+		if m.TraceID == "" {
+			m.TraceID = NewTraceID()
+		}
+	}
+	m.SpanID = NewSpanID()
+}
+
+// messageSpan returns the span recorded for a message send.
+func messageSpan(m Message) Span {
+	ms := time.Now().UnixMilli()
+	attrs := map[string]string{
+		"msg.id":     m.ID,
+		"msg.from":   m.From,
+		"msg.to":     m.To,
+		"msg.type":   m.Type,
+		"msg.action": m.Action,
+	}
+	if m.ReplyTo != "" {
+		attrs["msg.reply_to"] = m.ReplyTo
+	}
+	return Span{
+		TraceID:  m.TraceID,
+		SpanID:   m.SpanID,
+		ParentID: m.ParentSpan,
+		Name:     fmt.Sprintf("%s → %s %s:%s", m.From, m.To, m.Type, m.Action),
+		Role:     m.From,
+		Start:    ms,
+		End:      ms,
+		Attrs:    attrs,
+	}
+}
+
+// RecordChainSpan records a chain action as a span in the sender's current
+// trace and returns its trace and span IDs, which the chain's outgoing
+// message is parented to.
+func RecordChainSpan(session, from, event, outcome, exitCode, command string) (traceID, spanID string) {
+	traceID, parent := CurrentTraceContext(session, from)
+	if traceID == "" {
+		traceID = NewTraceID()
+	}
+	spanID = NewSpanID()
+	ms := time.Now().UnixMilli()
+	_ = RecordSpan(session, Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parent,
+		Name:     fmt.Sprintf("chain %s %s", event, outcome),
+		Role:     from,
+		Start:    ms,
+		End:      ms,
+		Attrs: map[string]string{
+			"chain.event":     event,
+			"chain.outcome":   outcome,
+			"chain.exit_code": exitCode,
+			"chain.command":   command,
+		},
+	})
+	return traceID, spanID
+}
+
+// RecordSpan appends a span to the session's trace log.
+func RecordSpan(session string, span Span) error {
+	data, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+	return appendToFile(TracePath(session), append(data, '\n'))
+}
+
+// ReadSpans reads all spans from the session's trace log.
+func ReadSpans(session string) ([]Span, error) {
+	data, err := os.ReadFile(TracePath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseSpans(data), nil
+}
+
+func parseSpans(data []byte) []Span {
+	var spans []Span
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var s Span
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil || s.TraceID == "" {
+			continue
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// FindTrace returns the spans of one trace. id may be a trace ID, a unique
+// trace ID prefix, or the ID of a message in the trace.
+func FindTrace(spans []Span, id string) []Span {
+	traceID := ""
+	for _, s := range spans {
+		if s.TraceID == id || s.Attrs["msg.id"] == id {
+			traceID = s.TraceID
+			break
+		}
+	}
+	if traceID == "" && len(id) >= 6 {
+		for _, s := range spans {
+			if strings.HasPrefix(s.TraceID, id) {
+				if traceID != "" && traceID != s.TraceID {
+					return nil // ambiguous prefix
+				}
+				traceID = s.TraceID
+			}
+		}
+	}
+	if traceID == "" {
+		return nil
+	}
+	var out []Span
+	for _, s := range spans {
+		if s.TraceID == traceID {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// FormatTraceTree renders a trace as an indented tree of spans ordered by
+// start time, with offsets relative to the first span.
+func FormatTraceTree(spans []Span) string {
+	if len(spans) == 0 {
+		return "No spans.\n"
+	}
+	sorted := append([]Span(nil), spans...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	ids := make(map[string]bool, len(sorted))
+	for _, s := range sorted {
+		ids[s.SpanID] = true
+	}
+	children := make(map[string][]Span)
+	var roots []Span
+	for _, s := range sorted {
+		if s.ParentID != "" && ids[s.ParentID] && s.ParentID != s.SpanID {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	t0 := sorted[0].Start
+	var b strings.Builder
+	fmt.Fprintf(&b, "Trace %s (%d spans, %s)\n", sorted[0].TraceID, len(sorted),
+		formatSpanDuration(traceEnd(sorted)-t0))
+	var walk func(s Span, depth int)
+	walk = func(s Span, depth int) {
+		line := fmt.Sprintf("%s+%-8s %s", strings.Repeat("  ", depth+1), formatSpanDuration(s.Start-t0), s.Name)
+		if s.End > s.Start {
+			line += fmt.Sprintf(" [%s]", formatSpanDuration(s.End-s.Start))
+		}
+		b.WriteString(line + "\n")
+		for _, c := range children[s.SpanID] {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	return b.String()
+}
+
+// FormatTraceList summarizes the most recent traces, newest first.
+func FormatTraceList(spans []Span, limit int) string {
+	type summary struct {
+		id         string
+		first      Span
+		start, end int64
+		count      int
+		roles      []string
+	}
+	byID := make(map[string]*summary)
+	var order []*summary
+	for _, s := range spans {
+		sum := byID[s.TraceID]
+		if sum == nil {
+			sum = &summary{id: s.TraceID, first: s, start: s.Start}
+			byID[s.TraceID] = sum
+			order = append(order, sum)
+		}
+		sum.count++
+		if s.Start < sum.start {
+			sum.start, sum.first = s.Start, s
+		}
+		if s.End > sum.end {
+			sum.end = s.End
+		}
+		if !containsRole(sum.roles, s.Role) {
+			sum.roles = append(sum.roles, s.Role)
+		}
+	}
+	if len(order) == 0 {
+		return "No traces.\n"
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].start > order[j].start })
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %-8s %5s %8s  %s\n", "Trace", "Started", "Spans", "Duration", "Root")
+	for _, sum := range order {
+		fmt.Fprintf(&b, "%-12s %-8s %5d %8s  %s (%s)\n",
+			sum.id[:12], time.UnixMilli(sum.start).Format("15:04:05"), sum.count,
+			formatSpanDuration(sum.end-sum.start), sum.first.Name, strings.Join(sum.roles, " → "))
+	}
+	return b.String()
+}
+
+func traceEnd(spans []Span) int64 {
+	var end int64
+	for _, s := range spans {
+		if s.End > end {
+			end = s.End
+		}
+	}
+	return end
+}
+
+func formatSpanDuration(ms int64) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// OTLPEndpoint returns the configured OTLP/HTTP traces URL, or "" when
+// export is disabled.
+func OTLPEndpoint() string {
+	endpoint := os.Getenv(OTLPEndpointEnv)
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return ""
+	}
+	return OTLPTracesURL(endpoint)
+}
+
+// OTLPTracesURL appends the OTLP/HTTP traces path to a collector base URL
+// unless it is already present.
+func OTLPTracesURL(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	return endpoint
+}
+
+// otlpAttrs converts span attributes to OTLP key/value pairs, sorted by key.
+func otlpAttrs(attrs map[string]string) []map[string]interface{} {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, map[string]interface{}{
+			"key":   k,
+			"value": map[string]string{"stringValue": attrs[k]},
+		})
+	}
+	return out
+}
+
+// OTLPRequest builds an OTLP/HTTP JSON ExportTraceServiceRequest body.
+func OTLPRequest(session string, spans []Span) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		attrs := map[string]string{"muxcode.role": s.Role}
+		for k, v := range s.Attrs {
+			attrs[k] = v
+		}
+		span := map[string]interface{}{
+			"traceId":           s.TraceID,
+			"spanId":            s.SpanID,
+			"name":              s.Name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(s.Start*int64(time.Millisecond), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End*int64(time.Millisecond), 10),
+			"attributes":        otlpAttrs(attrs),
+		}
+		if s.ParentID != "" {
+			span["parentSpanId"] = s.ParentID
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttrs(map[string]string{
+					"service.name":    "muxcode",
+					"muxcode.session": session,
+				}),
+			},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]string{"name": "muxcode-agent-bus"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// ExportSpans POSTs spans to an OTLP/HTTP collector.
+func ExportSpans(endpoint, session string, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	_, err := postJSON(endpoint, nil, OTLPRequest(session, spans))
+	return err
+}
+
+// ExportNewSpans exports spans appended to the trace log since offset and
+// returns the new offset. Only complete lines are consumed, so a span
+// being written is picked up on the next call.
+func ExportNewSpans(session, endpoint string, offset int64) (int64, int, error) {
+	data, err := os.ReadFile(TracePath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return offset, 0, err
+	}
+	if int64(len(data)) < offset {
+		offset = 0 // log was reset
+	}
+	chunk := data[offset:]
+	end := bytes.LastIndexByte(chunk, '\n')
+	if end < 0 {
+		return offset, 0, nil
+	}
+	spans := parseSpans(chunk[:end+1])
+	if err := ExportSpans(endpoint, session, spans); err != nil {
+		return offset, 0, err
+	}
+	return offset + int64(end+1), len(spans), nil
+}
@@ -0,0 +1,144 @@
+package bus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, spanID := NewTraceID(), NewSpanID()
+	gotTrace, gotSpan, ok := ParseTraceParent(FormatTraceParent(traceID, spanID) + "\n")
+	if !ok || gotTrace != traceID || gotSpan != spanID {
+		t.Errorf("round trip = %q %q %v", gotTrace, gotSpan, ok)
+	}
+	for _, bad := range []string{"", "00-abc-def-01", "00-" + strings.Repeat("z", 32) + "-" + spanID + "-01"} {
+		if _, _, ok := ParseTraceParent(bad); ok {
+			t.Errorf("ParseTraceParent(%q) ok, want malformed", bad)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	session := testSession(t)
+	t.Setenv(TraceParentEnv, "")
+
+	// edit starts a trace
+	req := NewMessage("edit", "build", "request", "build", "go build ./...", "")
+	if err := Send(session, req); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := Receive(session, "build")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("Receive = %v, %v", msgs, err)
+	}
+	root := msgs[0]
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 || root.ParentSpan != "" {
+		t.Fatalf("root message trace = %q/%q/%q", root.TraceID, root.SpanID, root.ParentSpan)
+	}
+
+	// build's chain action and reply join edit's trace
+	traceID, chainSpan := RecordChainSpan(session, "build", "build", "success", "0", "go build ./...")
+	if traceID != root.TraceID {
+		t.Errorf("chain trace = %s, want %s", traceID, root.TraceID)
+	}
+	chain := NewMessage("build", "test", "request", "test", "run tests", "")
+	chain.TraceID, chain.ParentSpan = traceID, chainSpan
+	if err := SendNoCC(session, chain); err != nil {
+		t.Fatal(err)
+	}
+	reply := NewMessage("build", "edit", "response", "build", "ok", root.ID)
+	if err := SendNoCC(session, reply); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := Peek(session, "edit")
+	if len(got) != 1 || got[0].TraceID != root.TraceID || got[0].ParentSpan != root.SpanID {
+		t.Errorf("reply trace = %+v", got)
+	}
+
+	// TRACEPARENT overrides the role's recorded context
+	override := FormatTraceParent(NewTraceID(), NewSpanID())
+	t.Setenv(TraceParentEnv, override)
+	if err := SendNoCC(session, NewMessage("build", "review", "request", "review", "x", "")); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = Peek(session, "review")
+	if len(got) != 1 || FormatTraceParent(got[0].TraceID, got[0].ParentSpan) != override {
+		t.Errorf("TRACEPARENT not honored: %+v", got)
+	}
+
+	spans, err := ReadSpans(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := FindTrace(spans, reply.ID)
+	if len(trace) != 4 {
+		t.Fatalf("trace has %d spans, want 4 (send, chain, chain send, reply)", len(trace))
+	}
+	tree := FormatTraceTree(trace)
+	for _, want := range []string{
+		"Trace " + root.TraceID + " (4 spans",
+		"  +0s       edit → build request:build",
+		"    +",
+		"chain build success",
+		"      +",
+		"build → test request:test",
+	} {
+		if !strings.Contains(tree, want) {
+			t.Errorf("tree missing %q:\n%s", want, tree)
+		}
+	}
+	if len(FindTrace(spans, root.TraceID[:8])) != 4 {
+		t.Error("FindTrace by prefix failed")
+	}
+	if list := FormatTraceList(spans, 10); !strings.Contains(list, root.TraceID[:12]) || !strings.Contains(list, "edit → build") {
+		t.Errorf("FormatTraceList:\n%s", list)
+	}
+}
+
+func TestExportNewSpans(t *testing.T) {
+	session := testSession(t)
+
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+	t.Setenv(OTLPEndpointEnv, srv.URL+"/")
+	endpoint := OTLPEndpoint()
+
+	span := Span{TraceID: NewTraceID(), SpanID: NewSpanID(), Name: "edit → build request:build", Role: "edit", Start: 1000, End: 1500, Attrs: map[string]string{"msg.id": "m1"}}
+	child := span
+	child.SpanID, child.ParentID = NewSpanID(), span.SpanID
+	_ = RecordSpan(session, span)
+	_ = RecordSpan(session, child)
+
+	offset, n, err := ExportNewSpans(session, endpoint, 0)
+	if err != nil || n != 2 || offset == 0 {
+		t.Fatalf("ExportNewSpans = %d, %d, %v", offset, n, err)
+	}
+	// Nothing new: no request
+	if _, n, _ := ExportNewSpans(session, endpoint, offset); n != 0 || len(bodies) != 1 {
+		t.Errorf("re-export sent %d spans, %d requests", n, len(bodies))
+	}
+
+	data, _ := json.Marshal(bodies[0])
+	for _, want := range []string{
+		`"service.name"`,
+		`"traceId":"` + span.TraceID + `"`,
+		`"parentSpanId":"` + span.SpanID + `"`,
+		`"startTimeUnixNano":"1000000000"`,
+		`"key":"msg.id","value":{"stringValue":"m1"}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("OTLP body missing %s:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Inbox transport backends selectable via the transport config section.
+const (
+	TransportFiles = "files"
+	TransportRedis = "redis"
+)
+
+// Transport is an alternative home for role inboxes, so agents on several
+// hosts or containers can share them. Each inbox is an ordered list of
+// encoded message lines (as written to the inbox file, encrypted when
+// encryption is on). When no transport is configured the bus uses the
+// inbox/<role>.jsonl files in the bus directory.
+type Transport interface {
+	// Deliver appends a message line to a role's inbox.
+	Deliver(session, role string, line []byte) error
+	// List returns a role's inbox entries, oldest first.
+	List(session, role string) ([]TransportEntry, error)
+	// Remove deletes entries by ID; unknown IDs are ignored.
+	Remove(session, role string, ids []string) error
+	// Clear deletes a role's inbox.
+	Clear(session, role string) error
+}
+
+// TransportEntry is one message line in a transport inbox.
+type TransportEntry struct {
+	ID   string
+	Line []byte
+}
+
+// TransportConfig is the transport config section.
+type TransportConfig struct {
+	Backend string `json:"backend,omitempty"` // "files" (default) or "redis"
+	URL     string `json:"url,omitempty"`     // redis://[[user]:password@]host[:port][/db]
+	Prefix  string `json:"prefix,omitempty"`  // key prefix (default "muxcode")
+}
+
+// Validate checks the backend name and, for redis, the URL.
+func (c TransportConfig) Validate() error {
+	switch c.Backend {
+	case "", TransportFiles:
+		return nil
+	case TransportRedis:
+		_, err := ParseRedisURL(c.URL)
+		return err
+	default:
+		return fmt.Errorf("unknown backend %q (want %s or %s)", c.Backend, TransportFiles, TransportRedis)
+	}
+}
+
+// Backoff between attempts to reach an unreachable transport, doubling
+// from transportRetryMin up to transportRetryMax.
+const (
+	transportRetryMin = 2 * time.Second
+	transportRetryMax = time.Minute
+)
+
+var (
+	transportMu      sync.Mutex
+	transportCached  Transport
+	transportKey     TransportConfig
+	transportErr     error         // last dial failure for transportKey
+	transportRetryAt time.Time     // when the next dial may be attempted
+	transportBackoff time.Duration // wait after the next failure
+)
+
+// ActiveTransport returns the configured inbox transport, or nil when the
+// default file backend is in use. The transport is kept for the life of the
+// process so its connection is reused. A configured backend that cannot be
+// reached is an error rather than a fallback to files, which agents on other
+// hosts would never see; the failure is remembered with a backoff, so
+// callers polling every role do not each wait out a dial timeout.
+func ActiveTransport() (Transport, error) {
+	cfg := Config().Transport
+	if cfg.Backend == "" || cfg.Backend == TransportFiles {
+		return nil, nil
+	}
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if transportKey == cfg {
+		if transportCached != nil {
+			return transportCached, nil
+		}
+		if transportErr != nil && time.Now().Before(transportRetryAt) {
+			return nil, transportErr
+		}
+	} else {
+		transportCached, transportErr, transportBackoff = nil, nil, 0
+	}
+	transportKey = cfg
+	switch cfg.Backend {
+	case TransportRedis:
+		t, err := NewRedisTransport(cfg.URL, cfg.Prefix)
+		if err != nil {
+			transportBackoff = min(max(2*transportBackoff, transportRetryMin), transportRetryMax)
+			transportErr = fmt.Errorf("redis transport unavailable (retrying in %s): %w", transportBackoff, err)
+			transportRetryAt = time.Now().Add(transportBackoff)
+			return nil, transportErr
+		}
+		transportCached, transportErr, transportBackoff = t, nil, 0
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown transport backend %q", cfg.Backend)
+	}
+}
+
+// deliverInbox appends an encoded message line to a role's inbox.
+func deliverInbox(session, role string, line []byte) error {
+	t, err := ActiveTransport()
+	if err != nil {
+		return err
+	}
+	if t != nil {
+		return t.Deliver(session, role, line)
+	}
+	return appendToFile(InboxPath(session, role), line)
+}
+
+// transportMessages decodes transport entries. An entry that cannot be
+// decrypted fails the whole read, so nothing is removed.
+func transportMessages(entries []TransportEntry) ([]Message, []string, error) {
+	var msgs []Message
+	var ids []string
+	for _, e := range entries {
+		m, err := DecodeMessage(e.Line)
+		if errors.Is(err, ErrSealed) {
+			return nil, nil, err
+		}
+		if err != nil {
+			continue // skip malformed lines, as readMessages does
+		}
+		msgs = append(msgs, MigrateMessage(m))
+		ids = append(ids, e.ID)
+	}
+	return msgs, ids, nil
+}
+
+// receiveTransport removes and returns the messages in a role's transport
+// inbox that match keep (all messages when keep is nil).
+func receiveTransport(t Transport, session, role string, keep func(Message) bool) ([]Message, error) {
+	entries, err := t.List(session, role)
+	if err != nil {
+		return nil, err
+	}
+	msgs, ids, err := transportMessages(entries)
+	if err != nil {
+		return nil, err
+	}
+	var matched []Message
+	var remove []string
+	for i, m := range msgs {
+		if keep == nil || keep(m) {
+			matched = append(matched, m)
+			remove = append(remove, ids[i])
+		}
+	}
+	if len(remove) == 0 {
+		return nil, nil
+	}
+	return matched, t.Remove(session, role, remove)
+}
+
+// clearInbox empties a role's inbox in the transport, if one is active.
+// The inbox file itself is handled by the caller.
+func clearInbox(session, role string) {
+	if t, _ := ActiveTransport(); t != nil {
+		_ = t.Clear(session, role)
+	}
+}
+
+// InboxSize returns the size in bytes of a role's inbox: the file size, or
+// the total line length in a transport. The watcher and notify dedup
+// compare sizes to spot new messages. An unreachable transport reads as
+// empty.
+func InboxSize(session, role string) int64 {
+	t, err := ActiveTransport()
+	if err != nil {
+		return 0
+	}
+	if t != nil {
+		entries, err := t.List(session, role)
+		if err != nil {
+			return 0
+		}
+		var n int64
+		for _, e := range entries {
+			n += int64(len(e.Line))
+		}
+		return n
+	}
+	info, err := os.Stat(InboxPath(session, role))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,260 @@
+package bus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds each connect and command round trip.
+const redisTimeout = 5 * time.Second
+
+// RedisOptions are the connection settings parsed from a redis:// URL.
+type RedisOptions struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+}
+
+// ParseRedisURL parses redis://[[user]:password@]host[:port][/db].
+func ParseRedisURL(raw string) (RedisOptions, error) {
+	if raw == "" {
+		return RedisOptions{}, fmt.Errorf("redis url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "redis" || u.Hostname() == "" {
+		return RedisOptions{}, fmt.Errorf("invalid redis url %q (want redis://host:port/db)", raw)
+	}
+	opts := RedisOptions{Addr: u.Host}
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		if pw, ok := u.User.Password(); ok {
+			opts.Username, opts.Password = u.User.Username(), pw
+		} else {
+			opts.Password = u.User.Username()
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil || n < 0 {
+			return RedisOptions{}, fmt.Errorf("invalid redis database %q", db)
+		}
+		opts.DB = n
+	}
+	return opts, nil
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn is a minimal RESP client: one connection, serialized commands,
+// reconnecting once when the connection drops.
+type redisConn struct {
+	mu   sync.Mutex
+	opts RedisOptions
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// Do runs one command and returns its reply: string, int64, nil, or []any.
+func (c *redisConn) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err := c.dial(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := c.roundTrip(args)
+		var re redisError
+		if err == nil || errors.As(err, &re) {
+			return v, err
+		}
+		c.close()
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *redisConn) dial() error {
+	conn, err := net.DialTimeout("tcp", c.opts.Addr, redisTimeout)
+	if err != nil {
+		return fmt.Errorf("connecting to redis at %s: %w", c.opts.Addr, err)
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+	if c.opts.Password != "" {
+		auth := []string{"AUTH", c.opts.Password}
+		if c.opts.Username != "" {
+			auth = []string{"AUTH", c.opts.Username, c.opts.Password}
+		}
+		if _, err := c.roundTrip(auth); err != nil {
+			c.close()
+			return err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(c.opts.DB)}); err != nil {
+			c.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *redisConn) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn, c.rd = nil, nil
+}
+
+func (c *redisConn) roundTrip(args []string) (any, error) {
+	_ = c.conn.SetDeadline(time.Now().Add(redisTimeout))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(c.rd)
+}
+
+// readRESP reads one RESP2 reply.
+func readRESP(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// RedisTransport keeps each inbox in a Redis stream,
+// "<prefix>:<session>:inbox:<role>", with one "line" field per entry.
+type RedisTransport struct {
+	Prefix string
+	conn   *redisConn
+}
+
+// NewRedisTransport connects to the server at rawURL and checks it with
+// PING. prefix defaults to "muxcode".
+func NewRedisTransport(rawURL, prefix string) (*RedisTransport, error) {
+	opts, err := ParseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = "muxcode"
+	}
+	t := &RedisTransport{Prefix: prefix, conn: &redisConn{opts: opts}}
+	if _, err := t.conn.Do("PING"); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *RedisTransport) key(session, role string) string {
+	return t.Prefix + ":" + session + ":inbox:" + role
+}
+
+// Deliver appends a line with XADD.
+func (t *RedisTransport) Deliver(session, role string, line []byte) error {
+	_, err := t.conn.Do("XADD", t.key(session, role), "*", "line", string(line))
+	return err
+}
+
+// List reads the whole stream with XRANGE.
+func (t *RedisTransport) List(session, role string) ([]TransportEntry, error) {
+	v, err := t.conn.Do("XRANGE", t.key(session, role), "-", "+")
+	if err != nil {
+		return nil, err
+	}
+	items, _ := v.([]any)
+	var entries []TransportEntry
+	for _, it := range items {
+		pair, ok := it.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		id, _ := pair[0].(string)
+		fields, _ := pair[1].([]any)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if name, _ := fields[i].(string); name == "line" {
+				val, _ := fields[i+1].(string)
+				entries = append(entries, TransportEntry{ID: id, Line: []byte(val)})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Remove deletes entries with XDEL.
+func (t *RedisTransport) Remove(session, role string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := t.conn.Do(append([]string{"XDEL", t.key(session, role)}, ids...)...)
+	return err
+}
+
+// Clear deletes the stream.
+func (t *RedisTransport) Clear(session, role string) error {
+	_, err := t.conn.Do("DEL", t.key(session, role))
+	return err
+}
@@ -0,0 +1,170 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourcePressureAction is the event action sent when an Ollama node's
+// loaded models crowd its GPU memory.
+const ResourcePressureAction = "resource-pressure"
+
+// defaultVRAMPressurePct is the GPU memory use that counts as pressure
+// when ollama.vram_pressure_pct is unset.
+const defaultVRAMPressurePct = 90
+
+// LoadedModel is one model an Ollama node holds in memory (GET /api/ps).
+type LoadedModel struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	SizeVRAM  int64  `json:"size_vram"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// GPUMemory is the memory used and available across the machine's GPUs, in
+// bytes.
+type GPUMemory struct {
+	Used  int64 `json:"used"`
+	Total int64 `json:"total"`
+}
+
+// ResourcePressure describes a node over its VRAM thresholds.
+type ResourcePressure struct {
+	Node      string        `json:"node"`
+	Models    []LoadedModel `json:"models"`
+	ModelVRAM int64         `json:"model_vram"`
+	GPU       *GPUMemory    `json:"gpu,omitempty"`
+	Reasons   []string      `json:"reasons"`
+}
+
+// gpuMemory reads system GPU memory; replaced in tests.
+var gpuMemory = nvidiaSMIMemory
+
+// LoadedModels lists the models a node has loaded via GET /api/ps.
+func LoadedModels(baseURL string, timeout time.Duration) ([]LoadedModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("listing loaded models at %s: %w", baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/api/ps returned status %d", resp.StatusCode)
+	}
+	var ps struct {
+		Models []LoadedModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ps); err != nil {
+		return nil, fmt.Errorf("decoding /api/ps: %w", err)
+	}
+	return ps.Models, nil
+}
+
+// nvidiaSMIMemory sums memory across NVIDIA GPUs. It returns nil when
+// nvidia-smi is missing or fails (no GPU, Apple silicon, ...).
+func nvidiaSMIMemory() *GPUMemory {
+	out, err := exec.Command("nvidia-smi", "--query-gpu=memory.used,memory.total",
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil
+	}
+	mem, ok := parseNvidiaSMIMemory(string(out))
+	if !ok {
+		return nil
+	}
+	return &mem
+}
+
+// parseNvidiaSMIMemory parses "used, total" MiB lines, one per GPU.
+func parseNvidiaSMIMemory(out string) (GPUMemory, bool) {
+	var mem GPUMemory
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			return GPUMemory{}, false
+		}
+		used, err1 := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+		total, err2 := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		if err1 != nil || err2 != nil {
+			return GPUMemory{}, false
+		}
+		mem.Used += used << 20
+		mem.Total += total << 20
+	}
+	return mem, mem.Total > 0
+}
+
+// CheckResourcePressure compares a node's loaded models, and GPU memory on
+// local nodes, against the ollama VRAM thresholds. It returns nil when the
+// node is within them.
+func CheckResourcePressure(node string, s OllamaSettings, timeout time.Duration) (*ResourcePressure, error) {
+	models, err := LoadedModels(node, timeout)
+	if err != nil {
+		return nil, err
+	}
+	p := &ResourcePressure{Node: node, Models: models}
+	for _, m := range models {
+		p.ModelVRAM += m.SizeVRAM
+	}
+
+	if s.VRAMLimitMB > 0 && p.ModelVRAM > int64(s.VRAMLimitMB)<<20 {
+		p.Reasons = append(p.Reasons, fmt.Sprintf("loaded models use %s VRAM, over the %d MB limit",
+			formatVRAM(p.ModelVRAM), s.VRAMLimitMB))
+	}
+
+	// System GPU memory only describes this machine's nodes
+	if IsLocalOllamaURL(node) {
+		if p.GPU = gpuMemory(); p.GPU != nil {
+			pct := s.VRAMPressurePct
+			if pct == 0 {
+				pct = defaultVRAMPressurePct
+			}
+			if p.GPU.Used*100 >= p.GPU.Total*int64(pct) {
+				p.Reasons = append(p.Reasons, fmt.Sprintf("GPU memory %s of %s used (%d%%), at or over %d%%",
+					formatVRAM(p.GPU.Used), formatVRAM(p.GPU.Total), p.GPU.Used*100/p.GPU.Total, pct))
+			}
+		}
+	}
+
+	if len(p.Reasons) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// FormatResourcePressure formats a resource-pressure event for the edit agent.
+func FormatResourcePressure(p *ResourcePressure) string {
+	var b strings.Builder
+	b.WriteString("⚠ RESOURCE PRESSURE\n")
+	b.WriteString(fmt.Sprintf("  Node: %s\n", p.Node))
+	for _, r := range p.Reasons {
+		b.WriteString(fmt.Sprintf("  %s\n", r))
+	}
+	if len(p.Models) > 0 {
+		b.WriteString("  Loaded models:\n")
+		for _, m := range p.Models {
+			b.WriteString(fmt.Sprintf("    %-30s %s VRAM\n", m.Name, formatVRAM(m.SizeVRAM)))
+		}
+	}
+	b.WriteString("  Consider a smaller model for local roles or pausing spawns.\n")
+	return b.String()
+}
+
+// formatVRAM renders a byte count in MB or GB.
+func formatVRAM(n int64) string {
+	if n >= 1<<30 {
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	}
+	return fmt.Sprintf("%d MB", n>>20)
+}
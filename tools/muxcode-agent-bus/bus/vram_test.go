@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func psServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ps" {
+			t.Errorf("path = %s, want /api/ps", r.URL.Path)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func stubGPUMemory(t *testing.T, mem *GPUMemory) {
+	t.Helper()
+	orig := gpuMemory
+	gpuMemory = func() *GPUMemory { return mem }
+	t.Cleanup(func() { gpuMemory = orig })
+}
+
+const twoModelsPS = `{"models":[
+	{"name":"qwen2.5-coder:7b","size":5368709120,"size_vram":5368709120},
+	{"name":"nomic-embed-text","size":314572800,"size_vram":314572800}]}`
+
+func TestLoadedModels(t *testing.T) {
+	server := psServer(t, twoModelsPS)
+	models, err := LoadedModels(server.URL, time.Second)
+	if err != nil {
+		t.Fatalf("LoadedModels: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "qwen2.5-coder:7b" || models[1].SizeVRAM != 314572800 {
+		t.Errorf("models = %+v", models)
+	}
+}
+
+func TestCheckResourcePressure_ModelLimit(t *testing.T) {
+	stubGPUMemory(t, nil)
+	server := psServer(t, twoModelsPS)
+
+	p, err := CheckResourcePressure(server.URL, OllamaSettings{VRAMLimitMB: 8192}, time.Second)
+	if err != nil || p != nil {
+		t.Fatalf("under limit: pressure = %+v, err = %v", p, err)
+	}
+
+	p, err = CheckResourcePressure(server.URL, OllamaSettings{VRAMLimitMB: 4096}, time.Second)
+	if err != nil {
+		t.Fatalf("CheckResourcePressure: %v", err)
+	}
+	if p == nil || len(p.Reasons) != 1 || !strings.Contains(p.Reasons[0], "over the 4096 MB limit") {
+		t.Fatalf("pressure = %+v, want model limit reason", p)
+	}
+	if p.ModelVRAM != 5368709120+314572800 {
+		t.Errorf("ModelVRAM = %d", p.ModelVRAM)
+	}
+}
+
+func TestCheckResourcePressure_GPUMemory(t *testing.T) {
+	server := psServer(t, twoModelsPS)
+
+	stubGPUMemory(t, &GPUMemory{Used: 20 << 30, Total: 24 << 30}) // 83%
+	if p, _ := CheckResourcePressure(server.URL, OllamaSettings{}, time.Second); p != nil {
+		t.Errorf("83%% under default 90%%: pressure = %+v", p)
+	}
+	p, _ := CheckResourcePressure(server.URL, OllamaSettings{VRAMPressurePct: 80}, time.Second)
+	if p == nil || !strings.Contains(p.Reasons[0], "(83%)") {
+		t.Fatalf("pressure = %+v, want GPU reason", p)
+	}
+
+	stubGPUMemory(t, &GPUMemory{Used: 23 << 30, Total: 24 << 30})
+	if p, _ := CheckResourcePressure(server.URL, OllamaSettings{}, time.Second); p == nil {
+		t.Error("95% should exceed the default threshold")
+	}
+}
+
+func TestCheckResourcePressure_Unreachable(t *testing.T) {
+	server := psServer(t, "{}")
+	server.Close()
+	if _, err := CheckResourcePressure(server.URL, OllamaSettings{VRAMLimitMB: 1}, time.Second); err == nil {
+		t.Error("expected error for unreachable node")
+	}
+}
+
+func TestParseNvidiaSMIMemory(t *testing.T) {
+	mem, ok := parseNvidiaSMIMemory("1024, 24576\n2048, 24576\n")
+	if !ok || mem.Used != 3072<<20 || mem.Total != 49152<<20 {
+		t.Errorf("mem = %+v, ok = %v", mem, ok)
+	}
+	for _, bad := range []string{"", "N/A, N/A", "1024"} {
+		if _, ok := parseNvidiaSMIMemory(bad); ok {
+			t.Errorf("parseNvidiaSMIMemory(%q) ok, want failure", bad)
+		}
+	}
+}
+
+func TestFormatResourcePressure(t *testing.T) {
+	out := FormatResourcePressure(&ResourcePressure{
+		Node:    "http://gpu1:11434",
+		Models:  []LoadedModel{{Name: "qwen2.5-coder:32b", SizeVRAM: 20 << 30}},
+		Reasons: []string{"loaded models use 20.0 GB VRAM, over the 16384 MB limit"},
+	})
+	for _, want := range []string{"RESOURCE PRESSURE", "Node: http://gpu1:11434", "16384 MB limit", "qwen2.5-coder:32b", "20.0 GB VRAM"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestResourcePressureIsSystemAction(t *testing.T) {
+	if !isSystemAction(ResourcePressureAction) {
+		t.Error("resource-pressure should be excluded from loop detection")
+	}
+	if got := MessageSeverity(Message{Action: ResourcePressureAction}); got != SeverityWarning {
+		t.Errorf("severity = %q, want warning", got)
+	}
+}
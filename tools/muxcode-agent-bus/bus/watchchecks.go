@@ -0,0 +1,139 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WatcherCheckNames lists the watcher's periodic checks in run order.
+var WatcherCheckNames = []string{
+	"inbox", "edits", "cron", "procs", "spawns", "loops", "compaction", "sla",
+	"tasks", "budget", "ollama", "traces", "archive", "heartbeat", "callbacks", "workflows",
+	"approvals", "retries", "flaky", "pr-threads", "intake",
+}
+
+// WatcherConfig enables, disables, and retimes watcher checks.
+type WatcherConfig struct {
+	Disable   []string          `json:"disable,omitempty"`   // checks that never run
+	Intervals map[string]string `json:"intervals,omitempty"` // check -> interval ("90s", "5m") replacing its default
+}
+
+// Validate rejects unknown check names and bad intervals.
+func (c WatcherConfig) Validate() error {
+	for _, name := range c.Disable {
+		if !containsRole(WatcherCheckNames, name) {
+			return fmt.Errorf("unknown watcher check %q (want one of %v)", name, WatcherCheckNames)
+		}
+	}
+	for name, v := range c.Intervals {
+		if !containsRole(WatcherCheckNames, name) {
+			return fmt.Errorf("unknown watcher check %q (want one of %v)", name, WatcherCheckNames)
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("watcher interval for %s: %v", name, err)
+		}
+		if d < time.Second {
+			return fmt.Errorf("watcher interval for %s must be at least 1s", name)
+		}
+	}
+	return nil
+}
+
+// Disabled reports whether a check is turned off.
+func (c WatcherConfig) Disabled(name string) bool {
+	return containsRole(c.Disable, name)
+}
+
+// Interval returns the configured interval for a check, or def when none
+// is set or it doesn't parse.
+func (c WatcherConfig) Interval(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(c.Intervals[name]); err == nil && d >= time.Second {
+		return d
+	}
+	return def
+}
+
+// WatcherCheckStat holds one check's run metrics since the watcher started.
+type WatcherCheckStat struct {
+	Name      string `json:"name"`
+	IntervalS int64  `json:"interval_s"` // 0 = every poll
+	Disabled  bool   `json:"disabled,omitempty"`
+	Runs      int    `json:"runs"`
+	Errors    int    `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+	LastRun   int64  `json:"last_run,omitempty"`
+	LastMs    int64  `json:"last_ms"`
+	MaxMs     int64  `json:"max_ms"`
+	TotalMs   int64  `json:"total_ms"`
+}
+
+// AvgMs returns the mean run latency in milliseconds.
+func (s WatcherCheckStat) AvgMs() int64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.TotalMs / int64(s.Runs)
+}
+
+// WatcherChecksPath returns the file the watcher writes its check metrics to.
+func WatcherChecksPath(session string) string {
+	return filepath.Join(BusDir(session), "watcher-checks.json")
+}
+
+// WriteWatcherChecks saves check metrics atomically.
+func WriteWatcherChecks(session string, stats []WatcherCheckStat) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := WatcherChecksPath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, WatcherChecksPath(session))
+}
+
+// ReadWatcherChecks loads the metrics written by the running watcher.
+func ReadWatcherChecks(session string) ([]WatcherCheckStat, error) {
+	data, err := os.ReadFile(WatcherChecksPath(session))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no watcher check metrics (is the watcher running?)")
+		}
+		return nil, err
+	}
+	var stats []WatcherCheckStat
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Base(WatcherChecksPath(session)), err)
+	}
+	return stats, nil
+}
+
+// FormatWatcherChecks formats check metrics as a table.
+func FormatWatcherChecks(stats []WatcherCheckStat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %-9s %-6s %-6s %-7s %-7s %-9s %s\n", "CHECK", "INTERVAL", "RUNS", "ERRORS", "AVG", "MAX", "LAST RUN", "LAST ERROR")
+	for _, s := range stats {
+		interval := "poll"
+		if s.IntervalS > 0 {
+			interval = (time.Duration(s.IntervalS) * time.Second).String()
+		}
+		if s.Disabled {
+			fmt.Fprintf(&b, "%-12s %-9s disabled\n", s.Name, interval)
+			continue
+		}
+		lastRun := "—"
+		if s.LastRun > 0 {
+			lastRun = time.Unix(s.LastRun, 0).Format("15:04:05")
+		}
+		fmt.Fprintf(&b, "%-12s %-9s %-6d %-6d %-7s %-7s %-9s %s\n",
+			s.Name, interval, s.Runs, s.Errors,
+			fmt.Sprintf("%dms", s.AvgMs()), fmt.Sprintf("%dms", s.MaxMs), lastRun, s.LastError)
+	}
+	return b.String()
+}
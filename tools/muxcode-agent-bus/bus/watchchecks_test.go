@@ -0,0 +1,83 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     WatcherConfig
+		wantErr string
+	}{
+		{"empty", WatcherConfig{}, ""},
+		{"valid", WatcherConfig{Disable: []string{"archive"}, Intervals: map[string]string{"loops": "90s"}}, ""},
+		{"unknown disable", WatcherConfig{Disable: []string{"nope"}}, "unknown watcher check"},
+		{"unknown interval", WatcherConfig{Intervals: map[string]string{"nope": "1m"}}, "unknown watcher check"},
+		{"bad duration", WatcherConfig{Intervals: map[string]string{"sla": "soon"}}, "interval for sla"},
+		{"too short", WatcherConfig{Intervals: map[string]string{"sla": "500ms"}}, "at least 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatcherConfig_Interval(t *testing.T) {
+	c := WatcherConfig{Intervals: map[string]string{"loops": "2m", "sla": "bogus"}}
+	if got := c.Interval("loops", time.Minute); got != 2*time.Minute {
+		t.Errorf("loops = %v, want 2m", got)
+	}
+	if got := c.Interval("sla", time.Minute); got != time.Minute {
+		t.Errorf("sla = %v, want default 1m", got)
+	}
+	if got := c.Interval("budget", 30*time.Second); got != 30*time.Second {
+		t.Errorf("budget = %v, want default 30s", got)
+	}
+}
+
+func TestMergeConfigs_Watcher(t *testing.T) {
+	base := &MuxcodeConfig{Watcher: WatcherConfig{Disable: []string{"traces"}, Intervals: map[string]string{"loops": "2m", "sla": "5m"}}}
+	override := &MuxcodeConfig{Watcher: WatcherConfig{Disable: []string{"archive"}, Intervals: map[string]string{"loops": "30s"}}}
+	got := mergeConfigs(base, override).Watcher
+
+	if !got.Disabled("traces") || !got.Disabled("archive") {
+		t.Errorf("disable = %v, want traces and archive", got.Disable)
+	}
+	if got.Intervals["loops"] != "30s" || got.Intervals["sla"] != "5m" {
+		t.Errorf("intervals = %v", got.Intervals)
+	}
+}
+
+func TestFormatWatcherChecks(t *testing.T) {
+	out := FormatWatcherChecks([]WatcherCheckStat{
+		{Name: "inbox", Runs: 4, TotalMs: 8, MaxMs: 3, LastRun: time.Now().Unix()},
+		{Name: "loops", IntervalS: 60, Runs: 1, Errors: 1, LastError: "boom"},
+		{Name: "archive", IntervalS: 60, Disabled: true},
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("output:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "poll") || !strings.Contains(lines[1], "2ms") {
+		t.Errorf("inbox line = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "1m0s") || !strings.HasSuffix(lines[2], "boom") {
+		t.Errorf("loops line = %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "disabled") {
+		t.Errorf("archive line = %q", lines[3])
+	}
+}
@@ -19,6 +19,9 @@ type WebhookConfig struct {
 	Port    int
 	Token   string
 	Session string
+	Routes  map[string]WebhookRoute // POST /hook/{name} routes (nil = none)
+	Slack   SlackBridge             // Slack slash-command bridge (served when enabled)
+	Metrics *WebhookMetrics         // delivery counters (nil = not tracked)
 }
 
 // SendRequest is the JSON body for POST /send.
@@ -28,25 +31,38 @@ type SendRequest struct {
 	Payload string `json:"payload"`
 	Type    string `json:"type"`
 	ReplyTo string `json:"reply_to"`
+	// CallbackURL receives the result when the request completes
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 // WebhookResponse is the JSON response for all webhook endpoints.
 type WebhookResponse struct {
-	OK      bool   `json:"ok"`
-	ID      string `json:"id,omitempty"`
-	Error   string `json:"error,omitempty"`
-	Session string `json:"session,omitempty"`
-	Uptime  int64  `json:"uptime_seconds,omitempty"`
+	OK      bool     `json:"ok"`
+	ID      string   `json:"id,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Session string   `json:"session,omitempty"`
+	Uptime  int64    `json:"uptime_seconds,omitempty"`
+	IDs     []string `json:"ids,omitempty"`     // message IDs when a delivery fans out to several agents
+	Ignored bool     `json:"ignored,omitempty"` // delivery verified but matched no event rule
 }
 
 // ServeWebhook starts the HTTP server in the foreground.
 // It blocks until the context is cancelled or the server is shut down.
 func ServeWebhook(ctx context.Context, cfg WebhookConfig) error {
 	startTime := time.Now()
+	if cfg.Metrics == nil {
+		cfg.Metrics = NewWebhookMetrics(cfg.Session)
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/send", makeSendHandler(cfg, startTime))
 	mux.HandleFunc("/health", makeHealthHandler(cfg, startTime))
+	mux.HandleFunc("/metrics", makeMetricsHandler(cfg))
+	mux.HandleFunc("/hook/", makeRouteHandler(cfg, cfg.Routes))
+	if cfg.Slack.Enabled() {
+		mux.HandleFunc("/slack/commands", makeSlackHandler(cfg, false))
+		mux.HandleFunc("/slack/interactive", makeSlackHandler(cfg, true))
+	}
 
 	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
 	server := &http.Server{
@@ -67,6 +83,7 @@ func ServeWebhook(ctx context.Context, cfg WebhookConfig) error {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = server.Shutdown(shutdownCtx)
+		cfg.Metrics.Flush()
 		_ = os.Remove(WebhookPidPath(cfg.Session))
 	}()
 
@@ -91,8 +108,8 @@ func makeSendHandler(cfg WebhookConfig, startTime time.Time) http.HandlerFunc {
 
 		// Auth check
 		if cfg.Token != "" {
-			auth := r.Header.Get("Authorization")
-			if !strings.HasPrefix(auth, "Bearer ") || strings.TrimPrefix(auth, "Bearer ") != cfg.Token {
+			if !bearerOK(r, cfg.Token) {
+				cfg.Metrics.Record("send", RejectUnauthorized)
 				writeJSON(w, http.StatusUnauthorized, WebhookResponse{
 					OK:    false,
 					Error: "unauthorized",
@@ -151,8 +168,20 @@ func makeSendHandler(cfg WebhookConfig, startTime time.Time) http.HandlerFunc {
 			return
 		}
 
-		// Check send policy
-		if deny := CheckSendPolicy("webhook", req.To); deny != "" {
+		if req.CallbackURL != "" {
+			if err := CheckCallbackURL(req.CallbackURL); err != nil {
+				writeJSON(w, http.StatusBadRequest, WebhookResponse{
+					OK:    false,
+					Error: err.Error(),
+				})
+				return
+			}
+		}
+
+		// Create the message and check send policy
+		msg := NewMessage("webhook", req.To, req.Type, req.Action, req.Payload, req.ReplyTo)
+		msg.CallbackURL = req.CallbackURL
+		if deny := CheckMessagePolicy(cfg.Session, msg); deny != "" {
 			writeJSON(w, http.StatusForbidden, WebhookResponse{
 				OK:    false,
 				Error: deny,
@@ -160,8 +189,6 @@ func makeSendHandler(cfg WebhookConfig, startTime time.Time) http.HandlerFunc {
 			return
 		}
 
-		// Create and send message
-		msg := NewMessage("webhook", req.To, req.Type, req.Action, req.Payload, req.ReplyTo)
 		if err := Send(cfg.Session, msg); err != nil {
 			writeJSON(w, http.StatusInternalServerError, WebhookResponse{
 				OK:    false,
@@ -170,9 +197,15 @@ func makeSendHandler(cfg WebhookConfig, startTime time.Time) http.HandlerFunc {
 			return
 		}
 
+		_ = RecordAudit(cfg.Session, AuditEvent{Actor: "webhook", Op: AuditWebhook, Role: req.To, Args: map[string]string{
+			"id": msg.ID, "type": req.Type, "action": req.Action, "remote": r.RemoteAddr,
+		}})
+
 		// Notify target agent
 		_ = Notify(cfg.Session, req.To)
 
+		cfg.Metrics.Record("send", "")
+
 		writeJSON(w, http.StatusOK, WebhookResponse{
 			OK: true,
 			ID: msg.ID,
@@ -281,5 +314,9 @@ func WebhookStatus(session string) string {
 		return "Webhook: not running (stale PID file cleaned)"
 	}
 
-	return fmt.Sprintf("Webhook: running on 127.0.0.1:%d (PID %d)", port, pid)
+	status := fmt.Sprintf("Webhook: running on 127.0.0.1:%d (PID %d)", port, pid)
+	if stats, err := ReadWebhookStats(session); err == nil && len(stats.Routes) > 0 {
+		status += "\n" + strings.TrimSuffix(FormatWebhookStats(stats), "\n")
+	}
+	return status
 }
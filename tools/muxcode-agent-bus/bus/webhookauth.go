@@ -0,0 +1,480 @@
+package bus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook signature providers.
+const (
+	ProviderGitHub = "github" // X-Hub-Signature-256: sha256=<hex hmac of body>
+	ProviderGitLab = "gitlab" // X-Gitlab-Token: <secret>
+	ProviderStripe = "stripe" // Stripe-Signature: t=<ts>,v1=<hex hmac of "ts.body">
+	ProviderHMAC   = "hmac"   // X-Signature: [sha256=]<hex hmac of body>
+	ProviderSlack  = "slack"  // X-Slack-Signature: v0=<hex hmac of "v0:ts:body"> (see webhookslack.go)
+)
+
+// maxRouteBody caps route request bodies; provider payloads (e.g. GitHub
+// push events) are larger than /send requests.
+const maxRouteBody = 1 << 20
+
+// stripeTolerance is how far a Stripe-style signature timestamp may drift.
+const stripeTolerance = 5 * time.Minute
+
+// WebhookSettings is the "webhook" section of muxcode.json.
+type WebhookSettings struct {
+	Routes map[string]WebhookRoute `json:"routes,omitempty"` // served at POST /hook/{name}
+	Slack  SlackBridge             `json:"slack,omitempty"`  // served at POST /slack/commands and /slack/interactive
+}
+
+// WebhookRoute turns provider deliveries into bus messages. Deliveries are
+// verified against the route's secret before anything reaches the bus.
+type WebhookRoute struct {
+	Provider  string `json:"provider,omitempty"`   // github, gitlab, stripe, hmac, slack, or "" (bearer token only)
+	Secret    string `json:"secret,omitempty"`     // shared secret
+	SecretEnv string `json:"secret_env,omitempty"` // env var holding the secret (preferred over secret)
+	To        string `json:"to"`
+	Action    string `json:"action,omitempty"` // default "webhook-{name}"
+	Type      string `json:"type,omitempty"`   // default "event"
+
+	Events []WebhookEventRule `json:"events,omitempty"` // github only: per-event routing instead of forwarding the raw body
+}
+
+// ValidWebhookProvider reports whether p is a known signature provider.
+func ValidWebhookProvider(p string) bool {
+	switch p {
+	case "", ProviderGitHub, ProviderGitLab, ProviderStripe, ProviderHMAC, ProviderSlack:
+		return true
+	}
+	return false
+}
+
+// CheckWebhookRoutes validates route providers and targets. Routes whose
+// secret is missing are reported too — they would reject every delivery.
+func CheckWebhookRoutes(routes map[string]WebhookRoute) error {
+	var names []string
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := routes[name]
+		switch {
+		case !ValidWebhookProvider(r.Provider):
+			return fmt.Errorf("webhook route %s: unknown provider %q (want github, gitlab, stripe, hmac, or slack)", name, r.Provider)
+		case len(r.Events) > 0 && r.Provider != ProviderGitHub:
+			return fmt.Errorf("webhook route %s: events require provider github", name)
+		case r.To == "" && len(r.Events) == 0:
+			return fmt.Errorf("webhook route %s: missing \"to\"", name)
+		case r.Provider != "" && r.RouteSecret() == "":
+			return fmt.Errorf("webhook route %s: provider %s needs secret or secret_env", name, r.Provider)
+		}
+		for i, rule := range r.Events {
+			if rule.Event == "" {
+				return fmt.Errorf("webhook route %s: events[%d] missing \"event\"", name, i)
+			}
+			if rule.To == "" && r.To == "" {
+				return fmt.Errorf("webhook route %s: events[%d] (%s) has no \"to\" and the route has none", name, i, rule.Event)
+			}
+		}
+	}
+	return nil
+}
+
+// RouteSecret returns the route's shared secret, preferring SecretEnv.
+func (r WebhookRoute) RouteSecret() string {
+	if r.SecretEnv != "" {
+		if v := os.Getenv(r.SecretEnv); v != "" {
+			return v
+		}
+	}
+	return r.Secret
+}
+
+// Rejection reasons recorded in webhook metrics.
+const (
+	RejectMissingSignature = "missing-signature"
+	RejectBadSignature     = "bad-signature"
+	RejectStaleTimestamp   = "stale-timestamp"
+	RejectNoSecret         = "no-secret"
+	RejectUnauthorized     = "unauthorized"
+	RejectUnknownRoute     = "unknown-route"
+	RejectBadRequest       = "bad-request"
+)
+
+// VerifyWebhookSignature checks a delivery's signature for the provider.
+// Returns "" when valid, or a rejection reason.
+func VerifyWebhookSignature(provider, secret string, header http.Header, body []byte, now time.Time) string {
+	if provider == "" {
+		return ""
+	}
+	if secret == "" {
+		return RejectNoSecret
+	}
+
+	switch provider {
+	case ProviderGitHub:
+		sig := header.Get("X-Hub-Signature-256")
+		if sig == "" {
+			return RejectMissingSignature
+		}
+		if !strings.HasPrefix(sig, "sha256=") || !hmacEqual(secret, body, strings.TrimPrefix(sig, "sha256=")) {
+			return RejectBadSignature
+		}
+	case ProviderGitLab:
+		token := header.Get("X-Gitlab-Token")
+		if token == "" {
+			return RejectMissingSignature
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return RejectBadSignature
+		}
+	case ProviderStripe:
+		return verifyStripeSignature(secret, header.Get("Stripe-Signature"), body, now)
+	case ProviderSlack:
+		return VerifySlackSignature(secret, header, body, now)
+	case ProviderHMAC:
+		sig := header.Get("X-Signature")
+		if sig == "" {
+			return RejectMissingSignature
+		}
+		if !hmacEqual(secret, body, strings.TrimPrefix(sig, "sha256=")) {
+			return RejectBadSignature
+		}
+	default:
+		return RejectBadSignature
+	}
+	return ""
+}
+
+// verifyStripeSignature checks a "t=<unix>,v1=<hex>[,v1=<hex>]" header.
+// The signed payload is "<t>.<body>"; any matching v1 signature passes.
+func verifyStripeSignature(secret, sigHeader string, body []byte, now time.Time) string {
+	if sigHeader == "" {
+		return RejectMissingSignature
+	}
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(sigHeader, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return RejectBadSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return RejectBadSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > stripeTolerance || d < -stripeTolerance {
+		return RejectStaleTimestamp
+	}
+	signed := append([]byte(ts+"."), body...)
+	for _, s := range sigs {
+		if hmacEqual(secret, signed, s) {
+			return ""
+		}
+	}
+	return RejectBadSignature
+}
+
+// hmacEqual compares a hex HMAC-SHA256 signature in constant time.
+func hmacEqual(secret string, data []byte, hexSig string) bool {
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// SignWebhookBody returns the hex HMAC-SHA256 of body, as used by the
+// github and hmac providers.
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRouteStats counts outcomes for one route.
+type WebhookRouteStats struct {
+	Accepted int            `json:"accepted"`
+	Rejected map[string]int `json:"rejected,omitempty"` // reason -> count
+}
+
+// UnknownWebhookRoute is the metrics key all deliveries to undefined
+// routes are counted under, so callers cannot add keys by choosing paths.
+const UnknownWebhookRoute = "(unknown)"
+
+// webhookStatsInterval is the least time between webhook-stats.json writes.
+const webhookStatsInterval = 5 * time.Second
+
+// WebhookMetrics tracks accepted and rejected deliveries per route and
+// mirrors them to webhook-stats.json so `webhook status` can report them.
+// The file is rewritten at most once per webhookStatsInterval.
+type WebhookMetrics struct {
+	mu      sync.Mutex
+	session string
+	saved   time.Time                     // last write of webhook-stats.json
+	pending bool                          // a write is scheduled
+	Routes  map[string]*WebhookRouteStats `json:"routes"`
+	Updated int64                         `json:"updated"`
+}
+
+// NewWebhookMetrics returns empty metrics persisted for session ("" = memory only).
+func NewWebhookMetrics(session string) *WebhookMetrics {
+	return &WebhookMetrics{session: session, Routes: make(map[string]*WebhookRouteStats)}
+}
+
+// Record counts one delivery for route. An empty reason means accepted.
+// Safe to call on a nil receiver.
+func (m *WebhookMetrics) Record(route, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.Routes[route]
+	if s == nil {
+		s = &WebhookRouteStats{}
+		m.Routes[route] = s
+	}
+	if reason == "" {
+		s.Accepted++
+	} else {
+		if s.Rejected == nil {
+			s.Rejected = make(map[string]int)
+		}
+		s.Rejected[reason]++
+	}
+	m.Updated = time.Now().Unix()
+	if m.session == "" || m.pending {
+		return
+	}
+	m.pending = true
+	time.AfterFunc(max(webhookStatsInterval-time.Since(m.saved), 0), m.Flush)
+}
+
+// Flush writes the metrics to webhook-stats.json now. Safe to call on a
+// nil receiver.
+func (m *WebhookMetrics) Flush() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = false
+	if m.session == "" {
+		return
+	}
+	m.saved = time.Now()
+	if data, err := json.Marshal(m); err == nil {
+		_ = os.WriteFile(WebhookStatsPath(m.session), data, 0644)
+	}
+}
+
+// JSON returns the metrics as JSON.
+func (m *WebhookMetrics) JSON() []byte {
+	if m == nil {
+		return []byte(`{"routes":{}}`)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// ReadWebhookStats loads the persisted metrics for a session.
+func ReadWebhookStats(session string) (*WebhookMetrics, error) {
+	data, err := os.ReadFile(WebhookStatsPath(session))
+	if err != nil {
+		return nil, err
+	}
+	m := NewWebhookMetrics("")
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FormatWebhookStats renders per-route accepted/rejected counts.
+func FormatWebhookStats(m *WebhookMetrics) string {
+	if m == nil || len(m.Routes) == 0 {
+		return "No webhook deliveries recorded.\n"
+	}
+	var names []string
+	for name := range m.Routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %-9s %-9s %s\n", "ROUTE", "ACCEPTED", "REJECTED", "REASONS")
+	for _, name := range names {
+		s := m.Routes[name]
+		total := 0
+		var reasons []string
+		for r, n := range s.Rejected {
+			total += n
+			reasons = append(reasons, fmt.Sprintf("%s=%d", r, n))
+		}
+		sort.Strings(reasons)
+		fmt.Fprintf(&b, "%-16s %-9d %-9d %s\n", name, s.Accepted, total, strings.Join(reasons, " "))
+	}
+	return b.String()
+}
+
+// makeRouteHandler returns an http.HandlerFunc for POST /hook/{name}.
+func makeRouteHandler(cfg WebhookConfig, routes map[string]WebhookRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, WebhookResponse{
+				OK:    false,
+				Error: "method not allowed, use POST",
+			})
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/hook/")
+		route, ok := routes[name]
+		if !ok || name == "" {
+			cfg.Metrics.Record(UnknownWebhookRoute, RejectUnknownRoute)
+			writeJSON(w, http.StatusNotFound, WebhookResponse{
+				OK:    false,
+				Error: fmt.Sprintf("unknown route '%s'", name),
+			})
+			return
+		}
+
+		// Routes without a provider fall back to the server's bearer token
+		if route.Provider == "" && cfg.Token != "" && !bearerOK(r, cfg.Token) {
+			cfg.Metrics.Record(name, RejectUnauthorized)
+			writeJSON(w, http.StatusUnauthorized, WebhookResponse{
+				OK:    false,
+				Error: "unauthorized",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRouteBody))
+		if err != nil {
+			cfg.Metrics.Record(name, RejectBadRequest)
+			writeJSON(w, http.StatusBadRequest, WebhookResponse{
+				OK:    false,
+				Error: "reading body: " + err.Error(),
+			})
+			return
+		}
+
+		if reason := VerifyWebhookSignature(route.Provider, route.RouteSecret(), r.Header, body, time.Now()); reason != "" {
+			cfg.Metrics.Record(name, reason)
+			status := http.StatusUnauthorized
+			if reason == RejectNoSecret {
+				status = http.StatusInternalServerError
+			}
+			writeJSON(w, status, WebhookResponse{
+				OK:    false,
+				Error: "signature rejected: " + reason,
+			})
+			return
+		}
+
+		if len(route.Events) > 0 {
+			dispatchGitHubEvent(w, cfg, name, route, r.Header.Get("X-GitHub-Event"), body)
+			return
+		}
+
+		if !IsKnownRole(route.To) {
+			cfg.Metrics.Record(name, RejectBadRequest)
+			writeJSON(w, http.StatusInternalServerError, WebhookResponse{
+				OK:    false,
+				Error: fmt.Sprintf("route '%s' targets unknown role '%s'", name, route.To),
+			})
+			return
+		}
+		action := route.Action
+		if action == "" {
+			action = "webhook-" + name
+		}
+		msgType := route.Type
+		if msgType == "" {
+			msgType = "event"
+		}
+
+		msg := NewMessage("webhook", route.To, msgType, action, string(body), "")
+		if deny := CheckMessagePolicy(cfg.Session, msg); deny != "" {
+			cfg.Metrics.Record(name, RejectUnauthorized)
+			writeJSON(w, http.StatusForbidden, WebhookResponse{
+				OK:    false,
+				Error: deny,
+			})
+			return
+		}
+		if err := Send(cfg.Session, msg); err != nil {
+			writeJSON(w, http.StatusInternalServerError, WebhookResponse{
+				OK:    false,
+				Error: "send failed: " + err.Error(),
+			})
+			return
+		}
+		_ = Notify(cfg.Session, route.To)
+
+		cfg.Metrics.Record(name, "")
+		writeJSON(w, http.StatusOK, WebhookResponse{
+			OK: true,
+			ID: msg.ID,
+		})
+	}
+}
+
+// makeMetricsHandler returns an http.HandlerFunc for GET /metrics. Like
+// /send, it requires the server's bearer token when one is set.
+func makeMetricsHandler(cfg WebhookConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, WebhookResponse{
+				OK:    false,
+				Error: "method not allowed, use GET",
+			})
+			return
+		}
+		if cfg.Token != "" && !bearerOK(r, cfg.Token) {
+			writeJSON(w, http.StatusUnauthorized, WebhookResponse{
+				OK:    false,
+				Error: "unauthorized",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(cfg.Metrics.JSON())
+	}
+}
+
+// bearerOK reports whether the request carries the expected bearer token.
+func bearerOK(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	return strings.HasPrefix(auth, "Bearer ") &&
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
@@ -0,0 +1,214 @@
+package bus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := "s3cret"
+	body := []byte(`{"action":"opened"}`)
+	now := time.Unix(1740000000, 0)
+	sig := SignWebhookBody(secret, body)
+	stripeSig := func(ts int64) string {
+		signed := append([]byte(fmt.Sprintf("%d.", ts)), body...)
+		return fmt.Sprintf("t=%d,v1=%s", ts, SignWebhookBody(secret, signed))
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		secret   string
+		headers  map[string]string
+		want     string
+	}{
+		{"no provider", "", "", nil, ""},
+		{"no secret", ProviderGitHub, "", map[string]string{"X-Hub-Signature-256": "sha256=" + sig}, RejectNoSecret},
+		{"github ok", ProviderGitHub, secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sig}, ""},
+		{"github missing", ProviderGitHub, secret, nil, RejectMissingSignature},
+		{"github no prefix", ProviderGitHub, secret, map[string]string{"X-Hub-Signature-256": sig}, RejectBadSignature},
+		{"github wrong secret", ProviderGitHub, "other", map[string]string{"X-Hub-Signature-256": "sha256=" + sig}, RejectBadSignature},
+		{"gitlab ok", ProviderGitLab, secret, map[string]string{"X-Gitlab-Token": secret}, ""},
+		{"gitlab bad", ProviderGitLab, secret, map[string]string{"X-Gitlab-Token": "nope"}, RejectBadSignature},
+		{"hmac ok", ProviderHMAC, secret, map[string]string{"X-Signature": sig}, ""},
+		{"hmac prefixed", ProviderHMAC, secret, map[string]string{"X-Signature": "sha256=" + sig}, ""},
+		{"hmac not hex", ProviderHMAC, secret, map[string]string{"X-Signature": "zz"}, RejectBadSignature},
+		{"stripe ok", ProviderStripe, secret, map[string]string{"Stripe-Signature": stripeSig(now.Unix())}, ""},
+		{"stripe rotated", ProviderStripe, secret, map[string]string{"Stripe-Signature": stripeSig(now.Unix()) + ",v1=deadbeef"}, ""},
+		{"stripe stale", ProviderStripe, secret, map[string]string{"Stripe-Signature": stripeSig(now.Add(-10 * time.Minute).Unix())}, RejectStaleTimestamp},
+		{"stripe malformed", ProviderStripe, secret, map[string]string{"Stripe-Signature": "v1=abc"}, RejectBadSignature},
+		{"unknown provider", "bitbucket", secret, nil, RejectBadSignature},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := VerifyWebhookSignature(tt.provider, tt.secret, h, body, now); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookRouteHandler(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+	cfg.Metrics = NewWebhookMetrics(cfg.Session)
+
+	routes := map[string]WebhookRoute{
+		"github": {Provider: ProviderGitHub, Secret: "s3cret", To: "build", Action: "ci"},
+		"plain":  {To: "review"},
+	}
+	handler := makeRouteHandler(cfg, routes)
+	body := `{"ref":"refs/heads/main"}`
+
+	post := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := post("/hook/github", map[string]string{"X-Hub-Signature-256": "sha256=" + SignWebhookBody("s3cret", []byte(body))}); w.Code != http.StatusOK {
+		t.Fatalf("signed delivery: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w := post("/hook/github", map[string]string{"X-Hub-Signature-256": "sha256=" + SignWebhookBody("wrong", []byte(body))}); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", w.Code)
+	}
+	if w := post("/hook/github", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned: status %d, want 401", w.Code)
+	}
+	if w := post("/hook/missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown route: status %d, want 404", w.Code)
+	}
+	if w := post("/hook/plain", nil); w.Code != http.StatusOK {
+		t.Errorf("route without provider: status %d", w.Code)
+	}
+
+	msgs, _ := Peek(cfg.Session, "build")
+	if len(msgs) != 1 || msgs[0].Action != "ci" || msgs[0].Type != "event" || msgs[0].Payload != body {
+		t.Errorf("build inbox = %+v", msgs)
+	}
+	msgs, _ = Peek(cfg.Session, "review")
+	if len(msgs) != 1 || msgs[0].Action != "webhook-plain" {
+		t.Errorf("review inbox = %+v", msgs)
+	}
+
+	cfg.Metrics.Flush()
+	stats, err := ReadWebhookStats(cfg.Session)
+	if err != nil {
+		t.Fatalf("ReadWebhookStats: %v", err)
+	}
+	gh := stats.Routes["github"]
+	if gh == nil || gh.Accepted != 1 || gh.Rejected[RejectBadSignature] != 1 || gh.Rejected[RejectMissingSignature] != 1 {
+		t.Errorf("github stats = %+v", gh)
+	}
+	if stats.Routes[UnknownWebhookRoute].Rejected[RejectUnknownRoute] != 1 || stats.Routes["missing"] != nil {
+		t.Errorf("unknown route not counted under %s: %+v", UnknownWebhookRoute, stats.Routes)
+	}
+
+	out := FormatWebhookStats(stats)
+	if !strings.Contains(out, "bad-signature=1 missing-signature=1") {
+		t.Errorf("FormatWebhookStats missing reasons:\n%s", out)
+	}
+}
+
+func TestWebhookRouteHandler_BearerFallback(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+	cfg.Token = "tok"
+
+	handler := makeRouteHandler(cfg, map[string]WebhookRoute{"plain": {To: "build"}})
+	req := httptest.NewRequest(http.MethodPost, "/hook/plain", bytes.NewBufferString("hi"))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("no bearer: status %d, want 401", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hook/plain", bytes.NewBufferString("hi"))
+	req.Header.Set("Authorization", "Bearer tok")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("with bearer: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebhookMetricsHandler_Token(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+	cfg.Token = "tok"
+	cfg.Metrics = NewWebhookMetrics("")
+
+	handler := makeMetricsHandler(cfg)
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("no bearer: status %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"routes"`) {
+		t.Errorf("with bearer: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebhookMetrics_ThrottledWrites(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+	m := NewWebhookMetrics(cfg.Session)
+
+	m.Flush()
+	m.Record("github", "")
+	m.Record("github", RejectBadSignature)
+	stats, err := ReadWebhookStats(cfg.Session)
+	if err != nil {
+		t.Fatalf("ReadWebhookStats: %v", err)
+	}
+	if len(stats.Routes) != 0 {
+		t.Errorf("stats written within the interval: %+v", stats.Routes)
+	}
+
+	m.Flush()
+	stats, _ = ReadWebhookStats(cfg.Session)
+	if gh := stats.Routes["github"]; gh == nil || gh.Accepted != 1 || gh.Rejected[RejectBadSignature] != 1 {
+		t.Errorf("github stats after Flush = %+v", gh)
+	}
+}
+
+func TestCheckWebhookRoutes(t *testing.T) {
+	t.Setenv("MUXCODE_TEST_HOOK_SECRET", "from-env")
+
+	ok := map[string]WebhookRoute{
+		"gh":    {Provider: ProviderGitHub, SecretEnv: "MUXCODE_TEST_HOOK_SECRET", To: "build"},
+		"plain": {To: "review"},
+	}
+	if err := CheckWebhookRoutes(ok); err != nil {
+		t.Errorf("valid routes: %v", err)
+	}
+	if got := ok["gh"].RouteSecret(); got != "from-env" {
+		t.Errorf("RouteSecret = %q, want from-env", got)
+	}
+
+	for name, r := range map[string]WebhookRoute{
+		"provider": {Provider: "bitbucket", Secret: "x", To: "build"},
+		"to":       {Provider: ProviderGitLab, Secret: "x"},
+		"secret":   {Provider: ProviderStripe, To: "build"},
+	} {
+		if err := CheckWebhookRoutes(map[string]WebhookRoute{name: r}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WebhookEventRule maps a GitHub event to a bus message. Message templates
+// use ${var} placeholders filled from the event (see GitHubEvent.Vars).
+type WebhookEventRule struct {
+	Event    string   `json:"event"`              // X-GitHub-Event: push, pull_request, issue_comment, check_run, ...
+	Actions  []string `json:"actions,omitempty"`  // payload "action" values to match (empty = any)
+	Branches []string `json:"branches,omitempty"` // branches to match (empty = any)
+	To       string   `json:"to,omitempty"`       // default: the route's "to"
+	Action   string   `json:"action,omitempty"`   // default "github-{event}"
+	Type     string   `json:"type,omitempty"`     // default "event"
+	Message  string   `json:"message,omitempty"`  // default: a one-line summary of the event
+}
+
+// GitHubEvent holds the fields of a GitHub delivery used for routing and
+// message templating.
+type GitHubEvent struct {
+	Event      string
+	Action     string
+	Repo       string
+	Branch     string
+	Base       string
+	SHA        string
+	PR         int
+	Title      string
+	URL        string
+	Sender     string
+	Comment    string
+	Check      string
+	Status     string
+	Conclusion string
+	Commits    int
+	Merged     bool
+}
+
+// githubPayload is the subset of GitHub webhook payloads we read.
+type githubPayload struct {
+	Action     string                `json:"action"`
+	Ref        string                `json:"ref"`
+	After      string                `json:"after"`
+	Compare    string                `json:"compare"`
+	Commits    []struct{ ID string } `json:"commits"`
+	HeadCommit *struct {
+		Message string `json:"message"`
+	} `json:"head_commit"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+	PullRequest *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Merged  bool   `json:"merged"`
+		Head    struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Number      int       `json:"number"`
+		Title       string    `json:"title"`
+		HTMLURL     string    `json:"html_url"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment *struct {
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	} `json:"comment"`
+	CheckRun *struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		HeadSHA    string `json:"head_sha"`
+		HTMLURL    string `json:"html_url"`
+		CheckSuite struct {
+			HeadBranch string `json:"head_branch"`
+		} `json:"check_suite"`
+		PullRequests []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_run"`
+}
+
+// ParseGitHubEvent extracts routing fields from a GitHub delivery body.
+func ParseGitHubEvent(event string, body []byte) (GitHubEvent, error) {
+	var p githubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return GitHubEvent{}, fmt.Errorf("invalid GitHub payload: %v", err)
+	}
+
+	ev := GitHubEvent{
+		Event:  event,
+		Action: p.Action,
+		Repo:   p.Repository.FullName,
+		Sender: p.Sender.Login,
+	}
+
+	switch {
+	case p.PullRequest != nil:
+		ev.PR = p.PullRequest.Number
+		ev.Title = p.PullRequest.Title
+		ev.URL = p.PullRequest.HTMLURL
+		ev.Branch = p.PullRequest.Head.Ref
+		ev.SHA = p.PullRequest.Head.SHA
+		ev.Base = p.PullRequest.Base.Ref
+		ev.Merged = p.PullRequest.Merged
+	case p.Issue != nil:
+		ev.Title = p.Issue.Title
+		ev.URL = p.Issue.HTMLURL
+		if p.Issue.PullRequest != nil {
+			ev.PR = p.Issue.Number
+		}
+	case p.CheckRun != nil:
+		ev.Check = p.CheckRun.Name
+		ev.Status = p.CheckRun.Status
+		ev.Conclusion = p.CheckRun.Conclusion
+		ev.SHA = p.CheckRun.HeadSHA
+		ev.URL = p.CheckRun.HTMLURL
+		ev.Branch = p.CheckRun.CheckSuite.HeadBranch
+		if len(p.CheckRun.PullRequests) > 0 {
+			ev.PR = p.CheckRun.PullRequests[0].Number
+		}
+	case p.Ref != "":
+		ev.Branch = strings.TrimPrefix(p.Ref, "refs/heads/")
+		ev.SHA = p.After
+		ev.URL = p.Compare
+		ev.Commits = len(p.Commits)
+		if p.HeadCommit != nil {
+			ev.Title, _, _ = strings.Cut(p.HeadCommit.Message, "\n")
+		}
+	}
+	if p.Comment != nil {
+		ev.Comment = p.Comment.Body
+		ev.URL = p.Comment.HTMLURL
+	}
+	return ev, nil
+}
+
+// Vars returns the template variables for an event.
+func (ev GitHubEvent) Vars() map[string]string {
+	pr := ""
+	if ev.PR > 0 {
+		pr = strconv.Itoa(ev.PR)
+	}
+	sha := ev.SHA
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	return map[string]string{
+		"event":      ev.Event,
+		"action":     ev.Action,
+		"repo":       ev.Repo,
+		"branch":     ev.Branch,
+		"base":       ev.Base,
+		"sha":        sha,
+		"pr":         pr,
+		"title":      ev.Title,
+		"url":        ev.URL,
+		"sender":     ev.Sender,
+		"comment":    ev.Comment,
+		"check":      ev.Check,
+		"status":     ev.Status,
+		"conclusion": ev.Conclusion,
+		"commits":    strconv.Itoa(ev.Commits),
+		"merged":     strconv.FormatBool(ev.Merged),
+	}
+}
+
+// ExpandGitHubTemplate replaces ${var} placeholders with event fields.
+// Unknown placeholders are left as-is.
+func ExpandGitHubTemplate(template string, ev GitHubEvent) string {
+	vars := ev.Vars()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, "${"+k+"}", vars[k])
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// GitHubSummary is the default message for an event without a template.
+func GitHubSummary(ev GitHubEvent) string {
+	var s string
+	switch ev.Event {
+	case "push":
+		s = fmt.Sprintf("Push to %s/%s by %s: %d commit(s), head %s", ev.Repo, ev.Branch, ev.Sender, ev.Commits, ev.Title)
+	case "pull_request":
+		action := ev.Action
+		if action == "closed" && ev.Merged {
+			action = "merged"
+		}
+		s = fmt.Sprintf("PR #%d %s in %s by %s: %s (%s → %s)", ev.PR, action, ev.Repo, ev.Sender, ev.Title, ev.Branch, ev.Base)
+	case "issue_comment":
+		target := "issue"
+		if ev.PR > 0 {
+			target = fmt.Sprintf("PR #%d", ev.PR)
+		}
+		comment := ev.Comment
+		if len(comment) > 500 {
+			comment = comment[:500] + "..."
+		}
+		s = fmt.Sprintf("Comment %s on %s in %s by %s (%s):\n%s", ev.Action, target, ev.Repo, ev.Sender, ev.Title, comment)
+	case "check_run":
+		result := ev.Conclusion
+		if result == "" {
+			result = ev.Status
+		}
+		s = fmt.Sprintf("Check %q %s on %s/%s (%s)", ev.Check, result, ev.Repo, ev.Branch, ev.Vars()["sha"])
+	default:
+		s = fmt.Sprintf("GitHub %s %s in %s by %s", ev.Event, ev.Action, ev.Repo, ev.Sender)
+	}
+	if ev.URL != "" {
+		s += "\n" + ev.URL
+	}
+	return s
+}
+
+// Match reports whether the rule applies to the event.
+func (rule WebhookEventRule) Match(ev GitHubEvent) bool {
+	if rule.Event != ev.Event && rule.Event != "*" {
+		return false
+	}
+	if len(rule.Actions) > 0 && !containsRole(rule.Actions, ev.Action) {
+		return false
+	}
+	if len(rule.Branches) > 0 && !containsRole(rule.Branches, ev.Branch) {
+		return false
+	}
+	return true
+}
+
+// GitHubEventMessages builds the bus messages for every rule matching the
+// event. defaultTo is used for rules without their own target.
+func GitHubEventMessages(rules []WebhookEventRule, defaultTo string, ev GitHubEvent) []Message {
+	var msgs []Message
+	for _, rule := range rules {
+		if !rule.Match(ev) {
+			continue
+		}
+		to := rule.To
+		if to == "" {
+			to = defaultTo
+		}
+		action := rule.Action
+		if action == "" {
+			action = "github-" + ev.Event
+		}
+		msgType := rule.Type
+		if msgType == "" {
+			msgType = "event"
+		}
+		payload := GitHubSummary(ev)
+		if rule.Message != "" {
+			payload = ExpandGitHubTemplate(rule.Message, ev)
+		}
+		msgs = append(msgs, NewMessage("webhook", to, msgType, action, payload, ""))
+	}
+	return msgs
+}
+
+// dispatchGitHubEvent routes a verified GitHub delivery through the route's
+// event rules. Deliveries matching no rule (including "ping") are
+// acknowledged and ignored.
+func dispatchGitHubEvent(w http.ResponseWriter, cfg WebhookConfig, name string, route WebhookRoute, event string, body []byte) {
+	if event == "" {
+		cfg.Metrics.Record(name, RejectBadRequest)
+		writeJSON(w, http.StatusBadRequest, WebhookResponse{
+			OK:    false,
+			Error: "missing X-GitHub-Event header",
+		})
+		return
+	}
+	ev, err := ParseGitHubEvent(event, body)
+	if err != nil {
+		cfg.Metrics.Record(name, RejectBadRequest)
+		writeJSON(w, http.StatusBadRequest, WebhookResponse{
+			OK:    false,
+			Error: err.Error(),
+		})
+		return
+	}
+
+	msgs := GitHubEventMessages(route.Events, route.To, ev)
+	for _, msg := range msgs {
+		if !IsKnownRole(msg.To) {
+			writeJSON(w, http.StatusInternalServerError, WebhookResponse{
+				OK:    false,
+				Error: fmt.Sprintf("route '%s' targets unknown role '%s'", name, msg.To),
+			})
+			return
+		}
+		if deny := CheckMessagePolicy(cfg.Session, msg); deny != "" {
+			cfg.Metrics.Record(name, RejectUnauthorized)
+			writeJSON(w, http.StatusForbidden, WebhookResponse{
+				OK:    false,
+				Error: deny,
+			})
+			return
+		}
+	}
+
+	resp := WebhookResponse{OK: true, Ignored: len(msgs) == 0}
+	for _, msg := range msgs {
+		if err := Send(cfg.Session, msg); err != nil {
+			writeJSON(w, http.StatusInternalServerError, WebhookResponse{
+				OK:    false,
+				Error: "send failed: " + err.Error(),
+			})
+			return
+		}
+		_ = Notify(cfg.Session, msg.To)
+		resp.IDs = append(resp.IDs, msg.ID)
+	}
+	if len(resp.IDs) == 1 {
+		resp.ID, resp.IDs = resp.IDs[0], nil
+	}
+
+	cfg.Metrics.Record(name, "")
+	writeJSON(w, http.StatusOK, resp)
+}
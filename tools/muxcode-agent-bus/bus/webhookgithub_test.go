@@ -0,0 +1,144 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPRPayload = `{
+  "action": "opened",
+  "repository": {"full_name": "mkober/muxcode"},
+  "sender": {"login": "octocat"},
+  "pull_request": {
+    "number": 42, "title": "Add cron expressions", "html_url": "https://github.com/mkober/muxcode/pull/42",
+    "head": {"ref": "feature/cron", "sha": "abcdef1234567890"},
+    "base": {"ref": "main"}
+  }
+}`
+
+func TestParseGitHubEvent(t *testing.T) {
+	ev, err := ParseGitHubEvent("pull_request", []byte(testPRPayload))
+	if err != nil {
+		t.Fatalf("ParseGitHubEvent: %v", err)
+	}
+	if ev.PR != 42 || ev.Branch != "feature/cron" || ev.Base != "main" || ev.Repo != "mkober/muxcode" || ev.Sender != "octocat" {
+		t.Errorf("pull_request = %+v", ev)
+	}
+
+	push := `{"ref": "refs/heads/main", "after": "0123456789abcdef", "compare": "https://github.com/x/y/compare/a...b",
+	  "commits": [{"id": "a"}, {"id": "b"}], "head_commit": {"message": "Fix flaky test\n\nDetails"},
+	  "repository": {"full_name": "x/y"}, "sender": {"login": "dev"}}`
+	ev, _ = ParseGitHubEvent("push", []byte(push))
+	if ev.Branch != "main" || ev.Commits != 2 || ev.Title != "Fix flaky test" || ev.Vars()["sha"] != "0123456" {
+		t.Errorf("push = %+v", ev)
+	}
+
+	comment := `{"action": "created", "issue": {"number": 7, "title": "Bug", "pull_request": {}},
+	  "comment": {"body": "/review please", "html_url": "https://github.com/x/y/pull/7#c1"},
+	  "repository": {"full_name": "x/y"}, "sender": {"login": "dev"}}`
+	ev, _ = ParseGitHubEvent("issue_comment", []byte(comment))
+	if ev.PR != 7 || ev.Comment != "/review please" || !strings.HasSuffix(ev.URL, "#c1") {
+		t.Errorf("issue_comment = %+v", ev)
+	}
+
+	check := `{"action": "completed", "check_run": {"name": "ci", "status": "completed", "conclusion": "failure",
+	  "head_sha": "fedcba987", "check_suite": {"head_branch": "feature/x"}, "pull_requests": [{"number": 9}]},
+	  "repository": {"full_name": "x/y"}}`
+	ev, _ = ParseGitHubEvent("check_run", []byte(check))
+	if ev.Check != "ci" || ev.Conclusion != "failure" || ev.Branch != "feature/x" || ev.PR != 9 {
+		t.Errorf("check_run = %+v", ev)
+	}
+
+	if _, err := ParseGitHubEvent("push", []byte("not json")); err == nil {
+		t.Error("expected error for invalid payload")
+	}
+}
+
+func TestGitHubEventMessages(t *testing.T) {
+	ev, _ := ParseGitHubEvent("pull_request", []byte(testPRPayload))
+	rules := []WebhookEventRule{
+		{Event: "pull_request", Actions: []string{"opened", "reopened"}, To: "pr-read", Action: "pr-opened",
+			Message: "Review PR #${pr} in ${repo} (${branch} → ${base}) at ${sha}: ${title} ${unknown}"},
+		{Event: "pull_request", Actions: []string{"closed"}, To: "deploy"},
+		{Event: "pull_request", Branches: []string{"main"}},
+		{Event: "pull_request", Branches: []string{"feature/cron"}},
+		{Event: "push", To: "build"},
+	}
+
+	msgs := GitHubEventMessages(rules, "edit", ev)
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2: %+v", len(msgs), msgs)
+	}
+	want := "Review PR #42 in mkober/muxcode (feature/cron → main) at abcdef1: Add cron expressions ${unknown}"
+	if msgs[0].To != "pr-read" || msgs[0].Action != "pr-opened" || msgs[0].Payload != want {
+		t.Errorf("templated message = %+v", msgs[0])
+	}
+	if msgs[1].To != "edit" || msgs[1].Action != "github-pull_request" || msgs[1].Type != "event" {
+		t.Errorf("default message = %+v", msgs[1])
+	}
+	if !strings.HasPrefix(msgs[1].Payload, "PR #42 opened in mkober/muxcode by octocat") {
+		t.Errorf("summary payload = %q", msgs[1].Payload)
+	}
+}
+
+func TestWebhookRouteHandler_GitHubEvents(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+
+	routes := map[string]WebhookRoute{
+		"github": {Provider: ProviderGitHub, Secret: "s3cret", To: "edit", Events: []WebhookEventRule{
+			{Event: "pull_request", Actions: []string{"opened"}, To: "review"},
+			{Event: "pull_request", To: "edit"},
+		}},
+	}
+	handler := makeRouteHandler(cfg, routes)
+
+	deliver := func(event, body string) (*httptest.ResponseRecorder, WebhookResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/hook/github", bytes.NewBufferString(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+SignWebhookBody("s3cret", []byte(body)))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		var resp WebhookResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := deliver("pull_request", testPRPayload)
+	if w.Code != http.StatusOK || len(resp.IDs) != 2 {
+		t.Fatalf("pull_request: status %d, resp %+v", w.Code, resp)
+	}
+	if msgs, _ := Peek(cfg.Session, "review"); len(msgs) != 1 || msgs[0].Action != "github-pull_request" {
+		t.Errorf("review inbox = %+v", msgs)
+	}
+
+	w, resp = deliver("ping", `{"zen": "Keep it logically awesome."}`)
+	if w.Code != http.StatusOK || !resp.Ignored {
+		t.Errorf("ping: status %d, resp %+v", w.Code, resp)
+	}
+
+	w, _ = deliver("", testPRPayload)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing event header: status %d, want 400", w.Code)
+	}
+}
+
+func TestCheckWebhookRoutes_Events(t *testing.T) {
+	ok := map[string]WebhookRoute{"gh": {Provider: ProviderGitHub, Secret: "x", Events: []WebhookEventRule{{Event: "push", To: "build"}}}}
+	if err := CheckWebhookRoutes(ok); err != nil {
+		t.Errorf("events without route to: %v", err)
+	}
+	for name, r := range map[string]WebhookRoute{
+		"provider": {Provider: ProviderGitLab, Secret: "x", To: "build", Events: []WebhookEventRule{{Event: "push"}}},
+		"event":    {Provider: ProviderGitHub, Secret: "x", To: "build", Events: []WebhookEventRule{{To: "build"}}},
+		"to":       {Provider: ProviderGitHub, Secret: "x", Events: []WebhookEventRule{{Event: "push"}}},
+	} {
+		if err := CheckWebhookRoutes(map[string]WebhookRoute{name: r}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
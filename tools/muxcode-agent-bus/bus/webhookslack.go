@@ -0,0 +1,393 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlackSigningSecretEnv is the default environment variable holding the
+// Slack app's signing secret for the slash-command bridge.
+const SlackSigningSecretEnv = "MUXCODE_SLACK_SIGNING_SECRET"
+
+// slackTolerance is how far a Slack request timestamp may drift.
+const slackTolerance = 5 * time.Minute
+
+// SlackBridge is the "webhook.slack" section of muxcode.json. When a
+// signing secret is available the webhook server accepts Slack slash
+// commands at /slack/commands and interactive payloads at /slack/interactive.
+type SlackBridge struct {
+	SigningSecret    string                  `json:"signing_secret,omitempty"`
+	SigningSecretEnv string                  `json:"signing_secret_env,omitempty"` // default MUXCODE_SLACK_SIGNING_SECRET
+	Commands         map[string]SlackCommand `json:"commands,omitempty"`           // first word of the text -> bus send
+	Users            []string                `json:"users,omitempty"`              // Slack user IDs allowed to send (empty = any)
+}
+
+// SlackCommand maps a slash-command word (e.g. "build" in "/muxcode build")
+// to a bus send. Words without a mapping that name a known role send to
+// that role with the word as the action.
+type SlackCommand struct {
+	To      string `json:"to"`
+	Action  string `json:"action,omitempty"`  // default: the command word
+	Type    string `json:"type,omitempty"`    // default "request"
+	Message string `json:"message,omitempty"` // payload when the command has no text
+}
+
+// Secret returns the signing secret, preferring the environment.
+func (s SlackBridge) Secret() string {
+	env := s.SigningSecretEnv
+	if env == "" {
+		env = SlackSigningSecretEnv
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return s.SigningSecret
+}
+
+// Enabled reports whether the Slack endpoints should be served.
+func (s SlackBridge) Enabled() bool {
+	return s.Secret() != "" || len(s.Commands) > 0
+}
+
+// Validate checks command targets. A bridge with commands but no signing
+// secret is an error — every request would be rejected.
+func (s SlackBridge) Validate() error {
+	var words []string
+	for w := range s.Commands {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	for _, w := range words {
+		c := s.Commands[w]
+		if strings.ContainsAny(w, " \t") || w == "" {
+			return fmt.Errorf("slack command %q: must be a single word", w)
+		}
+		if c.To == "" {
+			return fmt.Errorf("slack command %s: missing \"to\"", w)
+		}
+		if c.Type != "" && !containsRole(MessageTypes, c.Type) {
+			return fmt.Errorf("slack command %s: unknown type %q", w, c.Type)
+		}
+	}
+	if len(s.Commands) > 0 && s.Secret() == "" {
+		return fmt.Errorf("slack: commands need signing_secret or %s", s.secretEnvName())
+	}
+	return nil
+}
+
+func (s SlackBridge) secretEnvName() string {
+	if s.SigningSecretEnv != "" {
+		return s.SigningSecretEnv
+	}
+	return SlackSigningSecretEnv
+}
+
+// VerifySlackSignature checks Slack's request signing: X-Slack-Signature is
+// "v0=" + hex HMAC-SHA256 of "v0:{X-Slack-Request-Timestamp}:{body}", and
+// the timestamp must be within 5 minutes. Returns "" when valid, or a
+// rejection reason.
+func VerifySlackSignature(secret string, header http.Header, body []byte, now time.Time) string {
+	if secret == "" {
+		return RejectNoSecret
+	}
+	sig := header.Get("X-Slack-Signature")
+	ts := header.Get("X-Slack-Request-Timestamp")
+	if sig == "" || ts == "" {
+		return RejectMissingSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return RejectBadSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > slackTolerance || d < -slackTolerance {
+		return RejectStaleTimestamp
+	}
+	if !strings.HasPrefix(sig, "v0=") {
+		return RejectBadSignature
+	}
+	signed := append([]byte("v0:"+ts+":"), body...)
+	if !hmacEqual(secret, signed, strings.TrimPrefix(sig, "v0=")) {
+		return RejectBadSignature
+	}
+	return ""
+}
+
+// SlackRequest is a slash command or interactive action reduced to what
+// the bridge needs.
+type SlackRequest struct {
+	UserID      string
+	UserName    string
+	ChannelID   string
+	Text        string // command text, e.g. "build" or "review check the auth change"
+	ResponseURL string
+}
+
+// ParseSlackCommand reads a slash-command form body.
+func ParseSlackCommand(body []byte) (SlackRequest, error) {
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SlackRequest{}, fmt.Errorf("invalid form body: %v", err)
+	}
+	return SlackRequest{
+		UserID:      v.Get("user_id"),
+		UserName:    v.Get("user_name"),
+		ChannelID:   v.Get("channel_id"),
+		Text:        strings.TrimSpace(v.Get("text")),
+		ResponseURL: v.Get("response_url"),
+	}, nil
+}
+
+// slackInteraction is the part of a Slack block_actions payload the bridge
+// reads. Button values carry command text, like a slash command's.
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		Value string `json:"value"`
+	} `json:"actions"`
+}
+
+// ParseSlackInteraction reads an interactive payload ("payload=" form field
+// holding JSON). Only block_actions with a value are supported; the first
+// action's value is the command text.
+func ParseSlackInteraction(body []byte) (SlackRequest, error) {
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SlackRequest{}, fmt.Errorf("invalid form body: %v", err)
+	}
+	var p slackInteraction
+	if err := json.Unmarshal([]byte(v.Get("payload")), &p); err != nil {
+		return SlackRequest{}, fmt.Errorf("invalid payload: %v", err)
+	}
+	if p.Type != "block_actions" || len(p.Actions) == 0 || strings.TrimSpace(p.Actions[0].Value) == "" {
+		return SlackRequest{}, fmt.Errorf("unsupported interaction %q", p.Type)
+	}
+	return SlackRequest{
+		UserID:      p.User.ID,
+		UserName:    p.User.Username,
+		ChannelID:   p.Channel.ID,
+		Text:        strings.TrimSpace(p.Actions[0].Value),
+		ResponseURL: p.ResponseURL,
+	}, nil
+}
+
+// SlackMessage maps a Slack request to a bus message: the first word picks
+// the command (or names the target role), the rest is the payload. The
+// message carries the request's response_url as its callback URL, so the
+// chain outcome is posted back to the channel.
+func (s SlackBridge) SlackMessage(req SlackRequest) (Message, error) {
+	word, rest, _ := strings.Cut(req.Text, " ")
+	rest = strings.TrimSpace(rest)
+
+	cmd, ok := s.Commands[word]
+	if !ok {
+		if !IsKnownRole(word) {
+			return Message{}, fmt.Errorf("unknown command %q", word)
+		}
+		cmd = SlackCommand{To: word}
+	}
+	if !IsKnownRole(cmd.To) {
+		return Message{}, fmt.Errorf("command %s targets unknown role '%s'", word, cmd.To)
+	}
+	action := cmd.Action
+	if action == "" {
+		action = word
+	}
+	msgType := cmd.Type
+	if msgType == "" {
+		msgType = "request"
+	}
+	payload := rest
+	if payload == "" {
+		payload = cmd.Message
+	}
+	if payload == "" {
+		payload = action + " requested from Slack"
+	}
+	if req.UserName != "" {
+		payload += " (Slack @" + req.UserName + ")"
+	}
+
+	msg := NewMessage("webhook", cmd.To, msgType, action, payload, "")
+	if checkWebhookURL(req.ResponseURL) == nil {
+		msg.CallbackURL = req.ResponseURL
+	}
+	return msg, nil
+}
+
+// SlackHelp lists the commands the bridge accepts.
+func (s SlackBridge) SlackHelp() string {
+	var words []string
+	for w := range s.Commands {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	var b strings.Builder
+	b.WriteString("Usage: /muxcode <command> [text]\n")
+	for _, w := range words {
+		c := s.Commands[w]
+		fmt.Fprintf(&b, "• `%s` → %s\n", w, c.To)
+	}
+	fmt.Fprintf(&b, "• `<role> [text]` → that role (%s)\n", strings.Join(KnownRoles, ", "))
+	return b.String()
+}
+
+// slackReply is a Slack response body for a slash command, interaction,
+// or response_url POST.
+type slackReply struct {
+	ResponseType    string `json:"response_type"` // in_channel or ephemeral
+	Text            string `json:"text"`
+	ReplaceOriginal bool   `json:"replace_original"`
+}
+
+// slackCallbackBody builds the response_url message for a finished
+// callback: the chain outcome, posted in-channel.
+func slackCallbackBody(p CallbackPayload) slackReply {
+	text := fmt.Sprintf(":hourglass: No result for `%s` from %s within the callback window", p.Action, p.To)
+	if p.Result != nil {
+		icon := ":white_check_mark:"
+		if p.Result.Type == "event" {
+			icon = ":bell:"
+		}
+		text = fmt.Sprintf("%s *%s* from %s: %s", icon, p.Result.Action, p.Result.From, p.Result.Payload)
+	}
+	return slackReply{ResponseType: "in_channel", Text: text}
+}
+
+// makeSlackHandler returns an http.HandlerFunc for POST /slack/commands
+// (interactive = false) or /slack/interactive. Requests are verified with
+// the Slack signing secret, mapped to a bus send, and acknowledged
+// in-channel; the chain outcome follows via the response_url callback.
+func makeSlackHandler(cfg WebhookConfig, interactive bool) http.HandlerFunc {
+	route := "slack-commands"
+	if interactive {
+		route = "slack-interactive"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, WebhookResponse{
+				OK:    false,
+				Error: "method not allowed, use POST",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRouteBody))
+		if err != nil {
+			cfg.Metrics.Record(route, RejectBadRequest)
+			writeJSON(w, http.StatusBadRequest, WebhookResponse{
+				OK:    false,
+				Error: "reading body: " + err.Error(),
+			})
+			return
+		}
+
+		if reason := VerifySlackSignature(cfg.Slack.Secret(), r.Header, body, time.Now()); reason != "" {
+			cfg.Metrics.Record(route, reason)
+			status := http.StatusUnauthorized
+			if reason == RejectNoSecret {
+				status = http.StatusInternalServerError
+			}
+			writeJSON(w, status, WebhookResponse{
+				OK:    false,
+				Error: "signature rejected: " + reason,
+			})
+			return
+		}
+
+		parse := ParseSlackCommand
+		if interactive {
+			parse = ParseSlackInteraction
+		}
+		req, err := parse(body)
+		if err != nil {
+			cfg.Metrics.Record(route, RejectBadRequest)
+			writeJSON(w, http.StatusBadRequest, WebhookResponse{
+				OK:    false,
+				Error: err.Error(),
+			})
+			return
+		}
+
+		// Slack shows replies to the user; errors go back as ephemeral messages
+		ephemeral := func(text string) {
+			writeJSON(w, http.StatusOK, slackReply{ResponseType: "ephemeral", Text: text})
+		}
+		if len(cfg.Slack.Users) > 0 && !containsRole(cfg.Slack.Users, req.UserID) {
+			cfg.Metrics.Record(route, RejectUnauthorized)
+			ephemeral("You are not allowed to send muxcode commands.")
+			return
+		}
+		if req.Text == "" || req.Text == "help" {
+			cfg.Metrics.Record(route, "")
+			ephemeral(cfg.Slack.SlackHelp())
+			return
+		}
+
+		msg, err := cfg.Slack.SlackMessage(req)
+		if err != nil {
+			cfg.Metrics.Record(route, RejectBadRequest)
+			ephemeral(err.Error() + "\n" + cfg.Slack.SlackHelp())
+			return
+		}
+		if deny := CheckMessagePolicy(cfg.Session, msg); deny != "" {
+			cfg.Metrics.Record(route, RejectUnauthorized)
+			ephemeral(deny)
+			return
+		}
+		if err := Send(cfg.Session, msg); err != nil {
+			ephemeral("send failed: " + err.Error())
+			return
+		}
+
+		_ = RecordAudit(cfg.Session, AuditEvent{Actor: "webhook", Op: AuditWebhook, Role: msg.To, Args: map[string]string{
+			"id": msg.ID, "type": msg.Type, "action": msg.Action, "slack_user": req.UserID, "channel": req.ChannelID,
+		}})
+		_ = Notify(cfg.Session, msg.To)
+
+		cfg.Metrics.Record(route, "")
+		writeJSON(w, http.StatusOK, slackReply{
+			ResponseType: "in_channel",
+			Text:         fmt.Sprintf(":inbox_tray: Sent `%s` to %s: %s", msg.Action, msg.To, msg.Payload),
+		})
+	}
+}
+
+// FormatSlackBridge describes the bridge for `webhook slack`: whether it is
+// enabled, the endpoints to configure in the Slack app, and the commands.
+func FormatSlackBridge(s SlackBridge, host string, port int) string {
+	var b strings.Builder
+	if !s.Enabled() {
+		fmt.Fprintf(&b, "Slack bridge: disabled (set %s or webhook.slack.signing_secret)\n", s.secretEnvName())
+		return b.String()
+	}
+	secret := "set"
+	if s.Secret() == "" {
+		secret = "MISSING (" + s.secretEnvName() + ")"
+	}
+	fmt.Fprintf(&b, "Slack bridge: enabled (signing secret %s)\n", secret)
+	base := fmt.Sprintf("http://%s:%d", host, port)
+	fmt.Fprintf(&b, "  Slash command URL: %s/slack/commands\n", base)
+	fmt.Fprintf(&b, "  Interactivity URL: %s/slack/interactive\n", base)
+	if len(s.Users) > 0 {
+		fmt.Fprintf(&b, "  Allowed users: %s\n", strings.Join(s.Users, ", "))
+	}
+	b.WriteString("\n")
+	b.WriteString(s.SlackHelp())
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slackHeaders signs body as Slack would at ts.
+func slackHeaders(secret string, ts time.Time, body string) http.Header {
+	h := http.Header{}
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	h.Set("X-Slack-Request-Timestamp", stamp)
+	h.Set("X-Slack-Signature", "v0="+SignWebhookBody(secret, []byte("v0:"+stamp+":"+body)))
+	return h
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Now()
+	body := "command=%2Fmuxcode&text=build"
+
+	if r := VerifySlackSignature("s3cret", slackHeaders("s3cret", now, body), []byte(body), now); r != "" {
+		t.Errorf("valid signature rejected: %s", r)
+	}
+	if r := VerifySlackSignature("s3cret", slackHeaders("wrong", now, body), []byte(body), now); r != RejectBadSignature {
+		t.Errorf("wrong secret = %q, want bad-signature", r)
+	}
+	if r := VerifySlackSignature("s3cret", slackHeaders("s3cret", now.Add(-10*time.Minute), body), []byte(body), now); r != RejectStaleTimestamp {
+		t.Errorf("old timestamp = %q, want stale-timestamp", r)
+	}
+	if r := VerifySlackSignature("s3cret", http.Header{}, []byte(body), now); r != RejectMissingSignature {
+		t.Errorf("unsigned = %q, want missing-signature", r)
+	}
+	if r := VerifySlackSignature("", slackHeaders("s3cret", now, body), []byte(body), now); r != RejectNoSecret {
+		t.Errorf("no secret = %q, want no-secret", r)
+	}
+	// Routes can use the slack provider too
+	if r := VerifyWebhookSignature(ProviderSlack, "s3cret", slackHeaders("s3cret", now, body), []byte(body), now); r != "" {
+		t.Errorf("slack provider rejected a valid signature: %s", r)
+	}
+}
+
+func TestParseSlackInteraction(t *testing.T) {
+	payload := `{"type":"block_actions","user":{"id":"U1","username":"alice"},"channel":{"id":"C1"},` +
+		`"response_url":"https://hooks.slack.com/actions/T/1/x","actions":[{"value":"review the auth change"}]}`
+	req, err := ParseSlackInteraction([]byte(url.Values{"payload": {payload}}.Encode()))
+	if err != nil {
+		t.Fatalf("ParseSlackInteraction: %v", err)
+	}
+	if req.UserID != "U1" || req.UserName != "alice" || req.Text != "review the auth change" || req.ResponseURL == "" {
+		t.Errorf("request = %+v", req)
+	}
+	if _, err := ParseSlackInteraction([]byte(url.Values{"payload": {`{"type":"view_submission"}`}}.Encode())); err == nil {
+		t.Error("expected error for unsupported interaction")
+	}
+}
+
+func TestSlackBridge_SlackMessage(t *testing.T) {
+	s := SlackBridge{Commands: map[string]SlackCommand{
+		"ship": {To: "deploy", Action: "deploy", Message: "Deploy the current build"},
+	}}
+	respURL := "https://hooks.slack.com/commands/T/1/x"
+
+	msg, err := s.SlackMessage(SlackRequest{UserName: "alice", Text: "ship", ResponseURL: respURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "deploy" || msg.Action != "deploy" || msg.Type != "request" || msg.From != "webhook" {
+		t.Errorf("mapped command = %+v", msg)
+	}
+	if msg.Payload != "Deploy the current build (Slack @alice)" || msg.CallbackURL != respURL {
+		t.Errorf("payload %q, callback %q", msg.Payload, msg.CallbackURL)
+	}
+
+	msg, err = s.SlackMessage(SlackRequest{Text: "build  with race detector"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "build" || msg.Action != "build" || msg.Payload != "with race detector" || msg.CallbackURL != "" {
+		t.Errorf("role command = %+v", msg)
+	}
+
+	if _, err := s.SlackMessage(SlackRequest{Text: "dance"}); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+func TestSlackBridge_Validate(t *testing.T) {
+	t.Setenv(SlackSigningSecretEnv, "")
+	ok := SlackBridge{SigningSecret: "x", Commands: map[string]SlackCommand{"ship": {To: "deploy"}}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, bad := range []SlackBridge{
+		{Commands: map[string]SlackCommand{"ship": {To: "deploy"}}},         // no secret
+		{SigningSecret: "x", Commands: map[string]SlackCommand{"ship": {}}}, // no target
+		{SigningSecret: "x", Commands: map[string]SlackCommand{"two words": {To: "build"}}},
+		{SigningSecret: "x", Commands: map[string]SlackCommand{"ship": {To: "deploy", Type: "shout"}}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestSlackCommandHandler(t *testing.T) {
+	cfg, cleanup := setupWebhookTest(t)
+	defer cleanup()
+	cfg.Metrics = NewWebhookMetrics(cfg.Session)
+	t.Setenv(SlackSigningSecretEnv, "")
+	cfg.Slack = SlackBridge{SigningSecret: "s3cret", Users: []string{"U1"}}
+	handler := makeSlackHandler(cfg, false)
+
+	post := func(form url.Values, secret string) *httptest.ResponseRecorder {
+		body := form.Encode()
+		req := httptest.NewRequest(http.MethodPost, "/slack/commands", bytes.NewBufferString(body))
+		for k, v := range slackHeaders(secret, time.Now(), body) {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	reply := func(w *httptest.ResponseRecorder) slackReply {
+		var r slackReply
+		_ = json.Unmarshal(w.Body.Bytes(), &r)
+		return r
+	}
+
+	form := url.Values{
+		"command": {"/muxcode"}, "text": {"build"}, "user_id": {"U1"}, "user_name": {"alice"},
+		"channel_id": {"C1"}, "response_url": {"https://hooks.slack.com/commands/T/1/x"},
+	}
+	w := post(form, "s3cret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed command: status %d, body %s", w.Code, w.Body.String())
+	}
+	if r := reply(w); r.ResponseType != "in_channel" || !strings.Contains(r.Text, "build") {
+		t.Errorf("reply = %+v", r)
+	}
+	msgs, _ := Peek(cfg.Session, "build")
+	if len(msgs) != 1 || msgs[0].Action != "build" || msgs[0].CallbackURL == "" {
+		t.Fatalf("build inbox = %+v", msgs)
+	}
+	if cbs, _ := ReadCallbacks(cfg.Session); len(cbs) != 1 || cbs[0].ID != msgs[0].ID {
+		t.Errorf("callbacks = %+v, want one for the sent message", cbs)
+	}
+
+	if w := post(form, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", w.Code)
+	}
+
+	form.Set("user_id", "U2")
+	if r := reply(post(form, "s3cret")); r.ResponseType != "ephemeral" || !strings.Contains(r.Text, "not allowed") {
+		t.Errorf("disallowed user reply = %+v", r)
+	}
+
+	form.Set("user_id", "U1")
+	form.Set("text", "help")
+	if r := reply(post(form, "s3cret")); r.ResponseType != "ephemeral" || !strings.Contains(r.Text, "Usage: /muxcode") {
+		t.Errorf("help reply = %+v", r)
+	}
+	if msgs, _ := Peek(cfg.Session, "build"); len(msgs) != 1 {
+		t.Errorf("rejected requests reached the bus: %+v", msgs)
+	}
+}
+
+func TestSlackCallbackBody(t *testing.T) {
+	done := slackCallbackBody(CallbackPayload{To: "build", Action: "build", Result: &CallbackResult{
+		From: "review", Type: "response", Action: "review-complete", Payload: "LGTM",
+	}})
+	if done.ResponseType != "in_channel" || !strings.Contains(done.Text, "review-complete") || !strings.Contains(done.Text, "LGTM") {
+		t.Errorf("completed body = %+v", done)
+	}
+	expired := slackCallbackBody(CallbackPayload{To: "build", Action: "build", Status: "expired"})
+	if !strings.Contains(expired.Text, "No result") {
+		t.Errorf("expired body = %+v", expired)
+	}
+}
@@ -0,0 +1,462 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Workflow transition targets that end a run instead of naming a step.
+const (
+	WorkflowEnd  = "end"  // finish the run as succeeded
+	WorkflowFail = "fail" // finish the run as failed
+)
+
+// defaultWorkflowTimeout is how long a step waits for its reply.
+const defaultWorkflowTimeout = time.Hour
+
+// defaultWorkflowFailMatch classifies a reply payload as a failure, matching
+// agent replies such as "Build failed: ..." or "Test failure in ...".
+const defaultWorkflowFailMatch = `(?i)\bfail(ed|ure|s)?\b`
+
+// workflowStepID restricts step IDs to names usable in ${steps.ID.result}.
+var workflowStepID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// WorkflowDir returns the project-local workflow directory.
+// Uses BUS_WORKFLOW_DIR env if set, otherwise defaults to ".muxcode/workflows".
+func WorkflowDir() string {
+	if v := os.Getenv("BUS_WORKFLOW_DIR"); v != "" {
+		return v
+	}
+	return filepath.Join(".muxcode", "workflows")
+}
+
+// Workflow is a declarative multi-agent pipeline loaded from a YAML or JSON
+// file. Each step sends a request to a role and waits for the reply
+// (reply_to set to the request's ID); the reply's outcome picks the next
+// step. A step with parallel lists member steps that are sent together and
+// succeed when all members succeed. An approval step waits for approve or
+// reject instead of a reply.
+type Workflow struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Vars        map[string]interface{} `json:"vars,omitempty"` // defaults for ${name}; run --var overrides
+	Steps       []WorkflowStep         `json:"steps"`
+}
+
+// WorkflowStep is one step. Task steps set role and action; group steps
+// set parallel instead. Approval steps (type approval) ask role, default
+// edit, to approve with payload as the prompt.
+type WorkflowStep struct {
+	ID      string `json:"id"`
+	Role    string `json:"role,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Type    string `json:"type,omitempty"`    // message type (default "request"), or "approval"
+	Payload string `json:"payload,omitempty"` // template: ${var}, ${run}, ${workflow}, ${steps.ID.result}, ${steps.ID.outcome}
+	// Parallel lists the member step IDs of a group step. Members run only
+	// as part of their group, and their own transitions are not used.
+	Parallel  []string `json:"parallel,omitempty"`
+	OnSuccess string   `json:"on_success,omitempty"` // default: the next top-level step, or end
+	OnFailure string   `json:"on_failure,omitempty"` // default: fail
+	Timeout   string   `json:"timeout,omitempty"`    // Go duration to wait for the reply (default 1h)
+	FailMatch string   `json:"fail_match,omitempty"` // regex marking a reply as failed (default: fail/failed/failure)
+	// Approval steps only (see ApprovalGate); timeout defaults to 24h.
+	EscalateAfter string `json:"escalate_after,omitempty"`
+	EscalateTo    string `json:"escalate_to,omitempty"`
+	When          string `json:"when,omitempty"`
+}
+
+// IsGroup reports whether the step is a parallel group.
+func (s WorkflowStep) IsGroup() bool {
+	return len(s.Parallel) > 0
+}
+
+// IsApproval reports whether the step is an approval gate.
+func (s WorkflowStep) IsApproval() bool {
+	return s.Type == "approval"
+}
+
+// gate returns an approval step's gate settings.
+func (s WorkflowStep) gate() ApprovalGate {
+	return ApprovalGate{
+		Approver:      s.Role,
+		Timeout:       s.Timeout,
+		EscalateAfter: s.EscalateAfter,
+		EscalateTo:    s.EscalateTo,
+		When:          s.When,
+	}
+}
+
+// TimeoutDuration returns the reply timeout, defaulting to 1h.
+func (s WorkflowStep) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(s.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultWorkflowTimeout
+}
+
+// Failed reports whether a reply payload counts as a failure.
+func (s WorkflowStep) Failed(payload string) bool {
+	pattern := s.FailMatch
+	if pattern == "" {
+		pattern = defaultWorkflowFailMatch
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false // rejected by Validate
+	}
+	return re.MatchString(payload)
+}
+
+// Step returns the step with the given ID.
+func (w Workflow) Step(id string) (WorkflowStep, bool) {
+	for _, s := range w.Steps {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return WorkflowStep{}, false
+}
+
+// members returns the IDs of steps that belong to a parallel group.
+func (w Workflow) members() map[string]bool {
+	m := make(map[string]bool)
+	for _, s := range w.Steps {
+		for _, id := range s.Parallel {
+			m[id] = true
+		}
+	}
+	return m
+}
+
+// StartStep returns the first top-level step: the first step in file order
+// that is not a member of a parallel group.
+func (w Workflow) StartStep() string {
+	members := w.members()
+	for _, s := range w.Steps {
+		if !members[s.ID] {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+// Next returns the step to run after id finishes with the given outcome.
+// Unset transitions go to the next top-level step on success (or end after
+// the last one) and to fail on failure.
+func (w Workflow) Next(id string, succeeded bool) string {
+	s, _ := w.Step(id)
+	if !succeeded {
+		if s.OnFailure != "" {
+			return s.OnFailure
+		}
+		return WorkflowFail
+	}
+	if s.OnSuccess != "" {
+		return s.OnSuccess
+	}
+	members := w.members()
+	found := false
+	for _, st := range w.Steps {
+		if found && !members[st.ID] {
+			return st.ID
+		}
+		if st.ID == id {
+			found = true
+		}
+	}
+	return WorkflowEnd
+}
+
+// Validate checks step IDs, roles, groups, transitions, timeouts, and
+// fail_match patterns, returning every problem found.
+func (w Workflow) Validate() []string {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	if w.Name == "" {
+		add("name is required")
+	} else if !workflowStepID.MatchString(w.Name) {
+		add("name %q may only contain letters, digits, - and _", w.Name)
+	}
+	if len(w.Steps) == 0 {
+		add("no steps")
+	}
+
+	ids := make(map[string]WorkflowStep)
+	for i, s := range w.Steps {
+		switch {
+		case s.ID == "":
+			add("steps[%d]: id is required", i)
+			continue
+		case !workflowStepID.MatchString(s.ID):
+			add("step %s: id may only contain letters, digits, - and _", s.ID)
+		case s.ID == WorkflowEnd || s.ID == WorkflowFail:
+			add("step %s: %q is reserved", s.ID, s.ID)
+		}
+		if _, dup := ids[s.ID]; dup {
+			add("step %s: duplicate id", s.ID)
+		}
+		ids[s.ID] = s
+	}
+
+	members := make(map[string]string) // member → group
+	for _, s := range w.Steps {
+		if !s.IsGroup() {
+			continue
+		}
+		for _, id := range s.Parallel {
+			if prev, ok := members[id]; ok {
+				add("step %s: %s is already in group %s", s.ID, id, prev)
+			}
+			members[id] = s.ID
+		}
+	}
+
+	for _, s := range w.Steps {
+		if s.ID == "" {
+			continue
+		}
+		if s.IsGroup() {
+			if s.Role != "" || s.Action != "" || s.Payload != "" {
+				add("step %s: a parallel group cannot also set role, action, or payload", s.ID)
+			}
+			if _, nested := members[s.ID]; nested {
+				add("step %s: groups cannot be nested", s.ID)
+			}
+			for _, id := range s.Parallel {
+				m, ok := ids[id]
+				if !ok {
+					add("step %s: parallel step %s does not exist", s.ID, id)
+				} else if m.IsGroup() {
+					add("step %s: parallel step %s is a group", s.ID, id)
+				}
+			}
+		} else if s.IsApproval() {
+			if s.Action != "" || s.FailMatch != "" {
+				add("step %s: an approval step cannot set action or fail_match", s.ID)
+			}
+			for _, r := range []string{s.Role, s.EscalateTo} {
+				if r != "" && !IsKnownRole(r) {
+					add("step %s: unknown role %q", s.ID, r)
+				}
+			}
+			if group, ok := members[s.ID]; ok {
+				add("step %s: approval steps cannot be in a parallel group (%s)", s.ID, group)
+			}
+			for _, e := range s.gate().Check() {
+				add("step %s: %s", s.ID, e)
+			}
+		} else {
+			if s.EscalateAfter != "" || s.EscalateTo != "" || s.When != "" {
+				add("step %s: escalate_after, escalate_to, and when are only used by approval steps", s.ID)
+			}
+			if s.Role == "" || s.Action == "" {
+				add("step %s: role and action are required", s.ID)
+			} else if !IsKnownRole(s.Role) {
+				add("step %s: unknown role %q", s.ID, s.Role)
+			} else if !actionNamePattern.MatchString(s.Action) {
+				add("step %s: invalid action %q", s.ID, s.Action)
+			}
+			if s.Type != "" && s.Type != "request" && s.Type != "event" {
+				add("step %s: type must be request, event, or approval", s.ID)
+			}
+		}
+		if group, ok := members[s.ID]; ok && (s.OnSuccess != "" || s.OnFailure != "") {
+			add("step %s: transitions on a member of group %s are not used; set them on the group", s.ID, group)
+		}
+		for _, t := range []struct{ field, target string }{{"on_success", s.OnSuccess}, {"on_failure", s.OnFailure}} {
+			if t.target == "" || t.target == WorkflowEnd || t.target == WorkflowFail {
+				continue
+			}
+			if _, ok := ids[t.target]; !ok {
+				add("step %s: %s target %s does not exist", s.ID, t.field, t.target)
+			} else if group, ok := members[t.target]; ok {
+				add("step %s: %s target %s is a member of group %s", s.ID, t.field, t.target, group)
+			}
+		}
+		if s.Timeout != "" && !s.IsApproval() {
+			if d, err := time.ParseDuration(s.Timeout); err != nil || d <= 0 {
+				add("step %s: invalid timeout %q", s.ID, s.Timeout)
+			}
+		}
+		if s.FailMatch != "" {
+			if _, err := regexp.Compile(s.FailMatch); err != nil {
+				add("step %s: invalid fail_match: %v", s.ID, err)
+			}
+		}
+	}
+	return errs
+}
+
+// LoadWorkflow reads a workflow from a YAML (the subset parseYAMLSubset
+// accepts) or JSON file. Unknown fields are rejected so typos surface.
+func LoadWorkflow(path string) (Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Workflow{}, err
+	}
+	if !strings.HasSuffix(path, ".json") {
+		doc, err := parseYAMLSubset(string(data))
+		if err != nil {
+			return Workflow{}, fmt.Errorf("parsing %s: %v", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return Workflow{}, err
+		}
+	}
+	var w Workflow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&w); err != nil {
+		return Workflow{}, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if w.Name == "" {
+		w.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return w, nil
+}
+
+// workflowExts are the file extensions searched for workflow definitions.
+var workflowExts = []string{".yaml", ".yml", ".json"}
+
+// WorkflowPaths returns the workflow files in WorkflowDir(), sorted.
+func WorkflowPaths() []string {
+	var paths []string
+	for _, ext := range workflowExts {
+		found, _ := filepath.Glob(filepath.Join(WorkflowDir(), "*"+ext))
+		paths = append(paths, found...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// FindWorkflow loads a workflow by name from WorkflowDir(), or from a file
+// path when nameOrPath names an existing file.
+func FindWorkflow(nameOrPath string) (Workflow, error) {
+	if info, err := os.Stat(nameOrPath); err == nil && !info.IsDir() {
+		return LoadWorkflow(nameOrPath)
+	}
+	for _, ext := range workflowExts {
+		p := filepath.Join(WorkflowDir(), nameOrPath+ext)
+		if _, err := os.Stat(p); err == nil {
+			return LoadWorkflow(p)
+		}
+	}
+	return Workflow{}, fmt.Errorf("workflow %q not found in %s", nameOrPath, WorkflowDir())
+}
+
+// workflowVar matches ${name} references in step payloads.
+var workflowVar = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// ExpandWorkflowPayload fills a payload template from the run: ${run} and
+// ${workflow}, run variables, and ${steps.ID.result} / ${steps.ID.outcome}
+// from finished steps. Unknown references are left as written.
+func ExpandWorkflowPayload(tmpl string, run *WorkflowRun) string {
+	return workflowVar.ReplaceAllStringFunc(tmpl, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		switch name {
+		case "run":
+			return run.ID
+		case "workflow":
+			return run.Workflow.Name
+		}
+		if rest, ok := strings.CutPrefix(name, "steps."); ok {
+			id, field, _ := strings.Cut(rest, ".")
+			st := run.Steps[id]
+			if st == nil {
+				return ref
+			}
+			switch field {
+			case "result":
+				return st.Result
+			case "outcome":
+				return st.Status
+			}
+			return ref
+		}
+		if v, ok := run.Vars[name]; ok {
+			return v
+		}
+		return ref
+	})
+}
+
+// FormatWorkflowList renders workflow files with their step counts.
+func FormatWorkflowList(paths []string) string {
+	if len(paths) == 0 {
+		return fmt.Sprintf("No workflows in %s.\n", WorkflowDir())
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-24s %-6s %s\n", "NAME", "STEPS", "DESCRIPTION")
+	for _, p := range paths {
+		w, err := LoadWorkflow(p)
+		if err != nil {
+			fmt.Fprintf(&b, "%-24s %-6s error: %v\n", filepath.Base(p), "-", err)
+			continue
+		}
+		fmt.Fprintf(&b, "%-24s %-6d %s\n", w.Name, len(w.Steps), w.Description)
+	}
+	return b.String()
+}
+
+// FormatWorkflow renders a workflow's steps and transitions.
+func FormatWorkflow(w Workflow) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Workflow: %s\n", w.Name)
+	if w.Description != "" {
+		fmt.Fprintf(&b, "%s\n", w.Description)
+	}
+	if len(w.Vars) > 0 {
+		names := make([]string, 0, len(w.Vars))
+		for k := range w.Vars {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		b.WriteString("\nVars:\n")
+		for _, k := range names {
+			fmt.Fprintf(&b, "  %s = %v\n", k, w.Vars[k])
+		}
+	}
+	members := w.members()
+	b.WriteString("\nSteps:\n")
+	for _, s := range w.Steps {
+		if members[s.ID] {
+			continue
+		}
+		writeWorkflowStep(&b, s, "  ")
+		for _, id := range s.Parallel {
+			m, _ := w.Step(id)
+			writeWorkflowStep(&b, m, "    | ")
+		}
+		fmt.Fprintf(&b, "      success → %s, failure → %s\n", w.Next(s.ID, true), w.Next(s.ID, false))
+	}
+	return b.String()
+}
+
+func writeWorkflowStep(b *strings.Builder, s WorkflowStep, indent string) {
+	if s.IsGroup() {
+		fmt.Fprintf(b, "%s%s: parallel %s\n", indent, s.ID, strings.Join(s.Parallel, ", "))
+		return
+	}
+	if s.IsApproval() {
+		fmt.Fprintf(b, "%s%s: approval by %s", indent, s.ID, s.gate().approver())
+		if s.When != "" {
+			fmt.Fprintf(b, " when %s", s.When)
+		}
+		b.WriteString("\n")
+		return
+	}
+	fmt.Fprintf(b, "%s%s: %s %s", indent, s.ID, s.Role, s.Action)
+	if s.Payload != "" {
+		fmt.Fprintf(b, " %q", s.Payload)
+	}
+	b.WriteString("\n")
+}
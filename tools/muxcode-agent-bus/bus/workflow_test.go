@@ -0,0 +1,293 @@
+package bus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWorkflowYAML = `
+name: release-checklist
+description: Build, verify, deploy
+vars:
+  version: 1.2
+steps:
+  - id: build
+    role: build
+    action: build
+    payload: "Build release ${version} (${run})"
+    on_failure: fix
+  - id: verify
+    parallel: [test, review]
+    on_success: deploy
+  - id: test
+    role: test
+    action: test
+    payload: "Test ${steps.build.result}"
+  - id: review
+    role: review
+    action: review
+  - id: deploy
+    role: deploy
+    action: deploy
+    timeout: 10m
+  - id: fix
+    role: edit
+    action: fix
+    payload: "Build failed: ${steps.build.result}"
+    fail_match: "^gave up"
+    on_success: build
+`
+
+func writeTestWorkflow(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("BUS_WORKFLOW_DIR", dir)
+	path := filepath.Join(dir, "release-checklist.yaml")
+	if err := os.WriteFile(path, []byte(testWorkflowYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadWorkflow(t *testing.T) {
+	writeTestWorkflow(t)
+	w, err := FindWorkflow("release-checklist")
+	if err != nil {
+		t.Fatalf("FindWorkflow: %v", err)
+	}
+	if errs := w.Validate(); len(errs) > 0 {
+		t.Fatalf("Validate: %v", errs)
+	}
+	if len(w.Steps) != 6 || w.StartStep() != "build" {
+		t.Errorf("steps = %d, start = %s", len(w.Steps), w.StartStep())
+	}
+	if s, _ := w.Step("deploy"); s.TimeoutDuration() != 10*time.Minute {
+		t.Errorf("deploy timeout = %s", s.TimeoutDuration())
+	}
+
+	for _, c := range []struct {
+		id        string
+		succeeded bool
+		want      string
+	}{
+		{"build", true, "verify"}, // next top-level step, skipping group members
+		{"build", false, "fix"},
+		{"verify", true, "deploy"},
+		{"verify", false, WorkflowFail},
+		{"deploy", true, "fix"}, // file order: fix follows deploy
+		{"fix", true, "build"},
+	} {
+		if got := w.Next(c.id, c.succeeded); got != c.want {
+			t.Errorf("Next(%s, %v) = %s, want %s", c.id, c.succeeded, got, c.want)
+		}
+	}
+
+	if paths := WorkflowPaths(); len(paths) != 1 {
+		t.Errorf("WorkflowPaths = %v", paths)
+	}
+	if _, err := FindWorkflow("missing"); err == nil {
+		t.Error("FindWorkflow(missing): want error")
+	}
+}
+
+func TestLoadWorkflow_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte(`{"name":"bad","steps":[{"id":"a","role":"build","action":"build","on_sucess":"end"}]}`), 0644)
+	if _, err := LoadWorkflow(path); err == nil || !strings.Contains(err.Error(), "on_sucess") {
+		t.Errorf("err = %v, want unknown field on_sucess", err)
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	w := Workflow{Name: "bad name", Steps: []WorkflowStep{
+		{ID: "a", Role: "nobody", Action: "x"},
+		{ID: "a", Role: "build"},
+		{ID: "g", Parallel: []string{"m", "missing"}, Role: "build"},
+		{ID: "m", Role: "test", Action: "test", OnSuccess: "a"},
+		{ID: "n", Role: "test", Action: "test", OnFailure: "m", Timeout: "soon", FailMatch: "("},
+		{ID: "end", Role: "test", Action: "test"},
+	}}
+	errs := strings.Join(w.Validate(), "\n")
+	for _, want := range []string{
+		`name "bad name"`,
+		`unknown role "nobody"`,
+		"duplicate id",
+		"role and action are required",
+		"cannot also set role",
+		"parallel step missing does not exist",
+		"transitions on a member of group g",
+		"on_failure target m is a member of group g",
+		`invalid timeout "soon"`,
+		"invalid fail_match",
+		`"end" is reserved`,
+	} {
+		if !strings.Contains(errs, want) {
+			t.Errorf("Validate missing %q in:\n%s", want, errs)
+		}
+	}
+}
+
+// replyTo sends the reply to a waiting step's request.
+func replyTo(t *testing.T, session string, r *WorkflowRun, step, from, payload string) {
+	t.Helper()
+	r, err := ReadWorkflowRun(session, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := r.Steps[step]
+	if st == nil || st.Status != stepWaiting {
+		t.Fatalf("step %s is not waiting: %+v", step, st)
+	}
+	if err := SendNoCC(session, NewMessage(from, r.From, "response", step, payload, st.MsgID)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func advance(t *testing.T, session string, r *WorkflowRun) *WorkflowRun {
+	t.Helper()
+	if _, err := AdvanceWorkflows(session, time.Now()); err != nil {
+		t.Fatalf("AdvanceWorkflows: %v", err)
+	}
+	r, err := ReadWorkflowRun(session, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWorkflowRun(t *testing.T) {
+	session := testSession(t)
+	writeTestWorkflow(t)
+	w, _ := FindWorkflow("release-checklist")
+
+	r, err := StartWorkflow(session, w, "edit", map[string]string{"version": "2.0"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	msgs, _ := Peek(session, "build")
+	if len(msgs) != 1 || msgs[0].Payload != "Build release 2.0 ("+r.ID+")" || msgs[0].From != "edit" {
+		t.Fatalf("build inbox = %+v", msgs)
+	}
+
+	// Failure goes to fix, whose success loops back to build
+	replyTo(t, session, r, "build", "build", "Build failed: missing import")
+	r = advance(t, session, r)
+	if r.Current != "fix" {
+		t.Fatalf("after build failure current = %s, want fix", r.Current)
+	}
+	if msgs, _ := Peek(session, "edit"); len(msgs) == 0 || msgs[len(msgs)-1].Payload != "Build failed: Build failed: missing import" {
+		t.Errorf("fix request = %+v", msgs)
+	}
+	replyTo(t, session, r, "fix", "edit", "Fixed the import")
+	r = advance(t, session, r)
+	if r.Current != "build" || r.Steps["build"].Runs != 2 {
+		t.Fatalf("after fix current = %s runs = %d", r.Current, r.Steps["build"].Runs)
+	}
+
+	// Success starts the parallel group; it waits for both members
+	replyTo(t, session, r, "build", "build", "Build succeeded: ok")
+	r = advance(t, session, r)
+	if r.Current != "verify" {
+		t.Fatalf("current = %s, want verify", r.Current)
+	}
+	if msgs, _ := Peek(session, "test"); len(msgs) != 1 || msgs[0].Payload != "Test Build succeeded: ok" {
+		t.Errorf("test request = %+v", msgs)
+	}
+	replyTo(t, session, r, "review", "review", "LGTM")
+	if r = advance(t, session, r); r.Current != "verify" {
+		t.Fatalf("group moved on with a member pending: %s", r.Current)
+	}
+	replyTo(t, session, r, "test", "test", "42 passed")
+	r = advance(t, session, r)
+	if r.Current != "deploy" || r.Steps["verify"].Status != WorkflowSucceeded {
+		t.Fatalf("current = %s verify = %+v", r.Current, r.Steps["verify"])
+	}
+
+	// A reply to an old request is ignored
+	if err := SendNoCC(session, NewMessage("build", "edit", "response", "build", "late", "no-such-id")); err != nil {
+		t.Fatal(err)
+	}
+	replyTo(t, session, r, "deploy", "deploy", "Deployed 2.0")
+	r = advance(t, session, r)
+	if r.Current != "fix" {
+		t.Fatalf("after deploy current = %s, want fix (next in file order)", r.Current)
+	}
+	replyTo(t, session, r, "fix", "edit", "gave up")
+	r = advance(t, session, r)
+	if r.Status != WorkflowFailed || !strings.Contains(r.Error, "step fix failed") {
+		t.Fatalf("status = %s error = %q", r.Status, r.Error)
+	}
+
+	// The sender hears how the run ended
+	msgs, _ = Peek(session, "edit")
+	if last := msgs[len(msgs)-1]; last.Action != "workflow-failed" || last.From != "workflow" {
+		t.Errorf("completion notice = %+v", last)
+	}
+
+	// Resume re-sends the failed step
+	if _, err := ResumeWorkflow(session, r.ID); err != nil {
+		t.Fatalf("ResumeWorkflow: %v", err)
+	}
+	r, _ = ReadWorkflowRun(session, r.ID)
+	if r.Status != WorkflowRunning || r.Current != "fix" || r.Steps["fix"].Status != stepWaiting {
+		t.Fatalf("resumed run = %s at %s (%+v)", r.Status, r.Current, r.Steps["fix"])
+	}
+	if _, err := ResumeWorkflow(session, r.ID); err == nil {
+		t.Error("resuming a running run: want error")
+	}
+	if err := CancelWorkflow(session, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = ReadWorkflowRun(session, r.ID); r.Status != WorkflowCancelled {
+		t.Errorf("status = %s, want cancelled", r.Status)
+	}
+}
+
+func TestWorkflowRun_GroupResumeAndTimeout(t *testing.T) {
+	session := testSession(t)
+	w := Workflow{Name: "checks", Steps: []WorkflowStep{
+		{ID: "both", Parallel: []string{"test", "review"}},
+		{ID: "test", Role: "test", Action: "test", Timeout: "1m"},
+		{ID: "review", Role: "review", Action: "review"},
+	}}
+	r, err := StartWorkflow(session, w, "edit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replyTo(t, session, r, "review", "review", "LGTM")
+	if _, err := AdvanceWorkflows(session, time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = ReadWorkflowRun(session, r.ID)
+	if r.Status != WorkflowFailed || r.Steps["test"].Error == "" {
+		t.Fatalf("after timeout: %s, test = %+v", r.Status, r.Steps["test"])
+	}
+
+	// Resuming a group re-sends only the members that did not succeed
+	before, _ := Peek(session, "review")
+	if _, err := ResumeWorkflow(session, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := Peek(session, "review")
+	if len(after) != len(before) {
+		t.Errorf("review re-sent on resume: %d → %d messages", len(before), len(after))
+	}
+	r, _ = ReadWorkflowRun(session, r.ID)
+	replyTo(t, session, r, "test", "test", "all passed")
+	if r = advance(t, session, r); r.Status != WorkflowSucceeded {
+		t.Errorf("status = %s (%s), want succeeded", r.Status, r.Error)
+	}
+}
+
+func TestExpandWorkflowPayload(t *testing.T) {
+	r := &WorkflowRun{ID: "wf-1", Workflow: Workflow{Name: "wf"}, Vars: map[string]string{"env": "prod"},
+		Steps: map[string]*WorkflowStepState{"build": {Status: WorkflowSucceeded, Result: "ok"}}}
+	got := ExpandWorkflowPayload("${workflow}/${run} to ${env}: ${steps.build.outcome} ${steps.build.result} ${missing} ${steps.x.result}", r)
+	want := "wf/wf-1 to prod: succeeded ok ${missing} ${steps.x.result}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,702 @@
+package bus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Workflow run status values.
+const (
+	WorkflowRunning   = "running"
+	WorkflowSucceeded = "succeeded"
+	WorkflowFailed    = "failed"
+	WorkflowCancelled = "cancelled"
+)
+
+// stepWaiting marks a step whose request is out and has no reply yet;
+// finished steps use WorkflowSucceeded or WorkflowFailed.
+const stepWaiting = "waiting"
+
+// maxWorkflowStepRuns stops on_failure loops (e.g. fix → build → fix)
+// from running forever: a step runs at most this many times per run.
+const maxWorkflowStepRuns = 10
+
+// WorkflowRun is the state of one workflow run, saved as
+// workflows/<id>.json in the bus directory. It holds a copy of the
+// definition, so editing the file does not affect runs in progress.
+type WorkflowRun struct {
+	ID        string                        `json:"id"`
+	Workflow  Workflow                      `json:"workflow"`
+	From      string                        `json:"from"` // sender of step requests; replies reach this role
+	Vars      map[string]string             `json:"vars,omitempty"`
+	Status    string                        `json:"status"`
+	Current   string                        `json:"current,omitempty"` // active top-level step
+	Steps     map[string]*WorkflowStepState `json:"steps"`
+	Error     string                        `json:"error,omitempty"`
+	CreatedAt int64                         `json:"created_at"`
+	UpdatedAt int64                         `json:"updated_at"`
+	DoneAt    int64                         `json:"done_at,omitempty"`
+}
+
+// WorkflowStepState is the progress of one step in a run.
+type WorkflowStepState struct {
+	Status     string `json:"status"` // waiting, succeeded, or failed
+	MsgID      string `json:"msg_id,omitempty"`
+	Runs       int    `json:"runs"`
+	SentAt     int64  `json:"sent_at"`
+	DoneAt     int64  `json:"done_at,omitempty"`
+	Result     string `json:"result,omitempty"` // reply payload
+	ResultFrom string `json:"result_from,omitempty"`
+	Error      string `json:"error,omitempty"` // timeout or send failure
+}
+
+// Finished reports whether the run has ended.
+func (r *WorkflowRun) Finished() bool {
+	return r.Status != WorkflowRunning
+}
+
+// WorkflowRunsDir returns the directory holding run state files.
+func WorkflowRunsDir(session string) string {
+	return filepath.Join(BusDir(session), "workflows")
+}
+
+// WorkflowRunPath returns the state file for a run.
+func WorkflowRunPath(session, id string) string {
+	return filepath.Join(WorkflowRunsDir(session), id+".json")
+}
+
+// lockWorkflows serializes read-modify-write cycles on run state between
+// senders (recording replies) and the watcher.
+func lockWorkflows(session string) func() {
+	return flockBusFile(session, "workflows")
+}
+
+// ReadWorkflowRun reads one run's state.
+func ReadWorkflowRun(session, id string) (*WorkflowRun, error) {
+	if !workflowStepID.MatchString(id) {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+	data, err := os.ReadFile(WorkflowRunPath(session, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %s not found", id)
+		}
+		return nil, err
+	}
+	var r WorkflowRun
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing run %s: %v", id, err)
+	}
+	if r.Steps == nil {
+		r.Steps = make(map[string]*WorkflowStepState)
+	}
+	return &r, nil
+}
+
+// ReadWorkflowRuns reads every run, oldest first. Unreadable files are
+// skipped.
+func ReadWorkflowRuns(session string) ([]*WorkflowRun, error) {
+	paths, err := filepath.Glob(filepath.Join(WorkflowRunsDir(session), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var runs []*WorkflowRun
+	for _, p := range paths {
+		r, err := ReadWorkflowRun(session, strings.TrimSuffix(filepath.Base(p), ".json"))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, r)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].CreatedAt < runs[j].CreatedAt })
+	return runs, nil
+}
+
+// writeWorkflowRun saves a run's state atomically.
+func writeWorkflowRun(session string, r *WorkflowRun) error {
+	if err := os.MkdirAll(WorkflowRunsDir(session), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := WorkflowRunPath(session, r.ID)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// newWorkflowRunID returns "<workflow>-<unix>-<4hex>".
+func newWorkflowRunID(name string) string {
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", name, time.Now().Unix(), hex.EncodeToString(b))
+}
+
+// workflowSend is a step's request, or an approval step's approval,
+// prepared under the lock and sent after it.
+type workflowSend struct {
+	step     string
+	msg      Message
+	approval *Approval
+}
+
+// dispatch prepares the request for a task step and marks it waiting. An
+// approval step whose when condition is not met succeeds at once and
+// sends nothing.
+func (r *WorkflowRun) dispatch(id string, now int64) (*workflowSend, error) {
+	step, ok := r.Workflow.Step(id)
+	if !ok {
+		return nil, fmt.Errorf("step %s does not exist", id)
+	}
+	st := r.Steps[id]
+	if st == nil {
+		st = &WorkflowStepState{}
+		r.Steps[id] = st
+	}
+	if st.Runs >= maxWorkflowStepRuns {
+		return nil, fmt.Errorf("step %s already ran %d times", id, st.Runs)
+	}
+	if step.IsApproval() {
+		return r.dispatchApproval(step, st, now)
+	}
+	msgType := step.Type
+	if msgType == "" {
+		msgType = "request"
+	}
+	payload := ExpandWorkflowPayload(step.Payload, r)
+	if payload == "" {
+		payload = fmt.Sprintf("Workflow %s step %s", r.Workflow.Name, id)
+	}
+	msg := NewMessage(r.From, step.Role, msgType, step.Action, payload, "")
+	*st = WorkflowStepState{Status: stepWaiting, MsgID: msg.ID, Runs: st.Runs + 1, SentAt: now}
+	return &workflowSend{step: id, msg: msg}, nil
+}
+
+// dispatchApproval prepares an approval step's approval, or skips the
+// step when its condition is not met.
+func (r *WorkflowRun) dispatchApproval(step WorkflowStep, st *WorkflowStepState, now int64) (*workflowSend, error) {
+	cond := ExpandWorkflowPayload(step.When, r)
+	met, err := ApprovalConditionMet(cond)
+	if err != nil {
+		return nil, fmt.Errorf("step %s: %v", step.ID, err)
+	}
+	if !met {
+		*st = WorkflowStepState{Status: WorkflowSucceeded, Runs: st.Runs + 1, SentAt: now, DoneAt: now,
+			Result: "approval not required: " + cond}
+		return nil, nil
+	}
+	prompt := ExpandWorkflowPayload(step.Payload, r)
+	if prompt == "" {
+		prompt = fmt.Sprintf("workflow %s is waiting at step %s", r.Workflow.Name, step.ID)
+	}
+	a := newApproval(step.gate(), r.ID, ApprovalWorkflow, r.From, prompt, time.Unix(now, 0))
+	a.Step = step.ID
+	*st = WorkflowStepState{Status: stepWaiting, Runs: st.Runs + 1, SentAt: now}
+	return &workflowSend{step: step.ID, approval: &a}, nil
+}
+
+// activate makes id the current step and returns the requests to send. A
+// skipped approval step moves straight on to the next step.
+func (r *WorkflowRun) activate(id string, now int64) ([]workflowSend, error) {
+	step, ok := r.Workflow.Step(id)
+	if !ok {
+		return nil, fmt.Errorf("step %s does not exist", id)
+	}
+	r.Current = id
+	if !step.IsGroup() {
+		send, err := r.dispatch(id, now)
+		if err != nil {
+			return nil, err
+		}
+		if send == nil {
+			return r.transition(true, now), nil
+		}
+		return []workflowSend{*send}, nil
+	}
+
+	st := r.Steps[id]
+	if st == nil {
+		st = &WorkflowStepState{}
+		r.Steps[id] = st
+	}
+	if st.Runs >= maxWorkflowStepRuns {
+		return nil, fmt.Errorf("step %s already ran %d times", id, st.Runs)
+	}
+	*st = WorkflowStepState{Status: stepWaiting, Runs: st.Runs + 1, SentAt: now}
+	var sends []workflowSend
+	for _, m := range step.Parallel {
+		send, err := r.dispatch(m, now)
+		if err != nil {
+			return nil, err
+		}
+		sends = append(sends, *send)
+	}
+	return sends, nil
+}
+
+// finish ends the run.
+func (r *WorkflowRun) finish(status, errMsg string, now int64) {
+	r.Status = status
+	r.Error = errMsg
+	r.DoneAt = now
+}
+
+// transition moves on from the current step after it finished.
+func (r *WorkflowRun) transition(succeeded bool, now int64) []workflowSend {
+	cur := r.Current
+	switch next := r.Workflow.Next(cur, succeeded); next {
+	case WorkflowEnd:
+		r.finish(WorkflowSucceeded, "", now)
+	case WorkflowFail:
+		r.finish(WorkflowFailed, fmt.Sprintf("step %s failed", cur), now)
+	default:
+		sends, err := r.activate(next, now)
+		if err != nil {
+			r.finish(WorkflowFailed, err.Error(), now)
+			return nil
+		}
+		return sends
+	}
+	return nil
+}
+
+// timedOut marks a waiting task step failed once its timeout passes.
+// Approval steps expire through CheckApprovals instead.
+func (r *WorkflowRun) timedOut(id string, now int64) bool {
+	st := r.Steps[id]
+	step, _ := r.Workflow.Step(id)
+	if st == nil || st.Status != stepWaiting || step.IsApproval() || now-st.SentAt < int64(step.TimeoutDuration()/time.Second) {
+		return false
+	}
+	st.Status = WorkflowFailed
+	st.Error = fmt.Sprintf("no reply within %s", step.TimeoutDuration())
+	st.DoneAt = now
+	return true
+}
+
+// advance applies timeouts and, when the current step has finished, moves
+// to the next one. It reports whether the run changed and returns the
+// requests to send.
+func (r *WorkflowRun) advance(now int64) (bool, []workflowSend) {
+	if r.Finished() {
+		return false, nil
+	}
+	cur := r.Current
+	step, ok := r.Workflow.Step(cur)
+	st := r.Steps[cur]
+	if !ok || st == nil {
+		r.finish(WorkflowFailed, fmt.Sprintf("step %s has no state", cur), now)
+		return true, nil
+	}
+
+	changed := false
+	if step.IsGroup() {
+		pending := false
+		for _, m := range step.Parallel {
+			if r.timedOut(m, now) {
+				changed = true
+			}
+			if ms := r.Steps[m]; ms == nil || ms.Status == stepWaiting {
+				pending = true
+			}
+		}
+		if pending {
+			return changed, nil
+		}
+		st.Status = WorkflowSucceeded
+		for _, m := range step.Parallel {
+			if r.Steps[m].Status != WorkflowSucceeded {
+				st.Status = WorkflowFailed
+			}
+		}
+		st.DoneAt = now
+	} else if r.timedOut(cur, now) {
+		changed = true
+	} else if st.Status == stepWaiting {
+		return false, nil
+	}
+	return true, r.transition(st.Status == WorkflowSucceeded, now)
+}
+
+// sendWorkflowMessages sends step requests prepared under the lock and
+// registers approvals. A send that fails, or is denied by policy, fails
+// its step; the next advance moves the run on.
+func sendWorkflowMessages(session, runID string, sends []workflowSend) {
+	for _, s := range sends {
+		var err error
+		if s.approval != nil {
+			if err = RequestApproval(session, *s.approval); err == nil {
+				continue
+			}
+		} else if deny := CheckMessagePolicy(session, s.msg); deny != "" {
+			err = fmt.Errorf("%s", deny)
+		} else if err = Send(session, s.msg); err == nil {
+			_ = Notify(session, s.msg.To)
+			continue
+		}
+		unlock := lockWorkflows(session)
+		if r, rerr := ReadWorkflowRun(session, runID); rerr == nil {
+			if st := r.Steps[s.step]; st != nil && st.Status == stepWaiting {
+				st.Status = WorkflowFailed
+				st.Error = "send failed: " + err.Error()
+				st.DoneAt = time.Now().Unix()
+			}
+			_ = writeWorkflowRun(session, r)
+		}
+		unlock()
+	}
+}
+
+// StartWorkflow validates w and starts a run that sends requests as from.
+// vars override the workflow's default vars.
+func StartWorkflow(session string, w Workflow, from string, vars map[string]string) (*WorkflowRun, error) {
+	if errs := w.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid workflow %s: %s", w.Name, strings.Join(errs, "; "))
+	}
+	if !validRoleName(from) {
+		return nil, fmt.Errorf("invalid sender %q", from)
+	}
+	now := time.Now().Unix()
+	r := &WorkflowRun{
+		ID:        newWorkflowRunID(w.Name),
+		Workflow:  w,
+		From:      from,
+		Vars:      make(map[string]string),
+		Status:    WorkflowRunning,
+		Steps:     make(map[string]*WorkflowStepState),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for k, v := range w.Vars {
+		r.Vars[k] = fmt.Sprint(v)
+	}
+	for k, v := range vars {
+		r.Vars[k] = v
+	}
+
+	sends, err := r.activate(w.StartStep(), now)
+	if err != nil {
+		return nil, err
+	}
+	unlock := lockWorkflows(session)
+	err = writeWorkflowRun(session, r)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	sendWorkflowMessages(session, r.ID, sends)
+	if r.Finished() {
+		notifyWorkflowDone(session, r)
+	}
+	return r, nil
+}
+
+// resolveWorkflowSteps records m as the reply to a waiting step when its
+// reply_to names the step's request. Cheap when no runs exist.
+func resolveWorkflowSteps(session string, m Message) error {
+	if m.ReplyTo == "" {
+		return nil
+	}
+	if _, err := os.Stat(WorkflowRunsDir(session)); err != nil {
+		return nil
+	}
+	unlock := lockWorkflows(session)
+	defer unlock()
+
+	runs, err := ReadWorkflowRuns(session)
+	if err != nil {
+		return err
+	}
+	for _, r := range runs {
+		if r.Finished() {
+			continue
+		}
+		for id, st := range r.Steps {
+			if st.Status != stepWaiting || st.MsgID == "" || st.MsgID != m.ReplyTo {
+				continue
+			}
+			step, _ := r.Workflow.Step(id)
+			st.Status = WorkflowSucceeded
+			if step.Failed(m.Payload) {
+				st.Status = WorkflowFailed
+			}
+			st.Result = m.Payload
+			st.ResultFrom = m.From
+			st.DoneAt = m.TS
+			r.UpdatedAt = m.TS
+			if err := writeWorkflowRun(session, r); err != nil {
+				return err
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// AdvanceWorkflows moves every running run on: steps past their timeout
+// fail, and runs whose current step has finished send the next step's
+// requests or end. Finished runs notify their sender with a
+// workflow-succeeded or workflow-failed event. The watcher calls it
+// periodically; it returns the runs that finished during this call.
+func AdvanceWorkflows(session string, now time.Time) ([]*WorkflowRun, error) {
+	if _, err := os.Stat(WorkflowRunsDir(session)); err != nil {
+		return nil, nil
+	}
+	unlock := lockWorkflows(session)
+	runs, err := ReadWorkflowRuns(session)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	sends := make(map[string][]workflowSend)
+	var finished []*WorkflowRun
+	for _, r := range runs {
+		changed, msgs := r.advance(now.Unix())
+		if !changed {
+			continue
+		}
+		r.UpdatedAt = now.Unix()
+		if err := writeWorkflowRun(session, r); err != nil {
+			unlock()
+			return finished, err
+		}
+		if len(msgs) > 0 {
+			sends[r.ID] = msgs
+		}
+		if r.Finished() {
+			finished = append(finished, r)
+		}
+	}
+	unlock()
+
+	// Send outside the lock: Send records replies through resolveWorkflowSteps
+	for id, msgs := range sends {
+		sendWorkflowMessages(session, id, msgs)
+	}
+	for _, r := range finished {
+		notifyWorkflowDone(session, r)
+	}
+	return finished, nil
+}
+
+// notifyWorkflowDone tells the run's sender how it ended.
+func notifyWorkflowDone(session string, r *WorkflowRun) {
+	action := "workflow-succeeded"
+	payload := fmt.Sprintf("Workflow %s (%s) succeeded", r.Workflow.Name, r.ID)
+	if r.Status != WorkflowSucceeded {
+		action = "workflow-failed"
+		payload = fmt.Sprintf("Workflow %s (%s) failed: %s", r.Workflow.Name, r.ID, r.Error)
+		if st := r.Steps[r.Current]; st != nil {
+			detail := st.Error
+			if detail == "" {
+				detail = st.Result
+			}
+			if detail != "" {
+				payload += " — " + clipWorkflowText(detail, 200)
+			}
+		}
+	}
+	if err := Send(session, NewMessage("workflow", r.From, "event", action, payload, "")); err == nil {
+		_ = Notify(session, r.From)
+	}
+}
+
+// ResumeWorkflow restarts a failed or cancelled run at its current step,
+// re-sending the step's request (for a group, only the members that did
+// not succeed).
+func ResumeWorkflow(session, id string) (*WorkflowRun, error) {
+	unlock := lockWorkflows(session)
+	r, err := ReadWorkflowRun(session, id)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if r.Status != WorkflowFailed && r.Status != WorkflowCancelled {
+		unlock()
+		return nil, fmt.Errorf("run %s is %s; only failed or cancelled runs can be resumed", id, r.Status)
+	}
+	now := time.Now().Unix()
+	step, ok := r.Workflow.Step(r.Current)
+	if !ok {
+		unlock()
+		return nil, fmt.Errorf("run %s has no current step", id)
+	}
+
+	var sends []workflowSend
+	r.Status, r.Error, r.DoneAt, r.UpdatedAt = WorkflowRunning, "", 0, now
+	if step.IsGroup() {
+		r.Steps[r.Current] = &WorkflowStepState{Status: stepWaiting, Runs: 1, SentAt: now}
+		for _, m := range step.Parallel {
+			if st := r.Steps[m]; st != nil && st.Status == WorkflowSucceeded {
+				continue
+			}
+			delete(r.Steps, m)
+			send, err := r.dispatch(m, now)
+			if err != nil {
+				unlock()
+				return nil, err
+			}
+			sends = append(sends, *send)
+		}
+	} else {
+		delete(r.Steps, r.Current)
+		if sends, err = r.activate(r.Current, now); err != nil {
+			unlock()
+			return nil, err
+		}
+	}
+	err = writeWorkflowRun(session, r)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	sendWorkflowMessages(session, r.ID, sends)
+	if r.Finished() {
+		notifyWorkflowDone(session, r)
+	}
+	return r, nil
+}
+
+// CancelWorkflow stops a running run. Replies that arrive later are
+// ignored; the run can be resumed.
+func CancelWorkflow(session, id string) error {
+	unlock := lockWorkflows(session)
+	defer unlock()
+	r, err := ReadWorkflowRun(session, id)
+	if err != nil {
+		return err
+	}
+	if r.Finished() {
+		return fmt.Errorf("run %s is already %s", id, r.Status)
+	}
+	now := time.Now().Unix()
+	r.finish(WorkflowCancelled, "cancelled", now)
+	r.UpdatedAt = now
+	if err := writeWorkflowRun(session, r); err != nil {
+		return err
+	}
+	return cancelApprovals(session, id, now)
+}
+
+// workflowAwaitingApproval reports whether run id is running and waiting
+// on approval step.
+func workflowAwaitingApproval(session, id, step string) bool {
+	r, err := ReadWorkflowRun(session, id)
+	if err != nil || r.Finished() || r.Current != step {
+		return false
+	}
+	st := r.Steps[step]
+	return st != nil && st.Status == stepWaiting
+}
+
+// recordWorkflowApproval records a decided or expired approval on the
+// step waiting for it: approved succeeds, anything else fails.
+func recordWorkflowApproval(session string, a Approval) error {
+	unlock := lockWorkflows(session)
+	defer unlock()
+	r, err := ReadWorkflowRun(session, a.ID)
+	if err != nil {
+		return err
+	}
+	st := r.Steps[a.Step]
+	if r.Finished() || r.Current != a.Step || st == nil || st.Status != stepWaiting {
+		return nil
+	}
+	st.Status = WorkflowFailed
+	if a.Status == ApprovalApproved {
+		st.Status = WorkflowSucceeded
+	}
+	st.Result = a.Status
+	if a.Reason != "" {
+		st.Result += ": " + a.Reason
+	}
+	st.ResultFrom = a.DecidedBy
+	if a.Status == ApprovalExpired {
+		st.Error = "no decision within the approval timeout"
+	}
+	st.DoneAt = a.DoneAt
+	r.UpdatedAt = a.DoneAt
+	return writeWorkflowRun(session, r)
+}
+
+// FormatWorkflowRuns renders runs as a table, newest first. Finished runs
+// are included only when all is true.
+func FormatWorkflowRuns(runs []*WorkflowRun, all bool) string {
+	var b strings.Builder
+	shown := 0
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		if r.Finished() && !all {
+			continue
+		}
+		if shown == 0 {
+			fmt.Fprintf(&b, "%-36s %-10s %-16s %s\n", "RUN", "STATUS", "STEP", "UPDATED")
+		}
+		shown++
+		fmt.Fprintf(&b, "%-36s %-10s %-16s %s\n", r.ID, r.Status, r.Current, time.Unix(r.UpdatedAt, 0).Format("2006-01-02 15:04:05"))
+	}
+	if shown == 0 {
+		return "No workflow runs.\n"
+	}
+	return b.String()
+}
+
+// FormatWorkflowRun renders one run with every step's state, in file order.
+func FormatWorkflowRun(r *WorkflowRun) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Run:      %s\n", r.ID)
+	fmt.Fprintf(&b, "Workflow: %s\n", r.Workflow.Name)
+	fmt.Fprintf(&b, "Status:   %s\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Error:    %s\n", r.Error)
+	}
+	fmt.Fprintf(&b, "Current:  %s\n", r.Current)
+	fmt.Fprintf(&b, "From:     %s\n\n", r.From)
+	for _, s := range r.Workflow.Steps {
+		st := r.Steps[s.ID]
+		status, runs := "pending", 0
+		if st != nil {
+			status, runs = st.Status, st.Runs
+		}
+		target := "parallel " + strings.Join(s.Parallel, ", ")
+		switch {
+		case s.IsApproval():
+			target = "approval by " + s.gate().approver()
+		case !s.IsGroup():
+			target = s.Role + " " + s.Action
+		}
+		fmt.Fprintf(&b, "  %-16s %-10s runs=%d  %s\n", s.ID, status, runs, target)
+		if st == nil {
+			continue
+		}
+		if st.Error != "" {
+			fmt.Fprintf(&b, "      error: %s\n", st.Error)
+		}
+		switch {
+		case st.Result != "" && st.ResultFrom != "":
+			fmt.Fprintf(&b, "      reply from %s: %s\n", st.ResultFrom, clipWorkflowText(st.Result, 120))
+		case st.Result != "":
+			fmt.Fprintf(&b, "      result: %s\n", clipWorkflowText(st.Result, 120))
+		}
+	}
+	return b.String()
+}
+
+// clipWorkflowText shortens s to at most n bytes for display.
+func clipWorkflowText(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,377 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxWorkspacePackages caps the packages listed in convention text.
+const maxWorkspacePackages = 20
+
+// WorkspacePackage is one package of a monorepo workspace.
+type WorkspacePackage struct {
+	Path  string `json:"path"` // relative to the workspace root
+	Name  string `json:"name"`
+	Tool  string `json:"tool"` // go, npm, pnpm, yarn, cargo, or bazel
+	Build string `json:"build,omitempty"`
+	Test  string `json:"test,omitempty"`
+}
+
+// detectMonorepo returns a "monorepo" project type when dir is the root of
+// a pnpm/yarn/npm, Go, Cargo, or Bazel workspace, listing its packages.
+func detectMonorepo(dir string) *ProjectType {
+	pt := ProjectType{Name: "monorepo"}
+	var tools []string
+	add := func(indicator, tool string, pkgs []WorkspacePackage) {
+		pt.Indicators = append(pt.Indicators, indicator)
+		tools = append(tools, tool)
+		pt.Packages = append(pt.Packages, pkgs...)
+	}
+
+	if ws, ok := nodeWorkspace(dir); ok {
+		add(ws.indicator, ws.tool, ws.packages)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.work")); err == nil {
+		add("go.work", "go", goWorkspace(dir))
+	}
+	if pkgs, ok := cargoWorkspace(dir); ok {
+		add("Cargo.toml [workspace]", "cargo", pkgs)
+	}
+	for _, f := range []string{"MODULE.bazel", "WORKSPACE", "WORKSPACE.bazel"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			add(f, "bazel", bazelWorkspace(dir))
+			break
+		}
+	}
+	if len(pt.Indicators) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(pt.Packages, func(i, j int) bool {
+		return pt.Packages[i].Path < pt.Packages[j].Path
+	})
+	pt.Metadata = map[string]string{
+		"tools":    strings.Join(tools, ","),
+		"packages": strconv.Itoa(len(pt.Packages)),
+	}
+	return &pt
+}
+
+// nodeWorkspaceResult is a detected JavaScript workspace.
+type nodeWorkspaceResult struct {
+	indicator string
+	tool      string
+	packages  []WorkspacePackage
+}
+
+// nodeWorkspace detects pnpm-workspace.yaml or package.json "workspaces".
+// The package manager is pnpm for pnpm-workspace.yaml, yarn when yarn.lock
+// exists, otherwise npm.
+func nodeWorkspace(dir string) (nodeWorkspaceResult, bool) {
+	var r nodeWorkspaceResult
+	var patterns []string
+	if data, err := os.ReadFile(filepath.Join(dir, "pnpm-workspace.yaml")); err == nil {
+		r.indicator, r.tool = "pnpm-workspace.yaml", "pnpm"
+		patterns = parsePnpmWorkspace(string(data))
+	} else {
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		if err != nil {
+			return r, false
+		}
+		var pkg struct {
+			Workspaces json.RawMessage `json:"workspaces"`
+		}
+		if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+			return r, false
+		}
+		// "workspaces" is a list or {"packages": [...]}
+		if json.Unmarshal(pkg.Workspaces, &patterns) != nil {
+			var obj struct {
+				Packages []string `json:"packages"`
+			}
+			if json.Unmarshal(pkg.Workspaces, &obj) != nil {
+				return r, false
+			}
+			patterns = obj.Packages
+		}
+		r.indicator, r.tool = "package.json workspaces", "npm"
+		if _, err := os.Stat(filepath.Join(dir, "yarn.lock")); err == nil {
+			r.tool = "yarn"
+		}
+	}
+
+	for _, rel := range expandWorkspaceGlobs(dir, patterns) {
+		meta := extractPackageJSON(filepath.Join(dir, rel))
+		if meta == nil {
+			continue
+		}
+		p := WorkspacePackage{Path: rel, Name: meta["name"], Tool: r.tool}
+		if p.Name == "" {
+			p.Name = filepath.Base(rel)
+		}
+		if meta["build"] != "" {
+			p.Build = nodeScriptCommand(r.tool, p, "build")
+		}
+		if meta["test"] != "" {
+			p.Test = nodeScriptCommand(r.tool, p, "test")
+		}
+		r.packages = append(r.packages, p)
+	}
+	return r, true
+}
+
+// nodeScriptCommand returns the command running a package script from the
+// workspace root.
+func nodeScriptCommand(tool string, p WorkspacePackage, script string) string {
+	switch tool {
+	case "pnpm":
+		return fmt.Sprintf("pnpm --filter %s run %s", p.Name, script)
+	case "yarn":
+		return fmt.Sprintf("yarn workspace %s run %s", p.Name, script)
+	}
+	return fmt.Sprintf("npm run %s -w %s", script, p.Path)
+}
+
+// parsePnpmWorkspace returns the packages list of a pnpm-workspace.yaml.
+func parsePnpmWorkspace(text string) []string {
+	var patterns []string
+	inPackages := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		if inPackages && strings.HasPrefix(trimmed, "-") {
+			patterns = append(patterns, strings.Trim(strings.TrimSpace(trimmed[1:]), `"'`))
+		}
+	}
+	return patterns
+}
+
+// expandWorkspaceGlobs expands workspace member patterns to directories
+// relative to dir. "**" is treated as a single level and "!" patterns
+// exclude matches.
+func expandWorkspaceGlobs(dir string, patterns []string) []string {
+	seen := map[string]bool{}
+	var excluded []string
+	var out []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			excluded = append(excluded, strings.TrimPrefix(p, "!"))
+			continue
+		}
+		p = strings.ReplaceAll(strings.TrimPrefix(p, "./"), "**", "*")
+		matches, err := filepath.Glob(filepath.Join(dir, p))
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			rel, err := filepath.Rel(dir, m)
+			if err != nil || seen[rel] {
+				continue
+			}
+			if info, err := os.Stat(m); err != nil || !info.IsDir() {
+				continue
+			}
+			seen[rel] = true
+			out = append(out, filepath.ToSlash(rel))
+		}
+	}
+
+	var kept []string
+	for _, rel := range out {
+		skip := false
+		for _, ex := range excluded {
+			if ok, _ := filepath.Match(strings.ReplaceAll(strings.TrimPrefix(ex, "./"), "**", "*"), rel); ok {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, rel)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// goWorkUse matches the directories of go.work use directives.
+var goWorkUse = regexp.MustCompile(`^\s*(?:use\s+)?(\.[^\s)]*)`)
+
+// goWorkspace returns the modules listed in go.work.
+func goWorkspace(dir string) []WorkspacePackage {
+	data, err := os.ReadFile(filepath.Join(dir, "go.work"))
+	if err != nil {
+		return nil
+	}
+	var pkgs []WorkspacePackage
+	inUse := false
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "use ("):
+			inUse = true
+			continue
+		case inUse && trimmed == ")":
+			inUse = false
+			continue
+		case !inUse && !strings.HasPrefix(trimmed, "use "):
+			continue
+		}
+		m := goWorkUse.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		rel := filepath.ToSlash(filepath.Clean(m[1]))
+		p := WorkspacePackage{Path: rel, Name: rel, Tool: "go"}
+		if meta := extractGoMod(filepath.Join(dir, rel)); meta["module"] != "" {
+			p.Name = meta["module"]
+		}
+		target := "./..."
+		if rel != "." {
+			target = "./" + rel + "/..."
+		}
+		p.Build = "go build " + target
+		p.Test = "go test " + target
+		pkgs = append(pkgs, p)
+	}
+	return pkgs
+}
+
+// cargoMembers matches the members list of a [workspace] section.
+var cargoMembers = regexp.MustCompile(`(?s)members\s*=\s*\[(.*?)\]`)
+
+// cargoPackageName matches a Cargo.toml `name = "..."` line.
+var cargoPackageName = regexp.MustCompile(`^\s*name\s*=\s*"([^"]+)"`)
+
+// cargoWorkspace returns the members of a Cargo.toml [workspace].
+func cargoWorkspace(dir string) ([]WorkspacePackage, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return nil, false
+	}
+	text := string(data)
+	idx := strings.Index(text, "[workspace]")
+	if idx < 0 {
+		return nil, false
+	}
+	section := text[idx+len("[workspace]"):]
+	if end := strings.Index(section, "\n["); end >= 0 {
+		section = section[:end]
+	}
+
+	var members []string
+	if m := cargoMembers.FindStringSubmatch(section); m != nil {
+		for _, item := range strings.Split(m[1], ",") {
+			item = strings.Trim(strings.TrimSpace(item), `"'`)
+			if item != "" && !strings.HasPrefix(item, "#") {
+				members = append(members, item)
+			}
+		}
+	}
+
+	var pkgs []WorkspacePackage
+	for _, rel := range expandWorkspaceGlobs(dir, members) {
+		name := filepath.Base(rel)
+		data, err := os.ReadFile(filepath.Join(dir, rel, "Cargo.toml"))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if m := cargoPackageName.FindStringSubmatch(line); m != nil {
+				name = m[1]
+				break
+			}
+		}
+		pkgs = append(pkgs, WorkspacePackage{
+			Path:  rel,
+			Name:  name,
+			Tool:  "cargo",
+			Build: "cargo build -p " + name,
+			Test:  "cargo test -p " + name,
+		})
+	}
+	return pkgs, true
+}
+
+// bazelWorkspace returns Bazel packages (directories with a BUILD file) up
+// to two levels below dir.
+func bazelWorkspace(dir string) []WorkspacePackage {
+	var pkgs []WorkspacePackage
+	var walk func(rel string, depth int)
+	walk = func(rel string, depth int) {
+		entries, err := os.ReadDir(filepath.Join(dir, rel))
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			name := e.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "bazel-") || name == "node_modules" {
+				continue
+			}
+			child := filepath.ToSlash(filepath.Join(rel, name))
+			if hasBazelBuild(filepath.Join(dir, child)) {
+				pkgs = append(pkgs, WorkspacePackage{
+					Path:  child,
+					Name:  "//" + child,
+					Tool:  "bazel",
+					Build: "bazel build //" + child + "/...",
+					Test:  "bazel test //" + child + "/...",
+				})
+			}
+			if depth < 2 {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk("", 1)
+	return pkgs
+}
+
+func hasBazelBuild(dir string) bool {
+	for _, f := range []string{"BUILD", "BUILD.bazel"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// monorepoConventionText lists workspace packages and their commands.
+func monorepoConventionText(pt ProjectType) string {
+	var b strings.Builder
+	b.WriteString("## Monorepo\n")
+	fmt.Fprintf(&b, "- Workspaces: %s\n", strings.Join(pt.Indicators, ", "))
+	fmt.Fprintf(&b, "- Build and test the package you changed, not the whole repository\n")
+	for i, p := range pt.Packages {
+		if i == maxWorkspacePackages {
+			fmt.Fprintf(&b, "- ... and %d more packages\n", len(pt.Packages)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- `%s` (%s)", p.Path, p.Name)
+		if p.Build != "" {
+			fmt.Fprintf(&b, " build: `%s`", p.Build)
+		}
+		if p.Test != "" {
+			fmt.Fprintf(&b, " test: `%s`", p.Test)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
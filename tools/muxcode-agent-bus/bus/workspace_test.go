@@ -0,0 +1,129 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+// findType returns the detected project type with the given name.
+func findType(types []ProjectType, name string) *ProjectType {
+	for i := range types {
+		if types[i].Name == name {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
+func TestDetectMonorepo_None(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "package.json", `{"name":"app"}`)
+	writeIndicatorFile(t, dir, "Cargo.toml", "[package]\nname = \"app\"\n")
+	if mono := findType(DetectProject(dir), "monorepo"); mono != nil {
+		t.Errorf("single-package project detected as monorepo: %+v", mono)
+	}
+}
+
+func TestDetectMonorepo_Pnpm(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "package.json", `{"name":"root"}`)
+	writeIndicatorFile(t, dir, "pnpm-workspace.yaml", "packages:\n  - 'packages/*'\n  - \"apps/**\"\n  - '!packages/legacy'\n")
+	writeIndicatorFile(t, dir, "packages/ui/package.json", `{"name":"@acme/ui","scripts":{"build":"tsup","test":"vitest"}}`)
+	writeIndicatorFile(t, dir, "packages/legacy/package.json", `{"name":"legacy"}`)
+	writeIndicatorFile(t, dir, "apps/web/package.json", `{"name":"web","scripts":{"build":"next build"}}`)
+	writeIndicatorFile(t, dir, "apps/notes.txt", "not a package")
+
+	mono := findType(DetectProject(dir), "monorepo")
+	if mono == nil {
+		t.Fatal("monorepo not detected")
+	}
+	if mono.Metadata["tools"] != "pnpm" || mono.Metadata["packages"] != "2" {
+		t.Errorf("metadata = %v", mono.Metadata)
+	}
+	if len(mono.Packages) != 2 {
+		t.Fatalf("packages = %+v", mono.Packages)
+	}
+	web, ui := mono.Packages[0], mono.Packages[1]
+	if web.Path != "apps/web" || web.Build != "pnpm --filter web run build" || web.Test != "" {
+		t.Errorf("web = %+v", web)
+	}
+	if ui.Name != "@acme/ui" || ui.Test != "pnpm --filter @acme/ui run test" {
+		t.Errorf("ui = %+v", ui)
+	}
+}
+
+func TestDetectMonorepo_NpmAndYarnWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "package.json", `{"workspaces":{"packages":["libs/*"]}}`)
+	writeIndicatorFile(t, dir, "libs/core/package.json", `{"name":"core","scripts":{"test":"jest"}}`)
+
+	mono := findType(DetectProject(dir), "monorepo")
+	if mono == nil || len(mono.Packages) != 1 || mono.Packages[0].Test != "npm run test -w libs/core" {
+		t.Fatalf("npm workspace = %+v", mono)
+	}
+
+	writeIndicatorFile(t, dir, "yarn.lock", "")
+	mono = findType(DetectProject(dir), "monorepo")
+	if mono.Packages[0].Test != "yarn workspace core run test" {
+		t.Errorf("yarn test = %q", mono.Packages[0].Test)
+	}
+}
+
+func TestDetectMonorepo_GoWork(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "go.work", "go 1.22\n\nuse (\n\t./svc/api\n\t./tools // helpers\n)\nuse ./lib\n")
+	writeIndicatorFile(t, dir, "svc/api/go.mod", "module example.com/api\n")
+	writeIndicatorFile(t, dir, "tools/go.mod", "module example.com/tools\n")
+
+	mono := findType(DetectProject(dir), "monorepo")
+	if mono == nil {
+		t.Fatal("go.work not detected")
+	}
+	var paths []string
+	for _, p := range mono.Packages {
+		paths = append(paths, p.Path)
+	}
+	if strings.Join(paths, ",") != "lib,svc/api,tools" {
+		t.Errorf("paths = %v", paths)
+	}
+	api := mono.Packages[1]
+	if api.Name != "example.com/api" || api.Build != "go build ./svc/api/..." || api.Test != "go test ./svc/api/..." {
+		t.Errorf("api = %+v", api)
+	}
+}
+
+func TestDetectMonorepo_CargoAndBazel(t *testing.T) {
+	dir := t.TempDir()
+	writeIndicatorFile(t, dir, "Cargo.toml", "[workspace]\nmembers = [\n  \"crates/*\",\n]\n\n[profile.release]\nlto = true\n")
+	writeIndicatorFile(t, dir, "crates/parser/Cargo.toml", "[package]\nname = \"acme-parser\"\n")
+	writeIndicatorFile(t, dir, "MODULE.bazel", "")
+	writeIndicatorFile(t, dir, "services/auth/BUILD.bazel", "")
+	writeIndicatorFile(t, dir, "bazel-out/x/BUILD", "")
+
+	mono := findType(DetectProject(dir), "monorepo")
+	if mono == nil {
+		t.Fatal("monorepo not detected")
+	}
+	if mono.Metadata["tools"] != "cargo,bazel" {
+		t.Errorf("tools = %q", mono.Metadata["tools"])
+	}
+	if len(mono.Packages) != 2 {
+		t.Fatalf("packages = %+v", mono.Packages)
+	}
+	if p := mono.Packages[0]; p.Name != "acme-parser" || p.Build != "cargo build -p acme-parser" {
+		t.Errorf("cargo package = %+v", p)
+	}
+	if p := mono.Packages[1]; p.Path != "services/auth" || p.Test != "bazel test //services/auth/..." {
+		t.Errorf("bazel package = %+v", p)
+	}
+
+	text := conventionText(*mono)
+	for _, want := range []string{"## Monorepo", "`crates/parser` (acme-parser) build: `cargo build -p acme-parser`"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("convention text missing %q:\n%s", want, text)
+		}
+	}
+	if out := FormatDetectOutput([]ProjectType{*mono}); !strings.Contains(out, "services/auth") {
+		t.Errorf("detect output missing packages:\n%s", out)
+	}
+}
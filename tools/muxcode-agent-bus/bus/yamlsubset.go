@@ -0,0 +1,322 @@
+package bus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is one significant line of a YAML document.
+type yamlLine struct {
+	num    int // 1-based line number
+	indent int
+	text   string // without indentation and trailing comment
+}
+
+// parseYAMLSubset parses the block-style YAML subset used by hand-written
+// files such as simulation scenarios: nested maps and "- " lists, plain,
+// single- and double-quoted scalars, "|" literal blocks, flow lists
+// ([a, b]) and flow maps ({k: v}), and # comments. Anchors, tags, and
+// multi-document streams are not supported. Values decode to
+// map[string]interface{}, []interface{}, string, int64, float64, bool, or
+// nil, ready to be re-encoded as JSON.
+func parseYAMLSubset(data string) (interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if lead := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]; strings.Contains(lead, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(strings.TrimLeft(text, " ")), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+// stripYAMLComment removes a trailing "# comment" outside quotes.
+func stripYAMLComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && yamlQuoteStart(s, i):
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' '):
+			return strings.TrimRight(s[:i], " ")
+		}
+	}
+	return strings.TrimRight(s, " ")
+}
+
+// yamlQuoteStart reports whether the quote at s[i] opens a quoted scalar
+// rather than being an apostrophe inside plain text.
+func yamlQuoteStart(s string, i int) bool {
+	return i == 0 || strings.ContainsRune(" :[{,-", rune(s[i-1]))
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// isYAMLListItem reports whether a line starts a list item.
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the map or list starting at the current line, whose
+// entries are at indent.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+// list parses "- item" lines at indent.
+func (p *yamlParser) list(indent int) (interface{}, error) {
+	out := []interface{}{}
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent != indent || !isYAMLListItem(ln.text) {
+			break
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(ln.text, "-"))
+		if item == "" {
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				out = append(out, nil)
+				continue
+			}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		if _, _, ok := splitYAMLKey(item); ok && !strings.HasPrefix(item, "{") {
+			// "- key: value" starts a map whose keys align with "key"
+			p.lines[p.pos] = yamlLine{num: ln.num, indent: indent + len(ln.text) - len(item), text: item}
+			v, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		p.pos++
+		v, err := parseYAMLScalar(item, ln.num)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// mapping parses "key: value" lines at indent.
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	out := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent < indent {
+			break
+		}
+		if ln.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", ln.num)
+		}
+		if isYAMLListItem(ln.text) {
+			break
+		}
+		key, rest, ok := splitYAMLKey(ln.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", ln.num)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", ln.num, key)
+		}
+		p.pos++
+
+		switch {
+		case rest == "|":
+			out[key] = p.literal(indent)
+		case rest != "":
+			v, err := parseYAMLScalar(rest, ln.num)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+		case p.pos < len(p.lines) && (p.lines[p.pos].indent > indent ||
+			(p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text))):
+			// Nested block; a list may sit at the key's own indentation
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+		default:
+			out[key] = nil
+		}
+	}
+	return out, nil
+}
+
+// literal collects the lines of a "|" block scalar indented deeper than
+// indent, keeping their relative indentation.
+func (p *yamlParser) literal(indent int) string {
+	var body []string
+	base := -1
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		ln := p.lines[p.pos]
+		if base < 0 {
+			base = ln.indent
+		}
+		body = append(body, strings.Repeat(" ", ln.indent-base)+ln.text)
+		p.pos++
+	}
+	return strings.Join(body, "\n") + "\n"
+}
+
+// splitYAMLKey splits "key: value" or "key:" outside quotes and brackets.
+func splitYAMLKey(s string) (key, rest string, ok bool) {
+	var quote rune
+	depth := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			if i == 0 {
+				quote = r
+			}
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ':' && depth == 0 && (i == len(s)-1 || s[i+1] == ' '):
+			key = strings.TrimSpace(s[:i])
+			if u, err := unquoteYAML(key); err == nil {
+				key = u
+			}
+			return key, strings.TrimSpace(s[i+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+// unquoteYAML removes YAML double or single quotes from s.
+func unquoteYAML(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, fmt.Errorf("not quoted")
+}
+
+// parseYAMLScalar parses an inline value: a quoted or plain scalar, or a
+// flow list or map.
+func parseYAMLScalar(s string, line int) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'"):
+		v, err := unquoteYAML(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: malformed quoted string %s", line, s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("line %d: unterminated flow list", line)
+		}
+		out := []interface{}{}
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+			v, err := parseYAMLScalar(item, line)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("line %d: unterminated flow map", line)
+		}
+		out := map[string]interface{}{}
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+			key, rest, ok := splitYAMLKey(item)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected \"key: value\" in flow map, got %q", line, item)
+			}
+			v, err := parseYAMLScalar(rest, line)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+		}
+		return out, nil
+	}
+	switch s {
+	case "", "~", "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// splitYAMLFlow splits the inside of a flow collection on top-level commas.
+func splitYAMLFlow(s string) []string {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && yamlQuoteStart(s, i):
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
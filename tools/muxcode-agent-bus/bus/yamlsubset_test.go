@@ -0,0 +1,60 @@
+package bus
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseYAMLSubset(t *testing.T) {
+	doc := `# scenario
+name: "build: then test"
+count: 3
+ratio: 0.5
+on: true
+empty:
+tags: [a, 'b c', 2]
+opts: {speed: 2x, dry: false}
+agents:
+  build:
+    - on: build
+      run: ./build.sh   # trailing comment
+      send:
+      - {to: test, action: test}
+  test:
+    - on: "*"
+steps:
+- send:
+    to: build
+    action: build
+- tick: 5m
+note: |
+  line one
+    indented
+`
+	v, err := parseYAMLSubset(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(v)
+	want := `{"agents":{"build":[{"on":"build","run":"./build.sh","send":[{"action":"test","to":"test"}]}],"test":[{"on":"*"}]},` +
+		`"count":3,"empty":null,"name":"build: then test","note":"line one\n  indented\n","on":true,` +
+		`"opts":{"dry":false,"speed":"2x"},"ratio":0.5,"steps":[{"send":{"action":"build","to":"build"}},{"tick":"5m"}],` +
+		`"tags":["a","b c",2]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseYAMLSubset_Errors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"tab indent":    "a:\n\tb: 1\n",
+		"duplicate key": "a: 1\na: 2\n",
+		"bad indent":    "a: 1\n  b: 2\n",
+		"no colon":      "a: 1\nplain\n",
+		"unterminated":  "a: [1, 2\n",
+	} {
+		if _, err := parseYAMLSubset(doc); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
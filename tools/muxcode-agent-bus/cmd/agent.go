@@ -80,8 +80,12 @@ func agentRun(args []string) {
 	} else {
 		ollamaCfg.Model = bus.RoleModel(role)
 	}
+	// --url pins a single node; otherwise use the model's configured nodes
 	if url != "" {
 		ollamaCfg.BaseURL = url
+	} else {
+		ollamaCfg.BaseURLs = bus.OllamaNodeURLs(ollamaCfg.Model)
+		ollamaCfg.BaseURL = ollamaCfg.BaseURLs[0]
 	}
 
 	// BusRole is the bus identity (window name) — used for inbox, lock, send.
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Approve handles the "muxcode-agent-bus approve" subcommand.
+// Usage: muxcode-agent-bus approve <id> [--reason TEXT]
+func Approve(args []string) {
+	decideApproval("approve", args, true)
+}
+
+// Reject handles the "muxcode-agent-bus reject" subcommand.
+// Usage: muxcode-agent-bus reject <id> [--reason TEXT]
+func Reject(args []string) {
+	decideApproval("reject", args, false)
+}
+
+// decideApproval records a decision. An approved chain gate runs its held
+// steps in a detached process, as the chain's original sender.
+func decideApproval(name string, args []string, approve bool) {
+	usage := fmt.Sprintf("Usage: muxcode-agent-bus %s <id> [--reason TEXT]\n", name)
+	if len(args) < 1 || args[0] == "" || args[0][0] == '-' {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	id := args[0]
+	reason := ""
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--reason":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --reason requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			reason = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	session := bus.BusSession()
+	a, err := bus.DecideApproval(session, id, approve, bus.BusRole(), reason)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case a.Kind == bus.ApprovalChain && approve:
+		g := a.Chain
+		if err := deferChainSteps(session, a.From, []string{g.Event, g.Outcome}, g.ExitCode, g.Command, g.NoNotify, g.Step, true); err != nil {
+			fmt.Fprintf(os.Stderr, "Error releasing chain: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Approved %s: releasing the %s %s chain\n", a.ID, g.Event, g.Outcome)
+	case a.Kind == bus.ApprovalChain:
+		fmt.Printf("Rejected %s: held chain steps dropped\n", a.ID)
+	case a.Kind == bus.ApprovalCost:
+		fmt.Printf("%s %s: told %s about %s\n", capitalize(a.Status), a.ID, a.From, a.Cost.Command)
+	default:
+		fmt.Printf("%s %s at step %s\n", capitalize(a.Status), a.ID, a.Step)
+	}
+}
+
+// Approval handles the "muxcode-agent-bus approval" subcommand.
+func Approval(args []string) {
+	usage := "Usage: muxcode-agent-bus approval list [--all] [--json]\n"
+	if len(args) < 1 || args[0] != "list" {
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "Unknown approval subcommand: %s\n", args[0])
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	all, asJSON := false, false
+	for _, a := range args[1:] {
+		switch a {
+		case "--all":
+			all = true
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	as, err := bus.ReadApprovals(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading approvals: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if as == nil {
+			as = []bus.Approval{}
+		}
+		data, _ := json.MarshalIndent(as, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatApprovals(as, all))
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Audit handles the "muxcode-agent-bus audit" subcommand.
+func Audit(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus audit show [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "show":
+		auditShow(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown audit subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus audit show [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// auditShow handles: audit show [--role ROLE] [--op OP] [--since DURATION] [--limit N] [--json]
+func auditShow(args []string) {
+	usage := "Usage: muxcode-agent-bus audit show [--role ROLE] [--op OP] [--since DURATION] [--limit N] [--json]"
+	f := bus.AuditFilter{Limit: 50}
+	jsonOut := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--role", "--op", "--since", "--limit":
+			flag := args[i]
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", flag)
+				os.Exit(1)
+			}
+			i++
+			switch flag {
+			case "--role":
+				f.Role = args[i]
+			case "--op":
+				f.Op = args[i]
+			case "--since":
+				d, err := parseSinceDuration(args[i])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: --since must be a duration like 24h or 7d\n")
+					os.Exit(1)
+				}
+				f.Since = time.Now().Add(-d).Unix()
+			case "--limit":
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid --limit: %s\n", args[i])
+					os.Exit(1)
+				}
+				f.Limit = n
+			}
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	events, err := bus.ReadAuditLog(bus.BusSession(), f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOut {
+		if events == nil {
+			events = []bus.AuditEvent{}
+		}
+		data, _ := json.MarshalIndent(events, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatAuditLog(events))
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Bus handles the "muxcode-agent-bus bus" subcommand: encryption-at-rest
+// status and key rotation for the bus directory and memory.
+func Bus(args []string) {
+	usage := "Usage: muxcode-agent-bus bus <status|rekey>"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[1])
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	switch args[0] {
+	case "status":
+		fmt.Print(bus.FormatEncryptionStatus(bus.GetEncryptionStatus(session)))
+	case "rekey":
+		n, err := bus.Rekey(session)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if n > 0 {
+				fmt.Fprintf(os.Stderr, "%d files were re-encrypted before the error; run rekey again to finish.\n", n)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Generated a new bus key and re-encrypted %d files.\n", n)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown bus subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+}
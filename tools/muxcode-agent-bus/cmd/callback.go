@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Callback handles the "muxcode-agent-bus callback" subcommand.
+func Callback(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus callback list [--all] [--json]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		callbackList(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown callback subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus callback list [--all] [--json]\n")
+		os.Exit(1)
+	}
+}
+
+// callbackList handles: callback list [--all] [--json]
+func callbackList(args []string) {
+	all, asJSON := false, false
+	for _, a := range args {
+		switch a {
+		case "--all":
+			all = true
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus callback list [--all] [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	cbs, err := bus.ReadCallbacks(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading callbacks: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if cbs == nil {
+			cbs = []bus.Callback{}
+		}
+		data, _ := json.MarshalIndent(cbs, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatCallbacks(cbs, all))
+}
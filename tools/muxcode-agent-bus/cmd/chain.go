@@ -1,17 +1,43 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
 // Chain handles the "muxcode-agent-bus chain" subcommand.
 // Usage: muxcode-agent-bus chain <event_type> <outcome> [--exit-code N] [--command CMD] [--no-notify] [--dry-run]
+// Runs the on_<outcome> action and any matching steps; delayed steps run in
+// a detached process, and an action with an approval gate holds the rest of
+// the chain until approve releases it. A failure with retries left asks the
+// role to re-run the command instead (see bus.ScheduleChainRetry).
 // Exit codes: 0 = sent, 1 = error, 2 = no chain configured
+//
+//	muxcode-agent-bus chain graph [--dot] [--json]
+//	muxcode-agent-bus chain simulate <event_type> <outcome> [--exit-code N] [--command CMD] [--no-notify]
+//	muxcode-agent-bus chain retries [--json]
 func Chain(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "graph":
+			chainGraph(args[1:])
+			return
+		case "retries":
+			chainRetries(args[1:])
+			return
+		case "simulate":
+			chainSimulate(args[1:])
+			return
+		}
+	}
 	if len(args) < 2 {
 		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus chain <event_type> <outcome> [--exit-code N] [--command CMD] [--no-notify] [--dry-run]\n")
 		os.Exit(1)
@@ -25,6 +51,9 @@ func Chain(args []string) {
 	command := ""
 	noNotify := false
 	dryRun := false
+	fromStep := 0
+	resumed := false // running held steps: skip the span, analyst, and subscriptions
+	approved := false
 
 	for i := 0; i < len(remaining); i++ {
 		switch remaining[i] {
@@ -46,62 +75,207 @@ func Chain(args []string) {
 			noNotify = true
 		case "--dry-run":
 			dryRun = true
+		case "--from-step":
+			// Internal: resume a chain at a delayed step (see deferChainSteps)
+			if i+1 >= len(remaining) {
+				fmt.Fprintf(os.Stderr, "Error: --from-step requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(remaining[i])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --from-step must be a non-negative integer\n")
+				os.Exit(1)
+			}
+			fromStep = n
+			resumed = true
+		case "--approved":
+			// Internal: the gate at --from-step was approved (see cmd/approve.go)
+			approved = true
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", remaining[i])
 			os.Exit(1)
 		}
 	}
 
-	// Look up chain action
-	action := bus.ResolveChain(eventType, outcome)
-	if action == nil {
+	session := bus.BusSession()
+	from := bus.BusRole()
+
+	// A failure with retries left asks the role to re-run the command after
+	// the backoff instead of running the failure actions; a success resets
+	// the count.
+	retried := 0
+	if !resumed {
+		switch {
+		case outcome == "failure" && dryRun:
+			if attempt, delay, ok := bus.PlanChainRetry(session, from, eventType); ok {
+				fmt.Printf("chain: %s failure -> retry %d/%d after %s: send request:%s to %s\n",
+					eventType, attempt, bus.Config().EventChains[eventType].Retries, delay, eventType, from)
+				return
+			}
+		case outcome == "failure":
+			r, retrying, err := bus.ScheduleChainRetry(session, from, eventType, exitCode, command, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: chain retry: %v\n", err)
+			}
+			if retrying {
+				fmt.Printf("Retry %d/%d scheduled for %s\n", r.Attempts, bus.Config().EventChains[eventType].Retries,
+					time.Unix(r.Due, 0).Format("15:04:05"))
+				return
+			}
+			retried = r.Attempts
+		case outcome == "success" && !dryRun:
+			if err := bus.ResetChainRetry(session, from, eventType); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: chain retry: %v\n", err)
+			}
+		}
+	}
+
+	// Look up chain actions (on_<outcome> plus matching steps)
+	actions := bus.ResolveChainSteps(eventType, outcome, command)
+	if len(actions) == 0 {
 		os.Exit(2) // no chain configured
 	}
 
-	session := bus.BusSession()
-	from := bus.BusRole()
-	message := bus.ExpandMessage(action.Message, exitCode, command)
+	notifyAnalyst := bus.ChainShouldNotifyAnalyst(eventType, outcome) && !chainTargets(actions, "analyze")
 
 	if dryRun {
-		fmt.Printf("chain: %s %s -> send %s:%s to %s: %s\n",
-			eventType, outcome, action.Type, action.Action, action.SendTo, message)
-		if bus.ChainShouldNotifyAnalyst(eventType, outcome) && action.SendTo != "analyze" {
+		for _, action := range actions {
+			message := chainMessage(session, from, action.Message, exitCode, command)
+			delay := ""
+			if d := action.DelayDuration(); d > 0 {
+				delay = " after " + d.String()
+			}
+			if action.Plugin != "" {
+				fmt.Printf("chain: %s %s -> run plugin %s%s: %s\n", eventType, outcome, action.Plugin, delay, message)
+				continue
+			}
+			fmt.Printf("chain: %s %s -> send %s:%s to %s%s: %s\n",
+				eventType, outcome, action.Type, action.Action, action.SendTo, delay, message)
+			if action.Promote != "" {
+				if err := checkChainPromotion(session, action); err != nil {
+					fmt.Printf("chain:   promotion to %s blocked: %v\n", action.Promote, err)
+				} else {
+					fmt.Printf("chain:   promotion to %s allowed\n", action.Promote)
+				}
+			}
+			if g := action.Approval; g != nil {
+				if met, _ := bus.ApprovalConditionMet(bus.ExpandMessage(g.When, exitCode, command)); met {
+					fmt.Printf("chain:   requires approval by %s\n", approverOf(*g))
+				}
+			}
+			if !noNotify {
+				fmt.Printf("chain:   notify %s\n", action.SendTo)
+			}
+		}
+		if notifyAnalyst {
 			fmt.Printf("chain: notify analyst: %s %s: %s\n", eventType, outcome, command)
 		}
 		// Show subscription fan-out in dry-run
 		subs, _ := bus.ReadSubscriptions(session)
-		matched := bus.MatchSubscriptions(subs, eventType, outcome)
+		matched := bus.MatchSubscriptions(subs, eventType, outcome, from, exitCode, command)
 		if len(matched) > 0 {
 			fmt.Printf("chain: %d subscription(s) would fire:\n", len(matched))
 			for _, s := range matched {
 				payload := bus.ExpandSubscriptionMessage(s.Message, eventType, outcome, exitCode, command)
-				fmt.Printf("  -> %s:%s to %s: %s\n", "event", s.Action, s.Notify, payload)
+				fmt.Printf("  -> %s:%s to %s: %s\n", "event", s.Action, s.Targets(), payload)
 			}
 		}
 		return
 	}
 
-	// Send the chain message (no auto-CC — chain intermediates are redundant for edit)
-	msg := bus.NewMessage(from, action.SendTo, action.Type, action.Action, message, "")
-	if err := bus.SendNoCC(session, msg); err != nil {
-		fmt.Fprintf(os.Stderr, "Error sending chain message: %v\n", err)
-		os.Exit(1)
+	// Send the chain messages in order (no auto-CC — chain intermediates are
+	// redundant for edit). The first delayed step hands the rest of the chain
+	// to a detached process so the hook is not blocked; that process sleeps
+	// through later delays itself. A gated step holds the rest of the chain
+	// until approve re-runs it from that step. A step promoting to an
+	// environment whose predecessor is not verified stops the chain.
+	var traceID, parentSpan string
+	if !resumed {
+		traceID, parentSpan = bus.RecordChainSpan(session, from, eventType, outcome, exitCode, command)
 	}
+	for i := fromStep; i < len(actions); i++ {
+		action := actions[i]
+		if action.Promote != "" {
+			if err := checkChainPromotion(session, action); err != nil {
+				blocked := fmt.Sprintf("Promotion to %s blocked: %v", action.Promote, err)
+				if err := bus.SendNoCC(session, bus.NewMessage(from, "edit", "event", bus.PromotionBlockedAction, blocked, "")); err != nil {
+					fmt.Fprintf(os.Stderr, "Error sending chain message: %v\n", err)
+				}
+				fmt.Println(blocked)
+				break
+			}
+		}
+		if action.Approval != nil && !(approved && i == fromStep) {
+			held, err := holdChainForApproval(session, from, action, eventType, outcome, exitCode, command, noNotify, i)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error requesting approval: %v\n", err)
+				os.Exit(1)
+			}
+			if held != "" {
+				fmt.Printf("Holding %d step(s) for approval %s\n", len(actions)-i, held)
+				break
+			}
+		}
+		if d := action.DelayDuration(); d > 0 {
+			if !resumed {
+				if err := deferChainSteps(session, from, args[:2], exitCode, command, noNotify, i, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scheduling delayed chain steps: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Scheduled %d delayed step(s)\n", len(actions)-i)
+				break
+			}
+			time.Sleep(d)
+		}
 
-	if !noNotify {
-		_ = bus.Notify(session, action.SendTo)
+		if action.Plugin != "" {
+			out, err := bus.RunChainPlugin(session, from, action, eventType, outcome, exitCode, command)
+			if out != "" {
+				fmt.Println(out)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error running chain plugin %s: %v\n", action.Plugin, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Ran plugin %s\n", action.Plugin)
+			continue
+		}
+
+		message := chainMessage(session, from, action.Message, exitCode, command)
+		if retried > 0 {
+			message += fmt.Sprintf(" (still failing, retried %dx)", retried)
+		}
+		msg := bus.NewMessage(from, action.SendTo, action.Type, action.Action, message, "")
+		msg.TraceID, msg.ParentSpan = traceID, parentSpan
+		if err := bus.SendNoCC(session, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending chain message: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !noNotify {
+			_ = bus.Notify(session, action.SendTo)
+		}
+
+		fmt.Printf("Sent %s:%s to %s\n", action.Type, action.Action, action.SendTo)
 	}
 
-	fmt.Printf("Sent %s:%s to %s\n", action.Type, action.Action, action.SendTo)
+	// A resumed chain only sends its remaining steps
+	if resumed {
+		return
+	}
 
 	// Notify analyst if configured (outcome-conditional) — skip when chain action already targets analyze
-	if bus.ChainShouldNotifyAnalyst(eventType, outcome) && action.SendTo != "analyze" {
+	if notifyAnalyst {
 		var analystMsg string
 		switch outcome {
 		case "success":
 			analystMsg = fmt.Sprintf("%s succeeded: %s", capitalize(eventType), command)
 		case "failure":
 			analystMsg = fmt.Sprintf("%s FAILED (exit %s): %s", capitalize(eventType), exitCode, command)
+			if retried > 0 {
+				analystMsg += fmt.Sprintf(" (retried %dx)", retried)
+			}
 		case "unknown":
 			analystMsg = fmt.Sprintf("%s completed (exit code unknown): %s", capitalize(eventType), command)
 		}
@@ -126,6 +300,155 @@ func Chain(args []string) {
 	}
 }
 
+// chainGraph prints the configured chains and subscriptions as a tree, a
+// Graphviz digraph, or JSON edges.
+func chainGraph(args []string) {
+	format := "text"
+	for _, a := range args {
+		switch a {
+		case "--dot":
+			format = "dot"
+		case "--json":
+			format = "json"
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus chain graph [--dot] [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	subs, err := bus.ReadSubscriptions(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading subscriptions: %v\n", err)
+		os.Exit(1)
+	}
+	edges := bus.ChainGraph(subs)
+
+	switch format {
+	case "dot":
+		fmt.Print(bus.FormatChainDOT(edges))
+	case "json":
+		data, _ := json.MarshalIndent(edges, "", "  ")
+		fmt.Println(string(data))
+	default:
+		fmt.Print(bus.FormatChainGraph(edges))
+	}
+}
+
+// chainRetries prints failed commands awaiting or counting retries.
+func chainRetries(args []string) {
+	asJSON := false
+	for _, a := range args {
+		switch a {
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus chain retries [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	retries, err := bus.ReadChainRetries(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading chain retries: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(retries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatChainRetries(retries))
+}
+
+// chainSimulate prints what a chain would send and notify without sending.
+// Exits 2 when no chain action matches, like chain itself.
+func chainSimulate(args []string) {
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus chain simulate <event_type> <outcome> [--exit-code N] [--command CMD] [--no-notify]\n")
+		os.Exit(1)
+	}
+	for _, a := range args {
+		if a == "--dry-run" || a == "--from-step" || a == "--approved" {
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			os.Exit(1)
+		}
+	}
+	Chain(append(append([]string(nil), args...), "--dry-run"))
+}
+
+// chainTargets reports whether any action sends to role.
+func chainTargets(actions []bus.ChainAction, role string) bool {
+	for _, a := range actions {
+		if a.SendTo == role {
+			return true
+		}
+	}
+	return false
+}
+
+// holdChainForApproval requests approval for a gated action when its
+// condition is met, returning the approval ID, or "" when the gate does not
+// apply.
+func holdChainForApproval(session, from string, action bus.ChainAction, eventType, outcome, exitCode, command string, noNotify bool, step int) (string, error) {
+	met, err := bus.ApprovalConditionMet(bus.ExpandMessage(action.Approval.When, exitCode, command))
+	if err != nil || !met {
+		return "", err
+	}
+	message := chainMessage(session, from, action.Message, exitCode, command)
+	a, err := bus.RequestChainApproval(session, from, action, message, bus.ChainGate{
+		Event: eventType, Outcome: outcome, ExitCode: exitCode, Command: command, Step: step, NoNotify: noNotify,
+	})
+	return a.ID, err
+}
+
+// chainMessage expands a chain message template, including the failed tests
+// from the sender's latest parsed run of command.
+func chainMessage(session, from, template, exitCode, command string) string {
+	return bus.ExpandFailedTests(bus.ExpandMessage(template, exitCode, command), session, from, command)
+}
+
+// checkChainPromotion checks an action's promotion gate against the
+// deploy registry for the current HEAD commit.
+func checkChainPromotion(session string, action bus.ChainAction) error {
+	head, _ := gitOutput("rev-parse", "HEAD")
+	return bus.CheckPromotion(session, action.Promote, head)
+}
+
+// approverOf returns the role a gate asks, for display.
+func approverOf(g bus.ApprovalGate) string {
+	if g.Approver != "" {
+		return g.Approver
+	}
+	return "edit"
+}
+
+// deferChainSteps re-runs the chain in a detached process starting at step,
+// with the session and sender pinned so the resumed steps keep their origin.
+// approved marks the gate at step as already approved.
+func deferChainSteps(session, from string, event []string, exitCode, command string, noNotify bool, step int, approved bool) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := append([]string{"chain"}, event...)
+	args = append(args, "--exit-code", exitCode, "--command", command, "--from-step", strconv.Itoa(step))
+	if noNotify {
+		args = append(args, "--no-notify")
+	}
+	if approved {
+		args = append(args, "--approved")
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), "BUS_SESSION="+session, "AGENT_ROLE="+from)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
 // capitalize returns the string with the first letter uppercased.
 func capitalize(s string) string {
 	if s == "" {
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/watcher"
 )
 
 // Cleanup handles the "muxcode-agent-bus cleanup" subcommand.
@@ -17,6 +18,13 @@ func Cleanup(args []string) {
 		session = bus.BusSession()
 	}
 
+	// Stop the watcher first; it would outlive its bus dir otherwise
+	if watcher.Status(session).Running {
+		if err := watcher.StopDaemon(session); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: stopping watcher: %v\n", err)
+		}
+	}
+
 	busDir := bus.BusDir(session)
 	if err := bus.Cleanup(session); err != nil {
 		fmt.Fprintf(os.Stderr, "Error cleaning up: %v\n", err)
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// ConfigCmd handles the "muxcode-agent-bus config" subcommand.
+func ConfigCmd(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus config validate [FILE...] [--json]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "validate":
+		configValidate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus config validate [FILE...] [--json]\n")
+		os.Exit(1)
+	}
+}
+
+// configValidate handles: config validate [FILE...] [--json]
+// With no files, checks the project and user muxcode.json and every session
+// template. Files under a templates directory are checked as templates.
+// Exits 1 when any issue is found.
+func configValidate(args []string) {
+	jsonOut := false
+	var files []string
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			jsonOut = true
+		case len(arg) > 1 && arg[0] == '-':
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus config validate [FILE...] [--json]\n")
+			os.Exit(1)
+		default:
+			files = append(files, arg)
+		}
+	}
+
+	templates := map[string]bool{}
+	if len(files) == 0 {
+		files = bus.ConfigFiles()
+		names, _ := bus.ListSessionTemplates()
+		for _, name := range names {
+			path := bus.SessionTemplatePath(name)
+			files = append(files, path)
+			templates[path] = true
+		}
+	}
+	for _, f := range files {
+		if filepath.Base(filepath.Dir(f)) == "templates" {
+			templates[f] = true
+		}
+	}
+
+	sources := map[string][]byte{}
+	issues := []bus.ConfigIssue{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			issues = append(issues, bus.ConfigIssue{File: f, Message: err.Error()})
+			continue
+		}
+		sources[f] = data
+		if templates[f] {
+			issues = append(issues, bus.ValidateTemplateData(f, data)...)
+		} else {
+			issues = append(issues, bus.ValidateConfigData(f, data)...)
+		}
+	}
+
+	if jsonOut {
+		data, _ := json.MarshalIndent(issues, "", "  ")
+		fmt.Println(string(data))
+	} else if len(issues) == 0 {
+		fmt.Printf("%d file(s) OK\n", len(files))
+	} else {
+		fmt.Print(bus.FormatConfigIssues(issues, sources))
+		fmt.Printf("%d issue(s) in %d file(s)\n", len(issues), len(files))
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
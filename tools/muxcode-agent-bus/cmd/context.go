@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
@@ -119,8 +121,18 @@ func contextPrompt(args []string) {
 
 func contextDetect(args []string) {
 	dir := "."
-	if len(args) > 0 {
-		dir = args[0]
+	vars := false
+	for _, a := range args {
+		switch {
+		case a == "--vars":
+			vars = true
+		case strings.HasPrefix(a, "--"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus context detect [dir] [--vars]\n")
+			os.Exit(1)
+		default:
+			dir = a
+		}
 	}
 
 	// Resolve to absolute path for cleaner output
@@ -129,6 +141,12 @@ func contextDetect(args []string) {
 		dir = absDir
 	}
 
+	if vars {
+		data, _ := json.MarshalIndent(bus.BuildContextData(dir), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
 	types := bus.DetectProject(dir)
 	fmt.Print(bus.FormatDetectOutput(types))
 }
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const costUsage = `Usage: muxcode-agent-bus cost check [--role ROLE] [--dir DIR] [--json] [--] <command>
+       muxcode-agent-bus cost rules [--json]
+`
+
+// Cost handles the "muxcode-agent-bus cost" subcommand.
+// Usage: muxcode-agent-bus cost check [--role ROLE] [--dir DIR] [--json] [--] <command>
+//
+//	muxcode-agent-bus cost rules [--json]
+//
+// check exits 1 with the reason on stdout when the cost guard blocks the
+// command; a first attempt requests approval. The PreToolUse hook and the
+// harness run it before each command.
+func Cost(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, costUsage)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "check":
+		costCheck(args[1:])
+
+	case "rules":
+		asJSON := false
+		for _, a := range args[1:] {
+			if a != "--json" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+				fmt.Fprint(os.Stderr, costUsage)
+				os.Exit(1)
+			}
+			asJSON = true
+		}
+		cfg := bus.Config().CostGuard
+		if asJSON {
+			data, _ := json.MarshalIndent(cfg.RuleList(), "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		state := "disabled (set cost_guard.enabled)"
+		if cfg.Enabled {
+			state = "enabled"
+		}
+		fmt.Printf("Cost guard %s for roles: %s\n", state, strings.Join(cfg.RoleList(), ", "))
+		for _, r := range cfg.RuleList() {
+			line := fmt.Sprintf("  %-20s %s", r.Name, r.Match)
+			if r.MinResources > 0 {
+				line += fmt.Sprintf(" (cdk: %d+ resources)", r.MinResources)
+			}
+			if r.Approver != "" {
+				line += " approver " + r.Approver
+			}
+			fmt.Println(line)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cost subcommand: %s\n", args[0])
+		fmt.Fprint(os.Stderr, costUsage)
+		os.Exit(1)
+	}
+}
+
+// costCheck handles "cost check".
+func costCheck(args []string) {
+	role, dir, asJSON := bus.BusRole(), "", false
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--role", "--dir":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			if args[i] == "--role" {
+				role = args[i+1]
+			} else {
+				dir = args[i+1]
+			}
+			i++
+		case "--json":
+			asJSON = true
+		case "--":
+			rest = append(rest, args[i+1:]...)
+			i = len(args)
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, costUsage)
+				os.Exit(1)
+			}
+			rest = append(rest, args[i:]...)
+			i = len(args)
+		}
+	}
+	command := strings.TrimSpace(strings.Join(rest, " "))
+	if command == "" {
+		fmt.Fprint(os.Stderr, costUsage)
+		os.Exit(1)
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	check, err := bus.CheckCostGuard(bus.BusSession(), role, command, dir, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(check, "", "  ")
+		fmt.Println(string(data))
+	} else if !check.Allowed {
+		fmt.Printf("BLOCKED: %s\n", check.Reason)
+	} else if check.Approval != "" {
+		fmt.Printf("Allowed by approval %s\n", check.Approval)
+	}
+	if !check.Allowed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Coverage handles the "muxcode-agent-bus coverage" subcommand.
+// Usage: muxcode-agent-bus coverage trend [role] [--limit N] [--json]
+func Coverage(args []string) {
+	usage := "Usage: muxcode-agent-bus coverage trend [role] [--limit N] [--json]\n"
+	if len(args) < 1 || args[0] != "trend" {
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "Unknown coverage subcommand: %s\n", args[0])
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	role := ""
+	limit := 10
+	asJSON := false
+	rest := args[1:]
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case "--limit":
+			if i+1 >= len(rest) {
+				fmt.Fprintf(os.Stderr, "Error: --limit requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(rest[i])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --limit must be a non-negative integer\n")
+				os.Exit(1)
+			}
+			limit = n
+		case "--json":
+			asJSON = true
+		default:
+			if rest[i] == "" || rest[i][0] == '-' || role != "" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", rest[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			role = rest[i]
+		}
+	}
+
+	records, err := bus.ReadCoverage(bus.BusSession(), role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading coverage: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if records == nil {
+			records = []bus.CoverageRecord{}
+		}
+		data, _ := json.MarshalIndent(records, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatCoverageTrend(records, limit))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
@@ -39,36 +40,100 @@ func Cron(args []string) {
 	}
 }
 
-// cronAdd handles: cron add "@every 5m" commit status "Run git status and report"
+// cronAdd handles: cron add [--tz ZONE] [--misfire POLICY] "@every 5m" commit status "Run git status and report"
+// and one-shot entries: cron add --in 1h deploy check "Check the canary deploy"
 func cronAdd(args []string) {
-	if len(args) < 4 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus cron add <schedule> <target> <action> <message>\n")
-		fmt.Fprintf(os.Stderr, "  schedule: @every 30s, @every 5m, @hourly, @daily, @half-hourly\n")
-		fmt.Fprintf(os.Stderr, "  target:   agent role (build, test, commit, etc.)\n")
+	tz, at, in, misfire := "", "", "", ""
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		if len(positional) == 0 && (args[i] == "--tz" || args[i] == "--at" || args[i] == "--in" || args[i] == "--misfire") {
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			switch args[i] {
+			case "--tz":
+				tz = args[i+1]
+			case "--at":
+				at = args[i+1]
+			case "--in":
+				in = args[i+1]
+			case "--misfire":
+				misfire = args[i+1]
+			}
+			i++
+			continue
+		}
+		positional = append(positional, args[i])
+	}
+
+	oneShot := at != "" || in != ""
+	minArgs := 4
+	if oneShot {
+		minArgs = 3
+	}
+	if len(positional) < minArgs || (at != "" && in != "") {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus cron add [--tz ZONE] [--misfire POLICY] <schedule> <target> <action> <message>\n")
+		fmt.Fprintf(os.Stderr, "       muxcode-agent-bus cron add [--tz ZONE] [--misfire POLICY] --at TIME|--in DURATION <target> <action> <message>\n")
+		fmt.Fprintf(os.Stderr, "  schedule: @every 30s, @every 5m, @hourly, @daily, @half-hourly,\n")
+		fmt.Fprintf(os.Stderr, "            or a 5-field cron expression (\"30 9 * * 1-5\")\n")
+		fmt.Fprintf(os.Stderr, "  target:   agent role (build, test, commit, etc.), or digest to email the\n")
+		fmt.Fprintf(os.Stderr, "            session digest (message: window such as 24h, or \"\" for the default)\n")
+		fmt.Fprintf(os.Stderr, "  --tz:     IANA time zone for cron expressions and --at (default: local)\n")
+		fmt.Fprintf(os.Stderr, "  --at:     fire once at a time (2025-07-01T09:00), then disable\n")
+		fmt.Fprintf(os.Stderr, "  --in:     fire once after a delay (45m, 2h), then disable\n")
+		fmt.Fprintf(os.Stderr, "  --misfire: runs missed while the watcher was down: skip, fire_once (default), fire_all\n")
 		os.Exit(1)
 	}
 
-	schedule := args[0]
-	target := args[1]
-	action := args[2]
-	message := strings.Join(args[3:], " ")
+	entry := bus.CronEntry{TZ: tz, Misfire: misfire}
+	if oneShot {
+		runAt, err := cronRunAt(at, in, tz)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		entry.Schedule = bus.OnceSchedule
+		entry.RunAt = runAt.Unix()
+	} else {
+		entry.Schedule = positional[0]
+		positional = positional[1:]
+	}
+	entry.Target = positional[0]
+	entry.Action = positional[1]
+	entry.Message = strings.Join(positional[2:], " ")
 
 	session := bus.BusSession()
 
-	entry, err := bus.AddCronEntry(session, bus.CronEntry{
-		Schedule: schedule,
-		Target:   target,
-		Action:   action,
-		Message:  message,
-	})
+	entry, err := bus.AddCronEntry(session, entry)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error adding cron entry: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("Added cron entry: %s\n", entry.ID)
-	fmt.Printf("  Schedule: %s  Target: %s  Action: %s\n", schedule, target, action)
-	fmt.Printf("  Message: %s\n", message)
+	fmt.Printf("  Schedule: %s  Target: %s  Action: %s\n", entry.Schedule, entry.Target, entry.Action)
+	fmt.Printf("  Message: %s\n", entry.Message)
+	if next, ok := bus.NextRun(entry, entry.CreatedAt); ok {
+		fmt.Printf("  Next run: %s\n", next.Format("2006-01-02 15:04 MST"))
+	}
+}
+
+// cronRunAt resolves --at / --in into an absolute fire time.
+func cronRunAt(at, in, tz string) (time.Time, error) {
+	now := time.Now()
+	if in != "" {
+		d, err := time.ParseDuration(in)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid --in duration %q", in)
+		}
+		return now.Add(d), nil
+	}
+	loc, err := bus.CronLocation(bus.CronEntry{TZ: tz})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time zone: %v", err)
+	}
+	return bus.ParseRunAt(at, loc, now)
 }
 
 // cronList handles: cron list [--all]
@@ -11,8 +11,9 @@ import (
 
 // Dashboard handles the "muxcode-agent-bus dashboard" subcommand.
 // Usage: muxcode-agent-bus dashboard [--refresh N]
+// Without --refresh the "dashboard" config section's interval is used.
 func Dashboard(args []string) {
-	refresh := 5
+	refresh := 0
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -42,6 +43,10 @@ func Dashboard(args []string) {
 		os.Exit(1)
 	}
 
+	if err := bus.Config().Dashboard.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	session := bus.BusSession()
 	if session == "" {
 		fmt.Fprintln(os.Stderr, "Could not determine tmux session name.")
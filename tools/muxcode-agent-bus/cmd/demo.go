@@ -3,7 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
@@ -11,7 +15,7 @@ import (
 // Demo handles the "muxcode-agent-bus demo" subcommand.
 func Demo(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus demo <run|list> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus demo <run|list|export|record|replay> [args...]\n")
 		os.Exit(1)
 	}
 
@@ -23,9 +27,15 @@ func Demo(args []string) {
 		demoRun(subArgs)
 	case "list":
 		demoList(subArgs)
+	case "export":
+		demoExport(subArgs)
+	case "record":
+		demoRecord(subArgs)
+	case "replay":
+		demoReplay(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown demo subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus demo <run|list> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus demo <run|list|export|record|replay> [args...]\n")
 		os.Exit(1)
 	}
 }
@@ -47,12 +57,7 @@ func demoRun(args []string) {
 				os.Exit(1)
 			}
 			i++
-			v, err := strconv.ParseFloat(args[i], 64)
-			if err != nil || v <= 0 {
-				fmt.Fprintf(os.Stderr, "Error: --speed must be a positive number\n")
-				os.Exit(1)
-			}
-			speed = v
+			speed = parseDemoSpeed(args[i])
 		case "--dry-run":
 			dryRun = true
 		case "--no-switch":
@@ -92,8 +97,209 @@ func demoRun(args []string) {
 	}
 }
 
+// parseDemoSpeed parses a --speed value such as "2", "0.5" or "2x", exiting
+// on anything that is not a positive number.
+func parseDemoSpeed(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || v <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --speed must be a positive number (e.g. 2 or 2x)\n")
+		os.Exit(1)
+	}
+	return v
+}
+
 // demoList handles: demo list
 func demoList(args []string) {
-	scenarios := bus.BuiltinScenarios()
+	scenarios := bus.AllScenarios()
 	fmt.Print(bus.FormatScenarioList(scenarios))
+	fmt.Print(bus.FormatDemoRecordings(bus.ListDemoRecordings()))
+}
+
+// demoRecord handles: demo record NAME [--duration DURATION]
+// Captures the live session until interrupted or the duration elapses.
+func demoRecord(args []string) {
+	usage := "Usage: muxcode-agent-bus demo record NAME [--duration DURATION]\n"
+	name := ""
+	var duration time.Duration
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--duration":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --duration requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: --duration must be a positive duration (e.g. 10m)\n")
+				os.Exit(1)
+			}
+			duration = d
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			if name != "" {
+				fmt.Fprintf(os.Stderr, "Unexpected argument: %s\n", args[i])
+				os.Exit(1)
+			}
+			name = args[i]
+		}
+	}
+	if name == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	rec, err := bus.NewDemoRecorder(session, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Recording session %s to %s — press Ctrl-C to stop\n", session, rec.Path())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	var deadline <-chan time.Time
+	if duration > 0 {
+		deadline = time.After(duration)
+	}
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-sigCh:
+			break loop
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+			if n, err := rec.Poll(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				break loop
+			} else if n > 0 {
+				fmt.Printf("  %s  %d event(s) captured (%d total)\n", time.Now().Format("15:04:05"), n, rec.Count)
+			}
+		}
+	}
+
+	if err := rec.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nRecorded %d events to %s\n", rec.Count, rec.Path())
+	fmt.Printf("Replay with: muxcode-agent-bus demo replay %s\n", name)
+}
+
+// demoReplay handles: demo replay NAME [--speed FACTOR] [--dry-run]
+func demoReplay(args []string) {
+	usage := "Usage: muxcode-agent-bus demo replay NAME [--speed FACTOR] [--dry-run]\n"
+	opts := bus.DemoOptions{Speed: 1.0}
+	name := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--speed":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --speed requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			opts.Speed = parseDemoSpeed(args[i])
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			if name != "" {
+				fmt.Fprintf(os.Stderr, "Unexpected argument: %s\n", args[i])
+				os.Exit(1)
+			}
+			name = args[i]
+		}
+	}
+	if name == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	rec, err := bus.LoadDemoRecording(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := bus.ReplayDemoRecording(bus.BusSession(), rec, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// demoExport handles: demo export --from-session X [--window 2h] [--name NAME] [--out DIR] [--anonymize] [--max-delay 5s]
+func demoExport(args []string) {
+	usage := "Usage: muxcode-agent-bus demo export --from-session SESSION [--window DURATION] [--name NAME] [--out DIR] [--anonymize] [--max-delay DURATION]\n"
+	opts := bus.DemoExportOptions{}
+	outDir := bus.DemoDir()
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from-session", "--window", "--name", "--out", "--max-delay":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			flag, val := args[i], args[i+1]
+			i++
+			switch flag {
+			case "--from-session":
+				opts.Session = val
+			case "--name":
+				opts.Name = val
+			case "--out":
+				outDir = val
+			case "--window", "--max-delay":
+				d, err := time.ParseDuration(val)
+				if err != nil || d <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: %s must be a positive duration (e.g. 2h, 30m)\n", flag)
+					os.Exit(1)
+				}
+				if flag == "--window" {
+					opts.Window = d
+				} else {
+					opts.MaxDelay = d
+				}
+			}
+		case "--anonymize":
+			opts.Anonymize = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	if opts.Session == "" {
+		opts.Session = bus.BusSession()
+	}
+
+	export, err := bus.ExportSessionDemo(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	jsonPath, mdPath, err := bus.WriteDemoExport(outDir, export)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Exported %d messages as %q (%d steps)\n", export.Messages, export.Scenario.Name, len(export.Scenario.Steps))
+	fmt.Printf("  Scenario:  %s\n", jsonPath)
+	fmt.Printf("  Narrative: %s\n", mdPath)
+	fmt.Printf("Replay with: muxcode-agent-bus demo run %s\n", jsonPath)
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const deployUsage = `Usage: muxcode-agent-bus deploy record <env> [--status deployed|failed|verified|verify-failed] [--commit SHA] [--by WHO] [--command CMD] [--note TEXT]
+       muxcode-agent-bus deploy status [--json]
+       muxcode-agent-bus deploy check <env> [--commit SHA]
+`
+
+// Deploy handles the "muxcode-agent-bus deploy" subcommand.
+// Usage: muxcode-agent-bus deploy record <env> [--status S] [--commit SHA] [--by WHO] [--command CMD] [--note TEXT]
+//
+//	muxcode-agent-bus deploy status [--json]
+//	muxcode-agent-bus deploy check <env> [--commit SHA]
+//
+// record defaults the commit to git HEAD for deploys and "by" to the
+// calling role. check exits 1 when promotion to env is blocked.
+func Deploy(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, deployUsage)
+		os.Exit(1)
+	}
+	session := bus.BusSession()
+	switch args[0] {
+	case "record":
+		deployRecord(session, args[1:])
+
+	case "status":
+		asJSON := false
+		for _, a := range args[1:] {
+			if a != "--json" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+				fmt.Fprint(os.Stderr, deployUsage)
+				os.Exit(1)
+			}
+			asJSON = true
+		}
+		envs, err := bus.ReadDeployStatus(session)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading deploys: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			data, _ := json.MarshalIndent(envs, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Print(bus.FormatDeployStatus(envs))
+
+	case "check":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, deployUsage)
+			os.Exit(1)
+		}
+		env, commit := args[1], ""
+		rest := args[2:]
+		for i := 0; i < len(rest); i++ {
+			switch rest[i] {
+			case "--commit":
+				if i+1 >= len(rest) {
+					fmt.Fprintln(os.Stderr, "Error: --commit requires a value")
+					os.Exit(1)
+				}
+				i++
+				commit = rest[i]
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", rest[i])
+				fmt.Fprint(os.Stderr, deployUsage)
+				os.Exit(1)
+			}
+		}
+		if err := bus.CheckPromotion(session, env, commit); err != nil {
+			fmt.Printf("Promotion to %s blocked: %v\n", env, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Promotion to %s allowed\n", env)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown deploy subcommand: %s\n", args[0])
+		fmt.Fprint(os.Stderr, deployUsage)
+		os.Exit(1)
+	}
+}
+
+// deployRecord handles "deploy record".
+func deployRecord(session string, args []string) {
+	if len(args) < 1 || args[0] == "" || args[0][0] == '-' {
+		fmt.Fprint(os.Stderr, deployUsage)
+		os.Exit(1)
+	}
+	r := bus.DeployRecord{Env: args[0], By: bus.BusRole()}
+	rest := args[1:]
+	for i := 0; i < len(rest); i++ {
+		var field *string
+		switch rest[i] {
+		case "--status":
+			field = &r.Status
+		case "--commit":
+			field = &r.Commit
+		case "--by":
+			field = &r.By
+		case "--command":
+			field = &r.Command
+		case "--note":
+			field = &r.Note
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", rest[i])
+			fmt.Fprint(os.Stderr, deployUsage)
+			os.Exit(1)
+		}
+		if i+1 >= len(rest) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", rest[i])
+			os.Exit(1)
+		}
+		i++
+		*field = rest[i]
+	}
+	if r.Commit == "" && r.Status != bus.DeployVerified && r.Status != bus.DeployVerifyFailed {
+		r.Commit, _ = gitOutput("rev-parse", "HEAD")
+	}
+	if err := bus.RecordDeploy(session, r); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	status := r.Status
+	if status == "" {
+		status = bus.DeployDeployed
+	}
+	fmt.Printf("Recorded %s %s\n", r.Env, status)
+}
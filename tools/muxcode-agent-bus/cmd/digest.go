@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Digest handles the "muxcode-agent-bus digest" subcommand: compile recent
+// history, alerts, and finished procs, and print or email them.
+// Usage: muxcode-agent-bus digest [--hours N] [--format html|markdown] [--send] [--to ADDR]...
+func Digest(args []string) {
+	usage := "Usage: muxcode-agent-bus digest [--hours N] [--format html|markdown] [--send] [--to ADDR]..."
+	cfg := bus.Config().Digest
+	window := cfg.Window()
+	format := ""
+	send := false
+	var to []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--hours":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --hours requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --hours must be a positive number\n")
+				os.Exit(1)
+			}
+			window = time.Duration(n) * time.Hour
+		case "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --format requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			format = args[i]
+			if format != bus.DigestHTML && format != bus.DigestMarkdown {
+				fmt.Fprintf(os.Stderr, "Error: --format must be html or markdown\n")
+				os.Exit(1)
+			}
+		case "--send":
+			send = true
+		case "--to":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --to requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			to = append(to, args[i])
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	session := bus.BusSession()
+	now := time.Now()
+
+	if send {
+		if format != "" {
+			cfg.Format = format
+		}
+		d, err := bus.SendDigest(session, cfg, window, to, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		recipients := cfg.To
+		if len(to) > 0 {
+			recipients = to
+		}
+		fmt.Printf("Sent digest to %s: %s\n", strings.Join(recipients, ", "), d.Subject())
+		return
+	}
+
+	// Printing: Markdown reads better in a terminal unless asked otherwise
+	if format == "" {
+		format = bus.DigestMarkdown
+	}
+	d := bus.BuildDigest(session, now.Add(-window).Unix(), now.Unix())
+	fmt.Print(bus.FormatDigest(d, format))
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Docs handles the "muxcode-agent-bus docs" subcommand.
+func Docs(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus docs <handbook> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "handbook":
+		docsHandbook(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown docs subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus docs <handbook> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// docsHandbook renders a role handbook to stdout or a file, or serves all
+// handbooks over HTTP.
+// Usage: muxcode-agent-bus docs handbook <role> [--out FILE] | --serve [ADDR]
+func docsHandbook(args []string) {
+	usage := "Usage: muxcode-agent-bus docs handbook <role> [--out FILE] | --serve [ADDR]\n"
+	role := ""
+	outFile := ""
+	serve := false
+	addr := "127.0.0.1:9091"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--out":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --out requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			outFile = args[i]
+		case "--serve":
+			serve = true
+			if i+1 < len(args) && args[i+1] != "" && args[i+1][0] != '-' {
+				i++
+				addr = args[i]
+			}
+		default:
+			if args[i] != "" && args[i][0] == '-' {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			role = args[i]
+		}
+	}
+
+	if serve {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			cancel()
+		}()
+
+		fmt.Printf("Serving agent handbooks at http://%s/\n", addr)
+		if err := bus.ServeHandbooks(ctx, addr, bus.KnownRoles); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if role == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	md := bus.FormatHandbook(bus.BuildHandbook(role))
+	if outFile == "" {
+		fmt.Print(md)
+		return
+	}
+	if err := os.WriteFile(outFile, []byte(md), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", outFile, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s handbook to %s\n", role, outFile)
+}
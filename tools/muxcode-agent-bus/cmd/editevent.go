@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// EditEvent handles the "muxcode-agent-bus edit-event" subcommand.
+// Usage: muxcode-agent-bus edit-event < hook-payload.json
+//
+// Reads a PostToolUse hook payload (Write/Edit/MultiEdit/NotebookEdit) from
+// stdin and appends a JSONL edit event to the session's edit log for the
+// watcher. Called by muxcode-analyze-hook.sh.
+func EditEvent(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Usage: muxcode-agent-bus edit-event < hook-payload.json")
+		os.Exit(1)
+	}
+
+	payload, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		os.Exit(1)
+	}
+	ev, err := bus.NewEditEvent(payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := bus.AppendEditEvent(bus.BusSession(), ev); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing edit log: %v\n", err)
+		os.Exit(1)
+	}
+}
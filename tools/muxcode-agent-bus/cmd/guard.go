@@ -1,20 +1,39 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
 // Guard handles the "muxcode-agent-bus guard" subcommand.
-// Usage: muxcode-agent-bus guard [role] [--json] [--threshold N] [--window N]
+// Usage: muxcode-agent-bus guard [role] [--json] [--threshold N] [--window N] [--similarity F]
+//
+//	muxcode-agent-bus guard budget [role] [--json]
+//	muxcode-agent-bus guard resume <role>
 func Guard(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "budget":
+			guardBudget(args[1:])
+			return
+		case "resume":
+			guardResume(args[1:])
+			return
+		}
+	}
+
 	role := ""
 	jsonOutput := false
-	threshold := 0 // 0 means use defaults (3 for commands, 4 for messages)
-	windowSecs := int64(300)
+	// Zero values keep each role's configured limits
+	threshold := 0
+	windowSecs := int64(0)
+	similarity := 0.0
 
 	remaining := args
 	for i := 0; i < len(remaining); i++ {
@@ -45,10 +64,22 @@ func Guard(args []string) {
 				os.Exit(1)
 			}
 			windowSecs = n
+		case "--similarity":
+			if i+1 >= len(remaining) {
+				fmt.Fprintf(os.Stderr, "Error: --similarity requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			f, err := strconv.ParseFloat(remaining[i], 64)
+			if err != nil || f <= 0 || f > 1 {
+				fmt.Fprintf(os.Stderr, "Error: --similarity must be between 0 and 1\n")
+				os.Exit(1)
+			}
+			similarity = f
 		default:
 			if remaining[i][0] == '-' {
 				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", remaining[i])
-				fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus guard [role] [--json] [--threshold N] [--window N]\n")
+				fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus guard [role] [--json] [--threshold N] [--window N] [--similarity F]\n")
 				os.Exit(1)
 			}
 			role = remaining[i]
@@ -59,10 +90,10 @@ func Guard(args []string) {
 
 	var alerts []bus.LoopAlert
 	if role != "" {
-		alerts = checkRole(session, role, threshold, windowSecs)
+		alerts = checkRole(session, role, threshold, windowSecs, similarity)
 	} else {
 		for _, r := range bus.KnownRoles {
-			alerts = append(alerts, checkRole(session, r, threshold, windowSecs)...)
+			alerts = append(alerts, checkRole(session, r, threshold, windowSecs, similarity)...)
 		}
 	}
 
@@ -82,31 +113,72 @@ func Guard(args []string) {
 	}
 }
 
-// checkRole runs loop detection for a single role with optional threshold overrides.
-func checkRole(session, role string, threshold int, windowSecs int64) []bus.LoopAlert {
-	var alerts []bus.LoopAlert
-
-	// Command loop detection
-	cmdThreshold := 3
+// checkRole runs loop detection for a single role, applying flag overrides
+// on top of the role's configured limits.
+func checkRole(session, role string, threshold int, windowSecs int64, similarity float64) []bus.LoopAlert {
+	limits := bus.GuardLimitsFor(role)
 	if threshold > 0 {
-		cmdThreshold = threshold
+		limits.CommandThreshold = threshold
+		limits.MessageThreshold = threshold
+	}
+	if windowSecs > 0 {
+		limits.Window = windowSecs
 	}
-	entries := bus.ReadHistory(session, role, 20)
-	if alert := bus.DetectCommandLoop(entries, cmdThreshold, windowSecs); alert != nil {
-		alert.Role = role
-		alerts = append(alerts, *alert)
+	if similarity > 0 {
+		limits.Similarity = similarity
 	}
+	return bus.CheckLoopsWith(session, role, limits)
+}
 
-	// Message loop detection
-	msgThreshold := 4
-	if threshold > 0 {
-		msgThreshold = threshold
+// guardBudget handles: guard budget [role] [--json]
+func guardBudget(args []string) {
+	role := ""
+	jsonOutput := false
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			jsonOutput = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus guard budget [role] [--json]\n")
+			os.Exit(1)
+		default:
+			role = arg
+		}
 	}
-	messages := bus.ReadLogHistory(session, role, 50)
-	if alert := bus.DetectMessageLoop(messages, role, msgThreshold, windowSecs); alert != nil {
-		alert.Role = role
-		alerts = append(alerts, *alert)
+
+	session := bus.BusSession()
+	roles := bus.KnownRoles
+	if role != "" {
+		roles = []string{role}
+	}
+	since := time.Now().Unix() - 3600
+	var usages []bus.RoleUsage
+	for _, r := range roles {
+		usages = append(usages, bus.ReadUsage(session, r, since))
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(usages, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
 	}
+	fmt.Print(bus.FormatUsage(usages, session))
+}
 
-	return alerts
+// guardResume handles: guard resume <role>
+func guardResume(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus guard resume <role>\n")
+		os.Exit(1)
+	}
+	if err := bus.ResumeHarness(bus.BusSession(), args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resuming %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("Resumed harness: %s\n", args[0])
 }
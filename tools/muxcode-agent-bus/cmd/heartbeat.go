@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const heartbeatUsage = "Usage: muxcode-agent-bus heartbeat [role] [--pid PID] [--interval SECS] | heartbeat list"
+
+// Heartbeat handles the "muxcode-agent-bus heartbeat" subcommand.
+//
+//	heartbeat [role]                 touch the role's heartbeat once
+//	heartbeat [role] --pid PID       touch it every interval while PID lives,
+//	                                 then mark it exited (run by muxcode-agent.sh)
+//	heartbeat list                   show every role's heartbeat age and state
+//	                                 and any active failovers
+func Heartbeat(args []string) {
+	session := bus.BusSession()
+	if len(args) > 0 && args[0] == "list" {
+		cfg := bus.Config().Heartbeat
+		now := time.Now().Unix()
+		fmt.Print(bus.FormatHeartbeats(bus.CheckHeartbeats(session, now, cfg.Stale())))
+		if fos := bus.ReadFailovers(session); len(fos) > 0 {
+			fmt.Print("\nFailovers:\n" + bus.FormatFailovers(fos, now))
+		}
+		return
+	}
+
+	role := bus.BusRole()
+	pid := 0
+	interval := bus.DefaultHeartbeatInterval
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--pid", "--interval":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a value\n", args[i])
+				fmt.Fprintln(os.Stderr, heartbeatUsage)
+				os.Exit(1)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Invalid %s: %s\n", args[i], args[i+1])
+				os.Exit(1)
+			}
+			if args[i] == "--pid" {
+				pid = n
+			} else {
+				interval = time.Duration(n) * time.Second
+			}
+			i++
+		default:
+			if len(args[i]) > 1 && args[i][0] == '-' {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprintln(os.Stderr, heartbeatUsage)
+				os.Exit(1)
+			}
+			role = args[i]
+		}
+	}
+
+	// The bus may not exist yet when an agent starts before "init"
+	if _, err := os.Stat(bus.BusDir(session)); err != nil {
+		os.Exit(0)
+	}
+
+	var err error
+	if pid > 0 {
+		err = bus.RunHeartbeat(session, role, pid, interval)
+	} else {
+		err = bus.TouchHeartbeat(session, role, os.Getppid())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing heartbeat: %v\n", err)
+		os.Exit(1)
+	}
+}
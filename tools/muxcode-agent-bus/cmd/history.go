@@ -1,20 +1,36 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
 // History handles the "muxcode-agent-bus history" subcommand.
 // Usage: muxcode-agent-bus history <role> [--limit N] [--context]
+//
+//	muxcode-agent-bus history report [--since DURATION] [--format text|md|json]
+//	muxcode-agent-bus history flaky [--json]
 func History(args []string) {
 	if len(args) < 1 {
 		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus history <role> [--limit N] [--context]\n")
+		fmt.Fprintf(os.Stderr, "       muxcode-agent-bus history report [--since DURATION] [--format text|md|json]\n")
+		fmt.Fprintf(os.Stderr, "       muxcode-agent-bus history flaky [--json]\n")
 		os.Exit(1)
 	}
+	if args[0] == "report" {
+		historyReport(args[1:])
+		return
+	}
+	if args[0] == "flaky" {
+		historyFlaky(args[1:])
+		return
+	}
 
 	role := args[0]
 	limit := 20
@@ -66,3 +82,106 @@ func History(args []string) {
 		fmt.Print(bus.FormatHistory(msgs, role))
 	}
 }
+
+// historyReport handles "history report": command success rates, mean
+// durations, most-failed commands, loop alerts, and message volume.
+func historyReport(args []string) {
+	usage := "Usage: muxcode-agent-bus history report [--since DURATION] [--format text|md|json]"
+	since := 24 * time.Hour
+	format := "text"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --since requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := parseSinceDuration(args[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --since must be a duration like 24h or 7d\n")
+				os.Exit(1)
+			}
+			since = d
+		case "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --format requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			format = args[i]
+			if format != "text" && format != "md" && format != "json" {
+				fmt.Fprintf(os.Stderr, "Error: --format must be text, md, or json\n")
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	now := time.Now().Unix()
+	report := bus.BuildHistoryReport(bus.BusSession(), now-int64(since.Seconds()), now)
+
+	switch format {
+	case "md":
+		fmt.Print(bus.FormatHistoryReportMarkdown(report))
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	default:
+		fmt.Print(bus.FormatHistoryReport(report))
+	}
+}
+
+// historyFlaky handles "history flaky": the session's flaky test suspects.
+func historyFlaky(args []string) {
+	asJSON := false
+	for _, a := range args {
+		switch a {
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintln(os.Stderr, "Usage: muxcode-agent-bus history flaky [--json]")
+			os.Exit(1)
+		}
+	}
+
+	suspects, err := bus.ReadFlakySuspects(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading flaky suspects: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if suspects == nil {
+			suspects = []bus.FlakySuspect{}
+		}
+		data, _ := json.MarshalIndent(suspects, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatFlakySuspects(suspects))
+}
+
+// parseSinceDuration parses a Go duration, also accepting whole days ("7d").
+func parseSinceDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
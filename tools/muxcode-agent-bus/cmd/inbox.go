@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -9,11 +10,33 @@ import (
 )
 
 // Inbox handles the "muxcode-agent-bus inbox" subcommand.
+//
+//	muxcode-agent-bus inbox [--peek] [--unread-only] [--raw] [--role ROLE] [--fetch-attachments] [--remote USER@HOST[:SESSION]]
+//	muxcode-agent-bus inbox mark-read [--role ROLE] [--id ID]
+//	muxcode-agent-bus inbox receipts [--role ROLE] [--limit N] [--json]
+//
+// With --remote, the command runs against the remote session over SSH.
 func Inbox(args []string) {
+	if remote, rest := splitRemote(args); remote != "" {
+		runRemote(remote, "inbox", rest)
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "mark-read":
+			inboxMarkRead(args[1:])
+			return
+		case "receipts":
+			inboxReceipts(args[1:])
+			return
+		}
+	}
+
 	fs := flag.NewFlagSet("inbox", flag.ExitOnError)
 	peek := fs.Bool("peek", false, "read without consuming messages")
+	unreadOnly := fs.Bool("unread-only", false, "show only unread messages and mark them read without consuming")
 	raw := fs.Bool("raw", false, "output raw JSONL")
 	role := fs.String("role", "", "override role (default: auto-detect)")
+	fetch := fs.Bool("fetch-attachments", false, "inline text attachments regardless of size")
 	fs.Parse(args)
 
 	session := bus.BusSession()
@@ -25,9 +48,15 @@ func Inbox(args []string) {
 	var msgs []bus.Message
 	var err error
 
-	if *peek {
+	switch {
+	case *unreadOnly:
+		msgs, err = bus.Unread(session, r)
+		if err == nil && !*peek {
+			err = bus.MarkRead(session, r, msgs)
+		}
+	case *peek:
 		msgs, err = bus.Peek(session, r)
-	} else {
+	default:
 		msgs, err = bus.Receive(session, r)
 	}
 
@@ -49,7 +78,56 @@ func Inbox(args []string) {
 			fmt.Println(string(data))
 		} else {
 			fmt.Print(bus.FormatMessage(m))
+			fmt.Print(bus.FormatAttachments(session, m, *fetch))
 			fmt.Println()
 		}
 	}
 }
+
+// inboxMarkRead handles: inbox mark-read [--role ROLE] [--id ID]
+func inboxMarkRead(args []string) {
+	fs := flag.NewFlagSet("inbox mark-read", flag.ExitOnError)
+	role := fs.String("role", "", "override role (default: auto-detect)")
+	id := fs.String("id", "", "mark messages up to and including this ID (default: all)")
+	fs.Parse(args)
+
+	r := *role
+	if r == "" {
+		r = bus.BusRole()
+	}
+	n, err := bus.MarkReadUpTo(bus.BusSession(), r, *id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Marked %d message(s) read for %s\n", n, r)
+}
+
+// inboxReceipts handles: inbox receipts [--role ROLE] [--limit N] [--json]
+// listing who has read the messages a role sent.
+func inboxReceipts(args []string) {
+	fs := flag.NewFlagSet("inbox receipts", flag.ExitOnError)
+	role := fs.String("role", "", "sender whose messages to report (default: auto-detect)")
+	limit := fs.Int("limit", 20, "show the last N receipts")
+	jsonOut := fs.Bool("json", false, "output JSON")
+	fs.Parse(args)
+
+	r := *role
+	if r == "" {
+		r = bus.BusRole()
+	}
+	receipts := bus.ReadReceipts(bus.BusSession(), r, *limit)
+	if *jsonOut {
+		if receipts == nil {
+			receipts = []bus.ReadReceipt{}
+		}
+		data, _ := json.MarshalIndent(receipts, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(receipts) == 0 {
+		fmt.Printf("No read receipts for messages from %s\n", r)
+		return
+	}
+	fmt.Print(bus.FormatReadReceipts(receipts))
+}
@@ -6,18 +6,46 @@ import (
 	"os"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/watcher"
 )
 
 // Init handles the "muxcode-agent-bus init" subcommand.
 func Init(args []string) {
 	fs := flag.NewFlagSet("init", flag.ExitOnError)
 	memoryDir := fs.String("memory-dir", "", "override memory directory path")
+	template := fs.String("template", "", "provision the session from ~/.config/muxcode/templates/<name>.json")
+	noWatcherCheck := fs.Bool("no-watcher-check", false, "skip the warning when no watcher is running for the session")
 	fs.Parse(args)
 
+	// Load the template first so a bad one fails before the bus is reset
+	var tmpl bus.SessionTemplate
+	if *template != "" {
+		t, err := bus.LoadSessionTemplate(*template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading template: %v\n", err)
+			os.Exit(1)
+		}
+		tmpl = t
+	}
+
 	session := bus.BusSession()
 	if err := bus.Init(session, *memoryDir); err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing bus: %v\n", err)
 		os.Exit(1)
 	}
 	fmt.Printf("Bus initialized: %s\n", bus.BusDir(session))
+
+	if *template != "" {
+		res, err := bus.ApplySessionTemplate(session, tmpl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying template %s: %v\n", *template, err)
+			os.Exit(1)
+		}
+		fmt.Print(bus.FormatTemplateResult(*template, tmpl, res))
+	}
+
+	if !*noWatcherCheck && !watcher.Status(session).Running {
+		fmt.Fprintf(os.Stderr, "Warning: no watcher is running for session %s; notifications, cron, and alerts are inactive\n", session)
+		fmt.Fprintf(os.Stderr, "  Start one with: muxcode-agent-bus watch start --daemon\n")
+	}
 }
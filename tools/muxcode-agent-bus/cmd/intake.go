@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const intakeUsage = `Usage: muxcode-agent-bus intake poll [--dry-run] [--json]
+       muxcode-agent-bus intake list [--json]
+       muxcode-agent-bus intake done <source:key|key|msg-id> "<summary>"
+`
+
+// Intake handles the "muxcode-agent-bus intake" subcommand.
+// Usage: muxcode-agent-bus intake poll [--dry-run] [--json]
+//
+//	muxcode-agent-bus intake list [--json]
+//	muxcode-agent-bus intake done <source:key|key|msg-id> "<summary>"
+//
+// poll takes in new issues from the configured sources now, regardless of
+// the intake interval; the watcher polls on the interval.
+func Intake(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, intakeUsage)
+		os.Exit(1)
+	}
+	session := bus.BusSession()
+	switch args[0] {
+	case "poll":
+		dryRun, asJSON := false, false
+		for _, a := range args[1:] {
+			switch a {
+			case "--dry-run":
+				dryRun = true
+			case "--json":
+				asJSON = true
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+				fmt.Fprint(os.Stderr, intakeUsage)
+				os.Exit(1)
+			}
+		}
+		if len(bus.Config().Intake.Sources) == 0 {
+			fmt.Fprintln(os.Stderr, "No intake sources configured (intake.sources in muxcode.json).")
+			os.Exit(1)
+		}
+		taken, err := bus.PollIntake(session, time.Now(), true, dryRun)
+		if asJSON {
+			if taken == nil {
+				taken = []bus.IntakeIssue{}
+			}
+			data, _ := json.MarshalIndent(taken, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			verb := "Took in"
+			if dryRun {
+				verb = "Would take in"
+			}
+			for _, i := range taken {
+				fmt.Printf("%s %s %s -> %s: %s\n", verb, i.Source, i.Key, i.To, i.Title)
+			}
+			fmt.Printf("%d new issue(s)\n", len(taken))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "list":
+		asJSON := false
+		for _, a := range args[1:] {
+			if a != "--json" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+				fmt.Fprint(os.Stderr, intakeUsage)
+				os.Exit(1)
+			}
+			asJSON = true
+		}
+		issues, err := bus.ReadIntakeIssues(session)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading intake: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			if issues == nil {
+				issues = []bus.IntakeIssue{}
+			}
+			data, _ := json.MarshalIndent(issues, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Print(bus.FormatIntakeIssues(issues))
+
+	case "done":
+		if len(args) != 3 || args[2] == "" {
+			fmt.Fprint(os.Stderr, intakeUsage)
+			os.Exit(1)
+		}
+		i, err := bus.MarkIntakeDone(session, args[1], args[2], time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Marked %s %s done; the watcher will comment on the issue\n", i.Source, i.Key)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown intake subcommand: %s\n", args[0])
+		fmt.Fprint(os.Stderr, intakeUsage)
+		os.Exit(1)
+	}
+}
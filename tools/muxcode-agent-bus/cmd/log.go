@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -13,7 +14,7 @@ import (
 )
 
 // Log handles the "muxcode-agent-bus log" subcommand.
-// Usage: muxcode-agent-bus log <role> "<summary>" [--exit-code N] [--command CMD] [--output TEXT] [--output-stdin] [--output-file PATH]
+// Usage: muxcode-agent-bus log <role> "<summary>" [--exit-code N] [--command CMD] [--output TEXT] [--output-stdin] [--output-file PATH] [--started-at TS] [--duration D] [--parse FORMAT] [--coverage PCT|auto]
 //
 // Output sources (mutually exclusive):
 //   --output TEXT        inline output string
@@ -24,8 +25,22 @@ import (
 // piping through printf, which breaks allowedTools glob patterns when the LLM
 // embeds literal newlines in the command string.
 //
-// Appends a timestamped JSON entry to <bus-dir>/<role>-history.jsonl.
-// Rotates to keep the last 100 entries.
+// Timing (optional, used by history report and guard slow-command alerts):
+//   --started-at TS      unix time the command started, in seconds or milliseconds
+//   --duration D         how long it ran, as a Go duration (e.g. 42s)
+//
+// The entry ends at the time of the log call.
+//
+// --parse FORMAT (go-test, jest, junit, cargo) parses the output as test
+// runner results. The failed tests are stored in the entry's "tests" field,
+// and the output is replaced by a summary listing them.
+//
+// --coverage PCT records the role's test coverage (e.g. 78.3); "auto" reads
+// it from the output (go tool cover, go test -cover, lcov). Coverage below
+// the configured floor sends a coverage-drop event.
+//
+// Appends a timestamped JSON entry to <bus-dir>/<role>-history.jsonl (or the
+// configured store). Rotates to keep the last 100 entries.
 func Log(args []string) {
 	if err := runLog(args, os.Stdin); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -38,7 +53,7 @@ func Log(args []string) {
 // of calling os.Exit.
 func runLog(args []string, stdin io.Reader) error {
 	if len(args) < 2 {
-		return fmt.Errorf("usage: muxcode-agent-bus log <role> \"<summary>\" [--exit-code N] [--command CMD] [--output TEXT] [--output-stdin] [--output-file PATH]")
+		return fmt.Errorf("usage: muxcode-agent-bus log <role> \"<summary>\" [--exit-code N] [--command CMD] [--output TEXT] [--output-stdin] [--output-file PATH] [--started-at TS] [--duration D] [--parse FORMAT] [--coverage PCT|auto]")
 	}
 
 	role := args[0]
@@ -50,6 +65,9 @@ func runLog(args []string, stdin io.Reader) error {
 	output := ""
 	outputStdin := false
 	outputFile := ""
+	var startedAt, durationMs int64
+	parse := ""
+	coverage := ""
 
 	for i := 0; i < len(remaining); i++ {
 		switch remaining[i] {
@@ -79,6 +97,46 @@ func runLog(args []string, stdin io.Reader) error {
 			}
 			i++
 			outputFile = remaining[i]
+		case "--started-at":
+			if i+1 >= len(remaining) {
+				return fmt.Errorf("--started-at requires a value")
+			}
+			i++
+			ts, err := parseStartedAt(remaining[i])
+			if err != nil {
+				return err
+			}
+			startedAt = ts
+		case "--parse":
+			if i+1 >= len(remaining) {
+				return fmt.Errorf("--parse requires a format (%s)", strings.Join(bus.TestParsers, ", "))
+			}
+			i++
+			parse = remaining[i]
+			if !validParser(parse) {
+				return fmt.Errorf("unknown --parse format %q (want one of %s)", parse, strings.Join(bus.TestParsers, ", "))
+			}
+		case "--coverage":
+			if i+1 >= len(remaining) {
+				return fmt.Errorf("--coverage requires a percentage or auto")
+			}
+			i++
+			coverage = remaining[i]
+			if coverage != "auto" {
+				if _, err := bus.ParseCoverage(coverage); err != nil {
+					return err
+				}
+			}
+		case "--duration":
+			if i+1 >= len(remaining) {
+				return fmt.Errorf("--duration requires a value")
+			}
+			i++
+			d, err := time.ParseDuration(remaining[i])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("--duration must be a positive duration (e.g. 42s), got %q", remaining[i])
+			}
+			durationMs = d.Milliseconds()
 		default:
 			return fmt.Errorf("unknown flag: %s", remaining[i])
 		}
@@ -103,6 +161,12 @@ func runLog(args []string, stdin io.Reader) error {
 	if outputSources > 1 {
 		return fmt.Errorf("--output, --output-stdin, and --output-file are mutually exclusive")
 	}
+	if parse != "" && outputSources == 0 {
+		return fmt.Errorf("--parse requires --output, --output-stdin, or --output-file")
+	}
+	if coverage == "auto" && outputSources == 0 {
+		return fmt.Errorf("--coverage auto requires --output, --output-stdin, or --output-file")
+	}
 
 	// Read output from stdin if --output-stdin is set
 	if outputStdin {
@@ -122,6 +186,29 @@ func runLog(args []string, stdin io.Reader) error {
 		output = strings.TrimRight(string(data), "\n")
 	}
 
+	// Coverage is read from the raw output, before --parse summarizes it
+	percent, hasCoverage := 0.0, false
+	if coverage == "auto" {
+		if percent, hasCoverage = bus.ExtractCoverage(output); !hasCoverage {
+			fmt.Fprintf(os.Stderr, "Warning: --coverage auto: no coverage found in output\n")
+		}
+	} else if coverage != "" {
+		percent, _ = bus.ParseCoverage(coverage)
+		hasCoverage = true
+	}
+
+	// Parse test results; unparseable output is logged as is
+	var tests *bus.TestResults
+	if parse != "" {
+		r, err := bus.ParseTestOutput(parse, []byte(output))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: --parse %s: %v; logging raw output\n", parse, err)
+		} else {
+			tests = r
+			output = bus.FormatTestResults(r)
+		}
+	}
+
 	// Derive outcome from exit code
 	outcome := "success"
 	if exitCode != "0" {
@@ -131,8 +218,19 @@ func runLog(args []string, stdin io.Reader) error {
 	session := bus.BusSession()
 	historyPath := bus.HistoryPath(session, role)
 
+	if hasCoverage {
+		dropped, err := bus.RecordCoverage(session, role, command, percent, time.Now())
+		if err != nil {
+			return fmt.Errorf("recording coverage: %v", err)
+		}
+		if dropped {
+			fmt.Printf("Coverage %.1f%% is below the %.1f%% floor\n", percent, bus.Config().Coverage.FloorFor(role))
+		}
+	}
+
+	now := time.Now()
 	entry := map[string]interface{}{
-		"ts":        time.Now().Unix(),
+		"ts":        now.Unix(),
 		"summary":   summary,
 		"exit_code": exitCode,
 		"command":   command,
@@ -140,10 +238,40 @@ func runLog(args []string, stdin io.Reader) error {
 		"outcome":   outcome,
 	}
 
+	// Timing: the command ends now; either flag gives the other
+	endedAt := now.UnixMilli()
+	if startedAt > endedAt {
+		return fmt.Errorf("--started-at is in the future")
+	}
+	if startedAt == 0 && durationMs > 0 {
+		startedAt = endedAt - durationMs
+	}
+	if startedAt > 0 {
+		if durationMs == 0 {
+			durationMs = endedAt - startedAt
+		}
+		entry["started_at"] = startedAt
+		entry["ended_at"] = endedAt
+		entry["duration_ms"] = durationMs
+	}
+	if tests != nil {
+		entry["tests"] = tests
+	}
+
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("encoding JSON: %v", err)
 	}
+	data = bus.RedactHistoryEntry(session, role, data)
+
+	// Alternate storage backend (MUXCODE_STORE=sqlite)
+	if bus.ActiveStore() != nil {
+		if err := bus.AppendHistory(session, role, data, 100); err != nil {
+			return fmt.Errorf("writing history entry: %v", err)
+		}
+		fmt.Printf("Logged %s: %s (%s)\n", role, summary, outcome)
+		return nil
+	}
 
 	// Ensure bus directory exists
 	busDir := bus.BusDir(session)
@@ -175,6 +303,29 @@ func runLog(args []string, stdin io.Reader) error {
 	return nil
 }
 
+// validParser reports whether format is one of bus.TestParsers.
+func validParser(format string) bool {
+	for _, p := range bus.TestParsers {
+		if p == format {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStartedAt parses a unix timestamp in seconds or milliseconds and
+// returns milliseconds. Values below 1e12 (2001 in milliseconds) are seconds.
+func parseStartedAt(s string) (int64, error) {
+	ts, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ts <= 0 {
+		return 0, fmt.Errorf("--started-at must be a unix timestamp, got %q", s)
+	}
+	if ts < 1e12 {
+		ts *= 1000
+	}
+	return ts, nil
+}
+
 // rotateHistory truncates a JSONL file to keep only the last maxEntries lines.
 func rotateHistory(path string, maxEntries int) {
 	data, err := os.ReadFile(path)
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
@@ -392,6 +394,80 @@ func TestLogOutputFile(t *testing.T) {
 	}
 }
 
+func TestLogTiming(t *testing.T) {
+	session := "test-log-timing"
+	t.Setenv("BUS_SESSION", session)
+	defer os.RemoveAll(bus.BusDir(session))
+
+	start := time.Now().Add(-90 * time.Second).Unix()
+	args := []string{"test", "tests passed", "--command", "go test ./...", "--started-at", strconv.FormatInt(start, 10)}
+	if err := runLog(args, strings.NewReader("")); err != nil {
+		t.Fatalf("runLog: %v", err)
+	}
+	if err := runLog([]string{"test", "tests passed", "--duration", "42s"}, strings.NewReader("")); err != nil {
+		t.Fatalf("runLog: %v", err)
+	}
+
+	entries := bus.ReadHistory(session, "test", 0)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if e := entries[0]; e.StartedAt != start*1000 || e.Duration() < 90000 || e.Duration() > 95000 {
+		t.Errorf("--started-at entry = %+v", e)
+	}
+	if e := entries[1]; e.DurationMs != 42000 || e.EndedAt-e.StartedAt != 42000 {
+		t.Errorf("--duration entry = %+v", e)
+	}
+}
+
+func TestLogParse(t *testing.T) {
+	session := "test-log-parse"
+	t.Setenv("BUS_SESSION", session)
+	defer os.RemoveAll(bus.BusDir(session))
+
+	out := `{"Action":"pass","Package":"app/bus","Test":"TestA"}
+{"Action":"fail","Package":"app/bus","Test":"TestB"}
+{"Action":"fail","Package":"app/bus"}`
+	args := []string{"test", "tests failed", "--exit-code", "1", "--command", "go test -json ./...", "--parse", "go-test", "--output", out}
+	if err := runLog(args, strings.NewReader("")); err != nil {
+		t.Fatalf("runLog: %v", err)
+	}
+
+	entries := bus.ReadHistory(session, "test", 0)
+	if len(entries) != 1 || entries[0].Tests == nil {
+		t.Fatalf("entries = %+v", entries)
+	}
+	e := entries[0]
+	if len(e.Tests.Failures) != 1 || e.Tests.Failures[0].Name != "TestB" || e.Tests.Passed != 1 {
+		t.Errorf("tests = %+v", e.Tests)
+	}
+	if e.Output != "1 passed, 1 failed (go-test)\nFAIL app/bus: TestB" {
+		t.Errorf("output = %q", e.Output)
+	}
+}
+
+func TestLogCoverage(t *testing.T) {
+	session := "test-log-coverage"
+	t.Setenv("BUS_SESSION", session)
+	defer os.RemoveAll(bus.BusDir(session))
+
+	if err := runLog([]string{"test", "tests passed", "--coverage", "81.5%"}, strings.NewReader("")); err != nil {
+		t.Fatalf("runLog: %v", err)
+	}
+	out := "ok  \tapp/bus\t0.1s\tcoverage: 76.0% of statements"
+	if err := runLog([]string{"test", "tests passed", "--command", "go test -cover ./...", "--coverage", "auto", "--output", out}, strings.NewReader("")); err != nil {
+		t.Fatalf("runLog: %v", err)
+	}
+
+	records, err := bus.ReadCoverage(session, "test")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ReadCoverage = %+v, %v", records, err)
+	}
+	if records[0].Percent != 81.5 || records[1].Percent != 76 || records[1].Command != "go test -cover ./..." {
+		t.Errorf("records = %+v", records)
+	}
+}
+
 func TestRunLogErrors(t *testing.T) {
 	// Exercise runLog error paths directly.
 	tests := []struct {
@@ -406,6 +482,12 @@ func TestRunLogErrors(t *testing.T) {
 		{"missing output-file path", []string{"review", "summary", "--output-file"}, "--output-file requires a path"},
 		{"unknown flag", []string{"review", "summary", "--bogus"}, "unknown flag: --bogus"},
 		{"mutually exclusive", []string{"review", "summary", "--output", "x", "--output-stdin"}, "mutually exclusive"},
+		{"bad started-at", []string{"review", "summary", "--started-at", "yesterday"}, "--started-at must be a unix timestamp"},
+		{"unknown parser", []string{"review", "summary", "--parse", "mocha", "--output", "x"}, "unknown --parse format"},
+		{"parse without output", []string{"review", "summary", "--parse", "jest"}, "--parse requires --output"},
+		{"bad coverage", []string{"review", "summary", "--coverage", "lots"}, "coverage must be a percentage"},
+		{"coverage auto without output", []string{"review", "summary", "--coverage", "auto"}, "--coverage auto requires --output"},
+		{"bad duration", []string{"review", "summary", "--duration", "-1s"}, "--duration must be a positive duration"},
 	}
 
 	for _, tt := range tests {
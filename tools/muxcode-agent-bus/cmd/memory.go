@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -12,7 +13,7 @@ import (
 // Memory handles the "muxcode-agent-bus memory" subcommand.
 func Memory(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory <read|write|write-shared|context|search|list> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory <read|write|write-shared|context|search|list|index|dedupe|archive-session|export|import> [args...]\n")
 		os.Exit(1)
 	}
 
@@ -32,9 +33,19 @@ func Memory(args []string) {
 		memorySearch(subArgs)
 	case "list":
 		memoryList(subArgs)
+	case "index":
+		memoryIndex(subArgs)
+	case "dedupe":
+		memoryDedupe(subArgs)
+	case "archive-session":
+		memoryArchiveSession(subArgs)
+	case "export":
+		memoryExport(subArgs)
+	case "import":
+		memoryImport(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown memory subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory <read|write|write-shared|context|search|list> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory <read|write|write-shared|context|search|list|index|dedupe|archive-session|export|import> [args...]\n")
 		os.Exit(1)
 	}
 }
@@ -123,6 +134,9 @@ func memorySearch(args []string) {
 	roleFilter := ""
 	limit := 0
 	mode := bus.SearchModeBM25 // default to BM25
+	allRoles := false
+	allSessions := false
+	includeScratch := false
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -133,6 +147,12 @@ func memorySearch(args []string) {
 			}
 			i++
 			roleFilter = args[i]
+		case "--all-roles":
+			allRoles = true
+		case "--all-sessions":
+			allSessions = true
+		case "--include-scratch":
+			includeScratch = true
 		case "--limit":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "Error: --limit requires a value\n")
@@ -147,7 +167,7 @@ func memorySearch(args []string) {
 			limit = n
 		case "--mode":
 			if i+1 >= len(args) {
-				fmt.Fprintf(os.Stderr, "Error: --mode requires a value (keyword|bm25)\n")
+				fmt.Fprintf(os.Stderr, "Error: --mode requires a value (keyword|bm25|semantic|hybrid)\n")
 				os.Exit(1)
 			}
 			i++
@@ -156,8 +176,12 @@ func memorySearch(args []string) {
 				mode = bus.SearchModeKeyword
 			case "bm25":
 				mode = bus.SearchModeBM25
+			case "semantic":
+				mode = bus.SearchModeSemantic
+			case "hybrid":
+				mode = bus.SearchModeHybrid
 			default:
-				fmt.Fprintf(os.Stderr, "Error: --mode must be 'keyword' or 'bm25'\n")
+				fmt.Fprintf(os.Stderr, "Error: --mode must be 'keyword', 'bm25', 'semantic', or 'hybrid'\n")
 				os.Exit(1)
 			}
 		default:
@@ -167,26 +191,121 @@ func memorySearch(args []string) {
 
 	query := strings.Join(queryParts, " ")
 	if query == "" {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory search <query> [--role ROLE] [--limit N] [--mode keyword|bm25]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory search <query> [--role ROLE | --all-roles] [--all-sessions] [--include-scratch] [--limit N] [--mode keyword|bm25|semantic|hybrid]\n")
+		os.Exit(1)
+	}
+	if allRoles && roleFilter != "" {
+		fmt.Fprintf(os.Stderr, "Error: --all-roles and --role are mutually exclusive\n")
 		os.Exit(1)
 	}
 
 	results, err := bus.SearchMemoryWithOptions(bus.SearchOptions{
-		Query:      query,
-		RoleFilter: roleFilter,
-		Limit:      limit,
-		Mode:       mode,
+		Query:          query,
+		RoleFilter:     roleFilter,
+		Limit:          limit,
+		Mode:           mode,
+		AllSessions:    allSessions,
+		IncludeScratch: includeScratch,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error searching memory: %v\n", err)
 		os.Exit(1)
 	}
 
-	if len(results) > 0 {
+	if len(results) == 0 {
+		return
+	}
+	if allRoles || allSessions {
+		fmt.Print(bus.FormatSearchResultsGrouped(results))
+	} else {
 		fmt.Print(bus.FormatSearchResults(results))
 	}
 }
 
+func memoryArchiveSession(args []string) {
+	name := bus.BusSession()
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	dir, n, err := bus.ArchiveSessionMemory(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error archiving memory: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Archived %d entries to %s\n", n, dir)
+}
+
+func memoryIndex(args []string) {
+	rebuild := false
+	for _, a := range args {
+		switch a {
+		case "--rebuild":
+			rebuild = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory index [--rebuild]\n")
+			os.Exit(1)
+		}
+	}
+
+	entries, err := bus.AllMemoryEntries()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading memory: %v\n", err)
+		os.Exit(1)
+	}
+
+	emb := bus.NewOllamaEmbedder()
+	n, index, err := bus.UpdateEmbeddingIndex(context.Background(), emb, emb.Model, entries, rebuild)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building embedding index: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Embedded %d entries (%d indexed, model %s) → %s\n", n, len(index), emb.Model, bus.EmbeddingIndexPath())
+}
+
+func memoryDedupe(args []string) {
+	usage := "Usage: muxcode-agent-bus memory dedupe [--role ROLE] [--threshold F] [--dry-run]\n"
+	opts := bus.DedupeOptions{}
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--role":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --role requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			opts.Role = args[i]
+		case "--threshold":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --threshold requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			f, err := strconv.ParseFloat(args[i], 64)
+			if err != nil || f <= 0 || f > 1 {
+				fmt.Fprintf(os.Stderr, "Error: --threshold must be between 0 and 1\n")
+				os.Exit(1)
+			}
+			opts.Threshold = f
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	result, err := bus.DedupeMemory(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error deduplicating memory: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatDedupeResult(result, opts.DryRun))
+}
+
 func memoryList(args []string) {
 	roleFilter := ""
 
@@ -226,3 +345,77 @@ func memoryList(args []string) {
 		fmt.Print(bus.FormatMemoryList(entries))
 	}
 }
+
+func memoryExport(args []string) {
+	format := bus.MemoryBundleJSON
+	out := "memory-bundle.tar.gz"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --format requires a value (json|md)\n")
+				os.Exit(1)
+			}
+			i++
+			format = args[i]
+			if format != bus.MemoryBundleJSON && format != bus.MemoryBundleMarkdown {
+				fmt.Fprintf(os.Stderr, "Error: --format must be 'json' or 'md'\n")
+				os.Exit(1)
+			}
+		case "--out":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --out requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			out = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory export [--format json|md] [--out FILE]\n")
+			os.Exit(1)
+		}
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating bundle: %v\n", err)
+		os.Exit(1)
+	}
+	m, err := bus.ExportMemory(f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out)
+		fmt.Fprintf(os.Stderr, "Error exporting memory: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d entries (%d roles) to %s\n", m.Entries, len(m.Roles), out)
+}
+
+func memoryImport(args []string) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "--") {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus memory import <bundle.tar.gz>\n")
+		os.Exit(1)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening bundle: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	_, entries, err := bus.ReadMemoryBundle(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bundle: %v\n", err)
+		os.Exit(1)
+	}
+	imported, skipped, err := bus.ImportMemory(entries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing memory after %d entries: %v\n", imported, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d entries (%d already present)\n", imported, skipped)
+}
@@ -9,12 +9,29 @@ import (
 )
 
 // Notify handles the "muxcode-agent-bus notify" subcommand.
+// Usage: muxcode-agent-bus notify <role>
+//
+//	muxcode-agent-bus notify --desktop [BACKEND]   send a test desktop notification
 func Notify(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus notify <role>\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus notify <role> | --desktop [BACKEND]\n")
 		os.Exit(1)
 	}
 
+	if args[0] == "--desktop" {
+		backend := ""
+		if len(args) > 1 {
+			backend = args[1]
+		}
+		name, err := bus.TestDesktopNotifier(bus.BusSession(), backend)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s desktop notification failed: %v\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sent test notification via %s\n", name)
+		return
+	}
+
 	role := args[0]
 	if !bus.IsKnownRole(role) {
 		fmt.Fprintf(os.Stderr, "Error: unknown role '%s'. Known roles: %s\n", role, strings.Join(bus.KnownRoles, ", "))
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Plugin handles the "muxcode-agent-bus plugin" subcommand.
+// Usage: muxcode-agent-bus plugin list [--json]
+func Plugin(args []string) {
+	if len(args) < 1 || args[0] != "list" {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus plugin list [--json]\n")
+		os.Exit(1)
+	}
+
+	jsonOut := false
+	for _, a := range args[1:] {
+		switch a {
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus plugin list [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	plugins := bus.ListPlugins()
+	if jsonOut {
+		data, _ := json.MarshalIndent(plugins, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(plugins) == 0 {
+		fmt.Printf("No plugins installed (looked in %s and PATH for %s*)\n", bus.PluginDir(), bus.PluginPrefix)
+		return
+	}
+	for _, p := range plugins {
+		fmt.Printf("  %-20s %-7s %s\n", p.Name, p.Source, p.Path)
+	}
+}
+
+// RunPlugin runs an installed plugin as a subcommand with the terminal
+// attached, exiting with its exit code. Returns false if no plugin is named
+// name.
+func RunPlugin(name string, args []string) bool {
+	p, ok := bus.FindPlugin(name)
+	if !ok {
+		return false
+	}
+	c := bus.PluginCommand(p, bus.BusSession(), bus.BusRole(), args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "Error running plugin %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	return true
+}
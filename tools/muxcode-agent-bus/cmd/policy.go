@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Policy handles the "muxcode-agent-bus policy" subcommand.
+func Policy(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus policy <check|log> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "check":
+		policyCheck(args[1:])
+	case "log":
+		policyLog(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown policy subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus policy <check|log> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// policyCheck handles: policy check <from> <to> <action> [--type TYPE] [--json]
+// Evaluates a hypothetical send without recording it. Exits 1 when denied.
+func policyCheck(args []string) {
+	usage := "Usage: muxcode-agent-bus policy check <from> <to> <action> [--type TYPE] [--json]\n"
+	msgType := "request"
+	jsonOut := false
+	var pos []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--type":
+			if i+1 >= len(args) {
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			i++
+			msgType = args[i]
+		case "--json":
+			jsonOut = true
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			pos = append(pos, args[i])
+		}
+	}
+	if len(pos) != 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	if err := bus.Config().Policy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	msg := bus.NewMessage(pos[0], pos[1], msgType, pos[2], "", "")
+	d := bus.EvaluatePolicy(bus.BusSession(), msg)
+
+	if jsonOut {
+		data, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Println(string(data))
+	} else if d.Allowed {
+		fmt.Printf("allowed: %s -> %s [%s:%s]\n", msg.From, msg.To, msg.Type, msg.Action)
+	} else {
+		verdict := "denied"
+		if d.Rule >= 0 && bus.Config().Policy.Mode == "audit" {
+			verdict = "denied (audit mode: logged, not enforced)"
+		}
+		fmt.Printf("%s: %s\n", verdict, d.Reason)
+	}
+	if !d.Allowed {
+		os.Exit(1)
+	}
+}
+
+// policyLog handles: policy log [--limit N] [--json]
+func policyLog(args []string) {
+	limit := 20
+	jsonOut := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--limit":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --limit requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --limit: %s\n", args[i])
+				os.Exit(1)
+			}
+			limit = n
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus policy log [--limit N] [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	events, err := bus.ReadPolicyLog(bus.BusSession(), limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOut {
+		if events == nil {
+			events = []bus.PolicyEvent{}
+		}
+		data, _ := json.MarshalIndent(events, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatPolicyLog(events))
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const prUsage = `Usage: muxcode-agent-bus pr create --title T [--branch B] [--base main] [--body-file F] [--draft] [--dry-run]
+       muxcode-agent-bus pr sync <number> [--repo OWNER/NAME] [--to ROLE] [--dry-run] [--json]
+       muxcode-agent-bus pr threads [--json]
+`
+
+// PR handles the "muxcode-agent-bus pr" subcommand.
+// Usage: muxcode-agent-bus pr create --title T [--branch B] [--base main] [--body-file F] [--draft] [--dry-run]
+//
+//	muxcode-agent-bus pr sync <number> [--repo OWNER/NAME] [--to ROLE] [--dry-run] [--json]
+//	muxcode-agent-bus pr threads [--json]
+func PR(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "create":
+			prCreate(args[1:])
+			return
+		case "sync":
+			prSync(args[1:])
+			return
+		case "threads":
+			prThreads(args[1:])
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Unknown pr subcommand: %s\n", args[0])
+	}
+	fmt.Fprint(os.Stderr, prUsage)
+	os.Exit(1)
+}
+
+// prCreate opens a GitHub pull request from the working tree: branches off
+// base when on it, commits pending changes, pushes, and runs gh pr create.
+// The body defaults to the review role's latest logged findings. The
+// result is logged to commit history and sent to edit as a pr-created event.
+func prCreate(args []string) {
+	opts, err := bus.ParsePRArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprint(os.Stderr, prUsage)
+		os.Exit(1)
+	}
+	session := bus.BusSession()
+
+	current, err := gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading current branch: %v\n", err)
+		os.Exit(1)
+	}
+	status, err := gitOutput("status", "--porcelain")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading working tree status: %v\n", err)
+		os.Exit(1)
+	}
+
+	bodyFile := opts.BodyFile
+	if bodyFile == "" {
+		f, err := os.CreateTemp("", "muxcode-pr-body-*.md")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing PR body: %v\n", err)
+			os.Exit(1)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(bus.PRBody(session))
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing PR body: %v\n", err)
+			os.Exit(1)
+		}
+		bodyFile = f.Name()
+	}
+
+	steps, err := bus.PlanPR(opts, current, status != "", bodyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.DryRun {
+		for _, s := range steps {
+			fmt.Printf("pr: %-6s %s\n", s.Name, s)
+		}
+		if opts.BodyFile == "" {
+			fmt.Printf("pr: body:\n%s", bus.PRBody(session))
+		}
+		return
+	}
+
+	var url string
+	for _, s := range steps {
+		fmt.Printf("$ %s\n", s)
+		c := exec.Command(s.Args[0], s.Args[1:]...)
+		c.Stderr = os.Stderr
+		out, err := c.Output()
+		fmt.Print(string(out))
+		if err != nil {
+			logPR(session, fmt.Sprintf("PR %s failed: %s", s.Name, opts.Title), "1", s.String(), string(out))
+			fmt.Fprintf(os.Stderr, "Error: %s failed: %v\n", s.Name, err)
+			os.Exit(1)
+		}
+		if s.Name == "pr" {
+			url = strings.TrimSpace(string(out))
+		}
+	}
+
+	logPR(session, fmt.Sprintf("Opened PR: %s", opts.Title), "0", steps[len(steps)-1].String(), url)
+	msg := bus.NewMessage(bus.BusRole(), "edit", "event", bus.PRCreatedAction, fmt.Sprintf("Opened PR %s: %s", url, opts.Title), "")
+	if err := bus.SendNoCC(session, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: notifying edit: %v\n", err)
+	}
+	_ = bus.Notify(session, "edit")
+}
+
+// prSync sends a pr-fix request for each unresolved review thread of a
+// pull request, and resolves the threads whose fixes were reported.
+func prSync(args []string) {
+	repo := ""
+	to := "edit"
+	dryRun := false
+	asJSON := false
+	number := 0
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--repo", "--to":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			if args[i] == "--repo" {
+				repo = args[i+1]
+			} else {
+				to = args[i+1]
+			}
+			i++
+		case "--dry-run":
+			dryRun = true
+		case "--json":
+			asJSON = true
+		default:
+			n, err := strconv.Atoi(strings.TrimPrefix(args[i], "#"))
+			if err != nil || n <= 0 || number != 0 {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, prUsage)
+				os.Exit(1)
+			}
+			number = n
+		}
+	}
+	if number == 0 {
+		fmt.Fprint(os.Stderr, prUsage)
+		os.Exit(1)
+	}
+	if !bus.IsKnownRole(to) {
+		fmt.Fprintf(os.Stderr, "Error: unknown role %q\n", to)
+		os.Exit(1)
+	}
+	if repo == "" {
+		r, err := bus.CurrentRepo()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving repository (use --repo): %v\n", err)
+			os.Exit(1)
+		}
+		repo = r
+	}
+
+	res, err := bus.SyncPRThreads(bus.BusSession(), bus.BusRole(), to, repo, number, dryRun, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error syncing PR #%d: %v\n", number, err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	verb := "Sent"
+	if dryRun {
+		verb = "Would send"
+	}
+	for _, t := range res.Resolved {
+		fmt.Printf("Resolved thread on %s (PR #%d)\n", t.Location(), t.PR)
+	}
+	for _, t := range res.Sent {
+		fmt.Printf("%s %s to %s: %s by %s\n", verb, bus.PRFixAction, t.To, t.Location(), t.Author)
+	}
+	fmt.Printf("PR #%d: %d new thread(s), %d already synced, %d resolved\n", number, len(res.Sent), res.Skipped, len(res.Resolved))
+}
+
+// prThreads lists the synced review threads and their status.
+func prThreads(args []string) {
+	asJSON := false
+	for _, a := range args {
+		if a != "--json" {
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprint(os.Stderr, prUsage)
+			os.Exit(1)
+		}
+		asJSON = true
+	}
+	threads, err := bus.ReadPRThreads(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading PR threads: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if threads == nil {
+			threads = []bus.PRThread{}
+		}
+		data, _ := json.MarshalIndent(threads, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatPRThreads(threads))
+}
+
+// gitOutput runs git and returns its trimmed stdout.
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// logPR appends a pr create outcome to commit history.
+func logPR(session, summary, exitCode, command, output string) {
+	outcome := "success"
+	if exitCode != "0" {
+		outcome = "failure"
+	}
+	data, _ := json.Marshal(bus.HistoryEntry{
+		TS: time.Now().Unix(), Command: command, Summary: summary,
+		ExitCode: exitCode, Outcome: outcome, Output: output,
+	})
+	if err := bus.AppendHistory(session, "commit", data, 100); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: logging to commit history: %v\n", err)
+	}
+}
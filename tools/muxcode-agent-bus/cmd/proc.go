@@ -1,18 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/watcher"
 )
 
 // Proc handles the "muxcode-agent-bus proc" subcommand.
 func Proc(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc <start|list|status|log|stop|clean> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc <start|list|status|log|stop|clean|templates> [args...]\n")
 		os.Exit(1)
 	}
 
@@ -32,23 +37,31 @@ func Proc(args []string) {
 		procStop(subArgs)
 	case "clean":
 		procClean(subArgs)
+	case "templates":
+		procTemplates(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown proc subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc <start|list|status|log|stop|clean> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc <start|list|status|log|stop|clean|templates> [args...]\n")
 		os.Exit(1)
 	}
 }
 
-// procStart handles: proc start "<command>" [--dir DIR]
+const procStartUsage = "Usage: muxcode-agent-bus proc start \"<command>\" | --template NAME [--dir DIR] [--timeout DURATION] [--max-log SIZE] [--nice N]\n"
+
+// procStart handles: proc start "<command>" | --template NAME [--dir DIR] [--timeout 30m] [--max-log 50MB] [--nice 10]
+// Flags given alongside --template override the template's values.
 func procStart(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc start \"<command>\" [--dir DIR]\n")
+		fmt.Fprint(os.Stderr, procStartUsage)
 		os.Exit(1)
 	}
 
-	dir, _ := os.Getwd()
-	var command string
+	cwd, _ := os.Getwd()
+	dir := ""
+	var command, template string
 	var positionals []string
+	var opts bus.ProcOptions
+	var set struct{ timeout, maxLog, nice bool }
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -59,22 +72,106 @@ func procStart(args []string) {
 			}
 			i++
 			dir = args[i]
+		case "--template":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --template requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			template = args[i]
+		case "--timeout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --timeout requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: --timeout must be a positive duration (e.g. 30m)\n")
+				os.Exit(1)
+			}
+			opts.Timeout = d
+			set.timeout = true
+		case "--max-log":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-log requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := bus.ParseByteSize(args[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --max-log: %v\n", err)
+				os.Exit(1)
+			}
+			opts.MaxLog = n
+			set.maxLog = true
+		case "--nice":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --nice requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < -20 || n > 19 {
+				fmt.Fprintf(os.Stderr, "Error: --nice must be between -20 and 19\n")
+				os.Exit(1)
+			}
+			opts.Nice = n
+			set.nice = true
 		default:
 			positionals = append(positionals, args[i])
 		}
 	}
 
-	if len(positionals) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: command is required\n")
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc start \"<command>\" [--dir DIR]\n")
-		os.Exit(1)
-	}
-
-	command = strings.Join(positionals, " ")
 	session := bus.BusSession()
 	owner := bus.BusRole()
 
-	entry, err := bus.StartProc(session, command, dir, owner)
+	if template != "" {
+		if len(positionals) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --template cannot be combined with a command\n")
+			fmt.Fprint(os.Stderr, procStartUsage)
+			os.Exit(1)
+		}
+		t, err := bus.GetProcTemplate(template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		topts, err := t.Options(template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if set.timeout {
+			topts.Timeout = opts.Timeout
+		}
+		if set.maxLog {
+			topts.MaxLog = opts.MaxLog
+		}
+		if set.nice {
+			topts.Nice = opts.Nice
+		}
+		opts = topts
+		command = t.Command
+		if dir == "" {
+			dir = t.ResolveDir(cwd)
+		}
+		if t.Owner != "" {
+			owner = t.Owner
+		}
+	} else {
+		if len(positionals) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: command is required\n")
+			fmt.Fprint(os.Stderr, procStartUsage)
+			os.Exit(1)
+		}
+		command = strings.Join(positionals, " ")
+	}
+	if dir == "" {
+		dir = cwd
+	}
+
+	entry, err := bus.StartProcWithOptions(session, command, dir, owner, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error starting process: %v\n", err)
 		os.Exit(1)
@@ -83,7 +180,14 @@ func procStart(args []string) {
 	fmt.Printf("Started process: %s\n", entry.ID)
 	fmt.Printf("  PID: %d  Owner: %s\n", entry.PID, entry.Owner)
 	fmt.Printf("  Command: %s\n", entry.Command)
+	if entry.Template != "" {
+		fmt.Printf("  Template: %s\n", entry.Template)
+	}
 	fmt.Printf("  Log: %s\n", entry.LogFile)
+	if (entry.Timeout > 0 || entry.MaxLog > 0) && !watcher.Status(session).Running {
+		fmt.Fprintf(os.Stderr, "Warning: no watcher is running for session %s; --timeout and --max-log are enforced only by the watcher\n", session)
+		fmt.Fprintf(os.Stderr, "  Start one with: muxcode-agent-bus watch start --daemon\n")
+	}
 }
 
 // procList handles: proc list [--all]
@@ -135,17 +239,15 @@ func procStatus(args []string) {
 	fmt.Print(bus.FormatProcStatus(entry))
 }
 
-// procLog handles: proc log <id> [--tail N]
+// procLog handles: proc log <id> [--tail N] [--follow] and proc log --follow --all
 func procLog(args []string) {
-	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc log <id> [--tail N]\n")
-		os.Exit(1)
-	}
+	const usage = "Usage: muxcode-agent-bus proc log <id> [--tail N] [--follow]\n       muxcode-agent-bus proc log --follow --all [--tail N]\n"
 
-	id := args[0]
+	id := ""
 	tail := 0
+	follow, all := false, false
 
-	for i := 1; i < len(args); i++ {
+	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--tail":
 			if i+1 >= len(args) {
@@ -159,13 +261,31 @@ func procLog(args []string) {
 				os.Exit(1)
 			}
 			tail = n
+		case "--follow", "-f":
+			follow = true
+		case "--all":
+			all = true
 		default:
-			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
-			os.Exit(1)
+			if strings.HasPrefix(args[i], "-") || id != "" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			id = args[i]
 		}
 	}
 
+	if (id == "" && !all) || (id != "" && all) || (all && !follow) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
 	session := bus.BusSession()
+	if follow {
+		procFollow(session, id, all, tail)
+		return
+	}
+
 	entry, err := bus.GetProcEntry(session, id)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -178,7 +298,8 @@ func procLog(args []string) {
 		os.Exit(1)
 	}
 
-	content := string(data)
+	// A running proc's log is only scrubbed on disk once it exits
+	content, _ := bus.Redact(string(data))
 
 	if tail > 0 {
 		lines := strings.Split(content, "\n")
@@ -191,6 +312,43 @@ func procLog(args []string) {
 	fmt.Print(content)
 }
 
+// procFollow streams one proc log (or all running proc logs, prefixed) until
+// the procs finish or the user interrupts.
+func procFollow(session, id string, all bool, tail int) {
+	opts := bus.FollowOptions{All: all, Tail: tail}
+	if !all {
+		if _, err := bus.GetProcEntry(session, id); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.IDs = []string{id}
+		if tail == 0 {
+			opts.Tail = 10 // like tail -f
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	err := bus.FollowProcLogs(ctx, session, opts, func(l bus.ProcLogLine) {
+		if all {
+			fmt.Printf("%s %s\n", bus.ProcPrefix(l.ProcID), l.Text)
+		} else {
+			fmt.Println(l.Text)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error following logs: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // procStop handles: proc stop <id>
 func procStop(args []string) {
 	if len(args) < 1 {
@@ -218,3 +376,13 @@ func procClean(args []string) {
 
 	fmt.Printf("Cleaned %d finished process(es).\n", removed)
 }
+
+// procTemplates handles: proc templates
+func procTemplates(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus proc templates\n")
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatProcTemplates(bus.Config().ProcTemplates))
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -8,15 +9,44 @@ import (
 )
 
 // Prompt handles the "muxcode-agent-bus prompt" subcommand.
-// Usage: muxcode-agent-bus prompt <role>
+// Usage: muxcode-agent-bus prompt <role> [--vars]
 //
-// Outputs the shared agent coordination prompt for the given role.
+// Outputs the shared agent coordination prompt for the given role, rendered
+// from .muxcode/prompts/<role>.tmpl when one exists. --vars prints the
+// template variables as JSON instead.
 func Prompt(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus prompt <role>\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus prompt <role> [--vars]\n")
 		os.Exit(1)
 	}
 
 	role := args[0]
-	fmt.Print(bus.SharedPrompt(role))
+	vars := false
+	for _, a := range args[1:] {
+		switch a {
+		case "--vars":
+			vars = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus prompt <role> [--vars]\n")
+			os.Exit(1)
+		}
+	}
+
+	session := bus.BusSession()
+	if vars {
+		data, _ := json.MarshalIndent(struct {
+			Template string `json:"template"`
+			bus.PromptData
+		}{bus.PromptTemplatePath(role), bus.BuildPromptData(session, role)}, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	out, err := bus.RenderPrompt(session, role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
 }
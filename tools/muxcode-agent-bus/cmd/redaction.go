@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Redaction handles the "muxcode-agent-bus redaction" subcommand.
+func Redaction(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus redaction report [--since DURATION] [--json]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "report":
+		redactionReport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown redaction subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus redaction report [--since DURATION] [--json]\n")
+		os.Exit(1)
+	}
+}
+
+// redactionReport handles: redaction report [--since DURATION] [--json]
+func redactionReport(args []string) {
+	usage := "Usage: muxcode-agent-bus redaction report [--since DURATION] [--json]"
+	var since int64
+	jsonOut := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --since requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := parseSinceDuration(args[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --since must be a duration like 24h or 7d\n")
+				os.Exit(1)
+			}
+			since = time.Now().Add(-d).Unix()
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	rs, err := bus.ReadRedactionLog(bus.BusSession(), since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOut {
+		if rs == nil {
+			rs = []bus.Redaction{}
+		}
+		data, _ := json.MarshalIndent(rs, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatRedactionReport(rs))
+}
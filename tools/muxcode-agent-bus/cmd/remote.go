@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// splitRemote removes "--remote SPEC" (or "--remote=SPEC") from args and
+// returns the spec, empty when the flag is absent.
+func splitRemote(args []string) (string, []string) {
+	spec := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--remote":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --remote requires a value (user@host:session)\n")
+				os.Exit(1)
+			}
+			i++
+			spec = args[i]
+		case strings.HasPrefix(args[i], "--remote="):
+			spec = strings.TrimPrefix(args[i], "--remote=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return spec, rest
+}
+
+// runRemote runs a subcommand against a remote session over SSH and exits
+// with its status. The local role is sent as the remote AGENT_ROLE.
+func runRemote(spec, command string, args []string) {
+	r, err := bus.ParseRemote(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	code, err := bus.RunRemote(r, bus.BusRole(), append([]string{command}, args...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if code == 255 {
+		fmt.Fprintf(os.Stderr, "Error: ssh to %s failed (exit 255)\n", r.Host)
+	}
+	os.Exit(code)
+}
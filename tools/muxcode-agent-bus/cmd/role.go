@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Role handles the "muxcode-agent-bus role" subcommand.
+func Role(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus role <add|remove|restart|list|model> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add":
+		roleAdd(args[1:])
+	case "remove":
+		roleRemove(args[1:])
+	case "restart":
+		roleRestart(args[1:])
+	case "list":
+		roleList(args[1:])
+	case "model":
+		roleModel(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown role subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus role <add|remove|restart|list|model> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// parseRoleArgs parses "<role> [--dir DIR] [--no-window]". allowNoWindow
+// controls whether --no-window is accepted. dir defaults to the working
+// directory.
+func parseRoleArgs(args []string, usage string, allowNoWindow bool) (role, dir string, noWindow bool) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dir" && i+1 < len(args):
+			i++
+			dir = args[i]
+		case args[i] == "--no-window" && allowNoWindow:
+			noWindow = true
+		case strings.HasPrefix(args[i], "-"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: %s\n", usage)
+			os.Exit(1)
+		case role == "":
+			role = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: %s\n", usage)
+			os.Exit(1)
+		}
+	}
+	if role == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n", usage)
+		os.Exit(1)
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return role, dir, noWindow
+}
+
+// roleAdd handles: role add <role> [--dir DIR] [--no-window]
+func roleAdd(args []string) {
+	role, dir, noWindow := parseRoleArgs(args,
+		"muxcode-agent-bus role add <role> [--dir DIR] [--no-window]", true)
+
+	session := bus.BusSession()
+	if err := bus.AddRole(session, role, dir, noWindow); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if noWindow {
+		fmt.Printf("Added role %s (no window)\n", role)
+	} else {
+		fmt.Printf("Added role %s in window %s:%s\n", role, session, role)
+	}
+}
+
+// roleRemove handles: role remove <role>
+func roleRemove(args []string) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus role remove <role>\n")
+		os.Exit(1)
+	}
+	if err := bus.RemoveRole(bus.BusSession(), args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed role %s (history and memory kept)\n", args[0])
+}
+
+// roleRestart handles: role restart <role> [--dir DIR]
+func roleRestart(args []string) {
+	role, dir, _ := parseRoleArgs(args,
+		"muxcode-agent-bus role restart <role> [--dir DIR]", false)
+
+	if err := bus.RestartRole(bus.BusSession(), role, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restarted role %s\n", role)
+}
+
+// roleList handles: role list [--json] [--windows] [--role-map]
+// --windows prints only the space-separated windows of roles declared in
+// muxcode.json; --role-map prints their window=role pairs (both used by
+// muxcode.sh).
+func roleList(args []string) {
+	jsonOut := false
+	windowsOnly := false
+	roleMap := false
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOut = true
+		case "--windows":
+			windowsOnly = true
+		case "--role-map":
+			roleMap = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus role list [--json] [--windows] [--role-map]\n")
+			os.Exit(1)
+		}
+	}
+
+	if windowsOnly {
+		fmt.Println(strings.Join(bus.ConfiguredWindows(), " "))
+		return
+	}
+	if roleMap {
+		fmt.Println(strings.Join(bus.ConfiguredRoleMap(), " "))
+		return
+	}
+	if err := bus.ValidateRoleDefs(bus.Config().Roles); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+
+	infos := bus.ListRoles(bus.BusSession())
+	if jsonOut {
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatRoleList(infos))
+}
+
+// roleModel handles: role model <role>
+// Prints the Ollama model declared for the role in muxcode.json, or nothing
+// (used by muxcode-agent.sh when no MUXCODE_{ROLE}_MODEL is set).
+func roleModel(args []string) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus role model <role>\n")
+		os.Exit(1)
+	}
+	if d, ok := bus.ConfiguredRole(args[0]); ok && d.Model != "" {
+		fmt.Println(d.Model)
+	}
+}
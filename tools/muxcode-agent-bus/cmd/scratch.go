@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Scratch handles the "muxcode-agent-bus scratch" subcommand.
+func Scratch(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus scratch <write|read|clear> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "write":
+		scratchWrite(args[1:])
+	case "read":
+		scratchRead(args[1:])
+	case "clear":
+		scratchClear(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown scratch subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus scratch <write|read|clear> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// scratchWrite handles: scratch write "<section>" "<text>"
+func scratchWrite(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus scratch write \"<section>\" \"<text>\"\n")
+		os.Exit(1)
+	}
+	if err := bus.AppendScratch(bus.BusSession(), bus.BusRole(), args[0], args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing scratch: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// scratchRead handles: scratch read [role]
+func scratchRead(args []string) {
+	role := bus.BusRole()
+	if len(args) > 0 {
+		role = args[0]
+	}
+	content, err := bus.ReadScratch(bus.BusSession(), role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading scratch: %v\n", err)
+		os.Exit(1)
+	}
+	if content != "" {
+		fmt.Print(content)
+	}
+}
+
+// scratchClear handles: scratch clear [role]
+func scratchClear(args []string) {
+	role := bus.BusRole()
+	if len(args) > 0 {
+		role = args[0]
+	}
+	if err := bus.ClearScratch(bus.BusSession(), role); err != nil {
+		fmt.Fprintf(os.Stderr, "Error clearing scratch: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Cleared scratch for %s\n", role)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Selftest handles the "muxcode-agent-bus selftest" subcommand.
+// Usage: muxcode-agent-bus selftest [--json] [--keep] [--skip-tmux] [--skip-ollama]
+func Selftest(args []string) {
+	jsonOutput := false
+	var opts bus.SelftestOptions
+
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		case "--keep":
+			opts.Keep = true
+		case "--skip-tmux":
+			opts.SkipTmux = true
+		case "--skip-ollama":
+			opts.SkipOllama = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus selftest [--json] [--keep] [--skip-tmux] [--skip-ollama]\n")
+			os.Exit(1)
+		}
+	}
+
+	session, memDir, results := bus.RunSelftest(opts)
+
+	if jsonOutput {
+		out, err := bus.FormatSelftestJSON(results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+	} else {
+		fmt.Printf("Selftest session: %s\n\n", session)
+		fmt.Print(bus.FormatSelftestResults(results))
+		if opts.Keep {
+			fmt.Printf("Kept bus directory: %s\n", bus.BusDir(session))
+			fmt.Printf("Kept memory directory: %s\n", memDir)
+		}
+	}
+
+	if !bus.SelftestPassed(results) {
+		os.Exit(1)
+	}
+}
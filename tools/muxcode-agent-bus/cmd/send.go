@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -11,12 +13,23 @@ import (
 )
 
 // Send handles the "muxcode-agent-bus send" subcommand.
-// Usage: muxcode-agent-bus send <to> <action> "<payload>" [--type TYPE] [--reply-to ID] [--no-notify] [--force] [--wait]
+// Usage: muxcode-agent-bus send <to> <action> "<payload>" [--type TYPE] [--reply-to ID] [--attach FILE]... [--callback-url URL] [--no-notify] [--force] [--wait | --await [--timeout DURATION]] [--remote USER@HOST[:SESSION]]
 func Send(args []string) {
+	remote, args := splitRemote(args)
 	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus send <to> <action> \"<payload>\" [--type TYPE] [--reply-to ID] [--no-notify] [--force] [--wait]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus send <to> <action> \"<payload>\" [--type TYPE] [--reply-to ID] [--attach FILE]... [--callback-url URL] [--no-notify] [--force] [--wait | --await [--timeout DURATION]] [--remote USER@HOST[:SESSION]]\n")
 		os.Exit(1)
 	}
+	if remote != "" {
+		// Attachments are local files the remote host cannot read
+		for _, a := range args {
+			if a == "--attach" {
+				fmt.Fprintf(os.Stderr, "Error: --attach is not supported with --remote\n")
+				os.Exit(1)
+			}
+		}
+		runRemote(remote, "send", args)
+	}
 
 	to := args[0]
 	action := args[1]
@@ -25,10 +38,14 @@ func Send(args []string) {
 	payload := ""
 	msgType := "request"
 	replyTo := ""
+	callbackURL := ""
 	noNotify := false
 	force := false
 	wait := false
+	await := false
+	awaitTimeout := time.Duration(0)
 	payloadSet := false
+	var attachFiles []string
 
 	remaining := args[2:]
 	for i := 0; i < len(remaining); i++ {
@@ -47,12 +64,44 @@ func Send(args []string) {
 			}
 			i++
 			replyTo = remaining[i]
+		case "--attach":
+			if i+1 >= len(remaining) {
+				fmt.Fprintf(os.Stderr, "Error: --attach requires a file\n")
+				os.Exit(1)
+			}
+			i++
+			attachFiles = append(attachFiles, remaining[i])
+		case "--callback-url":
+			if i+1 >= len(remaining) {
+				fmt.Fprintf(os.Stderr, "Error: --callback-url requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			callbackURL = remaining[i]
+			if err := bus.CheckCallbackURL(callbackURL); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
 		case "--no-notify":
 			noNotify = true
 		case "--force":
 			force = true
 		case "--wait":
 			wait = true
+		case "--await":
+			await = true
+		case "--timeout":
+			if i+1 >= len(remaining) {
+				fmt.Fprintf(os.Stderr, "Error: --timeout requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := parseAwaitTimeout(remaining[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			awaitTimeout = d
 		default:
 			if strings.HasPrefix(remaining[i], "--") {
 				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", remaining[i])
@@ -69,6 +118,27 @@ func Send(args []string) {
 		}
 	}
 
+	if wait && await {
+		fmt.Fprintf(os.Stderr, "Error: --wait and --await cannot be combined\n")
+		os.Exit(1)
+	}
+	if awaitTimeout > 0 && !await {
+		fmt.Fprintf(os.Stderr, "Error: --timeout requires --await\n")
+		os.Exit(1)
+	}
+	if awaitTimeout == 0 {
+		awaitTimeout = time.Duration(inboxPollTimeout()) * time.Second
+	}
+
+	if !payloadSet && len(attachFiles) > 0 {
+		// Attachments alone are a valid message; name them in the payload
+		names := make([]string, len(attachFiles))
+		for i, f := range attachFiles {
+			names[i] = filepath.Base(f)
+		}
+		payload = "Attached: " + strings.Join(names, ", ")
+		payloadSet = true
+	}
 	if !payloadSet {
 		fmt.Fprintf(os.Stderr, "Error: payload is required\n")
 		os.Exit(1)
@@ -88,8 +158,11 @@ func Send(args []string) {
 	session := bus.BusSession()
 	from := bus.BusRole()
 
-	// Check send policy (hard error)
-	if deny := bus.CheckSendPolicy(from, to); deny != "" {
+	msg := bus.NewMessage(from, to, msgType, action, payload, replyTo)
+	msg.CallbackURL = callbackURL
+
+	// Check send policy and policy rules (hard error)
+	if deny := bus.CheckMessagePolicy(session, msg); deny != "" {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", deny)
 		os.Exit(1)
 	}
@@ -102,7 +175,15 @@ func Send(args []string) {
 		}
 	}
 
-	msg := bus.NewMessage(from, to, msgType, action, payload, replyTo)
+	for _, f := range attachFiles {
+		a, err := bus.StoreAttachment(session, f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error attaching %s: %v\n", f, err)
+			os.Exit(1)
+		}
+		msg.Attachments = append(msg.Attachments, a)
+	}
+
 	if err := bus.Send(session, msg); err != nil {
 		fmt.Fprintf(os.Stderr, "Error sending message: %v\n", err)
 		os.Exit(1)
@@ -118,6 +199,21 @@ func Send(args []string) {
 		}
 	}
 
+	// --await: stdout carries only the reply payload
+	if await {
+		reply, err := bus.AwaitReply(session, from, msg.ID, awaitTimeout)
+		if errors.Is(err, bus.ErrAwaitTimeout) {
+			fmt.Fprintf(os.Stderr, "No reply to %s from %s within %s\n", msg.ID, to, awaitTimeout)
+			os.Exit(124)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading inbox: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(reply.Payload)
+		return
+	}
+
 	fmt.Printf("Sent %s:%s to %s\n", msgType, action, to)
 
 	// --wait: poll own inbox until a response from the target arrives or timeout
@@ -130,12 +226,7 @@ func Send(args []string) {
 // the target agent. Messages from other agents are left in the inbox.
 // Timeout is controlled by MUXCODE_INBOX_POLL_TIMEOUT (default 120s).
 func waitForResponse(session, role, target string) {
-	timeout := 120
-	if v := os.Getenv("MUXCODE_INBOX_POLL_TIMEOUT"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			timeout = n
-		}
-	}
+	timeout := inboxPollTimeout()
 
 	const pollInterval = 2 // seconds
 	for elapsed := 0; elapsed < timeout; elapsed += pollInterval {
@@ -157,6 +248,7 @@ func waitForResponse(session, role, target string) {
 		fmt.Println()
 		for _, m := range msgs {
 			fmt.Print(bus.FormatMessage(m))
+			fmt.Print(bus.FormatAttachments(session, m, false))
 			fmt.Println()
 		}
 		return
@@ -165,6 +257,29 @@ func waitForResponse(session, role, target string) {
 	fmt.Fprintf(os.Stderr, "\nNo response from %s within %ds — check: muxcode-agent-bus inbox --peek\n", target, timeout)
 }
 
+// inboxPollTimeout returns MUXCODE_INBOX_POLL_TIMEOUT in seconds (default 120).
+func inboxPollTimeout() int {
+	if v := os.Getenv("MUXCODE_INBOX_POLL_TIMEOUT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 120
+}
+
+// parseAwaitTimeout parses a --timeout value: a Go duration ("90s", "5m")
+// or a number of seconds.
+func parseAwaitTimeout(v string) (time.Duration, error) {
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --timeout %q (want a duration like 120s)", v)
+	}
+	return d, nil
+}
+
 // isCommitAction returns true for actions that trigger actual git commits.
 // Read-only operations (status, log, diff, pr-read) are not blocked.
 func isCommitAction(action string) bool {
@@ -182,7 +297,7 @@ func validatePayload(payload string) []string {
 		warnings = append(warnings, "payload contains newlines — this may break allowedTools glob matching")
 	}
 	if len(payload) > 500 {
-		warnings = append(warnings, fmt.Sprintf("payload is %d chars (>500) — consider using shorter messages", len(payload)))
+		warnings = append(warnings, fmt.Sprintf("payload is %d chars (>500) — consider --attach for large content", len(payload)))
 	}
 	return warnings
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidatePayload_Clean(t *testing.T) {
@@ -60,3 +61,22 @@ func TestValidatePayload_ExactlyAtLimit(t *testing.T) {
 		t.Errorf("expected no warnings for exactly 500 chars, got %v", warnings)
 	}
 }
+
+func TestParseAwaitTimeout(t *testing.T) {
+	cases := map[string]time.Duration{
+		"120s": 120 * time.Second,
+		"2m":   2 * time.Minute,
+		"45":   45 * time.Second,
+		"1.5s": 1500 * time.Millisecond,
+	}
+	for in, want := range cases {
+		if got, err := parseAwaitTimeout(in); err != nil || got != want {
+			t.Errorf("parseAwaitTimeout(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "-5s", "soon"} {
+		if _, err := parseAwaitTimeout(in); err == nil {
+			t.Errorf("parseAwaitTimeout(%q): want error", in)
+		}
+	}
+}
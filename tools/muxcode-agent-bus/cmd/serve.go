@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Serve handles the "muxcode-agent-bus serve" subcommand: the REST API
+// server, run in the foreground.
+// Usage: muxcode-agent-bus serve [--port N] [--host ADDR] [--token TOKEN]
+// [--grpc-port N [--tls-cert FILE --tls-key FILE]]
+func Serve(args []string) {
+	port := 8077
+	host := "127.0.0.1"
+	token := ""
+	grpcPort := 0
+	tlsCert, tlsKey := "", ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--port":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --port requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			p, err := strconv.Atoi(args[i])
+			if err != nil || p < 1 || p > 65535 {
+				fmt.Fprintf(os.Stderr, "Error: --port must be 1-65535\n")
+				os.Exit(1)
+			}
+			port = p
+		case "--host":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --host requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			host = args[i]
+		case "--token":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --token requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			token = args[i]
+		case "--grpc-port":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --grpc-port requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			p, err := strconv.Atoi(args[i])
+			if err != nil || p < 1 || p > 65535 {
+				fmt.Fprintf(os.Stderr, "Error: --grpc-port must be 1-65535\n")
+				os.Exit(1)
+			}
+			grpcPort = p
+		case "--tls-cert":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --tls-cert requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			tlsCert = args[i]
+		case "--tls-key":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --tls-key requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			tlsKey = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus serve [--port N] [--host ADDR] [--token TOKEN] [--grpc-port N [--tls-cert FILE --tls-key FILE]]\n")
+			os.Exit(1)
+		}
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintf(os.Stderr, "Error: --tls-cert and --tls-key must be given together\n")
+		os.Exit(1)
+	}
+	if tlsCert != "" && grpcPort == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --tls-cert requires --grpc-port\n")
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	if token == "" {
+		t, err := bus.ServeToken(session)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating API token: %v\n", err)
+			os.Exit(1)
+		}
+		token = t
+		if os.Getenv("MUXCODE_SERVE_TOKEN") == "" {
+			fmt.Printf("API token: %s\n", bus.ServeTokenPath(session))
+		}
+	}
+
+	// Set up signal handling for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	cfg := bus.ServeConfig{Host: host, Port: port, Token: token, Session: session}
+	if grpcPort > 0 {
+		var cert tls.Certificate
+		var err error
+		if tlsCert != "" {
+			cert, err = tls.LoadX509KeyPair(tlsCert, tlsKey)
+		} else {
+			cert, err = bus.GRPCCert(session, host)
+			if err == nil {
+				fmt.Printf("gRPC certificate: %s\n", bus.GRPCCertPath(session))
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading TLS certificate: %v\n", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := bus.ServeGRPC(ctx, cfg, grpcPort, cert); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: gRPC: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
+	if err := bus.ServeAPI(ctx, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -80,17 +80,5 @@ func sessionStatus() {
 		return
 	}
 
-	// Count inbox messages for status display
-	inboxPath := bus.InboxPath(session, role)
-	msgCount := 0
-	data, err := os.ReadFile(inboxPath)
-	if err == nil {
-		for _, line := range splitLines(data) {
-			if len(line) > 0 {
-				msgCount++
-			}
-		}
-	}
-
-	fmt.Print(bus.FormatSessionStatus(meta, role, msgCount))
+	fmt.Print(bus.FormatSessionStatus(meta, role, bus.InboxCount(session, role)))
 }
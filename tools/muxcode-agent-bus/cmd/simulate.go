@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// simulateJSON is the --json output of "muxcode-agent-bus simulate".
+type simulateJSON struct {
+	Name     string          `json:"name"`
+	Session  string          `json:"session"`
+	Passed   bool            `json:"passed"`
+	Error    string          `json:"error,omitempty"`
+	Messages []bus.Message   `json:"messages"`
+	Notes    []string        `json:"notes,omitempty"`
+	Checks   []simulateCheck `json:"checks,omitempty"`
+}
+
+type simulateCheck struct {
+	Expect string `json:"expect"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// Simulate handles the "muxcode-agent-bus simulate" subcommand.
+// Usage: muxcode-agent-bus simulate --scenario FILE [--keep] [--json]
+func Simulate(args []string) {
+	usage := "Usage: muxcode-agent-bus simulate --scenario FILE [--keep] [--json]"
+	scenario := ""
+	keep, jsonOutput := false, false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--scenario":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --scenario requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			scenario = args[i]
+		case "--keep":
+			keep = true
+		case "--json":
+			jsonOutput = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+	if scenario == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	s, err := bus.LoadSimScenario(scenario)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	res := bus.RunSimulation(s, keep)
+
+	if jsonOutput {
+		out := simulateJSON{Name: res.Name, Session: res.Session, Passed: res.Passed(), Messages: res.Messages, Notes: res.Notes}
+		if out.Messages == nil {
+			out.Messages = []bus.Message{}
+		}
+		if res.Err != nil {
+			out.Error = res.Err.Error()
+		}
+		for _, c := range res.Checks {
+			out.Checks = append(out.Checks, simulateCheck{Expect: c.Expect.String(), Passed: c.Passed, Detail: c.Detail})
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(bus.FormatSimResult(res))
+		if keep {
+			fmt.Printf("Kept bus directory: %s\n", bus.BusDir(res.Session))
+		}
+	}
+
+	if !res.Passed() {
+		os.Exit(1)
+	}
+}
@@ -11,7 +11,7 @@ import (
 // Skill handles the "muxcode-agent-bus skill" subcommand.
 func Skill(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill <list|load|search|create|prompt> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill <list|load|search|create|install|add|update|prompt> [args...]\n")
 		os.Exit(1)
 	}
 
@@ -27,17 +27,25 @@ func Skill(args []string) {
 		skillSearch(subArgs)
 	case "create":
 		skillCreate(subArgs)
+	case "install":
+		skillInstall(subArgs)
+	case "add":
+		skillAdd(subArgs)
+	case "update":
+		skillUpdate(subArgs)
 	case "prompt":
 		skillPrompt(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown skill subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill <list|load|search|create|prompt> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill <list|load|search|create|install|add|update|prompt> [args...]\n")
 		os.Exit(1)
 	}
 }
 
 func skillList(args []string) {
 	roleFilter := ""
+	outdated := false
+	from := ""
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -48,13 +56,27 @@ func skillList(args []string) {
 			}
 			i++
 			roleFilter = args[i]
+		case "--outdated":
+			outdated = true
+		case "--from":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --from requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			from = args[i]
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
-			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill list [--role ROLE]\n")
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill list [--role ROLE] [--outdated [--from DIR|GIT_URL]]\n")
 			os.Exit(1)
 		}
 	}
 
+	if outdated {
+		skillListOutdated(roleFilter, from)
+		return
+	}
+
 	var skills []bus.SkillDef
 	var err error
 	if roleFilter != "" {
@@ -70,6 +92,130 @@ func skillList(args []string) {
 	if len(skills) > 0 {
 		fmt.Print(bus.FormatSkillList(skills))
 	}
+
+	all, _ := bus.ListSkills()
+	missing := bus.MissingSkillDeps(all)
+	for _, s := range skills {
+		for _, req := range missing[s.Name] {
+			fmt.Fprintf(os.Stderr, "warning: %s requires %s (not installed or too old)\n", s.Name, req)
+		}
+	}
+}
+
+// skillListOutdated prints installed skills with a newer version in the
+// skill source.
+func skillListOutdated(roleFilter, from string) {
+	dir, cleanup, err := bus.OpenSkillSource(from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	source, err := bus.ReadSkillSource(dir)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error reading skill source: %v\n", err)
+		os.Exit(1)
+	}
+	var installed []bus.SkillDef
+	if roleFilter != "" {
+		installed, err = bus.SkillsForRole(roleFilter)
+	} else {
+		installed, err = bus.ListSkills()
+	}
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error listing skills: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(bus.FormatSkillUpgrades(bus.OutdatedSkills(source, installed)))
+}
+
+func skillInstall(args []string) {
+	name := ""
+	from := ""
+	user := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --from requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			from = args[i]
+		case "--user":
+			user = true
+		default:
+			if strings.HasPrefix(args[i], "--") || name != "" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill install <name> [--from DIR|GIT_URL] [--user]\n")
+				os.Exit(1)
+			}
+			name = args[i]
+		}
+	}
+	if name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill install <name> [--from DIR|GIT_URL] [--user]\n")
+		os.Exit(1)
+	}
+
+	dir, cleanup, err := bus.OpenSkillSource(from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	installs, err := bus.InstallSkill(name, dir, user)
+	fmt.Print(bus.FormatSkillInstalls(installs))
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error installing skill: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func skillAdd(args []string) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "--") {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill add <host/org/repo/skill[@ref]>\n")
+		os.Exit(1)
+	}
+
+	ref, err := bus.ParseSkillRef(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	installs, err := bus.AddSkill(ref)
+	fmt.Print(bus.FormatSkillInstalls(installs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error adding skill: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func skillUpdate(args []string) {
+	for _, a := range args {
+		if strings.HasPrefix(a, "--") {
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus skill update [name...]\n")
+			os.Exit(1)
+		}
+	}
+
+	installs, err := bus.UpdateSkills(args)
+	fmt.Print(bus.FormatSkillInstalls(installs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating skills: %v\n", err)
+		os.Exit(1)
+	}
+	if len(installs) == 0 {
+		fmt.Println("All registry skills are up to date.")
+	}
 }
 
 func skillLoad(args []string) {
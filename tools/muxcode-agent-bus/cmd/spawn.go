@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
@@ -11,7 +12,7 @@ import (
 // Spawn handles the "muxcode-agent-bus spawn" subcommand.
 func Spawn(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn <start|list|status|result|stop|clean> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn <start|fanout|list|status|result|stop|clean|quota> [args...]\n")
 		os.Exit(1)
 	}
 
@@ -21,6 +22,8 @@ func Spawn(args []string) {
 	switch subcmd {
 	case "start":
 		spawnStart(subArgs)
+	case "fanout":
+		spawnFanout(subArgs)
 	case "list":
 		spawnList(subArgs)
 	case "status":
@@ -31,9 +34,11 @@ func Spawn(args []string) {
 		spawnStop(subArgs)
 	case "clean":
 		spawnClean(subArgs)
+	case "quota":
+		spawnQuota(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown spawn subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn <start|list|status|result|stop|clean> [args...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn <start|fanout|list|status|result|stop|clean|quota> [args...]\n")
 		os.Exit(1)
 	}
 }
@@ -50,18 +55,92 @@ func spawnStart(args []string) {
 	session := bus.BusSession()
 	owner := bus.BusRole()
 
+	entries, _ := bus.ReadSpawnEntries(session)
+	reason := bus.CheckSpawnQuota(entries, role, owner)
+
 	entry, err := bus.StartSpawn(session, role, task, owner)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error starting spawn: %v\n", err)
 		os.Exit(1)
 	}
 
+	if entry.Status == "queued" {
+		fmt.Printf("Queued spawn: %s (%s)\n", entry.ID, reason)
+		fmt.Printf("  Role: %s  Spawn Role: %s  Owner: %s\n", entry.Role, entry.SpawnRole, entry.Owner)
+		fmt.Printf("  The watcher launches it when a slot frees.\n")
+		return
+	}
+
 	fmt.Printf("Started spawn: %s\n", entry.ID)
 	fmt.Printf("  Role: %s  Spawn Role: %s  Owner: %s\n", entry.Role, entry.SpawnRole, entry.Owner)
 	fmt.Printf("  Window: %s\n", entry.Window)
 	fmt.Printf("  Task: %s\n", entry.Task)
 }
 
+const spawnFanoutUsage = "Usage: muxcode-agent-bus spawn fanout --role ROLE --task-file FILE [--count N]\n"
+
+// spawnFanout handles: spawn fanout --role ROLE --task-file FILE [--count N]
+func spawnFanout(args []string) {
+	var role, taskFile string
+	count := 0
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--role", "--task-file", "--count":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			flag := args[i]
+			i++
+			switch flag {
+			case "--role":
+				role = args[i]
+			case "--task-file":
+				taskFile = args[i]
+			case "--count":
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "Error: --count must be a positive integer\n")
+					os.Exit(1)
+				}
+				count = n
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, spawnFanoutUsage)
+			os.Exit(1)
+		}
+	}
+	if role == "" || taskFile == "" {
+		fmt.Fprint(os.Stderr, spawnFanoutUsage)
+		os.Exit(1)
+	}
+
+	tasks, err := bus.ReadTaskFile(taskFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading task file: %v\n", err)
+		os.Exit(1)
+	}
+	if count == 0 {
+		count = len(tasks)
+	}
+
+	session := bus.BusSession()
+	owner := bus.BusRole()
+	group, started, err := bus.StartSpawnGroup(session, role, tasks, count, owner)
+	if group != "" && len(started) > 0 {
+		fmt.Printf("Started spawn group: %s\n", group)
+		fmt.Printf("  Role: %s  Owner: %s  Tasks: %d  Spawns: %d\n", role, owner, len(tasks), len(started))
+		for _, e := range started {
+			fmt.Printf("  %s  %s  %s\n", e.ID, e.SpawnRole, e.Status)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting spawn group: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // spawnList handles: spawn list [--all]
 func spawnList(args []string) {
 	showAll := false
@@ -87,14 +166,18 @@ func spawnList(args []string) {
 	fmt.Print(bus.FormatSpawnList(entries, showAll))
 }
 
-// spawnStatus handles: spawn status <id>
+// spawnStatus handles: spawn status <id|group-id>
 func spawnStatus(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn status <id>\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn status <id|group-id>\n")
 		os.Exit(1)
 	}
 
 	session := bus.BusSession()
+	if members := spawnGroupMembers(session, args[0]); len(members) > 0 {
+		fmt.Print(bus.FormatSpawnList(members, true))
+		return
+	}
 
 	entry, err := bus.GetSpawnEntry(session, args[0])
 	if err != nil {
@@ -105,14 +188,18 @@ func spawnStatus(args []string) {
 	fmt.Print(bus.FormatSpawnStatus(entry))
 }
 
-// spawnResult handles: spawn result <id>
+// spawnResult handles: spawn result <id|group-id>
 func spawnResult(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn result <id>\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn result <id|group-id>\n")
 		os.Exit(1)
 	}
 
 	session := bus.BusSession()
+	if members := spawnGroupMembers(session, args[0]); len(members) > 0 {
+		fmt.Print(bus.FormatSpawnGroupReport(session, args[0], members, 0))
+		return
+	}
 
 	entry, err := bus.GetSpawnEntry(session, args[0])
 	if err != nil {
@@ -156,3 +243,31 @@ func spawnClean(args []string) {
 
 	fmt.Printf("Cleaned %d finished spawn(s).\n", removed)
 }
+
+// spawnQuota handles: spawn quota
+func spawnQuota(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus spawn quota\n")
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	entries, err := bus.ReadSpawnEntries(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading spawn entries: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(bus.FormatSpawnQuota(entries, bus.Config().SpawnQuota))
+}
+
+// spawnGroupMembers returns the spawns in a fanout group, or nil if id is
+// not a group ID.
+func spawnGroupMembers(session, id string) []bus.SpawnEntry {
+	entries, err := bus.ReadSpawnEntries(session)
+	if err != nil {
+		return nil
+	}
+	return bus.SpawnGroupEntries(entries, id)
+}
@@ -1,29 +1,71 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/tui"
 )
 
 // Status handles the "muxcode-agent-bus status" subcommand.
-// Usage: muxcode-agent-bus status [--json]
+// Usage: muxcode-agent-bus status [sla|checks] [--json] [--watch [--refresh N]]
 func Status(args []string) {
+	if len(args) > 0 && args[0] == "sla" {
+		statusSLA(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "checks" {
+		statusChecks(args[1:])
+		return
+	}
+
+	const usage = "Usage: muxcode-agent-bus status [sla|checks] [--json] [--watch [--refresh N]]\n"
 	jsonOutput := false
+	watch := false
+	refresh := 2
 
-	for _, arg := range args {
-		switch arg {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
 		case "--json":
 			jsonOutput = true
+		case "--watch", "-w":
+			watch = true
+		case "--refresh":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --refresh requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			v, err := strconv.Atoi(args[i])
+			if err != nil || v < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --refresh must be a positive integer\n")
+				os.Exit(1)
+			}
+			refresh = v
 		default:
-			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
-			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus status [--json]\n")
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
 			os.Exit(1)
 		}
 	}
 
 	session := bus.BusSession()
+
+	if watch {
+		if jsonOutput {
+			fmt.Fprintf(os.Stderr, "Error: --watch and --json are mutually exclusive\n")
+			os.Exit(1)
+		}
+		if err := tui.NewStatusView(session, refresh).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	statuses := bus.GetAllAgentStatus(session)
 
 	if jsonOutput {
@@ -37,3 +79,77 @@ func Status(args []string) {
 		fmt.Print(bus.FormatStatusTable(statuses))
 	}
 }
+
+// statusSLA handles: status sla [--json] [--breaches]
+func statusSLA(args []string) {
+	jsonOutput := false
+	breachesOnly := false
+
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		case "--breaches":
+			breachesOnly = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus status sla [--json] [--breaches]\n")
+			os.Exit(1)
+		}
+	}
+
+	session := bus.BusSession()
+	results, summaries, err := bus.SLAReport(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+		os.Exit(1)
+	}
+
+	if breachesOnly {
+		for _, r := range results {
+			if r.Status == "breached" {
+				fmt.Println(bus.FormatSLABreach(r))
+			}
+		}
+		return
+	}
+
+	if jsonOutput {
+		out, err := bus.FormatSLAReportJSON(summaries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+	} else {
+		fmt.Print(bus.FormatSLAReport(summaries))
+	}
+}
+
+// statusChecks handles: status checks [--json]
+func statusChecks(args []string) {
+	jsonOutput := false
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus status checks [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	stats, err := bus.ReadWatcherChecks(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatWatcherChecks(stats))
+}
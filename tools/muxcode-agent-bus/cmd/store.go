@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Store handles the "muxcode-agent-bus store" subcommand.
+func Store(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus store <info|migrate> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "info":
+		storeInfo(args[1:])
+	case "migrate":
+		storeMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown store subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus store <info|migrate> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// storeInfo handles: store info
+func storeInfo(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus store info\n")
+		os.Exit(1)
+	}
+
+	backend := bus.StoreBackend()
+	fmt.Printf("Backend: %s\n", backend)
+	if backend != bus.StoreSQLite {
+		fmt.Println("  History, API history, and memory are read from JSONL and markdown files.")
+		fmt.Println("  Set MUXCODE_STORE=sqlite to use SQLite.")
+		return
+	}
+
+	path := bus.StorePath()
+	fmt.Printf("Database: %s\n", path)
+	s, err := bus.OpenSQLiteStore(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	streams, err := s.Streams("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing streams: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Streams: %d\n", len(streams))
+	for _, stream := range streams {
+		fmt.Printf("  %s\n", stream)
+	}
+}
+
+// storeMigrate handles: store migrate [--db PATH]
+func storeMigrate(args []string) {
+	path := bus.StorePath()
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--db":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --db requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			path = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus store migrate [--db PATH]\n")
+			os.Exit(1)
+		}
+	}
+
+	s, err := bus.OpenSQLiteStore(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	results, err := bus.MigrateToStore(bus.BusSession(), s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatMigrateResults(results, path))
+	if bus.StoreBackend() != bus.StoreSQLite {
+		fmt.Println("Set MUXCODE_STORE=sqlite to start using it. Source files were left in place.")
+	}
+}
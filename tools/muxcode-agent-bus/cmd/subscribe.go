@@ -36,32 +36,72 @@ func Subscribe(args []string) {
 	}
 }
 
-// subscribeAdd handles: subscribe add <event> <outcome> <notify> [message...]
+// subscribeAdd handles: subscribe add <event> <outcome> <notify> [message...] [--webhook URL] [--slack CHANNEL] [--match REGEX] [--from ROLE]
 func subscribeAdd(args []string) {
-	if len(args) < 3 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus subscribe add <event> <outcome> <notify> [message]\n")
+	var webhookURL, slackChannel, match, roleFrom string
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--webhook":
+			if i+1 < len(args) {
+				webhookURL = args[i+1]
+				i++
+			}
+		case "--slack":
+			if i+1 < len(args) {
+				slackChannel = args[i+1]
+				i++
+			}
+		case "--match":
+			if i+1 < len(args) {
+				match = args[i+1]
+				i++
+			}
+		case "--from":
+			if i+1 < len(args) {
+				roleFrom = args[i+1]
+				i++
+			}
+		default:
+			positional = append(positional, args[i])
+		}
+	}
+
+	if len(positional) < 3 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus subscribe add <event> <outcome> <notify> [message] [--webhook URL] [--slack CHANNEL] [--match REGEX] [--from ROLE]\n")
 		fmt.Fprintf(os.Stderr, "  event:   build, test, deploy, or * (all)\n")
 		fmt.Fprintf(os.Stderr, "  outcome: success, failure, or * (any)\n")
-		fmt.Fprintf(os.Stderr, "  notify:  agent role to notify\n")
+		fmt.Fprintf(os.Stderr, "  notify:  agent role to notify, or - for none (requires --webhook or --slack)\n")
 		fmt.Fprintf(os.Stderr, "  message: template (supports ${event}, ${outcome}, ${exit_code}, ${command})\n")
+		fmt.Fprintf(os.Stderr, "  --webhook URL:   POST a JSON payload to URL\n")
+		fmt.Fprintf(os.Stderr, "  --slack CHANNEL: post to a Slack channel (token in %s)\n", bus.SlackTokenEnv)
+		fmt.Fprintf(os.Stderr, "  --match REGEX:   only fire when REGEX matches the command or message\n")
+		fmt.Fprintf(os.Stderr, "  --from ROLE:     only fire for events sent by ROLE\n")
 		os.Exit(1)
 	}
 
-	event := args[0]
-	outcome := args[1]
-	notify := args[2]
+	event := positional[0]
+	outcome := positional[1]
+	notify := positional[2]
+	if notify == "-" {
+		notify = ""
+	}
 	message := ""
-	if len(args) > 3 {
-		message = strings.Join(args[3:], " ")
+	if len(positional) > 3 {
+		message = strings.Join(positional[3:], " ")
 	}
 
 	session := bus.BusSession()
 
 	entry, err := bus.AddSubscription(session, bus.Subscription{
-		Event:   event,
-		Outcome: outcome,
-		Notify:  notify,
-		Message: message,
+		Event:        event,
+		Outcome:      outcome,
+		Notify:       notify,
+		Message:      message,
+		WebhookURL:   webhookURL,
+		SlackChannel: slackChannel,
+		Match:        match,
+		RoleFrom:     roleFrom,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error adding subscription: %v\n", err)
@@ -69,7 +109,16 @@ func subscribeAdd(args []string) {
 	}
 
 	fmt.Printf("Added subscription: %s\n", entry.ID)
-	fmt.Printf("  Event: %s  Outcome: %s  Notify: %s\n", entry.Event, entry.Outcome, entry.Notify)
+	fmt.Printf("  Event: %s  Outcome: %s  Notify: %s\n", entry.Event, entry.Outcome, entry.Targets())
+	if entry.WebhookURL != "" {
+		fmt.Printf("  Webhook: %s\n", entry.WebhookURL)
+	}
+	if entry.Match != "" || entry.RoleFrom != "" {
+		fmt.Printf("  Filter: %s\n", entry.FilterSummary())
+	}
+	if entry.SlackChannel != "" && os.Getenv(bus.SlackTokenEnv) == "" {
+		fmt.Fprintf(os.Stderr, "  warning: %s is not set; Slack delivery will fail until it is\n", bus.SlackTokenEnv)
+	}
 	fmt.Printf("  Message: %s\n", entry.Message)
 }
 
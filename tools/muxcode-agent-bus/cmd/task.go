@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Task handles the "muxcode-agent-bus task" subcommand.
+func Task(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task <defer|list|remove|clean|add|claim|update|done|board|show> [args...]\n")
+		os.Exit(1)
+	}
+
+	subcmd := args[0]
+	subArgs := args[1:]
+
+	switch subcmd {
+	case "defer":
+		taskDefer(subArgs)
+	case "list":
+		taskList(subArgs)
+	case "remove":
+		taskRemove(subArgs)
+	case "clean":
+		taskClean(subArgs)
+	case "add":
+		taskAdd(subArgs)
+	case "claim":
+		taskClaim(subArgs)
+	case "update":
+		taskUpdate(subArgs)
+	case "done":
+		taskDone(subArgs)
+	case "board":
+		taskBoard(subArgs)
+	case "show":
+		taskShow(subArgs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown task subcommand: %s\n", subcmd)
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task <defer|list|remove|clean|add|claim|update|done|board|show> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// taskDefer handles: task defer [--role ROLE] [--action ACTION] <message>
+func taskDefer(args []string) {
+	usage := "Usage: muxcode-agent-bus task defer [--role ROLE] [--action ACTION] <message>\n"
+	var entry bus.TaskEntry
+	var words []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--role":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --role requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			entry.Target = args[i]
+		case "--action":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --action requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			entry.Action = args[i]
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			words = append(words, args[i])
+		}
+	}
+
+	entry.Message = strings.Join(words, " ")
+	if entry.Message == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	task, err := bus.DeferTask(session, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error deferring task: %v\n", err)
+		os.Exit(1)
+	}
+
+	target := task.Target
+	if target == "" {
+		target = "any idle local-LLM agent"
+	}
+	fmt.Printf("Deferred task: %s\n", task.ID)
+	fmt.Printf("  Target: %s  Action: %s\n", target, task.Action)
+	fmt.Printf("  Message: %s\n", task.Message)
+}
+
+// taskList handles: task list [--all] [--json]
+func taskList(args []string) {
+	showAll := false
+	jsonOut := false
+	for _, arg := range args {
+		switch arg {
+		case "--all":
+			showAll = true
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task list [--all] [--json]\n")
+			os.Exit(1)
+		}
+	}
+
+	session := bus.BusSession()
+	tasks, err := bus.ReadTasks(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		var out []bus.TaskEntry
+		for _, t := range tasks {
+			if showAll || t.Status == bus.TaskPending {
+				out = append(out, t)
+			}
+		}
+		if out == nil {
+			out = []bus.TaskEntry{}
+		}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Print(bus.FormatTaskList(tasks, showAll))
+}
+
+// taskRemove handles: task remove <id>
+func taskRemove(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task remove <id>\n")
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	if err := bus.RemoveTask(session, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error removing task: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removed task: %s\n", args[0])
+}
+
+// taskClean handles: task clean
+func taskClean(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task clean\n")
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	n, err := bus.CleanTasks(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error cleaning tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removed %d dispatched task(s)\n", n)
+}
+
+// taskAdd handles: task add [--priority P] [--assign ROLE] [--link MSGID]... <title>
+func taskAdd(args []string) {
+	usage := "Usage: muxcode-agent-bus task add [--priority high|normal|low] [--assign ROLE] [--link MSGID]... <title>\n"
+	task := bus.BoardTask{CreatedBy: bus.BusRole()}
+	var words []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--priority", "--assign", "--link":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			flag := args[i]
+			i++
+			switch flag {
+			case "--priority":
+				task.Priority = args[i]
+			case "--assign":
+				task.Assignee = args[i]
+			case "--link":
+				task.Links = append(task.Links, args[i])
+			}
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			words = append(words, args[i])
+		}
+	}
+
+	task.Title = strings.Join(words, " ")
+	if task.Title == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	added, err := bus.AddBoardTask(bus.BusSession(), task)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error adding task: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Added task: %s\n", added.ID)
+	fmt.Printf("  Priority: %s  Title: %s\n", added.Priority, added.Title)
+}
+
+// taskClaim handles: task claim <id>
+func taskClaim(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task claim <id>\n")
+		os.Exit(1)
+	}
+
+	role := bus.BusRole()
+	task, err := bus.ClaimBoardTask(bus.BusSession(), args[0], role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error claiming task: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Claimed task %s for %s: %s\n", task.ID, role, task.Title)
+}
+
+// taskUpdate handles: task update <id> [--status S] [--priority P] [--assign ROLE] [--note TEXT] [--link MSGID]...
+func taskUpdate(args []string) {
+	usage := "Usage: muxcode-agent-bus task update <id> [--status S] [--priority P] [--assign ROLE] [--note TEXT] [--link MSGID]...\n"
+	var id string
+	var u bus.BoardUpdate
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--status", "--priority", "--assign", "--note", "--link":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			flag := args[i]
+			i++
+			switch flag {
+			case "--status":
+				u.Status = args[i]
+			case "--priority":
+				u.Priority = args[i]
+			case "--assign":
+				u.Assignee = args[i]
+			case "--note":
+				u.Note = args[i]
+			case "--link":
+				u.Links = append(u.Links, args[i])
+			}
+		default:
+			if strings.HasPrefix(args[i], "--") || id != "" {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(1)
+			}
+			id = args[i]
+		}
+	}
+
+	if id == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	task, err := bus.UpdateBoardTask(bus.BusSession(), id, bus.BusRole(), u)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating task: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatBoardTask(task))
+}
+
+// taskDone handles: task done <id> [note]
+func taskDone(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task done <id> [note]\n")
+		os.Exit(1)
+	}
+
+	task, err := bus.CompleteBoardTask(bus.BusSession(), args[0], bus.BusRole(), strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Done: %s %s\n", task.ID, task.Title)
+}
+
+// taskBoard handles: task board [--status S] [--role ROLE] [--all] [--json]
+func taskBoard(args []string) {
+	usage := "Usage: muxcode-agent-bus task board [--status S] [--role ROLE] [--all] [--json]\n"
+	var f bus.BoardFilter
+	jsonOut := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--status", "--role":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			if args[i] == "--status" {
+				f.Status = args[i+1]
+			} else {
+				f.Assignee = args[i+1]
+			}
+			i++
+		case "--all":
+			f.All = true
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	tasks, err := bus.ReadBoard(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading task board: %v\n", err)
+		os.Exit(1)
+	}
+	tasks = bus.FilterBoard(tasks, f)
+
+	if jsonOut {
+		if tasks == nil {
+			tasks = []bus.BoardTask{}
+		}
+		data, _ := json.MarshalIndent(tasks, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatBoard(tasks))
+}
+
+// taskShow handles: task show <id>
+func taskShow(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus task show <id>\n")
+		os.Exit(1)
+	}
+
+	task, err := bus.GetBoardTask(bus.BusSession(), args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatBoardTask(task))
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Template handles the "muxcode-agent-bus template" subcommand.
+func Template(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus template <list|show> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		templateList(args[1:])
+	case "show":
+		templateShow(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown template subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus template <list|show> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// templateList handles: template list
+func templateList(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus template list\n")
+		os.Exit(1)
+	}
+
+	names, err := bus.ListSessionTemplates()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing templates: %v\n", err)
+		os.Exit(1)
+	}
+	if len(names) == 0 {
+		fmt.Printf("No templates in %s\n", bus.TemplatesDir())
+		return
+	}
+	for _, name := range names {
+		t, err := bus.LoadSessionTemplate(name)
+		if err != nil {
+			fmt.Printf("%-20s (invalid: %v)\n", name, err)
+			continue
+		}
+		fmt.Printf("%-20s %s\n", name, t.Description)
+	}
+}
+
+// templateShow handles: template show <name> [--windows]
+// --windows prints only the space-separated window list (used by muxcode.sh).
+func templateShow(args []string) {
+	name := ""
+	windowsOnly := false
+	for _, arg := range args {
+		switch {
+		case arg == "--windows":
+			windowsOnly = true
+		case strings.HasPrefix(arg, "--"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus template show <name> [--windows]\n")
+			os.Exit(1)
+		default:
+			name = arg
+		}
+	}
+	if name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus template show <name> [--windows]\n")
+		os.Exit(1)
+	}
+
+	t, err := bus.LoadSessionTemplate(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if windowsOnly {
+		fmt.Println(strings.Join(t.Windows, " "))
+		return
+	}
+	data, err := os.ReadFile(bus.SessionTemplatePath(name))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("# %s\n", bus.SessionTemplatePath(name))
+	fmt.Print(string(data))
+}
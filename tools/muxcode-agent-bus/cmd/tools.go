@@ -9,22 +9,62 @@ import (
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
+const toolsUsage = `Usage: muxcode-agent-bus tools <role> [--json] [--sandbox]
+       muxcode-agent-bus tools check <role> "<command>" [--json]
+       muxcode-agent-bus tools explain <role> [--json]
+       muxcode-agent-bus tools diff <role-a> <role-b> [--json]
+`
+
 // Tools handles the "muxcode-agent-bus tools" subcommand.
-// Usage: muxcode-agent-bus tools <role> [--json]
+// Usage: muxcode-agent-bus tools <role> [--json] [--sandbox]
+//
+//	muxcode-agent-bus tools check <role> "<command>" [--json]
+//	muxcode-agent-bus tools explain <role> [--json]
+//	muxcode-agent-bus tools diff <role-a> <role-b> [--json]
 func Tools(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus tools <role> [--json]\n")
+		fmt.Fprint(os.Stderr, toolsUsage)
 		os.Exit(1)
 	}
+	switch args[0] {
+	case "check":
+		toolsCheck(args[1:])
+		return
+	case "explain":
+		toolsExplain(args[1:])
+		return
+	case "diff":
+		toolsDiff(args[1:])
+		return
+	}
 
 	role := args[0]
 	asJSON := false
+	sandbox := false
 	for _, a := range args[1:] {
-		if a == "--json" {
+		switch a {
+		case "--json":
 			asJSON = true
+		case "--sandbox":
+			sandbox = true
 		}
 	}
 
+	if sandbox {
+		// Sandbox policy is always JSON — consumed by the LLM harness
+		policy := bus.ResolveSandbox(role)
+		if policy == nil {
+			return
+		}
+		data, err := json.Marshal(policy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
 	tools := bus.ResolveTools(role)
 	if tools == nil {
 		// No profile for this role — silent exit (bash caller checks for empty)
@@ -42,3 +82,85 @@ func Tools(args []string) {
 		fmt.Println(strings.Join(tools, "\n"))
 	}
 }
+
+// toolsArgs splits positional arguments from --json, exiting on any other
+// flag or when the positional count is not n.
+func toolsArgs(args []string, n int) ([]string, bool) {
+	asJSON := false
+	var pos []string
+	for _, a := range args {
+		switch {
+		case a == "--json":
+			asJSON = true
+		case strings.HasPrefix(a, "--"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprint(os.Stderr, toolsUsage)
+			os.Exit(1)
+		default:
+			pos = append(pos, a)
+		}
+	}
+	if len(pos) != n {
+		fmt.Fprint(os.Stderr, toolsUsage)
+		os.Exit(1)
+	}
+	return pos, asJSON
+}
+
+// toolsExplain shows a role's effective tool list with the profile part
+// and config layer each pattern came from.
+func toolsExplain(args []string) {
+	pos, asJSON := toolsArgs(args, 1)
+	ex, err := bus.ExplainTools(pos[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(ex, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatToolExplanation(ex, bus.ConfigLayers()))
+}
+
+// toolsDiff shows the patterns only one of two roles is allowed.
+func toolsDiff(args []string) {
+	pos, asJSON := toolsArgs(args, 2)
+	d, err := bus.DiffTools(pos[0], pos[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatToolDiff(d))
+}
+
+// toolsCheck reports whether a role's tool profile allows a command, and
+// which pattern does. Exits 1 when the command is denied.
+func toolsCheck(args []string) {
+	pos, asJSON := toolsArgs(args, 2)
+	if strings.TrimSpace(pos[1]) == "" {
+		fmt.Fprint(os.Stderr, toolsUsage)
+		os.Exit(1)
+	}
+
+	check, err := bus.CheckToolCommand(pos[0], pos[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(check, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(bus.FormatToolCheck(check))
+	}
+	if !check.Allowed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Trace handles the "muxcode-agent-bus trace" subcommand.
+func Trace(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus trace <list|show|context|export> [args...]\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		traceList(args[1:])
+	case "show":
+		traceShow(args[1:])
+	case "context":
+		traceContext(args[1:])
+	case "export":
+		traceExport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown trace subcommand: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus trace <list|show|context|export> [args...]\n")
+		os.Exit(1)
+	}
+}
+
+// traceList handles: trace list [--limit N]
+func traceList(args []string) {
+	limit := 20
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--limit":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "Invalid --limit: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				limit = n
+				i++
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus trace list [--limit N]\n")
+			os.Exit(1)
+		}
+	}
+
+	spans, err := bus.ReadSpans(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading spans: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatTraceList(spans, limit))
+}
+
+// traceShow handles: trace show <trace-id|message-id>
+func traceShow(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus trace show <trace-id|message-id>\n")
+		os.Exit(1)
+	}
+
+	spans, err := bus.ReadSpans(bus.BusSession())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading spans: %v\n", err)
+		os.Exit(1)
+	}
+	trace := bus.FindTrace(spans, args[0])
+	if len(trace) == 0 {
+		fmt.Fprintf(os.Stderr, "Trace not found: %s\n", args[0])
+		os.Exit(1)
+	}
+	fmt.Print(bus.FormatTraceTree(trace))
+}
+
+// traceContext handles: trace context [role]
+// Prints the role's current traceparent, for passing to other tools.
+func traceContext(args []string) {
+	role := bus.BusRole()
+	if len(args) > 0 {
+		role = args[0]
+	}
+
+	traceID, spanID := bus.CurrentTraceContext(bus.BusSession(), role)
+	if traceID == "" {
+		fmt.Fprintf(os.Stderr, "No trace context for %s\n", role)
+		os.Exit(1)
+	}
+	fmt.Println(bus.FormatTraceParent(traceID, spanID))
+}
+
+// traceExport handles: trace export [--endpoint URL]
+// Sends every recorded span to the OTLP collector.
+func traceExport(args []string) {
+	endpoint := bus.OTLPEndpoint()
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--endpoint":
+			if i+1 < len(args) {
+				endpoint = bus.OTLPTracesURL(args[i+1])
+				i++
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus trace export [--endpoint URL]\n")
+			os.Exit(1)
+		}
+	}
+	if endpoint == "" {
+		fmt.Fprintf(os.Stderr, "No OTLP endpoint: set %s or pass --endpoint\n", bus.OTLPEndpointEnv)
+		os.Exit(1)
+	}
+
+	session := bus.BusSession()
+	spans, err := bus.ReadSpans(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading spans: %v\n", err)
+		os.Exit(1)
+	}
+	if err := bus.ExportSpans(endpoint, session, spans); err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting spans: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d span(s) to %s\n", len(spans), endpoint)
+}
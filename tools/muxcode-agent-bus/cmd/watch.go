@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/watcher"
@@ -11,10 +14,38 @@ import (
 
 // Watch handles the "muxcode-agent-bus watch" subcommand.
 // Usage: muxcode-agent-bus watch [session] [--poll N] [--debounce N]
+//
+//	muxcode-agent-bus watch start [--daemon] [session] [--poll N] [--debounce N]
+//	muxcode-agent-bus watch <status|stop> [session]
 func Watch(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "start":
+			watchStart(args[1:])
+			return
+		case "status":
+			watchStatus(args[1:])
+			return
+		case "stop":
+			watchStop(args[1:])
+			return
+		case "supervise":
+			watchSupervise(args[1:])
+			return
+		}
+	}
+
+	session, pollSecs, debounceSecs, _ := parseWatchArgs(args, false)
+	runWatcher(session, pollSecs, debounceSecs)
+}
+
+// parseWatchArgs parses [session] [--poll N] [--debounce N], plus --daemon
+// when allowDaemon is set. The session defaults to BUS_SESSION.
+func parseWatchArgs(args []string, allowDaemon bool) (string, int, int, bool) {
 	session := ""
 	pollSecs := 2
 	debounceSecs := 8
+	daemon := false
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -42,6 +73,12 @@ func Watch(args []string) {
 				os.Exit(1)
 			}
 			debounceSecs = v
+		case "--daemon", "-d":
+			if !allowDaemon {
+				fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+				os.Exit(1)
+			}
+			daemon = true
 		default:
 			// First non-flag argument is the session name
 			if session == "" && len(args[i]) > 0 && args[i][0] != '-' {
@@ -56,9 +93,87 @@ func Watch(args []string) {
 	if session == "" {
 		session = bus.BusSession()
 	}
+	return session, pollSecs, debounceSecs, daemon
+}
+
+// parseWatchSession parses the optional [session] argument of status and stop.
+func parseWatchSession(args []string, subcmd string) string {
+	switch {
+	case len(args) == 0:
+		return bus.BusSession()
+	case len(args) == 1 && args[0] != "" && args[0][0] != '-':
+		return args[0]
+	}
+	fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus watch %s [session]\n", subcmd)
+	os.Exit(1)
+	return ""
+}
+
+// runWatcher runs the watcher in the foreground until SIGTERM, SIGINT, or
+// SIGHUP, which shut it down gracefully.
+func runWatcher(session string, pollSecs, debounceSecs int) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		sig := <-sigs
+		cancel(fmt.Errorf("received %s", sig))
+		signal.Stop(sigs) // a second signal kills the watcher
+	}()
 
 	w := watcher.New(session, pollSecs, debounceSecs)
-	if err := w.Run(); err != nil {
+	if err := w.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// watchStart handles: watch start [--daemon] [session] [--poll N] [--debounce N]
+func watchStart(args []string) {
+	session, pollSecs, debounceSecs, daemon := parseWatchArgs(args, true)
+	if !daemon {
+		runWatcher(session, pollSecs, debounceSecs)
+		return
+	}
+
+	pid, err := watcher.StartDaemon(session, pollSecs, debounceSecs)
+	if err != nil && pid == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Printf("Watcher daemon started for session %s (PID %d)\n", session, pid)
+	fmt.Printf("  Log: %s\n", bus.WatcherLogPath(session))
+}
+
+// watchStatus handles: watch status [session]
+func watchStatus(args []string) {
+	session := parseWatchSession(args, "status")
+	st := watcher.Status(session)
+	fmt.Print(watcher.FormatStatus(session, st))
+	if !st.Running {
+		os.Exit(1)
+	}
+}
+
+// watchStop handles: watch stop [session]
+func watchStop(args []string) {
+	session := parseWatchSession(args, "stop")
+	if err := watcher.StopDaemon(session); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Watcher stopped for session %s\n", session)
+}
+
+// watchSupervise runs the daemon supervisor in the foreground (used by
+// "watch start --daemon").
+func watchSupervise(args []string) {
+	session, pollSecs, debounceSecs, _ := parseWatchArgs(args, false)
+	if err := watcher.Supervise(session, pollSecs, debounceSecs); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
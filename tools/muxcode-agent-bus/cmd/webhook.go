@@ -17,7 +17,7 @@ import (
 // Webhook handles the "muxcode-agent-bus webhook" subcommand.
 func Webhook(args []string) {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus webhook <start|stop|status|serve> [flags]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus webhook <start|stop|status|serve|slack> [flags]\n")
 		os.Exit(1)
 	}
 
@@ -33,9 +33,11 @@ func Webhook(args []string) {
 		webhookStatus(subArgs)
 	case "serve":
 		webhookServe(subArgs)
+	case "slack":
+		webhookSlack(subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown webhook subcommand: %s\n", subcmd)
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus webhook <start|stop|status|serve> [flags]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus webhook <start|stop|status|serve|slack> [flags]\n")
 		os.Exit(1)
 	}
 }
@@ -84,6 +86,16 @@ func webhookStart(args []string) {
 		os.Exit(1)
 	}
 
+	// Validate routes here — the detached server's errors are not visible
+	if err := bus.CheckWebhookRoutes(bus.Config().Webhook.Routes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := bus.Config().Webhook.Slack.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Find the current binary path for re-exec
 	exe, err := os.Executable()
 	if err != nil {
@@ -194,10 +206,22 @@ func webhookServe(args []string) {
 	}
 
 	session := bus.BusSession()
+	routes := bus.Config().Webhook.Routes
+	if err := bus.CheckWebhookRoutes(routes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	slack := bus.Config().Webhook.Slack
+	if err := slack.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	cfg := bus.WebhookConfig{
 		Host:    host,
 		Port:    port,
 		Token:   token,
+		Routes:  routes,
+		Slack:   slack,
 		Session: session,
 	}
 
@@ -217,3 +241,23 @@ func webhookServe(args []string) {
 		os.Exit(1)
 	}
 }
+
+// webhookSlack shows the Slack slash-command bridge: whether it is enabled,
+// the request URLs to configure in the Slack app, and the command mapping.
+func webhookSlack(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus webhook slack\n")
+		os.Exit(1)
+	}
+	slack := bus.Config().Webhook.Slack
+	if err := slack.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	port := 9090
+	if p, _, err := bus.ReadWebhookPid(bus.BusSession()); err == nil {
+		port = p
+	}
+	fmt.Print(bus.FormatSlackBridge(slack, "127.0.0.1", port))
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+const workflowUsage = `Usage: muxcode-agent-bus workflow <subcommand>
+  list                                     Workflows in .muxcode/workflows
+  show <name|file>                         Steps and transitions
+  validate [name|file]...                  Check definitions (all when none given)
+  run <name|file> [--var K=V]... [--from ROLE]
+  status [run-id] [--all] [--json]         Runs in progress, or one run's steps
+  resume <run-id>                          Re-run the failed or cancelled step
+  cancel <run-id>                          Stop a running run
+`
+
+// Workflow handles the "muxcode-agent-bus workflow" subcommand.
+func Workflow(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, workflowUsage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Print(bus.FormatWorkflowList(bus.WorkflowPaths()))
+	case "show":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus workflow show <name|file>\n")
+			os.Exit(1)
+		}
+		w := loadWorkflow(args[1])
+		fmt.Print(bus.FormatWorkflow(w))
+	case "validate":
+		workflowValidate(args[1:])
+	case "run":
+		workflowRun(args[1:])
+	case "status":
+		workflowStatus(args[1:])
+	case "resume":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus workflow resume <run-id>\n")
+			os.Exit(1)
+		}
+		r, err := bus.ResumeWorkflow(bus.BusSession(), args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Resumed %s at step %s\n", r.ID, r.Current)
+	case "cancel":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus workflow cancel <run-id>\n")
+			os.Exit(1)
+		}
+		if err := bus.CancelWorkflow(bus.BusSession(), args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cancelled %s\n", args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown workflow subcommand: %s\n", args[0])
+		fmt.Fprint(os.Stderr, workflowUsage)
+		os.Exit(1)
+	}
+}
+
+// loadWorkflow loads a workflow by name or path, exiting on error.
+func loadWorkflow(nameOrPath string) bus.Workflow {
+	w, err := bus.FindWorkflow(nameOrPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return w
+}
+
+// workflowValidate handles: workflow validate [name|file]...
+// Exits 1 when any workflow has problems.
+func workflowValidate(args []string) {
+	targets := args
+	if len(targets) == 0 {
+		targets = bus.WorkflowPaths()
+		if len(targets) == 0 {
+			fmt.Printf("No workflows in %s.\n", bus.WorkflowDir())
+			return
+		}
+	}
+	bad := 0
+	for _, t := range targets {
+		w, err := bus.FindWorkflow(t)
+		if err != nil {
+			fmt.Printf("%s: %v\n", t, err)
+			bad++
+			continue
+		}
+		errs := w.Validate()
+		if len(errs) == 0 {
+			fmt.Printf("%s: ok (%d steps)\n", w.Name, len(w.Steps))
+			continue
+		}
+		bad++
+		fmt.Printf("%s:\n", w.Name)
+		for _, e := range errs {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	if bad > 0 {
+		os.Exit(1)
+	}
+}
+
+// workflowRun handles: workflow run <name|file> [--var K=V]... [--from ROLE]
+func workflowRun(args []string) {
+	usage := "Usage: muxcode-agent-bus workflow run <name|file> [--var K=V]... [--from ROLE]\n"
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	name := args[0]
+	from := bus.BusRole()
+	vars := make(map[string]string)
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --var requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			k, v, ok := strings.Cut(args[i], "=")
+			if !ok || k == "" {
+				fmt.Fprintf(os.Stderr, "Error: --var must be KEY=VALUE\n")
+				os.Exit(1)
+			}
+			vars[k] = v
+		case "--from":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --from requires a value\n")
+				os.Exit(1)
+			}
+			i++
+			from = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", args[i])
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(1)
+		}
+	}
+
+	w := loadWorkflow(name)
+	r, err := bus.StartWorkflow(bus.BusSession(), w, from, vars)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Started %s at step %s\n", r.ID, r.Current)
+	fmt.Printf("Track it with: muxcode-agent-bus workflow status %s\n", r.ID)
+}
+
+// workflowStatus handles: workflow status [run-id] [--all] [--json]
+func workflowStatus(args []string) {
+	all, asJSON := false, false
+	id := ""
+	for _, a := range args {
+		switch {
+		case a == "--all":
+			all = true
+		case a == "--json":
+			asJSON = true
+		case strings.HasPrefix(a, "--"):
+			fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", a)
+			fmt.Fprintf(os.Stderr, "Usage: muxcode-agent-bus workflow status [run-id] [--all] [--json]\n")
+			os.Exit(1)
+		default:
+			id = a
+		}
+	}
+
+	session := bus.BusSession()
+	if id != "" {
+		r, err := bus.ReadWorkflowRun(session, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			data, _ := json.MarshalIndent(r, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Print(bus.FormatWorkflowRun(r))
+		return
+	}
+
+	runs, err := bus.ReadWorkflowRuns(session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading workflow runs: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		if runs == nil {
+			runs = []*bus.WorkflowRun{}
+		}
+		data, _ := json.MarshalIndent(runs, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(bus.FormatWorkflowRuns(runs, all))
+}
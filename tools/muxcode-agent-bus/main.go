@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/cmd"
 )
 
@@ -12,33 +13,65 @@ var usage = `Usage: muxcode-agent-bus <command> [args...]
 Commands:
   init        Initialize bus directories and memory
   send        Send a message to an agent
+  callback    List callback URLs waiting for or delivering results (list)
+  workflow    Run declarative multi-agent workflows (list, show, validate, run, status, resume, cancel)
+  approve     Approve a gated workflow step or chain action (approve <id> [--reason TEXT])
+  reject      Reject a gated workflow step or chain action (reject <id> [--reason TEXT])
+  approval    List approval gates waiting for a decision (list)
+  bus         Encryption-at-rest status and key rotation (status, rekey)
   inbox       Read messages from your inbox
   memory      Read/write persistent agent memory
-  watch       Watch for file changes and route events
+  scratch     Ephemeral per-session notes, separate from memory (write, read, clear)
+  watch       Watch for file changes and route events (start --daemon, status, stop)
   dashboard   Launch the agent dashboard TUI
   cleanup     Remove bus session directory
   notify      Send tmux notification to an agent
   lock        Set agent lock (busy indicator)
   unlock      Remove agent lock
   is-locked   Check if agent is locked
-  tools       List allowed tools for a role
-  chain       Execute an event chain action
-  log         Append an entry to a role's history log
-  prompt      Output shared agent coordination prompt for a role
+  edit-event  Record a file edit from hook JSON on stdin (used by the analyze hook)
+  heartbeat   Report agent liveness (touch, --pid loop, list)
+  tools       List allowed tools for a role (check, explain, diff)
+  chain       Execute, simulate, or graph event chains; list pending retries
+  log         Append an entry to a role's history log (--parse test results, --coverage)
+  prompt      Output agent coordination prompt for a role (templates, --vars)
   skill       Manage reusable instruction skills/plugins
   context     Manage per-agent drop-in context files
   session     Session compaction and context management
   cron        Manage scheduled tasks (add, list, remove, enable, disable, history)
-  status      Show all agents' current state (busy/idle/inbox/last-activity)
-  history     Show recent messages to/from an agent
+  task        Idle-agent task queue (defer, list, remove, clean) and shared task board (add, claim, update, done, board, show)
+  status      Show all agents' current state (busy/idle/inbox/last-activity; --watch for live view; sla, checks)
+  history     Show recent messages to/from an agent, a summary report (report), or flaky test suspects (flaky)
+  coverage    Show test coverage per role across runs (trend)
+  intake      Take in GitHub/Jira issues as requests and comment when done (poll, list, done)
+  deploy      Track environment deploys and verification; check promotion gates (record, status, check)
+  pr          Open PRs and sync review comments as pr-fix requests (create, sync, threads)
+  digest      Print or email a digest of recent history, alerts, and finished procs (--send)
   guard       Check for agent loop patterns (command retries, message ping-pong)
-  proc        Manage background processes (start, list, status, log, stop, clean)
-  spawn       Manage spawned agent sessions (start, list, status, result, stop, clean)
-  demo        Run scripted demo scenarios (run, list)
+  cost        Require approval before costly cloud commands run (check, rules)
+  proc        Manage background processes (start, list, status, log, stop, clean, templates)
+  spawn       Manage spawned agent sessions (start, fanout, list, status, result, stop, clean, quota)
+  demo        Run scripted demo scenarios (run, list, export, record, replay)
   webhook     Manage webhook HTTP endpoint (start, stop, status)
+  serve       Run the REST API server (send, inbox, status, history, memory, proc, spawn; gRPC with --grpc-port)
   subscribe   Manage event subscriptions (add, list, remove, enable, disable)
   agent       Run local LLM agent loop (run)
   api         Manage API collections, environments, and history
+  selftest    Run an end-to-end smoke test in a temporary session
+  simulate    Run a scenario against muxcode.json with scripted agents (--scenario FILE)
+  docs        Generate role handbooks (tools, policies, prompts, skills, context)
+  store       Storage backend info and JSONL-to-SQLite migration (info, migrate)
+  trace       Inspect and export message traces (list, show, context, export)
+  template    Inspect session templates for init --template (list, show)
+  role        Reshape the agent team at runtime (add, remove, restart, list)
+  policy      Evaluate send policy rules and show denials (check, log)
+  audit       Show the audit log of privileged operations (show)
+  redaction   Show what secret redaction scrubbed (report)
+  config      Check muxcode.json and session templates for errors (validate)
+  plugin      List installed plugins (list)
+
+Any other command runs the plugin of that name, if installed
+(see "muxcode-agent-bus plugin list").
 `
 
 func main() {
@@ -50,6 +83,9 @@ func main() {
 	subcmd := os.Args[1]
 	args := os.Args[2:]
 
+	// Roles added or removed at runtime ("role add/remove") apply to every command
+	bus.LoadSessionRoles(bus.BusSession())
+
 	switch subcmd {
 	case "init":
 		cmd.Init(args)
@@ -59,6 +95,20 @@ func main() {
 		cmd.Inbox(args)
 	case "memory":
 		cmd.Memory(args)
+	case "bus":
+		cmd.Bus(args)
+	case "callback":
+		cmd.Callback(args)
+	case "workflow":
+		cmd.Workflow(args)
+	case "approve":
+		cmd.Approve(args)
+	case "reject":
+		cmd.Reject(args)
+	case "approval":
+		cmd.Approval(args)
+	case "scratch":
+		cmd.Scratch(args)
 	case "watch":
 		cmd.Watch(args)
 	case "dashboard":
@@ -73,6 +123,10 @@ func main() {
 		cmd.Unlock(args)
 	case "is-locked":
 		cmd.IsLocked(args)
+	case "edit-event":
+		cmd.EditEvent(args)
+	case "heartbeat":
+		cmd.Heartbeat(args)
 	case "tools":
 		cmd.Tools(args)
 	case "chain":
@@ -89,10 +143,24 @@ func main() {
 		cmd.Session(args)
 	case "cron":
 		cmd.Cron(args)
+	case "task":
+		cmd.Task(args)
 	case "status":
 		cmd.Status(args)
+	case "digest":
+		cmd.Digest(args)
 	case "history":
 		cmd.History(args)
+	case "coverage":
+		cmd.Coverage(args)
+	case "pr":
+		cmd.PR(args)
+	case "intake":
+		cmd.Intake(args)
+	case "deploy":
+		cmd.Deploy(args)
+	case "cost":
+		cmd.Cost(args)
 	case "guard":
 		cmd.Guard(args)
 	case "proc":
@@ -101,6 +169,8 @@ func main() {
 		cmd.Spawn(args)
 	case "demo":
 		cmd.Demo(args)
+	case "serve":
+		cmd.Serve(args)
 	case "webhook":
 		cmd.Webhook(args)
 	case "subscribe":
@@ -109,7 +179,34 @@ func main() {
 		cmd.Agent(args)
 	case "api":
 		cmd.Api(args)
+	case "selftest":
+		cmd.Selftest(args)
+	case "simulate":
+		cmd.Simulate(args)
+	case "docs":
+		cmd.Docs(args)
+	case "store":
+		cmd.Store(args)
+	case "trace":
+		cmd.Trace(args)
+	case "template":
+		cmd.Template(args)
+	case "role":
+		cmd.Role(args)
+	case "policy":
+		cmd.Policy(args)
+	case "audit":
+		cmd.Audit(args)
+	case "redaction":
+		cmd.Redaction(args)
+	case "config":
+		cmd.ConfigCmd(args)
+	case "plugin":
+		cmd.Plugin(args)
 	default:
+		if cmd.RunPlugin(subcmd, args) {
+			return
+		}
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", subcmd)
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
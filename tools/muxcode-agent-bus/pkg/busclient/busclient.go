@@ -0,0 +1,188 @@
+// Package busclient is the stable Go API for muxcode agent bus messaging.
+// It lets other Go tools send and read bus messages, query agent status and
+// history, and search or append agent memory without shelling out to the
+// muxcode-agent-bus CLI.
+//
+// busclient is its own module, tagged tools/muxcode-agent-bus/pkg/busclient/vX.Y.Z
+// and versioned by semver; Version matches the latest tag. Its types are
+// declared here and converted from the bus package at the boundary, so
+// changes to bus internals do not change this API.
+//
+//	c := busclient.New("", "ci")
+//	msg, err := c.Send("build", "request", "build", "Run ./build.sh", "")
+//	...
+//	replies, err := c.Receive("ci")
+package busclient
+
+import (
+	"fmt"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Version is the module's release, matching its most recent tag.
+const Version = "v1.0.0"
+
+// Message is a bus message between agents.
+type Message struct {
+	ID      string `json:"id"`
+	TS      int64  `json:"ts"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Payload string `json:"payload"`
+	ReplyTo string `json:"reply_to"`
+}
+
+// AgentStatus is one agent's current state.
+type AgentStatus struct {
+	Role       string `json:"role"`
+	Locked     bool   `json:"locked"`
+	InboxCount int    `json:"inbox_count"`
+	Unread     int    `json:"unread"`
+	LastMsgTS  int64  `json:"last_msg_ts"`
+	LastAction string `json:"last_action"`
+	LastPeer   string `json:"last_peer"`
+	LastDir    string `json:"last_dir"` // "sent" or "recv"
+	Tasks      int    `json:"tasks"`    // active board tasks assigned to the role
+}
+
+// MemoryResult is one memory search hit.
+type MemoryResult struct {
+	Role      string  `json:"role"`
+	Section   string  `json:"section"`
+	Timestamp string  `json:"timestamp"`
+	Content   string  `json:"content"`
+	Score     float64 `json:"score"`
+}
+
+// Client sends and reads messages on one session's bus as one sender.
+type Client struct {
+	Session string
+	From    string
+	// NoNotify skips tmux and desktop notification after Send.
+	NoNotify bool
+}
+
+// New returns a client for session, sending as from. An empty session uses
+// the current one (BUS_SESSION, SESSION, or the tmux session); an empty from
+// uses the current role (AGENT_ROLE, BUS_ROLE, or the tmux window).
+func New(session, from string) *Client {
+	if session == "" {
+		session = bus.BusSession()
+	}
+	if from == "" {
+		from = bus.BusRole()
+	}
+	bus.LoadSessionRoles(session)
+	return &Client{Session: session, From: from}
+}
+
+// Roles returns the roles that can receive messages in the session.
+func (c *Client) Roles() []string {
+	return append([]string(nil), bus.KnownRoles...)
+}
+
+// Send delivers a message to a role, applying send policy and auto-CC, and
+// notifies the recipient unless NoNotify is set.
+func (c *Client) Send(to, msgType, action, payload, replyTo string) (Message, error) {
+	if !bus.IsKnownRole(to) {
+		return Message{}, fmt.Errorf("unknown role %q", to)
+	}
+	if msgType == "" {
+		msgType = "request"
+	}
+	msg := bus.NewMessage(c.From, to, msgType, action, payload, replyTo)
+	if deny := bus.CheckMessagePolicy(c.Session, msg); deny != "" {
+		return Message{}, fmt.Errorf("%s", deny)
+	}
+	if err := bus.Send(c.Session, msg); err != nil {
+		return Message{}, err
+	}
+	if !c.NoNotify {
+		_ = bus.Notify(c.Session, to)
+	}
+	return fromBusMessage(msg), nil
+}
+
+// Peek returns a role's unread messages without consuming them.
+func (c *Client) Peek(role string) ([]Message, error) {
+	msgs, err := bus.Peek(c.Session, role)
+	return fromBusMessages(msgs), err
+}
+
+// Receive returns and consumes a role's unread messages.
+func (c *Client) Receive(role string) ([]Message, error) {
+	msgs, err := bus.Receive(c.Session, role)
+	return fromBusMessages(msgs), err
+}
+
+// Status returns every agent's current state.
+func (c *Client) Status() []AgentStatus {
+	statuses := bus.GetAllAgentStatus(c.Session)
+	out := make([]AgentStatus, 0, len(statuses))
+	for _, s := range statuses {
+		out = append(out, AgentStatus{
+			Role: s.Role, Locked: s.Locked, InboxCount: s.InboxCount, Unread: s.Unread,
+			LastMsgTS: s.LastMsgTS, LastAction: s.LastAction, LastPeer: s.LastPeer,
+			LastDir: s.LastDir, Tasks: s.Tasks,
+		})
+	}
+	return out
+}
+
+// History returns up to limit recent messages to or from role ("" for all).
+func (c *Client) History(role string, limit int) []Message {
+	return fromBusMessages(bus.ReadLogHistory(c.Session, role, limit))
+}
+
+func fromBusMessage(m bus.Message) Message {
+	return Message{
+		ID: m.ID, TS: m.TS, From: m.From, To: m.To, Type: m.Type,
+		Action: m.Action, Payload: m.Payload, ReplyTo: m.ReplyTo,
+	}
+}
+
+func fromBusMessages(msgs []bus.Message) []Message {
+	if msgs == nil {
+		return nil
+	}
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, fromBusMessage(m))
+	}
+	return out
+}
+
+// ReadMemory returns a role's memory file ("shared" for shared memory).
+func ReadMemory(role string) (string, error) {
+	return bus.ReadMemory(role)
+}
+
+// AppendMemory adds a titled entry to a role's memory.
+func AppendMemory(role, section, content string) error {
+	return bus.AppendMemory(section, content, role)
+}
+
+// SearchMemory ranks memory entries against query with BM25. role limits
+// the search to one role's memory ("" for all); limit <= 0 means 10.
+func SearchMemory(query, role string, limit int) ([]MemoryResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	results, err := bus.SearchMemoryWithOptions(bus.SearchOptions{
+		Query: query, RoleFilter: role, Limit: limit, Mode: bus.SearchModeBM25,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MemoryResult, 0, len(results))
+	for _, r := range results {
+		out = append(out, MemoryResult{
+			Role: r.Entry.Role, Section: r.Entry.Section, Timestamp: r.Entry.Timestamp,
+			Content: r.Entry.Content, Score: r.Score,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package busclient
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func testClient(t *testing.T, from string) *Client {
+	t.Helper()
+	memDir := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", memDir)
+	session := fmt.Sprintf("test-busclient-%d", rand.Int())
+	if err := bus.Init(session, memDir); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Cleanup(session) })
+	c := New(session, from)
+	c.NoNotify = true
+	return c
+}
+
+func TestClient_SendPeekReceive(t *testing.T) {
+	c := testClient(t, "edit")
+
+	msg, err := c.Send("review", "", "review", "check the diff", "")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if msg.From != "edit" || msg.Type != "request" {
+		t.Errorf("msg = %+v, want from edit, type request", msg)
+	}
+	if _, err := c.Send("nobody", "request", "x", "y", ""); err == nil {
+		t.Error("expected error for unknown role")
+	}
+
+	peeked, err := c.Peek("review")
+	if err != nil || len(peeked) != 1 || peeked[0].ID != msg.ID {
+		t.Fatalf("Peek = %v, %v", peeked, err)
+	}
+	received, err := c.Receive("review")
+	if err != nil || len(received) != 1 {
+		t.Fatalf("Receive = %v, %v", received, err)
+	}
+	if left, _ := c.Peek("review"); len(left) != 0 {
+		t.Errorf("inbox after Receive = %v, want empty", left)
+	}
+
+	if h := c.History("review", 10); len(h) != 1 || h[0].ID != msg.ID {
+		t.Errorf("History = %v", h)
+	}
+	found := false
+	for _, s := range c.Status() {
+		if s.Role == "review" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Status missing review")
+	}
+}
+
+func TestFromBusMessage(t *testing.T) {
+	m := bus.NewMessage("edit", "build", "request", "build", "go build", "")
+	m.TraceID = "trace-1"
+	got := fromBusMessage(m)
+	want := Message{
+		ID: m.ID, TS: m.TS, From: "edit", To: "build", Type: "request",
+		Action: "build", Payload: "go build",
+	}
+	if got != want {
+		t.Errorf("fromBusMessage = %+v, want %+v", got, want)
+	}
+	if fromBusMessages(nil) != nil {
+		t.Error("fromBusMessages(nil) should be nil")
+	}
+}
+
+func TestClient_SendPolicyDenied(t *testing.T) {
+	bus.SetConfig(bus.DefaultConfig())
+	defer bus.SetConfig(nil)
+	c := testClient(t, "build")
+
+	// The default send_policy denies build → test (the hook chain handles it)
+	if _, err := c.Send("test", "request", "test", "run tests", ""); err == nil {
+		t.Error("expected send policy denial")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testClient(t, "edit")
+
+	if err := AppendMemory("build", "Flaky test", "TestRetry fails under -race"); err != nil {
+		t.Fatalf("AppendMemory: %v", err)
+	}
+	text, err := ReadMemory("build")
+	if err != nil || text == "" {
+		t.Fatalf("ReadMemory = %q, %v", text, err)
+	}
+	results, err := SearchMemory("race", "", 0)
+	if err != nil {
+		t.Fatalf("SearchMemory: %v", err)
+	}
+	if len(results) != 1 || results[0].Role != "build" || results[0].Section != "Flaky test" {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/mkober/muxcode/tools/muxcode-agent-bus/pkg/busclient
+
+go 1.22
+
+require github.com/mkober/muxcode/tools/muxcode-agent-bus v0.0.0-00010101000000-000000000000
+
+replace github.com/mkober/muxcode/tools/muxcode-agent-bus => ../..
@@ -0,0 +1,430 @@
+// Package buspb holds the message types and client for the bus gRPC API
+// defined in bus.proto. The types are written by hand against the protobuf
+// wire format rather than generated with protoc, so neither the server nor
+// callers depend on the grpc or protobuf modules; any gRPC client generated
+// from bus.proto interoperates with the server.
+//
+//	c, err := buspb.Dial("127.0.0.1:8078", token, "/path/to/grpc-cert.pem")
+//	...
+//	stream, err := c.Subscribe(ctx, &buspb.SubscribeRequest{Role: "build"})
+//	for {
+//		msg, err := stream.Recv()
+//		...
+//	}
+package buspb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Full method names, as used in gRPC request paths.
+const (
+	ServiceName     = "muxcode.bus.v1.Bus"
+	MethodSubscribe = "/" + ServiceName + "/Subscribe"
+	MethodGetStatus = "/" + ServiceName + "/GetStatus"
+	MethodSend      = "/" + ServiceName + "/Send"
+)
+
+// gRPC status codes used by the service.
+const (
+	CodeOK               = 0
+	CodeCanceled         = 1
+	CodeInvalidArgument  = 3
+	CodePermissionDenied = 7
+	CodeUnimplemented    = 12
+	CodeInternal         = 13
+	CodeUnavailable      = 14
+	CodeUnauthenticated  = 16
+)
+
+// Status is a non-OK gRPC status returned by a call.
+type Status struct {
+	Code    int
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", s.Code, s.Message)
+}
+
+// MaxFrame caps the size of one length-prefixed gRPC message.
+const MaxFrame = 4 << 20
+
+// WriteFrame writes one uncompressed length-prefixed gRPC message.
+func WriteFrame(w io.Writer, b []byte) error {
+	hdr := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(b)))
+	_, err := w.Write(append(hdr, b...))
+	return err
+}
+
+// ReadFrame reads one length-prefixed gRPC message. It returns io.EOF when
+// the stream ends cleanly between messages.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated frame header")
+		}
+		return nil, err
+	}
+	if hdr[0] != 0 {
+		return nil, fmt.Errorf("compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > MaxFrame {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", n, MaxFrame)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("truncated frame: %w", err)
+	}
+	return b, nil
+}
+
+// Wire types used by the messages in bus.proto.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// encoder appends protobuf fields, omitting proto3 default values.
+type encoder struct{ b []byte }
+
+func (e *encoder) tag(field, wt int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wt))
+}
+
+func (e *encoder) bytes(field int, v []byte) {
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) string(field int, v string) {
+	if v != "" {
+		e.bytes(field, []byte(v))
+	}
+}
+
+func (e *encoder) int64(field int, v int64) {
+	if v != 0 {
+		e.tag(field, wireVarint)
+		e.b = binary.AppendUvarint(e.b, uint64(v))
+	}
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.int64(field, 1)
+	}
+}
+
+// field is one decoded protobuf field: num with either a varint value or
+// length-delimited data.
+type field struct {
+	num    int
+	varint uint64
+	data   []byte
+}
+
+func (f field) str() string { return string(f.data) }
+func (f field) i64() int64  { return int64(f.varint) }
+func (f field) i32() int32  { return int32(f.varint) }
+
+// decode calls fn for each field in b. Fixed-width fields, which bus.proto
+// does not use, are skipped.
+func decode(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint in field %d", f.num)
+			}
+			f.varint, b = v, b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("invalid length in field %d", f.num)
+			}
+			f.data, b = b[n:n+int(l)], b[n+int(l):]
+		case wireI64:
+			if len(b) < 8 {
+				return fmt.Errorf("truncated field %d", f.num)
+			}
+			b = b[8:]
+			continue
+		case wireI32:
+			if len(b) < 4 {
+				return fmt.Errorf("truncated field %d", f.num)
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", key&7, f.num)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Message is a bus message.
+type Message struct {
+	ID      string
+	TS      int64
+	From    string
+	To      string
+	Type    string
+	Action  string
+	Payload string
+	ReplyTo string
+	TraceID string
+}
+
+// Marshal encodes m in protobuf wire format.
+func (m *Message) Marshal() []byte {
+	var e encoder
+	e.string(1, m.ID)
+	e.int64(2, m.TS)
+	e.string(3, m.From)
+	e.string(4, m.To)
+	e.string(5, m.Type)
+	e.string(6, m.Action)
+	e.string(7, m.Payload)
+	e.string(8, m.ReplyTo)
+	e.string(9, m.TraceID)
+	return e.b
+}
+
+// Unmarshal decodes b into m.
+func (m *Message) Unmarshal(b []byte) error {
+	*m = Message{}
+	return decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			m.ID = f.str()
+		case 2:
+			m.TS = f.i64()
+		case 3:
+			m.From = f.str()
+		case 4:
+			m.To = f.str()
+		case 5:
+			m.Type = f.str()
+		case 6:
+			m.Action = f.str()
+		case 7:
+			m.Payload = f.str()
+		case 8:
+			m.ReplyTo = f.str()
+		case 9:
+			m.TraceID = f.str()
+		}
+		return nil
+	})
+}
+
+// SubscribeRequest selects the messages a Subscribe stream carries.
+type SubscribeRequest struct {
+	Role  string // only messages to or from this role; empty for all
+	Since int64  // replay logged messages newer than this unix time
+}
+
+// Marshal encodes r in protobuf wire format.
+func (r *SubscribeRequest) Marshal() []byte {
+	var e encoder
+	e.string(1, r.Role)
+	e.int64(2, r.Since)
+	return e.b
+}
+
+// Unmarshal decodes b into r.
+func (r *SubscribeRequest) Unmarshal(b []byte) error {
+	*r = SubscribeRequest{}
+	return decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.Role = f.str()
+		case 2:
+			r.Since = f.i64()
+		}
+		return nil
+	})
+}
+
+// GetStatusRequest is the (empty) GetStatus request.
+type GetStatusRequest struct{}
+
+// Marshal encodes r in protobuf wire format.
+func (r *GetStatusRequest) Marshal() []byte { return nil }
+
+// Unmarshal decodes b into r.
+func (r *GetStatusRequest) Unmarshal(b []byte) error {
+	return decode(b, func(field) error { return nil })
+}
+
+// AgentStatus is one agent's current state.
+type AgentStatus struct {
+	Role       string
+	Locked     bool
+	InboxCount int32
+	Unread     int32
+	LastMsgTS  int64
+	LastAction string
+	LastPeer   string
+	LastDir    string
+	Tasks      int32
+}
+
+// Marshal encodes s in protobuf wire format.
+func (s *AgentStatus) Marshal() []byte {
+	var e encoder
+	e.string(1, s.Role)
+	e.bool(2, s.Locked)
+	e.int64(3, int64(s.InboxCount))
+	e.int64(4, int64(s.Unread))
+	e.int64(5, s.LastMsgTS)
+	e.string(6, s.LastAction)
+	e.string(7, s.LastPeer)
+	e.string(8, s.LastDir)
+	e.int64(9, int64(s.Tasks))
+	return e.b
+}
+
+// Unmarshal decodes b into s.
+func (s *AgentStatus) Unmarshal(b []byte) error {
+	*s = AgentStatus{}
+	return decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			s.Role = f.str()
+		case 2:
+			s.Locked = f.varint != 0
+		case 3:
+			s.InboxCount = f.i32()
+		case 4:
+			s.Unread = f.i32()
+		case 5:
+			s.LastMsgTS = f.i64()
+		case 6:
+			s.LastAction = f.str()
+		case 7:
+			s.LastPeer = f.str()
+		case 8:
+			s.LastDir = f.str()
+		case 9:
+			s.Tasks = f.i32()
+		}
+		return nil
+	})
+}
+
+// GetStatusResponse lists every agent's state.
+type GetStatusResponse struct {
+	Agents []*AgentStatus
+}
+
+// Marshal encodes r in protobuf wire format.
+func (r *GetStatusResponse) Marshal() []byte {
+	var e encoder
+	for _, a := range r.Agents {
+		e.bytes(1, a.Marshal())
+	}
+	return e.b
+}
+
+// Unmarshal decodes b into r.
+func (r *GetStatusResponse) Unmarshal(b []byte) error {
+	*r = GetStatusResponse{}
+	return decode(b, func(f field) error {
+		if f.num == 1 {
+			a := &AgentStatus{}
+			if err := a.Unmarshal(f.data); err != nil {
+				return err
+			}
+			r.Agents = append(r.Agents, a)
+		}
+		return nil
+	})
+}
+
+// SendRequest is a message to deliver.
+type SendRequest struct {
+	From    string // default "api"
+	To      string
+	Type    string // default "request"
+	Action  string
+	Payload string
+	ReplyTo string
+}
+
+// Marshal encodes r in protobuf wire format.
+func (r *SendRequest) Marshal() []byte {
+	var e encoder
+	e.string(1, r.From)
+	e.string(2, r.To)
+	e.string(3, r.Type)
+	e.string(4, r.Action)
+	e.string(5, r.Payload)
+	e.string(6, r.ReplyTo)
+	return e.b
+}
+
+// Unmarshal decodes b into r.
+func (r *SendRequest) Unmarshal(b []byte) error {
+	*r = SendRequest{}
+	return decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.From = f.str()
+		case 2:
+			r.To = f.str()
+		case 3:
+			r.Type = f.str()
+		case 4:
+			r.Action = f.str()
+		case 5:
+			r.Payload = f.str()
+		case 6:
+			r.ReplyTo = f.str()
+		}
+		return nil
+	})
+}
+
+// SendResponse carries the ID of the sent message.
+type SendResponse struct {
+	ID string
+}
+
+// Marshal encodes r in protobuf wire format.
+func (r *SendResponse) Marshal() []byte {
+	var e encoder
+	e.string(1, r.ID)
+	return e.b
+}
+
+// Unmarshal decodes b into r.
+func (r *SendResponse) Unmarshal(b []byte) error {
+	*r = SendResponse{}
+	return decode(b, func(f field) error {
+		if f.num == 1 {
+			r.ID = f.str()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package buspb
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	in := Message{ID: "1-build-abcd", TS: 1700000000, From: "build", To: "test", Type: "request",
+		Action: "test", Payload: "run tests\nplease ✓", ReplyTo: "x", TraceID: "t1"}
+	var out Message
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestMessageWireFormat(t *testing.T) {
+	// Field 1 (id) "a", field 2 (ts) 150: the protobuf encoding protoc emits
+	m := Message{ID: "a", TS: 150}
+	want := []byte{0x0a, 0x01, 'a', 0x10, 0x96, 0x01}
+	if got := m.Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("Marshal = % x, want % x", got, want)
+	}
+	if got := (&Message{}).Marshal(); len(got) != 0 {
+		t.Errorf("empty message = % x, want no bytes", got)
+	}
+}
+
+func TestUnmarshal_SkipsUnknownFields(t *testing.T) {
+	// Unknown varint field 15, fixed64 field 16, fixed32 field 17, then id
+	b := []byte{0x78, 0x05, 0x81, 0x01, 1, 2, 3, 4, 5, 6, 7, 8, 0x8d, 0x01, 1, 2, 3, 4, 0x0a, 0x01, 'z'}
+	var m Message
+	if err := m.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "z" {
+		t.Errorf("ID = %q, want z", m.ID)
+	}
+	if err := m.Unmarshal([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Error("truncated string: want error")
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	in := GetStatusResponse{Agents: []*AgentStatus{
+		{Role: "build", Locked: true, InboxCount: 2, Unread: 1, LastMsgTS: 42, LastAction: "build", LastPeer: "edit", LastDir: "recv", Tasks: 3},
+		{Role: "test", InboxCount: -1},
+	}}
+	var out GetStatusResponse
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Agents) != 2 || *out.Agents[0] != *in.Agents[0] || *out.Agents[1] != *in.Agents[1] {
+		t.Errorf("round trip = %+v %+v", out.Agents[0], out.Agents[1])
+	}
+}
+
+func TestRequestRoundTrips(t *testing.T) {
+	send := SendRequest{From: "ide", To: "build", Type: "request", Action: "build", Payload: "go", ReplyTo: "r"}
+	var gotSend SendRequest
+	if err := gotSend.Unmarshal(send.Marshal()); err != nil || gotSend != send {
+		t.Errorf("SendRequest = %+v, %v", gotSend, err)
+	}
+	sub := SubscribeRequest{Role: "build", Since: 99}
+	var gotSub SubscribeRequest
+	if err := gotSub.Unmarshal(sub.Marshal()); err != nil || gotSub != sub {
+		t.Errorf("SubscribeRequest = %+v, %v", gotSub, err)
+	}
+	resp := SendResponse{ID: "id-1"}
+	var gotResp SendResponse
+	if err := gotResp.Unmarshal(resp.Marshal()); err != nil || gotResp != resp {
+		t.Errorf("SendResponse = %+v, %v", gotResp, err)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFrame(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes()[:5], []byte{0, 0, 0, 0, 3}) {
+		t.Errorf("header = % x", buf.Bytes()[:5])
+	}
+	if b, err := ReadFrame(&buf); err != nil || string(b) != "abc" {
+		t.Errorf("first frame = %q, %v", b, err)
+	}
+	if b, err := ReadFrame(&buf); err != nil || len(b) != 0 {
+		t.Errorf("empty frame = %q, %v", b, err)
+	}
+	if _, err := ReadFrame(&buf); err != io.EOF {
+		t.Errorf("end = %v, want io.EOF", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{1, 0, 0, 0, 0})); err == nil {
+		t.Error("compressed frame: want error")
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{0, 0, 0, 0, 4, 'a'})); err == nil {
+		t.Error("truncated frame: want error")
+	}
+}
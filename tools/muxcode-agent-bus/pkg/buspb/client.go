@@ -0,0 +1,184 @@
+package buspb
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// CodeUnknown is reported when a response carries no usable gRPC status.
+const CodeUnknown = 2
+
+type marshaler interface{ Marshal() []byte }
+
+// Client calls the bus gRPC service over HTTP/2.
+type Client struct {
+	addr  string
+	token string
+	hc    *http.Client
+}
+
+// Dial returns a client for the server at addr (host:port). certFile is the
+// PEM certificate to trust, such as the self-signed grpc-cert.pem the server
+// writes to its bus directory; empty uses the system roots.
+func Dial(addr, token, certFile string) (*Client, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		pem, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", certFile)
+		}
+		cfg.RootCAs = pool
+	}
+	hc := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+	return NewClient(addr, token, hc), nil
+}
+
+// NewClient returns a client that sends requests through hc, which must
+// speak HTTP/2 to the server.
+func NewClient(addr, token string, hc *http.Client) *Client {
+	return &Client{addr: addr, token: token, hc: hc}
+}
+
+// call starts an RPC and returns the response once headers arrive.
+func (c *Client) call(ctx context.Context, method string, req marshaler) (*http.Response, error) {
+	var body bytes.Buffer
+	if err := WriteFrame(&body, req.Marshal()); err != nil {
+		return nil, err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+c.addr+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/grpc")
+	hreq.Header.Set("TE", "trailers")
+	if c.token != "" {
+		hreq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.hc.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &Status{Code: CodeUnknown, Message: "http status " + resp.Status}
+	}
+	// A trailers-only response carries the status in its headers
+	if err := statusFrom(resp.Header); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// statusFrom returns the gRPC status in h as an error, or nil when h has
+// none or it is OK.
+func statusFrom(h http.Header) error {
+	v := h.Get("Grpc-Status")
+	if v == "" {
+		return nil
+	}
+	code, err := strconv.Atoi(v)
+	if err != nil {
+		return &Status{Code: CodeUnknown, Message: "invalid grpc-status " + v}
+	}
+	if code == CodeOK {
+		return nil
+	}
+	msg := h.Get("Grpc-Message")
+	if m, err := url.PathUnescape(msg); err == nil {
+		msg = m
+	}
+	return &Status{Code: code, Message: msg}
+}
+
+// finish drains the body and returns the status from the trailers.
+func finish(resp *http.Response) error {
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.Trailer.Get("Grpc-Status") == "" {
+		return &Status{Code: CodeUnknown, Message: "response ended without grpc-status"}
+	}
+	return statusFrom(resp.Trailer)
+}
+
+// unary runs a single-response RPC.
+func (c *Client) unary(ctx context.Context, method string, req marshaler, out interface{ Unmarshal([]byte) error }) error {
+	resp, err := c.call(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	b, err := ReadFrame(resp.Body)
+	if err != nil {
+		if serr := finish(resp); serr != nil {
+			return serr
+		}
+		return err
+	}
+	if err := finish(resp); err != nil {
+		return err
+	}
+	return out.Unmarshal(b)
+}
+
+// Send delivers a message and returns its ID.
+func (c *Client) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	out := &SendResponse{}
+	return out, c.unary(ctx, MethodSend, req, out)
+}
+
+// GetStatus returns every agent's current state.
+func (c *Client) GetStatus(ctx context.Context) (*GetStatusResponse, error) {
+	out := &GetStatusResponse{}
+	return out, c.unary(ctx, MethodGetStatus, &GetStatusRequest{}, out)
+}
+
+// Subscribe opens a message stream. Cancel ctx or call Close to end it.
+func (c *Client) Subscribe(ctx context.Context, req *SubscribeRequest) (*SubscribeStream, error) {
+	resp, err := c.call(ctx, MethodSubscribe, req)
+	if err != nil {
+		return nil, err
+	}
+	return &SubscribeStream{resp: resp}, nil
+}
+
+// SubscribeStream is an open Subscribe call.
+type SubscribeStream struct {
+	resp *http.Response
+}
+
+// Recv blocks for the next message. It returns io.EOF when the server ends
+// the stream with an OK status, or a *Status for any other status.
+func (s *SubscribeStream) Recv() (*Message, error) {
+	b, err := ReadFrame(s.resp.Body)
+	if errors.Is(err, io.EOF) {
+		if err := finish(s.resp); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{}
+	return m, m.Unmarshal(b)
+}
+
+// Close ends the stream.
+func (s *SubscribeStream) Close() error {
+	return s.resp.Body.Close()
+}
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// boardPaneMax caps the task board lines shown on the dashboard.
+const boardPaneMax = 10
+
+// boardStatusColor returns the color for a board task status.
+func boardStatusColor(status string) string {
+	switch status {
+	case bus.BoardInProgress, bus.BoardClaimed:
+		return Green
+	case bus.BoardBlocked:
+		return Red
+	case bus.BoardDone:
+		return Comment
+	}
+	return Yellow
+}
+
+// formatBoardLine renders one board task: status, priority, assignee, title.
+func formatBoardLine(t bus.BoardTask) string {
+	assignee := t.Assignee
+	if assignee == "" {
+		assignee = "-"
+	}
+	pri := Pad(t.Priority, 6)
+	if t.Priority == "high" {
+		pri = Red + Bold + pri + RST
+	}
+	return fmt.Sprintf("  %s%s%s %s %s%s%s %s",
+		boardStatusColor(t.Status), Pad(t.Status, 11), RST,
+		pri,
+		Purple, Pad(assignee, 10), RST,
+		t.Title)
+}
+
+// RenderBoard returns the TASKS pane: active board tasks by priority.
+func RenderBoard(session string) []string {
+	tasks, _ := bus.ReadBoard(session)
+	active := bus.FilterBoard(tasks, bus.BoardFilter{})
+	if len(active) == 0 {
+		return []string{fmt.Sprintf("  %s(no open tasks)%s", Comment, RST)}
+	}
+	var lines []string
+	for i, t := range active {
+		if i == boardPaneMax {
+			lines = append(lines, fmt.Sprintf("  %s... %d more (task board)%s", Comment, len(active)-boardPaneMax, RST))
+			break
+		}
+		lines = append(lines, formatBoardLine(t))
+	}
+	return lines
+}
@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestRenderBoard(t *testing.T) {
+	session := fmt.Sprintf("test-board-%d", os.Getpid())
+	memDir := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", memDir)
+	if err := bus.Init(session, memDir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bus.Cleanup(session) }()
+
+	lines := RenderBoard(session)
+	if len(lines) != 1 || !strings.Contains(StripAnsi(lines[0]), "(no open tasks)") {
+		t.Errorf("empty board = %q", lines)
+	}
+
+	if _, err := bus.AddBoardTask(session, bus.BoardTask{Title: "low chore", Priority: "low"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bus.AddBoardTask(session, bus.BoardTask{Title: "urgent fix", Priority: "high", Assignee: "build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines = RenderBoard(session)
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want 2", lines)
+	}
+	first := StripAnsi(lines[0])
+	if !strings.Contains(first, "urgent fix") || !strings.Contains(first, "build") {
+		t.Errorf("high priority task should come first: %q", first)
+	}
+	if !strings.Contains(StripAnsi(lines[1]), "low chore") {
+		t.Errorf("second line = %q", StripAnsi(lines[1]))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
@@ -13,16 +14,28 @@ import (
 
 // logEntry is a minimal struct for parsing log.jsonl lines.
 type logEntry struct {
-	TS     int64  `json:"ts"`
-	From   string `json:"from"`
-	To     string `json:"to"`
-	Type   string `json:"type"`
-	Action string `json:"action"`
+	TS      int64  `json:"ts"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Payload string `json:"payload"`
 }
 
+// filterMatches is how many matching log entries are shown while a filter
+// is active.
+const filterMatches = 8
+
 // RenderBus returns lines of ANSI-colored text showing bus state.
 // inner is the usable width between box borders.
 func RenderBus(session string, inner int) []string {
+	return RenderBusFiltered(session, inner, Filter{})
+}
+
+// RenderBusFiltered is RenderBus with a filter: when the filter is not
+// empty, the recent activity list is replaced by the latest matching
+// entries from the whole session log.
+func RenderBusFiltered(session string, inner int, f Filter) []string {
 	busDir := bus.BusDir(session)
 	if _, err := os.Stat(busDir); os.IsNotExist(err) {
 		return []string{
@@ -41,11 +54,17 @@ func RenderBus(session string, inner int) []string {
 			locked = "*"
 		}
 
-		color := Comment
+		// Read-but-unconsumed messages stay dim; unread ones are highlighted
+		color, unread := Comment, ""
 		if count > 0 {
-			color = Yellow
+			if n := bus.UnreadCount(session, role); n > 0 {
+				color = Yellow
+				if n < count {
+					unread = fmt.Sprintf("(%d new)", n)
+				}
+			}
 		}
-		entries = append(entries, fmt.Sprintf("%s%s:%d%s%s", color, role, count, locked, RST))
+		entries = append(entries, fmt.Sprintf("%s%s:%d%s%s%s", color, role, count, unread, locked, RST))
 	}
 
 	// Wrap entries into lines that fit within inner width.
@@ -72,6 +91,10 @@ func RenderBus(session string, inner int) []string {
 		lines = append(lines, currentLine)
 	}
 
+	if !f.Empty() {
+		return append(lines, renderMatches(bus.LogPath(session), f)...)
+	}
+
 	// Last 3 log entries
 	logPath := bus.LogPath(session)
 	logLines := tailFile(logPath, 3)
@@ -79,7 +102,7 @@ func RenderBus(session string, inner int) []string {
 		lines = append(lines, fmt.Sprintf("  %sRecent:%s", Comment, RST))
 		for _, raw := range logLines {
 			var entry logEntry
-			if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			if err := json.Unmarshal(unsealLogLine(raw), &entry); err != nil {
 				lines = append(lines, fmt.Sprintf("  %s  (parse error)%s", Comment, RST))
 				continue
 			}
@@ -94,7 +117,44 @@ func RenderBus(session string, inner int) []string {
 	return lines
 }
 
-// tailFile reads the last n non-empty lines from a file.
+// unsealLogLine decrypts an encrypted log line; undecryptable lines come
+// back unchanged and fail to parse.
+func unsealLogLine(raw string) []byte {
+	if data, err := bus.UnsealLine([]byte(raw)); err == nil {
+		return data
+	}
+	return []byte(raw)
+}
+
+// renderMatches lists the latest log entries passing f, with the payload so
+// full-text hits are visible.
+func renderMatches(logPath string, f Filter) []string {
+	all := tailFile(logPath, -1)
+	var matched []logEntry
+	for _, raw := range all {
+		var entry logEntry
+		if err := json.Unmarshal(unsealLogLine(raw), &entry); err != nil {
+			continue
+		}
+		if f.MatchEntry(entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("  %sMatches: %d of %d%s", Comment, len(matched), len(all), RST)}
+	if len(matched) > filterMatches {
+		matched = matched[len(matched)-filterMatches:]
+	}
+	for _, entry := range matched {
+		ts := time.Unix(entry.TS, 0).Format("15:04:05")
+		payload := strings.Join(strings.Fields(entry.Payload), " ")
+		lines = append(lines, fmt.Sprintf("    %s%s %s->%s %s:%s%s %s",
+			Comment, ts, entry.From, entry.To, entry.Type, entry.Action, RST, payload))
+	}
+	return lines
+}
+
+// tailFile reads the last n non-empty lines from a file (all when n < 0).
 func tailFile(path string, n int) []string {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -110,7 +170,7 @@ func tailFile(path string, n int) []string {
 		}
 	}
 
-	if len(allLines) <= n {
+	if n < 0 || len(allLines) <= n {
 		return allLines
 	}
 	return allLines[len(allLines)-n:]
@@ -0,0 +1,270 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Compose fields, in tab order.
+const (
+	fieldRole = iota
+	fieldAction
+	fieldPayload
+	fieldCount
+)
+
+// composeFrom is the sender for dashboard-composed messages. Replies land in
+// the edit inbox, where the operator is already working.
+const composeFrom = "edit"
+
+// Composer is the state of the dashboard's message compose pane.
+type Composer struct {
+	Active  bool
+	roles   []string
+	roleIdx int
+	field   int
+	action  []rune
+	payload []rune
+	status  string // result of the last send, shown under the form
+}
+
+// NewComposer creates a composer that picks from the given roles.
+func NewComposer(roles []string) *Composer {
+	return &Composer{roles: roles}
+}
+
+// Open activates the compose pane with empty fields.
+func (c *Composer) Open() {
+	c.Active = true
+	c.field = fieldAction
+	c.action = nil
+	c.payload = nil
+	c.status = ""
+}
+
+// Role returns the currently selected target role.
+func (c *Composer) Role() string {
+	if len(c.roles) == 0 {
+		return ""
+	}
+	return c.roles[c.roleIdx]
+}
+
+// HandleKey applies a decoded key (see decodeKey) to the compose pane.
+// It returns true when the key completes the form and the message should be
+// sent; Esc closes the pane without sending.
+func (c *Composer) HandleKey(key string) bool {
+	switch key {
+	case "esc":
+		c.Active = false
+		return false
+	case "tab", "down":
+		c.field = (c.field + 1) % fieldCount
+		return false
+	case "backtab", "up":
+		c.field = (c.field + fieldCount - 1) % fieldCount
+		return false
+	case "enter":
+		if c.field != fieldPayload {
+			c.field++
+			return false
+		}
+		return true
+	}
+
+	if c.field == fieldRole {
+		c.pickRole(key)
+		return false
+	}
+
+	buf := &c.action
+	if c.field == fieldPayload {
+		buf = &c.payload
+	}
+	switch key {
+	case "backspace":
+		if len(*buf) > 0 {
+			*buf = (*buf)[:len(*buf)-1]
+		}
+	case "ctrl-u":
+		*buf = nil
+	default:
+		if r := []rune(key); len(r) == 1 && r[0] >= ' ' {
+			if c.field == fieldAction && r[0] == ' ' {
+				return false // actions are single words
+			}
+			*buf = append(*buf, r[0])
+		}
+	}
+	return false
+}
+
+// pickRole moves the role selection: arrows and space cycle through the
+// roles, a letter jumps to the next role starting with it.
+func (c *Composer) pickRole(key string) {
+	n := len(c.roles)
+	if n == 0 {
+		return
+	}
+	switch key {
+	case "right", " ":
+		c.roleIdx = (c.roleIdx + 1) % n
+	case "left":
+		c.roleIdx = (c.roleIdx + n - 1) % n
+	default:
+		if len(key) != 1 {
+			return
+		}
+		for i := 1; i <= n; i++ {
+			j := (c.roleIdx + i) % n
+			if strings.HasPrefix(c.roles[j], key) {
+				c.roleIdx = j
+				return
+			}
+		}
+	}
+}
+
+// Message validates the form and builds the request it describes.
+func (c *Composer) Message() (bus.Message, error) {
+	to := c.Role()
+	action := strings.TrimSpace(string(c.action))
+	payload := strings.TrimSpace(string(c.payload))
+	switch {
+	case !bus.IsKnownRole(to):
+		return bus.Message{}, fmt.Errorf("unknown role '%s'", to)
+	case action == "":
+		return bus.Message{}, fmt.Errorf("action is required")
+	case payload == "":
+		return bus.Message{}, fmt.Errorf("payload is required")
+	}
+	return bus.NewMessage(composeFrom, to, "request", action, payload, ""), nil
+}
+
+// Send delivers the composed message and notifies the target. On success the
+// pane closes; either way the outcome is kept for display.
+func (c *Composer) Send(session string) error {
+	msg, err := c.Message()
+	if err == nil {
+		if deny := bus.CheckMessagePolicy(session, msg); deny != "" {
+			err = fmt.Errorf("%s", deny)
+		}
+	}
+	if err == nil {
+		err = bus.Send(session, msg)
+	}
+	if err != nil {
+		c.status = "Error: " + err.Error()
+		return err
+	}
+	_ = bus.Notify(session, msg.To)
+	c.status = fmt.Sprintf("Sent request:%s to %s", msg.Action, msg.To)
+	c.Active = false
+	return nil
+}
+
+// Status returns the outcome of the last send, or "".
+func (c *Composer) Status() string {
+	return c.status
+}
+
+// Render returns the compose pane lines. inner is the usable width between
+// box borders.
+func (c *Composer) Render(inner int) []string {
+	label := func(field int, name string) string {
+		if c.field == field {
+			return Pink + Bold + "> " + name + RST
+		}
+		return Comment + "  " + name + RST
+	}
+	cursor := func(field int) string {
+		if c.field == field {
+			return Purple + "_" + RST
+		}
+		return ""
+	}
+
+	// Show the payload tail so the cursor stays visible while typing.
+	payload := string(c.payload)
+	if max := inner - 16; max > 0 && len(c.payload) > max {
+		payload = "…" + string(c.payload[len(c.payload)-max+1:])
+	}
+
+	lines := []string{
+		fmt.Sprintf("  %s  %s< %s >%s", label(fieldRole, Pad("To:", 8)), Cyan+Bold, c.Role(), RST),
+		fmt.Sprintf("  %s  %s%s", label(fieldAction, Pad("Action:", 8)), string(c.action), cursor(fieldAction)),
+		fmt.Sprintf("  %s  %s%s", label(fieldPayload, Pad("Payload:", 8)), payload, cursor(fieldPayload)),
+	}
+	if c.status != "" {
+		lines = append(lines, fmt.Sprintf("  %s%s%s", Red, c.status, RST))
+	}
+	return lines
+}
+
+// splitKeys splits a chunk read from the terminal into individual key
+// sequences: escape sequences, UTF-8 characters, and single control bytes.
+// A fast typist or a paste can deliver several keys in one read.
+func splitKeys(chunk []byte) [][]byte {
+	var keys [][]byte
+	for i := 0; i < len(chunk); {
+		n := 1
+		switch {
+		case chunk[i] == '\x1b' && i+2 < len(chunk) && chunk[i+1] == 'O':
+			n = 3
+		case chunk[i] == '\x1b' && i+1 < len(chunk) && chunk[i+1] == '[':
+			// CSI: parameters then a final byte in @..~
+			n = 2
+			for i+n < len(chunk) {
+				b := chunk[i+n]
+				n++
+				if b >= 0x40 && b <= 0x7e {
+					break
+				}
+			}
+		case chunk[i] >= 0x80:
+			_, n = utf8.DecodeRune(chunk[i:])
+		}
+		keys = append(keys, chunk[i:i+n])
+		i += n
+	}
+	return keys
+}
+
+// decodeKey turns a raw key sequence from the terminal into a key name:
+// "esc", "enter", "tab", "backtab", "backspace", "ctrl-u", the arrow names,
+// "pgup", "pgdn", or the typed character itself. Unrecognized sequences decode to "".
+func decodeKey(seq []byte) string {
+	switch string(seq) {
+	case "\x1b":
+		return "esc"
+	case "\r", "\n":
+		return "enter"
+	case "\t":
+		return "tab"
+	case "\x1b[Z":
+		return "backtab"
+	case "\x7f", "\b":
+		return "backspace"
+	case "\x15":
+		return "ctrl-u"
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\x1b[C", "\x1bOC":
+		return "right"
+	case "\x1b[D", "\x1bOD":
+		return "left"
+	case "\x1b[5~":
+		return "pgup"
+	case "\x1b[6~":
+		return "pgdn"
+	}
+	if len(seq) == 0 || seq[0] == '\x1b' || seq[0] < ' ' {
+		return ""
+	}
+	return string(seq)
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func typeKeys(c *Composer, input string) bool {
+	send := false
+	for _, seq := range splitKeys([]byte(input)) {
+		if c.HandleKey(decodeKey(seq)) {
+			send = true
+		}
+	}
+	return send
+}
+
+func TestSplitKeys(t *testing.T) {
+	got := splitKeys([]byte("a\x1b[C\x1bOD\x1b[Zé\x1b"))
+	want := []string{"a", "right", "left", "backtab", "é", "esc"}
+	if len(got) != len(want) {
+		t.Fatalf("splitKeys = %q, want %d keys", got, len(want))
+	}
+	for i, seq := range got {
+		if k := decodeKey(seq); k != want[i] {
+			t.Errorf("key %d = %q, want %q", i, k, want[i])
+		}
+	}
+	if k := decodeKey([]byte("\x1b[15~")); k != "" {
+		t.Errorf("F5 decoded to %q, want ignored", k)
+	}
+}
+
+func TestComposer_Form(t *testing.T) {
+	c := NewComposer([]string{"edit", "build", "test", "review"})
+	c.Open()
+
+	// Opens on the action field; typing edits it, spaces are dropped
+	if typeKeys(c, "bu ild\x7fd") {
+		t.Fatal("typing should not send")
+	}
+	// Back to the role picker: letter jump and arrows
+	typeKeys(c, "\x1b[Zt")
+	if c.Role() != "test" {
+		t.Errorf("role = %q, want test", c.Role())
+	}
+	typeKeys(c, "\x1b[C\x1b[C")
+	if c.Role() != "edit" {
+		t.Errorf("role after wrap = %q, want edit", c.Role())
+	}
+	typeKeys(c, "b\t")
+
+	// Enter advances to payload, then sends
+	if typeKeys(c, "\r") {
+		t.Fatal("enter on action field should advance, not send")
+	}
+	if !typeKeys(c, "run  all\r") {
+		t.Fatal("enter on payload should send")
+	}
+	msg, err := c.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != composeFrom || msg.To != "build" || msg.Action != "build" || msg.Payload != "run  all" || msg.Type != "request" {
+		t.Errorf("message = %+v", msg)
+	}
+
+	lines := strings.Join(c.Render(60), "\n")
+	if !strings.Contains(lines, "< build >") || !strings.Contains(lines, "run  all") {
+		t.Errorf("render:\n%s", lines)
+	}
+
+	typeKeys(c, "\x15")
+	if _, err := c.Message(); err == nil || !strings.Contains(err.Error(), "payload") {
+		t.Errorf("cleared payload: err = %v", err)
+	}
+	typeKeys(c, "\x1b")
+	if c.Active {
+		t.Error("esc should close the composer")
+	}
+}
+
+func TestComposer_Send(t *testing.T) {
+	session := fmt.Sprintf("test-compose-%d", os.Getpid())
+	if err := bus.Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bus.Cleanup(session) }()
+
+	c := NewComposer([]string{"build"})
+	c.Open()
+	typeKeys(c, "build\rgo build ./...\r")
+	if err := c.Send(session); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if c.Active || c.Status() != "Sent request:build to build" {
+		t.Errorf("after send: active=%v status=%q", c.Active, c.Status())
+	}
+	msgs, err := bus.Peek(session, "build")
+	if err != nil || len(msgs) != 1 || msgs[0].Payload != "go build ./..." {
+		t.Errorf("inbox = %+v, %v", msgs, err)
+	}
+
+	c.Open()
+	if err := c.Send(session); err == nil || !c.Active || !strings.HasPrefix(c.Status(), "Error: action") {
+		t.Errorf("empty form: err=%v active=%v status=%q", err, c.Active, c.Status())
+	}
+}
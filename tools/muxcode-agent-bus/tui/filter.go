@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"strings"
+)
+
+// Filter narrows the dashboard's message views. It is parsed from a query
+// such as "role:build type:request failed": field prefixes match message
+// fields exactly, remaining words must all appear somewhere in the message
+// (case-insensitive).
+type Filter struct {
+	Role   string // from or to
+	From   string
+	To     string
+	Type   string
+	Action string
+	Terms  []string
+}
+
+// ParseFilter parses a dashboard search query.
+func ParseFilter(query string) Filter {
+	var f Filter
+	for _, word := range strings.Fields(query) {
+		key, val, ok := strings.Cut(word, ":")
+		if ok && val != "" {
+			switch strings.ToLower(key) {
+			case "role":
+				f.Role = val
+				continue
+			case "from":
+				f.From = val
+				continue
+			case "to":
+				f.To = val
+				continue
+			case "type":
+				f.Type = val
+				continue
+			case "action":
+				f.Action = val
+				continue
+			}
+		}
+		f.Terms = append(f.Terms, strings.ToLower(word))
+	}
+	return f
+}
+
+// Empty reports whether the filter matches everything.
+func (f Filter) Empty() bool {
+	return f.Role == "" && f.From == "" && f.To == "" && f.Type == "" && f.Action == "" && len(f.Terms) == 0
+}
+
+// String returns the filter in query form.
+func (f Filter) String() string {
+	var parts []string
+	for _, kv := range [][2]string{{"role", f.Role}, {"from", f.From}, {"to", f.To}, {"type", f.Type}, {"action", f.Action}} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+":"+kv[1])
+		}
+	}
+	return strings.Join(append(parts, f.Terms...), " ")
+}
+
+// MatchEntry reports whether a bus log entry passes the filter.
+func (f Filter) MatchEntry(e logEntry) bool {
+	switch {
+	case f.Role != "" && e.From != f.Role && e.To != f.Role,
+		f.From != "" && e.From != f.From,
+		f.To != "" && e.To != f.To,
+		f.Type != "" && e.Type != f.Type,
+		f.Action != "" && e.Action != f.Action:
+		return false
+	}
+	return f.matchTerms(strings.Join([]string{e.From, e.To, e.Type, e.Action, e.Payload}, " "))
+}
+
+// MatchText reports whether a free-form line (a scanned pane message)
+// passes the filter. Field filters match as words in the line.
+func (f Filter) MatchText(text string) bool {
+	lower := strings.ToLower(text)
+	for _, v := range []string{f.Role, f.From, f.To, f.Type, f.Action} {
+		if v != "" && !strings.Contains(lower, strings.ToLower(v)) {
+			return false
+		}
+	}
+	return f.matchTerms(text)
+}
+
+func (f Filter) matchTerms(text string) bool {
+	lower := strings.ToLower(text)
+	for _, t := range f.Terms {
+		if !strings.Contains(lower, t) {
+			return false
+		}
+	}
+	return true
+}
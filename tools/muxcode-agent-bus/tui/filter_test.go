@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestParseFilter(t *testing.T) {
+	f := ParseFilter("role:build TYPE:request Deploy failed http://x")
+	if f.Role != "build" || f.Type != "request" {
+		t.Errorf("fields = %+v", f)
+	}
+	if strings.Join(f.Terms, ",") != "deploy,failed,http://x" {
+		t.Errorf("terms = %q", f.Terms)
+	}
+	if got := f.String(); got != "role:build type:request deploy failed http://x" {
+		t.Errorf("String = %q", got)
+	}
+	if !ParseFilter("  ").Empty() || f.Empty() {
+		t.Error("Empty mismatch")
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	e := logEntry{From: "build", To: "test", Type: "request", Action: "test", Payload: "Run the Unit tests"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"role:test", true},
+		{"role:edit", false},
+		{"from:build to:test", true},
+		{"from:test", false},
+		{"type:response", false},
+		{"action:test unit", true},
+		{"unit missing", false},
+	}
+	for _, tt := range tests {
+		if got := ParseFilter(tt.query).MatchEntry(e); got != tt.want {
+			t.Errorf("MatchEntry(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if !ParseFilter("role:build send").MatchText("12:00  build: muxcode-agent-bus send test run") {
+		t.Error("MatchText should match role and term")
+	}
+	if ParseFilter("role:deploy").MatchText("12:00  build: muxcode-agent-bus send test run") {
+		t.Error("MatchText should reject other roles")
+	}
+}
+
+func TestRenderBusFiltered(t *testing.T) {
+	session := fmt.Sprintf("test-filter-%d", os.Getpid())
+	if err := bus.Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bus.Cleanup(session) }()
+
+	for i := 0; i < 12; i++ {
+		to := "build"
+		if i%2 == 1 {
+			to = "test"
+		}
+		if err := bus.Send(session, bus.NewMessage("edit", to, "request", to, fmt.Sprintf("job %d", i), "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := strings.Join(RenderBusFiltered(session, 100, ParseFilter("to:test")), "\n")
+	if !strings.Contains(out, "Matches: 6 of 12") || !strings.Contains(out, "job 11") || strings.Contains(out, "job 10") {
+		t.Errorf("filtered render:\n%s", out)
+	}
+	out = strings.Join(RenderBusFiltered(session, 100, ParseFilter("job 1")), "\n")
+	if !strings.Contains(out, "Matches: 3 of 12") {
+		t.Errorf("full-text render:\n%s", out)
+	}
+	if out := strings.Join(RenderBus(session, 100), "\n"); !strings.Contains(out, "Recent:") {
+		t.Errorf("unfiltered render:\n%s", out)
+	}
+}
@@ -0,0 +1,339 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// logScrollback is how many lines of a log the tail pane keeps for scrolling.
+const logScrollback = 1000
+
+// logPaneHeight is the number of log lines shown in the tail pane.
+const logPaneHeight = 12
+
+// LogSource is a proc or spawn whose output the tail pane can show.
+type LogSource struct {
+	Kind    string // "proc" or "spawn"
+	ID      string
+	Label   string
+	Status  string
+	Path    string // proc log file
+	Window  string // spawn tmux window
+	Started int64
+}
+
+// LogSources lists the session's procs and spawns, newest first.
+func LogSources(session string) []LogSource {
+	var sources []LogSource
+	procs, _ := bus.ReadProcEntries(session)
+	for _, p := range procs {
+		path := p.LogFile
+		if path == "" {
+			path = bus.ProcLogPath(session, p.ID)
+		}
+		sources = append(sources, LogSource{
+			Kind: "proc", ID: p.ID, Label: p.Command, Status: p.Status,
+			Path: path, Started: p.StartedAt,
+		})
+	}
+	spawns, _ := bus.ReadSpawnEntries(session)
+	for _, s := range spawns {
+		sources = append(sources, LogSource{
+			Kind: "spawn", ID: s.ID, Label: s.Role + ": " + s.Task, Status: s.Status,
+			Window: s.Window, Started: s.StartedAt,
+		})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Started > sources[j].Started
+	})
+	return sources
+}
+
+// readLogLines returns the last logScrollback lines of a source's output:
+// the proc log file, or the spawn window's scrollback.
+func readLogLines(session string, src LogSource) []string {
+	var raw string
+	switch src.Kind {
+	case "proc":
+		data, err := os.ReadFile(src.Path)
+		if err != nil {
+			return nil
+		}
+		raw = string(data)
+	case "spawn":
+		raw = CapturePane(session, PaneTarget(session, src.Window), logScrollback)
+	}
+	raw = strings.TrimRight(StripAnsi(raw), "\n")
+	if raw == "" {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(raw, "\t", "    "), "\n")
+	if len(lines) > logScrollback {
+		lines = lines[len(lines)-logScrollback:]
+	}
+	return lines
+}
+
+// LogPane is the state of the dashboard's log tail pane.
+type LogPane struct {
+	Active    bool
+	sources   []LogSource
+	selected  int
+	lines     []string
+	follow    bool
+	scroll    int // lines scrolled up from the bottom
+	query     string
+	searching bool // typing a search query
+	matchLine int  // line index of the current match, or -1
+}
+
+// NewLogPane creates an inactive log tail pane.
+func NewLogPane() *LogPane {
+	return &LogPane{matchLine: -1}
+}
+
+// Open activates the pane on the newest source, following its output.
+func (p *LogPane) Open(session string) {
+	p.Active = true
+	p.selected = 0
+	p.query = ""
+	p.searching = false
+	p.reset()
+	p.Load(session)
+}
+
+func (p *LogPane) reset() {
+	p.lines = nil
+	p.follow = true
+	p.scroll = 0
+	p.matchLine = -1
+}
+
+// Source returns the selected source, or false when there is none.
+func (p *LogPane) Source() (LogSource, bool) {
+	if p.selected < 0 || p.selected >= len(p.sources) {
+		return LogSource{}, false
+	}
+	return p.sources[p.selected], true
+}
+
+// Load refreshes the source list and re-reads the selected log. When not
+// following, the scroll position is kept on the same lines as output grows.
+func (p *LogPane) Load(session string) {
+	cur, hadSource := p.Source()
+	p.sources = LogSources(session)
+	p.selected = 0
+	if hadSource {
+		for i, s := range p.sources {
+			if s.Kind == cur.Kind && s.ID == cur.ID {
+				p.selected = i
+				break
+			}
+		}
+	}
+	src, ok := p.Source()
+	if !ok {
+		p.lines = nil
+		return
+	}
+	lines := readLogLines(session, src)
+	if !p.follow && len(lines) > len(p.lines) {
+		p.scroll += len(lines) - len(p.lines)
+	}
+	p.lines = lines
+	p.clampScroll()
+}
+
+func (p *LogPane) clampScroll() {
+	max := len(p.lines) - logPaneHeight
+	if max < 0 {
+		max = 0
+	}
+	if p.scroll > max {
+		p.scroll = max
+	}
+	if p.scroll < 0 {
+		p.scroll = 0
+	}
+}
+
+// HandleKey applies a decoded key (see decodeKey) to the pane. Keys that
+// change the selected source reload it from session.
+func (p *LogPane) HandleKey(session, key string) {
+	if p.searching {
+		switch key {
+		case "esc":
+			p.searching = false
+			p.query = ""
+			p.matchLine = -1
+		case "enter":
+			p.searching = false
+			p.matchLine = -1
+			p.nextMatch(-1)
+		case "backspace":
+			if r := []rune(p.query); len(r) > 0 {
+				p.query = string(r[:len(r)-1])
+			}
+		default:
+			if r := []rune(key); len(r) == 1 && r[0] >= ' ' {
+				p.query += key
+			}
+		}
+		return
+	}
+
+	switch key {
+	case "esc", "l", "L":
+		p.Active = false
+	case "left", "[":
+		p.selectSource(session, -1)
+	case "right", "]":
+		p.selectSource(session, 1)
+	case "up", "k":
+		p.scrollBy(1)
+	case "down", "j":
+		p.scrollBy(-1)
+	case "pgup":
+		p.scrollBy(logPaneHeight)
+	case "pgdn":
+		p.scrollBy(-logPaneHeight)
+	case "g":
+		p.scrollBy(len(p.lines))
+	case "G":
+		p.scroll = 0
+		p.follow = true
+	case "f", "F":
+		p.follow = !p.follow
+		if p.follow {
+			p.scroll = 0
+		}
+	case "/":
+		p.searching = true
+		p.query = ""
+	case "n":
+		p.nextMatch(1)
+	case "N":
+		p.nextMatch(-1)
+	}
+}
+
+func (p *LogPane) selectSource(session string, delta int) {
+	if len(p.sources) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.sources)) % len(p.sources)
+	p.reset()
+	src := p.sources[p.selected]
+	p.lines = readLogLines(session, src)
+}
+
+// scrollBy moves the view up (positive) or down (negative). Scrolling up
+// pauses follow mode; reaching the bottom resumes it.
+func (p *LogPane) scrollBy(n int) {
+	p.scroll += n
+	p.clampScroll()
+	p.follow = p.scroll == 0
+}
+
+// nextMatch moves to the next (dir 1) or previous (dir -1) line containing
+// the query, searching upward from the current match — newest output first.
+// With no current match the search starts at the bottom.
+func (p *LogPane) nextMatch(dir int) {
+	if p.query == "" || len(p.lines) == 0 {
+		return
+	}
+	q := strings.ToLower(p.query)
+	start := p.matchLine
+	if start < 0 {
+		start = len(p.lines)
+		dir = -1
+	} else {
+		dir = -dir // n searches back toward older lines
+	}
+	for i := 1; i <= len(p.lines); i++ {
+		idx := start + dir*i
+		if idx < 0 || idx >= len(p.lines) {
+			break
+		}
+		if strings.Contains(strings.ToLower(p.lines[idx]), q) {
+			p.matchLine = idx
+			// Centre the match in the view
+			p.scroll = len(p.lines) - idx - logPaneHeight/2 - 1
+			p.clampScroll()
+			p.follow = false
+			return
+		}
+	}
+}
+
+// Render returns the pane lines: a header describing the source and mode,
+// then logPaneHeight lines of output. inner is the usable width between box
+// borders.
+func (p *LogPane) Render(inner int) []string {
+	src, ok := p.Source()
+	if !ok {
+		return []string{fmt.Sprintf("  %s(no procs or spawns)%s", Comment, RST)}
+	}
+
+	mode := Green + "FOLLOW" + RST
+	if !p.follow {
+		mode = fmt.Sprintf("%sPAUSED -%d%s", Yellow, p.scroll, RST)
+	}
+	header := fmt.Sprintf("  %s%s %d/%d%s  %s%s%s  %s  %s",
+		Cyan+Bold, src.Kind, p.selected+1, len(p.sources), RST,
+		Comment, src.Status, RST, mode, src.Label)
+	lines := []string{header}
+
+	end := len(p.lines) - p.scroll
+	start := end - logPaneHeight
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < end; i++ {
+		line := "  " + p.lines[i]
+		if p.query != "" {
+			line = highlight(line, p.query, i == p.matchLine)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < logPaneHeight+1 {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case p.searching:
+		lines = append(lines, fmt.Sprintf("  %s/%s%s_%s", Pink+Bold, p.query, Purple, RST))
+	case p.query != "" && p.matchLine < 0:
+		lines = append(lines, fmt.Sprintf("  %sno match: %s%s", Red, p.query, RST))
+	}
+	return lines
+}
+
+// highlight colors case-insensitive occurrences of query in line; the
+// current match is shown in reverse video.
+func highlight(line, query string, current bool) string {
+	lower := strings.ToLower(line)
+	q := strings.ToLower(query)
+	if len(lower) != len(line) || !strings.Contains(lower, q) {
+		return line
+	}
+	color := Yellow + Bold
+	if current {
+		color = "\033[7m" + Yellow
+	}
+	var b strings.Builder
+	for {
+		idx := strings.Index(lower, q)
+		if idx < 0 {
+			b.WriteString(line)
+			return b.String()
+		}
+		b.WriteString(line[:idx])
+		b.WriteString(color + line[idx:idx+len(q)] + RST)
+		line, lower = line[idx+len(q):], lower[idx+len(q):]
+	}
+}
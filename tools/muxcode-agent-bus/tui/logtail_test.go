@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func writeLog(t *testing.T, path string, n int) {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogPane(t *testing.T) {
+	session := fmt.Sprintf("test-logtail-%d", os.Getpid())
+	if err := bus.Init(session, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bus.Cleanup(session) }()
+
+	dir := t.TempDir()
+	oldLog, newLog := filepath.Join(dir, "old.log"), filepath.Join(dir, "new.log")
+	writeLog(t, oldLog, 3)
+	writeLog(t, newLog, 40)
+	if err := bus.WriteProcEntries(session, []bus.ProcEntry{
+		{ID: "p-old", Command: "make lint", Status: "exited", StartedAt: 100, LogFile: oldLog},
+		{ID: "p-new", Command: "npm run dev", Status: "running", StartedAt: 200, LogFile: newLog},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewLogPane()
+	p.Open(session)
+	if src, _ := p.Source(); src.ID != "p-new" || !p.follow {
+		t.Fatalf("opened on %+v follow=%v, want newest proc following", src, p.follow)
+	}
+	out := strings.Join(p.Render(80), "\n")
+	if !strings.Contains(out, "npm run dev") || !strings.Contains(out, "line 40") || strings.Contains(out, "line 28\n") {
+		t.Errorf("follow render:\n%s", out)
+	}
+
+	// Scrolling up pauses follow and holds position as output grows
+	p.HandleKey(session, "pgup")
+	if p.follow || p.scroll != logPaneHeight {
+		t.Errorf("after pgup: follow=%v scroll=%d", p.follow, p.scroll)
+	}
+	writeLog(t, newLog, 45)
+	p.Load(session)
+	if p.scroll != logPaneHeight+5 {
+		t.Errorf("scroll after growth = %d, want %d", p.scroll, logPaneHeight+5)
+	}
+	p.HandleKey(session, "G")
+	if !p.follow || p.scroll != 0 {
+		t.Errorf("G: follow=%v scroll=%d", p.follow, p.scroll)
+	}
+
+	// Search finds the newest match first, n steps to older ones
+	for _, k := range []string{"/", "l", "i", "n", "e", " ", "1", "enter"} {
+		p.HandleKey(session, k)
+	}
+	if p.matchLine != 18 || p.follow {
+		t.Errorf("first match line = %d follow=%v, want 18 (line 19)", p.matchLine, p.follow)
+	}
+	p.HandleKey(session, "n")
+	if p.matchLine != 17 {
+		t.Errorf("next match line = %d, want 17 (line 18)", p.matchLine)
+	}
+	if out := strings.Join(p.Render(80), "\n"); !strings.Contains(out, "\033[7m"+Yellow+"line 1"+RST+"8") {
+		t.Errorf("current match not highlighted:\n%s", out)
+	}
+
+	// Switching sources resets the view
+	p.HandleKey(session, "]")
+	if src, _ := p.Source(); src.ID != "p-old" || len(p.lines) != 3 || p.matchLine != -1 {
+		t.Errorf("after ]: %+v lines=%d match=%d", src, len(p.lines), p.matchLine)
+	}
+	p.HandleKey(session, "esc")
+	if p.Active {
+		t.Error("esc should close the pane")
+	}
+}
+
+func TestLogPane_NoSources(t *testing.T) {
+	p := NewLogPane()
+	p.Open("test-logtail-missing")
+	if out := p.Render(60); len(out) != 1 || !strings.Contains(out[0], "no procs or spawns") {
+		t.Errorf("render = %q", out)
+	}
+	p.HandleKey("test-logtail-missing", "]")
+}
@@ -4,10 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"os/signal"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
@@ -20,12 +18,29 @@ type Dashboard struct {
 	windows    []string
 	prevHashes map[string]string
 	msgBuffer  *MessageBuffer
-	keyCh      chan byte
+	composer   *Composer
+	logPane    *LogPane
+	filter     Filter
+	searching  bool     // typing a filter query
+	query      []rune   // filter query being typed
+	panes      []string // configured pane order, hidden panes removed
+	compact    bool
 }
 
 // NewDashboard creates a new Dashboard instance.
 // Windows are read from the tmux session; falls back to KnownRoles.
+// Theme and layout come from the "dashboard" config section; a refresh of 0
+// uses the configured interval (default 5s).
 func NewDashboard(session string, refresh int) *Dashboard {
+	cfg := bus.Config().Dashboard
+	ApplyTheme(cfg.ResolveTheme())
+	if refresh <= 0 {
+		refresh = cfg.Refresh
+	}
+	if refresh <= 0 {
+		refresh = 5
+	}
+
 	windows := sessionWindows(session)
 	if len(windows) == 0 {
 		// Fallback: use all known roles
@@ -38,6 +53,10 @@ func NewDashboard(session string, refresh int) *Dashboard {
 		windows:    windows,
 		prevHashes: make(map[string]string),
 		msgBuffer:  NewMessageBuffer(5),
+		composer:   NewComposer(bus.KnownRoles),
+		logPane:    NewLogPane(),
+		panes:      cfg.ResolvePanes(),
+		compact:    cfg.Compact,
 	}
 }
 
@@ -61,70 +80,81 @@ func sessionWindows(session string) []string {
 
 // Run starts the main render loop.
 func (d *Dashboard) Run() error {
-	// Clear screen and hide cursor
-	fmt.Print("\033[2J\033[H")
-	fmt.Print("\033[?25l")
-
-	// Set up signal handler for clean exit
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start non-blocking key reader
-	d.keyCh = make(chan byte, 16)
-	go d.readKeys()
-
-	defer d.cleanup()
+	t := openTerminal()
+	defer t.close()
 
 	for {
-		frame := d.render()
-
-		// Move cursor to 0,0 and print frame
-		fmt.Print("\033[H")
-		fmt.Print(frame)
-		// Clear to end of screen
-		fmt.Print("\033[J")
-
-		// Wait for refresh interval, checking for keys and signals
-		deadline := time.After(time.Duration(d.refresh) * time.Second)
-
-	waitLoop:
-		for {
-			select {
-			case <-sigCh:
-				return nil
-			case key := <-d.keyCh:
-				switch key {
-				case 'q', 'Q':
-					return nil
-				case 'r', 'R':
-					break waitLoop
-				}
-			case <-deadline:
-				break waitLoop
-			}
+		t.draw(d.render())
+
+		// Wait for refresh interval, checking for keys and signals.
+		// A following log pane refreshes every second.
+		interval := time.Duration(d.refresh) * time.Second
+		if d.logPane.Active && d.logPane.follow && interval > time.Second {
+			interval = time.Second
+		}
+		chunk, quit := t.wait(interval)
+		if quit || (chunk != nil && d.handleKeys(chunk)) {
+			return nil
 		}
 	}
 }
 
-// readKeys reads single bytes from stdin in a loop, sending to keyCh.
-func (d *Dashboard) readKeys() {
-	buf := make([]byte, 1)
-	for {
-		n, err := os.Stdin.Read(buf)
-		if err != nil || n == 0 {
-			time.Sleep(50 * time.Millisecond)
+// handleKeys dispatches a chunk of input to the compose pane when it is open,
+// or to the dashboard shortcuts otherwise. It returns true to quit.
+func (d *Dashboard) handleKeys(chunk []byte) bool {
+	for _, seq := range splitKeys(chunk) {
+		key := decodeKey(seq)
+		if d.composer.Active {
+			if d.composer.HandleKey(key) {
+				_ = d.composer.Send(d.session)
+			}
+			continue
+		}
+		if d.logPane.Active {
+			d.logPane.HandleKey(d.session, key)
 			continue
 		}
-		d.keyCh <- buf[0]
+		if d.searching {
+			d.handleSearchKey(key)
+			continue
+		}
+		switch key {
+		case "q", "Q":
+			return true
+		case "s", "S":
+			d.composer.Open()
+		case "l", "L":
+			d.logPane.Open(d.session)
+		case "/":
+			d.searching = true
+			d.query = []rune(d.filter.String())
+		case "x", "X":
+			d.filter = Filter{}
+		}
 	}
+	return false
 }
 
-// cleanup restores the terminal to a usable state.
-func (d *Dashboard) cleanup() {
-	fmt.Print("\033[?25h") // show cursor
-	fmt.Print(RST)        // reset colors
-	fmt.Print("\033[2J")   // clear screen
-	fmt.Print("\033[H")    // move to top
+// handleSearchKey edits the filter query. Enter applies it (an empty query
+// clears the filter); Esc keeps the previous filter.
+func (d *Dashboard) handleSearchKey(key string) {
+	switch key {
+	case "esc":
+		d.searching = false
+	case "enter":
+		d.searching = false
+		d.filter = ParseFilter(string(d.query))
+	case "backspace":
+		if len(d.query) > 0 {
+			d.query = d.query[:len(d.query)-1]
+		}
+	case "ctrl-u":
+		d.query = nil
+	default:
+		if r := []rune(key); len(r) == 1 && r[0] >= ' ' {
+			d.query = append(d.query, r[0])
+		}
+	}
 }
 
 // termWidth returns the terminal width, defaulting to 62.
@@ -169,9 +199,9 @@ func (d *Dashboard) render() string {
 
 	// ── Top border ──
 	b.WriteString(border)
-	b.WriteRune('\u2554') // ╔
-	b.WriteString(HLine('\u2550', inner))
-	b.WriteRune('\u2557') // ╗
+	b.WriteRune(BoxTL)
+	b.WriteString(HLine(BoxH, inner))
+	b.WriteRune(BoxTR)
 	b.WriteString(borderRst)
 	b.WriteRune('\n')
 
@@ -183,7 +213,7 @@ func (d *Dashboard) render() string {
 		gap = 1
 	}
 	b.WriteString(border)
-	b.WriteRune('\u2551') // ║
+	b.WriteRune(BoxV)
 	b.WriteString(borderRst)
 	b.WriteString("  ")
 	b.WriteString(Pink + Bold + title + RST)
@@ -191,15 +221,107 @@ func (d *Dashboard) render() string {
 	b.WriteString(Comment + right + RST)
 	b.WriteString("  ")
 	b.WriteString(border)
-	b.WriteRune('\u2551') // ║
+	b.WriteRune(BoxV)
 	b.WriteString(borderRst)
 	b.WriteRune('\n')
 
+	// ── Panes, in configured order ──
+	for _, pane := range d.panes {
+		switch pane {
+		case "agents":
+			d.section(&b, "AGENTS", inner)
+			d.writeAgents(&b, inner)
+		case "bus":
+			d.section(&b, "MESSAGE BUS", inner)
+			if !d.filter.Empty() {
+				b.WriteString(d.boxLine(fmt.Sprintf("  %sFilter:%s %s", Pink+Bold, RST, d.filter), inner))
+			}
+			for _, line := range RenderBusFiltered(d.session, inner, d.filter) {
+				b.WriteString(d.boxLine(line, inner))
+			}
+		case "teams":
+			d.section(&b, "TEAMS", inner)
+			for _, line := range RenderTeams() {
+				b.WriteString(d.boxLine(line, inner))
+			}
+		case "messages":
+			d.section(&b, "MESSAGES", inner)
+			d.writeMessages(&b, inner)
+		case "tasks":
+			d.section(&b, "TASKS", inner)
+			for _, line := range RenderBoard(d.session) {
+				b.WriteString(d.boxLine(TruncateAnsi(line, inner-2), inner))
+			}
+		}
+	}
+
+	// ── LOG section ──
+	if d.logPane.Active {
+		d.logPane.Load(d.session)
+		d.section(&b, "LOG", inner)
+		for _, line := range d.logPane.Render(inner) {
+			b.WriteString(d.boxLine(line, inner))
+		}
+	}
+
+	// ── COMPOSE section ──
+	if d.composer.Active {
+		d.section(&b, "COMPOSE", inner)
+		for _, line := range d.composer.Render(inner) {
+			b.WriteString(d.boxLine(line, inner))
+		}
+	}
+
 	// ── Separator ──
 	b.WriteString(d.separator(inner))
 
-	// ── AGENTS section ──
-	b.WriteString(d.sectionHeader("AGENTS", inner))
+	// ── Footer ──
+	footer := "q: quit  r: refresh  s: send message  l: logs  /: filter  F1-F8: jump to window"
+	if !d.filter.Empty() {
+		footer = "q: quit  r: refresh  s: send message  l: logs  /: edit filter  x: clear filter"
+	}
+	if d.searching {
+		footer = "/" + string(d.query) + "_  (role: from: to: type: action: words; enter: apply  esc: cancel)"
+	} else if d.composer.Active {
+		footer = "tab: next field  left/right: role  enter: send  esc: cancel"
+	} else if d.logPane.Active {
+		footer = "[/]: source  up/down/pgup/pgdn: scroll  f: follow  /: search  n/N: next/prev  esc: close"
+	} else if st := d.composer.Status(); st != "" {
+		footer = st + "  (s: send another)"
+	}
+	if len([]rune(footer)) > inner-4 && inner > 4 {
+		footer = string([]rune(footer)[:inner-4])
+	}
+	fpad := inner - len([]rune(footer)) - 4
+	if fpad < 0 {
+		fpad = 0
+	}
+	footerLine := fmt.Sprintf("  %s%s%s%s  ", Comment, footer, RST, strings.Repeat(" ", fpad))
+	b.WriteString(border)
+	b.WriteRune(BoxV)
+	b.WriteString(borderRst)
+	b.WriteString(footerLine)
+	b.WriteString(border)
+	b.WriteRune(BoxV)
+	b.WriteString(borderRst)
+	b.WriteRune('\n')
+
+	// ── Bottom border ──
+	b.WriteString(border)
+	b.WriteRune(BoxBL)
+	b.WriteString(HLine(BoxH, inner))
+	b.WriteRune(BoxBR)
+	b.WriteString(borderRst)
+	b.WriteRune('\n')
+
+	return b.String()
+}
+
+// writeAgents writes the AGENTS pane: one status line per window and the
+// session cost total.
+func (d *Dashboard) writeAgents(b *strings.Builder, inner int) {
+	border := Purple + Bold
+	borderRst := RST
 
 	sessionCost := 0.0
 	sessionTokens := 0
@@ -280,11 +402,11 @@ func (d *Dashboard) render() string {
 			Comment, snip, RST,
 			strings.Repeat(" ", trailing))
 		b.WriteString(border)
-		b.WriteRune('\u2551')
+		b.WriteRune(BoxV)
 		b.WriteString(borderRst)
 		b.WriteString(line)
 		b.WriteString(border)
-		b.WriteRune('\u2551')
+		b.WriteRune(BoxV)
 		b.WriteString(borderRst)
 		b.WriteRune('\n')
 	}
@@ -302,42 +424,29 @@ func (d *Dashboard) render() string {
 		Yellow+Bold, "Session total: "+totalFmt, RST,
 		Cyan+Bold+totalTokensFmt, RST)
 	b.WriteString(border)
-	b.WriteRune('\u2551')
+	b.WriteRune(BoxV)
 	b.WriteString(borderRst)
 	b.WriteString(totalLine)
 	b.WriteString(border)
-	b.WriteRune('\u2551')
+	b.WriteRune(BoxV)
 	b.WriteString(borderRst)
 	b.WriteRune('\n')
+}
 
-	// ── Separator ──
-	b.WriteString(d.separator(inner))
-
-	// ── MESSAGE BUS section ──
-	b.WriteString(d.sectionHeader("MESSAGE BUS", inner))
-	busLines := RenderBus(d.session, inner)
-	for _, line := range busLines {
-		b.WriteString(d.boxLine(line, inner))
-	}
-
-	// ── Separator ──
-	b.WriteString(d.separator(inner))
-
-	// ── TEAMS section ──
-	b.WriteString(d.sectionHeader("TEAMS", inner))
-	teamLines := RenderTeams()
-	for _, line := range teamLines {
-		b.WriteString(d.boxLine(line, inner))
+// writeMessages writes the MESSAGES pane, applying the active filter.
+func (d *Dashboard) writeMessages(b *strings.Builder, inner int) {
+	var msgs []string
+	for _, msg := range d.msgBuffer.Messages() {
+		if d.filter.MatchText(msg) {
+			msgs = append(msgs, msg)
+		}
 	}
-
-	// ── Separator ──
-	b.WriteString(d.separator(inner))
-
-	// ── MESSAGES section ──
-	b.WriteString(d.sectionHeader("MESSAGES", inner))
-	msgs := d.msgBuffer.Messages()
 	if len(msgs) == 0 {
-		noMsg := fmt.Sprintf("  %s(no recent messages)%s", Comment, RST)
+		empty := "(no recent messages)"
+		if !d.filter.Empty() {
+			empty = "(no matching messages)"
+		}
+		noMsg := fmt.Sprintf("  %s%s%s", Comment, empty, RST)
 		b.WriteString(d.boxLine(noMsg, inner))
 	} else {
 		for _, msg := range msgs {
@@ -350,42 +459,38 @@ func (d *Dashboard) render() string {
 			b.WriteString(d.boxLine(line, inner))
 		}
 	}
+}
 
-	// ── Separator ──
-	b.WriteString(d.separator(inner))
-
-	// ── Footer ──
-	footer := "q: quit  r: refresh  F1-F8: jump to window"
-	fpad := inner - len(footer) - 4
-	if fpad < 0 {
-		fpad = 0
+// section starts a pane: a separator and title line, or in compact mode a
+// single separator carrying the title.
+func (d *Dashboard) section(b *strings.Builder, title string, inner int) {
+	if d.compact {
+		b.WriteString(d.titledSeparator(title, inner))
+		return
 	}
-	footerLine := fmt.Sprintf("  %s%s%s%s  ", Comment, footer, RST, strings.Repeat(" ", fpad))
-	b.WriteString(border)
-	b.WriteRune('\u2551')
-	b.WriteString(borderRst)
-	b.WriteString(footerLine)
-	b.WriteString(border)
-	b.WriteRune('\u2551')
-	b.WriteString(borderRst)
-	b.WriteRune('\n')
-
-	// ── Bottom border ──
-	b.WriteString(border)
-	b.WriteRune('\u255a') // ╚
-	b.WriteString(HLine('\u2550', inner))
-	b.WriteRune('\u255d') // ╝
-	b.WriteString(borderRst)
-	b.WriteRune('\n')
-
-	return b.String()
+	b.WriteString(d.separator(inner))
+	b.WriteString(d.sectionHeader(title, inner))
 }
 
 // separator writes a ╠═══╣ divider line.
 func (d *Dashboard) separator(inner int) string {
 	border := Purple + Bold
-	return fmt.Sprintf("%s\u2560%s\u2563%s\n",
-		border, HLine('\u2550', inner), RST)
+	return fmt.Sprintf("%s%c%s%c%s\n",
+		border, BoxML, HLine(BoxH, inner), BoxMR, RST)
+}
+
+// titledSeparator writes a divider with a section title in it
+// (e.g. "╠═ AGENTS ═══╣"), used in compact mode.
+func (d *Dashboard) titledSeparator(title string, inner int) string {
+	border := Purple + Bold
+	rest := inner - len(title) - 3
+	if rest < 0 {
+		rest = 0
+	}
+	return fmt.Sprintf("%s%c%c %s%s%s%s %s%c%s\n",
+		border, BoxML, BoxH,
+		Orange+Bold, title, RST, border,
+		HLine(BoxH, rest), BoxMR, RST)
 }
 
 // sectionHeader writes a section title inside the box (e.g. "║  AGENTS  ║").
@@ -395,11 +500,11 @@ func (d *Dashboard) sectionHeader(title string, inner int) string {
 	if pad < 0 {
 		pad = 0
 	}
-	return fmt.Sprintf("%s\u2551%s  %s%s%s%s  %s\u2551%s\n",
-		border, RST,
+	return fmt.Sprintf("%s%c%s  %s%s%s%s  %s%c%s\n",
+		border, BoxV, RST,
 		Orange+Bold, title, RST,
 		strings.Repeat(" ", pad),
-		border, RST)
+		border, BoxV, RST)
 }
 
 // boxLine wraps a content line inside ║...║, padding or truncating to inner width.
@@ -411,11 +516,11 @@ func (d *Dashboard) boxLine(content string, inner int) string {
 		plen = inner
 	}
 	padN := inner - plen
-	return fmt.Sprintf("%s\u2551%s%s%s%s\u2551%s\n",
-		border, RST,
+	return fmt.Sprintf("%s%c%s%s%s%s%c%s\n",
+		border, BoxV, RST,
 		content,
 		strings.Repeat(" ", padN),
-		border, RST)
+		border, BoxV, RST)
 }
 
 // windowExists checks if a tmux window exists in the session.
@@ -0,0 +1,270 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Drill-down limits for the status view's role detail.
+const (
+	detailMessages = 10
+	detailCommands = 8
+	detailMemory   = 12
+)
+
+// StatusRow is one role in the status view.
+type StatusRow struct {
+	bus.AgentStatus
+	LastCommand *bus.HistoryEntry // nil when the role has run no commands
+	Alerts      []bus.LoopAlert
+}
+
+// CollectStatus gathers the status view rows for every known role.
+func CollectStatus(session string) []StatusRow {
+	var rows []StatusRow
+	for _, s := range bus.GetAllAgentStatus(session) {
+		row := StatusRow{AgentStatus: s, Alerts: bus.CheckLoops(session, s.Role)}
+		if h := bus.ReadHistory(session, s.Role, 1); len(h) > 0 {
+			row.LastCommand = &h[len(h)-1]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// FormatStatusRows renders the role table with the selected row highlighted.
+func FormatStatusRows(rows []StatusRow, selected int) []string {
+	lines := []string{
+		fmt.Sprintf("%s  %-10s %-6s %-6s %-6s %-24s %-30s %s%s", Comment,
+			"ROLE", "STATE", "INBOX", "UNREAD", "LAST MESSAGE", "LAST COMMAND", "ALERTS", RST),
+	}
+	for i, r := range rows {
+		state, stateColor := "idle", Dim
+		if r.Locked {
+			state, stateColor = "busy", Green+Bold
+		}
+
+		inboxColor := Comment
+		if r.InboxCount > 0 {
+			inboxColor = Yellow
+		}
+		unreadColor := Comment
+		if r.Unread > 0 {
+			unreadColor = Yellow + Bold
+		}
+
+		msg := "-"
+		if r.LastMsgTS > 0 {
+			arrow := "<-"
+			if r.LastDir == "sent" {
+				arrow = "->"
+			}
+			msg = fmt.Sprintf("%s %s %s:%s", time.Unix(r.LastMsgTS, 0).Format("15:04"), arrow, r.LastPeer, r.LastAction)
+		}
+
+		cmd, cmdColor := "-", Comment
+		if c := r.LastCommand; c != nil {
+			cmd = c.Outcome + " " + c.Command
+			cmdColor = Green
+			if c.Outcome != "success" {
+				cmdColor = Red
+			}
+		}
+
+		alerts, alertColor := "-", Comment
+		if len(r.Alerts) > 0 {
+			alerts = fmt.Sprintf("%d %s loop", len(r.Alerts), r.Alerts[0].Type)
+			alertColor = Red + Bold
+		}
+
+		cursor := "  "
+		if i == selected {
+			cursor = Pink + Bold + "> " + RST
+		}
+		lines = append(lines, fmt.Sprintf("%s%s%s%s %s%s%s %s%s%s %s%s%s %s %s%s%s %s%s%s",
+			cursor,
+			Bold, Pad(r.Role, 10), RST,
+			stateColor, Pad(state, 6), RST,
+			inboxColor, Pad(fmt.Sprintf("%d", r.InboxCount), 6), RST,
+			unreadColor, Pad(fmt.Sprintf("%d", r.Unread), 6), RST,
+			Pad(msg, 24),
+			cmdColor, Pad(cmd, 30), RST,
+			alertColor, alerts, RST))
+	}
+	return lines
+}
+
+// FormatRoleDetail renders the drill-down for one role: loop alerts, board
+// tasks assigned to it, recent messages, command history, and the tail of
+// its memory.
+func FormatRoleDetail(session string, row StatusRow) []string {
+	heading := func(title string) string {
+		return Orange + Bold + title + RST
+	}
+	var lines []string
+
+	if len(row.Alerts) > 0 {
+		lines = append(lines, heading("ALERTS"))
+		for _, a := range row.Alerts {
+			lines = append(lines, "  "+Red+a.Message+RST)
+		}
+		lines = append(lines, "")
+	}
+
+	if tasks, _ := bus.ReadBoard(session); len(tasks) > 0 {
+		if mine := bus.FilterBoard(tasks, bus.BoardFilter{Assignee: row.Role}); len(mine) > 0 {
+			lines = append(lines, heading("TASKS"))
+			for _, t := range mine {
+				lines = append(lines, formatBoardLine(t))
+			}
+			lines = append(lines, "")
+		}
+	}
+
+	lines = append(lines, heading("MESSAGES"))
+	msgs := bus.ReadLogHistory(session, row.Role, detailMessages)
+	if len(msgs) == 0 {
+		lines = append(lines, "  "+Comment+"(none)"+RST)
+	}
+	for _, m := range msgs {
+		payload := strings.Join(strings.Fields(m.Payload), " ")
+		lines = append(lines, fmt.Sprintf("  %s%s %s -> %s [%s:%s]%s %s",
+			Comment, time.Unix(m.TS, 0).Format("15:04:05"), m.From, m.To, m.Type, m.Action, RST, payload))
+	}
+
+	lines = append(lines, "", heading("COMMANDS"))
+	history := bus.ReadHistory(session, row.Role, detailCommands)
+	if len(history) == 0 {
+		lines = append(lines, "  "+Comment+"(none)"+RST)
+	}
+	for _, h := range history {
+		color := Green
+		if h.Outcome != "success" {
+			color = Red
+		}
+		lines = append(lines, fmt.Sprintf("  %s%s%s %s%-7s%s %s",
+			Comment, time.Unix(h.TS, 0).Format("15:04:05"), RST, color, h.Outcome, RST, h.Command))
+	}
+
+	lines = append(lines, "", heading("MEMORY"))
+	memory, _ := bus.ReadMemory(row.Role)
+	memLines := strings.Split(strings.TrimSpace(memory), "\n")
+	if strings.TrimSpace(memory) == "" {
+		memLines = []string{Comment + "(empty)" + RST}
+	} else if len(memLines) > detailMemory {
+		memLines = memLines[len(memLines)-detailMemory:]
+	}
+	for _, l := range memLines {
+		lines = append(lines, "  "+l)
+	}
+	return lines
+}
+
+// StatusView is the full-screen "status --watch" view: a live role table
+// with drill-down into one role.
+type StatusView struct {
+	session  string
+	refresh  int
+	rows     []StatusRow
+	selected int
+	detail   bool
+}
+
+// NewStatusView creates a status view refreshing every refresh seconds.
+func NewStatusView(session string, refresh int) *StatusView {
+	ApplyTheme(bus.Config().Dashboard.ResolveTheme())
+	return &StatusView{session: session, refresh: refresh}
+}
+
+// HandleKey applies a decoded key. It returns true to quit.
+func (v *StatusView) HandleKey(key string) bool {
+	if v.detail {
+		switch key {
+		case "esc", "backspace", "left", "h":
+			v.detail = false
+		case "q", "Q":
+			return true
+		}
+		return false
+	}
+	switch key {
+	case "q", "Q", "esc":
+		return true
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+		}
+	case "down", "j":
+		if v.selected < len(v.rows)-1 {
+			v.selected++
+		}
+	case "enter", "right", "l":
+		if len(v.rows) > 0 {
+			v.detail = true
+		}
+	}
+	return false
+}
+
+// Render builds the frame for the current state, clipped to height lines.
+func (v *StatusView) Render(width, height int) string {
+	v.rows = CollectStatus(v.session)
+	if v.selected >= len(v.rows) {
+		v.selected = len(v.rows) - 1
+	}
+
+	title := "AGENT STATUS"
+	footer := "up/down: select  enter: drill down  q: quit"
+	var body []string
+	if v.detail && v.selected >= 0 {
+		row := v.rows[v.selected]
+		title = "AGENT STATUS: " + row.Role
+		footer = "esc: back  q: quit"
+		body = FormatRoleDetail(v.session, row)
+	} else {
+		body = FormatStatusRows(v.rows, v.selected)
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s%s%s  %sSession: %s  %ds  %s%s",
+			Pink+Bold, title, RST, Comment, v.session, v.refresh, time.Now().Format("15:04:05"), RST),
+		"",
+	}
+	if room := height - len(lines) - 2; room > 0 && len(body) > room {
+		body = body[:room]
+	}
+	lines = append(lines, body...)
+	lines = append(lines, "", Comment+footer+RST)
+
+	var b strings.Builder
+	for _, l := range lines {
+		if VisibleWidth(l) > width {
+			l = TruncateAnsi(l, width)
+		}
+		b.WriteString(l)
+		b.WriteString("\033[K\n") // clear the rest of the line
+	}
+	return b.String()
+}
+
+// Run starts the status view loop.
+func (v *StatusView) Run() error {
+	t := openTerminal()
+	defer t.close()
+
+	for {
+		t.draw(v.Render(termWidth(), termHeight()))
+		chunk, quit := t.wait(time.Duration(v.refresh) * time.Second)
+		if quit {
+			return nil
+		}
+		for _, seq := range splitKeys(chunk) {
+			if v.HandleKey(decodeKey(seq)) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestStatusView(t *testing.T) {
+	session := fmt.Sprintf("test-status-%d", os.Getpid())
+	memDir := t.TempDir()
+	t.Setenv("BUS_MEMORY_DIR", memDir)
+	if err := bus.Init(session, memDir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bus.Cleanup(session) }()
+
+	if err := bus.Send(session, bus.NewMessage("edit", "build", "request", "build", "compile it", "")); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	for i := 0; i < 3; i++ {
+		data, _ := json.Marshal(bus.HistoryEntry{TS: now, Command: "go build ./...", ExitCode: "1", Outcome: "failure"})
+		if err := bus.AppendHistory(session, "build", data, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := NewStatusView(session, 2)
+	frame := v.Render(200, 60)
+	if !strings.Contains(frame, "AGENT STATUS") {
+		t.Fatalf("frame:\n%s", frame)
+	}
+	var buildRow string
+	for _, l := range strings.Split(StripAnsi(frame), "\n") {
+		if len(l) > 2 && strings.HasPrefix(l[2:], "build ") {
+			buildRow = l
+			break
+		}
+	}
+	for _, want := range []string{"1 ", "<- edit:build", "failure go build ./...", "command loop"} {
+		if !strings.Contains(buildRow, want) {
+			t.Errorf("build row missing %q: %q", want, buildRow)
+		}
+	}
+
+	// Select build and drill in
+	idx := -1
+	for i, r := range v.rows {
+		if r.Role == "build" {
+			idx = i
+		}
+	}
+	for v.selected < idx {
+		v.HandleKey("down")
+	}
+	v.HandleKey("enter")
+	detail := StripAnsi(v.Render(200, 60))
+	for _, want := range []string{"AGENT STATUS: build", "ALERTS", "edit -> build [request:build] compile it", "failure go build ./...", "MEMORY"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("detail missing %q:\n%s", want, detail)
+		}
+	}
+
+	if v.HandleKey("esc") || v.detail {
+		t.Error("esc in detail should go back, not quit")
+	}
+	if !v.HandleKey("q") {
+		t.Error("q should quit")
+	}
+}
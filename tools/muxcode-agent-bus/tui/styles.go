@@ -5,9 +5,11 @@ import (
 	"strings"
 )
 
-// Dracula palette — ANSI 256-color escape codes.
-const (
-	RST     = "\033[0m"
+// RST resets all colors and attributes.
+const RST = "\033[0m"
+
+// Active palette. Defaults to Dracula (ANSI 256-color); ApplyTheme swaps it.
+var (
 	Bold    = "\033[1m"
 	Dim     = "\033[2m"
 	FG      = "\033[38;5;253m"
@@ -22,6 +24,44 @@ const (
 	BG      = "\033[48;5;236m"
 )
 
+// Box-drawing characters for the dashboard frame.
+var (
+	BoxH, BoxV   = '\u2550', '\u2551' // ═ ║
+	BoxTL, BoxTR = '\u2554', '\u2557' // ╔ ╗
+	BoxBL, BoxBR = '\u255a', '\u255d' // ╚ ╝
+	BoxML, BoxMR = '\u2560', '\u2563' // ╠ ╣
+)
+
+// ApplyTheme switches the palette and box characters. "basic" uses the 16
+// standard ANSI colors for terminals without 256-color support; "mono" has
+// no color (bold and dim only) and ASCII borders. Anything else selects
+// Dracula.
+func ApplyTheme(name string) {
+	switch name {
+	case "basic":
+		setPalette("\033[1m", "\033[2m", "\033[37m", "\033[35m", "\033[32m", "\033[36m",
+			"\033[95m", "\033[33m", "\033[93m", "\033[31m", "\033[90m", "\033[40m")
+		setBox('\u2550', '\u2551', '\u2554', '\u2557', '\u255a', '\u255d', '\u2560', '\u2563')
+	case "mono":
+		setPalette("\033[1m", "\033[2m", "", "", "", "", "", "", "", "", "", "")
+		setBox('-', '|', '+', '+', '+', '+', '+', '+')
+	default:
+		setPalette("\033[1m", "\033[2m", "\033[38;5;253m", "\033[38;5;141m", "\033[38;5;84m", "\033[38;5;117m",
+			"\033[38;5;212m", "\033[38;5;228m", "\033[38;5;215m", "\033[38;5;203m", "\033[38;5;103m", "\033[48;5;236m")
+		setBox('\u2550', '\u2551', '\u2554', '\u2557', '\u255a', '\u255d', '\u2560', '\u2563')
+	}
+}
+
+func setPalette(bold, dim, fg, purple, green, cyan, pink, yellow, orange, red, comment, bg string) {
+	Bold, Dim, FG, Purple, Green, Cyan = bold, dim, fg, purple, green, cyan
+	Pink, Yellow, Orange, Red, Comment, BG = pink, yellow, orange, red, comment, bg
+}
+
+func setBox(h, v, tl, tr, bl, br, ml, mr rune) {
+	BoxH, BoxV, BoxTL, BoxTR = h, v, tl, tr
+	BoxBL, BoxBR, BoxML, BoxMR = bl, br, ml, mr
+}
+
 var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 // Pad pads or truncates s to exactly width visible characters.
@@ -161,3 +161,29 @@ func TestHLine_Zero(t *testing.T) {
 		t.Errorf("HLine('═', 0) = %q, want empty", got)
 	}
 }
+
+func TestApplyTheme(t *testing.T) {
+	defer ApplyTheme("dracula")
+	d := &Dashboard{}
+
+	ApplyTheme("mono")
+	if Purple != "" || Red != "" || Bold == "" {
+		t.Errorf("mono palette: Purple=%q Red=%q Bold=%q", Purple, Red, Bold)
+	}
+	if got := StripAnsi(d.separator(6)); got != "+------+\n" {
+		t.Errorf("mono separator = %q", got)
+	}
+	if got := StripAnsi(d.titledSeparator("BUS", 10)); got != "+- BUS ----+\n" {
+		t.Errorf("mono titled separator = %q", got)
+	}
+
+	ApplyTheme("basic")
+	if Purple != "\033[35m" || BoxV != '║' {
+		t.Errorf("basic palette: Purple=%q BoxV=%q", Purple, BoxV)
+	}
+
+	ApplyTheme("dracula")
+	if got := StripAnsi(d.titledSeparator("BUS", 10)); got != "╠═ BUS ════╣\n" {
+		t.Errorf("dracula titled separator = %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// terminal owns the screen while a full-screen view runs: the cursor is
+// hidden, stdin is switched to unbuffered no-echo input, and keystrokes are
+// delivered as raw chunks (see splitKeys).
+type terminal struct {
+	keys      chan []byte
+	signals   chan os.Signal
+	sttyState string
+}
+
+// openTerminal clears the screen and starts reading keys.
+func openTerminal() *terminal {
+	t := &terminal{
+		keys:    make(chan []byte, 16),
+		signals: make(chan os.Signal, 1),
+	}
+
+	// Clear screen and hide cursor
+	fmt.Print("\033[2J\033[H")
+	fmt.Print("\033[?25l")
+
+	// Set up signal handler for clean exit
+	signal.Notify(t.signals, syscall.SIGINT, syscall.SIGTERM)
+
+	// Read keys as they are pressed rather than line by line
+	t.rawMode()
+
+	// Start non-blocking key reader
+	go t.readKeys()
+	return t
+}
+
+// draw prints a frame from the top-left corner, clearing anything below it.
+func (t *terminal) draw(frame string) {
+	fmt.Print("\033[H")
+	fmt.Print(frame)
+	fmt.Print("\033[J")
+}
+
+// wait blocks until a key chunk arrives, the interval elapses (nil chunk),
+// or a termination signal is received (quit).
+func (t *terminal) wait(interval time.Duration) (chunk []byte, quit bool) {
+	select {
+	case <-t.signals:
+		return nil, true
+	case chunk := <-t.keys:
+		return chunk, false
+	case <-time.After(interval):
+		return nil, false
+	}
+}
+
+// readKeys reads from stdin in a loop, sending each chunk to keys.
+// A chunk holds whole escape sequences (arrows, Shift-Tab) as the terminal
+// writes them in one go.
+func (t *terminal) readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil || n == 0 {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		chunk := make([]byte, n)
+		copy(chunk, buf[:n])
+		t.keys <- chunk
+	}
+}
+
+// rawMode switches the terminal to unbuffered, no-echo input so single
+// keystrokes reach the view. The previous settings are saved for close.
+func (t *terminal) rawMode() {
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	t.sttyState = strings.TrimSpace(string(out))
+	cmd = exec.Command("stty", "-icanon", "-echo", "min", "1")
+	cmd.Stdin = os.Stdin
+	_ = cmd.Run()
+}
+
+// close restores the terminal to a usable state.
+func (t *terminal) close() {
+	signal.Stop(t.signals)
+	if t.sttyState != "" {
+		cmd := exec.Command("stty", t.sttyState)
+		cmd.Stdin = os.Stdin
+		_ = cmd.Run()
+	}
+	fmt.Print("\033[?25h") // show cursor
+	fmt.Print(RST)         // reset colors
+	fmt.Print("\033[2J")   // clear screen
+	fmt.Print("\033[H")    // move to top
+}
+
+// termHeight returns the terminal height, defaulting to 24.
+func termHeight() int {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		parts := strings.Fields(strings.TrimSpace(string(out)))
+		if len(parts) == 2 {
+			if h, err := strconv.Atoi(parts[0]); err == nil && h > 0 {
+				return h
+			}
+		}
+	}
+	return 24
+}
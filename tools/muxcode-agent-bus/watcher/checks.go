@@ -0,0 +1,166 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Check is a periodic watcher task. Registered checks run in registration
+// order on each poll once their interval has passed since the last run.
+// ctx is cancelled when the watcher shuts down; a running check should
+// finish its current write and skip the rest of its work.
+type Check interface {
+	Name() string
+	Interval() time.Duration // 0 runs on every poll
+	Run(ctx context.Context) error
+}
+
+// checkFunc adapts a function to Check.
+type checkFunc struct {
+	name     string
+	interval time.Duration
+	delay    bool // wait one interval before the first run
+	fn       func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                  { return c.name }
+func (c checkFunc) Interval() time.Duration       { return c.interval }
+func (c checkFunc) Run(ctx context.Context) error { return c.fn(ctx) }
+
+// DelayFirst reports whether the first run waits a full interval.
+func (c checkFunc) DelayFirst() bool { return c.delay }
+
+// registeredCheck is a check with its effective schedule and metrics.
+type registeredCheck struct {
+	check    Check
+	interval time.Duration
+	disabled bool
+	last     time.Time
+	stat     bus.WatcherCheckStat
+}
+
+// checkStatsEvery is how often check metrics are saved to the bus dir.
+const checkStatsEvery = 10 * time.Second
+
+// registerChecks registers the built-in checks. Loops, compaction, and
+// Ollama wait one interval before their first run to avoid stale alerts
+// on startup.
+func (w *Watcher) registerChecks() {
+	for _, c := range []checkFunc{
+		{name: "inbox", fn: w.checkInboxes},
+		{name: "edits", fn: w.checkEdits},
+		{name: "cron", fn: w.checkCron},
+		{name: "procs", fn: w.checkProcs},
+		{name: "spawns", fn: w.checkSpawns},
+		{name: "loops", interval: 60 * time.Second, delay: true, fn: w.checkLoops},
+		{name: "compaction", interval: 120 * time.Second, delay: true, fn: w.checkCompaction},
+		{name: "sla", interval: 60 * time.Second, fn: w.checkSLA},
+		{name: "tasks", interval: 30 * time.Second, fn: w.checkIdleTasks},
+		{name: "budget", interval: 60 * time.Second, fn: w.checkBudget},
+		{name: "ollama", interval: 30 * time.Second, delay: true, fn: w.checkOllama},
+		{name: "traces", interval: 10 * time.Second, fn: w.checkTraces},
+		{name: "archive", interval: 60 * time.Second, fn: w.checkArchive},
+		{name: "heartbeat", interval: 15 * time.Second, fn: w.checkHeartbeats},
+		{name: "callbacks", interval: 5 * time.Second, fn: w.checkCallbacks},
+		{name: "workflows", interval: 5 * time.Second, fn: w.checkWorkflows},
+		{name: "approvals", interval: 15 * time.Second, fn: w.checkApprovals},
+		{name: "retries", interval: 15 * time.Second, fn: w.checkChainRetries},
+		{name: "flaky", interval: 60 * time.Second, delay: true, fn: w.checkFlaky},
+		{name: "pr-threads", interval: 30 * time.Second, fn: w.checkPRThreads},
+		{name: "intake", interval: 30 * time.Second, fn: w.checkIntake},
+	} {
+		w.Register(c)
+	}
+}
+
+// Register adds a check, applying the interval and disable settings from
+// the watcher section of muxcode.json. A check registered under an existing
+// name replaces it in place.
+func (w *Watcher) Register(c Check) {
+	cfg := bus.Config().Watcher
+	rc := &registeredCheck{
+		check:    c,
+		interval: cfg.Interval(c.Name(), c.Interval()),
+		disabled: cfg.Disabled(c.Name()),
+	}
+	if d, ok := c.(interface{ DelayFirst() bool }); ok && d.DelayFirst() {
+		rc.last = time.Now()
+	}
+	rc.stat = bus.WatcherCheckStat{Name: c.Name(), IntervalS: int64(rc.interval / time.Second), Disabled: rc.disabled}
+
+	for i, existing := range w.checks {
+		if existing.check.Name() == c.Name() {
+			w.checks[i] = rc
+			return
+		}
+	}
+	w.checks = append(w.checks, rc)
+}
+
+// runChecks runs every enabled check that is due at now, recording its
+// latency and any error it returns. Checks not yet started when ctx is
+// cancelled are skipped.
+func (w *Watcher) runChecks(ctx context.Context, now time.Time) {
+	for _, rc := range w.checks {
+		if ctx.Err() != nil {
+			return
+		}
+		if rc.disabled || now.Sub(rc.last) < rc.interval {
+			continue
+		}
+		rc.last = now
+
+		start := time.Now()
+		err := rc.check.Run(ctx)
+		ms := time.Since(start).Milliseconds()
+
+		rc.stat.Runs++
+		rc.stat.LastRun = now.Unix()
+		rc.stat.LastMs = ms
+		rc.stat.TotalMs += ms
+		if ms > rc.stat.MaxMs {
+			rc.stat.MaxMs = ms
+		}
+		if err != nil {
+			rc.stat.Errors++
+			rc.stat.LastError = err.Error()
+			fmt.Fprintf(os.Stderr, "  [%s] %v\n", rc.check.Name(), err)
+		}
+	}
+}
+
+// checkStats returns the metrics of every registered check.
+func (w *Watcher) checkStats() []bus.WatcherCheckStat {
+	stats := make([]bus.WatcherCheckStat, 0, len(w.checks))
+	for _, rc := range w.checks {
+		stats = append(stats, rc.stat)
+	}
+	return stats
+}
+
+// saveCheckStats writes check metrics for "status checks", at most every
+// checkStatsEvery unless force is set.
+func (w *Watcher) saveCheckStats(force bool) {
+	if !force && time.Since(w.checksWritten) < checkStatsEvery {
+		return
+	}
+	w.checksWritten = time.Now()
+	if err := bus.WriteWatcherChecks(w.session, w.checkStats()); err != nil {
+		fmt.Fprintf(os.Stderr, "  [watcher] failed to save check metrics: %v\n", err)
+	}
+}
+
+// disabledChecks returns the names of checks turned off in config.
+func (w *Watcher) disabledChecks() []string {
+	var names []string
+	for _, rc := range w.checks {
+		if rc.disabled {
+			names = append(names, rc.check.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestRegisterChecks_MatchesNames(t *testing.T) {
+	w := New(testSession(t), 5, 8)
+	var names []string
+	for _, rc := range w.checks {
+		names = append(names, rc.check.Name())
+	}
+	if got, want := strings.Join(names, ","), strings.Join(bus.WatcherCheckNames, ","); got != want {
+		t.Errorf("registered checks = %s\nwant %s", got, want)
+	}
+}
+
+func TestRunChecks_Intervals(t *testing.T) {
+	w := New(testSession(t), 5, 8)
+	w.checks = nil
+
+	var every, slow, delayed int
+	w.Register(checkFunc{name: "every", fn: func(context.Context) error { every++; return nil }})
+	w.Register(checkFunc{name: "slow", interval: 60 * time.Second, fn: func(context.Context) error { slow++; return nil }})
+	w.Register(checkFunc{name: "delayed", interval: 60 * time.Second, delay: true, fn: func(context.Context) error { delayed++; return nil }})
+
+	start := time.Now()
+	w.runChecks(context.Background(), start)
+	w.runChecks(context.Background(), start.Add(30*time.Second))
+	if every != 2 || slow != 1 || delayed != 0 {
+		t.Errorf("after 30s: every=%d slow=%d delayed=%d, want 2 1 0", every, slow, delayed)
+	}
+	w.runChecks(context.Background(), start.Add(61*time.Second))
+	if every != 3 || slow != 2 || delayed != 1 {
+		t.Errorf("after 61s: every=%d slow=%d delayed=%d, want 3 2 1", every, slow, delayed)
+	}
+}
+
+func TestRunChecks_Metrics(t *testing.T) {
+	w := New(testSession(t), 5, 8)
+	w.checks = nil
+
+	fail := true
+	w.Register(checkFunc{name: "flaky", fn: func(context.Context) error {
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	}})
+	w.runChecks(context.Background(), time.Now())
+	fail = false
+	w.runChecks(context.Background(), time.Now())
+
+	stats := w.checkStats()
+	if len(stats) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	s := stats[0]
+	if s.Runs != 2 || s.Errors != 1 || s.LastError != "boom" || s.LastRun == 0 {
+		t.Errorf("unexpected stat %+v", s)
+	}
+
+	w.saveCheckStats(true)
+	saved, err := bus.ReadWatcherChecks(w.session)
+	if err != nil || len(saved) != 1 || saved[0].Runs != 2 {
+		t.Errorf("saved stats = %+v, err %v", saved, err)
+	}
+}
+
+func TestRegister_Config(t *testing.T) {
+	bus.SetConfig(&bus.MuxcodeConfig{Watcher: bus.WatcherConfig{
+		Disable:   []string{"archive"},
+		Intervals: map[string]string{"loops": "5m"},
+	}})
+	defer bus.SetConfig(nil)
+
+	w := New(testSession(t), 5, 8)
+	for _, rc := range w.checks {
+		switch rc.check.Name() {
+		case "archive":
+			if !rc.disabled {
+				t.Error("archive should be disabled")
+			}
+		case "loops":
+			if rc.interval != 5*time.Minute {
+				t.Errorf("loops interval = %v, want 5m", rc.interval)
+			}
+		case "sla":
+			if rc.disabled || rc.interval != 60*time.Second {
+				t.Errorf("sla should keep its defaults: disabled=%v interval=%v", rc.disabled, rc.interval)
+			}
+		}
+	}
+	if got := w.disabledChecks(); len(got) != 1 || got[0] != "archive" {
+		t.Errorf("disabledChecks = %v", got)
+	}
+
+	// Disabled checks never run
+	ran := false
+	w.Register(checkFunc{name: "archive", fn: func(context.Context) error { ran = true; return nil }})
+	w.runChecks(context.Background(), time.Now().Add(time.Hour))
+	if ran {
+		t.Error("disabled check ran")
+	}
+}
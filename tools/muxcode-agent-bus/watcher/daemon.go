@@ -0,0 +1,304 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// Supervisor restart policy: back off from 1s up to 30s between restarts,
+// and give up after maxRestarts restarts that each ran less than
+// stableRun.
+const (
+	maxRestarts = 5
+	stableRun   = 10 * time.Minute
+	maxBackoff  = 30 * time.Second
+)
+
+// watcherLockPath returns the flock file held by the running watcher.
+func watcherLockPath(session string) string {
+	return filepath.Join(bus.BusDir(session), "lock", "watcher.lock")
+}
+
+// DaemonStatus describes the watcher running for a session, if any.
+type DaemonStatus struct {
+	Running       bool
+	PID           int       // watcher process (0 if unknown)
+	SupervisorPID int       // daemon supervisor, 0 for a foreground watcher
+	Started       time.Time // when the daemon was started
+	LogPath       string    // daemon log, "" for a foreground watcher
+	ChecksAge     time.Duration
+	HasChecks     bool       // ChecksAge is set: the watcher has saved check metrics
+	StaleCleaned  bool       // a PID file for a dead daemon was removed
+	LastRun       *RunMarker // marker of the last run, when not running
+}
+
+// lockHolder reports whether the watcher lock is held and by which PID.
+func lockHolder(session string) (bool, int) {
+	f, err := os.OpenFile(watcherLockPath(session), os.O_RDWR, 0644)
+	if err != nil {
+		return false, 0
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == nil {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return false, 0
+	}
+	data, _ := os.ReadFile(watcherLockPath(session))
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return true, pid
+}
+
+// readDaemonPid reads the daemon supervisor's PID file.
+func readDaemonPid(session string) (int, time.Time, error) {
+	path := bus.WatcherPidPath(session)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid PID file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return pid, info.ModTime(), nil
+}
+
+// Status reports whether a watcher is running for the session. A watcher
+// holding the session lock counts as running whether or not it was started
+// as a daemon; a live daemon supervisor counts while it restarts its
+// watcher. A PID file left by a dead daemon is removed.
+func Status(session string) DaemonStatus {
+	var st DaemonStatus
+	st.Running, st.PID = lockHolder(session)
+
+	if pid, started, err := readDaemonPid(session); err == nil {
+		if bus.CheckProcAlive(pid) {
+			st.Running = true
+			st.SupervisorPID = pid
+			st.Started = started
+			st.LogPath = bus.WatcherLogPath(session)
+		} else {
+			_ = os.Remove(bus.WatcherPidPath(session))
+			st.StaleCleaned = true
+		}
+	}
+
+	if info, err := os.Stat(bus.WatcherChecksPath(session)); err == nil && st.Running {
+		st.HasChecks = true
+		st.ChecksAge = time.Since(info.ModTime()).Round(time.Second)
+	}
+	if !st.Running {
+		if m, err := ReadRunMarker(session); err == nil {
+			st.LastRun = &m
+		}
+	}
+	return st
+}
+
+// formatLastRun describes how the last watcher run ended.
+func formatLastRun(m RunMarker) string {
+	if !m.Clean {
+		return fmt.Sprintf("  Last run (PID %d, started %s) did not shut down cleanly\n",
+			m.PID, time.Unix(m.Started, 0).Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("  Last run (PID %d) shut down cleanly at %s (%s)\n",
+		m.PID, time.Unix(m.Stopped, 0).Format("2006-01-02 15:04:05"), m.Reason)
+}
+
+// FormatStatus returns a human-readable watcher status.
+func FormatStatus(session string, st DaemonStatus) string {
+	if !st.Running {
+		out := fmt.Sprintf("Watcher: not running for session %s\n", session)
+		if st.StaleCleaned {
+			out = fmt.Sprintf("Watcher: not running for session %s (stale PID file cleaned)\n", session)
+		}
+		if st.LastRun != nil {
+			out += formatLastRun(*st.LastRun)
+		}
+		return out
+	}
+
+	var b strings.Builder
+	pid := "starting"
+	if st.PID > 0 {
+		pid = fmt.Sprintf("PID %d", st.PID)
+	}
+	if st.SupervisorPID > 0 {
+		fmt.Fprintf(&b, "Watcher: running for session %s (%s, daemon PID %d since %s)\n",
+			session, pid, st.SupervisorPID, st.Started.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(&b, "  Log: %s\n", st.LogPath)
+	} else {
+		fmt.Fprintf(&b, "Watcher: running for session %s (%s, foreground)\n", session, pid)
+	}
+	if st.HasChecks {
+		fmt.Fprintf(&b, "  Check metrics updated %s ago (muxcode-agent-bus status checks)\n", st.ChecksAge)
+	}
+	return b.String()
+}
+
+// StartDaemon launches a detached supervisor ("watch supervise") that runs
+// the watcher for the session, logging to the bus dir. Returns the
+// supervisor PID once the watcher holds its lock.
+func StartDaemon(session string, pollSecs, debounceSecs int) (int, error) {
+	if st := Status(session); st.Running {
+		return 0, fmt.Errorf("a watcher is already running for session %s (PID %d)", session, st.PID)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("finding executable: %w", err)
+	}
+	logFile, err := os.OpenFile(bus.WatcherLogPath(session), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("opening watcher log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "watch", "supervise", session,
+		"--poll", strconv.Itoa(pollSecs), "--debounce", strconv.Itoa(debounceSecs))
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Stdin = nil
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("starting watcher daemon: %w", err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	// Wait up to 3 seconds for the watcher to take its lock
+	for i := 0; i < 15; i++ {
+		time.Sleep(200 * time.Millisecond)
+		if held, _ := lockHolder(session); held {
+			return pid, nil
+		}
+		if !bus.CheckProcAlive(pid) {
+			return 0, fmt.Errorf("watcher daemon exited; see %s", bus.WatcherLogPath(session))
+		}
+	}
+	return pid, fmt.Errorf("watcher daemon started (PID %d) but the watcher has not taken its lock; see %s", pid, bus.WatcherLogPath(session))
+}
+
+// StopDaemon stops the session's watcher: the daemon supervisor if there is
+// one, otherwise the foreground watcher holding the lock. Waits up to 10
+// seconds for it to exit, long enough for the supervisor's own 5 second
+// wait on a watcher shutting down.
+func StopDaemon(session string) error {
+	st := Status(session)
+	if !st.Running {
+		return fmt.Errorf("no watcher running for session %s", session)
+	}
+	pid := st.SupervisorPID
+	if pid == 0 {
+		pid = st.PID
+	}
+	if pid == 0 {
+		return fmt.Errorf("watcher for session %s has no known PID", session)
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("sending signal to %d: %w", pid, err)
+	}
+	for i := 0; i < 50; i++ {
+		if !bus.CheckProcAlive(pid) {
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	if bus.CheckProcAlive(pid) {
+		return fmt.Errorf("watcher (PID %d) did not exit within 10s", pid)
+	}
+	if st.SupervisorPID > 0 {
+		_ = os.Remove(bus.WatcherPidPath(session))
+	}
+	return nil
+}
+
+// Supervise runs the watcher for the session as a child process ("watch
+// <session> ..."), restarting it with backoff when it exits with an error.
+// SIGTERM or SIGINT stop the child and the supervisor. It writes the PID
+// file and removes it on exit; output goes to stdout, which StartDaemon
+// points at the watcher log.
+func Supervise(session string, pollSecs, debounceSecs int) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding executable: %w", err)
+	}
+	if err := os.WriteFile(bus.WatcherPidPath(session), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("writing PID file: %w", err)
+	}
+	defer os.Remove(bus.WatcherPidPath(session))
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigs)
+
+	logf := func(format string, args ...interface{}) {
+		fmt.Printf("  %s  [supervisor] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+	}
+	logf("supervising watcher for session %s (PID %d)", session, os.Getpid())
+
+	restarts := 0
+	backoff := time.Second
+	for {
+		cmd := exec.Command(exe, "watch", session,
+			"--poll", strconv.Itoa(pollSecs), "--debounce", strconv.Itoa(debounceSecs))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		started := time.Now()
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("starting watcher: %w", err)
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+
+		select {
+		case sig := <-sigs:
+			logf("received %s, stopping watcher (PID %d)", sig, cmd.Process.Pid)
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				_ = cmd.Process.Kill()
+				<-done
+			}
+			return nil
+		case err := <-done:
+			if err == nil {
+				logf("watcher exited")
+				return nil
+			}
+			if time.Since(started) >= stableRun {
+				restarts = 0
+				backoff = time.Second
+			}
+			restarts++
+			if restarts > maxRestarts {
+				logf("watcher exited: %v; giving up after %d restarts", err, maxRestarts)
+				return fmt.Errorf("watcher keeps exiting: %v", err)
+			}
+			logf("watcher exited: %v; restarting in %s (%d/%d)", err, backoff, restarts, maxRestarts)
+			select {
+			case sig := <-sigs:
+				logf("received %s while waiting to restart", sig)
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
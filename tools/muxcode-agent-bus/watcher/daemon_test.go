@@ -0,0 +1,100 @@
+package watcher
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestStatus_NotRunning(t *testing.T) {
+	session := testSession(t)
+
+	st := Status(session)
+	if st.Running {
+		t.Fatalf("unexpected running status %+v", st)
+	}
+	if out := FormatStatus(session, st); !strings.Contains(out, "not running") {
+		t.Errorf("FormatStatus = %q", out)
+	}
+	if err := StopDaemon(session); err == nil {
+		t.Error("expected error stopping a watcher that isn't running")
+	}
+}
+
+func TestStatus_ForegroundLock(t *testing.T) {
+	session := testSession(t)
+
+	unlock, err := acquireWatcherLock(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	st := Status(session)
+	if !st.Running || st.PID != os.Getpid() || st.SupervisorPID != 0 {
+		t.Fatalf("status = %+v, want running foreground watcher with our PID", st)
+	}
+	if out := FormatStatus(session, st); !strings.Contains(out, "foreground") {
+		t.Errorf("FormatStatus = %q", out)
+	}
+	if _, err := StartDaemon(session, 2, 8); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("StartDaemon with a running watcher: %v", err)
+	}
+}
+
+func TestStatus_Daemon(t *testing.T) {
+	session := testSession(t)
+
+	// A live supervisor PID counts as running even before the watcher locks
+	if err := os.WriteFile(bus.WatcherPidPath(session), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	st := Status(session)
+	if !st.Running || st.SupervisorPID != os.Getpid() || st.LogPath != bus.WatcherLogPath(session) {
+		t.Fatalf("status = %+v", st)
+	}
+	if out := FormatStatus(session, st); !strings.Contains(out, "daemon PID") || !strings.Contains(out, "Log:") {
+		t.Errorf("FormatStatus = %q", out)
+	}
+}
+
+func TestStatus_StalePidFile(t *testing.T) {
+	session := testSession(t)
+
+	// PID 0 is never alive
+	if err := os.WriteFile(bus.WatcherPidPath(session), []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	st := Status(session)
+	if st.Running || !st.StaleCleaned {
+		t.Fatalf("status = %+v, want stale and not running", st)
+	}
+	if _, err := os.Stat(bus.WatcherPidPath(session)); !os.IsNotExist(err) {
+		t.Error("stale PID file not removed")
+	}
+}
+
+func TestStatus_LastRun(t *testing.T) {
+	session := testSession(t)
+
+	if err := writeRunMarker(session, RunMarker{PID: 42, Started: 1, Stopped: 2, Clean: true, Reason: "received terminated"}); err != nil {
+		t.Fatal(err)
+	}
+	st := Status(session)
+	if st.LastRun == nil || !st.LastRun.Clean {
+		t.Fatalf("LastRun = %+v", st.LastRun)
+	}
+	if out := FormatStatus(session, st); !strings.Contains(out, "shut down cleanly") || !strings.Contains(out, "received terminated") {
+		t.Errorf("FormatStatus = %q", out)
+	}
+
+	if err := writeRunMarker(session, RunMarker{PID: 42, Started: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if out := FormatStatus(session, Status(session)); !strings.Contains(out, "did not shut down cleanly") {
+		t.Errorf("FormatStatus = %q", out)
+	}
+}
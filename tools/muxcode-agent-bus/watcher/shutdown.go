@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+// RunMarker records a watcher run in watcher-state.json. The watcher writes
+// it with Clean unset at startup and set on graceful shutdown, so the next
+// start can tell whether the previous run was interrupted.
+type RunMarker struct {
+	PID     int    `json:"pid"`
+	Started int64  `json:"started"`
+	Stopped int64  `json:"stopped,omitempty"`
+	Clean   bool   `json:"clean"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ReadRunMarker reads the marker left by the last watcher run.
+func ReadRunMarker(session string) (RunMarker, error) {
+	var m RunMarker
+	data, err := os.ReadFile(bus.WatcherStatePath(session))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid watcher state: %w", err)
+	}
+	return m, nil
+}
+
+// writeRunMarker saves the run marker atomically.
+func writeRunMarker(session string, m RunMarker) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := bus.WatcherStatePath(session) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, bus.WatcherStatePath(session))
+}
+
+// startRun checks the previous run's marker, reporting a run that did not
+// shut down cleanly, and records this run as started.
+func (w *Watcher) startRun() {
+	if prev, err := ReadRunMarker(w.session); err == nil && !prev.Clean {
+		fmt.Printf("  Warning: previous watcher (PID %d, started %s) did not shut down cleanly\n",
+			prev.PID, time.Unix(prev.Started, 0).Format("2006-01-02 15:04:05"))
+	}
+	w.marker = RunMarker{PID: os.Getpid(), Started: time.Now().Unix()}
+	if err := writeRunMarker(w.session, w.marker); err != nil {
+		fmt.Fprintf(os.Stderr, "  [watcher] failed to write run marker: %v\n", err)
+	}
+}
+
+// shutdown flushes pending state after the run loop stops: edits still in
+// their debounce window are routed now, check metrics are saved, and the
+// run marker is marked clean.
+func (w *Watcher) shutdown(cause error) {
+	reason := "stopped"
+	if cause != nil {
+		reason = cause.Error()
+	}
+	fmt.Printf("  %s  Shutting down (%s)\n", time.Now().Format("15:04:05"), reason)
+
+	if n := len(w.pendingOrder); n > 0 {
+		fmt.Printf("  %s  Routing %d pending edited file(s)\n", time.Now().Format("15:04:05"), n)
+	}
+	w.drainEdits(math.MaxInt64)
+	w.saveCheckStats(true)
+
+	w.marker.Stopped = time.Now().Unix()
+	w.marker.Clean = true
+	w.marker.Reason = reason
+	if err := writeRunMarker(w.session, w.marker); err != nil {
+		fmt.Fprintf(os.Stderr, "  [watcher] failed to write run marker: %v\n", err)
+	}
+}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
+)
+
+func TestRun_GracefulShutdown(t *testing.T) {
+	session := testSession(t)
+
+	// A previous run that was killed
+	if err := writeRunMarker(session, RunMarker{PID: 1, Started: time.Now().Unix() - 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(session, 1, 3600)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	// Wait for the first poll so the run marker is written
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if m, err := ReadRunMarker(session); err == nil && m.PID == os.Getpid() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not write its run marker")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// An edit still inside the (1 hour) debounce window
+	ev, _ := json.Marshal(bus.EditEvent{TS: time.Now().Unix(), Path: "pending.go", Op: "edit"})
+	if err := os.WriteFile(bus.EditLogPath(session), append(ev, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1200 * time.Millisecond)
+
+	cancel(errors.New("received terminated"))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+
+	m, err := ReadRunMarker(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Clean || m.Stopped == 0 || m.Reason != "received terminated" {
+		t.Errorf("run marker = %+v, want clean shutdown", m)
+	}
+	msgs, _ := bus.Receive(session, "analyze")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Payload, "pending.go") {
+		t.Errorf("pending edit not flushed on shutdown: %+v", msgs)
+	}
+	if _, err := bus.ReadWatcherChecks(session); err != nil {
+		t.Errorf("check metrics not saved: %v", err)
+	}
+}
+
+func TestRunChecks_StopsWhenCancelled(t *testing.T) {
+	w := New(testSession(t), 5, 8)
+	w.checks = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var second bool
+	w.Register(checkFunc{name: "first", fn: func(context.Context) error { cancel(); return nil }})
+	w.Register(checkFunc{name: "second", fn: func(context.Context) error { second = true; return nil }})
+	w.runChecks(ctx, time.Now())
+	if second {
+		t.Error("check started after cancellation")
+	}
+}
@@ -1,11 +1,11 @@
 package watcher
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -14,32 +14,64 @@ import (
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
-// Watcher monitors agent inboxes and a trigger file for file-edit events.
+// Watcher monitors agent inboxes and the edit log for file-edit events.
 type Watcher struct {
 	session          string
 	pollInterval     time.Duration
 	debounceSecs     int
-	triggerFile      string
+	editLog          string
 	inboxSizes       map[string]int64
-	lastTriggerSize  int64
-	pendingSince     int64
+	editOffset       int64                      // edit log bytes already read
+	pendingEdits     map[string][]bus.EditEvent // unrouted edits by file, waiting to stabilize
+	pendingOrder     []string                   // pendingEdits keys in first-edit order
 	cronEntries      []bus.CronEntry
 	lastCronLoad     int64
-	lastLoopCheck    int64
-	lastCompactCheck int64
+	checks           []*registeredCheck // periodic checks in run order
+	checksWritten    time.Time          // when check metrics were last saved
+	marker           RunMarker          // this run, written to watcher-state.json
+	downRoles        map[string]int64   // roles reported agent-down and when, until their heartbeat recovers
+	relaunches       map[string]int     // automatic pane relaunches per role
+	traceRetryAt     int64              // after a failed OTLP export, skip exports until this time
+	traceOffset      int64              // trace.jsonl bytes already exported
+	slaSince         int64              // only report SLA breaches with deadlines after watcher start
 	lastAlertKey     map[string]int64
 	hasRunningProcs  bool
 	hasRunningSpawns bool
 	lastProcSize     int64
 	lastSpawnSize    int64
 	// Ollama health monitoring
-	ollamaRoles     []string // populated once in New()
-	lastOllamaCheck int64    // 30s interval
-	ollamaFailCount int      // consecutive probe failures
-	ollamaWasDown   bool     // for recovery detection
-	ollamaRestarts  int      // cap at 3 to prevent restart loops
-	ollamaURL       string   // Ollama base URL
-	ollamaModel     string   // Ollama model name
+	ollamaRoles []string      // populated once in New()
+	ollamaNodes []*ollamaNode // one per base URL serving ollamaRoles
+}
+
+// ollamaNode is the probe state for one Ollama base URL.
+type ollamaNode struct {
+	url       string
+	model     string   // model probed on this node
+	roles     []string // local-LLM roles this node serves
+	failCount int      // consecutive probe failures
+	wasDown   bool     // for recovery detection
+	restarts  int      // cap at 3 to prevent restart loops
+}
+
+// newOllamaNodes groups the local-LLM roles by the nodes serving their
+// models (see bus.OllamaNodeURLs).
+func newOllamaNodes(roles []string) []*ollamaNode {
+	var nodes []*ollamaNode
+	byURL := map[string]*ollamaNode{}
+	for _, role := range roles {
+		model := bus.RoleModel(role)
+		for _, u := range bus.OllamaNodeURLs(model) {
+			n, ok := byURL[u]
+			if !ok {
+				n = &ollamaNode{url: u, model: model}
+				byURL[u] = n
+				nodes = append(nodes, n)
+			}
+			n.roles = append(n.roles, role)
+		}
+	}
+	return nodes
 }
 
 // New creates a new Watcher for the given session.
@@ -49,30 +81,29 @@ func New(session string, pollSecs, debounceSecs int) *Watcher {
 	// Discover which roles use local LLM
 	ollamaRoles := bus.LocalLLMRoles()
 
-	// Read Ollama config for health probes
-	ollamaCfg := bus.DefaultOllamaConfig()
-
-	return &Watcher{
-		session:          session,
-		pollInterval:     time.Duration(pollSecs) * time.Second,
-		debounceSecs:     debounceSecs,
-		triggerFile:      bus.TriggerFile(session),
-		inboxSizes:       make(map[string]int64),
-		lastAlertKey:     make(map[string]int64),
-		lastLoopCheck:    now, // skip first interval — avoids stale alerts on startup
-		lastCompactCheck: now, // skip first interval — avoids stale alerts on startup
-		lastOllamaCheck:  now, // skip first interval
-		ollamaRoles:      ollamaRoles,
-		ollamaURL:        ollamaCfg.BaseURL,
-		ollamaModel:      ollamaCfg.Model,
+	w := &Watcher{
+		session:      session,
+		pollInterval: time.Duration(pollSecs) * time.Second,
+		debounceSecs: debounceSecs,
+		editLog:      bus.EditLogPath(session),
+		inboxSizes:   make(map[string]int64),
+		pendingEdits: make(map[string][]bus.EditEvent),
+		downRoles:    make(map[string]int64),
+		relaunches:   make(map[string]int),
+		lastAlertKey: make(map[string]int64),
+		slaSince:     now,
+		ollamaRoles:  ollamaRoles,
+		ollamaNodes:  newOllamaNodes(ollamaRoles),
 	}
+	w.registerChecks()
+	return w
 }
 
 // acquireWatcherLock ensures only one watcher runs per session.
 // Uses flock on a lock file for race-free single-instance enforcement.
 // Returns an unlock function, or an error if another watcher is already running.
 func acquireWatcherLock(session string) (func(), error) {
-	lockPath := filepath.Join(bus.BusDir(session), "lock", "watcher.lock")
+	lockPath := watcherLockPath(session)
 	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open watcher lock: %w", err)
@@ -95,10 +126,11 @@ func acquireWatcherLock(session string) (func(), error) {
 	}, nil
 }
 
-// Run starts the main watcher loop. It never returns under normal operation.
-// Acquires a per-session flock to prevent duplicate watcher processes — stale
-// watchers from previous session starts cause duplicate tmux notifications.
-func (w *Watcher) Run() error {
+// Run starts the main watcher loop and returns nil once ctx is cancelled
+// and the watcher has shut down (see shutdown). Acquires a per-session
+// flock to prevent duplicate watcher processes — stale watchers from
+// previous session starts cause duplicate tmux notifications.
+func (w *Watcher) Run(ctx context.Context) error {
 	busDir := bus.BusDir(w.session)
 
 	// Single-instance enforcement: exit immediately if another watcher is running
@@ -112,23 +144,39 @@ func (w *Watcher) Run() error {
 	fmt.Println("  Agent Bus Watcher")
 	fmt.Printf("  Session: %s\n", w.session)
 	fmt.Printf("  Bus: %s\n", busDir)
-	fmt.Printf("  Trigger: %s\n", w.triggerFile)
+	fmt.Printf("  Edit log: %s\n", w.editLog)
 	fmt.Printf("  Poll: %ds  Debounce: %ds\n", int(w.pollInterval.Seconds()), w.debounceSecs)
 	if len(w.ollamaRoles) > 0 {
-		fmt.Printf("  Ollama monitoring: %s (roles: %s)\n", w.ollamaURL, strings.Join(w.ollamaRoles, ", "))
+		var urls []string
+		for _, n := range w.ollamaNodes {
+			urls = append(urls, n.url)
+		}
+		fmt.Printf("  Ollama monitoring: %s (roles: %s)\n", strings.Join(urls, ", "), strings.Join(w.ollamaRoles, ", "))
 	}
+	if n := len(bus.Config().Routing); n > 0 {
+		fmt.Printf("  File routing: %d rule(s) from muxcode.json\n", n)
+	}
+	if err := bus.UpdateRoutingMarker(w.session); err != nil {
+		fmt.Fprintf(os.Stderr, "  [route] failed to update routing marker: %v\n", err)
+	}
+
+	if disabled := w.disabledChecks(); len(disabled) > 0 {
+		fmt.Printf("  Disabled checks: %s\n", strings.Join(disabled, ", "))
+	}
+	w.startRun()
 	fmt.Println()
 
 	for {
-		w.checkInboxes()
-		w.checkTrigger()
-		w.checkCron()
-		w.checkProcs()
-		w.checkSpawns()
-		w.checkLoops()
-		w.checkCompaction()
-		w.checkOllama()
-		time.Sleep(w.pollInterval)
+		bus.LoadSessionRoles(w.session) // pick up "role add" / "role remove"
+		w.runChecks(ctx, time.Now())
+		w.saveCheckStats(false)
+
+		select {
+		case <-ctx.Done():
+			w.shutdown(context.Cause(ctx))
+			return nil
+		case <-time.After(w.pollInterval):
+		}
 	}
 }
 
@@ -137,13 +185,7 @@ func (w *Watcher) Run() error {
 // re-notifying for messages that were already handled.
 func (w *Watcher) refreshInboxSizes() {
 	for _, role := range bus.KnownRoles {
-		inboxPath := bus.InboxPath(w.session, role)
-		info, err := os.Stat(inboxPath)
-		if err != nil {
-			w.inboxSizes[role] = 0
-			continue
-		}
-		w.inboxSizes[role] = info.Size()
+		w.inboxSizes[role] = bus.InboxSize(w.session, role)
 	}
 }
 
@@ -153,23 +195,12 @@ func (w *Watcher) refreshInboxSizes() {
 // auto-CC'd messages to edit don't need immediate notification since
 // edit will see them on its next inbox read. The watcher's role is to
 // catch messages that arrive without a Notify (e.g. auto-CC).
-func (w *Watcher) checkInboxes() {
+func (w *Watcher) checkInboxes(ctx context.Context) error {
 	for _, role := range bus.KnownRoles {
-		inboxPath := bus.InboxPath(w.session, role)
-		info, err := os.Stat(inboxPath)
-		if err != nil {
-			w.inboxSizes[role] = 0
-			continue
-		}
-
-		size := info.Size()
+		size := bus.InboxSize(w.session, role)
 		prev := w.inboxSizes[role]
 
 		if size > prev && size > 0 {
-			// Notify handles per-role logic: display-message for edit
-			// (non-intrusive status bar flash), skip for harness panes,
-			// send-keys for all others. Dedup is handled inside Notify
-			// via file locking + cooldown.
 			ts := time.Now().Format("15:04:05")
 			fmt.Printf("  %s  New message(s) for %s — notifying\n", ts, role)
 			_ = bus.Notify(w.session, role)
@@ -177,84 +208,126 @@ func (w *Watcher) checkInboxes() {
 
 		w.inboxSizes[role] = size
 	}
+
+	// Deliver debounced and batched notifications that are now due
+	for _, role := range bus.FlushNotifications(w.session) {
+		fmt.Printf("  %s  Deferred notification for %s delivered\n", time.Now().Format("15:04:05"), role)
+	}
+	return nil
 }
 
-// checkTrigger monitors the trigger file for file-edit events with debouncing.
-func (w *Watcher) checkTrigger() {
-	info, err := os.Stat(w.triggerFile)
-	if err != nil || info.Size() == 0 {
+// checkEdits reads new edit events and routes each file once its edits
+// have been quiet for the debounce interval.
+func (w *Watcher) checkEdits(ctx context.Context) error {
+	w.drainEdits(time.Now().Unix() - int64(w.debounceSecs))
+	return nil
+}
+
+// drainEdits reads new edit events and routes each file whose last edit is
+// at or before cutoff. The log is truncated when every edit read from it
+// has been routed.
+func (w *Watcher) drainEdits(cutoff int64) {
+	info, err := os.Stat(w.editLog)
+	if err != nil {
+		return
+	}
+	if info.Size() < w.editOffset {
+		w.editOffset = 0 // truncated by someone else
+	}
+	if info.Size() > w.editOffset {
+		w.readEdits()
+	}
+	if len(w.pendingOrder) == 0 {
 		return
 	}
 
-	size := info.Size()
-	now := time.Now().Unix()
+	var ready []bus.EditEvent
+	var waiting []string
+	for _, path := range w.pendingOrder {
+		events := w.pendingEdits[path]
+		if events[len(events)-1].TS <= cutoff {
+			ready = append(ready, events...)
+			delete(w.pendingEdits, path)
+		} else {
+			waiting = append(waiting, path)
+		}
+	}
+	w.pendingOrder = waiting
+	if len(ready) > 0 {
+		w.routeEdits(ready)
+	}
 
-	if size != w.lastTriggerSize {
-		if w.pendingSince == 0 {
-			ts := time.Now().Format("15:04:05")
-			fmt.Printf("  %s  Claude edits detected, waiting to stabilize...\n", ts)
-		}
-		w.pendingSince = now
-		w.lastTriggerSize = size
-	} else if w.pendingSince > 0 {
-		elapsed := now - w.pendingSince
-		if elapsed >= int64(w.debounceSecs) {
-			w.routeTrigger()
-			// Truncate the trigger file
-			f, err := os.OpenFile(w.triggerFile, os.O_WRONLY|os.O_TRUNC, 0644)
-			if err == nil {
+	if len(w.pendingOrder) == 0 {
+		if info, err := os.Stat(w.editLog); err == nil && info.Size() == w.editOffset {
+			if f, err := os.OpenFile(w.editLog, os.O_WRONLY|os.O_TRUNC, 0644); err == nil {
 				f.Close()
 			}
-			w.pendingSince = 0
-			w.lastTriggerSize = 0
+			w.editOffset = 0
 		}
 	}
 }
 
-// routeTrigger reads the trigger file, extracts unique file paths, and sends
-// an aggregate analyze event. Individual file routing (test/deploy/build) is
-// handled by claude-teach-hook.sh to avoid duplicate messages.
-func (w *Watcher) routeTrigger() {
-	f, err := os.Open(w.triggerFile)
+// readEdits parses complete lines appended to the edit log since the last
+// read into pendingEdits.
+func (w *Watcher) readEdits() {
+	f, err := os.Open(w.editLog)
 	if err != nil {
 		return
 	}
 	defer f.Close()
+	if _, err := f.Seek(w.editOffset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+	// Leave a partially written last line for the next read
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return
+	}
+	w.editOffset += int64(end + 1)
 
-	// Collect unique file paths
-	seen := make(map[string]bool)
-	var files []string
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+	if len(w.pendingOrder) == 0 {
+		fmt.Printf("  %s  Claude edits detected, waiting to stabilize...\n", time.Now().Format("15:04:05"))
+	}
+	now := time.Now().Unix()
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		ev, ok := bus.ParseEditLine(line)
+		if !ok {
 			continue
 		}
-		// Format: "timestamp filepath" — split by first space
-		parts := strings.SplitN(line, " ", 2)
-		var fp string
-		if len(parts) == 2 {
-			fp = strings.TrimSpace(parts[1])
-		} else {
-			fp = parts[0]
+		if ev.TS == 0 || ev.TS > now {
+			ev.TS = now
 		}
-		if fp != "" && !seen[fp] {
-			seen[fp] = true
-			files = append(files, fp)
+		if _, seen := w.pendingEdits[ev.Path]; !seen {
+			w.pendingOrder = append(w.pendingOrder, ev.Path)
 		}
+		w.pendingEdits[ev.Path] = append(w.pendingEdits[ev.Path], ev)
 	}
+}
 
-	if len(files) == 0 {
+// routeEdits sends an aggregate analyze event describing the stabilized
+// edits. When muxcode.json has routing rules, the files are also routed to
+// agents by path and detected project type (see routeFiles); otherwise
+// per-file routing is left to muxcode-analyze-hook.sh.
+func (w *Watcher) routeEdits(events []bus.EditEvent) {
+	summary := bus.SummarizeEdits(events)
+	if len(summary) == 0 {
 		return
 	}
 
 	ts := time.Now().Format("15:04:05")
-	fmt.Printf("  %s  Edits stabilized — routing %d file(s)\n", ts, len(files))
+	fmt.Printf("  %s  Edits stabilized — routing %d file(s)\n", ts, len(summary))
 
 	// Send aggregate event to analyze agent
-	fileList := strings.Join(files, ", ")
-	analyzePayload := fmt.Sprintf("Claude edited files: %s — Read those files and explain what was changed and why.", fileList)
+	var described, files []string
+	for _, f := range summary {
+		described = append(described, f.Describe())
+		files = append(files, f.Path)
+	}
+	analyzePayload := fmt.Sprintf("Claude edited files: %s — Read those files and explain what was changed and why.", strings.Join(described, ", "))
 	msg := bus.NewMessage("watcher", "analyze", "event", "analyze", analyzePayload, "")
 	if err := bus.Send(w.session, msg); err != nil {
 		fmt.Fprintf(os.Stderr, "  [route] failed to send analyze event: %v\n", err)
@@ -266,11 +339,37 @@ func (w *Watcher) routeTrigger() {
 		fmt.Fprintf(os.Stderr, "  [route] failed to notify analyze: %v\n", err)
 	}
 
+	w.routeFiles(files)
+
 	// Refresh inbox sizes so checkInboxes doesn't re-notify for the
 	// message we just sent (prevents double notification).
 	w.refreshInboxSizes()
 }
 
+// routeFiles sends one event per agent for the changed files matched by
+// the configured routing rules.
+func (w *Watcher) routeFiles(files []string) {
+	rules := bus.Config().Routing
+	if len(rules) == 0 {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	for _, g := range bus.RouteChangedFiles(files, rules, cwd) {
+		msg := bus.NewMessage("watcher", g.To, "event", g.Action, g.Message(), "")
+		if err := bus.SendNoCC(w.session, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "  [route] failed to send %s event to %s: %v\n", g.Action, g.To, err)
+			continue
+		}
+		fmt.Printf("  %s  Routed %d file(s) to %s\n", time.Now().Format("15:04:05"), len(g.Files), g.To)
+		if err := bus.Notify(w.session, g.To); err != nil {
+			fmt.Fprintf(os.Stderr, "  [route] failed to notify %s: %v\n", g.To, err)
+		}
+	}
+}
+
 // loadCron reloads cron entries from disk at most once per 10 seconds.
 // Skips loading if the cron file is empty or missing.
 func (w *Watcher) loadCron() {
@@ -297,7 +396,7 @@ func (w *Watcher) loadCron() {
 }
 
 // checkCron iterates cached cron entries, fires due ones, and updates state.
-func (w *Watcher) checkCron() {
+func (w *Watcher) checkCron(ctx context.Context) error {
 	w.loadCron()
 
 	now := time.Now().Unix()
@@ -307,36 +406,62 @@ func (w *Watcher) checkCron() {
 			continue
 		}
 
+		fires, missed := bus.CronMisfire(entry, now)
 		ts := time.Now().Format("15:04:05")
-		fmt.Printf("  %s  Cron firing: %s → %s:%s\n", ts, entry.ID, entry.Target, entry.Action)
+		histEntry := bus.CronHistoryEntry{
+			CronID: entry.ID,
+			TS:     now,
+			Target: entry.Target,
+			Action: entry.Action,
+			Missed: missed,
+		}
+
+		if fires == 0 {
+			fmt.Printf("  %s  Cron skipped %d missed run(s): %s\n", ts, missed, entry.ID)
+			if err := bus.RecordCronRuns(w.session, entry.ID, now, 0); err != nil {
+				fmt.Fprintf(os.Stderr, "  [cron] failed to update last_run for %s: %v\n", entry.ID, err)
+			}
+			if err := bus.AppendCronHistory(w.session, histEntry); err != nil {
+				fmt.Fprintf(os.Stderr, "  [cron] failed to append history for %s: %v\n", entry.ID, err)
+			}
+			w.lastCronLoad = 0
+			continue
+		}
 
-		msgID, err := bus.ExecuteCron(w.session, entry)
-		if err != nil {
+		if missed > 0 {
+			fmt.Printf("  %s  Cron firing: %s → %s:%s (%d missed, firing %d)\n", ts, entry.ID, entry.Target, entry.Action, missed, fires)
+		} else {
+			fmt.Printf("  %s  Cron firing: %s → %s:%s\n", ts, entry.ID, entry.Target, entry.Action)
+		}
+
+		var msgID string
+		var err error
+		for i := 0; i < fires; i++ {
+			if msgID, err = bus.ExecuteCron(w.session, entry); err != nil {
+				break
+			}
+			histEntry.Fired++
+		}
+		if histEntry.Fired == 0 {
 			fmt.Fprintf(os.Stderr, "  [cron] failed to execute %s: %v\n", entry.ID, err)
 			continue
 		}
+		histEntry.MessageID = msgID
 
 		fired = true
 
 		// Update last run timestamp
-		if err := bus.UpdateLastRun(w.session, entry.ID, now); err != nil {
+		if err := bus.RecordCronRuns(w.session, entry.ID, now, histEntry.Fired); err != nil {
 			fmt.Fprintf(os.Stderr, "  [cron] failed to update last_run for %s: %v\n", entry.ID, err)
 		}
 
 		// Append history
-		histEntry := bus.CronHistoryEntry{
-			CronID:    entry.ID,
-			TS:        now,
-			MessageID: msgID,
-			Target:    entry.Target,
-			Action:    entry.Action,
-		}
 		if err := bus.AppendCronHistory(w.session, histEntry); err != nil {
 			fmt.Fprintf(os.Stderr, "  [cron] failed to append history for %s: %v\n", entry.ID, err)
 		}
 
 		// Notify target agent (skip harness panes — they poll directly)
-		if !bus.IsHarnessActive(w.session, entry.Target) {
+		if entry.Target != bus.DigestTarget && !bus.IsHarnessActive(w.session, entry.Target) {
 			if err := bus.Notify(w.session, entry.Target); err != nil {
 				fmt.Fprintf(os.Stderr, "  [cron] failed to notify %s: %v\n", entry.Target, err)
 			}
@@ -349,11 +474,12 @@ func (w *Watcher) checkCron() {
 		// Force cron reload on next cycle so updated last_run_ts values are picked up
 		w.lastCronLoad = 0
 	}
+	return nil
 }
 
 // checkProcs polls running background processes and notifies owners on completion.
 // Skips entirely if proc file is empty/missing and no running procs are tracked.
-func (w *Watcher) checkProcs() {
+func (w *Watcher) checkProcs(ctx context.Context) error {
 	// Skip if proc file is empty/missing and no running procs cached
 	info, err := os.Stat(bus.ProcPath(w.session))
 	currentSize := int64(0)
@@ -361,7 +487,7 @@ func (w *Watcher) checkProcs() {
 		currentSize = info.Size()
 	}
 	if currentSize == 0 && !w.hasRunningProcs {
-		return
+		return nil
 	}
 	// Reset running flag if file size changed (new proc may have been added)
 	if currentSize != w.lastProcSize {
@@ -371,8 +497,7 @@ func (w *Watcher) checkProcs() {
 
 	completed, err := bus.RefreshProcStatus(w.session)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "  [proc] failed to refresh proc status: %v\n", err)
-		return
+		return fmt.Errorf("failed to refresh proc status: %w", err)
 	}
 
 	// Update running state: check if any procs are still running
@@ -387,7 +512,7 @@ func (w *Watcher) checkProcs() {
 	w.hasRunningProcs = hasRunning
 
 	if len(completed) == 0 {
-		return
+		return nil
 	}
 
 	for _, entry := range completed {
@@ -397,6 +522,9 @@ func (w *Watcher) checkProcs() {
 
 		payload := fmt.Sprintf("Background process completed: %s\n  Command: %s\n  Status: %s  Exit code: %d\n  Log: %s",
 			entry.ID, entry.Command, entry.Status, entry.ExitCode, entry.LogFile)
+		if entry.RestartedAs != "" {
+			payload += fmt.Sprintf("\n  Restarted as %s (%s, restart %d)", entry.RestartedAs, entry.Restart, entry.Restarts+1)
+		}
 
 		msg := bus.NewMessage("proc", entry.Owner, "event", "proc-complete", payload, "")
 		if err := bus.Send(w.session, msg); err != nil {
@@ -404,12 +532,8 @@ func (w *Watcher) checkProcs() {
 			continue
 		}
 
-		// Skip Notify for edit — tmux send-keys disrupts Claude Code input buffer
-		// Skip harness panes — they poll inbox directly
-		if entry.Owner != "edit" && !bus.IsHarnessActive(w.session, entry.Owner) {
-			if err := bus.Notify(w.session, entry.Owner); err != nil {
-				fmt.Fprintf(os.Stderr, "  [proc] failed to notify %s: %v\n", entry.Owner, err)
-			}
+		if err := bus.Notify(w.session, entry.Owner); err != nil {
+			fmt.Fprintf(os.Stderr, "  [proc] failed to notify %s: %v\n", entry.Owner, err)
 		}
 
 		// Mark as notified
@@ -419,11 +543,13 @@ func (w *Watcher) checkProcs() {
 	}
 
 	w.refreshInboxSizes()
+	return nil
 }
 
-// checkSpawns polls running spawned agents and notifies owners on completion.
+// checkSpawns polls running spawned agents, notifies owners on completion,
+// and launches queued spawns as quota slots free.
 // Skips entirely if spawn file is empty/missing and no running spawns are tracked.
-func (w *Watcher) checkSpawns() {
+func (w *Watcher) checkSpawns(ctx context.Context) error {
 	// Skip if spawn file is empty/missing and no running spawns cached
 	info, err := os.Stat(bus.SpawnPath(w.session))
 	currentSize := int64(0)
@@ -431,7 +557,7 @@ func (w *Watcher) checkSpawns() {
 		currentSize = info.Size()
 	}
 	if currentSize == 0 && !w.hasRunningSpawns {
-		return
+		return nil
 	}
 	// Reset running flag if file size changed (new spawn may have been added)
 	if currentSize != w.lastSpawnSize {
@@ -441,23 +567,39 @@ func (w *Watcher) checkSpawns() {
 
 	completed, err := bus.RefreshSpawnStatus(w.session)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "  [spawn] failed to refresh spawn status: %v\n", err)
-		return
+		return fmt.Errorf("failed to refresh spawn status: %w", err)
 	}
 
-	// Update running state: check if any spawns are still running
+	groups := make(map[string]bool)
+	launched, err := bus.LaunchQueuedSpawns(w.session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  [spawn] failed to launch queued spawns: %v\n", err)
+	}
+	for _, entry := range launched {
+		ts := time.Now().Format("15:04:05")
+		if entry.Status == "failed" {
+			fmt.Fprintf(os.Stderr, "  [spawn] %s  queued spawn %s failed to launch\n", ts, entry.ID)
+			if entry.Group != "" {
+				groups[entry.Group] = true
+			}
+			continue
+		}
+		fmt.Printf("  %s  Queued spawn launched: %s (role: %s)\n", ts, entry.ID, entry.Role)
+	}
+
+	// Update running state: check if any spawns are still running or queued
 	entries, _ := bus.ReadSpawnEntries(w.session)
 	hasRunning := false
 	for _, e := range entries {
-		if e.Status == "running" {
+		if e.Status == "running" || e.Status == "queued" {
 			hasRunning = true
 			break
 		}
 	}
 	w.hasRunningSpawns = hasRunning
 
-	if len(completed) == 0 {
-		return
+	if len(completed) == 0 && len(groups) == 0 {
+		return nil
 	}
 
 	for _, entry := range completed {
@@ -465,9 +607,19 @@ func (w *Watcher) checkSpawns() {
 		fmt.Printf("  %s  Spawn completed: %s (role: %s, window: %s)\n",
 			ts, entry.ID, entry.Role, entry.Window)
 
+		// Fanout members report once per group, below
+		if entry.Group != "" {
+			groups[entry.Group] = true
+			continue
+		}
+
 		// Try to extract the last result message from the spawn
 		resultInfo := "No result message found."
+		parentSpan := entry.ParentSpan
 		if result, ok := bus.GetSpawnResult(w.session, entry.SpawnRole); ok {
+			if result.SpanID != "" {
+				parentSpan = result.SpanID
+			}
 			resultInfo = result.Payload
 			if len(resultInfo) > 200 {
 				resultInfo = resultInfo[:200] + "..."
@@ -478,17 +630,16 @@ func (w *Watcher) checkSpawns() {
 			entry.ID, entry.Role, entry.SpawnRole, entry.Task, resultInfo)
 
 		msg := bus.NewMessage("spawn", entry.Owner, "event", "spawn-complete", payload, "")
+		if entry.TraceID != "" {
+			msg.TraceID, msg.ParentSpan = entry.TraceID, parentSpan
+		}
 		if err := bus.Send(w.session, msg); err != nil {
 			fmt.Fprintf(os.Stderr, "  [spawn] failed to send completion event to %s: %v\n", entry.Owner, err)
 			continue
 		}
 
-		// Skip Notify for edit — tmux send-keys disrupts Claude Code input buffer
-		// Skip harness panes — they poll inbox directly
-		if entry.Owner != "edit" && !bus.IsHarnessActive(w.session, entry.Owner) {
-			if err := bus.Notify(w.session, entry.Owner); err != nil {
-				fmt.Fprintf(os.Stderr, "  [spawn] failed to notify %s: %v\n", entry.Owner, err)
-			}
+		if err := bus.Notify(w.session, entry.Owner); err != nil {
+			fmt.Fprintf(os.Stderr, "  [spawn] failed to notify %s: %v\n", entry.Owner, err)
 		}
 
 		// Mark as notified
@@ -497,21 +648,47 @@ func (w *Watcher) checkSpawns() {
 		})
 	}
 
+	for group := range groups {
+		w.notifySpawnGroup(entries, group)
+	}
+
 	w.refreshInboxSizes()
+	return nil
 }
 
-// checkLoops runs loop detection every 60 seconds and sends alerts to the edit agent.
-// Deduplicates alerts within a 10-minute cooldown to avoid spamming.
-func (w *Watcher) checkLoops() {
-	now := time.Now().Unix()
-	if now-w.lastLoopCheck < 60 {
+// notifySpawnGroup sends one aggregated spawn-group-complete event to the
+// owner once every spawn in a fanout group has finished.
+func (w *Watcher) notifySpawnGroup(entries []bus.SpawnEntry, group string) {
+	members := bus.SpawnGroupEntries(entries, group)
+	if !bus.SpawnGroupDone(members) {
 		return
 	}
-	w.lastLoopCheck = now
+	owner := members[0].Owner
+	fmt.Printf("  %s  Spawn group completed: %s (%d spawns)\n",
+		time.Now().Format("15:04:05"), group, len(members))
 
+	payload := bus.FormatSpawnGroupReport(w.session, group, members, bus.SpawnGroupResultLimit)
+	msg := bus.NewMessage("spawn", owner, "event", bus.SpawnGroupCompleteAction, payload, "")
+	if err := bus.Send(w.session, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "  [spawn] failed to send group completion to %s: %v\n", owner, err)
+		return
+	}
+	if err := bus.Notify(w.session, owner); err != nil {
+		fmt.Fprintf(os.Stderr, "  [spawn] failed to notify %s: %v\n", owner, err)
+	}
+	for _, m := range members {
+		_ = bus.UpdateSpawnEntry(w.session, m.ID, func(e *bus.SpawnEntry) {
+			e.Notified = true
+		})
+	}
+}
+
+// checkLoops runs loop detection and sends alerts to the edit agent.
+// Deduplicates alerts within a 10-minute cooldown to avoid spamming.
+func (w *Watcher) checkLoops(ctx context.Context) error {
 	alerts := bus.CheckAllLoops(w.session)
 	if len(alerts) == 0 {
-		return
+		return nil
 	}
 
 	// Filter out alerts that were already sent within the cooldown window.
@@ -519,45 +696,45 @@ func (w *Watcher) checkLoops() {
 	// loop-detected events from sustaining their own detection window.
 	fresh := bus.FilterNewAlerts(alerts, w.lastAlertKey, 600)
 	if len(fresh) == 0 {
-		return
+		return nil
 	}
 
 	for _, alert := range fresh {
 		ts := time.Now().Format("15:04:05")
 		fmt.Printf("  %s  Loop detected: %s (%s)\n", ts, alert.Role, alert.Type)
 
-		msg := bus.NewMessage("watcher", "edit", "event", "loop-detected", alert.Message, "")
+		payload := alert.Message
+		if results := bus.Remediate(w.session, alert); len(results) > 0 {
+			summary := bus.FormatRemediation(results)
+			fmt.Printf("  %s  Remediation: %s\n", ts, summary)
+			payload += " — remediation: " + summary
+		}
+
+		msg := bus.NewMessage("watcher", "edit", "event", "loop-detected", payload, "")
 		if err := bus.Send(w.session, msg); err != nil {
 			fmt.Fprintf(os.Stderr, "  [guard] failed to send loop alert: %v\n", err)
 			continue
 		}
-		// Skip Notify for edit — same pattern as checkInboxes(). Edit reads
-		// its inbox frequently; injecting tmux send-keys while Claude Code
-		// is mid-turn causes text to get stuck in the input buffer.
+		_ = bus.Notify(w.session, "edit")
 	}
 
 	w.refreshInboxSizes()
+	return nil
 }
 
-// checkCompaction runs compaction checks every 120 seconds and sends recommendations
-// to the role itself. Deduplicates alerts within a 10-minute cooldown.
-func (w *Watcher) checkCompaction() {
-	now := time.Now().Unix()
-	if now-w.lastCompactCheck < 120 {
-		return
-	}
-	w.lastCompactCheck = now
-
+// checkCompaction runs compaction checks and sends recommendations to the
+// role itself. Deduplicates alerts within a 10-minute cooldown.
+func (w *Watcher) checkCompaction(ctx context.Context) error {
 	th := bus.DefaultCompactThresholds()
 	alerts := bus.CheckCompaction(w.session, th)
 	if len(alerts) == 0 {
-		return
+		return nil
 	}
 
 	// Filter out alerts that were already sent within the cooldown window (600s = 10 min)
 	fresh := bus.FilterNewCompactAlerts(alerts, w.lastAlertKey, 600)
 	if len(fresh) == 0 {
-		return
+		return nil
 	}
 
 	for _, alert := range fresh {
@@ -569,49 +746,395 @@ func (w *Watcher) checkCompaction() {
 			fmt.Fprintf(os.Stderr, "  [compact] failed to send compact alert to %s: %v\n", alert.Role, err)
 			continue
 		}
-		// Skip Notify for edit — tmux send-keys disrupts Claude Code input buffer
-		// Skip harness panes — they poll inbox directly
-		if alert.Role != "edit" && !bus.IsHarnessActive(w.session, alert.Role) {
-			if err := bus.Notify(w.session, alert.Role); err != nil {
-				fmt.Fprintf(os.Stderr, "  [compact] failed to notify %s: %v\n", alert.Role, err)
+		if err := bus.Notify(w.session, alert.Role); err != nil {
+			fmt.Fprintf(os.Stderr, "  [compact] failed to notify %s: %v\n", alert.Role, err)
+		}
+	}
+
+	w.refreshInboxSizes()
+	return nil
+}
+
+// checkSLA evaluates configured SLAs and sends an sla-breach event to edit
+// for each newly breached request.
+func (w *Watcher) checkSLA(ctx context.Context) error {
+	if len(bus.Config().SLA) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+
+	sent := false
+	for _, breach := range bus.CheckSLABreaches(w.session, w.slaSince) {
+		key := bus.SLABreachKey(breach)
+		if _, ok := w.lastAlertKey[key]; ok {
+			continue
+		}
+		w.lastAlertKey[key] = now
+
+		ts := time.Now().Format("15:04:05")
+		fmt.Printf("  %s  SLA breach: %s (%s)\n", ts, breach.Rule, breach.RequestID)
+
+		msg := bus.NewMessage("watcher", "edit", "event", "sla-breach", bus.FormatSLABreach(breach), "")
+		if err := bus.Send(w.session, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "  [sla] failed to send breach event: %v\n", err)
+			continue
+		}
+		sent = true
+		_ = bus.Notify(w.session, "edit")
+	}
+
+	if sent {
+		w.refreshInboxSizes()
+	}
+	return nil
+}
+
+// checkIdleTasks dispatches queued low-priority tasks to idle agents.
+// Skips entirely if the task file is empty or missing.
+func (w *Watcher) checkIdleTasks(ctx context.Context) error {
+	info, err := os.Stat(bus.TaskPath(w.session))
+	if err != nil || info.Size() == 0 {
+		return nil
+	}
+
+	dispatched, dispatchErr := bus.DispatchIdleTasks(w.session)
+	for _, t := range dispatched {
+		ts := time.Now().Format("15:04:05")
+		fmt.Printf("  %s  Idle task: %s → %s:%s\n", ts, t.ID, t.DispatchedTo, t.Action)
+
+		// Notify target agent (skip harness panes — they poll directly)
+		if !bus.IsHarnessActive(w.session, t.DispatchedTo) {
+			if err := bus.Notify(w.session, t.DispatchedTo); err != nil {
+				fmt.Fprintf(os.Stderr, "  [task] failed to notify %s: %v\n", t.DispatchedTo, err)
+			}
+		}
+	}
+
+	if len(dispatched) > 0 {
+		w.refreshInboxSizes()
+	}
+	return dispatchErr
+}
+
+// checkBudget compares each role's hourly harness usage to its budget,
+// sends budget-exceeded events to edit, and pauses the harness when
+// guard.budget_pause is set.
+func (w *Watcher) checkBudget(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	alerts := bus.FilterNewAlerts(bus.CheckAllBudgets(w.session), w.lastAlertKey, 600)
+	for _, alert := range alerts {
+		ts := time.Now().Format("15:04:05")
+		fmt.Printf("  %s  Budget exceeded: %s\n", ts, alert.Message)
+
+		payload := alert.Message
+		if bus.Config().Guard.BudgetPause {
+			// Pause until the oldest call in the window ages out
+			until := now - alert.Window + 3600
+			if err := bus.PauseHarness(w.session, alert.Role, until); err != nil {
+				fmt.Fprintf(os.Stderr, "  [budget] failed to pause %s: %v\n", alert.Role, err)
+			} else {
+				payload += fmt.Sprintf(" — harness paused until %s", time.Unix(until, 0).Format("15:04"))
 			}
 		}
+		if results := bus.Remediate(w.session, alert); len(results) > 0 {
+			payload += " — remediation: " + bus.FormatRemediation(results)
+		}
+
+		msg := bus.NewMessage("watcher", "edit", "event", bus.BudgetExceededAction, payload, "")
+		if err := bus.Send(w.session, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "  [budget] failed to send budget alert: %v\n", err)
+		}
 	}
+	if len(alerts) > 0 {
+		w.refreshInboxSizes()
+	}
+	return nil
+}
 
+// checkArchive moves expired and overflow inbox messages, and the oldest
+// log entries past the cap, into inbox-archive/.
+func (w *Watcher) checkArchive(ctx context.Context) error {
+	now := time.Now()
+	res, err := bus.CompactInboxes(w.session, now)
+	if err != nil {
+		err = fmt.Errorf("inbox compaction failed: %w", err)
+	}
+	if res.Total() == 0 {
+		return err
+	}
+	fmt.Printf("  %s  Archived %d message(s): %s\n", now.Format("15:04:05"), res.Total(), bus.FormatArchiveResult(res))
 	w.refreshInboxSizes()
+	return err
+}
+
+// checkTraces exports newly recorded spans to the OTLP collector when
+// MUXCODE_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_ENDPOINT) is set. A failed
+// export is retried from the same offset after 60 seconds.
+func (w *Watcher) checkTraces(ctx context.Context) error {
+	endpoint := bus.OTLPEndpoint()
+	now := time.Now().Unix()
+	if endpoint == "" || now < w.traceRetryAt {
+		return nil
+	}
+
+	offset, _, err := bus.ExportNewSpans(w.session, endpoint, w.traceOffset)
+	if err != nil {
+		w.traceRetryAt = now + 60
+		return fmt.Errorf("OTLP export failed: %w", err)
+	}
+	w.traceOffset = offset
+	return nil
+}
+
+// checkCallbacks POSTs callback URLs whose requests have a result, and
+// expiry notices for those that waited too long (see bus.DeliverCallbacks).
+func (w *Watcher) checkCallbacks(ctx context.Context) error {
+	finished, err := bus.DeliverCallbacks(w.session, time.Now())
+	for _, c := range finished {
+		fmt.Printf("  %s  Callback for %s %s\n", time.Now().Format("15:04:05"), c.ID, c.Status)
+	}
+	return err
+}
+
+// checkWorkflows moves workflow runs on to their next step once the
+// current step has its reply or timed out (see bus.AdvanceWorkflows).
+func (w *Watcher) checkWorkflows(ctx context.Context) error {
+	finished, err := bus.AdvanceWorkflows(w.session, time.Now())
+	for _, r := range finished {
+		fmt.Printf("  %s  Workflow %s %s\n", time.Now().Format("15:04:05"), r.ID, r.Status)
+	}
+	return err
+}
+
+// checkApprovals escalates and expires approval gates.
+func (w *Watcher) checkApprovals(ctx context.Context) error {
+	changed, err := bus.CheckApprovals(w.session, time.Now())
+	for _, a := range changed {
+		what := "expired"
+		if a.Status == bus.ApprovalPending {
+			what = "escalated to " + a.EscalateTo
+		}
+		fmt.Printf("  %s  Approval %s %s\n", time.Now().Format("15:04:05"), a.ID, what)
+	}
+	return err
+}
+
+// checkChainRetries sends chain retry requests whose backoff has passed.
+func (w *Watcher) checkChainRetries(ctx context.Context) error {
+	sent, err := bus.SendDueChainRetries(w.session, time.Now())
+	for _, r := range sent {
+		fmt.Printf("  %s  Chain retry %d for %s %s\n", time.Now().Format("15:04:05"), r.Attempts, r.From, r.Event)
+	}
+	return err
+}
+
+// checkFlaky records flaky test suspects and alerts edit about new ones.
+func (w *Watcher) checkFlaky(ctx context.Context) error {
+	fresh, err := bus.TrackFlakySuspects(w.session, time.Now())
+	for _, s := range fresh {
+		fmt.Printf("  %s  Flaky test suspect: %s (%d/%d failed)\n", time.Now().Format("15:04:05"), s.Command, s.Failures, s.Runs)
+	}
+	return err
+}
+
+// checkPRThreads resolves on GitHub the review threads whose pr-fix
+// requests got a reply (see bus.ResolveFixedPRThreads).
+func (w *Watcher) checkPRThreads(ctx context.Context) error {
+	resolved, err := bus.ResolveFixedPRThreads(w.session, time.Now())
+	for _, t := range resolved {
+		fmt.Printf("  %s  Resolved PR #%d thread on %s\n", time.Now().Format("15:04:05"), t.PR, t.Location())
+	}
+	return err
+}
+
+// checkIntake takes in new issues once the intake interval has passed, and
+// posts completion comments on issues reported done (see bus.PollIntake).
+func (w *Watcher) checkIntake(ctx context.Context) error {
+	reported, rerr := bus.ReportIntake(w.session, time.Now())
+	for _, i := range reported {
+		fmt.Printf("  %s  Commented on %s %s\n", time.Now().Format("15:04:05"), i.Source, i.Key)
+	}
+	taken, err := bus.PollIntake(w.session, time.Now(), false, false)
+	for _, i := range taken {
+		fmt.Printf("  %s  Intake %s %s -> %s\n", time.Now().Format("15:04:05"), i.Source, i.Key, i.To)
+	}
+	if err != nil {
+		return err
+	}
+	return rerr
 }
 
-// checkOllama runs Ollama health probes every 30 seconds for roles using local LLM.
-// Detection timeline: 30s first probe, 60s alert, 90s restart attempt.
-// Caps automatic restarts at 3 to prevent restart loops.
-func (w *Watcher) checkOllama() {
+// checkOllama runs Ollama health probes for roles using local LLM. At the
+// default 30s interval the detection timeline is: 30s first probe, 60s
+// alert, 90s restart attempt.
+// Caps automatic restarts at 3 to prevent restart loops. Each node is
+// probed and alerted on separately; only local nodes are restarted.
+func (w *Watcher) checkOllama(ctx context.Context) error {
 	if len(w.ollamaRoles) == 0 {
-		return
+		return nil
 	}
 
 	now := time.Now().Unix()
-	if now-w.lastOllamaCheck < 30 {
+
+	// Agent failure sentinels mean every node failed the agent's request
+	hasSentinels := bus.HasOllamaFailSentinel(w.session)
+	for _, n := range w.ollamaNodes {
+		if ctx.Err() != nil {
+			break
+		}
+		w.checkOllamaNode(ctx, n, hasSentinels, now)
+	}
+	return nil
+}
+
+// checkHeartbeats reports agents whose process exited or whose heartbeat
+// went stale with an agent-down event to edit, relaunching the pane when
+// heartbeat.relaunch is set, and reports their recovery with agent-up.
+// Roles in heartbeat.failover that stay down are replaced by a spawned
+// agent (failover-start) and handed back on recovery (failover-end).
+func (w *Watcher) checkHeartbeats(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	cfg := bus.Config().Heartbeat
+	ts := time.Now().Format("15:04:05")
+	for _, st := range bus.CheckHeartbeats(w.session, now, cfg.Stale()) {
+		if !st.Down {
+			if _, ok := w.downRoles[st.Role]; ok {
+				delete(w.downRoles, st.Role)
+				fmt.Printf("  %s  Agent %s is back up\n", ts, st.Role)
+				w.sendHeartbeatEvent("agent-up", fmt.Sprintf("Agent %s is back up (pid %d).", st.Role, st.PID))
+			}
+			w.endFailover(st.Role)
+			continue
+		}
+		if since, ok := w.downRoles[st.Role]; ok {
+			if cfg.FailsOver(st.Role) && now-since >= cfg.FailoverAfter() {
+				w.startFailover(st.Role, now-since)
+			}
+			continue
+		}
+		w.downRoles[st.Role] = now
+		fmt.Printf("  %s  Agent %s is down: %s\n", ts, st.Role, st.Reason)
+
+		relaunch := ""
+		if cfg.Relaunch {
+			if n := w.relaunches[st.Role]; n >= cfg.RelaunchLimit() {
+				relaunch = fmt.Sprintf("Not relaunching: %d relaunches already.", n)
+			} else if cwd, err := os.Getwd(); err != nil {
+				relaunch = fmt.Sprintf("Relaunch failed: %v.", err)
+			} else if err := bus.RestartRole(w.session, st.Role, cwd); err != nil {
+				relaunch = fmt.Sprintf("Relaunch failed: %v.", err)
+			} else {
+				w.relaunches[st.Role] = n + 1
+				relaunch = fmt.Sprintf("Relaunched its pane (%d/%d).", n+1, cfg.RelaunchLimit())
+				fmt.Printf("  %s  Relaunched %s (%d/%d)\n", ts, st.Role, n+1, cfg.RelaunchLimit())
+			}
+		}
+		w.sendHeartbeatEvent("agent-down", bus.AgentDownMessage(w.session, st, relaunch))
+	}
+	return nil
+}
+
+// startFailover replaces a role that has been down for secs seconds with a
+// spawned agent, unless it is already failed over.
+func (w *Watcher) startFailover(role string, secs int64) {
+	if _, ok := bus.FailoverTarget(w.session, role); ok {
+		return
+	}
+	ts := time.Now().Format("15:04:05")
+	fo, err := bus.StartFailover(w.session, role)
+	if fo.SpawnRole == "" {
+		fmt.Fprintf(os.Stderr, "  [heartbeat] failover of %s failed: %v\n", role, err)
 		return
 	}
-	w.lastOllamaCheck = now
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  [heartbeat] failover of %s: %v\n", role, err)
+	}
+	fmt.Printf("  %s  Failed over %s to %s\n", ts, role, fo.SpawnRole)
+	w.sendHeartbeatEvent("failover-start", fmt.Sprintf(
+		"Agent %s has been down for %ds; %s is standing in for it. Moved %d pending message(s); new messages to %s go to %s until it recovers.",
+		role, secs, fo.SpawnRole, fo.Moved, role, fo.SpawnRole))
+}
 
-	// Run inference probe
-	err := bus.CheckOllamaInference(w.ollamaURL, w.ollamaModel, bus.OllamaProbeTimeout)
+// endFailover hands a recovered role back from its replacement, if any.
+func (w *Watcher) endFailover(role string) {
+	target, ok := bus.FailoverTarget(w.session, role)
+	if !ok {
+		return
+	}
+	n, err := bus.EndFailover(w.session, role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  [heartbeat] hand-back of %s: %v\n", role, err)
+	}
+	fmt.Printf("  %s  Handed %s back from %s\n", time.Now().Format("15:04:05"), role, target)
+	w.sendHeartbeatEvent("failover-end", fmt.Sprintf(
+		"Agent %s recovered; stopped %s and handed back %d unread message(s).", role, target, n))
+}
 
-	// Also check for agent failure sentinels
-	hasSentinels := bus.HasOllamaFailSentinel(w.session)
+// sendHeartbeatEvent sends an agent liveness event to edit.
+func (w *Watcher) sendHeartbeatEvent(action, payload string) {
+	msg := bus.NewMessage("watcher", "edit", "event", action, payload, "")
+	if err := bus.Send(w.session, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "  [heartbeat] failed to send %s event: %v\n", action, err)
+		return
+	}
+	w.refreshInboxSizes()
+}
+
+// checkResourcePressure alerts edit when a healthy node's loaded models
+// exceed the ollama VRAM thresholds, deduped per node for 600s.
+func (w *Watcher) checkResourcePressure(n *ollamaNode, now int64) {
+	p, err := bus.CheckResourcePressure(n.url, bus.Config().Ollama, bus.OllamaProbeTimeout)
+	if err != nil || p == nil {
+		return
+	}
+	alertKey := bus.ResourcePressureAction + ":" + n.url
+	if lastTS, ok := w.lastAlertKey[alertKey]; ok && (now-lastTS) < 600 {
+		return
+	}
+	w.lastAlertKey[alertKey] = now
+
+	ts := time.Now().Format("15:04:05")
+	fmt.Printf("  %s  Resource pressure on %s: %s\n", ts, n.url, strings.Join(p.Reasons, "; "))
+	msg := bus.NewMessage("watcher", "edit", "event", bus.ResourcePressureAction, bus.FormatResourcePressure(p), "")
+	if sendErr := bus.Send(w.session, msg); sendErr != nil {
+		fmt.Fprintf(os.Stderr, "  [ollama] failed to send resource-pressure alert: %v\n", sendErr)
+	}
+	w.refreshInboxSizes()
+}
+
+// checkOllamaNode probes one node and raises down, restarting, and
+// recovered alerts for it.
+func (w *Watcher) checkOllamaNode(ctx context.Context, n *ollamaNode, hasSentinels bool, now int64) {
+	multi := len(w.ollamaNodes) > 1
+	label, alertSuffix := "Ollama", ""
+	if multi {
+		label, alertSuffix = "Ollama node "+n.url, ":"+n.url
+	}
+	alertText := func(text string) string {
+		if multi {
+			return fmt.Sprintf("Node %s: %s", n.url, text)
+		}
+		return text
+	}
+
+	// Run inference probe
+	err := bus.CheckOllamaInference(n.url, n.model, bus.OllamaProbeTimeout)
 
 	ts := time.Now().Format("15:04:05")
 
 	if err == nil && !hasSentinels {
 		// Healthy
-		if w.ollamaWasDown {
+		w.checkResourcePressure(n, now)
+		if n.wasDown {
 			// Recovery detected
-			fmt.Printf("  %s  Ollama recovered — inference probe healthy\n", ts)
-			w.ollamaWasDown = false
-			w.ollamaFailCount = 0
+			fmt.Printf("  %s  %s recovered — inference probe healthy\n", ts, label)
+			n.wasDown = false
+			n.failCount = 0
 
-			alert := bus.FormatOllamaAlert("recovered", w.ollamaRoles, "Ollama is responsive again")
+			alert := bus.FormatOllamaAlert("recovered", n.roles, alertText("Ollama is responsive again"))
 			msg := bus.NewMessage("watcher", "edit", "event", "ollama-recovered", alert, "")
 			if sendErr := bus.Send(w.session, msg); sendErr != nil {
 				fmt.Fprintf(os.Stderr, "  [ollama] failed to send recovery alert: %v\n", sendErr)
@@ -622,7 +1145,7 @@ func (w *Watcher) checkOllama() {
 	}
 
 	// Unhealthy
-	w.ollamaFailCount++
+	n.failCount++
 	errMsg := ""
 	if err != nil {
 		errMsg = err.Error()
@@ -635,17 +1158,17 @@ func (w *Watcher) checkOllama() {
 		}
 	}
 
-	fmt.Printf("  %s  Ollama probe failure #%d: %s\n", ts, w.ollamaFailCount, errMsg)
+	fmt.Printf("  %s  %s probe failure #%d: %s\n", ts, label, n.failCount, errMsg)
 
 	// Second consecutive failure (60s) — send ollama-down alert
-	if w.ollamaFailCount == 2 && !w.ollamaWasDown {
-		w.ollamaWasDown = true
+	if n.failCount == 2 && !n.wasDown {
+		n.wasDown = true
 
 		// Dedup via lastAlertKey with 600s cooldown
-		alertKey := bus.OllamaHealthAlertKey("down")
+		alertKey := bus.OllamaHealthAlertKey("down" + alertSuffix)
 		if lastTS, ok := w.lastAlertKey[alertKey]; !ok || (now-lastTS) >= 600 {
 			w.lastAlertKey[alertKey] = now
-			alert := bus.FormatOllamaAlert("down", w.ollamaRoles, errMsg)
+			alert := bus.FormatOllamaAlert("down", n.roles, alertText(errMsg))
 			msg := bus.NewMessage("watcher", "edit", "event", "ollama-down", alert, "")
 			if sendErr := bus.Send(w.session, msg); sendErr != nil {
 				fmt.Fprintf(os.Stderr, "  [ollama] failed to send down alert: %v\n", sendErr)
@@ -654,15 +1177,16 @@ func (w *Watcher) checkOllama() {
 		}
 	}
 
-	// Third consecutive failure (90s) — attempt restart
-	if w.ollamaFailCount == 3 {
-		if w.ollamaRestarts >= 3 {
+	// Third consecutive failure (90s) — attempt restart. Remote nodes are
+	// left to their owners; agents fail over to the other nodes.
+	if n.failCount == 3 && bus.IsLocalOllamaURL(n.url) {
+		if n.restarts >= 3 {
 			// Cap reached — periodic alerts only
-			alertKey := bus.OllamaHealthAlertKey("down")
+			alertKey := bus.OllamaHealthAlertKey("down" + alertSuffix)
 			if lastTS, ok := w.lastAlertKey[alertKey]; !ok || (now-lastTS) >= 600 {
 				w.lastAlertKey[alertKey] = now
-				alert := bus.FormatOllamaAlert("down", w.ollamaRoles,
-					fmt.Sprintf("Restart cap (3) reached. %s. Manual intervention required.", errMsg))
+				alert := bus.FormatOllamaAlert("down", n.roles,
+					alertText(fmt.Sprintf("Restart cap (3) reached. %s. Manual intervention required.", errMsg)))
 				msg := bus.NewMessage("watcher", "edit", "event", "ollama-down", alert, "")
 				_ = bus.Send(w.session, msg)
 				w.refreshInboxSizes()
@@ -670,19 +1194,19 @@ func (w *Watcher) checkOllama() {
 			return
 		}
 
-		fmt.Printf("  %s  Attempting Ollama restart (#%d)...\n", ts, w.ollamaRestarts+1)
-		w.ollamaRestarts++
+		fmt.Printf("  %s  Attempting %s restart (#%d)...\n", ts, label, n.restarts+1)
+		n.restarts++
 
 		// Send restarting alert
-		alert := bus.FormatOllamaAlert("restarting", w.ollamaRoles,
-			fmt.Sprintf("Attempt %d/3 — killing and restarting ollama serve", w.ollamaRestarts))
+		alert := bus.FormatOllamaAlert("restarting", n.roles,
+			alertText(fmt.Sprintf("Attempt %d/3 — killing and restarting ollama serve", n.restarts)))
 		msg := bus.NewMessage("watcher", "edit", "event", "ollama-restarting", alert, "")
 		_ = bus.Send(w.session, msg)
 		w.refreshInboxSizes()
 
-		// Attempt restart with 30s timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		restartErr := bus.RestartOllama(ctx, w.ollamaURL)
+		// Attempt restart with 30s timeout; shutdown cancels it
+		restartCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		restartErr := bus.RestartOllama(restartCtx, n.url)
 		cancel()
 
 		if restartErr != nil {
@@ -690,10 +1214,10 @@ func (w *Watcher) checkOllama() {
 			return
 		}
 
-		fmt.Printf("  %s  Ollama restarted successfully, relaunching agents...\n", ts)
+		fmt.Printf("  %s  %s restarted successfully, relaunching agents...\n", ts, label)
 
 		// Relaunch affected agents
-		for _, role := range w.ollamaRoles {
+		for _, role := range n.roles {
 			if restartErr := bus.RestartLocalAgent(w.session, role); restartErr != nil {
 				fmt.Fprintf(os.Stderr, "  [ollama] failed to restart agent %s: %v\n", role, restartErr)
 			} else {
@@ -702,7 +1226,7 @@ func (w *Watcher) checkOllama() {
 		}
 
 		// Reset fail count to let the next probe cycle detect recovery
-		w.ollamaFailCount = 0
+		n.failCount = 0
 	}
 }
 
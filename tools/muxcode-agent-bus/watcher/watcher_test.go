@@ -1,41 +1,16 @@
 package watcher
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mkober/muxcode/tools/muxcode-agent-bus/bus"
 )
 
-func TestCheckLoops_60sInterval(t *testing.T) {
-	session := testSession(t)
-	w := New(session, 5, 8)
-
-	// lastLoopCheck is initialized to now in New()
-	now := time.Now().Unix()
-
-	// Immediately after creation, checkLoops should be a no-op (within 60s)
-	if now-w.lastLoopCheck >= 60 {
-		t.Fatal("expected lastLoopCheck to be recent after New()")
-	}
-
-	// Simulate 30s passing — should still skip (was 30s before, now 60s)
-	w.lastLoopCheck = now - 30
-	beforeCheck := w.lastLoopCheck
-	w.checkLoops()
-	if w.lastLoopCheck != beforeCheck {
-		t.Error("checkLoops should have skipped at 30s interval (now requires 60s)")
-	}
-
-	// Simulate 60s passing — should run
-	w.lastLoopCheck = now - 61
-	w.checkLoops()
-	if w.lastLoopCheck <= now-61 {
-		t.Error("checkLoops should have updated lastLoopCheck after 60s interval")
-	}
-}
-
 func TestCheckCron_SkipsEmptyFile(t *testing.T) {
 	session := testSession(t)
 	w := New(session, 5, 8)
@@ -71,7 +46,7 @@ func TestCheckProcs_SkipsEmptyFile(t *testing.T) {
 	}
 
 	// This should return immediately without error
-	w.checkProcs()
+	w.checkProcs(context.Background())
 
 	// hasRunningProcs should still be false
 	if w.hasRunningProcs {
@@ -93,7 +68,7 @@ func TestCheckSpawns_SkipsEmptyFile(t *testing.T) {
 	}
 
 	// This should return immediately without error
-	w.checkSpawns()
+	w.checkSpawns(context.Background())
 
 	// hasRunningSpawns should still be false
 	if w.hasRunningSpawns {
@@ -126,3 +101,70 @@ func TestWatcher_NewInitializesFields(t *testing.T) {
 		t.Error("hasRunningSpawns should be false initially")
 	}
 }
+
+func TestCheckEdits_PerFileDebounce(t *testing.T) {
+	session := testSession(t)
+	w := New(session, 5, 8)
+
+	old := time.Now().Unix() - 20
+	for _, ev := range []bus.EditEvent{
+		{TS: old, Path: "settled.go", Op: "edit", Delta: 10},
+		{TS: old, Path: "settled.go", Op: "edit", Delta: 5},
+		{TS: time.Now().Unix(), Path: "busy.go", Op: "edit"},
+	} {
+		if err := bus.AppendEditEvent(session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.checkEdits(context.Background())
+	msgs, err := bus.Receive(session, "analyze")
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Payload, "settled.go (2 edits, +15 bytes)") || strings.Contains(msgs[0].Payload, "busy.go") {
+		t.Fatalf("analyze messages = %+v", msgs)
+	}
+	if len(w.pendingOrder) != 1 || w.pendingOrder[0] != "busy.go" {
+		t.Errorf("pending = %v, want busy.go still waiting", w.pendingOrder)
+	}
+	if info, _ := os.Stat(bus.EditLogPath(session)); info.Size() == 0 {
+		t.Error("edit log truncated while edits are pending")
+	}
+
+	// Once the last file settles, it is routed and the log is truncated
+	w.pendingEdits["busy.go"][0].TS = old
+	w.checkEdits(context.Background())
+	msgs, _ = bus.Receive(session, "analyze")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Payload, "busy.go") {
+		t.Fatalf("analyze messages = %+v", msgs)
+	}
+	if info, _ := os.Stat(bus.EditLogPath(session)); info.Size() != 0 || w.editOffset != 0 {
+		t.Errorf("edit log not truncated: size %d, offset %d", info.Size(), w.editOffset)
+	}
+}
+
+func TestCheckHeartbeats_DownAndUp(t *testing.T) {
+	session := testSession(t)
+	w := New(session, 5, 8)
+
+	os.WriteFile(bus.HeartbeatPath(session, "test"), []byte(fmt.Sprintf("4242 %d exited", time.Now().Unix())), 0644)
+	w.checkHeartbeats(context.Background())
+	msgs, _ := bus.Receive(session, "edit")
+	if len(msgs) != 1 || msgs[0].Action != "agent-down" || !strings.Contains(msgs[0].Payload, "Agent test is down") {
+		t.Fatalf("edit messages = %+v", msgs)
+	}
+
+	// Reported once while down
+	w.checkHeartbeats(context.Background())
+	if msgs, _ := bus.Receive(session, "edit"); len(msgs) != 0 {
+		t.Errorf("repeated agent-down: %+v", msgs)
+	}
+
+	bus.TouchHeartbeat(session, "test", os.Getpid())
+	w.checkHeartbeats(context.Background())
+	msgs, _ = bus.Receive(session, "edit")
+	if len(msgs) != 1 || msgs[0].Action != "agent-up" {
+		t.Fatalf("edit messages = %+v", msgs)
+	}
+}
@@ -0,0 +1,152 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes caps an image sent to the model; larger images are
+// described as text instead.
+const maxImageBytes = 8 << 20
+
+// imageTypes maps the image extensions sent as multimodal content to their
+// MIME types.
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// visionModelHints are model name fragments of vision-capable models, used
+// when Ollama does not report capabilities.
+var visionModelHints = []string{"llava", "vision", "-vl", "minicpm-v", "moondream", "gemma3", "llama4"}
+
+// Attachment is a content-addressed file stored with a bus message
+// (see muxcode-agent-bus send --attach).
+type Attachment struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha256"`
+	Size int64  `json:"size"`
+}
+
+// ImageType returns the attachment's image MIME type, or "" if it is not
+// an image.
+func (a Attachment) ImageType() string {
+	return imageTypes[strings.ToLower(filepath.Ext(a.Name))]
+}
+
+// AttachmentPath returns where the bus stores an attachment's blob.
+func AttachmentPath(busDir, sha string) string {
+	return filepath.Join(busDir, "attachments", sha)
+}
+
+// LoadImage reads an image attachment and returns it as a data URL. The
+// blob is checked against its hash.
+func LoadImage(busDir string, a Attachment) (string, error) {
+	mime := a.ImageType()
+	if mime == "" {
+		return "", fmt.Errorf("%s is not an image", a.Name)
+	}
+	if a.Size > maxImageBytes {
+		return "", fmt.Errorf("%s is %d bytes, over the %d byte image limit", a.Name, a.Size, maxImageBytes)
+	}
+	data, err := os.ReadFile(AttachmentPath(busDir, a.SHA))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != a.SHA {
+		return "", fmt.Errorf("%s: attachment blob does not match its hash", a.Name)
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// BuildTaskMessage returns the user message for a batch: the formatted
+// task plus its image attachments. When vision is false, or an image cannot
+// be loaded, the image is described in the text instead so text-only models
+// still know it exists.
+func BuildTaskMessage(busDir string, msgs []Message, vision bool) ChatMessage {
+	msg := ChatMessage{Role: "user", Content: FormatTask(msgs)}
+	var notes []string
+	for _, m := range msgs {
+		for _, a := range m.Attachments {
+			if a.ImageType() == "" {
+				continue
+			}
+			if !vision {
+				notes = append(notes, fmt.Sprintf("Image %s was not shown: this model does not accept images. File: %s",
+					a.Name, AttachmentPath(busDir, a.SHA)))
+				continue
+			}
+			url, err := LoadImage(busDir, a)
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("Image %s was not shown: %v", a.Name, err))
+				continue
+			}
+			msg.Images = append(msg.Images, url)
+		}
+	}
+	if len(notes) > 0 {
+		msg.Content += "\n" + strings.Join(notes, "\n") + "\n"
+	}
+	return msg
+}
+
+// stripImages drops images from a conversation, noting each removal in
+// the message text. It reports whether any images were removed.
+func stripImages(conversation []ChatMessage) bool {
+	stripped := false
+	for i := range conversation {
+		if n := len(conversation[i].Images); n > 0 {
+			conversation[i].Content += fmt.Sprintf("\n(%d image attachment(s) removed: the model rejected image input.)\n", n)
+			conversation[i].Images = nil
+			stripped = true
+		}
+	}
+	return stripped
+}
+
+// SupportsVision reports whether the model accepts images, from the
+// capabilities Ollama reports for it (POST /api/show), or from the model
+// name when Ollama does not report capabilities.
+func (c *OllamaClient) SupportsVision(ctx context.Context) bool {
+	body, _ := json.Marshal(map[string]string{"model": c.Model})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/show", bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := c.HTTP.Do(req); err == nil {
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			var show struct {
+				Capabilities []string `json:"capabilities"`
+			}
+			if resp.StatusCode == http.StatusOK && json.Unmarshal(data, &show) == nil && len(show.Capabilities) > 0 {
+				for _, cap := range show.Capabilities {
+					if cap == "vision" {
+						return true
+					}
+				}
+				return false
+			}
+		}
+	}
+	model := strings.ToLower(c.Model)
+	for _, hint := range visionModelHints {
+		if strings.Contains(model, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package harness
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// storeBlob writes data into busDir's attachment store and returns its
+// reference.
+func storeBlob(t *testing.T, busDir, name string, data []byte) Attachment {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	sha := hex.EncodeToString(sum[:])
+	if err := os.MkdirAll(filepath.Join(busDir, "attachments"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(AttachmentPath(busDir, sha), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Attachment{Name: name, SHA: sha, Size: int64(len(data))}
+}
+
+func TestAttachmentImageType(t *testing.T) {
+	for name, want := range map[string]string{
+		"shot.png":  "image/png",
+		"Photo.JPG": "image/jpeg",
+		"build.log": "",
+		"noext":     "",
+	} {
+		if got := (Attachment{Name: name}).ImageType(); got != want {
+			t.Errorf("ImageType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	dir := t.TempDir()
+	a := storeBlob(t, dir, "shot.png", []byte("\x89PNG fake"))
+
+	url, err := LoadImage(dir, a)
+	if err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if !strings.HasPrefix(url, "data:image/png;base64,") {
+		t.Errorf("url = %q", url)
+	}
+
+	os.WriteFile(AttachmentPath(dir, a.SHA), []byte("tampered"), 0644)
+	if _, err := LoadImage(dir, a); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("tampered blob: err = %v", err)
+	}
+
+	big := a
+	big.Size = maxImageBytes + 1
+	if _, err := LoadImage(dir, big); err == nil {
+		t.Error("expected error for oversized image")
+	}
+}
+
+func TestBuildTaskMessage(t *testing.T) {
+	dir := t.TempDir()
+	img := storeBlob(t, dir, "ui.png", []byte("png bytes"))
+	log := storeBlob(t, dir, "test.log", []byte("FAIL"))
+	msgs := []Message{{From: "edit", Action: "fix", Payload: "The button overlaps", Attachments: []Attachment{img, log}}}
+
+	msg := BuildTaskMessage(dir, msgs, true)
+	if len(msg.Images) != 1 || !strings.HasPrefix(msg.Images[0], "data:image/png;base64,") {
+		t.Errorf("Images = %v, want one PNG data URL", msg.Images)
+	}
+	for _, want := range []string{"**Attachment**: ui.png (9 bytes)", "**Attachment**: test.log (4 bytes)"} {
+		if !strings.Contains(msg.Content, want) {
+			t.Errorf("content missing %q:\n%s", want, msg.Content)
+		}
+	}
+
+	// Text-only models get a note with the blob path instead
+	msg = BuildTaskMessage(dir, msgs, false)
+	if len(msg.Images) != 0 {
+		t.Errorf("Images = %v, want none for text-only model", msg.Images)
+	}
+	if !strings.Contains(msg.Content, "Image ui.png was not shown") || !strings.Contains(msg.Content, AttachmentPath(dir, img.SHA)) {
+		t.Errorf("content missing fallback note:\n%s", msg.Content)
+	}
+
+	// A missing blob degrades to a note rather than failing the batch
+	os.Remove(AttachmentPath(dir, img.SHA))
+	msg = BuildTaskMessage(dir, msgs, true)
+	if len(msg.Images) != 0 || !strings.Contains(msg.Content, "Image ui.png was not shown") {
+		t.Errorf("missing blob: images = %d, content:\n%s", len(msg.Images), msg.Content)
+	}
+}
+
+func TestChatMessageMarshalImages(t *testing.T) {
+	data, err := json.Marshal(ChatMessage{Role: "user", Content: "look", Images: []string{"data:image/png;base64,AAAA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			ImageURL struct {
+				URL string `json:"url"`
+			} `json:"image_url"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	if got.Role != "user" || len(got.Content) != 2 || got.Content[0].Text != "look" ||
+		got.Content[1].Type != "image_url" || got.Content[1].ImageURL.URL != "data:image/png;base64,AAAA" {
+		t.Errorf("marshaled = %s", data)
+	}
+
+	// Without images, content stays a plain string
+	data, _ = json.Marshal(ChatMessage{Role: "user", Content: "plain"})
+	if !strings.Contains(string(data), `"content":"plain"`) {
+		t.Errorf("marshaled = %s", data)
+	}
+}
+
+func TestStripImages(t *testing.T) {
+	conv := []ChatMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: "task", Images: []string{"a", "b"}}}
+	if !stripImages(conv) {
+		t.Fatal("stripImages = false, want true")
+	}
+	if conv[1].Images != nil || !strings.Contains(conv[1].Content, "2 image attachment(s) removed") {
+		t.Errorf("conv[1] = %+v", conv[1])
+	}
+	if stripImages(conv) {
+		t.Error("second stripImages = true, want false")
+	}
+}
+
+func TestSupportsVision(t *testing.T) {
+	caps := `{"capabilities":["completion","vision"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(caps))
+	}))
+	defer server.Close()
+
+	c := NewOllamaClient(server.URL, "qwen2.5:7b")
+	if !c.SupportsVision(context.Background()) {
+		t.Error("reported vision capability should be used")
+	}
+	caps = `{"capabilities":["completion","tools"]}`
+	if c.SupportsVision(context.Background()) {
+		t.Error("model without vision capability reported as vision")
+	}
+
+	// Older Ollama: no capabilities, fall back to the model name
+	caps = `{}`
+	if c.SupportsVision(context.Background()) {
+		t.Error("qwen2.5:7b should not look like a vision model")
+	}
+	c.Model = "llava:13b"
+	if !c.SupportsVision(context.Background()) {
+		t.Error("llava:13b should look like a vision model")
+	}
+}
+
+func TestProcessBatch_ImageFallback(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		user := string(body.Messages[1])
+		requests = append(requests, user)
+		if strings.Contains(user, "image_url") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"model does not support images"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []ChatChoice{{
+			Message: ChatMessage{Role: "assistant", Content: "Looked at the layout; done."}, FinishReason: "stop",
+		}}})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	img := storeBlob(t, dir, "ui.png", []byte("png bytes"))
+	cfg := Config{Role: "edit", Session: "test", BusDir: dir, MaxTurns: 10}
+	ollama := NewOllamaClient(server.URL, "test-model")
+	ollama.Vision = true
+	bus := &BusClient{BusDir: dir, Role: "edit", BinPath: "echo"} // echo as a no-op
+	msgs := []Message{{ID: "1", From: "review", To: "edit", Action: "fix", Payload: "Fix layout", Attachments: []Attachment{img}}}
+
+	processBatch(context.Background(), cfg, bus, ollama, NewExecutor(nil), nil, "system prompt", NewFilter("edit"), msgs)
+
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want image request then text-only retry", len(requests))
+	}
+	if strings.Contains(requests[1], "image_url") || !strings.Contains(requests[1], "image attachment(s) removed") {
+		t.Errorf("retry should be text-only with a note: %s", requests[1])
+	}
+	if ollama.Vision {
+		t.Error("Vision should be turned off after the model rejects images")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,6 +17,9 @@ type BusClient struct {
 	Role      string // bus identity role (for inbox, lock, send, history)
 	AgentRole string // agent definition role (for tools, skills, context)
 	BusDir    string
+	// TraceParent is passed to bus commands as TRACEPARENT while a turn
+	// is running, so its sends join the turn's trace.
+	TraceParent string
 }
 
 // NewBusClient creates a bus client from harness config.
@@ -114,6 +118,35 @@ func (b *BusClient) ResolveTools() ([]string, error) {
 	return patterns, nil
 }
 
+// ResolveSandbox gets the sandbox policy for the agent definition role.
+// Returns nil when the role has no sandbox configured.
+func (b *BusClient) ResolveSandbox() (*SandboxPolicy, error) {
+	out, err := b.run("tools", b.AgentRole, "--sandbox")
+	if err != nil {
+		return nil, fmt.Errorf("tools --sandbox: %w: %s", err, out)
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	var policy SandboxPolicy
+	if err := json.Unmarshal([]byte(out), &policy); err != nil {
+		return nil, fmt.Errorf("tools --sandbox: %w", err)
+	}
+	return &policy, nil
+}
+
+// CheckCost asks the bus cost guard whether this role may run command.
+// It returns the reason a command is blocked, or "" to run it; a bus
+// error that gives no reason does not block.
+func (b *BusClient) CheckCost(command string) string {
+	out, err := b.run("cost", "check", "--role", b.Role, "--", command)
+	if err == nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
 // SkillPrompt returns the skills prompt for the agent definition role.
 func (b *BusClient) SkillPrompt() (string, error) {
 	out, err := b.run("skill", "prompt", b.AgentRole)
@@ -132,22 +165,31 @@ func (b *BusClient) ContextPrompt() (string, error) {
 	return strings.TrimSpace(out), nil
 }
 
-// LogHistory appends a bash command execution to the role's history JSONL.
-func (b *BusClient) LogHistory(command, output, exitCode, outcome string) error {
+// LogHistory appends a bash command execution to the role's history JSONL,
+// with the same exit code and truncation metadata the model saw.
+func (b *BusClient) LogHistory(command string, r ToolResult) error {
 	historyPath := b.BusDir + "/" + b.Role + "-history.jsonl"
 
 	// Truncate output for history
+	output := r.Text()
 	if len(output) > 2000 {
 		output = output[:2000] + "..."
 	}
 
 	entry := map[string]interface{}{
-		"ts":        time.Now().Unix(),
-		"summary":   command,
-		"exit_code": exitCode,
-		"command":   command,
-		"output":    output,
-		"outcome":   outcome,
+		"ts":            time.Now().Unix(),
+		"summary":       command,
+		"exit_code":     r.ExitCodeString(),
+		"command":       command,
+		"output":        output,
+		"outcome":       r.Outcome(),
+		"duration_ms":   r.DurationMs,
+		"truncated":     r.Truncated,
+		"bytes_omitted": r.BytesOmitted,
+	}
+	if !r.StartedAt.IsZero() {
+		entry["started_at"] = r.StartedAt.UnixMilli()
+		entry["ended_at"] = r.EndedAt.UnixMilli()
 	}
 
 	data, err := json.Marshal(entry)
@@ -164,6 +206,66 @@ func (b *BusClient) LogHistory(command, output, exitCode, outcome string) error
 	return err
 }
 
+// LogUsage appends one LLM call's token usage to the role's usage JSONL,
+// which the bus watcher sums to enforce hourly budgets.
+func (b *BusClient) LogUsage(tokens int) error {
+	usagePath := b.BusDir + "/" + b.Role + "-usage.jsonl"
+	data, err := json.Marshal(map[string]interface{}{
+		"ts":     time.Now().Unix(),
+		"tokens": tokens,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(usagePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// LogSpan appends a harness turn span to the session's trace log, in the
+// same format the bus records message spans.
+func (b *BusClient) LogSpan(span Span) error {
+	data, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(b.BusDir+"/trace.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Heartbeat records that this harness is alive in the role's heartbeat file
+// (lock/{role}.heartbeat, "{pid} {unix_timestamp}"), which the watcher
+// checks for crash detection.
+func (b *BusClient) Heartbeat() error {
+	content := fmt.Sprintf("%d %d", os.Getpid(), time.Now().Unix())
+	return os.WriteFile(b.BusDir+"/lock/"+b.Role+".heartbeat", []byte(content), 0644)
+}
+
+// PausedUntil returns when the watcher's budget pause lifts, or 0 if the
+// harness is not paused.
+func (b *BusClient) PausedUntil() int64 {
+	data, err := os.ReadFile(b.BusDir + "/harness-" + b.Role + ".paused")
+	if err != nil {
+		return 0
+	}
+	until, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || until <= time.Now().Unix() {
+		return 0
+	}
+	return until
+}
+
 // run executes a bus CLI command and returns stdout only.
 // Stderr is forwarded to the harness's own stderr so bus warnings/errors
 // appear in the log without contaminating parsed command output.
@@ -173,6 +275,9 @@ func (b *BusClient) run(args ...string) (string, error) {
 		"BUS_SESSION="+b.Session,
 		"AGENT_ROLE="+b.Role,
 	)
+	if b.TraceParent != "" {
+		cmd.Env = append(cmd.Env, "TRACEPARENT="+b.TraceParent)
+	}
 	cmd.Stderr = os.Stderr
 	out, err := cmd.Output()
 	return string(out), err
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHasMessages_EmptyFile(t *testing.T) {
@@ -43,7 +46,7 @@ func TestLogHistory(t *testing.T) {
 		Role:   "test",
 	}
 
-	err := bc.LogHistory("git status", "On branch main", "0", "success")
+	err := bc.LogHistory("git status", ToolResult{Tool: "bash", Output: "On branch main", DurationMs: 40})
 	if err != nil {
 		t.Fatalf("LogHistory: %v", err)
 	}
@@ -67,6 +70,31 @@ func TestLogHistory(t *testing.T) {
 	if entry["exit_code"] != "0" {
 		t.Errorf("exit_code = %q, want '0'", entry["exit_code"])
 	}
+	if entry["duration_ms"] != float64(40) || entry["truncated"] != false {
+		t.Errorf("duration_ms = %v, truncated = %v, want 40, false", entry["duration_ms"], entry["truncated"])
+	}
+	if _, ok := entry["started_at"]; ok {
+		t.Errorf("started_at logged for an untimed result: %v", entry["started_at"])
+	}
+}
+
+func TestLogHistory_Timestamps(t *testing.T) {
+	dir := t.TempDir()
+	bc := &BusClient{BusDir: dir, Role: "test"}
+	start := time.UnixMilli(1_800_000_000_000)
+	r := ToolResult{Tool: "bash", DurationMs: 1500, StartedAt: start, EndedAt: start.Add(1500 * time.Millisecond)}
+	if err := bc.LogHistory("go test ./...", r); err != nil {
+		t.Fatalf("LogHistory: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "test-history.jsonl"))
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data[:len(data)-1], &entry); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if entry["started_at"] != float64(1_800_000_000_000) || entry["ended_at"] != float64(1_800_000_001_500) {
+		t.Errorf("started_at = %v, ended_at = %v", entry["started_at"], entry["ended_at"])
+	}
 }
 
 func TestLogHistory_TruncatesOutput(t *testing.T) {
@@ -81,7 +109,7 @@ func TestLogHistory_TruncatesOutput(t *testing.T) {
 		longOutput[i] = 'x'
 	}
 
-	err := bc.LogHistory("cmd", string(longOutput), "0", "success")
+	err := bc.LogHistory("cmd", ToolResult{Output: string(longOutput)})
 	if err != nil {
 		t.Fatalf("LogHistory: %v", err)
 	}
@@ -99,3 +127,70 @@ func TestLogHistory_TruncatesOutput(t *testing.T) {
 		t.Errorf("output length = %d, should be truncated to ~2000", len(output))
 	}
 }
+
+func TestLogUsage(t *testing.T) {
+	dir := t.TempDir()
+	bc := &BusClient{BusDir: dir, Role: "build"}
+
+	if err := bc.LogUsage(1200); err != nil {
+		t.Fatalf("LogUsage: %v", err)
+	}
+	if err := bc.LogUsage(300); err != nil {
+		t.Fatalf("LogUsage: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "build-usage.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 usage lines, got %d", len(lines))
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if entry["tokens"] != float64(1200) {
+		t.Errorf("tokens = %v, want 1200", entry["tokens"])
+	}
+}
+
+func TestPausedUntil(t *testing.T) {
+	dir := t.TempDir()
+	bc := &BusClient{BusDir: dir, Role: "build"}
+	marker := filepath.Join(dir, "harness-build.paused")
+
+	if bc.PausedUntil() != 0 {
+		t.Error("no marker should mean not paused")
+	}
+
+	until := time.Now().Add(time.Hour).Unix()
+	os.WriteFile(marker, []byte(strconv.FormatInt(until, 10)), 0644)
+	if got := bc.PausedUntil(); got != until {
+		t.Errorf("PausedUntil = %d, want %d", got, until)
+	}
+
+	os.WriteFile(marker, []byte(strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)), 0644)
+	if bc.PausedUntil() != 0 {
+		t.Error("expired marker should mean not paused")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lock"), 0755)
+	bc := &BusClient{BusDir: dir, Role: "build"}
+
+	if err := bc.Heartbeat(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "lock", "build.heartbeat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("heartbeat = %q", data)
+	}
+}
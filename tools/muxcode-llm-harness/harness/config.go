@@ -4,27 +4,39 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// DefaultMaxParallelTools is the default number of read-only tool calls executed
+// concurrently when a model returns several in one turn.
+const DefaultMaxParallelTools = 4
+
 // Config holds configuration for the LLM harness.
 type Config struct {
-	Role        string // agent definition role (git, build, etc.) — for tools, skills, agent def
-	BusRole     string // bus identity role (commit, build, etc.) — for inbox, lock, send, history
-	Session     string // bus session name
-	OllamaURL   string // default http://localhost:11434
-	OllamaModel string // default qwen2.5:7b (must support tool calling)
-	MaxTurns    int    // max tool-calling turns per batch (default 10)
-	BusDir      string // /tmp/muxcode-bus-{session}/
-	BusBin      string // path to muxcode-agent-bus binary
+	Role             string // agent definition role (git, build, etc.) — for tools, skills, agent def
+	BusRole          string // bus identity role (commit, build, etc.) — for inbox, lock, send, history
+	Session          string // bus session name
+	OllamaURL        string // default http://localhost:11434
+	OllamaModel      string // default qwen2.5:7b (must support tool calling)
+	MaxTurns         int    // max tool-calling turns per batch (default 10)
+	MaxParallelTools int    // max concurrent read-only tool calls per turn (default 4)
+	Vision           string // image attachments: auto (detect, default), on, off
+	OutputHead       int    // bash output lines kept from the start (default 50)
+	OutputTail       int    // bash output lines kept from the end (default 50)
+	BusDir           string // /tmp/muxcode-bus-{session}/
+	BusBin           string // path to muxcode-agent-bus binary
 }
 
 // DefaultConfig returns a Config with sensible defaults, reading from env vars.
 func DefaultConfig() Config {
 	cfg := Config{
-		OllamaURL:   "http://localhost:11434",
-		OllamaModel: "qwen2.5:7b",
-		MaxTurns:    10,
+		OllamaURL:        "http://localhost:11434",
+		OllamaModel:      "qwen2.5:7b",
+		MaxTurns:         10,
+		MaxParallelTools: DefaultMaxParallelTools,
+		OutputHead:       DefaultOutputHead,
+		OutputTail:       DefaultOutputTail,
 	}
 
 	// Session detection — matches bus.BusSession() resolution order
@@ -58,6 +70,24 @@ func DefaultConfig() Config {
 	if v := os.Getenv("MUXCODE_OLLAMA_MODEL"); v != "" {
 		cfg.OllamaModel = v
 	}
+	if v := os.Getenv("MUXCODE_OLLAMA_VISION"); v != "" {
+		cfg.Vision = v
+	}
+	if v := os.Getenv("MUXCODE_HARNESS_OUTPUT_HEAD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.OutputHead = n
+		}
+	}
+	if v := os.Getenv("MUXCODE_HARNESS_OUTPUT_TAIL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.OutputTail = n
+		}
+	}
+	if v := os.Getenv("MUXCODE_HARNESS_PARALLEL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxParallelTools = n
+		}
+	}
 
 	cfg.BusDir = "/tmp/muxcode-bus-" + cfg.Session
 	cfg.BusBin = findBusBin()
@@ -22,6 +22,18 @@ const (
 type Executor struct {
 	Patterns []string // allowed tool patterns
 	WorkDir  string   // working directory for commands
+	Sandbox  *Sandbox // optional sandbox policy (nil = unrestricted)
+	// TraceParent is exported to bash commands as TRACEPARENT (empty = unset)
+	TraceParent string
+	// Long bash output keeps its first OutputHead and last OutputTail lines
+	// (0 = defaults); the full output is saved under OutputLogDir.
+	OutputHead   int
+	OutputTail   int
+	OutputLogDir string
+	Role         string // names saved output logs
+	// CostGuard, when set, is asked before each bash command; a non-empty
+	// reason blocks the command (see muxcode-agent-bus cost check).
+	CostGuard func(command string) string
 }
 
 // NewExecutor creates a new executor with the given patterns.
@@ -35,29 +47,40 @@ func NewExecutor(patterns []string) *Executor {
 
 // Execute runs a tool call and returns the result text.
 func (e *Executor) Execute(ctx context.Context, call ToolCall) string {
+	return e.Run(ctx, call).Text()
+}
+
+// Run runs a tool call and returns its structured result.
+func (e *Executor) Run(ctx context.Context, call ToolCall) ToolResult {
 	name := call.Function.Name
 	args := call.Function.Arguments
+	start := time.Now()
 
+	var r ToolResult
 	switch name {
 	case "bash":
-		return e.executeBash(ctx, args)
+		r = e.executeBash(ctx, args)
 	case "read_file":
-		return e.executeRead(args)
+		r = e.executeRead(args)
 	case "glob":
-		return e.executeGlob(args)
+		r = e.executeGlob(args)
 	case "grep":
-		return e.executeGrep(ctx, args)
+		r = e.executeGrep(ctx, args)
 	case "write_file":
-		return e.executeWrite(args)
+		r = e.executeWrite(args)
 	case "edit_file":
-		return e.executeEdit(args)
+		r = e.executeEdit(args)
 	default:
-		return fmt.Sprintf("Error: unknown tool %q", name)
+		r = toolError(ExitError, "unknown tool %q", name)
 	}
+	r.Tool = name
+	r.StartedAt, r.EndedAt = start, time.Now()
+	r.DurationMs = r.EndedAt.Sub(start).Milliseconds()
+	return r
 }
 
 // executeBash runs a bash command with timeout and output truncation.
-func (e *Executor) executeBash(ctx context.Context, argsJSON json.RawMessage) string {
+func (e *Executor) executeBash(ctx context.Context, argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Command string `json:"command"`
 	}
@@ -67,15 +90,25 @@ func (e *Executor) executeBash(ctx context.Context, argsJSON json.RawMessage) st
 		if err2 := json.Unmarshal(argsJSON, &cmdStr); err2 == nil && cmdStr != "" {
 			args.Command = unwrapCommand(cmdStr)
 		} else {
-			return fmt.Sprintf("Error: invalid arguments: %v", err)
+			return toolError(ExitError, "invalid arguments: %v", err)
 		}
 	}
 	if args.Command == "" {
-		return "Error: command is required"
+		return toolError(ExitError, "command is required")
 	}
 
 	if !IsToolAllowed("bash", args.Command, e.Patterns) {
-		return fmt.Sprintf("Error: command not allowed by tool profile: %s", args.Command)
+		return toolError(ExitNotAllowed, "command not allowed by tool profile: %s", args.Command)
+	}
+	if e.Sandbox != nil {
+		if err := e.Sandbox.CheckCommand(args.Command); err != nil {
+			return sandboxError(err)
+		}
+	}
+	if e.CostGuard != nil {
+		if reason := e.CostGuard(args.Command); reason != "" {
+			return toolError(ExitNotAllowed, "%s", reason)
+		}
 	}
 
 	cmdCtx, cancel := context.WithTimeout(ctx, BashTimeout)
@@ -83,19 +116,26 @@ func (e *Executor) executeBash(ctx context.Context, argsJSON json.RawMessage) st
 
 	cmd := exec.CommandContext(cmdCtx, "bash", "-c", args.Command)
 	cmd.Dir = e.WorkDir
+	if e.Sandbox != nil {
+		cmd.Env = e.Sandbox.Env()
+	}
+	if e.TraceParent != "" {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, "TRACEPARENT="+e.TraceParent)
+	}
 
 	out, err := cmd.CombinedOutput()
-	result := string(out)
-
-	if len(result) > MaxOutputLen {
-		result = result[:MaxOutputLen] + "\n... [output truncated]"
-	}
+	result := e.captureCommand(string(out))
 
 	if err != nil {
 		if cmdCtx.Err() == context.DeadlineExceeded {
-			return result + "\nError: command timed out after 60 seconds"
+			result.ExitCode = ExitTimeout
+			result.Error = "command timed out after 60 seconds"
+			return result
 		}
-		return result + "\nExit code: " + exitCodeStr(err)
+		result.ExitCode = exitCode(err)
 	}
 
 	return result
@@ -150,7 +190,7 @@ func unwrapPattern(s string) string {
 }
 
 // executeRead reads a file and returns its contents.
-func (e *Executor) executeRead(argsJSON json.RawMessage) string {
+func (e *Executor) executeRead(argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Path string `json:"path"`
 	}
@@ -159,32 +199,32 @@ func (e *Executor) executeRead(argsJSON json.RawMessage) string {
 		if err2 := json.Unmarshal(argsJSON, &pathStr); err2 == nil && pathStr != "" {
 			args.Path = unwrapPath(pathStr)
 		} else {
-			return fmt.Sprintf("Error: invalid arguments: %v", err)
+			return toolError(ExitError, "invalid arguments: %v", err)
 		}
 	}
 	if args.Path == "" {
-		return "Error: path is required"
+		return toolError(ExitError, "path is required")
 	}
 
 	if !IsToolAllowed("read_file", "", e.Patterns) {
-		return "Error: read_file not allowed by tool profile"
+		return toolError(ExitNotAllowed, "read_file not allowed by tool profile")
+	}
+	if e.Sandbox != nil {
+		if err := e.Sandbox.CheckPath(args.Path, false); err != nil {
+			return sandboxError(err)
+		}
 	}
 
 	data, err := os.ReadFile(args.Path)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return toolError(ExitError, "%v", err)
 	}
 
-	result := string(data)
-	if len(result) > MaxOutputLen {
-		result = result[:MaxOutputLen] + "\n... [output truncated]"
-	}
-
-	return result
+	return e.capture(string(data))
 }
 
 // executeGlob finds files matching a glob pattern.
-func (e *Executor) executeGlob(argsJSON json.RawMessage) string {
+func (e *Executor) executeGlob(argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Pattern string `json:"pattern"`
 	}
@@ -193,36 +233,42 @@ func (e *Executor) executeGlob(argsJSON json.RawMessage) string {
 		if err2 := json.Unmarshal(argsJSON, &patStr); err2 == nil && patStr != "" {
 			args.Pattern = unwrapPattern(patStr)
 		} else {
-			return fmt.Sprintf("Error: invalid arguments: %v", err)
+			return toolError(ExitError, "invalid arguments: %v", err)
 		}
 	}
 	if args.Pattern == "" {
-		return "Error: pattern is required"
+		return toolError(ExitError, "pattern is required")
 	}
 
 	if !IsToolAllowed("glob", "", e.Patterns) {
-		return "Error: glob not allowed by tool profile"
+		return toolError(ExitNotAllowed, "glob not allowed by tool profile")
 	}
 
 	matches, err := filepath.Glob(args.Pattern)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return toolError(ExitError, "%v", err)
 	}
 
-	if len(matches) == 0 {
-		return "No matches found"
+	if e.Sandbox != nil {
+		// Drop matches outside the jail rather than failing the whole glob
+		kept := matches[:0]
+		for _, m := range matches {
+			if e.Sandbox.CheckPath(m, false) == nil {
+				kept = append(kept, m)
+			}
+		}
+		matches = kept
 	}
 
-	result := strings.Join(matches, "\n")
-	if len(result) > MaxOutputLen {
-		result = result[:MaxOutputLen] + "\n... [output truncated]"
+	if len(matches) == 0 {
+		return ToolResult{Output: "No matches found"}
 	}
 
-	return result
+	return e.capture(strings.Join(matches, "\n"))
 }
 
 // executeGrep searches files using grep -rn.
-func (e *Executor) executeGrep(ctx context.Context, argsJSON json.RawMessage) string {
+func (e *Executor) executeGrep(ctx context.Context, argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Pattern string `json:"pattern"`
 		Path    string `json:"path"`
@@ -232,21 +278,26 @@ func (e *Executor) executeGrep(ctx context.Context, argsJSON json.RawMessage) st
 		if err2 := json.Unmarshal(argsJSON, &patStr); err2 == nil && patStr != "" {
 			args.Pattern = unwrapPattern(patStr)
 		} else {
-			return fmt.Sprintf("Error: invalid arguments: %v", err)
+			return toolError(ExitError, "invalid arguments: %v", err)
 		}
 	}
 	if args.Pattern == "" {
-		return "Error: pattern is required"
+		return toolError(ExitError, "pattern is required")
 	}
 
 	if !IsToolAllowed("grep", "", e.Patterns) {
-		return "Error: grep not allowed by tool profile"
+		return toolError(ExitNotAllowed, "grep not allowed by tool profile")
 	}
 
 	path := args.Path
 	if path == "" {
 		path = "."
 	}
+	if e.Sandbox != nil {
+		if err := e.Sandbox.CheckPath(path, false); err != nil {
+			return sandboxError(err)
+		}
+	}
 
 	cmdCtx, cancel := context.WithTimeout(ctx, BashTimeout)
 	defer cancel()
@@ -258,104 +309,129 @@ func (e *Executor) executeGrep(ctx context.Context, argsJSON json.RawMessage) st
 	cmd.Dir = e.WorkDir
 
 	out, err := cmd.CombinedOutput()
-	result := string(out)
-
-	if len(result) > MaxOutputLen {
-		result = result[:MaxOutputLen] + "\n... [output truncated]"
-	}
+	result := e.capture(string(out))
 
 	if err != nil {
-		if exitCodeStr(err) == "1" && result == "" {
-			return "No matches found"
+		if exitCode(err) == 1 && result.Output == "" {
+			return ToolResult{Output: "No matches found"}
 		}
 		if cmdCtx.Err() == context.DeadlineExceeded {
-			return result + "\nError: grep timed out"
-		}
-		if result != "" {
+			result.ExitCode = ExitTimeout
+			result.Error = "grep timed out"
 			return result
 		}
-		return fmt.Sprintf("Error: %v", err)
+		result.ExitCode = exitCode(err)
+		if result.Output == "" {
+			result.Error = err.Error()
+		}
 	}
 
 	return result
 }
 
 // executeWrite writes content to a file.
-func (e *Executor) executeWrite(argsJSON json.RawMessage) string {
+func (e *Executor) executeWrite(argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
 	}
 	if err := json.Unmarshal(argsJSON, &args); err != nil {
-		return fmt.Sprintf("Error: invalid arguments: %v", err)
+		return toolError(ExitError, "invalid arguments: %v", err)
 	}
 	if args.Path == "" {
-		return "Error: path is required"
+		return toolError(ExitError, "path is required")
 	}
 
 	if !IsToolAllowed("write_file", "", e.Patterns) {
-		return "Error: write_file not allowed by tool profile"
+		return toolError(ExitNotAllowed, "write_file not allowed by tool profile")
+	}
+	if e.Sandbox != nil {
+		if err := e.Sandbox.CheckPath(args.Path, true); err != nil {
+			return sandboxError(err)
+		}
 	}
 
 	dir := filepath.Dir(args.Path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Sprintf("Error creating directory: %v", err)
+		return toolError(ExitError, "creating directory: %v", err)
 	}
 
 	if err := os.WriteFile(args.Path, []byte(args.Content), 0644); err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return toolError(ExitError, "%v", err)
 	}
 
-	return fmt.Sprintf("Wrote %d bytes to %s", len(args.Content), args.Path)
+	return ToolResult{Output: fmt.Sprintf("Wrote %d bytes to %s", len(args.Content), args.Path)}
 }
 
 // executeEdit performs a string replacement in a file.
-func (e *Executor) executeEdit(argsJSON json.RawMessage) string {
+func (e *Executor) executeEdit(argsJSON json.RawMessage) ToolResult {
 	var args struct {
 		Path      string `json:"path"`
 		OldString string `json:"old_string"`
 		NewString string `json:"new_string"`
 	}
 	if err := json.Unmarshal(argsJSON, &args); err != nil {
-		return fmt.Sprintf("Error: invalid arguments: %v", err)
+		return toolError(ExitError, "invalid arguments: %v", err)
 	}
 	if args.Path == "" {
-		return "Error: path is required"
+		return toolError(ExitError, "path is required")
 	}
 	if args.OldString == "" {
-		return "Error: old_string is required"
+		return toolError(ExitError, "old_string is required")
 	}
 
 	if !IsToolAllowed("edit_file", "", e.Patterns) {
-		return "Error: edit_file not allowed by tool profile"
+		return toolError(ExitNotAllowed, "edit_file not allowed by tool profile")
+	}
+	if e.Sandbox != nil {
+		if err := e.Sandbox.CheckPath(args.Path, true); err != nil {
+			return sandboxError(err)
+		}
 	}
 
 	data, err := os.ReadFile(args.Path)
 	if err != nil {
-		return fmt.Sprintf("Error reading file: %v", err)
+		return toolError(ExitError, "reading file: %v", err)
 	}
 
 	content := string(data)
 	count := strings.Count(content, args.OldString)
 	if count == 0 {
-		return "Error: old_string not found in file"
+		return toolError(ExitError, "old_string not found in file")
 	}
 	if count > 1 {
-		return fmt.Sprintf("Error: old_string found %d times — must be unique", count)
+		return toolError(ExitError, "old_string found %d times — must be unique", count)
 	}
 
 	newContent := strings.Replace(content, args.OldString, args.NewString, 1)
 	if err := os.WriteFile(args.Path, []byte(newContent), 0644); err != nil {
-		return fmt.Sprintf("Error writing file: %v", err)
+		return toolError(ExitError, "writing file: %v", err)
 	}
 
-	return fmt.Sprintf("Replaced 1 occurrence in %s", args.Path)
+	return ToolResult{Output: fmt.Sprintf("Replaced 1 occurrence in %s", args.Path)}
+}
+
+// capture wraps tool output, capped at the sandbox limit or MaxOutputLen.
+func (e *Executor) capture(out string) ToolResult {
+	limit := MaxOutputLen
+	if e.Sandbox != nil {
+		limit = e.Sandbox.Limit()
+	}
+	if len(out) > limit {
+		return ToolResult{Output: out[:limit], Truncated: true, BytesOmitted: len(out) - limit}
+	}
+	return ToolResult{Output: out}
+}
+
+// sandboxError formats a sandbox violation as a tool error for the model.
+func sandboxError(err error) ToolResult {
+	return toolError(ExitNotAllowed, "sandbox violation: %v", err)
 }
 
-// exitCodeStr extracts the exit code from an exec error.
-func exitCodeStr(err error) string {
+// exitCode extracts the exit code from an exec error, or ExitUnknown.
+func exitCode(err error) int {
 	if exitErr, ok := err.(*exec.ExitError); ok {
-		return fmt.Sprintf("%d", exitErr.ExitCode())
+		return exitErr.ExitCode()
 	}
-	return "unknown"
+	return ExitUnknown
 }
@@ -41,6 +41,29 @@ func TestExecuteBash_DeniedCommand(t *testing.T) {
 	}
 }
 
+func TestExecuteBash_CostGuard(t *testing.T) {
+	var asked []string
+	e := &Executor{Patterns: []string{"Bash(echo *)"}, CostGuard: func(command string) string {
+		asked = append(asked, command)
+		if strings.Contains(command, "run-instances") {
+			return "BLOCKED: costly command (ec2-run-instances) requires approval"
+		}
+		return ""
+	}}
+
+	r := e.Run(context.Background(), ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"echo aws ec2 run-instances"}`)}})
+	if r.ExitCode != ExitNotAllowed || !strings.Contains(r.Error, "requires approval") || r.Output != "" {
+		t.Errorf("blocked result = %+v", r)
+	}
+	r = e.Run(context.Background(), ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"echo ok"}`)}})
+	if r.ExitCode != 0 || !strings.Contains(r.Output, "ok") {
+		t.Errorf("allowed result = %+v", r)
+	}
+	if len(asked) != 2 {
+		t.Errorf("guard asked %d times, want 2", len(asked))
+	}
+}
+
 func TestExecuteBash_EmptyCommand(t *testing.T) {
 	e := &Executor{Patterns: []string{"Bash(*)"}}
 
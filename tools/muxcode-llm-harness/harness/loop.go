@@ -8,13 +8,18 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	// PollInterval is the sleep between inbox checks when idle.
 	PollInterval = 3 * time.Second
+
+	// HeartbeatInterval is how often the role's heartbeat file is touched.
+	HeartbeatInterval = 15 * time.Second
 )
 
 // runStty runs stty with the given arguments, explicitly passing os.Stdin
@@ -58,8 +63,24 @@ func Run(ctx context.Context, cfg Config) error {
 	// Build tool definitions for Ollama
 	tools := BuildToolDefs(patterns)
 
-	// Initialize executor
+	// Initialize executor, with the role's sandbox policy if one is configured
+	// and the bus cost guard checking each bash command. When the policy
+	// cannot be resolved there is no telling whether one applies, so the
+	// harness stops rather than run the role unsandboxed.
 	executor := NewExecutor(patterns)
+	executor.OutputHead, executor.OutputTail = cfg.OutputHead, cfg.OutputTail
+	executor.OutputLogDir = filepath.Join(cfg.BusDir, "proc")
+	executor.Role = cfg.busRole()
+	executor.CostGuard = bus.CheckCost
+	policy, err := bus.ResolveSandbox()
+	if err != nil {
+		return fmt.Errorf("resolving sandbox: %w", err)
+	}
+	if policy != nil {
+		executor.Sandbox = NewSandbox(*policy, executor.WorkDir)
+		fmt.Fprintf(os.Stderr, "[harness] Sandbox: jail %s, deny network %v, %d read-only paths\n",
+			executor.Sandbox.Root, executor.Sandbox.DenyNetwork, len(executor.Sandbox.ReadOnly))
+	}
 
 	// Initialize Ollama client
 	ollama := NewOllamaClient(cfg.OllamaURL, cfg.OllamaModel)
@@ -73,6 +94,20 @@ func Run(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("Ollama health check failed: %w", err)
 	}
 	fmt.Fprintf(os.Stderr, "[harness] Connected to Ollama (%s), model: %s\n", cfg.OllamaURL, cfg.OllamaModel)
+
+	// Image attachments: sent as multimodal content only to vision models
+	switch cfg.Vision {
+	case "on":
+		ollama.Vision = true
+	case "off":
+	default:
+		visionCtx, visionCancel := context.WithTimeout(ctx, 5*time.Second)
+		ollama.Vision = ollama.SupportsVision(visionCtx)
+		visionCancel()
+	}
+	if ollama.Vision {
+		fmt.Fprintf(os.Stderr, "[harness] Vision: image attachments sent to the model\n")
+	}
 	fmt.Fprintf(os.Stderr, "[harness] Tools: %d patterns, %d tool defs\n", len(patterns), len(tools))
 
 	// Build system prompt once at startup
@@ -101,10 +136,25 @@ func Run(ctx context.Context, cfg Config) error {
 	}
 	fmt.Fprintf(os.Stderr, "[harness] Ready, polling inbox for %s...\n", busRole)
 
+	// Heartbeat for the watcher's crash detection, independent of long turns
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			_ = bus.Heartbeat()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
 	// Initialize filter — use bus identity for self-send detection
 	filter := NewFilter(busRole)
 
 	// Main polling loop
+	paused := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -118,7 +168,18 @@ func Run(ctx context.Context, cfg Config) error {
 
 		inboxPath := cfg.InboxPath()
 
-		if bus.HasMessages(inboxPath) {
+		// Budget pause: leave messages queued until the watcher's pause lifts
+		if until := bus.PausedUntil(); until > 0 {
+			if !paused {
+				fmt.Fprintf(os.Stderr, "[harness] Paused: token budget exceeded, resuming at %s\n", time.Unix(until, 0).Format("15:04"))
+				paused = true
+			}
+		} else if paused {
+			fmt.Fprintf(os.Stderr, "[harness] Budget pause lifted\n")
+			paused = false
+		}
+
+		if !paused && bus.HasMessages(inboxPath) {
 			if err := bus.Lock(); err != nil {
 				fmt.Fprintf(os.Stderr, "[harness] lock error: %v\n", err)
 			}
@@ -151,8 +212,8 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 	// Find last message for reply routing
 	lastMsg := msgs[len(msgs)-1]
 
-	// Build structured task content
-	taskContent := FormatTask(msgs)
+	// Build structured task content, with images for vision models
+	task := BuildTaskMessage(cfg.BusDir, msgs, ollama.Vision)
 
 	// Display each incoming message once (replaces noisy tmux notifications)
 	for _, m := range msgs {
@@ -165,10 +226,26 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 	fmt.Fprintf(os.Stderr, "[harness] Processing %d message(s) from %s: %s\n",
 		len(msgs), lastMsg.From, lastMsg.Action)
 
+	// Trace the batch as one span; bus commands and bash tools run during
+	// it inherit TRACEPARENT so their messages are parented to the turn.
+	span := newTurnSpan(bus.Role, cfg.OllamaModel, msgs)
+	bus.TraceParent = span.traceParent()
+	executor.TraceParent = bus.TraceParent
+	tokens, calls := 0, 0
+	defer func() {
+		bus.TraceParent, executor.TraceParent = "", ""
+		span.End = time.Now().UnixMilli()
+		span.Attrs["harness.llm_calls"] = strconv.Itoa(calls)
+		span.Attrs["harness.tokens"] = strconv.Itoa(tokens)
+		if err := bus.LogSpan(span); err != nil {
+			fmt.Fprintf(os.Stderr, "[harness] trace log error: %v\n", err)
+		}
+	}()
+
 	// Fresh conversation: system + task
 	conversation := []ChatMessage{
 		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: taskContent},
+		task,
 	}
 
 	// Tool-calling loop
@@ -180,11 +257,24 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 	}
 
 	for turn := 0; turn < maxTurns; turn++ {
+		if bus.PausedUntil() > 0 {
+			finalResponse = "Stopped: token budget exceeded for this hour. Partial work may be incomplete."
+			break
+		}
+
 		resp, err := ollama.ChatComplete(ctx, conversation, tools)
+		if err != nil && stripImages(conversation) {
+			// Graceful fallback: the model refused images, continue text-only
+			fmt.Fprintf(os.Stderr, "[harness] Model rejected images, retrying text-only: %v\n", err)
+			ollama.Vision = false
+			resp, err = ollama.ChatComplete(ctx, conversation, tools)
+		}
 		if err != nil {
 			finalResponse = fmt.Sprintf("Error calling Ollama: %v", err)
 			break
 		}
+		tokens += logUsage(bus, resp)
+		calls++
 
 		if len(resp.Choices) == 0 {
 			finalResponse = "Error: empty response from Ollama"
@@ -211,30 +301,27 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 			break
 		}
 
-		// Execute tool calls
+		// Execute tool calls (concurrently, results kept in call order)
+		results := executeToolCalls(ctx, executor, filter, choice.Message.ToolCalls, cfg.MaxParallelTools)
 		allBlocked := true
-		for _, tc := range choice.Message.ToolCalls {
-			result := filter.Check(tc)
-
-			var toolOutput string
-			if result.Blocked {
-				toolOutput = result.Reason
-				fmt.Fprintf(os.Stderr, "[harness] BLOCKED: %s\n", result.Reason)
+		for i, tc := range choice.Message.ToolCalls {
+			r := results[i]
+			if r.Blocked {
+				fmt.Fprintf(os.Stderr, "[harness] BLOCKED: %s\n", r.Error)
 			} else {
 				allBlocked = false
 				toolsExecuted = true
-				toolOutput = executor.Execute(ctx, tc)
 
 				// Log bash commands to history
 				if tc.Function.Name == "bash" {
-					logToolToHistory(bus, tc, toolOutput)
+					logToolToHistory(bus, tc, r)
 				}
 			}
 
-			// Add tool result to conversation
+			// Add tool result to conversation as a JSON envelope
 			conversation = append(conversation, ChatMessage{
 				Role:       "tool",
-				Content:    toolOutput,
+				Content:    r.JSON(),
 				ToolCallID: tc.ID,
 			})
 		}
@@ -258,6 +345,10 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 			Content: "You already executed the commands above. Now provide ONLY a short factual summary of the result. Start with the outcome: succeeded or failed. Do not describe what you plan to do — just summarize what already happened.",
 		})
 		resp, err := ollama.ChatComplete(ctx, conversation, nil) // no tools — text only
+		if err == nil {
+			tokens += logUsage(bus, resp)
+			calls++
+		}
 		if err == nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
 			finalResponse = resp.Choices[0].Message.Content
 		}
@@ -280,6 +371,80 @@ func processBatch(ctx context.Context, cfg Config, bus *BusClient, ollama *Ollam
 	}
 }
 
+// logUsage records a completion's token usage for budget tracking and
+// returns the token count. Calls without usage data still count as a turn.
+func logUsage(bus *BusClient, resp *ChatResponse) int {
+	tokens := 0
+	if resp.Usage != nil {
+		tokens = resp.Usage.TotalTokens
+	}
+	if err := bus.LogUsage(tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "[harness] usage log error: %v\n", err)
+	}
+	return tokens
+}
+
+// executeToolCalls runs a turn's tool calls and returns results indexed by
+// call position, so the follow-up conversation is identical regardless of
+// completion order. Filter checks run serially first because the filter
+// tracks per-batch repeat counts. Runs of consecutive read-only calls execute
+// with at most maxParallel in flight; bash and file writes run alone, in call
+// order, so each sees the effects of the calls before it.
+func executeToolCalls(ctx context.Context, executor *Executor, filter *Filter, calls []ToolCall, maxParallel int) []ToolResult {
+	results := make([]ToolResult, len(calls))
+	if maxParallel <= 0 {
+		maxParallel = DefaultMaxParallelTools
+	}
+
+	var runnable []int
+	for i, tc := range calls {
+		if check := filter.Check(tc); check.Blocked {
+			results[i] = ToolResult{Tool: tc.Function.Name, ExitCode: ExitNotAllowed, Error: check.Reason, Blocked: true}
+			continue
+		}
+		runnable = append(runnable, i)
+	}
+
+	sem := make(chan struct{}, maxParallel)
+	for _, batch := range toolBatches(calls, runnable) {
+		var wg sync.WaitGroup
+		for _, i := range batch {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i] = executor.Run(ctx, calls[i])
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	return results
+}
+
+// toolBatches splits the runnable call positions into batches run one after
+// another: each run of consecutive read-only calls is one batch, and every
+// other call is a batch of its own.
+func toolBatches(calls []ToolCall, runnable []int) [][]int {
+	var batches [][]int
+	for _, i := range runnable {
+		n := len(batches)
+		if isReadOnlyTool(calls[i].Function.Name) && n > 0 && isReadOnlyTool(calls[batches[n-1][0]].Function.Name) {
+			batches[n-1] = append(batches[n-1], i)
+			continue
+		}
+		batches = append(batches, []int{i})
+	}
+	return batches
+}
+
+// isReadOnlyTool returns true for tools that cannot change the workspace and
+// so may run concurrently.
+func isReadOnlyTool(name string) bool {
+	return name == "read_file" || name == "glob" || name == "grep"
+}
+
 // looksLikeNarration detects when the LLM generated a planning/narration
 // response instead of summarizing tool results. Common with smaller models
 // that describe what they'll do instead of reporting what happened.
@@ -309,7 +474,7 @@ func looksLikeNarration(response string) bool {
 }
 
 // logToolToHistory extracts command info and logs to the role's history JSONL.
-func logToolToHistory(bus *BusClient, tc ToolCall, result string) {
+func logToolToHistory(bus *BusClient, tc ToolCall, result ToolResult) {
 	var args struct {
 		Command string `json:"command"`
 	}
@@ -321,22 +486,5 @@ func logToolToHistory(bus *BusClient, tc ToolCall, result string) {
 		}
 	}
 
-	outcome := "success"
-	exitCode := "0"
-	if strings.Contains(result, "timed out") {
-		outcome = "failure"
-		exitCode = "124"
-	} else if strings.Contains(result, "not allowed") {
-		outcome = "failure"
-		exitCode = "126"
-	} else if idx := strings.LastIndex(result, "Exit code: "); idx >= 0 {
-		outcome = "failure"
-		code := strings.TrimSpace(result[idx+len("Exit code: "):])
-		if nl := strings.IndexByte(code, '\n'); nl >= 0 {
-			code = code[:nl]
-		}
-		exitCode = code
-	}
-
-	_ = bus.LogHistory(args.Command, result, exitCode, outcome)
+	_ = bus.LogHistory(args.Command, result)
 }
@@ -3,6 +3,7 @@ package harness
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -193,7 +194,7 @@ func TestLogToolToHistory(t *testing.T) {
 		},
 	}
 
-	logToolToHistory(bus, tc, "On branch main\nnothing to commit")
+	logToolToHistory(bus, tc, ToolResult{Tool: "bash", Output: "On branch main\nnothing to commit", DurationMs: 12})
 
 	data, err := os.ReadFile(filepath.Join(dir, "commit-history.jsonl"))
 	if err != nil {
@@ -222,7 +223,7 @@ func TestLogToolToHistory_Failure(t *testing.T) {
 		},
 	}
 
-	logToolToHistory(bus, tc, "error: failed to push\nExit code: 1")
+	logToolToHistory(bus, tc, ToolResult{Tool: "bash", Output: "error: failed to push", ExitCode: 1})
 
 	data, _ := os.ReadFile(filepath.Join(dir, "commit-history.jsonl"))
 	var entry map[string]interface{}
@@ -236,6 +237,76 @@ func TestLogToolToHistory_Failure(t *testing.T) {
 	}
 }
 
+func TestExecuteToolCalls_BashRunsInOrder(t *testing.T) {
+	executor := NewExecutor([]string{"Bash(sleep *)"})
+	filter := NewFilter("build")
+	out := filepath.Join(t.TempDir(), "out")
+
+	// The first call sleeps longest; run concurrently, it would append last.
+	var calls []ToolCall
+	for i, secs := range []string{"0.3", "0.1", "0"} {
+		calls = append(calls, ToolCall{
+			ID: fmt.Sprintf("call_%d", i),
+			Function: FunctionCall{
+				Name:      "bash",
+				Arguments: json.RawMessage(`{"command":"sleep ` + secs + ` && echo ` + secs + ` >> ` + out + ` && echo ` + secs + `"}`),
+			},
+		})
+	}
+
+	results := executeToolCalls(context.Background(), executor, filter, calls, 3)
+
+	for i, want := range []string{"0.3", "0.1", "0"} {
+		if strings.TrimSpace(results[i].Output) != want {
+			t.Errorf("results[%d] = %q, want %q", i, results[i].Output, want)
+		}
+	}
+	data, _ := os.ReadFile(out)
+	if got := strings.Fields(string(data)); strings.Join(got, " ") != "0.3 0.1 0" {
+		t.Errorf("bash calls ran out of order: %v", got)
+	}
+}
+
+func TestToolBatches(t *testing.T) {
+	var calls []ToolCall
+	for _, name := range []string{"read_file", "grep", "bash", "glob", "read_file", "write_file", "edit_file", "grep"} {
+		calls = append(calls, ToolCall{Function: FunctionCall{Name: name}})
+	}
+
+	got := fmt.Sprint(toolBatches(calls, []int{0, 1, 2, 3, 4, 5, 6, 7}))
+	if want := "[[0 1] [2] [3 4] [5] [6] [7]]"; got != want {
+		t.Errorf("toolBatches = %s, want %s", got, want)
+	}
+	// Blocked calls are skipped, so the reads around them share a batch.
+	got = fmt.Sprint(toolBatches(calls, []int{0, 1, 3, 4}))
+	if want := "[[0 1 3 4]]"; got != want {
+		t.Errorf("toolBatches without bash = %s, want %s", got, want)
+	}
+}
+
+func TestExecuteToolCalls_BlockedKeepPosition(t *testing.T) {
+	executor := NewExecutor([]string{"Bash(echo *)", "Bash(muxcode-agent-bus *)"})
+	filter := NewFilter("build")
+
+	calls := []ToolCall{
+		{ID: "a", Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"echo first"}`)}},
+		{ID: "b", Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"muxcode-agent-bus inbox"}`)}},
+		{ID: "c", Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"echo third"}`)}},
+	}
+
+	results := executeToolCalls(context.Background(), executor, filter, calls, 1)
+
+	if results[0].Blocked || strings.TrimSpace(results[0].Output) != "first" {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if !results[1].Blocked || !strings.Contains(results[1].Error, "BLOCKED") {
+		t.Errorf("results[1] should be blocked, got %+v", results[1])
+	}
+	if results[2].Blocked || strings.TrimSpace(results[2].Output) != "third" {
+		t.Errorf("results[2] = %+v", results[2])
+	}
+}
+
 func TestRun_ContextCancellation(t *testing.T) {
 	// Verify Run exits cleanly when context is cancelled
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -253,7 +324,7 @@ func TestRun_ContextCancellation(t *testing.T) {
 		OllamaModel: "test-model",
 		MaxTurns:    10,
 		BusDir:      t.TempDir(),
-		BusBin:      "echo", // no-op
+		BusBin:      "true", // no-op
 	}
 
 	// Create inbox dir so HasMessages works
@@ -268,6 +339,23 @@ func TestRun_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestRun_SandboxUnresolvedFailsClosed(t *testing.T) {
+	cfg := Config{
+		Role:        "build",
+		Session:     "test-sandbox",
+		OllamaURL:   "http://127.0.0.1:1",
+		OllamaModel: "test-model",
+		MaxTurns:    10,
+		BusDir:      t.TempDir(),
+		BusBin:      "false", // every bus command fails
+	}
+
+	err := Run(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "resolving sandbox") {
+		t.Errorf("Run err = %v, want sandbox resolution error", err)
+	}
+}
+
 func TestRun_HarnessMarkerLifecycle(t *testing.T) {
 	// Verify harness writes marker on startup and removes it on exit
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -286,7 +374,7 @@ func TestRun_HarnessMarkerLifecycle(t *testing.T) {
 		OllamaModel: "test-model",
 		MaxTurns:    10,
 		BusDir:      busDir,
-		BusBin:      "echo",
+		BusBin:      "true",
 	}
 
 	// Create inbox dir
@@ -8,14 +8,22 @@ import (
 
 // Message represents a bus message between agents.
 type Message struct {
-	ID      string `json:"id"`
-	TS      int64  `json:"ts"`
-	From    string `json:"from"`
-	To      string `json:"to"`
-	Type    string `json:"type"`
-	Action  string `json:"action"`
-	Payload string `json:"payload"`
-	ReplyTo string `json:"reply_to"`
+	// Format version written by the bus (see bus.MessageSchemaVersion)
+	SchemaVersion int    `json:"schema_version,omitempty"`
+	ID            string `json:"id"`
+	TS            int64  `json:"ts"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Type          string `json:"type"`
+	Action        string `json:"action"`
+	Payload       string `json:"payload"`
+	ReplyTo       string `json:"reply_to"`
+	// Trace context stamped by the bus
+	TraceID    string `json:"trace_id,omitempty"`
+	SpanID     string `json:"span_id,omitempty"`
+	ParentSpan string `json:"parent_span,omitempty"`
+	// Content-addressed files stored with the message
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 // ParseMessages parses JSONL output (one JSON object per line) into messages.
@@ -54,6 +62,9 @@ func FormatTask(msgs []Message) string {
 		b.WriteString(fmt.Sprintf("- **Action**: %s\n", m.Action))
 		b.WriteString(fmt.Sprintf("- **From**: %s\n", m.From))
 		b.WriteString(fmt.Sprintf("- **Instructions**: %s\n", m.Payload))
+		for _, a := range m.Attachments {
+			b.WriteString(fmt.Sprintf("- **Attachment**: %s (%d bytes)\n", a.Name, a.Size))
+		}
 	}
 	b.WriteString("\nExecute this task now using your available tools. Do NOT run `muxcode-agent-bus inbox`.\n")
 	return b.String()
@@ -21,6 +21,35 @@ type ChatMessage struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Images are data URLs sent with the message to vision models
+	// (see MarshalJSON).
+	Images []string `json:"-"`
+}
+
+// MarshalJSON implements custom marshaling for ChatMessage. Messages with
+// images are sent as OpenAI-style content parts (text followed by
+// image_url parts); all others keep plain string content.
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type plain ChatMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+	type imageURL struct {
+		URL string `json:"url"`
+	}
+	type part struct {
+		Type     string    `json:"type"`
+		Text     string    `json:"text,omitempty"`
+		ImageURL *imageURL `json:"image_url,omitempty"`
+	}
+	parts := []part{{Type: "text", Text: m.Content}}
+	for _, img := range m.Images {
+		parts = append(parts, part{Type: "image_url", ImageURL: &imageURL{URL: img}})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []part `json:"content"`
+	}{plain(m), parts})
 }
 
 // ToolCall represents a tool invocation requested by the model.
@@ -121,6 +150,8 @@ type OllamaClient struct {
 	Temperature float64
 	MaxTokens   int
 	HTTP        *http.Client
+	// Vision sends image attachments to the model as multimodal content
+	Vision bool
 }
 
 // NewOllamaClient creates a new Ollama client.
@@ -45,6 +45,10 @@ Your inbox has already been read — do NOT run ` + "`muxcode-agent-bus inbox`"
 3. Do NOT ask for confirmation — execute autonomously
 4. After completing, provide a short summary of what you did
 
+### Tool Results
+Each tool result is a JSON object: ` + "`exit_code`" + ` (0 = success), ` + "`duration_ms`" + `, ` + "`output`" + `, ` + "`error`" + `,
+and ` + "`truncated`" + ` / ` + "`bytes_omitted`" + ` when output was cut. Judge success by ` + "`exit_code`" + `, not by the output text.
+
 ### Sending Results
 Your final text response is automatically sent to the requesting agent as the reply.
 Do NOT use ` + "`muxcode-agent-bus send`" + ` to reply — just provide a concise summary as your last text output.
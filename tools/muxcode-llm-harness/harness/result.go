@@ -0,0 +1,92 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Tool result exit codes for failures that are not a process exit status.
+// They follow shell conventions so history and guard treat them alike.
+const (
+	ExitError      = 1   // tool error (bad arguments, missing file, ...)
+	ExitTimeout    = 124 // command timed out
+	ExitNotAllowed = 126 // blocked by tool profile, sandbox, or filter
+	ExitUnknown    = -1  // process failed without an exit status
+)
+
+// ToolResult is the structured outcome of one tool call. It is sent to the
+// model as a JSON envelope and logged to the role's history, so both see
+// the same exit code and truncation metadata.
+type ToolResult struct {
+	Tool         string `json:"tool"`
+	ExitCode     int    `json:"exit_code"`
+	DurationMs   int64  `json:"duration_ms"`
+	Truncated    bool   `json:"truncated"`
+	BytesOmitted int    `json:"bytes_omitted"`
+	Output       string `json:"output"`
+	Error        string `json:"error,omitempty"`
+	Blocked      bool   `json:"blocked,omitempty"`
+	LogPath      string `json:"log_path,omitempty"` // full output of a truncated command
+	// Start and end of the call, logged to history but not sent to the model
+	StartedAt time.Time `json:"-"`
+	EndedAt   time.Time `json:"-"`
+}
+
+// toolError returns a failed result with an error message and no output.
+func toolError(code int, format string, args ...interface{}) ToolResult {
+	return ToolResult{ExitCode: code, Error: fmt.Sprintf(format, args...)}
+}
+
+// OK reports whether the tool call succeeded.
+func (r ToolResult) OK() bool {
+	return r.ExitCode == 0 && r.Error == ""
+}
+
+// Outcome returns "success" or "failure", as recorded in history.
+func (r ToolResult) Outcome() string {
+	if r.OK() {
+		return "success"
+	}
+	return "failure"
+}
+
+// ExitCodeString returns the exit code as history records it ("unknown"
+// for ExitUnknown).
+func (r ToolResult) ExitCodeString() string {
+	if r.ExitCode == ExitUnknown {
+		return "unknown"
+	}
+	return strconv.Itoa(r.ExitCode)
+}
+
+// Text renders the result as plain text: output, a truncation marker, and
+// the error or non-zero exit code.
+func (r ToolResult) Text() string {
+	s := r.Output
+	if r.Truncated {
+		s += "\n... [output truncated]"
+		if r.LogPath != "" {
+			s += "\n[full output: " + r.LogPath + "]"
+		}
+	}
+	switch {
+	case r.Error != "" && s == "":
+		return "Error: " + r.Error
+	case r.Error != "":
+		return s + "\nError: " + r.Error
+	case r.ExitCode != 0:
+		return s + "\nExit code: " + r.ExitCodeString()
+	}
+	return s
+}
+
+// JSON renders the result as the envelope sent to the model.
+func (r ToolResult) JSON() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return r.Text()
+	}
+	return string(data)
+}
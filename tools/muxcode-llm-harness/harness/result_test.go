@@ -0,0 +1,81 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToolResultText(t *testing.T) {
+	tests := []struct {
+		r    ToolResult
+		want string
+	}{
+		{ToolResult{Output: "ok"}, "ok"},
+		{ToolResult{Output: "abc", Truncated: true, BytesOmitted: 10}, "abc\n... [output truncated]"},
+		{ToolResult{Output: "boom", ExitCode: 2}, "boom\nExit code: 2"},
+		{ToolResult{ExitCode: ExitUnknown}, "\nExit code: unknown"},
+		{toolError(ExitError, "path is required"), "Error: path is required"},
+		{ToolResult{Output: "partial", ExitCode: ExitTimeout, Error: "command timed out after 60 seconds"},
+			"partial\nError: command timed out after 60 seconds"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Text(); got != tt.want {
+			t.Errorf("Text(%+v) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestToolResultOutcome(t *testing.T) {
+	if got := (ToolResult{}).Outcome(); got != "success" {
+		t.Errorf("zero result outcome = %q", got)
+	}
+	if got := (ToolResult{ExitCode: 3}).Outcome(); got != "failure" {
+		t.Errorf("exit 3 outcome = %q", got)
+	}
+	// An error with exit code 0 still counts as a failure
+	if got := (ToolResult{Error: "x"}).Outcome(); got != "failure" {
+		t.Errorf("error outcome = %q", got)
+	}
+}
+
+func TestRun_StructuredBash(t *testing.T) {
+	e := &Executor{Patterns: []string{"Bash(sh *)"}}
+	call := ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"sh -c 'echo hi; exit 3'"}`)}}
+
+	r := e.Run(context.Background(), call)
+	if r.Tool != "bash" || r.ExitCode != 3 || strings.TrimSpace(r.Output) != "hi" || r.DurationMs < 0 {
+		t.Errorf("result = %+v", r)
+	}
+
+	var env map[string]interface{}
+	if err := json.Unmarshal([]byte(r.JSON()), &env); err != nil {
+		t.Fatalf("JSON(): %v", err)
+	}
+	for _, key := range []string{"tool", "exit_code", "duration_ms", "truncated", "bytes_omitted", "output"} {
+		if _, ok := env[key]; !ok {
+			t.Errorf("envelope missing %q: %s", key, r.JSON())
+		}
+	}
+}
+
+func TestRun_TruncationMetadata(t *testing.T) {
+	e := &Executor{Patterns: []string{"Bash(python3 *)"}}
+	call := ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"python3 -c \"print('x' * 10499)\""}`)}}
+
+	r := e.Run(context.Background(), call)
+	if !r.Truncated || r.BytesOmitted != 500 || len(r.Output) != MaxOutputLen {
+		t.Errorf("truncated = %v, bytes_omitted = %d, output = %d bytes", r.Truncated, r.BytesOmitted, len(r.Output))
+	}
+}
+
+func TestRun_NotAllowed(t *testing.T) {
+	e := &Executor{Patterns: []string{"Bash(git *)"}}
+	call := ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"rm -rf /tmp/x"}`)}}
+
+	r := e.Run(context.Background(), call)
+	if r.ExitCode != ExitNotAllowed || !strings.Contains(r.Error, "not allowed") {
+		t.Errorf("result = %+v", r)
+	}
+}
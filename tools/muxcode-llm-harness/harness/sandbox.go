@@ -0,0 +1,294 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxPolicy mirrors the per-role "sandbox" block in muxcode.json, as
+// returned by `muxcode-agent-bus tools <role> --sandbox`.
+type SandboxPolicy struct {
+	Jail        string   `json:"jail,omitempty"`
+	DenyNetwork bool     `json:"deny_network,omitempty"`
+	ReadOnly    []string `json:"read_only,omitempty"`
+	MaxOutput   int      `json:"max_output,omitempty"`
+}
+
+// Sandbox enforces a SandboxPolicy for tool execution. It is a policy layer
+// over the executor, not a kernel-level jail: paths are resolved (including
+// symlinks) and checked against the jail root and read-only list, and bash
+// commands are scanned for network tools and out-of-jail paths before running.
+type Sandbox struct {
+	Root        string   // absolute jail root
+	WorkDir     string   // base for relative paths
+	ReadOnly    []string // absolute read-only paths
+	DenyNetwork bool
+	MaxOutput   int
+}
+
+// NewSandbox builds a Sandbox from a policy, resolving relative paths
+// against workDir.
+func NewSandbox(policy SandboxPolicy, workDir string) *Sandbox {
+	s := &Sandbox{
+		WorkDir:     workDir,
+		DenyNetwork: policy.DenyNetwork,
+		MaxOutput:   policy.MaxOutput,
+	}
+	jail := policy.Jail
+	if jail == "" {
+		jail = "."
+	}
+	s.Root = realPath(s.abs(jail))
+	for _, p := range policy.ReadOnly {
+		s.ReadOnly = append(s.ReadOnly, realPath(s.abs(p)))
+	}
+	return s
+}
+
+// abs resolves a path against the sandbox working directory.
+func (s *Sandbox) abs(path string) string {
+	if strings.HasPrefix(path, "~/") || path == "~" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.WorkDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// realPath resolves symlinks in the longest existing prefix of path, so
+// links inside the jail cannot point tools outside it.
+func realPath(path string) string {
+	rest := ""
+	p := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// within returns true if path is dir or inside it.
+func within(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// sandboxDevices are paths commands may reference regardless of the jail.
+var sandboxDevices = map[string]bool{
+	"/dev/null":   true,
+	"/dev/stdin":  true,
+	"/dev/stdout": true,
+	"/dev/stderr": true,
+}
+
+// CheckPath returns an error if path escapes the jail, or if write is true
+// and path falls under a read-only entry.
+func (s *Sandbox) CheckPath(path string, write bool) error {
+	if sandboxDevices[path] {
+		return nil
+	}
+	resolved := realPath(s.abs(path))
+	if !within(resolved, s.Root) {
+		return fmt.Errorf("path %s is outside the sandbox jail %s", path, s.Root)
+	}
+	if write {
+		for _, ro := range s.ReadOnly {
+			if within(resolved, ro) {
+				return fmt.Errorf("path %s is read-only", path)
+			}
+		}
+	}
+	return nil
+}
+
+// sandboxNetworkCommands are executables treated as network access.
+var sandboxNetworkCommands = map[string]bool{
+	"curl": true, "wget": true, "ssh": true, "scp": true, "sftp": true,
+	"rsync": true, "nc": true, "ncat": true, "netcat": true, "telnet": true,
+	"ftp": true, "ping": true, "dig": true, "nslookup": true, "host": true,
+	"gh": true,
+}
+
+// sandboxNetworkSubcommands are tool subcommands that reach the network.
+var sandboxNetworkSubcommands = map[string][]string{
+	"git":    {"push", "pull", "fetch", "clone", "ls-remote"},
+	"npm":    {"install", "i", "ci", "add", "publish", "update"},
+	"yarn":   {"install", "add", "publish", "upgrade"},
+	"pnpm":   {"install", "i", "add", "publish", "update"},
+	"pip":    {"install", "download"},
+	"pip3":   {"install", "download"},
+	"go":     {"get", "install"},
+	"cargo":  {"install", "publish", "fetch"},
+	"docker": {"pull", "push", "login"},
+}
+
+// sandboxWriteCommands are executables whose path arguments are writes.
+var sandboxWriteCommands = map[string]bool{
+	"rm": true, "rmdir": true, "mv": true, "cp": true, "touch": true,
+	"mkdir": true, "tee": true, "chmod": true, "chown": true, "ln": true,
+	"truncate": true,
+}
+
+// CheckCommand scans a bash command and returns an error describing the
+// first sandbox violation: a network command when network is denied, a path
+// argument outside the jail, or a write to a read-only path.
+func (s *Sandbox) CheckCommand(command string) error {
+	for _, seg := range splitShellSegments(command) {
+		words := strings.Fields(seg)
+		// Skip env assignments and wrappers to find the executable
+		for len(words) > 0 && (strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-") ||
+			words[0] == "sudo" || words[0] == "env" || words[0] == "command" || words[0] == "exec") {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		exe := filepath.Base(unquote(words[0]))
+
+		if s.DenyNetwork {
+			if sandboxNetworkCommands[exe] {
+				return fmt.Errorf("network access denied: %s", exe)
+			}
+			if subs, ok := sandboxNetworkSubcommands[exe]; ok {
+				for i := 1; i < len(words); i++ {
+					w := words[i]
+					if w == "-C" || w == "-c" {
+						i++ // global flag with a value (git -C dir, git -c key=val)
+						continue
+					}
+					if strings.HasPrefix(w, "-") {
+						continue
+					}
+					for _, sub := range subs {
+						if w == sub {
+							return fmt.Errorf("network access denied: %s %s", exe, sub)
+						}
+					}
+					break
+				}
+			}
+		}
+
+		if err := s.checkArgs(exe, words[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkArgs checks the path arguments and redirect targets of one command.
+// For cp and ln only the last argument is a write; for other write commands
+// (and sed -i) every argument is.
+func (s *Sandbox) checkArgs(exe string, words []string) error {
+	writesAll := sandboxWriteCommands[exe] && exe != "cp" && exe != "ln" ||
+		exe == "sed" && hasFlag(words, "-i")
+	writesLast := exe == "cp" || exe == "ln"
+
+	var paths []string
+	var redirects []string
+	for i := 0; i < len(words); i++ {
+		w := unquote(words[i])
+		switch {
+		case w == ">" || w == ">>" || w == "2>" || w == "&>":
+			if i+1 < len(words) {
+				redirects = append(redirects, unquote(words[i+1]))
+				i++
+			}
+		case strings.HasPrefix(w, ">") || strings.HasPrefix(w, "2>") || strings.HasPrefix(w, "&>"):
+			redirects = append(redirects, strings.TrimLeft(w, "0123456789&>"))
+		case strings.HasPrefix(w, "-"):
+			// --out=/path style flag values
+			if j := strings.Index(w, "="); j >= 0 && looksLikePath(w[j+1:]) {
+				paths = append(paths, w[j+1:])
+			}
+		case w != "" && !strings.HasPrefix(w, "$"):
+			paths = append(paths, w)
+		}
+	}
+
+	for i, p := range paths {
+		write := writesAll || writesLast && i == len(paths)-1
+		if !write && !looksLikePath(p) {
+			continue
+		}
+		if err := s.CheckPath(p, write); err != nil {
+			return err
+		}
+	}
+	for _, p := range redirects {
+		if strings.HasPrefix(p, "&") || p == "" {
+			continue // fd duplication (2>&1)
+		}
+		if err := s.CheckPath(p, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Env returns the environment for sandboxed commands. With network denied,
+// proxy variables point at a closed local port so HTTP clients fail fast.
+func (s *Sandbox) Env() []string {
+	env := os.Environ()
+	if !s.DenyNetwork {
+		return env
+	}
+	const blackhole = "http://127.0.0.1:9"
+	for _, k := range []string{"http_proxy", "https_proxy", "HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "all_proxy"} {
+		env = append(env, k+"="+blackhole)
+	}
+	return append(env, "NO_PROXY=", "no_proxy=")
+}
+
+// Limit returns the output cap for sandboxed tools, never exceeding MaxOutputLen.
+func (s *Sandbox) Limit() int {
+	if s.MaxOutput > 0 && s.MaxOutput < MaxOutputLen {
+		return s.MaxOutput
+	}
+	return MaxOutputLen
+}
+
+// splitShellSegments splits a command on shell operators and substitutions
+// so each segment starts with an executable.
+func splitShellSegments(command string) []string {
+	r := strings.NewReplacer("&&", "\n", "||", "\n", ";", "\n", "|", "\n",
+		"$(", "\n", "`", "\n", "(", "\n", ")", "\n")
+	return strings.Split(r.Replace(command), "\n")
+}
+
+// unquote strips surrounding single or double quotes from a shell word.
+func unquote(w string) string {
+	if len(w) >= 2 && (w[0] == '"' || w[0] == '\'') && w[len(w)-1] == w[0] {
+		return w[1 : len(w)-1]
+	}
+	return w
+}
+
+// looksLikePath returns true for words that reference the filesystem
+// outside the current directory: absolute, home-relative, or parent paths.
+func looksLikePath(w string) bool {
+	return strings.HasPrefix(w, "/") || strings.HasPrefix(w, "~") ||
+		w == ".." || strings.HasPrefix(w, "../") || strings.Contains(w, "/../")
+}
+
+// hasFlag returns true if words contains flag or a flag starting with it.
+func hasFlag(words []string, flag string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, flag) {
+			return true
+		}
+	}
+	return false
+}
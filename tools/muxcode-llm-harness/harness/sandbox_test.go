@@ -0,0 +1,151 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSandbox(t *testing.T, policy SandboxPolicy) (*Sandbox, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return NewSandbox(policy, dir), dir
+}
+
+func TestSandboxCheckPath(t *testing.T) {
+	s, dir := newTestSandbox(t, SandboxPolicy{ReadOnly: []string{"vendor"}})
+
+	tests := []struct {
+		path    string
+		write   bool
+		wantErr bool
+	}{
+		{"main.go", false, false},
+		{"main.go", true, false},
+		{filepath.Join(dir, "src/a.go"), true, false},
+		{"../outside.txt", false, true},
+		{"/etc/passwd", false, true},
+		{"vendor/lib.go", false, false},
+		{"vendor/lib.go", true, true},
+		{"vendor", true, true},
+		{"/dev/null", true, false},
+	}
+	for _, tt := range tests {
+		err := s.CheckPath(tt.path, tt.write)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckPath(%q, %v) err = %v, wantErr %v", tt.path, tt.write, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSandboxCheckPath_SymlinkEscape(t *testing.T) {
+	s, dir := newTestSandbox(t, SandboxPolicy{})
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+
+	if err := s.CheckPath("link/file.txt", true); err == nil {
+		t.Error("expected symlink escape to be rejected")
+	}
+}
+
+func TestSandboxCheckCommand(t *testing.T) {
+	s, _ := newTestSandbox(t, SandboxPolicy{DenyNetwork: true, ReadOnly: []string{"vendor"}})
+
+	tests := []struct {
+		command string
+		wantErr string
+	}{
+		{"go test ./...", ""},
+		{"git status && git diff", ""},
+		{"ls -la 2>&1 | head -5", ""},
+		{"echo hi > /dev/null", ""},
+		{"curl https://example.com", "network"},
+		{"FOO=1 wget -q x", "network"},
+		{"git -C . push origin main", "network"},
+		{"make build; npm install", "network"},
+		{"cat /etc/hosts", "outside"},
+		{"cd .. && ls", "outside"},
+		{"echo x > ../out.txt", "outside"},
+		{"rm -rf vendor/pkg", "read-only"},
+		{"echo x >> vendor/file", "read-only"},
+		{"cp vendor/a.go src/a.go", ""},
+		{"cp src/a.go vendor/a.go", "read-only"},
+		{"sed -i 's/a/b/' vendor/x.go", "read-only"},
+	}
+	for _, tt := range tests {
+		err := s.CheckCommand(tt.command)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("CheckCommand(%q) = %v, want nil", tt.command, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("CheckCommand(%q) = %v, want error containing %q", tt.command, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSandboxCheckCommand_NetworkAllowed(t *testing.T) {
+	s, _ := newTestSandbox(t, SandboxPolicy{})
+	if err := s.CheckCommand("git push origin main"); err != nil {
+		t.Errorf("expected network command allowed without deny_network, got %v", err)
+	}
+}
+
+func TestSandboxEnv_DenyNetwork(t *testing.T) {
+	s, _ := newTestSandbox(t, SandboxPolicy{DenyNetwork: true})
+	env := strings.Join(s.Env(), "\n")
+	if !strings.Contains(env, "HTTPS_PROXY=http://127.0.0.1:9") {
+		t.Error("expected blackhole HTTPS_PROXY in sandbox env")
+	}
+}
+
+func TestSandboxLimit(t *testing.T) {
+	if got := (&Sandbox{MaxOutput: 100}).Limit(); got != 100 {
+		t.Errorf("Limit() = %d, want 100", got)
+	}
+	if got := (&Sandbox{}).Limit(); got != MaxOutputLen {
+		t.Errorf("Limit() = %d, want %d", got, MaxOutputLen)
+	}
+	if got := (&Sandbox{MaxOutput: MaxOutputLen * 2}).Limit(); got != MaxOutputLen {
+		t.Errorf("Limit() = %d, want cap at %d", got, MaxOutputLen)
+	}
+}
+
+func TestExecute_SandboxViolations(t *testing.T) {
+	s, dir := newTestSandbox(t, SandboxPolicy{ReadOnly: []string{"vendor"}, MaxOutput: 10})
+	e := &Executor{
+		Patterns: []string{"Bash(*)", "Read", "Write"},
+		WorkDir:  dir,
+		Sandbox:  s,
+	}
+
+	call := func(name, args string) string {
+		return e.Execute(context.Background(), ToolCall{
+			Function: FunctionCall{Name: name, Arguments: json.RawMessage(args)},
+		})
+	}
+
+	if got := call("read_file", `{"path":"/etc/hostname"}`); !strings.Contains(got, "sandbox violation") {
+		t.Errorf("read outside jail = %q, want sandbox violation", got)
+	}
+	ro := filepath.Join(dir, "vendor", "x.txt")
+	if got := call("write_file", `{"path":"`+ro+`","content":"x"}`); !strings.Contains(got, "read-only") {
+		t.Errorf("write to read-only = %q, want read-only violation", got)
+	}
+	if got := call("bash", `{"command":"cat /etc/hostname"}`); !strings.Contains(got, "sandbox violation") {
+		t.Errorf("bash outside jail = %q, want sandbox violation", got)
+	}
+
+	// Output capped at MaxOutput
+	got := call("bash", `{"command":"echo abcdefghijklmnopqrstuvwxyz"}`)
+	if !strings.HasPrefix(got, "abcdefghij\n... [output truncated]") {
+		t.Errorf("bash output = %q, want truncated at 10 bytes", got)
+	}
+}
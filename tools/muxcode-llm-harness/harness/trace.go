@@ -0,0 +1,56 @@
+package harness
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Span mirrors the bus trace log entry (muxcode-agent-bus bus.Span).
+// Times are Unix milliseconds.
+type Span struct {
+	TraceID  string            `json:"trace_id"`
+	SpanID   string            `json:"span_id"`
+	ParentID string            `json:"parent_id,omitempty"`
+	Name     string            `json:"name"`
+	Role     string            `json:"role"`
+	Start    int64             `json:"start_ms"`
+	End      int64             `json:"end_ms"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+}
+
+// newTurnSpan starts a span for processing a batch, parented to the last
+// message's span. A batch of untraced messages starts a new trace.
+func newTurnSpan(role, model string, msgs []Message) Span {
+	last := msgs[len(msgs)-1]
+	span := Span{
+		TraceID:  last.TraceID,
+		SpanID:   randomHex(8),
+		ParentID: last.SpanID,
+		Name:     fmt.Sprintf("turn %s %s", role, last.Action),
+		Role:     role,
+		Start:    time.Now().UnixMilli(),
+		Attrs: map[string]string{
+			"harness.model":    model,
+			"harness.messages": strconv.Itoa(len(msgs)),
+		},
+	}
+	if span.TraceID == "" {
+		span.TraceID = randomHex(16)
+		span.ParentID = ""
+	}
+	return span
+}
+
+// traceParent returns the span as a W3C traceparent header value.
+func (s Span) traceParent() string {
+	return "00-" + s.TraceID + "-" + s.SpanID + "-01"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
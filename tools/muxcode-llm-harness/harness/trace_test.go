@@ -0,0 +1,63 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTurnSpan(t *testing.T) {
+	msgs := []Message{
+		{ID: "m1", Action: "build"},
+		{ID: "m2", Action: "test", TraceID: strings.Repeat("a", 32), SpanID: strings.Repeat("b", 16)},
+	}
+	span := newTurnSpan("build", "qwen2.5:7b", msgs)
+	if span.TraceID != msgs[1].TraceID || span.ParentID != msgs[1].SpanID || len(span.SpanID) != 16 {
+		t.Errorf("span = %+v", span)
+	}
+	if span.Name != "turn build test" || span.Attrs["harness.messages"] != "2" {
+		t.Errorf("span name/attrs = %q %v", span.Name, span.Attrs)
+	}
+	if want := "00-" + span.TraceID + "-" + span.SpanID + "-01"; span.traceParent() != want {
+		t.Errorf("traceParent = %q, want %q", span.traceParent(), want)
+	}
+
+	untraced := newTurnSpan("build", "m", msgs[:1])
+	if len(untraced.TraceID) != 32 || untraced.ParentID != "" {
+		t.Errorf("untraced span = %+v", untraced)
+	}
+}
+
+func TestLogSpan(t *testing.T) {
+	dir := t.TempDir()
+	bc := &BusClient{BusDir: dir, Role: "build"}
+
+	span := newTurnSpan("build", "m", []Message{{Action: "build"}})
+	if err := bc.LogSpan(span); err != nil {
+		t.Fatalf("LogSpan: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "trace.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Span
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &got); err != nil || got.SpanID != span.SpanID {
+		t.Errorf("logged span = %s (%v)", data, err)
+	}
+}
+
+func TestExecuteBash_TraceParent(t *testing.T) {
+	e := &Executor{Patterns: []string{"Bash(echo *)"}, TraceParent: "00-abc-def-01"}
+	call := ToolCall{
+		Function: FunctionCall{
+			Name:      "bash",
+			Arguments: json.RawMessage(`{"command":"echo $TRACEPARENT"}`),
+		},
+	}
+	if result := e.Execute(context.Background(), call); !strings.Contains(result, "00-abc-def-01") {
+		t.Errorf("result = %q, want TRACEPARENT exported", result)
+	}
+}
@@ -0,0 +1,111 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default command output line budget: the first and last N lines are kept
+// when output runs long.
+const (
+	DefaultOutputHead = 50
+	DefaultOutputTail = 50
+	// maxErrorLines caps the error-looking lines kept from the middle.
+	maxErrorLines = 20
+)
+
+// errorLinePattern matches lines worth keeping from the omitted middle of
+// long output.
+var errorLinePattern = regexp.MustCompile(`(?i)error|fail|panic|fatal`)
+
+// truncateLines keeps the first head and last tail lines of out plus up to
+// maxErrorLines error-looking lines between them. It returns out unchanged
+// when it fits in head+tail lines, or when the omission marker and kept
+// error lines would be no shorter than the middle they replace.
+func truncateLines(out string, head, tail int) (string, bool) {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) <= head+tail {
+		return out, false
+	}
+
+	middle := lines[head : len(lines)-tail]
+	var errs []string
+	for i, line := range middle {
+		if errorLinePattern.MatchString(line) {
+			errs = append(errs, fmt.Sprintf("L%d: %s", head+i+1, line))
+			if len(errs) == maxErrorLines {
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range lines[:head] {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(fmt.Sprintf("... [%d lines omitted", len(middle)))
+	if len(errs) > 0 {
+		b.WriteString(fmt.Sprintf("; %d error line(s) kept", len(errs)))
+	}
+	b.WriteString("]\n")
+	for _, line := range errs {
+		b.WriteString(line + "\n")
+	}
+	if len(errs) > 0 {
+		b.WriteString("...\n")
+	}
+	for _, line := range lines[len(lines)-tail:] {
+		b.WriteString(line + "\n")
+	}
+	if b.Len() >= len(out) {
+		return out, false
+	}
+	return b.String(), true
+}
+
+// captureCommand wraps command output like capture, first trimming long
+// output to its head, tail, and error lines. The full output is saved to a
+// log under OutputLogDir and referenced in the result.
+func (e *Executor) captureCommand(out string) ToolResult {
+	head, tail := e.OutputHead, e.OutputTail
+	if head <= 0 {
+		head = DefaultOutputHead
+	}
+	if tail <= 0 {
+		tail = DefaultOutputTail
+	}
+
+	kept, trimmed := truncateLines(out, head, tail)
+	r := e.capture(kept)
+	if !trimmed && !r.Truncated {
+		return r
+	}
+	r.Truncated = true
+	r.BytesOmitted = max(len(out)-len(r.Output), 0)
+	if path, err := e.saveOutput(out); err == nil {
+		r.LogPath = path
+	} else if e.OutputLogDir != "" {
+		fmt.Fprintf(os.Stderr, "[harness] could not save full output: %v\n", err)
+	}
+	return r
+}
+
+// saveOutput writes full command output to a proc-style log file.
+func (e *Executor) saveOutput(out string) (string, error) {
+	if e.OutputLogDir == "" {
+		return "", fmt.Errorf("no output log directory")
+	}
+	if err := os.MkdirAll(e.OutputLogDir, 0755); err != nil {
+		return "", err
+	}
+	role := e.Role
+	if role == "" {
+		role = "harness"
+	}
+	path := filepath.Join(e.OutputLogDir, fmt.Sprintf("tool-%s-%d.log", role, time.Now().UnixNano()))
+	return path, os.WriteFile(path, []byte(out), 0644)
+}
@@ -0,0 +1,126 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n lines "line 1".."line n", with "panic: boom" at
+// line panicAt (0 = none).
+func numberedLines(n, panicAt int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if i == panicAt {
+			b.WriteString("panic: boom\n")
+			continue
+		}
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func TestTruncateLines_Short(t *testing.T) {
+	out := numberedLines(10, 0)
+	got, trimmed := truncateLines(out, 5, 5)
+	if trimmed || got != out {
+		t.Errorf("10 lines with 5+5 budget: trimmed = %v", trimmed)
+	}
+}
+
+func TestTruncateLines_KeepsHeadTailAndErrors(t *testing.T) {
+	got, trimmed := truncateLines(numberedLines(100, 40), 3, 2)
+	if !trimmed {
+		t.Fatal("expected trimming")
+	}
+	want := "line 1\nline 2\nline 3\n" +
+		"... [95 lines omitted; 1 error line(s) kept]\n" +
+		"L40: panic: boom\n...\n" +
+		"line 99\nline 100\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTruncateLines_MarkerLongerThanMiddle(t *testing.T) {
+	// head+tail+1 short lines: the marker would outgrow the one line it drops.
+	out := strings.Repeat("x\n", 101)
+	got, trimmed := truncateLines(out, 50, 50)
+	if trimmed || got != out {
+		t.Errorf("101 one-byte lines with 50+50 budget: trimmed = %v, %d bytes", trimmed, len(got))
+	}
+
+	e := &Executor{OutputHead: 50, OutputTail: 50}
+	if r := e.captureCommand(out); r.Truncated || r.BytesOmitted != 0 || r.Output != out {
+		t.Errorf("captureCommand: truncated = %v, bytes_omitted = %d", r.Truncated, r.BytesOmitted)
+	}
+
+	// Once the middle outweighs the marker, output is trimmed and smaller.
+	long := strings.Repeat(strings.Repeat("x", 80)+"\n", 101)
+	got, trimmed = truncateLines(long, 50, 50)
+	if !trimmed || len(got) >= len(long) {
+		t.Errorf("long lines: trimmed = %v, %d of %d bytes", trimmed, len(got), len(long))
+	}
+}
+
+func TestTruncateLines_CapsErrorLines(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString("FAIL something\n")
+	}
+	got, _ := truncateLines(b.String(), 1, 1)
+	if n := strings.Count(got, "FAIL"); n != maxErrorLines+2 {
+		t.Errorf("FAIL lines kept = %d, want %d", n, maxErrorLines+2)
+	}
+}
+
+func TestCaptureCommand_SavesFullOutput(t *testing.T) {
+	dir := t.TempDir()
+	e := &Executor{OutputHead: 2, OutputTail: 2, OutputLogDir: dir, Role: "build"}
+	out := numberedLines(30, 15)
+
+	r := e.captureCommand(out)
+	if !r.Truncated || r.BytesOmitted != len(out)-len(r.Output) {
+		t.Errorf("truncated = %v, bytes_omitted = %d", r.Truncated, r.BytesOmitted)
+	}
+	if !strings.Contains(r.Output, "L15: panic: boom") {
+		t.Errorf("error line not kept:\n%s", r.Output)
+	}
+	if !strings.HasPrefix(r.LogPath, dir+"/tool-build-") {
+		t.Fatalf("LogPath = %q", r.LogPath)
+	}
+	if data, err := os.ReadFile(r.LogPath); err != nil || string(data) != out {
+		t.Errorf("saved output mismatch: %v", err)
+	}
+	if !strings.Contains(r.Text(), "[full output: "+r.LogPath+"]") {
+		t.Errorf("Text() missing log reference:\n%s", r.Text())
+	}
+}
+
+func TestCaptureCommand_ShortOutputUntouched(t *testing.T) {
+	dir := t.TempDir()
+	e := &Executor{OutputLogDir: dir}
+	r := e.captureCommand("ok\n")
+	if r.Truncated || r.LogPath != "" || r.Output != "ok\n" {
+		t.Errorf("result = %+v", r)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("short output should not be saved, found %d file(s)", len(entries))
+	}
+}
+
+func TestExecuteBash_SmartTruncation(t *testing.T) {
+	e := &Executor{Patterns: []string{"Bash(seq *)"}, OutputHead: 3, OutputTail: 3, OutputLogDir: t.TempDir(), Role: "test"}
+	call := ToolCall{Function: FunctionCall{Name: "bash", Arguments: json.RawMessage(`{"command":"seq 1 500"}`)}}
+
+	r := e.Run(context.Background(), call)
+	if !strings.HasPrefix(r.Output, "1\n2\n3\n... [494 lines omitted]") || !strings.HasSuffix(r.Output, "498\n499\n500\n") {
+		t.Errorf("output:\n%s", r.Output)
+	}
+	if r.LogPath == "" {
+		t.Error("LogPath should reference the full output")
+	}
+}
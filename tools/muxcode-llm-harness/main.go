@@ -13,7 +13,7 @@ import (
 
 func main() {
 	if len(os.Args) < 3 || os.Args[1] != "run" {
-		fmt.Fprintf(os.Stderr, "Usage: muxcode-llm-harness run <role> [--model MODEL] [--url URL] [--max-turns N]\n")
+		fmt.Fprintf(os.Stderr, "Usage: muxcode-llm-harness run <role> [--model MODEL] [--url URL] [--max-turns N] [--parallel N]\n")
 		os.Exit(1)
 	}
 
@@ -44,6 +44,13 @@ func main() {
 				}
 				i++
 			}
+		case "--parallel":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil && n > 0 {
+					cfg.MaxParallelTools = n
+				}
+				i++
+			}
 		}
 	}
 